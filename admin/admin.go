@@ -25,18 +25,32 @@ func AdminHandler(w http.ResponseWriter, r *http.Request) {
 	users := auth.GetAllAccounts()
 
 	content := `<div class="admin-links">
-		<a href="/admin/usage">API Usage</a>
+		<a href="/admin/usage/api">API Usage</a>
 		<a href="/admin/api">API Log</a>
+		<a href="/admin/audit">Audit Log</a>
 		<a href="/admin/blocklist">Blocklist</a>
+		<a href="/admin/rooms">Chat Rooms</a>
+		<a href="/admin/config">Config</a>
 		<a href="/admin/console">Console</a>
+		<a href="/admin/egress">Egress</a>
 		<a href="/admin/env">Environment</a>
+		<a href="/admin/feeds">Feeds</a>
+		<a href="/admin/front">Front Page</a>
 		<a href="/admin/invite">Invites</a>
+		<a href="/admin/jobs">Jobs</a>
+		<a href="/admin/login">Login</a>
 		<a href="/admin/email">Mail Log</a>
 		<a href="/admin/moderate">Moderation</a>
+		<a href="/admin/oauth">OAuth</a>
+		<a href="/places/import">Place Import</a>
+		<a href="/admin/plugins">Plugins</a>
+		<a href="/admin/ratelimit">Rate Limits</a>
 		<a href="/admin/server">Server</a>
 		<a href="/admin/spam">Spam Filter</a>
-		<a href="/admin/log">System Log</a>
+		<a href="/admin/logs">System Log</a>
+		<a href="/admin/usage">Usage</a>
 		<a href="/admin/users">Users <span class="count">` + fmt.Sprintf("%d", len(users)) + `</span></a>
+		<a href="/admin/weekly">Weekly Digest</a>
 	</div>`
 
 	html := app.RenderHTMLForRequest("Admin", "Admin Dashboard", content, r)
@@ -119,6 +133,9 @@ func UsersHandler(w http.ResponseWriter, r *http.Request) {
 	sb.WriteString(`<table class="admin-table"><thead><tr><th>Username</th><th>Name</th><th class="created-col">Created</th><th>Status</th><th class="center">Actions</th></tr></thead><tbody>`)
 	for _, u := range filtered {
 		created := u.Created.Format("2006-01-02")
+		if u.InvitedBy != "" {
+			created += fmt.Sprintf(`<br><span class="text-muted" style="font-size:12px">invited by <a href="/@%s">%s</a></span>`, u.InvitedBy, u.InvitedBy)
+		}
 		var badges []string
 		if u.Admin {
 			badges = append(badges, `<span style="background:#000;color:#fff;padding:1px 6px;border-radius:8px;font-size:11px">admin</span>`)
@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// auditPageSize is how many entries /admin/audit shows.
+const auditPageSize = 200
+
+// AuditHandler serves /admin/audit: the admin audit log, newest first,
+// filtered by ?actor=, ?action= and ?target=. With ?format=json (or an
+// Accept: application/json header) it returns every matching entry as
+// JSON, as a download for the former.
+func AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := auth.RequireAdmin(r); err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+	if r.Method != "GET" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	f := app.AuditFilter{
+		Actor:  strings.TrimSpace(q.Get("actor")),
+		Action: strings.TrimSpace(q.Get("action")),
+		Target: strings.TrimSpace(q.Get("target")),
+	}
+	export := q.Get("format") == "json"
+	if export || app.WantsJSON(r) {
+		entries := app.AuditLog(f)
+		if export {
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mu-audit-%s.json"`, time.Now().UTC().Format("2006-01-02")))
+		}
+		app.RespondJSON(w, map[string]interface{}{"entries": entries})
+		return
+	}
+
+	f.Limit = auditPageSize
+	entries := app.AuditLog(f)
+
+	exportQuery := url.Values{"format": {"json"}}
+	for k, v := range map[string]string{"actor": f.Actor, "action": f.Action, "target": f.Target} {
+		if v != "" {
+			exportQuery.Set(k, v)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(`<div class="card">`)
+	b.WriteString(`<h3>Audit Log</h3>`)
+	b.WriteString(`<p class="text-sm text-muted">Every change made by an admin: who did what, to what, and when. The log is append-only.</p>`)
+	fmt.Fprintf(&b, `<form method="GET" action="/admin/audit" class="d-flex gap-3" style="flex-wrap:wrap">
+	<input name="actor" placeholder="Admin" value="%s">
+	<input name="action" placeholder="Action, e.g. ban" value="%s">
+	<input name="target" placeholder="Target" value="%s">
+	<button type="submit">Filter</button>
+	<a href="/admin/audit?%s" class="btn btn-secondary">Export JSON</a>
+</form>`, html.EscapeString(f.Actor), html.EscapeString(f.Action), html.EscapeString(f.Target), html.EscapeString(exportQuery.Encode()))
+	if len(entries) == 0 {
+		b.WriteString(`<p class="text-muted">No matching entries.</p>`)
+	} else {
+		b.WriteString(`<table class="email-log"><tr><th>Time</th><th>Admin</th><th>Action</th><th>Target</th><th class="hide-mobile">Route</th></tr>`)
+		for _, e := range entries {
+			fmt.Fprintf(&b, `<tr><td title="%s">%s</td><td><a href="/admin/audit?actor=%s">%s</a></td><td>%s</td><td>%s</td><td class="hide-mobile">%s</td></tr>`,
+				e.Time.UTC().Format(time.RFC3339), e.Time.Format("Jan 2 15:04"),
+				url.QueryEscape(e.Actor), html.EscapeString(e.Actor),
+				html.EscapeString(e.Action), html.EscapeString(e.Target), html.EscapeString(e.Path))
+		}
+		b.WriteString(`</table>`)
+		if len(entries) == auditPageSize {
+			fmt.Fprintf(&b, `<p class="text-sm text-muted">Showing the latest %d. Narrow the filter or export for the rest.</p>`, auditPageSize)
+		}
+	}
+	b.WriteString(`</div>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest("Audit Log", "Admin audit log", b.String(), r)))
+}
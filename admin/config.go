@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/env"
+	"mu/internal/settings"
+)
+
+// ConfigHandler serves /admin/config: the settings in effect, where each
+// came from and anything wrong with them. Secrets only show whether
+// they're set. Changing a value is done in /admin/env or the config file.
+func ConfigHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	var problems []string
+	for _, err := range settings.Validate() {
+		problems = append(problems, err.Error())
+	}
+
+	if app.WantsJSON(r) {
+		type configVar struct {
+			Key     string `json:"key"`
+			Value   string `json:"value,omitempty"`
+			Type    string `json:"type"`
+			Default string `json:"default,omitempty"`
+			Secret  bool   `json:"secret,omitempty"`
+			Source  string `json:"source,omitempty"`
+			Where   string `json:"where,omitempty"`
+			Doc     string `json:"doc,omitempty"`
+		}
+		groups := map[string][]configVar{}
+		for _, g := range settings.Groups() {
+			for _, v := range g.Vars {
+				groups[g.Name] = append(groups[g.Name], configVar{
+					Key:     v.Key,
+					Value:   settings.Redact(v.Key),
+					Type:    v.Type.String(),
+					Default: v.Default,
+					Secret:  settings.IsSecret(v.Key),
+					Source:  settings.Source(v.Key),
+					Where:   settings.Where(v.Key),
+					Doc:     v.Doc,
+				})
+			}
+		}
+		app.RespondJSON(w, map[string]any{
+			"file":     env.ConfigPath(),
+			"problems": problems,
+			"groups":   groups,
+		})
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(`<div class="card">`)
+	b.WriteString(`<h3>Config File</h3>`)
+	if path := env.ConfigPath(); path != "" {
+		b.WriteString(fmt.Sprintf(`<p>Read from <code>%s</code>.</p>`, html.EscapeString(path)))
+	} else {
+		b.WriteString(`<p class="text-muted">No config file. Settings come from the environment, .env and /admin/env. Put a mu.toml in ~/.mu or point MU_CONFIG at one.</p>`)
+	}
+	if len(problems) > 0 {
+		b.WriteString(`<ul style="color:#c00;">`)
+		for _, p := range problems {
+			b.WriteString(`<li>` + html.EscapeString(p) + `</li>`)
+		}
+		b.WriteString(`</ul>`)
+	}
+	b.WriteString(`</div>`)
+
+	for _, g := range settings.Groups() {
+		b.WriteString(`<div class="card">`)
+		b.WriteString(fmt.Sprintf(`<h3>%s</h3>`, html.EscapeString(g.Name)))
+		b.WriteString(`<table class="email-log"><tr><th>Setting</th><th>Value</th><th>From</th><th>Type</th><th>Default</th></tr>`)
+		for _, v := range g.Vars {
+			val := settings.Redact(v.Key)
+			if val == "" {
+				val = `<span class="text-muted">not set</span>`
+			} else {
+				val = `<code>` + html.EscapeString(val) + `</code>`
+			}
+			b.WriteString(fmt.Sprintf(`<tr><td><code title="%s">%s</code></td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+				html.EscapeString(v.Doc),
+				v.Key,
+				val,
+				html.EscapeString(settings.Where(v.Key)),
+				v.Type,
+				html.EscapeString(v.Default),
+			))
+		}
+		b.WriteString(`</table></div>`)
+	}
+
+	b.WriteString(`<p><a href="/admin/env">Edit settings</a> · <a href="/admin">← Back to Admin</a></p>`)
+
+	pageHTML := app.RenderHTMLForRequest("Config", "Settings in effect", b.String(), r)
+	w.Write([]byte(pageHTML))
+}
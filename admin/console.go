@@ -3,10 +3,12 @@ package admin
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +32,15 @@ func InviteHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		r.ParseForm()
 		action := r.FormValue("action")
+		if action == "code" {
+			uses, _ := strconv.Atoi(r.FormValue("uses"))
+			if _, err := auth.CreateInviteCode(sess.Account, uses); err != nil {
+				app.BadRequest(w, r, err.Error())
+				return
+			}
+			http.Redirect(w, r, "/admin/invite", http.StatusSeeOther)
+			return
+		}
 		email := strings.TrimSpace(r.FormValue("email"))
 		if email == "" {
 			app.BadRequest(w, r, "Email is required")
@@ -80,6 +91,13 @@ func InviteHandler(w http.ResponseWriter, r *http.Request) {
 	var sb strings.Builder
 	sb.WriteString(`<p><a href="/admin">← Admin</a></p>`)
 
+	modes := map[string]string{
+		auth.RegistrationOpen:   "open: anyone can sign up",
+		auth.RegistrationInvite: "invite only: signing up needs a code",
+		auth.RegistrationClosed: "closed: no one can sign up",
+	}
+	sb.WriteString(fmt.Sprintf(`<div class="card"><h4>Registration</h4><p>Signups are <strong>%s</strong>. Change it with REGISTRATION on <a href="/admin/config">Config</a>.</p></div>`, modes[auth.Registration()]))
+
 	requests := auth.ListInviteRequests()
 	pending := 0
 	for _, req := range requests {
@@ -126,6 +144,28 @@ func InviteHandler(w http.ResponseWriter, r *http.Request) {
 </form>
 </div>`)
 
+	sb.WriteString(fmt.Sprintf(`<div class="card" style="margin-top:16px">
+<h4>Make an invite code</h4>
+<p class="text-sm">A link to share, good for a number of signups.</p>
+<form method="POST" action="/admin/invite" class="mt-4">
+	<input type="hidden" name="action" value="code">
+	<input type="number" name="uses" value="10" min="1" max="%d" class="form-input" style="width:6em"> signups
+	<button type="submit" class="mt-2">Make code</button>
+</form>
+</div>`, auth.InviteAllowance(sess.Account)))
+
+	if list := auth.ListInvites(); len(list) > 0 {
+		sb.WriteString(`<div class="card" style="margin-top:16px"><h4>All invites</h4><table class="email-log"><tr><th>Code</th><th>By</th><th>For</th><th>Used</th><th>Joined</th><th class="hide-mobile">Created</th></tr>`)
+		for _, inv := range list {
+			sb.WriteString(fmt.Sprintf(`<tr><td class="addr"><a href="%s">%s…</a></td><td>%s</td><td>%s</td><td>%d of %d</td><td>%s</td><td class="hide-mobile">%s</td></tr>`,
+				html.EscapeString(app.PublicURL()+"/signup?invite="+inv.Code), inv.Code[:8],
+				html.EscapeString(inv.CreatedBy), html.EscapeString(inv.Email),
+				inv.Used(), inv.Limit(), html.EscapeString(strings.Join(inv.Accounts, ", ")),
+				inv.CreatedAt.Format("2 Jan 15:04")))
+		}
+		sb.WriteString(`</table></div>`)
+	}
+
 	w.Write([]byte(app.RenderHTML("Invites", "Invite requests and send invites", sb.String())))
 }
 
@@ -409,8 +449,8 @@ func runCommand(cmd string) string {
 		var sb strings.Builder
 		for _, inv := range list {
 			used := "unused"
-			if inv.UsedBy != "" {
-				used = "used by " + inv.UsedBy
+			if inv.Used() > 0 {
+				used = fmt.Sprintf("used %d of %d, last by %s", inv.Used(), inv.Limit(), inv.UsedBy)
 			}
 			sb.WriteString(fmt.Sprintf("  %s → %s (%s, %s)\n", inv.Code[:8]+"...", inv.Email, used, inv.CreatedAt.Format("2 Jan 15:04")))
 		}
@@ -445,6 +485,27 @@ func runCommand(cmd string) string {
 		wallet.AddCredits(arg(1), amount, "admin_grant", nil)
 		return fmt.Sprintf("Added %d credits to %s", amount, arg(1))
 
+	case "payments":
+		list := wallet.Payments(arg(1))
+		if len(list) == 0 {
+			return "No payments"
+		}
+		var sb strings.Builder
+		unreconciled := 0
+		for i, p := range list {
+			state := p.Status
+			if p.Reconciled() {
+				state += " → " + p.TransactionID
+			} else {
+				unreconciled++
+			}
+			if i < 20 {
+				sb.WriteString(fmt.Sprintf("  %s  %s %s  %s  %s  %s\n", p.Created.Format("2 Jan 15:04"), p.Provider, p.ExternalID, p.UserID, wallet.FormatCredits(p.Amount), state))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%d payments, %d not reconciled\n", len(list), unreconciled))
+		return sb.String()
+
 	// --- Apps ---
 	case "apps":
 		allApps := apps.GetPublicApps()
@@ -514,7 +575,7 @@ func runCommand(cmd string) string {
 
 	case "help":
 		return `Users:    users · user <id> · credit <id> <amount>
-Wallet:   wallet <id>
+Wallet:   wallet <id> · payments [id]
 Apps:     apps · app <slug>
 Content:  search <query> · delete <type> <id> · flags
 System:   stats · types · help`
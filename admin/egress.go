@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/egress"
+	"mu/internal/netx"
+)
+
+// EgressHandler lists every external host the instance has contacted, with
+// request counts, last contact and a per-host kill switch, and whether the
+// responses from the APIs Mu parses still have the shape it expects.
+func EgressHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		if err := r.ParseForm(); err != nil {
+			app.BadRequest(w, r, "Failed to parse form")
+			return
+		}
+		host := strings.TrimSpace(r.FormValue("host"))
+		switch r.FormValue("action") {
+		case "disable":
+			err = egress.SetDisabled(host, true)
+		case "enable":
+			err = egress.SetDisabled(host, false)
+		case "reset":
+			err = egress.Reset()
+		case "reset_netx":
+			netx.Reset()
+		default:
+			err = fmt.Errorf("unknown action")
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		app.Log("admin", "%s egress %s %s", acc.ID, r.FormValue("action"), host)
+		http.Redirect(w, r, "/admin/egress", http.StatusSeeOther)
+		return
+	}
+
+	hosts := egress.Hosts()
+
+	if app.WantsJSON(r) {
+		type hostJSON struct {
+			egress.Host
+			Purpose string `json:"purpose,omitempty"`
+		}
+		out := make([]hostJSON, len(hosts))
+		for i, h := range hosts {
+			out[i] = hostJSON{Host: h, Purpose: h.Purpose()}
+		}
+		app.RespondJSON(w, map[string]interface{}{"hosts": out, "clients": netx.Stats(), "formats": egress.Formats()})
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>External Hosts <span class="count">%d</span></h3>`, len(hosts)))
+	content.WriteString(`<p class="text-sm text-muted">Every host this instance has made an outbound HTTP request to. Disabling a host refuses all further requests to it and its subdomains.</p>`)
+	content.WriteString(`<form method="POST" class="block-form">
+		<input type="hidden" name="action" value="disable">
+		<input type="text" name="host" placeholder="host or domain, e.g. googleapis.com" required>
+		<button type="submit">Disable</button>
+	</form>`)
+
+	if len(hosts) == 0 {
+		content.WriteString(`<p class="text-muted">No outbound requests recorded yet.</p>`)
+	} else {
+		content.WriteString(`<table class="email-log">`)
+		content.WriteString(`<tr><th>Host</th><th class="hide-mobile">Purpose</th><th>Requests</th><th>Errors</th><th class="hide-mobile">Last contact</th><th></th></tr>`)
+		for _, h := range hosts {
+			last := "never"
+			if !h.LastContact.IsZero() {
+				last = app.TimeAgo(h.LastContact)
+			}
+			errClass := ""
+			if h.Errors > 0 {
+				errClass = "dir-out"
+			}
+			action, label, style := "disable", "Disable", "border:1px solid #c00;color:#c00"
+			if h.Disabled {
+				action, label, style = "enable", "Enable", "border:1px solid #22c55e;color:#22c55e"
+			}
+			denied := ""
+			if h.Denied > 0 {
+				denied = fmt.Sprintf(` <span class="text-muted text-sm">(%d denied)</span>`, h.Denied)
+			}
+			content.WriteString(fmt.Sprintf(`<tr>
+				<td class="addr">%s%s</td>
+				<td class="hide-mobile">%s</td>
+				<td>%d</td>
+				<td class="%s">%d</td>
+				<td class="hide-mobile">%s</td>
+				<td><form method="POST" class="d-inline"><input type="hidden" name="action" value="%s"><input type="hidden" name="host" value="%s"><button type="submit" style="font-size:12px;padding:2px 8px;border-radius:4px;background:#fff;cursor:pointer;%s">%s</button></form></td>
+			</tr>`,
+				html.EscapeString(h.Host), denied,
+				html.EscapeString(h.Purpose()),
+				h.Requests,
+				errClass, h.Errors,
+				last,
+				action, html.EscapeString(h.Host), style, label,
+			))
+		}
+		content.WriteString(`</table>`)
+		content.WriteString(`<form method="POST" class="mt-2" onsubmit="return confirm('Reset all counters? Kill switches are kept.')"><input type="hidden" name="action" value="reset"><button type="submit" class="btn-secondary">Reset counters</button></form>`)
+	}
+
+	content.WriteString(`</div>`)
+
+	// Client policy metrics: breakers, retries and cache hits from netx.
+	stats := netx.Stats()
+	content.WriteString(`<div class="card">`)
+	content.WriteString(`<h3>Client Policy</h3>`)
+	content.WriteString(`<p class="text-sm text-muted">Circuit breakers, retries and response cache for fetchers using the shared client. An open breaker fails requests fast until its cooldown ends.</p>`)
+	if len(stats) == 0 {
+		content.WriteString(`<p class="text-muted">No requests through the shared client yet.</p>`)
+	} else {
+		content.WriteString(`<table class="email-log">`)
+		content.WriteString(`<tr><th>Host</th><th>Requests</th><th>Failures</th><th class="hide-mobile">Retries</th><th class="hide-mobile">Cache hits</th><th class="hide-mobile">Rejected</th><th>Breaker</th></tr>`)
+		for _, s := range stats {
+			state := "closed"
+			if s.Open {
+				state = fmt.Sprintf(`<span class="dir-out">open until %s</span>`, s.OpenUntil.Format("15:04:05"))
+			}
+			content.WriteString(fmt.Sprintf(`<tr><td class="addr">%s</td><td>%d</td><td>%d</td><td class="hide-mobile">%d</td><td class="hide-mobile">%d</td><td class="hide-mobile">%d</td><td>%s</td></tr>`,
+				html.EscapeString(s.Host), s.Requests, s.Failures, s.Retries, s.CacheHits, s.Rejected, state))
+		}
+		content.WriteString(`</table>`)
+		content.WriteString(`<form method="POST" class="mt-2"><input type="hidden" name="action" value="reset_netx"><button type="submit" class="btn-secondary">Reset breakers and cache</button></form>`)
+	}
+	content.WriteString(`</div>`)
+
+	// Upstream formats: whether each parsed API still returns what we expect.
+	formats := egress.Formats()
+	content.WriteString(`<div class="card">`)
+	content.WriteString(`<h3>Upstream Formats</h3>`)
+	content.WriteString(`<p class="text-sm text-muted">Responses from the APIs Mu parses. A drifting source returned something its parser didn't recognise, usually because the upstream changed its format; the error says what was wrong.</p>`)
+	if len(formats) == 0 {
+		content.WriteString(`<p class="text-muted">No responses parsed yet.</p>`)
+	} else {
+		content.WriteString(`<table class="email-log">`)
+		content.WriteString(`<tr><th>Source</th><th class="hide-mobile">Format</th><th>Parsed</th><th>Drifted</th><th>Status</th></tr>`)
+		for _, f := range formats {
+			status := "ok"
+			if f.Drifting() {
+				status = fmt.Sprintf(`<span class="dir-out">drifting since %s</span><br><span class="text-sm text-muted">%s</span>`,
+					app.TimeAgo(f.LastDrift), html.EscapeString(f.LastError))
+			} else if f.Drifted > 0 {
+				status = fmt.Sprintf(`ok <span class="text-sm text-muted">(last drift %s)</span>`, app.TimeAgo(f.LastDrift))
+			}
+			content.WriteString(fmt.Sprintf(`<tr><td class="addr">%s</td><td class="hide-mobile">%s</td><td>%d</td><td>%d</td><td>%s</td></tr>`,
+				html.EscapeString(f.Source), html.EscapeString(f.Version), f.Parsed, f.Drifted, status))
+		}
+		content.WriteString(`</table>`)
+	}
+	content.WriteString(`</div>`)
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	html := app.RenderHTMLForRequest("Egress", "External hosts", content.String(), r)
+	w.Write([]byte(html))
+}
@@ -3,6 +3,7 @@ package admin
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"mu/internal/app"
@@ -10,64 +11,9 @@ import (
 	"mu/internal/settings"
 )
 
-type settingGroup struct {
-	Name string
-	Vars []string
-}
-
-var settingGroups = []settingGroup{
-	{"AI", []string{
-		"ANTHROPIC_API_KEY",
-		"ANTHROPIC_MODEL",
-		"ATLAS_API_KEY",
-		"OPENAI_BASE_URL",
-		"OPENAI_API_KEY",
-	}},
-	{"Search", []string{
-		"BRAVE_API_KEY",
-		"YOUTUBE_API_KEY",
-		"GOOGLE_API_KEY",
-	}},
-	{"Mail", []string{
-		"MAIL_DOMAIN",
-		"MAIL_PORT",
-		"MAIL_SELECTOR",
-		"DKIM_PRIVATE_KEY",
-		"SMTP_HOST",
-		"SMTP_PORT",
-		"SMTP_USER",
-		"SMTP_PASS",
-	}},
-	{"Payments", []string{
-		"STRIPE_SECRET_KEY",
-		"STRIPE_PUBLISHABLE_KEY",
-		"STRIPE_WEBHOOK_SECRET",
-		"X402_PAY_TO",
-	}},
-	{"Trading", []string{
-		"TRADE_RPC_URL",
-		"TRADE_CHAIN",
-	}},
-	{"Discord", []string{
-		"DISCORD_BOT_TOKEN",
-	}},
-	{"Telegram", []string{
-		"TELEGRAM_BOT_TOKEN",
-	}},
-	{"WhatsApp", []string{
-		"WHATSAPP_TOKEN",
-		"WHATSAPP_PHONE_ID",
-		"WHATSAPP_VERIFY_TOKEN",
-		"WHATSAPP_APP_SECRET",
-	}},
-	{"Platform", []string{
-		"MU_DOMAIN",
-		"DATA_DIR",
-		"PASSKEY_ORIGIN",
-		"PASSKEY_RP_ID",
-	}},
-}
-
+// EnvHandler serves /admin/env: every setting the packages declare, to
+// set or override from the browser. Values are checked against their
+// type before anything is saved.
 func EnvHandler(w http.ResponseWriter, r *http.Request) {
 	_, _, err := auth.RequireAdmin(r)
 	if err != nil {
@@ -77,15 +23,32 @@ func EnvHandler(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method == "POST" {
 		r.ParseForm()
-		for _, group := range settingGroups {
-			for _, key := range group.Vars {
-				val := r.FormValue(key)
+		changes := map[string]string{}
+		var problems []string
+		for _, group := range settings.Groups() {
+			for _, v := range group.Vars {
+				val := r.FormValue(v.Key)
 				if val == "••••••" || val == "" {
 					continue
 				}
-				settings.Set(key, val)
+				if err := settings.Check(v.Key, val); err != nil {
+					problems = append(problems, err.Error())
+					continue
+				}
+				changes[v.Key] = val
 			}
 		}
+		if len(problems) > 0 {
+			app.BadRequest(w, r, "Nothing saved. "+strings.Join(problems, "; "))
+			return
+		}
+		keys := make([]string, 0, len(changes))
+		for key, val := range changes {
+			settings.Set(key, val)
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		app.SetAuditTarget(r, strings.Join(keys, ", "))
 		http.Redirect(w, r, "/admin/env?saved=1", http.StatusSeeOther)
 		return
 	}
@@ -98,33 +61,30 @@ func EnvHandler(w http.ResponseWriter, r *http.Request) {
 
 	b.WriteString(`<form method="POST" action="/admin/env">`)
 
-	for _, group := range settingGroups {
+	for _, group := range settings.Groups() {
 		b.WriteString(`<div class="card">`)
 		b.WriteString(fmt.Sprintf(`<h3>%s</h3>`, group.Name))
 
-		for _, key := range group.Vars {
+		for _, v := range group.Vars {
+			key := v.Key
 			source := settings.Source(key)
-			val := settings.Get(key)
 
 			displayVal := ""
 			badge := `<span style="font-size:11px;color:#c00">not set</span>`
-			if source == "env" {
+			switch source {
+			case "env":
 				displayVal = "••••••"
 				badge = `<span style="font-size:11px;color:#27ae60">env</span>`
-			} else if source == "saved" {
+			case "file":
+				displayVal = "••••••"
+				badge = `<span style="font-size:11px;color:#8e44ad">file</span>`
+			case "saved":
 				displayVal = "••••••"
 				badge = `<span style="font-size:11px;color:#2980b9">saved</span>`
 			}
 
-			_ = val
-
-			isSecret := strings.Contains(strings.ToUpper(key), "KEY") ||
-				strings.Contains(strings.ToUpper(key), "SECRET") ||
-				strings.Contains(strings.ToUpper(key), "TOKEN") ||
-				strings.Contains(strings.ToUpper(key), "PASS")
-
 			inputType := "text"
-			if isSecret {
+			if settings.IsSecret(key) {
 				inputType = "password"
 			}
 
@@ -2,7 +2,10 @@ package admin
 
 import (
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 
 	"mu/internal/app"
@@ -31,183 +34,121 @@ var (
 	CheckContent    = flag.CheckContent
 	IsHidden        = flag.IsHidden
 	AdminFlag       = flag.AdminFlag
+	DeleteReports   = flag.DeleteReports
+	RenameReporter  = flag.RenameReporter
 )
 
 func Load() {
 	flag.Load()
+	flag.OnReportResolved = notifyReporter
 }
 
 // ============================================
 // HTTP HANDLERS
 // ============================================
 
-// FlagHandler handles flag submissions
+// FlagHandler serves /flag: POST reports content, with its type, id and a
+// category (spam, abuse or misinformation). Each member can report an
+// item once; three reports hide it until reviewed.
 func FlagHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
 		return
 	}
 
-	var contentType, contentID string
-
+	var req struct {
+		Type     string `json:"type"`
+		ID       string `json:"id"`
+		Category string `json:"category"`
+	}
 	if app.SendsJSON(r) {
-		var req struct {
-			Type string `json:"type"`
-			ID   string `json:"id"`
-		}
 		if err := app.DecodeJSON(r, &req); err != nil {
-			app.RespondError(w, http.StatusBadRequest, "invalid json")
+			app.BadRequest(w, r, "invalid JSON")
 			return
 		}
-		contentType = req.Type
-		contentID = req.ID
 	} else {
-		contentType = r.FormValue("type")
-		contentID = r.FormValue("id")
+		r.ParseForm()
+		req.Type = r.FormValue("type")
+		req.ID = r.FormValue("id")
+		req.Category = r.FormValue("category")
 	}
-
-	if contentID == "" || contentType == "" {
-		http.Error(w, "Content ID and type required", http.StatusBadRequest)
+	if req.ID == "" || req.Type == "" {
+		app.BadRequest(w, r, "Content ID and type required")
 		return
 	}
-
-	// Get the authenticated user
-	flagger := "Anonymous"
-	_, acc := auth.TrySession(r)
-	if acc != nil {
-		flagger = acc.Name
-	}
-
-	// Add flag
-	count, alreadyFlagged, err := flag.Add(contentType, contentID, flagger)
-	if err != nil {
-		http.Error(w, "Failed to flag content", http.StatusInternalServerError)
+	if req.Category == "" {
+		app.BadRequest(w, r, "Choose a category: "+strings.Join(flag.Categories, ", "))
 		return
 	}
 
-	if alreadyFlagged {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"success": false, "message": "Already flagged"}`))
+	count, already, err := flag.File(req.Type, req.ID, acc.ID, req.Category)
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
 		return
 	}
 
 	// Refresh cache if content was hidden
-	if count >= 3 {
-		if deleter, ok := flag.GetDeleter(contentType); ok {
+	if !already && count >= 3 {
+		if deleter, ok := flag.GetDeleter(req.Type); ok {
 			deleter.RefreshCache()
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"success": true, "count": ` + fmt.Sprintf("%d", count) + `}`))
+	if app.SendsJSON(r) || app.WantsJSON(r) {
+		if already {
+			app.RespondJSON(w, map[string]interface{}{"success": false, "message": "You've already reported this"})
+			return
+		}
+		app.RespondJSON(w, map[string]interface{}{"success": true, "count": count})
+		return
+	}
+	http.Redirect(w, r, "/reports", http.StatusSeeOther)
 }
 
-// ModerateHandler shows all flagged content
+// ModerateHandler serves /admin/moderate: the review queue of flagged
+// content, grouped by type, with the audit log of past decisions. POST
+// reviews an item.
 func ModerateHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		handleModeration(w, r)
 		return
 	}
 
-	// Check if user is admin
-	_, acc, err := auth.RequireAdmin(r)
+	_, _, err := auth.RequireAdmin(r)
 	if err != nil {
 		app.Forbidden(w, r, "Admin access required")
 		return
 	}
 
-	_ = acc // acc.Admin is always true here
-
-	flaggedItems := flag.GetAll()
+	queue := moderationQueue()
+	audit := flag.AuditLog(auditShown)
 
-	var itemsList []string
-	for _, item := range flaggedItems {
-		var contentHTML string
-		var title string
-		var author string
-		var createdAt string
-
-		// Get content from the appropriate handler
-		if deleter, ok := flag.GetDeleter(item.ContentType); ok {
-			content := deleter.Get(item.ContentID)
-			switch item.ContentType {
-			case "post":
-				if post, ok := content.(PostContent); ok {
-					title = post.Title
-					if title == "" {
-						title = "Untitled"
-					}
-					text := post.Content
-					if len(text) > 300 {
-						text = text[:300] + "..."
-					}
-					contentHTML = fmt.Sprintf(`<p class="whitespace-pre-wrap">%s</p>`, text)
-					author = post.Author
-					createdAt = app.TimeAgo(post.CreatedAt)
-				}
-			case "news":
-				title = "News Article"
-				contentHTML = `<p>[News content]</p>`
-			case "video":
-				title = "Video"
-				contentHTML = `<p>[Video content]</p>`
-			}
-		}
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{
+			"queue": queue,
+			"audit": audit,
+		})
+		return
+	}
 
-		status := "Under review"
-		if item.Flagged {
-			status = "Hidden"
+	var groupsHTML []string
+	for _, g := range queue {
+		var itemsList []string
+		for _, q := range g.Items {
+			itemsList = append(itemsList, queuedItemHTML(q))
 		}
-
-		actionButtons := fmt.Sprintf(`
-				<form method="POST" action="/admin/moderate">
-					<input type="hidden" name="action" value="approve">
-					<input type="hidden" name="type" value="%s">
-					<input type="hidden" name="id" value="%s">
-					<button type="submit" class="btn-approve">Approve</button>
-				</form>
-				<form method="POST" action="/admin/moderate" onsubmit="event.preventDefault(); muConfirm('Permanently delete this content?').then(function(ok){if(ok)event.target.submit()})">
-					<input type="hidden" name="action" value="delete">
-					<input type="hidden" name="type" value="%s">
-					<input type="hidden" name="id" value="%s">
-					<button type="submit" class="btn-delete">Delete</button>
-				</form>`,
-			item.ContentType, item.ContentID,
-			item.ContentType, item.ContentID)
-
-		html := fmt.Sprintf(`<div class="flagged-item">
-			<div>
-				<span class="content-type-badge">%s</span>
-				<h3>%s</h3>
-			</div>
-			%s
-			<div class="info">
-				%s by %s · Flags: %d · Status: %s<br>
-				Flagged by: %s
-			</div>
-			<div class="actions">
-				%s
-				<a href="/%s?id=%s" target="_blank">view</a>
-			</div>
-		</div>`,
-			item.ContentType,
-			title,
-			contentHTML,
-			createdAt,
-			author,
-			item.FlagCount,
-			status,
-			strings.Join(item.FlaggedBy, ", "),
-			actionButtons,
-			getViewPath(item.ContentType),
-			item.ContentID)
-
-		itemsList = append(itemsList, html)
+		groupsHTML = append(groupsHTML, fmt.Sprintf(`<h3>%s <span class="count">%d</span></h3>
+		%s`, html.EscapeString(g.Type), len(g.Items), strings.Join(itemsList, "\n")))
 	}
 
 	listHTML := "<p style='color: #666;'>No flagged content</p>"
-	if len(itemsList) > 0 {
-		listHTML = strings.Join(itemsList, "\n")
+	if len(groupsHTML) > 0 {
+		listHTML = strings.Join(groupsHTML, "\n")
 	}
 
 	// Get new account blog posts section
@@ -243,7 +184,7 @@ func ModerateHandler(w http.ResponseWriter, r *http.Request) {
 						<input type="hidden" name="id" value="%s">
 						<button type="submit" class="btn-approve">Approve</button>
 					</form>
-					<form method="POST" action="/admin/moderate" onsubmit="event.preventDefault(); muConfirm('Flag this post?').then(function(ok){if(ok){fetch('/admin/flag',{method:'POST',headers:{'Content-Type':'application/json'},credentials:'same-origin',body:JSON.stringify({type:'post',id:'%s'})}).then(r=>r.json()).then(d=>{if(d.success){location.reload()}else{alert(d.message||'Failed')}}).catch(()=>alert('Error'))}});return false;">
+					<form method="POST" action="/admin/moderate" onsubmit="event.preventDefault(); muConfirm('Flag this post?').then(function(ok){if(ok){fetch('/admin/flag',{method:'POST',headers:{'Content-Type':'application/json'},credentials:'same-origin',body:JSON.stringify({type:'post',id:'%s',category:'spam'})}).then(r=>r.json()).then(d=>{if(d.success){location.reload()}else{alert(d.message||'Failed')}}).catch(()=>alert('Error'))}});return false;">
 						<button type="submit" class="btn-delete">Flag</button>
 					</form>
 					<a href="/blog/post?id=%s" target="_blank">view</a>
@@ -272,24 +213,175 @@ func ModerateHandler(w http.ResponseWriter, r *http.Request) {
 	content := fmt.Sprintf(`<div id="moderation">
 		<div class="info-banner">
 			<strong>Community Moderation</strong><br>
-			Review content that has been flagged by users. Content is automatically hidden after 3 flags.
-			You can approve (clear flags) or delete the content permanently.
+			Review content that has been flagged by members or the classifier. Content is hidden after 3 flags, or straight away when the classifier flags it.
+			Approve clears the flags and shows it again, Reject deletes it, and Ban deletes it and bans the author. Every decision goes in the audit log below.
 		</div>
-		<h2>Flagged Content</h2>
+		<h2>Review Queue</h2>
 		<div id="flagged-content">
 			%s
 		</div>
 		%s
-	</div>`, listHTML, newAccountPostsHTML)
+		<h2 class="mt-6">Audit Log</h2>
+		%s
+		<p><a href="/admin">← Back to Admin</a></p>
+	</div>`, listHTML, newAccountPostsHTML, auditLogHTML(audit))
 
 	html := app.RenderHTMLForRequest("Moderate", "Review flagged content", content, r)
 	w.Write([]byte(html))
 }
 
+// auditShown is how many audit log entries the moderation page shows.
+const auditShown = 100
+
+// queuedItem is a flagged item with the content it refers to, if its
+// type's deleter can still find it, and its open reports by category.
+// However many members report an item it is queued once.
+type queuedItem struct {
+	*flag.FlaggedItem
+	Content *flag.PostContent `json:"content,omitempty"`
+	Reports map[string]int    `json:"reports,omitempty"`
+}
+
+// queueGroup is the queued items of one content type, oldest first.
+type queueGroup struct {
+	Type  string       `json:"type"`
+	Items []queuedItem `json:"items"`
+}
+
+// moderationQueue returns the flagged items grouped by content type.
+func moderationQueue() []queueGroup {
+	byType := map[string][]queuedItem{}
+	for _, item := range flag.GetAll() {
+		q := queuedItem{FlaggedItem: item, Reports: flag.ReportCounts(item.ContentType, item.ContentID)}
+		if deleter, ok := flag.GetDeleter(item.ContentType); ok {
+			if c, ok := deleter.Get(item.ContentID).(PostContent); ok {
+				q.Content = &c
+			}
+		}
+		byType[item.ContentType] = append(byType[item.ContentType], q)
+	}
+	groups := make([]queueGroup, 0, len(byType))
+	for t, items := range byType {
+		sort.Slice(items, func(i, j int) bool { return items[i].FlaggedAt.Before(items[j].FlaggedAt) })
+		groups = append(groups, queueGroup{Type: t, Items: items})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Type < groups[j].Type })
+	return groups
+}
+
+// queuedItemHTML renders one item in the review queue.
+func queuedItemHTML(q queuedItem) string {
+	title := "Content unavailable"
+	contentHTML := ""
+	byline := ""
+	view := ""
+	if path := getViewPath(q.ContentType); path != "" {
+		view = "/" + path + "?id=" + url.QueryEscape(q.ContentID)
+	}
+	if c := q.Content; c != nil {
+		title = c.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		text := c.Content
+		if len(text) > 300 {
+			text = text[:300] + "..."
+		}
+		contentHTML = fmt.Sprintf(`<p class="whitespace-pre-wrap">%s</p>`, html.EscapeString(text))
+		byline = fmt.Sprintf("%s by %s · ", app.TimeAgo(c.CreatedAt), html.EscapeString(c.Author))
+		if author, err := auth.GetAccount(c.AuthorID); err == nil && author.InvitedBy != "" {
+			byline = fmt.Sprintf("%s by %s, invited by %s · ", app.TimeAgo(c.CreatedAt), html.EscapeString(c.Author), html.EscapeString(author.InvitedBy))
+		}
+		if c.URL != "" {
+			view = c.URL
+		}
+	}
+
+	status := "Under review"
+	if q.Flagged {
+		status = "Hidden"
+	}
+	reports := ""
+	if len(q.Reports) > 0 {
+		reports = " · Reported for " + flag.FormatCounts(q.Reports)
+	}
+
+	form := func(action, label, class, confirm string) string {
+		onsubmit := ""
+		if confirm != "" {
+			onsubmit = fmt.Sprintf(` onsubmit="event.preventDefault(); muConfirm('%s').then(function(ok){if(ok)event.target.submit()})"`, confirm)
+		}
+		return fmt.Sprintf(`<form method="POST" action="/admin/moderate"%s>
+					<input type="hidden" name="action" value="%s">
+					<input type="hidden" name="type" value="%s">
+					<input type="hidden" name="id" value="%s">
+					<button type="submit" class="%s">%s</button>
+				</form>`, onsubmit, action, html.EscapeString(q.ContentType), html.EscapeString(q.ContentID), class, label)
+	}
+	actions := form(flag.ActionApprove, "Approve", "btn-approve", "") +
+		form(flag.ActionReject, "Reject", "btn-delete", "Permanently delete this content?")
+	if q.Content != nil && q.Content.AuthorID != "" {
+		actions += form(flag.ActionBan, "Ban author", "btn-delete", "Delete this content and ban its author?")
+	}
+	if view != "" {
+		actions += fmt.Sprintf(`<a href="%s" target="_blank">view</a>`, html.EscapeString(view))
+	}
+
+	return fmt.Sprintf(`<div class="flagged-item">
+			<div>
+				<span class="content-type-badge">%s</span>
+				<h3>%s</h3>
+			</div>
+			%s
+			<div class="info">
+				%sFlags: %d%s · Status: %s · Since %s<br>
+				Flagged by: %s
+			</div>
+			<div class="actions">
+				%s
+			</div>
+		</div>`,
+		html.EscapeString(q.ContentType),
+		html.EscapeString(title),
+		contentHTML,
+		byline,
+		q.FlagCount,
+		html.EscapeString(reports),
+		status,
+		app.TimeAgo(q.FlaggedAt),
+		html.EscapeString(strings.Join(q.FlaggedBy, ", ")),
+		actions)
+}
+
+// auditLogHTML renders the audit log as a table.
+func auditLogHTML(entries []flag.AuditEntry) string {
+	if len(entries) == 0 {
+		return `<p class="text-muted">No moderation decisions yet.</p>`
+	}
+	var b strings.Builder
+	b.WriteString(`<table class="email-log"><tr><th>Time</th><th>Reviewer</th><th>Action</th><th>Type</th><th>Item</th><th>Author</th><th class="hide-mobile">Note</th></tr>`)
+	for _, e := range entries {
+		b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td class="addr">%s</td><td>%s</td><td class="subject hide-mobile">%s</td></tr>`,
+			e.Time.Format("Jan 2 15:04:05"),
+			html.EscapeString(e.Reviewer),
+			e.Action,
+			html.EscapeString(e.ContentType),
+			html.EscapeString(e.ContentID),
+			html.EscapeString(e.Author),
+			html.EscapeString(e.Note)))
+	}
+	b.WriteString(`</table>`)
+	return b.String()
+}
+
 func getViewPath(contentType string) string {
 	switch contentType {
 	case "post":
 		return "post"
+	case "qa":
+		return "qa"
+	case "chat":
+		return "chat"
 	case "news":
 		return "news"
 	case "video":
@@ -299,42 +391,67 @@ func getViewPath(contentType string) string {
 	}
 }
 
+// handleModeration carries out a review: approve, reject (or its old
+// name, delete) or ban, attributed to the signed-in admin; or
+// approve_account for a new account.
 func handleModeration(w http.ResponseWriter, r *http.Request) {
-	action := r.FormValue("action")
-	contentType := r.FormValue("type")
-	contentID := r.FormValue("id")
-
-	if contentID == "" || contentType == "" {
-		app.BadRequest(w, r, "Content ID and type required")
-		return
-	}
-
-	_, _, err := auth.RequireAdmin(r)
+	_, acc, err := auth.RequireAdmin(r)
 	if err != nil {
 		app.Forbidden(w, r, "Admin access required")
 		return
 	}
 
-	switch action {
-	case "approve":
-		flag.Approve(contentType, contentID)
-		http.Redirect(w, r, "/admin/moderate", http.StatusSeeOther)
+	var req struct {
+		Action string `json:"action"`
+		Type   string `json:"type"`
+		ID     string `json:"id"`
+		Note   string `json:"note"`
+	}
+	if app.SendsJSON(r) {
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Action = r.FormValue("action")
+		req.Type = r.FormValue("type")
+		req.ID = r.FormValue("id")
+		req.Note = r.FormValue("note")
+	}
 
-	case "delete":
-		flag.Delete(contentType, contentID)
-		http.Redirect(w, r, "/admin/moderate", http.StatusSeeOther)
+	if req.ID == "" || req.Type == "" {
+		app.BadRequest(w, r, "Content ID and type required")
+		return
+	}
 
-	case "approve_account":
-		if err := auth.ApproveAccount(contentID); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+	switch req.Action {
+	case flag.ActionApprove:
+		err = flag.Review(req.Type, req.ID, acc.ID, flag.ActionApprove, req.Note)
+	case flag.ActionReject, "delete":
+		err = flag.Review(req.Type, req.ID, acc.ID, flag.ActionReject, req.Note)
+	case flag.ActionBan:
+		author := flag.Author(req.Type, req.ID)
+		if author == "" {
+			err = fmt.Errorf("no author to ban")
+		} else if err = auth.BanAccount(author); err == nil {
+			err = flag.Review(req.Type, req.ID, acc.ID, flag.ActionBan, req.Note)
 		}
-		if RefreshBlogCache != nil {
+	case "approve_account":
+		if err = auth.ApproveAccount(req.ID); err == nil && RefreshBlogCache != nil {
 			RefreshBlogCache()
 		}
-		http.Redirect(w, r, "/admin/moderate", http.StatusSeeOther)
-
 	default:
-		http.Error(w, "Invalid action", http.StatusBadRequest)
+		err = fmt.Errorf("invalid action")
+	}
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return
+	}
+
+	if app.SendsJSON(r) || app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"success": true})
+		return
 	}
+	http.Redirect(w, r, "/admin/moderate", http.StatusSeeOther)
 }
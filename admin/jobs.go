@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// JobsHandler serves /admin/jobs: the background jobs, when each last ran,
+// how long it took and how it went. POST runs a job now.
+func JobsHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "Invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Name = r.FormValue("name")
+		}
+		if err := app.RunJob(req.Name); err != nil {
+			app.NotFound(w, r, "No such job: "+req.Name)
+			return
+		}
+		app.Log("admin", "Job %s run by hand", req.Name)
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.RespondJSON(w, map[string]string{"status": "started", "name": req.Name})
+			return
+		}
+		http.Redirect(w, r, "/admin/jobs", http.StatusSeeOther)
+		return
+	}
+
+	jobs := app.Jobs()
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, jobs)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(`<div class="card">`)
+	b.WriteString(fmt.Sprintf(`<h3>Jobs <span class="count">%d</span></h3>`, len(jobs)))
+	if len(jobs) == 0 {
+		b.WriteString(`<p class="text-muted">No jobs scheduled.</p>`)
+	} else {
+		b.WriteString(`<div style="overflow-x:auto;">`)
+		b.WriteString(`<table class="email-log"><tr><th>Job</th><th>Every</th><th>Last run</th><th>Took</th><th>Runs</th><th>Failures</th><th>Next</th><th></th></tr>`)
+		for _, j := range jobs {
+			last, next := "never", "on demand"
+			if !j.LastRun.IsZero() {
+				last = app.TimeAgo(j.LastRun)
+			}
+			if j.Running {
+				last = "running"
+			}
+			if !j.NextRun.IsZero() {
+				next = "in " + time.Until(j.NextRun).Round(time.Second).String()
+			}
+			every := "once"
+			if j.Every > 0 {
+				every = j.Every.String()
+			}
+			b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%d</td><td>%d</td><td>%s</td>`,
+				html.EscapeString(j.Name), every, last, j.LastDuration.Round(time.Millisecond), j.Runs, j.Failures, next))
+			b.WriteString(fmt.Sprintf(`<td><form method="POST" action="/admin/jobs" style="display:inline;"><input type="hidden" name="name" value="%s"><button type="submit">Run now</button></form></td></tr>`,
+				html.EscapeString(j.Name)))
+			if j.LastError != "" {
+				b.WriteString(fmt.Sprintf(`<tr><td colspan="8" style="color:#c00;font-size:12px;word-break:break-all;">%s</td></tr>`, html.EscapeString(j.LastError)))
+			}
+		}
+		b.WriteString(`</table></div>`)
+	}
+	b.WriteString(`</div>`)
+	b.WriteString(`<p><a href="/admin/logs?pkg=jobs">Job log</a> · <a href="/admin">← Back to Admin</a></p>`)
+
+	pageHTML := app.RenderHTMLForRequest("Jobs", "Background jobs", b.String(), r)
+	w.Write([]byte(pageHTML))
+}
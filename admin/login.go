@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/settings"
+)
+
+// LoginHandler serves /admin/login, where an admin can make email login
+// links the only way to log in. Passwords, passkeys and Google sign-in
+// are refused while it is on; existing sessions carry on. Session
+// lifetimes and limits are set here too.
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if r.FormValue("form") == "sessions" {
+			if err := saveSessionSettings(r); err != nil {
+				app.BadRequest(w, r, err.Error())
+				return
+			}
+			app.Log("admin", "%s updated session settings", acc.ID)
+			http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+			return
+		}
+		on := r.FormValue("magic_link_only") == "on"
+		if on && app.EmailSender == nil {
+			app.BadRequest(w, r, "Mail is not configured, so nobody could receive a login link.")
+			return
+		}
+		if on && (acc.Email == "" || !acc.EmailVerified) {
+			app.BadRequest(w, r, "Verify an email on your own account first, or you will be locked out.")
+			return
+		}
+		auth.SetMagicLinkOnly(on)
+		app.Log("admin", "%s set magic-link-only login to %v", acc.ID, on)
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	checked := ""
+	if auth.MagicLinkOnly() {
+		checked = " checked"
+	}
+	var without int
+	for _, a := range auth.GetAllAccounts() {
+		if a.Email == "" || !a.EmailVerified {
+			without++
+		}
+	}
+
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(`<h3>Login</h3>`)
+	content.WriteString(fmt.Sprintf(`<p class="text-sm text-muted">Anyone with a verified email can ask for a one-time login link at <a href="/login/link">/login/link</a>. Links expire after %d minutes.</p>`, int(auth.MagicLinkTTL.Minutes())))
+	if app.EmailSender == nil {
+		content.WriteString(`<p class="text-sm text-error">Mail is not configured, so login links can't be sent.</p>`)
+	}
+	content.WriteString(fmt.Sprintf(`<form method="POST" class="block-form">
+		<label><input type="checkbox" name="magic_link_only"%s> Only allow login by email link</label>
+		<button type="submit">Save</button>
+	</form>`, checked))
+	if without > 0 {
+		content.WriteString(fmt.Sprintf(`<p class="text-sm text-muted mt-2">%d account(s) have no verified email and can't log in while this is on.</p>`, without))
+	}
+	content.WriteString(`</div>`)
+
+	idle := ""
+	if d := auth.IdleTimeout(); d > 0 {
+		idle = d.String()
+	}
+	content.WriteString(`<div class="card">`)
+	content.WriteString(`<h3>Sessions</h3>`)
+	content.WriteString(`<p class="text-sm text-muted">Durations are like <code>12h</code> or <code>30m</code>. "Keep me logged in" renews sessions with a single-use refresh token for the remember period.</p>`)
+	content.WriteString(fmt.Sprintf(`<form method="POST" class="block-form">
+		<input type="hidden" name="form" value="sessions">
+		<label>Session length <input name="ttl" value="%s"></label>
+		<label>Keep me logged in for <input name="remember_ttl" value="%s"></label>
+		<label>Log out after idle <input name="idle" value="%s" placeholder="off"></label>
+		<label>Max sessions per account <input name="max" type="number" min="0" value="%d"></label>
+		<button type="submit">Save</button>
+	</form>`, auth.SessionTTL(), auth.RememberTTL(), idle, auth.MaxSessions()))
+	content.WriteString(`</div>`)
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest("Login", "Login settings", content.String(), r)))
+}
+
+// saveSessionSettings validates and stores the session lifetime form.
+func saveSessionSettings(r *http.Request) error {
+	durations := map[string]string{
+		"SESSION_TTL":          r.FormValue("ttl"),
+		"SESSION_REMEMBER_TTL": r.FormValue("remember_ttl"),
+		"SESSION_IDLE_TIMEOUT": r.FormValue("idle"),
+	}
+	for key, v := range durations {
+		v = strings.TrimSpace(v)
+		if v == "" || v == "off" || v == "0" {
+			durations[key] = ""
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d < time.Minute {
+			return fmt.Errorf("%q is not a duration of a minute or more", v)
+		}
+		durations[key] = v
+	}
+	max := strings.TrimSpace(r.FormValue("max"))
+	if max != "" {
+		if n, err := strconv.Atoi(max); err != nil || n < 0 {
+			return fmt.Errorf("max sessions must be a number")
+		}
+	}
+	for key, v := range durations {
+		settings.Set(key, v)
+	}
+	settings.Set("SESSION_MAX", max)
+	return nil
+}
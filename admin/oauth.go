@@ -0,0 +1,127 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// OAuthHandler lists the OAuth clients and lets an admin register the
+// companion services that sign people in with their Mu account.
+func OAuthHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	var created *auth.OAuthClient
+	if r.Method == "POST" {
+		if err := r.ParseForm(); err != nil {
+			app.BadRequest(w, r, "Failed to parse form")
+			return
+		}
+		switch r.FormValue("action") {
+		case "register":
+			name := strings.TrimSpace(r.FormValue("name"))
+			var uris []string
+			for _, line := range strings.Split(r.FormValue("redirect_uris"), "\n") {
+				if u := strings.TrimSpace(line); u != "" {
+					uris = append(uris, u)
+				}
+			}
+			if name == "" || len(uris) == 0 {
+				app.BadRequest(w, r, "A name and at least one redirect URI are required")
+				return
+			}
+			for _, u := range uris {
+				if !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://localhost") && !strings.HasPrefix(u, "http://127.0.0.1") {
+					app.BadRequest(w, r, "Redirect URIs must use https, except on localhost")
+					return
+				}
+			}
+			created = auth.RegisterServiceClient(name, uris)
+			app.Log("admin", "%s registered OAuth service %s (%s)", acc.ID, name, created.ClientID)
+		case "delete":
+			id := r.FormValue("client_id")
+			auth.DeleteOAuthClient(id)
+			app.Log("admin", "%s deleted OAuth client %s", acc.ID, id)
+			http.Redirect(w, r, "/admin/oauth", http.StatusSeeOther)
+			return
+		default:
+			app.BadRequest(w, r, "unknown action")
+			return
+		}
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, created)
+			return
+		}
+	}
+
+	clients := auth.GetAllOAuthClients()
+	sort.Slice(clients, func(i, j int) bool { return clients[i].CreatedAt.After(clients[j].CreatedAt) })
+	if r.Method == "GET" && app.WantsJSON(r) {
+		list := make([]map[string]interface{}, 0, len(clients))
+		for _, c := range clients {
+			list = append(list, map[string]interface{}{
+				"client_id":     c.ClientID,
+				"name":          c.Name,
+				"redirect_uris": c.RedirectURIs,
+				"service":       c.Service,
+				"created_at":    c.CreatedAt,
+			})
+		}
+		app.RespondJSON(w, map[string]interface{}{"clients": list})
+		return
+	}
+
+	var content strings.Builder
+	if created != nil {
+		content.WriteString(`<div class="card">`)
+		content.WriteString(fmt.Sprintf(`<h3>Registered %s</h3>`, html.EscapeString(created.Name)))
+		content.WriteString(`<p class="text-sm">Copy the secret now, it isn't shown again.</p>`)
+		content.WriteString(fmt.Sprintf(`<p class="text-sm">Client ID: <code>%s</code><br>Client secret: <code>%s</code></p>`,
+			html.EscapeString(created.ClientID), html.EscapeString(created.ClientSecret)))
+		content.WriteString(`</div>`)
+	}
+
+	content.WriteString(`<div class="card">`)
+	content.WriteString(`<h3>Register a service</h3>`)
+	content.WriteString(`<p class="text-sm text-muted">Self-hosted services can sign people in with their Mu account using OpenID Connect. Point the service at <code>/.well-known/openid-configuration</code>, with the client ID and secret shown after registering. See <a href="/docs/oidc">OpenID Connect</a>.</p>`)
+	content.WriteString(`<form method="POST"><input type="hidden" name="action" value="register">`)
+	content.WriteString(`<input type="text" name="name" placeholder="Name" required>`)
+	content.WriteString(`<textarea name="redirect_uris" rows="3" placeholder="Redirect URIs, one per line" required></textarea>`)
+	content.WriteString(`<button type="submit">Register</button></form>`)
+	content.WriteString(`</div>`)
+
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>Clients <span class="count">%d</span></h3>`, len(clients)))
+	if len(clients) == 0 {
+		content.WriteString(`<p class="text-muted">No clients registered.</p>`)
+	} else {
+		content.WriteString(`<table class="email-log"><tr><th>Name</th><th>Kind</th><th>Redirect URIs</th><th>Registered</th><th></th></tr>`)
+		for _, c := range clients {
+			kind := "MCP"
+			if c.Service {
+				kind = "Service"
+			}
+			var uris []string
+			for _, u := range c.RedirectURIs {
+				uris = append(uris, html.EscapeString(u))
+			}
+			content.WriteString(fmt.Sprintf(`<tr><td>%s<br><code class="text-sm">%s</code></td><td>%s</td><td class="text-sm">%s</td><td>%s</td><td><form method="POST" onsubmit="return confirm('Delete this client?')"><input type="hidden" name="action" value="delete"><input type="hidden" name="client_id" value="%s"><button type="submit" class="btn-secondary">Delete</button></form></td></tr>`,
+				html.EscapeString(c.Name), html.EscapeString(c.ClientID), kind, strings.Join(uris, "<br>"), app.TimeAgo(c.CreatedAt), html.EscapeString(c.ClientID)))
+		}
+		content.WriteString(`</table>`)
+	}
+	content.WriteString(`</div>`)
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	html := app.RenderHTMLForRequest("OAuth", "OAuth clients", content.String(), r)
+	w.Write([]byte(html))
+}
@@ -0,0 +1,144 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/plugin"
+)
+
+// PluginsHandler lists the installed plugins and lets an admin enable
+// them, grant the permissions they ask for and set their limits.
+func PluginsHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		if err := r.ParseForm(); err != nil {
+			app.BadRequest(w, r, "Failed to parse form")
+			return
+		}
+		name := r.FormValue("name")
+		switch r.FormValue("action") {
+		case "scan":
+			plugin.Scan()
+		case "save":
+			limit := func(key string) int {
+				n, _ := strconv.Atoi(strings.TrimSpace(r.FormValue(key)))
+				return n
+			}
+			err = plugin.Configure(name, plugin.Config{
+				Enabled: r.FormValue("enabled") == "on",
+				Granted: r.Form["granted"],
+				Limits: plugin.Limits{
+					TimeoutSeconds:    limit("timeout_seconds"),
+					MaxBodyKB:         limit("max_body_kb"),
+					RequestsPerMinute: limit("requests_per_minute"),
+					MemoryMB:          limit("memory_mb"),
+				},
+			})
+		default:
+			err = fmt.Errorf("unknown action")
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		app.Log("admin", "%s plugins %s %s", acc.ID, r.FormValue("action"), name)
+		http.Redirect(w, r, "/admin/plugins", http.StatusSeeOther)
+		return
+	}
+
+	plugins := plugin.List()
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"plugins": plugins})
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>Plugins <span class="count">%d</span></h3>`, len(plugins)))
+	content.WriteString(fmt.Sprintf(`<p class="text-sm text-muted">Plugins run as their own processes from <code>%s</code>, one directory each. They're installed disabled, and can only use the permissions granted here, within their limits. See <a href="/docs/plugins">Plugins</a>.</p>`,
+		html.EscapeString(plugin.Dir())))
+	content.WriteString(`<form method="POST"><input type="hidden" name="action" value="scan"><button type="submit" class="btn-secondary">Rescan</button></form>`)
+	content.WriteString(`</div>`)
+
+	for _, p := range plugins {
+		status := `<span class="text-muted">stopped</span>`
+		if p.Running {
+			status = fmt.Sprintf(`running since %s`, app.TimeAgo(p.StartedAt))
+		}
+		if p.LastError != "" {
+			status += fmt.Sprintf(`<br><span class="dir-out text-sm">%s</span>`, html.EscapeString(p.LastError))
+		}
+
+		content.WriteString(`<div class="card">`)
+		content.WriteString(fmt.Sprintf(`<h3>%s <span class="text-muted text-sm">%s</span></h3>`, html.EscapeString(p.Name), html.EscapeString(p.Version)))
+		if p.Description != "" {
+			content.WriteString(fmt.Sprintf(`<p>%s</p>`, html.EscapeString(p.Description)))
+		}
+		content.WriteString(fmt.Sprintf(`<p class="text-sm">%s<br><span class="text-muted">%d calls, %d over the rate limit, %d restarts</span></p>`,
+			status, p.Calls, p.Limited, p.Restarts))
+
+		var offers []string
+		for _, c := range p.Cards {
+			offers = append(offers, "card "+html.EscapeString(c.Title))
+		}
+		for _, c := range p.Commands {
+			offers = append(offers, "/"+html.EscapeString(c.Name))
+		}
+		if len(offers) > 0 {
+			content.WriteString(fmt.Sprintf(`<p class="text-sm text-muted">Offers: %s</p>`, strings.Join(offers, ", ")))
+		}
+
+		checked := func(on bool) string {
+			if on {
+				return " checked"
+			}
+			return ""
+		}
+		content.WriteString(`<form method="POST" class="plugin-form">`)
+		content.WriteString(fmt.Sprintf(`<input type="hidden" name="action" value="save"><input type="hidden" name="name" value="%s">`, html.EscapeString(p.Name)))
+		content.WriteString(fmt.Sprintf(`<label><input type="checkbox" name="enabled"%s> Enabled</label>`, checked(p.Config.Enabled)))
+		if len(p.Permissions) > 0 {
+			content.WriteString(`<fieldset><legend>Permissions</legend>`)
+			for _, perm := range p.Permissions {
+				granted := false
+				for _, g := range p.Config.Granted {
+					granted = granted || g == perm
+				}
+				content.WriteString(fmt.Sprintf(`<label><input type="checkbox" name="granted" value="%s"%s> %s</label>`,
+					html.EscapeString(perm), checked(granted), html.EscapeString(permissionLabels[perm])))
+			}
+			content.WriteString(`</fieldset>`)
+		}
+		l := p.Config.Limits
+		content.WriteString(`<fieldset><legend>Limits</legend>`)
+		content.WriteString(fmt.Sprintf(`<label>Timeout (seconds) <input type="number" name="timeout_seconds" min="1" value="%d"></label>`, l.TimeoutSeconds))
+		content.WriteString(fmt.Sprintf(`<label>Body size (KB) <input type="number" name="max_body_kb" min="1" value="%d"></label>`, l.MaxBodyKB))
+		content.WriteString(fmt.Sprintf(`<label>Requests per minute <input type="number" name="requests_per_minute" min="1" value="%d"></label>`, l.RequestsPerMinute))
+		content.WriteString(fmt.Sprintf(`<label>Memory (MB) <input type="number" name="memory_mb" min="16" value="%d"></label>`, l.MemoryMB))
+		content.WriteString(`</fieldset>`)
+		content.WriteString(`<button type="submit">Save</button></form>`)
+		content.WriteString(`</div>`)
+	}
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	html := app.RenderHTMLForRequest("Plugins", "Third-party plugins", content.String(), r)
+	w.Write([]byte(html))
+}
+
+var permissionLabels = map[string]string{
+	plugin.PermRoutes:   "Serve pages under /plugins/",
+	plugin.PermCards:    "Add cards to the home screen",
+	plugin.PermCommands: "Answer chat commands",
+	plugin.PermUser:     "See who is signed in",
+}
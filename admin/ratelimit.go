@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// RateLimitHandler shows the rate limits in force, how many requests
+// each has let through and turned away, and who was turned away.
+func RateLimitHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := auth.RequireAdmin(r); err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	stats, offenders := app.GetRateStats()
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"rules": stats, "offenders": offenders})
+		return
+	}
+
+	limit := func(n int) string {
+		if n <= 0 {
+			return "—"
+		}
+		return fmt.Sprintf("%d/min", n)
+	}
+
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(`<h3>Rules</h3>`)
+	content.WriteString(`<p class="text-sm text-muted">Counted since the server started. Signed in people are limited per account where a rule has an account limit, otherwise per IP. Change the limits with <code>RATE_LIMITS</code>, e.g. <code>/search=60/120</code>.</p>`)
+	content.WriteString(`<table class="email-log"><tr><th>Path</th><th>Per IP</th><th>Per account</th><th>Allowed</th><th>Limited</th><th>Last limited</th></tr>`)
+	for _, s := range stats {
+		path := html.EscapeString(s.Prefix)
+		if s.WritesOnly {
+			path += ` <span class="text-muted text-sm">writes</span>`
+		}
+		last := "—"
+		if !s.LastHit.IsZero() {
+			last = app.TimeAgo(s.LastHit)
+		}
+		content.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%d</td><td>%d</td><td>%s</td></tr>`,
+			path, limit(s.PerIP), limit(s.PerAccount), s.Allowed, s.Limited, last))
+	}
+	content.WriteString(`</table></div>`)
+
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>Limited in the last day <span class="count">%d</span></h3>`, len(offenders)))
+	if len(offenders) == 0 {
+		content.WriteString(`<p class="text-muted">Nobody has been limited.</p>`)
+	} else {
+		if len(offenders) > 50 {
+			offenders = offenders[:50]
+		}
+		content.WriteString(`<table class="email-log"><tr><th>Who</th><th>Path</th><th>Limited</th><th>Last</th></tr>`)
+		for _, o := range offenders {
+			content.WriteString(fmt.Sprintf(`<tr><td><code>%s</code></td><td>%s</td><td>%d</td><td>%s</td></tr>`,
+				html.EscapeString(o.Key), html.EscapeString(o.Prefix), o.Limited, app.TimeAgo(o.Last)))
+		}
+		content.WriteString(`</table>`)
+	}
+	content.WriteString(`</div>`)
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	html := app.RenderHTMLForRequest("Rate Limits", "Request rate limits", content.String(), r)
+	w.Write([]byte(html))
+}
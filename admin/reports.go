@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/flag"
+	"mu/mail"
+)
+
+// reportStatus describes a report's status to the member who filed it.
+var reportStatus = map[string]string{
+	flag.ReportOpen:      "Waiting for review",
+	flag.ReportDismissed: "Reviewed, left up",
+	flag.ReportRemoved:   "Reviewed, removed",
+}
+
+// ReportsHandler serves /reports: the signed-in member's reports and what
+// became of them.
+func ReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	reports := flag.ReportsBy(acc.ID)
+	if app.WantsJSON(r) {
+		if reports == nil {
+			reports = []flag.Report{}
+		}
+		app.RespondJSON(w, map[string]interface{}{"reports": reports})
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(`<div class="card">`)
+	b.WriteString(fmt.Sprintf(`<h3>My Reports <span class="count">%d</span></h3>`, len(reports)))
+	if len(reports) == 0 {
+		b.WriteString(`<p class="text-muted">You haven't reported anything. Use Report on a post or comment that shouldn't be here.</p>`)
+	} else {
+		b.WriteString(`<table class="email-log"><tr><th>Reported</th><th>Item</th><th>Category</th><th>Status</th><th class="hide-mobile">Resolved</th></tr>`)
+		for _, rep := range reports {
+			item := html.EscapeString(rep.ContentType)
+			if c := reportedContent(rep); c != nil && c.URL != "" {
+				item = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(c.URL), item)
+			}
+			resolved := ""
+			if !rep.Resolved.IsZero() {
+				resolved = app.TimeAgo(rep.Resolved)
+			}
+			b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td class="hide-mobile">%s</td></tr>`,
+				app.TimeAgo(rep.Created), item, html.EscapeString(rep.Category), reportStatus[rep.Status], resolved))
+		}
+		b.WriteString(`</table>`)
+	}
+	b.WriteString(`</div>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest("My Reports", "Content you've reported", b.String(), r)))
+}
+
+// reportedContent looks up the item a report is about, if it's still there.
+func reportedContent(rep flag.Report) *flag.PostContent {
+	d, ok := flag.GetDeleter(rep.ContentType)
+	if !ok {
+		return nil
+	}
+	if c, ok := d.Get(rep.ContentID).(flag.PostContent); ok {
+		return &c
+	}
+	return nil
+}
+
+// notifyReporter mails a member to say their report has been reviewed,
+// which also reaches their devices. Set as flag.OnReportResolved in Load.
+func notifyReporter(rep flag.Report) {
+	acc, err := auth.GetAccount(rep.Reporter)
+	if err != nil {
+		return
+	}
+	outcome := "A moderator looked at it and decided to leave it up."
+	if rep.Status == flag.ReportRemoved {
+		outcome = "A moderator looked at it and took it down."
+	}
+	body := fmt.Sprintf("Thanks for reporting a %s for %s.\n\n%s\n\nYour reports are at /reports.", rep.ContentType, rep.Category, outcome)
+	if err := mail.SendMessage("Moderation", "moderation", acc.Name, acc.ID, "Your report has been reviewed", body, "", ""); err != nil {
+		app.Log("moderation", "Report notice to %s failed: %v", acc.ID, err)
+	}
+}
@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"net/http"
+
+	"mu/internal/app"
+)
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	for path, h := range map[string]func(w http.ResponseWriter, r *http.Request){
+		"/admin":             AdminHandler,
+		"/admin/api":         APILogHandler,
+		"/admin/blocklist":   BlocklistHandler,
+		"/admin/config":      ConfigHandler,
+		"/admin/console":     ConsoleHandler,
+		"/admin/delete":      DeleteHandler,
+		"/admin/diagnostics": DiagnosticsHandler,
+		"/admin/egress":      EgressHandler,
+		"/admin/email":       EmailLogHandler,
+		"/admin/env":         EnvHandler,
+		"/admin/flag":        FlagHandler,
+		"/admin/invite":      InviteHandler,
+		"/admin/jobs":        JobsHandler,
+		"/admin/login":       LoginHandler,
+		"/admin/logs":        SysLogHandler,
+		"/admin/moderate":    ModerateHandler,
+		"/admin/oauth":       OAuthHandler,
+		"/admin/plugins":     PluginsHandler,
+		"/admin/ratelimit":   RateLimitHandler,
+		"/admin/server":      UpdateHandler,
+		"/admin/spam":        SpamFilterHandler,
+		"/admin/usage":       UsageHandler,
+		"/admin/usage/api":   AIUsageHandler,
+		"/admin/users":       UsersHandler,
+		"/admin/weekly":      WeeklyHandler,
+	} {
+		r.HandleFunc(path, h, app.Authenticated, app.Audited)
+	}
+	r.HandleFunc("/admin/audit", AuditHandler, app.Authenticated)
+	r.HandleFunc("/flag", FlagHandler, app.Authenticated)
+	r.HandleFunc("/reports", ReportsHandler, app.Authenticated)
+	r.HandleFunc("/admin/log", app.Moved("/admin/log", "/admin/logs"))
+}
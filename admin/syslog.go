@@ -3,14 +3,23 @@ package admin
 import (
 	"fmt"
 	"html"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 
 	"mu/internal/app"
 	"mu/internal/auth"
 )
 
-// SysLogHandler shows the in-memory system log page.
+// logLevelNames are the levels offered for each package.
+var logLevelNames = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// SysLogHandler serves /admin/logs: the in-memory system log, filtered by
+// level, package, request ID or text, and each package's log level. POST
+// sets a package's level, or with an empty level puts it back to the
+// default.
 func SysLogHandler(w http.ResponseWriter, r *http.Request) {
 	_, _, err := auth.RequireAdmin(r)
 	if err != nil {
@@ -18,66 +27,154 @@ func SysLogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries := app.GetSysLog()
+	if r.Method == "POST" {
+		var req struct {
+			Package string `json:"package"`
+			Level   string `json:"level"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "Invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Package, req.Level = r.FormValue("package"), r.FormValue("level")
+		}
+		req.Package = strings.TrimSpace(req.Package)
+		if req.Package == "" {
+			app.BadRequest(w, r, "Package required")
+			return
+		}
+		var level *slog.Level
+		if req.Level != "" {
+			l, err := app.ParseLevel(req.Level)
+			if err != nil {
+				app.BadRequest(w, r, "Unknown level: "+req.Level)
+				return
+			}
+			level = &l
+		}
+		if err := app.SetLogLevel(req.Package, level); err != nil {
+			app.ServerError(w, r, "Failed to save: "+err.Error())
+			return
+		}
+		app.Log("admin", "Log level for %s set to %s", req.Package, app.LogLevel(req.Package))
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.RespondJSON(w, levelNames(app.LogLevels()))
+			return
+		}
+		http.Redirect(w, r, "/admin/logs", http.StatusSeeOther)
+		return
+	}
+
+	q := r.URL.Query()
+	minLevel := slog.LevelDebug
+	if l, err := app.ParseLevel(q.Get("level")); err == nil {
+		minLevel = l
+	}
+	pkg, rid, text := q.Get("pkg"), q.Get("rid"), strings.ToLower(q.Get("q"))
+	var entries []*app.SysLogEntry
+	for _, e := range app.GetSysLog() {
+		if e.Level < minLevel || (pkg != "" && e.Package != pkg) || (rid != "" && e.RequestID != rid) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(e.Message), text) {
+			continue
+		}
+		entries = append(entries, e)
+	}
 
 	// JSON API
 	if app.WantsJSON(r) {
 		type logEntry struct {
-			Time    string `json:"time"`
-			Package string `json:"package"`
-			Message string `json:"message"`
+			Time      string            `json:"time"`
+			Level     string            `json:"level"`
+			Package   string            `json:"package"`
+			Message   string            `json:"message"`
+			RequestID string            `json:"request_id,omitempty"`
+			Attrs     map[string]string `json:"attrs,omitempty"`
+		}
+		if q.Get("levels") != "" {
+			app.RespondJSON(w, levelNames(app.LogLevels()))
+			return
 		}
 		out := make([]logEntry, len(entries))
 		for i, e := range entries {
 			out[i] = logEntry{
-				Time:    e.Time.Format("15:04:05"),
-				Package: e.Package,
-				Message: e.Message,
+				Time:      e.Time.Format("15:04:05"),
+				Level:     e.Level.String(),
+				Package:   e.Package,
+				Message:   e.Message,
+				RequestID: e.RequestID,
+				Attrs:     e.Attrs,
 			}
 		}
-		// Optional filter
-		pkg := r.URL.Query().Get("pkg")
-		if pkg != "" {
-			var filtered []logEntry
-			for _, e := range out {
-				if e.Package == pkg {
-					filtered = append(filtered, e)
-				}
-			}
-			out = filtered
-		}
 		app.RespondJSON(w, out)
 		return
 	}
 
 	var content strings.Builder
+	content.WriteString(logLevelsCard())
+
 	content.WriteString(`<div class="card">`)
 	content.WriteString(fmt.Sprintf(`<h3>System Log <span class="count">%d</span></h3>`, len(entries)))
+	content.WriteString(`<form method="GET" action="/admin/logs" style="display:flex;gap:6px;flex-wrap:wrap;margin-bottom:10px;">`)
+	content.WriteString(`<select name="level">`)
+	for _, name := range logLevelNames {
+		l, _ := app.ParseLevel(name)
+		sel := ""
+		if l == minLevel {
+			sel = " selected"
+		}
+		content.WriteString(fmt.Sprintf(`<option value="%s"%s>%s and up</option>`, name, sel, name))
+	}
+	content.WriteString(`</select>`)
+	content.WriteString(fmt.Sprintf(`<input name="pkg" placeholder="Package" value="%s" style="width:110px;">`, html.EscapeString(pkg)))
+	content.WriteString(fmt.Sprintf(`<input name="rid" placeholder="Request ID" value="%s" style="width:150px;">`, html.EscapeString(rid)))
+	content.WriteString(fmt.Sprintf(`<input name="q" placeholder="Search" value="%s">`, html.EscapeString(q.Get("q"))))
+	content.WriteString(`<button type="submit">Filter</button></form>`)
 
 	if len(entries) == 0 {
-		content.WriteString(`<p class="text-muted">No log entries yet.</p>`)
+		content.WriteString(`<p class="text-muted">No log entries.</p>`)
 	} else {
 		content.WriteString(`<script>function muToggleSyslog(id){var d=document.getElementById(id);if(d){d.style.display=d.style.display==='none'?'table-row':'none';}}</script>`)
 		content.WriteString(`<div style="overflow-x:auto;">`)
 		content.WriteString(`<table class="email-log" style="width:100%;table-layout:fixed;">`)
-		content.WriteString(`<colgroup><col style="width:110px"><col style="width:90px"><col></colgroup>`)
-		content.WriteString(`<tr><th>Time</th><th>Package</th><th>Message</th></tr>`)
+		content.WriteString(`<colgroup><col style="width:110px"><col style="width:60px"><col style="width:90px"><col></colgroup>`)
+		content.WriteString(`<tr><th>Time</th><th>Level</th><th>Package</th><th>Message</th></tr>`)
 		for i, e := range entries {
 			rowID := fmt.Sprintf("syslog-row-%d", i)
+			var detail strings.Builder
+			detail.WriteString(html.EscapeString(e.Message))
+			if e.RequestID != "" {
+				detail.WriteString(fmt.Sprintf("\nrequest <a href=\"/admin/logs?rid=%s\">%s</a>", url.QueryEscape(e.RequestID), html.EscapeString(e.RequestID)))
+			}
+			keys := make([]string, 0, len(e.Attrs))
+			for k := range e.Attrs {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				detail.WriteString("\n" + html.EscapeString(k+"="+e.Attrs[k]))
+			}
 			content.WriteString(fmt.Sprintf(`<tr style="cursor:pointer;" onclick="muToggleSyslog('%s')" title="Click to expand">
 				<td style="white-space:nowrap;">%s</td>
-				<td style="white-space:nowrap;overflow:hidden;text-overflow:ellipsis;">%s</td>
+				<td style="white-space:nowrap;">%s</td>
+				<td style="white-space:nowrap;overflow:hidden;text-overflow:ellipsis;"><a href="/admin/logs?pkg=%s">%s</a></td>
 				<td style="overflow:hidden;text-overflow:ellipsis;white-space:nowrap;">%s</td>
 			</tr>
 			<tr id="%s" style="display:none;">
-				<td colspan="3" style="background:#f9f9f9;padding:8px;word-break:break-all;white-space:pre-wrap;font-size:12px;">%s</td>
+				<td colspan="4" style="background:#f9f9f9;padding:8px;word-break:break-all;white-space:pre-wrap;font-size:12px;">%s</td>
 			</tr>`,
 				rowID,
 				e.Time.Format("Jan 2 15:04:05"),
+				e.Level.String(),
+				url.QueryEscape(e.Package),
 				html.EscapeString(e.Package),
 				html.EscapeString(truncateMsg(e.Message, 80)),
 				rowID,
-				html.EscapeString(e.Message),
+				detail.String(),
 			))
 		}
 		content.WriteString(`</table>`)
@@ -91,6 +188,47 @@ func SysLogHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(pageHTML))
 }
 
+// logLevelsCard lists each package's log level, with a form to change it.
+func logLevelsCard() string {
+	levels := app.LogLevels()
+	pkgs := make([]string, 0, len(levels))
+	for p := range levels {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+	def := app.DefaultLogLevel()
+
+	var b strings.Builder
+	b.WriteString(`<div class="card">`)
+	b.WriteString(fmt.Sprintf(`<h3>Log Levels</h3><p class="text-muted">Packages log at %s unless set here. LOG_LEVEL changes the default.</p>`, def))
+	b.WriteString(`<table class="email-log"><tr><th>Package</th><th>Level</th></tr>`)
+	for _, p := range pkgs {
+		b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td><form method="POST" action="/admin/logs" style="display:inline;">`, html.EscapeString(p)))
+		b.WriteString(fmt.Sprintf(`<input type="hidden" name="package" value="%s">`, html.EscapeString(p)))
+		b.WriteString(`<select name="level" onchange="this.form.submit()">`)
+		b.WriteString(`<option value="">Default</option>`)
+		for _, name := range logLevelNames {
+			sel := ""
+			if l, _ := app.ParseLevel(name); l == levels[p] && l != def {
+				sel = " selected"
+			}
+			b.WriteString(fmt.Sprintf(`<option value="%s"%s>%s</option>`, name, sel, name))
+		}
+		b.WriteString(`</select></form></td></tr>`)
+	}
+	b.WriteString(`</table></div>`)
+	return b.String()
+}
+
+// levelNames spells out levels for the JSON API.
+func levelNames(levels map[string]slog.Level) map[string]string {
+	out := make(map[string]string, len(levels))
+	for p, l := range levels {
+		out[p] = l.String()
+	}
+	return out
+}
+
 // truncateMsg shortens s to at most max characters for display, appending "…" if truncated.
 func truncateMsg(s string, max int) string {
 	if len([]rune(s)) <= max {
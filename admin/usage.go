@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/wallet"
+)
+
+// usageWindows are the periods /admin/usage can cover, in the order the
+// page offers them.
+var usageWindows = []struct {
+	Key, Label string
+	Span       time.Duration
+}{
+	{"24h", "24 hours", 24 * time.Hour},
+	{"7d", "7 days", 7 * 24 * time.Hour},
+	{"30d", "30 days", 30 * 24 * time.Hour},
+	{"90d", "90 days", 90 * 24 * time.Hour},
+	{"all", "All time", 0},
+}
+
+// maxTopConsumers is how many accounts the page lists.
+const maxTopConsumers = 25
+
+// UsageHandler shows what members have used of the paid operations over
+// ?window=, summed from the wallet ledger: credits issued against credits
+// spent, each operation's uses and cost, and the top consumers. External
+// API costs are on /admin/usage/api.
+func UsageHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	window := r.URL.Query().Get("window")
+	var since time.Time
+	found := false
+	for _, uw := range usageWindows {
+		if uw.Key == window {
+			found = true
+			if uw.Span > 0 {
+				since = time.Now().Add(-uw.Span)
+			}
+		}
+	}
+	if !found {
+		window = "7d"
+		since = time.Now().Add(-7 * 24 * time.Hour)
+	}
+	report := wallet.Usage(since)
+
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"window": window, "usage": report})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card">`)
+	sb.WriteString(`<p>`)
+	for i, uw := range usageWindows {
+		if i > 0 {
+			sb.WriteString(` · `)
+		}
+		if uw.Key == window {
+			sb.WriteString(fmt.Sprintf(`<strong>%s</strong>`, uw.Label))
+		} else {
+			sb.WriteString(fmt.Sprintf(`<a href="/admin/usage?window=%s">%s</a>`, uw.Key, uw.Label))
+		}
+	}
+	sb.WriteString(`</p>`)
+	sb.WriteString(`<table class="email-log">`)
+	sb.WriteString(fmt.Sprintf(`<tr><td>Credits issued</td><td>%d <span class="text-muted">(%s)</span></td></tr>`, report.Issued, wallet.FormatCredits(report.Issued)))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Credits spent</td><td>%d <span class="text-muted">(%s)</span></td></tr>`, report.Spent, wallet.FormatCredits(report.Spent)))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Net issued</td><td>%+d</td></tr>`, report.Issued-report.Spent))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Transferred between members</td><td>%d</td></tr>`, report.Transferred))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Uses</td><td>%d by %d accounts</td></tr>`, report.Uses, len(report.Users)))
+	sb.WriteString(`</table>`)
+	sb.WriteString(`<p class="text-sm text-muted">Uses covered by the daily free quota, and every use by an admin, cost nothing and count only as uses. Issued counts top-ups and admin grants.</p>`)
+	sb.WriteString(`</div>`)
+
+	sb.WriteString(`<div class="card">`)
+	sb.WriteString(`<h3>By Operation</h3>`)
+	if len(report.Operations) == 0 {
+		sb.WriteString(`<p class="text-muted">No usage in this window.</p>`)
+	} else {
+		sb.WriteString(`<div style="overflow-x:auto;"><table class="email-log"><thead><tr><th>Operation</th><th>Uses</th><th>Paid</th><th>Credits</th><th>Accounts</th></tr></thead><tbody>`)
+		for _, o := range report.Operations {
+			sb.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%d</td></tr>`,
+				html.EscapeString(o.Label), o.Uses, o.Paid, o.Credits, o.Users))
+		}
+		sb.WriteString(`</tbody></table></div>`)
+	}
+	sb.WriteString(`</div>`)
+
+	sb.WriteString(`<div class="card">`)
+	sb.WriteString(`<h3>Top Consumers</h3>`)
+	if len(report.Users) == 0 {
+		sb.WriteString(`<p class="text-muted">No accounts used anything in this window.</p>`)
+	} else {
+		sb.WriteString(`<div style="overflow-x:auto;"><table class="email-log"><thead><tr><th>Account</th><th>Uses</th><th>Credits spent</th><th>Credits added</th><th>Most used</th></tr></thead><tbody>`)
+		for i, u := range report.Users {
+			if i == maxTopConsumers {
+				break
+			}
+			sb.WriteString(fmt.Sprintf(`<tr><td><a href="/@%s">%s</a></td><td>%d</td><td>%d</td><td>%d</td><td>%s</td></tr>`,
+				html.EscapeString(u.UserID), html.EscapeString(u.UserID), u.Uses, u.Credits, u.Issued, html.EscapeString(u.TopOperation())))
+		}
+		sb.WriteString(`</tbody></table></div>`)
+		if len(report.Users) > maxTopConsumers {
+			sb.WriteString(fmt.Sprintf(`<p class="text-sm text-muted">%d more accounts. The JSON view lists them all.</p>`, len(report.Users)-maxTopConsumers))
+		}
+	}
+	sb.WriteString(`</div>`)
+	sb.WriteString(`<p class="text-sm"><a href="/admin/usage/api">External API costs →</a></p>`)
+
+	page := app.RenderHTMLForRequest("Usage", "Credit usage by operation and account", sb.String(), r)
+	w.Write([]byte(page))
+}
@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/news/digest"
+)
+
+// WeeklyHandler is the review step for the weekly digest: admins preview
+// drafts, add an introduction, then publish (or discard) them.
+func WeeklyHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		if err := r.ParseForm(); err != nil {
+			app.BadRequest(w, r, "Failed to parse form")
+			return
+		}
+		id := r.FormValue("id")
+		switch r.FormValue("action") {
+		case "compile":
+			var issue *digest.Weekly
+			if issue, err = digest.CompileWeekly(id); err == nil {
+				id = issue.ID
+			}
+		case "intro":
+			err = digest.SetWeeklyIntro(id, r.FormValue("intro"))
+		case "publish":
+			if err = digest.SetWeeklyIntro(id, r.FormValue("intro")); err == nil {
+				err = digest.PublishWeekly(id, acc.ID, r.FormValue("blog") == "on")
+			}
+		case "discard":
+			err = digest.DiscardWeekly(id)
+		case "send_mail":
+			n := digest.SendMailDigests(time.Now(), true)
+			app.Log("admin", "%s mailed the weekly digest to %d members", acc.ID, n)
+		default:
+			err = fmt.Errorf("unknown action")
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		app.Log("admin", "%s weekly %s %s", acc.ID, r.FormValue("action"), id)
+		http.Redirect(w, r, "/admin/weekly?id="+id, http.StatusSeeOther)
+		return
+	}
+
+	issues := digest.Weeklies()
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"issues": issues})
+		return
+	}
+
+	// Show the requested issue, else the newest draft.
+	var selected *digest.Weekly
+	id := r.URL.Query().Get("id")
+	for _, issue := range issues {
+		if (id != "" && issue.ID == id) || (id == "" && issue.Status == digest.WeeklyDraft) {
+			selected = issue
+			break
+		}
+	}
+
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>Weekly Digest <span class="count">%d</span></h3>`, len(issues)))
+	content.WriteString(`<p class="text-sm text-muted">Each Monday the week just gone is compiled as a draft. Review it here; nothing appears at <a href="/weekly">/weekly</a> until it is published.</p>`)
+	content.WriteString(`<form method="POST" class="mt-2"><input type="hidden" name="action" value="compile"><button type="submit" class="btn-secondary">Compile last week now</button></form>`)
+	content.WriteString(fmt.Sprintf(`<p class="text-sm text-muted mt-2">%d members get last week's digest by mail each Monday (opt in on /account).</p>`, len(digest.MailDigestSubscribers())))
+	content.WriteString(`<form method="POST"><input type="hidden" name="action" value="send_mail"><button type="submit" class="btn-secondary" onclick="return confirm('Mail the digest to every subscriber now?')">Mail digest now</button></form>`)
+	if len(issues) > 0 {
+		content.WriteString(`<table class="email-log mt-2">`)
+		content.WriteString(`<tr><th>Week</th><th>Status</th><th class="hide-mobile">Compiled</th></tr>`)
+		for _, issue := range issues {
+			status := issue.Status
+			if issue.Status == digest.WeeklyPublished {
+				status = fmt.Sprintf(`published %s by %s`, app.TimeAgo(issue.PublishedAt), html.EscapeString(issue.PublishedBy))
+			}
+			content.WriteString(fmt.Sprintf(`<tr><td><a href="/admin/weekly?id=%s">%s</a></td><td>%s</td><td class="hide-mobile">%s</td></tr>`,
+				issue.ID, html.EscapeString(issue.Title()), status, app.TimeAgo(issue.CompiledAt)))
+		}
+		content.WriteString(`</table>`)
+	}
+	content.WriteString(`</div>`)
+
+	if selected != nil {
+		content.WriteString(`<div class="card">`)
+		content.WriteString(fmt.Sprintf(`<h3>%s <span class="count">%s</span></h3>`, html.EscapeString(selected.Title()), selected.Status))
+		if selected.Status == digest.WeeklyPublished {
+			content.WriteString(fmt.Sprintf(`<p class="text-sm"><a href="/weekly?id=%s">View on /weekly →</a></p>`, selected.ID))
+		} else {
+			content.WriteString(fmt.Sprintf(`<form method="POST">
+				<input type="hidden" name="id" value="%s">
+				<p class="text-sm text-muted">Introduction (markdown, optional)</p>
+				<textarea name="intro" rows="4" style="width:100%%">%s</textarea>
+				<label class="text-sm" style="display:flex;align-items:center;gap:8px;margin:8px 0"><input type="checkbox" name="blog"> Also post to the blog</label>
+				<button type="submit" name="action" value="publish">Publish</button>
+				<button type="submit" name="action" value="intro" class="btn-secondary">Save intro</button>
+				<button type="submit" name="action" value="compile" class="btn-secondary">Recompile</button>
+				<button type="submit" name="action" value="discard" class="btn-secondary" onclick="return confirm('Discard this draft?')">Discard</button>
+			</form>`, selected.ID, html.EscapeString(selected.Intro)))
+		}
+		// Preview as a guest sees it; members-only welcomes are left out.
+		content.WriteString(`<hr>`)
+		content.WriteString(digest.WeeklyHTML(selected, ""))
+		content.WriteString(`</div>`)
+	}
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	html := app.RenderHTMLForRequest("Weekly Digest", "Review the weekly digest", content.String(), r)
+	w.Write([]byte(html))
+}
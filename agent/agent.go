@@ -548,7 +548,7 @@ func serveFlowPage(w http.ResponseWriter, r *http.Request, id string) {
 	// Actions — no card wrapper, just links
 	b.WriteString(`<div style="display:flex;gap:12px;align-items:center;margin-top:12px;font-size:13px;">`)
 	b.WriteString(`<a href="/agent?continue=` + f.ID + `">Continue →</a>`)
-	b.WriteString(`<a href="#" onclick="var u=location.href;if(navigator.share){navigator.share({url:u})}else if(navigator.clipboard){navigator.clipboard.writeText(u).then(function(){this.textContent='Copied!'}.bind(this))}else{prompt('Copy:',u)};return false;">Share</a>`)
+	b.WriteString(app.ShareLink("", "Share", ""))
 	b.WriteString(`</div>`)
 
 	html := app.RenderHTMLForRequest("Agent", "Saved agent query: "+htmlEsc(f.Prompt), b.String(), r)
@@ -0,0 +1,16 @@
+package agent
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/agent", Handler) // public page, auth checked in the handler
+	r.HandleFunc("/agent/", Handler)
+	r.HandleFunc("/agent/agents", AgentsHandler)
+	r.HandleFunc("/agent/new", NewAgentHandler)
+	r.HandleFunc("/agent/run", RunHandler)
+	r.HandleFunc("/agent/exec", ExecResultHandler)
+}
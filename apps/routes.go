@@ -0,0 +1,12 @@
+package apps
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/apps", Handler) // public directory; creating and editing need a session
+	r.HandleFunc("/apps/", Handler)
+}
@@ -30,19 +30,17 @@ var (
 
 func init() {
 	// Clean up old code runs every 10 minutes
-	go func() {
-		for {
-			time.Sleep(10 * time.Minute)
-			runMu.Lock()
-			cutoff := time.Now().Add(-1 * time.Hour)
-			for id, r := range codeRuns {
-				if r.CreatedAt.Before(cutoff) {
-					delete(codeRuns, id)
-				}
+	app.Schedule(app.Job{Name: "apps.runs", Every: 10 * time.Minute, Delay: 10 * time.Minute, Run: func() error {
+		runMu.Lock()
+		cutoff := time.Now().Add(-1 * time.Hour)
+		for id, r := range codeRuns {
+			if r.CreatedAt.Before(cutoff) {
+				delete(codeRuns, id)
 			}
-			runMu.Unlock()
 		}
-	}()
+		runMu.Unlock()
+		return nil
+	}})
 }
 
 func codeRunID() string {
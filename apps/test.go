@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 
+	"mu/internal/app"
 	"mu/internal/auth"
 )
 
@@ -159,7 +160,7 @@ func executeSDKCall(sc sdkCall, authorID string) APITestResult {
 	req.Header.Set("Accept", "application/json")
 
 	recorder := httptest.NewRecorder()
-	http.DefaultServeMux.ServeHTTP(recorder, req)
+	app.Internal(recorder, req)
 
 	tr.Status = recorder.Code
 	if recorder.Code >= 400 {
@@ -1,10 +1,12 @@
 package blog
 
 import (
-	_ "embed"
+	"embed"
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
@@ -18,11 +20,18 @@ import (
 	"mu/internal/flag"
 	"mu/internal/service"
 	"mu/internal/snapshot"
+	"mu/places"
 )
 
 //go:embed topics.json
 var topicsJSON []byte
 
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// templates are the blog pages built with html/template.
+var templates = app.ParseTemplates(templateFiles, "templates/*.html")
+
 // cardSnap is the go-micro read-plane channel for the blog preview card (store +
 // broker); see internal/snapshot and docs/GO_MICRO_ARCHITECTURE.md.
 var cardSnap *snapshot.Snapshot
@@ -41,8 +50,23 @@ var comments []*Comment
 // cached HTML for home page preview
 var postsPreviewHtml string
 
+// OnPost is called when a post goes live and OnComment when a comment is
+// added. Wired from main.go.
+var (
+	OnPost    func(post *Post)
+	OnComment func(post *Post, comment *Comment)
+)
+
 // cached HTML for full blog page
-var postsList string
+// postsEntries is the rendered /blog list, one entry per visible post,
+// newest first. Pages are sliced from it by post ID.
+var postsEntries []listEntry
+
+// listEntry is one rendered post in the /blog list.
+type listEntry struct {
+	ID   string
+	HTML string
+}
 
 // Valid topics/categories for posts
 var topics []string
@@ -58,6 +82,14 @@ type Post struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at,omitempty"`
 	Comments  []*Comment `json:"-"` // Not persisted, populated on load
+
+	// ModeratedAt is when an admin last edited the post (see ModeratorEdit).
+	ModeratedAt time.Time `json:"moderated_at,omitempty"`
+
+	// Draft posts are kept apart from published ones until they go live
+	// (see drafts.go); PublishAt schedules one to go live on its own.
+	Draft     bool      `json:"draft,omitempty"`
+	PublishAt time.Time `json:"publish_at,omitempty"`
 }
 
 type Comment struct {
@@ -67,6 +99,9 @@ type Comment struct {
 	Author    string    `json:"author"`
 	AuthorID  string    `json:"author_id"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// ModeratedAt is when an admin last edited the comment.
+	ModeratedAt time.Time `json:"moderated_at,omitempty"`
 }
 
 // tagRegex validates tag format: alphanumeric only
@@ -137,6 +172,11 @@ func parseTags(input string) string {
 
 // Load initializes the blog package and sets up event subscriptions
 func Load() {
+	data.RegisterTable("blog.json")
+	data.RegisterTable("comments.json")
+	data.RegisterTable("blog_drafts.json")
+	data.RegisterExporter(exporter{})
+
 	if err := service.Register("blog", new(Server)); err != nil {
 		app.Log("blog", "service register failed: %v", err)
 	}
@@ -259,6 +299,15 @@ func Load() {
 	// Link comments to posts
 	populateComments()
 
+	// Load the moderator edit history
+	loadModeratorEdits()
+
+	// Load unpublished drafts
+	loadDrafts()
+
+	// Load reactions on posts and comments
+	loadReactions()
+
 	// Update cached HTML
 	updateCache()
 
@@ -282,6 +331,7 @@ func Load() {
 
 	// Register with moderation subsystem
 	flag.RegisterDeleter("post", &postDeleter{})
+	flag.RegisterDeleter("comment", &commentDeleter{})
 
 	// Register with admin delete
 	data.RegisterDeleter("blog", DeletePost)
@@ -300,10 +350,13 @@ func (d *postDeleter) Get(id string) interface{} {
 		return nil
 	}
 	return flag.PostContent{
+		ID:        post.ID,
 		Title:     post.Title,
 		Content:   post.Content,
 		Author:    post.Author,
+		AuthorID:  post.AuthorID,
 		CreatedAt: post.CreatedAt,
+		URL:       "/blog/post?id=" + post.ID,
 	}
 }
 
@@ -508,114 +561,149 @@ func updateCacheUnlocked() {
 		postsPreviewHtml = strings.Join(preview, "\n")
 	}
 
-	// Generate full list for blog page (exclude flagged posts)
-	var fullList []string
-	for _, post := range posts {
-		// Skip flagged posts
-		if flag.IsHidden("post", post.ID) || auth.IsBanned(post.AuthorID) {
-			continue
+	// Generate full list for blog page. In low-memory mode it is not kept;
+	// listPage renders each page on demand instead.
+	var fullList []listEntry
+	if !data.LowMemory() {
+		for _, post := range posts {
+			if listed(post) {
+				fullList = append(fullList, renderListEntry(post))
+			}
 		}
+	}
+	postsEntries = fullList
 
-		// Skip private posts (blog list shows only public posts)
-		if post.Private {
-			continue
-		}
+	// Publish the rebuilt preview snapshot to the go-micro store + broker; runs
+	// under the caller's lock (nil-safe before Load wires cardSnap).
+	cardSnap.Publish(postsPreviewHtml)
+}
 
-		// Skip posts from new accounts (< 24 hours old)
-		if post.AuthorID != "" && auth.IsNewAccount(post.AuthorID) {
-			continue
-		}
+// listed reports whether a post appears in the /blog list: public, not
+// flagged, and not from a banned or brand-new account.
+func listed(post *Post) bool {
+	if flag.IsHidden("post", post.ID) || auth.IsBanned(post.AuthorID) {
+		return false
+	}
+	if post.Private {
+		return false
+	}
+	// Skip posts from new accounts (< 24 hours old)
+	if post.AuthorID != "" && auth.IsNewAccount(post.AuthorID) {
+		return false
+	}
+	return true
+}
 
-		title := post.Title
-		if title == "" {
-			title = "Untitled"
-		}
+// renderListEntry renders a post for the /blog list. Caller must hold mutex.
+func renderListEntry(post *Post) listEntry {
+	title := post.Title
+	if title == "" {
+		title = "Untitled"
+	}
 
-		// Use pre-rendered HTML, truncate for list view
-		content := post.Content
+	// Use pre-rendered HTML, truncate for list view
+	content := post.Content
 
-		// Truncate plain text before rendering
-		truncated := false
-		if len(content) > 500 {
-			lastSpace := 500
-			for i := 499; i >= 0 && i < len(content); i-- {
-				if content[i] == ' ' {
-					lastSpace = i
-					break
-				}
+	// Truncate plain text before rendering
+	truncated := false
+	if len(content) > 500 {
+		lastSpace := 500
+		for i := 499; i >= 0 && i < len(content); i-- {
+			if content[i] == ' ' {
+				lastSpace = i
+				break
 			}
-			content = content[:lastSpace] + "..."
-			truncated = true
 		}
+		content = content[:lastSpace] + "..."
+		truncated = true
+	}
 
-		// Add links and YouTube embeds
-		content = Linkify(content)
-
-		authorLink := post.Author
-		if post.AuthorID != "" {
-			authorLink = fmt.Sprintf(`<a href="/@%s">%s</a>`, post.AuthorID, post.Author)
-		}
+	// Add links and YouTube embeds
+	content = Linkify(content)
 
-		tagsHtml := ""
-		if post.Tags != "" {
-			tagsHtml = formatTags(post.Tags)
-		}
+	authorLink := post.Author
+	if post.AuthorID != "" {
+		authorLink = fmt.Sprintf(`<a href="/@%s">%s</a>`, post.AuthorID, post.Author)
+	}
 
-		// Add private badge if post is private
-		if post.Private {
-			privateBadge := `<span class="category badge-private">Private</span>`
-			if tagsHtml != "" {
-				tagsHtml = tagsHtml + " " + privateBadge
-			} else {
-				tagsHtml = privateBadge
-			}
-		}
+	tagsHtml := ""
+	if post.Tags != "" {
+		tagsHtml = formatTags(post.Tags)
+	}
 
+	// Add private badge if post is private
+	if post.Private {
+		privateBadge := `<span class="category badge-private">Private</span>`
 		if tagsHtml != "" {
-			tagsHtml = `<div class="mt-2">` + tagsHtml + `</div>`
-		}
-
-		// Add Comment/Comments count
-		commentCount := countComments(post)
-		replyLink := ""
-		if commentCount == 0 {
-			replyLink = fmt.Sprintf(` · <a href="/blog/post?id=%s">Comment</a>`, post.ID)
+			tagsHtml = tagsHtml + " " + privateBadge
 		} else {
-			replyLink = fmt.Sprintf(` · <a href="/blog/post?id=%s">Comments (%d)</a>`, post.ID, commentCount)
+			tagsHtml = privateBadge
 		}
+	}
 
-		keepReading := ""
-		if truncated {
-			keepReading = fmt.Sprintf(`<a href="/blog/post?id=%s" class="keep-reading">Keep Reading →</a>`, post.ID)
-		}
+	if tagsHtml != "" {
+		tagsHtml = `<div class="mt-2">` + tagsHtml + `</div>`
+	}
 
-		listTime := post.CreatedAt
-		listTimeLabel := app.TimeAgo(listTime)
-		if !post.UpdatedAt.IsZero() {
-			listTime = post.UpdatedAt
-			listTimeLabel = "Updated " + app.TimeAgo(listTime)
-		}
+	// Add Comment/Comments count
+	commentCount := countComments(post)
+	replyLink := ""
+	if commentCount == 0 {
+		replyLink = fmt.Sprintf(` · <a href="/blog/post?id=%s">Comment</a>`, post.ID)
+	} else {
+		replyLink = fmt.Sprintf(` · <a href="/blog/post?id=%s">Comments (%d)</a>`, post.ID, commentCount)
+	}
 
-		controls := app.StaticControls("post", post.ID)
-		item := fmt.Sprintf(`<div class="post-item">
-			%s
-			<h3><a href="/blog/post?id=%s">%s</a></h3>
-			<div class="info"><span data-timestamp="%d">%s</span> · %s%s%s</div>
-			<div>%s</div>
-			%s
-		</div>`, tagsHtml, post.ID, title, listTime.Unix(), listTimeLabel, authorLink, replyLink, controls, content, keepReading)
-		fullList = append(fullList, item)
+	keepReading := ""
+	if truncated {
+		keepReading = fmt.Sprintf(`<a href="/blog/post?id=%s" class="keep-reading">Keep Reading →</a>`, post.ID)
 	}
 
-	if len(fullList) == 0 {
-		postsList = "<p>No blog posts yet. Write something below!</p>"
-	} else {
-		postsList = strings.Join(fullList, "\n")
+	listTime := post.CreatedAt
+	listTimeLabel := app.TimeAgo(listTime)
+	if !post.UpdatedAt.IsZero() {
+		listTime = post.UpdatedAt
+		listTimeLabel = "Updated " + app.TimeAgo(listTime)
 	}
 
-	// Publish the rebuilt preview snapshot to the go-micro store + broker; runs
-	// under the caller's lock (nil-safe before Load wires cardSnap).
-	cardSnap.Publish(postsPreviewHtml)
+	controls := app.StaticControls("post", post.ID)
+	item := fmt.Sprintf(`<div class="post-item">
+		%s
+		<h3><a href="/blog/post?id=%s">%s</a></h3>
+		<div class="info"><span data-timestamp="%d">%s</span> · %s%s%s</div>
+		<div>%s</div>
+		%s
+	</div>`, tagsHtml, post.ID, title, listTime.Unix(), listTimeLabel, authorLink, replyLink, controls, content, keepReading)
+	return listEntry{ID: post.ID, HTML: item}
+}
+
+// listPage returns one page of the rendered /blog list, the number of
+// listed posts, and the cursor for the next page. It slices postsEntries,
+// or in low-memory mode renders just the posts on the page.
+func listPage(after string, limit int) ([]string, int, string) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var items []string
+	if !data.LowMemory() {
+		start, end, next := app.PageAfter(len(postsEntries), func(i int) string { return postsEntries[i].ID }, after, limit)
+		for _, e := range postsEntries[start:end] {
+			items = append(items, e.HTML)
+		}
+		return items, len(postsEntries), next
+	}
+
+	var list []*Post
+	for _, post := range posts {
+		if listed(post) {
+			list = append(list, post)
+		}
+	}
+	start, end, next := app.PageAfter(len(list), func(i int) string { return list[i].ID }, after, limit)
+	for _, post := range list[start:end] {
+		items = append(items, renderListEntry(post).HTML)
+	}
+	return items, len(list), next
 }
 
 // Preview returns HTML preview of latest posts for home page
@@ -755,7 +843,7 @@ func PostingForm(action string) string {
 	return fmt.Sprintf(`<div id="post-form-container">
 		<form id="post-form" class="blog-form" method="POST" action="%s">
 			<input type="text" name="title" placeholder="Title (optional)">
-			<textarea name="content" rows="4" placeholder="Share a thought. Be mindful of Allah" required></textarea>
+			<textarea name="content" data-mentions rows="4" placeholder="Share a thought. Be mindful of Allah" required></textarea>
 			<input type="text" name="tags" placeholder="Tags (optional, comma-separated)" class="text-sm">
 			<button type="submit">Post</button>
 		</form>
@@ -775,6 +863,12 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 // handleGetBlog handles GET /blog - returns posts as JSON or HTML
 func handleGetBlog(w http.ResponseWriter, r *http.Request) {
+	// The signed-in author's drafts
+	if r.URL.Query().Get("drafts") == "true" {
+		handleDrafts(w, r)
+		return
+	}
+
 	// Return JSON if requested
 	if app.WantsJSON(r) {
 		mutex.RLock()
@@ -795,43 +889,95 @@ func handleGetBlog(w http.ResponseWriter, r *http.Request) {
 		}
 		mutex.RUnlock()
 
-		app.RespondJSON(w, visiblePosts)
+		after, limit := app.Cursor(r)
+		start, end, next := app.PageAfter(len(visiblePosts), func(i int) string { return visiblePosts[i].ID }, after, limit)
+		if next != "" {
+			w.Header().Set("Link", fmt.Sprintf(`</blog?after=%s&limit=%d>; rel="next"`, url.QueryEscape(next), limit))
+		}
+		page := visiblePosts[start:end]
+		if page == nil {
+			page = []*Post{}
+		}
+		app.RespondJSON(w, page)
 		return
 	}
 
-	mutex.RLock()
-	list := postsList
-	mutex.RUnlock()
+	after, limit := app.Cursor(r)
+	items, total, next := listPage(after, limit)
+	list := strings.Join(items, "\n")
+	if total == 0 {
+		list = "<p>No blog posts yet. Write something below!</p>"
+	}
+	loadMore := ""
+	if next != "" {
+		loadMore = `<div class="mt-4">` + app.LoadMore("posts-list", fmt.Sprintf("/blog?after=%s&limit=%d", url.QueryEscape(next), limit)) + `</div>`
+	}
 
 	// Check if write mode is requested
 	showWriteForm := r.URL.Query().Get("write") == "true"
 
-	// Require authentication for write mode
+	// Require authentication for write mode. Editing a draft fills the
+	// form with it instead of what was last typed.
+	var draft *Post
+	var composeInput string
 	if showWriteForm {
 		_, acc := auth.TrySession(r)
 		if acc == nil {
 			app.Unauthorized(w, r)
 			return
 		}
+		composeInput = composeField(acc.ID)
+		if id := r.URL.Query().Get("draft"); id != "" {
+			if draft = GetDraft(acc.ID, id); draft == nil {
+				app.NotFound(w, r, "Draft not found")
+				return
+			}
+		}
 	}
 
 	var content string
 	if showWriteForm {
-		// Show only the posting form
+		// Show only the posting form, filled in when editing a draft
+		var draftID, title, body, tags, publishAt, scheduleOpen, privateSelected string
+		publicSelected, cancel, editingDraft := "selected", "/blog", "false"
+		if draft != nil {
+			draftID = draft.ID
+			title = html.EscapeString(draft.Title)
+			body = html.EscapeString(draft.Content)
+			tags = html.EscapeString(draft.Tags)
+			if !draft.PublishAt.IsZero() {
+				publishAt = draft.PublishAt.UTC().Format(time.RFC3339)
+				scheduleOpen = " open"
+			}
+			if draft.Private {
+				publicSelected, privateSelected = "", "selected"
+			}
+			cancel, editingDraft = "/blog?drafts=true", "true"
+		}
 		content = `<div id="blog">
 			<div class="mb-6">
 				<form id="blog-form" class="blog-form" method="POST" action="/blog">
-					<input type="text" id="post-title" name="title" placeholder="Title (optional)">
-					<textarea id="post-content" name="content" rows="6" placeholder="Share a thought. Be mindful of Allah" required></textarea>
-					<input type="text" id="post-tags" name="tags" placeholder="Tags (optional, comma-separated)">
+					<input type="hidden" name="draft_id" value="` + draftID + `">
+					` + composeInput + `
+					<input type="text" id="post-title" name="title" placeholder="Title (optional)" value="` + title + `">
+					<textarea id="post-content" data-mentions name="content" rows="6" placeholder="Share a thought. Be mindful of Allah" required>` + body + `</textarea>
+					` + places.PickerHTML("post-content") + `
+					` + app.ImageUploadHTML("post-content") + `
+					` + app.PreviewHTML("post-content") + `
+					<input type="text" id="post-tags" name="tags" placeholder="Tags (optional, comma-separated)" value="` + tags + `">
+					<details class="text-sm text-muted"` + scheduleOpen + `><summary>Schedule</summary>
+						<input type="datetime-local" id="post-publish-at" name="publish_at_local" data-publish-at="` + publishAt + `"> <span class="text-xs">Leave empty to publish now</span>
+					</details>
+					<input type="hidden" name="publish_at" value="">
 					<div class="blog-form-row">
 						<select id="post-visibility" name="visibility">
-							<option value="public" selected>Public</option>
-							<option value="private">Private (Admin only)</option>
+							<option value="public" ` + publicSelected + `>Public</option>
+							<option value="private" ` + privateSelected + `>Private (Admin only)</option>
 						</select>
 						<div class="blog-form-actions">
-							<a href="/blog" class="btn btn-secondary">Cancel</a>
-							<button type="submit">Post</button>
+							<a href="` + cancel + `" class="btn btn-secondary">Cancel</a>
+							<button type="submit" name="action" value="draft" class="btn-secondary">Save draft</button>
+							<button type="submit" id="post-submit" name="action" value="publish">Post</button>
 						</div>
 					</div>
 					<div class="blog-form-hint">
@@ -840,6 +986,7 @@ func handleGetBlog(w http.ResponseWriter, r *http.Request) {
 				</form>
 			</div>
 			<script>
+				const editingDraft = ` + editingDraft + `;
 				const form = document.getElementById('blog-form');
 				const titleInput = document.getElementById('post-title');
 				const textarea = document.getElementById('post-content');
@@ -866,6 +1013,7 @@ func handleGetBlog(w http.ResponseWriter, r *http.Request) {
 				
 				// Save form values to localStorage
 				function saveFormValues() {
+					if (editingDraft) return;
 					localStorage.setItem('blog-post-title', titleInput.value);
 					localStorage.setItem('blog-post-content', textarea.value);
 					localStorage.setItem('blog-post-tags', tagsInput.value);
@@ -917,8 +1065,38 @@ func handleGetBlog(w http.ResponseWriter, r *http.Request) {
 					saveFormValues();
 				});
 				
+				// Schedule: show a saved time in the writer's timezone, label
+				// the button to match, and submit the time in UTC.
+				const publishAtInput = document.getElementById('post-publish-at');
+				const submitButton = document.getElementById('post-submit');
+				// New posts wait until the writer has had a moment with
+				// them; drafts and scheduled posts don't.
+				const postAfter = parseInt(form.elements.compose_token.dataset.postAfter || '0', 10);
+				function updateSubmitLabel() {
+					const wait = Math.ceil((postAfter - Date.now()) / 1000);
+					if (wait > 0 && !publishAtInput.value && !editingDraft) {
+						submitButton.textContent = 'Post in ' + wait + 's';
+						submitButton.disabled = true;
+						return;
+					}
+					submitButton.disabled = false;
+					submitButton.textContent = publishAtInput.value ? 'Schedule' : 'Post';
+				}
+				setInterval(updateSubmitLabel, 1000);
+				if (publishAtInput.dataset.publishAt) {
+					const d = new Date(publishAtInput.dataset.publishAt);
+					if (!isNaN(d)) {
+						d.setMinutes(d.getMinutes() - d.getTimezoneOffset());
+						publishAtInput.value = d.toISOString().slice(0, 16);
+					}
+				}
+				publishAtInput.addEventListener('input', updateSubmitLabel);
+				updateSubmitLabel();
+				
 				// Clear on successful submit
 				form.addEventListener('submit', function() {
+					const v = publishAtInput.value;
+					form.elements.publish_at.value = v ? new Date(v).toISOString() : '';
 					clearFormValues();
 				});
 				
@@ -926,23 +1104,34 @@ func handleGetBlog(w http.ResponseWriter, r *http.Request) {
 				window.addEventListener('resize', autoGrow);
 				
 				// Initial setup
-				restoreFormValues();
+				if (editingDraft) {
+					updateCharCount();
+					autoGrow();
+				} else {
+					restoreFormValues();
+				}
 			</script>
 		</div>`
 	} else {
 		// Show posts list with conditional write link
 		var actions string
 		_, acc := auth.TrySession(r)
+		draftsLink := ""
+		if acc != nil {
+			if n := CountDrafts(acc.ID); n > 0 {
+				draftsLink = fmt.Sprintf(`<a href="/blog?drafts=true" class="text-muted text-sm ml-4">Drafts (%d)</a>`, n)
+			}
+		}
 		if acc != nil && acc.Admin {
 			// Admin: show write and moderate links
 			actions = `<div class="mb-4">
-				<a href="/blog?write=true" class="btn">+ Write</a>
+				<a href="/blog?write=true" class="btn">+ Write</a>` + draftsLink + `
 				<a href="/admin/moderate" class="text-muted text-sm ml-4">Moderate</a>
 			</div>`
 		} else if acc != nil {
 			// Regular user: show only write link
 			actions = `<div class="mb-4">
-				<a href="/blog?write=true" class="btn">+ Write</a>
+				<a href="/blog?write=true" class="btn">+ Write</a>` + draftsLink + `
 			</div>`
 		} else {
 			// Guest user, show login prompt
@@ -955,7 +1144,9 @@ func handleGetBlog(w http.ResponseWriter, r *http.Request) {
 			<div id="posts-list">
 				%s
 			</div>
-		</div>`, actions, list)
+			%s
+			<p class="text-muted text-sm mt-4">Subscribe: <a href="/blog/feed.xml" class="text-muted">RSS</a> · <a href="/blog/atom.xml" class="text-muted">Atom</a></p>
+		</div>`, actions, list, loadMore)
 	}
 
 	html := app.RenderHTMLForRequest("Blog", "Share your thoughts", content, r)
@@ -975,7 +1166,12 @@ func CreatePost(title, content, author, authorID, tags string, private bool) err
 		Private:   private,
 		CreatedAt: time.Now(),
 	}
+	return publishPost(post)
+}
 
+// publishPost puts a post live: it is added to the list, saved, cached,
+// indexed and, if untagged, sent for auto-tagging.
+func publishPost(post *Post) error {
 	mutex.Lock()
 	// Add to beginning of slice (newest first)
 	posts = append([]*Post{post}, posts...)
@@ -1006,12 +1202,16 @@ func CreatePost(title, content, author, authorID, tags string, private bool) err
 			},
 		)
 	}(post.ID, post.Title, post.Content, post.Author, post.Tags)
+	indexPlaces(post)
 
 	// Auto-tag if no tags provided
-	if tags == "" {
-		go autoTagPost(post.ID, title, content)
+	if post.Tags == "" {
+		go autoTagPost(post.ID, post.Title, post.Content)
 	}
 
+	if OnPost != nil {
+		go OnPost(post)
+	}
 	return nil
 }
 
@@ -1046,7 +1246,8 @@ func CreateComment(postID, content, author, authorID string) (*Comment, error) {
 	comments = append(comments, comment)
 
 	// Add comment directly to the post's Comments slice using map lookup
-	if post := postsMap[postID]; post != nil {
+	post := postsMap[postID]
+	if post != nil {
 		post.Comments = append(post.Comments, comment)
 	}
 
@@ -1057,6 +1258,9 @@ func CreateComment(postID, content, author, authorID string) (*Comment, error) {
 	if err := data.SaveJSON("comments.json", comments); err != nil {
 		return nil, err
 	}
+	if post != nil && OnComment != nil {
+		go OnComment(post, comment)
+	}
 	return comment, nil
 }
 
@@ -1100,6 +1304,8 @@ func DeletePost(id string) error {
 
 	// Remove from map
 	delete(postsMap, id)
+	places.SetMentions("post", id, "", "", "")
+	data.Unindex(id)
 
 	// Remove from slice
 	for i, post := range posts {
@@ -1109,6 +1315,18 @@ func DeletePost(id string) error {
 		}
 	}
 
+	// Its reactions, and those on its comments, go with it
+	if dropReactionsLocked(func(re *Reaction) bool {
+		if re.Type == "comment" {
+			c := getCommentLocked(re.ID)
+			return c != nil && c.PostID == id
+		}
+		return re.ID == id
+	}) {
+		saveReactions()
+	}
+	deleteRevisionsLocked(id)
+
 	save()
 	updateCacheUnlocked()
 	return nil
@@ -1123,7 +1341,14 @@ func UpdatePost(id, title, content, tags string, private bool) error {
 	if post == nil {
 		return fmt.Errorf("post not found")
 	}
+	saveRevisionLocked(post, title, content, tags, private, false)
+	updatePostLocked(post, title, content, tags, private)
+	return nil
+}
 
+// updatePostLocked applies an edit, saves and re-indexes. Caller must hold
+// mutex.
+func updatePostLocked(post *Post, title, content, tags string, private bool) {
 	post.Title = title
 	post.Content = content
 	post.Tags = tags
@@ -1147,8 +1372,17 @@ func UpdatePost(id, title, content, tags string, private bool) error {
 			},
 		)
 	}(post.ID, post.Title, post.Content, post.Author, post.Tags)
+	indexPlaces(post)
+}
 
-	return nil
+// indexPlaces records the places a post embeds so their pages can list
+// it. Private posts aren't listed.
+func indexPlaces(post *Post) {
+	text := post.Content
+	if post.Private {
+		text = ""
+	}
+	places.SetMentions("post", post.ID, post.Title, "/blog/post?id="+post.ID, text)
 }
 
 // RefreshCache updates the cached HTML
@@ -1193,6 +1427,79 @@ func FindTodayDigest() *Post {
 	return nil
 }
 
+// Discussion is a post and the number of comments it drew in a period.
+type Discussion struct {
+	Post     *Post
+	Comments int
+}
+
+// publicPost reports whether p can be shown to everyone (caller must hold
+// mutex).
+func publicPost(p *Post) bool {
+	return !p.Private && !flag.IsHidden("post", p.ID) && !auth.IsBanned(p.AuthorID)
+}
+
+// postArchived reports whether a post is closed to comments, going by its
+// latest comment (see app.IsArchived). Caller must hold mutex.
+func postArchived(post *Post) bool {
+	last := post.CreatedAt
+	for _, c := range post.Comments {
+		if c.CreatedAt.After(last) {
+			last = c.CreatedAt
+		}
+	}
+	return app.IsArchived("post", post.ID, last)
+}
+
+// PostsBetween returns public posts by members created in [from, to),
+// newest first. Posts by the system user (digests, opinions) are left out.
+func PostsBetween(from, to time.Time) []*Post {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var result []*Post
+	for _, post := range posts {
+		if post.AuthorID == app.SystemUserID || !publicPost(post) {
+			continue
+		}
+		if !post.CreatedAt.Before(from) && post.CreatedAt.Before(to) {
+			result = append(result, post)
+		}
+	}
+	return result
+}
+
+// ActiveDiscussions returns up to n public posts with the most comments
+// made in [from, to), busiest first.
+func ActiveDiscussions(from, to time.Time, n int) []Discussion {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	counts := map[string]int{}
+	for _, c := range comments {
+		if c.CreatedAt.Before(from) || !c.CreatedAt.Before(to) {
+			continue
+		}
+		if flag.IsHidden("comment", c.ID) || auth.IsBanned(c.AuthorID) {
+			continue
+		}
+		counts[c.PostID]++
+	}
+	var result []Discussion
+	for _, post := range posts {
+		if counts[post.ID] > 0 && publicPost(post) && !postArchived(post) {
+			result = append(result, Discussion{Post: post, Comments: counts[post.ID]})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Comments > result[j].Comments
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
 // handlePost processes the POST request to create a new blog post
 // PostHandler serves individual blog posts (public, no auth required) and handles PATCH for editing
 // Supports both HTML and JSON requests
@@ -1205,15 +1512,16 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Handle POST to create new post (no id required)
 	if r.Method == "POST" && id == "" {
-		var title, content, tags string
+		var title, content, tags, composeToken string
 		var private bool
 
 		if app.SendsJSON(r) {
 			var req struct {
-				Title   string `json:"title"`
-				Content string `json:"content"`
-				Tags    string `json:"tags"`
-				Private bool   `json:"private"`
+				Title        string `json:"title"`
+				Content      string `json:"content"`
+				Tags         string `json:"tags"`
+				Private      bool   `json:"private"`
+				ComposeToken string `json:"compose_token"`
 			}
 			if err := app.DecodeJSON(r, &req); err != nil {
 				app.RespondError(w, http.StatusBadRequest, "invalid json")
@@ -1223,6 +1531,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			content = strings.TrimSpace(req.Content)
 			tags = parseTags(req.Tags)
 			private = req.Private
+			composeToken = req.ComposeToken
 		} else {
 			if err := r.ParseForm(); err != nil {
 				http.Error(w, "Failed to parse form", http.StatusBadRequest)
@@ -1232,6 +1541,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			content = strings.TrimSpace(r.FormValue("content"))
 			tags = parseTags(r.FormValue("tags"))
 			private = r.FormValue("visibility") == "private"
+			composeToken = r.FormValue("compose_token")
 		}
 
 		// Validate content
@@ -1256,6 +1566,10 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		author := acc.Name
 		authorID := acc.ID
+		held := &Post{Title: title, Content: content, Tags: tags, Private: private}
+		if holdPost(w, r, authorID, composeToken, held) {
+			return
+		}
 
 		// Create post
 		postID := fmt.Sprintf("%d", time.Now().UnixNano())
@@ -1316,7 +1630,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var title, content, tags string
+		var title, content, tags, reason string
 		var private bool
 
 		if app.SendsJSON(r) {
@@ -1325,6 +1639,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 				Content    string `json:"content"`
 				Tags       string `json:"tags"`
 				Visibility string `json:"visibility"`
+				Reason     string `json:"reason"`
 			}
 			if err := app.DecodeJSON(r, &req); err != nil {
 				app.RespondError(w, http.StatusBadRequest, "invalid json")
@@ -1334,6 +1649,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			content = strings.TrimSpace(req.Content)
 			tags = parseTags(req.Tags)
 			private = req.Visibility == "private"
+			reason = strings.TrimSpace(req.Reason)
 		} else {
 			if err := r.ParseForm(); err != nil {
 				http.Error(w, "Failed to parse form", http.StatusBadRequest)
@@ -1343,6 +1659,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			content = strings.TrimSpace(r.FormValue("content"))
 			tags = parseTags(r.FormValue("tags"))
 			private = r.FormValue("visibility") == "private"
+			reason = strings.TrimSpace(r.FormValue("reason"))
 		}
 
 		if content == "" {
@@ -1357,7 +1674,14 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := UpdatePost(id, title, content, tags, private); err != nil {
+		// An admin editing someone else's post is a moderator edit: it is
+		// recorded separately and the post shows that it was modified.
+		if post.AuthorID != acc.ID {
+			err = ModeratorEditPost(id, title, content, tags, private, acc, reason)
+		} else {
+			err = UpdatePost(id, title, content, tags, private)
+		}
+		if err != nil {
 			http.Error(w, "Failed to update post", http.StatusInternalServerError)
 			return
 		}
@@ -1394,6 +1718,9 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			app.ServerError(w, r, "Failed to delete post")
 			return
 		}
+		if post.AuthorID != acc.ID {
+			app.Audit(acc.ID, "blog delete_post", id, r.URL.Path)
+		}
 
 		if app.SendsJSON(r) {
 			app.RespondJSON(w, map[string]interface{}{"success": true})
@@ -1404,6 +1731,18 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Moderation history for the post and its comments
+	if r.URL.Query().Get("moderation") == "true" {
+		renderModerationHistory(w, r, post)
+		return
+	}
+
+	// Earlier versions of the post, for its author and admins
+	if r.URL.Query().Get("history") == "true" {
+		renderRevisionHistory(w, r, post)
+		return
+	}
+
 	// GET - return JSON if requested
 	if r.Method == "GET" && app.WantsJSON(r) {
 		app.RespondJSON(w, post)
@@ -1419,12 +1758,23 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Check if user is the author
-		if post.AuthorID != acc.ID {
+		// Check if user is the author, or an admin moderating the post
+		if post.AuthorID != acc.ID && !acc.Admin {
 			app.Forbidden(w, r, "You can only edit your own posts")
 			return
 		}
 
+		// Admins editing someone else's post are told it will be marked,
+		// and asked why.
+		moderation := ""
+		if post.AuthorID != acc.ID {
+			moderation = fmt.Sprintf(`<p class="text-muted">Editing a post by %s. The change is recorded and the post will show that a moderator modified it.</p>`, html.EscapeString(post.Author))
+		}
+		reasonField := ""
+		if moderation != "" {
+			reasonField = `<input type="text" name="reason" placeholder="Reason for the edit (shown publicly)">`
+		}
+
 		// Show edit form
 		pageTitle := "Edit Post"
 		if post.Title != "" {
@@ -1439,16 +1789,20 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 			publicSelected = "selected"
 		}
 
-		content := fmt.Sprintf(`<div id="blog">
+		content := fmt.Sprintf(`<div id="blog">%s
 			<form method="POST" action="/blog/post?id=%s" class="blog-form">
 				<input type="hidden" name="_method" value="PATCH">
 				<input type="text" name="title" placeholder="Title (optional)" value="%s">
-				<textarea name="content" rows="15" required>%s</textarea>
+				<textarea id="edit-content" data-mentions name="content" rows="15" required>%s</textarea>
+				%s
+				%s
+				%s
 				<input type="text" name="tags" placeholder="Tags (optional, comma-separated)" value="%s">
 				<select name="visibility">
 					<option value="public" %s>Public</option>
 					<option value="private" %s>Private (Admin only)</option>
 				</select>
+				%s
 				<div class="blog-form-hint">
 					Supports markdown: **bold**, *italic**, `+"`code`"+`, `+"```"+` for code blocks, # headers, - lists
 				</div>
@@ -1457,7 +1811,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 					<a href="/blog/post?id=%s" class="btn btn-secondary">Cancel</a>
 				</div>
 			</form>
-		</div>`, post.ID, post.Title, post.Content, post.Tags, publicSelected, privateSelected, post.ID)
+		</div>`, moderation, post.ID, html.EscapeString(post.Title), html.EscapeString(post.Content), places.PickerHTML("edit-content"), app.ImageUploadHTML("edit-content"), app.PreviewHTML("edit-content"), html.EscapeString(post.Tags), publicSelected, privateSelected, reasonField, post.ID)
 
 		html := app.RenderHTMLForRequest(pageTitle, "", content, r)
 		w.Write([]byte(html))
@@ -1487,6 +1841,10 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	editButton := app.ItemControls(userID, isAdmin, "post", post.ID, post.AuthorID, "/blog/post?id="+post.ID+"&edit=true", "/blog/post?id="+post.ID)
 
+	mutex.RLock()
+	archived := postArchived(post)
+	mutex.RUnlock()
+
 	tagsHtml := ""
 	if post.Tags != "" {
 		var tagSpans []string
@@ -1515,7 +1873,17 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 		timeInfo = "Updated " + app.TimeAgo(post.UpdatedAt)
 	}
 
-	shareButton := ` · <a href="#" class="share-btn" onclick="event.preventDefault();if(navigator.share){navigator.share({title:document.title,url:location.href})}else{navigator.clipboard.writeText(location.href).then(()=>{this.textContent='Copied!';setTimeout(()=>{this.textContent='Share'},2000)})}" title="Share this post">Share</a>`
+	shareButton := ` · ` + app.ShareLink("", "Share", `class="share-btn" title="Share this post"`)
+
+	if b := app.BookmarkButton(r, "post", post.ID); b != "" {
+		shareButton += ` · ` + b
+	}
+	if !post.UpdatedAt.IsZero() && (userID == post.AuthorID || isAdmin) && userID != "" {
+		shareButton += ` · <a href="/blog/post?id=` + post.ID + `&history=true">History</a>`
+	}
+	if userID != "" && userID != post.AuthorID {
+		shareButton += ` · ` + reportLink("post", post.ID)
+	}
 
 	var contentSB strings.Builder
 	contentSB.WriteString(`<div id="blog">`)
@@ -1523,11 +1891,15 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 	contentSB.WriteString(`<div class="info">`)
 	contentSB.WriteString(timeInfo + ` · ` + authorLink + shareButton + editButton)
 	contentSB.WriteString(`</div>`)
+	contentSB.WriteString(moderatedNotice(post.ID, post.ModeratedAt))
+	contentSB.WriteString(app.ArchiveNotice(r, "post", post.ID, archived))
 	contentSB.WriteString(`<hr class="my-5 border-t">`)
 	contentSB.WriteString(`<div class="mb-5">` + contentHTML + `</div>`)
+	contentSB.WriteString(renderReactions("post", post.ID, post.ID, userID))
+	contentSB.WriteString(app.LinkedFrom(post.ID))
 	contentSB.WriteString(`<hr class="my-5 border-t">`)
 	contentSB.WriteString(`<h3 class="mt-6">Comments</h3>`)
-	contentSB.WriteString(renderComments(post.ID, r))
+	contentSB.WriteString(renderComments(post.ID, archived, r))
 	contentSB.WriteString(`<div class="mt-6"><a href="/blog" class="text-muted">← Back to posts</a></div>`)
 	contentSB.WriteString(`</div>`)
 	content := contentSB.String()
@@ -1544,7 +1916,7 @@ func min(a, b int) int {
 }
 
 // renderComments displays comments for a post
-func renderComments(postID string, r *http.Request) string {
+func renderComments(postID string, archived bool, r *http.Request) string {
 	postComments := GetComments(postID)
 
 	var commentsHTML strings.Builder
@@ -1552,17 +1924,22 @@ func renderComments(postID string, r *http.Request) string {
 	// Add comment form if authenticated
 	_, acc := auth.TrySession(r)
 	isAuthenticated := acc != nil
+	userID := ""
+	if acc != nil {
+		userID = acc.ID
+	}
 
-	if isAuthenticated {
+	// Archived posts are closed to comments; the notice above says so.
+	if isAuthenticated && !archived {
 		commentsHTML.WriteString(fmt.Sprintf(`
 			<form method="POST" action="/blog/post/%s/comment" class="blog-form my-5">
-				<textarea name="content" rows="3" placeholder="Add a comment..." required></textarea>
+				<textarea name="content" data-mentions rows="3" placeholder="Add a comment..." required></textarea>
 				<div>
 					<button type="submit">Add Comment</button>
 				</div>
 			</form>
 		`, postID))
-	} else {
+	} else if !archived {
 		commentsHTML.WriteString(`<p class="text-muted my-5"><a href="/login">Login</a> to add a comment</p>`)
 	}
 
@@ -1585,13 +1962,20 @@ func renderComments(postID string, r *http.Request) string {
 			authorLink = fmt.Sprintf(`<a href="/@%s">%s</a>`, comment.AuthorID, comment.Author)
 		}
 
-		renderedContent := app.RenderString(comment.Content)
+		renderedContent := app.LinkMentions(app.RenderString(comment.Content))
+		editLink := ""
+		if isAdmin {
+			editLink = fmt.Sprintf(` · <a href="/blog/post/%s/comment?id=%s&edit=true" class="text-muted">Edit</a>`, postID, comment.ID)
+		} else if userID != "" && userID != comment.AuthorID {
+			editLink = ` · ` + reportLink("comment", comment.ID)
+		}
 		commentsHTML.WriteString(fmt.Sprintf(`
-			<div class="p-4 bg-light rounded mb-3">
-				<div class="text-muted text-xs mb-1">%s · %s</div>
-				<div>%s</div>
+			<div id="comment-%s" class="p-4 bg-light rounded mb-3">
+				<div class="text-muted text-xs mb-1">%s · %s%s</div>
+				<div>%s</div>%s%s
 			</div>
-		`, app.TimeAgo(comment.CreatedAt), authorLink, renderedContent))
+		`, comment.ID, app.TimeAgo(comment.CreatedAt), authorLink, editLink, renderedContent, moderatedNotice(postID, comment.ModeratedAt),
+			renderReactions("comment", comment.ID, postID, userID)))
 	}
 	commentsHTML.WriteString(`</div>`)
 
@@ -1601,10 +1985,11 @@ func renderComments(postID string, r *http.Request) string {
 // EditHandler serves the post edit form
 // RenderMarkdown converts markdown to HTML without embeds (for storage/previews)
 func RenderMarkdown(text string) string {
-	return string(app.Render([]byte(text)))
+	return string(app.Render([]byte(places.StripEmbeds(text))))
 }
 
-// Linkify converts markdown to HTML and embeds YouTube videos (for full post display)
+// Linkify converts markdown to HTML, links @mentions and embeds YouTube
+// videos and place cards (for full post display)
 func Linkify(text string) string {
 	// Render markdown to HTML first (Render handles LaTeX stripping)
 	html := string(app.Render([]byte(text)))
@@ -1623,7 +2008,7 @@ func Linkify(text string) string {
 		return match
 	})
 
-	return html
+	return places.RenderEmbeds(app.LinkMentions(html))
 }
 
 func handlePost(w http.ResponseWriter, r *http.Request) {
@@ -1639,6 +2024,11 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Saving, scheduling and publishing drafts
+	if handleDraft(w, r, acc) {
+		return
+	}
+
 	title := strings.TrimSpace(r.FormValue("title"))
 	content := strings.TrimSpace(r.FormValue("content"))
 	tags := parseTags(r.FormValue("tags"))
@@ -1654,16 +2044,43 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 	author := acc.Name
 	authorID := acc.ID
 
-	// Content validation: minimum and maximum length
-	if len(content) < 50 {
-		http.Error(w, "Post content must be at least 50 characters", http.StatusBadRequest)
+	if err := validatePost(title, content); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if len(content) > 10000 {
-		http.Error(w, "Post content must not exceed 10,000 characters", http.StatusBadRequest)
+	held := &Post{Title: title, Content: content, Tags: tags, Private: private}
+	if holdPost(w, r, authorID, r.FormValue("compose_token"), held) {
 		return
 	}
 
+	// Create the post
+	postID := fmt.Sprintf("%d", time.Now().UnixNano())
+	if err := CreatePost(title, content, author, authorID, tags, private); err != nil {
+		http.Error(w, "Failed to save post", http.StatusInternalServerError)
+		return
+	}
+
+	// Run async LLM-based content moderation (non-blocking)
+	go flag.CheckContent("post", postID, title, content)
+
+	// Redirect back to posts page
+	http.Redirect(w, r, "/blog", http.StatusSeeOther)
+}
+
+// maxPostLength is the longest post, draft or not, in bytes.
+const maxPostLength = 10000
+
+// validatePost checks a post before it goes live: length, and the spam
+// and low-quality heuristics.
+func validatePost(title, content string) error {
+	// Content validation: minimum and maximum length
+	if len(content) < 50 {
+		return fmt.Errorf("Post content must be at least 50 characters")
+	}
+	if len(content) > maxPostLength {
+		return fmt.Errorf("Post content must not exceed 10,000 characters")
+	}
+
 	// Spam detection: check for common test patterns and inappropriate content
 	contentLower := strings.ToLower(content)
 	titleLower := strings.ToLower(title)
@@ -1689,8 +2106,7 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 
 	for _, pattern := range spamPatterns {
 		if strings.Contains(combined, pattern) && len(content) < 200 {
-			http.Error(w, "Post appears to be spam or inappropriate. Please share meaningful content.", http.StatusBadRequest)
-			return
+			return fmt.Errorf("Post appears to be spam or inappropriate. Please share meaningful content.")
 		}
 	}
 
@@ -1703,8 +2119,7 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 
 		// Require at least 3 words/spaces for non-URL content
 		if wordCount < 3 {
-			http.Error(w, "Post must contain at least 3 words. Share something meaningful.", http.StatusBadRequest)
-			return
+			return fmt.Errorf("Post must contain at least 3 words. Share something meaningful.")
 		}
 
 		// Check for excessive repeated characters (e.g., "aaaaaa" or "asdfasdfasdf")
@@ -1714,8 +2129,7 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 			if char == lastChar && char != ' ' && char != '\n' {
 				repeatedChars++
 				if repeatedChars > 4 {
-					http.Error(w, "Post contains too many repeated characters. Please share something meaningful.", http.StatusBadRequest)
-					return
+					return fmt.Errorf("Post contains too many repeated characters. Please share something meaningful.")
 				}
 			} else {
 				repeatedChars = 0
@@ -1731,27 +2145,20 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		if len(uniqueChars) < 10 {
-			http.Error(w, "Post lacks character diversity. Please share something meaningful.", http.StatusBadRequest)
-			return
+			return fmt.Errorf("Post lacks character diversity. Please share something meaningful.")
 		}
 	}
-
-	// Create the post
-	postID := fmt.Sprintf("%d", time.Now().UnixNano())
-	if err := CreatePost(title, content, author, authorID, tags, private); err != nil {
-		http.Error(w, "Failed to save post", http.StatusInternalServerError)
-		return
-	}
-
-	// Run async LLM-based content moderation (non-blocking)
-	go flag.CheckContent("post", postID, title, content)
-
-	// Redirect back to posts page
-	http.Redirect(w, r, "/blog", http.StatusSeeOther)
+	return nil
 }
 
 // CommentHandler handles comment submissions
 func CommentHandler(w http.ResponseWriter, r *http.Request) {
+	// Reactions: /blog/post/{postID}/react
+	if strings.HasSuffix(r.URL.Path, "/react") {
+		ReactHandler(w, r)
+		return
+	}
+
 	// Only handle /blog/post/{postID}/comment paths
 	if !strings.Contains(r.URL.Path, "/comment") {
 		// Not a comment path, pass through to PostHandler
@@ -1759,7 +2166,8 @@ func CommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.Method != "POST" {
+	// GET is only the admin comment editor
+	if r.Method != "POST" && !(r.Method == "GET" && r.URL.Query().Get("edit") == "true") {
 		app.MethodNotAllowed(w, r)
 		return
 	}
@@ -1789,12 +2197,48 @@ func CommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Admin edit of an existing comment (a moderator edit)
+	if r.Method == "GET" || r.URL.Query().Get("id") != "" {
+		if !acc.Admin {
+			app.Forbidden(w, r, "Only admins can edit comments")
+			return
+		}
+		comment := GetComment(r.FormValue("id"))
+		if comment == nil || comment.PostID != postID {
+			app.NotFound(w, r, "Comment not found")
+			return
+		}
+		if r.Method == "GET" {
+			renderCommentEditForm(w, r, comment)
+			return
+		}
+		content := strings.TrimSpace(r.FormValue("content"))
+		if content == "" {
+			app.BadRequest(w, r, "Comment content is required")
+			return
+		}
+		if err := ModeratorEditComment(comment.ID, content, acc, strings.TrimSpace(r.FormValue("reason"))); err != nil {
+			app.ServerError(w, r, "Failed to save comment")
+			return
+		}
+		http.Redirect(w, r, "/blog/post?id="+postID, http.StatusSeeOther)
+		return
+	}
+
 	content := strings.TrimSpace(r.FormValue("content"))
 	if content == "" {
 		app.BadRequest(w, r, "Comment content is required")
 		return
 	}
 
+	mutex.RLock()
+	archived := postArchived(post)
+	mutex.RUnlock()
+	if archived {
+		app.Forbidden(w, r, "This post is archived and closed to new comments")
+		return
+	}
+
 	// Get the authenticated user
 	author := acc.Name
 	authorID := acc.ID
@@ -1821,6 +2265,8 @@ func DeletePostsByAuthor(authorID string) {
 	for _, p := range posts {
 		if p.AuthorID != authorID {
 			kept = append(kept, p)
+		} else {
+			deleteRevisionsLocked(p.ID)
 		}
 	}
 	posts = kept
@@ -1835,10 +2281,96 @@ func DeletePostsByAuthor(authorID string) {
 		}
 	}
 	comments = keptComments
+	var keptDrafts []*Post
+	for _, d := range drafts {
+		if d.AuthorID != authorID {
+			keptDrafts = append(keptDrafts, d)
+		}
+	}
+	drafts = keptDrafts
+	saveDrafts()
 	populateComments()
+	// Their reactions, and any left on what they wrote
+	if dropReactionsLocked(func(re *Reaction) bool {
+		if re.UserID == authorID {
+			return true
+		}
+		if re.Type == "comment" {
+			return getCommentLocked(re.ID) == nil
+		}
+		return postsMap[re.ID] == nil
+	}) {
+		saveReactions()
+	}
 	updateCacheUnlocked()
 	mutex.Unlock()
 	save()
 	data.SaveJSON("comments.json", comments)
 	event.Publish(event.Event{Type: "blog_updated"})
 }
+
+// RenameAuthor moves a renamed member's posts, comments and drafts to
+// their new ID, and their author name where it was just the old ID.
+// Called when a username changes.
+func RenameAuthor(oldID, newID string) {
+	rename := func(id, name *string) {
+		if *id != oldID {
+			return
+		}
+		*id = newID
+		if *name == oldID {
+			*name = newID
+		}
+	}
+	mutex.Lock()
+	var renamed []*Post
+	for _, p := range posts {
+		if p.AuthorID == oldID {
+			rename(&p.AuthorID, &p.Author)
+			renamed = append(renamed, p)
+		}
+	}
+	for _, c := range comments {
+		rename(&c.AuthorID, &c.Author)
+	}
+	for _, d := range drafts {
+		rename(&d.AuthorID, &d.Author)
+	}
+	saveDrafts()
+	var moved []*Reaction
+	for key, re := range reactions {
+		if key.UserID == oldID {
+			delete(reactions, key)
+			moved = append(moved, re)
+		}
+	}
+	for _, re := range moved {
+		re.UserID = newID
+		reactions[reactionKey{re.Type, re.ID, newID}] = re
+	}
+	if len(moved) > 0 {
+		saveReactions()
+	}
+	updateCacheUnlocked()
+	mutex.Unlock()
+	save()
+	data.SaveJSON("comments.json", comments)
+	event.Publish(event.Event{Type: "blog_updated"})
+
+	// Re-index so search shows the new author
+	go func() {
+		for _, post := range renamed {
+			data.Index(
+				post.ID,
+				"post",
+				post.Title,
+				post.Content,
+				map[string]interface{}{
+					"url":    "/blog/post?id=" + post.ID,
+					"author": post.Author,
+					"tags":   post.Tags,
+				},
+			)
+		}
+	}()
+}
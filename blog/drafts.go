@@ -0,0 +1,394 @@
+package blog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/flag"
+)
+
+// Drafts are posts that aren't live yet. They are kept in
+// blog_drafts.json, apart from the published list, so nothing that reads
+// posts (the blog, feeds, federation, search) has to know about them.
+// Only the author sees their drafts, at /blog?drafts=true. A draft with a
+// PublishAt is scheduled: the publisher puts it live when the time comes,
+// as if it had been posted then.
+
+// Draft limits.
+const (
+	MaxDrafts         = 50
+	MaxPublishAhead   = 365 * 24 * time.Hour
+	publisherInterval = 30 * time.Second
+)
+
+var (
+	ErrTooManyDrafts   = fmt.Errorf("at most %d drafts", MaxDrafts)
+	ErrPublishAtInPast = errors.New("publish time must be in the future")
+	ErrPublishAtTooFar = errors.New("publish time must be within a year")
+	ErrDraftNotFound   = errors.New("draft not found")
+)
+
+// drafts is every member's unpublished posts. Guarded by mutex.
+var drafts []*Post
+
+func loadDrafts() {
+	b, err := data.LoadFile("blog_drafts.json")
+	if err != nil {
+		return
+	}
+	var stored []*Post
+	if err := json.Unmarshal(b, &stored); err != nil {
+		app.Log("blog", "Failed to parse drafts: %v", err)
+		return
+	}
+	mutex.Lock()
+	drafts = stored
+	mutex.Unlock()
+}
+
+// saveDrafts persists the drafts. Caller must hold mutex.
+func saveDrafts() error {
+	return data.SaveJSON("blog_drafts.json", drafts)
+}
+
+// findDraft returns one of the author's drafts. Caller must hold mutex.
+func findDraft(authorID, id string) *Post {
+	for _, d := range drafts {
+		if d.ID == id && d.AuthorID == authorID {
+			return d
+		}
+	}
+	return nil
+}
+
+// SaveDraft creates a draft, or updates one of the author's when id is
+// set. A non-zero publishAt schedules it; zero leaves it unscheduled.
+func SaveDraft(id, title, content, tags string, private bool, publishAt time.Time, acc *auth.Account) (*Post, error) {
+	now := time.Now()
+	if !publishAt.IsZero() {
+		if !publishAt.After(now) {
+			return nil, ErrPublishAtInPast
+		}
+		if publishAt.After(now.Add(MaxPublishAhead)) {
+			return nil, ErrPublishAtTooFar
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	d := findDraft(acc.ID, id)
+	if id != "" && d == nil {
+		return nil, ErrDraftNotFound
+	}
+	if d == nil {
+		if countDrafts(acc.ID) >= MaxDrafts {
+			return nil, ErrTooManyDrafts
+		}
+		d = &Post{
+			ID:        fmt.Sprintf("%d", now.UnixNano()),
+			Author:    acc.Name,
+			AuthorID:  acc.ID,
+			CreatedAt: now,
+			Draft:     true,
+		}
+		drafts = append(drafts, d)
+	}
+	d.Title = title
+	d.Content = content
+	d.Tags = tags
+	d.Private = private
+	d.PublishAt = publishAt
+	d.UpdatedAt = now
+	if err := saveDrafts(); err != nil {
+		return nil, err
+	}
+	cp := *d
+	return &cp, nil
+}
+
+// GetDraft returns a copy of one of the author's drafts, or nil.
+func GetDraft(authorID, id string) *Post {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	d := findDraft(authorID, id)
+	if d == nil {
+		return nil
+	}
+	cp := *d
+	return &cp
+}
+
+// GetDrafts returns the author's drafts: scheduled ones first, soonest
+// first, then the rest by when they were last saved.
+func GetDrafts(authorID string) []*Post {
+	mutex.RLock()
+	out := []*Post{}
+	for _, d := range drafts {
+		if d.AuthorID == authorID {
+			cp := *d
+			out = append(out, &cp)
+		}
+	}
+	mutex.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.PublishAt.IsZero() != b.PublishAt.IsZero() {
+			return !a.PublishAt.IsZero()
+		}
+		if !a.PublishAt.IsZero() {
+			return a.PublishAt.Before(b.PublishAt)
+		}
+		return a.UpdatedAt.After(b.UpdatedAt)
+	})
+	return out
+}
+
+// CountDrafts returns how many drafts the author has.
+func CountDrafts(authorID string) int {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return countDrafts(authorID)
+}
+
+// countDrafts is CountDrafts for callers that hold mutex.
+func countDrafts(authorID string) int {
+	n := 0
+	for _, d := range drafts {
+		if d.AuthorID == authorID {
+			n++
+		}
+	}
+	return n
+}
+
+// takeDraft removes a draft and returns it, or nil if there's no such
+// draft. An empty authorID matches any author. Caller must hold mutex.
+func takeDraft(authorID, id string) *Post {
+	for i, d := range drafts {
+		if d.ID == id && (authorID == "" || d.AuthorID == authorID) {
+			drafts = append(drafts[:i:i], drafts[i+1:]...)
+			if err := saveDrafts(); err != nil {
+				app.Log("blog", "Failed to save drafts: %v", err)
+			}
+			return d
+		}
+	}
+	return nil
+}
+
+// DeleteDraft discards one of the author's drafts.
+func DeleteDraft(authorID, id string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if takeDraft(authorID, id) == nil {
+		return ErrDraftNotFound
+	}
+	return nil
+}
+
+// PublishDraft puts one of the author's drafts live now, whether or not
+// it was scheduled.
+func PublishDraft(authorID, id string) error {
+	mutex.Lock()
+	d := takeDraft(authorID, id)
+	mutex.Unlock()
+	if d == nil {
+		return ErrDraftNotFound
+	}
+	return publishDraft(d)
+}
+
+// publishDraft turns a draft, already taken off the drafts list, into a
+// post dated now and publishes it.
+func publishDraft(d *Post) error {
+	d.Draft = false
+	d.PublishAt = time.Time{}
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = time.Time{}
+	if err := publishPost(d); err != nil {
+		return err
+	}
+	go flag.CheckContent("post", d.ID, d.Title, d.Content)
+	return nil
+}
+
+// publishDue publishes every scheduled draft whose time has come.
+func publishDue(now time.Time) {
+	mutex.Lock()
+	var due []*Post
+	for _, d := range drafts {
+		if !d.PublishAt.IsZero() && !d.PublishAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	for _, d := range due {
+		takeDraft(d.AuthorID, d.ID)
+	}
+	mutex.Unlock()
+
+	for _, d := range due {
+		if err := publishDraft(d); err != nil {
+			app.Log("blog", "Scheduled post %s by %s failed to publish: %v", d.ID, d.AuthorID, err)
+		} else {
+			app.Log("blog", "Published scheduled post %s by %s", d.ID, d.AuthorID)
+		}
+	}
+}
+
+var publisherOnce sync.Once
+
+// StartPublisher starts the background loop that publishes scheduled
+// drafts when they are due. Not started in read-only mode.
+func StartPublisher() {
+	publisherOnce.Do(func() {
+		go func() {
+			for {
+				publishDue(time.Now())
+				time.Sleep(publisherInterval)
+			}
+		}()
+	})
+}
+
+// parsePublishAt parses the schedule time from the write form: its script
+// submits RFC 3339 in UTC, and a bare datetime-local value is read as UTC.
+func parsePublishAt(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02T15:04", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("invalid publish time")
+}
+
+// handleDraft handles the draft side of a POST to /blog: saving or
+// scheduling from the write form, posting an edited draft, and the
+// buttons on the drafts list. It reports whether it handled the request.
+func handleDraft(w http.ResponseWriter, r *http.Request, acc *auth.Account) bool {
+	action := r.FormValue("action")
+	id := r.FormValue("draft_id")
+	publishAtValue := strings.TrimSpace(r.FormValue("publish_at"))
+	switch {
+	case action == "delete_draft":
+		if err := DeleteDraft(acc.ID, id); err != nil {
+			app.NotFound(w, r, "Draft not found")
+			return true
+		}
+		http.Redirect(w, r, "/blog?drafts=true", http.StatusSeeOther)
+		return true
+	case action == "publish_draft":
+		d := GetDraft(acc.ID, id)
+		if d == nil {
+			app.NotFound(w, r, "Draft not found")
+			return true
+		}
+		if err := validatePost(d.Title, d.Content); err != nil {
+			app.BadRequest(w, r, err.Error())
+			return true
+		}
+		// Held, it comes back as an edit of the draft to publish now.
+		held := *d
+		held.PublishAt = time.Time{}
+		if holdPost(w, r, acc.ID, r.FormValue("compose_token"), &held) {
+			return true
+		}
+		if err := PublishDraft(acc.ID, id); err != nil {
+			app.ServerError(w, r, "Failed to publish draft")
+			return true
+		}
+		http.Redirect(w, r, "/blog/post?id="+id, http.StatusSeeOther)
+		return true
+	case action != "draft" && id == "" && publishAtValue == "":
+		// A plain new post
+		return false
+	}
+
+	title := strings.TrimSpace(r.FormValue("title"))
+	content := strings.TrimSpace(r.FormValue("content"))
+	tags := parseTags(r.FormValue("tags"))
+	private := r.FormValue("visibility") == "private"
+
+	if content == "" {
+		app.BadRequest(w, r, "Content is required")
+		return true
+	}
+	if len(content) > maxPostLength {
+		app.BadRequest(w, r, "Post content must not exceed 10,000 characters")
+		return true
+	}
+
+	var publishAt time.Time
+	if publishAtValue != "" {
+		var err error
+		if publishAt, err = parsePublishAt(publishAtValue); err != nil {
+			app.BadRequest(w, r, err.Error())
+			return true
+		}
+	}
+
+	// Anything that will go live, now or on schedule, gets the same checks
+	// as a new post, the reflection gate included. A plain draft can be
+	// rough.
+	if action != "draft" || !publishAt.IsZero() {
+		if err := validatePost(title, content); err != nil {
+			app.BadRequest(w, r, err.Error())
+			return true
+		}
+		held := &Post{ID: id, Title: title, Content: content, Tags: tags, Private: private, PublishAt: publishAt}
+		if holdPost(w, r, acc.ID, r.FormValue("compose_token"), held) {
+			return true
+		}
+	}
+
+	d, err := SaveDraft(id, title, content, tags, private, publishAt, acc)
+	if err == ErrDraftNotFound {
+		app.NotFound(w, r, "Draft not found")
+		return true
+	} else if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return true
+	}
+	if action == "draft" || !publishAt.IsZero() {
+		http.Redirect(w, r, "/blog?drafts=true", http.StatusSeeOther)
+		return true
+	}
+
+	if err := PublishDraft(acc.ID, d.ID); err != nil {
+		app.ServerError(w, r, "Failed to save post")
+		return true
+	}
+	http.Redirect(w, r, "/blog/post?id="+d.ID, http.StatusSeeOther)
+	return true
+}
+
+// handleDrafts serves /blog?drafts=true: the signed-in author's drafts,
+// as JSON or a page.
+func handleDrafts(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+	list := GetDrafts(acc.ID)
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"drafts": list})
+		return
+	}
+
+	templates.Page(w, r, "Drafts", "Posts you haven't published yet", "drafts", list)
+}
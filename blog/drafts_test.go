@@ -0,0 +1,64 @@
+package blog
+
+import (
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestDraftsStayOffTheBlogUntilPublished(t *testing.T) {
+	withModerationState(t, nil, nil)
+	mutex.Lock()
+	savedDrafts := drafts
+	drafts = nil
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		drafts = savedDrafts
+		mutex.Unlock()
+	})
+
+	alice := &auth.Account{ID: "alice", Name: "Alice"}
+	d, err := SaveDraft("", "Hello", "a rough first draft", "", false, time.Time{}, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetPost(d.ID) != nil {
+		t.Fatal("draft is live")
+	}
+	if got := GetDrafts("alice"); len(got) != 1 || !got[0].Draft {
+		t.Fatalf("drafts = %+v", got)
+	}
+	if GetDraft("bob", d.ID) != nil {
+		t.Fatal("another member can see the draft")
+	}
+	if _, err := SaveDraft(d.ID, "Hello", "edit", "", false, time.Time{}, &auth.Account{ID: "bob"}); err != ErrDraftNotFound {
+		t.Fatalf("another member edited the draft: %v", err)
+	}
+
+	// Scheduling
+	now := time.Now()
+	if _, err := SaveDraft(d.ID, "Hello", "ready", "", false, now.Add(-time.Minute), alice); err != ErrPublishAtInPast {
+		t.Fatalf("past schedule: %v", err)
+	}
+	if _, err := SaveDraft(d.ID, "Hello", "ready", "", false, now.Add(time.Hour), alice); err != nil {
+		t.Fatal(err)
+	}
+	publishDue(now)
+	if GetPost(d.ID) != nil {
+		t.Fatal("published before its time")
+	}
+
+	publishDue(now.Add(2 * time.Hour))
+	p := GetPost(d.ID)
+	if p == nil {
+		t.Fatal("not published when due")
+	}
+	if p.Draft || !p.PublishAt.IsZero() || p.Content != "ready" || p.AuthorID != "alice" {
+		t.Fatalf("published post = %+v", p)
+	}
+	if CountDrafts("alice") != 0 {
+		t.Fatal("draft left behind after publishing")
+	}
+}
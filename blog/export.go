@@ -0,0 +1,45 @@
+package blog
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// exporter exports the posts (drafts included) and comments a user has
+// written, and the reactions they've left.
+type exporter struct{}
+
+func (exporter) Name() string        { return "blog" }
+func (exporter) Description() string { return "Your posts, comments and reactions" }
+func (exporter) Formats() []string   { return []string{"json"} }
+
+func (exporter) Export(w io.Writer, userID, format string) error {
+	mutex.RLock()
+	out := struct {
+		Posts     []*Post     `json:"posts"`
+		Comments  []*Comment  `json:"comments"`
+		Reactions []*Reaction `json:"reactions"`
+	}{Posts: []*Post{}, Comments: []*Comment{}, Reactions: []*Reaction{}}
+	for _, p := range posts {
+		if p.AuthorID == userID {
+			out.Posts = append(out.Posts, p)
+		}
+	}
+	for _, d := range drafts {
+		if d.AuthorID == userID {
+			out.Posts = append(out.Posts, d)
+		}
+	}
+	for _, c := range comments {
+		if c.AuthorID == userID {
+			out.Comments = append(out.Comments, c)
+		}
+	}
+	for key, re := range reactions {
+		if key.UserID == userID {
+			out.Reactions = append(out.Reactions, re)
+		}
+	}
+	mutex.RUnlock()
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,518 @@
+package blog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/flag"
+)
+
+// feedLimit is the number of most recent posts included in the feeds.
+const feedLimit = 50
+
+// feedCache holds the last rendered version of each feed, by feed and
+// format, so polling readers don't re-render it on every request.
+var (
+	feedMu    sync.Mutex
+	feedCache = map[string]cachedFeed{}
+)
+
+type cachedFeed struct {
+	etag string
+	body []byte
+}
+
+// PublicPosts reports whether an author lets everyone see their posts, so
+// they can be offered as a feed. Wired from main.go to the author's
+// privacy settings.
+var PublicPosts func(authorID string) bool
+
+// feedInfo describes a feed as a whole. Paths are relative to the base URL.
+type feedInfo struct {
+	Title       string
+	Description string
+	Home        string // the page the feed follows
+	Self        string // the feed itself, in the format being rendered
+	Author      *atomAuthor
+}
+
+// rssFeed is an RSS 2.0 document with the slash extension for comment counts.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Slash   string     `xml:"xmlns:slash,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Author      string   `xml:"author,omitempty"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+	Comments    string   `xml:"comments"`
+	SlashCount  int      `xml:"slash:comments"`
+}
+
+// atomFeed is an Atom 1.0 document with the threading extension (RFC 4685)
+// for comment counts.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Thr     string      `xml:"xmlns:thr,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+	Total      int            `xml:"thr:total"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedPost is a snapshot of a post taken under the read lock so the XML
+// can be rendered without holding it.
+type feedPost struct {
+	post     Post
+	comments int
+}
+
+// feedPosts returns the most recent public, visible posts newest first,
+// plus the time the newest of them was last touched.
+func feedPosts() ([]feedPost, time.Time) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return collectFeedPosts(posts)
+}
+
+// authorFeedPosts is feedPosts for one author's posts.
+func authorFeedPosts(acc *auth.Account) ([]feedPost, time.Time) {
+	list := GetPostsByAuthor(acc.Name)
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return collectFeedPosts(list)
+}
+
+// collectFeedPosts snapshots the public, visible posts of list, which is
+// newest first. Caller must hold mutex.
+func collectFeedPosts(list []*Post) ([]feedPost, time.Time) {
+	var out []feedPost
+	var latest time.Time
+	for _, post := range list {
+		if post.Private || flag.IsHidden("post", post.ID) || auth.IsBanned(post.AuthorID) {
+			continue
+		}
+		out = append(out, feedPost{post: *post, comments: countComments(post)})
+		if t := postModified(post); t.After(latest) {
+			latest = t
+		}
+		if len(out) >= feedLimit {
+			break
+		}
+	}
+	return out, latest
+}
+
+// postModified returns the last time a post changed.
+func postModified(post *Post) time.Time {
+	if post.UpdatedAt.After(post.CreatedAt) {
+		return post.UpdatedAt
+	}
+	return post.CreatedAt
+}
+
+// postTags splits a post's comma-separated tags.
+func postTags(tags string) []string {
+	var out []string
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// feedETag derives a validator from everything that appears in the feed.
+func feedETag(format string, items []feedPost) string {
+	h := sha256.New()
+	h.Write([]byte(format))
+	for _, it := range items {
+		fmt.Fprintf(h, "|%s|%d|%d", it.post.ID, postModified(&it.post).UnixNano(), it.comments)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:16] + `"`
+}
+
+// renderRSS renders posts as an RSS 2.0 document.
+func renderRSS(base string, info feedInfo, items []feedPost, latest time.Time) ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		Slash:   "http://purl.org/rss/1.0/modules/slash/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       info.Title,
+			Link:        base + info.Home,
+			Description: info.Description,
+			SelfLink:    rssLink{Href: base + info.Self, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if !latest.IsZero() {
+		feed.Channel.LastBuildDate = latest.UTC().Format(time.RFC1123Z)
+	}
+	for _, it := range items {
+		p := it.post
+		link := base + "/blog/post?id=" + p.ID
+		title := p.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       title,
+			Link:        link,
+			GUID:        link,
+			Author:      p.Author,
+			PubDate:     p.CreatedAt.UTC().Format(time.RFC1123Z),
+			Categories:  postTags(p.Tags),
+			Description: RenderMarkdown(p.Content),
+			Comments:    link + "#comments",
+			SlashCount:  it.comments,
+		})
+	}
+	return marshalFeed(feed)
+}
+
+// renderAtom renders posts as an Atom 1.0 document.
+func renderAtom(base string, info feedInfo, items []feedPost, latest time.Time) ([]byte, error) {
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	feed := atomFeed{
+		Thr:     "http://purl.org/syndication/thread/1.0",
+		Title:   info.Title,
+		ID:      base + info.Home,
+		Updated: latest.UTC().Format(time.RFC3339),
+		Author:  info.Author,
+		Links: []atomLink{
+			{Href: base + info.Home},
+			{Href: base + info.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	for _, it := range items {
+		p := it.post
+		link := base + "/blog/post?id=" + p.ID
+		title := p.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		var cats []atomCategory
+		for _, t := range postTags(p.Tags) {
+			cats = append(cats, atomCategory{Term: t})
+		}
+		author := atomAuthor{Name: p.Author}
+		if p.AuthorID != "" {
+			author.URI = base + "/@" + p.AuthorID
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title: title,
+			ID:    link,
+			Links: []atomLink{
+				{Href: link},
+				{Href: link + "#comments", Rel: "replies", Type: "text/html"},
+			},
+			Published:  p.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:    postModified(&p).UTC().Format(time.RFC3339),
+			Author:     author,
+			Categories: cats,
+			Content:    atomContent{Type: "html", Body: RenderMarkdown(p.Content)},
+			Total:      it.comments,
+		})
+	}
+	return marshalFeed(feed)
+}
+
+// jsonFeed is a JSON Feed 1.1 document (https://jsonfeed.org/version/1.1).
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description,omitempty"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Tags          []string         `json:"tags,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+// renderJSONFeed renders posts as a JSON Feed.
+func renderJSONFeed(base string, info feedInfo, items []feedPost) ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       info.Title,
+		HomePageURL: base + info.Home,
+		FeedURL:     base + info.Self,
+		Description: info.Description,
+		Items:       []jsonFeedItem{},
+	}
+	if info.Author != nil {
+		feed.Authors = []jsonFeedAuthor{{Name: info.Author.Name, URL: info.Author.URI}}
+	}
+	for _, it := range items {
+		p := it.post
+		link := base + "/blog/post?id=" + p.ID
+		title := p.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		author := jsonFeedAuthor{Name: p.Author}
+		if p.AuthorID != "" {
+			author.URL = base + "/@" + p.AuthorID
+		}
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            link,
+			URL:           link,
+			Title:         title,
+			ContentHTML:   RenderMarkdown(p.Content),
+			DatePublished: p.CreatedAt.UTC().Format(time.RFC3339),
+			DateModified:  postModified(&p).UTC().Format(time.RFC3339),
+			Tags:          postTags(p.Tags),
+			Authors:       []jsonFeedAuthor{author},
+		})
+	}
+	return json.MarshalIndent(feed, "", "  ")
+}
+
+func marshalFeed(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// feedBaseURL returns the absolute base used for links inside the feeds.
+// Feed readers need absolute URLs, so fall back to the request host.
+func feedBaseURL(r *http.Request) string {
+	if u := app.PublicURL(); u != "" {
+		return u
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// feedFormat picks the format a feed is asked for in: def, unless
+// ?format=atom|rss|json says otherwise.
+func feedFormat(r *http.Request, def string) string {
+	if f := r.URL.Query().Get("format"); f == "atom" || f == "rss" || f == "json" {
+		return f
+	}
+	return def
+}
+
+// FeedHandler serves the blog as a syndication feed. /blog/feed.xml is
+// RSS 2.0 and /blog/atom.xml is Atom; ?format=atom|rss|json overrides
+// either. Responses carry ETag and Last-Modified so readers can poll with
+// conditional GETs.
+func FeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	def := "rss"
+	if strings.HasSuffix(r.URL.Path, "/atom.xml") {
+		def = "atom"
+	}
+	format := feedFormat(r, def)
+	info := feedInfo{
+		Title:       "Mu Blog",
+		Description: "Posts from " + APDomain(),
+		Home:        "/blog",
+	}
+	switch format {
+	case "atom":
+		info.Self = "/blog/atom.xml"
+	case "json":
+		info.Self = "/blog/feed.xml?format=json"
+	default:
+		info.Self = "/blog/feed.xml"
+	}
+
+	items, latest := feedPosts()
+	serveFeed(w, r, "blog", format, info, items, latest)
+}
+
+// AuthorFeedHandler serves one author's public posts as a feed, so they
+// can be followed from a feed reader. It's RSS 2.0 unless ?format=atom or
+// ?format=json asks for Atom or JSON Feed. Authors who don't show their
+// posts to everyone have no feed.
+//
+//	GET /@{username}/feed
+func AuthorFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	username := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/@"), "/feed")
+	acc, err := auth.GetAccount(username)
+	if err != nil || acc.Banned || (PublicPosts != nil && !PublicPosts(acc.ID)) {
+		app.NotFound(w, r, "No feed for this user")
+		return
+	}
+
+	format := feedFormat(r, "rss")
+	info := feedInfo{
+		Title:       acc.Name + " on Mu",
+		Description: "Posts by @" + acc.ID + " on " + APDomain(),
+		Home:        "/@" + acc.ID,
+		Self:        "/@" + acc.ID + "/feed",
+	}
+	if format != "rss" {
+		info.Self += "?format=" + format
+	}
+	base := feedBaseURL(r)
+	info.Author = &atomAuthor{Name: acc.Name, URI: base + "/@" + acc.ID}
+
+	items, latest := authorFeedPosts(acc)
+	serveFeed(w, r, "@"+acc.ID, format, info, items, latest)
+}
+
+// serveFeed writes items as the named feed in format, answering
+// conditional GETs without rendering.
+func serveFeed(w http.ResponseWriter, r *http.Request, name, format string, info feedInfo, items []feedPost, latest time.Time) {
+	base := feedBaseURL(r)
+	etag := feedETag(format+"|"+base+"|"+info.Title, items)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if !latest.IsZero() {
+		w.Header().Set("Last-Modified", latest.UTC().Format(http.TimeFormat))
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if match == etag || match == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	} else if since := r.Header.Get("If-Modified-Since"); since != "" && !latest.IsZero() {
+		if t, err := http.ParseTime(since); err == nil && !latest.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	key := name + "|" + format
+	feedMu.Lock()
+	cached, ok := feedCache[key]
+	feedMu.Unlock()
+
+	body := cached.body
+	if !ok || cached.etag != etag {
+		var err error
+		switch format {
+		case "atom":
+			body, err = renderAtom(base, info, items, latest)
+		case "json":
+			body, err = renderJSONFeed(base, info, items)
+		default:
+			body, err = renderRSS(base, info, items, latest)
+		}
+		if err != nil {
+			app.Log("blog", "Feed render error: %v", err)
+			app.ServerError(w, r, "failed to render feed")
+			return
+		}
+		feedMu.Lock()
+		feedCache[key] = cachedFeed{etag: etag, body: body}
+		feedMu.Unlock()
+	}
+
+	switch format {
+	case "atom":
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	case "json":
+		w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	}
+	if r.Method == "HEAD" {
+		return
+	}
+	w.Write(body)
+}
@@ -0,0 +1,162 @@
+package blog
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func withFeedPosts(t *testing.T, list []*Post) {
+	t.Helper()
+	mutex.Lock()
+	savedPosts, savedComments := posts, comments
+	posts, comments = list, nil
+	mutex.Unlock()
+	feedMu.Lock()
+	feedCache = map[string]cachedFeed{}
+	feedMu.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		posts, comments = savedPosts, savedComments
+		mutex.Unlock()
+	})
+}
+
+func TestFeedHandler_RSS(t *testing.T) {
+	now := time.Now()
+	withFeedPosts(t, []*Post{
+		{ID: "p1", Title: "Hello", Content: "Some **text**", Author: "Alice", AuthorID: "alice", Tags: "Tech, Dev", CreatedAt: now,
+			Comments: []*Comment{{ID: "c1"}, {ID: "c2"}}},
+		{ID: "p2", Title: "Secret", Content: "hidden", Private: true, CreatedAt: now},
+	})
+
+	rec := httptest.NewRecorder()
+	FeedHandler(rec, httptest.NewRequest("GET", "http://example.com/blog/feed.xml", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var doc struct {
+		Items []struct {
+			Title      string   `xml:"title"`
+			Categories []string `xml:"category"`
+			Comments   int      `xml:"http://purl.org/rss/1.0/modules/slash/ comments"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid RSS: %v\n%s", err, rec.Body.String())
+	}
+	if len(doc.Items) != 1 {
+		t.Fatalf("got %d items, want 1 (private post excluded)", len(doc.Items))
+	}
+	it := doc.Items[0]
+	if it.Title != "Hello" || len(it.Categories) != 2 || it.Comments != 2 {
+		t.Errorf("unexpected item: %+v", it)
+	}
+}
+
+func TestFeedHandler_Atom(t *testing.T) {
+	withFeedPosts(t, []*Post{
+		{ID: "p1", Title: "Hello", Content: "body", Author: "Alice", Tags: "Tech", CreatedAt: time.Now()},
+	})
+
+	rec := httptest.NewRecorder()
+	FeedHandler(rec, httptest.NewRequest("GET", "http://example.com/blog/atom.xml", nil))
+	var doc struct {
+		XMLName xml.Name
+		Entries []struct {
+			Title string `xml:"title"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid Atom: %v", err)
+	}
+	if doc.XMLName.Local != "feed" || len(doc.Entries) != 1 {
+		t.Errorf("unexpected atom doc: %+v", doc)
+	}
+}
+
+func TestFeedHandler_ConditionalGet(t *testing.T) {
+	withFeedPosts(t, []*Post{
+		{ID: "p1", Title: "Hello", Content: "body", CreatedAt: time.Now().Add(-time.Hour)},
+	})
+
+	rec := httptest.NewRecorder()
+	FeedHandler(rec, httptest.NewRequest("GET", "/blog/feed.xml", nil))
+	etag := rec.Header().Get("ETag")
+	lastMod := rec.Header().Get("Last-Modified")
+	if etag == "" || lastMod == "" {
+		t.Fatalf("missing validators: etag=%q last-modified=%q", etag, lastMod)
+	}
+
+	req := httptest.NewRequest("GET", "/blog/feed.xml", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	FeedHandler(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status = %d, want 304", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/blog/feed.xml", nil)
+	req.Header.Set("If-Modified-Since", lastMod)
+	rec = httptest.NewRecorder()
+	FeedHandler(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: status = %d, want 304", rec.Code)
+	}
+}
+
+func TestAuthorFeedHandler(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	acc := &auth.Account{ID: "feedwriter", Name: "Writer", Secret: "secret", Created: time.Now()}
+	if err := auth.Create(acc); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount(acc.ID)
+	withFeedPosts(t, []*Post{
+		{ID: "p1", Title: "Mine", Content: "body", Author: "Writer", AuthorID: "feedwriter", CreatedAt: time.Now()},
+		{ID: "p2", Title: "Theirs", Content: "body", Author: "Someone", AuthorID: "someone", CreatedAt: time.Now()},
+		{ID: "p3", Title: "Private", Content: "body", Author: "Writer", AuthorID: "feedwriter", Private: true, CreatedAt: time.Now()},
+	})
+
+	rec := httptest.NewRecorder()
+	AuthorFeedHandler(rec, httptest.NewRequest("GET", "http://example.com/@feedwriter/feed?format=json", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/feed+json") {
+		t.Fatalf("status = %d, Content-Type = %q", rec.Code, ct)
+	}
+	var doc jsonFeed
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON Feed: %v", err)
+	}
+	if len(doc.Items) != 1 || doc.Items[0].Title != "Mine" {
+		t.Errorf("items = %+v, want only the author's public post", doc.Items)
+	}
+	if doc.FeedURL != "http://example.com/@feedwriter/feed?format=json" || len(doc.Authors) != 1 {
+		t.Errorf("feed = %+v", doc)
+	}
+
+	rec = httptest.NewRecorder()
+	AuthorFeedHandler(rec, httptest.NewRequest("GET", "/@feedwriter/feed", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+		t.Errorf("default Content-Type = %q", ct)
+	}
+
+	// No feed for unknown users, or authors who hide their posts.
+	PublicPosts = func(string) bool { return false }
+	defer func() { PublicPosts = nil }()
+	for _, path := range []string{"/@feedwriter/feed", "/@nobody/feed"} {
+		rec = httptest.NewRecorder()
+		AuthorFeedHandler(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package blog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"mu/internal/data"
+)
+
+func TestLowMemoryListMatchesCachedList(t *testing.T) {
+	now := time.Now()
+	var list []*Post
+	for i := 0; i < 5; i++ {
+		list = append(list, &Post{ID: fmt.Sprintf("p%d", i), Title: fmt.Sprintf("Post %d", i), Content: "hello", Author: "Alice", CreatedAt: now.Add(-time.Duration(i) * time.Hour)})
+	}
+	list[2].Private = true
+	withModerationState(t, list, nil)
+
+	mutex.Lock()
+	updateCacheUnlocked()
+	mutex.Unlock()
+	cached, total, next := listPage("", 2)
+
+	data.SetLowMemory(true)
+	defer data.SetLowMemory(false)
+	mutex.Lock()
+	updateCacheUnlocked()
+	mutex.Unlock()
+	if len(postsEntries) != 0 {
+		t.Fatal("list kept in memory in low-memory mode")
+	}
+	onDemand, total2, next2 := listPage("", 2)
+
+	if total != 4 || total2 != total || next2 != next {
+		t.Fatalf("totals %d/%d, next %q/%q", total, total2, next, next2)
+	}
+	if fmt.Sprint(onDemand) != fmt.Sprint(cached) {
+		t.Fatalf("on-demand page differs:\n%v\n%v", onDemand, cached)
+	}
+}
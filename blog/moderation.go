@@ -0,0 +1,300 @@
+package blog
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/flag"
+)
+
+// ModeratorEdit records an admin's change to someone else's post or comment.
+// These are kept apart from the author's own edits so readers can always
+// tell when content no longer says exactly what its author wrote: the item
+// carries a "modified by a moderator" notice linking to this history.
+type ModeratorEdit struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"` // "post" or "comment"
+	ContentID  string    `json:"content_id"`
+	PostID     string    `json:"post_id"`
+	AuthorID   string    `json:"author_id"`
+	EditorID   string    `json:"editor_id"`
+	EditorName string    `json:"editor_name"`
+	Reason     string    `json:"reason,omitempty"`
+	OldTitle   string    `json:"old_title,omitempty"`
+	OldContent string    `json:"old_content"`
+	NewTitle   string    `json:"new_title,omitempty"`
+	NewContent string    `json:"new_content"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// moderatorEdits is the full history, oldest first. Guarded by mutex.
+var moderatorEdits []*ModeratorEdit
+
+func loadModeratorEdits() {
+	var edits []*ModeratorEdit
+	if err := data.LoadJSON("blog_moderation.json", &edits); err == nil {
+		mutex.Lock()
+		moderatorEdits = edits
+		mutex.Unlock()
+	}
+}
+
+// saveModeratorEdits persists the history. Caller must hold mutex.
+func saveModeratorEdits() {
+	if err := data.SaveJSON("blog_moderation.json", moderatorEdits); err != nil {
+		app.Log("blog", "Failed to save moderator edits: %v", err)
+	}
+}
+
+// ModeratorEditPost applies an admin's edit to a post and records it in the
+// moderation history.
+func ModeratorEditPost(id, title, content, tags string, private bool, editor *auth.Account, reason string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	post := postsMap[id]
+	if post == nil {
+		return fmt.Errorf("post not found")
+	}
+	now := time.Now()
+	moderatorEdits = append(moderatorEdits, &ModeratorEdit{
+		ID:         fmt.Sprintf("%d", now.UnixNano()),
+		Type:       "post",
+		ContentID:  post.ID,
+		PostID:     post.ID,
+		AuthorID:   post.AuthorID,
+		EditorID:   editor.ID,
+		EditorName: editor.Name,
+		Reason:     reason,
+		OldTitle:   post.Title,
+		OldContent: post.Content,
+		NewTitle:   title,
+		NewContent: content,
+		CreatedAt:  now,
+	})
+	post.ModeratedAt = now
+	saveRevisionLocked(post, title, content, tags, private, true)
+	updatePostLocked(post, title, content, tags, private)
+	saveModeratorEdits()
+	app.Log("blog", "Moderator %s edited post %s", editor.ID, post.ID)
+	app.Audit(editor.ID, "blog edit_post", post.ID, "")
+	return nil
+}
+
+// ModeratorEditComment applies an admin's edit to a comment and records it
+// in the moderation history.
+func ModeratorEditComment(id, content string, editor *auth.Account, reason string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var comment *Comment
+	for _, c := range comments {
+		if c.ID == id {
+			comment = c
+			break
+		}
+	}
+	if comment == nil {
+		return fmt.Errorf("comment not found")
+	}
+	now := time.Now()
+	moderatorEdits = append(moderatorEdits, &ModeratorEdit{
+		ID:         fmt.Sprintf("%d", now.UnixNano()),
+		Type:       "comment",
+		ContentID:  comment.ID,
+		PostID:     comment.PostID,
+		AuthorID:   comment.AuthorID,
+		EditorID:   editor.ID,
+		EditorName: editor.Name,
+		Reason:     reason,
+		OldContent: comment.Content,
+		NewContent: content,
+		CreatedAt:  now,
+	})
+	comment.Content = content
+	comment.ModeratedAt = now
+	if err := data.SaveJSON("comments.json", comments); err != nil {
+		app.Log("blog", "Failed to save comments: %v", err)
+	}
+	saveModeratorEdits()
+	app.Log("blog", "Moderator %s edited comment %s", editor.ID, comment.ID)
+	app.Audit(editor.ID, "blog edit_comment", comment.ID, "")
+	return nil
+}
+
+// GetModeratorEdits returns the moderation history for a post and its
+// comments, newest first.
+func GetModeratorEdits(postID string) []*ModeratorEdit {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	var out []*ModeratorEdit
+	for i := len(moderatorEdits) - 1; i >= 0; i-- {
+		if moderatorEdits[i].PostID == postID {
+			out = append(out, moderatorEdits[i])
+		}
+	}
+	return out
+}
+
+// GetComment returns a comment by ID, or nil.
+func GetComment(id string) *Comment {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return getCommentLocked(id)
+}
+
+// getCommentLocked is GetComment for callers holding mutex.
+func getCommentLocked(id string) *Comment {
+	for _, c := range comments {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// DeleteComment removes a comment and the reactions on it.
+func DeleteComment(id string) error {
+	mutex.Lock()
+	kept := comments[:0:0]
+	found := false
+	for _, c := range comments {
+		if c.ID == id {
+			found = true
+			continue
+		}
+		kept = append(kept, c)
+	}
+	if !found {
+		mutex.Unlock()
+		return fmt.Errorf("comment not found")
+	}
+	comments = kept
+	populateComments()
+	if dropReactionsLocked(func(re *Reaction) bool { return re.Type == "comment" && re.ID == id }) {
+		saveReactions()
+	}
+	updateCacheUnlocked()
+	err := data.SaveJSON("comments.json", comments)
+	mutex.Unlock()
+	return err
+}
+
+// commentDeleter implements flag.ContentDeleter for comments.
+type commentDeleter struct{}
+
+func (d *commentDeleter) Delete(id string) error {
+	return DeleteComment(id)
+}
+
+func (d *commentDeleter) Get(id string) interface{} {
+	c := GetComment(id)
+	if c == nil {
+		return nil
+	}
+	return flag.PostContent{
+		ID:        c.ID,
+		Title:     "Comment",
+		Content:   c.Content,
+		Author:    c.Author,
+		AuthorID:  c.AuthorID,
+		CreatedAt: c.CreatedAt,
+		URL:       "/blog/post?id=" + c.PostID,
+	}
+}
+
+// Hidden comments are filtered as pages are rendered.
+func (d *commentDeleter) RefreshCache() {}
+
+// reportLink is the Report link on a post or comment, for signed-in
+// readers who didn't write it.
+func reportLink(contentType, id string) string {
+	return fmt.Sprintf(`<a href="#" class="text-muted" data-flag="%s" data-id="%s">Report</a>`, contentType, html.EscapeString(id))
+}
+
+// moderatedNotice renders the notice shown on moderated content.
+func moderatedNotice(postID string, at time.Time) string {
+	if at.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(`<div class="text-muted text-xs mt-1">✎ Modified by a moderator %s · <a href="/blog/post?id=%s&moderation=true" class="text-muted">history</a></div>`,
+		app.TimeAgo(at), postID)
+}
+
+// renderModerationHistory serves the moderation history of a post. Anyone
+// can see that, when and why content was changed; the original text is only
+// shown to the author and admins, since it was often removed for a reason.
+func renderModerationHistory(w http.ResponseWriter, r *http.Request, post *Post) {
+	edits := GetModeratorEdits(post.ID)
+	_, acc := auth.TrySession(r)
+
+	if app.WantsJSON(r) {
+		type editJSON struct {
+			Type      string    `json:"type"`
+			ContentID string    `json:"content_id"`
+			Reason    string    `json:"reason,omitempty"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		out := make([]editJSON, len(edits))
+		for i, e := range edits {
+			out[i] = editJSON{e.Type, e.ContentID, e.Reason, e.CreatedAt}
+		}
+		app.RespondJSON(w, map[string]interface{}{"post_id": post.ID, "edits": out})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div id="blog">`)
+	sb.WriteString(`<p class="text-muted">Changes made by moderators to this post and its comments. Edits by the author are not listed here.</p>`)
+	if len(edits) == 0 {
+		sb.WriteString(`<p class="text-muted italic">This post has not been modified by a moderator.</p>`)
+	}
+	for _, e := range edits {
+		reason := "No reason given"
+		if e.Reason != "" {
+			reason = html.EscapeString(e.Reason)
+		}
+		sb.WriteString(`<div class="p-4 bg-light rounded mb-3">`)
+		label := "Post"
+		if e.Type == "comment" {
+			label = "Comment"
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="text-muted text-xs mb-1">%s edited %s</div>`, label, app.TimeAgo(e.CreatedAt)))
+		sb.WriteString(fmt.Sprintf(`<div>%s</div>`, reason))
+		if acc != nil && (acc.Admin || acc.ID == e.AuthorID) {
+			sb.WriteString(fmt.Sprintf(`<div class="text-xs text-muted mt-2">By %s. Original:</div>`, html.EscapeString(e.EditorName)))
+			if e.OldTitle != e.NewTitle {
+				sb.WriteString(fmt.Sprintf(`<div class="text-sm"><strong>%s</strong></div>`, html.EscapeString(e.OldTitle)))
+			}
+			sb.WriteString(fmt.Sprintf(`<pre class="text-sm" style="white-space:pre-wrap">%s</pre>`, html.EscapeString(e.OldContent)))
+		}
+		sb.WriteString(`</div>`)
+	}
+	sb.WriteString(fmt.Sprintf(`<div class="mt-6"><a href="/blog/post?id=%s" class="text-muted">← Back to post</a></div>`, post.ID))
+	sb.WriteString(`</div>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest("Moderation history", "", sb.String(), r)))
+}
+
+// renderCommentEditForm serves the comment editor to an admin.
+func renderCommentEditForm(w http.ResponseWriter, r *http.Request, comment *Comment) {
+	content := fmt.Sprintf(`<div id="blog">
+		<p class="text-muted">Editing a comment by %s. The change is recorded and the comment will show that a moderator modified it.</p>
+		<form method="POST" action="/blog/post/%s/comment?id=%s" class="blog-form">
+			<input type="hidden" name="_method" value="PATCH">
+			<textarea name="content" rows="6" required>%s</textarea>
+			<input type="text" name="reason" placeholder="Reason for the edit (shown publicly)">
+			<div class="blog-form-actions">
+				<button type="submit">Save Changes</button>
+				<a href="/blog/post?id=%s" class="btn btn-secondary">Cancel</a>
+			</div>
+		</form>
+	</div>`, html.EscapeString(comment.Author), comment.PostID, comment.ID, html.EscapeString(comment.Content), comment.PostID)
+	w.Write([]byte(app.RenderHTMLForRequest("Edit comment", "", content, r)))
+}
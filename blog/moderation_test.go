@@ -0,0 +1,82 @@
+package blog
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func withModerationState(t *testing.T, list []*Post, cs []*Comment) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	mutex.Lock()
+	savedPosts, savedMap, savedComments, savedEdits := posts, postsMap, comments, moderatorEdits
+	posts, comments, moderatorEdits = list, cs, nil
+	postsMap = map[string]*Post{}
+	for _, p := range list {
+		postsMap[p.ID] = p
+	}
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		posts, postsMap, comments, moderatorEdits = savedPosts, savedMap, savedComments, savedEdits
+		mutex.Unlock()
+	})
+}
+
+func TestModeratorEdits(t *testing.T) {
+	now := time.Now()
+	withModerationState(t,
+		[]*Post{{ID: "p1", Title: "Hello", Content: "original post text", Author: "Alice", AuthorID: "alice", CreatedAt: now}},
+		[]*Comment{{ID: "c1", PostID: "p1", Content: "original comment", Author: "Bob", AuthorID: "bob", CreatedAt: now}},
+	)
+	admin := &auth.Account{ID: "mod", Name: "Mod", Admin: true}
+
+	// An author's own edit is not a moderator edit.
+	if err := UpdatePost("p1", "Hello", "author's own edit", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetModeratorEdits("p1"); len(got) != 0 {
+		t.Fatalf("author edit recorded as moderation: %+v", got)
+	}
+	if !GetPost("p1").ModeratedAt.IsZero() {
+		t.Fatal("author edit marked the post as moderated")
+	}
+
+	if err := ModeratorEditPost("p1", "Hello", "cleaned up", "", false, admin, "removed a phone number"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ModeratorEditComment("c1", "[removed]", admin, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	post := GetPost("p1")
+	if post.Content != "cleaned up" || post.ModeratedAt.IsZero() {
+		t.Fatalf("post not updated: %+v", post)
+	}
+	if c := GetComment("c1"); c.Content != "[removed]" || c.ModeratedAt.IsZero() {
+		t.Fatalf("comment not updated: %+v", c)
+	}
+
+	edits := GetModeratorEdits("p1")
+	if len(edits) != 2 || edits[0].Type != "comment" || edits[1].Type != "post" {
+		t.Fatalf("unexpected history: %+v", edits)
+	}
+	if edits[1].OldContent != "author's own edit" || edits[1].EditorID != "mod" || edits[1].AuthorID != "alice" {
+		t.Fatalf("post edit not recorded correctly: %+v", edits[1])
+	}
+
+	// The public history gives the reason but never the original text.
+	rec := httptest.NewRecorder()
+	renderModerationHistory(rec, httptest.NewRequest("GET", "/blog/post?id=p1&moderation=true", nil), post)
+	body := rec.Body.String()
+	if !strings.Contains(body, "removed a phone number") {
+		t.Fatal("history should show the reason")
+	}
+	if strings.Contains(body, "author&#39;s own edit") || strings.Contains(body, "original comment") {
+		t.Fatal("history leaked the original text to an anonymous reader")
+	}
+}
@@ -81,16 +81,12 @@ func notesEnabled() bool {
 // StartNotes begins the background notes posting loop. Called from main.go after
 // the building blocks are loaded (next to StartOpinion).
 func StartNotes() {
-	go notesLoop()
-}
-
-func notesLoop() {
-	// Let the other services settle, and stagger after the opinion loop.
-	time.Sleep(90 * time.Second)
-	for {
+	// Let the other services settle, and stagger after the opinion job.
+	// The actual pacing is time-based, see cadence.
+	app.Schedule(app.Job{Name: "blog.notes", Every: 6 * time.Hour, Delay: 90 * time.Second, Run: func() error {
 		publishNextNote()
-		time.Sleep(6 * time.Hour) // actual pacing is time-based, see cadence
-	}
+		return nil
+	}})
 }
 
 // publishNextNote posts one note if enabled and enough time has passed since the
@@ -65,18 +65,13 @@ func opinionCategories() []string {
 // Called from main.go after all building blocks are loaded.
 func StartOpinion() {
 	memory = loadMemory()
-	go opinionLoop()
-	go opinionEngageLoop()
-}
-
-func opinionLoop() {
-	// Wait for other services to load first
-	time.Sleep(30 * time.Second)
-
-	for {
+	// Checked every 30m once other services have loaded; the actual
+	// pacing is time-based.
+	app.Schedule(app.Job{Name: "blog.opinion", Every: 30 * time.Minute, Delay: 30 * time.Second, Run: func() error {
 		publishNextOpinion()
-		time.Sleep(30 * time.Minute) // check every 30m, actual pacing is time-based
-	}
+		return nil
+	}})
+	go opinionEngageLoop()
 }
 
 // opinionEngageLoop runs the opinion agent's engagement cycle.
@@ -0,0 +1,261 @@
+package blog
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Reactions are a small, fixed set of reflective responses a reader can
+// leave on a post or comment, in place of likes. They are shown as counts
+// next to the item and nothing else: no list, feed or digest is ordered
+// or filtered by them, and who reacted isn't shown.
+
+// ReactionKinds are the reactions a reader can choose from, in the order
+// they're shown.
+var ReactionKinds = []string{"thoughtful", "benefited", "ameen"}
+
+var reactionLabels = map[string]string{
+	"thoughtful": "Thoughtful",
+	"benefited":  "Benefited",
+	"ameen":      "Ameen",
+}
+
+// Reaction is one reader's reaction to a post or comment. A reader has at
+// most one reaction on an item; choosing another replaces it.
+type Reaction struct {
+	Type      string    `json:"type"` // "post" or "comment"
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type reactionKey struct {
+	Type, ID, UserID string
+}
+
+// reactions holds every reaction by (type, id, user). Guarded by mutex.
+var reactions = map[reactionKey]*Reaction{}
+
+func loadReactions() {
+	var list []*Reaction
+	if err := data.LoadJSON("blog_reactions.json", &list); err != nil {
+		return
+	}
+	mutex.Lock()
+	reactions = make(map[reactionKey]*Reaction, len(list))
+	for _, re := range list {
+		reactions[reactionKey{re.Type, re.ID, re.UserID}] = re
+	}
+	mutex.Unlock()
+}
+
+// saveReactions persists the reactions. Caller must hold mutex.
+func saveReactions() {
+	list := make([]*Reaction, 0, len(reactions))
+	for _, re := range reactions {
+		list = append(list, re)
+	}
+	if err := data.SaveJSON("blog_reactions.json", list); err != nil {
+		app.Log("blog", "Failed to save reactions: %v", err)
+	}
+}
+
+// ToggleReaction sets userID's reaction on a post or comment to kind, or
+// takes it back if that's already their reaction. It reports whether the
+// reaction is now set.
+func ToggleReaction(itemType, id, userID, kind string) (bool, error) {
+	if userID == "" {
+		return false, fmt.Errorf("not logged in")
+	}
+	if _, ok := reactionLabels[kind]; !ok {
+		return false, fmt.Errorf("unknown reaction %q", kind)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	switch itemType {
+	case "post":
+		if postsMap[id] == nil {
+			return false, fmt.Errorf("post not found")
+		}
+	case "comment":
+		if getCommentLocked(id) == nil {
+			return false, fmt.Errorf("comment not found")
+		}
+	default:
+		return false, fmt.Errorf("can't react to %q", itemType)
+	}
+
+	key := reactionKey{itemType, id, userID}
+	set := true
+	if re := reactions[key]; re != nil && re.Kind == kind {
+		delete(reactions, key)
+		set = false
+	} else {
+		reactions[key] = &Reaction{Type: itemType, ID: id, UserID: userID, Kind: kind, CreatedAt: time.Now()}
+	}
+	saveReactions()
+	return set, nil
+}
+
+// ReactionCounts returns how many readers left each kind of reaction on a
+// post or comment. Kinds nobody chose are left out.
+func ReactionCounts(itemType, id string) map[string]int {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return reactionCountsLocked(itemType, id)
+}
+
+func reactionCountsLocked(itemType, id string) map[string]int {
+	counts := map[string]int{}
+	for key, re := range reactions {
+		if key.Type == itemType && key.ID == id {
+			counts[re.Kind]++
+		}
+	}
+	return counts
+}
+
+// UserReaction returns userID's reaction on a post or comment, or "".
+func UserReaction(itemType, id, userID string) string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if re := reactions[reactionKey{itemType, id, userID}]; re != nil {
+		return re.Kind
+	}
+	return ""
+}
+
+// dropReactionsLocked removes the reactions matching drop. Caller must
+// hold mutex; it reports whether anything was removed.
+func dropReactionsLocked(drop func(*Reaction) bool) bool {
+	removed := false
+	for key, re := range reactions {
+		if drop(re) {
+			delete(reactions, key)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// renderReactions shows the counts for a post or comment. Signed in
+// readers get them as buttons that toggle their own reaction; guests see
+// just the counts, and nothing when there are none.
+func renderReactions(itemType, id, postID, userID string) string {
+	mutex.RLock()
+	counts := reactionCountsLocked(itemType, id)
+	mine := ""
+	if re := reactions[reactionKey{itemType, id, userID}]; re != nil {
+		mine = re.Kind
+	}
+	mutex.RUnlock()
+
+	if userID == "" {
+		var parts []string
+		for _, kind := range ReactionKinds {
+			if n := counts[kind]; n > 0 {
+				parts = append(parts, fmt.Sprintf(`%s <span class="reaction-count">%d</span>`, reactionLabels[kind], n))
+			}
+		}
+		if len(parts) == 0 {
+			return ""
+		}
+		return `<div class="reactions text-muted">` + strings.Join(parts, " · ") + `</div>`
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`<form method="POST" action="/blog/post/%s/react" class="reactions">`, html.EscapeString(postID)))
+	if itemType == "comment" {
+		b.WriteString(fmt.Sprintf(`<input type="hidden" name="comment" value="%s">`, html.EscapeString(id)))
+	}
+	for _, kind := range ReactionKinds {
+		class, pressed := "", "false"
+		if kind == mine {
+			class, pressed = ` class="active"`, "true"
+		}
+		count := ""
+		if n := counts[kind]; n > 0 {
+			count = fmt.Sprintf(` <span class="reaction-count">%d</span>`, n)
+		}
+		b.WriteString(fmt.Sprintf(`<button type="submit" name="kind" value="%s"%s aria-pressed="%s">%s%s</button>`,
+			kind, class, pressed, reactionLabels[kind], count))
+	}
+	b.WriteString(`</form>`)
+	return b.String()
+}
+
+// ReactHandler toggles the signed in reader's reaction on a post, or on
+// one of its comments when a comment ID is given.
+//
+//	POST /blog/post/{postID}/react  kind=thoughtful[&comment={commentID}]
+func ReactHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.Unauthorized(w, r)
+			return
+		}
+		app.RedirectToLogin(w, r)
+		return
+	}
+
+	postID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/blog/post/"), "/react")
+	post := GetPost(postID)
+	if post == nil || (post.Private && !acc.Admin) {
+		app.NotFound(w, r, "Post not found")
+		return
+	}
+
+	var req struct {
+		Kind    string `json:"kind"`
+		Comment string `json:"comment"`
+	}
+	if app.SendsJSON(r) {
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			app.BadRequest(w, r, "Failed to parse form")
+			return
+		}
+		req.Kind, req.Comment = r.FormValue("kind"), r.FormValue("comment")
+	}
+
+	itemType, id, anchor := "post", postID, ""
+	if req.Comment != "" {
+		if c := GetComment(req.Comment); c == nil || c.PostID != postID {
+			app.NotFound(w, r, "Comment not found")
+			return
+		}
+		itemType, id, anchor = "comment", req.Comment, "#comment-"+req.Comment
+	}
+
+	set, err := ToggleReaction(itemType, id, acc.ID, req.Kind)
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return
+	}
+	if app.SendsJSON(r) || app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{
+			"reacted": set,
+			"counts":  ReactionCounts(itemType, id),
+		})
+		return
+	}
+	http.Redirect(w, r, "/blog/post?id="+postID+anchor, http.StatusSeeOther)
+}
@@ -0,0 +1,122 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func withReactionState(t *testing.T, list []*Post, cs []*Comment) {
+	t.Helper()
+	withModerationState(t, list, cs)
+	mutex.Lock()
+	saved := reactions
+	reactions = map[reactionKey]*Reaction{}
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		reactions = saved
+		mutex.Unlock()
+	})
+}
+
+func TestToggleReaction(t *testing.T) {
+	now := time.Now()
+	withReactionState(t,
+		[]*Post{{ID: "p1", Title: "Hello", Content: "a post", AuthorID: "alice", CreatedAt: now}},
+		[]*Comment{{ID: "c1", PostID: "p1", Content: "a comment", AuthorID: "bob", CreatedAt: now}},
+	)
+
+	for _, r := range []struct{ user, kind string }{{"bob", "thoughtful"}, {"carol", "thoughtful"}, {"dave", "ameen"}} {
+		if set, err := ToggleReaction("post", "p1", r.user, r.kind); err != nil || !set {
+			t.Fatalf("%s %s: set=%v err=%v", r.user, r.kind, set, err)
+		}
+	}
+	if got := ReactionCounts("post", "p1"); got["thoughtful"] != 2 || got["ameen"] != 1 || len(got) != 2 {
+		t.Fatalf("counts = %v", got)
+	}
+
+	// Choosing another reaction replaces yours; choosing it again takes it back.
+	ToggleReaction("post", "p1", "bob", "benefited")
+	if got := ReactionCounts("post", "p1"); got["thoughtful"] != 1 || got["benefited"] != 1 {
+		t.Fatalf("after switching: %v", got)
+	}
+	if set, _ := ToggleReaction("post", "p1", "bob", "benefited"); set {
+		t.Fatal("toggling the same reaction should take it back")
+	}
+	if UserReaction("post", "p1", "bob") != "" {
+		t.Fatal("reaction still set")
+	}
+
+	if _, err := ToggleReaction("comment", "c1", "alice", "benefited"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ReactionCounts("comment", "c1"); got["benefited"] != 1 {
+		t.Fatalf("comment counts = %v", got)
+	}
+
+	for _, bad := range []struct{ itemType, id, user, kind string }{
+		{"post", "p1", "bob", "like"},
+		{"post", "nope", "bob", "ameen"},
+		{"comment", "nope", "bob", "ameen"},
+		{"video", "p1", "bob", "ameen"},
+		{"post", "p1", "", "ameen"},
+	} {
+		if _, err := ToggleReaction(bad.itemType, bad.id, bad.user, bad.kind); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
+
+func TestRenderReactions(t *testing.T) {
+	withReactionState(t, []*Post{{ID: "p1", Content: "a post", AuthorID: "alice"}}, nil)
+
+	if got := renderReactions("post", "p1", "p1", ""); got != "" {
+		t.Fatalf("guests should see nothing without reactions: %q", got)
+	}
+	ToggleReaction("post", "p1", "bob", "ameen")
+
+	guest := renderReactions("post", "p1", "p1", "")
+	if !strings.Contains(guest, "Ameen") || strings.Contains(guest, "<form") || strings.Contains(guest, "Thoughtful") {
+		t.Fatalf("guest view = %q", guest)
+	}
+	mine := renderReactions("post", "p1", "p1", "bob")
+	if !strings.Contains(mine, `action="/blog/post/p1/react"`) || !strings.Contains(mine, `value="ameen" class="active" aria-pressed="true"`) {
+		t.Fatalf("reader view = %q", mine)
+	}
+	if strings.Contains(mine, "bob") {
+		t.Fatal("who reacted should not be shown")
+	}
+}
+
+func TestReactionsFollowContentAndAccounts(t *testing.T) {
+	now := time.Now()
+	withReactionState(t,
+		[]*Post{
+			{ID: "p1", Content: "first", AuthorID: "alice", CreatedAt: now},
+			{ID: "p2", Content: "second", AuthorID: "bob", CreatedAt: now},
+		},
+		[]*Comment{{ID: "c1", PostID: "p1", Content: "a comment", AuthorID: "bob", CreatedAt: now}},
+	)
+	ToggleReaction("post", "p1", "carol", "ameen")
+	ToggleReaction("comment", "c1", "carol", "thoughtful")
+	ToggleReaction("post", "p2", "carol", "benefited")
+	ToggleReaction("post", "p2", "dave", "benefited")
+
+	if err := DeletePost("p1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ReactionCounts("post", "p1")) != 0 || len(ReactionCounts("comment", "c1")) != 0 {
+		t.Fatal("reactions on a deleted post and its comments should go with it")
+	}
+
+	RenameAuthor("carol", "caroline")
+	if UserReaction("post", "p2", "caroline") != "benefited" || UserReaction("post", "p2", "carol") != "" {
+		t.Fatal("reaction not moved to the new username")
+	}
+
+	DeletePostsByAuthor("dave")
+	if got := ReactionCounts("post", "p2"); got["benefited"] != 1 {
+		t.Fatalf("a deleted account's reactions should go: %v", got)
+	}
+}
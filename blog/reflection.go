@@ -0,0 +1,221 @@
+package blog
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/settings"
+)
+
+func init() {
+	settings.Register("Posts",
+		settings.Var{Key: "POST_REFLECTION_SECONDS", Type: settings.TypeInt, Default: "30", Doc: "Seconds between opening the write form and posting; 0 turns the wait off"},
+	)
+}
+
+// The reflection gate: a new post can't go out the moment it's typed. The
+// write form carries a compose token signed with when it was loaded, and a
+// post sent before POST_REFLECTION_SECONDS have passed gets a short
+// countdown page, with the post kept and ready to send, rather than being
+// published. The same goes for publishing or scheduling a draft. Scripts
+// posting with a personal access token are exempt; signing in through a
+// browser and sending JSON is not.
+
+var (
+	composeKey     []byte
+	composeKeyOnce sync.Once
+)
+
+// composeSecret is a per-process key for compose tokens. A restart
+// invalidates them, which just restarts the wait.
+func composeSecret() []byte {
+	composeKeyOnce.Do(func() {
+		composeKey = make([]byte, 32)
+		rand.Read(composeKey)
+	})
+	return composeKey
+}
+
+// reflectionDelay is how long a post must be written over, 0 for no wait.
+func reflectionDelay() time.Duration {
+	n := settings.Int("POST_REFLECTION_SECONDS")
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// composeToken returns a token recording that accountID started writing
+// at the given time.
+func composeToken(accountID string, at time.Time) string {
+	ms := strconv.FormatInt(at.UnixMilli(), 10)
+	return ms + "." + composeSignature(accountID, ms)
+}
+
+func composeSignature(accountID, ms string) string {
+	mac := hmac.New(sha256.New, composeSecret())
+	mac.Write([]byte(accountID + "|" + ms))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// composeStarted returns when accountID started writing, by their token.
+func composeStarted(accountID, token string) (time.Time, bool) {
+	ms, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(composeSignature(accountID, ms))) {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(n), true
+}
+
+// reflectionWait returns how much longer accountID must wait before
+// posting, and the token to post with then. A missing or invalid token
+// starts the wait now.
+func reflectionWait(accountID, token string) (time.Duration, string) {
+	delay := reflectionDelay()
+	if delay == 0 {
+		return 0, token
+	}
+	started, ok := composeStarted(accountID, token)
+	if !ok {
+		return delay, composeToken(accountID, time.Now())
+	}
+	wait := delay - time.Since(started)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, token
+}
+
+// composeField is the write form's compose token, and when it may post.
+func composeField(accountID string) string {
+	now := time.Now()
+	return fmt.Sprintf(`<input type="hidden" name="compose_token" value="%s" data-post-after="%d">`,
+		composeToken(accountID, now), now.Add(reflectionDelay()).UnixMilli())
+}
+
+// publishes reports whether a POST to /blog puts a post live, now or on
+// schedule, rather than saving or deleting a draft.
+func publishes(r *http.Request) bool {
+	switch r.FormValue("action") {
+	case "delete_draft":
+		return false
+	case "draft":
+		return strings.TrimSpace(r.FormValue("publish_at")) != ""
+	}
+	return true
+}
+
+// tokenPoster reports whether r comes from a personal access token that
+// may publish, which the gate lets straight through.
+func tokenPoster(r *http.Request) bool {
+	sess, err := auth.GetSession(r)
+	return err == nil && sess.Type == "token" && sess.Allows("write:posts")
+}
+
+// Reflecting reports whether the post in r would be held by the
+// reflection gate, so main.go doesn't charge for it.
+func Reflecting(r *http.Request) bool {
+	if !publishes(r) || tokenPoster(r) {
+		return false
+	}
+	_, acc := auth.TrySession(r)
+	if acc == nil {
+		return false
+	}
+	wait, _ := reflectionWait(acc.ID, r.FormValue("compose_token"))
+	return wait > 0
+}
+
+// holdPost checks the reflection gate for post p, sent with the given
+// compose token. When the writer must wait it answers with the countdown
+// page, or a JSON error carrying the token to retry with, and returns
+// true. A p with an ID is a draft, posted again by ID once the wait is
+// over; one with PublishAt set is scheduled rather than published.
+func holdPost(w http.ResponseWriter, r *http.Request, accountID, token string, p *Post) bool {
+	if tokenPoster(r) {
+		return false
+	}
+	wait, token := reflectionWait(accountID, token)
+	if wait == 0 {
+		return false
+	}
+	secs := int((wait + time.Second - 1) / time.Second)
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		w.WriteHeader(http.StatusTooEarly)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error":         fmt.Sprintf("Take a moment: you can post in %d seconds", secs),
+			"compose_token": token,
+			"retry_after":   secs,
+		})
+		return true
+	}
+
+	private := ""
+	if p.Private {
+		private = "private"
+	}
+	var hidden strings.Builder
+	if p.ID != "" {
+		fmt.Fprintf(&hidden, `<input type="hidden" name="draft_id" value="%s">`, html.EscapeString(p.ID))
+	}
+	if !p.PublishAt.IsZero() {
+		fmt.Fprintf(&hidden, `<input type="hidden" name="publish_at" value="%s">`, p.PublishAt.UTC().Format(time.RFC3339))
+	}
+	content := fmt.Sprintf(`<div id="blog">
+		<p>Take a moment to read it over. You can post in <span id="reflect-seconds">%d</span> seconds.</p>
+		<form id="reflect-form" class="blog-form" method="POST" action="%s">
+			<input type="hidden" name="compose_token" value="%s">
+			<input type="hidden" name="visibility" value="%s">%s
+			<input type="text" name="title" placeholder="Title (optional)" value="%s">
+			<textarea name="content" data-mentions rows="8" required>%s</textarea>
+			<input type="text" name="tags" placeholder="Tags (optional, comma-separated)" value="%s">
+			<div class="blog-form-actions">
+				<a href="/blog" class="btn btn-secondary">Cancel</a>
+				<button type="submit" id="reflect-submit">Post</button>
+			</div>
+		</form>
+		<script>
+			(function() {
+				var left = %d;
+				var label = document.getElementById('reflect-seconds');
+				var button = document.getElementById('reflect-submit');
+				button.disabled = true;
+				var timer = setInterval(function() {
+					left--;
+					if (left > 0) {
+						label.textContent = left;
+						return;
+					}
+					clearInterval(timer);
+					label.parentNode.textContent = 'Ready when you are.';
+					button.disabled = false;
+				}, 1000);
+			})();
+		</script>
+	</div>`, secs, html.EscapeString(r.URL.Path), html.EscapeString(token), private, hidden.String(),
+		html.EscapeString(p.Title), html.EscapeString(p.Content),
+		html.EscapeString(p.Tags), secs)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusTooEarly)
+	w.Write([]byte(app.RenderHTMLForRequest("Take a moment", "Take a moment before posting", content, r)))
+	return true
+}
@@ -0,0 +1,159 @@
+package blog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestReflectionGate(t *testing.T) {
+	fresh := composeToken("alice", time.Now())
+	if wait, _ := reflectionWait("alice", fresh); wait < 29*time.Second {
+		t.Errorf("a post straight away waits %v, want about 30s", wait)
+	}
+	old := composeToken("alice", time.Now().Add(-time.Minute))
+	if wait, _ := reflectionWait("alice", old); wait != 0 {
+		t.Errorf("a post after a minute waits %v", wait)
+	}
+	wait, token := reflectionWait("bob", old)
+	if wait < 29*time.Second || token == old {
+		t.Errorf("someone else's token let them post after %v", wait)
+	}
+
+	form := url.Values{"title": {"Thinking"}, "content": {"Some <thoughts> worth sharing"}, "compose_token": {fresh}}
+	r := httptest.NewRequest("POST", "/blog", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	if !holdPost(w, r, "alice", fresh, &Post{Title: "Thinking", Content: form.Get("content")}) {
+		t.Fatal("a post straight away wasn't held")
+	}
+	if w.Code != 425 || !strings.Contains(w.Body.String(), "Some &lt;thoughts&gt; worth sharing") {
+		t.Errorf("held post page: %d %q", w.Code, w.Body.String())
+	}
+
+	form.Set("compose_token", old)
+	r = httptest.NewRequest("POST", "/blog", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if holdPost(httptest.NewRecorder(), r, "alice", old, &Post{Title: "Thinking", Content: form.Get("content")}) {
+		t.Error("held a post written over a minute")
+	}
+}
+
+func TestReflectionGateEveryPublishPath(t *testing.T) {
+	withModerationState(t, nil, nil)
+	mutex.Lock()
+	savedDrafts := drafts
+	drafts = nil
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		drafts = savedDrafts
+		mutex.Unlock()
+	})
+	acc := &auth.Account{ID: "reflector", Name: "Reflector", Secret: "secret", Created: time.Now()}
+	if err := auth.Create(acc); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount(acc.ID)
+	sess, err := auth.Login(acc.ID, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "A few thoughts on the allotment, now that the beans are finally up."
+	d, err := SaveDraft("", "Beans", content, "", false, time.Time{}, acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := composeToken(acc.ID, time.Now())
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/blog", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.ParseForm()
+		w := httptest.NewRecorder()
+		if !handleDraft(w, r, acc) {
+			t.Fatalf("%v wasn't handled as a draft", form)
+		}
+		return w
+	}
+
+	// Publishing a draft from the drafts list is held, and the countdown
+	// page posts it back by ID.
+	w := post(url.Values{"action": {"publish_draft"}, "draft_id": {d.ID}})
+	if w.Code != http.StatusTooEarly || !strings.Contains(w.Body.String(), `name="draft_id" value="`+d.ID+`"`) {
+		t.Fatalf("publish_draft: %d %s", w.Code, w.Body.String())
+	}
+	if GetPost(d.ID) != nil {
+		t.Fatal("publish_draft went live straight away")
+	}
+
+	// So is scheduling one, from the write form or as an edit.
+	at := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	for _, form := range []url.Values{
+		{"action": {"draft"}, "title": {"Later"}, "content": {content}, "publish_at": {at}, "compose_token": {fresh}},
+		{"draft_id": {d.ID}, "title": {"Beans"}, "content": {content}, "publish_at": {at}, "compose_token": {fresh}},
+	} {
+		w := post(form)
+		if w.Code != http.StatusTooEarly || !strings.Contains(w.Body.String(), `name="publish_at" value="`+at+`"`) {
+			t.Fatalf("schedule %v: %d %s", form, w.Code, w.Body.String())
+		}
+	}
+	if got := GetDraft(acc.ID, d.ID); got == nil || !got.PublishAt.IsZero() || CountDrafts(acc.ID) != 1 {
+		t.Fatalf("a held schedule was saved: %+v", got)
+	}
+
+	// Saving a plain draft isn't held.
+	if w := post(url.Values{"action": {"draft"}, "content": {"rough"}}); w.Code != http.StatusSeeOther {
+		t.Fatalf("draft: %d %s", w.Code, w.Body.String())
+	}
+
+	// Once the moment has passed the draft goes out.
+	old := composeToken(acc.ID, time.Now().Add(-time.Minute))
+	w = post(url.Values{"action": {"publish_draft"}, "draft_id": {d.ID}, "compose_token": {old}})
+	if w.Code != http.StatusSeeOther || GetPost(d.ID) == nil {
+		t.Fatalf("publish_draft after a minute: %d %s", w.Code, w.Body.String())
+	}
+
+	// Sending JSON from a signed-in browser is held like a form...
+	body := `{"title":"Hasty","content":"` + content + `"}`
+	r := httptest.NewRequest("POST", "/blog/post", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	w = httptest.NewRecorder()
+	PostHandler(w, r)
+	var held struct {
+		ComposeToken string `json:"compose_token"`
+	}
+	if w.Code != http.StatusTooEarly || json.Unmarshal(w.Body.Bytes(), &held) != nil || held.ComposeToken == "" {
+		t.Fatalf("JSON post: %d %s", w.Code, w.Body.String())
+	}
+	if len(GetPostsByAuthor(acc.Name)) != 1 {
+		t.Fatal("a held JSON post was published")
+	}
+
+	// ...but a script with a personal access token isn't held.
+	_, raw, err := auth.CreateToken(acc.ID, "script", []string{"write:posts"}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = httptest.NewRequest("POST", "/blog/post", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+raw)
+	if holdPost(httptest.NewRecorder(), r, acc.ID, "", &Post{Content: content}) {
+		t.Error("held a post from a personal access token")
+	}
+	_, raw, err = auth.CreateToken(acc.ID, "reader", []string{"read"}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+raw)
+	if !holdPost(httptest.NewRecorder(), r, acc.ID, "", &Post{Content: content}) {
+		t.Error("a read-only token skipped the wait")
+	}
+}
@@ -0,0 +1,295 @@
+package blog
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Revision is a post as it was before an edit replaced it. Every edit,
+// the author's or a moderator's, keeps the version it replaces, so no
+// edit loses what was there and any version can be restored.
+type Revision struct {
+	ID         string    `json:"id"`
+	PostID     string    `json:"post_id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Tags       string    `json:"tags,omitempty"`
+	Private    bool      `json:"private,omitempty"`
+	WrittenAt  time.Time `json:"written_at"`          // when this version was saved
+	ReplacedAt time.Time `json:"replaced_at"`         // when an edit replaced it
+	Moderated  bool      `json:"moderated,omitempty"` // replaced by a moderator's edit
+}
+
+// maxRevisions is how many earlier versions of a post are kept.
+const maxRevisions = 50
+
+// Revisions are kept a file per post, since they're only read when
+// someone opens a post's history.
+func revisionsKey(postID string) string {
+	return "blog_revisions_" + postID + ".json"
+}
+
+// loadRevisions returns a post's earlier versions, oldest first. Caller
+// must hold mutex.
+func loadRevisions(postID string) []*Revision {
+	var list []*Revision
+	data.LoadJSON(revisionsKey(postID), &list)
+	return list
+}
+
+// saveRevisionLocked keeps post's current version before an edit replaces
+// it, unless the edit changes nothing. Caller must hold mutex.
+func saveRevisionLocked(post *Post, title, content, tags string, private, moderated bool) {
+	if post.Title == title && post.Content == content && post.Tags == tags && post.Private == private {
+		return
+	}
+	now := time.Now()
+	written := post.UpdatedAt
+	if written.IsZero() {
+		written = post.CreatedAt
+	}
+	list := append(loadRevisions(post.ID), &Revision{
+		ID:         fmt.Sprintf("%d", now.UnixNano()),
+		PostID:     post.ID,
+		Title:      post.Title,
+		Content:    post.Content,
+		Tags:       post.Tags,
+		Private:    post.Private,
+		WrittenAt:  written,
+		ReplacedAt: now,
+		Moderated:  moderated,
+	})
+	if len(list) > maxRevisions {
+		list = list[len(list)-maxRevisions:]
+	}
+	if err := data.SaveJSON(revisionsKey(post.ID), list); err != nil {
+		app.Log("blog", "Failed to save revision of post %s: %v", post.ID, err)
+	}
+}
+
+// deleteRevisionsLocked removes a deleted post's history. Caller must
+// hold mutex.
+func deleteRevisionsLocked(postID string) {
+	data.DeleteFile(revisionsKey(postID))
+}
+
+// GetRevisions returns a post's earlier versions, newest first.
+func GetRevisions(postID string) []*Revision {
+	mutex.RLock()
+	list := loadRevisions(postID)
+	mutex.RUnlock()
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+// moderatedUntil is when a moderator last replaced a version of the post,
+// given its revisions.
+func moderatedUntil(revisions []*Revision) time.Time {
+	var t time.Time
+	for _, r := range revisions {
+		if r.Moderated && r.ReplacedAt.After(t) {
+			t = r.ReplacedAt
+		}
+	}
+	return t
+}
+
+// canRestore reports whether acc may restore rev on post. Authors can go
+// back to their own versions written since a moderator last edited the
+// post, so restoring can't undo moderation; admins can restore any
+// version.
+func canRestore(acc *auth.Account, post *Post, rev *Revision, moderated time.Time) bool {
+	if acc.Admin {
+		return true
+	}
+	return acc.ID == post.AuthorID && rev.ReplacedAt.After(moderated)
+}
+
+// RestoreRevision makes an earlier version of a post its current one. The
+// version it replaces is kept like any other edit's, so a restore can
+// itself be undone. An admin restoring someone else's post makes a
+// moderator edit.
+func RestoreRevision(postID, revisionID string, acc *auth.Account) error {
+	post := GetPost(postID)
+	if post == nil {
+		return fmt.Errorf("post not found")
+	}
+	revisions := GetRevisions(postID)
+	var rev *Revision
+	for _, r := range revisions {
+		if r.ID == revisionID {
+			rev = r
+		}
+	}
+	if rev == nil {
+		return fmt.Errorf("revision not found")
+	}
+	if !canRestore(acc, post, rev, moderatedUntil(revisions)) {
+		return fmt.Errorf("you can't restore this version")
+	}
+	if acc.ID != post.AuthorID {
+		reason := "Restored the version from " + rev.WrittenAt.Format("2 Jan 2006 15:04")
+		return ModeratorEditPost(postID, rev.Title, rev.Content, rev.Tags, rev.Private, acc, reason)
+	}
+	return UpdatePost(postID, rev.Title, rev.Content, rev.Tags, rev.Private)
+}
+
+// renderRevisionHistory serves a post's earlier versions to its author
+// and admins, each with what changed in the edit that replaced it.
+//
+//	GET  /blog/post?id={id}&history=true
+//	POST /blog/post?id={id}&history=true  restore={revisionID}
+func renderRevisionHistory(w http.ResponseWriter, r *http.Request, post *Post) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.RedirectToLogin(w, r)
+		return
+	}
+	if post.AuthorID != acc.ID && !acc.Admin {
+		app.Forbidden(w, r, "Only the author can see a post's history")
+		return
+	}
+
+	if r.Method == "POST" {
+		if err := RestoreRevision(post.ID, r.FormValue("restore"), acc); err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"success": true, "id": post.ID})
+			return
+		}
+		http.Redirect(w, r, "/blog/post?id="+post.ID, http.StatusSeeOther)
+		return
+	}
+
+	revisions := GetRevisions(post.ID)
+	moderated := moderatedUntil(revisions)
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"post_id": post.ID, "revisions": revisions})
+		return
+	}
+
+	// Each version is compared with the one that replaced it: the current
+	// post for the newest, the next newer version for the rest.
+	type revisionView struct {
+		*Revision
+		NewerTitle string
+		Diff       template.HTML
+		CanRestore bool
+	}
+	views := make([]revisionView, 0, len(revisions))
+	newerTitle, newerContent := post.Title, post.Content
+	for _, rev := range revisions {
+		views = append(views, revisionView{
+			Revision:   rev,
+			NewerTitle: newerTitle,
+			Diff:       template.HTML(renderDiff(diffLines(rev.Content, newerContent))),
+			CanRestore: canRestore(acc, post, rev, moderated),
+		})
+		newerTitle, newerContent = rev.Title, rev.Content
+	}
+	templates.Page(w, r, "Post history", "", "history", map[string]interface{}{
+		"PostID":    post.ID,
+		"Revisions": views,
+	})
+}
+
+// diffLine is one line of a diff: kept (' '), removed ('-') or added ('+').
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+// maxDiffCells bounds the work diffLines does; past it, the whole of the
+// old text is shown as removed and the new as added.
+const maxDiffCells = 4_000_000
+
+// diffLines returns the line diff turning a into b, from their longest
+// common subsequence of lines.
+func diffLines(a, b string) []diffLine {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if a == "" {
+		x = nil
+	}
+	if b == "" {
+		y = nil
+	}
+	if len(x)*len(y) > maxDiffCells {
+		var out []diffLine
+		for _, l := range x {
+			out = append(out, diffLine{'-', l})
+		}
+		for _, l := range y {
+			out = append(out, diffLine{'+', l})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the length of the common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []diffLine
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, diffLine{' ', x[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{'-', x[i]})
+			i++
+		default:
+			out = append(out, diffLine{'+', y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, diffLine{'-', x[i]})
+	}
+	for ; j < len(y); j++ {
+		out = append(out, diffLine{'+', y[j]})
+	}
+	return out
+}
+
+// renderDiff shows a line diff as preformatted text.
+func renderDiff(lines []diffLine) string {
+	var sb strings.Builder
+	sb.WriteString(`<pre class="diff">`)
+	for _, l := range lines {
+		text := html.EscapeString(l.Text)
+		switch l.Op {
+		case '-':
+			sb.WriteString(`<del>- ` + text + "</del>\n")
+		case '+':
+			sb.WriteString(`<ins>+ ` + text + "</ins>\n")
+		default:
+			sb.WriteString(`  ` + text + "\n")
+		}
+	}
+	sb.WriteString(`</pre>`)
+	return sb.String()
+}
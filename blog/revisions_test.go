@@ -0,0 +1,84 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestRevisions(t *testing.T) {
+	withModerationState(t,
+		[]*Post{{ID: "p1", Title: "Hello", Content: "one\ntwo", Author: "Alice", AuthorID: "alice", CreatedAt: time.Now()}},
+		nil,
+	)
+	alice := &auth.Account{ID: "alice", Name: "Alice"}
+	admin := &auth.Account{ID: "mod", Name: "Mod", Admin: true}
+
+	if err := UpdatePost("p1", "Hello", "one\ntwo\nthree", "", false); err != nil {
+		t.Fatal(err)
+	}
+	// An edit that changes nothing keeps no revision.
+	if err := UpdatePost("p1", "Hello", "one\ntwo\nthree", "", false); err != nil {
+		t.Fatal(err)
+	}
+	revs := GetRevisions("p1")
+	if len(revs) != 1 || revs[0].Content != "one\ntwo" {
+		t.Fatalf("revisions = %+v", revs)
+	}
+
+	// Restoring brings the old version back and keeps the one it replaced.
+	if err := RestoreRevision("p1", revs[0].ID, alice); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetPost("p1").Content; got != "one\ntwo" {
+		t.Fatalf("restored content = %q", got)
+	}
+	revs = GetRevisions("p1")
+	if len(revs) != 2 || revs[0].Content != "one\ntwo\nthree" {
+		t.Fatalf("revisions after restore = %+v", revs)
+	}
+
+	// The author can't restore what a moderator removed, or anything
+	// before it; an admin can.
+	if err := ModeratorEditPost("p1", "Hello", "[removed]", "", false, admin, ""); err != nil {
+		t.Fatal(err)
+	}
+	revs = GetRevisions("p1")
+	for _, rev := range revs {
+		if err := RestoreRevision("p1", rev.ID, alice); err == nil {
+			t.Errorf("author restored %q from before moderation", rev.Content)
+		}
+	}
+	if err := RestoreRevision("p1", revs[0].ID, &auth.Account{ID: "bob"}); err == nil {
+		t.Error("someone else restored the post")
+	}
+	if err := RestoreRevision("p1", revs[0].ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetPost("p1").Content; got != "one\ntwo" {
+		t.Fatalf("admin restored content = %q", got)
+	}
+
+	if err := DeletePost("p1"); err != nil {
+		t.Fatal(err)
+	}
+	if revs := GetRevisions("p1"); len(revs) != 0 {
+		t.Errorf("revisions kept after delete: %+v", revs)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	var got []string
+	for _, l := range diffLines("a\nb\nc", "a\nx\nc\nd") {
+		got = append(got, string(l.Op)+l.Text)
+	}
+	want := " a -b +x  c +d"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("diff = %q, want %q", s, want)
+	}
+	if d := diffLines("", "new"); len(d) != 1 || d[0].Op != '+' {
+		t.Errorf("diff from empty = %+v", d)
+	}
+}
@@ -0,0 +1,32 @@
+package blog
+
+import (
+	"net/http"
+
+	"mu/internal/app"
+)
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/blog", Handler) // public viewing, posting needs a session
+	// A single post, or its ActivityPub object when that's asked for.
+	r.HandleFunc("/blog/post", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && WantsActivityPub(r) {
+			PostObjectHandler(w, r)
+			return
+		}
+		PostHandler(w, r)
+	})
+	r.HandleFunc("/blog/post/", CommentHandler) // /blog/post/{id}/comment
+	r.HandleFunc("/blog/feed.xml", FeedHandler)
+	r.HandleFunc("/blog/atom.xml", FeedHandler)
+	r.HandleFunc("/.well-known/webfinger", WebFingerHandler)
+
+	// The old URLs.
+	r.HandleFunc("/post/", app.Moved("/post/", "/blog/post/"))
+	r.HandleFunc("/post", app.Moved("/post", "/blog/post"))
+	r.HandleFunc("/posts/feed.xml", app.Moved("/posts/feed.xml", "/blog/feed.xml"))
+}
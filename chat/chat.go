@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"sync"
@@ -27,13 +28,14 @@ var f embed.FS
 var Template = `
 <div id="topic-selector">
   <div class="topic-tabs">%s</div>
+  %s
 </div>
 <div id="messages"></div>
 %s
 <form id="chat-form" onsubmit="event.preventDefault(); askLLM(this);">
 <input id="context" name="context" type="hidden">
 <input id="topic" name="topic" type="hidden">
-<input id="prompt" name="prompt" type="text" placeholder="Ask a question or type /clear" autocomplete=off>
+<input id="prompt" name="prompt" data-mentions type="text" placeholder="Ask a question or type /clear" autocomplete=off>
 <button>Send</button>
 </form>`
 
@@ -103,15 +105,18 @@ type Room struct {
 	Register     chan *Client                // Register client
 	Unregister   chan *Client                // Unregister client
 	Shutdown     chan bool                   // Signal for graceful shutdown
+	Changes      chan messageChange          // Edits and deletions
 	mutex        sync.RWMutex
 }
 
 // RoomMessage represents a message in a chat room
 type RoomMessage struct {
+	ID        string    `json:"id,omitempty"`
 	UserID    string    `json:"username"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
 	IsLLM     bool      `json:"is_llm"`
+	Edited    bool      `json:"edited,omitempty"`
 }
 
 // Client represents a connected websocket client
@@ -123,12 +128,40 @@ type Client struct {
 	LastMicroReply time.Time // when micro last replied to this user
 }
 
+// OnMessage is called for each message a person sends in a room. Wired
+// from main.go.
+var OnMessage func(roomID, roomTitle string, msg RoomMessage)
+
+// Command answers a slash command, such as "/weather london", sent from
+// the chat prompt. It returns the reply as markdown and as HTML, and false
+// when nothing offers the command. Wired from main.go.
+var Command func(userID, text string) (reply, replyHTML string, ok bool)
+
 var rooms = make(map[string]*Room)
 var roomsMutex sync.RWMutex
 
+// RoomTranscript returns a room's title and recent messages, oldest first.
+// Rooms that aren't open fall back to their persisted messages.
+func RoomTranscript(roomID string) (string, []RoomMessage) {
+	roomsMutex.RLock()
+	room := rooms[roomID]
+	roomsMutex.RUnlock()
+	if room == nil {
+		return "", visibleMessages(roomID, loadRoomMessages(roomID))
+	}
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+	return room.Title, visibleMessages(roomID, room.Messages)
+}
+
+// roomFile is the file a room's messages are kept in.
+func roomFile(roomID string) string {
+	return "room_" + strings.ReplaceAll(roomID, "/", "_") + ".json"
+}
+
 // saveRoomMessages persists room messages to disk
 func saveRoomMessages(roomID string, messages []RoomMessage) {
-	filename := "room_" + strings.ReplaceAll(roomID, "/", "_") + ".json"
+	filename := roomFile(roomID)
 	b, err := json.Marshal(messages)
 	if err != nil {
 		app.Log("chat", "Error marshaling room messages: %v", err)
@@ -140,9 +173,9 @@ func saveRoomMessages(roomID string, messages []RoomMessage) {
 }
 
 // loadRoomMessages loads persisted room messages from disk
-// Messages older than 24 hours are pruned
+// Messages older than 24 hours are pruned, except in named rooms
 func loadRoomMessages(roomID string) []RoomMessage {
-	filename := "room_" + strings.ReplaceAll(roomID, "/", "_") + ".json"
+	filename := roomFile(roomID)
 	b, err := data.LoadFile(filename)
 	if err != nil {
 		return nil
@@ -152,6 +185,9 @@ func loadRoomMessages(roomID string) []RoomMessage {
 		app.Log("chat", "Error unmarshaling room messages: %v", err)
 		return nil
 	}
+	if isNamedRoom(roomID) {
+		return messages
+	}
 
 	// Prune messages older than 24 hours
 	cutoff := time.Now().Add(-24 * time.Hour)
@@ -286,7 +322,8 @@ func getOrCreateRoom(id string) *Room {
 		Register:     make(chan *Client),
 		Unregister:   make(chan *Client),
 		Shutdown:     make(chan bool),
-		Messages:     make([]RoomMessage, 0, 20),
+		Changes:      make(chan messageChange),
+		Messages:     make([]RoomMessage, 0, historyLimit(id)),
 		LastActivity: time.Now(),
 	}
 
@@ -430,6 +467,17 @@ func getOrCreateRoom(id string) *Room {
 			}
 		}
 		app.Log("chat", "Created chat room for topic: %s (lastAI: %v)", itemID, room.LastAIMsg)
+	case "room":
+		// Named rooms: archived and unknown ones can't be joined
+		named, ok := namedRoom(id)
+		if !ok || named.Archived {
+			return nil
+		}
+		room.Title = "#" + named.Slug
+		room.Summary = named.Description
+		if saved := loadRoomMessages(id); saved != nil {
+			room.Messages = saved
+		}
 	case "reminder":
 		// For reminder, lookup by exact ID
 		app.Log("chat", "Attempting to get reminder item %s from index", itemID)
@@ -631,22 +679,32 @@ func (room *Room) run() {
 			// Broadcast updated user list
 			room.broadcastUserList()
 
+		case change := <-room.Changes:
+			room.applyChange(change)
+
 		case message := <-room.Broadcast:
-			// Add message to history (keep last 20)
+			// Add message to history (keep the last 20, or 100 in named rooms)
 			room.mutex.Lock()
 			room.Messages = append(room.Messages, message)
-			if len(room.Messages) > 20 {
-				room.Messages = room.Messages[len(room.Messages)-20:]
+			if limit := historyLimit(room.ID); len(room.Messages) > limit {
+				room.Messages = room.Messages[len(room.Messages)-limit:]
 			}
 			room.LastActivity = time.Now()
 			messagesToSave := make([]RoomMessage, len(room.Messages))
 			copy(messagesToSave, room.Messages)
 			room.mutex.Unlock()
 
-			// Persist messages for topic chat rooms
-			if strings.HasPrefix(room.ID, "chat_") {
+			// Persist messages for topic and named chat rooms
+			if strings.HasPrefix(room.ID, "chat_") || isNamedRoom(room.ID) {
 				go saveRoomMessages(room.ID, messagesToSave)
 			}
+			if isNamedRoom(room.ID) {
+				noteMessage(room.ID, message.Timestamp)
+			}
+
+			if !message.IsLLM && OnMessage != nil {
+				go OnMessage(room.ID, room.Title, message)
+			}
 
 			// Broadcast to all clients
 			room.mutex.RLock()
@@ -772,7 +830,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, room *Room) {
 
 	// Send room history to new client
 	room.mutex.RLock()
-	for _, msg := range room.Messages {
+	for _, msg := range visibleMessages(room.ID, room.Messages) {
 		conn.WriteJSON(msg)
 	}
 	room.mutex.RUnlock()
@@ -793,12 +851,14 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, room *Room) {
 			if content, ok := msg["content"].(string); ok && len(content) > 0 {
 				// Broadcast user message
 				userMsg := RoomMessage{
+					ID:        newMessageID(),
 					UserID:    client.UserID,
 					Content:   content,
 					Timestamp: time.Now(),
 					IsLLM:     false,
 				}
 				room.Broadcast <- userMsg
+				go flag.CheckContent("chat", messageRef(room.ID, userMsg.ID), "", content)
 
 				// Check if micro should respond:
 				// For item-specific rooms (news_, video_, post_), ALWAYS respond - these are AI discussions
@@ -916,7 +976,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, room *Room) {
 						app.Log("chat", "Building history from %d room messages", len(room.Messages))
 
 						var currentPrompt string
-						for _, m := range room.Messages {
+						for _, m := range visibleMessages(room.ID, room.Messages) {
 							if m.IsLLM {
 								// This is an AI response - pair it with the previous user prompt
 								if currentPrompt != "" {
@@ -973,31 +1033,28 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, room *Room) {
 							}
 						}
 
-						// Simple RAG: search and build context
-						ragEntries := data.Search(searchContent, 8)
-						for _, entry := range ragEntries {
-							contextStr := fmt.Sprintf("%s: %s", entry.Title, entry.Content)
-							if len(contextStr) > 2000 {
-								contextStr = contextStr[:2000] + "..."
-							}
-							if url, ok := entry.Metadata["url"].(string); ok && len(url) > 0 {
-								contextStr += fmt.Sprintf(" (Source: %s)", url)
-							}
-							ragContext = append(ragContext, contextStr)
+						// Retrieve indexed content, after the room's own
+						rv := retrieve(searchContent)
+						var roomSources []source
+						for range ragContext {
+							roomSources = append(roomSources, source{Title: room.Title, URL: room.URL})
 						}
+						rv.context = append(ragContext, rv.context...)
+						rv.sources = append(roomSources, rv.sources...)
 
 						// Supplement with web search if RAG is thin
-						if len(ragEntries) < 3 && ai.ShouldWebSearch(content) {
+						if rv.found < 3 && ai.ShouldWebSearch(content) {
 							app.Log("chat", "[WebSearch] RAG thin, searching web for: %s", searchContent)
 							if webResults, err := ai.WebSearch(searchContent); err == nil && len(webResults) > 0 {
-								ragContext = append(ragContext, ai.FormatSearchResults(webResults)...)
+								rv.addWeb(webResults)
 							}
 						}
-						app.Log("chat", "RAG: found %d results for '%s'", len(ragEntries), searchContent)
+						rv.log(searchContent)
 
 						prompt := &ai.Prompt{
 							Topic:    room.Title,
-							Rag:      ragContext,
+							Rag:      rv.context,
+							Cite:     true,
 							Context:  history,
 							Question: content,
 						}
@@ -1007,7 +1064,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, room *Room) {
 							client.LastMicroReply = time.Now()
 							llmMsg := RoomMessage{
 								UserID:    "micro",
-								Content:   resp,
+								Content:   rv.cite(resp),
 								Timestamp: time.Now(),
 								IsLLM:     true,
 							}
@@ -1038,6 +1095,7 @@ func Load() {
 
 	// Register LLM analyzer for content moderation
 	flag.SetAnalyzer(&llmAnalyzer{})
+	flag.RegisterDeleter("chat", &chatDeleter{})
 
 	// Load existing summaries from disk
 	if b, err := data.LoadFile("chat_summaries.json"); err == nil {
@@ -1056,6 +1114,11 @@ func Load() {
 		}
 	}
 
+	loadNamedRooms()
+	loadThreadSummaries()
+	loadQA()
+	data.RegisterExporter(exporter{})
+
 	// Subscribe to summary generation requests
 	summaryRequestSub := event.Subscribe(event.EventGenerateSummary)
 	go func() {
@@ -1314,7 +1377,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 // handleClearChat handles DELETE /chat - clear chat history (admin only)
 func handleClearChat(w http.ResponseWriter, r *http.Request, roomID string) {
 	// Require admin
-	_, _, err := auth.RequireAdmin(r)
+	_, acc, err := auth.RequireAdmin(r)
 	if err != nil {
 		http.Error(w, "Admin access required", http.StatusForbidden)
 		return
@@ -1331,9 +1394,9 @@ func handleClearChat(w http.ResponseWriter, r *http.Request, roomID string) {
 		roomsMutex.Unlock()
 
 		// Delete persisted messages
-		filename := "room_" + strings.ReplaceAll(roomID, "/", "_") + ".json"
-		data.DeleteFile(filename)
+		data.DeleteFile(roomFile(roomID))
 		app.Log("chat", "Admin cleared messages for room %s", roomID)
+		app.Audit(acc.ID, "chat clear", roomID, r.URL.Path)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -1365,6 +1428,10 @@ func handleGetChat(w http.ResponseWriter, r *http.Request, roomID string) {
 				roomData["summary"] = result.room.Summary
 				roomData["url"] = result.room.URL
 				roomData["isRoom"] = true
+				if _, acc := auth.TrySession(r); acc != nil {
+					roomData["viewer"] = acc.ID
+					roomData["admin"] = acc.Admin
+				}
 				app.Log("chat", "Room data loaded for: %s", roomID)
 			} else {
 				app.Log("chat", "Room is nil for: %s", roomID)
@@ -1389,6 +1456,7 @@ func handleGetChat(w http.ResponseWriter, r *http.Request, roomID string) {
 			"topics":       topicsData,
 			"summaries":    summariesData,
 			"summary_meta": summaryMetaData,
+			"rooms":        roomList(),
 		}
 		if len(roomData) > 0 {
 			response["room"] = roomData
@@ -1406,9 +1474,11 @@ func handleGetChat(w http.ResponseWriter, r *http.Request, roomID string) {
 	guestNotice := ""
 	if _, acc := auth.TrySession(r); acc == nil {
 		guestNotice = guestChatAuthNotice()
+	} else if roomID != "" {
+		guestNotice = fmt.Sprintf(`<p class="text-sm text-muted"><a href="/mail/chat/forward?room=%s">Send this conversation to mail →</a></p>`, url.QueryEscape(roomID))
 	}
 
-	tmpl := app.RenderHTMLForRequest("Chat", "Chat with AI", fmt.Sprintf(Template, topicTabs, guestNotice), r)
+	tmpl := app.RenderHTMLForRequest("Chat", "Chat with AI", fmt.Sprintf(Template, topicTabs, roomTabs(roomID), guestNotice), r)
 
 	tmpl = strings.Replace(tmpl, "</body>", fmt.Sprintf(`<script>var summaries = %s; var summaryMeta = %s; var roomData = %s;</script></body>`, summariesJSON, summaryMetaJSON, roomJSON), 1)
 
@@ -1488,6 +1558,17 @@ func handlePostChat(w http.ResponseWriter, r *http.Request) {
 
 	q := fmt.Sprintf("%v", form["prompt"])
 
+	// Slash commands are answered by whatever offers them rather than the
+	// model, and aren't charged.
+	if Command != nil {
+		if reply, replyHTML, ok := Command(sess.Account, q); ok {
+			form["answer"] = replyHTML
+			form["markdown"] = reply
+			writeAnswer(w, r, form)
+			return
+		}
+	}
+
 	// Check quota before LLM query
 	canProceed, _, cost, _ := wallet.CheckQuota(sess.Account, wallet.OpChatQuery)
 	if !canProceed {
@@ -1516,7 +1597,6 @@ func handlePostChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if this is a follow-up query with pronouns
-	var ragContext []string
 	qLower := strings.ToLower(q)
 	pronouns := []string{" him", " her", " them", " they", " it ", " this", " that", " he ", " she "}
 	isFollowUp := false
@@ -1559,35 +1639,17 @@ func handlePostChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Search the index for relevant context (RAG)
-	ragEntries := data.Search(searchQuery, 8)
-	for _, entry := range ragEntries {
-		contextStr := fmt.Sprintf("%s: %s", entry.Title, entry.Content)
-		if len(contextStr) > 2000 {
-			contextStr = contextStr[:2000] + "..."
-		}
-		if url, ok := entry.Metadata["url"].(string); ok && len(url) > 0 {
-			contextStr += fmt.Sprintf(" (Source: %s)", url)
-		}
-		ragContext = append(ragContext, contextStr)
-	}
+	rv := retrieve(searchQuery)
 
 	// If RAG results are thin and query suggests need for current info, do web search
-	if len(ragEntries) < 3 && ai.ShouldWebSearch(q) {
-		app.Log("chat", "[WebSearch] RAG thin (%d results), searching web for: %s", len(ragEntries), searchQuery)
+	if rv.found < 3 && ai.ShouldWebSearch(q) {
+		app.Log("chat", "[WebSearch] RAG thin (%d results), searching web for: %s", rv.found, searchQuery)
 		if webResults, err := ai.WebSearch(searchQuery); err == nil && len(webResults) > 0 {
-			ragContext = append(ragContext, ai.FormatSearchResults(webResults)...)
+			rv.addWeb(webResults)
 			app.Log("chat", "[WebSearch] Added %d web results", len(webResults))
 		}
 	}
-
-	// Debug: Log what we found
-	if len(ragEntries) > 0 {
-		app.Log("chat", "[RAG] Query: %s, Search: %s", q, searchQuery)
-		app.Log("chat", "[RAG] Found %d entries:", len(ragEntries))
-		for i, entry := range ragEntries {
-			app.Log("chat", "  %d. [%s] %s", i+1, entry.Type, entry.Title)
-		}
-	}
+	rv.log(searchQuery)
 
 	// Debug: Log conversation context being passed
 	app.Log("chat", "[POST] Conversation history has %d messages", len(context))
@@ -1597,7 +1659,8 @@ func handlePostChat(w http.ResponseWriter, r *http.Request) {
 
 	prompt := &ai.Prompt{
 		Topic:    topic,
-		Rag:      ragContext,
+		Rag:      rv.context,
+		Cite:     true,
 		Context:  context,
 		Question: q,
 	}
@@ -1616,10 +1679,17 @@ func handlePostChat(w http.ResponseWriter, r *http.Request) {
 	// Consume quota after successful LLM response
 	wallet.ConsumeQuota(sess.Account, wallet.OpChatQuery)
 
-	// save the response
+	// save the response, with the sources it cites
+	resp = rv.cite(resp)
 	html := app.Render([]byte(resp))
 	form["answer"] = string(html)
+	form["markdown"] = resp
+	writeAnswer(w, r, form)
+}
 
+// writeAnswer responds to a chat POST with the prompt and its answer, as
+// JSON or as the chat page.
+func writeAnswer(w http.ResponseWriter, r *http.Request, form map[string]interface{}) {
 	// if JSON request then respond with json
 	if app.SendsJSON(r) {
 		app.RespondJSON(w, form)
@@ -1634,7 +1704,7 @@ func handlePostChat(w http.ResponseWriter, r *http.Request) {
 	topicTabs := app.Head("chat", topics)
 	mutex.RUnlock()
 
-	output := fmt.Sprintf(Template, topicTabs)
+	output := fmt.Sprintf(Template, topicTabs, roomTabs(""), "")
 	renderHTML := app.RenderHTMLForRequest("Chat", "Chat with AI", output, r)
 	renderHTML = strings.Replace(renderHTML, `<div id="messages"></div>`, fmt.Sprintf(`<div id="messages">%s</div>`, messages), 1)
 
@@ -1,8 +1,12 @@
 package chat
 
 import (
+	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"mu/internal/auth"
 )
 
 func TestHandlePatternMatchRecognizesKnownPricePromptsWithoutData(t *testing.T) {
@@ -84,3 +88,67 @@ func TestCurrentSummaryMetaDefaultsUnavailable(t *testing.T) {
 		t.Fatalf("Source should explain summary provenance")
 	}
 }
+
+func TestPendingMessagesAfterSummary(t *testing.T) {
+	now := time.Now()
+	history := []RoomMessage{
+		{UserID: "alice", Content: "one", Timestamp: now.Add(-3 * time.Minute)},
+		{UserID: "bob", Content: "two", Timestamp: now.Add(-2 * time.Minute)},
+		{UserID: "alice", Content: "three", Timestamp: now.Add(-time.Minute)},
+	}
+	if got := pendingMessages(history, nil); len(got) != 3 {
+		t.Fatalf("no summary: pending = %d, want 3", len(got))
+	}
+	s := &ThreadSummary{LastMessage: history[1].Timestamp, Covered: 2}
+	got := pendingMessages(history, s)
+	if len(got) != 1 || got[0].Content != "three" {
+		t.Fatalf("pending = %+v, want only the message after the summary", got)
+	}
+}
+
+func TestQAEntryLifecycle(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	qaMu.Lock()
+	qaEntries = nil
+	qaMu.Unlock()
+
+	if got := parseQATags("Go, #go, prayer times, salah,a,b,c,d"); strings.Join(got, ",") != "go,salah,a,b,c" {
+		t.Fatalf("parseQATags = %v", got)
+	}
+
+	alice := &auth.Account{ID: "alice", Name: "Alice"}
+	bob := &auth.Account{ID: "bob", Name: "Bob"}
+	admin := &auth.Account{ID: "root", Name: "Root", Admin: true}
+
+	if _, err := CreateQA(alice, "", "q", "a", nil, ""); err != ErrQAInvalid {
+		t.Fatalf("empty title: err = %v", err)
+	}
+	e, err := CreateQA(alice, "Fajr time", "When is fajr?", "At dawn.", []string{"salah"}, "Islam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ListQA("salah", "DAWN"); len(got) != 1 || got[0].ID != e.ID {
+		t.Fatalf("ListQA = %v", got)
+	}
+	if got := ListQA("news", ""); len(got) != 0 {
+		t.Fatalf("tag filter returned %v", got)
+	}
+
+	// Only the author or an admin may edit.
+	if _, err := UpdateQA(bob, e.ID, "x", "y", "z", nil); err != ErrQANotFound {
+		t.Fatalf("bob edit: err = %v", err)
+	}
+	updated, err := UpdateQA(admin, e.ID, "Fajr prayer time", "When is fajr?", "At true dawn.", []string{"salah"})
+	if err != nil || updated.EditedBy != "root" || updated.Author != "Alice" {
+		t.Fatalf("admin edit = %+v, %v", updated, err)
+	}
+
+	// Entries survive a restart.
+	loadQA()
+	if got := GetQA(e.ID); got == nil || got.Title != "Fajr prayer time" {
+		t.Fatalf("reloaded = %+v", got)
+	}
+	if err := DeleteQA(e.ID); err != nil || GetQA(e.ID) != nil {
+		t.Fatalf("delete: %v", err)
+	}
+}
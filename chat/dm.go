@@ -0,0 +1,445 @@
+package chat
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Direct messages are one-to-one conversations, quicker than mail. Each
+// conversation is kept in dm_<a>-<b>.json, the two account IDs sorted, and
+// dm_index.json holds everyone's conversations with their unread counts.
+// A message reaches the other person straight away if they have /chat/dm
+// open; if not, OnDirectMessage lets main.go notify them another way.
+// Whether someone may be messaged is up to them, through CanMessage.
+
+// DirectMessage is one message between two people.
+type DirectMessage struct {
+	ID      string    `json:"id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Content string    `json:"content"`
+	Sent    time.Time `json:"sent"`
+}
+
+// Conversation is one person's view of a conversation: who with, its last
+// message and how many they haven't read.
+type Conversation struct {
+	With    string    `json:"with"`
+	Last    time.Time `json:"last"`
+	Preview string    `json:"preview"`
+	Unread  int       `json:"unread"`
+}
+
+const (
+	dmIndexKey = "dm_index.json"
+	// dmHistory is how many messages a conversation keeps.
+	dmHistory = 500
+	// dmMaxLength caps a message, in characters.
+	dmMaxLength = 4000
+)
+
+// CanMessage reports whether from may send to a direct message. Wired in
+// main.go to to's privacy settings; when nil anyone signed in can.
+var CanMessage func(from, to string) bool
+
+// OnDirectMessage is called after each direct message is sent, with
+// whether it reached the recipient live. Wired in main.go to push a
+// notification when it didn't.
+var OnDirectMessage func(msg DirectMessage, delivered bool)
+
+var (
+	dmMutex sync.Mutex
+	dmIndex map[string]map[string]*Conversation // account → peer → conversation, nil until loaded
+
+	dmConnsMutex sync.Mutex
+	dmConns      = map[string]map[*websocket.Conn]*sync.Mutex{} // account → open /chat/dm sockets
+)
+
+// dmKey is where the conversation between a and b is kept.
+func dmKey(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return "dm_" + a + "-" + b + ".json"
+}
+
+// loadDMIndex reads the index, once. The caller holds dmMutex.
+func loadDMIndex() {
+	if dmIndex != nil {
+		return
+	}
+	dmIndex = map[string]map[string]*Conversation{}
+	data.LoadJSON(dmIndexKey, &dmIndex)
+}
+
+// conversation returns owner's conversation with peer, creating it. The
+// caller holds dmMutex.
+func conversation(owner, peer string) *Conversation {
+	if dmIndex[owner] == nil {
+		dmIndex[owner] = map[string]*Conversation{}
+	}
+	c := dmIndex[owner][peer]
+	if c == nil {
+		c = &Conversation{With: peer}
+		dmIndex[owner][peer] = c
+	}
+	return c
+}
+
+// SendDirect sends a direct message from one person to another.
+func SendDirect(from, to, content string) (DirectMessage, error) {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "":
+		return DirectMessage{}, fmt.Errorf("message is empty")
+	case len([]rune(content)) > dmMaxLength:
+		return DirectMessage{}, fmt.Errorf("messages are limited to %d characters", dmMaxLength)
+	case from == to:
+		return DirectMessage{}, fmt.Errorf("you can't message yourself")
+	}
+	if _, err := auth.GetAccount(to); err != nil {
+		return DirectMessage{}, fmt.Errorf("no such user")
+	}
+	if CanMessage != nil && !CanMessage(from, to) {
+		return DirectMessage{}, fmt.Errorf("@%s doesn't accept messages from you", to)
+	}
+
+	msg := DirectMessage{
+		ID:      fmt.Sprintf("%d", time.Now().UnixNano()),
+		From:    from,
+		To:      to,
+		Content: content,
+		Sent:    time.Now(),
+	}
+
+	dmMutex.Lock()
+	var thread []DirectMessage
+	data.LoadJSON(dmKey(from, to), &thread)
+	thread = append(thread, msg)
+	if len(thread) > dmHistory {
+		thread = thread[len(thread)-dmHistory:]
+	}
+	if err := data.SaveJSON(dmKey(from, to), thread); err != nil {
+		dmMutex.Unlock()
+		return DirectMessage{}, err
+	}
+	loadDMIndex()
+	preview := dmPreview(content)
+	sent := conversation(from, to)
+	sent.Last, sent.Preview, sent.Unread = msg.Sent, preview, 0
+	got := conversation(to, from)
+	got.Last, got.Preview = msg.Sent, preview
+	got.Unread++
+	data.SaveJSON(dmIndexKey, dmIndex)
+	dmMutex.Unlock()
+
+	delivered := deliverDirect(to, msg)
+	deliverDirect(from, msg) // the sender's other tabs
+	if OnDirectMessage != nil {
+		go OnDirectMessage(msg, delivered)
+	}
+	return msg, nil
+}
+
+// dmPreview is a message's first line, cut to fit a conversation list.
+func dmPreview(content string) string {
+	line, _, _ := strings.Cut(content, "\n")
+	if r := []rune(line); len(r) > 80 {
+		return string(r[:77]) + "..."
+	}
+	return line
+}
+
+// Thread returns the messages between a and b, oldest first.
+func Thread(a, b string) []DirectMessage {
+	dmMutex.Lock()
+	defer dmMutex.Unlock()
+	var thread []DirectMessage
+	data.LoadJSON(dmKey(a, b), &thread)
+	return thread
+}
+
+// MarkRead marks owner's conversation with peer read.
+func MarkRead(owner, peer string) {
+	dmMutex.Lock()
+	defer dmMutex.Unlock()
+	loadDMIndex()
+	if c := dmIndex[owner][peer]; c != nil && c.Unread > 0 {
+		c.Unread = 0
+		data.SaveJSON(dmIndexKey, dmIndex)
+	}
+}
+
+// Conversations returns owner's conversations, latest first.
+func Conversations(owner string) []Conversation {
+	dmMutex.Lock()
+	defer dmMutex.Unlock()
+	loadDMIndex()
+	list := make([]Conversation, 0, len(dmIndex[owner]))
+	for _, c := range dmIndex[owner] {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Last.After(list[j].Last) })
+	return list
+}
+
+// UnreadDirect returns how many direct messages owner hasn't read.
+func UnreadDirect(owner string) int {
+	dmMutex.Lock()
+	defer dmMutex.Unlock()
+	loadDMIndex()
+	n := 0
+	for _, c := range dmIndex[owner] {
+		n += c.Unread
+	}
+	return n
+}
+
+// DeleteDirectMessages removes a user's conversations, for both sides.
+// Used on account deletion.
+func DeleteDirectMessages(userID string) {
+	dmMutex.Lock()
+	defer dmMutex.Unlock()
+	loadDMIndex()
+	for peer := range dmIndex[userID] {
+		data.DeleteFile(dmKey(userID, peer))
+		delete(dmIndex[peer], userID)
+		if len(dmIndex[peer]) == 0 {
+			delete(dmIndex, peer)
+		}
+	}
+	delete(dmIndex, userID)
+	data.SaveJSON(dmIndexKey, dmIndex)
+}
+
+// RenameDirectMessages moves a renamed user's conversations to their new
+// ID.
+func RenameDirectMessages(oldID, newID string) {
+	dmMutex.Lock()
+	defer dmMutex.Unlock()
+	loadDMIndex()
+	mine, ok := dmIndex[oldID]
+	if !ok {
+		return
+	}
+	for peer := range mine {
+		var thread []DirectMessage
+		data.LoadJSON(dmKey(oldID, peer), &thread)
+		for i := range thread {
+			if thread[i].From == oldID {
+				thread[i].From = newID
+			}
+			if thread[i].To == oldID {
+				thread[i].To = newID
+			}
+		}
+		data.SaveJSON(dmKey(newID, peer), thread)
+		data.DeleteFile(dmKey(oldID, peer))
+		if theirs := dmIndex[peer][oldID]; theirs != nil {
+			delete(dmIndex[peer], oldID)
+			theirs.With = newID
+			dmIndex[peer][newID] = theirs
+		}
+	}
+	delete(dmIndex, oldID)
+	dmIndex[newID] = mine
+	data.SaveJSON(dmIndexKey, dmIndex)
+}
+
+// deliverDirect writes msg to each of the account's open /chat/dm
+// sockets, reporting whether there were any.
+func deliverDirect(accountID string, msg DirectMessage) bool {
+	dmConnsMutex.Lock()
+	conns := make(map[*websocket.Conn]*sync.Mutex, len(dmConns[accountID]))
+	for c, mu := range dmConns[accountID] {
+		conns[c] = mu
+	}
+	dmConnsMutex.Unlock()
+
+	delivered := false
+	for c, mu := range conns {
+		mu.Lock()
+		c.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		err := c.WriteJSON(msg)
+		mu.Unlock()
+		if err == nil {
+			delivered = true
+		}
+	}
+	return delivered
+}
+
+// handleDMSocket keeps a socket open to deliver the account's direct
+// messages as they arrive. The page sends {"read": peer} when it shows a
+// conversation's new messages.
+func handleDMSocket(w http.ResponseWriter, r *http.Request, accountID string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		app.Log("chat", "DM WebSocket upgrade error: %v", err)
+		return
+	}
+	dmConnsMutex.Lock()
+	if dmConns[accountID] == nil {
+		dmConns[accountID] = map[*websocket.Conn]*sync.Mutex{}
+	}
+	dmConns[accountID][conn] = &sync.Mutex{}
+	dmConnsMutex.Unlock()
+	auth.UpdatePresence(accountID)
+
+	defer func() {
+		dmConnsMutex.Lock()
+		delete(dmConns[accountID], conn)
+		if len(dmConns[accountID]) == 0 {
+			delete(dmConns, accountID)
+		}
+		dmConnsMutex.Unlock()
+		conn.Close()
+	}()
+	for {
+		var msg struct {
+			Read string `json:"read"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Read != "" {
+			MarkRead(accountID, msg.Read)
+		}
+		auth.UpdatePresence(accountID)
+	}
+}
+
+// DMHandler serves /chat/dm. GET lists the caller's conversations, or
+// shows one with ?with=, marking it read; ?unread=count returns the unread
+// total for the header badge. POST sends a message (to and content).
+// Opened as a WebSocket it delivers messages as they arrive.
+func DMHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	if r.Header.Get("Upgrade") == "websocket" {
+		handleDMSocket(w, r, acc.ID)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if r.URL.Query().Get("unread") == "count" {
+			app.RespondJSON(w, map[string]int{"count": UnreadDirect(acc.ID)})
+			return
+		}
+		if with := strings.TrimPrefix(r.URL.Query().Get("with"), "@"); with != "" {
+			handleDMThread(w, r, acc.ID, with)
+			return
+		}
+		handleDMList(w, r, acc.ID)
+	case "POST":
+		var req struct {
+			To      string `json:"to"`
+			Content string `json:"content"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.To = r.FormValue("to")
+			req.Content = r.FormValue("content")
+		}
+		req.To = strings.TrimPrefix(strings.TrimSpace(req.To), "@")
+		if err := auth.CheckPostRate(acc.ID); err != nil {
+			app.Error(w, r, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		msg, err := SendDirect(acc.ID, req.To, req.Content)
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, msg)
+			return
+		}
+		http.Redirect(w, r, "/chat/dm?with="+req.To, http.StatusSeeOther)
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+func handleDMList(w http.ResponseWriter, r *http.Request, accountID string) {
+	list := Conversations(accountID)
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"conversations": list})
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString(`<form action="/chat/dm" method="GET" class="block-form"><input type="text" name="with" placeholder="Username, e.g. alice" required><button type="submit">Message</button></form>`)
+	if len(list) == 0 {
+		content.WriteString(`<p class="text-muted">No messages yet. Message someone from their profile, or by username above.</p>`)
+	}
+	for _, c := range list {
+		name := htmlpkg.EscapeString(c.With)
+		unread := ""
+		if c.Unread > 0 {
+			unread = fmt.Sprintf(` <span class="count">%d</span>`, c.Unread)
+		}
+		content.WriteString(fmt.Sprintf(`<div class="card"><a href="/chat/dm?with=%s"><strong>@%s</strong></a>%s <span class="text-muted text-sm">%s</span><p class="text-sm">%s</p></div>`,
+			name, name, unread, app.TimeAgo(c.Last), htmlpkg.EscapeString(c.Preview)))
+	}
+	w.Write([]byte(app.RenderHTMLForRequest("Messages", "Direct messages", content.String(), r)))
+}
+
+func handleDMThread(w http.ResponseWriter, r *http.Request, accountID, with string) {
+	if _, err := auth.GetAccount(with); err != nil {
+		app.NotFound(w, r, "No such user")
+		return
+	}
+	thread := Thread(accountID, with)
+	MarkRead(accountID, with)
+	allowed := CanMessage == nil || CanMessage(accountID, with)
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{
+			"with":        with,
+			"online":      auth.IsOnline(with),
+			"can_message": allowed,
+			"messages":    thread,
+		})
+		return
+	}
+
+	name := htmlpkg.EscapeString(with)
+	var content strings.Builder
+	status := "offline"
+	if auth.IsOnline(with) {
+		status = "online"
+	}
+	content.WriteString(fmt.Sprintf(`<p><a href="/chat/dm">← Messages</a> · <a href="/@%s">@%s</a> <span class="text-muted text-sm">%s</span></p>`, name, name, status))
+	content.WriteString(fmt.Sprintf(`<div id="dm-thread" data-with="%s" data-me="%s">`, name, htmlpkg.EscapeString(accountID)))
+	for _, m := range thread {
+		content.WriteString(fmt.Sprintf(`<div class="message"><span class="you"><a href="/@%s">%s</a></span> %s <span class="text-muted text-sm">%s</span></div>`,
+			htmlpkg.EscapeString(m.From), htmlpkg.EscapeString(m.From), htmlpkg.EscapeString(m.Content), app.TimeAgo(m.Sent)))
+	}
+	content.WriteString(`</div>`)
+	if allowed {
+		content.WriteString(fmt.Sprintf(`<form id="dm-form" action="/chat/dm" method="POST" class="block-form"><input type="hidden" name="to" value="%s"><input type="text" name="content" placeholder="Message @%s" autocomplete="off" maxlength="%d" required><button type="submit">Send</button></form>`,
+			name, name, dmMaxLength))
+	} else {
+		content.WriteString(fmt.Sprintf(`<p class="text-muted">@%s only accepts messages from people they allow in their privacy settings.</p>`, name))
+	}
+	w.Write([]byte(app.RenderHTMLForRequest("@"+with, "Messages with @"+with, content.String(), r)))
+}
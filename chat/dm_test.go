@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestDirectMessages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dmMutex.Lock()
+	dmIndex = nil
+	dmMutex.Unlock()
+	for _, id := range []string{"dmalice", "dmbobby", "dmcarol"} {
+		if err := auth.Create(&auth.Account{ID: id, Name: id, Secret: "secret", Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notified := make(chan bool, 4)
+	OnDirectMessage = func(msg DirectMessage, delivered bool) { notified <- delivered }
+	CanMessage = func(from, to string) bool { return from != "dmcarol" }
+	t.Cleanup(func() { OnDirectMessage, CanMessage = nil, nil })
+
+	if _, err := SendDirect("dmalice", "dmbobby", "hi bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SendDirect("dmalice", "dmbobby", "are you there?"); err != nil {
+		t.Fatal(err)
+	}
+	if delivered := <-notified; delivered {
+		t.Error("delivered live with no socket open")
+	}
+	if n := UnreadDirect("dmbobby"); n != 2 {
+		t.Errorf("bob has %d unread, want 2", n)
+	}
+	if n := UnreadDirect("dmalice"); n != 0 {
+		t.Errorf("alice has %d unread of their own messages", n)
+	}
+	if got := Thread("dmbobby", "dmalice"); len(got) != 2 || got[1].Content != "are you there?" {
+		t.Errorf("thread = %+v", got)
+	}
+	MarkRead("dmbobby", "dmalice")
+	if n := UnreadDirect("dmbobby"); n != 0 {
+		t.Errorf("bob has %d unread after reading", n)
+	}
+
+	if _, err := SendDirect("dmcarol", "dmbobby", "hello"); err == nil {
+		t.Error("sent a message the recipient doesn't allow")
+	}
+	if _, err := SendDirect("dmalice", "nobody", "hello"); err == nil {
+		t.Error("sent a message to no one")
+	}
+
+	RenameDirectMessages("dmalice", "dmalicia")
+	if list := Conversations("dmbobby"); len(list) != 1 || list[0].With != "dmalicia" {
+		t.Errorf("bob's conversations after the rename = %+v", list)
+	}
+	if got := Thread("dmalicia", "dmbobby"); len(got) != 2 || got[0].From != "dmalicia" {
+		t.Errorf("renamed thread = %+v", got)
+	}
+	DeleteDirectMessages("dmalicia")
+	if list := Conversations("dmbobby"); len(list) != 0 {
+		t.Errorf("conversations left after deletion: %+v", list)
+	}
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// exporter exports a user's Q&A entries and their messages in the chat
+// rooms currently held in memory.
+type exporter struct{}
+
+func (exporter) Name() string        { return "chat" }
+func (exporter) Description() string { return "Your Q&A entries and recent room messages" }
+func (exporter) Formats() []string   { return []string{"json"} }
+
+type exportedRoomMessage struct {
+	Room      string    `json:"room"`
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (exporter) Export(w io.Writer, userID, format string) error {
+	out := struct {
+		QA       []*QAEntry            `json:"qa"`
+		Messages []exportedRoomMessage `json:"room_messages"`
+	}{QA: []*QAEntry{}, Messages: []exportedRoomMessage{}}
+
+	qaMu.RLock()
+	for _, e := range qaEntries {
+		if e.AuthorID == userID {
+			out.QA = append(out.QA, e)
+		}
+	}
+	qaMu.RUnlock()
+
+	roomsMutex.RLock()
+	list := make([]*Room, 0, len(rooms))
+	for _, room := range rooms {
+		list = append(list, room)
+	}
+	roomsMutex.RUnlock()
+
+	for _, room := range list {
+		room.mutex.RLock()
+		for _, m := range room.Messages {
+			if m.UserID == userID && !m.IsLLM {
+				out.Messages = append(out.Messages, exportedRoomMessage{Room: room.ID, Title: room.Title, Content: m.Content, Timestamp: m.Timestamp})
+			}
+		}
+		room.mutex.RUnlock()
+	}
+
+	return json.NewEncoder(w).Encode(out)
+}
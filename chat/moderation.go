@@ -0,0 +1,370 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/flag"
+)
+
+// People can edit their own room messages for editWindow after sending
+// them, and delete them whenever they like. Admins can delete anyone's,
+// which goes in the moderation log on /admin/rooms. Each message is run
+// past flag.CheckContent as it's sent; those it flags are hidden from the
+// room until an admin approves or deletes them at /admin/moderate.
+
+const (
+	// editWindow is how long after sending a message it can be edited.
+	editWindow = 15 * time.Minute
+	// moderationLogKey is where admin actions on messages are kept.
+	moderationLogKey = "chat_moderation.json"
+	// moderationLogSize is how many actions the log keeps.
+	moderationLogSize = 500
+)
+
+// ModerationEntry records an admin deleting someone else's message.
+type ModerationEntry struct {
+	Time      time.Time `json:"time"`
+	By        string    `json:"by"`
+	Room      string    `json:"room"`
+	MessageID string    `json:"message_id"`
+	Author    string    `json:"author"`
+	Content   string    `json:"content"`
+}
+
+// messageChange edits, removes or hides a message in a live room. It goes
+// through the room's run loop, which owns the connections.
+type messageChange struct {
+	ID      string
+	Content string // the new content, or empty to remove the message
+	Hide    bool   // only tell the clients to drop it
+	done    chan error
+}
+
+var (
+	moderationMutex sync.Mutex
+	moderationLog   []ModerationEntry
+	moderationOnce  sync.Once
+)
+
+// newMessageID returns an ID for a room message.
+func newMessageID() string {
+	return fmt.Sprintf("%d", time.Now().UnixNano())
+}
+
+// messageRef is a message's ID for the moderation queue: the room and
+// message IDs joined by "#", so /chat?id= on it opens the room.
+func messageRef(roomID, msgID string) string {
+	return roomID + "#" + msgID
+}
+
+// splitMessageRef undoes messageRef.
+func splitMessageRef(ref string) (roomID, msgID string, ok bool) {
+	i := strings.LastIndex(ref, "#")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", false
+	}
+	return ref[:i], ref[i+1:], true
+}
+
+// hidden reports whether the moderator has hidden a message.
+func (m RoomMessage) hidden(roomID string) bool {
+	return m.ID != "" && flag.IsHidden("chat", messageRef(roomID, m.ID))
+}
+
+// visibleMessages drops the hidden messages from a room's history.
+func visibleMessages(roomID string, msgs []RoomMessage) []RoomMessage {
+	out := make([]RoomMessage, 0, len(msgs))
+	for _, m := range msgs {
+		if !m.hidden(roomID) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// findMessage returns a message from a room's history.
+func findMessage(roomID, msgID string) (RoomMessage, bool) {
+	for _, m := range roomHistory(roomID) {
+		if m.ID == msgID {
+			return m, true
+		}
+	}
+	return RoomMessage{}, false
+}
+
+// EditMessage replaces the content of one of userID's messages.
+func EditMessage(roomID, msgID, userID, content string) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return fmt.Errorf("message is empty")
+	}
+	m, ok := findMessage(roomID, msgID)
+	if !ok {
+		return fmt.Errorf("no such message")
+	}
+	if m.IsLLM || m.UserID != userID {
+		return fmt.Errorf("you can only edit your own messages")
+	}
+	if time.Since(m.Timestamp) > editWindow {
+		return fmt.Errorf("messages can only be edited for %d minutes", int(editWindow.Minutes()))
+	}
+	if err := changeMessage(roomID, messageChange{ID: msgID, Content: content}); err != nil {
+		return err
+	}
+	go flag.CheckContent("chat", messageRef(roomID, msgID), "", content)
+	return nil
+}
+
+// DeleteMessage removes a message. People can delete their own, admins
+// anyone's; an admin deleting someone else's is logged.
+func DeleteMessage(roomID, msgID string, acc *auth.Account) error {
+	m, ok := findMessage(roomID, msgID)
+	if !ok {
+		return fmt.Errorf("no such message")
+	}
+	own := !m.IsLLM && m.UserID == acc.ID
+	if !own && !acc.Admin {
+		return fmt.Errorf("you can only delete your own messages")
+	}
+	if err := changeMessage(roomID, messageChange{ID: msgID}); err != nil {
+		return err
+	}
+	if !own {
+		logModeration(acc.ID, roomID, m)
+	}
+	return nil
+}
+
+// changeMessage applies a change to a room's history, through the room's
+// run loop if it's open or on disk if not.
+func changeMessage(roomID string, change messageChange) error {
+	roomsMutex.RLock()
+	room := rooms[roomID]
+	if room == nil {
+		defer roomsMutex.RUnlock()
+		msgs := loadRoomMessages(roomID)
+		msgs, err := applyChange(msgs, change)
+		if err != nil {
+			return err
+		}
+		saveRoomMessages(roomID, msgs)
+		return nil
+	}
+	roomsMutex.RUnlock()
+
+	change.done = make(chan error, 1)
+	select {
+	case room.Changes <- change:
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("room is busy, try again")
+	}
+	return <-change.done
+}
+
+// applyChange returns msgs with change made.
+func applyChange(msgs []RoomMessage, change messageChange) ([]RoomMessage, error) {
+	for i, m := range msgs {
+		if m.ID != change.ID {
+			continue
+		}
+		if change.Hide {
+			return msgs, nil
+		}
+		if change.Content == "" {
+			return append(msgs[:i:i], msgs[i+1:]...), nil
+		}
+		msgs[i].Content = change.Content
+		msgs[i].Edited = true
+		return msgs, nil
+	}
+	return msgs, fmt.Errorf("no such message")
+}
+
+// applyChange makes a change in the room and tells its clients. Called
+// from run.
+func (room *Room) applyChange(change messageChange) {
+	room.mutex.Lock()
+	msgs, err := applyChange(room.Messages, change)
+	if err != nil {
+		room.mutex.Unlock()
+		change.done <- err
+		return
+	}
+	room.Messages = msgs
+	toSave := append([]RoomMessage(nil), msgs...)
+	room.mutex.Unlock()
+
+	if !change.Hide && (strings.HasPrefix(room.ID, "chat_") || isNamedRoom(room.ID)) {
+		saveRoomMessages(room.ID, toSave)
+	}
+	event := map[string]interface{}{"type": "delete", "id": change.ID}
+	if !change.Hide && change.Content != "" {
+		event = map[string]interface{}{"type": "edit", "id": change.ID, "content": change.Content}
+	}
+	room.mutex.RLock()
+	for conn := range room.Clients {
+		conn.WriteJSON(event)
+	}
+	room.mutex.RUnlock()
+	change.done <- nil
+}
+
+// loadModerationLog reads the log, once. The caller holds moderationMutex.
+func loadModerationLog() {
+	moderationOnce.Do(func() {
+		data.LoadJSON(moderationLogKey, &moderationLog)
+	})
+}
+
+// logModeration records an admin deleting someone's message.
+func logModeration(by, roomID string, m RoomMessage) {
+	app.Log("chat", "%s deleted message %s by %s in %s", by, m.ID, m.UserID, roomID)
+	app.Audit(by, "chat delete_message", m.ID, "")
+	moderationMutex.Lock()
+	defer moderationMutex.Unlock()
+	loadModerationLog()
+	moderationLog = append(moderationLog, ModerationEntry{
+		Time:      time.Now(),
+		By:        by,
+		Room:      roomID,
+		MessageID: m.ID,
+		Author:    m.UserID,
+		Content:   m.Content,
+	})
+	if len(moderationLog) > moderationLogSize {
+		moderationLog = moderationLog[len(moderationLog)-moderationLogSize:]
+	}
+	data.SaveJSON(moderationLogKey, moderationLog)
+}
+
+// ModerationLog returns the logged actions, newest first.
+func ModerationLog() []ModerationEntry {
+	moderationMutex.Lock()
+	defer moderationMutex.Unlock()
+	loadModerationLog()
+	out := make([]ModerationEntry, len(moderationLog))
+	for i, e := range moderationLog {
+		out[len(out)-1-i] = e
+	}
+	return out
+}
+
+// MessageHandler serves /chat/message: POST with room, id and action
+// "edit" (with content) or "delete".
+func MessageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	var req struct {
+		Room    string `json:"room"`
+		ID      string `json:"id"`
+		Action  string `json:"action"`
+		Content string `json:"content"`
+	}
+	if app.SendsJSON(r) {
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Room = r.FormValue("room")
+		req.ID = r.FormValue("id")
+		req.Action = r.FormValue("action")
+		req.Content = r.FormValue("content")
+	}
+	switch req.Action {
+	case "edit":
+		err = EditMessage(req.Room, req.ID, acc.ID, req.Content)
+	case "delete":
+		err = DeleteMessage(req.Room, req.ID, acc)
+	default:
+		err = fmt.Errorf("unknown action")
+	}
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return
+	}
+	if app.SendsJSON(r) || app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"success": true})
+		return
+	}
+	http.Redirect(w, r, "/chat?id="+req.Room, http.StatusSeeOther)
+}
+
+// chatDeleter implements flag.ContentDeleter for room messages, by their
+// messageRef.
+type chatDeleter struct{}
+
+func (d *chatDeleter) Delete(ref string) error {
+	roomID, msgID, ok := splitMessageRef(ref)
+	if !ok {
+		return fmt.Errorf("bad message reference")
+	}
+	m, found := findMessage(roomID, msgID)
+	if !found {
+		return nil
+	}
+	if err := changeMessage(roomID, messageChange{ID: msgID}); err != nil {
+		return err
+	}
+	logModeration("moderation queue", roomID, m)
+	return nil
+}
+
+func (d *chatDeleter) Get(ref string) interface{} {
+	roomID, msgID, ok := splitMessageRef(ref)
+	if !ok {
+		return nil
+	}
+	m, found := findMessage(roomID, msgID)
+	if !found {
+		return nil
+	}
+	return flag.PostContent{
+		ID:        ref,
+		Title:     "Message in " + roomID,
+		Content:   m.Content,
+		Author:    m.UserID,
+		AuthorID:  m.UserID,
+		CreatedAt: m.Timestamp,
+		URL:       "/chat?id=" + roomID,
+	}
+}
+
+// RefreshCache drops newly hidden messages from the open rooms.
+func (d *chatDeleter) RefreshCache() {
+	roomsMutex.RLock()
+	list := make([]*Room, 0, len(rooms))
+	for _, room := range rooms {
+		list = append(list, room)
+	}
+	roomsMutex.RUnlock()
+
+	for _, room := range list {
+		room.mutex.RLock()
+		var hide []RoomMessage
+		for _, m := range room.Messages {
+			if m.hidden(room.ID) {
+				hide = append(hide, m)
+			}
+		}
+		room.mutex.RUnlock()
+		for _, m := range hide {
+			changeMessage(room.ID, messageChange{ID: m.ID, Hide: true})
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/flag"
+)
+
+func TestEditAndDeleteMessages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Now()
+	saveRoomMessages("room_general", []RoomMessage{
+		{ID: "1", UserID: "alice", Content: "helo", Timestamp: now},
+		{ID: "2", UserID: "bob", Content: "hi", Timestamp: now},
+		{ID: "3", UserID: "alice", Content: "old news", Timestamp: now.Add(-time.Hour)},
+		{ID: "4", UserID: "bob", Content: "buy my stuff", Timestamp: now},
+	})
+
+	if err := EditMessage("room_general", "1", "alice", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := findMessage("room_general", "1"); m.Content != "hello" || !m.Edited {
+		t.Errorf("edited message = %+v", m)
+	}
+	if err := EditMessage("room_general", "2", "alice", "hacked"); err == nil {
+		t.Error("edited someone else's message")
+	}
+	if err := EditMessage("room_general", "3", "alice", "new news"); err == nil {
+		t.Error("edited a message after the window")
+	}
+
+	alice, admin := &auth.Account{ID: "alice"}, &auth.Account{ID: "root", Admin: true}
+	if err := DeleteMessage("room_general", "2", alice); err == nil {
+		t.Error("deleted someone else's message")
+	}
+	if err := DeleteMessage("room_general", "3", alice); err != nil {
+		t.Fatal(err)
+	}
+	if len(ModerationLog()) != 0 {
+		t.Error("logged someone deleting their own message")
+	}
+	if err := DeleteMessage("room_general", "2", admin); err != nil {
+		t.Fatal(err)
+	}
+	if log := ModerationLog(); len(log) != 1 || log[0].By != "root" || log[0].Author != "bob" || log[0].Content != "hi" {
+		t.Errorf("moderation log = %+v", log)
+	}
+
+	// Flagged messages are hidden until approved.
+	ref := messageRef("room_general", "4")
+	flag.AdminFlag("chat", ref, "system:spam")
+	t.Cleanup(func() { flag.Approve("chat", ref) })
+	if _, msgs := RoomTranscript("room_general"); len(msgs) != 1 || msgs[0].ID != "1" {
+		t.Errorf("transcript with a hidden message = %+v", msgs)
+	}
+	if post, ok := (&chatDeleter{}).Get(ref).(flag.PostContent); !ok || post.Author != "bob" {
+		t.Errorf("moderation queue sees %+v", post)
+	}
+	flag.Approve("chat", ref)
+	if _, msgs := RoomTranscript("room_general"); len(msgs) != 2 {
+		t.Errorf("%d messages after approving, want 2", len(msgs))
+	}
+}
@@ -0,0 +1,594 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/flag"
+)
+
+// The Q&A knowledge base at /qa collects answers members found useful:
+// an AI reply or a chat exchange is promoted into an entry with a title,
+// an edited question and answer, and tags. Entries are public, indexed for
+// search, and picked up by chat's RAG lookups like any other content, so
+// the community stops re-asking the same things.
+
+const (
+	qaMaxTitle  = 200
+	qaMaxBody   = 20000
+	qaMaxTags   = 5
+	qaIndexType = "qa"
+)
+
+// QAEntry is one curated question and answer.
+type QAEntry struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Question  string    `json:"question"`
+	Answer    string    `json:"answer"` // markdown
+	Tags      []string  `json:"tags,omitempty"`
+	Source    string    `json:"source,omitempty"` // chat topic or room it came from
+	AuthorID  string    `json:"author_id"`
+	Author    string    `json:"author"`
+	EditedBy  string    `json:"edited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	qaMu      sync.RWMutex
+	qaEntries []*QAEntry // newest first
+
+	ErrQAInvalid  = errors.New("a title, question and answer are required")
+	ErrQANotFound = errors.New("entry not found")
+)
+
+var qaTagRe = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func loadQA() {
+	data.RegisterTable("qa.json")
+	var stored []*QAEntry
+	if err := data.LoadJSON("qa.json", &stored); err == nil {
+		qaMu.Lock()
+		qaEntries = stored
+		qaMu.Unlock()
+	}
+	flag.RegisterDeleter("qa", &qaDeleter{})
+	data.RegisterDeleter("qa", DeleteQA)
+}
+
+// saveQA persists the entries. Caller must hold qaMu.
+func saveQA() error {
+	return data.SaveJSON("qa.json", qaEntries)
+}
+
+func indexQA(e *QAEntry) {
+	data.Index(
+		"qa_"+e.ID,
+		qaIndexType,
+		e.Title,
+		e.Question+"\n\n"+e.Answer,
+		map[string]interface{}{
+			"url":       "/qa?id=" + e.ID,
+			"tags":      e.Tags,
+			"author":    e.Author,
+			"posted_at": e.CreatedAt,
+		},
+	)
+}
+
+// parseQATags normalises a comma-separated tag list.
+func parseQATags(s string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, t := range strings.Split(s, ",") {
+		t = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(t), "#")))
+		if t == "" || !qaTagRe.MatchString(t) || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+		if len(tags) == qaMaxTags {
+			break
+		}
+	}
+	return tags
+}
+
+func validQA(title, question, answer string) error {
+	if title == "" || question == "" || answer == "" {
+		return ErrQAInvalid
+	}
+	if len(title) > qaMaxTitle {
+		return fmt.Errorf("title must be at most %d characters", qaMaxTitle)
+	}
+	if len(question) > qaMaxBody || len(answer) > qaMaxBody {
+		return fmt.Errorf("question and answer must each be at most %d characters", qaMaxBody)
+	}
+	return nil
+}
+
+// CreateQA adds an entry to the knowledge base.
+func CreateQA(acc *auth.Account, title, question, answer string, tags []string, source string) (*QAEntry, error) {
+	title, question, answer = strings.TrimSpace(title), strings.TrimSpace(question), strings.TrimSpace(answer)
+	if err := validQA(title, question, answer); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	e := &QAEntry{
+		ID:        fmt.Sprintf("%d", now.UnixNano()),
+		Title:     title,
+		Question:  question,
+		Answer:    answer,
+		Tags:      tags,
+		Source:    strings.TrimSpace(source),
+		AuthorID:  acc.ID,
+		Author:    acc.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	qaMu.Lock()
+	qaEntries = append([]*QAEntry{e}, qaEntries...)
+	err := saveQA()
+	qaMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	indexQA(e)
+	go flag.CheckContent("qa", e.ID, e.Title, e.Question+"\n\n"+e.Answer)
+	cp := *e
+	return &cp, nil
+}
+
+// UpdateQA edits an entry. Only its author or an admin may edit it.
+func UpdateQA(acc *auth.Account, id, title, question, answer string, tags []string) (*QAEntry, error) {
+	title, question, answer = strings.TrimSpace(title), strings.TrimSpace(question), strings.TrimSpace(answer)
+	if err := validQA(title, question, answer); err != nil {
+		return nil, err
+	}
+	qaMu.Lock()
+	e := findQA(id)
+	if e == nil || !canEditQA(acc, e) {
+		qaMu.Unlock()
+		return nil, ErrQANotFound
+	}
+	e.Title, e.Question, e.Answer, e.Tags = title, question, answer, tags
+	e.UpdatedAt = time.Now()
+	if acc.ID != e.AuthorID {
+		e.EditedBy = acc.ID
+	}
+	err := saveQA()
+	cp := *e
+	qaMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	indexQA(&cp)
+	go flag.CheckContent("qa", cp.ID, cp.Title, cp.Question+"\n\n"+cp.Answer)
+	return &cp, nil
+}
+
+// DeleteQA removes an entry. Registered as the admin deleter for "qa".
+func DeleteQA(id string) error {
+	qaMu.Lock()
+	defer qaMu.Unlock()
+	for i, e := range qaEntries {
+		if e.ID == id {
+			qaEntries = append(qaEntries[:i], qaEntries[i+1:]...)
+			data.Unindex("qa_" + id)
+			return saveQA()
+		}
+	}
+	return ErrQANotFound
+}
+
+// findQA returns the entry with id. Caller must hold qaMu.
+func findQA(id string) *QAEntry {
+	for _, e := range qaEntries {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func canEditQA(acc *auth.Account, e *QAEntry) bool {
+	return acc != nil && (acc.ID == e.AuthorID || acc.Admin)
+}
+
+// GetQA returns a visible entry, or nil.
+func GetQA(id string) *QAEntry {
+	qaMu.RLock()
+	defer qaMu.RUnlock()
+	e := findQA(id)
+	if e == nil || flag.IsHidden("qa", id) {
+		return nil
+	}
+	cp := *e
+	return &cp
+}
+
+// ListQA returns visible entries, newest first, optionally with a tag and
+// matching every word of query.
+func ListQA(tag, query string) []*QAEntry {
+	words := strings.Fields(strings.ToLower(query))
+	qaMu.RLock()
+	defer qaMu.RUnlock()
+	var out []*QAEntry
+	for _, e := range qaEntries {
+		if flag.IsHidden("qa", e.ID) {
+			continue
+		}
+		if tag != "" && !hasTag(e.Tags, tag) {
+			continue
+		}
+		if len(words) > 0 {
+			text := strings.ToLower(e.Title + " " + e.Question + " " + e.Answer + " " + strings.Join(e.Tags, " "))
+			match := true
+			for _, w := range words {
+				if !strings.Contains(text, w) {
+					match = false
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+		}
+		cp := *e
+		out = append(out, &cp)
+	}
+	return out
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// qaTagCounts returns each tag with how many visible entries use it, most
+// used first.
+func qaTagCounts() []string {
+	counts := map[string]int{}
+	for _, e := range ListQA("", "") {
+		for _, t := range e.Tags {
+			counts[t]++
+		}
+	}
+	tags := make([]string, 0, len(counts))
+	for t := range counts {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	return tags
+}
+
+// qaDeleter implements flag.ContentDeleter.
+type qaDeleter struct{}
+
+func (d *qaDeleter) Delete(id string) error { return DeleteQA(id) }
+
+func (d *qaDeleter) Get(id string) interface{} {
+	qaMu.RLock()
+	defer qaMu.RUnlock()
+	e := findQA(id)
+	if e == nil {
+		return nil
+	}
+	return flag.PostContent{
+		ID:        e.ID,
+		Title:     e.Title,
+		Content:   e.Question + "\n\n" + e.Answer,
+		Author:    e.Author,
+		AuthorID:  e.AuthorID,
+		CreatedAt: e.CreatedAt,
+		URL:       "/qa?id=" + e.ID,
+	}
+}
+
+func (d *qaDeleter) RefreshCache() {}
+
+// QAHandler serves /qa: the list (?tag=, ?q=) and single entries (?id=),
+// as HTML or JSON.
+func QAHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc := auth.TrySession(r)
+
+	if id := r.URL.Query().Get("id"); id != "" {
+		e := GetQA(id)
+		if e == nil {
+			app.NotFound(w, r, "Entry not found")
+			return
+		}
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, e)
+			return
+		}
+		w.Write([]byte(app.RenderHTMLForRequest(e.Title, "Q&A", renderQAEntry(e, acc), r)))
+		return
+	}
+
+	tag := strings.ToLower(r.URL.Query().Get("tag"))
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	entries := ListQA(tag, query)
+	if app.WantsJSON(r) {
+		if entries == nil {
+			entries = []*QAEntry{}
+		}
+		app.RespondJSON(w, map[string]interface{}{"entries": entries})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<form method="GET" action="/qa" class="mb-3" style="display:flex;gap:8px">`)
+	sb.WriteString(fmt.Sprintf(`<input type="text" name="q" value="%s" placeholder="Search questions..." style="flex:1">`, html.EscapeString(query)))
+	if tag != "" {
+		sb.WriteString(fmt.Sprintf(`<input type="hidden" name="tag" value="%s">`, html.EscapeString(tag)))
+	}
+	sb.WriteString(`<button type="submit">Search</button>`)
+	if acc != nil {
+		sb.WriteString(`<a href="/qa/new" class="btn">Add</a>`)
+	}
+	sb.WriteString(`</form>`)
+
+	if tags := qaTagCounts(); len(tags) > 0 {
+		sb.WriteString(`<div class="mb-3 text-sm">`)
+		if tag != "" {
+			sb.WriteString(`<a href="/qa" class="mr-2">all</a>`)
+		}
+		for _, t := range tags {
+			style := ""
+			if t == tag {
+				style = ` style="font-weight:bold"`
+			}
+			sb.WriteString(fmt.Sprintf(`<a href="/qa?tag=%s" class="mr-2"%s>#%s</a>`, url.QueryEscape(t), style, html.EscapeString(t)))
+		}
+		sb.WriteString(`</div>`)
+	}
+
+	if len(entries) == 0 {
+		sb.WriteString(`<p class="text-muted">No entries yet. Save a useful answer from <a href="/chat">chat</a> with "Save to Q&amp;A", or add one yourself.</p>`)
+	}
+	for _, e := range entries {
+		sb.WriteString(`<div class="card">`)
+		sb.WriteString(fmt.Sprintf(`<h4 style="margin:0 0 4px"><a href="/qa?id=%s">%s</a></h4>`, e.ID, html.EscapeString(e.Title)))
+		q := e.Question
+		if len(q) > 200 {
+			q = q[:200] + "..."
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="text-sm">%s</div>`, html.EscapeString(q)))
+		sb.WriteString(fmt.Sprintf(`<div class="text-muted text-xs mt-2">%s%s · %s</div>`, renderQATags(e.Tags), html.EscapeString(e.Author), app.TimeAgo(e.CreatedAt)))
+		sb.WriteString(`</div>`)
+	}
+
+	w.Write([]byte(app.RenderHTMLForRequest("Q&A", "Questions answered by the community", sb.String(), r)))
+}
+
+func renderQATags(tags []string) string {
+	var sb strings.Builder
+	for _, t := range tags {
+		sb.WriteString(fmt.Sprintf(`<a href="/qa?tag=%s">#%s</a> `, url.QueryEscape(t), html.EscapeString(t)))
+	}
+	return sb.String()
+}
+
+func renderQAEntry(e *QAEntry, acc *auth.Account) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="card">`)
+	sb.WriteString(fmt.Sprintf(`<div class="text-muted text-xs mb-2">Question</div><div style="white-space:pre-wrap">%s</div>`, html.EscapeString(e.Question)))
+	sb.WriteString(`</div><div class="card">`)
+	sb.WriteString(`<div class="text-muted text-xs mb-2">Answer</div>`)
+	sb.WriteString(app.RenderString(e.Answer))
+	sb.WriteString(`</div>`)
+
+	meta := fmt.Sprintf(`%sAdded by <a href="/@%s">%s</a> %s`, renderQATags(e.Tags), url.PathEscape(e.AuthorID), html.EscapeString(e.Author), app.TimeAgo(e.CreatedAt))
+	if e.UpdatedAt.After(e.CreatedAt.Add(time.Minute)) {
+		meta += " · edited " + app.TimeAgo(e.UpdatedAt)
+	}
+	if e.Source != "" {
+		meta += " · from " + html.EscapeString(e.Source)
+	}
+	sb.WriteString(fmt.Sprintf(`<p class="text-muted text-xs">%s</p>`, meta))
+	if canEditQA(acc, e) {
+		sb.WriteString(fmt.Sprintf(`<p class="text-sm"><a href="/qa/edit?id=%s">Edit</a></p>`, e.ID))
+	}
+	sb.WriteString(`<p class="text-sm"><a href="/qa">← All questions</a></p>`)
+	return sb.String()
+}
+
+// qaForm renders the create/edit form. On /qa/new it picks up an answer
+// stashed by the chat "Save to Q&A" link.
+func qaForm(action string, e *QAEntry, errMsg string) string {
+	var sb strings.Builder
+	if errMsg != "" {
+		sb.WriteString(fmt.Sprintf(`<p class="text-error">%s</p>`, html.EscapeString(errMsg)))
+	}
+	sb.WriteString(fmt.Sprintf(`<form method="POST" action="%s" id="qa-form">
+<input type="hidden" name="source" value="%s">
+<label>Title<input type="text" name="title" value="%s" maxlength="%d" required style="width:100%%"></label>
+<label>Question<textarea name="question" rows="3" required style="width:100%%">%s</textarea></label>
+<label>Answer (markdown)<textarea name="answer" rows="12" required style="width:100%%">%s</textarea></label>
+<label>Tags<input type="text" name="tags" value="%s" placeholder="comma separated, up to %d" style="width:100%%"></label>
+<button type="submit">Save</button>
+</form>`, action, html.EscapeString(e.Source), html.EscapeString(e.Title), qaMaxTitle,
+		html.EscapeString(e.Question), html.EscapeString(e.Answer), html.EscapeString(strings.Join(e.Tags, ", ")), qaMaxTags))
+	if e.ID != "" {
+		sb.WriteString(fmt.Sprintf(`<form method="POST" action="/qa/edit?id=%s" onsubmit="return confirm('Delete this entry?')" class="mt-3">
+<input type="hidden" name="_method" value="DELETE">
+<button type="submit" class="btn-secondary">Delete</button>
+</form>`, e.ID))
+	} else {
+		sb.WriteString(`<script>
+(function(){
+  try{
+    var d=JSON.parse(sessionStorage.getItem('mu_qa_draft')||'null');
+    if(!d)return;
+    sessionStorage.removeItem('mu_qa_draft');
+    var f=document.getElementById('qa-form');
+    if(d.question&&!f.question.value){f.question.value=d.question;f.title.value=d.question.slice(0,120)}
+    if(d.answer&&!f.answer.value)f.answer.value=d.answer;
+    if(d.source&&!f.source.value)f.source.value=d.source;
+  }catch(e){}
+})();
+</script>`)
+	}
+	return sb.String()
+}
+
+// QANewHandler serves /qa/new: GET shows the form (prefilled from ?q=),
+// POST creates the entry.
+func QANewHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		e := &QAEntry{Question: r.URL.Query().Get("q"), Source: r.URL.Query().Get("source")}
+		w.Write([]byte(app.RenderHTMLForRequest("Add to Q&A", "Save a useful answer", qaForm("/qa/new", e, ""), r)))
+	case "POST":
+		var req struct {
+			Title    string   `json:"title"`
+			Question string   `json:"question"`
+			Answer   string   `json:"answer"`
+			Tags     []string `json:"tags"`
+			Source   string   `json:"source"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.RespondError(w, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+		} else {
+			if err := r.ParseForm(); err != nil {
+				app.BadRequest(w, r, "Failed to parse form")
+				return
+			}
+			req.Title, req.Question, req.Answer, req.Source = r.FormValue("title"), r.FormValue("question"), r.FormValue("answer"), r.FormValue("source")
+			req.Tags = []string{r.FormValue("tags")}
+		}
+		tags := parseQATags(strings.Join(req.Tags, ","))
+		e, err := CreateQA(acc, req.Title, req.Question, req.Answer, tags, req.Source)
+		if err != nil {
+			if app.SendsJSON(r) || app.WantsJSON(r) {
+				app.RespondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			draft := &QAEntry{Title: req.Title, Question: req.Question, Answer: req.Answer, Tags: tags, Source: req.Source}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(app.RenderHTMLForRequest("Add to Q&A", "Save a useful answer", qaForm("/qa/new", draft, err.Error()), r)))
+			return
+		}
+		app.Log("chat", "Q&A entry %s added by %s", e.ID, acc.ID)
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, e)
+			return
+		}
+		http.Redirect(w, r, "/qa?id="+e.ID, http.StatusSeeOther)
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+// QAEditHandler serves /qa/edit?id=: GET shows the form, POST saves it,
+// and DELETE (or POST with _method=DELETE) removes the entry. Authors can
+// edit their own entries; admins can edit any.
+func QAEditHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	qaMu.RLock()
+	found := findQA(id)
+	var e QAEntry
+	if found != nil {
+		e = *found
+	}
+	qaMu.RUnlock()
+	if found == nil || !canEditQA(acc, &e) {
+		app.NotFound(w, r, "Entry not found")
+		return
+	}
+
+	method := r.Method
+	if method == "POST" && r.FormValue("_method") == "DELETE" {
+		method = "DELETE"
+	}
+	switch method {
+	case "GET":
+		w.Write([]byte(app.RenderHTMLForRequest("Edit Q&A", e.Title, qaForm("/qa/edit?id="+e.ID, &e, ""), r)))
+	case "POST":
+		var req struct {
+			Title    string   `json:"title"`
+			Question string   `json:"question"`
+			Answer   string   `json:"answer"`
+			Tags     []string `json:"tags"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.RespondError(w, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+		} else {
+			req.Title, req.Question, req.Answer = r.FormValue("title"), r.FormValue("question"), r.FormValue("answer")
+			req.Tags = []string{r.FormValue("tags")}
+		}
+		tags := parseQATags(strings.Join(req.Tags, ","))
+		updated, err := UpdateQA(acc, id, req.Title, req.Question, req.Answer, tags)
+		if err != nil {
+			if app.SendsJSON(r) || app.WantsJSON(r) {
+				app.RespondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			e.Title, e.Question, e.Answer, e.Tags = req.Title, req.Question, req.Answer, tags
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(app.RenderHTMLForRequest("Edit Q&A", e.Title, qaForm("/qa/edit?id="+e.ID, &e, err.Error()), r)))
+			return
+		}
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, updated)
+			return
+		}
+		http.Redirect(w, r, "/qa?id="+id, http.StatusSeeOther)
+	case "DELETE":
+		if err := DeleteQA(id); err != nil {
+			app.ServerError(w, r, "Failed to delete entry")
+			return
+		}
+		app.Log("chat", "Q&A entry %s deleted by %s", id, acc.ID)
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.RespondJSON(w, map[string]bool{"success": true})
+			return
+		}
+		http.Redirect(w, r, "/qa", http.StatusSeeOther)
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mu/internal/ai"
+	"mu/internal/app"
+	"mu/internal/data"
+)
+
+// Retrieval for chat. Each turn searches the index by keyword and by
+// similarity together (data.HybridSearch), gives the model the best
+// entries as numbered context and asks it to cite them by number. The
+// sources an answer cites are listed under it with their links.
+
+const (
+	// ragLimit is how many indexed entries a turn draws on.
+	ragLimit = 8
+	// ragEntryMax caps each entry's share of the prompt, in characters.
+	ragEntryMax = 2000
+)
+
+// source is where a numbered piece of context came from.
+type source struct {
+	Title string
+	URL   string
+}
+
+// retrieval is the context found for a chat turn, numbered from 1 in the
+// prompt, and the source of each piece.
+type retrieval struct {
+	context []string
+	sources []source
+	found   int // from the index, as opposed to the web
+}
+
+// retrieve searches public indexed content for query.
+func retrieve(query string) *retrieval {
+	rv := &retrieval{}
+	for _, r := range data.HybridSearch(query, ragLimit) {
+		e := r.Entry
+		text := fmt.Sprintf("%s: %s", e.Title, e.Content)
+		if runes := []rune(text); len(runes) > ragEntryMax {
+			text = string(runes[:ragEntryMax]) + "..."
+		}
+		link := e.Link()
+		rv.context = append(rv.context, fmt.Sprintf("%s (Source: %s)", text, link))
+		rv.sources = append(rv.sources, source{Title: e.Title, URL: link})
+	}
+	rv.found = len(rv.context)
+	return rv
+}
+
+// addWeb adds web search results after the indexed entries.
+func (rv *retrieval) addWeb(results []ai.SearchResult) {
+	for i, text := range ai.FormatSearchResults(results) {
+		rv.context = append(rv.context, text)
+		rv.sources = append(rv.sources, source{Title: results[i].Title, URL: results[i].URL})
+	}
+}
+
+// log records what was found, for tuning retrieval.
+func (rv *retrieval) log(query string) {
+	app.Log("chat", "[RAG] %q: %d indexed, %d web", query, rv.found, len(rv.context)-rv.found)
+	for i, s := range rv.sources {
+		app.LogDebug("chat", "[RAG]   [%d] %s %s", i+1, s.Title, s.URL)
+	}
+}
+
+// citeRe matches a citation like [3].
+var citeRe = regexp.MustCompile(`\[(\d{1,2})\]`)
+
+// cite lists the sources the answer cites beneath it, in the order they
+// were first cited. Numbers that match no source are left alone.
+func (rv *retrieval) cite(answer string) string {
+	seen := map[int]bool{}
+	var list []string
+	for _, m := range citeRe.FindAllStringSubmatch(answer, -1) {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > len(rv.sources) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		s := rv.sources[n-1]
+		title := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(strings.TrimSpace(s.Title))
+		if title == "" {
+			title = s.URL
+		}
+		if s.URL == "" {
+			list = append(list, fmt.Sprintf("[%d] %s", n, title))
+			continue
+		}
+		list = append(list, fmt.Sprintf("[%d] [%s](%s)", n, title, s.URL))
+	}
+	if len(list) == 0 {
+		return answer
+	}
+	return strings.TrimRight(answer, "\n") + "\n\n**Sources**\n\n- " + strings.Join(list, "\n- ")
+}
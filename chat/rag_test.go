@@ -0,0 +1,26 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"mu/internal/ai"
+)
+
+func TestRetrievalCite(t *testing.T) {
+	rv := &retrieval{sources: []source{
+		{Title: "Fed cuts rates", URL: "/news?id=n1"},
+		{Title: "Savers [explained]", URL: "/blog/post?id=p1"},
+	}}
+	rv.addWeb([]ai.SearchResult{{Title: "Web page", URL: "https://example.com", Snippet: "x"}})
+
+	got := rv.cite("Rates fell [2], as expected [1]. Savers lose out [2]; see also [9] and [3].")
+	want := "**Sources**\n\n- [2] [Savers \\[explained\\]](/blog/post?id=p1)\n- [1] [Fed cuts rates](/news?id=n1)\n- [3] [Web page](https://example.com)"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("cite =\n%s\nwant it to end with\n%s", got, want)
+	}
+
+	if got := rv.cite("No citations here."); got != "No citations here." {
+		t.Errorf("uncited answer changed: %q", got)
+	}
+}
@@ -0,0 +1,359 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Named rooms are standing conversations between people, unlike the
+// discussion rooms that come and go with a post or article. They start
+// out as general, news and dev; admins add and archive them at
+// /admin/rooms. Each room's ID is "room_" plus its slug, its history is
+// kept on disk without the 24-hour cutoff, and the room switcher on /chat
+// marks the rooms with messages since the browser last looked.
+
+// NamedRoom is a standing chat room.
+type NamedRoom struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Archived    bool      `json:"archived,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// ID is the room's ID, as used in /chat?id=.
+func (n NamedRoom) ID() string { return "room_" + n.Slug }
+
+const (
+	namedRoomsKey = "chat_rooms.json"
+	// namedRoomHistory is how many messages a named room keeps.
+	namedRoomHistory = 100
+	// itemRoomHistory is how many messages any other room keeps.
+	itemRoomHistory = 20
+)
+
+// roomSlugRe limits slugs to what reads well after a #.
+var roomSlugRe = regexp.MustCompile(`^[a-z][a-z0-9-]{0,23}$`)
+
+var (
+	namedMutex sync.RWMutex
+	namedRooms []NamedRoom
+	// lastMessage is when each named room last had a message, by ID.
+	lastMessage = map[string]time.Time{}
+)
+
+// defaultRooms are the rooms a new instance starts with.
+func defaultRooms() []NamedRoom {
+	now := time.Now()
+	return []NamedRoom{
+		{Slug: "general", Name: "General", Description: "Anything and everything.", Created: now},
+		{Slug: "news", Name: "News", Description: "What's happening in the world.", Created: now},
+		{Slug: "dev", Name: "Dev", Description: "Building things: code, startups and tools.", Created: now},
+	}
+}
+
+// loadNamedRooms loads the rooms, or the defaults, and when each last had
+// a message.
+func loadNamedRooms() {
+	var list []NamedRoom
+	if err := data.LoadJSON(namedRoomsKey, &list); err != nil || len(list) == 0 {
+		list = defaultRooms()
+	}
+	last := map[string]time.Time{}
+	for _, n := range list {
+		b, err := data.LoadFile(roomFile(n.ID()))
+		if err != nil {
+			continue
+		}
+		var msgs []RoomMessage
+		if json.Unmarshal(b, &msgs) == nil && len(msgs) > 0 {
+			last[n.ID()] = msgs[len(msgs)-1].Timestamp
+		}
+	}
+	namedMutex.Lock()
+	namedRooms = list
+	lastMessage = last
+	namedMutex.Unlock()
+}
+
+// NamedRooms returns the rooms in the order they were added, archived
+// ones too if asked.
+func NamedRooms(archived bool) []NamedRoom {
+	namedMutex.RLock()
+	defer namedMutex.RUnlock()
+	var list []NamedRoom
+	for _, n := range namedRooms {
+		if !n.Archived || archived {
+			list = append(list, n)
+		}
+	}
+	return list
+}
+
+// namedRoom returns the room with the given ID, "room_" and its slug.
+func namedRoom(id string) (NamedRoom, bool) {
+	namedMutex.RLock()
+	defer namedMutex.RUnlock()
+	if i := namedRoomIndex(strings.TrimPrefix(id, "room_")); i >= 0 {
+		return namedRooms[i], true
+	}
+	return NamedRoom{}, false
+}
+
+// namedRoomIndex returns the position of the room with slug, or -1
+// (caller must hold namedMutex).
+func namedRoomIndex(slug string) int {
+	for i, n := range namedRooms {
+		if n.Slug == slug {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddRoom adds a named room.
+func AddRoom(slug, name, description string) error {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	name, description = strings.TrimSpace(name), strings.TrimSpace(description)
+	if !roomSlugRe.MatchString(slug) {
+		return fmt.Errorf("slug must be a letter followed by up to 23 lower-case letters, digits or hyphens")
+	}
+	if name == "" {
+		name = slug
+	}
+	if len(name) > 40 || len(description) > 200 {
+		return fmt.Errorf("name is limited to 40 characters and description to 200")
+	}
+	namedMutex.Lock()
+	defer namedMutex.Unlock()
+	if namedRoomIndex(slug) >= 0 {
+		return fmt.Errorf("a room called %s already exists", slug)
+	}
+	namedRooms = append(namedRooms, NamedRoom{Slug: slug, Name: name, Description: description, Created: time.Now()})
+	return data.SaveJSON(namedRoomsKey, namedRooms)
+}
+
+// ArchiveRoom takes a room out of the switcher and closes it. Its history
+// is kept, and RestoreRoom brings it back.
+func ArchiveRoom(slug string) error {
+	if err := setArchived(slug, true); err != nil {
+		return err
+	}
+	roomsMutex.Lock()
+	if room, ok := rooms["room_"+slug]; ok {
+		select {
+		case room.Shutdown <- true:
+		default:
+		}
+		delete(rooms, room.ID)
+	}
+	roomsMutex.Unlock()
+	return nil
+}
+
+// RestoreRoom reopens an archived room.
+func RestoreRoom(slug string) error {
+	return setArchived(slug, false)
+}
+
+func setArchived(slug string, archived bool) error {
+	namedMutex.Lock()
+	defer namedMutex.Unlock()
+	i := namedRoomIndex(slug)
+	if i < 0 {
+		return fmt.Errorf("no room called %s", slug)
+	}
+	namedRooms[i].Archived = archived
+	return data.SaveJSON(namedRoomsKey, namedRooms)
+}
+
+// isNamedRoom reports whether id is a named room's.
+func isNamedRoom(id string) bool {
+	return strings.HasPrefix(id, "room_")
+}
+
+// historyLimit is how many messages a room keeps.
+func historyLimit(id string) int {
+	if isNamedRoom(id) {
+		return namedRoomHistory
+	}
+	return itemRoomHistory
+}
+
+// noteMessage records when a named room last had a message.
+func noteMessage(id string, at time.Time) {
+	namedMutex.Lock()
+	lastMessage[id] = at
+	namedMutex.Unlock()
+}
+
+// roomTabs renders the room switcher. Each tab carries the time of the
+// room's last message, so the page can mark those not seen yet.
+func roomTabs(current string) string {
+	list := NamedRooms(false)
+	if len(list) == 0 {
+		return ""
+	}
+	namedMutex.RLock()
+	defer namedMutex.RUnlock()
+	var b strings.Builder
+	b.WriteString(`<div id="room-selector" class="room-tabs">`)
+	for _, n := range list {
+		class := "room-tab"
+		if n.ID() == current {
+			class += " active"
+		}
+		var last int64
+		if t, ok := lastMessage[n.ID()]; ok {
+			last = t.UnixMilli()
+		}
+		b.WriteString(fmt.Sprintf(`<a href="/chat?id=%s" class="%s" data-room="%s" data-last="%d" title="%s">#%s</a>`,
+			n.ID(), class, n.ID(), last, htmlpkg.EscapeString(n.Description), htmlpkg.EscapeString(n.Slug)))
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// roomList is the rooms with when each last had a message, for the JSON
+// form of /chat.
+func roomList() []map[string]interface{} {
+	list := NamedRooms(false)
+	namedMutex.RLock()
+	defer namedMutex.RUnlock()
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, n := range list {
+		r := map[string]interface{}{
+			"id":          n.ID(),
+			"name":        n.Name,
+			"description": n.Description,
+		}
+		if t, ok := lastMessage[n.ID()]; ok {
+			r["last_message"] = t.UnixMilli()
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// RoomsHandler serves /admin/rooms. GET lists the named rooms and the
+// moderation log (JSON when requested); POST adds, archives or restores a
+// room.
+func RoomsHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		var req struct {
+			Action      string `json:"action"`
+			Slug        string `json:"slug"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Action = r.FormValue("action")
+			req.Slug = r.FormValue("slug")
+			req.Name = r.FormValue("name")
+			req.Description = r.FormValue("description")
+		}
+		switch req.Action {
+		case "add":
+			err = AddRoom(req.Slug, req.Name, req.Description)
+		case "archive":
+			err = ArchiveRoom(req.Slug)
+		case "restore":
+			err = RestoreRoom(req.Slug)
+		default:
+			err = fmt.Errorf("unknown action")
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		app.Log("chat", "%s rooms %s %s", acc.ID, req.Action, req.Slug)
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"rooms": NamedRooms(true)})
+			return
+		}
+		http.Redirect(w, r, "/admin/rooms", http.StatusSeeOther)
+		return
+	}
+
+	list := NamedRooms(true)
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"rooms": list, "moderation": ModerationLog()})
+		return
+	}
+
+	btn := `style="font-size:12px;padding:2px 8px;border-radius:4px;background:#fff;cursor:pointer;border:1px solid #ccc"`
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>Chat Rooms <span class="count">%d</span></h3>`, len(list)))
+	content.WriteString(`<p class="text-sm text-muted">Standing rooms in the switcher on <a href="/chat">/chat</a>. Archiving a room closes it and hides it; its history is kept.</p>`)
+	content.WriteString(`<form method="POST" class="block-form">
+		<input type="hidden" name="action" value="add">
+		<input type="text" name="slug" placeholder="Slug, e.g. music" required>
+		<input type="text" name="name" placeholder="Name">
+		<input type="text" name="description" placeholder="Description">
+		<button type="submit">Add</button>
+	</form>`)
+	content.WriteString(`<table class="email-log">`)
+	content.WriteString(`<tr><th>Room</th><th class="hide-mobile">Description</th><th>Last message</th><th></th></tr>`)
+	namedMutex.RLock()
+	for _, n := range list {
+		last := "never"
+		if t, ok := lastMessage[n.ID()]; ok {
+			last = app.TimeAgo(t)
+		}
+		action, label := "archive", "Archive"
+		room := fmt.Sprintf(`<a href="/chat?id=%s">#%s</a> %s`, n.ID(), htmlpkg.EscapeString(n.Slug), htmlpkg.EscapeString(n.Name))
+		if n.Archived {
+			action, label = "restore", "Restore"
+			room = fmt.Sprintf(`#%s %s <span class="text-muted">(archived)</span>`, htmlpkg.EscapeString(n.Slug), htmlpkg.EscapeString(n.Name))
+		}
+		content.WriteString(fmt.Sprintf(`<tr><td>%s</td><td class="hide-mobile">%s</td><td>%s</td><td><form method="POST" class="d-inline"><input type="hidden" name="action" value="%s"><input type="hidden" name="slug" value="%s"><button type="submit" %s>%s</button></form></td></tr>`,
+			room, htmlpkg.EscapeString(n.Description), last, action, htmlpkg.EscapeString(n.Slug), btn, label))
+	}
+	namedMutex.RUnlock()
+	content.WriteString(`</table>`)
+	content.WriteString(`</div>`)
+
+	entries := ModerationLog()
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>Moderation Log <span class="count">%d</span></h3>`, len(entries)))
+	content.WriteString(`<p class="text-sm text-muted">Messages admins deleted from other people, here or from <a href="/admin/moderate">/admin/moderate</a>.</p>`)
+	if len(entries) == 0 {
+		content.WriteString(`<p class="text-muted">Nothing deleted yet.</p>`)
+	} else {
+		content.WriteString(`<table class="email-log">`)
+		content.WriteString(`<tr><th>When</th><th>By</th><th>Author</th><th class="hide-mobile">Room</th><th>Message</th></tr>`)
+		for _, e := range entries {
+			content.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td><a href="/@%s">%s</a></td><td class="hide-mobile"><a href="/chat?id=%s">%s</a></td><td>%s</td></tr>`,
+				app.TimeAgo(e.Time), htmlpkg.EscapeString(e.By), htmlpkg.EscapeString(e.Author), htmlpkg.EscapeString(e.Author),
+				htmlpkg.EscapeString(e.Room), htmlpkg.EscapeString(e.Room), htmlpkg.EscapeString(e.Content)))
+		}
+		content.WriteString(`</table>`)
+	}
+	content.WriteString(`</div>`)
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest("Chat Rooms", "Chat rooms", content.String(), r)))
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamedRoomLifecycle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	loadNamedRooms()
+
+	if got := NamedRooms(false); len(got) != 3 || got[0].ID() != "room_general" {
+		t.Fatalf("default rooms = %+v", got)
+	}
+	if err := AddRoom("Not OK!", "", ""); err == nil {
+		t.Error("accepted a bad slug")
+	}
+	if err := AddRoom("dev", "Dev", ""); err == nil {
+		t.Error("accepted a duplicate room")
+	}
+	if err := AddRoom("music", "Music", "What we're listening to."); err != nil {
+		t.Fatal(err)
+	}
+
+	// History is kept past a day, unlike in the topic rooms.
+	old := []RoomMessage{{UserID: "alice", Content: "hello", Timestamp: time.Now().Add(-72 * time.Hour)}}
+	saveRoomMessages("room_music", old)
+	loadNamedRooms()
+	room := getOrCreateRoom("room_music")
+	if room == nil || room.Title != "#music" || len(room.Messages) != 1 {
+		t.Fatalf("room = %+v", room)
+	}
+	if tabs := roomTabs("room_music"); tabs == "" || roomList()[3]["last_message"] != old[0].Timestamp.UnixMilli() {
+		t.Errorf("the switcher doesn't carry the last message time: %v", roomList())
+	}
+
+	if err := ArchiveRoom("music"); err != nil {
+		t.Fatal(err)
+	}
+	if getOrCreateRoom("room_music") != nil {
+		t.Error("joined an archived room")
+	}
+	if n := len(NamedRooms(false)); n != 3 {
+		t.Errorf("%d rooms in the switcher after archiving, want 3", n)
+	}
+	if err := RestoreRoom("music"); err != nil {
+		t.Fatal(err)
+	}
+	loadNamedRooms()
+	if n := len(NamedRooms(false)); n != 4 {
+		t.Errorf("%d rooms after restoring and reloading, want 4", n)
+	}
+	if getOrCreateRoom("room_nowhere") != nil {
+		t.Error("joined a room that doesn't exist")
+	}
+}
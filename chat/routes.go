@@ -0,0 +1,18 @@
+package chat
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/chat", Handler) // public viewing, chatting needs a session
+	r.HandleFunc("/chat/summary", SummaryHandler)
+	r.HandleFunc("/chat/dm", DMHandler, app.Authenticated)
+	r.HandleFunc("/chat/message", MessageHandler, app.Authenticated)
+	r.HandleFunc("/admin/rooms", RoomsHandler, app.Authenticated, app.Audited)
+	r.HandleFunc("/qa", QAHandler) // the public knowledge base
+	r.HandleFunc("/qa/new", QANewHandler, app.Authenticated)
+	r.HandleFunc("/qa/edit", QAEditHandler, app.Authenticated)
+}
@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"mu/internal/ai"
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/wallet"
+)
+
+// SummaryMinMessages is how long a room's conversation has to be before it
+// can be summarised.
+const SummaryMinMessages = 10
+
+// ThreadSummary is the cached summary of a room's conversation. LastMessage
+// points at the newest message it covers: asking again only sends the
+// messages since then, folded into the existing summary, so a summary keeps
+// covering a conversation after its older messages have rolled out of the
+// room's history.
+type ThreadSummary struct {
+	RoomID      string    `json:"room_id"`
+	Summary     string    `json:"summary"`
+	LastMessage time.Time `json:"last_message"`
+	Covered     int       `json:"covered"` // messages summarised so far
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+var (
+	threadSummariesMu sync.RWMutex
+	threadSummaries   = map[string]*ThreadSummary{} // roomID -> summary
+)
+
+// summariseThread generates the summary text. Swapped out in tests.
+var summariseThread = ai.SummariseThread
+
+func loadThreadSummaries() {
+	var stored map[string]*ThreadSummary
+	if err := data.LoadJSON("room_summaries.json", &stored); err == nil && stored != nil {
+		threadSummariesMu.Lock()
+		threadSummaries = stored
+		threadSummariesMu.Unlock()
+	}
+}
+
+// GetThreadSummary returns the cached summary for a room, or nil.
+func GetThreadSummary(roomID string) *ThreadSummary {
+	threadSummariesMu.RLock()
+	defer threadSummariesMu.RUnlock()
+	if s := threadSummaries[roomID]; s != nil {
+		cp := *s
+		return &cp
+	}
+	return nil
+}
+
+// roomHistory returns a room's messages, oldest first, from the live room
+// if it is open or from disk otherwise.
+func roomHistory(roomID string) []RoomMessage {
+	roomsMutex.RLock()
+	room := rooms[roomID]
+	roomsMutex.RUnlock()
+	if room == nil {
+		return loadRoomMessages(roomID)
+	}
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+	return append([]RoomMessage(nil), room.Messages...)
+}
+
+// pendingMessages returns the messages not yet covered by summary.
+func pendingMessages(history []RoomMessage, summary *ThreadSummary) []RoomMessage {
+	if summary == nil {
+		return history
+	}
+	var out []RoomMessage
+	for _, m := range history {
+		if m.Timestamp.After(summary.LastMessage) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// updateThreadSummary folds the pending messages into the room's summary
+// and caches the result.
+func updateThreadSummary(roomID string, prev *ThreadSummary, pending []RoomMessage) (*ThreadSummary, error) {
+	lines := make([]string, len(pending))
+	for i, m := range pending {
+		name := m.UserID
+		if m.IsLLM {
+			name = "micro"
+		}
+		lines[i] = name + ": " + m.Content
+	}
+	previous := ""
+	covered := 0
+	if prev != nil {
+		previous = prev.Summary
+		covered = prev.Covered
+	}
+	text, err := summariseThread(previous, lines, "chat-thread-summary")
+	if err != nil {
+		return nil, err
+	}
+	s := &ThreadSummary{
+		RoomID:      roomID,
+		Summary:     app.StripLatexDollars(text),
+		LastMessage: pending[len(pending)-1].Timestamp,
+		Covered:     covered + len(pending),
+		UpdatedAt:   time.Now(),
+	}
+
+	threadSummariesMu.Lock()
+	threadSummaries[roomID] = s
+	err = data.SaveJSON("room_summaries.json", threadSummaries)
+	threadSummariesMu.Unlock()
+	if err != nil {
+		app.Log("chat", "Error saving room summaries: %v", err)
+	}
+	cp := *s
+	return &cp, nil
+}
+
+// SummaryHandler serves /chat/summary?id=<room>. GET returns the cached
+// summary and how many messages have arrived since. POST brings the summary
+// up to date, charging the wallet only when there is something new to
+// summarise.
+func SummaryHandler(w http.ResponseWriter, r *http.Request) {
+	roomID := r.URL.Query().Get("id")
+	if roomID == "" {
+		app.RespondError(w, http.StatusBadRequest, "missing room id")
+		return
+	}
+
+	respond := func(s *ThreadSummary, pending int, charged bool) {
+		resp := map[string]interface{}{
+			"room_id": roomID,
+			"pending": pending,
+			"charged": charged,
+		}
+		if s != nil {
+			resp["summary"] = s.Summary
+			resp["covered"] = s.Covered
+			resp["updated_at"] = s.UpdatedAt
+		}
+		app.RespondJSON(w, resp)
+	}
+
+	history := visibleMessages(roomID, roomHistory(roomID))
+	prev := GetThreadSummary(roomID)
+	pending := pendingMessages(history, prev)
+
+	switch r.Method {
+	case "GET":
+		respond(prev, len(pending), false)
+		return
+	case "POST":
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.RespondError(w, http.StatusUnauthorized, "sign in to summarise")
+		return
+	}
+	if prev == nil && len(history) < SummaryMinMessages {
+		app.RespondError(w, http.StatusBadRequest, fmt.Sprintf("summaries need at least %d messages", SummaryMinMessages))
+		return
+	}
+	if len(pending) == 0 {
+		respond(prev, 0, false)
+		return
+	}
+
+	canProceed, _, cost, _ := wallet.CheckQuota(acc.ID, wallet.OpThreadSummary)
+	if !canProceed {
+		app.RespondError(w, http.StatusPaymentRequired, fmt.Sprintf("A summary costs %d credits. Top up at /wallet", cost))
+		return
+	}
+
+	s, err := updateThreadSummary(roomID, prev, pending)
+	if err != nil {
+		app.Log("chat", "Thread summary failed for room %s: %v", roomID, err)
+		app.RespondError(w, http.StatusBadGateway, "summary failed, please try again")
+		return
+	}
+	wallet.ConsumeQuota(acc.ID, wallet.OpThreadSummary) //nolint:errcheck
+	respond(s, 0, true)
+}
@@ -31,6 +31,12 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+func init() {
+	settings.Register("Discord",
+		settings.Var{Key: "DISCORD_BOT_TOKEN", Secret: true, Doc: "Discord bot token"},
+	)
+}
+
 var (
 	botToken string
 	botID    string
@@ -99,6 +105,9 @@ func DeleteLinks(muAccount string) {
 
 // autoCreateAccount creates a Mu account from a Discord user and links it.
 func autoCreateAccount(discordID, username string) string {
+	if !auth.OpenRegistration() {
+		return ""
+	}
 	// Sanitise Discord username for Mu (lowercase, alphanumeric, 4-24 chars)
 	id := strings.ToLower(username)
 	id = strings.Map(func(r rune) rune {
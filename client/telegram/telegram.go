@@ -27,6 +27,12 @@ import (
 	"mu/internal/settings"
 )
 
+func init() {
+	settings.Register("Telegram",
+		settings.Var{Key: "TELEGRAM_BOT_TOKEN", Secret: true, Doc: "Telegram bot token"},
+	)
+}
+
 var (
 	linkMu sync.RWMutex
 	links  = map[string]string{} // telegram user ID → mu account ID
@@ -415,6 +421,9 @@ func uniqueAccountID(baseID string, exists func(string) bool) string {
 }
 
 func autoCreateAccount(telegramID, username, displayName string) string {
+	if !auth.OpenRegistration() {
+		return ""
+	}
 	id := uniqueAccountID(sanitizeAccountID(telegramID, username), func(id string) bool {
 		_, err := auth.GetAccount(id)
 		return err == nil
@@ -33,6 +33,15 @@ import (
 	"mu/internal/settings"
 )
 
+func init() {
+	settings.Register("WhatsApp",
+		settings.Var{Key: "WHATSAPP_TOKEN", Secret: true, Doc: "WhatsApp Cloud API token"},
+		settings.Var{Key: "WHATSAPP_PHONE_ID", Doc: "WhatsApp phone number ID"},
+		settings.Var{Key: "WHATSAPP_VERIFY_TOKEN", Secret: true, Doc: "Token the webhook is verified with"},
+		settings.Var{Key: "WHATSAPP_APP_SECRET", Secret: true, Doc: "App secret webhook payloads are signed with"},
+	)
+}
+
 const apiBase = "https://graph.facebook.com/v21.0"
 
 var (
@@ -347,6 +356,9 @@ func DeleteLinks(muAccount string) {
 }
 
 func autoCreateAccount(phone string) string {
+	if !auth.OpenRegistration() {
+		return ""
+	}
 	// Use last 6 digits of phone as username base
 	id := "wa" + phone
 	if len(id) > 10 {
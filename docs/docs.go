@@ -41,9 +41,11 @@ var catalog = []Document{
 	// Reference
 	{Slug: "environment", Filename: "ENVIRONMENT_VARIABLES.md", Title: "Configuration", Description: "Environment variables", Category: "Reference"},
 	{Slug: "mcp", Filename: "MCP.md", Title: "MCP Server", Description: "AI tool integration via MCP", Category: "Reference"},
+	{Slug: "oidc", Filename: "OIDC.md", Title: "OpenID Connect", Description: "Sign in to companion services with a Mu account", Category: "Reference"},
 	{Slug: "screenshots", Filename: "SCREENSHOTS.md", Title: "Screenshots", Description: "Application screenshots", Category: "Reference"},
 
 	// Developer (accessible but not prominent)
+	{Slug: "plugins", Filename: "PLUGINS.md", Title: "Plugins", Description: "Add pages, home cards and chat commands as separate programs", Category: "Developer"},
 	{Slug: "system-design", Filename: "SYSTEM_DESIGN.md", Title: "System Design", Description: "Architecture overview", Category: "Developer"},
 	{Slug: "whitepaper", Filename: "WHITEPAPER.md", Title: "Whitepaper", Description: "Full network overview and future direction", Category: "Developer"},
 }
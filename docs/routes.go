@@ -0,0 +1,14 @@
+package docs
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/docs", Handler)
+	r.HandleFunc("/docs/", Handler)
+	r.HandleFunc("/whitepaper", WhitepaperHandler)
+	r.HandleFunc("/whitepaper.pdf", WhitepaperHandler)
+}
@@ -0,0 +1,426 @@
+package home
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/ai"
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/mail"
+	"mu/markets"
+	"mu/news"
+	"mu/wallet"
+)
+
+// The morning briefing is an opt-in home card ("briefing") that pulls the
+// day together: headlines from the user's chosen news categories, notable
+// price moves, unread mail and today's tasks. Each morning it is generated
+// once per user; if an AI provider is configured and the wallet covers it,
+// a short written summary is added on top. Otherwise, or if generation
+// fails, the card shows the same sections as a plain list.
+
+const (
+	briefingHeadlines      = 5
+	briefingPerCategory    = 2
+	briefingSenders        = 3
+	briefingCheckInterval  = 10 * time.Minute
+	briefingDefaultHourEnv = "BRIEFING_HOUR"
+)
+
+// BriefingHeadline is one news item in a briefing.
+type BriefingHeadline struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Category string `json:"category"`
+}
+
+// Briefing is a user's briefing for one day.
+type Briefing struct {
+	UserID      string             `json:"user_id"`
+	Date        string             `json:"date"` // 2006-01-02, server time
+	Summary     string             `json:"summary,omitempty"`
+	Headlines   []BriefingHeadline `json:"headlines"`
+	Movers      string             `json:"movers,omitempty"`
+	Unread      int                `json:"unread"`
+	Senders     []string           `json:"senders,omitempty"`
+	Tasks       []string           `json:"tasks,omitempty"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// BriefingPrefs are a user's briefing settings.
+type BriefingPrefs struct {
+	Categories []string `json:"categories,omitempty"` // news categories; empty = all
+}
+
+// BriefingTasks returns a user's tasks and events for a day, one line each.
+// Set by main.go once a task source is available; nil leaves the section out.
+var BriefingTasks func(userID string, day time.Time) []string
+
+var (
+	briefingMu    sync.RWMutex
+	briefings     = map[string]*Briefing{}     // userID -> latest briefing
+	briefingPrefs = map[string]BriefingPrefs{} // userID -> prefs
+)
+
+// writeBriefing produces the written summary. Swapped out in tests.
+var writeBriefing = func(b *Briefing) (string, error) {
+	return ai.Ask(&ai.Prompt{
+		System:    `You write a short morning briefing for one person on the Mu platform. Using only the facts given, write 3 to 5 plain sentences: what matters in the news, any notable market moves, and what is waiting for them today. No headings, no lists, no greetings, at most 120 words.`,
+		Question:  briefingFacts(b),
+		Priority:  ai.PriorityLow,
+		Model:     ai.BackgroundModel(),
+		Caller:    "daily-briefing",
+		MaxTokens: 400,
+	})
+}
+
+func loadBriefings() {
+	var stored map[string]*Briefing
+	if err := data.LoadJSON("briefings.json", &stored); err == nil && stored != nil {
+		briefingMu.Lock()
+		briefings = stored
+		briefingMu.Unlock()
+	}
+	var prefs map[string]BriefingPrefs
+	if err := data.LoadJSON("briefing_prefs.json", &prefs); err == nil && prefs != nil {
+		briefingMu.Lock()
+		briefingPrefs = prefs
+		briefingMu.Unlock()
+	}
+}
+
+// briefingHour is the server-local hour after which the day's briefings are
+// generated.
+func briefingHour() int {
+	if h, err := strconv.Atoi(os.Getenv(briefingDefaultHourEnv)); err == nil && h >= 0 && h < 24 {
+		return h
+	}
+	return 6
+}
+
+// GetBriefingPrefs returns a user's briefing settings.
+func GetBriefingPrefs(userID string) BriefingPrefs {
+	briefingMu.RLock()
+	defer briefingMu.RUnlock()
+	return briefingPrefs[userID]
+}
+
+// SetBriefingPrefs saves a user's briefing settings.
+func SetBriefingPrefs(userID string, p BriefingPrefs) error {
+	briefingMu.Lock()
+	defer briefingMu.Unlock()
+	briefingPrefs[userID] = p
+	return data.SaveJSON("briefing_prefs.json", briefingPrefs)
+}
+
+// GetBriefing returns the user's briefing for the given day, or nil.
+func GetBriefing(userID string, day time.Time) *Briefing {
+	briefingMu.RLock()
+	defer briefingMu.RUnlock()
+	b := briefings[userID]
+	if b == nil || b.Date != day.Format("2006-01-02") {
+		return nil
+	}
+	cp := *b
+	return &cp
+}
+
+// newsCategories returns the categories in the current news feed.
+func newsCategories() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, p := range news.GetFeed() {
+		if p.Category != "" && !seen[p.Category] {
+			seen[p.Category] = true
+			out = append(out, p.Category)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// pickHeadlines returns the newest headlines in the given categories (all
+// when empty), at most briefingPerCategory from any one.
+func pickHeadlines(feed []*news.Post, categories []string) []BriefingHeadline {
+	want := map[string]bool{}
+	for _, c := range categories {
+		want[strings.ToLower(c)] = true
+	}
+	posts := append([]*news.Post(nil), feed...)
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].PostedAt.After(posts[j].PostedAt) })
+
+	perCat := map[string]int{}
+	var out []BriefingHeadline
+	for _, p := range posts {
+		if p == nil || strings.TrimSpace(p.Title) == "" {
+			continue
+		}
+		cat := strings.ToLower(p.Category)
+		if len(want) > 0 && !want[cat] {
+			continue
+		}
+		if perCat[cat] >= briefingPerCategory {
+			continue
+		}
+		perCat[cat]++
+		out = append(out, BriefingHeadline{Title: p.Title, URL: p.URL, Category: p.Category})
+		if len(out) == briefingHeadlines {
+			break
+		}
+	}
+	return out
+}
+
+// gatherBriefing collects the plain briefing for a user. It costs nothing,
+// so the card can always fall back to it.
+func gatherBriefing(userID string, now time.Time) *Briefing {
+	b := &Briefing{
+		UserID:      userID,
+		Date:        now.Format("2006-01-02"),
+		Headlines:   pickHeadlines(news.GetFeed(), GetBriefingPrefs(userID).Categories),
+		Movers:      markets.TopMovers(3),
+		Unread:      mail.GetUnreadCount(userID),
+		Senders:     mail.UnreadSenders(userID, briefingSenders),
+		GeneratedAt: now,
+	}
+	if BriefingTasks != nil {
+		b.Tasks = BriefingTasks(userID, now)
+	}
+	return b
+}
+
+// briefingFacts is the briefing as plain text for the summary prompt.
+func briefingFacts(b *Briefing) string {
+	var sb strings.Builder
+	if len(b.Headlines) > 0 {
+		sb.WriteString("Headlines:\n")
+		for _, h := range b.Headlines {
+			sb.WriteString(fmt.Sprintf("- [%s] %s\n", h.Category, h.Title))
+		}
+	}
+	if b.Movers != "" {
+		sb.WriteString("Market moves (24h): " + b.Movers + "\n")
+	}
+	if b.Unread > 0 {
+		sb.WriteString(fmt.Sprintf("Unread mail: %d", b.Unread))
+		if len(b.Senders) > 0 {
+			sb.WriteString(", including from " + strings.Join(b.Senders, ", "))
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("No unread mail.\n")
+	}
+	if len(b.Tasks) > 0 {
+		sb.WriteString("Today's tasks and events:\n")
+		for _, t := range b.Tasks {
+			sb.WriteString("- " + t + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// generateBriefing builds and stores a user's briefing for today. The
+// written summary is only added, and only charged, when an AI provider is
+// configured and the user's wallet covers it.
+func generateBriefing(acc *auth.Account, now time.Time) *Briefing {
+	b := gatherBriefing(acc.ID, now)
+
+	if ai.Configured() {
+		if canProceed, _, _, _ := wallet.CheckQuota(acc.ID, wallet.OpDailyBriefing); canProceed {
+			text, err := writeBriefing(b)
+			if err != nil {
+				app.Log("home", "Briefing summary for %s failed: %v", acc.ID, err)
+			} else {
+				b.Summary = strings.TrimSpace(app.StripLatexDollars(text))
+				wallet.ConsumeQuota(acc.ID, wallet.OpDailyBriefing) //nolint:errcheck
+			}
+		}
+	}
+
+	briefingMu.Lock()
+	briefings[acc.ID] = b
+	if err := data.SaveJSON("briefings.json", briefings); err != nil {
+		app.Log("home", "Failed to save briefings: %v", err)
+	}
+	briefingMu.Unlock()
+	cp := *b
+	return &cp
+}
+
+// generateDueBriefings generates today's briefing for every account that
+// has the card on and doesn't have one yet, once the briefing hour has
+// passed.
+func generateDueBriefings(now time.Time) {
+	if now.Hour() < briefingHour() {
+		return
+	}
+	for _, acc := range auth.GetAllAccounts() {
+		if !acc.HomeCardActive("briefing") || GetBriefing(acc.ID, now) != nil {
+			continue
+		}
+		generateBriefing(acc, now)
+		app.Log("home", "Generated briefing for %s", acc.ID)
+	}
+}
+
+var briefingsOnce sync.Once
+
+// StartBriefings starts the loop that generates morning briefings. Not
+// started in read-only mode.
+func StartBriefings() {
+	briefingsOnce.Do(func() {
+		go func() {
+			for {
+				generateDueBriefings(time.Now())
+				time.Sleep(briefingCheckInterval)
+			}
+		}()
+	})
+}
+
+// BriefingCard renders the briefing card for a user: today's generated
+// briefing if there is one, otherwise the plain layout built now.
+func BriefingCard(userID string) string {
+	now := time.Now()
+	b := GetBriefing(userID, now)
+	if b == nil {
+		b = gatherBriefing(userID, now)
+	}
+	return renderBriefing(b) + app.Link("Topics", "/home/briefing")
+}
+
+// renderBriefing renders a briefing's summary, if any, above its sections.
+func renderBriefing(b *Briefing) string {
+	var sb strings.Builder
+	if b.Summary != "" {
+		sb.WriteString(fmt.Sprintf(`<p style="margin:0 0 10px;white-space:pre-wrap">%s</p>`, htmlEsc(b.Summary)))
+	}
+	if len(b.Headlines) > 0 {
+		sb.WriteString(`<div class="text-sm" style="margin-bottom:8px">`)
+		for _, h := range b.Headlines {
+			sb.WriteString(fmt.Sprintf(`<div style="margin-bottom:4px"><a href="%s" target="_blank" rel="noopener noreferrer">%s</a> <span class="text-muted text-xs">%s</span></div>`,
+				htmlEsc(h.URL), htmlEsc(h.Title), htmlEsc(h.Category)))
+		}
+		sb.WriteString(`</div>`)
+	}
+	if b.Movers != "" {
+		sb.WriteString(fmt.Sprintf(`<div class="text-sm" style="margin-bottom:6px">📈 %s</div>`, htmlEsc(b.Movers)))
+	}
+	mailLine := "No unread mail"
+	if b.Unread > 0 {
+		mailLine = fmt.Sprintf("%d unread", b.Unread)
+		if len(b.Senders) > 0 {
+			mailLine += " from " + strings.Join(b.Senders, ", ")
+		}
+	}
+	sb.WriteString(fmt.Sprintf(`<div class="text-sm" style="margin-bottom:6px">✉️ <a href="/mail">%s</a></div>`, htmlEsc(mailLine)))
+	if len(b.Tasks) > 0 {
+		sb.WriteString(`<div class="text-sm">`)
+		for _, t := range b.Tasks {
+			sb.WriteString(fmt.Sprintf(`<div>☐ %s</div>`, htmlEsc(t)))
+		}
+		sb.WriteString(`</div>`)
+	}
+	return sb.String()
+}
+
+// BriefingHandler serves /home/briefing: GET shows today's briefing and the
+// topic settings (JSON with Accept: application/json); POST saves topics.
+func BriefingHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var cats []string
+		if app.SendsJSON(r) {
+			var req struct {
+				Categories []string `json:"categories"`
+			}
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.RespondError(w, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+			cats = req.Categories
+		} else {
+			if err := r.ParseForm(); err != nil {
+				app.BadRequest(w, r, "Failed to parse form")
+				return
+			}
+			cats = r.Form["categories"]
+		}
+		if err := SetBriefingPrefs(acc.ID, BriefingPrefs{Categories: cats}); err != nil {
+			app.ServerError(w, r, "Failed to save briefing settings")
+			return
+		}
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]bool{"success": true})
+			return
+		}
+		http.Redirect(w, r, "/home/briefing", http.StatusSeeOther)
+		return
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	now := time.Now()
+	b := GetBriefing(acc.ID, now)
+	if b == nil {
+		b = gatherBriefing(acc.ID, now)
+	}
+	prefs := GetBriefingPrefs(acc.ID)
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{
+			"briefing":   b,
+			"categories": prefs.Categories,
+			"enabled":    acc.HomeCardActive("briefing"),
+		})
+		return
+	}
+
+	chosen := map[string]bool{}
+	for _, c := range prefs.Categories {
+		chosen[c] = true
+	}
+	var boxes strings.Builder
+	for _, c := range newsCategories() {
+		checked := ""
+		if chosen[c] {
+			checked = " checked"
+		}
+		boxes.WriteString(fmt.Sprintf(`<label style="display:inline-flex;align-items:center;gap:6px;margin:0 12px 6px 0;font-size:14px"><input type="checkbox" name="categories" value="%s"%s> %s</label>`,
+			htmlEsc(c), checked, htmlEsc(c)))
+	}
+
+	status := "Your briefing is written each morning"
+	if !acc.HomeCardActive("briefing") {
+		status = `The briefing card is off. Turn on "Morning briefing" in your home screen settings to get one each morning`
+	}
+	cost := ""
+	if wallet.PaymentsEnabled() {
+		cost = fmt.Sprintf(" The written summary costs %dp; without credits you get the plain list.", wallet.CostDailyBriefing)
+	}
+	content := fmt.Sprintf(`<div class="card">%s</div>
+<div class="card">
+<h4>Topics</h4>
+<p class="text-sm text-muted">%s.%s Choose the news categories to include, or none for all.</p>
+<form method="POST" action="/home/briefing">
+<div style="margin:8px 0">%s</div>
+<button type="submit">Save</button>
+</form>
+</div>`, renderBriefing(b), status, cost, boxes.String())
+	w.Write([]byte(app.RenderHTMLForRequest("Morning briefing", "Your day at a glance", content, r)))
+}
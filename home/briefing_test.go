@@ -0,0 +1,57 @@
+package home
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mu/news"
+)
+
+func TestPickHeadlines(t *testing.T) {
+	now := time.Now()
+	post := func(title, cat string, ago time.Duration) *news.Post {
+		return &news.Post{Title: title, URL: "https://example.com/" + title, Category: cat, PostedAt: now.Add(-ago)}
+	}
+	feed := []*news.Post{
+		post("t1", "Tech", 5*time.Hour),
+		post("t2", "Tech", time.Hour),
+		post("t3", "Tech", 2*time.Hour),
+		post("w1", "World", 3*time.Hour),
+		post("f1", "Finance", 4*time.Hour),
+	}
+
+	// At most two per category, newest first.
+	got := pickHeadlines(feed, nil)
+	var titles []string
+	for _, h := range got {
+		titles = append(titles, h.Title)
+	}
+	if strings.Join(titles, ",") != "t2,t3,w1,f1" {
+		t.Fatalf("all categories = %v", titles)
+	}
+
+	// Only the chosen categories, case-insensitively.
+	got = pickHeadlines(feed, []string{"world"})
+	if len(got) != 1 || got[0].Title != "w1" {
+		t.Fatalf("world only = %+v", got)
+	}
+}
+
+func TestRenderBriefingPlain(t *testing.T) {
+	b := &Briefing{
+		Headlines: []BriefingHeadline{{Title: "<b>Big</b> news", URL: "https://example.com", Category: "World"}},
+		Unread:    2,
+		Senders:   []string{"alice"},
+		Tasks:     []string{"Dentist at 3pm"},
+	}
+	html := renderBriefing(b)
+	for _, want := range []string{"&lt;b&gt;Big&lt;/b&gt; news", "2 unread from alice", "Dentist at 3pm"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("plain briefing missing %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "white-space:pre-wrap") {
+		t.Error("plain briefing should have no summary paragraph")
+	}
+}
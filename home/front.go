@@ -0,0 +1,398 @@
+package home
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// The front page is what logged-out visitors see at the root. By default
+// that is the live home screen (Handler); an admin can instead publish a
+// page assembled from public sections at /admin/front. Edits are saved
+// as a draft, previewed, and only reach visitors once published.
+
+// FrontPage configures the logged-out front page.
+type FrontPage struct {
+	Enabled   bool      `json:"enabled"`
+	Headline  string    `json:"headline"`
+	About     string    `json:"about"` // markdown
+	CTA       string    `json:"cta"`
+	Sections  []string  `json:"sections"` // in display order
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// frontSections lists the sections an admin can place, in default order.
+var frontSections = []struct{ ID, Label string }{
+	{"about", "About"},
+	{"news", "Headlines"},
+	{"blog", "Latest posts"},
+	{"markets", "Markets"},
+	{"signup", "Sign up"},
+}
+
+const frontKey = "front.json"
+
+var (
+	frontMu     sync.RWMutex
+	frontDraft  *FrontPage
+	frontLive   *FrontPage
+	frontLoaded bool
+
+	// frontCache holds the rendered published page until it expires or
+	// the page is republished.
+	frontCache   string
+	frontCacheAt time.Time
+)
+
+type frontState struct {
+	Draft     *FrontPage `json:"draft,omitempty"`
+	Published *FrontPage `json:"published,omitempty"`
+}
+
+// loadFront reads the stored front page once (caller must hold frontMu).
+func loadFront() {
+	if frontLoaded {
+		return
+	}
+	var st frontState
+	data.LoadJSON(frontKey, &st)
+	frontDraft, frontLive = st.Draft, st.Published
+	frontLoaded = true
+}
+
+// saveFront stores the draft and published pages (caller must hold frontMu).
+func saveFront() error {
+	return data.SaveJSON(frontKey, frontState{Draft: frontDraft, Published: frontLive})
+}
+
+// defaultFront is the starting point for a first draft.
+func defaultFront() *FrontPage {
+	return &FrontPage{
+		Enabled:  true,
+		Headline: "Your personal home server",
+		About:    "News, mail, search, weather, markets and video, handled by one agent. No ads, no tracking.",
+		CTA:      "Create your free account",
+		Sections: []string{"about", "news", "blog", "signup"},
+	}
+}
+
+// PublishedFront returns a copy of the published front page, or nil.
+func PublishedFront() *FrontPage {
+	frontMu.Lock()
+	defer frontMu.Unlock()
+	loadFront()
+	if frontLive == nil {
+		return nil
+	}
+	p := *frontLive
+	return &p
+}
+
+// DraftFront returns a copy of the draft, falling back to the published
+// page and then the defaults.
+func DraftFront() *FrontPage {
+	frontMu.Lock()
+	defer frontMu.Unlock()
+	loadFront()
+	src := frontDraft
+	if src == nil {
+		src = frontLive
+	}
+	if src == nil {
+		return defaultFront()
+	}
+	p := *src
+	return &p
+}
+
+// SaveFrontDraft validates and stores a draft without publishing it.
+func SaveFrontDraft(p *FrontPage, by string) error {
+	known := map[string]bool{}
+	for _, s := range frontSections {
+		known[s.ID] = true
+	}
+	seen := map[string]bool{}
+	var sections []string
+	for _, id := range p.Sections {
+		if !known[id] {
+			return fmt.Errorf("unknown section %s", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			sections = append(sections, id)
+		}
+	}
+	if p.Enabled && len(sections) == 0 {
+		return fmt.Errorf("choose at least one section")
+	}
+	d := *p
+	d.Headline = strings.TrimSpace(d.Headline)
+	d.About = strings.TrimSpace(d.About)
+	d.CTA = strings.TrimSpace(d.CTA)
+	d.Sections = sections
+	d.UpdatedBy = by
+	d.UpdatedAt = time.Now()
+
+	frontMu.Lock()
+	defer frontMu.Unlock()
+	loadFront()
+	frontDraft = &d
+	return saveFront()
+}
+
+// PublishFront makes the draft the live front page.
+func PublishFront(by string) error {
+	frontMu.Lock()
+	defer frontMu.Unlock()
+	loadFront()
+	if frontDraft == nil {
+		return fmt.Errorf("no draft to publish")
+	}
+	p := *frontDraft
+	p.UpdatedBy = by
+	p.UpdatedAt = time.Now()
+	prevDraft, prevLive := frontDraft, frontLive
+	frontLive, frontDraft = &p, nil
+	if err := saveFront(); err != nil {
+		frontDraft, frontLive = prevDraft, prevLive
+		return err
+	}
+	frontCache = ""
+	return nil
+}
+
+// DiscardFrontDraft drops unpublished changes.
+func DiscardFrontDraft() error {
+	frontMu.Lock()
+	defer frontMu.Unlock()
+	loadFront()
+	if frontDraft == nil {
+		return nil
+	}
+	frontDraft = nil
+	return saveFront()
+}
+
+// cardHTML returns the cached content of a home card, or "".
+func cardHTML(id string) (title, content string) {
+	RefreshCards()
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+	for _, card := range Cards {
+		if card.ID == id {
+			content = card.CachedHTML
+			if strings.TrimSpace(content) == "" {
+				return "", ""
+			}
+			if card.Link != "" {
+				content += app.Link("More", card.Link)
+			}
+			return card.Title, content
+		}
+	}
+	return "", ""
+}
+
+// renderFront renders the sections of a front page. Public cards come
+// from the home card cache, so nothing here is per-visitor.
+func renderFront(p *FrontPage) string {
+	var b strings.Builder
+	if p.Headline != "" {
+		b.WriteString(fmt.Sprintf(`<h2 id="front-headline" style="text-align:center;margin:10px 0 20px">%s</h2>`, htmlEsc(p.Headline)))
+	}
+	for _, id := range p.Sections {
+		switch id {
+		case "about":
+			if p.About != "" {
+				b.WriteString(fmt.Sprintf(app.CardTemplate, "about", "front-about", "About", app.RenderString(p.About)))
+			}
+		case "signup":
+			cta := p.CTA
+			if cta == "" {
+				cta = "Sign up free"
+			}
+			b.WriteString(fmt.Sprintf(app.CardTemplate, "signup", "front-signup", "Join",
+				fmt.Sprintf(`<p><a href="/signup" class="btn">%s</a> <a href="/login" style="margin-left:10px;color:#888">Log in</a></p>`, htmlEsc(cta))))
+		default:
+			if title, content := cardHTML(id); content != "" {
+				b.WriteString(fmt.Sprintf(app.CardTemplate, id, id, title, content))
+			}
+		}
+	}
+	return `<div id="front">` + b.String() + `</div>`
+}
+
+// Front serves the root for logged-out visitors: the published front
+// page if there is one, otherwise the live home screen.
+func Front(w http.ResponseWriter, r *http.Request) {
+	p := PublishedFront()
+	if p == nil || !p.Enabled || app.WantsJSON(r) {
+		Handler(w, r)
+		return
+	}
+
+	frontMu.Lock()
+	if frontCache == "" || time.Since(frontCacheAt) > cacheTTL {
+		frontCache = renderFront(p)
+		frontCacheAt = time.Now()
+	}
+	body := frontCache
+	frontMu.Unlock()
+
+	w.Write([]byte(app.RenderHTMLForRequestWithBody("Home", "The home screen", body, ` class="page-home"`, r)))
+}
+
+// FrontAdminHandler serves /admin/front. GET shows the draft editor, or
+// with ?preview=1 renders the draft as visitors would see it; POST
+// applies one action: save, publish or discard.
+func FrontAdminHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		var req struct {
+			Action string `json:"action"`
+			FrontPage
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Action = r.FormValue("action")
+			req.Enabled = r.FormValue("enabled") == "on"
+			req.Headline = r.FormValue("headline")
+			req.About = r.FormValue("about")
+			req.CTA = r.FormValue("cta")
+			req.Sections = formSections(r)
+		}
+		switch req.Action {
+		case "save":
+			err = SaveFrontDraft(&req.FrontPage, acc.ID)
+		case "publish":
+			err = PublishFront(acc.ID)
+		case "discard":
+			err = DiscardFrontDraft()
+		default:
+			err = fmt.Errorf("unknown action")
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		app.Log("home", "%s front page %s", acc.ID, req.Action)
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"draft": DraftFront(), "published": PublishedFront()})
+			return
+		}
+		http.Redirect(w, r, "/admin/front", http.StatusSeeOther)
+		return
+	}
+
+	draft := DraftFront()
+	live := PublishedFront()
+
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"draft": draft, "published": live})
+		return
+	}
+
+	if r.URL.Query().Get("preview") == "1" {
+		banner := `<p class="text-sm" style="background:#fff8e1;border:1px solid #f0d98c;border-radius:6px;padding:8px 12px">Preview of the draft — not yet published. <a href="/admin/front">← Back to editor</a></p>`
+		page := banner + renderFront(draft)
+		if !draft.Enabled {
+			page = banner + `<p class="text-muted">The front page is disabled in this draft, so visitors will see the live home screen.</p>`
+		}
+		w.Write([]byte(app.RenderHTMLForRequest("Front Page Preview", "Front page preview", page, r)))
+		return
+	}
+
+	frontMu.RLock()
+	hasDraft := frontDraft != nil
+	frontMu.RUnlock()
+
+	state := "Not published — visitors see the live home screen."
+	if live != nil {
+		if live.Enabled {
+			state = fmt.Sprintf("Published %s by %s.", app.TimeAgo(live.UpdatedAt), htmlEsc(live.UpdatedBy))
+		} else {
+			state = fmt.Sprintf("Disabled %s by %s — visitors see the live home screen.", app.TimeAgo(live.UpdatedAt), htmlEsc(live.UpdatedBy))
+		}
+	}
+	if hasDraft {
+		state += fmt.Sprintf(` <strong>Unpublished draft</strong> saved %s.`, app.TimeAgo(draft.UpdatedAt))
+	}
+
+	pos := map[string]int{}
+	for i, id := range draft.Sections {
+		pos[id] = i + 1
+	}
+	var rows strings.Builder
+	for i, s := range frontSections {
+		checked := ""
+		n := len(frontSections) + i + 1
+		if p, ok := pos[s.ID]; ok {
+			checked = " checked"
+			n = p
+		}
+		rows.WriteString(fmt.Sprintf(`<tr><td><label><input type="checkbox" name="section" value="%s"%s> %s</label></td><td><input type="number" name="order_%s" value="%d" min="1" style="width:60px"></td></tr>`,
+			s.ID, checked, s.Label, s.ID, n))
+	}
+	enabled := ""
+	if draft.Enabled {
+		enabled = " checked"
+	}
+
+	btn := `style="font-size:12px;padding:2px 8px;border-radius:4px;background:#fff;cursor:pointer;border:1px solid #ccc"`
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(`<h3>Front Page</h3>`)
+	content.WriteString(`<p class="text-sm text-muted">What logged-out visitors see at <a href="/">/</a>. Save a draft, preview it, then publish.</p>`)
+	content.WriteString(`<p class="text-sm">` + state + `</p>`)
+	content.WriteString(fmt.Sprintf(`<form method="POST" class="block-form">
+		<input type="hidden" name="action" value="save">
+		<label><input type="checkbox" name="enabled"%s> Show this page instead of the live home screen</label>
+		<input type="text" name="headline" value="%s" placeholder="Headline">
+		<textarea name="about" rows="5" placeholder="About (markdown)">%s</textarea>
+		<input type="text" name="cta" value="%s" placeholder="Sign-up button text">
+		<table class="email-log"><tr><th>Section</th><th>Order</th></tr>%s</table>
+		<button type="submit">Save draft</button>
+	</form>`, enabled, htmlEsc(draft.Headline), htmlEsc(draft.About), htmlEsc(draft.CTA), rows.String()))
+	content.WriteString(`<p class="mt-2"><a href="/admin/front?preview=1">Preview draft →</a></p>`)
+	if hasDraft {
+		content.WriteString(fmt.Sprintf(`<form method="POST" class="d-inline"><input type="hidden" name="action" value="publish"><button type="submit" %s>Publish</button></form>
+		<form method="POST" class="d-inline" onsubmit="return confirm('Discard the draft?')"><input type="hidden" name="action" value="discard"><button type="submit" %s>Discard draft</button></form>`, btn, btn))
+	}
+	content.WriteString(`</div>`)
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest("Front Page", "Front page", content.String(), r)))
+}
+
+// formSections returns the ticked sections ordered by their order fields.
+func formSections(r *http.Request) []string {
+	ids := r.Form["section"]
+	order := func(id string) int {
+		n, err := strconv.Atoi(r.FormValue("order_" + id))
+		if err != nil {
+			return len(frontSections) + 1
+		}
+		return n
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return order(ids[i]) < order(ids[j]) })
+	return ids
+}
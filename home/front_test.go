@@ -0,0 +1,78 @@
+package home
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func resetFront() {
+	frontMu.Lock()
+	frontDraft, frontLive, frontLoaded, frontCache = nil, nil, false, ""
+	frontMu.Unlock()
+}
+
+func TestFrontDraftAndPublish(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	resetFront()
+
+	if PublishedFront() != nil {
+		t.Fatal("published without an admin")
+	}
+	if err := SaveFrontDraft(&FrontPage{Enabled: true, Sections: []string{"ads"}}, "admin"); err == nil {
+		t.Error("saved an unknown section")
+	}
+	if err := SaveFrontDraft(&FrontPage{Enabled: true}, "admin"); err == nil {
+		t.Error("saved an enabled page with no sections")
+	}
+	draft := &FrontPage{Enabled: true, Headline: "Hi <there>", About: "Hello **world**", Sections: []string{"signup", "about", "signup"}}
+	if err := SaveFrontDraft(draft, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if PublishedFront() != nil {
+		t.Fatal("draft is live before publishing")
+	}
+	if got := DraftFront().Sections; strings.Join(got, ",") != "signup,about" {
+		t.Fatalf("sections = %v", got)
+	}
+
+	if err := PublishFront("admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishFront("admin"); err == nil {
+		t.Error("published with no draft")
+	}
+
+	// The published page survives a restart.
+	resetFront()
+	live := PublishedFront()
+	if live == nil || live.Headline != "Hi <there>" {
+		t.Fatalf("published = %+v", live)
+	}
+
+	w := httptest.NewRecorder()
+	Front(w, httptest.NewRequest("GET", "/", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "Hi &lt;there&gt;") || !strings.Contains(body, "<strong>world</strong>") {
+		t.Fatalf("front page missing content:\n%s", body)
+	}
+	if strings.Index(body, "front-signup") > strings.Index(body, "front-about") {
+		t.Error("sections out of order")
+	}
+
+	// A new draft leaves the cached live page alone until published.
+	draft.Headline = "Changed"
+	SaveFrontDraft(draft, "admin")
+	w = httptest.NewRecorder()
+	Front(w, httptest.NewRequest("GET", "/", nil))
+	if strings.Contains(w.Body.String(), "Changed") {
+		t.Fatal("draft leaked to visitors")
+	}
+	PublishFront("admin")
+	w = httptest.NewRecorder()
+	Front(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), "Changed") {
+		t.Fatal("publish did not refresh the cached page")
+	}
+}
@@ -76,16 +76,26 @@ type Card struct {
 	Column      string // "left" or "right"
 	Position    int
 	Link        string
+	TTL         time.Duration // How long content stays fresh; cacheTTL if zero
 	Content     func() string
 	CachedHTML  string    // Cached rendered content
 	ContentHash string    // Hash of content for change detection
 	UpdatedAt   time.Time // Last update timestamp
+	RefreshedAt time.Time // Last render, changed or not
+}
+
+// ttl is how long the card's content stays fresh: how often it's
+// re-rendered, and refreshed on the page.
+func (c *Card) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return cacheTTL
 }
 
 var (
-	lastRefresh time.Time
-	cacheMutex  sync.RWMutex
-	cacheTTL    = 2 * time.Minute
+	cacheMutex sync.RWMutex
+	cacheTTL   = 2 * time.Minute
 )
 
 type CardConfig struct {
@@ -96,6 +106,7 @@ type CardConfig struct {
 		Position int    `json:"position"`
 		Link     string `json:"link"`
 		Icon     string `json:"icon"`
+		TTL      int    `json:"ttl"` // seconds
 	} `json:"left"`
 	Right []struct {
 		ID       string `json:"id"`
@@ -104,12 +115,36 @@ type CardConfig struct {
 		Position int    `json:"position"`
 		Link     string `json:"link"`
 		Icon     string `json:"icon"`
+		TTL      int    `json:"ttl"` // seconds
 	} `json:"right"`
 }
 
 var Cards []Card
 
+// PluginCards returns the home cards contributed by plugins, with their
+// CachedHTML already rendered. Wired from main.go.
+var PluginCards func() []Card
+
+func pluginCards() []Card {
+	if PluginCards == nil {
+		return nil
+	}
+	return PluginCards()
+}
+
+// pluginCardHTML renders a plugin card. Its title comes from outside Mu,
+// so it's escaped.
+func pluginCardHTML(card Card) string {
+	content := card.CachedHTML
+	if card.Link != "" {
+		content += app.Link("More", card.Link)
+	}
+	return fmt.Sprintf(app.CardTemplate, card.ID, card.ID, htmlEsc(card.Title), content)
+}
+
 func Load() {
+	loadBriefings()
+
 	b, _ := f.ReadFile("cards.json")
 	var config CardConfig
 	if err := json.Unmarshal(b, &config); err != nil {
@@ -123,6 +158,7 @@ func Load() {
 		"blog":     blog.Preview,
 		"chat":     ChatCard,
 		"news":     newsCard,
+		"trending": news.Trending,
 		"markets":  markets.MarketsHTML,
 		"reminder": reminder.ReminderHTML,
 		"video":    video.Latest,
@@ -144,6 +180,7 @@ func Load() {
 				Column:   "left",
 				Position: c.Position,
 				Link:     c.Link,
+				TTL:      time.Duration(c.TTL) * time.Second,
 				Content:  fn,
 			})
 		}
@@ -158,11 +195,22 @@ func Load() {
 				Column:   "right",
 				Position: c.Position,
 				Link:     c.Link,
+				TTL:      time.Duration(c.TTL) * time.Second,
 				Content:  fn,
 			})
 		}
 	}
 
+	// Cards packages have registered, and home's own for members.
+	registerBuiltinCards()
+	registryMu.RLock()
+	for _, def := range registry {
+		if def.Content != nil {
+			addCachedCard(def)
+		}
+	}
+	registryMu.RUnlock()
+
 	// Sort by column and position
 	sort.Slice(Cards, func(i, j int) bool {
 		if Cards[i].Column != Cards[j].Column {
@@ -207,15 +255,14 @@ func RefreshCards() {
 	defer cacheMutex.Unlock()
 
 	now := time.Now()
-
-	// Check if cache is still valid
-	if now.Sub(lastRefresh) < cacheTTL {
-		return
-	}
-
 	for i := range Cards {
 		card := &Cards[i]
 
+		// Each card keeps for its own TTL.
+		if now.Sub(card.RefreshedAt) < card.ttl() {
+			continue
+		}
+
 		// Get fresh content
 		content := card.Content()
 
@@ -228,22 +275,25 @@ func RefreshCards() {
 			card.ContentHash = hash
 			card.UpdatedAt = now
 		}
+		card.RefreshedAt = now
 	}
-
-	lastRefresh = now
 }
 
 // ForceRefresh forces an immediate cache refresh (for admin actions)
 func ForceRefresh() {
 	cacheMutex.Lock()
-	lastRefresh = time.Time{} // Reset to zero to force refresh
+	for i := range Cards {
+		Cards[i].RefreshedAt = time.Time{} // Reset to zero to force refresh
+	}
 	cacheMutex.Unlock()
 	RefreshCards()
 }
 
-// CardHandler serves individual card HTML fragments at /home/card/{id}.
-// Each card loads independently so one slow/broken card can't block
-// the entire home page.
+// CardHandler serves individual cards at /home/card/{id}: the card's
+// HTML, or with Accept: application/json its id, title, body HTML and TTL
+// in seconds, for the home page to refresh cards in place. Each card loads
+// independently so one slow/broken card can't block the entire home page,
+// and responses may be cached for the card's TTL.
 func CardHandler(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/home/card/")
 	if id == "" {
@@ -251,30 +301,6 @@ func CardHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// User-specific cards that need session context.
-	if id == "mail" || id == "web" {
-		viewerID := ""
-		if sess, _ := auth.TrySession(r); sess != nil {
-			viewerID = sess.Account
-		}
-		if viewerID == "" {
-			w.WriteHeader(204)
-			return
-		}
-		switch id {
-		case "mail":
-			content := mail.GetRecentThreadsPreview(viewerID, 3)
-			content += app.Link("More", "/mail")
-			w.Header().Set("Content-Type", "text/html")
-			fmt.Fprintf(w, app.CardTemplate, "mail", "mail", "Mail", content)
-		case "web":
-			content := `<form method="GET" action="/web"><input type="text" name="q" placeholder="Search the web..." style="width:100%%;padding:8px;border:1px solid #ddd;border-radius:6px;font-size:14px;box-sizing:border-box"></form>`
-			w.Header().Set("Content-Type", "text/html")
-			fmt.Fprintf(w, app.CardTemplate, "web", "web", "Search", content)
-		}
-		return
-	}
-
 	// App widget cards.
 	if strings.HasPrefix(id, "app-") {
 		slug := strings.TrimPrefix(id, "app-")
@@ -289,43 +315,90 @@ func CardHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Standard cached cards — serve with a 3-second timeout to prevent
-	// deadlocks from blocking the response.
-	done := make(chan string, 1)
-	go func() {
-		RefreshCards()
-		cacheMutex.RLock()
-		defer cacheMutex.RUnlock()
-		for _, card := range Cards {
+	// Plugin cards.
+	if strings.HasPrefix(id, "plugin-") {
+		for _, card := range pluginCards() {
 			if card.ID == id {
-				content := card.CachedHTML
-				if strings.TrimSpace(content) == "" {
-					done <- ""
-					return
-				}
-				if card.Link != "" {
-					content += app.Link("More", card.Link)
-				}
-				done <- fmt.Sprintf(app.CardTemplate, card.ID, card.ID, card.Title, content)
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(pluginCardHTML(card)))
 				return
 			}
 		}
-		done <- ""
+		w.WriteHeader(204)
+		return
+	}
+
+	var def CardDef
+	var found bool
+	for _, d := range homeCards() {
+		if d.ID == id {
+			def, found = d, true
+			break
+		}
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	var viewerID string
+	if sess, _ := auth.TrySession(r); sess != nil {
+		viewerID = sess.Account
+	}
+	// A member's own cards need them signed in.
+	if def.User != nil && viewerID == "" {
+		w.WriteHeader(204)
+		return
+	}
+
+	// Render with a 3-second timeout to prevent deadlocks from blocking
+	// the response.
+	done := make(chan string, 1)
+	go func() {
+		if def.User == nil {
+			RefreshCards()
+		}
+		done <- cardBody(def, viewerID)
 	}()
 
+	var content string
 	select {
-	case html := <-done:
-		if html == "" {
-			w.WriteHeader(204)
-			return
-		}
-		w.Header().Set("Content-Type", "text/html")
-		w.Header().Set("Cache-Control", "no-store")
-		w.Write([]byte(html))
+	case content = <-done:
 	case <-time.After(3 * time.Second):
 		app.Log("home", "Card %s timed out", id)
 		w.WriteHeader(204)
+		return
+	}
+	if strings.TrimSpace(content) == "" {
+		w.WriteHeader(204)
+		return
+	}
+	if def.Link != "" {
+		content += app.Link("More", def.Link)
+	}
+
+	// Cards can be personal (mail, or the video card's continue
+	// watching), so only the browser may keep them.
+	sum := sha256.Sum256([]byte(content))
+	etag := fmt.Sprintf(`"%x"`, sum[:8])
+	ttl := def.ttl()
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(ttl.Seconds())))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept, Cookie")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]any{
+			"id":    def.ID,
+			"title": def.Title,
+			"html":  content,
+			"ttl":   int(ttl.Seconds()),
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprintf(w, app.CardTemplate, def.ID, def.ID, def.Title, content)
 }
 
 // RefreshHandler clears the last_visit cookie to show all cards again
@@ -345,9 +418,22 @@ func RefreshHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
 
+// personalise adds what's the viewer's own to a shared card: the video
+// card leads with what they're part way through.
+func personalise(id, viewerID, content string) string {
+	if id == "video" && viewerID != "" {
+		return video.ContinueWatchingHTML(viewerID) + content
+	}
+	return content
+}
+
 func Handler(w http.ResponseWriter, r *http.Request) {
 	// JSON endpoint for auto-refresh polling
 	if app.WantsJSON(r) {
+		var viewerID string
+		if sess, _ := auth.TrySession(r); sess != nil {
+			viewerID = sess.Account
+		}
 		RefreshCards()
 		cacheMutex.RLock()
 		type cardData struct {
@@ -364,7 +450,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			result = append(result, cardData{
 				ID:     card.ID,
 				Title:  card.Title,
-				HTML:   card.CachedHTML,
+				HTML:   personalise(card.ID, viewerID, card.CachedHTML),
 				Column: card.Column,
 			})
 		}
@@ -508,28 +594,6 @@ function fetchW(la,lo){
 
 	// Inline card preferences panel
 	if viewerAcc != nil {
-		allCardDefs := []struct{ id, label string }{
-			{"reminder", "Islam"}, {"blog", "Blog"}, {"news", "News"},
-			{"markets", "Markets"}, {"social", "Social"}, {"video", "Video"},
-			{"images", "Images"}, {"mail", "Mail"}, {"web", "Search"},
-		}
-		optIn := map[string]bool{"mail": true, "web": true}
-		activeSet := map[string]bool{}
-		for _, c := range allCardDefs {
-			if optIn[c.id] {
-				activeSet[c.id] = viewerAcc.HomeCardActive(c.id)
-			} else {
-				activeSet[c.id] = viewerAcc.ShowHomeCard(c.id)
-			}
-		}
-		var checkboxes string
-		for _, c := range allCardDefs {
-			checked := ""
-			if activeSet[c.id] {
-				checked = " checked"
-			}
-			checkboxes += fmt.Sprintf(`<label style="display:flex;align-items:center;gap:8px;padding:6px 0;font-size:14px;border-bottom:1px solid #f0f0f0"><input type="checkbox" name="cards" value="%s"%s style="width:18px;height:18px"> %s</label>`, c.id, checked, c.label)
-		}
 		// App widget checkboxes — any public app can be pinned as a card.
 		var widgetCheckboxes string
 		activeWidgets := map[string]bool{}
@@ -551,8 +615,8 @@ function fetchW(la,lo){
 
 		b.WriteString(fmt.Sprintf(`<div id="home-card-prefs" style="display:none;padding:12px 16px;margin-bottom:12px;background:#f9f9f9;border-radius:8px;border:1px solid #eee">
 <p style="font-weight:600;font-size:14px;margin:0 0 4px">Customise home screen</p>
-<p style="font-size:12px;color:#999;margin:0 0 8px">Choose what your agent keeps an eye on.</p>
-<div id="card-checkboxes">%s</div>`, checkboxes))
+<p style="font-size:12px;color:#999;margin:0 0 8px">Choose what your agent keeps an eye on, and drag cards into the order you want.</p>
+%s`, renderLayoutPanel(viewerAcc)))
 		if widgetCheckboxes != "" {
 			b.WriteString(fmt.Sprintf(`<p style="font-weight:600;font-size:13px;margin:10px 0 4px">Apps</p>
 <p style="font-size:12px;color:#999;margin:0 0 6px">Pin apps to the top of your home screen.</p>
@@ -561,11 +625,11 @@ function fetchW(la,lo){
 		b.WriteString(`<script>
 (function(){
   function csrfToken(){var m=document.cookie.match(/(?:^|; )csrf_token=([^;]+)/);return m?decodeURIComponent(m[1]):'';}
-  function savePrefs(type,containerId){
+  function savePrefs(containerId){
     var checks=document.querySelectorAll('#'+containerId+' input[type=checkbox]');
     var body=new URLSearchParams();
-    body.set(type==='cards'?'save_cards':'save_widgets','1');
-    checks.forEach(function(c){if(c.checked)body.append(type==='cards'?'cards':'widgets',c.value)});
+    body.set('save_widgets','1');
+    checks.forEach(function(c){if(c.checked)body.append('widgets',c.value)});
     var h={'Content-Type':'application/x-www-form-urlencoded'};
     var tok=csrfToken();if(tok)h['X-CSRF-Token']=tok;
     fetch('/account',{method:'POST',credentials:'same-origin',headers:h,body:body.toString()})
@@ -574,78 +638,45 @@ function fetchW(la,lo){
   // Delay listener attachment so browser form-restore doesn't
   // trigger an immediate save+reload loop.
   setTimeout(function(){
-    document.querySelectorAll('#card-checkboxes input').forEach(function(c){
-      c.addEventListener('change',function(){savePrefs('cards','card-checkboxes')});
-    });
     document.querySelectorAll('#widget-checkboxes input').forEach(function(c){
-      c.addEventListener('change',function(){savePrefs('widgets','widget-checkboxes')});
+      c.addEventListener('change',function(){savePrefs('widget-checkboxes')});
     });
   }, 500);
 })();
-</script></div>`)
-	}
-
-	// Which cards to show. Default cards (cards.json) show unless the user has
-	// deselected them; cards added after the user last customised default to
-	// visible (see auth.Account.ShowHomeCard). Order and column come from
-	// cards.json. mail/web are opt-in and off unless explicitly enabled.
-	showDefault := func(id string) bool {
-		if viewerAcc == nil {
-			return true // logged out → every default card shows
-		}
-		return viewerAcc.ShowHomeCard(id)
-	}
-	isCardEnabled := func(id string) bool {
-		if viewerAcc == nil {
-			return false // mail/web are opt-in, never in the default set
-		}
-		return viewerAcc.HomeCardActive(id)
-	}
-
-	tooltips := map[string]string{
-		"blog":     "Microblog posts with daily AI-generated digests",
-		"news":     "Headlines from RSS feeds, sorted by time",
-		"markets":  "Live crypto, futures, and commodity prices",
-		"reminder": "Daily Islamic reminder with verse and hadith",
-		"social":   "Public discussion threads",
-		"video":    "Latest videos from curated channels",
+</script>` + app.Script(r, layoutPanelScript) + `</div>`)
 	}
 
+	// Which cards to show, in the viewer's order. Default cards show unless
+	// the member has turned them off; cards added after they last customised
+	// default to visible (see auth.Account.ShowHomeCard). Opt-in cards
+	// (mail, web, briefing, prayer) are off unless turned on.
 	var leftHTML, rightHTML []string
-	for _, card := range Cards {
-		if !showDefault(card.ID) {
-			continue
-		}
-		content := card.CachedHTML
+	refresh := map[string]int{} // card ID → seconds between refreshes
+	for _, def := range layout(viewerAcc) {
+		content := cardBody(def, viewerID)
 		if strings.TrimSpace(content) == "" {
 			continue
 		}
-		if card.Link != "" {
-			content += app.Link("More", card.Link)
+		if ttl := def.ttl(); ttl > 0 {
+			refresh[def.ID] = int(ttl.Seconds())
+		}
+		if def.Link != "" {
+			content += app.Link("More", def.Link)
 		}
-		title := card.Title
-		if tip, ok := tooltips[card.ID]; ok {
-			title += fmt.Sprintf(` <span class="card-tooltip" data-tip="%s" onclick="event.stopPropagation();document.querySelectorAll('.card-tooltip.show').forEach(function(e){e.classList.remove('show')});this.classList.toggle('show')">?</span>`, htmlEsc(tip))
+		title := def.Title
+		if def.Tooltip != "" {
+			title += fmt.Sprintf(` <span class="card-tooltip" data-tip="%s" onclick="event.stopPropagation();document.querySelectorAll('.card-tooltip.show').forEach(function(e){e.classList.remove('show')});this.classList.toggle('show')">?</span>`, htmlEsc(def.Tooltip))
 		}
-		html := fmt.Sprintf(app.CardTemplate, card.ID, card.ID, title, content)
-		if card.Column == "left" {
+		html := fmt.Sprintf(app.CardTemplate, def.ID, def.ID, title, content)
+		if def.Column == "left" {
 			leftHTML = append(leftHTML, html)
 		} else {
 			rightHTML = append(rightHTML, html)
 		}
 	}
 
-	// Per-user cards (opt-in): mail and web search.
-	if viewerID != "" {
-		if isCardEnabled("mail") {
-			mailContent := mail.GetRecentThreadsPreview(viewerID, 3)
-			mailContent += app.Link("More", "/mail")
-			rightHTML = append(rightHTML, fmt.Sprintf(app.CardTemplate, "mail", "mail", "Mail", mailContent))
-		}
-		if isCardEnabled("web") {
-			webContent := `<form method="GET" action="/web"><input type="text" name="q" placeholder="Search the web..." style="width:100%;padding:8px;border:1px solid #ddd;border-radius:6px;font-size:14px;box-sizing:border-box"></form>`
-			rightHTML = append(rightHTML, fmt.Sprintf(app.CardTemplate, "web", "web", "Search", webContent))
-		}
+	for _, card := range pluginCards() {
+		rightHTML = append(rightHTML, pluginCardHTML(card))
 	}
 
 	if len(leftHTML) > 0 || len(rightHTML) > 0 {
@@ -654,12 +685,15 @@ function fetchW(la,lo){
 
 	b.WriteString(`</div>`) // close #home-cards
 
-	// Auto-refresh: poll every 2 minutes, update card content in-place
+	// Auto-refresh: each card from /home/card/{id} as often as it changes,
+	// updated in place while the page is visible.
 	displayMode := r.URL.Query().Get("mode") == "display"
-	refreshInterval := 120000 // 2 minutes
 	if displayMode {
-		refreshInterval = 60000 // 1 minute in display mode
+		for id, secs := range refresh {
+			refresh[id] = min(secs, 60) // at least every minute in display mode
+		}
 	}
+	refreshJSON, _ := json.Marshal(refresh)
 	wakeLockJS := ""
 	if displayMode {
 		wakeLockJS = `
@@ -672,22 +706,21 @@ function fetchW(la,lo){
 	}
 	b.WriteString(fmt.Sprintf(`<script>
 (function(){
-  var interval = %d;
-  setInterval(function(){
-    fetch('/', {headers:{Accept:'application/json'}})
-    .then(function(r){return r.json()})
-    .then(function(cards){
-      cards.forEach(function(c){
-        var el = document.getElementById(c.id);
-        if(el){
-          var content = el.querySelector('.card-body');
-          if(content) content.innerHTML = c.html;
-        }
-      });
-    }).catch(function(){});
-  }, interval);%s
+  var refresh = %s;
+  Object.keys(refresh).forEach(function(id){
+    setInterval(function(){
+      if(document.hidden) return;
+      fetch('/home/card/'+encodeURIComponent(id), {credentials:'same-origin', headers:{Accept:'application/json'}})
+      .then(function(r){return r.status === 200 ? r.json() : null})
+      .then(function(c){
+        var el = document.getElementById(id);
+        var content = el && el.querySelector('.card-body');
+        if(c && content && content.innerHTML !== c.html) content.innerHTML = c.html;
+      }).catch(function(){});
+    }, refresh[id] * 1000);
+  });%s
 })();
-</script>`, refreshInterval, wakeLockJS))
+</script>`, refreshJSON, wakeLockJS))
 
 	// Deep-link prefill: /?q=... or /home?prompt=... seeds the agent and submits
 	// it, so a shared link lands on the home screen with the answer already coming.
@@ -705,11 +738,16 @@ function fetchW(la,lo){
 		bodyClass = ` class="page-home display-mode"`
 	}
 
-	lang := app.GetUserLanguage(r)
-	html := app.RenderHTMLWithLangAndBody("Home", "The home screen", b.String(), lang, bodyClass, viewerAcc)
+	html := app.RenderHTMLForRequestWithBody("Home", "The home screen", b.String(), bodyClass, r)
 	w.Write([]byte(html))
 }
 
+// prayerCard is the prayer times card: the next prayer where the user
+// is and the Hijri date.
+func prayerCard(userID string) string {
+	return reminder.PrayerCard(userID) + app.Link("More", "/islam/prayer")
+}
+
 // htmlEsc escapes HTML special characters.
 func htmlEsc(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
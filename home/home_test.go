@@ -1,7 +1,14 @@
 package home
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"mu/video"
 )
 
 func TestHtmlEsc(t *testing.T) {
@@ -22,3 +29,95 @@ func TestHtmlEsc(t *testing.T) {
 		}
 	}
 }
+
+func TestPersonaliseVideoCard(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer video.DeleteHistory("home_viewer")
+	if err := video.RecordWatch("home_viewer", "dQw4w9WgXcQ", 120, 600); err != nil {
+		t.Fatal(err)
+	}
+	if got := personalise("video", "home_viewer", "latest"); !strings.Contains(got, "Continue watching") || !strings.HasSuffix(got, "latest") {
+		t.Errorf("video card = %q", got)
+	}
+	if got := personalise("video", "", "latest"); got != "latest" {
+		t.Errorf("logged out = %q", got)
+	}
+	if got := personalise("news", "home_viewer", "headlines"); got != "headlines" {
+		t.Errorf("news card = %q", got)
+	}
+}
+
+func TestCardHandler(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	registryMu.Lock()
+	origRegistry := registry
+	registry = nil
+	registryMu.Unlock()
+	cacheMutex.Lock()
+	origCards := Cards
+	renders := 0
+	Cards = []Card{{ID: "markets", Title: "Markets", Column: "right", Link: "/markets", TTL: time.Minute, Content: func() string {
+		renders++
+		return "prices"
+	}}}
+	cacheMutex.Unlock()
+	defer func() {
+		registryMu.Lock()
+		registry = origRegistry
+		registryMu.Unlock()
+		cacheMutex.Lock()
+		Cards = origCards
+		cacheMutex.Unlock()
+	}()
+	RegisterCard(CardDef{ID: "mine", Title: "Mine", User: func(userID string) string { return "for " + userID }})
+
+	get := func(path, accept, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		CardHandler(w, r)
+		return w
+	}
+
+	w := get("/home/card/markets", "application/json", "")
+	var card struct {
+		ID   string `json:"id"`
+		HTML string `json:"html"`
+		TTL  int    `json:"ttl"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&card); err != nil {
+		t.Fatal(err)
+	}
+	if card.ID != "markets" || !strings.HasPrefix(card.HTML, "prices") || card.TTL != 60 {
+		t.Errorf("card = %+v", card)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "private, max-age=60" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+
+	// Within its TTL the card isn't rendered again, and an unchanged card
+	// isn't sent again.
+	etag := w.Header().Get("ETag")
+	if w := get("/home/card/markets", "application/json", etag); w.Code != http.StatusNotModified {
+		t.Errorf("revalidate: %d", w.Code)
+	}
+	if renders != 1 {
+		t.Errorf("rendered %d times", renders)
+	}
+	if w := get("/home/card/markets", "", ""); !strings.Contains(w.Body.String(), `<div id="markets" class="card">`) {
+		t.Errorf("html = %s", w.Body)
+	}
+
+	if w := get("/home/card/mine", "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("member card logged out: %d", w.Code)
+	}
+	if w := get("/home/card/nope", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown card: %d", w.Code)
+	}
+}
@@ -0,0 +1,387 @@
+package home
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/mail"
+	"mu/weather"
+)
+
+// Home screen layout: which cards a member sees, and in what order.
+//
+// Cards come from cards.json, from packages that register one with
+// RegisterCard, and from plugins. Members turn cards on and off and drag
+// them into their own order from the customise panel; both are kept on
+// their account (HomeCards, HomeOrder).
+
+// CardDef is a card a package adds to the home screen.
+type CardDef struct {
+	ID       string
+	Title    string
+	Link     string // the "More" link under it
+	Column   string // "left" or "right"; right if empty
+	Position int    // default order within the column
+	Tooltip  string
+	OptIn    bool // off until a member turns it on
+	// TTL is how long the card stays fresh, in the cache and on the page.
+	// Zero is two minutes; negative is a card that never changes.
+	TTL time.Duration
+
+	// Content renders a card that's the same for everyone. It's cached
+	// and refreshed with the cards from cards.json.
+	Content func() string
+	// User renders a member's own card, which only members see.
+	User func(userID string) string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []CardDef
+)
+
+// cardTooltips explain the cards from cards.json.
+var cardTooltips = map[string]string{
+	"blog":     "Microblog posts with daily AI-generated digests",
+	"news":     "Headlines from RSS feeds, sorted by time",
+	"markets":  "Live crypto, futures, and commodity prices",
+	"reminder": "Daily Islamic reminder with verse and hadith",
+	"social":   "Public discussion threads",
+	"video":    "Latest videos from curated channels",
+}
+
+// RegisterCard adds a card to the home screen, replacing any registered
+// with the same ID. A card needs Content or User to render.
+func RegisterCard(def CardDef) {
+	if def.ID == "" || (def.Content == nil && def.User == nil) {
+		return
+	}
+	if def.Column != "left" {
+		def.Column = "right"
+	}
+	registryMu.Lock()
+	replaced := false
+	for i := range registry {
+		if registry[i].ID == def.ID {
+			registry[i] = def
+			replaced = true
+		}
+	}
+	if !replaced {
+		registry = append(registry, def)
+	}
+	registryMu.Unlock()
+
+	if def.Content != nil {
+		cacheMutex.Lock()
+		addCachedCard(def) // rendered on the next refresh
+		cacheMutex.Unlock()
+	}
+}
+
+// addCachedCard puts a shared card among the cached ones. Caller must hold
+// cacheMutex, or be loading.
+func addCachedCard(def CardDef) {
+	c := Card{
+		ID:       def.ID,
+		Title:    def.Title,
+		Column:   def.Column,
+		Position: def.Position,
+		Link:     def.Link,
+		TTL:      def.TTL,
+		Content:  def.Content,
+	}
+	for i := range Cards {
+		if Cards[i].ID == def.ID {
+			Cards[i] = c
+			return
+		}
+	}
+	Cards = append(Cards, c)
+}
+
+// registerBuiltinCards registers home's own cards for members.
+func registerBuiltinCards() {
+	RegisterCard(CardDef{ID: "briefing", Title: "Morning briefing", Column: "left", Position: -2, OptIn: true, TTL: 30 * time.Minute, User: BriefingCard})
+	RegisterCard(CardDef{ID: "prayer", Title: "Prayer times", Column: "left", Position: -1, OptIn: true, TTL: 5 * time.Minute, User: prayerCard})
+	RegisterCard(CardDef{ID: "weather", Title: "Weather", Link: "/weather", Position: -1, TTL: 10 * time.Minute, Tooltip: "The forecast for your saved place", User: weather.Card})
+	RegisterCard(CardDef{ID: "mail", Title: "Mail", Link: "/mail", Position: 100, OptIn: true, TTL: time.Minute, User: func(userID string) string {
+		return mail.GetRecentThreadsPreview(userID, 3)
+	}})
+	RegisterCard(CardDef{ID: "web", Title: "Search", Position: 101, OptIn: true, TTL: -1, User: func(string) string {
+		return `<form method="GET" action="/web"><input type="text" name="q" placeholder="Search the web..." style="width:100%;padding:8px;border:1px solid #ddd;border-radius:6px;font-size:14px;box-sizing:border-box"></form>`
+	}})
+}
+
+// homeCards returns every card a home screen can show in the default
+// order: by column, then position.
+func homeCards() []CardDef {
+	registryMu.RLock()
+	defs := append([]CardDef(nil), registry...)
+	registryMu.RUnlock()
+	registered := map[string]bool{}
+	for _, d := range defs {
+		registered[d.ID] = true
+	}
+	cacheMutex.RLock()
+	for _, c := range Cards {
+		if !registered[c.ID] {
+			defs = append(defs, CardDef{
+				ID:       c.ID,
+				Title:    c.Title,
+				Link:     c.Link,
+				Column:   c.Column,
+				Position: c.Position,
+				Tooltip:  cardTooltips[c.ID],
+				TTL:      c.TTL,
+				Content:  c.Content,
+			})
+		}
+	}
+	cacheMutex.RUnlock()
+	sort.SliceStable(defs, func(i, j int) bool {
+		if defs[i].Column != defs[j].Column {
+			return defs[i].Column < defs[j].Column
+		}
+		return defs[i].Position < defs[j].Position
+	})
+	return defs
+}
+
+// arrange puts cards in a member's own order: those they've placed
+// first, as placed, then the rest in the default order. Cards stay in
+// their column.
+func arrange(defs []CardDef, order []string) []CardDef {
+	if len(order) == 0 {
+		return defs
+	}
+	rank := map[string]int{}
+	for i, id := range order {
+		rank[id] = i
+	}
+	sort.SliceStable(defs, func(i, j int) bool {
+		ri, iok := rank[defs[i].ID]
+		rj, jok := rank[defs[j].ID]
+		if iok && jok {
+			return ri < rj
+		}
+		return iok && !jok
+	})
+	return defs
+}
+
+// ttl is how long def stays fresh, or 0 if it never needs refreshing.
+func (def CardDef) ttl() time.Duration {
+	switch {
+	case def.TTL < 0:
+		return 0
+	case def.TTL == 0:
+		return cacheTTL
+	}
+	return def.TTL
+}
+
+// cardEnabled reports whether acc has def on their home screen. Logged
+// out, every shared card that isn't opt-in shows.
+func cardEnabled(acc *auth.Account, def CardDef) bool {
+	if acc == nil {
+		return def.User == nil && !def.OptIn
+	}
+	if def.OptIn {
+		return acc.HomeCardActive(def.ID)
+	}
+	return acc.ShowHomeCard(def.ID)
+}
+
+// layout returns the cards on acc's home screen, in their order.
+func layout(acc *auth.Account) []CardDef {
+	var order []string
+	if acc != nil {
+		order = acc.HomeOrder
+	}
+	var out []CardDef
+	for _, def := range arrange(homeCards(), order) {
+		if cardEnabled(acc, def) {
+			out = append(out, def)
+		}
+	}
+	return out
+}
+
+// cardBody renders a card's content for the viewer: from the cache for a
+// shared card, or freshly for a member's own.
+func cardBody(def CardDef, viewerID string) string {
+	if def.User != nil {
+		if viewerID == "" {
+			return ""
+		}
+		return def.User(viewerID)
+	}
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+	for _, c := range Cards {
+		if c.ID == def.ID {
+			return personalise(c.ID, viewerID, c.CachedHTML)
+		}
+	}
+	return ""
+}
+
+// saveLayout sets which cards acc has on (enabled) and their order.
+// Unknown and repeated IDs are dropped.
+func saveLayout(acc *auth.Account, enabled, order []string) {
+	known := map[string]bool{}
+	var all []string
+	for _, def := range homeCards() {
+		known[def.ID] = true
+		all = append(all, def.ID)
+	}
+	clean := func(ids []string) []string {
+		seen := map[string]bool{}
+		var out []string
+		for _, id := range ids {
+			if known[id] && !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+		return out
+	}
+	acc.HomeCards = clean(enabled)
+	// Everything offered, so cards added later default to visible rather
+	// than being hidden by the HomeCards allowlist.
+	acc.HomeCardsSeen = all
+	acc.HomeOrder = clean(order)
+}
+
+// LayoutCard is a card as the layout API reports it.
+type LayoutCard struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Column  string `json:"column"`
+	Enabled bool   `json:"enabled"`
+	OptIn   bool   `json:"opt_in,omitempty"`
+}
+
+// layoutCards lists every card in acc's order, on or off.
+func layoutCards(acc *auth.Account) []LayoutCard {
+	var out []LayoutCard
+	for _, def := range arrange(homeCards(), acc.HomeOrder) {
+		out = append(out, LayoutCard{
+			ID:      def.ID,
+			Title:   def.Title,
+			Column:  def.Column,
+			Enabled: cardEnabled(acc, def),
+			OptIn:   def.OptIn,
+		})
+	}
+	return out
+}
+
+// LayoutHandler serves /home/layout: GET lists the member's cards in
+// their order and whether each is on; POST saves cards (the IDs to show)
+// and order (IDs in the order to show them), as a form or JSON.
+func LayoutHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Cards []string `json:"cards"`
+			Order []string `json:"order"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "Invalid request")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Cards, req.Order = r.Form["cards"], r.Form["order"]
+		}
+		saveLayout(acc, req.Cards, req.Order)
+		if err := auth.UpdateAccount(acc); err != nil {
+			app.ServerError(w, r, "Could not save your layout")
+			return
+		}
+		if !app.WantsJSON(r) && !app.SendsJSON(r) {
+			http.Redirect(w, r, "/home", http.StatusSeeOther)
+			return
+		}
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	app.RespondJSON(w, map[string]any{"cards": layoutCards(acc)})
+}
+
+// renderLayoutPanel renders the customise panel's cards: a row each, in
+// the member's order, grouped by column, to tick and drag into place.
+func renderLayoutPanel(acc *auth.Account) string {
+	var cols = map[string]*strings.Builder{"left": {}, "right": {}}
+	for _, c := range layoutCards(acc) {
+		checked := ""
+		if c.Enabled {
+			checked = " checked"
+		}
+		fmt.Fprintf(cols[c.Column], `<div class="home-layout-row" draggable="true" data-id="%s"><span class="home-layout-handle" title="Drag to reorder">⠿</span><label><input type="checkbox" name="cards" value="%s"%s> %s</label><button type="button" class="btn-link" data-move="-1" title="Move up">↑</button><button type="button" class="btn-link" data-move="1" title="Move down">↓</button></div>`,
+			htmlEsc(c.ID), htmlEsc(c.ID), checked, htmlEsc(c.Title))
+	}
+	return fmt.Sprintf(`<div id="card-checkboxes" class="home-layout">
+<p class="home-layout-col-label">Left</p><div class="home-layout-col">%s</div>
+<p class="home-layout-col-label">Right</p><div class="home-layout-col">%s</div>
+</div>`, cols["left"].String(), cols["right"].String())
+}
+
+// layoutPanelScript saves the panel: ticking a card or moving one posts
+// the whole layout and reloads.
+const layoutPanelScript = `
+(function(){
+  var panel=document.getElementById('card-checkboxes');
+  if(!panel)return;
+  function save(){
+    var body=new URLSearchParams();
+    panel.querySelectorAll('.home-layout-row').forEach(function(row){
+      body.append('order',row.dataset.id);
+      if(row.querySelector('input').checked)body.append('cards',row.dataset.id);
+    });
+    fetch('/home/layout',{method:'POST',credentials:'same-origin',headers:{'Content-Type':'application/x-www-form-urlencoded','Accept':'application/json'},body:body.toString()})
+    .then(function(){location.reload()});
+  }
+  var dragged=null;
+  panel.querySelectorAll('.home-layout-row').forEach(function(row){
+    row.addEventListener('dragstart',function(e){dragged=row;row.classList.add('dragging');e.dataTransfer.effectAllowed='move';e.dataTransfer.setData('text/plain',row.dataset.id);});
+    row.addEventListener('dragend',function(){row.classList.remove('dragging');});
+    row.addEventListener('dragover',function(e){
+      if(!dragged||dragged===row||dragged.parentNode!==row.parentNode)return;
+      e.preventDefault();
+      var r=row.getBoundingClientRect();
+      row.parentNode.insertBefore(dragged,e.clientY<r.top+r.height/2?row:row.nextSibling);
+    });
+    row.addEventListener('drop',function(e){e.preventDefault();dragged=null;save();});
+    row.querySelectorAll('[data-move]').forEach(function(b){
+      b.addEventListener('click',function(){
+        var sib=b.dataset.move==='-1'?row.previousElementSibling:row.nextElementSibling;
+        if(!sib)return;
+        row.parentNode.insertBefore(row,b.dataset.move==='-1'?sib:sib.nextSibling);
+        save();
+      });
+    });
+  });
+  // Delay listener attachment so browser form-restore doesn't
+  // trigger an immediate save+reload loop.
+  setTimeout(function(){
+    panel.querySelectorAll('input[type=checkbox]').forEach(function(c){c.addEventListener('change',save);});
+  },500);
+})();
+`
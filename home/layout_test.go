@@ -0,0 +1,107 @@
+package home
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func ids(defs []CardDef) []string {
+	var out []string
+	for _, d := range defs {
+		out = append(out, d.ID)
+	}
+	return out
+}
+
+func TestHomeLayout(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	registryMu.Lock()
+	origRegistry := registry
+	registry = nil
+	registryMu.Unlock()
+	cacheMutex.Lock()
+	origCards := Cards
+	Cards = []Card{
+		{ID: "news", Title: "News", Column: "left", Position: 0, CachedHTML: "headlines"},
+		{ID: "blog", Title: "Blog", Column: "left", Position: 1, CachedHTML: "posts"},
+		{ID: "markets", Title: "Markets", Column: "right", Position: 0, CachedHTML: "prices"},
+	}
+	cacheMutex.Unlock()
+	defer func() {
+		registryMu.Lock()
+		registry = origRegistry
+		registryMu.Unlock()
+		cacheMutex.Lock()
+		Cards = origCards
+		cacheMutex.Unlock()
+	}()
+
+	RegisterCard(CardDef{ID: "tasks", Title: "Tasks", Column: "left", Position: 5, User: func(userID string) string { return "tasks for " + userID }})
+	RegisterCard(CardDef{ID: "notes", Title: "Notes", Position: 9, OptIn: true, User: func(string) string { return "notes" }})
+	RegisterCard(CardDef{ID: "", Title: "No ID", Content: func() string { return "x" }})
+
+	// Logged out: shared cards only, in the default order.
+	if got := strings.Join(ids(layout(nil)), ","); got != "news,blog,markets" {
+		t.Errorf("logged out = %s", got)
+	}
+
+	acc := &auth.Account{ID: "layout_user", Name: "Layout", Secret: "secret", Created: time.Now()}
+	if err := auth.Create(acc); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount(acc.ID)
+	if got := strings.Join(ids(layout(acc)), ","); got != "news,blog,tasks,markets" {
+		t.Errorf("default = %s", got)
+	}
+	if got := cardBody(layout(acc)[2], acc.ID); got != "tasks for layout_user" {
+		t.Errorf("user card = %q", got)
+	}
+
+	sess, err := auth.Login(acc.ID, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/home/layout", strings.NewReader(
+		`{"cards":["blog","tasks","notes","markets","bogus"],"order":["tasks","markets","blog","news","tasks"]}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	w := httptest.NewRecorder()
+	LayoutHandler(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("save: %d %s", w.Code, w.Body)
+	}
+	var rsp struct {
+		Cards []LayoutCard `json:"cards"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Cards) != 5 || rsp.Cards[0].ID != "tasks" || rsp.Cards[3].ID != "news" || rsp.Cards[3].Enabled {
+		t.Errorf("layout = %+v", rsp.Cards)
+	}
+
+	saved, err := auth.GetAccount(acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(saved.HomeOrder, ",") != "tasks,markets,blog,news" {
+		t.Errorf("order = %v", saved.HomeOrder)
+	}
+	// News was turned off; the opt-in card turned on; order kept per column.
+	if got := strings.Join(ids(layout(saved)), ","); got != "tasks,markets,blog,notes" {
+		t.Errorf("arranged = %s", got)
+	}
+	// A card registered later shows, after those placed, until it's
+	// turned off.
+	RegisterCard(CardDef{ID: "weather", Title: "Weather", Content: func() string { return "sunny" }})
+	if got := strings.Join(ids(layout(saved)), ","); got != "tasks,markets,blog,weather,notes" {
+		t.Errorf("with new card = %s", got)
+	}
+}
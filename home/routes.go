@@ -0,0 +1,63 @@
+package home
+
+import (
+	"net/http"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/setup"
+)
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	// The dashboard lives at the named URL /home, consistent with every other
+	// section (/news, /mail, /agent …). It renders for everyone: logged out, the
+	// home screen is the public face — real cards plus the agent — so a visitor
+	// sees the product rather than a separate marketing page.
+	r.HandleFunc("/home", Handler)
+	r.HandleFunc("/home/briefing", BriefingHandler, app.Authenticated)
+	r.HandleFunc("/home/layout", LayoutHandler, app.Authenticated)
+	r.HandleFunc("/home/card/", CardHandler) // members' own cards need a session; checked in the handler
+	r.HandleFunc("/about", Landing)          // the "what is Mu" pitch, no longer the front door
+	r.HandleFunc("/pricing", PricingHandler)
+	r.HandleFunc("/admin/front", FrontAdminHandler, app.Authenticated, app.Audited)
+	r.Handle("/", root(app.Serve()))
+}
+
+// root serves the front door, and static files everywhere else.
+func root(static http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			static.ServeHTTP(w, r)
+			return
+		}
+		// Fresh instance with no admin yet → guide the operator
+		// through the one-time setup wizard.
+		if setup.Needed() {
+			http.Redirect(w, r, "/setup", http.StatusSeeOther)
+			return
+		}
+		if _, acc := auth.TrySession(r); acc != nil {
+			// Every section has a named URL: the dashboard is /home and a
+			// query goes to the agent (/agent). The root just funnels
+			// logged-in users to the right named place.
+			q := r.URL.Query()
+			if q.Get("q") != "" || q.Get("prompt") != "" {
+				http.Redirect(w, r, "/agent?"+r.URL.RawQuery, http.StatusFound)
+			} else {
+				http.Redirect(w, r, "/home", http.StatusFound)
+			}
+			return
+		}
+		// Logged out: the live home IS the front door — real cards
+		// plus a working guest agent — so visitors can use Mu
+		// immediately and sign up once they've felt the value,
+		// rather than bouncing off a sign-in wall. The "what is
+		// this" pitch lives at /about. An admin can publish a
+		// curated front page instead (/admin/front).
+		Front(w, r)
+	}
+}
@@ -0,0 +1,11 @@
+package images
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/images", Handler) // public daily image; generating needs a session
+}
@@ -0,0 +1,12 @@
+package a2a
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/.well-known/agent.json", AgentCardHandler)
+	r.HandleFunc("/a2a", Handler)
+}
@@ -0,0 +1,20 @@
+package agents
+
+import (
+	"net/http"
+
+	"mu/internal/app"
+)
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	// The API face for agents: MCP + REST, pay-per-call over x402.
+	r.HandleFunc("/agents", Handler)
+	// Redirect the old path so existing links keep working.
+	r.HandleFunc("/developers", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/agents", http.StatusMovedPermanently)
+	})
+}
@@ -34,7 +34,8 @@ const (
 type Prompt struct {
 	System    string   // System prompt override
 	Topic     string   // User-selected topic/context
-	Rag       []string // RAG context sources
+	Rag       []string // RAG context sources, numbered from 1
+	Cite      bool     // Ask for the RAG context used to be cited by number
 	Context   History  // Conversation history
 	Question  string   // User's question
 	Priority  int      // Request priority (0=high, 1=medium, 2=low)
@@ -51,7 +52,9 @@ type systemPromptData struct {
 }
 
 // Default system prompt template
-var systemPrompt = template.Must(template.New("system_prompt").Parse(`
+var systemPrompt = template.Must(template.New("system_prompt").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`
 You are Micro, the assistant on the Mu platform. You have broad expertise across finance, technology, geopolitics, economics, and current events.{{if .Topic}} The conversation is focused on "{{.Topic}}".{{end}}
 Today's date is {{.Now}}.
 If asked your name, say Micro. Never refer to yourself as Claude or any other AI assistant name.
@@ -60,7 +63,7 @@ If asked your name, say Micro. Never refer to yourself as Claude or any other AI
 
 Current context (live market data, recent news, or articles fetched now):
 {{- range $index, $context := .Rag }}
-[{{ $index }}] {{ . }}
+[{{ inc $index }}] {{ . }}
 {{- end }}
 
 {{- end }}
@@ -71,6 +74,9 @@ How to respond:
 - For prices: the data provided in context is current and live — quote it directly as the current price
 - Be direct and substantive - the user wants insight, not hedging
 - When you don't know something current, say so and explain what you do know
+{{- if and .Rag .Cite }}
+- When you use a numbered context item, cite it by its number in square brackets, like [2], right after the claim. Don't make up numbers or list the sources yourself
+{{- end }}
 
 Keep responses concise but informative. Use markdown for structure when helpful.
 `))
@@ -85,7 +91,7 @@ func BuildSystemPrompt(p *Prompt) (string, error) {
 		sb.WriteString(p.System)
 		sb.WriteString("\n\nCurrent context (live market data, recent news, or articles fetched now):\n")
 		for i, r := range p.Rag {
-			sb.WriteString(fmt.Sprintf("[%d] %s\n", i, r))
+			sb.WriteString(fmt.Sprintf("[%d] %s\n", i+1, r))
 		}
 		return sb.String(), nil
 	}
@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/settings"
+)
+
+var embedHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+// embedMaxChars keeps each text within what embedding models accept.
+const embedMaxChars = 8000
+
+// EmbedModel returns the embedding model set in EMBED_MODEL, or "" when
+// embeddings aren't configured. It's served by the OpenAI-compatible
+// endpoint, OPENAI_BASE_URL or Atlas Cloud: nomic-embed-text on a local
+// Ollama, for instance, or text-embedding-3-small on OpenAI.
+func EmbedModel() string {
+	return strings.TrimSpace(settings.Get("EMBED_MODEL"))
+}
+
+// Embed returns an embedding of each text, in order, from EmbedModel.
+func Embed(texts []string) ([][]float32, error) {
+	model := EmbedModel()
+	if model == "" {
+		return nil, fmt.Errorf("no embedding model configured (set EMBED_MODEL)")
+	}
+	input := make([]string, len(texts))
+	for i, t := range texts {
+		if len(t) > embedMaxChars {
+			t = t[:embedMaxChars]
+		}
+		input[i] = t
+	}
+	body, _ := json.Marshal(map[string]any{"model": model, "input": input})
+	req, err := http.NewRequest(http.MethodPost, getAtlasBaseURL()+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := getAtlasAPIKey(); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := embedHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API error (%s): %s", resp.Status, truncateLog(strings.TrimSpace(string(raw)), 200))
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("unexpected embeddings response: %v", err)
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("asked for %d embeddings, got %d", len(texts), len(out.Data))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(texts) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("malformed embedding at index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -14,6 +14,17 @@ import (
 	"mu/internal/settings"
 )
 
+func init() {
+	settings.Register("AI",
+		settings.Var{Key: "ANTHROPIC_API_KEY", Secret: true, Doc: "Anthropic API key for chat and the agent"},
+		settings.Var{Key: "ANTHROPIC_MODEL", Doc: "Anthropic model to use"},
+		settings.Var{Key: "ATLAS_API_KEY", Secret: true, Doc: "Atlas Cloud API key"},
+		settings.Var{Key: "OPENAI_BASE_URL", Type: settings.TypeURL, Doc: "OpenAI-compatible endpoint, such as a local Ollama"},
+		settings.Var{Key: "OPENAI_API_KEY", Secret: true, Doc: "Key for the OpenAI-compatible endpoint"},
+		settings.Var{Key: "EMBED_MODEL", Doc: "Embedding model on the OpenAI-compatible endpoint, for semantic search"},
+	)
+}
+
 var (
 	// Limit concurrent LLM requests to prevent memory bloat
 	llmSemaphore = semaphore.NewWeighted(5)
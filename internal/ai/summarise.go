@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxThreadInput caps the transcript sent for a thread summary. When a
+// thread is longer, the oldest new messages are dropped; they are usually
+// already covered by the previous summary.
+const maxThreadInput = 24000
+
+const threadSummarySystem = `You summarise conversations on the Mu platform. Write a short, neutral summary of the discussion: the main points, any decisions or answers reached, and open questions. Refer to participants by name. Use plain prose or a few bullet points, no headings, at most 200 words. Only use what is in the conversation.`
+
+// SummariseThread summarises a conversation. Each line is one message,
+// already formatted as "name: text". If previous is set, it is the summary
+// of the earlier part of the thread and the lines are the messages since;
+// the result covers the whole thread.
+func SummariseThread(previous string, lines []string, caller string) (string, error) {
+	if len(lines) == 0 {
+		return previous, nil
+	}
+
+	// Keep the most recent messages that fit.
+	size := 0
+	start := len(lines)
+	for start > 0 && size+len(lines[start-1]) <= maxThreadInput {
+		start--
+		size += len(lines[start])
+	}
+	if start == len(lines) {
+		start = len(lines) - 1
+	}
+
+	var q strings.Builder
+	if previous != "" {
+		q.WriteString("Summary of the conversation so far:\n")
+		q.WriteString(previous)
+		q.WriteString("\n\nNew messages since then:\n")
+	} else {
+		q.WriteString("Conversation:\n")
+	}
+	if start > 0 {
+		q.WriteString(fmt.Sprintf("[%d earlier messages omitted]\n", start))
+	}
+	for _, l := range lines[start:] {
+		if len(l) > maxThreadInput {
+			l = l[:maxThreadInput]
+		}
+		q.WriteString(l)
+		q.WriteString("\n")
+	}
+	if previous != "" {
+		q.WriteString("\nUpdate the summary so it covers the whole conversation, including the new messages.")
+	} else {
+		q.WriteString("\nSummarise this conversation.")
+	}
+
+	return Ask(&Prompt{
+		System:    threadSummarySystem,
+		Question:  q.String(),
+		Priority:  PriorityMedium,
+		Caller:    caller,
+		MaxTokens: 600,
+	})
+}
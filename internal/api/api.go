@@ -244,7 +244,19 @@ var Endpoints = []*Endpoint{{
 	Name:        "Blog",
 	Path:        "/blog",
 	Method:      "GET",
-	Description: "Get all blog posts",
+	Description: "Get blog posts, newest first. A Link header with rel=\"next\" points to the next page",
+	Params: []*Param{
+		{
+			Name:        "after",
+			Value:       "string",
+			Description: "Return posts after the post with this ID",
+		},
+		{
+			Name:        "limit",
+			Value:       "number",
+			Description: "Posts per page (default 20, max 100)",
+		},
+	},
 	Response: []*Value{
 		{
 			Type: "JSON",
@@ -341,7 +353,7 @@ func init() {
 			{
 				Name:        "permissions",
 				Value:       "array",
-				Description: "Permissions array (e.g., ['read', 'write']). Default: ['read', 'write']",
+				Description: "Scopes array (e.g., ['read:news', 'write:posts', 'read:mail']). 'read' and 'write' grant every read or write scope, 'all' everything. Default: ['read', 'write']",
 			},
 			{
 				Name:        "expires_in",
@@ -507,6 +519,42 @@ func init() {
 		},
 	})
 
+	Endpoints = append(Endpoints, &Endpoint{
+		Name:        "React to Post",
+		Path:        "/blog/post/{id}/react",
+		Method:      "POST",
+		Description: "Toggle your reaction on a blog post or one of its comments. Reactions are shown as counts and never affect ordering",
+		Params: []*Param{
+			{
+				Name:        "kind",
+				Value:       "string",
+				Description: "thoughtful, benefited or ameen. Sending your current reaction again takes it back",
+			},
+			{
+				Name:        "comment",
+				Value:       "string",
+				Description: "Comment ID, to react to a comment on the post (optional)",
+			},
+		},
+		Response: []*Value{
+			{
+				Type: "JSON",
+				Params: []*Param{
+					{
+						Name:        "reacted",
+						Value:       "boolean",
+						Description: "Whether your reaction is now set",
+					},
+					{
+						Name:        "counts",
+						Value:       "object",
+						Description: "Number of readers per reaction kind",
+					},
+				},
+			},
+		},
+	})
+
 	Endpoints = append(Endpoints, &Endpoint{
 		Name:        "Search Data",
 		Path:        "/search",
@@ -536,9 +584,9 @@ func init() {
 	// Flag endpoint
 	Endpoints = append(Endpoints, &Endpoint{
 		Name:        "Flag Content",
-		Path:        "/admin/flag",
+		Path:        "/flag",
 		Method:      "POST",
-		Description: "Flag inappropriate content",
+		Description: "Report inappropriate content. Each account can report an item once; follow reports at /reports",
 		Params: []*Param{
 			{
 				Name:        "type",
@@ -550,6 +598,11 @@ func init() {
 				Value:       "string",
 				Description: "Content ID",
 			},
+			{
+				Name:        "category",
+				Value:       "string",
+				Description: "Why: spam, abuse or misinformation",
+			},
 		},
 		Response: []*Value{
 			{
@@ -558,7 +611,7 @@ func init() {
 					{
 						Name:        "success",
 						Value:       "boolean",
-						Description: "Whether the flag was recorded",
+						Description: "Whether the report was recorded (false if already reported)",
 					},
 					{
 						Name:        "count",
@@ -721,6 +774,7 @@ func init() {
 			{Name: "lat", Value: "number", Description: "Latitude of the search location"},
 			{Name: "lon", Value: "number", Description: "Longitude of the search location"},
 			{Name: "radius", Value: "number", Description: "Search radius in metres, 100–5000 (default 500)"},
+			{Name: "filter", Value: "string", Description: "Curated filter: halal, mosque or pharmacy (optional)"},
 		},
 		Response: []*Value{
 			{
@@ -731,6 +785,7 @@ func init() {
 					{Name: "lat", Value: "number", Description: "Resolved latitude"},
 					{Name: "lon", Value: "number", Description: "Resolved longitude"},
 					{Name: "radius", Value: "number", Description: "Search radius used"},
+					{Name: "filter", Value: "string", Description: "The curated filter applied, if any"},
 				},
 			},
 		},
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"mu/internal/app"
 	"mu/internal/auth"
 )
 
@@ -435,12 +436,13 @@ var tools = []Tool{
 	},
 	{
 		Name:        "wallet_transfer",
-		Description: "Transfer credits to another user by username",
+		Description: "Send credits to another user by username; they're notified",
 		Method:      "POST",
-		Path:        "/wallet/transfer",
+		Path:        "/wallet/send",
 		Params: []ToolParam{
 			{Name: "to", Type: "string", Description: "Recipient username", Required: true},
 			{Name: "amount", Type: "number", Description: "Number of credits to transfer", Required: true},
+			{Name: "note", Type: "string", Description: "Optional note for the recipient, up to 140 characters", Required: false},
 		},
 	},
 	{
@@ -714,7 +716,7 @@ func ExecuteTool(r *http.Request, name string, args map[string]any) (string, boo
 	}
 
 	recorder := httptest.NewRecorder()
-	http.DefaultServeMux.ServeHTTP(recorder, internalReq)
+	app.Internal(recorder, internalReq)
 
 	isError := recorder.Code >= 400
 	return recorder.Body.String(), isError, nil
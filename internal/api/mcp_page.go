@@ -53,7 +53,7 @@ func mcpPageHandler(w http.ResponseWriter, r *http.Request) {
 		b.WriteString(`<li><strong>Pay per call (x402)</strong> &mdash; no login. Call a metered tool with no auth and you get an HTTP <code>402</code> with the price and pay-to address; your x402 wallet pays in USDC and retries. Your first calls per wallet are free.</li>`)
 		b.WriteString(`<li><strong>Token &amp; credits</strong> &mdash; log in and pass a token; metered calls draw from your credit balance:`)
 		b.WriteString(`<pre style="background:#f5f5f5;padding:8px;font-size:12px;overflow-x:auto">Authorization: Bearer YOUR_TOKEN</pre>`)
-		b.WriteString(`Create a <a href="/token">Personal Access Token</a>, or call the <code>signup</code> / <code>login</code> tool to get one programmatically.</li>`)
+		b.WriteString(`Create a <a href="/account/tokens">Personal Access Token</a>, or call the <code>signup</code> / <code>login</code> tool to get one programmatically.</li>`)
 		b.WriteString(`</ol>`)
 		b.WriteString(`<p>Non-metered tools are free to call. A few tools that touch your account (wallet, mail, editing your apps) always need a logged-in session.</p>`)
 	} else {
@@ -61,7 +61,7 @@ func mcpPageHandler(w http.ResponseWriter, r *http.Request) {
 		b.WriteString(`<pre style="background:#f5f5f5;padding:8px;font-size:12px;overflow-x:auto">Authorization: Bearer YOUR_TOKEN</pre>`)
 		b.WriteString(`<p>Two ways to obtain a token:</p>`)
 		b.WriteString(`<ol>`)
-		b.WriteString(`<li><strong>Personal Access Token (PAT)</strong> &mdash; create one at <a href="/token">/token</a> after logging in.</li>`)
+		b.WriteString(`<li><strong>Personal Access Token (PAT)</strong> &mdash; create one at <a href="/account/tokens">/account/tokens</a> after logging in.</li>`)
 		b.WriteString(`<li><strong>Signup / Login</strong> &mdash; the agent can call the <code>signup</code> or <code>login</code> tool to obtain a session token programmatically.</li>`)
 		b.WriteString(`</ol>`)
 	}
@@ -186,7 +186,7 @@ func mcpPageHandler(w http.ResponseWriter, r *http.Request) {
 		b.WriteString(`<p class="card-desc">Metered tools are pay-per-call. Two ways to pay:</p>`)
 		b.WriteString(`<ul style="margin:0;padding-left:20px;font-size:14px">`)
 		b.WriteString(`<li><strong>x402</strong> &mdash; agents pay per call in USDC on Base. Call a metered tool with no auth and you get an HTTP <code>402</code> whose body lists the price and pay-to address; pay and retry. No account needed.</li>`)
-		b.WriteString(`<li><strong>Credits</strong> &mdash; <a href="/token">log in with a token</a> and calls draw from your credit balance instead.</li>`)
+		b.WriteString(`<li><strong>Credits</strong> &mdash; <a href="/account/tokens">log in with a token</a> and calls draw from your credit balance instead.</li>`)
 		b.WriteString(`</ul>`)
 		b.WriteString(`<p class="card-meta" style="margin-top:8px">Prices are shown per tool below.</p>`)
 		b.WriteString(`</div>`)
@@ -7,8 +7,19 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"mu/internal/app"
 )
 
+// internalRoute serves pattern to the tool calls made during the test.
+func internalRoute(t *testing.T, pattern string, h http.HandlerFunc) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, h)
+	saved := app.Internal
+	app.Internal = mux.ServeHTTP
+	t.Cleanup(func() { app.Internal = saved })
+}
+
 func TestMCPHandler_GETReturnsPage(t *testing.T) {
 	req := httptest.NewRequest("GET", "/mcp", nil)
 	w := httptest.NewRecorder()
@@ -240,7 +251,7 @@ func TestMCPHandler_ToolsCallForwardsAuth(t *testing.T) {
 	// Register a test handler that checks auth headers
 	var receivedAuth string
 	var receivedToken string
-	http.HandleFunc("/test-mcp-auth", func(w http.ResponseWriter, r *http.Request) {
+	internalRoute(t, "/test-mcp-auth", func(w http.ResponseWriter, r *http.Request) {
 		receivedAuth = r.Header.Get("Authorization")
 		receivedToken = r.Header.Get(TokenHeader)
 		w.Header().Set("Content-Type", "application/json")
@@ -358,7 +369,7 @@ func TestMCPHandler_QuotaCheckAllows(t *testing.T) {
 	defer func() { QuotaCheck = origQuotaCheck }()
 
 	// Register a test handler
-	http.HandleFunc("/test-quota-pass", func(w http.ResponseWriter, r *http.Request) {
+	internalRoute(t, "/test-quota-pass", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"ok":true}`))
 	})
@@ -398,7 +409,7 @@ func TestMCPHandler_IncludedToolsSkipQuotaCheck(t *testing.T) {
 	defer func() { QuotaCheck = origQuotaCheck }()
 
 	// Register a free test handler
-	http.HandleFunc("/test-free-tool", func(w http.ResponseWriter, r *http.Request) {
+	internalRoute(t, "/test-free-tool", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"free":true}`))
 	})
@@ -0,0 +1,13 @@
+package api
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/api", APIPageHandler)
+	// GET is the MCP page, POST is JSON-RPC, which authenticates itself.
+	r.HandleFunc("/mcp", MCPHandler, app.SkipCSRF)
+}
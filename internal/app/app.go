@@ -1,17 +1,20 @@
 package app
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	htmlpkg "html"
 	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -80,7 +83,8 @@ func envInt(key string, def int) int {
 	return def
 }
 
-// Version for cache busting static assets (generated at startup)
+// Version identifies this process (its start time). Static assets are
+// busted by content instead, with Asset.
 var Version = fmt.Sprintf("%d", time.Now().Unix())
 
 // ANSI color codes
@@ -125,18 +129,9 @@ func init() {
 }
 
 // Log prints a formatted log message with a colored package prefix
-// and stores it in the in-memory system log ring buffer.
+// and stores it in the in-memory system log ring buffer, at info level.
 func Log(pkg string, format string, args ...interface{}) {
-	color := pkgColors[pkg]
-	if color == "" {
-		color = colorWhite
-	}
-	timestamp := time.Now().Format("15:04:05")
-	prefix := fmt.Sprintf("%s[%s %s]%s ", color, timestamp, pkg, colorReset)
-	if !cliMode {
-		fmt.Printf(prefix+format+"\n", args...)
-	}
-	appendSysLog(pkg, format, args...)
+	logf(context.Background(), slog.LevelInfo, pkg, format, args...)
 }
 
 // Response holds data for responding in either JSON or HTML format
@@ -145,6 +140,11 @@ type Response struct {
 	HTML        string      // Pre-rendered HTML body (used when Data is nil for HTML)
 	Title       string      // Page title for HTML response
 	Description string      // Meta description for HTML response
+
+	// Modified is when the content last changed. When set, the HTML
+	// page carries an ETag and Last-Modified and conditional requests
+	// for an unchanged page get a 304.
+	Modified time.Time
 }
 
 // WantsJSON returns true if the request prefers JSON response
@@ -223,6 +223,7 @@ func ServerError(w http.ResponseWriter, r *http.Request, message string) {
 	if message == "" {
 		message = "Internal server error"
 	}
+	logf(r.Context(), slog.LevelError, "http", "%s %s: %s", r.Method, r.URL.Path, message)
 	Error(w, r, http.StatusInternalServerError, message)
 }
 
@@ -252,8 +253,11 @@ func Respond(w http.ResponseWriter, r *http.Request, resp Response) {
 
 	// HTML response — RenderHTMLForRequest already prepends the verify
 	// banner for unverified users on verification-gated instances.
-	html := RenderHTMLForRequest(resp.Title, resp.Description, resp.HTML, r)
-	w.Write([]byte(html))
+	html := []byte(RenderHTMLForRequest(resp.Title, resp.Description, resp.HTML, r))
+	if !resp.Modified.IsZero() && NotModified(w, r, ETag(html), resp.Modified) {
+		return
+	}
+	w.Write(html)
 }
 
 //go:embed html/*
@@ -267,7 +271,7 @@ func torFooterLink() string {
 }
 
 var Template = `
-<html lang="%s">
+<html lang="%s" dir="%s" data-theme="%s">
   <head>
     <title>%s | Mu</title>
     <meta name="viewport" content="width=device-width, initial-scale=1, interactive-widget=resizes-content, viewport-fit=cover" />
@@ -279,57 +283,57 @@ var Template = `
     <meta name="apple-mobile-web-app-title" content="Mu">
     <meta name="application-name" content="Mu">
     <link rel="apple-touch-icon" href="/icon-192.png">
-    <link rel="preload" href="/home.png?` + Version + `" as="image">
-    <link rel="preload" href="/mail.png?` + Version + `" as="image">
-    <link rel="preload" href="/chat.png?` + Version + `" as="image">
-    <link rel="preload" href="/post.png?` + Version + `" as="image">
-    <link rel="preload" href="/news.png?` + Version + `" as="image">
-    <link rel="preload" href="/video.png?` + Version + `" as="image">
-    <link rel="preload" href="/account.png?` + Version + `" as="image">
-    <link rel="preload" href="/weather.png?` + Version + `" as="image">
-    <link rel="preload" href="/reminder.svg?` + Version + `" as="image">
+    <link rel="preload" href="` + Asset("/home.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/mail.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/chat.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/post.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/news.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/video.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/account.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/weather.png") + `" as="image">
+    <link rel="preload" href="` + Asset("/reminder.svg") + `" as="image">
     <link rel="preconnect" href="https://fonts.googleapis.com">
     <link rel="preconnect" href="https://fonts.gstatic.com" crossorigin>
     <link href="https://fonts.googleapis.com/css2?family=Nunito+Sans:ital,opsz,wght@0,6..12,200..1000;1,6..12,200..1000&display=swap" rel="stylesheet">
-    <link rel="manifest" href="/manifest.webmanifest">
-    <link rel="stylesheet" href="/mu.css?` + Version + `">
-    <script src="/mu.js?` + Version + `"></script>
+    <link rel="manifest" href="` + Asset("/manifest.webmanifest") + `">
+    <link rel="stylesheet" href="` + Asset("/mu.css") + `">
+    <script src="` + Asset("/mu.js") + `"></script>
   </head>
   <body%s>
     <div id="head">
-      <button id="menu-toggle" onclick="toggleMenu()" aria-label="Menu"><span></span><span></span><span></span></button>
+      <button id="menu-toggle" data-toggle-menu aria-label="{t:nav.menu}"><span></span><span></span><span></span></button>
       <div id="brand">
         <a href="/">Mu</a>
       </div>
-      <a id="head-mail" href="/mail" aria-label="Mail"><svg width="18" height="18" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round"><rect x="2" y="4" width="20" height="16" rx="2"/><polyline points="22,7 12,13 2,7"/></svg><span id="head-mail-badge"></span></a>
-      <a id="head-wallet" href="/wallet" aria-label="Wallet"><svg width="18" height="18" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round"><path d="M20 12V8H6a2 2 0 0 1-2-2c0-1.1.9-2 2-2h12v4"/><path d="M4 6v12a2 2 0 0 0 2 2h14v-4"/><path d="M18 12a2 2 0 0 0-2 2 2 2 0 0 0 2 2h4v-4h-4z"/></svg><span id="head-wallet-badge"></span></a>
+      <a id="head-mail" href="/mail" aria-label="{t:nav.mail}"><svg width="18" height="18" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round"><rect x="2" y="4" width="20" height="16" rx="2"/><polyline points="22,7 12,13 2,7"/></svg><span id="head-mail-badge"></span></a>
+      <a id="head-wallet" href="/wallet" aria-label="{t:nav.wallet}"><svg width="18" height="18" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round"><path d="M20 12V8H6a2 2 0 0 1-2-2c0-1.1.9-2 2-2h12v4"/><path d="M4 6v12a2 2 0 0 0 2 2h14v-4"/><path d="M18 12a2 2 0 0 0-2 2 2 2 0 0 0 2 2h4v-4h-4z"/></svg><span id="head-wallet-badge"></span></a>
     </div>
 
-    <div id="nav-overlay" onclick="toggleMenu()"></div>
+    <div id="nav-overlay" data-toggle-menu></div>
     <div id="container">
       <div id="nav-container">
         <div id="nav-search">
           <form action="/agent" method="GET" id="nav-assist-form">
-            <input type="text" name="q" placeholder="Talk to Micro..." aria-label="Ask Micro" id="nav-assist-input">
+            <input type="text" name="q" placeholder="{t:nav.ask}" aria-label="{t:nav.ask_label}" id="nav-assist-input">
           </form>
         </div>
         <div id="nav">
-          <a href="/home"><img src="/home.png?` + Version + `"><span class="label">Home</span></a>
-          <a href="/agent"><img src="/agent.svg?` + Version + `"><span class="label">Agent</span></a>
-          <a href="/apps"><img src="/apps.svg?` + Version + `"><span class="label">Apps</span></a>
-          <a href="/blog"><img src="/post.png?` + Version + `"><span class="label">Blog</span></a>
-          <a href="/chat"><img src="/chat.png?` + Version + `"><span class="label">Chat</span></a>
-          <a href="/images"><img src="/images.svg?` + Version + `"><span class="label">Images</span></a>
-          <a href="/islam"><img src="/reminder.svg?` + Version + `"><span class="label">Islam</span></a>
-          <a id="nav-mail" href="/mail"><img src="/mail.png?` + Version + `"><span class="label">Mail</span><span id="nav-mail-badge"></span></a>
-          <a href="/markets"><img src="/markets.svg?` + Version + `"><span class="label">Markets</span></a>
-          <a href="/news"><img src="/news.png?` + Version + `"><span class="label">News</span></a>
-          <a href="/places"><img src="/places.svg?` + Version + `"><span class="label">Places</span></a>
-          <a href="/search"><img src="/search.svg?` + Version + `"><span class="label">Search</span></a>
-          <a href="/social"><img src="/social.svg?` + Version + `"><span class="label">Social</span></a>
-          <a href="/video"><img src="/video.png?` + Version + `"><span class="label">Video</span></a>
-          <a id="nav-wallet" href="/wallet"><img src="/wallet.png?` + Version + `"><span class="label">Wallet</span></a>
-          <a href="/weather"><img src="/weather.svg?` + Version + `"><span class="label">Weather</span></a>
+          <a href="/home"><img src="` + Asset("/home.png") + `"><span class="label">{t:nav.home}</span></a>
+          <a href="/agent"><img src="` + Asset("/agent.svg") + `"><span class="label">{t:nav.agent}</span></a>
+          <a href="/apps"><img src="` + Asset("/apps.svg") + `"><span class="label">{t:nav.apps}</span></a>
+          <a href="/blog"><img src="` + Asset("/post.png") + `"><span class="label">{t:nav.blog}</span></a>
+          <a href="/chat"><img src="` + Asset("/chat.png") + `"><span class="label">{t:nav.chat}</span></a>
+          <a href="/images"><img src="` + Asset("/images.svg") + `"><span class="label">{t:nav.images}</span></a>
+          <a href="/islam"><img src="` + Asset("/reminder.svg") + `"><span class="label">{t:nav.islam}</span></a>
+          <a id="nav-mail" href="/mail"><img src="` + Asset("/mail.png") + `"><span class="label">{t:nav.mail}</span><span id="nav-mail-badge"></span></a>
+          <a href="/markets"><img src="` + Asset("/markets.svg") + `"><span class="label">{t:nav.markets}</span></a>
+          <a href="/news"><img src="` + Asset("/news.png") + `"><span class="label">{t:nav.news}</span></a>
+          <a href="/places"><img src="` + Asset("/places.svg") + `"><span class="label">{t:nav.places}</span></a>
+          <a href="/search"><img src="` + Asset("/search.svg") + `"><span class="label">{t:nav.search}</span></a>
+          <a href="/social"><img src="` + Asset("/social.svg") + `"><span class="label">{t:nav.social}</span></a>
+          <a href="/video"><img src="` + Asset("/video.png") + `"><span class="label">{t:nav.video}</span></a>
+          <a id="nav-wallet" href="/wallet"><img src="` + Asset("/wallet.png") + `"><span class="label">{t:nav.wallet}</span></a>
+          <a href="/weather"><img src="` + Asset("/weather.svg") + `"><span class="label">{t:nav.weather}</span></a>
 
         </div>
         <div class="nav-bottom">
@@ -341,22 +345,9 @@ var Template = `
         %s
       </div>
       <div id="footer">
-        <a href="/about">About</a> · <a href="/agents">Agents</a> · <a href="/api">API</a> · <a href="/docs">Docs</a> · <a href="/mcp">MCP</a> · <a href="/status">Status</a>` + torFooterLink() + `
+        <a href="/about">{t:footer.about}</a> · <a href="/agents">Agents</a> · <a href="/api">API</a> · <a href="/docs">{t:footer.docs}</a> · <a href="/mcp">MCP</a> · <a href="/status">{t:footer.status}</a>` + torFooterLink() + `
       </div>
     </div>
-  <script>
-      if (navigator.serviceWorker) {
-        navigator.serviceWorker.register (
-          '/mu.js',
-          {scope: '/'}
-        );
-      }
-      
-      function toggleMenu() {
-        document.body.classList.toggle('menu-open');
-      }
-      document.addEventListener('click',function(){document.querySelectorAll('.ctrl-menu').forEach(function(m){m.style.display='none'})});
-  </script>
   </body>
 </html>
 `
@@ -366,7 +357,7 @@ var Template = `
 // introduced later can default to visible instead of being hidden by the
 // HomeCards allowlist. Keep in sync with the panels and home/cards.json.
 var homeCardUniverse = []string{
-	"blog", "news", "markets", "reminder", "social", "video", "images", "mail", "web",
+	"blog", "news", "trending", "markets", "reminder", "social", "video", "images", "places", "weather", "tasks", "mail", "web", "briefing", "prayer",
 }
 
 var CardTemplate = `
@@ -377,7 +368,7 @@ var CardTemplate = `
 </div>
 `
 
-var LoginTemplate = `<html lang="en">
+var LoginTemplate = `<html lang="%s" dir="%s">
   <head>
     <title>Login | Mu</title>
     <meta name="viewport" content="width=device-width, initial-scale=1, interactive-widget=resizes-content, viewport-fit=cover" />
@@ -385,7 +376,7 @@ var LoginTemplate = `<html lang="en">
     <link rel="preconnect" href="https://fonts.googleapis.com">
     <link rel="preconnect" href="https://fonts.gstatic.com" crossorigin>
     <link href="https://fonts.googleapis.com/css2?family=Nunito+Sans:ital,opsz,wght@0,6..12,200..1000;1,6..12,200..1000&display=swap" rel="stylesheet">
-    <link rel="stylesheet" href="/mu.css?` + Version + `">
+    <link rel="stylesheet" href="` + Asset("/mu.css") + `">
   </head>
   <body>
     <div id="head">
@@ -396,26 +387,37 @@ var LoginTemplate = `<html lang="en">
     <div id="container">
       <div id="content">
 	<form id="login" action="/login%s" method="POST">
-	  <h1>Login</h1>
+	  <h1>{t:auth.login}</h1>
 	  %s
-	  <input id="id" name="id" placeholder="Username" required>
-	  <input id="secret" name="secret" type="password" placeholder="Password" required>
+	  <input id="id" name="id" placeholder="{t:auth.username}" required>
+	  <input id="secret" name="secret" type="password" placeholder="{t:auth.password}" required>
+	  <label class="remember-me"><input type="checkbox" name="remember"> {t:auth.remember}</label>
 	  <br>
-	  <button>Login</button>
+	  <button>{t:auth.login}</button>
 	</form>
 	<div id="passkey-login" style="display:none; text-align:center; margin-top:20px;">
-	  <p class="text-muted">or</p>
-	  <button onclick="loginWithPasskey()">Login with Passkey</button>
+	  <p class="text-muted">{t:auth.or}</p>
+	  <button id="passkey-button">{t:auth.passkey}</button>
 	</div>
-	<p class="text-center mt-5"><a href="/signup">Sign up</a> if you don't have an account</p>
-	<script>
+	<p class="text-center mt-5"><a href="/signup">{t:auth.sign_up}</a> {t:auth.no_account}</p>
+	%s
+      </div>
+    </div>
+  </body>
+</html>
+`
+
+// passkeyLoginScript offers passkey sign-in on the login page, where
+// browsers support it. loginPage emits it with the request's nonce.
+const passkeyLoginScript = `
 	if (window.PublicKeyCredential) {
 	  PublicKeyCredential.isConditionalMediationAvailable && PublicKeyCredential.isConditionalMediationAvailable().then(function(){});
 	  document.getElementById('passkey-login').style.display = 'block';
+	  document.getElementById('passkey-button').addEventListener('click', loginWithPasskey);
 	}
 
 	function base64urlToBuffer(b64) {
-	  var pad = b64.length %% 4;
+	  var pad = b64.length % 4;
 	  if (pad) b64 += '='.repeat(4 - pad);
 	  var str = atob(b64.replace(/-/g, '+').replace(/_/g, '/'));
 	  var buf = new Uint8Array(str.length);
@@ -475,14 +477,9 @@ var LoginTemplate = `<html lang="en">
 	    if (e.name !== 'NotAllowedError') alert('Error: ' + e.message);
 	  }
 	}
-	</script>
-      </div>
-    </div>
-  </body>
-</html>
-`
+	`
 
-var SignupTemplate = `<html lang="en">
+var SignupTemplate = `<html lang="%s" dir="%s">
   <head>
     <title>Signup | Mu</title>
     <meta name="viewport" content="width=device-width, initial-scale=1, interactive-widget=resizes-content, viewport-fit=cover" />
@@ -490,7 +487,7 @@ var SignupTemplate = `<html lang="en">
     <link rel="preconnect" href="https://fonts.googleapis.com">
     <link rel="preconnect" href="https://fonts.gstatic.com" crossorigin>
     <link href="https://fonts.googleapis.com/css2?family=Nunito+Sans:ital,opsz,wght@0,6..12,200..1000;1,6..12,200..1000&display=swap" rel="stylesheet">
-    <link rel="stylesheet" href="/mu.css?` + Version + `">
+    <link rel="stylesheet" href="` + Asset("/mu.css") + `">
   </head>
   <body>
     <div id="head">
@@ -501,17 +498,17 @@ var SignupTemplate = `<html lang="en">
     <div id="container">
       <div id="content">
 	<form id="signup" action="/signup" method="POST">
-	  <h1>Signup</h1>
+	  <h1>{t:auth.signup}</h1>
 	  %s
-	  <input id="id" name="id" placeholder="Username (4-24 chars, lowercase)" required>
-	  <input id="name" name="name" placeholder="Name (optional)">
-  	  <input id="secret" name="secret" type="password" placeholder="Password (min 6 chars)" required>
+	  <input id="id" name="id" placeholder="{t:auth.username_hint}" required>
+	  <input id="name" name="name" placeholder="{t:auth.name_optional}">
+  	  <input id="secret" name="secret" type="password" placeholder="{t:auth.password_hint}" required>
 	  %s
 	  %s
 	  <br>
-	  <button>Signup</button>
+	  <button>{t:auth.signup}</button>
 	</form>
-	<p class="text-center mt-5"><a href="/login">Login</a> if you have an account</p>
+	<p class="text-center mt-5"><a href="/login">{t:auth.login}</a> {t:auth.have_account}</p>
       </div>
     </div>
   </body>
@@ -522,21 +519,39 @@ var SignupTemplate = `<html lang="en">
 // through signup renders without changing every call site.
 var currentInviteCode string
 
-// renderSignup renders the signup template with a fresh captcha challenge
-// and the given error HTML (or empty string).
-func renderSignup(errHTML string) string {
+// renderSignup renders the signup template in the viewer's language with
+// a fresh captcha challenge and the given error HTML (or empty string).
+func renderSignup(r *http.Request, errHTML string) string {
+	lang := GetUserLanguage(r)
 	c := NewCaptchaChallenge()
 	inviteField := ""
 	if currentInviteCode != "" {
 		inviteField = fmt.Sprintf(`<input type="hidden" name="invite" value="%s">`, currentInviteCode)
 	}
-	html := fmt.Sprintf(SignupTemplate, errHTML, CaptchaHTML(c), inviteField)
-	if btn := googleButtonHTML("Continue with Google"); btn != "" {
-		html = strings.Replace(html, `<h1>Signup</h1>`, `<h1>Signup</h1>`+btn, 1)
+	html := fmt.Sprintf(Localize(SignupTemplate, lang), lang, Dir(lang), errHTML, CaptchaHTML(c), inviteField)
+	if EmailSender != nil {
+		html = strings.Replace(html, `<input id="secret"`,
+			`<input id="email" name="email" type="email" placeholder="`+T(lang, "auth.email_optional")+`">
+  	  <input id="secret"`, 1)
+	}
+	if btn := googleButtonHTML(T(lang, "auth.continue_google")); btn != "" {
+		heading := `<h1>` + T(lang, "auth.signup") + `</h1>`
+		html = strings.Replace(html, heading, heading+btn, 1)
 	}
 	return html
 }
 
+// renderSignupClosed is the signup page when REGISTRATION=closed.
+func renderSignupClosed(w http.ResponseWriter) {
+	body := `<div class="card" style="max-width:440px;margin:0 auto">
+<h3>Signups are closed</h3>
+<p>Mu isn't taking new accounts at the moment.</p>
+<p class="text-muted text-sm mt-3">Already have an account? <a href="/login">Log in</a></p>
+</div>`
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(RenderHTML("Signups Closed", "Signups are closed", body)))
+}
+
 // renderRequestInvitePage shows the "request an invite" form that
 // replaces the dead-end "invite only" page. Captcha-protected and
 // rate-limited by IP so it can't be flooded.
@@ -560,7 +575,9 @@ func renderRequestInvitePage(w http.ResponseWriter, r *http.Request, message str
 	w.Write([]byte(RenderHTML("Request an Invite", "Request an invite to Mu", body)))
 }
 
-// InviteHandler lets any logged-in user invite someone by email.
+// InviteHandler lets any logged-in user invite people: by email with a
+// single-use link, or with a code they hand out themselves. Members have
+// INVITE_MEMBER_LIMIT invites out at a time.
 func InviteHandler(w http.ResponseWriter, r *http.Request) {
 	_, acc, err := auth.RequireSession(r)
 	if err != nil {
@@ -569,12 +586,29 @@ func InviteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "POST" {
+		if auth.SignupClosed() {
+			BadRequest(w, r, "Signups are closed, so invites can't be used")
+			return
+		}
 		r.ParseForm()
+		if r.FormValue("action") == "code" {
+			uses, _ := strconv.Atoi(r.FormValue("uses"))
+			if _, err := auth.CreateInviteCode(acc.ID, uses); err != nil {
+				BadRequest(w, r, err.Error())
+				return
+			}
+			http.Redirect(w, r, "/invite", http.StatusSeeOther)
+			return
+		}
 		email := strings.TrimSpace(r.FormValue("email"))
 		if email == "" {
 			BadRequest(w, r, "Email is required")
 			return
 		}
+		if auth.InviteAllowance(acc.ID) == 0 {
+			BadRequest(w, r, "You have no invites left")
+			return
+		}
 		code, err := auth.CreateInvite(email, acc.ID)
 		if err != nil {
 			ServerError(w, r, "Failed to create invite: "+err.Error())
@@ -595,22 +629,63 @@ func InviteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body := `<p><a href="/home">← Home</a></p>
-<div class="card">
+	var b strings.Builder
+	b.WriteString(`<p><a href="/home">← Home</a></p>`)
+	if auth.SignupClosed() {
+		b.WriteString(`<div class="card"><h4>Invite someone to Mu</h4><p class="text-muted">Signups are closed at the moment, so invites can't be used.</p></div>`)
+	} else {
+		left := auth.InviteAllowance(acc.ID)
+		b.WriteString(fmt.Sprintf(`<div class="card">
 <h4>Invite someone to Mu</h4>
 <p class="text-sm">Enter their email — they'll get a signup link.</p>
 <form method="POST" action="/invite" style="margin-top:8px">
-	<input type="email" name="email" placeholder="friend@example.com" required class="form-input" style="width:100%">
+	<input type="email" name="email" placeholder="friend@example.com" required class="form-input" style="width:100%%">
 	<button type="submit" class="mt-2">Send invite</button>
 </form>
-</div>`
-	w.Write([]byte(RenderHTML("Invite", "Invite someone to Mu", body)))
+</div>
+<div class="card">
+<h4>Make an invite code</h4>
+<p class="text-sm">A link you can share yourself, good for a number of signups.%s</p>
+<form method="POST" action="/invite" style="margin-top:8px">
+	<input type="hidden" name="action" value="code">
+	<input type="number" name="uses" value="1" min="1" max="%d" class="form-input" style="width:6em"> signups
+	<button type="submit" class="mt-2">Make code</button>
+</form>
+</div>`, inviteAllowanceNote(acc, left), max(left, 1)))
+	}
+
+	if list := auth.InvitesBy(acc.ID); len(list) > 0 {
+		b.WriteString(`<div class="card"><h4>Your invites</h4><table class="email-log"><tr><th>Link</th><th>For</th><th>Used</th><th>Joined</th></tr>`)
+		for _, inv := range list {
+			link := PublicURL() + "/signup?invite=" + inv.Code
+			joined := make([]string, 0, len(inv.Accounts))
+			for _, id := range inv.Accounts {
+				joined = append(joined, fmt.Sprintf(`<a href="/@%s">@%s</a>`, htmlpkg.EscapeString(id), htmlpkg.EscapeString(id)))
+			}
+			b.WriteString(fmt.Sprintf(`<tr><td class="addr"><a href="%s">%s…</a></td><td>%s</td><td>%d of %d</td><td>%s</td></tr>`,
+				htmlpkg.EscapeString(link), inv.Code[:8], htmlpkg.EscapeString(inv.Email), inv.Used(), inv.Limit(), strings.Join(joined, ", ")))
+		}
+		b.WriteString(`</table></div>`)
+	}
+	w.Write([]byte(RenderHTML("Invite", "Invite someone to Mu", b.String())))
+}
+
+// inviteAllowanceNote tells a member how many invites they have left.
+func inviteAllowanceNote(acc *auth.Account, left int) string {
+	if acc.Admin {
+		return ""
+	}
+	return fmt.Sprintf(" You can invite %d more; invites nobody has used yet count towards that.", left)
 }
 
 // RequestInvite handles POST /request-invite — someone is asking to
 // join. Validates captcha + rate limit, stores the request for admin
 // review.
 func RequestInvite(w http.ResponseWriter, r *http.Request) {
+	if auth.SignupClosed() {
+		renderSignupClosed(w)
+		return
+	}
 	if r.Method == "GET" {
 		renderRequestInvitePage(w, r, "")
 		return
@@ -714,13 +789,16 @@ func CardWithIcon(id, title, icon, content string) string {
 
 // Login handler
 func Login(w http.ResponseWriter, r *http.Request) {
+	if auth.MagicLinkOnly() {
+		// Passwords are switched off at /admin/login; send everyone
+		// to the email link form.
+		http.Redirect(w, r, "/login/link"+redirectQuery(r), http.StatusSeeOther)
+		return
+	}
+
 	if r.Method == "GET" {
 		// Preserve redirect parameter in form action
-		redirectParam := ""
-		if redirect := r.URL.Query().Get("redirect"); redirect != "" {
-			redirectParam = "?redirect=" + url.QueryEscape(redirect)
-		}
-		w.Write([]byte(loginPage(redirectParam, "")))
+		w.Write([]byte(loginPage(r, redirectQuery(r), "")))
 		return
 	}
 
@@ -731,42 +809,25 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		secret := r.Form.Get("secret")
 
 		// Preserve redirect parameter for error messages
-		redirectParam := ""
-		if redirect := r.URL.Query().Get("redirect"); redirect != "" {
-			redirectParam = "?redirect=" + url.QueryEscape(redirect)
-		}
+		redirectParam := redirectQuery(r)
 
 		if len(id) == 0 {
-			w.Write([]byte(loginPage(redirectParam, `<p class="text-error">Username is required</p>`)))
+			w.Write([]byte(loginPage(r, redirectParam, `<p class="text-error">`+T(GetUserLanguage(r), "auth.username_required")+`</p>`)))
 			return
 		}
 		if len(secret) == 0 {
-			w.Write([]byte(loginPage(redirectParam, `<p class="text-error">Password is required</p>`)))
+			w.Write([]byte(loginPage(r, redirectParam, `<p class="text-error">`+T(GetUserLanguage(r), "auth.password_required")+`</p>`)))
 			return
 		}
 
 		sess, err := auth.Login(id, secret)
 		if err != nil {
-			w.Write([]byte(loginPage(redirectParam, `<p class="text-error">Invalid username or password</p>`)))
+			w.Write([]byte(loginPage(r, redirectParam, `<p class="text-error">`+T(GetUserLanguage(r), "auth.invalid_login")+`</p>`)))
 			return
 		}
 
-		var secure bool
-
-		if h := r.Header.Get("X-Forwarded-Proto"); h == "https" {
-			secure = true
-		}
-
 		// set a new token
-		http.SetCookie(w, &http.Cookie{
-			Name:     "session",
-			Value:    sess.Token,
-			Path:     "/",
-			MaxAge:   2592000,
-			Secure:   secure,
-			HttpOnly: true,
-			SameSite: http.SameSiteLaxMode,
-		})
+		SetSessionCookie(w, r, sess, r.Form.Get("remember") == "on")
 
 		// Check for redirect parameter, default to home
 		redirectTo := r.URL.Query().Get("redirect")
@@ -778,6 +839,14 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// redirectQuery carries a ?redirect= parameter over to another login URL.
+func redirectQuery(r *http.Request) string {
+	if redirect := r.URL.Query().Get("redirect"); redirect != "" {
+		return "?redirect=" + url.QueryEscape(redirect)
+	}
+	return ""
+}
+
 // Signup handler
 func Signup(w http.ResponseWriter, r *http.Request) {
 	// Thread the invite code through renders so the hidden field persists.
@@ -789,6 +858,11 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 	}
 	currentInviteCode = invCode
 
+	if auth.SignupClosed() {
+		renderSignupClosed(w)
+		return
+	}
+
 	// Invite codes are optional — if one is provided (referral link),
 	// it's consumed after signup for tracking. Signup works without one.
 	// When INVITE_ONLY=true, a valid code IS required.
@@ -798,13 +872,13 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 	}
 	if auth.InviteOnly() && invCode != "" {
 		if err := auth.ValidateInvite(invCode); err != nil {
-			w.Write([]byte(renderSignup(fmt.Sprintf(`<p class="text-error">%s</p>`, err.Error()))))
+			w.Write([]byte(renderSignup(r, fmt.Sprintf(`<p class="text-error">%s</p>`, err.Error()))))
 			return
 		}
 	}
 
 	if r.Method == "GET" {
-		w.Write([]byte(renderSignup("")))
+		w.Write([]byte(renderSignup(r, "")))
 		return
 	}
 
@@ -814,7 +888,7 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		// Captcha is checked before the IP rate limit so that a failed
 		// captcha doesn't burn an attempt against the IP bucket.
 		if err := VerifyCaptchaRequest(r); err != nil {
-			w.Write([]byte(renderSignup(fmt.Sprintf(`<p class="text-error">%s</p>`, err.Error()))))
+			w.Write([]byte(renderSignup(r, fmt.Sprintf(`<p class="text-error">%s</p>`, err.Error()))))
 			return
 		}
 
@@ -822,7 +896,7 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		ip := ClientIP(r)
 		if !SignupRateLimit(ip) {
 			Log("auth", "Signup rate limit hit for IP: %s", ip)
-			w.Write([]byte(renderSignup(`<p class="text-error">Too many sign-ups from your network. Please try again later.</p>`)))
+			w.Write([]byte(renderSignup(r, `<p class="text-error">Too many sign-ups from your network. Please try again later.</p>`)))
 			return
 		}
 
@@ -835,27 +909,33 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		usernameRegex := regexp.MustCompile(usernamePattern)
 
 		if len(id) == 0 {
-			w.Write([]byte(renderSignup(`<p class="text-error">Username is required</p>`)))
+			w.Write([]byte(renderSignup(r, `<p class="text-error">Username is required</p>`)))
 			return
 		}
 
 		if !usernameRegex.MatchString(id) {
-			w.Write([]byte(renderSignup(`<p class="text-error">Invalid username format. Must start with a letter, be 4-24 characters, and contain only lowercase letters, numbers, and underscores</p>`)))
+			w.Write([]byte(renderSignup(r, `<p class="text-error">Invalid username format. Must start with a letter, be 4-24 characters, and contain only lowercase letters, numbers, and underscores</p>`)))
 			return
 		}
 
 		if reason := auth.ValidateUsername(id); reason != "" {
-			w.Write([]byte(renderSignup(fmt.Sprintf(`<p class="text-error">%s</p>`, reason))))
+			w.Write([]byte(renderSignup(r, fmt.Sprintf(`<p class="text-error">%s</p>`, reason))))
 			return
 		}
 
 		if len(secret) == 0 {
-			w.Write([]byte(renderSignup(`<p class="text-error">Password is required</p>`)))
+			w.Write([]byte(renderSignup(r, `<p class="text-error">Password is required</p>`)))
 			return
 		}
 
 		if len(secret) < 6 {
-			w.Write([]byte(renderSignup(`<p class="text-error">Password must be at least 6 characters</p>`)))
+			w.Write([]byte(renderSignup(r, `<p class="text-error">Password must be at least 6 characters</p>`)))
+			return
+		}
+
+		email := strings.TrimSpace(r.Form.Get("email"))
+		if email != "" && !validEmail(email) {
+			w.Write([]byte(renderSignup(r, `<p class="text-error">Please enter a valid email address, or leave it blank</p>`)))
 			return
 		}
 
@@ -870,7 +950,7 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 			Name:    name,
 			Created: time.Now(),
 		}); err != nil {
-			w.Write([]byte(renderSignup(fmt.Sprintf(`<p class="text-error">%s</p>`, err.Error()))))
+			w.Write([]byte(renderSignup(r, fmt.Sprintf(`<p class="text-error">%s</p>`, err.Error()))))
 			return
 		}
 
@@ -879,29 +959,26 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 			auth.ConsumeInvite(invCode, id)
 		}
 
+		// An email given at signup is kept unverified until the link
+		// we send is clicked. A failed send isn't fatal: it can be
+		// retried from /account.
+		if email != "" && EmailSender != nil {
+			if acc, err := auth.GetAccount(id); err == nil {
+				if err := sendVerificationEmail(acc, email); err != nil {
+					Log("auth", "Failed to send signup verification email to %s: %v", email, err)
+				}
+			}
+		}
+
 		// login
 		sess, err := auth.Login(id, secret)
 		if err != nil {
-			w.Write([]byte(renderSignup(`<p class="text-error">Account created but login failed. Please try logging in.</p>`)))
+			w.Write([]byte(renderSignup(r, `<p class="text-error">Account created but login failed. Please try logging in.</p>`)))
 			return
 		}
 
-		var secure bool
-
-		if h := r.Header.Get("X-Forwarded-Proto"); h == "https" {
-			secure = true
-		}
-
 		// set a new token
-		http.SetCookie(w, &http.Cookie{
-			Name:     "session",
-			Value:    sess.Token,
-			Path:     "/",
-			MaxAge:   2592000,
-			Secure:   secure,
-			HttpOnly: true,
-			SameSite: http.SameSiteLaxMode,
-		})
+		SetSessionCookie(w, r, sess, false)
 
 		// return to home
 		http.Redirect(w, r, "/home", 302)
@@ -966,6 +1043,27 @@ func Account(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Username change
+		if newID := strings.ToLower(strings.TrimSpace(r.Form.Get("username"))); newID != "" {
+			oldID := acc.ID
+			if err := auth.RenameAccount(oldID, newID); err != nil {
+				BadRequest(w, r, err.Error())
+				return
+			}
+			Log("auth", "%s changed username to %s", oldID, newID)
+			http.Redirect(w, r, "/account", http.StatusSeeOther)
+			return
+		}
+
+		// Weekly digest by mail
+		if r.Form.Get("save_digest") != "" {
+			acc.WeeklyDigest = r.Form.Get("weekly_digest") == "on"
+			acc.WeeklyDigestEmail = acc.WeeklyDigest && r.Form.Get("weekly_digest_email") == "on"
+			auth.UpdateAccount(acc)
+			http.Redirect(w, r, "/account", http.StatusSeeOther)
+			return
+		}
+
 		// Discord link code generation
 		if r.Form.Get("discord_link") != "" {
 			if DiscordLinkCodeFunc != nil {
@@ -1011,10 +1109,12 @@ func Account(w http.ResponseWriter, r *http.Request) {
 	// Home card preferences
 	allCards := []struct{ id, label string }{
 		{"reminder", "Islam"}, {"blog", "Blog"}, {"news", "News"},
-		{"markets", "Markets"}, {"social", "Social"}, {"video", "Video"},
-		{"images", "Images"}, {"mail", "Mail"}, {"web", "Search"},
+		{"trending", "Trending"}, {"markets", "Markets"}, {"social", "Social"},
+		{"video", "Video"}, {"images", "Images"}, {"places", "Places"},
+		{"weather", "Weather"}, {"tasks", "Tasks"}, {"mail", "Mail"}, {"web", "Search"},
+		{"briefing", "Morning briefing"}, {"prayer", "Prayer times"},
 	}
-	optInCards := map[string]bool{"mail": true, "web": true}
+	optInCards := map[string]bool{"mail": true, "web": true, "briefing": true, "prayer": true}
 	activeCards := map[string]bool{}
 	for _, c := range allCards {
 		if optInCards[c.id] {
@@ -1041,6 +1141,8 @@ func Account(w http.ResponseWriter, r *http.Request) {
 </form>
 </div>`, cardsCheckboxes)
 
+	digestCard := renderDigestCard(acc)
+
 	// Discord link card
 	discordCard := ""
 	if DiscordLinkCodeFunc != nil {
@@ -1067,6 +1169,7 @@ func Account(w http.ResponseWriter, r *http.Request) {
 <h4>Profile</h4>
 <p><strong>%s</strong> · %s · Joined %s</p>
 <p><a href="/@%s">Public profile →</a></p>
+%s
 </div>
 
 %s
@@ -1087,22 +1190,30 @@ func Account(w http.ResponseWriter, r *http.Request) {
 
 %s
 
+%s
+
 <div class="card">
 <h4>Settings</h4>
 %s
-<p><a href="/token">API Credentials →</a></p>
+<p><a href="/account/tokens">API Credentials →</a></p>
+<p><a href="/user/privacy">Privacy →</a></p>
 <p><a href="/app/blocked">Blocked Users →</a></p>
-<p><a href="/app/saved">Saved →</a></p>
+<p><a href="/saved">Saved →</a></p>
+<p><a href="/account/export">Export your data →</a></p>
+<p><a href="/account/recovery">Account recovery →</a></p>
+<p><a href="/account/sessions">Sessions →</a></p>
 <p style="margin-top:12px"><a href="/logout" class="text-error">Logout</a></p>
 </div>`,
 		acc.ID,
 		acc.Name,
 		acc.Created.Format("January 2, 2006"),
 		acc.ID,
+		renderUsernameForm(acc),
 		emailCard,
 		googleCard,
 		languageOptions,
 		homeCardsCard,
+		digestCard,
 		PasskeyListHTML(acc.ID),
 		discordCard,
 		adminLinks,
@@ -1112,6 +1223,48 @@ func Account(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
+// renderUsernameForm renders the username change form in the profile
+// card, or when the next change is allowed.
+func renderUsernameForm(acc *auth.Account) string {
+	if next := auth.NextUsernameChange(acc); !next.IsZero() {
+		return fmt.Sprintf(`<p class="text-sm text-muted">You can change your username again on %s.</p>`, next.Format("2 January 2006"))
+	}
+	days := int(auth.UsernameRedirectPeriod.Hours() / 24)
+	return fmt.Sprintf(`<details class="text-sm mt-2"><summary>Change username</summary>
+<form action="/account" method="POST" class="mt-2" onsubmit="return confirm('Change your username? You can only do this once every %d days.')">
+<input type="text" name="username" placeholder="New username" pattern="[a-z][a-z0-9_]{3,23}" required autocomplete="off">
+<p class="text-sm text-muted">Links to /@%s redirect for %d days and nobody else can take it in that time. Your posts, mail and credits move with you.</p>
+<button type="submit">Change</button>
+</form>
+</details>`, int(auth.UsernameChangeInterval.Hours()/24), acc.ID, days)
+}
+
+// renderDigestCard renders the weekly digest opt-in on the account page.
+// The email copy is only offered once the address is verified.
+func renderDigestCard(acc *auth.Account) string {
+	checked := func(on bool) string {
+		if on {
+			return " checked"
+		}
+		return ""
+	}
+	email := ""
+	if acc.EmailVerified && acc.Email != "" && EmailSender != nil {
+		email = fmt.Sprintf(`<label style="display:flex;align-items:center;gap:8px;padding:6px 0;font-size:14px"><input type="checkbox" name="weekly_digest_email"%s style="width:18px;height:18px"> Also email it to %s</label>`,
+			checked(acc.WeeklyDigestEmail), htmlpkg.EscapeString(acc.Email))
+	}
+	return fmt.Sprintf(`<div class="card">
+<h4>Weekly Digest</h4>
+<p class="text-sm text-muted">Every Monday: the week's top headlines, new blog posts and your unread mail count, sent to your Mu inbox.</p>
+<form action="/account" method="POST" style="margin-top:8px">
+<input type="hidden" name="save_digest" value="1">
+<label style="display:flex;align-items:center;gap:8px;padding:6px 0;font-size:14px"><input type="checkbox" name="weekly_digest"%s style="width:18px;height:18px"> Send me the weekly digest</label>
+%s
+<button type="submit" class="mt-2">Save</button>
+</form>
+</div>`, checked(acc.WeeklyDigest), email)
+}
+
 // renderEmailCard renders the email verification card on the account
 // page. The card looks different depending on whether the email is set,
 // pending, or verified — and whether email sending is configured at all.
@@ -1163,16 +1316,24 @@ func handleVerifyStart(w http.ResponseWriter, r *http.Request, acc *auth.Account
 		return
 	}
 
-	// Persist the pending email so the UI can show it.
-	if err := auth.SetAccountEmail(acc.ID, email); err != nil {
-		ServerError(w, r, "Failed to save email")
+	if err := sendVerificationEmail(acc, email); err != nil {
+		Log("auth", "Failed to send verification email to %s: %v", email, err)
+		ServerError(w, r, "Failed to send verification email. Please try again.")
 		return
 	}
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
 
+// sendVerificationEmail records email as the account's pending address
+// and mails it a link to /verify.
+func sendVerificationEmail(acc *auth.Account, email string) error {
+	// Persist the pending email so the UI can show it.
+	if err := auth.SetAccountEmail(acc.ID, email); err != nil {
+		return err
+	}
 	tok, err := auth.CreateEmailVerificationToken(acc.ID, email)
 	if err != nil {
-		ServerError(w, r, "Failed to create verification token")
-		return
+		return err
 	}
 
 	link := PublicURL() + "/verify?token=" + tok
@@ -1180,12 +1341,10 @@ func handleVerifyStart(w http.ResponseWriter, r *http.Request, acc *auth.Account
 	html := fmt.Sprintf(`<p>Hi %s,</p><p>Click the link below to verify your email and unlock posting on Mu:</p><p><a href="%s">%s</a></p><p>This link expires in 24 hours. If you didn't request this, you can ignore this email.</p><p>— Mu</p>`, htmlpkg.EscapeString(acc.Name), link, link)
 
 	if err := EmailSender(email, "Verify your Mu account", plain, html); err != nil {
-		Log("auth", "Failed to send verification email to %s: %v", email, err)
-		ServerError(w, r, "Failed to send verification email. Please try again.")
-		return
+		return err
 	}
 	Log("auth", "Sent verification email to %s for account %s", email, acc.ID)
-	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
 }
 
 // Verify handles GET /verify?token=XXX — consumes a verification token
@@ -1238,21 +1397,8 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var secure bool
-
-	if h := r.Header.Get("X-Forwarded-Proto"); h == "https" {
-		secure = true
-	}
-	// delete the session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		Secure:   secure,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	// delete the session cookies
+	ClearSessionCookies(w, r)
 	auth.Logout(sess.Token)
 	http.Redirect(w, r, "/", 302)
 }
@@ -1374,11 +1520,13 @@ var SupportedLanguages = map[string]string{
 	"zh": "中文",
 }
 
-// GetUserLanguage returns the language preference for the current user, defaults to "en"
+// GetUserLanguage returns the language preference for the current user.
+// Guests, and accounts that haven't chosen, get their browser's language
+// if it's supported, else "en".
 func GetUserLanguage(r *http.Request) string {
 	_, acc := auth.TrySession(r)
 	if acc == nil || acc.Language == "" {
-		return "en"
+		return requestLanguage(r)
 	}
 	return acc.Language
 }
@@ -1398,8 +1546,14 @@ func RenderHTMLForRequest(title, desc, html string, r *http.Request) string {
 		html = banner + html
 	}
 	_, acc := auth.TrySession(r)
-	out := RenderHTMLWithLangAndAuth(title, desc, html, lang, acc)
-	return out
+	return renderPage(title, desc, html, lang, "", UserTheme(r), acc)
+}
+
+// RenderHTMLForRequestWithBody is RenderHTMLForRequest with a custom body
+// attribute string (e.g. ` class="page-home"` to enable page-specific CSS).
+func RenderHTMLForRequestWithBody(title, desc, html, bodyAttr string, r *http.Request) string {
+	_, acc := auth.TrySession(r)
+	return renderPage(title, desc, html, GetUserLanguage(r), bodyAttr, UserTheme(r), acc)
 }
 
 // VerifyBanner returns banner HTML inviting the user to verify their
@@ -1426,15 +1580,15 @@ func VerifyBanner(r *http.Request) string {
 </div>`
 }
 
-func navAuthHTML(acc *auth.Account) string {
+func navAuthHTML(acc *auth.Account, lang string) string {
 	if acc == nil {
-		return `<a id="nav-login" href="/login"><img src="/account.png?` + Version + `"><span class="label">Login</span></a>`
+		return `<a id="nav-login" href="/login"><img src="` + Asset("/account.png") + `"><span class="label">` + T(lang, "nav.login") + `</span></a>`
 	}
 	username := htmlpkg.EscapeString(acc.ID)
-	return `<div id="nav-username">Signed in as @` + username + `</div>
-          <a id="nav-account" href="/account"><img src="/account.png?` + Version + `"><span class="label">Account</span></a>
-          <a id="nav-logout" href="/logout"><img src="/logout.png?` + Version + `"><span class="label">Logout</span></a>
-          <a id="nav-login" href="/login" style="display: none;"><img src="/account.png?` + Version + `"><span class="label">Login</span></a>`
+	return `<div id="nav-username">` + T(lang, "nav.signed_in_as", username) + `</div>
+          <a id="nav-account" href="/account"><img src="` + Asset("/account.png") + `"><span class="label">` + T(lang, "nav.account") + `</span></a>
+          <a id="nav-logout" href="/logout"><img src="` + Asset("/logout.png") + `"><span class="label">` + T(lang, "nav.logout") + `</span></a>
+          <a id="nav-login" href="/login" style="display: none;"><img src="` + Asset("/account.png") + `"><span class="label">` + T(lang, "nav.login") + `</span></a>`
 }
 
 // RenderHTMLWithLang renders the given html in a template with specified language
@@ -1443,20 +1597,16 @@ func RenderHTMLWithLang(title, desc, html, lang string) string {
 }
 
 func RenderHTMLWithLangAndAuth(title, desc, html, lang string, acc *auth.Account) string {
-	if lang == "" {
-		lang = "en"
-	}
-	return fmt.Sprintf(Template, lang, title, desc, "", navAuthHTML(acc), title, html)
+	return renderPage(title, desc, html, lang, "", accountTheme(acc), acc)
 }
 
-// RenderHTMLWithLangAndBody renders html with a custom body attribute string
-// (e.g. ` class="page-home"` to enable page-specific CSS). Pass the viewer's
-// account so the sidebar shows Account/Logout when signed in (nil for guests).
-func RenderHTMLWithLangAndBody(title, desc, html, lang, bodyAttr string, acc *auth.Account) string {
+// renderPage fills in the layout. Pass the viewer's account so the
+// sidebar shows Account/Logout when signed in (nil for guests).
+func renderPage(title, desc, html, lang, bodyAttr, theme string, acc *auth.Account) string {
 	if lang == "" {
 		lang = "en"
 	}
-	return fmt.Sprintf(Template, lang, title, desc, bodyAttr, navAuthHTML(acc), title, html)
+	return fmt.Sprintf(Localize(Template, lang), lang, Dir(lang), theme, title, desc, bodyAttr, themeToggleHTML(theme)+navAuthHTML(acc, lang), title, html)
 }
 
 // RenderString renders a markdown string as html
@@ -1466,7 +1616,7 @@ func RenderString(v string) string {
 
 // RenderTemplate renders a markdown string in a html template
 func RenderTemplate(title string, desc, text string) string {
-	return fmt.Sprintf(Template, "en", title, desc, "", navAuthHTML(nil), title, RenderString(text))
+	return renderPage(title, desc, RenderString(text), "en", "", ThemeAuto, nil)
 }
 
 func ServeHTML(html string) http.Handler {
@@ -1487,8 +1637,17 @@ func Serve() http.Handler {
 
 	// Wrap with cache headers for static assets
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if leafletFallback(w, r, htmlContent) {
+			return
+		}
 		// Set cache headers for static assets
-		if strings.HasSuffix(r.URL.Path, ".css") ||
+		if strings.HasSuffix(r.URL.Path, ".webmanifest") {
+			w.Header().Set("Content-Type", "application/manifest+json")
+		}
+		if fingerprinted(r.URL.Path, r.URL.Query().Get("v")) {
+			// The URL changes whenever the file does; see Asset.
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else if strings.HasSuffix(r.URL.Path, ".css") ||
 			strings.HasSuffix(r.URL.Path, ".js") ||
 			strings.HasSuffix(r.URL.Path, ".png") ||
 			strings.HasSuffix(r.URL.Path, ".ico") ||
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/settings"
+)
+
+func init() {
+	settings.Register("Content",
+		settings.Var{Key: "ARCHIVE_AFTER_DAYS", Type: settings.TypeInt, Default: "180", Doc: "Days without activity before posts and threads are archived; 0 turns it off"},
+	)
+}
+
+// Archive mode. Blog posts and social threads with no activity for
+// ARCHIVE_AFTER_DAYS (default 180, 0 turns it off) are archived: new
+// comments and replies are refused, the page says so, and they drop out
+// of "active" listings. They stay indexed and searchable. An admin can
+// reopen an archived thread, or archive one early, from its page.
+
+const defaultArchiveDays = 180
+
+// Archive overrides set by admins.
+const (
+	ArchiveAuto   = ""
+	ArchiveOpen   = "open"
+	ArchiveLocked = "archived"
+)
+
+var (
+	archiveMu        sync.RWMutex
+	archiveOverrides map[string]string // "type:id" → ArchiveOpen or ArchiveLocked
+)
+
+// ArchiveAfter returns how long a thread may go quiet before it's
+// archived, or 0 when archiving is off.
+func ArchiveAfter() time.Duration {
+	days := settings.Int("ARCHIVE_AFTER_DAYS")
+	if days < 0 {
+		days = defaultArchiveDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func loadArchiveOverrides() {
+	if archiveOverrides != nil {
+		return
+	}
+	archiveOverrides = map[string]string{}
+	data.LoadJSON("archive_overrides.json", &archiveOverrides)
+}
+
+// ArchiveOverride returns the admin's setting for an item.
+func ArchiveOverride(itemType, id string) string {
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	loadArchiveOverrides()
+	return archiveOverrides[itemType+":"+id]
+}
+
+// SetArchiveOverride reopens (ArchiveOpen), archives (ArchiveLocked) or
+// returns an item to automatic archiving (ArchiveAuto).
+func SetArchiveOverride(itemType, id, state string) error {
+	if state != ArchiveAuto && state != ArchiveOpen && state != ArchiveLocked {
+		return fmt.Errorf("unknown archive state %q", state)
+	}
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	loadArchiveOverrides()
+	key := itemType + ":" + id
+	if state == ArchiveAuto {
+		delete(archiveOverrides, key)
+	} else {
+		archiveOverrides[key] = state
+	}
+	return data.SaveJSON("archive_overrides.json", archiveOverrides)
+}
+
+// IsArchived reports whether a thread whose latest activity was at
+// lastActivity is archived.
+func IsArchived(itemType, id string, lastActivity time.Time) bool {
+	switch ArchiveOverride(itemType, id) {
+	case ArchiveOpen:
+		return false
+	case ArchiveLocked:
+		return true
+	}
+	age := ArchiveAfter()
+	return age > 0 && !lastActivity.IsZero() && time.Since(lastActivity) > age
+}
+
+// ArchiveNotice renders the archived banner for a thread, with the admin
+// toggle for admins. It returns "" for an open thread viewed by a member.
+func ArchiveNotice(r *http.Request, itemType, id string, archived bool) string {
+	var sb strings.Builder
+	if archived {
+		sb.WriteString(`<div class="archived-notice">Archived · this thread is closed to new replies</div>`)
+	}
+	if _, acc := auth.TrySession(r); acc != nil && acc.Admin {
+		state, label := ArchiveLocked, "Archive"
+		if archived {
+			state, label = ArchiveOpen, "Reopen"
+		}
+		fmt.Fprintf(&sb, `<form method="POST" action="/archive" class="archive-toggle"><input type="hidden" name="type" value="%s"><input type="hidden" name="id" value="%s"><input type="hidden" name="state" value="%s"><input type="hidden" name="redirect" value="%s"><button type="submit" class="btn-link text-sm">%s</button></form>`,
+			htmlpkg.EscapeString(itemType), htmlpkg.EscapeString(id), state, htmlpkg.EscapeString(r.URL.RequestURI()), label)
+	}
+	return sb.String()
+}
+
+// ArchiveHandler serves POST /archive for admins: type, id and state
+// (open, archived, or empty for automatic).
+func ArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		MethodNotAllowed(w, r)
+		return
+	}
+	if _, _, err := auth.RequireAdmin(r); err != nil {
+		Forbidden(w, r, "Admin access required")
+		return
+	}
+	var req struct {
+		Type  string `json:"type"`
+		ID    string `json:"id"`
+		State string `json:"state"`
+	}
+	redirect := ""
+	if SendsJSON(r) {
+		if err := DecodeJSON(r, &req); err != nil {
+			BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Type, req.ID, req.State = r.FormValue("type"), r.FormValue("id"), r.FormValue("state")
+		redirect = r.FormValue("redirect")
+	}
+	if req.Type == "" || req.ID == "" {
+		BadRequest(w, r, "type and id are required")
+		return
+	}
+	if err := SetArchiveOverride(req.Type, req.ID, req.State); err != nil {
+		BadRequest(w, r, err.Error())
+		return
+	}
+	Log("archive", "%s:%s set to %q", req.Type, req.ID, req.State)
+	// Only redirect within the site.
+	if u, err := url.Parse(redirect); err == nil && redirect != "" && u.Host == "" && strings.HasPrefix(u.Path, "/") && !strings.HasPrefix(redirect, "//") {
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+	RespondJSON(w, map[string]string{"type": req.Type, "id": req.ID, "state": req.State})
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsArchivedByAgeAndOverride(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ARCHIVE_AFTER_DAYS", "30")
+	archiveMu.Lock()
+	archiveOverrides = nil
+	archiveMu.Unlock()
+
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	if !IsArchived("post", "1", old) || IsArchived("post", "2", recent) {
+		t.Fatal("age-based archiving wrong")
+	}
+
+	SetArchiveOverride("post", "1", ArchiveOpen)
+	SetArchiveOverride("post", "2", ArchiveLocked)
+	if IsArchived("post", "1", old) || !IsArchived("post", "2", recent) {
+		t.Error("admin overrides not applied")
+	}
+	SetArchiveOverride("post", "1", ArchiveAuto)
+	if !IsArchived("post", "1", old) {
+		t.Error("clearing the override should restore automatic archiving")
+	}
+
+	t.Setenv("ARCHIVE_AFTER_DAYS", "0")
+	if IsArchived("social", "3", old) {
+		t.Error("archiving should be off at 0 days")
+	}
+}
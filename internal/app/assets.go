@@ -0,0 +1,68 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// The static assets in html/ are fingerprinted by content at startup.
+// Asset links them with ?v=<hash>, so a deploy that changes a file
+// changes its URL, and Serve lets browsers keep a fingerprinted URL for
+// a year. Unchanged files keep their URL, and their cache, across deploys.
+
+// assetHashes maps each asset's path, e.g. "/mu.css", to a short hash of
+// its content.
+var assetHashes = hashAssets()
+
+func hashAssets() map[string]string {
+	out := map[string]string{}
+	fs.WalkDir(htmlFiles, "html", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		b, err := htmlFiles.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		sum := sha256.Sum256(b)
+		out[strings.TrimPrefix(p, "html")] = hex.EncodeToString(sum[:5])
+		return nil
+	})
+	return out
+}
+
+// Asset returns the fingerprinted URL of a static asset, such as
+// Asset("/mu.css"). Unknown paths are busted with the process Version.
+func Asset(name string) string {
+	if h, ok := assetHashes[name]; ok {
+		return name + "?v=" + h
+	}
+	return name + "?" + Version
+}
+
+// fingerprinted reports whether a request for name carries its current
+// fingerprint.
+func fingerprinted(name, v string) bool {
+	h, ok := assetHashes[name]
+	return ok && v != "" && v == h
+}
+
+// precacheAssets are the assets the service worker stores at install,
+// so the layout renders offline: the stylesheet, script, manifest and
+// icons.
+func precacheAssets() []string {
+	var out []string
+	for name := range assetHashes {
+		switch ext := path.Ext(name); {
+		case name == "/mu.css", name == "/mu.js", name == "/offline.html", name == "/manifest.webmanifest",
+			strings.Count(name, "/") == 1 && (ext == ".png" || ext == ".svg" || ext == ".ico"):
+			out = append(out, Asset(name))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
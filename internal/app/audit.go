@@ -0,0 +1,205 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// The admin audit log records every change an admin makes: who, what, to
+// what, and when. It is append-only, one JSON line per entry in
+// admin_audit.jsonl, and nothing here rewrites or trims it. Writes to
+// Audited routes are recorded by the router; admin actions taken on
+// ordinary routes, such as deleting someone else's post, call Audit.
+
+const adminAuditKey = "admin_audit.jsonl"
+
+// AuditEntry is one admin action.
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`            // the admin's account ID
+	Action string    `json:"action"`           // e.g. "users ban", "blocklist block_email"
+	Target string    `json:"target,omitempty"` // what it was done to: an account, address, ID…
+	Path   string    `json:"path,omitempty"`   // the route it came through
+}
+
+// AuditFilter narrows AuditLog. Empty fields match everything.
+type AuditFilter struct {
+	Actor  string
+	Action string // matches entries whose action contains it
+	Target string // matches entries whose target contains it
+	Limit  int    // 0 for no limit
+}
+
+var adminAuditMu sync.Mutex
+
+// Audit records an admin action.
+func Audit(actor, action, target, path string) {
+	b, err := json.Marshal(AuditEntry{Time: time.Now(), Actor: actor, Action: action, Target: target, Path: path})
+	if err != nil {
+		return
+	}
+	adminAuditMu.Lock()
+	defer adminAuditMu.Unlock()
+	if err := data.AppendFile(adminAuditKey, string(b)+"\n"); err != nil {
+		Log("admin", "Audit log write error: %v", err)
+	}
+}
+
+// AuditLog returns the entries matching f, newest first.
+func AuditLog(f AuditFilter) []AuditEntry {
+	b, err := data.LoadFile(adminAuditKey)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	var entries []AuditEntry
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	out := []AuditEntry{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if f.Actor != "" && e.Actor != f.Actor {
+			continue
+		}
+		if f.Action != "" && !strings.Contains(e.Action, f.Action) {
+			continue
+		}
+		if f.Target != "" && !strings.Contains(strings.ToLower(e.Target), strings.ToLower(f.Target)) {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// auditTargetFields are the request fields that name what an admin
+// control acted on, most specific first. Values of anything else, such
+// as settings, are never recorded.
+var auditTargetFields = []string{"user_id", "id", "email", "ip", "host", "slug", "name", "package", "key", "type"}
+
+// Audit records the writes admins make through Audited routes. The
+// action is the route's last path segment and the request's "action"
+// field, the target the first of auditTargetFields it carries. Requests
+// that fail (a 4xx or 5xx status) aren't recorded.
+func (rt *Router) Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" || rt.Options(r.URL.Path)&Audited == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		_, acc := auth.TrySession(r)
+		if acc == nil || !acc.Admin {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fields := auditFields(r)
+		override := new(string)
+		r = r.WithContext(context.WithValue(r.Context(), auditTargetKey{}, override))
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		if sw.status >= 400 {
+			return
+		}
+
+		action := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if a := fields["action"]; a != "" {
+			action += " " + a
+		} else if r.Method != "POST" {
+			action += " " + strings.ToLower(r.Method)
+		}
+		target := *override
+		if target == "" {
+			for _, k := range auditTargetFields {
+				if v := strings.TrimSpace(fields[k]); v != "" {
+					target = v
+					break
+				}
+			}
+		}
+		Audit(acc.ID, action, target, r.URL.Path)
+	})
+}
+
+type auditTargetKey struct{}
+
+// SetAuditTarget names what an Audited request acted on, for handlers
+// whose target isn't one of the usual fields, such as the settings an
+// admin changed.
+func SetAuditTarget(r *http.Request, target string) {
+	if p, ok := r.Context().Value(auditTargetKey{}).(*string); ok {
+		*p = target
+	}
+}
+
+// auditFields reads the action and target fields from a form or JSON
+// request, leaving the body for the handler.
+func auditFields(r *http.Request) map[string]string {
+	out := map[string]string{}
+	keys := append([]string{"action"}, auditTargetFields...)
+	if SendsJSON(r) {
+		b, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		var m map[string]interface{}
+		if err != nil || json.Unmarshal(b, &m) != nil {
+			return out
+		}
+		for _, k := range keys {
+			if s, ok := m[k].(string); ok {
+				out[k] = s
+			}
+		}
+		return out
+	}
+	r.ParseForm()
+	for _, k := range keys {
+		out[k] = r.FormValue(k)
+	}
+	return out
+}
+
+// statusWriter notes the status a handler responds with.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (s *statusWriter) WriteHeader(code int) {
+	if !s.wrote {
+		s.wrote = true
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusWriter) Write(b []byte) (int, error) {
+	s.wrote = true
+	return s.ResponseWriter.Write(b)
+}
+
+// Flush sends what's been written so far, for handlers that stream.
+func (s *statusWriter) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
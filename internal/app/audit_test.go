@@ -0,0 +1,92 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestAuditRecordsAdminWrites(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, a := range []*auth.Account{
+		{ID: "root_admin", Name: "Root", Secret: "secret", Admin: true, Created: time.Now()},
+		{ID: "member", Name: "Member", Secret: "secret", Created: time.Now()},
+	} {
+		if err := auth.Create(a); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(a.ID)
+	}
+
+	rt := NewRouter()
+	rt.HandleFunc("/admin/users", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.FormValue("user_id") == "" {
+			BadRequest(w, r, "user_id required")
+			return
+		}
+		io.WriteString(w, "ok")
+	}, Audited)
+	rt.HandleFunc("/admin/env", func(w http.ResponseWriter, r *http.Request) {
+		SetAuditTarget(r, "SESSION_TTL")
+		io.WriteString(w, "ok")
+	}, Audited)
+	rt.HandleFunc("/admin/rooms", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Action, Slug string }
+		DecodeJSON(r, &req)
+		io.WriteString(w, req.Slug)
+	}, Audited)
+	rt.Use(rt.Audit)
+
+	post := func(user, path, contentType, body string) *httptest.ResponseRecorder {
+		sess, err := auth.Login(user, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		return w
+	}
+	form := "application/x-www-form-urlencoded"
+
+	post("root_admin", "/admin/users", form, url.Values{"action": {"ban"}, "user_id": {"spammer"}}.Encode())
+	post("root_admin", "/admin/users", form, "action=ban") // fails, not recorded
+	post("member", "/admin/users", form, "action=ban&user_id=root_admin")
+	post("root_admin", "/admin/env", form, "SESSION_TTL=1h")
+	if w := post("root_admin", "/admin/rooms", "application/json", `{"action":"archive","slug":"general"}`); w.Body.String() != "general" {
+		t.Errorf("handler didn't get the JSON body: %q", w.Body.String())
+	}
+
+	got := AuditLog(AuditFilter{})
+	want := []AuditEntry{
+		{Actor: "root_admin", Action: "rooms archive", Target: "general", Path: "/admin/rooms"},
+		{Actor: "root_admin", Action: "env", Target: "SESSION_TTL", Path: "/admin/env"},
+		{Actor: "root_admin", Action: "users ban", Target: "spammer", Path: "/admin/users"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("AuditLog = %+v", got)
+	}
+	for i := range want {
+		g := got[i]
+		g.Time = time.Time{}
+		if g != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, g, want[i])
+		}
+	}
+
+	if f := AuditLog(AuditFilter{Action: "ban"}); len(f) != 1 || f[0].Target != "spammer" {
+		t.Errorf("filter by action = %+v", f)
+	}
+	if f := AuditLog(AuditFilter{Target: "GEN", Limit: 5}); len(f) != 1 || f[0].Action != "rooms archive" {
+		t.Errorf("filter by target = %+v", f)
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"strings"
+
+	"mu/internal/data"
+)
+
+// LinkedFrom renders the "Linked from" section for an item: everything
+// public that links to it (see data.Backlinks), or "" when nothing does.
+func LinkedFrom(id string) string {
+	list := data.Backlinks(id)
+	if len(list) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<div class="linked-from"><h4>Linked from</h4>`)
+	for _, l := range list {
+		if l.URL == "" {
+			continue
+		}
+		title := l.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		label := typeLabels[l.Type]
+		if label == "" {
+			label = l.Type
+		}
+		sb.WriteString(fmt.Sprintf(`<p><a href="%s">%s</a> <span class="text-muted text-sm">%s · %s</span></p>`,
+			htmlpkg.EscapeString(l.URL), htmlpkg.EscapeString(title), htmlpkg.EscapeString(label), TimeAgo(l.Time)))
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"strings"
+
+	"mu/internal/auth"
+)
+
+// Read-it-later bookmarks. News, blog and video pages call BookmarkButton
+// to let the viewer save what they are reading; /saved lists everything
+// grouped by type. Bookmarks live in the user's prefs (see SaveItem).
+
+// bookmarkOrder is the order types are grouped in on /saved.
+var bookmarkOrder = []string{"news", "post", "video", "social", "web", "app", "work"}
+
+// Bookmark saves a content item for the user to come back to.
+func Bookmark(userID, contentType, contentID string) error {
+	if userID == "" {
+		return fmt.Errorf("not logged in")
+	}
+	if _, ok := typeLabels[contentType]; !ok {
+		return fmt.Errorf("can't bookmark %q", contentType)
+	}
+	if contentID == "" || len(contentID) > 2048 {
+		return fmt.Errorf("invalid id")
+	}
+	SaveItem(userID, contentType, contentID)
+	return nil
+}
+
+// Unbookmark removes a bookmark.
+func Unbookmark(userID, contentType, contentID string) {
+	UnsaveItem(userID, contentType, contentID)
+}
+
+// BookmarkButton renders a save/saved toggle for the viewer, or "" for
+// guests. Pages place it next to their other actions.
+func BookmarkButton(r *http.Request, contentType, contentID string) string {
+	sess, _ := auth.TrySession(r)
+	if sess == nil {
+		return ""
+	}
+	saved, label := "0", "☆ Save"
+	if IsSaved(sess.Account, contentType, contentID) {
+		saved, label = "1", "★ Saved"
+	}
+	return fmt.Sprintf(`<a href="#" class="bookmark-btn" data-type="%s" data-id="%s" data-saved="%s" title="Read it later" onclick="var el=this,on=el.dataset.saved==='1',h={'Content-Type':'application/json'},t=(document.cookie.match(/(?:^|; )csrf_token=([^;]+)/)||[])[1];if(t)h['X-CSRF-Token']=decodeURIComponent(t);fetch('/saved',{method:on?'DELETE':'POST',credentials:'same-origin',headers:h,body:JSON.stringify({type:el.dataset.type,id:el.dataset.id})}).then(function(r){if(r.ok){el.dataset.saved=on?'0':'1';el.textContent=on?'☆ Save':'★ Saved'}});return false;">%s</a>`,
+		htmlpkg.EscapeString(contentType), htmlpkg.EscapeString(contentID), saved, label)
+}
+
+// SavedHandler serves /saved:
+//
+//	GET     the saved items grouped by type (JSON when requested; ?type= filters)
+//	POST    {"type","id"} bookmarks an item
+//	DELETE  {"type","id"} removes it
+func SavedHandler(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		if WantsJSON(r) || SendsJSON(r) {
+			Unauthorized(w, r)
+			return
+		}
+		RedirectToLogin(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		renderSavedPage(w, r, sess.Account)
+	case "POST", "DELETE":
+		var req struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		}
+		if SendsJSON(r) {
+			if err := DecodeJSON(r, &req); err != nil {
+				BadRequest(w, r, "invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Type, req.ID = r.FormValue("type"), r.FormValue("id")
+		}
+		status := "saved"
+		if r.Method == "DELETE" || r.FormValue("action") == "remove" {
+			Unbookmark(sess.Account, req.Type, req.ID)
+			status = "removed"
+		} else if err := Bookmark(sess.Account, req.Type, req.ID); err != nil {
+			BadRequest(w, r, err.Error())
+			return
+		}
+		if SendsJSON(r) || WantsJSON(r) {
+			RespondJSON(w, map[string]string{"status": status})
+			return
+		}
+		http.Redirect(w, r, "/saved", http.StatusSeeOther)
+	default:
+		MethodNotAllowed(w, r)
+	}
+}
+
+// groupSaved splits saved items by type, keeping each group newest first.
+func groupSaved(items []SavedEntry) map[string][]SavedEntry {
+	groups := map[string][]SavedEntry{}
+	for _, it := range items {
+		groups[it.Type] = append(groups[it.Type], it)
+	}
+	return groups
+}
+
+func renderSavedPage(w http.ResponseWriter, r *http.Request, userID string) {
+	items := GetSavedList(userID)
+	if t := r.URL.Query().Get("type"); t != "" {
+		var filtered []SavedEntry
+		for _, it := range items {
+			if it.Type == t {
+				filtered = append(filtered, it)
+			}
+		}
+		items = filtered
+	}
+	groups := groupSaved(items)
+
+	if WantsJSON(r) {
+		if items == nil {
+			items = []SavedEntry{}
+		}
+		RespondJSON(w, map[string]interface{}{"saved": items, "groups": groups})
+		return
+	}
+
+	var sb strings.Builder
+	if len(items) == 0 {
+		sb.WriteString(`<div class="card"><p class="text-muted">Nothing saved yet. Use ☆ Save on a news article, post or video to read it later.</p></div>`)
+	}
+	order := append([]string(nil), bookmarkOrder...)
+	for t := range groups {
+		if _, ok := typeLabels[t]; !ok {
+			order = append(order, t) // saved before types were checked
+		}
+	}
+	for _, t := range order {
+		group := groups[t]
+		if len(group) == 0 {
+			continue
+		}
+		label := typeLabels[t]
+		if label == "" {
+			label = t
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="card" id="saved-%s">`, htmlpkg.EscapeString(t)))
+		sb.WriteString(fmt.Sprintf(`<h4>%s <span class="count">%d</span></h4>`, htmlpkg.EscapeString(label), len(group)))
+		for _, it := range group {
+			sb.WriteString(fmt.Sprintf(`<div style="padding:8px 0;border-bottom:1px solid #f0f0f0">
+				<a href="%s">%s</a>
+				<span class="text-sm text-muted"> · %s · <form method="POST" action="/saved" class="d-inline"><input type="hidden" name="action" value="remove"><input type="hidden" name="type" value="%s"><input type="hidden" name="id" value="%s"><button type="submit" class="link-btn" style="background:none;border:none;padding:0;color:inherit;cursor:pointer;text-decoration:underline">remove</button></form></span>
+			</div>`, htmlpkg.EscapeString(it.URL), htmlpkg.EscapeString(it.Title), TimeAgo(it.SavedAt),
+				htmlpkg.EscapeString(it.Type), htmlpkg.EscapeString(it.ID)))
+		}
+		sb.WriteString(`</div>`)
+	}
+
+	html := RenderHTMLForRequest("Saved", "Your saved items", sb.String(), r)
+	w.Write([]byte(html))
+}
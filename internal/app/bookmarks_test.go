@@ -0,0 +1,58 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBookmarksGroupedByType(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	defer ClearUserPrefs("reader")
+
+	if err := Bookmark("reader", "spaceship", "1"); err == nil {
+		t.Error("bookmarked an unknown type")
+	}
+	if err := Bookmark("reader", "news", ""); err == nil {
+		t.Error("bookmarked without an id")
+	}
+	for _, b := range [][2]string{{"news", "n1"}, {"post", "p1"}, {"news", "n2"}, {"video", "v1"}} {
+		if err := Bookmark("reader", b[0], b[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Unbookmark("reader", "video", "v1")
+	if IsSaved("reader", "video", "v1") {
+		t.Fatal("still saved after unbookmark")
+	}
+
+	req := httptest.NewRequest("GET", "/saved", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	renderSavedPage(w, req, "reader")
+	var got struct {
+		Saved  []SavedEntry            `json:"saved"`
+		Groups map[string][]SavedEntry `json:"groups"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Saved) != 3 || len(got.Groups["news"]) != 2 || len(got.Groups["post"]) != 1 || got.Groups["video"] != nil {
+		t.Fatalf("saved = %+v", got)
+	}
+	if u := got.Groups["news"][0].URL; !strings.HasPrefix(u, "/news?id=n") {
+		t.Errorf("news URL = %q", u)
+	}
+
+	req = httptest.NewRequest("GET", "/saved?type=post", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	renderSavedPage(w, req, "reader")
+	got.Saved = nil
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if len(got.Saved) != 1 || got.Saved[0].ID != "p1" {
+		t.Fatalf("filtered = %+v", got.Saved)
+	}
+}
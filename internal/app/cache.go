@@ -0,0 +1,64 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Pages built from cached content, such as /news from news.html and
+// headlines.html, carry an ETag and Last-Modified so repeat visits can be
+// answered with a 304 and no body. The pages differ per viewer, so they
+// are private and revalidated on every visit rather than cached blind.
+
+// ETag returns a weak validator for a response body. Weak, because Gzip
+// changes the bytes on the wire without changing the page.
+func ETag(body []byte) string {
+	h := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(h[:])[:16] + `"`
+}
+
+// NotModified sets the validators for a GET response and reports whether
+// the client's copy is still current, in which case it has written a 304
+// and the caller should stop. A zero modified time sends only the ETag.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "private, no-cache")
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+
+	// If-None-Match wins when both are sent.
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else if since := r.Header.Get("If-Modified-Since"); since == "" || modified.IsZero() {
+		return false
+	} else if t, err := http.ParseTime(since); err != nil || modified.Truncate(time.Second).After(t) {
+		return false
+	}
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compares an If-None-Match list with etag, weakly: W/"x"
+// and "x" are the same page.
+func etagMatches(list, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, m := range strings.Split(list, ",") {
+		m = strings.TrimSpace(m)
+		if m == "*" || strings.TrimPrefix(m, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
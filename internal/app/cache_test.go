@@ -0,0 +1,67 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRespondConditional(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	get := func(header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/news", nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		Respond(w, r, Response{Title: "News", HTML: "<p>headlines</p>", Modified: modified})
+		return w
+	}
+
+	first := get()
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.Len() == 0 {
+		t.Fatalf("first visit = %d, ETag %q", first.Code, etag)
+	}
+	if lm := first.Header().Get("Last-Modified"); lm != "Sun, 01 Mar 2026 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", lm)
+	}
+
+	for name, h := range map[string][]string{
+		"etag":           {"If-None-Match", etag},
+		"strong etag":    {"If-None-Match", etag[2:]},
+		"etag in list":   {"If-None-Match", `"other", ` + etag},
+		"not modified":   {"If-Modified-Since", "Sun, 01 Mar 2026 12:00:00 GMT"},
+		"modified since": {"If-Modified-Since", "Mon, 02 Mar 2026 00:00:00 GMT"},
+	} {
+		if w := get(h...); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: %d with %d bytes, want an empty 304", name, w.Code, w.Body.Len())
+		}
+	}
+	for name, h := range map[string][]string{
+		"stale etag": {"If-None-Match", `W/"stale"`},
+		"stale date": {"If-Modified-Since", "Sat, 28 Feb 2026 00:00:00 GMT"},
+		"etag wins":  {"If-None-Match", `W/"stale"`, "If-Modified-Since", "Mon, 02 Mar 2026 00:00:00 GMT"},
+	} {
+		if w := get(h...); w.Code != http.StatusOK {
+			t.Errorf("%s: %d, want 200", name, w.Code)
+		}
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	for header, want := range map[string]bool{
+		"gzip, deflate, br":    true,
+		"br;q=1.0, gzip;q=0.8": true,
+		"gzip;q=0":             false,
+		"identity":             false,
+		"*":                    true,
+		"*, gzip;q=0":          false,
+		"":                     false,
+	} {
+		if got := acceptsEncoding(header, "gzip"); got != want {
+			t.Errorf("acceptsEncoding(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
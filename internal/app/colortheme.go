@@ -0,0 +1,129 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// The colour theme is light, dark, or auto to follow the device. An
+// account keeps its choice in Account.Theme, a guest in a cookie. The
+// layout puts it on <html data-theme>, and mu.css switches its colour
+// variables on that. The nav toggle cycles auto → dark → light.
+
+const (
+	ThemeAuto  = "auto"
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+const themeCookie = "theme"
+
+func validTheme(t string) bool {
+	return t == ThemeAuto || t == ThemeLight || t == ThemeDark
+}
+
+// UserTheme returns the viewer's colour theme.
+func UserTheme(r *http.Request) string {
+	if r == nil {
+		return ThemeAuto
+	}
+	if _, acc := auth.TrySession(r); acc != nil && validTheme(acc.Theme) {
+		return acc.Theme
+	}
+	if c, err := r.Cookie(themeCookie); err == nil && validTheme(c.Value) {
+		return c.Value
+	}
+	return ThemeAuto
+}
+
+// accountTheme is the theme an account chose, for rendering without a
+// request.
+func accountTheme(acc *auth.Account) string {
+	if acc != nil && validTheme(acc.Theme) {
+		return acc.Theme
+	}
+	return ThemeAuto
+}
+
+// nextTheme is what the nav toggle switches to from t.
+func nextTheme(t string) string {
+	switch t {
+	case ThemeDark:
+		return ThemeLight
+	case ThemeLight:
+		return ThemeAuto
+	}
+	return ThemeDark
+}
+
+var themeLabels = map[string]string{ThemeAuto: "Auto", ThemeLight: "Light", ThemeDark: "Dark"}
+
+// themeToggleHTML is the nav's theme switch. It's a form, so it works
+// without JavaScript; mu.js applies the change in place.
+func themeToggleHTML(current string) string {
+	return `<form method="POST" action="/theme" class="theme-toggle"><input type="hidden" name="theme" value="` + nextTheme(current) + `"><button type="submit" title="Switch theme"><span class="theme-icon"></span><span class="label">Theme: ` + themeLabels[current] + `</span></button></form>`
+}
+
+// ThemeHandler serves /theme: POST theme=light|dark|auto saves the
+// choice to the account, or to a cookie for guests, and returns to the
+// page it came from.
+func ThemeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		MethodNotAllowed(w, r)
+		return
+	}
+	var req struct {
+		Theme string `json:"theme"`
+	}
+	if SendsJSON(r) {
+		if err := DecodeJSON(r, &req); err != nil {
+			BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Theme = r.FormValue("theme")
+	}
+	if !validTheme(req.Theme) {
+		BadRequest(w, r, "theme must be light, dark or auto")
+		return
+	}
+
+	if _, acc := auth.TrySession(r); acc != nil {
+		acc.Theme = req.Theme
+		if req.Theme == ThemeAuto {
+			acc.Theme = ""
+		}
+		if err := auth.UpdateAccount(acc); err != nil {
+			ServerError(w, r, "Could not save your theme")
+			return
+		}
+	}
+	// The cookie keeps the choice on this browser after logging out too.
+	http.SetCookie(w, &http.Cookie{
+		Name:     themeCookie,
+		Value:    req.Theme,
+		Path:     "/",
+		Expires:  time.Now().AddDate(1, 0, 0),
+		Secure:   requestSecure(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if SendsJSON(r) || WantsJSON(r) {
+		RespondJSON(w, map[string]string{"theme": req.Theme, "next": nextTheme(req.Theme), "label": themeLabels[req.Theme]})
+		return
+	}
+	back := "/home"
+	if ref := r.Header.Get("Referer"); ref != "" {
+		if u, err := url.Parse(ref); err == nil && (u.Host == "" || u.Host == r.Host) {
+			if to := safeRedirect(u.RequestURI()); to != "" {
+				back = to
+			}
+		}
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
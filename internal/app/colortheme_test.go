@@ -0,0 +1,74 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestThemePreference(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// A guest's choice is kept in a cookie.
+	r := httptest.NewRequest("POST", "/theme", strings.NewReader("theme=dark"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Referer", "http://example.com/news?id=1")
+	r.Host = "example.com"
+	w := httptest.NewRecorder()
+	ThemeHandler(w, r)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/news?id=1" {
+		t.Fatalf("got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != themeCookie || cookies[0].Value != ThemeDark {
+		t.Fatalf("unexpected cookies: %v", cookies)
+	}
+	page := httptest.NewRequest("GET", "/news", nil)
+	page.AddCookie(cookies[0])
+	if got := UserTheme(page); got != ThemeDark {
+		t.Fatalf("guest theme = %q", got)
+	}
+	if html := RenderHTMLForRequest("News", "", "", page); !strings.Contains(html, `data-theme="dark"`) || !strings.Contains(html, `name="theme" value="light"`) {
+		t.Fatal("page doesn't carry the guest's theme and the toggle to the next one")
+	}
+
+	// An account's choice wins over the browser's cookie.
+	if err := auth.Create(&auth.Account{ID: "theme_user", Name: "T", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("theme_user")
+	sess, err := auth.Login("theme_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = httptest.NewRequest("POST", "/theme", strings.NewReader(`{"theme":"light"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	w = httptest.NewRecorder()
+	ThemeHandler(w, r)
+	if !strings.Contains(w.Body.String(), `"theme":"light"`) {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	page = httptest.NewRequest("GET", "/news", nil)
+	page.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	page.AddCookie(&http.Cookie{Name: themeCookie, Value: ThemeDark})
+	if got := UserTheme(page); got != ThemeLight {
+		t.Fatalf("account theme = %q", got)
+	}
+
+	// Anything else is refused, and no request means auto.
+	r = httptest.NewRequest("POST", "/theme", strings.NewReader("theme=neon"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	ThemeHandler(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid theme got %d", w.Code)
+	}
+	if UserTheme(httptest.NewRequest("GET", "/", nil)) != ThemeAuto {
+		t.Fatal("default should be auto")
+	}
+}
@@ -90,7 +90,7 @@ func renderMenu(actions []Action) string {
 
 		switch {
 		case a.Label == "Share":
-			sb.WriteString(fmt.Sprintf(`<a href="#" style="%s" onclick="var u=location.origin+'%s';if(navigator.share){navigator.share({url:u})}else if(navigator.clipboard){navigator.clipboard.writeText(u).then(function(){this.textContent='Copied!'}.bind(this))}else{prompt('Copy link:',u)};return false;">Share</a>`, style, a.URL))
+			sb.WriteString(ShareLink(a.URL, "Share", `style="`+style+`"`))
 		case a.Label == "Edit":
 			sb.WriteString(fmt.Sprintf(`<a href="%s" style="%s">Edit</a>`, a.URL, style))
 		case a.Label == "Delete" && a.Confirm != "":
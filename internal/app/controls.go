@@ -63,7 +63,7 @@ func ControlsHandler(w http.ResponseWriter, r *http.Request) {
 		redirect = r.Referer()
 	}
 	if redirect == "" {
-		redirect = "/app/saved"
+		redirect = "/saved"
 	}
 
 	respond := func(status string) {
@@ -197,36 +197,6 @@ func savedTitle(ct, cid string) string {
 	return ct
 }
 
-func renderSavedPage(w http.ResponseWriter, r *http.Request, userID string) {
-	items := GetSavedList(userID)
-
-	if WantsJSON(r) {
-		RespondJSON(w, map[string]interface{}{"saved": items})
-		return
-	}
-
-	var sb strings.Builder
-	if len(items) == 0 {
-		sb.WriteString(`<div class="card"><p class="text-muted">Nothing saved yet. Use the ☆ icon on any content to save it.</p></div>`)
-	} else {
-		sb.WriteString(`<div class="card">`)
-		for _, it := range items {
-			typeLabel := typeLabels[it.Type]
-			if typeLabel == "" {
-				typeLabel = it.Type
-			}
-			sb.WriteString(fmt.Sprintf(`<div style="padding:8px 0;border-bottom:1px solid #f0f0f0">
-				<a href="%s">%s</a>
-				<span class="text-sm text-muted"> · %s · %s · <a href="#" onclick="fetch('/app/unsave?type=%s&id=%s',{method:'POST'}).then(function(){location.reload()});return false;">remove</a></span>
-			</div>`, it.URL, it.Title, typeLabel, it.SavedAt.Format("2 Jan 15:04"), it.Type, it.ID))
-		}
-		sb.WriteString(`</div>`)
-	}
-
-	html := RenderHTMLForRequest("Saved", "Your saved items", sb.String(), r)
-	w.Write([]byte(html))
-}
-
 func renderBlockedPage(w http.ResponseWriter, r *http.Request, userID string) {
 	blocked := GetBlockedUsers(userID)
 
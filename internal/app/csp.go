@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"mu/internal/settings"
+)
+
+func init() {
+	settings.Register("Platform",
+		settings.Var{Key: "CSP_MODE", Default: "report-only", Doc: "Content-Security-Policy: enforce, report-only or off"},
+		settings.Var{Key: "CSP_POLICY", Doc: "Policy to send instead of the default; {nonce} is replaced with the request's nonce"},
+	)
+}
+
+// Content-Security-Policy. Pages are moving off inline handlers to the
+// data-attribute actions in mu.js (data-share, data-flag, data-post,
+// data-href), and inline scripts that remain carry the request's nonce
+// via Script. Until every page has moved the policy is sent report-only
+// by default: violations are reported to /csp-report and logged once
+// each, and CSP_MODE=enforce turns it on for real.
+
+// defaultCSP allows the site's own assets, the fonts it loads from a CDN,
+// Leaflet from its CDN when it isn't vendored, and inline scripts with
+// the request's nonce.
+const defaultCSP = "default-src 'self'; " +
+	"script-src 'self' 'nonce-{nonce}' https://unpkg.com; " +
+	"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com https://unpkg.com; " +
+	"font-src 'self' https://fonts.gstatic.com; " +
+	"img-src 'self' data: blob: https:; " +
+	"media-src 'self' blob: https:; " +
+	"connect-src 'self'; " +
+	"frame-src 'self' https://www.youtube.com https://www.youtube-nocookie.com; " +
+	"frame-ancestors 'self'; " +
+	"base-uri 'self'; " +
+	"form-action 'self' https:; " +
+	"object-src 'none'"
+
+type nonceKey struct{}
+
+// Nonce returns the request's script nonce, or "" when CSP is off.
+func Nonce(r *http.Request) string {
+	n, _ := r.Context().Value(nonceKey{}).(string)
+	return n
+}
+
+// Script wraps js in a script tag carrying the request's nonce, for the
+// inline scripts that haven't moved to mu.js.
+func Script(r *http.Request, js string) string {
+	if n := Nonce(r); n != "" {
+		return `<script nonce="` + n + `">` + js + `</script>`
+	}
+	return `<script>` + js + `</script>`
+}
+
+// cspHeader returns the header to send for mode, or "" for none.
+func cspHeader(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "enforce", "on":
+		return "Content-Security-Policy"
+	case "off", "none", "false":
+		return ""
+	}
+	return "Content-Security-Policy-Report-Only"
+}
+
+// CSP sends the Content-Security-Policy header, with a fresh nonce for
+// every request.
+func CSP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := cspHeader(settings.String("CSP_MODE"))
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		nonce := base64.StdEncoding.EncodeToString(b)
+		policy := settings.String("CSP_POLICY")
+		if policy == "" {
+			policy = defaultCSP
+		}
+		policy = strings.ReplaceAll(policy, "{nonce}", nonce)
+		if !strings.Contains(policy, "report-uri") {
+			policy += "; report-uri /csp-report"
+		}
+		w.Header().Set(header, policy)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), nonceKey{}, nonce)))
+	})
+}
+
+var (
+	cspSeenMu sync.Mutex
+	cspSeen   = map[string]bool{}
+)
+
+// maxCSPSeen bounds the violations remembered, so reports can't grow it
+// without end; past it they are no longer logged.
+const maxCSPSeen = 1000
+
+// CSPReport receives the browser's violation reports and logs each
+// distinct one once.
+func CSPReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		MethodNotAllowed(w, r)
+		return
+	}
+	var report struct {
+		Body struct {
+			DocumentURI string `json:"document-uri"`
+			Directive   string `json:"violated-directive"`
+			BlockedURI  string `json:"blocked-uri"`
+		} `json:"csp-report"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 16<<10)).Decode(&report); err != nil {
+		BadRequest(w, r, "invalid report")
+		return
+	}
+	rep := report.Body
+	// Query strings can carry tokens (reset links, verification), so only
+	// paths are kept.
+	doc, blocked := stripQuery(rep.DocumentURI), stripQuery(rep.BlockedURI)
+	key := rep.Directive + " " + blocked
+	cspSeenMu.Lock()
+	first := !cspSeen[key] && len(cspSeen) < maxCSPSeen
+	if first {
+		cspSeen[key] = true
+	}
+	cspSeenMu.Unlock()
+	if first {
+		Log("csp", "%s blocked %q on %s", rep.Directive, blocked, doc)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func stripQuery(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSP(t *testing.T) {
+	var script string
+	h := CSP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		script = Script(r, "go()")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	policy := w.Header().Get("Content-Security-Policy-Report-Only")
+	if policy == "" || w.Header().Get("Content-Security-Policy") != "" {
+		t.Fatalf("default should be report-only, got %v", w.Header())
+	}
+	i := strings.Index(script, `nonce="`)
+	if i < 0 {
+		t.Fatalf("script has no nonce: %s", script)
+	}
+	nonce := script[i+7 : strings.Index(script[i+7:], `"`)+i+7]
+	if !strings.Contains(policy, "'nonce-"+nonce+"'") || !strings.Contains(policy, "report-uri /csp-report") {
+		t.Fatalf("policy doesn't carry the nonce: %s", policy)
+	}
+
+	// A second request gets a new nonce.
+	first := script
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if script == first {
+		t.Fatal("nonce reused across requests")
+	}
+
+	t.Setenv("CSP_MODE", "enforce")
+	t.Setenv("CSP_POLICY", "script-src 'nonce-{nonce}'")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if p := w.Header().Get("Content-Security-Policy"); !strings.HasPrefix(p, "script-src 'nonce-") || strings.Contains(p, "{nonce}") {
+		t.Fatalf("unexpected enforced policy: %q", p)
+	}
+
+	t.Setenv("CSP_MODE", "off")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get("Content-Security-Policy") != "" || w.Header().Get("Content-Security-Policy-Report-Only") != "" || script != "<script>go()</script>" {
+		t.Fatalf("CSP should be off: %v %s", w.Header(), script)
+	}
+}
+
+func TestCSPReport(t *testing.T) {
+	body := `{"csp-report":{"document-uri":"https://mu.xyz/reset?token=secret","violated-directive":"script-src","blocked-uri":"inline"}}`
+	w := httptest.NewRecorder()
+	CSPReport(w, httptest.NewRequest("POST", "/csp-report", strings.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if stripQuery("https://mu.xyz/reset?token=secret") != "https://mu.xyz/reset" {
+		t.Fatal("query not stripped")
+	}
+}
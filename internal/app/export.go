@@ -0,0 +1,193 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// exportTypes maps export formats to their content types.
+var exportTypes = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv; charset=utf-8",
+	"mbox": "application/mbox",
+	"gpx":  "application/gpx+xml",
+	"zip":  "application/zip",
+}
+
+func init() {
+	data.RegisterExporter(savedExporter{})
+	auth.AccountDeleteHooks = append(auth.AccountDeleteHooks, data.DeleteArchive)
+	auth.AccountRenameHooks = append(auth.AccountRenameHooks, func(oldID, newID string) {
+		data.DeleteArchive(oldID)
+	})
+}
+
+// savedExporter exports a user's saved items (bookmarks).
+type savedExporter struct{}
+
+func (savedExporter) Name() string        { return "saved" }
+func (savedExporter) Description() string { return "Items you have saved" }
+func (savedExporter) Formats() []string   { return []string{"json", "csv"} }
+
+func (savedExporter) Export(w io.Writer, userID, format string) error {
+	items := GetSavedList(userID)
+	if format == "json" {
+		if items == nil {
+			items = []SavedEntry{}
+		}
+		return json.NewEncoder(w).Encode(items)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"saved_at", "type", "id", "title", "url"})
+	for _, s := range items {
+		cw.Write([]string{s.SavedAt.Format(time.RFC3339), s.Type, s.ID, s.Title, s.URL})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportHandler serves /account/export. Without parameters it lists every
+// module's exports (JSON when requested); ?module=mail&format=mbox
+// downloads one. Everything together is assembled in the background: a
+// POST starts it, and once it's ready ?module=all downloads the zip.
+func ExportHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		Unauthorized(w, r)
+		return
+	}
+
+	if r.Method == "POST" {
+		a := data.PrepareArchive(acc.ID)
+		Log("export", "Preparing export archive for %s", acc.ID)
+		if SendsJSON(r) || WantsJSON(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			RespondJSON(w, a)
+			return
+		}
+		http.Redirect(w, r, "/account/export", http.StatusSeeOther)
+		return
+	}
+	if r.Method != "GET" {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	stamp := time.Now().Format("2006-01-02")
+	module := r.URL.Query().Get("module")
+	if module == "all" {
+		f, err := data.OpenArchive(acc.ID)
+		if err != nil {
+			if WantsJSON(r) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				RespondJSON(w, data.GetArchive(acc.ID))
+				return
+			}
+			http.Redirect(w, r, "/account/export", http.StatusSeeOther)
+			return
+		}
+		defer f.Close()
+		a := data.GetArchive(acc.ID)
+		w.Header().Set("Content-Type", exportTypes["zip"])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mu-%s-%s.zip"`, acc.ID, a.Ready.Format("2006-01-02")))
+		http.ServeContent(w, r, "", a.Ready, f)
+		return
+	}
+	if module != "" {
+		format := r.URL.Query().Get("format")
+		e, ok := data.GetExporter(module, format)
+		if e == nil {
+			NotFound(w, r, "No such export")
+			return
+		}
+		if !ok {
+			BadRequest(w, r, "Unsupported format for "+module)
+			return
+		}
+		ct := exportTypes[format]
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", ct)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mu-%s-%s.%s"`, module, stamp, format))
+		if err := e.Export(w, acc.ID, format); err != nil {
+			Log("export", "Export %s.%s for %s failed: %v", module, format, acc.ID, err)
+		}
+		return
+	}
+
+	exporters := data.Exporters()
+	if WantsJSON(r) {
+		type exportJSON struct {
+			Name        string   `json:"name"`
+			Description string   `json:"description"`
+			Formats     []string `json:"formats"`
+		}
+		out := make([]exportJSON, len(exporters))
+		for i, e := range exporters {
+			out[i] = exportJSON{e.Name(), e.Description(), e.Formats()}
+		}
+		RespondJSON(w, map[string]interface{}{"exports": out, "archive": data.GetArchive(acc.ID)})
+		return
+	}
+
+	var rows strings.Builder
+	for _, e := range exporters {
+		var links []string
+		for _, f := range e.Formats() {
+			links = append(links, fmt.Sprintf(`<a href="/account/export?module=%s&format=%s">%s</a>`, e.Name(), f, strings.ToUpper(f)))
+		}
+		rows.WriteString(fmt.Sprintf(`<div style="display:flex;justify-content:space-between;gap:8px;padding:8px 0;border-bottom:1px solid #f0f0f0"><div><strong>%s</strong><br><span class="text-sm text-muted">%s</span></div><div class="text-sm" style="white-space:nowrap">%s</div></div>`,
+			html.EscapeString(strings.Title(e.Name())), html.EscapeString(e.Description()), strings.Join(links, " · ")))
+	}
+
+	content := fmt.Sprintf(`<div class="card">
+<h4>Export your data</h4>
+<p class="text-sm text-muted">Download what you have stored here, one module at a time or all together.</p>
+%s
+%s
+</div>
+<p><a href="/account">← Account</a></p>`, rows.String(), archiveHTML(data.GetArchive(acc.ID)))
+
+	w.Write([]byte(RenderHTMLForRequest("Export", "Export your data", content, r)))
+}
+
+// archiveHTML shows the state of the user's archive of everything, with
+// what they can do next.
+func archiveHTML(a data.Archive) string {
+	prepare := `<form method="POST" action="/account/export" class="mt-4"><button type="submit" class="btn">%s</button></form>`
+	switch a.Status {
+	case data.ArchivePreparing:
+		return `<p class="mt-4 text-sm">Preparing your archive of everything… This page refreshes until it's ready.</p>
+<script>setTimeout(function() { location.reload(); }, 3000);</script>`
+	case data.ArchiveReady:
+		return fmt.Sprintf(`<p class="mt-4"><a href="/account/export?module=all" class="btn">Download everything (.zip, %s)</a></p>
+<p class="text-sm text-muted">Prepared %s, kept until %s.</p>`,
+			formatSize(a.Size), TimeAgo(a.Ready), a.Expires.Format("2 Jan 15:04")) +
+			fmt.Sprintf(prepare, "Prepare again")
+	case data.ArchiveFailed:
+		return fmt.Sprintf(`<p class="mt-4 text-sm text-error">Preparing your archive failed: %s</p>`, html.EscapeString(a.Error)) +
+			fmt.Sprintf(prepare, "Try again")
+	}
+	return `<p class="mt-4 text-sm text-muted">Everything together is prepared as a zip in the background; you can leave this page meanwhile.</p>` +
+		fmt.Sprintf(prepare, "Prepare everything (.zip)")
+}
+
+// formatSize shows a byte count in KB or MB.
+func formatSize(n int64) string {
+	if n < 1024*1024 {
+		return fmt.Sprintf("%d KB", (n+1023)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
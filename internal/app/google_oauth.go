@@ -135,7 +135,16 @@ func GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if auth.MagicLinkOnly() {
+		http.Redirect(w, r, "/login/link", http.StatusSeeOther)
+		return
+	}
+
 	acc := findOrCreateGoogleAccount(info)
+	if acc == nil && !auth.OpenRegistration() {
+		http.Error(w, "There's no account for that email, and signups need an invite", http.StatusForbidden)
+		return
+	}
 	if acc == nil {
 		http.Error(w, "Could not create your account", http.StatusInternalServerError)
 		return
@@ -150,10 +159,7 @@ func GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Session error, please try again", http.StatusInternalServerError)
 		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name: "session", Value: sess.Token, Path: "/", MaxAge: 2592000,
-		HttpOnly: true, Secure: requestSecure(r), SameSite: http.SameSiteLaxMode,
-	})
+	SetSessionCookie(w, r, sess, false)
 	http.Redirect(w, r, "/home", http.StatusFound)
 }
 
@@ -211,13 +217,16 @@ func googleUserInfo(accessToken string) (*googleUser, error) {
 }
 
 // findOrCreateGoogleAccount links by email, or provisions a new account with a
-// username derived from the email. Google users have a random secret (they sign
-// in via Google, not a password).
+// username derived from the email when registration is open. Google users have
+// a random secret (they sign in via Google, not a password).
 func findOrCreateGoogleAccount(info *googleUser) *auth.Account {
 	email := strings.ToLower(strings.TrimSpace(info.Email))
 	if acc, err := auth.GetAccountByEmail(email); err == nil && acc != nil {
 		return acc
 	}
+	if !auth.OpenRegistration() {
+		return nil
+	}
 	id := uniqueUsernameFromEmail(email)
 	name := strings.TrimSpace(info.Name)
 	if name == "" {
@@ -336,12 +345,23 @@ func renderGoogleCard(acc *auth.Account) string {
 </div>`
 }
 
-// loginPage renders the login template with the Google button injected above
-// the form (when configured). Mirrors the fmt.Sprintf(LoginTemplate, ...) shape.
-func loginPage(redirectParam, errHTML string) string {
-	html := fmt.Sprintf(LoginTemplate, redirectParam, errHTML)
-	if btn := googleButtonHTML("Continue with Google"); btn != "" {
-		html = strings.Replace(html, `<h1>Login</h1>`, `<h1>Login</h1>`+btn, 1)
-	}
+// loginPage renders the login template in the viewer's language with the
+// Google button injected above the form (when configured). Mirrors the
+// fmt.Sprintf(LoginTemplate, ...) shape.
+func loginPage(r *http.Request, redirectParam, errHTML string) string {
+	lang := GetUserLanguage(r)
+	html := fmt.Sprintf(Localize(LoginTemplate, lang), lang, Dir(lang), redirectParam, errHTML, Script(r, passkeyLoginScript))
+	if btn := googleButtonHTML(T(lang, "auth.continue_google")); btn != "" {
+		heading := `<h1>` + T(lang, "auth.login") + `</h1>`
+		html = strings.Replace(html, heading, heading+btn, 1)
+	}
+	if EmailSender != nil {
+		html = strings.Replace(html, `<p class="text-center mt-5"><a href="/signup">`,
+			`<p class="text-center mt-3"><a href="/login/link`+redirectParam+`">`+T(lang, "auth.email_link")+`</a> · <a href="/reset">`+T(lang, "auth.forgot")+`</a></p>
+	<p class="text-center mt-5"><a href="/signup">`, 1)
+	}
+	html = strings.Replace(html, `<p class="text-center mt-5"><a href="/signup">`,
+		`<p class="text-center mt-3"><a href="/recover">`+T(lang, "auth.recover")+`</a></p>
+	<p class="text-center mt-5"><a href="/signup">`, 1)
 	return html
 }
@@ -1,82 +1,12 @@
-// ============================================
-// SERVICE WORKER CONFIGURATION
-// ============================================
-var APP_PREFIX = 'mu_';
-var VERSION = 'v149';
-var CACHE_NAME = APP_PREFIX + VERSION;
-
-// Minimal caching - only icons
-var STATIC_CACHE = [
-  '/mu.png',
-  '/home.png',
-  '/chat.png',
-  '/mail.png',
-  '/post.png',
-  '/news.png',
-  '/video.png',
-  '/wallet.png',
-  '/agent.svg',
-  '/places.svg',
-  '/weather.png',
-  '/markets.svg',
-  '/reminder.svg',
-  '/account.png',
-  '/logout.png',
-  '/icon-192.png',
-  '/icon-512.png'
-];
-
-// ============================================
-// SERVICE WORKER EVENT LISTENERS
-// ============================================
-
-self.addEventListener('fetch', function (e) {
-  // Let browser handle all fetches naturally - only cache icons
-  const url = new URL(e.request.url);
-  
-  if (e.request.method !== 'GET') {
-    return;
-  }
-  
-  // Only intercept icons
-  if (url.pathname.match(/\.(png|jpg|jpeg|gif|svg|ico)$/)) {
-    e.respondWith(
-      caches.match(e.request).then(cached => cached || fetch(e.request))
-    );
-  }
-});
-
-self.addEventListener('install', function (e) {
-  e.waitUntil(
-    caches.open(CACHE_NAME).then(cache => cache.addAll(STATIC_CACHE))
-      .then(() => self.skipWaiting())
-  );
-});
-
-self.addEventListener('activate', function (e) {
-  e.waitUntil(
-    caches.keys().then(keys => {
-      console.log('Clearing all old caches');
-      return Promise.all(
-        keys.map(key => {
-          if (key !== CACHE_NAME) {
-            console.log('Deleting cache:', key);
-            return caches.delete(key);
-          }
-        })
-      );
-    }).then(() => self.clients.claim())
-  );
-});
-
 // ============================================
 // PAGE JAVASCRIPT (only run in window context)
 // ============================================
 
-// Exit early if we're in service worker context
+// The service worker is generated at /sw.js. Browsers that installed
+// this file as their worker before that still load it until they
+// update, so it does nothing outside a page.
 if (typeof document === 'undefined') {
-  // We're in service worker context, don't execute page code
-  // Service worker code above will still run
+  // Service worker context: nothing to do
 } else {
   // We're in window context, execute page code
 
@@ -137,6 +67,170 @@ document.addEventListener('click', function() {
   });
 });
 
+// "Load more" links (app.LoadMore): fetch the next page, append its items
+// to the list and point the link at the page after, or drop it at the end.
+document.addEventListener('click', function(e) {
+  var a = e.target.closest ? e.target.closest('a.load-more') : null;
+  if (!a) return;
+  var list = document.getElementById(a.dataset.list);
+  if (!list) return;
+  e.preventDefault();
+  var href = a.getAttribute('href');
+  a.textContent = 'Loading...';
+  fetch(href).then(function(r) { return r.text(); }).then(function(text) {
+    var doc = new DOMParser().parseFromString(text, 'text/html');
+    var page = doc.getElementById(a.dataset.list);
+    if (!page) { window.location.href = href; return; }
+    Array.prototype.slice.call(page.children).forEach(function(c) { list.appendChild(c); });
+    var more = doc.querySelector('a.load-more[data-list="' + a.dataset.list + '"]');
+    if (more) {
+      a.setAttribute('href', more.getAttribute('href'));
+      a.textContent = 'Load more';
+    } else {
+      a.remove();
+    }
+  }).catch(function() { window.location.href = href; });
+});
+
+// ============================================
+// DECLARATIVE ACTIONS
+// ============================================
+// Pages describe actions with data attributes rather than inline
+// handlers, so the Content-Security-Policy needn't allow inline script:
+//
+//   data-share[="url"]        share or copy a link (this page by default)
+//   data-flag="type" data-id  report content
+//   data-post="/path"         POST data-fields (JSON), after data-confirm
+//   <form data-confirm="...">  ask before submitting
+//   data-autosubmit           submit the control's form when it changes
+//   data-href="/path"         go there, for cards that are links
+//   data-toggle-menu          open or close the nav menu
+//   data-nearby-filter="key"  rerun the nearby places search with a filter
+//   data-local-time="RFC3339" show a time in the reader's timezone
+
+function postFields(action, fields) {
+  var f = document.createElement('form');
+  f.method = 'POST';
+  f.action = action;
+  fields = fields || {};
+  if (!fields._csrf && getCsrfToken()) fields._csrf = getCsrfToken();
+  Object.keys(fields).forEach(function(k) {
+    var i = document.createElement('input');
+    i.type = 'hidden';
+    i.name = k;
+    i.value = fields[k];
+    f.appendChild(i);
+  });
+  document.body.appendChild(f);
+  f.submit();
+}
+
+function shareLink(el) {
+  var u = new URL(el.getAttribute('data-share') || location.href, location.href).href;
+  if (navigator.share) {
+    navigator.share({ title: el.dataset.title || document.title, url: u }).catch(function() {});
+  } else if (navigator.clipboard) {
+    var label = el.textContent;
+    navigator.clipboard.writeText(u).then(function() {
+      el.textContent = 'Copied!';
+      setTimeout(function() { el.textContent = label; }, 2000);
+    });
+  } else {
+    prompt('Copy link:', u);
+  }
+}
+
+document.addEventListener('click', function(e) {
+  var el = e.target.closest ? e.target.closest('[data-share],[data-flag],[data-post],[data-href],[data-toggle-menu],[data-nearby-filter]') : null;
+  if (!el) return;
+  if (el.hasAttribute('data-toggle-menu')) {
+    document.body.classList.toggle('menu-open');
+  } else if (el.hasAttribute('data-share')) {
+    e.preventDefault();
+    shareLink(el);
+  } else if (el.hasAttribute('data-flag')) {
+    e.preventDefault();
+    flagContent(el.dataset.flag, el.dataset.id);
+  } else if (el.hasAttribute('data-post')) {
+    e.preventDefault();
+    if (el.dataset.confirm && !confirm(el.dataset.confirm)) return;
+    var fields = {};
+    try { fields = JSON.parse(el.dataset.fields || '{}'); } catch (err) {}
+    postFields(el.dataset.post, fields);
+  } else if (el.hasAttribute('data-nearby-filter')) {
+    nearbyFilter(el.dataset.nearbyFilter);
+  } else if (el.hasAttribute('data-href')) {
+    // Links and controls inside the card keep their own behaviour.
+    var inner = e.target.closest('a,button,input,select,textarea,label,form');
+    if (inner && el.contains(inner) && inner !== el) return;
+    window.location.href = el.dataset.href;
+  }
+});
+
+// nearbyFilter sets the nearby search's filter and runs it, from the
+// location already given or, failing that, the reader's own.
+function nearbyFilter(key) {
+  var form = document.getElementById('nearby-form');
+  if (!form) return;
+  document.getElementById('nearby-filter').value = key;
+  if (document.getElementById('nearby-address').value || document.getElementById('nearby-lat').value) {
+    form.submit();
+  } else if (typeof useNearbyLocation === 'function') {
+    useNearbyLocation(null);
+  }
+}
+
+document.addEventListener('submit', function(e) {
+  var f = e.target;
+  if (f && f.dataset && f.dataset.confirm && !confirm(f.dataset.confirm)) {
+    e.preventDefault();
+    e.stopImmediatePropagation();
+  }
+}, true);
+
+document.addEventListener('change', function(e) {
+  var el = e.target;
+  if (!el || !el.form || !el.hasAttribute || !el.hasAttribute('data-autosubmit')) return;
+  if (el.form.requestSubmit) el.form.requestSubmit();
+  else el.form.submit();
+});
+
+// The nav theme toggle: save the choice and switch without a reload.
+document.addEventListener('submit', function(e) {
+  var f = e.target;
+  if (!f || !f.classList || !f.classList.contains('theme-toggle')) return;
+  e.preventDefault();
+  fetch('/theme', {
+    method: 'POST',
+    credentials: 'same-origin',
+    headers: { 'Content-Type': 'application/json', 'Accept': 'application/json' },
+    body: JSON.stringify({ theme: f.elements.theme.value })
+  }).then(function(r) { return r.json(); }).then(function(j) {
+    if (!j.theme) return;
+    document.documentElement.dataset.theme = j.theme;
+    f.elements.theme.value = j.next;
+    var label = f.querySelector('.label');
+    if (label) label.textContent = 'Theme: ' + j.label;
+  }).catch(function() { f.submit(); });
+});
+
+// Close open control menus on any click elsewhere.
+document.addEventListener('click', function() {
+  document.querySelectorAll('.ctrl-menu').forEach(function(m) { m.style.display = 'none'; });
+});
+
+function localTimes() {
+  document.querySelectorAll('[data-local-time]').forEach(function(e) {
+    var d = new Date(e.dataset.localTime);
+    if (!isNaN(d)) e.textContent = d.toLocaleString([], { dateStyle: 'medium', timeStyle: 'short' });
+  });
+}
+document.addEventListener('DOMContentLoaded', localTimes);
+
+if (navigator.serviceWorker) {
+  navigator.serviceWorker.register('/sw.js', { scope: '/' });
+}
+
 // ============================================
 // TIMESTAMP UPDATES
 // ============================================
@@ -500,6 +594,7 @@ function askLLM(el) {
     
     // Display the full response immediately
     responseContent.innerHTML = result.answer;
+    responseDiv.appendChild(qaSaveLink(prompt, result.markdown || responseContent.innerText, topic));
     d.scrollTop = d.scrollHeight;
     
     // Save context after response is displayed
@@ -678,8 +773,12 @@ function setSession() {
       if (navAccount) navAccount.style.display = 'flex';
       if (navLogout) navLogout.style.display = 'flex';
       if (navLogin) navLogin.style.display = 'none';
+      // The server renders the line in the viewer's language; only fill
+      // it in when a cached page was rendered for someone else.
       if (navUsername && sess.account) {
-        navUsername.textContent = 'Signed in as @' + sess.account;
+        if (navUsername.textContent.indexOf('@' + sess.account) < 0) {
+          navUsername.textContent = 'Signed in as @' + sess.account;
+        }
         navUsername.style.display = 'block';
       }
       // Show the wallet link and badge its credit balance for logged-in users.
@@ -694,20 +793,22 @@ function setSession() {
           }
         })
         .catch(() => {});
-      // Fetch unread mail count for badge
+      // Fetch unread mail and direct message counts for badge
       var headMail = document.getElementById("head-mail");
       var headMailBadge = document.getElementById("head-mail-badge");
-      fetch('/mail?unread=count')
-        .then(res => res.json())
-        .then(data => {
-          if (data.count > 0) {
-            var label = data.count > 9 ? '9+' : data.count;
+      var unreadCount = url => fetch(url).then(res => res.json()).then(data => data.count || 0).catch(() => 0);
+      Promise.all([unreadCount('/mail?unread=count'), unreadCount('/chat/dm?unread=count')])
+        .then(([mail, dms]) => {
+          var count = mail + dms;
+          if (count > 0) {
+            var label = count > 9 ? '9+' : count;
             if (navMailBadge) navMailBadge.textContent = label;
             if (headMailBadge) headMailBadge.textContent = label;
             if (headMail) headMail.classList.add('has-mail');
+            // Only messages waiting: the badge goes straight to them
+            if (mail === 0 && headMail) headMail.href = '/chat/dm';
           }
-        })
-        .catch(() => {});
+        });
       // Initialize card customization for home page
       if (window.location.pathname === '/home') {
         initCardCustomization();
@@ -1020,28 +1121,51 @@ window.muConfirm = function(message) {
   });
 };
 
-// Flag a post (assigned to window for onclick access)
-window.flagPost = async function flagPost(postId) {
-  if (!await muConfirm('Flag this post as inappropriate? It will be hidden after 3 flags.')) {
+// Report content with a category (assigned to window for onclick access).
+// Three reports hide it until a moderator has reviewed it.
+window.flagContent = async function flagContent(type, id) {
+  const category = await new Promise(function(resolve) {
+    var overlay = document.createElement('div');
+    overlay.className = 'modal';
+    overlay.innerHTML = '<div class="modal-content" style="max-width:400px;text-align:center;">' +
+      '<p style="margin-bottom:20px;">Why are you reporting this?</p>' +
+      '<div style="display:flex;flex-direction:column;gap:10px;">' +
+      '<button data-category="spam" class="btn-secondary">Spam</button>' +
+      '<button data-category="abuse" class="btn-secondary">Abuse</button>' +
+      '<button data-category="misinformation" class="btn-secondary">Misinformation</button>' +
+      '<button data-category="" style="padding:10px;border:1px solid #ccc;border-radius:5px;background:#f5f5f5;color:black;cursor:pointer;">Cancel</button>' +
+      '</div></div>';
+    document.body.appendChild(overlay);
+    overlay.querySelectorAll('button').forEach(function(b) {
+      b.onclick = function() { overlay.remove(); resolve(b.dataset.category); };
+    });
+    overlay.addEventListener('click', function(e) { if (e.target === overlay) { overlay.remove(); resolve(''); } });
+  });
+  if (!category) {
     return;
   }
 
-  const result = await apiCall('/flag', { body: { type: 'post', id: postId } });
+  const result = await apiCall('/flag', { body: { type: type, id: id, category: category } });
 
   if (!result.ok) {
     return; // apiCall already shows error toast
   }
 
   if (result.data.success) {
-    showToast('Post flagged. Flag count: ' + result.data.count, 'success');
+    showToast('Thanks, a moderator will take a look. Follow it at /reports.', 'success');
     if (result.data.count >= 3) {
       setTimeout(() => location.reload(), 1000);
     }
   } else {
-    showToast(result.data.message || 'Could not flag post', 'error');
+    showToast(result.data.message || 'Could not report this', 'error');
   }
 }
 
+// Flag a post (assigned to window for onclick access)
+window.flagPost = function flagPost(postId) {
+  return flagContent('post', postId);
+}
+
 
 
 
@@ -1110,9 +1234,12 @@ function connectRoomWebSocket(roomId) {
     
     if (msg.type === 'user_list') {
       updateUserList(msg.users);
+    } else if (msg.type === 'edit' || msg.type === 'delete') {
+      applyRoomChange(roomId, msg);
     } else {
       saveMessageToStorage(roomId, msg);
       displayRoomMessage(msg, true);
+      markRoomSeen(roomId);
     }
   };
   
@@ -1129,6 +1256,103 @@ function connectRoomWebSocket(roomId) {
   };
 }
 
+// Named rooms (room_*) are marked unread in the switcher when their last
+// message is newer than when this browser last looked at them.
+function roomSeenKey(roomId) {
+  return 'mu_chat_seen_' + roomId;
+}
+
+function markRoomSeen(roomId) {
+  if (!roomId || !roomId.startsWith('room_')) return;
+  localStorage.setItem(roomSeenKey(roomId), String(Date.now()));
+  const tab = document.querySelector('.room-tab[data-room="' + roomId + '"]');
+  if (tab) tab.classList.remove('unread');
+}
+
+function markUnreadRooms() {
+  document.querySelectorAll('.room-tab').forEach(tab => {
+    const roomId = tab.dataset.room;
+    const last = parseInt(tab.dataset.last || '0', 10);
+    const seen = parseInt(localStorage.getItem(roomSeenKey(roomId)) || '0', 10);
+    tab.classList.toggle('unread', roomId !== currentRoomId && last > seen);
+  });
+}
+
+function refreshRoomTabs() {
+  fetch('/chat', { headers: { 'Accept': 'application/json' } })
+    .then(r => r.json())
+    .then(d => {
+      (d.rooms || []).forEach(room => {
+        const tab = document.querySelector('.room-tab[data-room="' + room.id + '"]');
+        if (tab) tab.dataset.last = room.last_message || 0;
+      });
+      markUnreadRooms();
+    })
+    .catch(() => {});
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+  if (!document.querySelector('.room-tab')) return;
+  if (typeof roomData !== 'undefined' && roomData && roomData.id) {
+    markRoomSeen(roomData.id);
+  }
+  markUnreadRooms();
+  setInterval(refreshRoomTabs, 60000);
+});
+
+// Direct message threads (/chat/dm?with=) get new messages over a
+// WebSocket, and send without reloading the page.
+document.addEventListener('DOMContentLoaded', function() {
+  const thread = document.getElementById('dm-thread');
+  if (!thread) return;
+  const peer = thread.dataset.with;
+  const form = document.getElementById('dm-form');
+  const escape = s => s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+  const show = msg => {
+    if (msg.from !== peer && msg.to !== peer) return false;
+    const div = document.createElement('div');
+    div.className = 'message';
+    div.innerHTML = '<span class="you"><a href="/@' + msg.from + '">' + msg.from + '</a></span> ' +
+      escape(msg.content) + ' <span class="text-muted text-sm">just now</span>';
+    thread.appendChild(div);
+    div.scrollIntoView({ block: 'end' });
+    return true;
+  };
+  thread.lastElementChild && thread.lastElementChild.scrollIntoView({ block: 'end' });
+
+  let ws;
+  const connect = () => {
+    const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+    ws = new WebSocket(protocol + '//' + window.location.host + '/chat/dm');
+    ws.onmessage = event => {
+      const msg = JSON.parse(event.data);
+      if (show(msg) && msg.from === peer) ws.send(JSON.stringify({ read: peer }));
+    };
+    ws.onclose = () => setTimeout(connect, 3000);
+  };
+  connect();
+
+  if (form) {
+    form.addEventListener('submit', event => {
+      event.preventDefault();
+      const input = form.querySelector('input[name="content"]');
+      const content = input.value.trim();
+      if (!content) return;
+      fetch('/chat/dm', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json', 'Accept': 'application/json' },
+        body: JSON.stringify({ to: peer, content: content })
+      })
+        .then(res => res.ok ? res.json() : res.json().then(d => Promise.reject(d.error || 'Message not sent')))
+        .then(msg => {
+          input.value = '';
+          if (ws.readyState !== WebSocket.OPEN) show(msg);
+        })
+        .catch(err => alert(typeof err === 'string' ? err : 'Message not sent'));
+    });
+  }
+});
+
 function displayRoomMessage(msg, shouldScroll = true) {
   const messagesDiv = document.getElementById('messages');
   if (!messagesDiv) return;
@@ -1148,10 +1372,23 @@ function displayRoomMessage(msg, shouldScroll = true) {
     // Escape HTML and linkify URLs for user messages
     content = msg.content.replace(/</g, '&lt;').replace(/>/g, '&gt;');
     content = linkifyText(content);
+    content = linkifyMentions(content);
     content = content.replace(/\n/g, '<br>');
   }
   
-  msgDiv.innerHTML = userSpan + '<p>' + content + '</p>';
+  if (msg.id) msgDiv.dataset.id = msg.id;
+  const edited = msg.edited ? ' <span class="text-xs text-muted">(edited)</span>' : '';
+  msgDiv.innerHTML = userSpan + '<p>' + content + edited + '</p>';
+  if (!msg.is_llm && msg.id) {
+    const controls = roomMessageControls(msg);
+    if (controls) msgDiv.appendChild(controls);
+  }
+  if (msg.is_llm) {
+    var source = (typeof roomData !== 'undefined' && roomData && roomData.title) ? roomData.title : '';
+    msgDiv.appendChild(qaSaveLink(lastRoomQuestion, msg.content, source));
+  } else {
+    lastRoomQuestion = msg.content;
+  }
   messagesDiv.appendChild(msgDiv);
   
   if (shouldScroll) {
@@ -1159,6 +1396,97 @@ function displayRoomMessage(msg, shouldScroll = true) {
   }
 }
 
+// Room messages can be edited by their author for 15 minutes, and
+// deleted by their author or an admin.
+const roomEditWindow = 15 * 60 * 1000;
+
+function roomMessageControls(msg) {
+  if (typeof roomData === 'undefined' || !roomData || !roomData.viewer) return null;
+  const own = msg.username === roomData.viewer;
+  if (!own && !roomData.admin) return null;
+  const span = document.createElement('span');
+  span.className = 'message-actions text-xs text-muted';
+  if (own && Date.now() - new Date(msg.timestamp).getTime() < roomEditWindow) {
+    const edit = document.createElement('a');
+    edit.href = '#';
+    edit.textContent = 'Edit';
+    edit.onclick = function(e) {
+      e.preventDefault();
+      const current = span.closest('.message').dataset.content || msg.content;
+      const content = prompt('Edit message', current);
+      if (content && content.trim() && content !== current) {
+        changeRoomMessage({ action: 'edit', id: msg.id, content: content });
+      }
+    };
+    span.appendChild(edit);
+    span.appendChild(document.createTextNode(' · '));
+  }
+  const del = document.createElement('a');
+  del.href = '#';
+  del.textContent = 'Delete';
+  del.onclick = function(e) {
+    e.preventDefault();
+    muConfirm('Delete this message?').then(function(ok) {
+      if (ok) changeRoomMessage({ action: 'delete', id: msg.id });
+    });
+  };
+  span.appendChild(del);
+  return span;
+}
+
+function changeRoomMessage(req) {
+  req.room = currentRoomId;
+  fetch('/chat/message', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', 'Accept': 'application/json' },
+    body: JSON.stringify(req)
+  })
+    .then(res => res.ok ? null : res.json().then(d => Promise.reject(d.error || 'Failed')))
+    .catch(err => alert(typeof err === 'string' ? err : 'Failed'));
+}
+
+// applyRoomChange shows an edit or deletion sent by the room, here and
+// in the stored copy of the conversation.
+function applyRoomChange(roomId, change) {
+  const div = document.querySelector('#messages .message[data-id="' + change.id + '"]');
+  const key = getRoomStorageKey(roomId);
+  let stored = JSON.parse(localStorage.getItem(key) || '[]');
+  if (change.type === 'delete') {
+    if (div) div.remove();
+    stored = stored.filter(m => m.id !== change.id);
+  } else {
+    if (div) {
+      div.dataset.content = change.content;
+      let content = change.content.replace(/</g, '&lt;').replace(/>/g, '&gt;');
+      content = linkifyMentions(linkifyText(content)).replace(/\n/g, '<br>');
+      div.querySelector('p').innerHTML = content + ' <span class="text-xs text-muted">(edited)</span>';
+    }
+    stored.forEach(m => { if (m.id === change.id) { m.content = change.content; m.edited = true; } });
+  }
+  localStorage.setItem(key, JSON.stringify(stored));
+}
+
+// The last member message in the room, used as the question when an AI
+// reply is saved to Q&A.
+var lastRoomQuestion = '';
+
+// qaSaveLink returns a "Save to Q&A" link that stashes the exchange and
+// opens /qa/new, where it prefills the form for editing.
+function qaSaveLink(question, answer, source) {
+  var a = document.createElement('a');
+  a.href = '/qa/new';
+  a.className = 'qa-save text-xs text-muted';
+  a.textContent = 'Save to Q&A';
+  a.onclick = function(e) {
+    e.preventDefault();
+    try {
+      sessionStorage.setItem('mu_qa_draft', JSON.stringify({question: question || '', answer: answer || '', source: source || ''}));
+    } catch (err) {}
+    window.location.href = '/qa/new';
+  };
+  return a;
+}
+
 // Linkify URLs in text
 function linkifyText(text) {
   const urlRegex = /(https?:\/\/[^\s]+)/g;
@@ -1214,6 +1542,52 @@ function sendRoomMessage(form) {
   }
 }
 
+// Thread summary: shows the cached summary of the room's conversation under
+// the context message, with an action to bring it up to date.
+function renderRoomSummary(roomId, s) {
+  const contextMsg = document.querySelector('#messages .context-message');
+  if (!contextMsg) return;
+  let box = document.getElementById('room-thread-summary');
+  if (!box) {
+    box = document.createElement('div');
+    box.id = 'room-thread-summary';
+    box.style.cssText = 'margin-top:8px;font-size:14px;color:#444;';
+    contextMsg.appendChild(box);
+  }
+  let html = '';
+  if (s.summary) {
+    html += '<div style="color:#999;font-size:12px;">Summary of ' + s.covered + ' messages</div>' + renderMarkdown(escapeHtml(s.summary));
+  }
+  if (isAuthenticated && (s.pending > 0 || !s.summary)) {
+    const label = s.summary ? 'Update summary (' + s.pending + ' new)' : 'Summarise thread';
+    html += '<div><a href="#" onclick="summariseRoom(\'' + roomId + '\'); return false;" style="font-size:13px;">' + label + '</a></div>';
+  }
+  box.innerHTML = html;
+}
+
+function loadRoomSummary(roomId) {
+  fetch('/chat/summary?id=' + encodeURIComponent(roomId), { headers: { 'Accept': 'application/json' } })
+    .then(r => r.json())
+    .then(s => renderRoomSummary(roomId, s))
+    .catch(() => {});
+}
+
+function summariseRoom(roomId) {
+  const box = document.getElementById('room-thread-summary');
+  if (box) box.innerHTML = '<span style="color:#999;">Summarising…</span>';
+  fetch('/chat/summary?id=' + encodeURIComponent(roomId), { method: 'POST', headers: { 'Accept': 'application/json' } })
+    .then(r => r.json())
+    .then(s => {
+      if (s.error) {
+        showToast(s.error, 'error');
+        loadRoomSummary(roomId);
+        return;
+      }
+      renderRoomSummary(roomId, s);
+    })
+    .catch(() => loadRoomSummary(roomId));
+}
+
 // Initialize room chat on page load and when switching topics
 document.addEventListener('DOMContentLoaded', function() {
   // Check if we're in a room (from roomData injected by server)
@@ -1258,6 +1632,7 @@ document.addEventListener('DOMContentLoaded', function() {
             (currentRoomData.url ? '<br><a href="' + currentRoomData.url + '" target="_blank" style="color: #0066cc; font-size: 13px;">→ View Original</a>' : '');
           // Insert at the top
           messages.insertBefore(contextMsg, messages.firstChild);
+          loadRoomSummary(currentRoomData.id);
         }
       }
     }, 100);
@@ -1502,6 +1877,128 @@ function saveCardPrefs() {
   closeCardModal();
 }
 
+// ============================================
+// MENTIONS
+// ============================================
+
+// Link @username mentions in escaped message text to profiles. A mention
+// must start a word, so email addresses and /@paths in links are skipped.
+function linkifyMentions(text) {
+  return text.replace(/(^|[^\w@\/])@([a-zA-Z0-9_-]{2,32})/g, function(m, pre, name) {
+    return pre + '<a href="/@' + name.toLowerCase() + '" class="mention">@' + name + '</a>';
+  });
+}
+
+// Suggest usernames while typing an @mention in any field marked
+// data-mentions.
+(function() {
+  let menu = null;      // the open suggestion list
+  let field = null;     // the field it belongs to
+  let active = 0;       // highlighted suggestion
+  let timer = null;
+  let seq = 0;          // drops responses to stale lookups
+
+  function closeMenu() {
+    if (menu) menu.remove();
+    menu = null;
+    field = null;
+  }
+
+  // The partial mention just before the caret, if any.
+  function partial(el) {
+    const before = el.value.slice(0, el.selectionStart);
+    const m = before.match(/(^|[^\w@\/])@([a-zA-Z0-9_-]{1,32})$/);
+    return m ? m[2] : null;
+  }
+
+  function choose(i) {
+    if (!menu || !field) return;
+    const item = menu.children[i];
+    const q = partial(field);
+    if (!item || q === null) return closeMenu();
+    const caret = field.selectionStart;
+    const start = caret - q.length;
+    const insert = item.dataset.id + ' ';
+    field.value = field.value.slice(0, start) + insert + field.value.slice(caret);
+    field.selectionStart = field.selectionEnd = start + insert.length;
+    field.focus();
+    closeMenu();
+  }
+
+  function highlight(i) {
+    active = i;
+    Array.from(menu.children).forEach(function(c, j) {
+      c.classList.toggle('active', j === i);
+    });
+  }
+
+  function show(el, list) {
+    closeMenu();
+    if (!list.length) return;
+    field = el;
+    menu = document.createElement('div');
+    menu.className = 'mention-menu';
+    list.forEach(function(u, i) {
+      const item = document.createElement('div');
+      item.className = 'mention-item';
+      item.dataset.id = u.id;
+      item.textContent = '@' + u.id + (u.name && u.name !== u.id ? ' · ' + u.name : '');
+      item.addEventListener('mousedown', function(e) {
+        e.preventDefault();
+        choose(i);
+      });
+      menu.appendChild(item);
+    });
+    const r = el.getBoundingClientRect();
+    menu.style.left = (r.left + window.scrollX) + 'px';
+    menu.style.top = (r.bottom + window.scrollY) + 'px';
+    menu.style.minWidth = Math.min(r.width, 240) + 'px';
+    document.body.appendChild(menu);
+    highlight(0);
+  }
+
+  document.addEventListener('input', function(e) {
+    const el = e.target;
+    if (!el.matches || !el.matches('[data-mentions]')) return;
+    const q = partial(el);
+    clearTimeout(timer);
+    if (q === null) return closeMenu();
+    const n = ++seq;
+    timer = setTimeout(function() {
+      fetch('/user/mentions?q=' + encodeURIComponent(q), {
+        headers: { 'Accept': 'application/json' },
+        credentials: 'same-origin'
+      })
+        .then(function(r) { return r.ok ? r.json() : []; })
+        .then(function(list) { if (n === seq) show(el, list || []); })
+        .catch(function() {});
+    }, 150);
+  });
+
+  // Capture phase, so choosing with Enter doesn't also send the form.
+  document.addEventListener('keydown', function(e) {
+    if (!menu || e.target !== field) return;
+    const n = menu.children.length;
+    if (e.key === 'ArrowDown') {
+      highlight((active + 1) % n);
+    } else if (e.key === 'ArrowUp') {
+      highlight((active + n - 1) % n);
+    } else if (e.key === 'Enter' || e.key === 'Tab') {
+      choose(active);
+    } else if (e.key === 'Escape') {
+      closeMenu();
+    } else {
+      return;
+    }
+    e.preventDefault();
+    e.stopPropagation();
+  }, true);
+
+  document.addEventListener('focusout', function(e) {
+    if (e.target === field) closeMenu();
+  });
+})();
+
 // ============================================
 // TOAST NOTIFICATIONS
 // ============================================
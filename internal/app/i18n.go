@@ -0,0 +1,126 @@
+package app
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Interface strings are looked up by key in a catalog per language, one
+// JSON file each in locales/. English is complete; any other catalog may
+// be partial, and a missing key falls back to English, then to the key
+// itself so a gap shows up on the page rather than as a blank.
+//
+// Go code calls T. The fmt layouts (the site template, login, signup)
+// carry {t:key} markers that Localize fills in, and html/template pages
+// use the t function. Arabic and the other right-to-left languages get
+// dir="rtl" on <html>, which mu.css mirrors the layout on.
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs maps a language code to its strings.
+var catalogs = loadCatalogs()
+
+// rtlLanguages are written right to left.
+var rtlLanguages = map[string]bool{"ar": true, "fa": true, "he": true, "ur": true}
+
+func loadCatalogs() map[string]map[string]string {
+	out := map[string]map[string]string{}
+	files, _ := localeFiles.ReadDir("locales")
+	for _, f := range files {
+		b, err := localeFiles.ReadFile("locales/" + f.Name())
+		if err != nil {
+			continue
+		}
+		var c map[string]string
+		if err := json.Unmarshal(b, &c); err != nil {
+			log.Printf("[i18n] %s: %v", f.Name(), err)
+			continue
+		}
+		out[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = c
+	}
+	return out
+}
+
+// T returns the string for key in lang. With args, the string is a
+// fmt format: T(lang, "mail.inbox_count", 3).
+func T(lang, key string, args ...interface{}) string {
+	s, ok := catalogs[lang][key]
+	if !ok {
+		if s, ok = catalogs["en"][key]; !ok {
+			s = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+// Dir returns the text direction of lang, "rtl" or "ltr".
+func Dir(lang string) string {
+	if rtlLanguages[lang] {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+var localizeMarker = regexp.MustCompile(`\{t:([a-z0-9_.]+)\}`)
+
+// localized caches Localize, keyed by layout and language. There are only
+// a handful of layouts and catalogs.
+var localized sync.Map
+
+type localizeKey struct{ layout, lang string }
+
+// Localize replaces the {t:key} markers in a layout with the strings for
+// lang. Layouts are fmt formats, so a % in a translation is escaped to
+// keep the later Sprintf from reading it as a verb.
+func Localize(layout, lang string) string {
+	k := localizeKey{layout, lang}
+	if v, ok := localized.Load(k); ok {
+		return v.(string)
+	}
+	out := localizeMarker.ReplaceAllStringFunc(layout, func(m string) string {
+		return strings.ReplaceAll(T(lang, m[3:len(m)-1]), "%", "%%")
+	})
+	localized.Store(k, out)
+	return out
+}
+
+// acceptLanguage picks the first supported language in an
+// Accept-Language header, or "" if there is none.
+func acceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := SupportedLanguages[base]; ok {
+			return base
+		}
+	}
+	return ""
+}
+
+// requestLanguage is the language of a request with no account: the
+// browser's preference if we support it, else English.
+func requestLanguage(r *http.Request) string {
+	if r != nil {
+		if lang := acceptLanguage(r.Header.Get("Accept-Language")); lang != "" {
+			return lang
+		}
+	}
+	return "en"
+}
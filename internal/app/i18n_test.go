@@ -0,0 +1,112 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestCatalogsCoverEnglish(t *testing.T) {
+	en := catalogs["en"]
+	if len(en) == 0 {
+		t.Fatal("no English catalog")
+	}
+	for lang, c := range catalogs {
+		for key := range c {
+			if _, ok := en[key]; !ok {
+				t.Errorf("%s has %q, which English doesn't", lang, key)
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("ar", "nav.home"); got != "الرئيسية" {
+		t.Errorf("ar nav.home = %q", got)
+	}
+	if got := T("zh", "nav.home"); got != "Home" {
+		t.Errorf("missing catalog should fall back to English, got %q", got)
+	}
+	if got := T("en", "mail.inbox_count", 3); got != "Inbox (3)" {
+		t.Errorf("formatted = %q", got)
+	}
+	if got := T("en", "no.such.key"); got != "no.such.key" {
+		t.Errorf("missing key = %q", got)
+	}
+	if Dir("ar") != "rtl" || Dir("en") != "ltr" {
+		t.Error("wrong text direction")
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	for header, want := range map[string]string{
+		"ar-EG,ar;q=0.9,en;q=0.8": "ar",
+		"fr-FR, zh-CN;q=0.5":      "zh",
+		"ar;q=0, en":              "en",
+		"fr":                      "",
+		"":                        "",
+	} {
+		if got := acceptLanguage(header); got != want {
+			t.Errorf("acceptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestRenderArabicPage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// A guest gets their browser's language.
+	r := httptest.NewRequest("GET", "/news", nil)
+	r.Header.Set("Accept-Language", "ar")
+	html := RenderHTMLForRequest("News", "", "<p>body</p>", r)
+	for _, want := range []string{`lang="ar" dir="rtl"`, "الأخبار", "تسجيل الدخول"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	if strings.Contains(html, "{t:") {
+		t.Error("page has unfilled markers")
+	}
+
+	// An account's choice wins over the browser.
+	if err := auth.Create(&auth.Account{ID: "lang_user", Name: "L", Secret: "secret", Created: time.Now(), Language: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("lang_user")
+	sess, err := auth.Login("lang_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	if got := GetUserLanguage(r); got != "en" {
+		t.Errorf("account language = %q", got)
+	}
+	if html := RenderHTMLForRequest("News", "", "", r); !strings.Contains(html, `dir="ltr"`) || !strings.Contains(html, "Signed in as @lang_user") {
+		t.Error("account page isn't English")
+	}
+}
+
+func TestLoginPageLocalized(t *testing.T) {
+	r := httptest.NewRequest("GET", "/login", nil)
+	r.Header.Set("Accept-Language", "ar")
+	html := loginPage(r, "", "")
+	if !strings.Contains(html, `dir="rtl"`) || !strings.Contains(html, "<h1>تسجيل الدخول</h1>") || strings.Contains(html, "{t:") {
+		t.Error("login page isn't Arabic")
+	}
+	// The passkey script's %% must survive localizing.
+	if !strings.Contains(html, "b64.length % 4") {
+		t.Error("login script was mangled")
+	}
+
+	// Under CSP the script carries the nonce and the button no handler.
+	CSP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		html = loginPage(r, "", "")
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/login", nil))
+	if strings.Contains(html, "onclick") || !strings.Contains(html, `<script nonce="`) {
+		t.Error("login page has inline handlers or an unnonced script")
+	}
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Background jobs. Packages schedule their recurring work here instead of
+// running their own sleep loops: runs are spread with a little jitter so
+// they don't all land at once, a failed run is retried with backoff, a
+// panic is logged and the job carries on, and each job's last run is kept
+// for /admin/jobs, where it can also be run straight away.
+
+// Job is a named task run every so often.
+type Job struct {
+	Name    string        // unique, e.g. "news.feeds"
+	Every   time.Duration // between runs; 0 runs once, then only on demand
+	Delay   time.Duration // before the first run
+	Jitter  time.Duration // later runs drift by up to this much; default a tenth of Every
+	Retries int           // further attempts after a failure, backing off between them
+	Run     func() error
+}
+
+// JobStatus is what's known about a job's runs.
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Every        time.Duration `json:"every"`
+	Running      bool          `json:"running"`
+	Runs         int           `json:"runs"`
+	Failures     int           `json:"failures"`
+	LastRun      time.Time     `json:"last_run,omitempty"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      time.Time     `json:"next_run,omitempty"`
+}
+
+// ErrNoJob is returned by RunJob for a name that isn't scheduled.
+var ErrNoJob = errors.New("no such job")
+
+// retryBackoff is the wait before the first retry; it doubles after each.
+var retryBackoff = 30 * time.Second
+
+type job struct {
+	Job
+	now chan struct{}
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+var (
+	jobsMu sync.Mutex
+	jobs   = map[string]*job{}
+)
+
+// Schedule starts running j in the background. Scheduling a name twice
+// is ignored.
+func Schedule(j Job) {
+	if j.Jitter == 0 {
+		j.Jitter = j.Every / 10
+	}
+	jobsMu.Lock()
+	if _, dup := jobs[j.Name]; dup {
+		jobsMu.Unlock()
+		LogWarn("jobs", "%s is already scheduled", j.Name)
+		return
+	}
+	sj := &job{Job: j, now: make(chan struct{}, 1)}
+	sj.status = JobStatus{Name: j.Name, Every: j.Every}
+	jobs[j.Name] = sj
+	jobsMu.Unlock()
+	go sj.loop()
+}
+
+// RunJob runs a job now rather than waiting for its next turn. If it's
+// running already, it runs again when it finishes.
+func RunJob(name string) error {
+	jobsMu.Lock()
+	j, ok := jobs[name]
+	jobsMu.Unlock()
+	if !ok {
+		return ErrNoJob
+	}
+	select {
+	case j.now <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Jobs returns every scheduled job's status, by name.
+func Jobs() []JobStatus {
+	jobsMu.Lock()
+	list := make([]*job, 0, len(jobs))
+	for _, j := range jobs {
+		list = append(list, j)
+	}
+	jobsMu.Unlock()
+	out := make([]JobStatus, len(list))
+	for i, j := range list {
+		j.mu.Lock()
+		out[i] = j.status
+		j.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (j *job) loop() {
+	wait := j.Delay
+	for ran := false; ; ran = true {
+		if j.Every <= 0 && ran {
+			j.setNext(time.Time{})
+			<-j.now
+		} else {
+			j.setNext(time.Now().Add(wait))
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-j.now:
+				t.Stop()
+			}
+		}
+		j.attempt()
+		wait = j.Every + jitter(j.Jitter)
+	}
+}
+
+// attempt runs the job, retrying a failure up to Retries times.
+func (j *job) attempt() {
+	backoff := retryBackoff
+	for try := 0; ; try++ {
+		if err := j.runOnce(); err == nil || try >= j.Retries {
+			return
+		}
+		j.setNext(time.Now().Add(backoff))
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-j.now:
+			t.Stop()
+		}
+		backoff *= 2
+	}
+}
+
+// runOnce runs the job and records how it went. A panic is a failure.
+func (j *job) runOnce() (err error) {
+	start := time.Now()
+	j.mu.Lock()
+	j.status.Running = true
+	j.mu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			LogError("jobs", "%s panicked: %v\n%s", j.Name, r, debug.Stack())
+		} else if err != nil {
+			LogWarn("jobs", "%s failed: %v", j.Name, err)
+		}
+		j.mu.Lock()
+		s := &j.status
+		s.Running = false
+		s.Runs++
+		s.LastRun = start
+		s.LastDuration = time.Since(start)
+		s.LastError = ""
+		if err != nil {
+			s.Failures++
+			s.LastError = err.Error()
+		}
+		j.mu.Unlock()
+	}()
+	return j.Run()
+}
+
+func (j *job) setNext(t time.Time) {
+	j.mu.Lock()
+	j.status.NextRun = t
+	j.mu.Unlock()
+}
+
+// jitter returns a random duration below max.
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
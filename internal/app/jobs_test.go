@@ -0,0 +1,93 @@
+package app
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls until cond holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func jobStatus(name string) JobStatus {
+	for _, s := range Jobs() {
+		if s.Name == name {
+			return s
+		}
+	}
+	return JobStatus{}
+}
+
+func TestJobRetriesAndRecordsFailures(t *testing.T) {
+	old := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = old })
+
+	var calls atomic.Int32
+	Schedule(Job{Name: "test.retry", Retries: 2, Run: func() error {
+		if calls.Add(1) < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}})
+	waitFor(t, "three attempts", func() bool { return jobStatus("test.retry").Runs == 3 })
+
+	s := jobStatus("test.retry")
+	if s.Failures != 2 || s.LastError != "" {
+		t.Errorf("failures %d, last error %q; want 2 and none after the retry succeeded", s.Failures, s.LastError)
+	}
+	if !s.NextRun.IsZero() {
+		t.Errorf("a job without an interval has a next run: %v", s.NextRun)
+	}
+}
+
+func TestJobSurvivesPanicAndRunsOnDemand(t *testing.T) {
+	var calls atomic.Int32
+	Schedule(Job{Name: "test.panic", Run: func() error {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		return nil
+	}})
+	waitFor(t, "the panicking run", func() bool { return jobStatus("test.panic").Runs == 1 })
+	if s := jobStatus("test.panic"); s.LastError != "panic: boom" {
+		t.Errorf("last error = %q, want panic: boom", s.LastError)
+	}
+
+	if err := RunJob("test.panic"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the run on demand", func() bool { return jobStatus("test.panic").Runs == 2 })
+	if s := jobStatus("test.panic"); s.LastError != "" {
+		t.Errorf("last error after a good run = %q", s.LastError)
+	}
+
+	if err := RunJob("test.missing"); !errors.Is(err, ErrNoJob) {
+		t.Errorf("RunJob of an unknown job = %v, want ErrNoJob", err)
+	}
+}
+
+func TestJobWaitsForItsInterval(t *testing.T) {
+	var calls atomic.Int32
+	Schedule(Job{Name: "test.every", Every: time.Hour, Delay: 30 * time.Minute, Run: func() error {
+		calls.Add(1)
+		return nil
+	}})
+	waitFor(t, "the next run to be set", func() bool { return !jobStatus("test.every").NextRun.IsZero() })
+	if next := time.Until(jobStatus("test.every").NextRun); next < 29*time.Minute || next > 30*time.Minute {
+		t.Errorf("first run in %v, want the 30m delay", next)
+	}
+	if calls.Load() != 0 {
+		t.Error("ran before its delay")
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// Leaflet, the places maps' library, is served from html/leaflet once
+// it's vendored there by scripts/vendor-leaflet.sh. Until then Serve
+// sends /leaflet/ to the pinned release. The maps load the script and
+// stylesheet with the release's integrity hashes, so the browser runs
+// them only if they're the release, from here or from the CDN.
+
+// leafletRelease is where the pinned release's dist files are.
+const leafletRelease = "https://unpkg.com/leaflet@1.9.4/dist/"
+
+// The release's subresource integrity hashes.
+const (
+	LeafletJSIntegrity  = "sha256-20nQCchB9co0qIjJZRGuk2/Z9VM+kNiyxNV1lvTlZBo="
+	LeafletCSSIntegrity = "sha256-p4NxAoJBhIIN+hmNHrzRCf9tD/miZyoHS5obTRR9BMY="
+)
+
+// leafletFiles are the release's files the maps use.
+var leafletFiles = map[string]bool{
+	"leaflet.js":                true,
+	"leaflet.css":               true,
+	"images/layers.png":         true,
+	"images/layers-2x.png":      true,
+	"images/marker-icon.png":    true,
+	"images/marker-icon-2x.png": true,
+	"images/marker-shadow.png":  true,
+}
+
+// leafletFallback redirects a request for a Leaflet file that isn't in
+// static to the release, and reports whether it did.
+func leafletFallback(w http.ResponseWriter, r *http.Request, static fs.FS) bool {
+	name, ok := strings.CutPrefix(r.URL.Path, "/leaflet/")
+	if !ok || !leafletFiles[name] {
+		return false
+	}
+	if _, err := fs.Stat(static, "leaflet/"+name); err == nil {
+		return false
+	}
+	http.Redirect(w, r, leafletRelease+name, http.StatusFound)
+	return true
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// MagicLink serves /login/link, passwordless login by email:
+//
+//	GET             the "email me a login link" form
+//	POST login=…    sends a link to the account's verified email
+//	GET  ?token=…   asks to confirm signing in on this device
+//	POST token=…    uses up the link and starts a session
+//
+// Confirming with a POST means mail scanners that prefetch links can't
+// use them up, and shows the person which device asked for the link.
+func MagicLink(w http.ResponseWriter, r *http.Request) {
+	redirect := safeRedirect(r.URL.Query().Get("redirect"))
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if tok := r.FormValue("token"); tok != "" {
+			magicLinkConsume(w, r, tok)
+			return
+		}
+		magicLinkRequest(w, r, redirect)
+		return
+	}
+
+	if tok := r.URL.Query().Get("token"); tok != "" {
+		l, err := auth.PeekMagicLink(tok)
+		if err != nil {
+			w.Write([]byte(magicLinkPage(redirect, `<p class="text-error">`+htmlpkg.EscapeString(err.Error())+`</p>`)))
+			return
+		}
+		body := fmt.Sprintf(`<div class="card" style="max-width:440px;margin:0 auto">
+<h3>Log in as @%s?</h3>
+<p>This link was requested from <strong>%s</strong> %s.</p>
+<p class="text-sm text-muted">Only continue if that was you. Logging in here signs this device in.</p>
+<form method="POST" action="/login/link">
+	<input type="hidden" name="token" value="%s">
+	<button type="submit">Log in on this device</button>
+</form>
+</div>`, htmlpkg.EscapeString(l.AccountID), htmlpkg.EscapeString(l.Device), TimeAgo(l.RequestedAt), htmlpkg.EscapeString(tok))
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(RenderHTML("Confirm Login", "Confirm login", body)))
+		return
+	}
+
+	w.Write([]byte(magicLinkPage(redirect, "")))
+}
+
+// magicLinkRequest emails a login link. The reply is the same whether or
+// not an account matched, so the form can't be used to probe for them.
+func magicLinkRequest(w http.ResponseWriter, r *http.Request, redirect string) {
+	if EmailSender == nil {
+		w.Write([]byte(magicLinkPage(redirect, `<p class="text-error">Email is not configured on this instance.</p>`)))
+		return
+	}
+	login := strings.TrimSpace(r.FormValue("login"))
+	if login == "" {
+		w.Write([]byte(magicLinkPage(redirect, `<p class="text-error">Username or email is required</p>`)))
+		return
+	}
+
+	ip := ClientIP(r)
+	device := deviceName(r.UserAgent())
+	acc, tok, err := auth.CreateMagicLink(login, ip, device, redirect)
+	switch err {
+	case auth.ErrMagicLinkRateLimited:
+		Log("auth", "Magic link rate limit hit for %s from %s", login, ip)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(magicLinkPage(redirect, `<p class="text-error">`+err.Error()+`</p>`)))
+		return
+	case auth.ErrMagicLinkAccountLimited:
+		// Answered as for any other name, so the limit can't be used
+		// to tell which accounts exist.
+		Log("auth", "Magic link account limit hit for %s from %s", login, ip)
+	}
+	if err == nil {
+		link := PublicURL() + "/login/link?token=" + tok
+		mins := int(auth.MagicLinkTTL.Minutes())
+		when := time.Now().UTC().Format("2 Jan 2006 15:04 MST")
+		plain := fmt.Sprintf("Hi %s,\n\nUse this link to log in to Mu:\n\n%s\n\nIt was requested from %s (%s) at %s, works once and expires in %d minutes.\n\nIf you didn't ask for it, ignore this email — nobody can log in without the link.\n\n— Mu",
+			acc.Name, link, device, ip, when, mins)
+		html := fmt.Sprintf(`<p>Hi %s,</p><p>Use this link to log in to Mu:</p><p><a href="%s">Log in to Mu</a></p><p>It was requested from <strong>%s</strong> (%s) at %s, works once and expires in %d minutes.</p><p>If you didn't ask for it, ignore this email — nobody can log in without the link.</p><p>— Mu</p>`,
+			htmlpkg.EscapeString(acc.Name), link, htmlpkg.EscapeString(device), htmlpkg.EscapeString(ip), when, mins)
+		if err := EmailSender(acc.Email, "Your Mu login link", plain, html); err != nil {
+			Log("auth", "Failed to send login link to %s: %v", acc.ID, err)
+		} else {
+			Log("auth", "Sent login link to %s (requested from %s)", acc.ID, ip)
+		}
+	}
+
+	body := `<div class="card" style="max-width:440px;margin:0 auto">
+<h3>Check your email</h3>
+<p>If that account has a verified email, a login link is on its way. It works once and expires in ` + fmt.Sprint(int(auth.MagicLinkTTL.Minutes())) + ` minutes.</p>
+<p class="text-sm text-muted">Open it on any device — you'll be asked to confirm before it logs you in.</p>
+<p class="mt-3"><a href="/login/link">Send another</a></p>
+</div>`
+	w.Write([]byte(RenderHTML("Check Your Email", "Login link sent", body)))
+}
+
+// magicLinkConsume uses up a confirmed link and sets the session cookie.
+func magicLinkConsume(w http.ResponseWriter, r *http.Request, tok string) {
+	sess, l, err := auth.ConsumeMagicLink(tok)
+	if err != nil {
+		w.Write([]byte(magicLinkPage("", `<p class="text-error">`+htmlpkg.EscapeString(err.Error())+`</p>`)))
+		return
+	}
+	Log("auth", "%s logged in by magic link from %s", sess.Account, ClientIP(r))
+	SetSessionCookie(w, r, sess, false)
+	to := l.Redirect
+	if to == "" {
+		to = "/home"
+	}
+	http.Redirect(w, r, to, http.StatusFound)
+}
+
+// magicLinkPage renders the request form.
+func magicLinkPage(redirect, errHTML string) string {
+	action := "/login/link"
+	if redirect != "" {
+		action += "?redirect=" + url.QueryEscape(redirect)
+	}
+	password := `<p class="text-center mt-5"><a href="/login">Log in with a password</a> instead</p>`
+	if auth.MagicLinkOnly() {
+		password = ""
+	}
+	body := fmt.Sprintf(`<form id="login" action="%s" method="POST">
+	<h1>Login</h1>
+	%s
+	<p class="text-sm text-muted">We'll email a one-time login link to the verified address on your account.</p>
+	<input name="login" placeholder="Username or email" autocomplete="username" required>
+	<br>
+	<button>Email me a login link</button>
+</form>
+%s
+<p class="text-center mt-5"><a href="/signup">Sign up</a> if you don't have an account</p>`, htmlpkg.EscapeString(action), errHTML, password)
+	return RenderHTML("Login", "Log in with an email link", body)
+}
+
+// safeRedirect keeps redirects on this site, or returns "".
+func safeRedirect(to string) string {
+	if to == "" || to[0] != '/' || strings.HasPrefix(to, "//") {
+		return ""
+	}
+	return to
+}
+
+// deviceName summarises a User-Agent as "Browser on OS".
+func deviceName(ua string) string {
+	browser := "a browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	platform := "an unknown device"
+	for _, o := range []struct{ token, name string }{
+		{"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Android", "Android"},
+		{"Mac OS X", "macOS"}, {"Windows", "Windows"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			platform = o.name
+			break
+		}
+	}
+	return browser + " on " + platform
+}
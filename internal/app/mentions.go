@@ -0,0 +1,98 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"mu/internal/auth"
+)
+
+// mentionPattern matches @username where it starts a word, so email
+// addresses and paths such as /@alice aren't taken for mentions.
+var mentionPattern = regexp.MustCompile(`(^|[^\w@/])@([a-zA-Z0-9_-]{2,32})`)
+
+// Mentions returns the accounts @named in text, once each, in the order
+// they're first named.
+func Mentions(text string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		id := strings.ToLower(m[2])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := auth.GetAccount(id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// LinkMentions links every @username in rendered HTML that names an
+// account to its profile. Text inside tags, links, code and pre blocks is
+// left alone.
+func LinkMentions(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	var b strings.Builder
+	skip := 0 // how deep we are in a, code and pre elements
+	for s != "" {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			i = len(s)
+		}
+		if skip == 0 {
+			b.WriteString(linkMentionsText(s[:i]))
+		} else {
+			b.WriteString(s[:i])
+		}
+		s = s[i:]
+		if s == "" {
+			break
+		}
+		j := strings.IndexByte(s, '>')
+		if j < 0 {
+			b.WriteString(s)
+			break
+		}
+		tag := s[:j+1]
+		switch tagName(tag) {
+		case "a", "code", "pre":
+			if strings.HasPrefix(tag, "</") {
+				if skip > 0 {
+					skip--
+				}
+			} else if !strings.HasSuffix(tag, "/>") {
+				skip++
+			}
+		}
+		b.WriteString(tag)
+		s = s[j+1:]
+	}
+	return b.String()
+}
+
+// linkMentionsText links the mentions in a run of HTML text.
+func linkMentionsText(text string) string {
+	return mentionPattern.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mentionPattern.FindStringSubmatch(m)
+		id := strings.ToLower(sub[2])
+		if _, err := auth.GetAccount(id); err != nil {
+			return m
+		}
+		return sub[1] + `<a href="/@` + id + `" class="mention">@` + sub[2] + `</a>`
+	})
+}
+
+// tagName returns the lowercased element name of an HTML tag such as
+// <a href="…"> or </code>.
+func tagName(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimPrefix(tag, "<"), "/")
+	end := 0
+	for end < len(tag) && (tag[end] >= 'a' && tag[end] <= 'z' || tag[end] >= 'A' && tag[end] <= 'Z' || tag[end] >= '0' && tag[end] <= '9') {
+		end++
+	}
+	return strings.ToLower(tag[:end])
+}
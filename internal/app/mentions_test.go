@@ -0,0 +1,36 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestMentions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, id := range []string{"alice", "bobby"} {
+		if err := auth.Create(&auth.Account{ID: id, Name: id, Secret: "secret", Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(id)
+	}
+
+	got := Mentions("@Alice and @nobody, then @bobby and @alice again; mail alice@example.com")
+	if want := []string{"alice", "bobby"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions = %v, want %v", got, want)
+	}
+
+	for in, want := range map[string]string{
+		`<p>hi @alice!</p>`:                           `<p>hi <a href="/@alice" class="mention">@alice</a>!</p>`,
+		`<p>@nobody here</p>`:                         `<p>@nobody here</p>`,
+		`<p><a href="/x">@alice</a></p>`:              `<p><a href="/x">@alice</a></p>`,
+		`<pre><code>@alice</code></pre> @bobby`:       `<pre><code>@alice</code></pre> <a href="/@bobby" class="mention">@bobby</a>`,
+		`<p>see https://mu.xyz/@alice or a@alice</p>`: `<p>see https://mu.xyz/@alice or a@alice</p>`,
+	} {
+		if got := LinkMentions(in); got != want {
+			t.Errorf("LinkMentions(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package app
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// Middleware shared by every Mu server. main composes them with
+// Router.Wrap and Router.Use.
+
+// RequestIDs gives each request an ID, from X-Request-ID if a proxy in
+// front set a sensible one, returns it in the same header and carries it
+// in the request's context, so what's logged while serving the request
+// can be found together.
+func RequestIDs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			b := make([]byte, 8)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID reports whether id is safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// LogRequests logs each request, Apache style, except static assets and
+// the chat websocket, which would drown out everything else.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		path := r.URL.Path
+		if strings.HasSuffix(path, ".css") || strings.HasSuffix(path, ".js") ||
+			strings.HasSuffix(path, ".png") || strings.HasSuffix(path, ".ico") ||
+			strings.HasPrefix(path, "/chat/ws") {
+			return
+		}
+		logf(r.Context(), slog.LevelInfo, "http", "%s %s %s %v", r.Method, path, r.RemoteAddr, time.Since(start))
+	})
+}
+
+// CORS lets any origin call the server, for development against a
+// front end served from elsewhere.
+func CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// OnionLocation points Tor Browser at the onion address in TOR_ONION.
+func OnionLocation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if onion := os.Getenv("TOR_ONION"); onion != "" {
+			w.Header().Set("Onion-Location", "http://"+onion+r.URL.RequestURI())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RefreshSessions renews a remembered login whose session has run out.
+func RefreshSessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, RefreshSession(w, r))
+	})
+}
+
+// RateLimiter throttles sign in, sign up, search, chat and the like; see
+// RateLimit.
+func RateLimiter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if RateLimit(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// TokenScopes keeps personal access tokens to what their scopes allow.
+func TokenScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-Micro-Token") != "" {
+			if sess, err := auth.GetSession(r); err == nil {
+				if scope := auth.ScopeFor(r.Method, r.URL.Path); !sess.Allows(scope) {
+					RespondError(w, http.StatusForbidden, "this token lacks the "+scope+" scope")
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+var gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// Gzip compresses text responses for clients that accept it. Streams,
+// websockets, partial content and responses the handler encoded itself
+// are passed through as they are.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsEncoding(r.Header.Get("Accept-Encoding"), "gzip") ||
+			r.Header.Get("Upgrade") != "" || r.Header.Get("Range") != "" || r.Method == "HEAD" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows coding,
+// honouring q=0 ("not this one") and the * wildcard.
+func acceptsEncoding(header, coding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+		ok := true
+		for _, p := range strings.Split(params, ";") {
+			if k, v, found := strings.Cut(strings.TrimSpace(p), "="); found && strings.TrimSpace(k) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q == 0 {
+					ok = false
+				}
+			}
+		}
+		if name == coding {
+			return ok
+		}
+		wildcard = ok
+	}
+	return wildcard
+}
+
+// compressible reports whether a response of this type is worth
+// compressing.
+func compressible(contentType string) bool {
+	ct, _, _ := strings.Cut(contentType, ";")
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	switch {
+	case ct == "text/event-stream":
+		return false
+	case strings.HasPrefix(ct, "text/"), strings.HasSuffix(ct, "+json"), strings.HasSuffix(ct, "+xml"):
+		return true
+	}
+	switch ct {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// gzipWriter decides whether to compress when the response starts, from
+// its status and content type.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	started bool
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if !g.started {
+		g.started = true
+		h := g.Header()
+		h.Add("Vary", "Accept-Encoding")
+		if code == http.StatusOK && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+			h.Set("Content-Encoding", "gzip")
+			h.Del("Content-Length")
+			g.gz = gzipPool.Get().(*gzip.Writer)
+			g.gz.Reset(g.ResponseWriter)
+		}
+	}
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	if !g.started {
+		if g.Header().Get("Content-Type") == "" {
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	return g.ResponseWriter.Write(b)
+}
+
+// Flush sends what's been written so far, for handlers that stream.
+func (g *gzipWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (g *gzipWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipWriter) close() {
+	if g.gz == nil {
+		return
+	}
+	g.gz.Close()
+	g.gz.Reset(nil)
+	gzipPool.Put(g.gz)
+	g.gz = nil
+}
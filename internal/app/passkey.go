@@ -10,12 +10,22 @@ import (
 	"time"
 
 	"mu/internal/auth"
+	"mu/internal/settings"
 
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 )
 
+func init() {
+	settings.Register("Platform",
+		settings.Var{Key: "MU_DOMAIN", Doc: "Public domain of this server"},
+		settings.Var{Key: "DATA_DIR", Doc: "Where data is stored"},
+		settings.Var{Key: "PASSKEY_ORIGIN", Type: settings.TypeURL, Doc: "Origin passkeys are registered for"},
+		settings.Var{Key: "PASSKEY_RP_ID", Doc: "Passkey relying party ID, the domain"},
+	)
+}
+
 var (
 	webAuthn       *webauthn.WebAuthn
 	webAuthnOnce   sync.Once
@@ -182,6 +192,10 @@ func passkeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
 }
 
 func passkeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	if auth.MagicLinkOnly() {
+		RespondError(w, http.StatusForbidden, "passkey login is disabled on this instance")
+		return
+	}
 	wan := getWebAuthn(r)
 	if wan == nil {
 		RespondError(w, http.StatusInternalServerError, "WebAuthn not configured")
@@ -253,7 +267,7 @@ func passkeyLoginFinish(w http.ResponseWriter, r *http.Request) {
 
 	// Handler to look up user by their WebAuthn user handle
 	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
-		return auth.FindUserByWebAuthnID(userHandle)
+		return auth.FindUserByWebAuthnID(rawID, userHandle)
 	}
 
 	user, credential, err := wan.FinishPasskeyLogin(handler, *session, r)
@@ -266,7 +280,7 @@ func passkeyLoginFinish(w http.ResponseWriter, r *http.Request) {
 	auth.UpdatePasskeyUsage(credential.ID, credential.Authenticator.SignCount)
 
 	// Create a session for the authenticated user
-	accountID := string(user.WebAuthnID())
+	accountID := user.(*auth.WebAuthnUser).AccountID()
 
 	sess, err := auth.CreateSession(accountID)
 	if err != nil {
@@ -274,15 +288,7 @@ func passkeyLoginFinish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session",
-		Value:    sess.Token,
-		Path:     "/",
-		MaxAge:   2592000,
-		Secure:   secure,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	SetSessionCookie(w, r, sess, false)
 
 	RespondJSON(w, map[string]interface{}{
 		"success":  true,
@@ -154,3 +154,21 @@ func ClearUserPrefs(userID string) {
 	delete(prefs, userID)
 	savePrefs()
 }
+
+// RenameUserPrefs moves a renamed user's preferences to their new ID and
+// keeps them blocked by anyone who had blocked them.
+func RenameUserPrefs(oldID, newID string) {
+	prefsMu.Lock()
+	defer prefsMu.Unlock()
+	if p, ok := prefs[oldID]; ok {
+		delete(prefs, oldID)
+		prefs[newID] = p
+	}
+	for _, p := range prefs {
+		if t, ok := p.Blocked[oldID]; ok {
+			delete(p.Blocked, oldID)
+			p.Blocked[newID] = t
+		}
+	}
+	savePrefs()
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Mu installs as a Progressive Web App: the manifest is in
+// html/manifest.webmanifest and the service worker is generated here,
+// at /sw.js, with the fingerprinted assets to precache. Pages are
+// network first: each successful visit to one of OfflinePages is kept,
+// and served when the network fails; anything else falls back to
+// /offline.html. The cache is named for the process Version, so a
+// deploy drops pages rendered by the old build, and logging out clears
+// it (see ClearSessionCookies).
+
+// OfflinePages are the path prefixes whose pages are kept for offline
+// use: the home screen, headlines, and posts, including the reader's
+// own on their profile.
+var OfflinePages = []string{"/home", "/news", "/blog", "/@"}
+
+// maxOfflinePages bounds the pages kept; the oldest go first.
+const maxOfflinePages = 50
+
+// ServiceWorker serves /sw.js.
+func ServiceWorker(w http.ResponseWriter, r *http.Request) {
+	assets, _ := json.Marshal(precacheAssets())
+	pages, _ := json.Marshal(OfflinePages)
+	js := strings.NewReplacer(
+		"{{CACHE}}", "mu-"+Version,
+		"{{ASSETS}}", string(assets),
+		"{{PAGES}}", string(pages),
+		"{{MAX_PAGES}}", strconv.Itoa(maxOfflinePages),
+		"{{OFFLINE}}", Asset("/offline.html"),
+	).Replace(serviceWorkerJS)
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	// Browsers check for a new worker on navigation; never serve a stale one.
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write([]byte(js))
+}
+
+const serviceWorkerJS = `// Generated by the server; see internal/app/pwa.go.
+var CACHE = '{{CACHE}}';
+var ASSETS = {{ASSETS}};
+var PAGES = {{PAGES}};
+var MAX_PAGES = {{MAX_PAGES}};
+var OFFLINE = '{{OFFLINE}}';
+
+self.addEventListener('install', function(e) {
+  e.waitUntil(caches.open(CACHE).then(function(c) { return c.addAll(ASSETS); }).then(function() { return self.skipWaiting(); }));
+});
+
+self.addEventListener('activate', function(e) {
+  e.waitUntil(caches.keys().then(function(keys) {
+    return Promise.all(keys.filter(function(k) { return k !== CACHE; }).map(function(k) { return caches.delete(k); }));
+  }).then(function() { return self.clients.claim(); }));
+});
+
+function offlinePage(url) {
+  if (url.pathname === '/') return true;
+  return PAGES.some(function(p) { return url.pathname.indexOf(p) === 0; });
+}
+
+// trim drops the oldest pages past MAX_PAGES; assets are never dropped.
+function trim(cache) {
+  return cache.keys().then(function(keys) {
+    var pages = keys.filter(function(k) { return ASSETS.indexOf(new URL(k.url).pathname + new URL(k.url).search) < 0; });
+    return Promise.all(pages.slice(0, Math.max(0, pages.length - MAX_PAGES)).map(function(k) { return cache.delete(k); }));
+  });
+}
+
+self.addEventListener('fetch', function(e) {
+  var req = e.request;
+  if (req.method !== 'GET') return;
+  var url = new URL(req.url);
+  if (url.origin !== location.origin) return;
+
+  if (req.mode === 'navigate') {
+    e.respondWith(fetch(req).then(function(res) {
+      if (res.ok && res.type === 'basic' && offlinePage(url) && !/no-store/.test(res.headers.get('Cache-Control') || '')) {
+        var copy = res.clone();
+        caches.open(CACHE).then(function(c) { return c.delete(req).then(function() { return c.put(req, copy); }).then(function() { return trim(c); }); });
+      }
+      return res;
+    }).catch(function() {
+      return caches.match(req).then(function(hit) { return hit || caches.match(OFFLINE); });
+    }));
+    return;
+  }
+
+  // Fingerprinted assets never change: cache first. Other static files
+  // are tried from the network and fall back to any cached version.
+  if (/\.(css|js|png|svg|ico|webmanifest)$/.test(url.pathname) && url.pathname !== '/sw.js') {
+    e.respondWith(caches.match(req).then(function(hit) {
+      return hit || fetch(req).catch(function() { return caches.match(req, { ignoreSearch: true }); });
+    }));
+  }
+});
+`
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAssetFingerprints(t *testing.T) {
+	css := Asset("/mu.css")
+	if !strings.HasPrefix(css, "/mu.css?v=") || len(css) != len("/mu.css?v=")+10 {
+		t.Fatalf("unexpected asset URL %q", css)
+	}
+	if got := Asset("/missing.css"); got != "/missing.css?"+Version {
+		t.Fatalf("unknown asset = %q", got)
+	}
+
+	w := httptest.NewRecorder()
+	Serve().ServeHTTP(w, httptest.NewRequest("GET", css, nil))
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Fatalf("fingerprinted asset Cache-Control = %q", cc)
+	}
+	w = httptest.NewRecorder()
+	Serve().ServeHTTP(w, httptest.NewRequest("GET", "/mu.css?v=stale", nil))
+	if cc := w.Header().Get("Cache-Control"); strings.Contains(cc, "immutable") {
+		t.Fatal("a stale fingerprint shouldn't be cached for good")
+	}
+}
+
+func TestServiceWorker(t *testing.T) {
+	w := httptest.NewRecorder()
+	ServiceWorker(w, httptest.NewRequest("GET", "/sw.js", nil))
+	js := w.Body.String()
+	for _, want := range []string{"'mu-" + Version + "'", `"` + Asset("/mu.css") + `"`, `"` + Asset("/offline.html") + `"`, `"/news"`} {
+		if !strings.Contains(js, want) {
+			t.Errorf("service worker missing %s", want)
+		}
+	}
+	if strings.Contains(js, "{{") {
+		t.Fatal("unfilled placeholder in the service worker")
+	}
+	if w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatal("the service worker must always be revalidated")
+	}
+}
+
+func TestLeaflet(t *testing.T) {
+	for name, want := range map[string]string{
+		"/leaflet/leaflet.js":  LeafletJSIntegrity,
+		"/leaflet/leaflet.css": LeafletCSSIntegrity,
+	} {
+		w := httptest.NewRecorder()
+		Serve().ServeHTTP(w, httptest.NewRequest("GET", name, nil))
+		switch w.Code {
+		case http.StatusOK:
+			sum := sha256.Sum256(w.Body.Bytes())
+			if got := "sha256-" + base64.StdEncoding.EncodeToString(sum[:]); got != want {
+				t.Errorf("%s doesn't match the release (%s)", name, got)
+			}
+		case http.StatusFound:
+			// Not vendored yet: the release, checked by the browser.
+			if loc := w.Header().Get("Location"); loc != leafletRelease+strings.TrimPrefix(name, "/leaflet/") {
+				t.Errorf("%s redirects to %q", name, loc)
+			}
+		default:
+			t.Errorf("%s: status %d", name, w.Code)
+		}
+	}
+
+	for _, name := range []string{"/leaflet/leaflet-src.js", "/leaflet/../mu.css"} {
+		w := httptest.NewRecorder()
+		Serve().ServeHTTP(w, httptest.NewRequest("GET", name, nil))
+		if w.Code == http.StatusFound {
+			t.Errorf("%s redirected to %s", name, w.Header().Get("Location"))
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package app
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// Rate limiting for the endpoints people and bots can hammer. Each route
+// has a token bucket per IP and, once signed in, per account instead, so
+// people sharing an address don't use up each other's requests. Buckets
+// refill continuously at the route's rate and hold a minute's worth.
+//
+// The defaults below can be changed with RATE_LIMITS, a comma separated
+// list of prefix=ip/account requests per minute, where 0 means no limit:
+//
+//	RATE_LIMITS="/search=60/120,/chat=0/0"
+//
+// Requests are limited by the address they come from. X-Forwarded-For
+// and X-Real-IP are believed only from TRUSTED_PROXIES, a comma separated
+// list of addresses and CIDR ranges, loopback by default; anyone else
+// could send them to pick a bucket, or pass as localhost.
+
+// RateRule limits the requests to paths under Prefix.
+type RateRule struct {
+	Prefix     string `json:"prefix"`
+	PerIP      int    `json:"per_ip"`      // requests a minute from one IP; 0 is unlimited
+	PerAccount int    `json:"per_account"` // requests a minute from one account; 0 falls back to PerIP
+	WritesOnly bool   `json:"writes_only"` // only POST and other writes count
+}
+
+// defaultRateRules covers sign in, sign up and the endpoints that are
+// expensive to serve.
+var defaultRateRules = []RateRule{
+	{Prefix: "/login", PerIP: 10, WritesOnly: true},
+	{Prefix: "/signup", PerIP: 10, WritesOnly: true},
+	{Prefix: "/recover", PerIP: 5, WritesOnly: true},
+	{Prefix: "/request-invite", PerIP: 5, WritesOnly: true},
+	{Prefix: "/oauth/token", PerIP: 20, WritesOnly: true},
+	{Prefix: "/search", PerIP: 30, PerAccount: 60},
+	{Prefix: "/web", PerIP: 30, PerAccount: 60},
+	{Prefix: "/chat", PerIP: 20, PerAccount: 30, WritesOnly: true},
+	{Prefix: "/tiles", PerIP: 600},
+	{Prefix: "/places/tile", PerIP: 600},
+	{Prefix: "/video/upload", PerAccount: 120, WritesOnly: true},
+}
+
+// RateStats counts what a rule has let through and turned away since the
+// server started.
+type RateStats struct {
+	RateRule
+	Allowed int64     `json:"allowed"`
+	Limited int64     `json:"limited"`
+	LastHit time.Time `json:"last_hit,omitempty"` // when it last turned someone away
+}
+
+// RateOffender is an IP or account that's been turned away.
+type RateOffender struct {
+	Key     string    `json:"key"` // ip:… or account:…
+	Prefix  string    `json:"prefix"`
+	Limited int64     `json:"limited"`
+	Last    time.Time `json:"last"`
+}
+
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+var (
+	rateMu        sync.Mutex
+	rateRules     []RateRule
+	rateRulesEnv  string // RATE_LIMITS the rules were parsed from
+	rateBuckets   = map[string]*rateBucket{}
+	rateStats     = map[string]*RateStats{}
+	rateOffenders = map[string]*RateOffender{}
+)
+
+// maxRateBuckets is how many buckets, or offenders, are kept before old
+// ones are swept.
+const maxRateBuckets = 10000
+
+// rules returns the rules in force, reparsing RATE_LIMITS if it changed.
+// Called with rateMu held.
+func rules() []RateRule {
+	env := os.Getenv("RATE_LIMITS")
+	if rateRules != nil && env == rateRulesEnv {
+		return rateRules
+	}
+	list := append([]RateRule{}, defaultRateRules...)
+	for _, item := range strings.Split(env, ",") {
+		prefix, limits, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			continue
+		}
+		ipStr, accStr, _ := strings.Cut(limits, "/")
+		perIP, err := strconv.Atoi(strings.TrimSpace(ipStr))
+		if err != nil {
+			continue
+		}
+		perAccount, _ := strconv.Atoi(strings.TrimSpace(accStr))
+		rule := RateRule{Prefix: prefix, PerIP: perIP, PerAccount: perAccount}
+		replaced := false
+		for i := range list {
+			if list[i].Prefix == prefix {
+				rule.WritesOnly = list[i].WritesOnly
+				list[i], replaced = rule, true
+			}
+		}
+		if !replaced {
+			list = append(list, rule)
+		}
+	}
+	// Longest prefix first, so /login/link can be limited apart from /login.
+	sort.SliceStable(list, func(i, j int) bool { return len(list[i].Prefix) > len(list[j].Prefix) })
+	rateRules, rateRulesEnv = list, env
+	return list
+}
+
+// rateRuleFor returns the rule for a request, or nil if it isn't limited.
+func rateRuleFor(r *http.Request) *RateRule {
+	for _, rule := range rules() {
+		if r.URL.Path != rule.Prefix && !strings.HasPrefix(r.URL.Path, rule.Prefix+"/") {
+			continue
+		}
+		if rule.WritesOnly && (r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS") {
+			return nil
+		}
+		rule := rule
+		return &rule
+	}
+	return nil
+}
+
+// take removes a token from the bucket for key, refilled at perMinute,
+// and returns how long until one is available if it's empty.
+func take(key string, perMinute int, now time.Time) (bool, time.Duration) {
+	b, ok := rateBuckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(perMinute), updated: now}
+		rateBuckets[key] = b
+	}
+	rate := float64(perMinute) / 60 // tokens a second
+	b.tokens = math.Min(float64(perMinute), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// sweepBuckets drops the buckets that have refilled, which are the same
+// as having none. Called with rateMu held.
+func sweepBuckets(now time.Time) {
+	for key, b := range rateBuckets {
+		if now.Sub(b.updated) > time.Minute {
+			delete(rateBuckets, key)
+		}
+	}
+	for key, o := range rateOffenders {
+		if now.Sub(o.Last) > 24*time.Hour {
+			delete(rateOffenders, key)
+		}
+	}
+}
+
+// trustedProxy reports whether ip is in TRUSTED_PROXIES.
+func trustedProxy(ip net.IP) bool {
+	list, ok := os.LookupEnv("TRUSTED_PROXIES")
+	if !ok {
+		list = "127.0.0.1,::1"
+	}
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if _, cidr, err := net.ParseCIDR(p); err == nil {
+			if cidr.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(p); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitIP returns the address a request is limited by: the
+// connection's, or when that's a trusted proxy, the last address it
+// forwarded for that isn't a proxy too. forwarded reports whether it
+// came from a header.
+func rateLimitIP(r *http.Request) (ip string, forwarded bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote := net.ParseIP(host)
+	if remote == nil || !trustedProxy(remote) {
+		return host, false
+	}
+	// Each proxy appends the address it heard from, so the entries
+	// nearest the end are the ones to believe.
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		if !trustedProxy(hop) {
+			return hop.String(), true
+		}
+	}
+	if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil && !real.Equal(remote) {
+		return real.String(), true
+	}
+	return host, false
+}
+
+// RateLimit checks the request against its route's limit. Over it, it
+// writes a 429 with Retry-After and returns false; the caller stops there.
+func RateLimit(w http.ResponseWriter, r *http.Request) bool {
+	rateMu.Lock()
+	rule := rateRuleFor(r)
+	rateMu.Unlock()
+	if rule == nil {
+		return true
+	}
+
+	ip, forwarded := rateLimitIP(r)
+	if !forwarded && net.ParseIP(ip).IsLoopback() {
+		return true // never rate-limit localhost (self-hosted, dev)
+	}
+	key, limit := "ip:"+ip, rule.PerIP
+	if rule.PerAccount > 0 {
+		// Look the session up only for rules that limit accounts apart.
+		if sess, err := auth.GetSession(r); err == nil {
+			key, limit = "account:"+sess.Account, rule.PerAccount
+		}
+	}
+	if limit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	rateMu.Lock()
+	if len(rateBuckets) > maxRateBuckets || len(rateOffenders) > maxRateBuckets {
+		sweepBuckets(now)
+	}
+	ok, wait := take(rule.Prefix+"|"+key, limit, now)
+	stats := rateStats[rule.Prefix]
+	if stats == nil {
+		stats = &RateStats{}
+		rateStats[rule.Prefix] = stats
+	}
+	stats.RateRule = *rule
+	if ok {
+		stats.Allowed++
+	} else {
+		stats.Limited++
+		stats.LastHit = now
+		o := rateOffenders[rule.Prefix+"|"+key]
+		if o == nil {
+			o = &RateOffender{Key: key, Prefix: rule.Prefix}
+			rateOffenders[rule.Prefix+"|"+key] = o
+		}
+		o.Limited++
+		o.Last = now
+	}
+	rateMu.Unlock()
+	if ok {
+		return true
+	}
+
+	logf(r.Context(), slog.LevelWarn, "ratelimit", "%s %s over %d/min for %s", r.Method, r.URL.Path, limit, key)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	Error(w, r, http.StatusTooManyRequests, "Too many requests, please wait a moment and try again")
+	return false
+}
+
+// GetRateStats returns the counters for every rule in force, and the IPs
+// and accounts turned away in the last day, most often first.
+func GetRateStats() ([]RateStats, []RateOffender) {
+	rateMu.Lock()
+	defer rateMu.Unlock()
+	var stats []RateStats
+	for _, rule := range rules() {
+		s := RateStats{RateRule: rule}
+		if c := rateStats[rule.Prefix]; c != nil {
+			s.Allowed, s.Limited, s.LastHit = c.Allowed, c.Limited, c.LastHit
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Prefix < stats[j].Prefix })
+	offenders := make([]RateOffender, 0, len(rateOffenders))
+	for _, o := range rateOffenders {
+		if time.Since(o.Last) < 24*time.Hour {
+			offenders = append(offenders, *o)
+		}
+	}
+	sort.Slice(offenders, func(i, j int) bool { return offenders[i].Limited > offenders[j].Limited })
+	return stats, offenders
+}
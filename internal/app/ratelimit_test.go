@@ -0,0 +1,153 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMITS", "/login=2/0, /search=0/0, /extra=1")
+	rateMu.Lock()
+	rateBuckets = map[string]*rateBucket{}
+	rateStats = map[string]*RateStats{}
+	rateOffenders = map[string]*RateOffender{}
+	rateMu.Unlock()
+
+	do := func(method, path, ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		if RateLimit(w, r) {
+			w.WriteHeader(http.StatusOK)
+		}
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := do("POST", "/login", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("login %d: status = %d", i+1, w.Code)
+		}
+	}
+	w := do("POST", "/login", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("over the limit: status = %d, Retry-After = %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := do("GET", "/login", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Error("viewing the login page was limited")
+	}
+	if w := do("POST", "/login/link", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Error("paths under /login aren't limited with it")
+	}
+	if w := do("POST", "/login", "10.0.0.2"); w.Code != http.StatusOK {
+		t.Error("another IP was limited")
+	}
+	if w := do("POST", "/login", "127.0.0.1"); w.Code != http.StatusOK {
+		t.Error("localhost was limited")
+	}
+	for i := 0; i < 50; i++ {
+		if w := do("GET", "/search", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatal("search limited after RATE_LIMITS turned it off")
+		}
+	}
+	do("GET", "/extra", "10.0.0.3")
+	if w := do("GET", "/extra", "10.0.0.3"); w.Code != http.StatusTooManyRequests {
+		t.Error("rule added by RATE_LIMITS not applied")
+	}
+
+	stats, offenders := GetRateStats()
+	for _, s := range stats {
+		if s.Prefix == "/login" && (s.Allowed != 3 || s.Limited != 2) {
+			t.Errorf("/login stats = %+v", s)
+		}
+	}
+	if len(offenders) != 2 || offenders[0].Key != "ip:10.0.0.1" || offenders[0].Limited != 2 {
+		t.Errorf("offenders = %+v", offenders)
+	}
+}
+
+func TestRateLimitDynamicRoutes(t *testing.T) {
+	t.Setenv("RATE_LIMITS", "/tiles=2")
+	rateMu.Lock()
+	rateBuckets = map[string]*rateBucket{}
+	rateStats = map[string]*RateStats{}
+	rateOffenders = map[string]*RateOffender{}
+	rateMu.Unlock()
+
+	rt := NewRouter()
+	tile := func(w http.ResponseWriter, r *http.Request) { w.Header().Set("Content-Type", "image/png") }
+	rt.HandleFunc("/tiles/", tile, Dynamic)
+	rt.HandleFunc("/logo.png", tile)
+	rt.Use(RateLimiter)
+
+	get := func(path string) int {
+		r := httptest.NewRequest("GET", path, nil)
+		r.RemoteAddr = "10.0.0.9:1234"
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		return w.Code
+	}
+	// Tiles look like static files but are proxied, so they're limited.
+	for i := 0; i < 2; i++ {
+		if code := get("/tiles/1/0/0.png"); code != http.StatusOK {
+			t.Fatalf("tile %d: status = %d", i+1, code)
+		}
+	}
+	if code := get("/tiles/1/1/0.png"); code != http.StatusTooManyRequests {
+		t.Errorf("tile over the limit: status = %d", code)
+	}
+	if code := get("/logo.png"); code != http.StatusOK {
+		t.Errorf("static asset: status = %d", code)
+	}
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	t.Setenv("RATE_LIMITS", "/login=1/0")
+	t.Setenv("TRUSTED_PROXIES", "10.1.0.0/16")
+	rateMu.Lock()
+	rateBuckets = map[string]*rateBucket{}
+	rateStats = map[string]*RateStats{}
+	rateOffenders = map[string]*RateOffender{}
+	rateMu.Unlock()
+
+	do := func(remote string, header ...string) int {
+		r := httptest.NewRequest("POST", "/login", nil)
+		r.RemoteAddr = remote + ":1234"
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		if RateLimit(w, r) {
+			w.WriteHeader(http.StatusOK)
+		}
+		return w.Code
+	}
+
+	// A client can't pass as localhost, or pick a new bucket each time.
+	do("10.0.0.1")
+	for _, xff := range []string{"127.0.0.1", "192.0.2.1", "::1"} {
+		if code := do("10.0.0.1", "X-Forwarded-For", xff, "X-Real-IP", xff); code != http.StatusTooManyRequests {
+			t.Errorf("X-Forwarded-For %s from a client: %d", xff, code)
+		}
+	}
+
+	// Behind a trusted proxy, the address it saw is limited, not what
+	// the client put before it.
+	if code := do("10.1.0.1", "X-Forwarded-For", "127.0.0.1, 192.0.2.2"); code != http.StatusOK {
+		t.Errorf("first request through the proxy: %d", code)
+	}
+	if code := do("10.1.0.1", "X-Forwarded-For", "198.51.100.9, 192.0.2.2, 10.1.0.2"); code != http.StatusTooManyRequests {
+		t.Errorf("second request through the proxy: %d", code)
+	}
+	if code := do("10.1.0.1", "X-Real-IP", "192.0.2.3"); code != http.StatusOK {
+		t.Errorf("another client through the proxy: %d", code)
+	}
+
+	// Localhost itself isn't limited, but only when it's the connection.
+	t.Setenv("TRUSTED_PROXIES", "")
+	for i := 0; i < 3; i++ {
+		if code := do("127.0.0.1", "X-Forwarded-For", "192.0.2.4"); code != http.StatusOK {
+			t.Fatalf("localhost limited: %d", code)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package app
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// Account recovery via trusted contacts. /account/recovery is where a
+// member picks their contacts and where contacts approve requests;
+// /recover is where someone locked out starts and follows one. The
+// account's email hears about every step.
+
+// recoveryNotify emails the account owner about a step of a recovery
+// request.
+func recoveryNotify(accountID, subject, text string) {
+	acc, err := auth.GetAccount(accountID)
+	if err != nil || acc.Email == "" || EmailSender == nil {
+		return
+	}
+	plain := fmt.Sprintf("Hi %s,\n\n%s\n\nIf this wasn't you, log in and cancel it at %s/account/recovery — or ask your trusted contacts not to approve it.\n\n— Mu",
+		acc.Name, text, PublicURL())
+	html := fmt.Sprintf(`<p>Hi %s,</p><p>%s</p><p>If this wasn't you, log in and cancel it at <a href="%s/account/recovery">your recovery settings</a> — or ask your trusted contacts not to approve it.</p><p>— Mu</p>`,
+		htmlpkg.EscapeString(acc.Name), htmlpkg.EscapeString(text), PublicURL())
+	if err := EmailSender(acc.Email, subject, plain, html); err != nil {
+		Log("auth", "Failed to send recovery notice to %s: %v", acc.ID, err)
+	}
+}
+
+// recoveryAskContacts emails the trusted contacts that have a verified
+// address, asking them to review a new request.
+func recoveryAskContacts(q *auth.RecoveryRequest) {
+	if EmailSender == nil {
+		return
+	}
+	for _, id := range q.Contacts {
+		c, err := auth.GetAccount(id)
+		if err != nil || c.Email == "" || !c.EmailVerified {
+			continue
+		}
+		plain := fmt.Sprintf("Hi %s,\n\nSomeone is trying to recover @%s's Mu account, and you are one of their trusted contacts.\n\nOnly approve it if @%s has told you directly — in person or on a call — that they've lost access. Review it at %s/account/recovery\n\n— Mu",
+			c.Name, q.AccountID, q.AccountID, PublicURL())
+		html := fmt.Sprintf(`<p>Hi %s,</p><p>Someone is trying to recover @%s's Mu account, and you are one of their trusted contacts.</p><p>Only approve it if @%s has told you directly — in person or on a call — that they've lost access.</p><p><a href="%s/account/recovery">Review the request</a></p><p>— Mu</p>`,
+			htmlpkg.EscapeString(c.Name), htmlpkg.EscapeString(q.AccountID), htmlpkg.EscapeString(q.AccountID), PublicURL())
+		if err := EmailSender(c.Email, "Recovery request for @"+q.AccountID, plain, html); err != nil {
+			Log("auth", "Failed to send recovery request to contact %s: %v", c.ID, err)
+		}
+	}
+}
+
+// RecoverySettings serves /account/recovery for the signed-in member:
+//
+//	GET                          contacts, requests to review, own requests
+//	POST action=contacts         set contacts (fields "contact", two or three, or none)
+//	POST action=approve id=X     approve a request as a trusted contact
+//	POST action=decline id=X     decline a request (or cancel one's own)
+func RecoverySettings(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if WantsJSON(r) || SendsJSON(r) {
+			Unauthorized(w, r)
+		} else {
+			RedirectToLogin(w, r)
+		}
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if WantsJSON(r) {
+			RespondJSON(w, map[string]interface{}{
+				"contacts": auth.RecoveryContacts(acc.ID),
+				"pending":  recoveryViews(auth.PendingRecoveries(acc.ID)),
+				"requests": recoveryViews(auth.AccountRecoveries(acc.ID)),
+			})
+			return
+		}
+		w.Write([]byte(RenderHTMLForRequest("Account Recovery", "Trusted contacts", recoverySettingsPage(acc, r.URL.Query().Get("error")), r)))
+		return
+	case "POST":
+	default:
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	var req struct {
+		Action   string   `json:"action"`
+		ID       string   `json:"id"`
+		Contacts []string `json:"contacts"`
+	}
+	if SendsJSON(r) {
+		if err := DecodeJSON(r, &req); err != nil {
+			BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Action, req.ID, req.Contacts = r.FormValue("action"), r.FormValue("id"), r.Form["contact"]
+	}
+
+	fail := func(err error) {
+		if SendsJSON(r) || WantsJSON(r) {
+			BadRequest(w, r, err.Error())
+			return
+		}
+		http.Redirect(w, r, "/account/recovery?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+	}
+
+	switch req.Action {
+	case "contacts":
+		if err := auth.SetRecoveryContacts(acc.ID, req.Contacts); err != nil {
+			fail(err)
+			return
+		}
+		contacts := auth.RecoveryContacts(acc.ID)
+		Log("auth", "%s set recovery contacts: %v", acc.ID, contacts)
+		if len(contacts) > 0 {
+			recoveryNotify(acc.ID, "Your Mu trusted contacts changed",
+				"Your trusted contacts for account recovery are now @"+strings.Join(contacts, ", @")+". Any recovery request in progress was cancelled.")
+		} else {
+			recoveryNotify(acc.ID, "Account recovery turned off",
+				"You removed your trusted contacts, so your account can no longer be recovered through them.")
+		}
+	case "approve":
+		q, err := auth.ApproveRecovery(req.ID, acc.ID)
+		if err != nil {
+			fail(err)
+			return
+		}
+		Log("auth", "%s approved recovery %s for %s (%d/%d)", acc.ID, q.ID, q.AccountID, len(q.Approvals), q.Quorum)
+		if q.Status == auth.RecoveryApproved {
+			recoveryNotify(q.AccountID, "Your Mu account recovery was approved",
+				fmt.Sprintf("@%s approved the recovery request started %s, and enough trusted contacts have now agreed. Whoever started it can now set a new password.", acc.ID, q.Created.UTC().Format("2 Jan 2006 15:04 MST")))
+		} else {
+			recoveryNotify(q.AccountID, "A trusted contact approved your Mu account recovery",
+				fmt.Sprintf("@%s approved the recovery request started %s. It has %d of the %d approvals it needs.", acc.ID, q.Created.UTC().Format("2 Jan 2006 15:04 MST"), len(q.Approvals), q.Quorum))
+		}
+	case "decline":
+		q, err := auth.DeclineRecovery(req.ID, acc.ID)
+		if err != nil {
+			fail(err)
+			return
+		}
+		Log("auth", "%s stopped recovery %s for %s", acc.ID, q.ID, q.AccountID)
+		who := "You"
+		if acc.ID != q.AccountID {
+			who = "@" + acc.ID
+		}
+		recoveryNotify(q.AccountID, "Your Mu account recovery was stopped",
+			fmt.Sprintf("%s stopped the recovery request started %s. Nobody can use it to reset your password.", who, q.Created.UTC().Format("2 Jan 2006 15:04 MST")))
+	default:
+		BadRequest(w, r, "unknown action")
+		return
+	}
+
+	if SendsJSON(r) || WantsJSON(r) {
+		RespondJSON(w, map[string]bool{"success": true})
+		return
+	}
+	http.Redirect(w, r, "/account/recovery", http.StatusSeeOther)
+}
+
+// recoveryViews trims requests to what members may see.
+func recoveryViews(list []auth.RecoveryRequest) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, q := range list {
+		approvals := make([]string, 0, len(q.Approvals))
+		for id := range q.Approvals {
+			approvals = append(approvals, id)
+		}
+		out = append(out, map[string]interface{}{
+			"id":        q.ID,
+			"account":   q.AccountID,
+			"status":    q.Status,
+			"approvals": approvals,
+			"quorum":    q.Quorum,
+			"device":    q.Device,
+			"created":   q.Created,
+			"expires":   q.Expires,
+			"events":    q.Events,
+		})
+	}
+	return out
+}
+
+func recoverySettingsPage(acc *auth.Account, errMsg string) string {
+	var sb strings.Builder
+	if errMsg != "" {
+		fmt.Fprintf(&sb, `<p class="text-error">%s</p>`, htmlpkg.EscapeString(errMsg))
+	}
+
+	// Requests this member is asked to review.
+	for _, q := range auth.PendingRecoveries(acc.ID) {
+		fmt.Fprintf(&sb, `<div class="card recovery-review">
+<h4>@%s wants to recover their account</h4>
+<p class="text-sm">Requested from <strong>%s</strong> %s · %d of %d approvals · expires %s</p>
+<p class="text-sm text-muted">Only approve if @%s told you directly — in person or on a call — that this is them. An approval helps whoever started the request take over the account.</p>
+<form method="POST" action="/account/recovery" class="d-flex gap-3">
+	<input type="hidden" name="id" value="%s">
+	<button type="submit" name="action" value="approve">Approve</button>
+	<button type="submit" name="action" value="decline" class="btn-secondary">Decline</button>
+</form>
+</div>`, htmlpkg.EscapeString(q.AccountID), htmlpkg.EscapeString(q.Device), TimeAgo(q.Created), len(q.Approvals), q.Quorum,
+			q.Expires.UTC().Format("2 Jan 15:04 MST"), htmlpkg.EscapeString(q.AccountID), htmlpkg.EscapeString(q.ID))
+	}
+
+	contacts := auth.RecoveryContacts(acc.ID)
+	sb.WriteString(`<div class="card"><h4>Trusted contacts</h4>`)
+	sb.WriteString(`<p class="text-sm">If you lose access to your account, two of your trusted contacts can approve a request to reset your password. Choose two or three members you know well and can reach outside Mu. Every step is emailed to your verified address.</p>`)
+	if !acc.EmailVerified {
+		sb.WriteString(`<p class="text-sm text-muted">Verify your email on <a href="/account">your account</a> first — that's where recovery notices are sent.</p>`)
+	}
+	sb.WriteString(`<form method="POST" action="/account/recovery" class="recovery-contacts"><input type="hidden" name="action" value="contacts">`)
+	for i := 0; i < 3; i++ {
+		v := ""
+		if i < len(contacts) {
+			v = contacts[i]
+		}
+		fmt.Fprintf(&sb, `<input name="contact" placeholder="Username" value="%s" autocomplete="off">`, htmlpkg.EscapeString(v))
+	}
+	sb.WriteString(`<button type="submit">Save</button></form>`)
+	if len(contacts) > 0 {
+		sb.WriteString(`<p class="text-sm text-muted">Clear all three and save to turn recovery off.</p>`)
+	}
+	if owners := auth.TrustedBy(acc.ID); len(owners) > 0 {
+		sb.WriteString(`<p class="text-sm text-muted">You are a trusted contact for `)
+		for i, o := range owners {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, `<a href="/@%s">@%s</a>`, htmlpkg.EscapeString(o), htmlpkg.EscapeString(o))
+		}
+		sb.WriteString(`.</p>`)
+	}
+	sb.WriteString(`</div>`)
+
+	// The member's own requests, with their log.
+	if list := auth.AccountRecoveries(acc.ID); len(list) > 0 {
+		sb.WriteString(`<div class="card"><h4>Recovery requests</h4>`)
+		now := time.Now()
+		for _, q := range list {
+			fmt.Fprintf(&sb, `<div class="recovery-request"><p><strong>%s</strong> · started %s from %s</p><ul class="recovery-log text-sm text-muted">`,
+				htmlpkg.EscapeString(q.Status), TimeAgo(q.Created), htmlpkg.EscapeString(q.Device))
+			for _, e := range q.Events {
+				fmt.Fprintf(&sb, `<li>%s · %s</li>`, e.Time.UTC().Format("2 Jan 15:04 MST"), htmlpkg.EscapeString(e.Text))
+			}
+			sb.WriteString(`</ul>`)
+			if q.Open(now) {
+				fmt.Fprintf(&sb, `<form method="POST" action="/account/recovery"><input type="hidden" name="action" value="decline"><input type="hidden" name="id" value="%s"><button type="submit" class="btn-link text-error">Cancel this request</button></form>`,
+					htmlpkg.EscapeString(q.ID))
+			}
+			sb.WriteString(`</div>`)
+		}
+		sb.WriteString(`</div>`)
+	}
+	sb.WriteString(`<p><a href="/account">← Account</a></p>`)
+	return sb.String()
+}
+
+// Recover serves /recover, for someone locked out of their account:
+//
+//	GET                    the "start recovery" form
+//	POST login=…           starts a request and shows its private status link
+//	GET  ?id=…&key=…       the request's status
+//	POST id=…&key=…        claims the reset token once approved
+//	POST token=…&secret=…  sets the new password and logs in
+func Recover(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		r.ParseForm()
+		switch {
+		case r.FormValue("token") != "":
+			recoverReset(w, r)
+		case r.FormValue("id") != "":
+			recoverClaim(w, r)
+		default:
+			recoverStart(w, r)
+		}
+		return
+	}
+
+	id, key := r.URL.Query().Get("id"), r.URL.Query().Get("key")
+	if id == "" {
+		w.Write([]byte(recoverPage("")))
+		return
+	}
+	q, err := auth.GetRecovery(id, key)
+	if err != nil {
+		w.Write([]byte(recoverPage(`<p class="text-error">` + htmlpkg.EscapeString(err.Error()) + `</p>`)))
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(RenderHTML("Account Recovery", "Recovery status", recoverStatus(q, key))))
+}
+
+// recoverStatus renders a request's progress for the key holder.
+func recoverStatus(q *auth.RecoveryRequest, key string) string {
+	var body string
+	switch {
+	case q.Status == auth.RecoveryPending:
+		body = fmt.Sprintf(`<p>%d of %d approvals so far. Ask your trusted contacts to sign in and approve it at <strong>/account/recovery</strong> — they'll need to hear from you directly.</p>
+<p class="text-sm text-muted">The request expires %s. Keep this page's address: it's the only way back to it.</p>`,
+			len(q.Approvals), q.Quorum, q.Expires.UTC().Format("2 Jan 2006 15:04 MST"))
+	case q.Status == auth.RecoveryApproved && q.TokenHash == "":
+		body = fmt.Sprintf(`<p>Your trusted contacts approved the request.</p>
+<form method="POST" action="/recover">
+	<input type="hidden" name="id" value="%s">
+	<input type="hidden" name="key" value="%s">
+	<button type="submit">Get a reset token</button>
+</form>
+<p class="text-sm text-muted">The token works once, for %d minutes.</p>`,
+			htmlpkg.EscapeString(q.ID), htmlpkg.EscapeString(key), int(auth.RecoveryTokenTTL.Minutes()))
+	case q.Status == auth.RecoveryApproved:
+		body = `<p>The reset token for this request has already been issued.</p>`
+	default:
+		body = fmt.Sprintf(`<p>This request is <strong>%s</strong>. <a href="/recover">Start a new one</a> if you still need to.</p>`, htmlpkg.EscapeString(q.Status))
+	}
+	return fmt.Sprintf(`<div class="card" style="max-width:440px;margin:0 auto">
+<h3>Recovering @%s</h3>
+%s
+</div>`, htmlpkg.EscapeString(q.AccountID), body)
+}
+
+// recoverStart opens a request and emails the owner and contacts.
+func recoverStart(w http.ResponseWriter, r *http.Request) {
+	ip := ClientIP(r)
+	device := deviceName(r.UserAgent())
+	q, key, err := auth.StartRecovery(r.FormValue("login"), ip, device)
+	if err != nil {
+		Log("auth", "Recovery start failed for %q from %s: %v", r.FormValue("login"), ip, err)
+		w.Write([]byte(recoverPage(`<p class="text-error">` + htmlpkg.EscapeString(err.Error()) + `</p>`)))
+		return
+	}
+	Log("auth", "Recovery %s started for %s from %s (%s)", q.ID, q.AccountID, ip, device)
+	recoveryNotify(q.AccountID, "Someone started recovering your Mu account",
+		fmt.Sprintf("A request to recover your account was started from %s (%s) at %s. If %d of your trusted contacts approve it, whoever started it can set a new password.",
+			device, ip, q.Created.UTC().Format("2 Jan 2006 15:04 MST"), q.Quorum))
+	recoveryAskContacts(q)
+
+	status := "/recover?id=" + url.QueryEscape(q.ID) + "&key=" + url.QueryEscape(key)
+	w.Header().Set("Cache-Control", "no-store")
+	body := fmt.Sprintf(`<div class="card" style="max-width:440px;margin:0 auto">
+<h3>Recovery started</h3>
+<p>Your trusted contacts have been asked to approve it. Contact them yourself too — they should only approve once they've heard from you.</p>
+<p><strong>Save this link.</strong> It's the only way to follow the request and finish resetting your password:</p>
+<p><a href="%s">%s%s</a></p>
+</div>`, htmlpkg.EscapeString(status), PublicURL(), htmlpkg.EscapeString(status))
+	w.Write([]byte(RenderHTML("Account Recovery", "Recovery started", body)))
+}
+
+// recoverClaim issues the reset token for an approved request.
+func recoverClaim(w http.ResponseWriter, r *http.Request) {
+	id, key := r.FormValue("id"), r.FormValue("key")
+	tok, err := auth.ClaimRecoveryToken(id, key)
+	if err != nil {
+		w.Write([]byte(recoverPage(`<p class="text-error">` + htmlpkg.EscapeString(err.Error()) + `</p>`)))
+		return
+	}
+	q, _ := auth.GetRecovery(id, key)
+	Log("auth", "Recovery %s reset token issued to %s", id, ClientIP(r))
+	if q != nil {
+		recoveryNotify(q.AccountID, "A reset token was issued for your Mu account",
+			fmt.Sprintf("An approved recovery request was used to get a password reset token from %s (%s). It expires in %d minutes.",
+				deviceName(r.UserAgent()), ClientIP(r), int(auth.RecoveryTokenTTL.Minutes())))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	body := fmt.Sprintf(`<form id="login" action="/recover" method="POST">
+	<h1>New password</h1>
+	<p class="text-sm text-muted">Setting a new password signs out every other device.</p>
+	<input type="hidden" name="token" value="%s">
+	<input name="secret" type="password" placeholder="New password (min 6 chars)" autocomplete="new-password" required>
+	<br>
+	<button>Set password and log in</button>
+</form>`, htmlpkg.EscapeString(tok))
+	w.Write([]byte(RenderHTML("Account Recovery", "Set a new password", body)))
+}
+
+// recoverReset sets the new password and starts a session.
+func recoverReset(w http.ResponseWriter, r *http.Request) {
+	sess, q, err := auth.RedeemRecoveryToken(r.FormValue("token"), r.FormValue("secret"))
+	if err != nil {
+		w.Write([]byte(recoverPage(`<p class="text-error">` + htmlpkg.EscapeString(err.Error()) + `</p>`)))
+		return
+	}
+	Log("auth", "Recovery %s completed: password reset for %s from %s", q.ID, q.AccountID, ClientIP(r))
+	recoveryNotify(q.AccountID, "Your Mu password was reset",
+		fmt.Sprintf("Your password was reset through account recovery from %s (%s), and your other devices were signed out.", deviceName(r.UserAgent()), ClientIP(r)))
+	SetSessionCookie(w, r, sess, false)
+	http.Redirect(w, r, "/account", http.StatusFound)
+}
+
+// recoverPage renders the start form.
+func recoverPage(errHTML string) string {
+	body := fmt.Sprintf(`<form id="login" action="/recover" method="POST">
+	<h1>Recover account</h1>
+	%s
+	<p class="text-sm text-muted">If you set up trusted contacts, they can approve a password reset. Your account's email is told about every step.</p>
+	<input name="login" placeholder="Username or email" autocomplete="username" required>
+	<br>
+	<button>Start recovery</button>
+</form>
+<p class="text-center mt-5"><a href="/login">Back to login</a></p>`, errHTML)
+	return RenderHTML("Account Recovery", "Recover your account", body)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"mu/internal/auth"
+)
+
+// RenderPreview turns markdown into the HTML a published post shows.
+// The blog sets it to its own renderer, which adds video and place embeds
+// on top of Render, so a preview matches the post exactly.
+var RenderPreview = RenderString
+
+// maxPreviewLength bounds what /render will take, above the longest post.
+const maxPreviewLength = 20000
+
+// RenderHandler serves POST /render: markdown in, as "content" in a form
+// or JSON body, and the rendered HTML out, as {"html": ...} for JSON
+// requests or the bare fragment otherwise.
+func RenderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		MethodNotAllowed(w, r)
+		return
+	}
+	if _, _, err := auth.RequireSession(r); err != nil {
+		Unauthorized(w, r)
+		return
+	}
+	var req struct {
+		Content string `json:"content"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxPreviewLength*2)
+	if SendsJSON(r) {
+		if err := DecodeJSON(r, &req); err != nil {
+			BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Content = r.FormValue("content")
+	}
+	if len(req.Content) > maxPreviewLength {
+		BadRequest(w, r, "Too long to preview")
+		return
+	}
+
+	out := RenderPreview(req.Content)
+	if WantsJSON(r) {
+		RespondJSON(w, map[string]string{"html": out})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(out))
+}
+
+// PreviewHTML returns a live preview pane for the textarea with the given
+// id. While open, it re-renders through /render as the text changes.
+func PreviewHTML(textareaID string) string {
+	id, _ := json.Marshal(textareaID)
+	return fmt.Sprintf(`<details class="post-preview">
+  <summary class="text-sm text-muted">Preview</summary>
+  <div class="post-preview-body"><p class="text-muted">Nothing to preview yet.</p></div>
+</details>
+<script>
+(function(){
+  var box = document.currentScript.previousElementSibling;
+  var out = box.querySelector('.post-preview-body');
+  var ta = document.getElementById(%s);
+  var timer, last = null;
+  function render(){
+    if (!box.open || ta.value === last) return;
+    last = ta.value;
+    if (!last.trim()) { out.innerHTML = '<p class="text-muted">Nothing to preview yet.</p>'; return; }
+    fetch('/render', {method: 'POST', headers: {'Content-Type': 'application/json', 'Accept': 'application/json'}, body: JSON.stringify({content: last})})
+      .then(function(r){ return r.json(); })
+      .then(function(d){ if (d.html !== undefined) out.innerHTML = d.html; else if (d.error) out.textContent = d.error; });
+  }
+  box.addEventListener('toggle', render);
+  ta.addEventListener('input', function(){
+    clearTimeout(timer);
+    timer = setTimeout(render, 500);
+  });
+})();
+</script>`, id)
+}
+
+// ImageUploadHTML returns an "Add image" control for the textarea with
+// the given id. Images chosen, dropped on or pasted into the textarea are
+// uploaded to /upload (see the media package) and inserted as markdown at
+// the cursor.
+func ImageUploadHTML(textareaID string) string {
+	id, _ := json.Marshal(textareaID)
+	return fmt.Sprintf(`<div class="media-upload text-sm text-muted">
+  <label class="media-upload-label">Add image <input type="file" accept="image/jpeg,image/png,image/gif" multiple hidden></label>
+  <span class="media-upload-status"></span>
+</div>
+<script>
+(function(){
+  var box = document.currentScript.previousElementSibling;
+  var input = box.querySelector('input[type=file]'), status = box.querySelector('.media-upload-status');
+  var ta = document.getElementById(%s);
+  function insert(text, at){
+    var before = ta.value.slice(0, at), after = ta.value.slice(at);
+    if (before && !/\n$/.test(before)) text = '\n' + text;
+    if (!/^\n/.test(after)) text += '\n';
+    ta.value = before + text + after;
+    ta.dispatchEvent(new Event('input'));
+  }
+  function upload(file){
+    if (!/^image\//.test(file.type)) return;
+    var fd = new FormData();
+    fd.append('file', file);
+    var at = ta.selectionStart;
+    status.textContent = 'Uploading ' + file.name + '…';
+    fetch('/upload', {method: 'POST', headers: {'Accept': 'application/json'}, body: fd})
+      .then(function(r){ return r.json(); })
+      .then(function(d){
+        if (d.error) { status.textContent = d.error; return; }
+        insert(d.markdown, at);
+        status.textContent = '';
+      })
+      .catch(function(){ status.textContent = 'Upload failed'; });
+  }
+  function uploadAll(files){ Array.prototype.forEach.call(files, upload); }
+  input.addEventListener('change', function(){ uploadAll(input.files); input.value = ''; });
+  ta.addEventListener('dragover', function(e){ if (e.dataTransfer.types.indexOf('Files') >= 0) e.preventDefault(); });
+  ta.addEventListener('drop', function(e){
+    if (!e.dataTransfer.files.length) return;
+    e.preventDefault();
+    uploadAll(e.dataTransfer.files);
+  });
+  ta.addEventListener('paste', function(e){
+    var files = e.clipboardData && e.clipboardData.files;
+    if (files && files.length) { e.preventDefault(); uploadAll(files); }
+  });
+})();
+</script>`, id)
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// Reset serves /reset, password reset by email:
+//
+//	GET                    the "email me a reset link" form
+//	POST login=…           sends a link to the account's verified email
+//	GET  ?token=…          the new password form
+//	POST token=…&secret=…  sets the new password and logs in
+func Reset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == "POST" {
+		r.ParseForm()
+		if tok := r.FormValue("token"); tok != "" {
+			resetConfirm(w, r, tok)
+			return
+		}
+		resetRequest(w, r)
+		return
+	}
+
+	tok := r.URL.Query().Get("token")
+	if tok == "" {
+		w.Write([]byte(resetPage("")))
+		return
+	}
+	id, err := auth.PeekPasswordReset(tok)
+	if err != nil {
+		w.Write([]byte(resetPage(`<p class="text-error">` + htmlpkg.EscapeString(err.Error()) + `</p>`)))
+		return
+	}
+	w.Write([]byte(resetPasswordForm(id, tok, "")))
+}
+
+// resetRequest emails a reset link. The reply is the same whether or not
+// an account matched, so the form can't be used to probe for them.
+func resetRequest(w http.ResponseWriter, r *http.Request) {
+	if EmailSender == nil {
+		w.Write([]byte(resetPage(`<p class="text-error">Email is not configured on this instance.</p>`)))
+		return
+	}
+	login := strings.TrimSpace(r.FormValue("login"))
+	if login == "" {
+		w.Write([]byte(resetPage(`<p class="text-error">Username or email is required</p>`)))
+		return
+	}
+
+	ip := ClientIP(r)
+	acc, tok, err := auth.CreatePasswordReset(login, ip)
+	switch err {
+	case auth.ErrPasswordResetRateLimited:
+		Log("auth", "Password reset rate limit hit for %s from %s", login, ip)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(resetPage(`<p class="text-error">` + err.Error() + `</p>`)))
+		return
+	case auth.ErrPasswordResetAccountLimited:
+		// Answered as for any other name, so the limit can't be used
+		// to tell which accounts exist.
+		Log("auth", "Password reset account limit hit for %s from %s", login, ip)
+	}
+	mins := int(auth.PasswordResetTTL.Minutes())
+	if err == nil {
+		link := PublicURL() + "/reset?token=" + tok
+		device := deviceName(r.UserAgent())
+		when := time.Now().UTC().Format("2 Jan 2006 15:04 MST")
+		plain := fmt.Sprintf("Hi %s,\n\nUse this link to set a new password for your Mu account:\n\n%s\n\nIt was requested from %s (%s) at %s, works once and expires in %d minutes. Setting a new password signs out your other devices.\n\nIf you didn't ask for it, ignore this email — your password stays as it is.\n\n— Mu",
+			acc.Name, link, device, ip, when, mins)
+		html := fmt.Sprintf(`<p>Hi %s,</p><p>Use this link to set a new password for your Mu account:</p><p><a href="%s">Reset your password</a></p><p>It was requested from <strong>%s</strong> (%s) at %s, works once and expires in %d minutes. Setting a new password signs out your other devices.</p><p>If you didn't ask for it, ignore this email — your password stays as it is.</p><p>— Mu</p>`,
+			htmlpkg.EscapeString(acc.Name), link, htmlpkg.EscapeString(device), htmlpkg.EscapeString(ip), when, mins)
+		if err := EmailSender(acc.Email, "Reset your Mu password", plain, html); err != nil {
+			Log("auth", "Failed to send password reset to %s: %v", acc.ID, err)
+		} else {
+			Log("auth", "Sent password reset to %s (requested from %s)", acc.ID, ip)
+		}
+	}
+
+	body := fmt.Sprintf(`<div class="card" style="max-width:440px;margin:0 auto">
+<h3>Check your email</h3>
+<p>If that account has a verified email, a reset link is on its way. It works once and expires in %d minutes.</p>
+<p class="text-sm text-muted">No verified email? <a href="/recover">Recover with trusted contacts</a> instead.</p>
+<p class="mt-3"><a href="/reset">Send another</a></p>
+</div>`, mins)
+	w.Write([]byte(RenderHTML("Check Your Email", "Password reset sent", body)))
+}
+
+// resetConfirm sets the new password and starts a session.
+func resetConfirm(w http.ResponseWriter, r *http.Request, tok string) {
+	id, err := auth.PeekPasswordReset(tok)
+	if err != nil {
+		w.Write([]byte(resetPage(`<p class="text-error">` + htmlpkg.EscapeString(err.Error()) + `</p>`)))
+		return
+	}
+	secret := r.FormValue("secret")
+	if len(secret) < 6 {
+		w.Write([]byte(resetPasswordForm(id, tok, `<p class="text-error">Password must be at least 6 characters</p>`)))
+		return
+	}
+	sess, err := auth.ResetPassword(tok, secret)
+	if err != nil {
+		w.Write([]byte(resetPage(`<p class="text-error">` + htmlpkg.EscapeString(err.Error()) + `</p>`)))
+		return
+	}
+	Log("auth", "Password reset for %s from %s", sess.Account, ClientIP(r))
+	SetSessionCookie(w, r, sess, false)
+	http.Redirect(w, r, "/home", http.StatusFound)
+}
+
+// resetPasswordForm asks for the new password.
+func resetPasswordForm(accountID, tok, errHTML string) string {
+	body := fmt.Sprintf(`<form id="login" action="/reset" method="POST">
+	<h1>New password</h1>
+	%s
+	<p class="text-sm text-muted">For <strong>@%s</strong>. Setting a new password signs out every other device.</p>
+	<input type="hidden" name="token" value="%s">
+	<input name="secret" type="password" placeholder="New password (min 6 chars)" autocomplete="new-password" required>
+	<br>
+	<button>Set password and log in</button>
+</form>`, errHTML, htmlpkg.EscapeString(accountID), htmlpkg.EscapeString(tok))
+	return RenderHTML("Reset Password", "Set a new password", body)
+}
+
+// resetPage renders the request form.
+func resetPage(errHTML string) string {
+	body := fmt.Sprintf(`<form id="login" action="/reset" method="POST">
+	<h1>Reset password</h1>
+	%s
+	<p class="text-sm text-muted">We'll email a reset link to the verified address on your account.</p>
+	<input name="login" placeholder="Username or email" autocomplete="username" required>
+	<br>
+	<button>Email me a reset link</button>
+</form>
+<p class="text-center mt-5"><a href="/login">Back to login</a></p>`, errHTML)
+	return RenderHTML("Reset Password", "Reset your password", body)
+}
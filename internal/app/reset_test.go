@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestResetRequestLimits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	orig := EmailSender
+	sent := 0
+	EmailSender = func(to, subject, plain, html string) error { sent++; return nil }
+	defer func() { EmailSender = orig }()
+
+	if err := auth.Create(&auth.Account{ID: "reset_user", Name: "Reset", Secret: "secret", Email: "reset@example.com", EmailVerified: true, Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("reset_user")
+
+	post := func(login, ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/reset", strings.NewReader(url.Values{"login": {login}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		resetRequest(w, r)
+		return w
+	}
+
+	unknown := post("no_such_user", "10.0.1.1").Body.String()
+	for i := 0; i < 4; i++ {
+		w := post("reset_user", "10.0.1.2")
+		if w.Code != http.StatusOK || w.Body.String() != unknown {
+			t.Errorf("request %d for an account differs from an unknown name: %d\n%s", i+1, w.Code, w.Body)
+		}
+	}
+	if sent != 3 {
+		t.Errorf("sent %d links, want 3", sent)
+	}
+
+	// Only too many requests from one address is said to be.
+	for i := 0; i < 10; i++ {
+		post("no_such_user", "10.0.1.3")
+	}
+	if w := post("no_such_user", "10.0.1.3"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("over the IP limit: %d", w.Code)
+	}
+}
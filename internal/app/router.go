@@ -2,8 +2,291 @@ package app
 
 import (
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"mu/internal/auth"
+)
+
+// Router serves Mu's pages and APIs. Each module declares its own routes,
+// and whether they need a session, by implementing RouteRegistrar and
+// calling Register from its init; main mounts them and composes the
+// middleware every request goes through.
+type Router struct {
+	mux      *http.ServeMux
+	routes   map[string]*route // by pattern
+	prefixes []*route          // HandlePrefix routes, longest first
+	wrap     []Middleware      // every request, static assets included
+	use      []Middleware      // pages and APIs
+	once     sync.Once
+	handler  http.Handler
+}
+
+// Middleware wraps a handler, to do something before or after it.
+type Middleware func(http.Handler) http.Handler
+
+// RouteOption declares how a route is served.
+type RouteOption int
+
+const (
+	// Authenticated routes need a session or token. Without one, API
+	// clients get a 401 and browsers are sent to the home page.
+	Authenticated RouteOption = 1 << iota
+	// SkipCSRF routes accept writes without a CSRF token: sign in, where
+	// there's no session yet, and endpoints whose callers authenticate
+	// some other way, such as webhooks and OAuth.
+	SkipCSRF
+	// Audited routes are admin controls: every change an admin makes
+	// through them is written to the audit log. See Router.Audit.
+	Audited
+	// Dynamic routes serve files made or fetched per request, such as
+	// proxied map tiles. Though their paths look like static assets, they
+	// go through the page middleware, and so are rate limited.
+	Dynamic
 )
 
+// route is a declared route.
+type route struct {
+	pattern string
+	opts    RouteOption
+	handler http.Handler
+}
+
+// RouteRegistrar is implemented by each module that serves pages or APIs.
+type RouteRegistrar interface {
+	RegisterRoutes(r *Router)
+}
+
+var (
+	registrarMu sync.Mutex
+	registrars  []RouteRegistrar
+)
+
+// Register adds a module's routes to those Registered returns. Packages
+// call this from init.
+func Register(m RouteRegistrar) {
+	registrarMu.Lock()
+	defer registrarMu.Unlock()
+	registrars = append(registrars, m)
+}
+
+// Registered returns every module passed to Register.
+func Registered() []RouteRegistrar {
+	registrarMu.Lock()
+	defer registrarMu.Unlock()
+	return append([]RouteRegistrar{}, registrars...)
+}
+
+// staticExtensions are the file types served without going through the
+// page middleware.
+var staticExtensions = []string{
+	".css", ".js", ".png", ".jpg", ".jpeg", ".gif", ".svg",
+	".ico", ".webmanifest", ".json",
+}
+
+// IsStaticAsset reports whether path names a static file.
+func IsStaticAsset(path string) bool {
+	for _, ext := range staticExtensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRouter returns an empty router.
+func NewRouter() *Router {
+	return &Router{mux: http.NewServeMux(), routes: map[string]*route{}}
+}
+
+// Handle serves pattern, with http.ServeMux's rules: a pattern ending in
+// a slash serves everything under it.
+func (rt *Router) Handle(pattern string, h http.Handler, opts ...RouteOption) {
+	rt.routes[pattern] = &route{pattern: pattern, opts: combine(opts), handler: h}
+	rt.mux.Handle(pattern, h)
+}
+
+// HandleFunc serves pattern with a handler function.
+func (rt *Router) HandleFunc(pattern string, h http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(pattern, h, opts...)
+}
+
+// HandlePrefix serves every path starting with prefix, for paths such as
+// /@username that http.ServeMux can't match.
+func (rt *Router) HandlePrefix(prefix string, h http.HandlerFunc, opts ...RouteOption) {
+	rt.prefixes = append(rt.prefixes, &route{pattern: prefix, opts: combine(opts), handler: h})
+	sort.SliceStable(rt.prefixes, func(i, j int) bool { return len(rt.prefixes[i].pattern) > len(rt.prefixes[j].pattern) })
+}
+
+func combine(opts []RouteOption) RouteOption {
+	var o RouteOption
+	for _, opt := range opts {
+		o |= opt
+	}
+	return o
+}
+
+// Mount adds the routes of each module.
+func (rt *Router) Mount(modules ...RouteRegistrar) {
+	for _, m := range modules {
+		m.RegisterRoutes(rt)
+	}
+}
+
+// Wrap adds middleware run for every request, static assets included.
+// The first added runs first.
+func (rt *Router) Wrap(mw ...Middleware) {
+	rt.wrap = append(rt.wrap, mw...)
+}
+
+// Use adds middleware run for pages and APIs, after Wrap's. The first
+// added runs first.
+func (rt *Router) Use(mw ...Middleware) {
+	rt.use = append(rt.use, mw...)
+}
+
+// lookup returns the route serving path, or nil.
+func (rt *Router) lookup(path string) *route {
+	for _, p := range rt.prefixes {
+		if strings.HasPrefix(path, p.pattern) {
+			return p
+		}
+	}
+	if r, ok := rt.routes[path]; ok {
+		return r
+	}
+	var best *route
+	for pattern, r := range rt.routes {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) && (best == nil || len(pattern) > len(best.pattern)) {
+			best = r
+		}
+	}
+	return best
+}
+
+// Options returns the options declared for the route serving path.
+func (rt *Router) Options(path string) RouteOption {
+	if r := rt.lookup(path); r != nil {
+		return r.opts
+	}
+	return 0
+}
+
+// ServeHTTP serves a request through the middleware to its route.
+// Trailing slashes are dropped, so /news/ is /news.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.once.Do(func() {
+		pages := chain(rt.use, http.HandlerFunc(rt.Dispatch))
+		rt.handler = chain(rt.wrap, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v := len(r.URL.Path); v > 1 && strings.HasSuffix(r.URL.Path, "/") {
+				r.URL.Path = r.URL.Path[:v-1]
+			}
+			if IsStaticAsset(r.URL.Path) && rt.Options(r.URL.Path)&Dynamic == 0 {
+				rt.mux.ServeHTTP(w, r)
+				return
+			}
+			pages.ServeHTTP(w, r)
+		}))
+	})
+	rt.handler.ServeHTTP(w, r)
+}
+
+// Dispatch serves a request straight from its route, without the
+// middleware.
+func (rt *Router) Dispatch(w http.ResponseWriter, r *http.Request) {
+	for _, p := range rt.prefixes {
+		if strings.HasPrefix(r.URL.Path, p.pattern) {
+			p.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	rt.mux.ServeHTTP(w, r)
+}
+
+// chain wraps h in mw, the first outermost.
+func chain(mw []Middleware, h http.Handler) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// Moved redirects permanently from paths under oldPrefix to the same
+// paths under newPrefix, so browsers and crawlers update old links.
+func Moved(oldPrefix, newPrefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := newPrefix + strings.TrimPrefix(r.URL.Path, oldPrefix)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}
+}
+
+// Internal serves requests Mu makes of itself, such as MCP tools that
+// call the REST API, straight from their routes: the caller has already
+// authenticated and charged for them. Set in main to Router.Dispatch.
+var Internal http.HandlerFunc = http.NotFound
+
+// PaymentAuth, when set, lets a request without a session through to an
+// Authenticated route by paying for it instead, returning the request to
+// serve. Set in main to x402.
+var PaymentAuth func(r *http.Request) (*http.Request, bool)
+
+// RequestToken returns the session or API token a request carries, from
+// the session cookie, the Authorization header or X-Micro-Token.
+func RequestToken(r *http.Request) string {
+	if c, err := r.Cookie("session"); err == nil && c.Value != "" {
+		return c.Value
+	}
+	if h := r.Header.Get("Authorization"); h != "" {
+		// Support both "Bearer <token>" and just "<token>"
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return r.Header.Get("X-Micro-Token")
+}
+
+// Authenticate turns away requests for Authenticated routes without a
+// valid session or token.
+func (rt *Router) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rt.Options(r.URL.Path)&Authenticated == 0 || auth.ValidateToken(RequestToken(r)) == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if PaymentAuth != nil {
+			if paid, ok := PaymentAuth(r); ok {
+				next.ServeHTTP(w, paid)
+				return
+			}
+		}
+		if SendsJSON(r) || WantsJSON(r) {
+			RespondError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+}
+
+// CSRF sets the CSRF cookie on every response and checks the token on
+// writes, except for routes declared SkipCSRF, requests authenticated by
+// a header rather than a cookie, and ActivityPub inboxes.
+func (rt *Router) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.SetCSRFCookie(w, r)
+		if r.Method != "GET" && r.Method != "HEAD" && r.Method != "OPTIONS" {
+			bearer := r.Header.Get("Authorization") != "" || r.Header.Get("X-Micro-Token") != ""
+			inbox := strings.HasSuffix(r.URL.Path, "/inbox")
+			if !bearer && !inbox && rt.Options(r.URL.Path)&SkipCSRF == 0 && !auth.ValidCSRF(r) {
+				RespondError(w, http.StatusForbidden, "invalid CSRF token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // RouteOpts defines handlers for different content types
 type RouteOpts struct {
 	// JSON handler - called when Accept: application/json or Content-Type: application/json
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	rt := NewRouter()
+	serve := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, name)
+		}
+	}
+	rt.HandleFunc("/qa", serve("qa"))
+	rt.HandleFunc("/qa/new", serve("qa/new"), Authenticated)
+	rt.HandleFunc("/docs/", serve("docs"))
+	rt.HandleFunc("/login", serve("login"), SkipCSRF)
+	rt.HandlePrefix("/@", serve("profile"))
+	rt.HandleFunc("/", serve("root"))
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	rt.Wrap(mark("wrap"), Gzip)
+	rt.Use(mark("use"), rt.Authenticate)
+
+	do := func(method, path string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		order = nil
+		rt.ServeHTTP(w, r)
+		return w
+	}
+
+	for path, want := range map[string]string{
+		"/qa":        "qa",
+		"/qa/":       "qa",
+		"/docs/auth": "docs",
+		"/@alice":    "profile",
+		"/nowhere":   "root",
+	} {
+		if w := do("GET", path); w.Body.String() != want {
+			t.Errorf("GET %s served %q, want %q", path, w.Body.String(), want)
+		}
+	}
+	if strings.Join(order, ",") != "wrap,use" {
+		t.Errorf("middleware ran %v", order)
+	}
+	do("GET", "/mu.css")
+	if strings.Join(order, ",") != "wrap" {
+		t.Errorf("static asset went through %v", order)
+	}
+
+	if w := do("GET", "/qa/new"); w.Code != http.StatusFound {
+		t.Errorf("signed out /qa/new = %d, want a redirect", w.Code)
+	}
+	if w := do("GET", "/qa/new", "Accept", "application/json"); w.Code != http.StatusUnauthorized {
+		t.Errorf("signed out API /qa/new = %d, want 401", w.Code)
+	}
+	if rt.Options("/login")&SkipCSRF == 0 || rt.Options("/docs/auth") != 0 {
+		t.Error("route options not found")
+	}
+
+	w := do("GET", "/qa", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("response not compressed")
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != "qa" {
+		t.Errorf("compressed body = %q", b)
+	}
+}
@@ -0,0 +1,60 @@
+package app
+
+import "mu/internal/auth"
+
+func init() { Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *Router) {
+	// Sign in and sign up, where there's no session yet.
+	r.HandleFunc("/login", Login, SkipCSRF)
+	r.HandleFunc("/login/link", MagicLink, SkipCSRF)
+	r.HandleFunc("/signup", Signup, SkipCSRF)
+	r.HandleFunc("/request-invite", RequestInvite, SkipCSRF)
+	r.HandleFunc("/invite", InviteHandler)
+	r.HandleFunc("/recover", Recover)                   // start and follow account recovery
+	r.HandleFunc("/reset", Reset)                       // password reset by email
+	r.HandleFunc("/verify", Verify)                     // the token in the URL is the credential
+	r.HandleFunc("/session", Session)                   // used to check auth status
+	r.HandleFunc("/passkey/", PasskeyHandler, SkipCSRF) // auth checked in the handler
+	r.HandleFunc("/logout", Logout, Authenticated)
+
+	r.HandleFunc("/account", Account, Authenticated)
+	r.HandleFunc("/account/export", ExportHandler, Authenticated)
+	r.HandleFunc("/account/recovery", RecoverySettings, Authenticated)
+	r.HandleFunc("/account/sessions", SessionsHandler, Authenticated)
+	r.HandleFunc("/account/tokens", TokenHandler, Authenticated)
+	r.HandleFunc("/theme", ThemeHandler) // light, dark or auto; guests too
+	r.HandleFunc("/token", TokenHandler, Authenticated)
+
+	// content controls (flag, save, dismiss, block, share)
+	r.HandleFunc("/app/", ControlsHandler)
+	// read-it-later bookmarks
+	r.HandleFunc("/saved", SavedHandler, Authenticated)
+	r.HandleFunc("/archive", ArchiveHandler, Audited)
+	// Markdown preview for the blog write and edit forms
+	r.HandleFunc("/render", RenderHandler, Authenticated)
+	// public status page - service health checks
+	r.HandleFunc("/status", StatusHandler)
+	// the generated service worker, for offline use when installed
+	r.HandleFunc("/sw.js", ServiceWorker)
+	// Content-Security-Policy violation reports from browsers
+	r.HandleFunc("/csp-report", CSPReport, SkipCSRF)
+
+	// Google sign-in (Mu as an OAuth client of Google).
+	r.HandleFunc("/oauth2/google", GoogleLogin)
+	r.HandleFunc("/oauth2/google/connect", GoogleConnect, Authenticated)
+	r.HandleFunc("/oauth2/callback", GoogleCallback)
+
+	// OAuth 2.1 for MCP authentication, and OpenID Connect for companion
+	// services registered in /admin/oauth.
+	r.HandleFunc("/.well-known/oauth-authorization-server", auth.OAuthMetadataHandler)
+	r.HandleFunc("/.well-known/oauth-protected-resource", auth.OAuthResourceHandler)
+	r.HandleFunc("/.well-known/openid-configuration", auth.OIDCDiscoveryHandler)
+	r.HandleFunc("/.well-known/jwks.json", auth.JWKSHandler)
+	r.HandleFunc("/oauth/register", auth.OAuthRegisterHandler, SkipCSRF)
+	r.HandleFunc("/oauth/authorize", auth.OAuthAuthorizePostHandler, SkipCSRF)
+	r.HandleFunc("/oauth/token", auth.OAuthTokenHandler, SkipCSRF)
+	r.HandleFunc("/oauth/userinfo", auth.OAuthUserInfoHandler, SkipCSRF)
+}
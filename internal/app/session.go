@@ -0,0 +1,97 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// rememberCookie holds the refresh token issued by "keep me logged in".
+const rememberCookie = "remember"
+
+// SetSessionCookie hands the browser a session, expiring the cookie with
+// the session itself. With remember set the session is also given a
+// refresh token so it outlives SESSION_TTL.
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, sess *auth.Session, remember bool) {
+	auth.SetSessionClient(sess.ID, ClientIP(r), r.UserAgent())
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    sess.Token,
+		Path:     "/",
+		MaxAge:   int(time.Until(sess.ExpiresAt()).Seconds()),
+		Secure:   requestSecure(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if remember {
+		setRememberCookie(w, r, auth.Remember(sess))
+	}
+}
+
+func setRememberCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     rememberCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(auth.RememberTTL().Seconds()),
+		Secure:   requestSecure(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookies removes the session and remember cookies and
+// revokes the refresh token, if any.
+func ClearSessionCookies(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(rememberCookie); err == nil {
+		auth.RevokeRefreshToken(c.Value)
+	}
+	// Pages kept for offline use were rendered for this account.
+	w.Header().Set("Clear-Site-Data", `"cache"`)
+	for _, name := range []string{"session", rememberCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   requestSecure(r),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// RefreshSession renews a remembered login whose session has run out. It
+// swaps in a new session and refresh token on the response, and returns
+// the request with its cookie updated so the rest of the chain sees the
+// user as logged in. Requests that don't need it are returned unchanged.
+func RefreshSession(w http.ResponseWriter, r *http.Request) *http.Request {
+	rc, err := r.Cookie(rememberCookie)
+	if err != nil || rc.Value == "" {
+		return r
+	}
+	if c, err := r.Cookie("session"); err == nil && auth.ValidateToken(c.Value) == nil {
+		return r
+	}
+	sess, token, err := auth.Refresh(rc.Value)
+	if err != nil {
+		http.SetCookie(w, &http.Cookie{Name: rememberCookie, Value: "", Path: "/", MaxAge: -1})
+		return r
+	}
+	SetSessionCookie(w, r, sess, false)
+	setRememberCookie(w, r, token)
+
+	// Replace the stale session cookie for downstream handlers.
+	r2 := r.Clone(r.Context())
+	var kept []string
+	for _, c := range r.Cookies() {
+		if c.Name != "session" {
+			kept = append(kept, c.Name+"="+c.Value)
+		}
+	}
+	kept = append(kept, "session="+sess.Token)
+	r2.Header.Set("Cookie", strings.Join(kept, "; "))
+	return r2
+}
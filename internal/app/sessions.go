@@ -0,0 +1,144 @@
+package app
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"strings"
+
+	"mu/internal/auth"
+)
+
+// SessionsHandler serves /account/sessions, the devices logged in to the
+// signed-in account:
+//
+//	GET                        the list, with where and when each was last used
+//	POST action=revoke id=X    logs out one session
+//	POST action=everywhere     logs out every session, this one included
+func SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	sess, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if WantsJSON(r) || SendsJSON(r) {
+			Unauthorized(w, r)
+		} else {
+			RedirectToLogin(w, r)
+		}
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		list := auth.AccountSessions(acc.ID)
+		if WantsJSON(r) {
+			RespondJSON(w, map[string]interface{}{"sessions": sessionViews(list, sess.ID)})
+			return
+		}
+		w.Write([]byte(RenderHTMLForRequest("Sessions", "Where you're logged in", sessionsPage(list, sess.ID), r)))
+		return
+	case "POST":
+	default:
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	var req struct {
+		Action string `json:"action"`
+		ID     string `json:"id"`
+	}
+	if SendsJSON(r) {
+		if err := DecodeJSON(r, &req); err != nil {
+			BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Action, req.ID = r.FormValue("action"), r.FormValue("id")
+	}
+
+	loggedOut := false
+	switch req.Action {
+	case "revoke":
+		if err := auth.RevokeSession(acc.ID, req.ID); err != nil {
+			BadRequest(w, r, err.Error())
+			return
+		}
+		Log("auth", "%s logged out session %s", acc.ID, req.ID)
+		loggedOut = req.ID == sess.ID
+	case "everywhere":
+		n := auth.RevokeSessions(acc.ID)
+		Log("auth", "%s logged out everywhere (%d sessions)", acc.ID, n)
+		loggedOut = true
+	default:
+		BadRequest(w, r, "unknown action")
+		return
+	}
+
+	if loggedOut && sess.Type == "account" {
+		ClearSessionCookies(w, r)
+	}
+	if SendsJSON(r) || WantsJSON(r) {
+		RespondJSON(w, map[string]bool{"success": true})
+		return
+	}
+	if loggedOut {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+}
+
+// sessionViews trims sessions to what their owner may see: never the token.
+func sessionViews(list []auth.Session, current string) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, s := range list {
+		out = append(out, map[string]interface{}{
+			"id":         s.ID,
+			"device":     deviceName(s.UserAgent),
+			"user_agent": s.UserAgent,
+			"ip":         s.IP,
+			"created":    s.Created,
+			"last_seen":  s.LastUsed(),
+			"expires":    s.ExpiresAt(),
+			"remember":   s.Remember,
+			"current":    s.ID == current,
+		})
+	}
+	return out
+}
+
+// sessionsPage renders the session list.
+func sessionsPage(list []auth.Session, current string) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="card">
+<h4>Where you're logged in</h4>
+<p class="text-sm text-muted">Log out any device you don't recognise, then change your password.</p>`)
+	if len(list) == 0 {
+		sb.WriteString(`<p class="text-muted">No active sessions.</p>`)
+	} else {
+		sb.WriteString(`<table class="email-log"><tr><th>Device</th><th class="hide-mobile">IP</th><th>Last seen</th><th class="hide-mobile">Signed in</th><th></th></tr>`)
+		for _, s := range list {
+			device := "Unknown device"
+			if s.UserAgent != "" {
+				device = deviceName(s.UserAgent)
+			}
+			device = htmlpkg.EscapeString(device)
+			if s.ID == current {
+				device += ` <span class="text-muted text-sm">(this device)</span>`
+			}
+			ip := s.IP
+			if ip == "" {
+				ip = "—"
+			}
+			fmt.Fprintf(&sb, `<tr><td title="%s">%s</td><td class="hide-mobile">%s</td><td>%s</td><td class="hide-mobile">%s</td><td><form method="POST" action="/account/sessions"><input type="hidden" name="action" value="revoke"><input type="hidden" name="id" value="%s"><button type="submit" class="btn-link text-error">Log out</button></form></td></tr>`,
+				htmlpkg.EscapeString(s.UserAgent), device, htmlpkg.EscapeString(ip), TimeAgo(s.LastUsed()), TimeAgo(s.Created), htmlpkg.EscapeString(s.ID))
+		}
+		sb.WriteString(`</table>`)
+	}
+	sb.WriteString(`<form method="POST" action="/account/sessions" class="mt-3" onsubmit="return confirm('Log out of every device, including this one?')">
+	<input type="hidden" name="action" value="everywhere">
+	<button type="submit">Log out everywhere</button>
+</form>
+</div>
+<p><a href="/account">← Account</a></p>`)
+	return sb.String()
+}
@@ -1,38 +1,309 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"mu/internal/data"
 )
 
-const sysLogMaxEntries = 500
+// The system log. Every package logs through Log, or a structured
+// Logger, tagged with its name; entries go to stdout and the last few
+// hundred are kept in memory for /admin/logs. Each package has its own
+// level, LOG_LEVEL by default, which an admin can change while the
+// server runs. Requests carry an ID (see RequestIDs), so the entries
+// logged while serving one can be found together.
+
+// defaultSysLogEntries is how many entries are kept unless LOG_RETAIN
+// says otherwise.
+const defaultSysLogEntries = 500
 
 // SysLogEntry is a single system log line.
 type SysLogEntry struct {
-	Time    time.Time
-	Package string
-	Message string
+	Time      time.Time         `json:"time"`
+	Level     slog.Level        `json:"level"`
+	Package   string            `json:"package"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"request_id,omitempty"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
 }
 
 var (
 	sysLogMu      sync.Mutex
 	sysLogEntries []*SysLogEntry
+	sysLogSeen    = map[string]bool{} // packages that have logged
+
+	logLevelsOnce sync.Once
+	logLevels     = map[string]slog.Level{} // per package, set in /admin/logs
 )
 
-// appendSysLog stores a log message in the in-memory ring buffer.
-func appendSysLog(pkg, format string, args ...interface{}) {
-	entry := &SysLogEntry{
-		Time:    time.Now(),
-		Package: pkg,
-		Message: fmt.Sprintf(format, args...),
+// sysLogRetain returns how many entries to keep.
+func sysLogRetain() int {
+	if n, err := strconv.Atoi(os.Getenv("LOG_RETAIN")); err == nil && n > 0 {
+		return n
+	}
+	return defaultSysLogEntries
+}
+
+// ParseLevel reads a level name: debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return l, err
+}
+
+// DefaultLogLevel is the level of packages without one of their own,
+// from LOG_LEVEL, or info.
+func DefaultLogLevel() slog.Level {
+	if l, err := ParseLevel(os.Getenv("LOG_LEVEL")); err == nil {
+		return l
 	}
+	return slog.LevelInfo
+}
+
+// loadLogLevels reads the levels saved in /admin/logs. Called with
+// sysLogMu held.
+func loadLogLevels() {
+	logLevelsOnce.Do(func() {
+		var saved map[string]string
+		if err := data.LoadJSON("log_levels.json", &saved); err != nil {
+			return
+		}
+		for pkg, name := range saved {
+			if l, err := ParseLevel(name); err == nil {
+				logLevels[pkg] = l
+			}
+		}
+	})
+}
+
+// LogLevel returns the level a package logs at.
+func LogLevel(pkg string) slog.Level {
+	sysLogMu.Lock()
+	defer sysLogMu.Unlock()
+	return logLevel(pkg)
+}
+
+// logLevel is LogLevel with sysLogMu held.
+func logLevel(pkg string) slog.Level {
+	loadLogLevels()
+	if l, ok := logLevels[pkg]; ok {
+		return l
+	}
+	return DefaultLogLevel()
+}
+
+// SetLogLevel sets the level a package logs at, and saves it. A nil level
+// puts it back to the default.
+func SetLogLevel(pkg string, level *slog.Level) error {
 	sysLogMu.Lock()
-	sysLogEntries = append(sysLogEntries, entry)
-	if len(sysLogEntries) > sysLogMaxEntries {
-		sysLogEntries = sysLogEntries[len(sysLogEntries)-sysLogMaxEntries:]
+	loadLogLevels()
+	if level == nil {
+		delete(logLevels, pkg)
+	} else {
+		logLevels[pkg] = *level
+	}
+	saved := make(map[string]string, len(logLevels))
+	for p, l := range logLevels {
+		saved[p] = l.String()
 	}
 	sysLogMu.Unlock()
+	return data.SaveJSON("log_levels.json", saved)
+}
+
+// LogLevels returns the level of every package that has logged or has a
+// level of its own.
+func LogLevels() map[string]slog.Level {
+	sysLogMu.Lock()
+	defer sysLogMu.Unlock()
+	loadLogLevels()
+	levels := map[string]slog.Level{}
+	for pkg := range sysLogSeen {
+		levels[pkg] = logLevel(pkg)
+	}
+	for pkg, l := range logLevels {
+		levels[pkg] = l
+	}
+	return levels
+}
+
+// logEnabled reports whether pkg logs at level.
+func logEnabled(pkg string, level slog.Level) bool {
+	sysLogMu.Lock()
+	defer sysLogMu.Unlock()
+	sysLogSeen[pkg] = true
+	return level >= logLevel(pkg)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying a request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// writeLog prints an entry and keeps it in the ring buffer.
+func writeLog(e *SysLogEntry) {
+	if !cliMode {
+		color := pkgColors[e.Package]
+		if color == "" {
+			color = colorWhite
+		}
+		var line strings.Builder
+		fmt.Fprintf(&line, "%s[%s %s]%s ", color, e.Time.Format("15:04:05"), e.Package, colorReset)
+		if e.Level != slog.LevelInfo {
+			line.WriteString(e.Level.String() + " ")
+		}
+		line.WriteString(e.Message)
+		keys := make([]string, 0, len(e.Attrs))
+		for k := range e.Attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&line, " %s=%q", k, e.Attrs[k])
+		}
+		if e.RequestID != "" {
+			line.WriteString(" rid=" + e.RequestID)
+		}
+		fmt.Println(line.String())
+	}
+
+	sysLogMu.Lock()
+	sysLogEntries = append(sysLogEntries, e)
+	if max := sysLogRetain(); len(sysLogEntries) > max {
+		sysLogEntries = sysLogEntries[len(sysLogEntries)-max:]
+	}
+	sysLogMu.Unlock()
+}
+
+// logf logs a formatted message for pkg at level.
+func logf(ctx context.Context, level slog.Level, pkg, format string, args ...interface{}) {
+	if !logEnabled(pkg, level) {
+		return
+	}
+	writeLog(&SysLogEntry{
+		Time:      time.Now(),
+		Level:     level,
+		Package:   pkg,
+		Message:   fmt.Sprintf(format, args...),
+		RequestID: RequestID(ctx),
+	})
+}
+
+// LogWarn logs something that went wrong but was handled.
+func LogWarn(pkg string, format string, args ...interface{}) {
+	logf(context.Background(), slog.LevelWarn, pkg, format, args...)
+}
+
+// LogError logs something that failed.
+func LogError(pkg string, format string, args ...interface{}) {
+	logf(context.Background(), slog.LevelError, pkg, format, args...)
+}
+
+// LogDebug logs detail that's only wanted when looking into a problem.
+func LogDebug(pkg string, format string, args ...interface{}) {
+	logf(context.Background(), slog.LevelDebug, pkg, format, args...)
+}
+
+// Logger returns a structured logger for pkg that writes to the system
+// log. Logging with a request's context records its ID:
+//
+//	app.Logger("mail").InfoContext(r.Context(), "sent", "to", addr)
+func Logger(pkg string) *slog.Logger {
+	return slog.New(&logHandler{pkg: pkg})
+}
+
+// logHandler is the slog.Handler behind Logger.
+type logHandler struct {
+	pkg   string
+	group string
+	attrs []slog.Attr
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return logEnabled(h.pkg, level)
+}
+
+func (h *logHandler) Handle(ctx context.Context, rec slog.Record) error {
+	e := &SysLogEntry{
+		Time:      rec.Time,
+		Level:     rec.Level,
+		Package:   h.pkg,
+		Message:   rec.Message,
+		RequestID: RequestID(ctx),
+	}
+	if rec.NumAttrs()+len(h.attrs) > 0 {
+		e.Attrs = map[string]string{}
+	}
+	for _, a := range h.attrs {
+		addAttr(e.Attrs, "", a)
+	}
+	rec.Attrs(func(a slog.Attr) bool {
+		addAttr(e.Attrs, h.group, a)
+		return true
+	})
+	writeLog(e)
+	return nil
+}
+
+// addAttr flattens an attribute into attrs, groups as dotted keys.
+func addAttr(attrs map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, g := range a.Value.Group() {
+			addAttr(attrs, key, g)
+		}
+		return
+	}
+	attrs[key] = a.Value.String()
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		if h.group != "" {
+			a = slog.Group(h.group, a)
+		}
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+	h2.group = name
+	return &h2
 }
 
 // GetSysLog returns a copy of the system log in reverse-chronological order.
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestSysLog(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LOG_LEVEL", "")
+	sysLogMu.Lock()
+	savedEntries, savedLevels := sysLogEntries, logLevels
+	sysLogEntries, logLevels = nil, map[string]slog.Level{}
+	sysLogMu.Unlock()
+	t.Cleanup(func() {
+		sysLogMu.Lock()
+		sysLogEntries, logLevels = savedEntries, savedLevels
+		sysLogMu.Unlock()
+	})
+
+	LogDebug("testpkg", "hidden at info")
+	Log("testpkg", "shown %d", 1)
+	debug := slog.LevelDebug
+	if err := SetLogLevel("testpkg", &debug); err != nil {
+		t.Fatal(err)
+	}
+	LogDebug("testpkg", "shown now")
+
+	var rid string
+	h := RequestIDs(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rid = RequestID(r.Context())
+		Logger("testpkg").WarnContext(r.Context(), "slow", "ms", 1200, slog.Group("db", "table", "posts"))
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if rid == "" || w.Header().Get("X-Request-ID") != rid {
+		t.Fatalf("request ID %q, header %q", rid, w.Header().Get("X-Request-ID"))
+	}
+
+	entries := GetSysLog()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if e := entries[0]; e.Level != slog.LevelWarn || e.RequestID != rid || e.Attrs["ms"] != "1200" || e.Attrs["db.table"] != "posts" {
+		t.Errorf("structured entry = %+v", e)
+	}
+	if entries[1].Message != "shown now" || entries[2].Message != "shown 1" {
+		t.Errorf("entries = %q, %q", entries[1].Message, entries[2].Message)
+	}
+
+	// Levels are saved, and a nil level goes back to the default.
+	logLevels = map[string]slog.Level{}
+	logLevelsOnce = sync.Once{}
+	if LogLevel("testpkg") != slog.LevelDebug {
+		t.Error("level not saved")
+	}
+	SetLogLevel("testpkg", nil)
+	if Logger("testpkg").Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("level not reset")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "edge-42")
+	RequestIDs(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { rid = RequestID(r.Context()) })).ServeHTTP(httptest.NewRecorder(), req)
+	if rid != "edge-42" {
+		t.Errorf("proxy's request ID not kept: %q", rid)
+	}
+}
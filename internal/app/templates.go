@@ -0,0 +1,162 @@
+package app
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pages are moving from fmt.Sprintf to html/template so what users write
+// is escaped by default. A module embeds its own templates and parses
+// them with ParseTemplates, which adds the shared partials in
+// templates/partials.html (cards, forms, pagination, back links) and the
+// helper functions below. The rendered body goes into the site layout,
+// the same as any other page.
+
+//go:embed templates/*.html
+var partialFiles embed.FS
+
+// Theme maps the names the shared partials use for their classes to the
+// classes in mu.css. SetTheme changes them, so an instance can restyle
+// every templated page from its own stylesheet.
+type Theme map[string]string
+
+var defaultTheme = Theme{
+	"card":        "card",
+	"title":       "card-title",
+	"meta":        "card-meta",
+	"preview":     "card-desc",
+	"muted":       "text-muted",
+	"error":       "text-error",
+	"empty":       "text-muted p-5",
+	"button":      "btn",
+	"button-alt":  "btn-secondary",
+	"button-link": "btn-inline text-sm text-muted",
+	"load-more":   "load-more btn-secondary",
+}
+
+var (
+	themeMu sync.RWMutex
+	theme   = defaultTheme
+)
+
+// SetTheme overrides the classes for the names in t. Names it doesn't
+// mention keep their defaults.
+func SetTheme(t Theme) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+	merged := Theme{}
+	for k, v := range defaultTheme {
+		merged[k] = v
+	}
+	for k, v := range t {
+		merged[k] = v
+	}
+	theme = merged
+}
+
+// themeClass returns the class for name, or name itself if the theme
+// doesn't define it.
+func themeClass(name string) string {
+	themeMu.RLock()
+	defer themeMu.RUnlock()
+	if c, ok := theme[name]; ok {
+		return c
+	}
+	return name
+}
+
+// Pagination is what the "pagination" partial renders: links to the
+// previous and next pages, either of which may be empty. List is the id
+// of the element the next page is appended to by mu.js.
+type Pagination struct {
+	List string
+	Prev string
+	Next string
+}
+
+// templateFuncs are available in every template.
+var templateFuncs = template.FuncMap{
+	"theme": themeClass,
+	// t looks up an interface string: {{t .Lang "mail.no_drafts"}}.
+	"t":       T,
+	"dir":     Dir,
+	"timeAgo": TimeAgo,
+	"rfc3339": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"date":    func(t time.Time) string { return t.UTC().Format("2 Jan 2006 15:04 MST") },
+	"truncate": func(n int, s string) string {
+		s = strings.TrimSpace(s)
+		if r := []rune(s); len(r) > n {
+			return string(r[:n]) + "..."
+		}
+		return s
+	},
+	"default": func(fallback, s string) string {
+		if strings.TrimSpace(s) == "" {
+			return fallback
+		}
+		return s
+	},
+	// dict builds the data for a partial: {{template "card" dict "Title" .Title}}.
+	"dict": func(kv ...interface{}) map[string]interface{} {
+		m := make(map[string]interface{}, len(kv)/2)
+		for i := 0; i+1 < len(kv); i += 2 {
+			if k, ok := kv[i].(string); ok {
+				m[k] = kv[i+1]
+			}
+		}
+		return m
+	},
+}
+
+// Templates is a module's parsed templates, with the shared partials.
+type Templates struct {
+	t *template.Template
+}
+
+// ParseTemplates parses the files in fsys matching patterns along with
+// the shared partials. It panics on a bad template, since they're
+// embedded and parsed at startup.
+func ParseTemplates(fsys fs.FS, patterns ...string) *Templates {
+	t := template.Must(template.New("").Funcs(templateFuncs).ParseFS(partialFiles, "templates/*.html"))
+	if len(patterns) > 0 {
+		t = template.Must(t.ParseFS(fsys, patterns...))
+	}
+	return &Templates{t: t}
+}
+
+// Render executes the named template and returns the HTML.
+func (t *Templates) Render(name string, data interface{}) (string, error) {
+	var b bytes.Buffer
+	if err := t.t.ExecuteTemplate(&b, name, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// HTML is Render for callers that build a larger page: a failure is
+// logged and renders nothing.
+func (t *Templates) HTML(name string, data interface{}) string {
+	s, err := t.Render(name, data)
+	if err != nil {
+		Log("app", "Template %s: %v", name, err)
+		return ""
+	}
+	return s
+}
+
+// Page renders the named template into the site layout and writes it.
+func (t *Templates) Page(w http.ResponseWriter, r *http.Request, title, description, name string, data interface{}) {
+	body, err := t.Render(name, data)
+	if err != nil {
+		Log("app", "Template %s: %v", name, err)
+		ServerError(w, r, "Something went wrong rendering this page")
+		return
+	}
+	w.Write([]byte(RenderHTMLForRequest(title, description, body, r)))
+}
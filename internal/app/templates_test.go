@@ -0,0 +1,41 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplatesEscapeAndTheme(t *testing.T) {
+	fsys := fstest.MapFS{"t/item.html": {Data: []byte(
+		`{{define "item"}}<div class="{{theme "card"}}">{{.Title}}</div>` +
+			`{{template "action-form" dict "Action" "/x" "Label" "Delete" "Confirm" .Title "Fields" (dict "id" .Title)}}` +
+			`{{template "pagination" .Pages}}{{end}}`)}}
+	tpl := ParseTemplates(fsys, "t/*.html")
+
+	data := map[string]interface{}{
+		"Title": `<script>alert("x")</script>'`,
+		"Pages": Pagination{List: "items", Next: "/items?after=3"},
+	}
+	out, err := tpl.Render("item", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "<script>") {
+		t.Fatalf("title not escaped: %s", out)
+	}
+	if !strings.Contains(out, `class="card"`) || !strings.Contains(out, `href="/items?after=3"`) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	SetTheme(Theme{"card": "panel"})
+	defer SetTheme(nil)
+	out, _ = tpl.Render("item", data)
+	if !strings.Contains(out, `class="panel"`) || !strings.Contains(out, `class="btn-inline`) {
+		t.Fatalf("theme not applied: %s", out)
+	}
+
+	if _, err := tpl.Render("missing", nil); err == nil {
+		t.Fatal("rendering an unknown template should fail")
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Built-in TLS, so a self-hosted server can face the internet without a
+// proxy in front. Certificates come from Let's Encrypt over ACME, for the
+// domains given, and are cached under ~/.mu/certs and renewed before they
+// expire. The plain HTTP server answers the ACME challenges and sends
+// everything else to https.
+
+// hstsMaxAge is how long browsers remember to use https: two years.
+const hstsMaxAge = "max-age=63072000; includeSubDomains"
+
+// TLSDomains splits a comma separated list of domains, dropping any
+// scheme, port or path so MU_DOMAIN can be used as it is.
+func TLSDomains(list string) []string {
+	var domains []string
+	for _, d := range strings.Split(list, ",") {
+		d = strings.TrimSpace(d)
+		d = strings.TrimPrefix(strings.TrimPrefix(d, "https://"), "http://")
+		d, _, _ = strings.Cut(d, "/")
+		if host, _, ok := strings.Cut(d, ":"); ok {
+			d = host
+		}
+		if d != "" {
+			domains = append(domains, strings.ToLower(d))
+		}
+	}
+	return domains
+}
+
+// ACME returns a manager that gets and renews certificates for domains.
+// ACME_EMAIL, if set, is given to Let's Encrypt for expiry notices.
+func ACME(domains []string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(filepath.Join(os.ExpandEnv("$HOME/.mu"), "certs")),
+		Email:      os.Getenv("ACME_EMAIL"),
+	}
+}
+
+// HSTS tells browsers to only use https from now on. It's only sent over
+// https, as the standard says.
+func HSTS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", hstsMaxAge)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
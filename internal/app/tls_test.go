@@ -0,0 +1,30 @@
+package app
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTLSDomains(t *testing.T) {
+	got := TLSDomains(" https://Mu.Example/ , www.mu.example:443,,")
+	if want := []string{"mu.example", "www.mu.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TLSDomains = %v, want %v", got, want)
+	}
+
+	h := HSTS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS sent over http")
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Header().Get("Strict-Transport-Security") == "" {
+		t.Error("HSTS not sent over https")
+	}
+}
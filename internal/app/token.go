@@ -33,7 +33,8 @@ func getFlash(sessionID, key string) string {
 	return v
 }
 
-// TokenHandler manages Personal Access Tokens (PATs)
+// TokenHandler manages Personal Access Tokens (PATs), at /account/tokens
+// and, for API clients, /token
 // GET /token - List all tokens for the authenticated user
 // POST /token - Create a new token
 // DELETE /token?id={id} - Delete a token
@@ -63,12 +64,12 @@ func TokenHandler(w http.ResponseWriter, r *http.Request) {
 			// Store credentials in session flash (not URL)
 			setFlash(sess.ID, "client_id", client.ClientID)
 			setFlash(sess.ID, "client_secret", client.ClientSecret)
-			http.Redirect(w, r, "/token?created=1", http.StatusSeeOther)
+			http.Redirect(w, r, "/account/tokens?created=1", http.StatusSeeOther)
 			return
 		}
 		if clientID := r.URL.Query().Get("delete_client"); clientID != "" && r.FormValue("_method") == "DELETE" {
 			auth.DeleteOAuthClient(clientID)
-			http.Redirect(w, r, "/token", http.StatusSeeOther)
+			http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
 			return
 		}
 		if r.FormValue("_method") == "DELETE" {
@@ -134,14 +135,14 @@ func handleTokenPage(w http.ResponseWriter, r *http.Request, accountID, sessionI
 	}
 	for _, c := range oauthClients {
 		sb.WriteString(fmt.Sprintf(`<tr><td data-label="Name">%s</td><td data-label="Client ID"><code>%s</code></td><td data-label="Created">%s</td><td>
-			<form method="POST" action="/token?delete_client=%s" style="display:inline" onsubmit="return confirm('Delete?')">
+			<form method="POST" action="/account/tokens?delete_client=%s" style="display:inline" onsubmit="return confirm('Delete?')">
 			<input type="hidden" name="_method" value="DELETE"><button type="submit" style="font-size:13px">Delete</button></form></td></tr>`,
 			c.Name, c.ClientID, c.CreatedAt.Format("2 Jan 2006"), c.ClientID))
 	}
 	sb.WriteString(`</tbody></table>`)
 
 	sb.WriteString(`<h4 style="margin-top:20px">Create OAuth Client</h4>`)
-	sb.WriteString(`<form method="POST" action="/token?create_client=1">`)
+	sb.WriteString(`<form method="POST" action="/account/tokens?create_client=1">`)
 	sb.WriteString(`<div style="margin-bottom:10px"><input type="text" name="client_name" placeholder="e.g. Claude" required></div>`)
 	sb.WriteString(`<button type="submit">Create Client</button></form>`)
 
@@ -149,13 +150,13 @@ func handleTokenPage(w http.ResponseWriter, r *http.Request, accountID, sessionI
 
 	// === Personal Access Tokens ===
 	sb.WriteString(`<h3>Personal Access Tokens</h3>`)
-	sb.WriteString(`<p style="color:#666;font-size:13px">For API authentication. Use with <code>Authorization: Bearer TOKEN</code> header.</p>`)
+	sb.WriteString(`<p style="color:#666;font-size:13px">For API authentication. Use with <code>Authorization: Bearer TOKEN</code> header. A token can only do what its scopes allow.</p>`)
 
 	sb.WriteString(`<div id="token-result" style="display:none;margin:20px 0;padding:15px;background:#d4edda;border:1px solid #c3e6cb;border-radius:5px">`)
 	sb.WriteString(`<strong>Token Created</strong><p>Copy this token now — you won't see it again:</p>`)
 	sb.WriteString(`<pre id="new-token" style="background:#fff;padding:10px;border:1px solid #c3e6cb;border-radius:3px;overflow-x:auto;white-space:pre-wrap;word-break:break-all"></pre></div>`)
 
-	sb.WriteString(`<table class="token-table"><thead><tr><th>Name</th><th>Permissions</th><th>Last Used</th><th>Expires</th><th></th></tr></thead><tbody>`)
+	sb.WriteString(`<table class="token-table"><thead><tr><th>Name</th><th>Scopes</th><th>Last Used</th><th>Expires</th><th></th></tr></thead><tbody>`)
 	tokens := auth.ListTokens(accountID)
 	if len(tokens) == 0 {
 		sb.WriteString(`<tr><td colspan="5" style="padding:20px;text-align:center;color:#666">No tokens yet.</td></tr>`)
@@ -169,8 +170,8 @@ func handleTokenPage(w http.ResponseWriter, r *http.Request, accountID, sessionI
 		if !token.LastUsed.IsZero() {
 			lastUsed = TimeAgo(token.LastUsed)
 		}
-		sb.WriteString(fmt.Sprintf(`<tr><td data-label="Name">%s</td><td data-label="Permissions">%s</td><td data-label="Last Used">%s</td><td data-label="Expires">%s</td><td>
-			<form method="POST" action="/token?id=%s" style="display:inline" onsubmit="return confirm('Delete?')">
+		sb.WriteString(fmt.Sprintf(`<tr><td data-label="Name">%s</td><td data-label="Scopes">%s</td><td data-label="Last Used">%s</td><td data-label="Expires">%s</td><td>
+			<form method="POST" action="/account/tokens?id=%s" style="display:inline" onsubmit="return confirm('Delete?')">
 			<input type="hidden" name="_method" value="DELETE"><button type="submit" style="font-size:13px">Delete</button></form></td></tr>`,
 			token.Name, strings.Join(token.Permissions, ", "), lastUsed, expires, token.ID))
 	}
@@ -182,6 +183,13 @@ func handleTokenPage(w http.ResponseWriter, r *http.Request, accountID, sessionI
 	sb.WriteString(`<div style="margin-bottom:10px"><select name="expires_in">`)
 	sb.WriteString(`<option value="0">Never</option><option value="7">7 days</option><option value="30">30 days</option>`)
 	sb.WriteString(`<option value="90" selected>90 days</option><option value="365">1 year</option></select></div>`)
+	sb.WriteString(`<fieldset style="margin-bottom:10px"><legend>Scopes</legend>`)
+	for _, scope := range auth.TokenScopes {
+		sb.WriteString(fmt.Sprintf(`<label style="display:block;font-size:14px"><input type="checkbox" name="scope" value="%s"> <code>%s</code> %s</label>`,
+			scope.Name, scope.Name, scope.Description))
+	}
+	sb.WriteString(`<label style="display:block;font-size:14px"><input type="checkbox" name="scope" value="all"> <code>all</code> Full access — everything you can do when logged in, except managing tokens</label>`)
+	sb.WriteString(`</fieldset>`)
 	sb.WriteString(`<button type="submit">Generate Token</button></form>`)
 
 	sb.WriteString(`<p style="margin-top:20px"><a href="/account">← Account</a> · <a href="/api">API Docs</a></p>`)
@@ -190,10 +198,15 @@ func handleTokenPage(w http.ResponseWriter, r *http.Request, accountID, sessionI
 async function createToken(e) {
 	e.preventDefault();
 	var form = e.target;
+	var scopes = Array.from(form.querySelectorAll('input[name=scope]:checked')).map(function(el) { return el.value; });
+	if (!scopes.length) {
+		alert('Choose at least one scope');
+		return;
+	}
 	var res = await fetch('/token', {
 		method: 'POST',
 		headers: {'Content-Type': 'application/json'},
-		body: JSON.stringify({name: form.name.value, expires_in: parseInt(form.expires_in.value), permissions: ['read', 'write']})
+		body: JSON.stringify({name: form.name.value, expires_in: parseInt(form.expires_in.value), permissions: scopes})
 	});
 	var result = await res.json();
 	if (result.success) {
@@ -313,7 +326,11 @@ func handleCreateToken(w http.ResponseWriter, r *http.Request, accountID string)
 
 	// Default permissions if none provided
 	if len(permissions) == 0 {
-		permissions = []string{"read", "write"}
+		permissions = []string{auth.ScopeRead, auth.ScopeWrite}
+	}
+	if err := auth.CheckScopes(permissions); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	// Calculate expiration
@@ -369,6 +386,6 @@ func handleDeleteToken(w http.ResponseWriter, r *http.Request, accountID string)
 		})
 	} else {
 		// Redirect back to token page for form submission
-		http.Redirect(w, r, "/token", http.StatusSeeOther)
+		http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
 	}
 }
@@ -1,8 +1,11 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
 	"html"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -195,7 +198,7 @@ func ItemMeta(parts ...string) string {
 }
 
 // DeleteButton renders a delete link with a confirmation dialog.
-// It creates a hidden form with _method=DELETE and submits on confirm.
+// It submits _method=DELETE to action on confirm.
 func DeleteButton(action, label, confirmMsg string) string {
 	if label == "" {
 		label = "Delete"
@@ -203,10 +206,34 @@ func DeleteButton(action, label, confirmMsg string) string {
 	if confirmMsg == "" {
 		confirmMsg = "Are you sure?"
 	}
-	return fmt.Sprintf(`<a href="#" onclick="if(confirm('%s')){var f=document.createElement('form');f.method='POST';f.action='%s';var i=document.createElement('input');i.type='hidden';i.name='_method';i.value='DELETE';f.appendChild(i);document.body.appendChild(f);f.submit();}return false;" class="text-error">%s</a>`,
-		html.EscapeString(confirmMsg),
-		html.EscapeString(action),
-		html.EscapeString(label))
+	return PostLink(action, map[string]string{"_method": "DELETE"}, confirmMsg, label, `class="text-error"`)
+}
+
+// PostLink renders a link that POSTs fields to action, after confirming
+// if confirmMsg is set. attrs, such as a class, are added to the link
+// as they are. mu.js does the posting, so there's no inline script.
+func PostLink(action string, fields map[string]string, confirmMsg, label, attrs string) string {
+	b, _ := json.Marshal(fields)
+	var sb strings.Builder
+	sb.WriteString(`<a href="#" data-post="` + html.EscapeString(action) + `" data-fields="` + html.EscapeString(string(b)) + `"`)
+	if confirmMsg != "" {
+		sb.WriteString(` data-confirm="` + html.EscapeString(confirmMsg) + `"`)
+	}
+	if attrs != "" {
+		sb.WriteString(" " + attrs)
+	}
+	sb.WriteString(`>` + html.EscapeString(label) + `</a>`)
+	return sb.String()
+}
+
+// ShareLink renders a link that shares url, or the page it's on when url
+// is empty, or copies it where the browser can't share.
+func ShareLink(url, label, attrs string) string {
+	s := `<a href="#" data-share="` + html.EscapeString(url) + `"`
+	if attrs != "" {
+		s += " " + attrs
+	}
+	return s + `>` + html.EscapeString(label) + `</a>`
 }
 
 // ReplyForm renders a reply/comment form with an optional hidden parent ID.
@@ -277,3 +304,48 @@ func BackLink(label, href string) string {
 	return fmt.Sprintf(`<p class="mt-5"><a href="%s">← %s</a></p>`,
 		html.EscapeString(href), html.EscapeString(label))
 }
+
+// Cursor pagination defaults for list pages.
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Cursor reads the ?after=<id>&limit=N pagination parameters.
+func Cursor(r *http.Request) (after string, limit int) {
+	after = r.URL.Query().Get("after")
+	limit = DefaultPageLimit
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	return after, limit
+}
+
+// PageAfter returns the bounds of the page of n items that follows the item
+// whose ID is after, and the cursor for the page after it ("" on the last
+// page). An empty or unknown cursor starts from the top.
+func PageAfter(n int, id func(i int) string, after string, limit int) (start, end int, next string) {
+	if after != "" {
+		for i := 0; i < n; i++ {
+			if id(i) == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end = start + limit
+	if end >= n {
+		return start, n, ""
+	}
+	return start, end, id(end - 1)
+}
+
+// LoadMore renders a "Load more" link to the next page of the list with
+// the given element id. mu.js appends the next page in place; without
+// JavaScript the link simply opens it.
+func LoadMore(listID, href string) string {
+	return `<a href="` + html.EscapeString(href) + `" class="load-more btn-secondary" data-list="` + html.EscapeString(listID) + `">Load more</a>`
+}
@@ -334,3 +334,29 @@ func TestBackLink(t *testing.T) {
 		t.Error("expected back link text")
 	}
 }
+
+func TestPageAfter(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	id := func(i int) string { return ids[i] }
+
+	cases := []struct {
+		after      string
+		limit      int
+		start, end int
+		next       string
+	}{
+		{"", 2, 0, 2, "b"},
+		{"b", 2, 2, 4, "d"},
+		{"d", 2, 4, 5, ""},
+		{"e", 2, 5, 5, ""},
+		{"gone", 2, 0, 2, "b"},
+		{"", 10, 0, 5, ""},
+	}
+	for _, c := range cases {
+		start, end, next := PageAfter(len(ids), id, c.after, c.limit)
+		if start != c.start || end != c.end || next != c.next {
+			t.Errorf("PageAfter(after=%q, limit=%d) = %d, %d, %q; want %d, %d, %q",
+				c.after, c.limit, start, end, next, c.start, c.end, c.next)
+		}
+	}
+}
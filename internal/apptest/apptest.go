@@ -0,0 +1,191 @@
+// Package apptest boots the whole of Mu in-process for end-to-end tests.
+// The app runs against a temporary data directory and every outbound
+// request is routed to fakes — a news feed, Nominatim and Overpass, and an
+// OpenAI-compatible LLM — so tests never touch the network. Tests drive
+// the app over HTTP with a Client, as a browser would.
+//
+// Packages register their routes and load their state in init(), so the
+// app can only be booted once per process, into a HOME that is set
+// before the process starts. Main takes care of both: it re-runs the test
+// binary with HOME pointing at a fresh directory and boots the app there
+// once, for every test in the package.
+//
+//	func TestMain(m *testing.M) { apptest.Main(m, boot) }
+//
+//	func TestSomething(t *testing.T) {
+//		env := apptest.Get(t)
+//		alice := env.Member(t, apptest.Username("alice"))
+//		...
+//	}
+package apptest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"testing"
+
+	"mu/internal/data"
+	"mu/internal/egress"
+)
+
+// homeEnv is set, to the temporary HOME, in the re-run test binary.
+const homeEnv = "MU_APPTEST_HOME"
+
+// The hosts the fakes answer for. The feed and the LLM live on made-up
+// hosts the app is configured to use; the map providers are reached at
+// their real addresses.
+const (
+	FeedHost      = "feeds.test"
+	LLMHost       = "llm.test"
+	NominatimHost = "nominatim.openstreetmap.org"
+	OverpassHost  = "overpass-api.de"
+)
+
+// Env is the running app and its fakes.
+type Env struct {
+	URL  string // the app's base URL
+	Home string // the temporary HOME holding the data directory
+
+	Feed *Feed // the news feed, at http://feeds.test/rss
+	LLM  *LLM  // the model behind OPENAI_BASE_URL
+	Geo  *Geo  // Nominatim and Overpass
+
+	app   *httptest.Server
+	fakes []*httptest.Server
+}
+
+var current *Env
+
+// Main is TestMain for a package with end-to-end tests; boot builds the
+// app's handler. With -short the app isn't started and the tests that
+// need it skip.
+func Main(m *testing.M, boot func() http.Handler) {
+	flag.Parse()
+	if testing.Short() {
+		os.Exit(m.Run())
+	}
+	if os.Getenv(homeEnv) == "" {
+		os.Exit(rerun())
+	}
+	env, err := start(os.Getenv(homeEnv), boot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "apptest:", err)
+		os.Exit(1)
+	}
+	current = env
+	code := m.Run()
+	env.close()
+	os.Exit(code)
+}
+
+// rerun runs the test binary again, with the same arguments, under a
+// temporary HOME, and returns its exit code.
+func rerun() int {
+	home, err := os.MkdirTemp("", "mu-apptest-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "apptest:", err)
+		return 1
+	}
+	defer os.RemoveAll(home)
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), "HOME="+home, homeEnv+"="+home)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return exit.ExitCode()
+		}
+		fmt.Fprintln(os.Stderr, "apptest:", err)
+		return 1
+	}
+	return 0
+}
+
+// Get returns the running app. It skips the test when there isn't one:
+// with -short, or in a package whose TestMain isn't Main.
+func Get(t testing.TB) *Env {
+	t.Helper()
+	if current == nil {
+		t.Skip("end-to-end test: needs apptest.Main and no -short")
+	}
+	return current
+}
+
+// start brings up the fakes, points the app at them and boots it.
+func start(home string, boot func() http.Handler) (*Env, error) {
+	if home == "" || os.Getenv("HOME") != home {
+		return nil, errors.New("HOME is not the temporary directory")
+	}
+	env := &Env{
+		Home: home,
+		Feed: newFeed(),
+		LLM:  newLLM(),
+		Geo:  newGeo(),
+	}
+
+	routes := map[string]*url.URL{}
+	for host, h := range map[string]http.Handler{
+		FeedHost:      env.Feed,
+		LLMHost:       env.LLM,
+		NominatimHost: env.Geo,
+		OverpassHost:  env.Geo,
+	} {
+		srv := httptest.NewServer(h)
+		env.fakes = append(env.fakes, srv)
+		routes[host], _ = url.Parse(srv.URL)
+	}
+	egress.SetBase(&router{routes: routes, base: &http.Transport{}})
+
+	// Only the fake model: a key for a hosted one would take precedence.
+	for _, key := range []string{"ANTHROPIC_API_KEY", "ATLAS_API_KEY", "OPENAI_API_KEY"} {
+		os.Unsetenv(key)
+	}
+	os.Setenv("OPENAI_BASE_URL", "http://"+LLMHost)
+
+	// Read by the news package as it loads.
+	if err := data.SaveJSON("feeds.json", []map[string]string{
+		{"name": "Test", "url": "http://" + FeedHost + "/rss"},
+	}); err != nil {
+		return nil, err
+	}
+
+	env.app = httptest.NewServer(boot())
+	env.URL = env.app.URL
+	return env, nil
+}
+
+func (e *Env) close() {
+	e.app.Close()
+	for _, srv := range e.fakes {
+		srv.Close()
+	}
+	egress.SetBase(nil)
+}
+
+// router sends requests for the fakes' hosts to the fakes, lets requests
+// to this machine through, and refuses everything else.
+type router struct {
+	routes map[string]*url.URL
+	base   http.RoundTripper
+}
+
+func (rt *router) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if to, ok := rt.routes[host]; ok {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host = to.Scheme, to.Host
+		return rt.base.RoundTrip(req)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return rt.base.RoundTrip(req)
+	}
+	return nil, fmt.Errorf("apptest: no fake for %s", host)
+}
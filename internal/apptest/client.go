@@ -0,0 +1,213 @@
+package apptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+// Password is the password of accounts made with Member and Admin.
+const Password = "apptest-password"
+
+var names atomic.Int64
+
+// Username returns a username starting with base that no other call has
+// returned, so tests can be run again (-count) in the same app.
+func Username(base string) string {
+	return fmt.Sprintf("%s_%d", base, names.Add(1))
+}
+
+// Client is a browser for the app. It keeps cookies, sends back the CSRF
+// token the app sets, as mu.js does, and doesn't follow redirects, so a
+// test can check where a form sends it.
+type Client struct {
+	t    testing.TB
+	base *url.URL
+	http *http.Client
+}
+
+// Response is a response with its body read.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   string
+}
+
+// Location is where a redirect points.
+func (r *Response) Location() string {
+	return r.Header.Get("Location")
+}
+
+// Client returns a new, signed out, client.
+func (e *Env) Client(t testing.TB) *Client {
+	jar, _ := cookiejar.New(nil)
+	base, _ := url.Parse(e.URL)
+	return &Client{
+		t:    t,
+		base: base,
+		http: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Member creates an approved account, so it can post straight away, and
+// returns a client signed in to it.
+func (e *Env) Member(t testing.TB, id string) *Client {
+	return e.account(t, id, false)
+}
+
+// Admin creates an admin account and returns a client signed in to it.
+func (e *Env) Admin(t testing.TB, id string) *Client {
+	return e.account(t, id, true)
+}
+
+func (e *Env) account(t testing.TB, id string, admin bool) *Client {
+	t.Helper()
+	err := auth.Create(&auth.Account{
+		ID:       id,
+		Name:     id,
+		Secret:   Password,
+		Created:  time.Now(),
+		Approved: true,
+		Admin:    admin,
+	})
+	if err != nil {
+		t.Fatalf("create account %s: %v", id, err)
+	}
+	sess, err := auth.Login(id, Password)
+	if err != nil {
+		t.Fatalf("log in %s: %v", id, err)
+	}
+	c := e.Client(t)
+	c.http.Jar.SetCookies(c.base, []*http.Cookie{{Name: "session", Value: sess.Token, Path: "/"}})
+	return c
+}
+
+var (
+	captchaQuestion = regexp.MustCompile(`What is (\d+) \+ (\d+)\?`)
+	hiddenInput     = regexp.MustCompile(`<input type="hidden" name="(captcha_[a-z]+)" value="([^"]*)">`)
+)
+
+// Signup signs up through the /signup form, answering its captcha as a
+// person would, and returns the signed in client. The new account is
+// subject to the usual new account restrictions.
+func (e *Env) Signup(t testing.TB, id, password string) *Client {
+	t.Helper()
+	c := e.Client(t)
+	page := c.Get("/signup")
+	q := captchaQuestion.FindStringSubmatch(page.Body)
+	if q == nil {
+		t.Fatalf("no captcha on the signup page:\n%s", page.Body)
+	}
+	a, _ := strconv.Atoi(q[1])
+	b, _ := strconv.Atoi(q[2])
+	form := url.Values{
+		"id":      {id},
+		"name":    {id},
+		"secret":  {password},
+		"captcha": {strconv.Itoa(a + b)},
+	}
+	for _, m := range hiddenInput.FindAllStringSubmatch(page.Body, -1) {
+		form.Set(m[1], m[2])
+	}
+
+	resp := c.PostForm("/signup", form)
+	if resp.Status != http.StatusFound || resp.Location() != "/home" {
+		t.Fatalf("signup %s: %d %s\n%s", id, resp.Status, resp.Location(), resp.Body)
+	}
+	return c
+}
+
+// Get fetches a page.
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	req, err := http.NewRequest("GET", c.url(path), nil)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.Do(req)
+}
+
+// GetJSON fetches path as JSON and decodes it into v.
+func (c *Client) GetJSON(path string, v any) *Response {
+	c.t.Helper()
+	req, err := http.NewRequest("GET", c.url(path), nil)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp := c.Do(req)
+	if resp.Status == http.StatusOK && v != nil {
+		if err := json.Unmarshal([]byte(resp.Body), v); err != nil {
+			c.t.Fatalf("GET %s: %v\n%s", path, err, resp.Body)
+		}
+	}
+	return resp
+}
+
+// PostForm submits a form.
+func (c *Client) PostForm(path string, form url.Values) *Response {
+	c.t.Helper()
+	req, err := http.NewRequest("POST", c.url(path), strings.NewReader(form.Encode()))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
+
+// Do sends a request, adding the CSRF token to anything but a GET.
+func (c *Client) Do(req *http.Request) *Response {
+	c.t.Helper()
+	if req.Method != "GET" && req.Method != "HEAD" {
+		for _, ck := range c.http.Jar.Cookies(c.base) {
+			if ck.Name == "csrf_token" {
+				req.Header.Set("X-CSRF-Token", ck.Value)
+			}
+		}
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: string(body)}
+}
+
+func (c *Client) url(path string) string {
+	return c.base.String() + path
+}
+
+// Eventually retries check until it returns true, failing the test if it
+// doesn't within timeout. For what the app does in the background, such
+// as moderation and feed fetches.
+func Eventually(t testing.TB, timeout time.Duration, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,270 @@
+package apptest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================
+// NEWS FEED
+// ============================================
+
+// FeedItem is an article in the fake feed.
+type FeedItem struct {
+	Title       string
+	Description string
+	Published   time.Time
+}
+
+// Feed is the fake news feed: an RSS document of its items at /rss, and
+// a page for each item at /article/<n>.
+type Feed struct {
+	mu    sync.Mutex
+	items []FeedItem
+}
+
+// Headlines the feed starts with, so the news page has something on it
+// from the first fetch.
+var DefaultFeedItems = []FeedItem{
+	{Title: "Council approves new cycle lanes", Description: "The scheme links the station to the town centre."},
+	{Title: "Local library extends opening hours", Description: "Evening opening starts next month."},
+}
+
+func newFeed() *Feed {
+	f := &Feed{}
+	for i, item := range DefaultFeedItems {
+		item.Published = time.Now().Add(-time.Duration(i+1) * time.Hour)
+		f.items = append(f.items, item)
+	}
+	return f
+}
+
+// Add publishes an item, at the top of the feed. The app sees it on its
+// next fetch.
+func (f *Feed) Add(item FeedItem) {
+	if item.Published.IsZero() {
+		item.Published = time.Now()
+	}
+	f.mu.Lock()
+	f.items = append([]FeedItem{item}, f.items...)
+	f.mu.Unlock()
+}
+
+// Items returns the items in the feed, newest first.
+func (f *Feed) Items() []FeedItem {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FeedItem(nil), f.items...)
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+}
+
+type rss struct {
+	XMLName xml.Name  `xml:"rss"`
+	Version string    `xml:"version,attr"`
+	Title   string    `xml:"channel>title"`
+	Link    string    `xml:"channel>link"`
+	Items   []rssItem `xml:"channel>item"`
+}
+
+func (f *Feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	items := f.Items()
+	if n, ok := strings.CutPrefix(r.URL.Path, "/article/"); ok {
+		i, err := strconv.Atoi(n)
+		if err != nil || i < 0 || i >= len(items) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>%[1]s</title><meta name="description" content="%[2]s"></head><body><h1>%[1]s</h1><p>%[2]s</p></body></html>`,
+			html.EscapeString(items[i].Title), html.EscapeString(items[i].Description))
+		return
+	}
+	if r.URL.Path != "/rss" {
+		http.NotFound(w, r)
+		return
+	}
+
+	doc := rss{Version: "2.0", Title: "Test", Link: "http://" + FeedHost + "/"}
+	for i, item := range items {
+		// Numbered from the oldest, so an item keeps its link as more are added.
+		link := fmt.Sprintf("http://%s/article/%d", FeedHost, len(items)-1-i)
+		doc.Items = append(doc.Items, rssItem{
+			Title:       item.Title,
+			Link:        link,
+			GUID:        link,
+			Description: item.Description,
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	w.Header().Set("Content-Type", "application/rss+xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(doc)
+}
+
+// ============================================
+// LLM
+// ============================================
+
+// LLM is the fake OpenAI-compatible model. Every chat completion is
+// answered by the function set with Reply; until then it answers "OK",
+// which content moderation reads as a pass.
+type LLM struct {
+	mu    sync.Mutex
+	reply func(system, prompt string) string
+	calls int
+}
+
+func newLLM() *LLM {
+	return &LLM{reply: func(string, string) string { return "OK" }}
+}
+
+// Reply sets how the model answers: fn gets the system prompt and the
+// last user message.
+func (l *LLM) Reply(fn func(system, prompt string) string) {
+	l.mu.Lock()
+	l.reply = fn
+	l.mu.Unlock()
+}
+
+// Calls is the number of completions asked for so far.
+func (l *LLM) Calls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls
+}
+
+func (l *LLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/models"):
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]string{{"id": "fake-llm"}},
+		})
+	case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		l.complete(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (l *LLM) complete(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model    string `json:"model"`
+		Stream   bool   `json:"stream"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var system, prompt string
+	for _, m := range req.Messages {
+		text := messageText(m.Content)
+		switch m.Role {
+		case "system":
+			system += text
+		case "user":
+			prompt = text
+		}
+	}
+
+	l.mu.Lock()
+	l.calls++
+	reply := l.reply
+	l.mu.Unlock()
+	out := reply(system, prompt)
+	usage := map[string]int{"prompt_tokens": len(system+prompt) / 4, "completion_tokens": len(out) / 4}
+
+	if req.Stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk, _ := json.Marshal(map[string]any{
+			"choices": []map[string]any{{"delta": map[string]string{"content": out}}},
+		})
+		done, _ := json.Marshal(map[string]any{"choices": []any{}, "usage": usage})
+		fmt.Fprintf(w, "data: %s\n\ndata: %s\n\ndata: [DONE]\n\n", chunk, done)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":     "fake",
+		"object": "chat.completion",
+		"model":  req.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": out},
+			"finish_reason": "stop",
+		}},
+		"usage": usage,
+	})
+}
+
+// messageText reads a message's content, either a string or a list of
+// text parts.
+func messageText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(raw, &parts)
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(p.Text)
+	}
+	return b.String()
+}
+
+// ============================================
+// MAPS
+// ============================================
+
+// Geo is the fake Nominatim and Overpass. A search finds one place,
+// named after the query, in the middle of London; Overpass knows of
+// nothing.
+type Geo struct{}
+
+func newGeo() *Geo { return &Geo{} }
+
+func (g *Geo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/search":
+		q := r.URL.Query().Get("q")
+		json.NewEncoder(w).Encode([]map[string]any{{
+			"place_id":     1,
+			"display_name": q + ", Westminster, London",
+			"lat":          "51.5007",
+			"lon":          "-0.1246",
+			"class":        "amenity",
+			"type":         "cafe",
+			"address":      map[string]string{"road": "Whitehall", "city": "London", "country": "United Kingdom"},
+		}})
+	case "/reverse":
+		json.NewEncoder(w).Encode(map[string]any{
+			"display_name": "Westminster, London",
+			"address":      map[string]string{"city": "London", "country": "United Kingdom"},
+		})
+	case "/api/interpreter":
+		json.NewEncoder(w).Encode(map[string]any{"elements": []any{}})
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -34,14 +35,23 @@ type Account struct {
 	Created         time.Time `json:"created"`
 	Admin           bool      `json:"admin"`
 	Language        string    `json:"language"`
+	Theme           string    `json:"theme,omitempty"`           // "light" or "dark"; empty or "auto" follows the device
 	Widgets         []string  `json:"widgets,omitempty"`         // App IDs to show as home widgets
 	HomeCards       []string  `json:"home_cards,omitempty"`      // Card IDs the user has chosen to show (empty = all defaults)
 	HomeCardsSeen   []string  `json:"home_cards_seen,omitempty"` // Card IDs the customise panel has offered this user; anything newer defaults to visible
+	HomeOrder       []string  `json:"home_order,omitempty"`      // Card IDs in the order the user arranged them (empty = default order)
 	Approved        bool      `json:"approved,omitempty"`        // Admin-approved, bypasses new account restrictions
 	Email           string    `json:"email,omitempty"`
 	EmailVerified   bool      `json:"email_verified,omitempty"`
 	EmailVerifiedAt time.Time `json:"email_verified_at,omitempty"`
-	Banned          bool      `json:"banned,omitempty"` // Silently hidden from everyone except themselves
+	Banned          bool      `json:"banned,omitempty"`     // Silently hidden from everyone except themselves
+	InvitedBy       string    `json:"invited_by,omitempty"` // Account whose invite code made this one
+
+	WeeklyDigest      bool `json:"weekly_digest,omitempty"`       // Mail the weekly digest to the Mu inbox
+	WeeklyDigestEmail bool `json:"weekly_digest_email,omitempty"` // Also send it to the verified email
+
+	UsernameChangedAt time.Time `json:"username_changed_at,omitempty"` // Last username change (see rename.go)
+	WebAuthnID        string    `json:"webauthn_id,omitempty"`         // Passkey user handle, the first username once changed
 }
 
 // preHomeCardsSeen is the set of home cards that existed before per-user
@@ -91,11 +101,19 @@ func (a *Account) HomeCardActive(id string) bool {
 }
 
 type Session struct {
-	ID      string    `json:"id"`
-	Type    string    `json:"type"`
-	Token   string    `json:"token"`
-	Account string    `json:"account"`
-	Created time.Time `json:"created"`
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Token    string    `json:"token"`
+	Account  string    `json:"account"`
+	Created  time.Time `json:"created"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`  // zero for sessions saved before expiry was tracked
+	Remember bool      `json:"remember,omitempty"` // issued with "keep me logged in"
+	Scopes   []string  `json:"scopes,omitempty"`   // what a personal access token may do; see Allows
+
+	// Where the session was started, shown at /account/sessions.
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
 }
 
 // Token represents a Personal Access Token (PAT) for API automation
@@ -128,6 +146,14 @@ func Create(acc *Account) error {
 		return errors.New("Account already exists")
 	}
 
+	// A name given up in a username change is held for its old owner.
+	renamesMu.Lock()
+	reserved := reservedName(acc.ID, "")
+	renamesMu.Unlock()
+	if reserved {
+		return errors.New("Account already exists")
+	}
+
 	// hash the secret
 	hash, err := bcrypt.GenerateFromPassword([]byte(acc.Secret), 10)
 	if err != nil {
@@ -327,21 +353,7 @@ func Login(id, secret string) (*Session, error) {
 		return nil, errors.New("invalid account secret")
 	}
 
-	guid := uuid.New().String()
-
-	sess := &Session{
-		ID:      guid,
-		Type:    "account",
-		Token:   base64.StdEncoding.EncodeToString([]byte(guid)),
-		Account: acc.ID,
-		Created: time.Now(),
-	}
-
-	// store the session
-	sessions[guid] = sess
-	data.SaveJSON("sessions.json", sessions)
-
-	return sess, nil
+	return newSession(acc.ID, false), nil
 }
 
 // CreateSession creates a new session for the given account ID without password validation.
@@ -355,20 +367,7 @@ func CreateSession(id string) (*Session, error) {
 		return nil, errors.New("account does not exist")
 	}
 
-	guid := uuid.New().String()
-
-	sess := &Session{
-		ID:      guid,
-		Type:    "account",
-		Token:   base64.StdEncoding.EncodeToString([]byte(guid)),
-		Account: id,
-		Created: time.Now(),
-	}
-
-	sessions[guid] = sess
-	data.SaveJSON("sessions.json", sessions)
-
-	return sess, nil
+	return newSession(id, false), nil
 }
 
 func Logout(tk string) error {
@@ -382,6 +381,7 @@ func Logout(tk string) error {
 	data.SaveJSON("sessions.json", sessions)
 	mutex.Unlock()
 
+	revokeRefreshForSession(sess.ID)
 	return nil
 }
 
@@ -418,11 +418,11 @@ func GetSession(r *http.Request) (*Session, error) {
 		}
 
 		// Try as PAT first
-		accountID, err := ValidatePAT(token)
-		if err == nil {
+		if t, err := validatePAT(token); err == nil {
 			return &Session{
 				Type:    "token",
-				Account: accountID,
+				Account: t.Account,
+				Scopes:  t.Permissions,
 			}, nil
 		}
 
@@ -444,12 +444,12 @@ func GetSession(r *http.Request) (*Session, error) {
 	// Try X-Micro-Token header (legacy)
 	tokenHeader := r.Header.Get("X-Micro-Token")
 	if tokenHeader != "" {
-		accountID, err := ValidatePAT(tokenHeader)
-		if err == nil {
+		if t, err := validatePAT(tokenHeader); err == nil {
 			// Create a pseudo-session for PAT
 			return &Session{
 				Type:    "token",
-				Account: accountID,
+				Account: t.Account,
+				Scopes:  t.Permissions,
 			}, nil
 		}
 	}
@@ -509,13 +509,24 @@ func ParseToken(tk string) (*Session, error) {
 	}
 
 	mutex.Lock()
+	defer mutex.Unlock()
 	sess, ok := sessions[id.String()]
-	mutex.Unlock()
-
 	if !ok {
 		return nil, errors.New("session not found")
 	}
 
+	// Expired and idle sessions end here, which covers cookies, bearer
+	// tokens and ValidateToken alike.
+	now := time.Now()
+	if sess.expired(now) {
+		delete(sessions, sess.ID)
+		data.SaveJSON("sessions.json", sessions)
+		return nil, errors.New("session expired")
+	}
+	if touchSession(sess, now) {
+		data.SaveJSON("sessions.json", sessions)
+	}
+
 	return sess, nil
 }
 
@@ -776,39 +787,66 @@ func CreateToken(accountID, name string, permissions []string, expiresAt time.Ti
 
 // ValidatePAT validates a Personal Access Token and returns the associated account ID
 func ValidatePAT(rawToken string) (string, error) {
+	t, err := validatePAT(rawToken)
+	if err != nil {
+		return "", err
+	}
+	return t.Account, nil
+}
+
+// patCache remembers which token a raw value matched, by its SHA-256, so
+// each request doesn't have to bcrypt against every token. Guarded by mutex.
+var patCache = map[string]string{}
+
+// validatePAT returns the unexpired token rawToken belongs to.
+func validatePAT(rawToken string) (*Token, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	// Normalize: strip trailing base64 padding so tokens work with or without '='
 	rawToken = strings.TrimRight(rawToken, "=")
-
-	// Check all tokens to find a match (try without padding, then with)
-	for _, token := range tokens {
-		// Try raw token (no padding) first, then with padding for older tokens
-		err := bcrypt.CompareHashAndPassword([]byte(token.Token), []byte(rawToken))
-		if err != nil {
-			// Retry with padding in case the hash was generated with padded token
-			padded := rawToken
-			if m := len(padded) % 4; m != 0 {
-				padded += strings.Repeat("=", 4-m)
+	sum := sha256.Sum256([]byte(rawToken))
+	key := string(sum[:])
+
+	var match *Token
+	if token, ok := tokens[patCache[key]]; ok {
+		match = token
+	} else {
+		// Check all tokens to find a match (try without padding, then with)
+		for _, token := range tokens {
+			// Try raw token (no padding) first, then with padding for older tokens
+			err := bcrypt.CompareHashAndPassword([]byte(token.Token), []byte(rawToken))
+			if err != nil {
+				// Retry with padding in case the hash was generated with padded token
+				padded := rawToken
+				if m := len(padded) % 4; m != 0 {
+					padded += strings.Repeat("=", 4-m)
+				}
+				err = bcrypt.CompareHashAndPassword([]byte(token.Token), []byte(padded))
 			}
-			err = bcrypt.CompareHashAndPassword([]byte(token.Token), []byte(padded))
-		}
-		if err == nil {
-			// Check if expired
-			if !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt) {
-				return "", errors.New("token expired")
+			if err == nil {
+				match = token
+				patCache[key] = token.ID
+				break
 			}
+		}
+	}
+	if match == nil {
+		return nil, errors.New("invalid token")
+	}
 
-			// Update last used time
-			token.LastUsed = time.Now()
-			data.SaveJSON("tokens.json", tokens)
+	// Check if expired
+	if !match.ExpiresAt.IsZero() && time.Now().After(match.ExpiresAt) {
+		return nil, errors.New("token expired")
+	}
 
-			return token.Account, nil
-		}
+	// Update last used time, at most once a minute
+	if time.Since(match.LastUsed) > time.Minute {
+		match.LastUsed = time.Now()
+		data.SaveJSON("tokens.json", tokens)
 	}
 
-	return "", errors.New("invalid token")
+	return match, nil
 }
 
 // ListTokens returns all PAT tokens for an account (with hashed values)
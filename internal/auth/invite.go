@@ -1,7 +1,9 @@
-// Invite-only signup. When enabled (INVITE_ONLY=true), new accounts
-// can only be created with a valid invite code. Admins generate invite
-// codes via the admin console and the code is emailed to the invitee
-// as a signup link.
+// Registration modes and invites. REGISTRATION sets whether signup is
+// open to anyone, needs an invite code, or is closed; INVITE_ONLY=true
+// is the older way of asking for invite-only. Admins and members create
+// codes, either emailed as a single-use signup link or handed out with a
+// number of uses, and every account made with one records who invited
+// it, which moderators see alongside what it posts.
 package auth
 
 import (
@@ -9,22 +11,68 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"mu/internal/data"
+	"mu/internal/settings"
 )
 
+func init() {
+	settings.Register("Accounts",
+		settings.Var{Key: "REGISTRATION", Type: settings.TypeString, Doc: "Who can sign up: open, invite (needs an invite code) or closed. Unset is open, or invite with INVITE_ONLY=true"},
+		settings.Var{Key: "INVITE_MEMBER_LIMIT", Type: settings.TypeInt, Default: "5", Doc: "Unused invite uses a member can have out at once; 0 stops members inviting"},
+	)
+}
+
+// Registration modes.
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+	RegistrationClosed = "closed"
+)
+
+// maxInviteUses caps the uses of any one code.
+const maxInviteUses = 1000
+
 // Invite stores a pending invitation.
 type Invite struct {
 	Code      string    `json:"code"`
 	Email     string    `json:"email"`      // who it was sent to (informational)
-	CreatedBy string    `json:"created_by"` // admin who created it
+	CreatedBy string    `json:"created_by"` // account that created it
 	CreatedAt time.Time `json:"created_at"`
-	UsedBy    string    `json:"used_by,omitempty"` // account ID that consumed it
+	UsedBy    string    `json:"used_by,omitempty"` // account ID that last consumed it
 	UsedAt    time.Time `json:"used_at,omitempty"`
+	MaxUses   int       `json:"max_uses,omitempty"` // 0 for a single use
+	Accounts  []string  `json:"accounts,omitempty"` // every account that signed up with it
+}
+
+// Limit returns how many accounts the code can make.
+func (inv *Invite) Limit() int {
+	if inv.MaxUses < 1 {
+		return 1
+	}
+	return inv.MaxUses
+}
+
+// Used returns how many accounts the code has made.
+func (inv *Invite) Used() int {
+	if len(inv.Accounts) == 0 && inv.UsedBy != "" {
+		return 1 // consumed before uses were counted
+	}
+	return len(inv.Accounts)
+}
+
+// Remaining returns how many more accounts the code can make.
+func (inv *Invite) Remaining() int {
+	if n := inv.Limit() - inv.Used(); n > 0 {
+		return n
+	}
+	return 0
 }
 
 var (
@@ -64,16 +112,59 @@ func init() {
 	}
 }
 
+// Registration returns the registration mode: RegistrationOpen,
+// RegistrationInvite or RegistrationClosed.
+func Registration() string {
+	switch v := strings.ToLower(settings.String("REGISTRATION")); v {
+	case RegistrationOpen, RegistrationInvite, RegistrationClosed:
+		return v
+	}
+	v := strings.ToLower(os.Getenv("INVITE_ONLY"))
+	if v == "true" || v == "1" || v == "yes" {
+		return RegistrationInvite
+	}
+	return RegistrationOpen
+}
+
 // InviteOnly returns true when signup requires an invite code.
-// Controlled by the INVITE_ONLY environment variable.
 func InviteOnly() bool {
-	v := strings.ToLower(os.Getenv("INVITE_ONLY"))
-	return v == "true" || v == "1" || v == "yes"
+	return Registration() == RegistrationInvite
+}
+
+// SignupClosed returns true when no one can sign up.
+func SignupClosed() bool {
+	return Registration() == RegistrationClosed
 }
 
-// CreateInvite generates a new invite code for the given email.
-// Returns the code. The caller is responsible for emailing it.
-func CreateInvite(email, adminID string) (string, error) {
+// OpenRegistration returns true when anyone can sign up without a code,
+// which is when accounts may be made on the fly (signing in with Google
+// or a chat app).
+func OpenRegistration() bool {
+	return Registration() == RegistrationOpen
+}
+
+// CreateInvite generates a new single-use invite code for the given
+// email. Returns the code. The caller is responsible for emailing it.
+func CreateInvite(email, createdBy string) (string, error) {
+	return createInvite(email, createdBy, 1)
+}
+
+// CreateInviteCode generates a code createdBy can hand out, good for
+// uses signups. Members are held to INVITE_MEMBER_LIMIT unused uses.
+func CreateInviteCode(createdBy string, uses int) (string, error) {
+	if uses < 1 || uses > maxInviteUses {
+		return "", fmt.Errorf("uses must be between 1 and %d", maxInviteUses)
+	}
+	if left := InviteAllowance(createdBy); uses > left {
+		if left == 0 {
+			return "", errors.New("you have no invites left")
+		}
+		return "", fmt.Errorf("you have %d invites left", left)
+	}
+	return createInvite("", createdBy, uses)
+}
+
+func createInvite(email, createdBy string, uses int) (string, error) {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
 		return "", err
@@ -83,17 +174,42 @@ func CreateInvite(email, adminID string) (string, error) {
 	inviteMu.Lock()
 	defer inviteMu.Unlock()
 
-	invites[code] = &Invite{
+	inv := &Invite{
 		Code:      code,
 		Email:     email,
-		CreatedBy: adminID,
+		CreatedBy: createdBy,
 		CreatedAt: time.Now(),
 	}
+	if uses > 1 {
+		inv.MaxUses = uses
+	}
+	invites[code] = inv
 	saveInvites()
 	return code, nil
 }
 
-// ValidateInvite checks whether a code is valid (exists and unused).
+// InviteAllowance returns how many more signups accountID can invite
+// right now: INVITE_MEMBER_LIMIT less the unused uses of their codes.
+// Admins have no limit beyond maxInviteUses a code.
+func InviteAllowance(accountID string) int {
+	if acc, err := GetAccount(accountID); err == nil && acc.Admin {
+		return maxInviteUses
+	}
+	left := settings.Int("INVITE_MEMBER_LIMIT")
+	inviteMu.Lock()
+	for _, inv := range invites {
+		if inv.CreatedBy == accountID {
+			left -= inv.Remaining()
+		}
+	}
+	inviteMu.Unlock()
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// ValidateInvite checks whether a code is valid (exists and has uses left).
 func ValidateInvite(code string) error {
 	if code == "" {
 		return errors.New("invite code required")
@@ -105,25 +221,41 @@ func ValidateInvite(code string) error {
 	if !ok {
 		return errors.New("invalid invite code")
 	}
-	if inv.UsedBy != "" {
+	if inv.Remaining() == 0 {
 		return errors.New("this invite has already been used")
 	}
 	return nil
 }
 
-// ConsumeInvite marks an invite code as used by the given account.
+// ConsumeInvite uses up one use of an invite code for the given account,
+// and records who invited it.
 func ConsumeInvite(code, accountID string) {
 	inviteMu.Lock()
-	defer inviteMu.Unlock()
+	inv, ok := invites[code]
+	if !ok || inv.Remaining() == 0 {
+		inviteMu.Unlock()
+		return
+	}
+	if len(inv.Accounts) == 0 && inv.UsedBy != "" {
+		inv.Accounts = []string{inv.UsedBy}
+	}
+	inv.Accounts = append(inv.Accounts, accountID)
+	inv.UsedBy = accountID
+	inv.UsedAt = time.Now()
+	inviter := inv.CreatedBy
+	saveInvites()
+	inviteMu.Unlock()
 
-	if inv, ok := invites[code]; ok {
-		inv.UsedBy = accountID
-		inv.UsedAt = time.Now()
-		saveInvites()
+	mutex.Lock()
+	defer mutex.Unlock()
+	if acc, ok := accounts[accountID]; ok && inviter != accountID {
+		acc.InvitedBy = inviter
+		data.SaveJSON("accounts.json", accounts)
 	}
 }
 
-// ListInvites returns all invites (for admin console display).
+// ListInvites returns all invites (for admin console display), newest
+// first.
 func ListInvites() []*Invite {
 	inviteMu.Lock()
 	defer inviteMu.Unlock()
@@ -132,9 +264,41 @@ func ListInvites() []*Invite {
 	for _, inv := range invites {
 		list = append(list, inv)
 	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
 	return list
 }
 
+// InvitesBy returns the invites createdBy has made, newest first.
+func InvitesBy(createdBy string) []*Invite {
+	var out []*Invite
+	for _, inv := range ListInvites() {
+		if inv.CreatedBy == createdBy {
+			out = append(out, inv)
+		}
+	}
+	return out
+}
+
+// renameInvites moves a renamed account's invites and uses to its new ID.
+func renameInvites(oldID, newID string) {
+	inviteMu.Lock()
+	defer inviteMu.Unlock()
+	for _, inv := range invites {
+		if inv.CreatedBy == oldID {
+			inv.CreatedBy = newID
+		}
+		if inv.UsedBy == oldID {
+			inv.UsedBy = newID
+		}
+		for i, id := range inv.Accounts {
+			if id == oldID {
+				inv.Accounts[i] = newID
+			}
+		}
+	}
+	saveInvites()
+}
+
 func saveInvites() {
 	data.SaveJSON("invites.json", invites)
 }
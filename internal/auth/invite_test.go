@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func resetInvitesForTest(t *testing.T) string {
@@ -107,3 +108,66 @@ func TestInviteRequestLifecycleAndOrdering(t *testing.T) {
 		t.Fatalf("DeleteInviteRequest left requests %#v, want only second@example.com", list)
 	}
 }
+
+func TestInviteCodesLimitsAndInviter(t *testing.T) {
+	resetInvitesForTest(t)
+	t.Setenv("INVITE_MEMBER_LIMIT", "3")
+	for _, id := range []string{"inviter", "first", "second", "third"} {
+		if err := Create(&Account{ID: id, Name: id, Secret: "secret123", Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := CreateInviteCode("inviter", 4); err == nil {
+		t.Fatal("a member made a code past their limit")
+	}
+	code, err := CreateInviteCode("inviter", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left := InviteAllowance("inviter"); left != 1 {
+		t.Fatalf("allowance = %d with two unused uses out, want 1", left)
+	}
+
+	ConsumeInvite(code, "first")
+	if err := ValidateInvite(code); err != nil {
+		t.Fatalf("a two-use code was spent after one signup: %v", err)
+	}
+	ConsumeInvite(code, "second")
+	if err := ValidateInvite(code); err == nil {
+		t.Fatal("ValidateInvite accepted a spent code")
+	}
+	ConsumeInvite(code, "third")
+	if acc, _ := GetAccount("third"); acc.InvitedBy != "" {
+		t.Fatal("a spent code still recorded an inviter")
+	}
+	if acc, _ := GetAccount("second"); acc.InvitedBy != "inviter" {
+		t.Fatalf("InvitedBy = %q, want inviter", acc.InvitedBy)
+	}
+	if inv := InvitesBy("inviter"); len(inv) != 1 || inv[0].Used() != 2 || inv[0].Remaining() != 0 {
+		t.Fatalf("InvitesBy = %+v", inv)
+	}
+	if left := InviteAllowance("inviter"); left != 3 {
+		t.Fatalf("allowance = %d once the code was used up, want 3", left)
+	}
+}
+
+func TestRegistrationModes(t *testing.T) {
+	t.Setenv("REGISTRATION", "")
+	t.Setenv("INVITE_ONLY", "")
+	if Registration() != RegistrationOpen || !OpenRegistration() {
+		t.Fatalf("default registration = %q, want open", Registration())
+	}
+	t.Setenv("INVITE_ONLY", "true")
+	if !InviteOnly() {
+		t.Fatal("INVITE_ONLY=true no longer means invite only")
+	}
+	t.Setenv("REGISTRATION", "closed")
+	if !SignupClosed() || InviteOnly() || OpenRegistration() {
+		t.Fatalf("registration = %q, want closed", Registration())
+	}
+	t.Setenv("REGISTRATION", "Invite")
+	if !InviteOnly() {
+		t.Fatalf("registration = %q, want invite", Registration())
+	}
+}
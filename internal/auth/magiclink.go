@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/settings"
+)
+
+// ============================================================
+// Magic-link login
+// ============================================================
+
+// A magic link signs its holder in without a password. It is emailed to
+// the account's verified address, is single-use and expires after
+// MagicLinkTTL. Links live in memory only: a restart simply means asking
+// for a new one.
+
+// MagicLinkTTL is how long a login link stays valid.
+const MagicLinkTTL = 15 * time.Minute
+
+// Per-account and per-IP request limits, over magicLinkWindow.
+const (
+	magicLinkPerAccount = 3
+	magicLinkPerIP      = 10
+	magicLinkWindow     = time.Hour
+)
+
+// ErrMagicLinkRateLimited is returned when too many links were requested
+// from one IP.
+var ErrMagicLinkRateLimited = errors.New("too many login links requested — please wait a while and try again")
+
+// ErrMagicLinkAccountLimited is returned when an account has been sent
+// too many links. It's only returned for accounts that exist, so callers
+// should reply as they would to an unknown name.
+var ErrMagicLinkAccountLimited = errors.New("too many login links requested for this account")
+
+// MagicLink is a pending login link.
+type MagicLink struct {
+	AccountID   string
+	Device      string // e.g. "Firefox on Linux", shown in the email and on confirmation
+	IP          string
+	Redirect    string
+	RequestedAt time.Time
+	ExpiresAt   time.Time
+}
+
+var (
+	magicMu       sync.Mutex
+	magicLinks    = map[string]*MagicLink{}
+	magicRequests = map[string][]time.Time{} // "acc:id" / "ip:addr" → request times
+)
+
+// MagicLinkOnly reports whether magic links are the only way to log in.
+// Set from /admin/login; stored as the MAGIC_LINK_ONLY setting.
+func MagicLinkOnly() bool {
+	switch strings.ToLower(strings.TrimSpace(settings.Get("MAGIC_LINK_ONLY"))) {
+	case "true", "1", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// SetMagicLinkOnly turns magic-link-only login on or off.
+func SetMagicLinkOnly(on bool) {
+	if on {
+		settings.Set("MAGIC_LINK_ONLY", "true")
+	} else {
+		settings.Set("MAGIC_LINK_ONLY", "")
+	}
+}
+
+// magicAllow records a request against key and reports whether it is
+// within max per magicLinkWindow (caller must hold magicMu).
+func magicAllow(key string, max int, now time.Time) bool {
+	var recent []time.Time
+	for _, t := range magicRequests[key] {
+		if now.Sub(t) < magicLinkWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= max {
+		magicRequests[key] = recent
+		return false
+	}
+	magicRequests[key] = append(recent, now)
+	return true
+}
+
+// CreateMagicLink issues a login link for the account identified by
+// username or email. Only accounts with a verified email can use one;
+// the returned account is where the link should be sent. Any earlier
+// link for the account stops working.
+func CreateMagicLink(login, ip, device, redirect string) (*Account, string, error) {
+	login = strings.ToLower(strings.TrimSpace(login))
+	if login == "" {
+		return nil, "", errors.New("username or email is required")
+	}
+
+	now := time.Now()
+	magicMu.Lock()
+	if ip != "" && !magicAllow("ip:"+ip, magicLinkPerIP, now) {
+		magicMu.Unlock()
+		return nil, "", ErrMagicLinkRateLimited
+	}
+	magicMu.Unlock()
+
+	var acc *Account
+	if strings.Contains(login, "@") {
+		acc, _ = GetAccountByEmail(login)
+	} else {
+		acc, _ = GetAccount(login)
+	}
+	if acc == nil || acc.Banned || acc.Email == "" || !acc.EmailVerified {
+		return nil, "", errors.New("no account with a verified email matches")
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	tok := base64.RawURLEncoding.EncodeToString(b)
+
+	magicMu.Lock()
+	defer magicMu.Unlock()
+	if !magicAllow("acc:"+acc.ID, magicLinkPerAccount, now) {
+		return nil, "", ErrMagicLinkAccountLimited
+	}
+	for k, v := range magicLinks {
+		if v.AccountID == acc.ID || now.After(v.ExpiresAt) {
+			delete(magicLinks, k)
+		}
+	}
+	magicLinks[tok] = &MagicLink{
+		AccountID:   acc.ID,
+		Device:      device,
+		IP:          ip,
+		Redirect:    redirect,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(MagicLinkTTL),
+	}
+	return acc, tok, nil
+}
+
+// PeekMagicLink returns a pending link without using it up, so the
+// confirmation page can say who and what it is for. Mail scanners that
+// prefetch links only ever reach this.
+func PeekMagicLink(token string) (*MagicLink, error) {
+	magicMu.Lock()
+	defer magicMu.Unlock()
+	l, ok := magicLinks[token]
+	if !ok || time.Now().After(l.ExpiresAt) {
+		return nil, errors.New("this login link is invalid or has expired — please request a new one")
+	}
+	c := *l
+	return &c, nil
+}
+
+// ConsumeMagicLink uses up a login link and starts a session.
+func ConsumeMagicLink(token string) (*Session, *MagicLink, error) {
+	magicMu.Lock()
+	l, ok := magicLinks[token]
+	if ok {
+		delete(magicLinks, token)
+	}
+	magicMu.Unlock()
+	if !ok || time.Now().After(l.ExpiresAt) {
+		return nil, nil, errors.New("this login link is invalid or has expired — please request a new one")
+	}
+	if IsBanned(l.AccountID) {
+		return nil, nil, errors.New("this account is not available")
+	}
+	sess, err := CreateSession(l.AccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sess, l, nil
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func resetMagicLinkStateForTest(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MAGIC_LINK_ONLY", "")
+
+	mutex.Lock()
+	accounts = map[string]*Account{
+		"alice": {ID: "alice", Name: "Alice", Email: "alice@example.com", EmailVerified: true, Created: time.Now()},
+		"bob":   {ID: "bob", Name: "Bob", Email: "bob@example.com", Created: time.Now()},
+	}
+	sessions = map[string]*Session{}
+	mutex.Unlock()
+
+	magicMu.Lock()
+	magicLinks = map[string]*MagicLink{}
+	magicRequests = map[string][]time.Time{}
+	magicMu.Unlock()
+}
+
+func TestMagicLinkIsSingleUse(t *testing.T) {
+	resetMagicLinkStateForTest(t)
+
+	acc, tok, err := CreateMagicLink("Alice@Example.com", "10.0.0.1", "Firefox on Linux", "/mail")
+	if err != nil || acc.ID != "alice" {
+		t.Fatalf("CreateMagicLink by email = %v, %v", acc, err)
+	}
+	if _, _, err := CreateMagicLink("bob", "10.0.0.1", "", ""); err == nil {
+		t.Error("issued a link for an unverified email")
+	}
+
+	// Looking at the link (as a mail scanner would) doesn't use it up.
+	if l, err := PeekMagicLink(tok); err != nil || l.Device != "Firefox on Linux" {
+		t.Fatalf("PeekMagicLink = %+v, %v", l, err)
+	}
+	sess, l, err := ConsumeMagicLink(tok)
+	if err != nil || sess.Account != "alice" || l.Redirect != "/mail" {
+		t.Fatalf("ConsumeMagicLink = %+v, %+v, %v", sess, l, err)
+	}
+	if _, _, err := ConsumeMagicLink(tok); err == nil {
+		t.Error("link worked twice")
+	}
+
+	// A new link replaces the previous one.
+	_, first, _ := CreateMagicLink("alice", "10.0.0.2", "", "")
+	_, second, _ := CreateMagicLink("alice", "10.0.0.2", "", "")
+	if _, err := PeekMagicLink(first); err == nil {
+		t.Error("older link still valid")
+	}
+	magicMu.Lock()
+	magicLinks[second].ExpiresAt = time.Now().Add(-time.Second)
+	magicMu.Unlock()
+	if _, _, err := ConsumeMagicLink(second); err == nil {
+		t.Error("expired link worked")
+	}
+}
+
+func TestMagicLinkRateLimits(t *testing.T) {
+	resetMagicLinkStateForTest(t)
+
+	for i := 0; i < magicLinkPerAccount; i++ {
+		if _, _, err := CreateMagicLink("alice", "", "", ""); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if _, _, err := CreateMagicLink("alice", "", "", ""); err != ErrMagicLinkAccountLimited {
+		t.Fatalf("over the account limit = %v", err)
+	}
+
+	// Unknown names still count against the IP.
+	for i := 0; i < magicLinkPerIP; i++ {
+		CreateMagicLink("nobody", "10.0.0.9", "", "")
+	}
+	if _, _, err := CreateMagicLink("nobody", "10.0.0.9", "", ""); err != ErrMagicLinkRateLimited {
+		t.Fatalf("over the IP limit = %v", err)
+	}
+}
+
+func TestMagicLinkOnlySetting(t *testing.T) {
+	resetMagicLinkStateForTest(t)
+	if MagicLinkOnly() {
+		t.Fatal("on by default")
+	}
+	SetMagicLinkOnly(true)
+	if !MagicLinkOnly() {
+		t.Fatal("not on after enabling")
+	}
+	SetMagicLinkOnly(false)
+	if MagicLinkOnly() {
+		t.Fatal("still on after disabling")
+	}
+}
@@ -22,6 +22,7 @@ type OAuthClient struct {
 	Name         string    `json:"name"`
 	RedirectURIs []string  `json:"redirect_uris"`
 	CreatedAt    time.Time `json:"created_at"`
+	Service      bool      `json:"service,omitempty"` // Registered by an admin for OpenID Connect sign-in
 }
 
 // OAuthCode represents a pending authorization code.
@@ -32,6 +33,8 @@ type OAuthCode struct {
 	RedirectURI         string
 	CodeChallenge       string
 	CodeChallengeMethod string
+	Scope               string // OpenID Connect scopes granted to a service
+	Nonce               string // Echoed in the service's ID token
 	ExpiresAt           time.Time
 }
 
@@ -90,12 +93,14 @@ func GetAllOAuthClients() []*OAuthClient {
 	return list
 }
 
-// DeleteOAuthClient removes a client.
+// DeleteOAuthClient removes a client, and revokes the tokens it holds
+// if it's a service.
 func DeleteOAuthClient(clientID string) {
 	oauthMu.Lock()
-	defer oauthMu.Unlock()
 	delete(oauthClients, clientID)
 	saveOAuthClients()
+	oauthMu.Unlock()
+	RevokeServiceTokens(clientID)
 }
 
 // CreateAuthorizationCode creates a code for the OAuth flow.
@@ -120,6 +125,26 @@ func CreateAuthorizationCode(clientID, accountID, redirectURI, codeChallenge, co
 // ExchangeAuthorizationCode exchanges a code for an access token.
 // Validates PKCE code_verifier against the stored code_challenge.
 func ExchangeAuthorizationCode(code, clientID, redirectURI, codeVerifier string) (string, error) {
+	if c := GetOAuthClient(clientID); c != nil && c.Service {
+		// Services authenticate and never get a session.
+		return "", errors.New("client must use its secret")
+	}
+	authCode, err := redeemCode(code, clientID, redirectURI, codeVerifier)
+	if err != nil {
+		return "", err
+	}
+
+	// Create a session token for this account
+	sess, err := CreateSession(authCode.AccountID)
+	if err != nil {
+		return "", err
+	}
+	return sess.Token, nil
+}
+
+// redeemCode uses up an authorization code, checking it was issued to
+// clientID for redirectURI and, with PKCE, that codeVerifier matches.
+func redeemCode(code, clientID, redirectURI, codeVerifier string) (*OAuthCode, error) {
 	oauthMu.Lock()
 	authCode, ok := oauthCodes[code]
 	if ok {
@@ -128,34 +153,28 @@ func ExchangeAuthorizationCode(code, clientID, redirectURI, codeVerifier string)
 	oauthMu.Unlock()
 
 	if !ok {
-		return "", errors.New("invalid authorization code")
+		return nil, errors.New("invalid authorization code")
 	}
 	if time.Now().After(authCode.ExpiresAt) {
-		return "", errors.New("authorization code expired")
+		return nil, errors.New("authorization code expired")
 	}
 	if authCode.ClientID != clientID {
-		return "", errors.New("client_id mismatch")
+		return nil, errors.New("client_id mismatch")
 	}
 	if authCode.RedirectURI != redirectURI {
-		return "", errors.New("redirect_uri mismatch")
+		return nil, errors.New("redirect_uri mismatch")
 	}
 
 	// Validate PKCE
 	if authCode.CodeChallenge != "" {
 		if codeVerifier == "" {
-			return "", errors.New("code_verifier required")
+			return nil, errors.New("code_verifier required")
 		}
 		if !validatePKCE(codeVerifier, authCode.CodeChallenge, authCode.CodeChallengeMethod) {
-			return "", errors.New("invalid code_verifier")
+			return nil, errors.New("invalid code_verifier")
 		}
 	}
-
-	// Create a session token for this account
-	sess, err := CreateSession(authCode.AccountID)
-	if err != nil {
-		return "", err
-	}
-	return sess.Token, nil
+	return authCode, nil
 }
 
 // validatePKCE checks the code_verifier against the code_challenge.
@@ -270,6 +289,10 @@ func OAuthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "client_id required", 400)
 		return
 	}
+	if c := GetOAuthClient(clientID); c != nil && c.Service {
+		authorizeService(w, r, c)
+		return
+	}
 
 	// Check if already logged in
 	sess, _ := TrySession(r)
@@ -322,6 +345,10 @@ func OAuthAuthorizePostHandler(w http.ResponseWriter, r *http.Request) {
 
 	r.ParseForm()
 	clientID := r.FormValue("client_id")
+	if c := GetOAuthClient(clientID); c != nil && c.Service {
+		authorizeService(w, r, c)
+		return
+	}
 	redirectURI := r.FormValue("redirect_uri")
 	state := r.FormValue("state")
 	codeChallenge := r.FormValue("code_challenge")
@@ -377,6 +404,14 @@ func OAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
 
 	r.ParseForm()
 	grantType := r.FormValue("grant_type")
+	if grantType == "authorization_code" {
+		if id, _ := clientSecret(r); id != "" {
+			if c := GetOAuthClient(id); c != nil && c.Service {
+				serviceTokenHandler(w, r)
+				return
+			}
+		}
+	}
 	code := r.FormValue("code")
 	clientID := r.FormValue("client_id")
 	redirectURI := r.FormValue("redirect_uri")
@@ -0,0 +1,427 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/data"
+)
+
+// OpenID Connect lets self-hosted companion services sign people in with
+// their Mu account. An admin registers each service as a client; the
+// service sends people to /oauth/authorize, exchanges the code it gets
+// back at /oauth/token with its secret, and receives an ID token and an
+// access token that's good for /oauth/userinfo and nothing else. Unlike
+// the MCP clients above, a service never gets a session.
+
+// Scopes a service can ask for.
+const (
+	ScopeOpenID  = "openid"  // the account's ID, as sub
+	ScopeProfile = "profile" // name, username and profile URL
+	ScopeEmail   = "email"   // verified email address
+)
+
+// oidcScopes lists every scope, in the order they're shown.
+var oidcScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// oidcTokenTTL is how long ID and access tokens last.
+const oidcTokenTTL = time.Hour
+
+// oidcToken is an access token issued to a service. Tokens are kept by
+// the hash of their value, so the store never holds a usable token.
+type oidcToken struct {
+	ClientID  string    `json:"client_id"`
+	AccountID string    `json:"account_id"`
+	Scope     string    `json:"scope"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var (
+	oidcTokens = map[string]*oidcToken{} // sha256 of the token → token; guarded by oauthMu
+
+	keyOnce sync.Once
+	signKey *rsa.PrivateKey
+	keyID   string
+)
+
+func init() {
+	data.LoadJSON("oidc_tokens.json", &oidcTokens)
+}
+
+func saveOIDCTokens() {
+	data.SaveJSON("oidc_tokens.json", oidcTokens)
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// RegisterServiceClient registers a companion service that signs people
+// in with OpenID Connect. Only the exact redirect URIs given are accepted.
+func RegisterServiceClient(name string, redirectURIs []string) *OAuthClient {
+	client := RegisterOAuthClient(name, redirectURIs)
+	oauthMu.Lock()
+	client.Service = true
+	saveOAuthClients()
+	oauthMu.Unlock()
+	return client
+}
+
+// signingKey returns the key ID tokens are signed with, creating and
+// storing it the first time.
+func signingKey() (*rsa.PrivateKey, string) {
+	keyOnce.Do(func() {
+		if b, err := data.LoadFile("oidc_key.pem"); err == nil {
+			if block, _ := pem.Decode(b); block != nil {
+				if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+					signKey = k
+				}
+			}
+		}
+		if signKey == nil {
+			k, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				panic("oidc: generating signing key: " + err.Error())
+			}
+			signKey = k
+			pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)})
+			data.SaveFile("oidc_key.pem", string(pemBytes))
+		}
+		h := sha256.Sum256(signKey.N.Bytes())
+		keyID = hex.EncodeToString(h[:8])
+	})
+	return signKey, keyID
+}
+
+// signJWT signs claims as an RS256 JSON Web Token.
+func signJWT(claims map[string]interface{}) (string, error) {
+	key, kid := signingKey()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	h := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// grantedScope keeps the scopes Mu knows from a requested scope string.
+func grantedScope(requested string) string {
+	asked := strings.Fields(requested)
+	var out []string
+	for _, s := range oidcScopes {
+		for _, a := range asked {
+			if a == s {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func hasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// userClaims returns what scope lets a service know about acc.
+func userClaims(issuer string, acc *Account, scope string) map[string]interface{} {
+	claims := map[string]interface{}{"sub": acc.ID}
+	if hasScope(scope, ScopeProfile) {
+		claims["name"] = acc.Name
+		claims["preferred_username"] = acc.ID
+		claims["profile"] = issuer + "/@" + acc.ID
+	}
+	if hasScope(scope, ScopeEmail) && acc.Email != "" && acc.EmailVerified {
+		claims["email"] = acc.Email
+		claims["email_verified"] = true
+	}
+	return claims
+}
+
+// validRedirect reports whether uri is one the client registered.
+func (c *OAuthClient) validRedirect(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeService handles /oauth/authorize for a service. People who
+// aren't signed in are sent to the login page and brought back.
+func authorizeService(w http.ResponseWriter, r *http.Request, c *OAuthClient) {
+	r.ParseForm()
+	redirectURI := r.Form.Get("redirect_uri")
+	if !c.validRedirect(redirectURI) {
+		// Never redirect to an address the service didn't register.
+		http.Error(w, "redirect_uri is not registered for this client", http.StatusBadRequest)
+		return
+	}
+	back := func(params url.Values) {
+		if state := r.Form.Get("state"); state != "" {
+			params.Set("state", state)
+		}
+		sep := "?"
+		if strings.Contains(redirectURI, "?") {
+			sep = "&"
+		}
+		http.Redirect(w, r, redirectURI+sep+params.Encode(), http.StatusFound)
+	}
+	if r.Form.Get("response_type") != "code" {
+		back(url.Values{"error": {"unsupported_response_type"}})
+		return
+	}
+	scope := grantedScope(r.Form.Get("scope"))
+	if !hasScope(scope, ScopeOpenID) {
+		back(url.Values{"error": {"invalid_scope"}, "error_description": {"the openid scope is required"}})
+		return
+	}
+
+	sess, acc := TrySession(r)
+	if sess == nil || acc == nil {
+		http.Redirect(w, r, "/login?redirect="+url.QueryEscape("/oauth/authorize?"+r.Form.Encode()), http.StatusSeeOther)
+		return
+	}
+	if acc.Banned {
+		back(url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	code := generateRandomString(32)
+	oauthMu.Lock()
+	oauthCodes[code] = &OAuthCode{
+		Code:                code,
+		ClientID:            c.ClientID,
+		AccountID:           acc.ID,
+		RedirectURI:         redirectURI,
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+		Scope:               scope,
+		Nonce:               r.Form.Get("nonce"),
+		ExpiresAt:           time.Now().Add(10 * time.Minute),
+	}
+	oauthMu.Unlock()
+	back(url.Values{"code": {code}})
+}
+
+// clientSecret returns the client credentials a token request carries, by
+// HTTP Basic auth or in the form.
+func clientSecret(r *http.Request) (id, secret string) {
+	if id, secret, ok := r.BasicAuth(); ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		return id, secret
+	}
+	return r.FormValue("client_id"), r.FormValue("client_secret")
+}
+
+// ServiceTokens is what a service gets for an authorization code.
+type ServiceTokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// ExchangeServiceCode redeems a service's authorization code for an ID
+// token and an access token for /oauth/userinfo.
+func ExchangeServiceCode(issuer, code, clientID, secret, redirectURI, codeVerifier string) (*ServiceTokens, error) {
+	c := GetOAuthClient(clientID)
+	if c == nil || !c.Service || subtle.ConstantTimeCompare([]byte(c.ClientSecret), []byte(secret)) != 1 {
+		return nil, errInvalidClient
+	}
+	authCode, err := redeemCode(code, clientID, redirectURI, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := GetAccount(authCode.AccountID)
+	if err != nil {
+		return nil, errors.New("account not found")
+	}
+
+	now := time.Now()
+	claims := userClaims(issuer, acc, authCode.Scope)
+	claims["iss"] = issuer
+	claims["aud"] = clientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(oidcTokenTTL).Unix()
+	if authCode.Nonce != "" {
+		claims["nonce"] = authCode.Nonce
+	}
+	idToken, err := signJWT(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	access := generateRandomString(43)
+	oauthMu.Lock()
+	for k, t := range oidcTokens {
+		if now.After(t.ExpiresAt) {
+			delete(oidcTokens, k)
+		}
+	}
+	oidcTokens[hashToken(access)] = &oidcToken{
+		ClientID:  clientID,
+		AccountID: acc.ID,
+		Scope:     authCode.Scope,
+		ExpiresAt: now.Add(oidcTokenTTL),
+	}
+	saveOIDCTokens()
+	oauthMu.Unlock()
+
+	return &ServiceTokens{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oidcTokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       authCode.Scope,
+	}, nil
+}
+
+var errInvalidClient = errors.New("invalid client credentials")
+
+// serviceToken returns the live access token issued to a service, and the
+// account it speaks for.
+func serviceToken(token string) (*oidcToken, *Account, error) {
+	oauthMu.Lock()
+	t := oidcTokens[hashToken(token)]
+	var client *OAuthClient
+	if t != nil {
+		client = oauthClients[t.ClientID]
+	}
+	oauthMu.Unlock()
+	if t == nil || time.Now().After(t.ExpiresAt) || client == nil {
+		return nil, nil, errors.New("invalid token")
+	}
+	acc, err := GetAccount(t.AccountID)
+	if err != nil || acc.Banned {
+		return nil, nil, errors.New("invalid token")
+	}
+	return t, acc, nil
+}
+
+// RevokeServiceTokens revokes every access token issued to a client, for
+// when it's deleted.
+func RevokeServiceTokens(clientID string) {
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	for k, t := range oidcTokens {
+		if t.ClientID == clientID {
+			delete(oidcTokens, k)
+		}
+	}
+	saveOIDCTokens()
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, desc string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": desc})
+}
+
+// serviceTokenHandler is /oauth/token for a service.
+func serviceTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, secret := clientSecret(r)
+	tokens, err := ExchangeServiceCode(getIssuer(r), r.FormValue("code"), id, secret, r.FormValue("redirect_uri"), r.FormValue("code_verifier"))
+	if err == errInvalidClient {
+		w.Header().Set("WWW-Authenticate", `Basic realm="mu"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", err.Error())
+		return
+	}
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// OAuthUserInfoHandler serves /oauth/userinfo: what a service's access
+// token lets it know about the signed in account.
+func OAuthUserInfoHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mu"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", "a bearer token is required")
+		return
+	}
+	t, acc, err := serviceToken(token)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mu", error="invalid_token"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(userClaims(getIssuer(r), acc, t.Scope))
+}
+
+// OIDCDiscoveryHandler serves /.well-known/openid-configuration.
+func OIDCDiscoveryHandler(w http.ResponseWriter, r *http.Request) {
+	issuer := getIssuer(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"scopes_supported":                      oidcScopes,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+		"claims_supported":                      []string{"sub", "name", "preferred_username", "profile", "email", "email_verified"},
+	})
+}
+
+// JWKSHandler serves /.well-known/jwks.json, the key ID tokens are signed
+// with.
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	key, kid := signingKey()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifyIDToken checks token against the key published at JWKSHandler and
+// returns its claims.
+func verifyIDToken(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	JWKSHandler(rec, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	var jwks struct {
+		Keys []struct{ Kid, N, E string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &jwks); err != nil || len(jwks.Keys) != 1 {
+		t.Fatalf("jwks = %s", rec.Body.String())
+	}
+	n, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].N)
+	e, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].E)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("id_token has %d parts", len(parts))
+	}
+	var header struct{ Alg, Kid string }
+	b, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	json.Unmarshal(b, &header)
+	if header.Alg != "RS256" || header.Kid != jwks.Keys[0].Kid {
+		t.Errorf("header = %+v", header)
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], sig); err != nil {
+		t.Fatalf("id_token signature: %v", err)
+	}
+	var claims map[string]interface{}
+	b, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(b, &claims)
+	return claims
+}
+
+func TestServiceSignIn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	resetOAuthTestState(t)
+	oauthMu.Lock()
+	savedTokens := oidcTokens
+	oidcTokens = map[string]*oidcToken{}
+	oauthMu.Unlock()
+	t.Cleanup(func() {
+		oauthMu.Lock()
+		oidcTokens = savedTokens
+		oauthMu.Unlock()
+	})
+
+	mutex.Lock()
+	accounts["alice"] = &Account{ID: "alice", Name: "Alice", Email: "alice@example.com", EmailVerified: true, Created: time.Now()}
+	mutex.Unlock()
+	sess, err := CreateSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := RegisterServiceClient("Wiki", []string{"https://wiki.example/callback"})
+
+	authorize := func(query url.Values, signedIn bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://mu.example/oauth/authorize?"+query.Encode(), nil)
+		if signedIn {
+			req.Header.Set("Authorization", "Bearer "+sess.Token)
+		}
+		rec := httptest.NewRecorder()
+		OAuthAuthorizePostHandler(rec, req)
+		return rec
+	}
+	query := url.Values{
+		"client_id":     {client.ClientID},
+		"redirect_uri":  {"https://wiki.example/callback"},
+		"response_type": {"code"},
+		"scope":         {"openid profile email"},
+		"state":         {"xyz"},
+		"nonce":         {"n-0S6"},
+	}
+
+	// Unregistered redirect URIs are refused, not redirected to.
+	bad := url.Values{}
+	for k, v := range query {
+		bad[k] = v
+	}
+	bad.Set("redirect_uri", "https://evil.example/callback")
+	if rec := authorize(bad, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("unregistered redirect_uri: status = %d", rec.Code)
+	}
+
+	// Signed out, people are sent to log in and brought back.
+	rec := authorize(query, false)
+	if loc := rec.Header().Get("Location"); !strings.HasPrefix(loc, "/login?redirect=%2Foauth%2Fauthorize") {
+		t.Errorf("signed out: Location = %q", loc)
+	}
+
+	rec = authorize(query, true)
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || loc.Host != "wiki.example" || loc.Query().Get("state") != "xyz" {
+		t.Fatalf("signed in: Location = %q", rec.Header().Get("Location"))
+	}
+	code := loc.Query().Get("code")
+
+	// The MCP exchange never hands a service a session.
+	if _, err := ExchangeAuthorizationCode(code, client.ClientID, "https://wiki.example/callback", ""); err == nil {
+		t.Fatal("service code exchanged without its secret")
+	}
+
+	token := func(secret string) *httptest.ResponseRecorder {
+		form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"https://wiki.example/callback"}}
+		req := httptest.NewRequest("POST", "http://mu.example/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(client.ClientID, secret)
+		rec := httptest.NewRecorder()
+		OAuthTokenHandler(rec, req)
+		return rec
+	}
+	if rec := token("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: status = %d", rec.Code)
+	}
+	rec = token(client.ClientSecret)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("token: status = %d %s", rec.Code, rec.Body.String())
+	}
+	var tokens ServiceTokens
+	json.Unmarshal(rec.Body.Bytes(), &tokens)
+
+	claims := verifyIDToken(t, tokens.IDToken)
+	if claims["sub"] != "alice" || claims["aud"] != client.ClientID || claims["iss"] != "https://mu.example" ||
+		claims["nonce"] != "n-0S6" || claims["email"] != "alice@example.com" {
+		t.Errorf("id_token claims = %v", claims)
+	}
+	if rec := token(client.ClientSecret); rec.Code != http.StatusBadRequest {
+		t.Errorf("code reused: status = %d", rec.Code)
+	}
+
+	userinfo := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://mu.example/oauth/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		rec := httptest.NewRecorder()
+		OAuthUserInfoHandler(rec, req)
+		return rec
+	}
+	rec = userinfo()
+	var info map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &info)
+	if rec.Code != http.StatusOK || info["preferred_username"] != "alice" || info["name"] != "Alice" {
+		t.Errorf("userinfo = %d %v", rec.Code, info)
+	}
+
+	// The access token isn't a session, and dies with its client.
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	if s, _ := TrySession(req); s != nil {
+		t.Error("access token accepted as a session")
+	}
+	DeleteOAuthClient(client.ClientID)
+	if rec := userinfo(); rec.Code != http.StatusUnauthorized {
+		t.Errorf("userinfo after client deleted: status = %d", rec.Code)
+	}
+}
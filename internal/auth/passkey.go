@@ -29,9 +29,18 @@ type WebAuthnUser struct {
 }
 
 func (u *WebAuthnUser) WebAuthnID() []byte {
+	if u.account.WebAuthnID != "" {
+		return []byte(u.account.WebAuthnID)
+	}
 	return []byte(u.account.ID)
 }
 
+// AccountID is the ID of the account the user belongs to. It differs from
+// the WebAuthn ID once the username has been changed.
+func (u *WebAuthnUser) AccountID() string {
+	return u.account.ID
+}
+
 func (u *WebAuthnUser) WebAuthnName() string {
 	return u.account.ID
 }
@@ -60,9 +69,20 @@ func NewWebAuthnUser(acc *Account) *WebAuthnUser {
 	return &WebAuthnUser{account: acc, creds: creds}
 }
 
-// FindUserByWebAuthnID looks up an account by its WebAuthn user handle
-func FindUserByWebAuthnID(userHandle []byte) (*WebAuthnUser, error) {
-	acc, err := GetAccount(string(userHandle))
+// FindUserByWebAuthnID looks up an account by the passkey used, or else
+// by its WebAuthn user handle (which is a username the account may have
+// since changed)
+func FindUserByWebAuthnID(rawID, userHandle []byte) (*WebAuthnUser, error) {
+	mutex.Lock()
+	id := string(userHandle)
+	for _, pk := range passkeys {
+		if string(pk.Credential.ID) == string(rawID) {
+			id = pk.Account
+			break
+		}
+	}
+	mutex.Unlock()
+	acc, err := GetAccount(id)
 	if err != nil {
 		return nil, err
 	}
@@ -198,7 +198,7 @@ func TestFindUserByWebAuthnID(t *testing.T) {
 	}
 	mutex.Unlock()
 
-	user, err := FindUserByWebAuthnID([]byte("findtest"))
+	user, err := FindUserByWebAuthnID(nil, []byte("findtest"))
 	if err != nil {
 		t.Fatalf("FindUserByWebAuthnID failed: %v", err)
 	}
@@ -207,7 +207,7 @@ func TestFindUserByWebAuthnID(t *testing.T) {
 	}
 
 	// Non-existent user
-	_, err = FindUserByWebAuthnID([]byte("nosuchuser"))
+	_, err = FindUserByWebAuthnID(nil, []byte("nosuchuser"))
 	if err == nil {
 		t.Error("expected error for non-existent user")
 	}
@@ -0,0 +1,538 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"mu/internal/data"
+)
+
+// ============================================================
+// Account recovery via trusted contacts
+// ============================================================
+
+// A member can name two or three other members as trusted contacts.
+// Someone locked out of the account starts a recovery request and is
+// given a private key to follow it. The contacts see the request when
+// they sign in, and once a quorum approve within RecoveryWindow the key
+// holder can claim a single-use reset token and set a new password.
+// Every step is recorded on the request, and the app layer emails the
+// account's address throughout so the owner can cancel a request they
+// didn't make.
+
+const (
+	// RecoveryWindow is how long contacts have to approve a request.
+	RecoveryWindow = 48 * time.Hour
+	// RecoveryTokenTTL is how long a claimed reset token stays valid.
+	RecoveryTokenTTL = time.Hour
+
+	minRecoveryContacts = 2
+	maxRecoveryContacts = 3
+	recoveryQuorum      = 2 // approvals needed: both of two, or two of three
+	recoveryPerAccount  = 3 // requests per account per day
+)
+
+// Recovery request states.
+const (
+	RecoveryPending   = "pending"
+	RecoveryApproved  = "approved"
+	RecoveryCompleted = "completed"
+	RecoveryCancelled = "cancelled"
+	RecoveryExpired   = "expired"
+)
+
+// RecoveryEvent is one logged step of a recovery request.
+type RecoveryEvent struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+// RecoveryRequest is an attempt to regain access to an account.
+type RecoveryRequest struct {
+	ID           string               `json:"id"`
+	AccountID    string               `json:"account_id"`
+	Contacts     []string             `json:"contacts"`
+	Quorum       int                  `json:"quorum"`
+	Approvals    map[string]time.Time `json:"approvals"`
+	Device       string               `json:"device"`
+	IP           string               `json:"ip"`
+	Created      time.Time            `json:"created"`
+	Expires      time.Time            `json:"expires"`
+	Status       string               `json:"status"`
+	KeyHash      string               `json:"key_hash"`   // requester's key, hashed
+	TokenHash    string               `json:"token_hash"` // reset token, hashed, once claimed
+	TokenExpires time.Time            `json:"token_expires,omitempty"`
+	Events       []RecoveryEvent      `json:"events"`
+}
+
+// Open reports whether the request can still be approved or redeemed.
+func (q *RecoveryRequest) Open(now time.Time) bool {
+	switch q.Status {
+	case RecoveryPending:
+		return now.Before(q.Expires)
+	case RecoveryApproved:
+		return q.TokenHash == "" || now.Before(q.TokenExpires)
+	}
+	return false
+}
+
+// clone copies a request for use outside recoveryMu.
+func (q *RecoveryRequest) clone() *RecoveryRequest {
+	c := *q
+	c.Contacts = append([]string(nil), q.Contacts...)
+	c.Events = append([]RecoveryEvent(nil), q.Events...)
+	c.Approvals = make(map[string]time.Time, len(q.Approvals))
+	for k, v := range q.Approvals {
+		c.Approvals[k] = v
+	}
+	return &c
+}
+
+func (q *RecoveryRequest) log(now time.Time, text string) {
+	q.Events = append(q.Events, RecoveryEvent{Time: now, Text: text})
+}
+
+var (
+	recoveryMu       sync.Mutex
+	recoveryContacts = map[string][]string{}         // account ID → trusted contact IDs
+	recoveryRequests = map[string]*RecoveryRequest{} // request ID → request
+)
+
+type recoveryFile struct {
+	Contacts map[string][]string         `json:"contacts"`
+	Requests map[string]*RecoveryRequest `json:"requests"`
+}
+
+func init() {
+	var f recoveryFile
+	data.LoadJSON("recovery.json", &f)
+	if f.Contacts != nil {
+		recoveryContacts = f.Contacts
+	}
+	if f.Requests != nil {
+		recoveryRequests = f.Requests
+	}
+	AccountDeleteHooks = append(AccountDeleteHooks, forgetRecovery)
+	AccountRenameHooks = append(AccountRenameHooks, renameRecovery)
+}
+
+// saveRecovery writes recovery.json, dropping requests finished more than
+// a month ago (caller must hold recoveryMu).
+func saveRecovery() {
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	for id, q := range recoveryRequests {
+		if q.Created.Before(cutoff) {
+			delete(recoveryRequests, id)
+		}
+	}
+	data.SaveJSON("recovery.json", recoveryFile{Contacts: recoveryContacts, Requests: recoveryRequests})
+}
+
+// forgetRecovery removes a deleted account's contacts, its requests, and
+// it from anyone else's contacts.
+func forgetRecovery(accountID string) {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	delete(recoveryContacts, accountID)
+	for owner, list := range recoveryContacts {
+		for i, c := range list {
+			if c == accountID {
+				recoveryContacts[owner] = append(list[:i:i], list[i+1:]...)
+				break
+			}
+		}
+	}
+	for id, q := range recoveryRequests {
+		if q.AccountID == accountID {
+			delete(recoveryRequests, id)
+		}
+	}
+	saveRecovery()
+}
+
+// renameRecovery moves a renamed account's contacts and requests, and
+// renames it in anyone else's contacts and approvals.
+func renameRecovery(oldID, newID string) {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	if list, ok := recoveryContacts[oldID]; ok {
+		delete(recoveryContacts, oldID)
+		recoveryContacts[newID] = list
+	}
+	for _, list := range recoveryContacts {
+		for i, c := range list {
+			if c == oldID {
+				list[i] = newID
+			}
+		}
+	}
+	for _, q := range recoveryRequests {
+		if q.AccountID == oldID {
+			q.AccountID = newID
+		}
+		for i, c := range q.Contacts {
+			if c == oldID {
+				q.Contacts[i] = newID
+			}
+		}
+		if t, ok := q.Approvals[oldID]; ok {
+			delete(q.Approvals, oldID)
+			q.Approvals[newID] = t
+		}
+	}
+	saveRecovery()
+}
+
+// RecoveryContacts returns the account's trusted contacts.
+func RecoveryContacts(accountID string) []string {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	return append([]string(nil), recoveryContacts[accountID]...)
+}
+
+// TrustedBy returns the accounts that name contactID as a trusted contact.
+func TrustedBy(contactID string) []string {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	var out []string
+	for owner, list := range recoveryContacts {
+		for _, c := range list {
+			if c == contactID {
+				out = append(out, owner)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SetRecoveryContacts replaces the account's trusted contacts. It takes
+// two or three other existing members, or none to turn recovery off. The
+// account needs a verified email so it can be told about requests.
+func SetRecoveryContacts(accountID string, contacts []string) error {
+	acc, err := GetAccount(accountID)
+	if err != nil {
+		return err
+	}
+	var list []string
+	seen := map[string]bool{}
+	for _, c := range contacts {
+		c = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c), "@"))
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if c == accountID {
+			return errors.New("you can't be your own trusted contact")
+		}
+		if contact, err := GetAccount(c); err != nil || contact.Banned {
+			return errors.New("no member @" + c)
+		}
+		list = append(list, c)
+	}
+	if len(list) > 0 {
+		if len(list) < minRecoveryContacts || len(list) > maxRecoveryContacts {
+			return errors.New("choose two or three trusted contacts")
+		}
+		if acc.Email == "" || !acc.EmailVerified {
+			return errors.New("verify your email first — recovery notices are sent there")
+		}
+	}
+
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	if len(list) == 0 {
+		delete(recoveryContacts, accountID)
+	} else {
+		recoveryContacts[accountID] = list
+	}
+	// Requests in flight were approved against the old contacts.
+	now := time.Now()
+	for _, q := range recoveryRequests {
+		if q.AccountID == accountID && q.Open(now) {
+			q.Status = RecoveryCancelled
+			q.log(now, "cancelled: trusted contacts changed")
+		}
+	}
+	saveRecovery()
+	return nil
+}
+
+// StartRecovery opens a recovery request for the account identified by
+// username or email. It returns the request and the requester's key, which
+// is needed to follow it and is shown only once. Any earlier open request
+// for the account is cancelled.
+func StartRecovery(login, ip, device string) (*RecoveryRequest, string, error) {
+	login = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(login), "@"))
+	if login == "" {
+		return nil, "", errors.New("username or email is required")
+	}
+	var acc *Account
+	if strings.Contains(login, "@") {
+		acc, _ = GetAccountByEmail(login)
+	} else {
+		acc, _ = GetAccount(login)
+	}
+	if acc == nil || acc.Banned {
+		return nil, "", errors.New("no account with trusted contacts matches")
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	key := base64.RawURLEncoding.EncodeToString(b)
+
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	contacts := recoveryContacts[acc.ID]
+	if len(contacts) < minRecoveryContacts {
+		return nil, "", errors.New("no account with trusted contacts matches")
+	}
+	now := time.Now()
+	recent := 0
+	for _, q := range recoveryRequests {
+		if q.AccountID == acc.ID && now.Sub(q.Created) < 24*time.Hour {
+			recent++
+		}
+	}
+	if recent >= recoveryPerAccount {
+		return nil, "", errors.New("too many recovery requests for this account — please try again tomorrow")
+	}
+	for _, q := range recoveryRequests {
+		if q.AccountID == acc.ID && q.Open(now) {
+			q.Status = RecoveryCancelled
+			q.log(now, "cancelled: replaced by a new request")
+		}
+	}
+	q := &RecoveryRequest{
+		ID:        uuid.New().String(),
+		AccountID: acc.ID,
+		Contacts:  append([]string(nil), contacts...),
+		Quorum:    recoveryQuorum,
+		Approvals: map[string]time.Time{},
+		Device:    device,
+		IP:        ip,
+		Created:   now,
+		Expires:   now.Add(RecoveryWindow),
+		Status:    RecoveryPending,
+		KeyHash:   hashRefreshToken(key),
+	}
+	q.log(now, "started from "+device+" ("+ip+")")
+	recoveryRequests[q.ID] = q
+	saveRecovery()
+	return q.clone(), key, nil
+}
+
+// expireRecovery marks a lapsed request expired (caller must hold
+// recoveryMu).
+func expireRecovery(q *RecoveryRequest, now time.Time) {
+	if q.Status == RecoveryPending && !now.Before(q.Expires) {
+		q.Status = RecoveryExpired
+		q.log(now, "expired without enough approvals")
+		saveRecovery()
+	}
+}
+
+// GetRecovery returns a request to the holder of its key.
+func GetRecovery(id, key string) (*RecoveryRequest, error) {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	q, ok := recoveryRequests[id]
+	if !ok || key == "" || q.KeyHash != hashRefreshToken(key) {
+		return nil, errors.New("recovery request not found")
+	}
+	expireRecovery(q, time.Now())
+	return q.clone(), nil
+}
+
+// PendingRecoveries returns the open requests contactID has been asked to
+// approve and hasn't yet.
+func PendingRecoveries(contactID string) []RecoveryRequest {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	now := time.Now()
+	var out []RecoveryRequest
+	for _, q := range recoveryRequests {
+		expireRecovery(q, now)
+		if q.Status != RecoveryPending {
+			continue
+		}
+		if _, done := q.Approvals[contactID]; done {
+			continue
+		}
+		for _, c := range q.Contacts {
+			if c == contactID {
+				out = append(out, *q.clone())
+				break
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out
+}
+
+// AccountRecoveries returns the account's requests, newest first.
+func AccountRecoveries(accountID string) []RecoveryRequest {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	now := time.Now()
+	var out []RecoveryRequest
+	for _, q := range recoveryRequests {
+		if q.AccountID == accountID {
+			expireRecovery(q, now)
+			out = append(out, *q.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out
+}
+
+// ApproveRecovery records contactID's approval of a request. The returned
+// request's Status is RecoveryApproved once the quorum is reached.
+func ApproveRecovery(id, contactID string) (*RecoveryRequest, error) {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	q, ok := recoveryRequests[id]
+	if !ok {
+		return nil, errors.New("recovery request not found")
+	}
+	now := time.Now()
+	expireRecovery(q, now)
+	if q.Status != RecoveryPending {
+		return nil, errors.New("this recovery request is no longer open")
+	}
+	trusted := false
+	for _, c := range q.Contacts {
+		if c == contactID {
+			trusted = true
+		}
+	}
+	if !trusted {
+		return nil, errors.New("you are not a trusted contact for this account")
+	}
+	if _, done := q.Approvals[contactID]; done {
+		return nil, errors.New("you have already approved this request")
+	}
+	q.Approvals[contactID] = now
+	q.log(now, "approved by @"+contactID)
+	if len(q.Approvals) >= q.Quorum {
+		q.Status = RecoveryApproved
+		q.log(now, "quorum reached")
+	}
+	saveRecovery()
+	return q.clone(), nil
+}
+
+// DeclineRecovery lets a contact, or the account owner, stop a request.
+func DeclineRecovery(id, accountID string) (*RecoveryRequest, error) {
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	q, ok := recoveryRequests[id]
+	if !ok {
+		return nil, errors.New("recovery request not found")
+	}
+	allowed := q.AccountID == accountID
+	for _, c := range q.Contacts {
+		if c == accountID {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, errors.New("recovery request not found")
+	}
+	now := time.Now()
+	if !q.Open(now) {
+		return nil, errors.New("this recovery request is no longer open")
+	}
+	q.Status = RecoveryCancelled
+	if accountID == q.AccountID {
+		q.log(now, "cancelled by the account owner")
+	} else {
+		q.log(now, "declined by @"+accountID)
+	}
+	saveRecovery()
+	return q.clone(), nil
+}
+
+// ClaimRecoveryToken issues the reset token for an approved request to the
+// holder of its key. It can be claimed once and lasts RecoveryTokenTTL.
+func ClaimRecoveryToken(id, key string) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	q, ok := recoveryRequests[id]
+	if !ok || key == "" || q.KeyHash != hashRefreshToken(key) {
+		return "", errors.New("recovery request not found")
+	}
+	if q.Status != RecoveryApproved {
+		return "", errors.New("this recovery request hasn't been approved")
+	}
+	if q.TokenHash != "" {
+		return "", errors.New("the reset token for this request was already issued")
+	}
+	now := time.Now()
+	q.TokenHash = hashRefreshToken(token)
+	q.TokenExpires = now.Add(RecoveryTokenTTL)
+	q.log(now, "reset token issued")
+	saveRecovery()
+	return token, nil
+}
+
+// RedeemRecoveryToken sets a new password with a reset token, signs out
+// the account's other sessions and starts a new one.
+func RedeemRecoveryToken(token, secret string) (*Session, *RecoveryRequest, error) {
+	if len(secret) < 6 {
+		return nil, nil, errors.New("password must be at least 6 characters")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), 10)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recoveryMu.Lock()
+	var q *RecoveryRequest
+	now := time.Now()
+	if token != "" {
+		h := hashRefreshToken(token)
+		for _, r := range recoveryRequests {
+			if r.TokenHash == h {
+				q = r
+				break
+			}
+		}
+	}
+	if q == nil || q.Status != RecoveryApproved || !now.Before(q.TokenExpires) {
+		recoveryMu.Unlock()
+		return nil, nil, errors.New("this reset token is invalid or has expired")
+	}
+	q.Status = RecoveryCompleted
+	q.log(now, "password reset")
+	saveRecovery()
+	c := q.clone()
+	recoveryMu.Unlock()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	acc, ok := accounts[c.AccountID]
+	if !ok || acc.Banned {
+		return nil, nil, errors.New("this account is not available")
+	}
+	acc.Secret = string(hash)
+	endSessions(acc.ID)
+	data.SaveJSON("accounts.json", accounts)
+	return newSession(acc.ID, false), c, nil
+}
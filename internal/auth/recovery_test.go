@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func resetRecoveryStateForTest(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	mutex.Lock()
+	accounts = map[string]*Account{}
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		accounts[id] = &Account{ID: id, Name: id, Email: id + "@example.com", EmailVerified: true, Created: time.Now()}
+	}
+	sessions = map[string]*Session{
+		"old": {ID: "old", Type: "account", Account: "alice", Created: time.Now(), Expires: time.Now().Add(time.Hour)},
+	}
+	mutex.Unlock()
+
+	recoveryMu.Lock()
+	recoveryContacts = map[string][]string{}
+	recoveryRequests = map[string]*RecoveryRequest{}
+	recoveryMu.Unlock()
+}
+
+func TestRecoveryNeedsQuorumOfContacts(t *testing.T) {
+	resetRecoveryStateForTest(t)
+
+	if err := SetRecoveryContacts("alice", []string{"bob"}); err == nil {
+		t.Error("accepted a single contact")
+	}
+	if err := SetRecoveryContacts("alice", []string{"bob", "alice"}); err == nil {
+		t.Error("accepted self as a contact")
+	}
+	if err := SetRecoveryContacts("alice", []string{"@Bob", "carol", "dave"}); err != nil {
+		t.Fatalf("SetRecoveryContacts = %v", err)
+	}
+
+	q, key, err := StartRecovery("alice@example.com", "10.0.0.1", "Firefox on Linux")
+	if err != nil || q.Quorum != 2 {
+		t.Fatalf("StartRecovery = %+v, %v", q, err)
+	}
+	if _, err := ClaimRecoveryToken(q.ID, key); err == nil {
+		t.Error("claimed a token before any approvals")
+	}
+	if _, err := ApproveRecovery(q.ID, "mallory"); err == nil {
+		t.Error("a stranger approved the request")
+	}
+	if got := PendingRecoveries("bob"); len(got) != 1 {
+		t.Fatalf("bob should have one request to review, got %d", len(got))
+	}
+
+	q, _ = ApproveRecovery(q.ID, "bob")
+	if q.Status != RecoveryPending {
+		t.Fatalf("one approval reached quorum: %s", q.Status)
+	}
+	if _, err := ApproveRecovery(q.ID, "bob"); err == nil {
+		t.Error("bob approved twice")
+	}
+	q, _ = ApproveRecovery(q.ID, "dave")
+	if q.Status != RecoveryApproved {
+		t.Fatalf("two approvals didn't reach quorum: %s", q.Status)
+	}
+
+	if _, err := ClaimRecoveryToken(q.ID, "wrong"); err == nil {
+		t.Error("claimed a token without the key")
+	}
+	tok, err := ClaimRecoveryToken(q.ID, key)
+	if err != nil {
+		t.Fatalf("ClaimRecoveryToken = %v", err)
+	}
+	if _, err := ClaimRecoveryToken(q.ID, key); err == nil {
+		t.Error("token issued twice")
+	}
+
+	sess, done, err := RedeemRecoveryToken(tok, "new-secret")
+	if err != nil || sess.Account != "alice" || done.Status != RecoveryCompleted {
+		t.Fatalf("RedeemRecoveryToken = %+v, %+v, %v", sess, done, err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(accounts["alice"].Secret), []byte("new-secret")) != nil {
+		t.Error("password not changed")
+	}
+	if _, ok := sessions["old"]; ok {
+		t.Error("old session survived the reset")
+	}
+	if _, _, err := RedeemRecoveryToken(tok, "another"); err == nil {
+		t.Error("token worked twice")
+	}
+	if len(done.Events) < 5 {
+		t.Errorf("expected each step logged, got %+v", done.Events)
+	}
+}
+
+func TestRecoveryExpiresAndCanBeCancelled(t *testing.T) {
+	resetRecoveryStateForTest(t)
+	SetRecoveryContacts("alice", []string{"bob", "carol"})
+
+	q, _, _ := StartRecovery("alice", "", "")
+	recoveryMu.Lock()
+	recoveryRequests[q.ID].Expires = time.Now().Add(-time.Second)
+	recoveryMu.Unlock()
+	if _, err := ApproveRecovery(q.ID, "bob"); err == nil {
+		t.Error("approved an expired request")
+	}
+
+	q, _, _ = StartRecovery("alice", "", "")
+	if _, err := DeclineRecovery(q.ID, "dave"); err == nil {
+		t.Error("an unrelated member cancelled the request")
+	}
+	if _, err := DeclineRecovery(q.ID, "alice"); err != nil {
+		t.Fatalf("owner cancel = %v", err)
+	}
+	if _, err := ApproveRecovery(q.ID, "bob"); err == nil {
+		t.Error("approved a cancelled request")
+	}
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"mu/internal/data"
+)
+
+// Username changes. An account's ID is its username: it is the /@name
+// URL and the key every building block stores the member's data under.
+// A change re-keys the account, its sessions, tokens and passkeys here,
+// then runs AccountRenameHooks so each block moves its own data. The old
+// name redirects to the new one for UsernameRedirectPeriod and nobody
+// else can register it in that time; the member can change again after
+// UsernameChangeInterval.
+
+const (
+	UsernameChangeInterval = 30 * 24 * time.Hour
+	UsernameRedirectPeriod = 90 * 24 * time.Hour
+)
+
+var (
+	ErrUsernameUnchanged = errors.New("that's already your username")
+	ErrUsernameTaken     = errors.New("that username is taken")
+)
+
+// Rename is a username change, kept so the old name can redirect.
+type Rename struct {
+	From string    `json:"from"`
+	To   string    `json:"to"` // the account's current name, followed through later changes
+	At   time.Time `json:"at"`
+}
+
+var (
+	renamesMu sync.Mutex
+	renames   = map[string]*Rename{} // old name → rename
+)
+
+// AccountRenameHooks are called after an account's ID changes, with the
+// old and new IDs. Like AccountDeleteHooks, each building block registers
+// one to move its own data, so auth doesn't import them.
+var AccountRenameHooks []func(oldID, newID string)
+
+func init() {
+	data.LoadJSON("renames.json", &renames)
+	if renames == nil {
+		renames = map[string]*Rename{}
+	}
+}
+
+// reservedName reports whether a name was given up too recently for
+// anyone but its last owner, now called owner, to take. Caller must hold
+// renamesMu.
+func reservedName(name, owner string) bool {
+	r, ok := renames[name]
+	return ok && r.To != owner && time.Since(r.At) < UsernameRedirectPeriod
+}
+
+// RenamedTo returns the current username of an account that used to be
+// called name, while the old name still redirects.
+func RenamedTo(name string) (string, bool) {
+	renamesMu.Lock()
+	defer renamesMu.Unlock()
+	r, ok := renames[name]
+	if !ok || time.Since(r.At) >= UsernameRedirectPeriod {
+		return "", false
+	}
+	return r.To, true
+}
+
+// NextUsernameChange returns when the account may next change its
+// username; zero means now.
+func NextUsernameChange(acc *Account) time.Time {
+	if acc.UsernameChangedAt.IsZero() {
+		return time.Time{}
+	}
+	next := acc.UsernameChangedAt.Add(UsernameChangeInterval)
+	if time.Now().After(next) {
+		return time.Time{}
+	}
+	return next
+}
+
+// RenameAccount changes an account's username from oldID to newID and
+// moves its data across. A display name that was just the old username
+// follows it.
+func RenameAccount(oldID, newID string) error {
+	if newID == oldID {
+		return ErrUsernameUnchanged
+	}
+	if reason := ValidateUsername(newID); reason != "" {
+		return errors.New(reason)
+	}
+
+	mutex.Lock()
+	acc, ok := accounts[oldID]
+	if !ok {
+		mutex.Unlock()
+		return errors.New("account does not exist")
+	}
+	if next := NextUsernameChange(acc); !next.IsZero() {
+		mutex.Unlock()
+		return fmt.Errorf("you can change your username again on %s", next.Format("2 Jan 2006"))
+	}
+	if _, exists := accounts[newID]; exists {
+		mutex.Unlock()
+		return ErrUsernameTaken
+	}
+	renamesMu.Lock()
+	if reservedName(newID, oldID) {
+		renamesMu.Unlock()
+		mutex.Unlock()
+		return ErrUsernameTaken
+	}
+
+	now := time.Now()
+	// Passkeys carry the user handle they were made with, so it stays put.
+	if acc.WebAuthnID == "" {
+		acc.WebAuthnID = oldID
+	}
+	if acc.Name == oldID {
+		acc.Name = newID
+	}
+	acc.ID = newID
+	acc.UsernameChangedAt = now
+	delete(accounts, oldID)
+	accounts[newID] = acc
+
+	for _, sess := range sessions {
+		if sess.Account == oldID {
+			sess.Account = newID
+		}
+	}
+	for _, tok := range tokens {
+		if tok.Account == oldID {
+			tok.Account = newID
+		}
+	}
+	for _, pk := range passkeys {
+		if pk.Account == oldID {
+			pk.Account = newID
+		}
+	}
+	for _, a := range accounts {
+		if a.InvitedBy == oldID {
+			a.InvitedBy = newID
+		}
+	}
+
+	// Earlier names now lead here; taking back a recent name clears it.
+	for _, r := range renames {
+		if r.To == oldID {
+			r.To = newID
+		}
+	}
+	delete(renames, newID)
+	renames[oldID] = &Rename{From: oldID, To: newID, At: now}
+	data.SaveJSON("renames.json", renames)
+	renamesMu.Unlock()
+
+	data.SaveJSON("accounts.json", accounts)
+	data.SaveJSON("sessions.json", sessions)
+	data.SaveJSON("tokens.json", tokens)
+	data.SaveJSON("passkeys.json", passkeys)
+	mutex.Unlock()
+
+	renameInvites(oldID, newID)
+
+	presenceMutex.Lock()
+	if t, ok := userPresence[oldID]; ok {
+		delete(userPresence, oldID)
+		userPresence[newID] = t
+	}
+	presenceMutex.Unlock()
+
+	// Run the hooks outside the lock, before returning, so the member's
+	// data is in place by the time they land on their new profile.
+	for _, hook := range AccountRenameHooks {
+		hook(oldID, newID)
+	}
+	return nil
+}
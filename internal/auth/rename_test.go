@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenameAccount(t *testing.T) {
+	resetRecoveryStateForTest(t)
+	renamesMu.Lock()
+	renames = map[string]*Rename{}
+	renamesMu.Unlock()
+	savedHooks := AccountRenameHooks
+	t.Cleanup(func() { AccountRenameHooks = savedHooks })
+
+	var moved [][2]string
+	AccountRenameHooks = []func(oldID, newID string){func(oldID, newID string) {
+		moved = append(moved, [2]string{oldID, newID})
+	}}
+
+	if err := RenameAccount("alice", "carol"); err != ErrUsernameTaken {
+		t.Fatalf("took an existing username: %v", err)
+	}
+	if err := RenameAccount("alice", "admin"); err == nil {
+		t.Fatal("took a reserved username")
+	}
+	if err := RenameAccount("alice", "alicia"); err != nil {
+		t.Fatal(err)
+	}
+
+	acc, err := GetAccount("alicia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Name != "alicia" || acc.WebAuthnID != "alice" {
+		t.Errorf("renamed account = %+v", acc)
+	}
+	if _, err := GetAccount("alice"); err == nil {
+		t.Error("old username still an account")
+	}
+	if sessions["old"].Account != "alicia" {
+		t.Error("session not moved")
+	}
+	if len(moved) != 1 || moved[0] != [2]string{"alice", "alicia"} {
+		t.Errorf("hooks ran with %v", moved)
+	}
+	if to, ok := RenamedTo("alice"); !ok || to != "alicia" {
+		t.Errorf("RenamedTo(alice) = %q, %v", to, ok)
+	}
+
+	// The old name is held: nobody else can sign up with it...
+	if err := Create(&Account{ID: "alice", Name: "alice", Secret: "secret", Created: time.Now()}); err == nil {
+		t.Error("old username registered by someone else")
+	}
+
+	// ...and a second change has to wait.
+	if err := RenameAccount("alicia", "alice"); err == nil {
+		t.Error("changed username again too soon")
+	}
+
+	// Its owner can take it back once allowed, and the redirect goes.
+	acc.UsernameChangedAt = time.Now().Add(-UsernameChangeInterval - time.Hour)
+	if err := RenameAccount("alicia", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := RenamedTo("alice"); ok {
+		t.Error("reclaimed username still redirects")
+	}
+	if to, _ := RenamedTo("alicia"); to != "alice" {
+		t.Errorf("RenamedTo(alicia) = %q", to)
+	}
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"mu/internal/data"
+)
+
+// ============================================================
+// Password reset by email
+// ============================================================
+
+// A password reset link is emailed to the account's verified address. It
+// works once, expires after PasswordResetTTL, and only its hash is kept,
+// in memory: a restart means asking for a new one. Using it sets the new
+// password and signs out every other device.
+
+// PasswordResetTTL is how long a reset link stays valid.
+const PasswordResetTTL = time.Hour
+
+// Per-account and per-IP request limits, over passwordResetWindow.
+const (
+	passwordResetPerAccount = 3
+	passwordResetPerIP      = 10
+	passwordResetWindow     = time.Hour
+)
+
+// ErrPasswordResetRateLimited is returned when too many resets were
+// requested from one IP.
+var ErrPasswordResetRateLimited = errors.New("too many password resets requested — please wait a while and try again")
+
+// ErrPasswordResetAccountLimited is returned when an account has been sent
+// too many links. It's only returned for accounts that exist, so callers
+// should reply as they would to an unknown name.
+var ErrPasswordResetAccountLimited = errors.New("too many password resets requested for this account")
+
+var errPasswordResetInvalid = errors.New("this reset link is invalid or has expired — please request a new one")
+
+type passwordReset struct {
+	AccountID string
+	ExpiresAt time.Time
+}
+
+var (
+	resetMu       sync.Mutex
+	resetTokens   = map[string]*passwordReset{} // token hash → reset
+	resetRequests = map[string][]time.Time{}    // "acc:id" / "ip:addr" → request times
+)
+
+// resetAllow records a request against key and reports whether it is
+// within max per passwordResetWindow (caller must hold resetMu).
+func resetAllow(key string, max int, now time.Time) bool {
+	var recent []time.Time
+	for _, t := range resetRequests[key] {
+		if now.Sub(t) < passwordResetWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= max {
+		resetRequests[key] = recent
+		return false
+	}
+	resetRequests[key] = append(recent, now)
+	return true
+}
+
+// CreatePasswordReset issues a reset link for the account identified by
+// username or email. Only accounts with a verified email can reset this
+// way; the returned account is where the link should be sent. Any earlier
+// link for the account stops working.
+func CreatePasswordReset(login, ip string) (*Account, string, error) {
+	login = strings.ToLower(strings.TrimSpace(login))
+	if login == "" {
+		return nil, "", errors.New("username or email is required")
+	}
+
+	now := time.Now()
+	resetMu.Lock()
+	if ip != "" && !resetAllow("ip:"+ip, passwordResetPerIP, now) {
+		resetMu.Unlock()
+		return nil, "", ErrPasswordResetRateLimited
+	}
+	resetMu.Unlock()
+
+	var acc *Account
+	if strings.Contains(login, "@") {
+		acc, _ = GetAccountByEmail(login)
+	} else {
+		acc, _ = GetAccount(login)
+	}
+	if acc == nil || acc.Banned || acc.Email == "" || !acc.EmailVerified {
+		return nil, "", errors.New("no account with a verified email matches")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	tok := base64.RawURLEncoding.EncodeToString(b)
+
+	resetMu.Lock()
+	defer resetMu.Unlock()
+	if !resetAllow("acc:"+acc.ID, passwordResetPerAccount, now) {
+		return nil, "", ErrPasswordResetAccountLimited
+	}
+	for k, v := range resetTokens {
+		if v.AccountID == acc.ID || now.After(v.ExpiresAt) {
+			delete(resetTokens, k)
+		}
+	}
+	resetTokens[hashRefreshToken(tok)] = &passwordReset{
+		AccountID: acc.ID,
+		ExpiresAt: now.Add(PasswordResetTTL),
+	}
+	return acc, tok, nil
+}
+
+// PeekPasswordReset returns the account a reset link is for without using
+// it up, so the new password form can say whose it is.
+func PeekPasswordReset(token string) (string, error) {
+	resetMu.Lock()
+	defer resetMu.Unlock()
+	p, ok := resetTokens[hashRefreshToken(token)]
+	if token == "" || !ok || time.Now().After(p.ExpiresAt) {
+		return "", errPasswordResetInvalid
+	}
+	return p.AccountID, nil
+}
+
+// ResetPassword uses up a reset link to set a new password, signs out the
+// account's other sessions and starts a new one.
+func ResetPassword(token, secret string) (*Session, error) {
+	if len(secret) < 6 {
+		return nil, errors.New("password must be at least 6 characters")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), 10)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hashRefreshToken(token)
+	resetMu.Lock()
+	p, ok := resetTokens[h]
+	if ok {
+		delete(resetTokens, h)
+	}
+	resetMu.Unlock()
+	if token == "" || !ok || time.Now().After(p.ExpiresAt) {
+		return nil, errPasswordResetInvalid
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	acc, ok := accounts[p.AccountID]
+	if !ok || acc.Banned {
+		return nil, errors.New("this account is not available")
+	}
+	acc.Secret = string(hash)
+	endSessions(acc.ID)
+	data.SaveJSON("accounts.json", accounts)
+	return newSession(acc.ID, false), nil
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func resetPasswordResetStateForTest(t *testing.T) {
+	t.Helper()
+	resetMagicLinkStateForTest(t)
+	if err := Create(&Account{ID: "carol", Name: "Carol", Secret: "oldpass", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	mutex.Lock()
+	accounts["carol"].Email = "carol@example.com"
+	accounts["carol"].EmailVerified = true
+	mutex.Unlock()
+
+	resetMu.Lock()
+	resetTokens = map[string]*passwordReset{}
+	resetRequests = map[string][]time.Time{}
+	resetMu.Unlock()
+}
+
+func TestPasswordResetIsSingleUse(t *testing.T) {
+	resetPasswordResetStateForTest(t)
+
+	old, err := Login("carol", "oldpass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, tok, err := CreatePasswordReset("Carol@Example.com", "10.0.0.1")
+	if err != nil || acc.ID != "carol" {
+		t.Fatalf("CreatePasswordReset by email = %v, %v", acc, err)
+	}
+	if _, _, err := CreatePasswordReset("bob", "10.0.0.1"); err == nil {
+		t.Error("issued a reset for an unverified email")
+	}
+
+	// Only the hash is kept.
+	resetMu.Lock()
+	_, plain := resetTokens[tok]
+	resetMu.Unlock()
+	if plain {
+		t.Error("reset token stored in the clear")
+	}
+
+	if id, err := PeekPasswordReset(tok); err != nil || id != "carol" {
+		t.Fatalf("PeekPasswordReset = %q, %v", id, err)
+	}
+	if _, err := ResetPassword(tok, "short"); err == nil {
+		t.Error("accepted a short password")
+	}
+	sess, err := ResetPassword(tok, "newpass")
+	if err != nil || sess.Account != "carol" {
+		t.Fatalf("ResetPassword = %+v, %v", sess, err)
+	}
+	if _, err := ResetPassword(tok, "another"); err == nil {
+		t.Error("reset link worked twice")
+	}
+	if _, err := Login("carol", "newpass"); err != nil {
+		t.Errorf("new password doesn't work: %v", err)
+	}
+	mutex.Lock()
+	_, survived := sessions[old.ID]
+	mutex.Unlock()
+	if survived {
+		t.Error("old session survived the reset")
+	}
+
+	// A new link replaces the previous one, and expired links don't work.
+	_, first, _ := CreatePasswordReset("carol", "10.0.0.2")
+	_, second, _ := CreatePasswordReset("carol", "10.0.0.2")
+	if _, err := PeekPasswordReset(first); err == nil {
+		t.Error("older link still valid")
+	}
+	resetMu.Lock()
+	resetTokens[hashRefreshToken(second)].ExpiresAt = time.Now().Add(-time.Second)
+	resetMu.Unlock()
+	if _, err := ResetPassword(second, "newerpass"); err == nil {
+		t.Error("expired link worked")
+	}
+}
+
+func TestPasswordResetRateLimits(t *testing.T) {
+	resetPasswordResetStateForTest(t)
+
+	for i := 0; i < passwordResetPerAccount; i++ {
+		if _, _, err := CreatePasswordReset("carol", ""); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if _, _, err := CreatePasswordReset("carol", ""); err != ErrPasswordResetAccountLimited {
+		t.Fatalf("over the account limit = %v", err)
+	}
+
+	// Unknown names still count against the IP.
+	for i := 0; i < passwordResetPerIP; i++ {
+		CreatePasswordReset("nobody", "10.0.0.9")
+	}
+	if _, _, err := CreatePasswordReset("nobody", "10.0.0.9"); err != ErrPasswordResetRateLimited {
+		t.Fatalf("over the IP limit = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+)
+
+// Personal access tokens carry scopes naming what they may do, such as
+// read:news or write:posts. A request made with a token is checked
+// against the scope its path and method need (ScopeFor) before any
+// handler runs, so handlers don't check scopes themselves.
+//
+// Tokens made before scopes existed hold the broad "read" and "write"
+// permissions, which grant every read:… or write:… scope and reach paths
+// that have no scope of their own. "all" grants everything.
+
+// Broad permissions from before scopes, still accepted.
+const (
+	ScopeAll   = "all"
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// TokenScope describes a scope a token can be granted.
+type TokenScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TokenScopes lists the scopes a token can be granted, in the order
+// they're offered.
+var TokenScopes = []TokenScope{
+	{"read:news", "Read the news, markets and weather"},
+	{"read:posts", "Read posts and comments"},
+	{"write:posts", "Publish, edit and comment"},
+	{"read:social", "Read the social feed"},
+	{"write:social", "Post to the social feed"},
+	{"read:mail", "Read your mail"},
+	{"write:mail", "Send mail"},
+	{"read:chat", "Read your chats"},
+	{"write:chat", "Send chat messages"},
+	{"read:wallet", "See your wallet balance and history"},
+	{"read:account", "See your account and profile"},
+	{"write:account", "Change your account and profile"},
+}
+
+// scopeAreas maps path prefixes to the area their scopes are named after.
+var scopeAreas = []struct{ prefix, area string }{
+	{"/news", "news"},
+	{"/markets", "news"},
+	{"/weather", "news"},
+	{"/blog", "posts"},
+	{"/post", "posts"},
+	{"/posts", "posts"},
+	{"/@", "posts"},
+	{"/social", "social"},
+	{"/mail", "mail"},
+	{"/chat", "chat"},
+	{"/wallet", "wallet"},
+	{"/account", "account"},
+	{"/user", "account"},
+}
+
+// ScopeFor returns the scope a token needs for a request, such as
+// write:mail for POST /mail. Paths with no scope of their own need the
+// broad read or write.
+func ScopeFor(method, path string) string {
+	access := ScopeWrite
+	if method == "GET" || method == "HEAD" || method == "OPTIONS" {
+		access = ScopeRead
+	}
+	for _, s := range scopeAreas {
+		if path == s.prefix || strings.HasPrefix(path, s.prefix+"/") || s.prefix == "/@" && strings.HasPrefix(path, s.prefix) {
+			return access + ":" + s.area
+		}
+	}
+	return access
+}
+
+// ValidScope reports whether a token can be granted scope.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeAll, ScopeRead, ScopeWrite:
+		return true
+	}
+	for _, s := range TokenScopes {
+		if s.Name == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the session may make a request needing scope,
+// as returned by ScopeFor. Sessions from logging in may do anything;
+// ones from a personal access token only what its scopes grant.
+func (s *Session) Allows(scope string) bool {
+	if s.Type != "token" {
+		return true
+	}
+	broad, _, _ := strings.Cut(scope, ":")
+	for _, have := range s.Scopes {
+		if have == ScopeAll || have == scope || have == broad {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePATScope validates a personal access token that must hold
+// scope, for protocols such as IMAP that don't go through ScopeFor.
+func ValidatePATScope(rawToken, scope string) (string, error) {
+	t, err := validatePAT(rawToken)
+	if err != nil {
+		return "", err
+	}
+	sess := &Session{Type: "token", Account: t.Account, Scopes: t.Permissions}
+	if !sess.Allows(scope) {
+		return "", errors.New("token lacks the " + scope + " scope")
+	}
+	return t.Account, nil
+}
+
+// CheckScopes validates the scopes asked for when creating a token.
+func CheckScopes(scopes []string) error {
+	for _, s := range scopes {
+		if !ValidScope(s) {
+			return errors.New("unknown scope " + s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScopeFor(t *testing.T) {
+	for _, tt := range []struct{ method, path, want string }{
+		{"GET", "/news", "read:news"},
+		{"GET", "/news/search", "read:news"},
+		{"POST", "/blog/post", "write:posts"},
+		{"GET", "/@alice", "read:posts"},
+		{"POST", "/mail", "write:mail"},
+		{"GET", "/mailbox", "read"},
+		{"POST", "/admin/users", "write"},
+	} {
+		if got := ScopeFor(tt.method, tt.path); got != tt.want {
+			t.Errorf("ScopeFor(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTokenScopes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mutex.Lock()
+	savedAccounts, savedTokens := accounts, tokens
+	accounts = map[string]*Account{"alice": {ID: "alice", Created: time.Now()}}
+	tokens = map[string]*Token{}
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		accounts, tokens = savedAccounts, savedTokens
+		mutex.Unlock()
+	})
+
+	_, newsOnly, err := CreateToken("alice", "news", []string{"read:news"}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, legacy, _ := CreateToken("alice", "legacy", []string{ScopeRead, ScopeWrite}, time.Time{})
+
+	session := func(raw string) *Session {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+raw)
+		sess, err := GetSession(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+
+	scoped := session(newsOnly)
+	if !scoped.Allows("read:news") || scoped.Allows("read:mail") || scoped.Allows("write:news") || scoped.Allows("read") {
+		t.Errorf("read:news token allows = %v", scoped.Scopes)
+	}
+	broad := session(legacy)
+	if !broad.Allows("read:mail") || !broad.Allows("write:posts") || !broad.Allows("write") {
+		t.Errorf("legacy token refused, scopes = %v", broad.Scopes)
+	}
+	if s := (&Session{Type: "account"}); !s.Allows("write:mail") {
+		t.Error("logged in session refused")
+	}
+
+	if _, err := ValidatePATScope(newsOnly, "read:mail"); err == nil {
+		t.Error("read:news token accepted for read:mail")
+	}
+	if id, err := ValidatePATScope(legacy, "read:mail"); err != nil || id != "alice" {
+		t.Errorf("ValidatePATScope = %q, %v", id, err)
+	}
+
+	if err := CheckScopes([]string{"read:news", "all"}); err != nil {
+		t.Error(err)
+	}
+	if err := CheckScopes([]string{"root"}); err == nil {
+		t.Error("unknown scope accepted")
+	}
+}
@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"mu/internal/data"
+	"mu/internal/settings"
+
+	"github.com/google/uuid"
+)
+
+func init() {
+	settings.Register("Sessions",
+		settings.Var{Key: "SESSION_TTL", Type: settings.TypeDuration, Default: "24h", Doc: "How long a login session lasts"},
+		settings.Var{Key: "SESSION_REMEMBER_TTL", Type: settings.TypeDuration, Default: "720h", Doc: "How long \"keep me logged in\" renews sessions"},
+		settings.Var{Key: "SESSION_IDLE_TIMEOUT", Type: settings.TypeDuration, Doc: "End sessions unused for this long"},
+		settings.Var{Key: "SESSION_MAX", Type: settings.TypeInt, Default: "20", Doc: "Concurrent sessions per account; 0 is no limit"},
+	)
+}
+
+// ============================================================
+// Session lifetimes and remember-me
+// ============================================================
+
+// Sessions are short by default so that logging in on a shared device
+// doesn't leave the account open for a month. Ticking "keep me logged in"
+// additionally issues a refresh token: when the short session runs out the
+// browser trades it for a fresh session and a fresh refresh token. Each
+// refresh token works once; presenting a spent one means it was copied, so
+// the whole chain and its sessions are revoked.
+//
+// All limits are settings (see /admin/login):
+//
+//	SESSION_TTL           lifetime of a session, default 24h
+//	SESSION_REMEMBER_TTL  lifetime of a refresh token, default 720h (30 days)
+//	SESSION_IDLE_TIMEOUT  end sessions unused for this long, default off
+//	SESSION_MAX           concurrent sessions per account, default 20
+
+const (
+	defaultSessionTTL  = 24 * time.Hour
+	defaultRememberTTL = 30 * 24 * time.Hour
+	defaultMaxSessions = 20
+
+	// legacySessionTTL applies to sessions saved before they carried an
+	// expiry; they were issued with 30-day cookies.
+	legacySessionTTL = 30 * 24 * time.Hour
+
+	// lastSeenGranularity limits how often LastSeen is written to disk.
+	lastSeenGranularity = time.Minute
+)
+
+// RefreshToken lets a remembered browser mint a new session. Only the
+// hash of the token is stored.
+type RefreshToken struct {
+	Hash    string    `json:"hash"`
+	Account string    `json:"account"`
+	Session string    `json:"session"` // session it was issued alongside
+	Family  string    `json:"family"`  // shared by every token in a rotation chain
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+	Used    bool      `json:"used,omitempty"`
+}
+
+// refreshMu may be taken while holding mutex, never the other way round.
+var (
+	refreshMu     sync.Mutex
+	refreshTokens = map[string]*RefreshToken{} // hash → token
+)
+
+func init() {
+	b, _ := data.LoadFile("refresh_tokens.json")
+	json.Unmarshal(b, &refreshTokens)
+}
+
+// SessionTTL is how long a session lasts.
+func SessionTTL() time.Duration {
+	if d := settings.Duration("SESSION_TTL"); d > 0 {
+		return d
+	}
+	return defaultSessionTTL
+}
+
+// RememberTTL is how long "keep me logged in" lasts without a visit.
+func RememberTTL() time.Duration {
+	if d := settings.Duration("SESSION_REMEMBER_TTL"); d > 0 {
+		return d
+	}
+	return defaultRememberTTL
+}
+
+// IdleTimeout ends sessions that haven't been used for this long.
+// Zero means sessions only end when they expire.
+func IdleTimeout() time.Duration {
+	if d := settings.Duration("SESSION_IDLE_TIMEOUT"); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// MaxSessions is the most sessions one account may hold at once; the
+// least recently used are logged out to make room. Zero means no limit.
+func MaxSessions() int {
+	if n := settings.Int("SESSION_MAX"); n >= 0 {
+		return n
+	}
+	return defaultMaxSessions
+}
+
+// ExpiresAt returns when the session ends, ignoring the idle timeout.
+func (s *Session) ExpiresAt() time.Time {
+	if !s.Expires.IsZero() {
+		return s.Expires
+	}
+	return s.Created.Add(legacySessionTTL)
+}
+
+// expired reports whether the session has run out or sat idle too long.
+func (s *Session) expired(now time.Time) bool {
+	if now.After(s.ExpiresAt()) {
+		return true
+	}
+	if idle := IdleTimeout(); idle > 0 {
+		last := s.LastSeen
+		if last.IsZero() {
+			last = s.Created
+		}
+		if now.Sub(last) > idle {
+			return true
+		}
+	}
+	return false
+}
+
+// lastUsed is when the session was last seen, or created if never.
+func (s *Session) lastUsed() time.Time {
+	if s.LastSeen.After(s.Created) {
+		return s.LastSeen
+	}
+	return s.Created
+}
+
+// newSession stores a new session for the account and enforces the
+// concurrent session limit (caller must hold mutex).
+func newSession(accountID string, remember bool) *Session {
+	guid := uuid.New().String()
+	now := time.Now()
+	sess := &Session{
+		ID:       guid,
+		Type:     "account",
+		Token:    base64.StdEncoding.EncodeToString([]byte(guid)),
+		Account:  accountID,
+		Created:  now,
+		LastSeen: now,
+		Expires:  now.Add(SessionTTL()),
+		Remember: remember,
+	}
+	sessions[sess.ID] = sess
+	evictSessions(accountID)
+	data.SaveJSON("sessions.json", sessions)
+	return sess
+}
+
+// evictSessions logs out the least recently used sessions of an account
+// beyond MaxSessions (caller must hold mutex).
+func evictSessions(accountID string) {
+	max := MaxSessions()
+	if max == 0 {
+		return
+	}
+	var own []*Session
+	for _, s := range sessions {
+		if s.Account == accountID && s.Type == "account" {
+			own = append(own, s)
+		}
+	}
+	if len(own) <= max {
+		return
+	}
+	sort.Slice(own, func(i, j int) bool { return own[i].lastUsed().After(own[j].lastUsed()) })
+	for _, s := range own[max:] {
+		delete(sessions, s.ID)
+		revokeRefreshForSession(s.ID)
+	}
+}
+
+// touchSession records use of a session and reports whether it should be
+// written to disk (caller must hold mutex).
+func touchSession(s *Session, now time.Time) bool {
+	if now.Sub(s.LastSeen) < lastSeenGranularity {
+		return false
+	}
+	s.LastSeen = now
+	return true
+}
+
+func hashRefreshToken(raw string) string {
+	h := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(h[:])
+}
+
+// issueRefresh stores a new refresh token for the session in the given
+// family and returns the raw token (caller must hold refreshMu).
+func issueRefresh(sess *Session, family string) string {
+	raw := GenerateToken() + GenerateToken()
+	h := hashRefreshToken(raw)
+	now := time.Now()
+	if family == "" {
+		family = h[:16]
+	}
+	refreshTokens[h] = &RefreshToken{
+		Hash:    h,
+		Account: sess.Account,
+		Session: sess.ID,
+		Family:  family,
+		Created: now,
+		Expires: now.Add(RememberTTL()),
+	}
+	data.SaveJSON("refresh_tokens.json", refreshTokens)
+	return raw
+}
+
+// Remember marks a freshly created session as "keep me logged in" and
+// returns the refresh token the browser should hold on to.
+func Remember(sess *Session) string {
+	mutex.Lock()
+	if s, ok := sessions[sess.ID]; ok {
+		s.Remember = true
+		data.SaveJSON("sessions.json", sessions)
+	}
+	sess.Remember = true
+	mutex.Unlock()
+
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	return issueRefresh(sess, "")
+}
+
+// Refresh trades a refresh token for a new session and a new refresh
+// token, ending the session the old one was issued with.
+func Refresh(raw string) (*Session, string, error) {
+	if raw == "" {
+		return nil, "", errors.New("invalid refresh token")
+	}
+	h := hashRefreshToken(raw)
+	now := time.Now()
+
+	refreshMu.Lock()
+	rt, ok := refreshTokens[h]
+	if !ok || now.After(rt.Expires) {
+		refreshMu.Unlock()
+		return nil, "", errors.New("invalid refresh token")
+	}
+	if rt.Used {
+		// A spent token came back: someone else has a copy.
+		family := rt.Family
+		refreshMu.Unlock()
+		revokeRefreshFamily(family)
+		return nil, "", errors.New("refresh token reused")
+	}
+	rt.Used = true
+	refreshMu.Unlock()
+
+	if IsBanned(rt.Account) {
+		return nil, "", errors.New("account not available")
+	}
+
+	mutex.Lock()
+	if _, ok := accounts[rt.Account]; !ok {
+		mutex.Unlock()
+		return nil, "", errors.New("account does not exist")
+	}
+	delete(sessions, rt.Session)
+	sess := newSession(rt.Account, true)
+	mutex.Unlock()
+
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	return sess, issueRefresh(sess, rt.Family), nil
+}
+
+// revokeRefreshFamily ends every token in a rotation chain along with the
+// sessions they were issued with.
+func revokeRefreshFamily(family string) {
+	var ended []string
+	refreshMu.Lock()
+	for h, t := range refreshTokens {
+		if t.Family == family {
+			ended = append(ended, t.Session)
+			delete(refreshTokens, h)
+		}
+	}
+	data.SaveJSON("refresh_tokens.json", refreshTokens)
+	refreshMu.Unlock()
+
+	mutex.Lock()
+	for _, id := range ended {
+		delete(sessions, id)
+	}
+	data.SaveJSON("sessions.json", sessions)
+	mutex.Unlock()
+}
+
+// revokeRefreshForSession drops the refresh tokens issued with a session
+// so that logging out also ends "keep me logged in".
+func revokeRefreshForSession(sessionID string) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	changed := false
+	for h, t := range refreshTokens {
+		if t.Session == sessionID {
+			delete(refreshTokens, h)
+			changed = true
+		}
+	}
+	if changed {
+		data.SaveJSON("refresh_tokens.json", refreshTokens)
+	}
+}
+
+// RevokeRefreshToken forgets a raw refresh token, e.g. on logout.
+func RevokeRefreshToken(raw string) {
+	if raw == "" {
+		return
+	}
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	h := hashRefreshToken(raw)
+	if _, ok := refreshTokens[h]; ok {
+		delete(refreshTokens, h)
+		data.SaveJSON("refresh_tokens.json", refreshTokens)
+	}
+}
+
+// ============================================================
+// Listing and ending sessions
+// ============================================================
+
+// SetSessionClient records the IP address and User-Agent a session was
+// started from, so its owner can tell their devices apart.
+func SetSessionClient(sessionID, ip, userAgent string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	s, ok := sessions[sessionID]
+	if !ok || (s.IP == ip && s.UserAgent == userAgent) {
+		return
+	}
+	s.IP = ip
+	s.UserAgent = userAgent
+	data.SaveJSON("sessions.json", sessions)
+}
+
+// AccountSessions returns copies of an account's live login sessions,
+// most recently used first.
+func AccountSessions(accountID string) []Session {
+	mutex.Lock()
+	defer mutex.Unlock()
+	now := time.Now()
+	var out []Session
+	for _, s := range sessions {
+		if s.Account == accountID && s.Type == "account" && !s.expired(now) {
+			out = append(out, *s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].lastUsed().After(out[j].lastUsed()) })
+	return out
+}
+
+// LastUsed is when the session was last seen, or created if never.
+func (s *Session) LastUsed() time.Time {
+	return s.lastUsed()
+}
+
+// RevokeSession logs out one of an account's sessions.
+func RevokeSession(accountID, sessionID string) error {
+	mutex.Lock()
+	s, ok := sessions[sessionID]
+	if !ok || s.Account != accountID {
+		mutex.Unlock()
+		return errors.New("session not found")
+	}
+	delete(sessions, sessionID)
+	data.SaveJSON("sessions.json", sessions)
+	mutex.Unlock()
+
+	revokeRefreshForSession(sessionID)
+	return nil
+}
+
+// RevokeSessions logs out every session of an account and returns how
+// many there were.
+func RevokeSessions(accountID string) int {
+	mutex.Lock()
+	defer mutex.Unlock()
+	n := endSessions(accountID)
+	if n > 0 {
+		data.SaveJSON("sessions.json", sessions)
+	}
+	return n
+}
+
+// endSessions deletes an account's sessions and their refresh tokens and
+// returns how many it ended (caller must hold mutex and save).
+func endSessions(accountID string) int {
+	n := 0
+	for sid, s := range sessions {
+		if s.Account == accountID {
+			delete(sessions, sid)
+			revokeRefreshForSession(sid)
+			n++
+		}
+	}
+	return n
+}
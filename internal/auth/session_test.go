@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func resetSessionStateForTest(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	for _, k := range []string{"SESSION_TTL", "SESSION_REMEMBER_TTL", "SESSION_IDLE_TIMEOUT", "SESSION_MAX"} {
+		t.Setenv(k, "")
+	}
+
+	mutex.Lock()
+	accounts = map[string]*Account{"alice": {ID: "alice", Created: time.Now()}}
+	sessions = map[string]*Session{}
+	mutex.Unlock()
+
+	refreshMu.Lock()
+	refreshTokens = map[string]*RefreshToken{}
+	refreshMu.Unlock()
+}
+
+func TestSessionExpiryAndIdleTimeout(t *testing.T) {
+	resetSessionStateForTest(t)
+
+	sess, _ := CreateSession("alice")
+	if err := ValidateToken(sess.Token); err != nil {
+		t.Fatalf("new session invalid: %v", err)
+	}
+	mutex.Lock()
+	sess.Expires = time.Now().Add(-time.Second)
+	mutex.Unlock()
+	if err := ValidateToken(sess.Token); err == nil {
+		t.Fatal("expired session still valid")
+	}
+
+	t.Setenv("SESSION_IDLE_TIMEOUT", "30m")
+	sess, _ = CreateSession("alice")
+	mutex.Lock()
+	sess.LastSeen = time.Now().Add(-time.Hour)
+	mutex.Unlock()
+	if err := ValidateToken(sess.Token); err == nil {
+		t.Fatal("idle session still valid")
+	}
+
+	// Sessions saved before expiry was tracked keep their 30 days.
+	legacy := &Session{ID: "00000000-0000-0000-0000-000000000001", Type: "account", Account: "alice", Created: time.Now().Add(-time.Minute)}
+	if legacy.expired(time.Now()) || !legacy.expired(time.Now().Add(31*24*time.Hour)) {
+		t.Error("legacy session lifetime wrong")
+	}
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	resetSessionStateForTest(t)
+
+	sess, _ := CreateSession("alice")
+	first := Remember(sess)
+
+	next, second, err := Refresh(first)
+	if err != nil || next.Account != "alice" || !next.Remember {
+		t.Fatalf("Refresh = %+v, %v", next, err)
+	}
+	if _, err := ParseToken(sess.Token); err == nil {
+		t.Error("old session survived a refresh")
+	}
+
+	// Replaying the spent token revokes the chain.
+	if _, _, err := Refresh(first); err == nil {
+		t.Fatal("spent refresh token accepted")
+	}
+	if _, _, err := Refresh(second); err == nil {
+		t.Error("chain still usable after reuse")
+	}
+	if _, err := ParseToken(next.Token); err == nil {
+		t.Error("session from a revoked chain still valid")
+	}
+}
+
+func TestMaxSessionsEvictsLeastRecentlyUsed(t *testing.T) {
+	resetSessionStateForTest(t)
+	t.Setenv("SESSION_MAX", "2")
+
+	a, _ := CreateSession("alice")
+	b, _ := CreateSession("alice")
+	mutex.Lock()
+	a.LastSeen = time.Now().Add(time.Minute)
+	b.LastSeen = time.Now().Add(-time.Hour)
+	mutex.Unlock()
+	CreateSession("alice")
+
+	if _, err := ParseToken(b.Token); err == nil {
+		t.Error("least recently used session not evicted")
+	}
+	if _, err := ParseToken(a.Token); err != nil {
+		t.Errorf("recent session evicted: %v", err)
+	}
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	resetSessionStateForTest(t)
+	mutex.Lock()
+	accounts["bob"] = &Account{ID: "bob", Created: time.Now()}
+	mutex.Unlock()
+
+	a, _ := CreateSession("alice")
+	b, _ := CreateSession("alice")
+	other, _ := CreateSession("bob")
+	SetSessionClient(a.ID, "10.0.0.1", "Firefox/120 (X11; Linux)")
+	rt := Remember(b)
+	mutex.Lock()
+	b.LastSeen = time.Now().Add(time.Minute)
+	mutex.Unlock()
+
+	list := AccountSessions("alice")
+	if len(list) != 2 || list[0].ID != b.ID || list[1].IP != "10.0.0.1" || list[1].UserAgent == "" {
+		t.Fatalf("AccountSessions = %+v", list)
+	}
+
+	if err := RevokeSession("alice", other.ID); err == nil {
+		t.Error("revoked another account's session")
+	}
+	if err := RevokeSession("alice", b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(b.Token); err == nil {
+		t.Error("revoked session still works")
+	}
+	if _, _, err := Refresh(rt); err == nil {
+		t.Error("revoked session's refresh token still works")
+	}
+
+	CreateSession("alice")
+	if n := RevokeSessions("alice"); n != 2 {
+		t.Errorf("RevokeSessions = %d, want 2", n)
+	}
+	if len(AccountSessions("alice")) != 0 {
+		t.Error("sessions left after logging out everywhere")
+	}
+	if _, err := ParseToken(other.Token); err != nil {
+		t.Errorf("another account's session ended: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -65,8 +66,34 @@ func dataPath(key string) (string, error) {
 	return file, nil
 }
 
+// FilePath returns where the file at key lives on disk, creating its
+// directory. It's for content too large to load whole, such as video,
+// which is kept in files even when the store is SQLite; callers read and
+// write it themselves and remove it with os.Remove.
+func FilePath(key string) (string, error) {
+	file, err := dataPath(key)
+	if err != nil {
+		return "", err
+	}
+	if !ReadOnly() {
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return "", err
+		}
+	}
+	return file, nil
+}
+
 // SaveFile saves data to disk
 func SaveFile(key, val string) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
+	if UseSQLiteStore {
+		if _, err := dataPath(key); err != nil {
+			return err
+		}
+		return storeSave(key, []byte(val))
+	}
 	file, err := dataPath(key)
 	if err != nil {
 		return err
@@ -78,24 +105,63 @@ func SaveFile(key, val string) error {
 	return os.WriteFile(file, []byte(val), 0600)
 }
 
+// AppendFile appends val to the file at key, creating it if needed. It
+// suits journals: each write costs the size of val, not of the file.
+func AppendFile(key, val string) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
+	file, err := dataPath(key)
+	if err != nil {
+		return err
+	}
+	if UseSQLiteStore {
+		return storeAppend(key, []byte(val))
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(val); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // LoadFile loads a file from disk
 func LoadFile(key string) ([]byte, error) {
 	file, err := dataPath(key)
 	if err != nil {
 		return nil, err
 	}
+	if UseSQLiteStore {
+		return storeLoad(key)
+	}
 	return os.ReadFile(file)
 }
 
 func DeleteFile(key string) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
 	file, err := dataPath(key)
 	if err != nil {
 		return err
 	}
+	if UseSQLiteStore {
+		return storeDelete(key)
+	}
 	return os.Remove(file)
 }
 
 func SaveJSON(key string, val interface{}) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
 	b, err := json.Marshal(val)
 	if err != nil {
 		return err
@@ -104,6 +170,9 @@ func SaveJSON(key string, val interface{}) error {
 	if err != nil {
 		return err
 	}
+	if UseSQLiteStore {
+		return storeSave(key, b)
+	}
 	// Create all parent directories
 	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
 		return err
@@ -112,11 +181,7 @@ func SaveJSON(key string, val interface{}) error {
 }
 
 func LoadJSON(key string, val interface{}) error {
-	file, err := dataPath(key)
-	if err != nil {
-		return err
-	}
-	b, err := os.ReadFile(file)
+	b, err := LoadFile(key)
 	if err != nil {
 		return err
 	}
@@ -193,6 +258,10 @@ var (
 	indexWorkersStarted = false
 )
 
+// lowMemoryIndexQueue caps the pending index operations in low-memory mode;
+// past it, work is done synchronously rather than held in the queue.
+const lowMemoryIndexQueue = 50
+
 // IndexEntry represents a searchable piece of content
 type IndexEntry struct {
 	ID        string                 `json:"id"`
@@ -204,6 +273,26 @@ type IndexEntry struct {
 	IndexedAt time.Time              `json:"indexed_at"`
 }
 
+// Link returns where an entry can be read: its page on Mu, or for
+// videos the original.
+func (e *IndexEntry) Link() string {
+	switch e.Type {
+	case "news":
+		return "/news?id=" + url.QueryEscape(e.ID)
+	case "video":
+		if u, ok := e.Metadata["url"].(string); ok && u != "" {
+			return u
+		}
+		return "/video"
+	case "blog", "post":
+		return "/blog/post?id=" + url.QueryEscape(e.ID)
+	case "qa":
+		return "/qa?id=" + url.QueryEscape(strings.TrimPrefix(e.ID, "qa_"))
+	default:
+		return "/" + e.Type
+	}
+}
+
 // SearchResult represents a search hit with relevance score
 type SearchResult struct {
 	Entry *IndexEntry
@@ -219,6 +308,9 @@ func Index(id, entryType, title, content string, metadata map[string]interface{}
 // marks the entry private: it is only returned by searches that pass
 // WithOwner(owner). Pass an empty owner for public content.
 func IndexOwned(id, entryType, title, content, owner string, metadata map[string]interface{}) {
+	recordLinks(id, entryType, title, content, owner, metadata)
+	embedEntry(id, entryType, title, content, owner)
+
 	// Use SQLite backend if enabled
 	if UseSQLite {
 		if err := IndexSQLite(id, entryType, title, content, owner, metadata); err != nil {
@@ -237,6 +329,10 @@ func IndexOwned(id, entryType, title, content, owner string, metadata map[string
 	}
 
 	// Queue the work instead of processing immediately
+	if LowMemory() && len(indexWorkQueue) >= lowMemoryIndexQueue {
+		processIndexWork(work)
+		return
+	}
 	select {
 	case indexWorkQueue <- work:
 		// Work queued successfully
@@ -246,6 +342,27 @@ func IndexOwned(id, entryType, title, content, owner string, metadata map[string
 	}
 }
 
+// Unindex removes an entry from the search index.
+func Unindex(id string) {
+	forgetLinks(id)
+	ForgetEmbedding(id)
+	if UseSQLite {
+		if err := UnindexSQLite(id); err != nil {
+			fmt.Printf("[data] SQLite unindex error: %v\n", err)
+		}
+		return
+	}
+
+	indexMutex.Lock()
+	_, ok := index[id]
+	delete(index, id)
+	indexMutex.Unlock()
+	forgetVector(id)
+	if ok {
+		go saveIndex()
+	}
+}
+
 // processIndexWork does the actual indexing work
 func processIndexWork(work IndexWork) {
 	indexMutex.RLock()
@@ -293,6 +410,7 @@ func processIndexWork(work IndexWork) {
 	indexMutex.Lock()
 	index[work.ID] = entry
 	indexMutex.Unlock()
+	forgetVector(work.ID)
 
 	// Publish event that indexing is complete
 	event.Publish(event.Event{
@@ -312,6 +430,9 @@ func StartIndexing() {
 	if !indexWorkersStarted {
 		indexWorkersStarted = true
 		numWorkers := 4
+		if LowMemory() {
+			numWorkers = 1
+		}
 		fmt.Printf("[data] Starting %d index workers\n", numWorkers)
 		for i := 0; i < numWorkers; i++ {
 			go indexWorker(i)
@@ -455,6 +576,10 @@ func ClearIndex() {
 	indexMutex.Lock()
 	index = make(map[string]*IndexEntry)
 	indexMutex.Unlock()
+	vectorMutex.Lock()
+	vectors = make(map[string]Vector)
+	vectorMutex.Unlock()
+	clearEmbeddings()
 	saveIndex()
 }
 
@@ -483,6 +608,12 @@ func saveIndex() {
 
 // Load loads the index from disk
 func Load() {
+	loadLinks()
+
+	if UseSQLiteStore {
+		fmt.Println("[data] SQLite store enabled")
+	}
+
 	// If SQLite is enabled, migrate from JSON and use SQLite
 	if UseSQLite {
 		fmt.Println("[data] SQLite backend enabled")
@@ -0,0 +1,445 @@
+package data
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================
+// EMBEDDINGS STORE FOR SEMANTIC SEARCH
+// ============================================
+
+// Alongside the keyword index, content can be embedded by a model and
+// searched by meaning: IndexEmbedding queues a text to be embedded and
+// SemanticSearch finds the texts nearest a query. The embedder is whatever
+// SetEmbedder installs, normally the LLM provider's embeddings endpoint;
+// until then texts are embedded locally with Embed. Vectors are kept in
+// memory for a brute-force cosine scan, which is quick enough for tens of
+// thousands of entries, and in the embeddings table of index.db so a
+// restart doesn't pay to embed everything again.
+//
+// Public news and posts are embedded as they're indexed. Other packages
+// (places, for one) call IndexEmbedding for their own content.
+
+// localModel names the built-in hashed embedding.
+const localModel = "local"
+
+const (
+	// embedBatch is how many texts go to the embedder in one call.
+	embedBatch = 16
+	// embedQueueSize bounds the texts waiting to be embedded.
+	embedQueueSize = 1024
+	// embedMaxBackoff caps the wait after the embedder fails.
+	embedMaxBackoff = 5 * time.Minute
+)
+
+// embedTypes are the index entry types embedded automatically.
+var embedTypes = map[string]bool{"news": true, "post": true}
+
+// Embedder returns an embedding of each text, in order.
+type Embedder func(texts []string) ([][]float32, error)
+
+// SemanticMatch is a stored text found by SemanticSearch.
+type SemanticMatch struct {
+	ID    string
+	Type  string
+	Score float64 // cosine similarity to the query
+}
+
+type embedding struct {
+	typ  string
+	hash string
+	vec  Vector
+}
+
+type embedWork struct {
+	id, typ, text, hash string
+}
+
+var (
+	embedMutex sync.RWMutex
+	embedModel = localModel
+	embedder   = Embedder(embedLocal)
+	embeddings map[string]*embedding // nil until loaded for embedModel
+
+	embedQueue = make(chan embedWork, embedQueueSize)
+	embedStart sync.Once
+	backfills  sync.WaitGroup // running backfillEmbeddings
+)
+
+// embedLocal embeds texts with the built-in hashed embedding.
+func embedLocal(texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = Embed(t)
+	}
+	return out, nil
+}
+
+// SetEmbedder makes fn, which embeds with model, the embedder. Vectors
+// stored for any other model are dropped, and public news and posts
+// without one are queued to be embedded.
+func SetEmbedder(model string, fn Embedder) {
+	embedMutex.Lock()
+	changed := model != embedModel
+	embedModel, embedder = model, fn
+	if changed {
+		embeddings = nil
+	}
+	embedMutex.Unlock()
+
+	if !ReadOnly() {
+		if db, err := getDB(); err == nil {
+			db.Exec(`DELETE FROM embeddings WHERE model != ?`, model)
+		}
+	}
+	useSQLite := UseSQLite
+	backfills.Add(1)
+	go func() {
+		defer backfills.Done()
+		backfillEmbeddings(useSQLite)
+	}()
+}
+
+// EmbeddingModel returns the model embeddings are made with, "local" for
+// the built-in hashed embedding.
+func EmbeddingModel() string {
+	embedMutex.RLock()
+	defer embedMutex.RUnlock()
+	return embedModel
+}
+
+// IndexEmbedding queues text to be embedded under id. Unchanged text isn't
+// embedded again. When the queue is full the text is skipped; it's picked
+// up the next time the embedder is set.
+func IndexEmbedding(id, entryType, text string) {
+	if id == "" || text == "" {
+		return
+	}
+	h := textHash(text)
+	embedMutex.Lock()
+	loadEmbeddings()
+	if e, ok := embeddings[id]; ok && e.hash == h {
+		embedMutex.Unlock()
+		return
+	}
+	embedMutex.Unlock()
+
+	embedStart.Do(func() { go embedWorker() })
+	select {
+	case embedQueue <- embedWork{id: id, typ: entryType, text: text, hash: h}:
+	default:
+	}
+}
+
+// ForgetEmbedding drops the embedding stored under id.
+func ForgetEmbedding(id string) {
+	embedMutex.Lock()
+	loadEmbeddings()
+	_, ok := embeddings[id]
+	delete(embeddings, id)
+	embedMutex.Unlock()
+	if ok && !ReadOnly() {
+		if db, err := getDB(); err == nil {
+			db.Exec(`DELETE FROM embeddings WHERE id = ?`, id)
+		}
+	}
+}
+
+// clearEmbeddings drops every stored embedding.
+func clearEmbeddings() {
+	embedMutex.Lock()
+	embeddings = map[string]*embedding{}
+	embedMutex.Unlock()
+	if !ReadOnly() {
+		if db, err := getDB(); err == nil {
+			db.Exec(`DELETE FROM embeddings`)
+		}
+	}
+}
+
+// SemanticSearch finds the stored texts nearest in meaning to query, most
+// similar first. Only WithType applies of the search options: everything
+// embedded is public.
+func SemanticSearch(query string, limit int, opts ...SearchOption) []SemanticMatch {
+	options := &SearchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	qv, err := embedQuery(query)
+	if err != nil {
+		fmt.Printf("[data] Query embedding error: %v\n", err)
+		return nil
+	}
+	if qv == nil {
+		return nil
+	}
+	return nearest(qv, limit, options.Type)
+}
+
+// embedQuery embeds query with the current embedder.
+func embedQuery(query string) (Vector, error) {
+	embedMutex.RLock()
+	fn := embedder
+	embedMutex.RUnlock()
+	out, err := fn([]string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(out))
+	}
+	return normalise(out[0]), nil
+}
+
+// modelQueryVector embeds query for HybridSearch when a model embedder is
+// set, or returns nil to leave it to the local embedding.
+func modelQueryVector(query string) Vector {
+	if EmbeddingModel() == localModel {
+		return nil
+	}
+	v, err := embedQuery(query)
+	if err != nil {
+		fmt.Printf("[data] Query embedding error: %v\n", err)
+		return nil
+	}
+	return v
+}
+
+// storedEmbedding returns the stored embedding of id, if there is one.
+func storedEmbedding(id string) Vector {
+	embedMutex.Lock()
+	defer embedMutex.Unlock()
+	loadEmbeddings()
+	if e, ok := embeddings[id]; ok {
+		return e.vec
+	}
+	return nil
+}
+
+// nearest scans every stored embedding for the closest to qv.
+func nearest(qv Vector, limit int, entryType string) []SemanticMatch {
+	embedMutex.Lock()
+	loadEmbeddings()
+	var matches []SemanticMatch
+	for id, e := range embeddings {
+		if entryType != "" && e.typ != entryType {
+			continue
+		}
+		if s := Similarity(qv, e.vec); s >= minSimilarity {
+			matches = append(matches, SemanticMatch{ID: id, Type: e.typ, Score: s})
+		}
+	}
+	embedMutex.Unlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// loadEmbeddings reads the vectors stored for the current model, once.
+// The caller holds embedMutex.
+func loadEmbeddings() {
+	if embeddings != nil {
+		return
+	}
+	embeddings = map[string]*embedding{}
+	db, err := getDB()
+	if err != nil {
+		return
+	}
+	rows, err := db.Query(`SELECT id, type, hash, vector FROM embeddings WHERE model = ?`, embedModel)
+	if err != nil {
+		fmt.Printf("[data] Embeddings load error: %v\n", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, typ, hash string
+		var blob []byte
+		if err := rows.Scan(&id, &typ, &hash, &blob); err != nil {
+			continue
+		}
+		embeddings[id] = &embedding{typ: typ, hash: hash, vec: decodeVector(blob)}
+	}
+}
+
+// embedWorker embeds queued texts in batches. When the embedder fails the
+// batch is dropped and the worker waits, longer each time, before trying
+// the next.
+func embedWorker() {
+	backoff := time.Second
+	for work := range embedQueue {
+		batch := []embedWork{work}
+	fill:
+		for len(batch) < embedBatch {
+			select {
+			case w := <-embedQueue:
+				batch = append(batch, w)
+			default:
+				break fill
+			}
+		}
+		if err := embedBatchNow(batch); err != nil {
+			fmt.Printf("[data] Embedding %d texts failed: %v\n", len(batch), err)
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > embedMaxBackoff {
+				backoff = embedMaxBackoff
+			}
+			continue
+		}
+		backoff = time.Second
+	}
+}
+
+// embedBatchNow embeds a batch and stores the vectors.
+func embedBatchNow(batch []embedWork) error {
+	embedMutex.RLock()
+	model, fn := embedModel, embedder
+	embedMutex.RUnlock()
+
+	texts := make([]string, len(batch))
+	for i, w := range batch {
+		texts[i] = w.text
+	}
+	out, err := fn(texts)
+	if err != nil {
+		return err
+	}
+	if len(out) != len(batch) {
+		return fmt.Errorf("asked for %d embeddings, got %d", len(batch), len(out))
+	}
+
+	embedMutex.Lock()
+	defer embedMutex.Unlock()
+	if model != embedModel {
+		// the embedder changed while this batch was out
+		return nil
+	}
+	loadEmbeddings()
+	for i, w := range batch {
+		e := &embedding{typ: w.typ, hash: w.hash, vec: normalise(out[i])}
+		if !ReadOnly() {
+			db, err := getDB()
+			if err != nil {
+				return err
+			}
+			if _, err := db.Exec(`INSERT OR REPLACE INTO embeddings (id, type, model, hash, vector) VALUES (?, ?, ?, ?, ?)`,
+				w.id, w.typ, model, w.hash, encodeVector(e.vec)); err != nil {
+				return err
+			}
+		}
+		embeddings[w.id] = e
+	}
+	return nil
+}
+
+// backfillEmbeddings queues the public news and posts that have no
+// embedding yet, waiting for room in the queue rather than skipping any.
+// useSQLite is UseSQLite when the backfill was started.
+func backfillEmbeddings(useSQLite bool) {
+	var entries []*IndexEntry
+	if useSQLite {
+		for t := range embedTypes {
+			if list, err := GetByTypeSQLite(t, 100000); err == nil {
+				entries = append(entries, list...)
+			}
+		}
+	} else {
+		indexMutex.RLock()
+		for _, e := range index {
+			if embedTypes[e.Type] {
+				entries = append(entries, e)
+			}
+		}
+		indexMutex.RUnlock()
+	}
+
+	embedStart.Do(func() { go embedWorker() })
+	queued := 0
+	for _, e := range entries {
+		if e.Owner != "" {
+			continue
+		}
+		text := entryText(e.Title, e.Content)
+		h := textHash(text)
+		embedMutex.Lock()
+		loadEmbeddings()
+		have, ok := embeddings[e.ID]
+		embedMutex.Unlock()
+		if ok && have.hash == h {
+			continue
+		}
+		embedQueue <- embedWork{id: e.ID, typ: e.Type, text: text, hash: h}
+		queued++
+	}
+	if queued > 0 {
+		fmt.Printf("[data] Queued %d entries to embed with %s\n", queued, EmbeddingModel())
+	}
+}
+
+// embedEntry queues a newly indexed entry to be embedded if it's public
+// and of a type that is.
+func embedEntry(id, entryType, title, content, owner string) {
+	if owner != "" || !embedTypes[entryType] {
+		return
+	}
+	IndexEmbedding(id, entryType, entryText(title, content))
+}
+
+// entryText is what an index entry is embedded from.
+func entryText(title, content string) string {
+	return title + "\n\n" + content
+}
+
+func textHash(text string) string {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// normalise scales v to unit length, so similarity is a dot product.
+func normalise(v []float32) Vector {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	out := make(Vector, len(v))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// encodeVector packs v as little-endian float32s.
+func encodeVector(v Vector) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decodeVector(b []byte) Vector {
+	v := make(Vector, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
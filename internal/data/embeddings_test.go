@@ -0,0 +1,112 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// conceptEmbedder embeds texts by the concepts their words belong to, the
+// way a model would place them: "restaurant" and "eat" land together
+// without sharing a word.
+func conceptEmbedder(texts []string) ([][]float32, error) {
+	concepts := [][]string{
+		{"eat", "restaurant", "food", "dinner", "cafe"},
+		{"football", "striker", "match", "goal"},
+		{"bank", "rates", "savings", "interest"},
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(concepts))
+		for _, w := range strings.Fields(strings.ToLower(text)) {
+			for c, words := range concepts {
+				for _, cw := range words {
+					if w == cw {
+						v[c]++
+					}
+				}
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func waitEmbedded(t *testing.T, ids ...string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for _, id := range ids {
+		for storedEmbedding(id) == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s was never embedded", id)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
+
+func TestSemanticSearch(t *testing.T) {
+	resetSQLiteTestDB(t)
+	UseSQLite = false
+	ClearIndex()
+	SetEmbedder("concepts", conceptEmbedder)
+	t.Cleanup(func() {
+		SetEmbedder(localModel, embedLocal)
+		backfills.Wait()
+	})
+	backfills.Wait()
+
+	IndexEmbedding("place:1", "place", "Luigi's restaurant Italian")
+	IndexEmbedding("place:2", "place", "Riverside football ground")
+	IndexEmbedding("n1", "news", "Bank holds interest rates")
+	IndexEmbedding("n2", "news", "Dinner at the new cafe")
+	waitEmbedded(t, "place:1", "place:2", "n1", "n2")
+
+	got := SemanticSearch("somewhere to eat food", 10, WithType("place"))
+	if len(got) != 1 || got[0].ID != "place:1" {
+		t.Fatalf("places to eat = %+v, want place:1 alone", got)
+	}
+	got = SemanticSearch("somewhere to eat food", 10)
+	if len(got) != 2 || got[0].Type == got[1].Type {
+		t.Errorf("any type = %+v, want the restaurant and the cafe", got)
+	}
+
+	// Vectors outlive a restart, and go when their model does.
+	embedMutex.Lock()
+	embeddings = nil
+	embedMutex.Unlock()
+	if storedEmbedding("n1") == nil {
+		t.Error("embedding wasn't persisted")
+	}
+	ForgetEmbedding("n1")
+	if got := SemanticSearch("savings", 10); len(got) != 0 {
+		t.Errorf("forgotten entry still found: %+v", got)
+	}
+	SetEmbedder("another", conceptEmbedder)
+	if n := len(SemanticSearch("restaurant", 10)); n != 0 {
+		t.Errorf("found %d vectors from the old model", n)
+	}
+}
+
+func TestIndexEmbedsPublicNewsAndPosts(t *testing.T) {
+	resetSQLiteTestDB(t)
+	UseSQLite = false
+	ClearIndex()
+	SetEmbedder("concepts", conceptEmbedder)
+	t.Cleanup(func() {
+		SetEmbedder(localModel, embedLocal)
+		backfills.Wait()
+	})
+	backfills.Wait()
+
+	Index("n1", "news", "Striker scores", "A late goal wins the match", nil)
+	IndexOwned("m1", "post", "My football notes", "goal", "alice", nil)
+	Index("v1", "video", "Football highlights", "every goal", nil)
+	waitEmbedded(t, "n1")
+	time.Sleep(50 * time.Millisecond)
+	for _, id := range []string{"m1", "v1"} {
+		if storedEmbedding(id) != nil {
+			t.Errorf("%s was embedded", id)
+		}
+	}
+}
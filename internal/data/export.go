@@ -0,0 +1,243 @@
+package data
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================
+// USER DATA EXPORT REGISTRY
+// ============================================
+
+// Exporter is implemented by each module that holds a user's data. The
+// account export page lists every registered exporter and its formats.
+type Exporter interface {
+	// Name identifies the module, e.g. "mail". It is also the file name
+	// inside an export archive.
+	Name() string
+	// Description says what the export contains, for the export page.
+	Description() string
+	// Formats lists the supported formats, preferred first, e.g. "json",
+	// "csv", "mbox" or "gpx". Each is also the file extension.
+	Formats() []string
+	// Export writes the user's data in the given format to w.
+	Export(w io.Writer, userID, format string) error
+}
+
+var (
+	exporterMu sync.RWMutex
+	exporters  = map[string]Exporter{}
+)
+
+// RegisterExporter registers a module's exporter. Packages call this
+// during Load().
+func RegisterExporter(e Exporter) {
+	exporterMu.Lock()
+	exporters[e.Name()] = e
+	exporterMu.Unlock()
+}
+
+// Exporters returns the registered exporters sorted by name.
+func Exporters() []Exporter {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	list := make([]Exporter, 0, len(exporters))
+	for _, e := range exporters {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// GetExporter returns the named exporter and whether it supports format.
+func GetExporter(name, format string) (Exporter, bool) {
+	exporterMu.RLock()
+	e, ok := exporters[name]
+	exporterMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	for _, f := range e.Formats() {
+		if f == format {
+			return e, true
+		}
+	}
+	return e, false
+}
+
+// ExportZip streams every registered export, in every format, as a zip
+// archive to w. Each file is written straight into the archive, so the
+// export is never held in memory. A failing module is noted in
+// errors.txt rather than aborting the rest.
+func ExportZip(w io.Writer, userID string) error {
+	zw := zip.NewWriter(w)
+	var failed []string
+	for _, e := range Exporters() {
+		for _, format := range e.Formats() {
+			name := e.Name() + "." + format
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+			if err != nil {
+				return err
+			}
+			if err := e.Export(f, userID, format); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		f, err := zw.Create("errors.txt")
+		if err != nil {
+			return err
+		}
+		for _, line := range failed {
+			fmt.Fprintln(f, line)
+		}
+	}
+	return zw.Close()
+}
+
+// ============================================
+// EXPORT ARCHIVES
+// ============================================
+
+// ArchiveRetention is how long a prepared export archive is kept for
+// download.
+const ArchiveRetention = 24 * time.Hour
+
+// Archive states.
+const (
+	ArchiveNone      = ""
+	ArchivePreparing = "preparing"
+	ArchiveReady     = "ready"
+	ArchiveFailed    = "failed"
+)
+
+// Archive is the state of a user's export archive.
+type Archive struct {
+	Status  string    `json:"status"`
+	Started time.Time `json:"started,omitempty"`
+	Ready   time.Time `json:"ready,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+var (
+	archiveMu sync.Mutex
+	archives  = map[string]*Archive{} // userID → archive being prepared, or that failed
+)
+
+// archiveDir is where archives are kept. They stay out of the data store:
+// they're large, short-lived and only ever streamed back.
+func archiveDir() string {
+	return filepath.Join(os.ExpandEnv("$HOME/.mu"), "exports")
+}
+
+func archivePath(userID string) string {
+	return filepath.Join(archiveDir(), userID+".zip")
+}
+
+// PrepareArchive starts assembling the user's export archive in the
+// background, unless one is already being prepared. It replaces any
+// archive prepared before.
+func PrepareArchive(userID string) Archive {
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	if a := archives[userID]; a != nil && a.Status == ArchivePreparing {
+		return *a
+	}
+	a := &Archive{Status: ArchivePreparing, Started: time.Now()}
+	archives[userID] = a
+	go buildArchive(userID, a.Started)
+	return *a
+}
+
+func buildArchive(userID string, started time.Time) {
+	cleanArchives()
+	err := writeArchive(userID)
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	if err != nil {
+		archives[userID] = &Archive{Status: ArchiveFailed, Started: started, Error: err.Error()}
+		return
+	}
+	delete(archives, userID)
+}
+
+// writeArchive writes the archive to a temporary file and moves it into
+// place once it's complete, so a download never sees half an archive.
+func writeArchive(userID string) error {
+	if err := os.MkdirAll(archiveDir(), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(archiveDir(), userID+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := ExportZip(f, userID); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), archivePath(userID))
+}
+
+// GetArchive returns the state of the user's export archive. Archives
+// past ArchiveRetention are deleted.
+func GetArchive(userID string) Archive {
+	archiveMu.Lock()
+	if a := archives[userID]; a != nil {
+		archiveMu.Unlock()
+		return *a
+	}
+	archiveMu.Unlock()
+
+	info, err := os.Stat(archivePath(userID))
+	if err != nil {
+		return Archive{}
+	}
+	expires := info.ModTime().Add(ArchiveRetention)
+	if time.Now().After(expires) {
+		os.Remove(archivePath(userID))
+		return Archive{}
+	}
+	return Archive{Status: ArchiveReady, Ready: info.ModTime(), Expires: expires, Size: info.Size()}
+}
+
+// OpenArchive opens the user's prepared export archive for download.
+func OpenArchive(userID string) (*os.File, error) {
+	if GetArchive(userID).Status != ArchiveReady {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(archivePath(userID))
+}
+
+// DeleteArchive removes the user's export archive, for when the account
+// goes.
+func DeleteArchive(userID string) {
+	archiveMu.Lock()
+	delete(archives, userID)
+	archiveMu.Unlock()
+	os.Remove(archivePath(userID))
+}
+
+// cleanArchives deletes every archive, and any file left by an interrupted
+// one, past ArchiveRetention.
+func cleanArchives() {
+	entries, _ := os.ReadDir(archiveDir())
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && time.Since(info.ModTime()) > ArchiveRetention {
+			os.Remove(filepath.Join(archiveDir(), e.Name()))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testExporter struct {
+	name string
+	err  error
+}
+
+func (e testExporter) Name() string        { return e.name }
+func (e testExporter) Description() string { return "test" }
+func (e testExporter) Formats() []string   { return []string{"json", "csv"} }
+
+func (e testExporter) Export(w io.Writer, userID, format string) error {
+	if e.err != nil {
+		return e.err
+	}
+	_, err := io.WriteString(w, e.name+":"+userID+":"+format)
+	return err
+}
+
+// withExporters gives the test an empty exporter registry.
+func withExporters(t *testing.T) {
+	t.Helper()
+	exporterMu.Lock()
+	saved := exporters
+	exporters = map[string]Exporter{}
+	exporterMu.Unlock()
+	t.Cleanup(func() {
+		exporterMu.Lock()
+		exporters = saved
+		exporterMu.Unlock()
+	})
+}
+
+func TestExportZip(t *testing.T) {
+	withExporters(t)
+
+	RegisterExporter(testExporter{name: "notes"})
+	RegisterExporter(testExporter{name: "broken", err: errors.New("boom")})
+
+	if e, ok := GetExporter("notes", "csv"); e == nil || !ok {
+		t.Fatal("notes.csv not found")
+	}
+	if e, ok := GetExporter("notes", "mbox"); e == nil || ok {
+		t.Fatal("unsupported format reported as supported")
+	}
+
+	var buf bytes.Buffer
+	if err := ExportZip(&buf, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	if files["notes.json"] != "notes:alice:json" || files["notes.csv"] != "notes:alice:csv" {
+		t.Errorf("files = %v", files)
+	}
+	if !strings.Contains(files["errors.txt"], "broken.json: boom") {
+		t.Errorf("errors.txt = %q", files["errors.txt"])
+	}
+}
+
+func TestPrepareArchive(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	withExporters(t)
+	RegisterExporter(testExporter{name: "notes"})
+
+	if a := GetArchive("alice"); a.Status != ArchiveNone {
+		t.Fatalf("archive before preparing: %+v", a)
+	}
+	if a := PrepareArchive("alice"); a.Status != ArchivePreparing {
+		t.Fatalf("PrepareArchive = %+v", a)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for GetArchive("alice").Status != ArchiveReady {
+		if time.Now().After(deadline) {
+			t.Fatalf("archive not ready: %+v", GetArchive("alice"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f, err := OpenArchive("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := f.Stat()
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "notes.json" {
+		t.Errorf("archive holds %d files", len(zr.File))
+	}
+	f.Close()
+	if _, err := OpenArchive("bob"); err == nil {
+		t.Error("opened an archive that was never prepared")
+	}
+
+	DeleteArchive("alice")
+	if a := GetArchive("alice"); a.Status != ArchiveNone {
+		t.Errorf("archive after delete: %+v", a)
+	}
+}
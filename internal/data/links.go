@@ -0,0 +1,116 @@
+package data
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Backlinks. When a public entry is indexed, any links in its content to
+// other items on the site — blog posts and news articles — are recorded
+// against the linked item, so its page can show what links to it. Walking
+// those links from page to page gives a lightweight knowledge graph.
+
+// Link is one entry linking to another.
+type Link struct {
+	From  string    `json:"from"` // ID of the linking entry
+	Type  string    `json:"type"`
+	Title string    `json:"title"`
+	URL   string    `json:"url"`
+	Time  time.Time `json:"time"`
+}
+
+// internalLinkPattern matches links to items by ID, relative or on any
+// host: /blog/post?id=X and /news?id=X.
+var internalLinkPattern = regexp.MustCompile(`(?:https?://[a-zA-Z0-9.-]+(?::\d+)?)?/(?:blog/post|news)\?id=([A-Za-z0-9_-]{1,64})`)
+
+var (
+	linksMu sync.RWMutex
+	links   = map[string][]Link{} // target ID → entries linking to it
+)
+
+func loadLinks() {
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	LoadJSON("backlinks.json", &links)
+	if links == nil {
+		links = map[string][]Link{}
+	}
+}
+
+// linkTargets returns the IDs linked from content, each once, excluding
+// self.
+func linkTargets(self, content string) []string {
+	var out []string
+	seen := map[string]bool{self: true}
+	for _, m := range internalLinkPattern.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+// recordLinks replaces the links recorded from an entry with those in its
+// content. Private entries record nothing.
+func recordLinks(id, entryType, title, content, owner string, metadata map[string]interface{}) {
+	var targets []string
+	if owner == "" {
+		targets = linkTargets(id, content)
+	}
+	url, _ := metadata["url"].(string)
+
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	changed := dropLinksFrom(id)
+	now := time.Now()
+	for _, t := range targets {
+		links[t] = append(links[t], Link{From: id, Type: entryType, Title: title, URL: url, Time: now})
+		changed = true
+	}
+	if changed {
+		SaveJSON("backlinks.json", links)
+	}
+}
+
+// dropLinksFrom removes every link recorded from the entry (caller holds
+// linksMu).
+func dropLinksFrom(id string) bool {
+	changed := false
+	for target, list := range links {
+		kept := list[:0]
+		for _, l := range list {
+			if l.From == id {
+				changed = true
+				continue
+			}
+			kept = append(kept, l)
+		}
+		if len(kept) == 0 {
+			delete(links, target)
+		} else {
+			links[target] = kept
+		}
+	}
+	return changed
+}
+
+// forgetLinks removes an unindexed entry's outgoing links.
+func forgetLinks(id string) {
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	if dropLinksFrom(id) {
+		SaveJSON("backlinks.json", links)
+	}
+}
+
+// Backlinks returns the entries linking to id, newest first.
+func Backlinks(id string) []Link {
+	linksMu.RLock()
+	out := append([]Link(nil), links[id]...)
+	linksMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.After(out[j].Time) })
+	return out
+}
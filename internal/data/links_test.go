@@ -0,0 +1,37 @@
+package data
+
+import "testing"
+
+func TestBacklinksRecordedAtIndexTime(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	UseSQLite = false
+	ClearIndex()
+	linksMu.Lock()
+	links = map[string][]Link{}
+	linksMu.Unlock()
+
+	content := "See [this](/blog/post?id=123) and https://mu.xyz/news?id=abcdef0123456789, also /blog/post?id=123 again and myself /blog/post?id=999"
+	Index("999", "post", "Roundup", content, map[string]interface{}{"url": "/blog/post?id=999"})
+	IndexOwned("m1", "mail", "Private", "/blog/post?id=123", "alice", nil)
+
+	got := Backlinks("123")
+	if len(got) != 1 || got[0].From != "999" || got[0].URL != "/blog/post?id=999" {
+		t.Fatalf("Backlinks(123) = %+v", got)
+	}
+	if len(Backlinks("abcdef0123456789")) != 1 {
+		t.Error("absolute news link not recorded")
+	}
+	if len(Backlinks("999")) != 0 {
+		t.Error("self link recorded")
+	}
+
+	// Re-indexing without the link drops it; unindexing drops the rest.
+	Index("999", "post", "Roundup", "see /news?id=abcdef0123456789", nil)
+	if len(Backlinks("123")) != 0 {
+		t.Error("stale link kept after edit")
+	}
+	Unindex("999")
+	if len(Backlinks("abcdef0123456789")) != 0 {
+		t.Error("links kept after unindex")
+	}
+}
@@ -0,0 +1,18 @@
+package data
+
+import "sync/atomic"
+
+var lowMemory atomic.Bool
+
+// SetLowMemory switches on the low-memory profile for small hosts such as
+// a Raspberry Pi. Packages check LowMemory to render pages on demand rather
+// than hold them in memory, to shrink their caches and to fetch less in
+// parallel. It must be set before the packages load.
+func SetLowMemory(on bool) {
+	lowMemory.Store(on)
+}
+
+// LowMemory reports whether the low-memory profile is on.
+func LowMemory() bool {
+	return lowMemory.Load()
+}
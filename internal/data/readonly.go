@@ -0,0 +1,25 @@
+package data
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrReadOnly is returned by every write while the store is read-only.
+var ErrReadOnly = errors.New("data: store is read-only")
+
+var readOnly atomic.Bool
+
+// SetReadOnly switches the store into (or out of) read-only mode. While
+// read-only, SaveFile, SaveJSON, DeleteFile and index writes to SQLite are
+// refused with ErrReadOnly, so a server started against a copy of production
+// data can never change it. In-memory state still updates; it is simply
+// never persisted.
+func SetReadOnly(on bool) {
+	readOnly.Store(on)
+}
+
+// ReadOnly reports whether the store is read-only.
+func ReadOnly() bool {
+	return readOnly.Load()
+}
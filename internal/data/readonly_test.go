@@ -0,0 +1,38 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadOnlyRefusesWrites(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SaveFile("keep.txt", "before"); err != nil {
+		t.Fatal(err)
+	}
+
+	SetReadOnly(true)
+	defer SetReadOnly(false)
+
+	if err := SaveFile("keep.txt", "after"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("SaveFile: got %v, want ErrReadOnly", err)
+	}
+	if err := SaveJSON("new.json", map[string]int{"a": 1}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("SaveJSON: got %v, want ErrReadOnly", err)
+	}
+	if err := DeleteFile("keep.txt"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("DeleteFile: got %v, want ErrReadOnly", err)
+	}
+	if err := IndexSQLite("x", "post", "t", "c", "", nil); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("IndexSQLite: got %v, want ErrReadOnly", err)
+	}
+
+	// Reads still work and see the original data.
+	b, err := LoadFile("keep.txt")
+	if err != nil || string(b) != "before" {
+		t.Fatalf("LoadFile = %q, %v", b, err)
+	}
+	if _, err := LoadFile("new.json"); err == nil {
+		t.Fatal("new.json should not have been written")
+	}
+}
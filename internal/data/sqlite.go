@@ -80,6 +80,23 @@ func initDB() error {
 			return
 		}
 
+		// Embeddings for semantic search (see embeddings.go), as
+		// little-endian float32s.
+		_, err = db.Exec(`
+			CREATE TABLE IF NOT EXISTS embeddings (
+				id TEXT PRIMARY KEY,
+				type TEXT NOT NULL,
+				model TEXT NOT NULL,
+				hash TEXT NOT NULL,
+				vector BLOB NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings(model);
+		`)
+		if err != nil {
+			initErr = fmt.Errorf("failed to create tables: %w", err)
+			return
+		}
+
 		fmt.Println("[data] SQLite database initialized at", dbPath)
 	})
 	return initErr
@@ -96,6 +113,9 @@ func getDB() (*sql.DB, error) {
 // IndexSQLite adds or updates an entry in the SQLite index. A non-empty owner
 // marks the entry private (see WithOwner).
 func IndexSQLite(id, entryType, title, content, owner string, metadata map[string]interface{}) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
 	db, err := getDB()
 	if err != nil {
 		return err
@@ -137,6 +157,20 @@ func IndexSQLite(id, entryType, title, content, owner string, metadata map[strin
 	return err
 }
 
+// UnindexSQLite removes an entry from the SQLite index.
+func UnindexSQLite(id string) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
+	db, err := getDB()
+	if err != nil {
+		return err
+	}
+	db.Exec(`DELETE FROM index_fts WHERE rowid = (SELECT rowid FROM index_entries WHERE id = ?)`, id)
+	_, err = db.Exec(`DELETE FROM index_entries WHERE id = ?`, id)
+	return err
+}
+
 // GetByIDSQLite retrieves an entry by ID from SQLite
 func GetByIDSQLite(id string) (*IndexEntry, error) {
 	db, err := getDB()
@@ -493,6 +527,9 @@ func GetByTypeSQLite(entryType string, limit int) ([]*IndexEntry, error) {
 
 // MigrateFromJSON migrates existing JSON data to SQLite
 func MigrateFromJSON() error {
+	if ReadOnly() {
+		return nil
+	}
 	db, err := getDB()
 	if err != nil {
 		return err
@@ -587,11 +624,19 @@ func GetIndexStats() (entries int, embeddingCount int, err error) {
 		return 0, 0, err
 	}
 
-	return entries, 0, nil
+	err = db.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&embeddingCount)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return entries, embeddingCount, nil
 }
 
 // rebuildFTS repopulates the FTS5 index from the index_entries table
 func rebuildFTS() {
+	if ReadOnly() {
+		return
+	}
 	db, err := getDB()
 	if err != nil {
 		return
@@ -0,0 +1,449 @@
+package data
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================
+// SQLITE FILE STORE
+// ============================================
+//
+// By default every key is a file under ~/.mu/data, rewritten whole on each
+// save. With MU_STORE=sqlite the same LoadFile/SaveFile/SaveJSON/LoadJSON
+// calls go to data/store.db instead:
+//
+//   - plain keys are rows in the files table, one value per key;
+//   - keys registered with RegisterTable (mail.json, blog.json, ...) are
+//     split into one row per record in the records table, and a save only
+//     writes the records that actually changed.
+//
+// On first start the existing files are imported (see migrateStore). The
+// files themselves are left in place as a backup.
+
+// UseSQLiteStore keeps data in SQLite instead of one file per key.
+// Set via MU_STORE=sqlite.
+var UseSQLiteStore = os.Getenv("MU_STORE") == "sqlite"
+
+var (
+	storeDB     *sql.DB
+	storeDBOnce sync.Once
+	storeDBErr  error
+
+	tablesMu sync.RWMutex
+	tables   = map[string]bool{}
+
+	// recordState remembers what each table looked like after its last
+	// load or save, so the next save can write just the difference.
+	recordMu    sync.Mutex
+	recordState = map[string]*tableState{}
+)
+
+type tableState struct {
+	order  []string
+	hashes map[string]uint64
+}
+
+// RegisterTable marks key as a collection of records: a JSON array of
+// objects, each with a unique string "id". When the SQLite store is on, the
+// key is kept as one row per record. Call it before the key is first
+// loaded or saved; it has no effect on the file store.
+func RegisterTable(key string) {
+	tablesMu.Lock()
+	tables[key] = true
+	tablesMu.Unlock()
+}
+
+func isTable(key string) bool {
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+	return tables[key]
+}
+
+// getStoreDB opens data/store.db, creating the schema if needed.
+func getStoreDB() (*sql.DB, error) {
+	storeDBOnce.Do(func() {
+		file, err := dataPath("store.db")
+		if err != nil {
+			storeDBErr = err
+			return
+		}
+		if ReadOnly() {
+			// Never create or alter the store when serving a copy.
+			d, err := sql.Open("sqlite", "file:"+file+"?mode=ro")
+			if err != nil {
+				storeDBErr = fmt.Errorf("failed to open store: %w", err)
+				return
+			}
+			storeDB = d
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			storeDBErr = err
+			return
+		}
+		d, err := sql.Open("sqlite", file+"?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=10000")
+		if err != nil {
+			storeDBErr = fmt.Errorf("failed to open store: %w", err)
+			return
+		}
+		d.SetMaxOpenConns(1)
+		d.SetMaxIdleConns(1)
+
+		_, err = d.Exec(`
+			CREATE TABLE IF NOT EXISTS files (
+				key TEXT PRIMARY KEY,
+				value BLOB NOT NULL,
+				updated_at DATETIME NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS records (
+				collection TEXT NOT NULL,
+				id TEXT NOT NULL,
+				pos INTEGER NOT NULL,
+				value BLOB NOT NULL,
+				updated_at DATETIME NOT NULL,
+				PRIMARY KEY (collection, id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_records_pos ON records(collection, pos);
+			CREATE TABLE IF NOT EXISTS store_meta (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			);
+		`)
+		if err != nil {
+			d.Close()
+			storeDBErr = fmt.Errorf("failed to create store tables: %w", err)
+			return
+		}
+		// Packages load their data from init(), so the import has to
+		// happen here, on first use, rather than from Load.
+		if err := migrateStore(d); err != nil {
+			fmt.Printf("[data] Store migration error: %v\n", err)
+		}
+		storeDB = d
+	})
+	return storeDB, storeDBErr
+}
+
+func notFound(key string) error {
+	return &fs.PathError{Op: "open", Path: key, Err: fs.ErrNotExist}
+}
+
+// storeLoad reads key from the SQLite store. Missing keys return an error
+// satisfying os.IsNotExist, like the file store.
+func storeLoad(key string) ([]byte, error) {
+	d, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+
+	// A table that has been split into records is rebuilt from its rows.
+	rows, err := d.Query(`SELECT id, value FROM records WHERE collection = ? ORDER BY pos`, key)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	state := &tableState{hashes: map[string]uint64{}}
+	for rows.Next() {
+		var id string
+		var value []byte
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if buf.Len() == 0 {
+			buf.WriteByte('[')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.Write(value)
+		state.order = append(state.order, id)
+		state.hashes[id] = hashRecord(value)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte(']')
+		recordMu.Lock()
+		recordState[key] = state
+		recordMu.Unlock()
+		return buf.Bytes(), nil
+	}
+
+	var value []byte
+	err = d.QueryRow(`SELECT value FROM files WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(key)
+	}
+	return value, err
+}
+
+// storeSave writes key to the SQLite store.
+func storeSave(key string, val []byte) error {
+	d, err := getStoreDB()
+	if err != nil {
+		return err
+	}
+	if isTable(key) {
+		if records, ok := splitRecords(val); ok && len(records) > 0 {
+			return saveRecords(d, key, records)
+		}
+		// Empty, or not a list of records after all: store it whole,
+		// dropping any rows from an earlier save.
+	}
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM records WHERE collection = ?`, key); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO files (key, value, updated_at) VALUES (?, ?, ?)`, key, val, time.Now()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	recordMu.Lock()
+	delete(recordState, key)
+	recordMu.Unlock()
+	return nil
+}
+
+// storeAppend appends val to key's value in the SQLite store.
+func storeAppend(key string, val []byte) error {
+	d, err := getStoreDB()
+	if err != nil {
+		return err
+	}
+	_, err = d.Exec(`INSERT INTO files (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = CAST(value || excluded.value AS BLOB), updated_at = excluded.updated_at`,
+		key, val, time.Now())
+	return err
+}
+
+// storeDelete removes key from the SQLite store.
+func storeDelete(key string) error {
+	d, err := getStoreDB()
+	if err != nil {
+		return err
+	}
+	res, err := d.Exec(`DELETE FROM files WHERE key = ?`, key)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	res, err = d.Exec(`DELETE FROM records WHERE collection = ?`, key)
+	if err != nil {
+		return err
+	}
+	m, _ := res.RowsAffected()
+	recordMu.Lock()
+	delete(recordState, key)
+	recordMu.Unlock()
+	if n+m == 0 {
+		return notFound(key)
+	}
+	return nil
+}
+
+type record struct {
+	id    string
+	value []byte
+}
+
+// splitRecords splits a JSON array of objects into records keyed by their
+// "id". It reports false if val isn't one, or if an id is missing or
+// repeated.
+func splitRecords(val []byte) ([]record, bool) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(val, &raw); err != nil {
+		return nil, false
+	}
+	out := make([]record, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, r := range raw {
+		var head struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(r, &head); err != nil || head.ID == "" || seen[head.ID] {
+			return nil, false
+		}
+		seen[head.ID] = true
+		out = append(out, record{id: head.ID, value: r})
+	}
+	return out, true
+}
+
+func hashRecord(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// saveRecords writes the records that were added, changed or moved since
+// the last load or save of key, and deletes the ones that are gone.
+func saveRecords(d *sql.DB, key string, records []record) error {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+
+	prev := recordState[key]
+	if prev == nil {
+		// Never loaded through the store (e.g. first save after migrating
+		// the file): start from whatever rows exist.
+		var err error
+		if prev, err = loadTableState(d, key); err != nil {
+			return err
+		}
+	}
+	prevPos := make(map[string]int, len(prev.order))
+	for i, id := range prev.order {
+		prevPos[id] = i
+	}
+
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	// The whole-value row, if any, is superseded by the records.
+	if _, err := tx.Exec(`DELETE FROM files WHERE key = ?`, key); err != nil {
+		tx.Rollback()
+		return err
+	}
+	upsert, err := tx.Prepare(`INSERT OR REPLACE INTO records (collection, id, pos, value, updated_at) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer upsert.Close()
+	move, err := tx.Prepare(`UPDATE records SET pos = ? WHERE collection = ? AND id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer move.Close()
+
+	now := time.Now()
+	next := &tableState{order: make([]string, len(records)), hashes: make(map[string]uint64, len(records))}
+	for i, r := range records {
+		h := hashRecord(r.value)
+		next.order[i] = r.id
+		next.hashes[r.id] = h
+
+		oldPos, existed := prevPos[r.id]
+		switch {
+		case !existed || prev.hashes[r.id] != h:
+			_, err = upsert.Exec(key, r.id, i, r.value, now)
+		case oldPos != i:
+			_, err = move.Exec(i, key, r.id)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, id := range prev.order {
+		if _, ok := next.hashes[id]; ok {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM records WHERE collection = ? AND id = ?`, key, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	recordState[key] = next
+	return nil
+}
+
+// loadTableState reads the current ids and hashes of a table's records.
+// Caller must hold recordMu.
+func loadTableState(d *sql.DB, key string) (*tableState, error) {
+	rows, err := d.Query(`SELECT id, value FROM records WHERE collection = ? ORDER BY pos`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	state := &tableState{hashes: map[string]uint64{}}
+	for rows.Next() {
+		var id string
+		var value []byte
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		state.order = append(state.order, id)
+		state.hashes[id] = hashRecord(value)
+	}
+	return state, rows.Err()
+}
+
+// migrateStore imports the files under the data directory into the SQLite
+// store. It runs once; later starts find the store already populated.
+// The files are left on disk untouched.
+func migrateStore(d *sql.DB) error {
+	var done string
+	if err := d.QueryRow(`SELECT value FROM store_meta WHERE key = 'migrated_at'`).Scan(&done); err == nil {
+		return nil
+	}
+
+	base, err := dataPath(".")
+	if err != nil {
+		return err
+	}
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	imported := 0
+	err = filepath.WalkDir(base, func(path string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		name := e.Name()
+		if strings.HasSuffix(name, ".db") || strings.HasSuffix(name, ".db-wal") || strings.HasSuffix(name, ".db-shm") {
+			return nil
+		}
+		key, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO files (key, value, updated_at) VALUES (?, ?, ?)`, key, b, time.Now()); err != nil {
+			return fmt.Errorf("import %s: %w", key, err)
+		}
+		imported++
+		return nil
+	})
+	if err == nil {
+		_, err = tx.Exec(`INSERT INTO store_meta (key, value) VALUES ('migrated_at', ?)`, time.Now().Format(time.RFC3339))
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("[data] Imported %d files into the SQLite store\n", imported)
+	return nil
+}
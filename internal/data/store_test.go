@@ -0,0 +1,128 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func resetStoreTestDB(t *testing.T) string {
+	t.Helper()
+	sqliteTestMu.Lock()
+	t.Cleanup(sqliteTestMu.Unlock)
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	if storeDB != nil {
+		_ = storeDB.Close()
+	}
+	storeDBOnce = sync.Once{}
+	storeDB, storeDBErr = nil, nil
+	recordState = map[string]*tableState{}
+	UseSQLiteStore = true
+	t.Cleanup(func() {
+		UseSQLiteStore = false
+		if storeDB != nil {
+			_ = storeDB.Close()
+		}
+		storeDBOnce = sync.Once{}
+		storeDB, storeDBErr = nil, nil
+	})
+	return tempDir
+}
+
+func TestStoreMigratesFiles(t *testing.T) {
+	tempDir := resetStoreTestDB(t)
+	dataDir := filepath.Join(tempDir, ".mu", "data")
+	os.MkdirAll(filepath.Join(dataDir, "apps"), 0700)
+	os.WriteFile(filepath.Join(dataDir, "settings.json"), []byte(`{"a":1}`), 0600)
+	os.WriteFile(filepath.Join(dataDir, "apps", "x.html"), []byte("<p>x</p>"), 0600)
+	os.WriteFile(filepath.Join(dataDir, "index.db"), []byte("not imported"), 0600)
+
+	// The first load opens the store, which imports the files.
+	var settings map[string]int
+	if err := LoadJSON("settings.json", &settings); err != nil || settings["a"] != 1 {
+		t.Fatalf("settings = %v, %v", settings, err)
+	}
+
+	// Migration runs once: after a restart, a changed file on disk is not
+	// re-imported.
+	os.WriteFile(filepath.Join(dataDir, "settings.json"), []byte(`{"a":2}`), 0600)
+	storeDB.Close()
+	storeDBOnce = sync.Once{}
+	storeDB, storeDBErr = nil, nil
+
+	settings = nil
+	if err := LoadJSON("settings.json", &settings); err != nil || settings["a"] != 1 {
+		t.Fatalf("settings = %v, %v", settings, err)
+	}
+	if b, err := LoadFile("apps/x.html"); err != nil || string(b) != "<p>x</p>" {
+		t.Fatalf("apps/x.html = %q, %v", b, err)
+	}
+	if _, err := LoadFile("index.db"); !os.IsNotExist(err) {
+		t.Fatalf("index.db should not be imported, got %v", err)
+	}
+
+	if err := DeleteFile("settings.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile("settings.json"); !os.IsNotExist(err) {
+		t.Fatalf("deleted key still loads: %v", err)
+	}
+}
+
+func TestStoreTableWritesChangedRecords(t *testing.T) {
+	resetStoreTestDB(t)
+	RegisterTable("things.json")
+
+	type thing struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	things := []thing{{"a", "one"}, {"b", "two"}, {"c", "three"}}
+	if err := SaveJSON("things.json", things); err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := getStoreDB()
+	updated := func(id string) string {
+		var at string
+		d.QueryRow(`SELECT updated_at FROM records WHERE collection = 'things.json' AND id = ?`, id).Scan(&at)
+		return at
+	}
+	before := updated("a")
+	if before == "" {
+		t.Fatal("records were not written")
+	}
+
+	// Change b, drop c, add d: a is left alone.
+	things = []thing{{"a", "one"}, {"b", "TWO"}, {"d", "four"}}
+	if err := SaveJSON("things.json", things); err != nil {
+		t.Fatal(err)
+	}
+	if updated("a") != before {
+		t.Error("unchanged record was rewritten")
+	}
+	if updated("c") != "" {
+		t.Error("removed record still stored")
+	}
+
+	// Reload from a fresh process state.
+	recordState = map[string]*tableState{}
+	var got []thing
+	if err := LoadJSON("things.json", &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != things[0] || got[1] != things[1] || got[2] != things[2] {
+		t.Fatalf("reloaded %v, want %v", got, things)
+	}
+
+	// An empty list is stored whole and loads back as empty.
+	if err := SaveJSON("things.json", []thing{}); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if err := LoadJSON("things.json", &got); err != nil || len(got) != 0 {
+		t.Fatalf("empty table = %v, %v", got, err)
+	}
+}
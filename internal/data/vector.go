@@ -0,0 +1,301 @@
+package data
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ============================================
+// VECTOR + KEYWORD (HYBRID) SEARCH FOR RAG
+// ============================================
+
+// Entries are embedded locally, without a model or an API call: words and
+// adjacent word pairs are hashed into a fixed number of dimensions, so
+// texts that share vocabulary point the same way even when the exact
+// phrase differs. That catches what plain substring search misses ("rate
+// cut" finds "cuts interest rates") and costs nothing per entry.
+//
+// HybridSearch ranks entries by keywords and by similarity separately and
+// fuses the two rankings, so an entry near the top of either list does
+// well and one near the top of both does best. Once a model embedder is
+// set (see SetEmbedder), similarity comes from the model's embeddings
+// instead.
+
+// vectorDims is the size of an embedding.
+const vectorDims = 256
+
+// minSimilarity is the least cosine similarity for an entry found by
+// vector alone, below which matches are mostly noise.
+const minSimilarity = 0.2
+
+// rrfK damps reciprocal rank fusion so the first few places of one list
+// don't swamp the other.
+const rrfK = 60
+
+// Vector is an embedding, normalised to unit length.
+type Vector []float32
+
+var (
+	vectorMutex sync.RWMutex
+	vectors     = make(map[string]Vector) // entry ID → embedding
+)
+
+// stopwords carry no meaning for retrieval.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "can": true, "do": true, "does": true,
+	"for": true, "from": true, "had": true, "has": true, "have": true, "how": true,
+	"i": true, "if": true, "in": true, "into": true, "is": true, "it": true,
+	"its": true, "me": true, "my": true, "not": true, "of": true, "on": true,
+	"or": true, "our": true, "so": true, "than": true, "that": true, "the": true,
+	"their": true, "them": true, "then": true, "there": true, "these": true,
+	"they": true, "this": true, "to": true, "was": true, "we": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true,
+	"why": true, "will": true, "with": true, "would": true, "you": true, "your": true,
+	"about": true, "tell": true, "any": true, "been": true, "more": true,
+}
+
+// Terms splits text into lower-case words for matching, without
+// stopwords, with plurals reduced to the singular.
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, w := range words {
+		if len(w) < 2 || stopwords[w] {
+			continue
+		}
+		terms = append(terms, stem(w))
+	}
+	return terms
+}
+
+// stem reduces the commonest English inflections, enough that "rates"
+// and "rate" land in the same dimension.
+func stem(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+// Embed returns the embedding of text.
+func Embed(text string) Vector {
+	v := make([]float64, vectorDims)
+	counts := map[string]float64{}
+	terms := Terms(text)
+	for i, t := range terms {
+		counts[t]++
+		if i > 0 {
+			counts[terms[i-1]+" "+t] += 0.5
+		}
+	}
+	for feature, n := range counts {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		w := 1 + math.Log(n)
+		if n < 1 {
+			w = n
+		}
+		// the top bit picks the sign, so collisions tend to cancel out
+		if sum&(1<<31) != 0 {
+			w = -w
+		}
+		v[sum%vectorDims] += w
+	}
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	out := make(Vector, vectorDims)
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = float32(x / norm)
+	}
+	return out
+}
+
+// Similarity is the cosine similarity of two embeddings.
+func Similarity(a, b Vector) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+// entryVector returns an entry's embedding, computing it if need be. It's
+// kept, except in low-memory mode, where it's cheaper to recompute.
+func entryVector(e *IndexEntry) Vector {
+	vectorMutex.RLock()
+	v, ok := vectors[e.ID]
+	vectorMutex.RUnlock()
+	if ok {
+		return v
+	}
+	v = Embed(e.Title + " " + e.Title + " " + e.Content)
+	if !LowMemory() {
+		vectorMutex.Lock()
+		vectors[e.ID] = v
+		vectorMutex.Unlock()
+	}
+	return v
+}
+
+// forgetVector drops an entry's embedding, after it changes or goes.
+func forgetVector(id string) {
+	vectorMutex.Lock()
+	delete(vectors, id)
+	vectorMutex.Unlock()
+}
+
+// keywordScore scores an entry by the query terms it contains, whole
+// words counting for more than parts of words, the title for more than
+// the body.
+func keywordScore(e *IndexEntry, terms []string, phrase string) float64 {
+	title := strings.ToLower(e.Title)
+	content := strings.ToLower(e.Content)
+	score := 0.0
+	for _, t := range terms {
+		switch {
+		case matchesWordBoundary(title, t):
+			score += 3
+		case strings.Contains(title, t):
+			score += 1
+		}
+		switch {
+		case matchesWordBoundary(content, t):
+			score += 1
+		case strings.Contains(content, t):
+			score += 0.25
+		}
+	}
+	if phrase != "" && strings.Contains(title+" "+content, phrase) {
+		score += 2
+	}
+	return score
+}
+
+// HybridSearch finds the entries most relevant to query, by keyword and
+// by similarity together. Each result's Score is its fused rank score;
+// only the order means anything. Options are as for Search.
+func HybridSearch(query string, limit int, opts ...SearchOption) []SearchResult {
+	options := &SearchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	phrase := strings.ToLower(strings.TrimSpace(query))
+	qv := Embed(query)
+	var mv Vector
+	if !options.KeywordOnly {
+		mv = modelQueryVector(query)
+	}
+
+	// The candidates: everything eligible when the index is in memory,
+	// otherwise what the full-text search turns up, and the model's
+	// nearest entries, reranked.
+	var candidates []*IndexEntry
+	if UseSQLite {
+		candidates = Search(strings.Join(terms, " "), max(limit, 10)*5, opts...)
+		if mv != nil {
+			seen := map[string]bool{}
+			for _, e := range candidates {
+				seen[e.ID] = true
+			}
+			for _, m := range nearest(mv, max(limit, 10)*5, options.Type) {
+				if seen[m.ID] {
+					continue
+				}
+				// embedded entries are public, but an ID may not be an entry
+				if e := GetByID(m.ID); e != nil && e.Owner == "" {
+					candidates = append(candidates, e)
+				}
+			}
+		}
+	} else {
+		indexMutex.RLock()
+		for _, e := range index {
+			if options.Type != "" && e.Type != options.Type {
+				continue
+			}
+			if e.Owner != "" && e.Owner != options.Owner {
+				continue
+			}
+			candidates = append(candidates, e)
+		}
+		indexMutex.RUnlock()
+	}
+
+	type scored struct {
+		entry   *IndexEntry
+		keyword float64
+		vector  float64
+	}
+	var byKeyword, byVector []scored
+	for _, e := range candidates {
+		s := scored{entry: e, keyword: keywordScore(e, terms, phrase)}
+		switch {
+		case options.KeywordOnly:
+		case mv != nil:
+			// entries the model hasn't embedded yet rank by keyword alone
+			if ev := storedEmbedding(e.ID); ev != nil {
+				s.vector = Similarity(mv, ev)
+			}
+		default:
+			s.vector = Similarity(qv, entryVector(e))
+		}
+		if s.keyword > 0 {
+			byKeyword = append(byKeyword, s)
+		}
+		if s.vector >= minSimilarity {
+			byVector = append(byVector, s)
+		}
+	}
+	sort.SliceStable(byKeyword, func(i, j int) bool { return byKeyword[i].keyword > byKeyword[j].keyword })
+	sort.SliceStable(byVector, func(i, j int) bool { return byVector[i].vector > byVector[j].vector })
+
+	fused := map[string]*SearchResult{}
+	var order []string
+	add := func(list []scored) {
+		for rank, s := range list {
+			r, ok := fused[s.entry.ID]
+			if !ok {
+				r = &SearchResult{Entry: s.entry}
+				fused[s.entry.ID] = r
+				order = append(order, s.entry.ID)
+			}
+			r.Score += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+	add(byKeyword)
+	add(byVector)
+
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *fused[id])
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestEmbedSimilarity(t *testing.T) {
+	a := Embed("Central bank cuts interest rates")
+	b := Embed("interest rate cut by the central bank")
+	c := Embed("Football club signs new striker")
+	if ab, ac := Similarity(a, b), Similarity(a, c); ab <= ac || ab < 0.5 {
+		t.Errorf("similar texts %.2f, unrelated %.2f", ab, ac)
+	}
+	if s := Similarity(a, a); s < 0.999 || s > 1.001 {
+		t.Errorf("self similarity = %.3f, want 1", s)
+	}
+	if got := Terms("The rates are rising"); len(got) != 2 || got[0] != "rate" || got[1] != "rising" {
+		t.Errorf("Terms = %v", got)
+	}
+}
+
+func TestHybridSearch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	UseSQLite = false
+	ClearIndex()
+
+	processIndexWork(IndexWork{ID: "n1", Type: "news", Title: "Fed cuts interest rates", Content: "The central bank lowered borrowing costs by a quarter point."})
+	processIndexWork(IndexWork{ID: "n2", Type: "news", Title: "Striker joins club", Content: "A transfer ends a long saga."})
+	processIndexWork(IndexWork{ID: "p1", Type: "post", Title: "What a rate cut means for savers", Content: "Savings accounts pay less when the central bank cuts."})
+	processIndexWork(IndexWork{ID: "m1", Type: "mail", Title: "Rate cut", Content: "private interest rates note", Owner: "alice"})
+
+	// Plain substring search misses the inflected title; hybrid doesn't.
+	if got := ids(Search("interest rate cut", 10)); len(got) != 0 {
+		t.Fatalf("substring search unexpectedly matched %v", got)
+	}
+	results := HybridSearch("interest rate cut", 10)
+	got := map[string]bool{}
+	for _, r := range results {
+		got[r.Entry.ID] = true
+	}
+	if !got["n1"] || !got["p1"] || got["n2"] {
+		t.Errorf("hybrid search = %v, want n1 and p1 only", got)
+	}
+	if got["m1"] {
+		t.Error("hybrid search leaked a private entry")
+	}
+	if len(results) > 1 && results[0].Score < results[len(results)-1].Score {
+		t.Error("results not ordered by score")
+	}
+
+	if r := HybridSearch("interest rate cut", 10, WithType("post")); len(r) != 1 || r[0].Entry.ID != "p1" {
+		t.Errorf("type filter = %v", r)
+	}
+	if r := HybridSearch("rate cut", 10, WithOwner("alice")); len(r) == 0 {
+		t.Error("owner scope found nothing")
+	}
+	if r := HybridSearch("the and of", 10); r != nil {
+		t.Errorf("stopwords only = %v, want nil", r)
+	}
+
+	// Changing an entry re-embeds it.
+	processIndexWork(IndexWork{ID: "n2", Type: "news", Title: "Bank of England holds rates", Content: "No interest rate cut this month."})
+	got = map[string]bool{}
+	for _, r := range HybridSearch("interest rate cut", 10) {
+		got[r.Entry.ID] = true
+	}
+	if !got["n2"] {
+		t.Error("updated entry not found by its new content")
+	}
+}
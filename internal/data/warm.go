@@ -0,0 +1,134 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================
+// WARM CACHES
+// ============================================
+
+// WarmCache is an in-memory cache that is kept across restarts, so a boot
+// doesn't refill it from upstream all at once. SaveWarm snapshots every
+// registered cache on shutdown; RegisterWarm restores the last snapshot
+// along with when it was taken, so the owner can treat what it gets back
+// as stale and refresh it in its own time.
+type WarmCache struct {
+	// Name identifies the cache; its snapshot is stored as warm/<Name>.json.
+	Name string
+	// MaxAge is how old a snapshot may be and still be restored.
+	MaxAge time.Duration
+	// Snapshot returns the contents of the cache, to be JSON encoded.
+	Snapshot func() any
+	// Restore loads a snapshot taken at savedAt back into the cache.
+	Restore func(raw json.RawMessage, savedAt time.Time) error
+}
+
+// WarmStatus describes a registered cache's last snapshot.
+type WarmStatus struct {
+	Name       string    `json:"name"`
+	RestoredAt time.Time `json:"restored_at,omitempty"` // zero if nothing was restored
+	SavedAt    time.Time `json:"saved_at,omitempty"`    // when the restored snapshot was taken
+	Stale      bool      `json:"stale"`                 // serving a snapshot, not yet refreshed
+}
+
+type warmSnapshot struct {
+	SavedAt time.Time       `json:"saved_at"`
+	Data    json.RawMessage `json:"data"`
+}
+
+var (
+	warmMu     sync.Mutex
+	warmCaches = map[string]*WarmCache{}
+	warmStatus = map[string]*WarmStatus{}
+)
+
+func warmKey(name string) string {
+	return filepath.Join("warm", name+".json")
+}
+
+// RegisterWarm registers a cache to be kept across restarts and restores
+// its last snapshot if there is one younger than MaxAge. Packages call it
+// during Load(), before they start refreshing the cache.
+func RegisterWarm(c WarmCache) {
+	warmMu.Lock()
+	warmCaches[c.Name] = &c
+	status := &WarmStatus{Name: c.Name}
+	warmStatus[c.Name] = status
+	warmMu.Unlock()
+
+	b, err := LoadFile(warmKey(c.Name))
+	if err != nil || len(b) == 0 {
+		return
+	}
+	var snap warmSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		fmt.Printf("[data] Warm cache %s: unreadable snapshot: %v\n", c.Name, err)
+		return
+	}
+	age := time.Since(snap.SavedAt)
+	if c.MaxAge > 0 && age > c.MaxAge {
+		fmt.Printf("[data] Warm cache %s: snapshot is %s old, starting cold\n", c.Name, age.Round(time.Second))
+		return
+	}
+	if err := c.Restore(snap.Data, snap.SavedAt); err != nil {
+		fmt.Printf("[data] Warm cache %s: restore failed: %v\n", c.Name, err)
+		return
+	}
+	warmMu.Lock()
+	status.RestoredAt = time.Now()
+	status.SavedAt = snap.SavedAt
+	status.Stale = true
+	warmMu.Unlock()
+	fmt.Printf("[data] Warm cache %s: restored snapshot from %s ago\n", c.Name, age.Round(time.Second))
+}
+
+// MarkWarm records that a cache has been refreshed from upstream, so it
+// no longer reports serving a stale snapshot.
+func MarkWarm(name string) {
+	warmMu.Lock()
+	if s, ok := warmStatus[name]; ok {
+		s.Stale = false
+	}
+	warmMu.Unlock()
+}
+
+// SaveWarm snapshots every registered cache. main calls it on shutdown.
+func SaveWarm() {
+	warmMu.Lock()
+	caches := make([]*WarmCache, 0, len(warmCaches))
+	for _, c := range warmCaches {
+		caches = append(caches, c)
+	}
+	warmMu.Unlock()
+
+	now := time.Now()
+	for _, c := range caches {
+		raw, err := json.Marshal(c.Snapshot())
+		if err != nil {
+			fmt.Printf("[data] Warm cache %s: snapshot failed: %v\n", c.Name, err)
+			continue
+		}
+		b, _ := json.Marshal(warmSnapshot{SavedAt: now, Data: raw})
+		if err := SaveFile(warmKey(c.Name), string(b)); err != nil {
+			fmt.Printf("[data] Warm cache %s: save failed: %v\n", c.Name, err)
+		}
+	}
+}
+
+// WarmCaches returns the status of every registered cache, by name.
+func WarmCaches() []WarmStatus {
+	warmMu.Lock()
+	defer warmMu.Unlock()
+	list := make([]WarmStatus, 0, len(warmStatus))
+	for _, s := range warmStatus {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
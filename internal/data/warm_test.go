@@ -0,0 +1,53 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWarmCacheSurvivesRestart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cache := map[string]int{"a": 1}
+	var restored map[string]int
+	var restoredFrom time.Time
+	register := func(maxAge time.Duration) {
+		restored = nil
+		RegisterWarm(WarmCache{
+			Name:     "test",
+			MaxAge:   maxAge,
+			Snapshot: func() any { return cache },
+			Restore: func(raw json.RawMessage, savedAt time.Time) error {
+				restoredFrom = savedAt
+				return json.Unmarshal(raw, &restored)
+			},
+		})
+	}
+
+	// Nothing saved yet: a cold start.
+	register(time.Hour)
+	if restored != nil {
+		t.Fatal("restored without a snapshot")
+	}
+
+	SaveWarm()
+	register(time.Hour)
+	if restored["a"] != 1 || time.Since(restoredFrom) > time.Minute {
+		t.Fatalf("restored %v from %v", restored, restoredFrom)
+	}
+	if s := WarmCaches(); len(s) != 1 || !s[0].Stale {
+		t.Fatalf("status after restore = %+v", s)
+	}
+	MarkWarm("test")
+	if s := WarmCaches(); s[0].Stale {
+		t.Fatal("still stale after a refresh")
+	}
+
+	// Too old to trust: dropped.
+	time.Sleep(2 * time.Millisecond)
+	register(time.Millisecond)
+	if restored != nil {
+		t.Fatal("restored an expired snapshot")
+	}
+}
@@ -0,0 +1,318 @@
+// Package egress records every outbound HTTP request the instance makes and
+// lets an admin switch individual hosts off. It works at the transport layer:
+// Install wraps http.DefaultTransport, so any client that doesn't bring its
+// own transport (the common case) is covered without changes, and packages
+// that do build their own transport wrap it with Wrap.
+//
+// The result is a live inventory of where data goes — feeds, price APIs,
+// map providers, LLMs — so self-hosters can see and control egress.
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/data"
+)
+
+// ErrDisabled is returned for requests to a host an admin has switched off.
+var ErrDisabled = errors.New("egress: host disabled by admin")
+
+// Host is the recorded state of one external host.
+type Host struct {
+	Host        string    `json:"host"`
+	Requests    int64     `json:"requests"`
+	Errors      int64     `json:"errors"`
+	Denied      int64     `json:"denied"` // requests refused by the kill switch
+	LastContact time.Time `json:"last_contact"`
+	LastStatus  int       `json:"last_status"`
+	Disabled    bool      `json:"disabled"`
+}
+
+// Purpose describes what a host is used for, from the known catalogue.
+// Unknown hosts (e.g. individual news feeds or fetched articles) return "".
+func (h Host) Purpose() string {
+	return Purpose(h.Host)
+}
+
+// known maps host suffixes to a human description of why Mu talks to them.
+var known = []struct{ suffix, purpose string }{
+	{"api.anthropic.com", "AI (Anthropic)"},
+	{"api.atlascloud.ai", "AI (Atlas Cloud)"},
+	{"api.openai.com", "AI (OpenAI-compatible)"},
+	{"api.search.brave.com", "Web search (Brave)"},
+	{"nominatim.openstreetmap.org", "Places geocoding"},
+	{"overpass-api.de", "Places search (Overpass)"},
+	{"places.googleapis.com", "Places search (Google)"},
+	{"basemaps.cartocdn.com", "Map tiles (CARTO)"},
+	{"weather.googleapis.com", "Weather (Google)"},
+	{"pollen.googleapis.com", "Pollen (Google)"},
+	{"youtube.googleapis.com", "Video (YouTube)"},
+	{"www.googleapis.com", "Google APIs (YouTube, OAuth)"},
+	{"oauth2.googleapis.com", "Google sign-in"},
+	{"openidconnect.googleapis.com", "Google sign-in"},
+	{"youtube.com", "Video (YouTube)"},
+	{"ytimg.com", "Video thumbnails (YouTube)"},
+	{"coingecko.com", "Market prices (CoinGecko)"},
+	{"finance.yahoo.com", "Market prices (Yahoo)"},
+	{"hacker-news.firebaseio.com", "News (Hacker News)"},
+	{"api.stripe.com", "Payments (Stripe)"},
+	{"x402.org", "Payments (x402 facilitator)"},
+	{"discord.com", "Discord bot"},
+	{"api.telegram.org", "Telegram bot"},
+	{"graph.facebook.com", "WhatsApp"},
+	{"api.aladhan.com", "Prayer times"},
+	{"reminder.dev", "Daily reminder"},
+}
+
+// Purpose returns the catalogued purpose of host, or "" if unknown.
+func Purpose(host string) string {
+	host = normalize(host)
+	for _, k := range known {
+		if host == k.suffix || strings.HasSuffix(host, "."+k.suffix) {
+			return k.purpose
+		}
+	}
+	return ""
+}
+
+var (
+	mu       sync.RWMutex
+	hosts    = map[string]*Host{}
+	disabled = map[string]bool{} // host or parent domain → switched off
+	dirty    bool
+
+	installOnce sync.Once
+
+	// defaultBase is the stock transport, captured before Install replaces
+	// http.DefaultTransport, so wrapped transports never record twice.
+	defaultBase = http.DefaultTransport
+
+	// baseOverride, if set, stands in for defaultBase (see SetBase).
+	baseMu       sync.RWMutex
+	baseOverride http.RoundTripper
+)
+
+// state is the persisted form of the package.
+type state struct {
+	Hosts    map[string]*Host   `json:"hosts"`
+	Disabled map[string]bool    `json:"disabled"`
+	Formats  map[string]*Format `json:"formats,omitempty"`
+}
+
+func init() {
+	var s state
+	if err := data.LoadJSON("egress.json", &s); err == nil {
+		if s.Hosts != nil {
+			hosts = s.Hosts
+		}
+		if s.Disabled != nil {
+			disabled = s.Disabled
+		}
+		if s.Formats != nil {
+			formats = s.Formats
+		}
+	}
+}
+
+// Install wraps http.DefaultTransport so every request made through a
+// default client is recorded and subject to the kill switches, and starts
+// the background saver. Safe to call more than once.
+func Install() {
+	installOnce.Do(func() {
+		http.DefaultTransport = Wrap(http.DefaultTransport)
+		go saver()
+	})
+}
+
+// Wrap returns a RoundTripper that records requests and enforces kill
+// switches before delegating to base. A nil base uses the default transport.
+func Wrap(base http.RoundTripper) http.RoundTripper {
+	if _, ok := base.(*transport); ok {
+		return base
+	}
+	if base == defaultBase {
+		base = nil
+	}
+	return &transport{base: base}
+}
+
+// SetBase replaces the default transport that recorded requests are sent
+// on, for the installed default and every Wrap(nil) alike. The end-to-end
+// tests use it to send outbound requests to fakes instead of the internet.
+// Transports wrapped around their own base are unaffected. A nil rt
+// restores the stock transport.
+func SetBase(rt http.RoundTripper) {
+	baseMu.Lock()
+	baseOverride = rt
+	baseMu.Unlock()
+}
+
+func currentBase() http.RoundTripper {
+	baseMu.RLock()
+	defer baseMu.RUnlock()
+	if baseOverride != nil {
+		return baseOverride
+	}
+	return defaultBase
+}
+
+type transport struct {
+	base http.RoundTripper // nil: the default transport
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := normalize(req.URL.Hostname())
+	if IsDisabled(host) {
+		record(host, 0, ErrDisabled)
+		return nil, fmt.Errorf("%w: %s", ErrDisabled, host)
+	}
+	base := t.base
+	if base == nil {
+		base = currentBase()
+	}
+	resp, err := base.RoundTrip(req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	record(host, status, err)
+	return resp, err
+}
+
+// normalize lower-cases a host and strips a leading "www." so the same
+// service isn't listed twice.
+func normalize(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return strings.TrimPrefix(host, "www.")
+}
+
+func record(host string, status int, err error) {
+	if host == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	h, ok := hosts[host]
+	if !ok {
+		h = &Host{Host: host}
+		hosts[host] = h
+	}
+	switch {
+	case errors.Is(err, ErrDisabled):
+		h.Denied++
+	case err != nil || status >= 500:
+		h.Requests++
+		h.Errors++
+		h.LastContact = time.Now()
+		h.LastStatus = status
+	default:
+		h.Requests++
+		h.LastContact = time.Now()
+		h.LastStatus = status
+	}
+	dirty = true
+}
+
+// IsDisabled reports whether requests to host are switched off, either
+// directly or via a parent domain (disabling "googleapis.com" covers
+// "places.googleapis.com").
+func IsDisabled(host string) bool {
+	host = normalize(host)
+	mu.RLock()
+	defer mu.RUnlock()
+	for h := host; h != ""; {
+		if disabled[h] {
+			return true
+		}
+		i := strings.Index(h, ".")
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	return false
+}
+
+// SetDisabled switches a host (and its subdomains) off or back on.
+func SetDisabled(host string, off bool) error {
+	host = normalize(host)
+	if host == "" || strings.ContainsAny(host, "/ :") {
+		return fmt.Errorf("invalid host %q", host)
+	}
+	mu.Lock()
+	if off {
+		disabled[host] = true
+	} else {
+		delete(disabled, host)
+	}
+	dirty = true
+	mu.Unlock()
+	return save()
+}
+
+// Hosts returns every recorded host, most requested first. Hosts that are
+// switched off but never contacted are included so they can be re-enabled.
+func Hosts() []Host {
+	mu.RLock()
+	out := make([]Host, 0, len(hosts)+len(disabled))
+	seen := map[string]bool{}
+	for name, h := range hosts {
+		c := *h
+		seen[name] = true
+		out = append(out, c)
+	}
+	for name := range disabled {
+		if !seen[name] {
+			out = append(out, Host{Host: name})
+		}
+	}
+	mu.RUnlock()
+
+	for i := range out {
+		out[i].Disabled = IsDisabled(out[i].Host)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+// Reset clears the recorded counters and formats but keeps the kill
+// switches.
+func Reset() error {
+	mu.Lock()
+	hosts = map[string]*Host{}
+	formats = map[string]*Format{}
+	dirty = true
+	mu.Unlock()
+	return save()
+}
+
+func save() error {
+	mu.Lock()
+	defer mu.Unlock()
+	dirty = false
+	return data.SaveJSON("egress.json", state{Hosts: hosts, Disabled: disabled, Formats: formats})
+}
+
+// saver persists counters every 30 seconds when they've changed.
+func saver() {
+	for {
+		time.Sleep(30 * time.Second)
+		mu.RLock()
+		d := dirty
+		mu.RUnlock()
+		if d {
+			save()
+		}
+	}
+}
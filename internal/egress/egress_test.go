@@ -0,0 +1,167 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func reset(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	savedHosts, savedDisabled, savedFormats := hosts, disabled, formats
+	hosts, disabled, formats = map[string]*Host{}, map[string]bool{}, map[string]*Format{}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		hosts, disabled, formats = savedHosts, savedDisabled, savedFormats
+		mu.Unlock()
+	})
+}
+
+func TestTransport_RecordsRequests(t *testing.T) {
+	reset(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Wrap(nil)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	u, _ := url.Parse(srv.URL)
+	list := Hosts()
+	if len(list) != 1 || list[0].Host != u.Hostname() {
+		t.Fatalf("Hosts() = %+v", list)
+	}
+	if list[0].Requests != 2 || list[0].LastStatus != http.StatusTeapot || list[0].LastContact.IsZero() {
+		t.Errorf("unexpected record: %+v", list[0])
+	}
+}
+
+func TestTransport_KillSwitch(t *testing.T) {
+	reset(t)
+	mu.Lock()
+	disabled["example.org"] = true
+	mu.Unlock()
+
+	client := &http.Client{Transport: Wrap(nil)}
+	_, err := client.Get("http://api.example.org/x")
+	if !errors.Is(err, ErrDisabled) {
+		t.Fatalf("err = %v, want ErrDisabled", err)
+	}
+
+	list := Hosts()
+	var found *Host
+	for i := range list {
+		if list[i].Host == "api.example.org" {
+			found = &list[i]
+		}
+	}
+	if found == nil || found.Denied != 1 || found.Requests != 0 || !found.Disabled {
+		t.Errorf("unexpected record: %+v", found)
+	}
+}
+
+func TestIsDisabled_ParentDomain(t *testing.T) {
+	reset(t)
+	mu.Lock()
+	disabled["googleapis.com"] = true
+	mu.Unlock()
+
+	cases := map[string]bool{
+		"places.googleapis.com": true,
+		"googleapis.com":        true,
+		"WWW.googleapis.com":    true,
+		"notgoogleapis.com":     false,
+		"example.com":           false,
+	}
+	for host, want := range cases {
+		if got := IsDisabled(host); got != want {
+			t.Errorf("IsDisabled(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestWrap_Idempotent(t *testing.T) {
+	rt := Wrap(nil)
+	if Wrap(rt) != rt {
+		t.Error("wrapping twice should return the same transport")
+	}
+}
+
+func TestSetBase(t *testing.T) {
+	reset(t)
+	t.Cleanup(func() { SetBase(nil) })
+	var got []string
+	SetBase(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = append(got, req.URL.Host)
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: req}, nil
+	}))
+
+	resp, err := (&http.Client{Transport: Wrap(nil)}).Get("http://feeds.example.org/rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || len(got) != 1 || got[0] != "feeds.example.org" {
+		t.Fatalf("status %d, replacement saw %v", resp.StatusCode, got)
+	}
+	if list := Hosts(); len(list) != 1 || list[0].Requests != 1 {
+		t.Errorf("request through the replacement not recorded: %+v", list)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestPurpose(t *testing.T) {
+	if got := Purpose("nominatim.openstreetmap.org"); got == "" {
+		t.Error("expected a purpose for nominatim")
+	}
+	if got := Purpose("feeds.example.net"); got != "" {
+		t.Errorf("unexpected purpose %q for unknown host", got)
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	reset(t)
+	if err := CheckFormat("coinbase", "v2", nil); err != nil {
+		t.Fatal(err)
+	}
+	drift := Drift("nominatim", "lat %q is not a number", "north")
+	if err := CheckFormat("nominatim", "v1", drift); err != drift {
+		t.Fatalf("CheckFormat returned %v, want the error it was given", err)
+	}
+	if !IsDrift(fmt.Errorf("places: %w", drift)) {
+		t.Error("IsDrift should see through wrapping")
+	}
+	CheckFormat("yahoo", "v8", errors.New("no data for symbol"))
+
+	list := Formats()
+	if len(list) != 2 || list[0].Source != "nominatim" || !list[0].Drifting() {
+		t.Fatalf("Formats() = %+v, want the drifting source first", list)
+	}
+	if list[0].LastError != drift.Error() || list[0].Version != "" {
+		t.Errorf("drifting source recorded as %+v", list[0])
+	}
+	if list[1].Drifting() || list[1].Parsed != 1 || list[1].Version != "v2" {
+		t.Errorf("parsing source recorded as %+v", list[1])
+	}
+
+	// One good response clears the drift.
+	CheckFormat("nominatim", "v1", nil)
+	if f := Formats()[1]; f.Source != "nominatim" || f.Drifting() || f.Drifted != 1 {
+		t.Errorf("after recovering: %+v", f)
+	}
+}
@@ -0,0 +1,97 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Format is the recorded state of one upstream response format: whether
+// the last response from the source parsed, and if not, why. Parsers check
+// the shape of what they're given, so when an API changes underneath them
+// the admin sees which one and how, rather than an empty card.
+type Format struct {
+	Source    string    `json:"source"`
+	Version   string    `json:"version,omitempty"` // the format last seen
+	Parsed    int64     `json:"parsed"`
+	Drifted   int64     `json:"drifted"`
+	LastOK    time.Time `json:"last_ok,omitempty"`
+	LastDrift time.Time `json:"last_drift,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Drifting reports whether the latest response from the source failed to
+// parse.
+func (f Format) Drifting() bool {
+	return f.LastDrift.After(f.LastOK)
+}
+
+// FormatError is returned by a parser when a response doesn't have the
+// shape it expects.
+type FormatError struct {
+	Source string
+	Detail string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%s: unexpected response format: %s", e.Source, e.Detail)
+}
+
+// Drift returns a FormatError for source.
+func Drift(source, format string, args ...any) error {
+	return &FormatError{Source: source, Detail: fmt.Sprintf(format, args...)}
+}
+
+// IsDrift reports whether err is, or wraps, a FormatError.
+func IsDrift(err error) bool {
+	var fe *FormatError
+	return errors.As(err, &fe)
+}
+
+var formats = map[string]*Format{}
+
+// CheckFormat records the outcome of parsing a response from source in
+// the given format version, and returns err. Only FormatErrors count as
+// drift: other errors, such as an upstream answering in the expected
+// format that it has nothing for a query, are returned unrecorded.
+func CheckFormat(source, version string, err error) error {
+	if err != nil && !IsDrift(err) {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	f, ok := formats[source]
+	if !ok {
+		f = &Format{Source: source}
+		formats[source] = f
+	}
+	if err != nil {
+		f.Drifted++
+		f.LastDrift = time.Now()
+		f.LastError = err.Error()
+	} else {
+		f.Parsed++
+		f.LastOK = time.Now()
+		f.Version = version
+	}
+	dirty = true
+	return err
+}
+
+// Formats returns every source checked so far, drifting ones first.
+func Formats() []Format {
+	mu.RLock()
+	out := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		out = append(out, *f)
+	}
+	mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Drifting() != out[j].Drifting() {
+			return out[i].Drifting()
+		}
+		return out[i].Source < out[j].Source
+	})
+	return out
+}
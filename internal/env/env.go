@@ -1,8 +1,9 @@
-// Package env loads a dotenv file into the process environment at startup so
-// that both `mu --serve` and one-off CLI commands (e.g. `mu x402`) see the same
-// configuration — without relying on the shell, or on systemd's EnvironmentFile
-// being applied. Values already present in the environment always win; the file
-// only fills in what is unset.
+// Package env loads the config file, mu.toml, and a dotenv file into the
+// process environment at startup so that both `mu --serve` and one-off CLI
+// commands (e.g. `mu x402`) see the same configuration — without relying on
+// the shell, or on systemd's EnvironmentFile being applied. Values already
+// present in the environment always win; the files only fill in what is
+// unset, mu.toml before .env.
 //
 // It is imported for its side effect by packages that read configuration at
 // init time (e.g. wallet), guaranteeing the file is loaded before those reads.
@@ -15,7 +16,10 @@ import (
 	"strings"
 )
 
-func init() { Load("") }
+func init() {
+	LoadConfig("")
+	Load("")
+}
 
 // Load reads KEY=VALUE lines from path and sets any key not already present in
 // the environment. When path is empty it tries $MU_ENV_FILE, then ~/.env, then
@@ -63,9 +67,7 @@ func loadFile(path string) bool {
 		if key == "" {
 			continue
 		}
-		if _, ok := os.LookupEnv(key); !ok {
-			_ = os.Setenv(key, val)
-		}
+		setFromFile(path, key, val)
 	}
 	return true
 }
@@ -3,6 +3,7 @@ package env
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +35,85 @@ func TestLoadSetsUnsetKeysOnly(t *testing.T) {
 func TestLoadMissingFileIsNoop(t *testing.T) {
 	Load(filepath.Join(t.TempDir(), "does-not-exist"))
 }
+
+func TestParseConfig(t *testing.T) {
+	src := `# top
+mu_domain = "example.com"
+
+[mail]
+domain = "mail.example.com" # MAIL_DOMAIN
+port = 2_525
+[anthropic]
+api_key = 'sk-#notacomment'
+[session]
+max = 5
+[x402]
+networks = ["base", "base-sepolia"]
+`
+	vars, err := ParseConfig(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"MU_DOMAIN":         "example.com",
+		"MAIL_DOMAIN":       "mail.example.com",
+		"MAIL_PORT":         "2525",
+		"ANTHROPIC_API_KEY": "sk-#notacomment",
+		"SESSION_MAX":       "5",
+		"X402_NETWORKS":     "base,base-sepolia",
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("%s = %q, want %q", k, vars[k], v)
+		}
+	}
+	if len(vars) != len(want) {
+		t.Errorf("got %d vars, want %d: %v", len(vars), len(want), vars)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		"[mail\n":                        "1: expected a table name",
+		"a = 1\nb\n":                     "2: expected key = value",
+		"[mail]\ndomain = example.com\n": "2: domain: example.com isn't a string",
+		"a = \"open\n":                   "1: a: unterminated",
+		"a = 1\nA = 2\n":                 "2: A is set twice",
+	} {
+		_, err := ParseConfig(src)
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("ParseConfig(%q) = %v, want %q...", src, err, want)
+		}
+	}
+}
+
+func TestLoadConfigRecordsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mu.toml")
+	if err := os.WriteFile(path, []byte("[mu_test]\nfrom_file = \"yes\"\nset_in_env = \"file\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("MU_TEST_FROM_FILE")
+	t.Cleanup(func() { os.Unsetenv("MU_TEST_FROM_FILE") })
+	t.Setenv("MU_TEST_SET_IN_ENV", "env")
+
+	if err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("MU_TEST_FROM_FILE"); got != "yes" {
+		t.Errorf("MU_TEST_FROM_FILE = %q, want yes", got)
+	}
+	if got := File("MU_TEST_FROM_FILE"); got != path {
+		t.Errorf("File = %q, want %q", got, path)
+	}
+	if got := os.Getenv("MU_TEST_SET_IN_ENV"); got != "env" {
+		t.Errorf("MU_TEST_SET_IN_ENV = %q, want env (env must win over file)", got)
+	}
+	if got := File("MU_TEST_SET_IN_ENV"); got != "" {
+		t.Errorf("File for an env value = %q, want empty", got)
+	}
+
+	os.Setenv("MU_TEST_FROM_FILE", "changed")
+	if got := File("MU_TEST_FROM_FILE"); got != "" {
+		t.Errorf("File after the value changed = %q, want empty", got)
+	}
+}
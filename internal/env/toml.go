@@ -0,0 +1,241 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The config file, mu.toml, is the tidier alternative to a long .env:
+// settings grouped under a table per module, each key the environment
+// variable's name without the prefix.
+//
+//	[mail]
+//	domain = "example.com"   # MAIL_DOMAIN
+//	port = 25                # MAIL_PORT
+//
+//	[anthropic]
+//	api_key = "sk-..."       # ANTHROPIC_API_KEY
+//
+// Keys outside a table are used as they are, upper-cased. Arrays become
+// comma separated lists. Like .env, it only fills in what the environment
+// leaves unset. Only this much of TOML is understood: tables, strings,
+// numbers, booleans and single-line arrays.
+
+var (
+	// configPath is the config file read, if any.
+	configPath string
+	// configErr is the first problem found in it.
+	configErr error
+	// fromFile records the keys set from a file, and the value set, so
+	// a value changed since can be told apart.
+	fromFile = map[string]fileValue{}
+)
+
+type fileValue struct {
+	path  string
+	value string
+}
+
+// ConfigPath returns the config file that was read, or "".
+func ConfigPath() string { return configPath }
+
+// ConfigError returns what was wrong with the config file, with its line
+// number, or nil.
+func ConfigError() error { return configErr }
+
+// File returns the file a variable was set from, .env or mu.toml, or ""
+// if it came from the environment.
+func File(key string) string {
+	fv, ok := fromFile[key]
+	if !ok || os.Getenv(key) != fv.value {
+		return ""
+	}
+	return fv.path
+}
+
+// setFromFile sets key unless the environment already has it.
+func setFromFile(path, key, val string) {
+	if _, ok := os.LookupEnv(key); ok {
+		return
+	}
+	_ = os.Setenv(key, val)
+	fromFile[key] = fileValue{path: path, value: val}
+}
+
+// LoadConfig reads the config file at path, or when path is empty
+// $MU_CONFIG, then ~/.mu/mu.toml. A missing file is not an error.
+func LoadConfig(path string) error {
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv("MU_CONFIG"))
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".mu", "mu.toml")
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		configErr = err
+		return err
+	}
+	configPath = path
+	vars, err := ParseConfig(string(b))
+	if err != nil {
+		configErr = fmt.Errorf("%s:%w", path, err)
+		return configErr
+	}
+	for key, val := range vars {
+		setFromFile(path, key, val)
+	}
+	return nil
+}
+
+// ParseConfig reads a config file into environment variables. Errors
+// start with the line number.
+func ParseConfig(src string) (map[string]string, error) {
+	vars := map[string]string{}
+	table := ""
+	for n, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("%d: expected a table name like [mail]", n+1)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if !validKey(table) {
+				return nil, fmt.Errorf("%d: table name %q should be letters, digits and underscores", n+1, table)
+			}
+			continue
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: expected key = value", n+1)
+		}
+		key = strings.TrimSpace(key)
+		if !validKey(key) {
+			return nil, fmt.Errorf("%d: key %q should be letters, digits and underscores", n+1, key)
+		}
+		val, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %v", n+1, key, err)
+		}
+		if table != "" {
+			key = table + "_" + key
+		}
+		key = strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if _, dup := vars[key]; dup {
+			return nil, fmt.Errorf("%d: %s is set twice", n+1, key)
+		}
+		vars[key] = val
+	}
+	return vars, nil
+}
+
+// stripComment drops a # comment that isn't inside a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // the escaped character can't end the string
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func validKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, c := range k {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseValue reads a value as the string an environment variable would
+// hold.
+func parseValue(raw string) (string, error) {
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(raw, `"""`) || strings.HasPrefix(raw, "'''"):
+		return "", fmt.Errorf("multi-line strings aren't supported")
+	case raw[0] == '"':
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("unterminated or badly escaped string")
+		}
+		return s, nil
+	case raw[0] == '\'':
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw[0] == '[':
+		if !strings.HasSuffix(raw, "]") {
+			return "", fmt.Errorf("arrays must be on one line")
+		}
+		var items []string
+		for _, item := range splitArray(raw[1 : len(raw)-1]) {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			v, err := parseValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, v)
+		}
+		return strings.Join(items, ","), nil
+	case raw == "true" || raw == "false":
+		return raw, nil
+	}
+	n := strings.ReplaceAll(raw, "_", "")
+	if _, err := strconv.ParseFloat(n, 64); err == nil {
+		return n, nil
+	}
+	return "", fmt.Errorf("%s isn't a string, number or boolean; put strings in quotes", raw)
+}
+
+// splitArray splits array items on commas outside strings.
+func splitArray(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
@@ -0,0 +1,132 @@
+package flag
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"mu/internal/data"
+)
+
+// Every moderation decision is written to an audit log: flags from
+// members, content hidden by the classifier or an admin, and each review
+// of a queued item. The log is append-only (one JSON line per entry in
+// moderation_audit.jsonl) and nothing here rewrites or trims it, so it is
+// a record of who decided what, and when.
+
+const auditKey = "moderation_audit.jsonl"
+
+// Audit actions.
+const (
+	ActionFlag    = "flag"    // a member flagged the content
+	ActionHide    = "hide"    // the classifier or an admin hid it
+	ActionApprove = "approve" // a reviewer cleared it and unhid it
+	ActionReject  = "reject"  // a reviewer removed it
+	ActionBan     = "ban"     // a reviewer removed it and banned the author
+)
+
+// AuditEntry is one moderation decision.
+type AuditEntry struct {
+	Time        time.Time `json:"time"`
+	Reviewer    string    `json:"reviewer"` // who acted: an account ID, a flagger's name or "system:<reason>"
+	Action      string    `json:"action"`
+	ContentType string    `json:"content_type"`
+	ContentID   string    `json:"content_id"`
+	Author      string    `json:"author,omitempty"`
+	Note        string    `json:"note,omitempty"`
+}
+
+// auditMutex keeps concurrent appends from interleaving.
+var auditMutex sync.Mutex
+
+// record appends an entry to the audit log.
+func record(e AuditEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	if err := data.AppendFile(auditKey, string(b)+"\n"); err != nil {
+		fmt.Printf("Moderation audit write error: %v\n", err)
+	}
+}
+
+// AuditLog returns up to limit entries, newest first; 0 for all of them.
+func AuditLog(limit int) []AuditEntry {
+	b, err := data.LoadFile(auditKey)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	var entries []AuditEntry
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	out := make([]AuditEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, entries[i])
+	}
+	return out
+}
+
+// Author returns the account ID of whoever wrote an item, as its
+// registered deleter knows it, or "".
+func Author(contentType, contentID string) string {
+	d, ok := GetDeleter(contentType)
+	if !ok {
+		return ""
+	}
+	if c, ok := d.Get(contentID).(PostContent); ok {
+		return c.AuthorID
+	}
+	return ""
+}
+
+// Review records a reviewer's decision on a queued item and carries it
+// out: ActionApprove clears the flags and unhides the content,
+// ActionReject and ActionBan remove it. Either way the item's open
+// reports are closed. Banning the author is left to the caller, which
+// knows about accounts.
+func Review(contentType, contentID, reviewer, action, note string) error {
+	author := Author(contentType, contentID)
+	var err error
+	switch action {
+	case ActionApprove:
+		err = Approve(contentType, contentID)
+	case ActionReject, ActionBan:
+		err = Delete(contentType, contentID)
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+	if err != nil {
+		return err
+	}
+	status := ReportRemoved
+	if action == ActionApprove {
+		status = ReportDismissed
+	}
+	resolveReports(contentType, contentID, status)
+	record(AuditEntry{
+		Reviewer:    reviewer,
+		Action:      action,
+		ContentType: contentType,
+		ContentID:   contentID,
+		Author:      author,
+		Note:        note,
+	})
+	return nil
+}
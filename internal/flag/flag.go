@@ -43,8 +43,9 @@ var (
 	analyzer LLMAnalyzer
 )
 
-// Load reads persisted flags from disk.
+// Load reads persisted flags and reports from disk.
 func Load() {
+	loadReports()
 	b, err := data.LoadFile("flags.json")
 	if err != nil {
 		return
@@ -113,10 +114,14 @@ Respond with just the single word.`
 
 // Add adds a flag to content (returns new flag count, already flagged bool, error).
 func Add(contentType, contentID, username string) (int, bool, error) {
+	return addFlag(contentType, contentID, username, "")
+}
+
+// addFlag is Add with a note for the audit log.
+func addFlag(contentType, contentID, username, note string) (int, bool, error) {
 	key := contentType + ":" + contentID
 
 	mutex.Lock()
-	defer mutex.Unlock()
 
 	item, exists := flags[key]
 	if !exists {
@@ -133,7 +138,9 @@ func Add(contentType, contentID, username string) (int, bool, error) {
 
 	for _, flagger := range item.FlaggedBy {
 		if flagger == username {
-			return item.FlagCount, true, nil
+			count := item.FlagCount
+			mutex.Unlock()
+			return count, true, nil
 		}
 	}
 
@@ -145,7 +152,18 @@ func Add(contentType, contentID, username string) (int, bool, error) {
 	}
 
 	saveUnlocked()
-	return item.FlagCount, false, nil
+	count := item.FlagCount
+	mutex.Unlock()
+
+	record(AuditEntry{
+		Reviewer:    username,
+		Action:      ActionFlag,
+		ContentType: contentType,
+		ContentID:   contentID,
+		Author:      Author(contentType, contentID),
+		Note:        note,
+	})
+	return count, false, nil
 }
 
 // GetCount returns flag count for content.
@@ -244,6 +262,14 @@ func AdminFlag(contentType, contentID, username string) error {
 		return err
 	}
 
+	record(AuditEntry{
+		Reviewer:    username,
+		Action:      ActionHide,
+		ContentType: contentType,
+		ContentID:   contentID,
+		Author:      Author(contentType, contentID),
+	})
+
 	if deleter, ok := deleters[contentType]; ok {
 		go deleter.RefreshCache()
 	}
@@ -291,4 +317,5 @@ type PostContent struct {
 	Author    string
 	AuthorID  string
 	CreatedAt time.Time
+	URL       string // where the item is seen, for the review queue
 }
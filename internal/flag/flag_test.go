@@ -1,7 +1,9 @@
 package flag
 
 import (
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func resetFlags(t *testing.T) {
@@ -13,6 +15,10 @@ func resetFlags(t *testing.T) {
 	deleters = make(map[string]ContentDeleter)
 	analyzer = nil
 	mutex.Unlock()
+
+	reportMutex.Lock()
+	reports = nil
+	reportMutex.Unlock()
 }
 
 func TestAdd_FirstFlag(t *testing.T) {
@@ -300,7 +306,7 @@ func TestCheckContent_NilAnalyzer(t *testing.T) {
 type recordingDeleter struct {
 	deleted      []string
 	deleteErr    error
-	refreshCount int
+	refreshCount atomic.Int32 // AdminFlag and Delete refresh in the background
 	content      map[string]interface{}
 }
 
@@ -317,7 +323,18 @@ func (d *recordingDeleter) Get(id string) interface{} {
 }
 
 func (d *recordingDeleter) RefreshCache() {
-	d.refreshCount++
+	d.refreshCount.Add(1)
+}
+
+// waitRefreshes waits for the deleter's cache to have been refreshed n
+// times.
+func (d *recordingDeleter) waitRefreshes(t *testing.T, n int32) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); d.refreshCount.Load() < n; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("cache refreshed %d times, want %d", d.refreshCount.Load(), n)
+		}
+	}
 }
 
 func TestApprove_RefreshesRegisteredDeleterCache(t *testing.T) {
@@ -330,7 +347,7 @@ func TestApprove_RefreshesRegisteredDeleterCache(t *testing.T) {
 		t.Fatalf("unexpected approve error: %v", err)
 	}
 
-	if got, want := deleter.refreshCount, 1; got != want {
+	if got, want := deleter.refreshCount.Load(), int32(1); got != want {
 		t.Fatalf("expected refresh count %d, got %d", want, got)
 	}
 	if _, ok := GetDeleter("post"); !ok {
@@ -351,6 +368,7 @@ func TestDelete_CallsRegisteredDeleter(t *testing.T) {
 	if got, want := len(deleter.deleted), 1; got != want {
 		t.Fatalf("expected %d deleted item, got %d", want, got)
 	}
+	deleter.waitRefreshes(t, 1)
 	if got, want := deleter.deleted[0], "delete-with-deleter"; got != want {
 		t.Fatalf("expected deleted id %q, got %q", want, got)
 	}
@@ -358,3 +376,104 @@ func TestDelete_CallsRegisteredDeleter(t *testing.T) {
 		t.Fatalf("expected flag state to be removed, got %#v", item)
 	}
 }
+
+func TestReview_RecordsAuditTrail(t *testing.T) {
+	resetFlags(t)
+	deleter := &recordingDeleter{content: map[string]interface{}{
+		"1": PostContent{ID: "1", AuthorID: "mallory"},
+		"2": PostContent{ID: "2", AuthorID: "mallory"},
+	}}
+	RegisterDeleter("post", deleter)
+
+	Add("post", "1", "alice")
+	AdminFlag("post", "2", "system:spam")
+	if err := Review("post", "1", "admin", ActionApprove, "fine"); err != nil {
+		t.Fatal(err)
+	}
+	if IsHidden("post", "1") || GetItem("post", "1") != nil {
+		t.Error("approved item still queued")
+	}
+	if err := Review("post", "2", "admin", ActionReject, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleter.deleted) != 1 || deleter.deleted[0] != "2" {
+		t.Errorf("deleted %v, want [2]", deleter.deleted)
+	}
+	deleter.waitRefreshes(t, 3) // approved, hidden, rejected
+	if err := Review("post", "3", "admin", "shrug", ""); err == nil {
+		t.Error("accepted an unknown action")
+	}
+
+	log := AuditLog(0)
+	want := []struct{ reviewer, action, id string }{
+		{"admin", ActionReject, "2"},
+		{"admin", ActionApprove, "1"},
+		{"system:spam", ActionHide, "2"},
+		{"alice", ActionFlag, "1"},
+	}
+	if len(log) != len(want) {
+		t.Fatalf("audit log = %+v", log)
+	}
+	for i, w := range want {
+		e := log[i]
+		if e.Reviewer != w.reviewer || e.Action != w.action || e.ContentID != w.id || e.Author != "mallory" {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+	if log[1].Note != "fine" {
+		t.Errorf("note = %q", log[1].Note)
+	}
+	if n := len(AuditLog(2)); n != 2 {
+		t.Errorf("AuditLog(2) returned %d entries", n)
+	}
+}
+
+func TestFile_ReportsAndResolution(t *testing.T) {
+	resetFlags(t)
+	resolved := make(chan Report, 3)
+	OnReportResolved = func(r Report) { resolved <- r }
+	t.Cleanup(func() { OnReportResolved = nil })
+
+	if _, _, err := File("post", "1", "alice", "boring"); err == nil {
+		t.Error("accepted an unknown category")
+	}
+	File("post", "1", "alice", CategorySpam)
+	if _, already, _ := File("post", "1", "alice", CategoryAbuse); !already {
+		t.Error("a second report from the same member counted")
+	}
+	File("post", "1", "bob", CategorySpam)
+	count, _, _ := File("post", "1", "carol", CategoryMisinformation)
+	if count != 3 || !IsHidden("post", "1") {
+		t.Errorf("count = %d, hidden = %v after three reports", count, IsHidden("post", "1"))
+	}
+	if got := FormatCounts(ReportCounts("post", "1")); got != "2 spam, 1 misinformation" {
+		t.Errorf("counts = %q", got)
+	}
+	if mine := ReportsBy("alice"); len(mine) != 1 || mine[0].Status != ReportOpen || mine[0].Category != CategorySpam {
+		t.Fatalf("alice's reports = %+v", mine)
+	}
+
+	if err := Review("post", "1", "admin", ActionReject, ""); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if r := <-resolved; r.Status != ReportRemoved {
+			t.Errorf("resolved %+v", r)
+		}
+	}
+	if mine := ReportsBy("alice"); mine[0].Status != ReportRemoved || mine[0].Resolved.IsZero() {
+		t.Errorf("alice's report after review = %+v", mine[0])
+	}
+	if len(ReportCounts("post", "1")) != 0 {
+		t.Error("resolved reports still counted")
+	}
+
+	RenameReporter("alice", "alicia")
+	if len(ReportsBy("alicia")) != 1 || len(ReportsBy("alice")) != 0 {
+		t.Error("rename didn't move the report")
+	}
+	DeleteReports("alicia")
+	if len(ReportsBy("alicia")) != 0 {
+		t.Error("reports kept after deletion")
+	}
+}
@@ -0,0 +1,210 @@
+package flag
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/data"
+)
+
+// A report is a member's flag with a reason attached. Reports on the same
+// item count towards one flagged item in the review queue, and each
+// member can report an item once. When a reviewer decides on the item its
+// open reports are closed with the outcome, and OnReportResolved lets the
+// reporters know.
+
+// Report categories.
+const (
+	CategorySpam           = "spam"
+	CategoryAbuse          = "abuse"
+	CategoryMisinformation = "misinformation"
+)
+
+// Categories lists the report categories in the order they're offered.
+var Categories = []string{CategorySpam, CategoryAbuse, CategoryMisinformation}
+
+// Report statuses.
+const (
+	ReportOpen      = "open"      // waiting for review
+	ReportDismissed = "dismissed" // reviewed, the content stays up
+	ReportRemoved   = "removed"   // reviewed, the content was taken down
+)
+
+// Report is one member's report of an item.
+type Report struct {
+	ID          string    `json:"id"`
+	Reporter    string    `json:"reporter"` // account ID
+	ContentType string    `json:"content_type"`
+	ContentID   string    `json:"content_id"`
+	Category    string    `json:"category"`
+	Status      string    `json:"status"`
+	Created     time.Time `json:"created"`
+	Resolved    time.Time `json:"resolved,omitempty"`
+}
+
+// OnReportResolved is called for each report closed by a review. main.go
+// wires it to tell the reporter.
+var OnReportResolved func(Report)
+
+var (
+	reportMutex sync.RWMutex
+	reports     []*Report
+)
+
+// loadReports reads persisted reports from disk. Called from Load.
+func loadReports() {
+	b, err := data.LoadFile("reports.json")
+	if err != nil {
+		return
+	}
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	json.Unmarshal(b, &reports)
+}
+
+// saveReports persists the reports. The caller holds reportMutex.
+func saveReports() error {
+	return data.SaveJSON("reports.json", reports)
+}
+
+// validCategory reports whether c is a report category.
+func validCategory(c string) bool {
+	for _, k := range Categories {
+		if k == c {
+			return true
+		}
+	}
+	return false
+}
+
+// File records reporterID's report of an item and flags it. It returns
+// the item's flag count, and whether reporterID had already reported it.
+func File(contentType, contentID, reporterID, category string) (int, bool, error) {
+	if !validCategory(category) {
+		return 0, false, fmt.Errorf("unknown category %q", category)
+	}
+	count, already, err := addFlag(contentType, contentID, reporterID, category)
+	if err != nil || already {
+		return count, already, err
+	}
+
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	now := time.Now()
+	reports = append(reports, &Report{
+		ID:          fmt.Sprintf("%d", now.UnixNano()),
+		Reporter:    reporterID,
+		ContentType: contentType,
+		ContentID:   contentID,
+		Category:    category,
+		Status:      ReportOpen,
+		Created:     now,
+	})
+	return count, false, saveReports()
+}
+
+// ReportsBy returns reporterID's reports, newest first.
+func ReportsBy(reporterID string) []Report {
+	reportMutex.RLock()
+	defer reportMutex.RUnlock()
+	var out []Report
+	for i := len(reports) - 1; i >= 0; i-- {
+		if reports[i].Reporter == reporterID {
+			out = append(out, *reports[i])
+		}
+	}
+	return out
+}
+
+// ReportCounts returns how many open reports an item has in each category.
+func ReportCounts(contentType, contentID string) map[string]int {
+	reportMutex.RLock()
+	defer reportMutex.RUnlock()
+	counts := map[string]int{}
+	for _, r := range reports {
+		if r.ContentType == contentType && r.ContentID == contentID && r.Status == ReportOpen {
+			counts[r.Category]++
+		}
+	}
+	return counts
+}
+
+// FormatCounts renders report counts as "2 spam, 1 abuse", most first.
+func FormatCounts(counts map[string]int) string {
+	cats := make([]string, 0, len(counts))
+	for c := range counts {
+		cats = append(cats, c)
+	}
+	sort.Slice(cats, func(i, j int) bool {
+		if counts[cats[i]] != counts[cats[j]] {
+			return counts[cats[i]] > counts[cats[j]]
+		}
+		return cats[i] < cats[j]
+	})
+	parts := make([]string, len(cats))
+	for i, c := range cats {
+		parts[i] = fmt.Sprintf("%d %s", counts[c], c)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resolveReports closes the open reports on an item with status and
+// tells their reporters.
+func resolveReports(contentType, contentID, status string) {
+	reportMutex.Lock()
+	var resolved []Report
+	now := time.Now()
+	for _, r := range reports {
+		if r.ContentType == contentType && r.ContentID == contentID && r.Status == ReportOpen {
+			r.Status = status
+			r.Resolved = now
+			resolved = append(resolved, *r)
+		}
+	}
+	if len(resolved) > 0 {
+		saveReports()
+	}
+	reportMutex.Unlock()
+
+	if OnReportResolved != nil {
+		for _, r := range resolved {
+			go OnReportResolved(r)
+		}
+	}
+}
+
+// DeleteReports removes a member's reports. Used on account deletion.
+func DeleteReports(reporterID string) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	kept := reports[:0:0]
+	for _, r := range reports {
+		if r.Reporter != reporterID {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) != len(reports) {
+		reports = kept
+		saveReports()
+	}
+}
+
+// RenameReporter moves a renamed member's reports to their new ID.
+func RenameReporter(oldID, newID string) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	changed := false
+	for _, r := range reports {
+		if r.Reporter == oldID {
+			r.Reporter = newID
+			changed = true
+		}
+	}
+	if changed {
+		saveReports()
+	}
+}
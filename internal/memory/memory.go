@@ -174,3 +174,14 @@ func Clear(userID string) {
 	delete(store, userID)
 	save()
 }
+
+// Rename moves a user's memory to their new ID (username change).
+func Rename(oldID, newID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if entries, ok := store[oldID]; ok {
+		delete(store, oldID)
+		store[newID] = entries
+		save()
+	}
+}
@@ -0,0 +1,415 @@
+// Package netx is the shared outbound HTTP client. New returns a standard
+// *http.Client whose transport applies a Policy to every request:
+//
+//   - a per-host circuit breaker, so a dead upstream fails fast instead of
+//     tying up goroutines for the full timeout on every call
+//   - retries with exponential backoff for idempotent requests that fail
+//     with a network error, 429 or 5xx
+//   - an in-memory response cache for successful, uncredentialed GETs
+//   - a per-host request budget (requests per minute)
+//   - per-host metrics, shown alongside egress on /admin/egress
+//
+// Requests still go through the egress transport, so they are recorded and
+// subject to the admin kill switches.
+package netx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/egress"
+)
+
+var (
+	// ErrCircuitOpen is returned while a host's breaker is open.
+	ErrCircuitOpen = errors.New("netx: circuit open")
+	// ErrBudgetExceeded is returned when a client has used its per-host
+	// request budget for the current minute.
+	ErrBudgetExceeded = errors.New("netx: request budget exceeded")
+)
+
+// Policy controls how a client talks to upstream hosts. Zero values fall
+// back to DefaultPolicy where noted.
+type Policy struct {
+	Timeout          time.Duration // overall per-call timeout, including retries
+	Retries          int           // extra attempts for idempotent requests
+	Backoff          time.Duration // first retry delay, doubled each attempt (default 500ms)
+	CacheTTL         time.Duration // cache successful GETs this long (0 = off)
+	Budget           int           // max requests per host per minute (0 = unlimited)
+	BreakerThreshold int           // consecutive failures that open the breaker (default 5)
+	BreakerCooldown  time.Duration // how long the breaker stays open (default 30s)
+}
+
+// DefaultPolicy suits small JSON APIs.
+var DefaultPolicy = Policy{
+	Timeout:          15 * time.Second,
+	Retries:          2,
+	Backoff:          500 * time.Millisecond,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// maxCacheBody caps the size of a cacheable response body; maxCacheEntries
+// caps the cache as a whole.
+const (
+	maxCacheBody    = 2 << 20
+	maxCacheEntries = 500
+)
+
+// New returns an HTTP client for the named caller (used in metrics) that
+// applies p to every request.
+func New(name string, p Policy) *http.Client {
+	if p.Backoff == 0 {
+		p.Backoff = DefaultPolicy.Backoff
+	}
+	if p.BreakerThreshold == 0 {
+		p.BreakerThreshold = DefaultPolicy.BreakerThreshold
+	}
+	if p.BreakerCooldown == 0 {
+		p.BreakerCooldown = DefaultPolicy.BreakerCooldown
+	}
+	return &http.Client{
+		Timeout:   p.Timeout,
+		Transport: &transport{name: name, policy: p, base: egress.Wrap(nil)},
+	}
+}
+
+// HostStats is the recorded state of one upstream host.
+type HostStats struct {
+	Host         string    `json:"host"`
+	Requests     int64     `json:"requests"`
+	Failures     int64     `json:"failures"`
+	Retries      int64     `json:"retries"`
+	CacheHits    int64     `json:"cache_hits"`
+	Rejected     int64     `json:"rejected"` // refused by breaker or budget
+	BreakerOpens int64     `json:"breaker_opens"`
+	Open         bool      `json:"open"`
+	OpenUntil    time.Time `json:"open_until,omitempty"`
+}
+
+type breaker struct {
+	stats     HostStats
+	failures  int  // consecutive
+	probing   bool // half-open trial in flight
+	openUntil time.Time
+}
+
+type cacheEntry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+type budgetWindow struct {
+	start time.Time
+	count int
+}
+
+var (
+	mu       sync.Mutex
+	breakers = map[string]*breaker{}
+	cache    = map[string]*cacheEntry{}
+	budgets  = map[string]*budgetWindow{} // client|host → window
+)
+
+func hostState(host string) *breaker {
+	b, ok := breakers[host]
+	if !ok {
+		b = &breaker{stats: HostStats{Host: host}}
+		breakers[host] = b
+	}
+	return b
+}
+
+type transport struct {
+	name   string
+	policy Policy
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	key := req.URL.String()
+	cacheable := t.policy.CacheTTL > 0 && req.Method == "GET" &&
+		req.Header.Get("Authorization") == "" && req.Header.Get("Cookie") == ""
+
+	if cacheable {
+		if resp := cached(key, host, req); resp != nil {
+			return resp, nil
+		}
+	}
+	if err := t.admit(host); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, host)
+	}
+
+	retries := 0
+	if idempotent(req) {
+		retries = t.policy.Retries
+	}
+	delay := t.policy.Backoff
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			if r, err = rewind(req); err != nil {
+				resp = nil
+				break
+			}
+		}
+		resp, err = t.base.RoundTrip(r)
+		if !retryable(resp, err) || attempt >= retries {
+			break
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		mu.Lock()
+		hostState(host).stats.Retries++
+		mu.Unlock()
+		if err = sleep(req.Context(), delay); err != nil {
+			resp = nil
+			break
+		}
+		delay *= 2
+	}
+
+	t.record(host, resp, err)
+
+	if err == nil && cacheable && resp.StatusCode == http.StatusOK && storable(resp) {
+		resp = store(key, resp, t.policy.CacheTTL)
+	}
+	return resp, err
+}
+
+// admit applies the circuit breaker and request budget.
+func (t *transport) admit(host string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	b := hostState(host)
+	now := time.Now()
+
+	if !b.openUntil.IsZero() {
+		if now.Before(b.openUntil) || b.probing {
+			b.stats.Rejected++
+			return ErrCircuitOpen
+		}
+		// Cooldown over: let one request through to probe the host.
+		b.probing = true
+	}
+
+	if t.policy.Budget > 0 {
+		k := t.name + "|" + host
+		w, ok := budgets[k]
+		if !ok || now.Sub(w.start) >= time.Minute {
+			w = &budgetWindow{start: now}
+			budgets[k] = w
+		}
+		if w.count >= t.policy.Budget {
+			b.probing = false
+			b.stats.Rejected++
+			return ErrBudgetExceeded
+		}
+		w.count++
+	}
+
+	b.stats.Requests++
+	return nil
+}
+
+// record updates the breaker with the outcome of a call.
+func (t *transport) record(host string, resp *http.Response, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	b := hostState(host)
+	b.probing = false
+
+	// Requests the admin switched off or the caller cancelled say nothing
+	// about the health of the host.
+	if errors.Is(err, egress.ErrDisabled) || errors.Is(err, context.Canceled) {
+		return
+	}
+	if err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+
+	b.stats.Failures++
+	b.failures++
+	if b.failures >= t.policy.BreakerThreshold {
+		if b.openUntil.IsZero() || time.Now().After(b.openUntil) {
+			b.stats.BreakerOpens++
+		}
+		b.openUntil = time.Now().Add(t.policy.BreakerCooldown)
+	}
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, egress.ErrDisabled) && !errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// rewind returns a copy of req with a fresh body for another attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// storable reports whether the upstream allows the response to be cached.
+func storable(resp *http.Response) bool {
+	cc := strings.ToLower(resp.Header.Get("Cache-Control"))
+	if strings.Contains(cc, "no-store") || strings.Contains(cc, "private") {
+		return false
+	}
+	return resp.Header.Get("Set-Cookie") == "" && resp.ContentLength <= maxCacheBody
+}
+
+// cached returns a fresh copy of a cached response, or nil.
+func cached(key, host string, req *http.Request) *http.Response {
+	mu.Lock()
+	defer mu.Unlock()
+	e, ok := cache[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expires) {
+		delete(cache, key)
+		return nil
+	}
+	hostState(host).stats.CacheHits++
+	return &http.Response{
+		Status:        http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// store buffers the response body into the cache and returns a response
+// that reads from the buffer.
+func store(key string, resp *http.Response, ttl time.Duration) *http.Response {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCacheBody+1))
+	if err != nil || len(body) > maxCacheBody {
+		// Too big (or failed mid-read): don't cache, and hand the caller
+		// what was read followed by the rest of the original body.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cache) >= maxCacheEntries {
+		evict()
+	}
+	cache[key] = &cacheEntry{
+		status:  resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    body,
+		expires: time.Now().Add(ttl),
+	}
+	markRefreshed()
+	return resp
+}
+
+// evict drops expired entries, or the one closest to expiry if none have.
+// Caller must hold mu.
+func evict() {
+	now := time.Now()
+	var oldest string
+	var oldestAt time.Time
+	for k, e := range cache {
+		if now.After(e.expires) {
+			delete(cache, k)
+			continue
+		}
+		if oldest == "" || e.expires.Before(oldestAt) {
+			oldest, oldestAt = k, e.expires
+		}
+	}
+	if len(cache) >= maxCacheEntries && oldest != "" {
+		delete(cache, oldest)
+	}
+}
+
+// Stats returns metrics for every host contacted through netx, busiest first.
+func Stats() []HostStats {
+	mu.Lock()
+	out := make([]HostStats, 0, len(breakers))
+	now := time.Now()
+	for _, b := range breakers {
+		s := b.stats
+		if now.Before(b.openUntil) {
+			s.Open = true
+			s.OpenUntil = b.openUntil
+		}
+		out = append(out, s)
+	}
+	mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+// Reset closes every breaker, clears metrics and empties the cache.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	breakers = map[string]*breaker{}
+	cache = map[string]*cacheEntry{}
+	budgets = map[string]*budgetWindow{}
+}
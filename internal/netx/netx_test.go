@@ -0,0 +1,121 @@
+package netx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	Reset()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := New("test", Policy{Timeout: 5 * time.Second, Retries: 2, Backoff: time.Millisecond})
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || calls != 3 {
+		t.Fatalf("status %d after %d calls, want 200 after 3", resp.StatusCode, calls)
+	}
+
+	// POSTs are never retried.
+	atomic.StoreInt32(&calls, 0)
+	resp, err = c.Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Fatalf("POST made %d calls, want 1", calls)
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	Reset()
+	var healthy int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	c := New("test", Policy{Timeout: 5 * time.Second, BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := c.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(60 * time.Millisecond)
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("probe after cooldown should pass: %v", err)
+	}
+	resp.Body.Close()
+
+	s := Stats()
+	if len(s) != 1 || s[0].Open || s[0].BreakerOpens != 1 || s[0].Rejected != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestCacheAndBudget(t *testing.T) {
+	Reset()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private")
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	c := New("test", Policy{Timeout: 5 * time.Second, CacheTTL: time.Minute, Budget: 3})
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(srv.URL + "/public")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if calls != 1 {
+		t.Fatalf("cacheable GET hit upstream %d times, want 1", calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL + "/private")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if calls != 3 {
+		t.Fatalf("private responses must not be cached: %d calls", calls)
+	}
+
+	// Three upstream requests used the budget of three.
+	if _, err := c.Get(srv.URL + "/other"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+}
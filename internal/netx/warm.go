@@ -0,0 +1,81 @@
+package netx
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"mu/internal/data"
+)
+
+// The response cache is kept across restarts (see data.WarmCache), so a
+// redeploy doesn't re-ask Nominatim, Hacker News and the rest for what
+// they answered minutes before. Entries keep their expiry: anything that
+// went stale while the server was down is simply a miss.
+
+type warmEntry struct {
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Expires time.Time   `json:"expires"`
+}
+
+// maxWarmBytes caps the response bodies written to a snapshot.
+const maxWarmBytes = 32 << 20
+
+// refreshed is set once a response has been cached since the restore.
+var refreshed atomic.Bool
+
+// Load restores the response cache from its last snapshot.
+func Load() {
+	data.RegisterWarm(data.WarmCache{
+		Name:     "netx",
+		MaxAge:   24 * time.Hour,
+		Snapshot: snapshotCache,
+		Restore:  restoreCache,
+	})
+}
+
+func snapshotCache() any {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	out := map[string]warmEntry{}
+	total := 0
+	for k, e := range cache {
+		if now.After(e.expires) || total+len(e.body) > maxWarmBytes {
+			continue
+		}
+		total += len(e.body)
+		out[k] = warmEntry{Status: e.status, Header: e.header, Body: e.body, Expires: e.expires}
+	}
+	return out
+}
+
+func restoreCache(raw json.RawMessage, _ time.Time) error {
+	var entries map[string]warmEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	for k, e := range entries {
+		if now.After(e.Expires) || len(cache) >= maxCacheEntries {
+			continue
+		}
+		if _, ok := cache[k]; !ok {
+			cache[k] = &cacheEntry{status: e.Status, header: e.Header, body: e.Body, expires: e.Expires}
+		}
+	}
+	return nil
+}
+
+// markRefreshed tells the warm cache layer the cache holds fresh
+// responses, the first time one is stored.
+func markRefreshed() {
+	if refreshed.CompareAndSwap(false, true) {
+		data.MarkWarm("netx")
+	}
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+
+	"mu/internal/app"
+)
+
+// Card is a home card from a plugin, rendered.
+type Card struct {
+	Plugin string
+	ID     string
+	Title  string
+	HTML   string
+	Link   string // the plugin's pages, when it serves any
+}
+
+// cardTTL is how long a card's content is kept before it's fetched again.
+var cardTTL = 2 * time.Minute
+
+type cardKey struct{ plugin, id string }
+
+type cachedCard struct {
+	html       string
+	fetched    time.Time
+	refreshing bool
+}
+
+var (
+	cardMu sync.Mutex
+	cards  = map[cardKey]*cachedCard{}
+)
+
+// Cards returns the cards of every running plugin allowed to contribute
+// them. Content comes from a cache that's refreshed in the background, so
+// a slow plugin never holds up the home screen; a card shows once its
+// first fetch has finished.
+func Cards() []Card {
+	var out []Card
+	for _, s := range List() {
+		p := Get(s.Name)
+		if p == nil || !s.Running || !p.Can(PermCards) {
+			continue
+		}
+		link := ""
+		if p.Can(PermRoutes) {
+			link = "/plugins/" + p.Name
+		}
+		for _, def := range p.Cards {
+			key := cardKey{p.Name, def.ID}
+			cardMu.Lock()
+			c := cards[key]
+			if c == nil {
+				c = &cachedCard{}
+				cards[key] = c
+			}
+			if time.Since(c.fetched) > cardTTL && !c.refreshing {
+				c.refreshing = true
+				go refreshCard(p, key)
+			}
+			content := c.html
+			cardMu.Unlock()
+			if content == "" {
+				continue
+			}
+			out = append(out, Card{Plugin: p.Name, ID: def.ID, Title: def.Title, HTML: content, Link: link})
+		}
+	}
+	return out
+}
+
+// refreshCard fetches a card's markdown from its plugin. A failed fetch
+// keeps the last content until the next try.
+func refreshCard(p *Plugin, key cardKey) {
+	var md string
+	err := p.call("GET", "/_mu/card/"+key.id, nil, &md)
+
+	cardMu.Lock()
+	defer cardMu.Unlock()
+	c := cards[key]
+	c.refreshing = false
+	c.fetched = time.Now()
+	if err != nil {
+		app.Log("plugin", "%s card %s: %v", key.plugin, key.id, err)
+		return
+	}
+	c.html = render(md)
+}
+
+// Command answers a chat command such as "/weather london" from the
+// plugin that offers it. It returns the reply as markdown and as HTML,
+// and reports false when no running plugin allowed to answer commands
+// has one by that name.
+func Command(userID, text string) (reply, replyHTML string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	name, args, _ := strings.Cut(text[1:], " ")
+	name = strings.ToLower(name)
+
+	for _, s := range List() {
+		p := Get(s.Name)
+		if p == nil || !s.Running || !p.Can(PermCommands) {
+			continue
+		}
+		for _, def := range p.Commands {
+			if def.Name != name {
+				continue
+			}
+			req := map[string]string{"command": name, "args": strings.TrimSpace(args)}
+			if p.Can(PermUser) {
+				req["user"] = userID
+			}
+			var rsp struct {
+				Reply string `json:"reply"`
+			}
+			if err := p.call("POST", "/_mu/command", req, &rsp); err != nil {
+				app.Log("plugin", "%s command /%s: %v", p.Name, name, err)
+				reply = "/" + name + " isn't available right now."
+				return reply, render(reply), true
+			}
+			return rsp.Reply, render(rsp.Reply), true
+		}
+	}
+	return "", "", false
+}
+
+// render turns markdown from a plugin into HTML. Unlike app.Render it
+// drops raw HTML and links to anything but web and mail addresses, since
+// the markdown comes from outside Mu.
+func render(md string) string {
+	p := parser.NewWithExtensions(parser.CommonExtensions &^ parser.MathJax)
+	r := html.NewRenderer(html.RendererOptions{
+		Flags: html.CommonFlags | html.HrefTargetBlank | html.SkipHTML | html.Safelink,
+	})
+	return string(markdown.Render(p.Parse([]byte(md)), r))
+}
@@ -0,0 +1,31 @@
+package plugin
+
+import (
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// sysProcAttr puts a plugin in its own process group, so stopping it also
+// stops anything it started.
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// applyLimits caps the memory of a started plugin process. The cap is on
+// its data segment, which is where a process's heap lives.
+func applyLimits(pid int, l Limits) error {
+	max := uint64(l.MemoryMB) << 20
+	return unix.Prlimit(pid, unix.RLIMIT_DATA, &unix.Rlimit{Cur: max, Max: max}, nil)
+}
+
+// signal asks a plugin's process group to stop, or kills it when force is
+// set.
+func signal(cmd *exec.Cmd, force bool) {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	syscall.Kill(-cmd.Process.Pid, sig)
+}
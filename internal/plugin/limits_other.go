@@ -0,0 +1,26 @@
+//go:build !linux
+
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func sysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// applyLimits does nothing: memory caps are only applied on Linux.
+func applyLimits(pid int, l Limits) error {
+	return nil
+}
+
+func signal(cmd *exec.Cmd, force bool) {
+	if force {
+		cmd.Process.Kill()
+		return
+	}
+	cmd.Process.Signal(os.Interrupt)
+}
@@ -0,0 +1,418 @@
+// Package plugin runs third-party extensions as separate processes. A
+// plugin is a directory under $HOME/.mu/plugins holding a plugin.json
+// manifest and an executable. Mu starts the executable with an address to
+// listen on and talks to it over HTTP on that address, so a plugin can be
+// written in any language and can't touch Mu's memory, data or secrets.
+//
+// A plugin can serve pages under /plugins/{name}/, contribute home cards
+// and answer chat commands, but only with the permissions an admin has
+// granted it on /admin/plugins, and within the limits set there: a
+// timeout and body size for every call, a request rate, and a memory cap
+// on the process. Plugins are installed disabled. See docs/PLUGINS.md for
+// the protocol.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/data"
+)
+
+// The permissions a plugin can ask for in its manifest and an admin can
+// grant.
+const (
+	PermRoutes   = "routes"   // serve pages under /plugins/{name}/
+	PermCards    = "cards"    // contribute home cards
+	PermCommands = "commands" // answer chat commands
+	PermUser     = "user"     // be told who the signed in user is
+)
+
+// Permissions lists every permission, in the order they're shown.
+var Permissions = []string{PermRoutes, PermCards, PermCommands, PermUser}
+
+// Manifest is a plugin's plugin.json.
+type Manifest struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Version     string    `json:"version"`
+	Command     []string  `json:"command"` // the executable, relative to the plugin's directory, and its arguments
+	Permissions []string  `json:"permissions"`
+	Cards       []CardDef `json:"cards,omitempty"`
+	Commands    []CmdDef  `json:"commands,omitempty"`
+}
+
+// CardDef is a home card a plugin offers.
+type CardDef struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// CmdDef is a chat command a plugin answers, without its leading slash.
+type CmdDef struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Limits bound what one plugin can use. Zero means the default.
+type Limits struct {
+	TimeoutSeconds    int `json:"timeout_seconds"`
+	MaxBodyKB         int `json:"max_body_kb"`
+	RequestsPerMinute int `json:"requests_per_minute"`
+	MemoryMB          int `json:"memory_mb"`
+}
+
+// DefaultLimits apply to a plugin until an admin changes them.
+var DefaultLimits = Limits{
+	TimeoutSeconds:    5,
+	MaxBodyKB:         512,
+	RequestsPerMinute: 120,
+	MemoryMB:          512,
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.TimeoutSeconds <= 0 {
+		l.TimeoutSeconds = DefaultLimits.TimeoutSeconds
+	}
+	if l.MaxBodyKB <= 0 {
+		l.MaxBodyKB = DefaultLimits.MaxBodyKB
+	}
+	if l.RequestsPerMinute <= 0 {
+		l.RequestsPerMinute = DefaultLimits.RequestsPerMinute
+	}
+	if l.MemoryMB <= 0 {
+		l.MemoryMB = DefaultLimits.MemoryMB
+	}
+	return l
+}
+
+func (l Limits) timeout() time.Duration {
+	return time.Duration(l.TimeoutSeconds) * time.Second
+}
+
+func (l Limits) maxBody() int64 {
+	return int64(l.MaxBodyKB) * 1024
+}
+
+// Config is how an admin has set up a plugin. It's kept by name, so it
+// survives the plugin being reinstalled or updated.
+type Config struct {
+	Enabled bool     `json:"enabled"`
+	Granted []string `json:"granted,omitempty"`
+	Limits  Limits   `json:"limits"`
+}
+
+// Status is a plugin as the admin page shows it.
+type Status struct {
+	Manifest
+	Dir       string    `json:"dir"`
+	Config    Config    `json:"config"`
+	Running   bool      `json:"running"`
+	Restarts  int       `json:"restarts"`
+	Calls     int64     `json:"calls"`
+	Limited   int64     `json:"limited"`
+	LastError string    `json:"last_error,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+}
+
+// Plugin is an installed plugin and, while it's enabled, its process.
+type Plugin struct {
+	Manifest
+	Dir string
+
+	mu        sync.Mutex
+	config    Config
+	proc      *process
+	restarts  int
+	calls     int64
+	limited   int64
+	window    time.Time // start of the current rate window
+	inWindow  int
+	lastError string
+}
+
+var (
+	mu      sync.RWMutex
+	plugins = map[string]*Plugin{}
+	configs = map[string]Config{}
+)
+
+// validName is what a plugin's name, which is also its directory and URL
+// path, and the IDs of its cards and commands must look like.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Dir is where plugins are installed, one directory each.
+func Dir() string {
+	return filepath.Join(os.ExpandEnv("$HOME/.mu"), "plugins")
+}
+
+// Load reads the admin's settings, finds the installed plugins and starts
+// the enabled ones.
+func Load() {
+	loaded := map[string]Config{}
+	data.LoadJSON("plugins.json", &loaded)
+	mu.Lock()
+	configs = loaded
+	mu.Unlock()
+
+	Scan()
+}
+
+// Scan looks for plugins installed or removed since the last scan and
+// starts or stops them to match.
+func Scan() {
+	found := map[string]*Plugin{}
+	entries, _ := os.ReadDir(Dir())
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(Dir(), e.Name())
+		m, err := readManifest(dir)
+		if err != nil {
+			app.Log("plugin", "Skipping %s: %v", dir, err)
+			continue
+		}
+		if m.Name != e.Name() {
+			app.Log("plugin", "Skipping %s: manifest name %q doesn't match its directory", dir, m.Name)
+			continue
+		}
+		found[m.Name] = &Plugin{Manifest: *m, Dir: dir}
+	}
+
+	mu.Lock()
+	var start, stop []*Plugin
+	for name, p := range plugins {
+		if _, ok := found[name]; !ok {
+			stop = append(stop, p)
+			delete(plugins, name)
+		}
+	}
+	for name, p := range found {
+		old, ok := plugins[name]
+		if ok && old.Version == p.Version {
+			continue
+		}
+		if ok {
+			stop = append(stop, old)
+		}
+		p.config = configs[name]
+		plugins[name] = p
+		if p.config.Enabled {
+			start = append(start, p)
+		}
+	}
+	mu.Unlock()
+
+	for _, p := range stop {
+		p.stop()
+	}
+	for _, p := range start {
+		go p.start()
+	}
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "plugin.json"))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("plugin.json: %v", err)
+	}
+	if !validName.MatchString(m.Name) {
+		return nil, fmt.Errorf("invalid name %q", m.Name)
+	}
+	if len(m.Command) == 0 {
+		return nil, fmt.Errorf("no command")
+	}
+	for _, perm := range m.Permissions {
+		if !known(perm) {
+			return nil, fmt.Errorf("unknown permission %q", perm)
+		}
+	}
+	for _, c := range m.Cards {
+		if !validName.MatchString(c.ID) {
+			return nil, fmt.Errorf("invalid card id %q", c.ID)
+		}
+	}
+	for _, c := range m.Commands {
+		if !validName.MatchString(c.Name) {
+			return nil, fmt.Errorf("invalid command %q", c.Name)
+		}
+	}
+	return &m, nil
+}
+
+func known(perm string) bool {
+	for _, p := range Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the installed plugin called name, or nil.
+func Get(name string) *Plugin {
+	mu.RLock()
+	defer mu.RUnlock()
+	return plugins[name]
+}
+
+// List returns every installed plugin, by name.
+func List() []Status {
+	mu.RLock()
+	list := make([]*Plugin, 0, len(plugins))
+	for _, p := range plugins {
+		list = append(list, p)
+	}
+	mu.RUnlock()
+
+	out := make([]Status, 0, len(list))
+	for _, p := range list {
+		out = append(out, p.Status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Status returns the plugin's manifest, settings and process state.
+func (p *Plugin) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := Status{
+		Manifest:  p.Manifest,
+		Dir:       p.Dir,
+		Config:    p.config,
+		Restarts:  p.restarts,
+		Calls:     p.calls,
+		Limited:   p.limited,
+		LastError: p.lastError,
+	}
+	s.Config.Limits = p.config.Limits.withDefaults()
+	if p.proc != nil {
+		s.Running = true
+		s.StartedAt = p.proc.started
+	}
+	return s
+}
+
+// Can reports whether the plugin asked for perm and an admin granted it.
+func (p *Plugin) Can(perm string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.can(perm)
+}
+
+// can is Can for a caller holding p.mu.
+func (p *Plugin) can(perm string) bool {
+	asked := false
+	for _, x := range p.Permissions {
+		asked = asked || x == perm
+	}
+	if !asked {
+		return false
+	}
+	for _, x := range p.config.Granted {
+		if x == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plugin) limits() Limits {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.config.Limits.withDefaults()
+}
+
+// Configure replaces the admin's settings for the plugin called name,
+// starting, stopping or restarting it to match. Permissions the plugin
+// didn't ask for are dropped.
+func Configure(name string, c Config) error {
+	p := Get(name)
+	if p == nil {
+		return fmt.Errorf("no plugin %q", name)
+	}
+	var granted []string
+	for _, perm := range c.Granted {
+		if !known(perm) {
+			return fmt.Errorf("unknown permission %q", perm)
+		}
+		for _, asked := range p.Permissions {
+			if asked == perm {
+				granted = append(granted, perm)
+			}
+		}
+	}
+	c.Granted = granted
+
+	p.mu.Lock()
+	was := p.config
+	p.config = c
+	p.mu.Unlock()
+
+	mu.Lock()
+	configs[name] = c
+	snapshot := make(map[string]Config, len(configs))
+	for k, v := range configs {
+		snapshot[k] = v
+	}
+	mu.Unlock()
+	if err := data.SaveJSON("plugins.json", snapshot); err != nil {
+		return err
+	}
+
+	// Limits such as memory are applied when the process starts.
+	switch {
+	case !c.Enabled:
+		p.stop()
+	case !was.Enabled:
+		go p.start()
+	case was.Limits != c.Limits:
+		p.stop()
+		go p.start()
+	}
+	return nil
+}
+
+// allow counts a call against the plugin's rate limit and reports whether
+// it's within it.
+func (p *Plugin) allow() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Sub(p.window) >= time.Minute {
+		p.window, p.inWindow = now, 0
+	}
+	if p.inWindow >= p.config.Limits.withDefaults().RequestsPerMinute {
+		p.limited++
+		return false
+	}
+	p.inWindow++
+	p.calls++
+	return true
+}
+
+// Stop stops every running plugin, for shutdown.
+func Stop() {
+	mu.RLock()
+	list := make([]*Plugin, 0, len(plugins))
+	for _, p := range plugins {
+		list = append(list, p)
+	}
+	mu.RUnlock()
+	for _, p := range list {
+		p.stop()
+	}
+}
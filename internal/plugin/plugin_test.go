@@ -0,0 +1,278 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test binary doubles as the plugin the tests install: started by Mu,
+// it has MU_PLUGIN_ADDR set and serves fakePlugin instead of running tests.
+func TestMain(m *testing.M) {
+	if addr := os.Getenv("MU_PLUGIN_ADDR"); addr != "" {
+		http.ListenAndServe(addr, fakePlugin())
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func fakePlugin() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_mu/health", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/_mu/card/today", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "**Be kind.** <script>alert(1)</script> [more](javascript:alert(1))")
+	})
+	mux.HandleFunc("/_mu/command", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]string{
+			"reply": fmt.Sprintf("%s said *%s*", req["user"], req["args"]),
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "plugin=1")
+		fmt.Fprintf(w, "path=%s query=%s prefix=%s user=%q cookie=%q",
+			r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Forwarded-Prefix"), r.Header.Get("X-Mu-User"), r.Header.Get("Cookie"))
+	})
+	return mux
+}
+
+// withPlugins gives the test an empty plugin directory and registry, and
+// stops whatever it started.
+func withPlugins(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	mu.Lock()
+	plugins, configs = map[string]*Plugin{}, map[string]Config{}
+	mu.Unlock()
+	cardMu.Lock()
+	cards = map[cardKey]*cachedCard{}
+	cardMu.Unlock()
+	t.Cleanup(Stop)
+}
+
+// install writes a plugin that runs the test binary, unless the manifest
+// has a command of its own.
+func install(t *testing.T, m Manifest) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Command == nil {
+		m.Command = []string{exe}
+	}
+	dir := filepath.Join(Dir(), m.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(m)
+	if err := os.WriteFile(filepath.Join(dir, "plugin.json"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventually(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func running(name string) func() bool {
+	return func() bool {
+		p := Get(name)
+		return p != nil && p.Status().Running
+	}
+}
+
+func fakeManifest() Manifest {
+	return Manifest{
+		Name:        "fake",
+		Version:     "1.0",
+		Permissions: []string{PermRoutes, PermCards, PermCommands, PermUser},
+		Cards:       []CardDef{{ID: "today", Title: "Today"}},
+		Commands:    []CmdDef{{Name: "echo"}},
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	withPlugins(t)
+	for _, m := range []Manifest{
+		{Name: "Bad Name", Command: []string{"x"}},
+		{Name: "nocommand", Command: []string{}},
+		{Name: "perms", Command: []string{"x"}, Permissions: []string{"root"}},
+		{Name: "card", Command: []string{"x"}, Cards: []CardDef{{ID: "a b"}}},
+	} {
+		install(t, m)
+		if _, err := readManifest(filepath.Join(Dir(), m.Name)); err == nil {
+			t.Errorf("manifest %+v accepted", m)
+		}
+	}
+	install(t, fakeManifest())
+	if _, err := readManifest(filepath.Join(Dir(), "fake")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstalledDisabled(t *testing.T) {
+	withPlugins(t)
+	install(t, fakeManifest())
+	Load()
+
+	p := Get("fake")
+	if p == nil {
+		t.Fatal("plugin not found")
+	}
+	if s := p.Status(); s.Running || s.Config.Enabled {
+		t.Fatalf("new plugin should be disabled: %+v", s)
+	}
+	if p.Can(PermRoutes) {
+		t.Error("permission held before it was granted")
+	}
+	if _, _, ok := Command("alice", "/echo hi"); ok {
+		t.Error("disabled plugin answered a command")
+	}
+}
+
+func TestConfigureGrantsOnlyAskedPermissions(t *testing.T) {
+	withPlugins(t)
+	m := fakeManifest()
+	m.Permissions = []string{PermCards}
+	install(t, m)
+	Load()
+
+	if err := Configure("fake", Config{Granted: []string{PermCards, PermUser}}); err != nil {
+		t.Fatal(err)
+	}
+	p := Get("fake")
+	if !p.Can(PermCards) || p.Can(PermUser) {
+		t.Errorf("granted = %v", p.Status().Config.Granted)
+	}
+	if err := Configure("fake", Config{Granted: []string{"root"}}); err == nil {
+		t.Error("unknown permission granted")
+	}
+
+	// Settings are kept across a restart of Mu.
+	mu.Lock()
+	plugins = map[string]*Plugin{}
+	mu.Unlock()
+	Load()
+	if !Get("fake").Can(PermCards) {
+		t.Error("grant not persisted")
+	}
+}
+
+func TestPluginEndToEnd(t *testing.T) {
+	withPlugins(t)
+	install(t, fakeManifest())
+	Load()
+	err := Configure("fake", Config{Enabled: true, Granted: []string{PermRoutes, PermCards, PermCommands}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the plugin to start", running("fake"))
+
+	// Cards are fetched in the background and rendered without raw HTML
+	// or unsafe links.
+	var got []Card
+	eventually(t, "the card", func() bool {
+		got = Cards()
+		return len(got) == 1
+	})
+	c := got[0]
+	if c.Title != "Today" || c.Link != "/plugins/fake" || !strings.Contains(c.HTML, "<strong>Be kind.</strong>") {
+		t.Errorf("card = %+v", c)
+	}
+	if strings.Contains(c.HTML, "<script") || strings.Contains(c.HTML, "javascript:") {
+		t.Errorf("card HTML not made safe: %s", c.HTML)
+	}
+
+	// Commands reach the plugin; without the user permission it isn't
+	// told who asked.
+	reply, replyHTML, ok := Command("alice", "/echo hello there")
+	if !ok || reply != " said *hello there*" || !strings.Contains(replyHTML, "<em>hello there</em>") {
+		t.Errorf("command = %q %q %v", reply, replyHTML, ok)
+	}
+	if _, _, ok := Command("alice", "/unknown"); ok {
+		t.Error("unknown command answered")
+	}
+
+	// Pages are proxied without credentials, and sandboxed.
+	r := httptest.NewRequest("GET", "/plugins/fake/hello?x=1", nil)
+	r.Header.Set("Cookie", "session=secret")
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	Handler(w, r)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `path=/hello query=x=1 prefix=/plugins/fake user="" cookie=""`) {
+		t.Errorf("proxied page: %d %s", w.Code, body)
+	}
+	if w.Header().Get("Set-Cookie") != "" || w.Header().Get("Content-Security-Policy") != sandboxCSP {
+		t.Errorf("response headers: %v", w.Header())
+	}
+
+	for _, path := range []string{"/plugins/fake/_mu/health", "/plugins/nobody/hello"} {
+		w := httptest.NewRecorder()
+		Handler(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d", path, w.Code)
+		}
+	}
+
+	// Calls over the rate are refused.
+	p := Get("fake")
+	p.mu.Lock()
+	p.config.Limits.RequestsPerMinute = p.inWindow
+	p.mu.Unlock()
+	w = httptest.NewRecorder()
+	Handler(w, httptest.NewRequest("GET", "/plugins/fake/hello", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("over the rate: %d", w.Code)
+	}
+	if p.Status().Limited != 1 {
+		t.Errorf("limited = %d", p.Status().Limited)
+	}
+
+	// Disabling stops the process.
+	if err := Configure("fake", Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status().Running {
+		t.Error("disabled plugin still running")
+	}
+	w = httptest.NewRecorder()
+	Handler(w, httptest.NewRequest("GET", "/plugins/fake/hello", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("page of a plugin without routes: %d", w.Code)
+	}
+}
+
+func TestRoutesNeedPermission(t *testing.T) {
+	withPlugins(t)
+	install(t, fakeManifest())
+	Load()
+	if err := Configure("fake", Config{Enabled: true, Granted: []string{PermCards}}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the plugin to start", running("fake"))
+
+	w := httptest.NewRecorder()
+	Handler(w, httptest.NewRequest("GET", "/plugins/fake/hello", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("page served without the routes permission: %d", w.Code)
+	}
+	if _, _, ok := Command("alice", "/echo hi"); ok {
+		t.Error("command answered without the commands permission")
+	}
+}
@@ -0,0 +1,226 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"mu/internal/app"
+)
+
+// process is a running plugin executable.
+type process struct {
+	cmd     *exec.Cmd
+	addr    string // host:port the plugin listens on
+	started time.Time
+	done    chan struct{} // closed when the process exits
+	err     error         // why it exited, once done is closed
+}
+
+// client talks to plugin processes. They're on this machine, so it
+// doesn't go through the egress gate.
+var client = &http.Client{Transport: &http.Transport{
+	Proxy:               nil,
+	MaxIdleConnsPerHost: 4,
+	IdleConnTimeout:     time.Minute,
+}}
+
+// startTimeout is how long a plugin has to start answering on its
+// address.
+var startTimeout = 10 * time.Second
+
+// start runs the plugin's executable, unless it's already running, has
+// been disabled or is no longer installed.
+func (p *Plugin) start() {
+	if Get(p.Name) != p {
+		return
+	}
+	p.mu.Lock()
+	if p.proc != nil || !p.config.Enabled {
+		p.mu.Unlock()
+		return
+	}
+	limits := p.config.Limits.withDefaults()
+	p.mu.Unlock()
+
+	proc, err := p.launch(limits)
+
+	p.mu.Lock()
+	if err != nil {
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		app.Log("plugin", "%s failed to start: %v", p.Name, err)
+		p.restartLater(nil)
+		return
+	}
+	if p.proc != nil || !p.config.Enabled {
+		// Stopped, or started by someone else, while launching.
+		p.mu.Unlock()
+		proc.kill()
+		return
+	}
+	p.proc = proc
+	p.lastError = ""
+	p.mu.Unlock()
+	app.Log("plugin", "%s %s started on %s", p.Name, p.Version, proc.addr)
+
+	go func() {
+		<-proc.done
+		p.restartLater(proc)
+	}()
+}
+
+// restartLater notes that proc has exited, or that the plugin failed to
+// start when proc is nil, and starts the plugin again after a backoff if
+// it's still enabled.
+func (p *Plugin) restartLater(proc *process) {
+	p.mu.Lock()
+	if proc != nil {
+		if p.proc != proc {
+			// Stopped on purpose.
+			p.mu.Unlock()
+			return
+		}
+		p.proc = nil
+		p.lastError = fmt.Sprintf("exited: %v", proc.err)
+		app.Log("plugin", "%s exited: %v", p.Name, proc.err)
+	}
+	p.restarts++
+	delay := time.Second << min(p.restarts, 6)
+	p.mu.Unlock()
+
+	time.AfterFunc(delay, p.start)
+}
+
+// stop kills the plugin's process, if it's running.
+func (p *Plugin) stop() {
+	p.mu.Lock()
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+	if proc != nil {
+		proc.kill()
+		app.Log("plugin", "%s stopped", p.Name)
+	}
+}
+
+// launch starts the executable and waits for it to answer its health
+// check. The process gets a clean environment: nothing of Mu's, such as
+// API keys, is passed on.
+func (p *Plugin) launch(limits Limits) (*process, error) {
+	addr, err := freeAddr()
+	if err != nil {
+		return nil, err
+	}
+	home := filepath.Join(p.Dir, "data")
+	if err := os.MkdirAll(home, 0700); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Dir = p.Dir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + home,
+		"MU_PLUGIN_NAME=" + p.Name,
+		"MU_PLUGIN_ADDR=" + addr,
+		"MU_PLUGIN_DATA=" + home,
+	}
+	out := &logWriter{name: p.Name}
+	cmd.Stdout, cmd.Stderr = out, out
+	cmd.SysProcAttr = sysProcAttr()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	proc := &process{cmd: cmd, addr: addr, started: time.Now(), done: make(chan struct{})}
+	go func() {
+		proc.err = cmd.Wait()
+		close(proc.done)
+	}()
+	if err := applyLimits(cmd.Process.Pid, limits); err != nil {
+		proc.kill()
+		return nil, fmt.Errorf("limits: %v", err)
+	}
+
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-proc.done:
+			return nil, fmt.Errorf("exited on start: %v", proc.err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		rsp, err := client.Get("http://" + addr + "/_mu/health")
+		if err != nil {
+			continue
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode == http.StatusOK {
+			return proc, nil
+		}
+	}
+	proc.kill()
+	return nil, fmt.Errorf("no health check answer on %s after %s", addr, startTimeout)
+}
+
+// kill asks the process to stop, and makes it stop if it hasn't after a
+// few seconds.
+func (proc *process) kill() {
+	signal(proc.cmd, false)
+	select {
+	case <-proc.done:
+	case <-time.After(3 * time.Second):
+		signal(proc.cmd, true)
+		<-proc.done
+	}
+}
+
+// freeAddr returns a loopback address with a port nothing is listening
+// on.
+func freeAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+// logWriter sends a plugin's output to the system log, a line at a time.
+// Stdout and stderr share one, so it's only written from one goroutine.
+type logWriter struct {
+	name string
+	buf  []byte
+}
+
+// maxLogLine is the longest line of plugin output that's logged whole.
+const maxLogLine = 500
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > maxLogLine {
+		w.log(w.buf)
+		w.buf = w.buf[:0]
+	}
+	return len(b), nil
+}
+
+func (w *logWriter) log(line []byte) {
+	if len(line) > maxLogLine {
+		line = line[:maxLogLine]
+	}
+	app.Log("plugin", "[%s] %s", w.name, line)
+}
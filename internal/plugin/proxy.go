@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// errNotRunning is returned for calls to a plugin whose process isn't up.
+var errNotRunning = errors.New("plugin is not running")
+
+// errRateLimited is returned for calls over a plugin's request rate.
+var errRateLimited = errors.New("plugin is over its request rate")
+
+// sandboxCSP is sent with every page a plugin serves. It gives the page an
+// origin of its own, so its scripts can't read Mu's cookies or call Mu's
+// API as the reader.
+const sandboxCSP = "sandbox allow-scripts allow-forms allow-popups"
+
+// addr returns where the plugin's process is listening, if it's running.
+func (p *Plugin) addr() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.proc == nil {
+		return "", false
+	}
+	return p.proc.addr, true
+}
+
+// Handler serves /plugins/{name}/... from the plugin's own server, for
+// plugins granted the routes permission. Cookies and credentials are
+// never passed on. A plugin granted the user permission is told who is
+// viewing a page in X-Mu-User, on GET and HEAD requests only, so a page
+// can't be used to act as the reader.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	name, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/plugins/"), "/")
+	path = "/" + path
+	p := Get(name)
+	if p == nil || !p.Can(PermRoutes) || path == "/_mu" || strings.HasPrefix(path, "/_mu/") {
+		app.NotFound(w, r, "Plugin not found")
+		return
+	}
+	addr, ok := p.addr()
+	if !ok {
+		app.Error(w, r, http.StatusServiceUnavailable, "This plugin isn't running")
+		return
+	}
+	if !p.allow() {
+		app.Error(w, r, http.StatusTooManyRequests, "Too many requests to this plugin")
+		return
+	}
+
+	limits := p.limits()
+	user := ""
+	if p.Can(PermUser) && (r.Method == "GET" || r.Method == "HEAD") {
+		if sess, _ := auth.TrySession(r); sess != nil {
+			user = sess.Account
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), limits.timeout())
+	defer cancel()
+	r.Body = http.MaxBytesReader(w, r.Body, limits.maxBody())
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme, pr.Out.URL.Host = "http", addr
+			pr.Out.URL.Path, pr.Out.URL.RawPath = path, ""
+			pr.Out.Host = addr
+			for _, h := range []string{"Cookie", "Authorization", "X-Micro-Token", "X-CSRF-Token", "X-Mu-User"} {
+				pr.Out.Header.Del(h)
+			}
+			pr.SetXForwarded()
+			pr.Out.Header.Set("X-Forwarded-Prefix", "/plugins/"+name)
+			if user != "" {
+				pr.Out.Header.Set("X-Mu-User", user)
+			}
+		},
+		Transport: client.Transport,
+		ModifyResponse: func(rsp *http.Response) error {
+			if rsp.ContentLength > limits.maxBody() {
+				return fmt.Errorf("response of %d bytes is over the limit", rsp.ContentLength)
+			}
+			rsp.Header.Del("Set-Cookie")
+			rsp.Header.Set("Content-Security-Policy", sandboxCSP)
+			rsp.Header.Set("X-Content-Type-Options", "nosniff")
+			rsp.Body = &limitedBody{ReadCloser: rsp.Body, left: limits.maxBody()}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			app.Log("plugin", "%s %s: %v", name, path, err)
+			app.Error(w, r, http.StatusBadGateway, "The plugin didn't answer")
+		},
+	}
+	proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// limitedBody fails a response body once it runs past the plugin's limit.
+type limitedBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, errors.New("response is over the limit")
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+// call makes a request to one of the plugin's /_mu/ endpoints, within its
+// limits, and decodes the JSON response into out, or returns it as text
+// when out is a *string.
+func (p *Plugin) call(method, path string, in, out any) error {
+	addr, ok := p.addr()
+	if !ok {
+		return errNotRunning
+	}
+	if !p.allow() {
+		return errRateLimited
+	}
+	limits := p.limits()
+	ctx, cancel := context.WithTimeout(context.Background(), limits.timeout())
+	defer cancel()
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+addr+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(rsp.Body, limits.maxBody()+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(b)) > limits.maxBody() {
+		return fmt.Errorf("%s: response is over the limit", path)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", path, rsp.StatusCode)
+	}
+	if s, ok := out.(*string); ok {
+		*s = string(b)
+		return nil
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package plugin
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	// Plugin pages are proxied to each plugin's own process. The proxy
+	// never acts as the reader on a POST, and the body must reach the
+	// plugin unread, so there's no CSRF check.
+	r.HandleFunc("/plugins/", Handler, app.SkipCSRF)
+}
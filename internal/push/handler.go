@@ -0,0 +1,223 @@
+package push
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+const (
+	defaultPollWait = 25 * time.Second
+	maxPollWait     = 60 * time.Second
+	pingInterval    = 30 * time.Second
+)
+
+// Native clients send no Origin; browsers must be on our own host.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && u.Host == r.Host
+	},
+}
+
+// Handler serves the push API. Authenticate with an API token in the
+// Authorization header.
+//
+//	GET    /push/devices             list devices
+//	POST   /push/devices             register {"name","platform"}
+//	DELETE /push/devices?id=X        remove a device
+//	GET    /push/poll?device=X       pending events, waiting up to ?wait= seconds
+//	POST   /push/ack                 {"device","seq"} acknowledge up to seq
+//	GET    /push/ws?device=X         WebSocket; send {"ack":seq} to acknowledge
+func Handler(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	account := sess.Account
+
+	switch r.URL.Path {
+	case "/push/devices":
+		handleDevices(w, r, account)
+	case "/push/poll":
+		handlePoll(w, r, account)
+	case "/push/ack":
+		if r.Method != "POST" {
+			app.MethodNotAllowed(w, r)
+			return
+		}
+		var req struct {
+			Device string `json:"device"`
+			Seq    uint64 `json:"seq"`
+		}
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+		if err := Ack(account, req.Device, req.Seq); err != nil {
+			app.NotFound(w, r, err.Error())
+			return
+		}
+		app.RespondJSON(w, map[string]interface{}{"acked": req.Seq})
+	case "/push/ws":
+		handleSocket(w, r, account)
+	default:
+		app.NotFound(w, r, "")
+	}
+}
+
+func handleDevices(w http.ResponseWriter, r *http.Request, account string) {
+	switch r.Method {
+	case "GET":
+		list := Devices(account)
+		if list == nil {
+			list = []Device{}
+		}
+		app.RespondJSON(w, map[string]interface{}{"devices": list})
+	case "POST":
+		var req struct {
+			Name     string `json:"name"`
+			Platform string `json:"platform"`
+		}
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+		d, err := Register(account, req.Name, req.Platform)
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		app.Log("push", "%s registered device %s (%s)", account, d.ID, d.Name)
+		app.RespondJSON(w, d)
+	case "DELETE":
+		if err := Unregister(account, r.URL.Query().Get("id")); err != nil {
+			app.NotFound(w, r, err.Error())
+			return
+		}
+		app.RespondJSON(w, map[string]string{"status": "removed"})
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+// handlePoll answers straight away when events are waiting, otherwise
+// holds the request until one arrives or the wait runs out.
+func handlePoll(w http.ResponseWriter, r *http.Request, account string) {
+	device := r.URL.Query().Get("device")
+	if s := r.URL.Query().Get("ack"); s != "" {
+		if seq, err := strconv.ParseUint(s, 10, 64); err == nil {
+			Ack(account, device, seq)
+		}
+	}
+	timeout := defaultPollWait
+	if s := r.URL.Query().Get("wait"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			timeout = time.Duration(n) * time.Second
+		}
+	}
+	if timeout > maxPollWait {
+		timeout = maxPollWait
+	}
+
+	ch, stop := wait(account)
+	defer stop()
+
+	events, err := Pending(account, device)
+	if err != nil {
+		app.NotFound(w, r, err.Error())
+		return
+	}
+	if len(events) == 0 && timeout > 0 {
+		select {
+		case <-ch:
+			events, _ = Pending(account, device)
+		case <-time.After(timeout):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	app.RespondJSON(w, map[string]interface{}{"events": events})
+}
+
+func handleSocket(w http.ResponseWriter, r *http.Request, account string) {
+	device := r.URL.Query().Get("device")
+	if _, err := Pending(account, device); err != nil {
+		app.NotFound(w, r, err.Error())
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ch, stop := wait(account)
+	defer stop()
+
+	// Acknowledgements come in on the read side.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var msg struct {
+				Ack uint64 `json:"ack"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Ack > 0 {
+				Ack(account, device, msg.Ack)
+			}
+		}
+	}()
+
+	var sent uint64
+	send := func() error {
+		events, err := Pending(account, device)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if e.Seq <= sent {
+				continue // sent already, awaiting ack
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return err
+			}
+			sent = e.Seq
+		}
+		return nil
+	}
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		if err := send(); err != nil {
+			return
+		}
+		select {
+		case <-ch:
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Package push delivers account notifications (new mail, mentions, prayer
+// times, credits received) to native clients without FCM or APNs. A client registers a device, then
+// either long-polls /push/poll or holds a WebSocket on /push/ws. Events
+// are kept per account until every device has acknowledged them, so a
+// phone that was offline catches up when it reconnects.
+package push
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Event kinds.
+const (
+	KindMail    = "mail"
+	KindMention = "mention"
+	KindMessage = "message"
+	KindPrayer  = "prayer"
+	KindCredits = "credits"
+)
+
+const (
+	maxEvents      = 100                // kept per account
+	eventRetention = 7 * 24 * time.Hour // dropped after this even if unacknowledged
+	maxDevices     = 10                 // per account
+)
+
+// Event is one notification. Seq increases per account so a device can
+// acknowledge everything up to a point.
+type Event struct {
+	Seq   uint64    `json:"seq"`
+	Kind  string    `json:"kind"`
+	Title string    `json:"title"`
+	Body  string    `json:"body,omitempty"`
+	URL   string    `json:"url,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// Device is a registered client.
+type Device struct {
+	ID       string    `json:"id"`
+	Account  string    `json:"account"`
+	Name     string    `json:"name"`
+	Platform string    `json:"platform,omitempty"`
+	Created  time.Time `json:"created"`
+	LastSeen time.Time `json:"last_seen"`
+	Acked    uint64    `json:"acked"` // highest Seq the device has acknowledged
+}
+
+type state struct {
+	Devices map[string]*Device `json:"devices"` // device ID → device
+	Events  map[string][]Event `json:"events"`  // account → events, oldest first
+	Seq     map[string]uint64  `json:"seq"`     // account → last Seq issued
+}
+
+var (
+	mu      sync.Mutex
+	st      = state{Devices: map[string]*Device{}, Events: map[string][]Event{}, Seq: map[string]uint64{}}
+	waiters = map[string][]chan struct{}{} // account → long-polls and sockets to wake
+)
+
+// ErrUnknownDevice is returned for a device ID that isn't registered to
+// the caller.
+var ErrUnknownDevice = errors.New("unknown device")
+
+func init() {
+	var loaded state
+	if err := data.LoadJSON("push.json", &loaded); err == nil {
+		if loaded.Devices != nil {
+			st.Devices = loaded.Devices
+		}
+		if loaded.Events != nil {
+			st.Events = loaded.Events
+		}
+		if loaded.Seq != nil {
+			st.Seq = loaded.Seq
+		}
+	}
+	auth.AccountDeleteHooks = append(auth.AccountDeleteHooks, deleteAccount)
+	auth.AccountRenameHooks = append(auth.AccountRenameHooks, renameAccount)
+}
+
+func save() {
+	data.SaveJSON("push.json", st)
+}
+
+// Register adds a device for the account. It starts with only events
+// raised after registration.
+func Register(accountID, name, platform string) (*Device, error) {
+	if name == "" {
+		name = "Device"
+	}
+	if len(name) > 100 || len(platform) > 20 {
+		return nil, errors.New("name or platform too long")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var own []*Device
+	for _, d := range st.Devices {
+		if d.Account == accountID {
+			own = append(own, d)
+		}
+	}
+	if len(own) >= maxDevices {
+		return nil, errors.New("too many devices — remove one first")
+	}
+	now := time.Now()
+	d := &Device{
+		ID:       uuid.New().String(),
+		Account:  accountID,
+		Name:     name,
+		Platform: platform,
+		Created:  now,
+		LastSeen: now,
+		Acked:    st.Seq[accountID],
+	}
+	st.Devices[d.ID] = d
+	save()
+	copy := *d
+	return &copy, nil
+}
+
+// Unregister removes one of the account's devices.
+func Unregister(accountID, deviceID string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	d, ok := st.Devices[deviceID]
+	if !ok || d.Account != accountID {
+		return ErrUnknownDevice
+	}
+	delete(st.Devices, deviceID)
+	trim(accountID)
+	save()
+	return nil
+}
+
+// Devices lists the account's devices.
+func Devices(accountID string) []Device {
+	mu.Lock()
+	defer mu.Unlock()
+	var out []Device
+	for _, d := range st.Devices {
+		if d.Account == accountID {
+			out = append(out, *d)
+		}
+	}
+	return out
+}
+
+// Notify queues an event for every device on the account and wakes any
+// connected clients. Accounts without devices are skipped.
+func Notify(accountID, kind, title, body, url string) {
+	mu.Lock()
+	defer mu.Unlock()
+	has := false
+	for _, d := range st.Devices {
+		if d.Account == accountID {
+			has = true
+			break
+		}
+	}
+	if !has {
+		return
+	}
+	st.Seq[accountID]++
+	st.Events[accountID] = append(st.Events[accountID], Event{
+		Seq:   st.Seq[accountID],
+		Kind:  kind,
+		Title: title,
+		Body:  body,
+		URL:   url,
+		Time:  time.Now(),
+	})
+	trim(accountID)
+	save()
+	for _, ch := range waiters[accountID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// NotifyMentions sends a mention event to every account @named in text,
+// other than the author.
+func NotifyMentions(authorID, text, title, url string) {
+	for _, id := range app.Mentions(text) {
+		if id == authorID {
+			continue
+		}
+		body := text
+		if len(body) > 200 {
+			body = body[:200] + "…"
+		}
+		Notify(id, KindMention, title, body, url)
+	}
+}
+
+// Pending returns the events the device hasn't acknowledged yet.
+func Pending(accountID, deviceID string) ([]Event, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	d, ok := st.Devices[deviceID]
+	if !ok || d.Account != accountID {
+		return nil, ErrUnknownDevice
+	}
+	d.LastSeen = time.Now()
+	out := []Event{}
+	for _, e := range st.Events[accountID] {
+		if e.Seq > d.Acked {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// Ack marks everything up to seq as delivered to the device. Events every
+// device has acknowledged are dropped.
+func Ack(accountID, deviceID string, seq uint64) error {
+	mu.Lock()
+	defer mu.Unlock()
+	d, ok := st.Devices[deviceID]
+	if !ok || d.Account != accountID {
+		return ErrUnknownDevice
+	}
+	if seq > st.Seq[accountID] {
+		seq = st.Seq[accountID]
+	}
+	if seq <= d.Acked {
+		return nil
+	}
+	d.Acked = seq
+	d.LastSeen = time.Now()
+	trim(accountID)
+	save()
+	return nil
+}
+
+// trim drops events that every device has acknowledged, that are too
+// old, or that exceed maxEvents (caller must hold mu).
+func trim(accountID string) {
+	var minAcked uint64
+	first := true
+	for _, d := range st.Devices {
+		if d.Account != accountID {
+			continue
+		}
+		if first || d.Acked < minAcked {
+			minAcked = d.Acked
+			first = false
+		}
+	}
+	if first {
+		delete(st.Events, accountID)
+		return
+	}
+	cutoff := time.Now().Add(-eventRetention)
+	var kept []Event
+	for _, e := range st.Events[accountID] {
+		if e.Seq > minAcked && e.Time.After(cutoff) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) > maxEvents {
+		kept = kept[len(kept)-maxEvents:]
+	}
+	if len(kept) == 0 {
+		delete(st.Events, accountID)
+		return
+	}
+	st.Events[accountID] = kept
+}
+
+// wait returns a channel signalled when the account gets a new event and
+// a function to stop listening.
+func wait(accountID string) (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	mu.Lock()
+	waiters[accountID] = append(waiters[accountID], ch)
+	mu.Unlock()
+	return ch, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		list := waiters[accountID]
+		for i, c := range list {
+			if c == ch {
+				waiters[accountID] = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(waiters[accountID]) == 0 {
+			delete(waiters, accountID)
+		}
+	}
+}
+
+func deleteAccount(accountID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for id, d := range st.Devices {
+		if d.Account == accountID {
+			delete(st.Devices, id)
+		}
+	}
+	delete(st.Events, accountID)
+	delete(st.Seq, accountID)
+	save()
+}
+
+// renameAccount moves a renamed account's devices and queued events.
+func renameAccount(oldID, newID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, d := range st.Devices {
+		if d.Account == oldID {
+			d.Account = newID
+		}
+	}
+	if ev, ok := st.Events[oldID]; ok {
+		delete(st.Events, oldID)
+		st.Events[newID] = ev
+	}
+	if seq, ok := st.Seq[oldID]; ok {
+		delete(st.Seq, oldID)
+		st.Seq[newID] = seq
+	}
+	save()
+}
@@ -0,0 +1,66 @@
+package push
+
+import (
+	"testing"
+	"time"
+)
+
+func resetForTest(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	mu.Lock()
+	st = state{Devices: map[string]*Device{}, Events: map[string][]Event{}, Seq: map[string]uint64{}}
+	mu.Unlock()
+}
+
+func TestEventsHeldUntilEveryDeviceAcks(t *testing.T) {
+	resetForTest(t)
+
+	Notify("alice", KindMail, "before any device", "", "")
+	phone, err := Register("alice", "Phone", "android")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tablet, _ := Register("alice", "Tablet", "ios")
+
+	Notify("alice", KindMail, "New email from bob", "hi", "/mail")
+	Notify("alice", KindMention, "@bob mentioned you", "hey @alice", "/@bob")
+
+	got, _ := Pending("alice", phone.ID)
+	if len(got) != 2 || got[0].Title != "New email from bob" || got[1].Seq != got[0].Seq+1 {
+		t.Fatalf("pending = %+v", got)
+	}
+	if _, err := Pending("bob", phone.ID); err != ErrUnknownDevice {
+		t.Error("another account read the device")
+	}
+
+	Ack("alice", phone.ID, got[1].Seq)
+	if p, _ := Pending("alice", phone.ID); len(p) != 0 {
+		t.Errorf("phone still has %d pending", len(p))
+	}
+	if p, _ := Pending("alice", tablet.ID); len(p) != 2 {
+		t.Errorf("tablet has %d pending, want 2", len(p))
+	}
+
+	Ack("alice", tablet.ID, got[0].Seq)
+	mu.Lock()
+	kept := len(st.Events["alice"])
+	mu.Unlock()
+	if kept != 1 {
+		t.Errorf("kept %d events after acks, want 1", kept)
+	}
+}
+
+func TestWaitWakesOnNotify(t *testing.T) {
+	resetForTest(t)
+	Register("alice", "Phone", "")
+
+	ch, stop := wait("alice")
+	defer stop()
+	go Notify("alice", KindMail, "x", "", "")
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not woken")
+	}
+}
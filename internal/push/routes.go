@@ -0,0 +1,11 @@
+package push
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/push/", Handler, app.Authenticated) // native clients, with an API token
+}
@@ -19,6 +19,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"mu/internal/egress"
 )
 
 // ErrBlocked is returned when a destination is not a public host.
@@ -157,7 +159,7 @@ func Fetch(ctx context.Context, raw string, opt Options) (*Response, error) {
 
 	client := &http.Client{
 		Timeout:   timeout,
-		Transport: transport,
+		Transport: egress.Wrap(transport),
 		CheckRedirect: func(r *http.Request, via []*http.Request) error {
 			if len(via) >= maxRedirects {
 				return fmt.Errorf("too many redirects")
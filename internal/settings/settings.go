@@ -1,6 +1,7 @@
 // Package settings provides persistent configuration that can be edited
 // from the admin UI. Values are stored in settings.json. Environment
-// variables take precedence when set.
+// variables take precedence when set, including those filled in from the
+// config file, mu.toml (see package env).
 package settings
 
 import (
@@ -8,6 +9,7 @@ import (
 	"sync"
 
 	"mu/internal/data"
+	"mu/internal/env"
 )
 
 var (
@@ -54,9 +56,13 @@ func IsSet(key string) bool {
 	return Get(key) != ""
 }
 
-// Source returns where the value comes from: "env", "saved", or "".
+// Source returns where the value comes from: "env", "file" (mu.toml or
+// .env), "saved", or "".
 func Source(key string) string {
 	if os.Getenv(key) != "" {
+		if env.File(key) != "" {
+			return "file"
+		}
 		return "env"
 	}
 	mu.RLock()
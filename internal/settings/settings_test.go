@@ -3,7 +3,9 @@ package settings
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func resetForTest(t *testing.T) {
@@ -116,3 +118,64 @@ func TestSetPersistsSettingsUnderHome(t *testing.T) {
 		t.Fatalf("expected settings file at %s: %v", path, err)
 	}
 }
+
+func TestRegisteredTypedSettings(t *testing.T) {
+	resetForTest(t)
+	Register("Test",
+		Var{Key: "MU_TEST_INT", Type: TypeInt, Default: "7"},
+		Var{Key: "MU_TEST_DUR", Type: TypeDuration, Default: "1h"},
+		Var{Key: "MU_TEST_BOOL", Type: TypeBool},
+		Var{Key: "MU_TEST_URL", Type: TypeURL},
+		Var{Key: "MU_TEST_PLAIN_TOKEN"},
+		Var{Key: "MU_TEST_HIDDEN", Secret: true},
+	)
+
+	if got := Int("MU_TEST_INT"); got != 7 {
+		t.Errorf("Int default = %d, want 7", got)
+	}
+	if got := Duration("MU_TEST_DUR"); got != time.Hour {
+		t.Errorf("Duration default = %v, want 1h", got)
+	}
+
+	t.Setenv("MU_TEST_INT", "12")
+	t.Setenv("MU_TEST_BOOL", "yes")
+	if Int("MU_TEST_INT") != 12 || !Bool("MU_TEST_BOOL") {
+		t.Errorf("Int, Bool = %d, %v; want 12, true", Int("MU_TEST_INT"), Bool("MU_TEST_BOOL"))
+	}
+
+	// a bad value falls back to the default and is reported
+	t.Setenv("MU_TEST_DUR", "soon")
+	t.Setenv("MU_TEST_URL", "example.com")
+	if got := Duration("MU_TEST_DUR"); got != time.Hour {
+		t.Errorf("Duration of a bad value = %v, want the 1h default", got)
+	}
+	var bad []string
+	for _, err := range Validate() {
+		bad = append(bad, err.Error())
+	}
+	all := strings.Join(bad, "\n")
+	for _, key := range []string{"MU_TEST_DUR", "MU_TEST_URL"} {
+		if !strings.Contains(all, key) {
+			t.Errorf("Validate missed %s: %s", key, all)
+		}
+	}
+	if strings.Contains(all, "MU_TEST_INT") {
+		t.Errorf("Validate flagged a good value: %s", all)
+	}
+	if !strings.Contains(all, "the environment") {
+		t.Errorf("Validate should say where a value was set: %s", all)
+	}
+
+	t.Setenv("MU_TEST_HIDDEN", "hunter2")
+	t.Setenv("MU_TEST_PLAIN_TOKEN", "abc")
+	t.Setenv("MU_TEST_BOOL", "true")
+	if got := Redact("MU_TEST_HIDDEN"); got == "hunter2" || got == "" {
+		t.Errorf("Redact secret = %q", got)
+	}
+	if got := Redact("MU_TEST_PLAIN_TOKEN"); got == "abc" {
+		t.Error("Redact shows a value named like a token")
+	}
+	if got := Redact("MU_TEST_BOOL"); got != "true" {
+		t.Errorf("Redact plain = %q, want true", got)
+	}
+}
@@ -0,0 +1,237 @@
+package settings
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/env"
+)
+
+// Each package declares the settings it reads, with Register from its
+// init: the type, default and whether the value is secret. That's what
+// the typed accessors fall back on, what Validate checks at startup and
+// what /admin/config and /admin/env list.
+
+// Type is how a setting's value is read.
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeInt
+	TypeBool
+	TypeDuration
+	TypeURL
+)
+
+func (t Type) String() string {
+	return [...]string{"string", "int", "bool", "duration", "url"}[t]
+}
+
+// Var declares a setting.
+type Var struct {
+	Key     string // environment variable name, e.g. MAIL_PORT
+	Type    Type
+	Default string // used when unset; "" for none
+	Secret  bool   // never shown, only whether it's set
+	Doc     string
+}
+
+// Group is a package's settings.
+type Group struct {
+	Name string
+	Vars []Var
+}
+
+var (
+	varsMu sync.RWMutex
+	groups = map[string][]Var{}
+	vars   = map[string]Var{}
+)
+
+// Register declares settings under a group name, such as "Mail".
+func Register(group string, vs ...Var) {
+	varsMu.Lock()
+	defer varsMu.Unlock()
+	for _, v := range vs {
+		if _, dup := vars[v.Key]; dup {
+			continue
+		}
+		vars[v.Key] = v
+		groups[group] = append(groups[group], v)
+	}
+}
+
+// Groups returns the registered settings, by group name.
+func Groups() []Group {
+	varsMu.RLock()
+	defer varsMu.RUnlock()
+	out := make([]Group, 0, len(groups))
+	for name, vs := range groups {
+		out = append(out, Group{Name: name, Vars: append([]Var{}, vs...)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Lookup returns the declaration of a setting.
+func Lookup(key string) (Var, bool) {
+	varsMu.RLock()
+	defer varsMu.RUnlock()
+	v, ok := vars[key]
+	return v, ok
+}
+
+// value returns a setting's value, or its default.
+func value(key string) string {
+	if v := strings.TrimSpace(Get(key)); v != "" {
+		return v
+	}
+	v, _ := Lookup(key)
+	return v.Default
+}
+
+// String returns a setting, or its default.
+func String(key string) string {
+	return value(key)
+}
+
+// Int returns an integer setting. A value that isn't a number gives
+// the default, and Validate reports it.
+func Int(key string) int {
+	n, err := strconv.Atoi(value(key))
+	if err != nil {
+		v, _ := Lookup(key)
+		n, _ = strconv.Atoi(v.Default)
+	}
+	return n
+}
+
+// Bool returns a boolean setting: true, 1, yes or on.
+func Bool(key string) bool {
+	b, err := parseBool(value(key))
+	if err != nil {
+		v, _ := Lookup(key)
+		b, _ = parseBool(v.Default)
+	}
+	return b
+}
+
+// Duration returns a duration setting, such as 24h.
+func Duration(key string) time.Duration {
+	d, err := time.ParseDuration(value(key))
+	if err != nil {
+		v, _ := Lookup(key)
+		d, _ = time.ParseDuration(v.Default)
+	}
+	return d
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "1", "yes", "on":
+		return true, nil
+	case "false", "0", "no", "off", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("not true or false")
+}
+
+// check reports why a value doesn't suit its type.
+func check(v Var, val string) error {
+	var err error
+	switch v.Type {
+	case TypeInt:
+		if _, e := strconv.Atoi(val); e != nil {
+			err = fmt.Errorf("%q is not a whole number", val)
+		}
+	case TypeBool:
+		if _, e := parseBool(val); e != nil {
+			err = fmt.Errorf("%q is not true or false", val)
+		}
+	case TypeDuration:
+		if _, e := time.ParseDuration(val); e != nil {
+			err = fmt.Errorf("%q is not a duration such as 30m or 24h", val)
+		}
+	case TypeURL:
+		if u, e := url.Parse(val); e != nil || u.Scheme == "" || u.Host == "" {
+			err = fmt.Errorf("%q is not a URL such as https://example.com", val)
+		}
+	}
+	return err
+}
+
+// Check reports why a value doesn't suit the setting, or nil.
+func Check(key, val string) error {
+	v, ok := Lookup(key)
+	if !ok || strings.TrimSpace(val) == "" {
+		return nil
+	}
+	if err := check(v, strings.TrimSpace(val)); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
+
+// Validate checks every registered setting that has a value, and the
+// config file, returning what's wrong with each and where it was set.
+func Validate() []error {
+	var errs []error
+	if err := env.ConfigError(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, g := range Groups() {
+		for _, v := range g.Vars {
+			err := Check(v.Key, Get(v.Key))
+			if err == nil {
+				continue
+			}
+			if where := Where(v.Key); where != "" {
+				err = fmt.Errorf("%v (set in %s)", err, where)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Where says where a setting's value comes from: the environment, the
+// file it was read from, or the settings saved in /admin/env.
+func Where(key string) string {
+	switch Source(key) {
+	case "file":
+		return env.File(key)
+	case "env":
+		return "the environment"
+	case "saved":
+		return "/admin/env"
+	}
+	return ""
+}
+
+// Redact shows whether a secret is set without giving it away.
+func Redact(key string) string {
+	val := Get(key)
+	if val == "" {
+		return ""
+	}
+	if !IsSecret(key) {
+		return val
+	}
+	return "••••••"
+}
+
+// IsSecret reports whether a setting is declared secret, or is named
+// like one that wasn't declared.
+func IsSecret(key string) bool {
+	if v, ok := Lookup(key); ok && v.Secret {
+		return true
+	}
+	k := strings.ToUpper(key)
+	return strings.Contains(k, "KEY") || strings.Contains(k, "SECRET") ||
+		strings.Contains(k, "TOKEN") || strings.Contains(k, "PASS")
+}
@@ -97,11 +97,7 @@ func applySetup(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
-	secure := r.Header.Get("X-Forwarded-Proto") == "https"
-	http.SetCookie(w, &http.Cookie{
-		Name: "session", Value: sess.Token, Path: "/", MaxAge: 2592000,
-		Secure: secure, HttpOnly: true, SameSite: http.SameSiteLaxMode,
-	})
+	app.SetSessionCookie(w, r, sess, false)
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
 
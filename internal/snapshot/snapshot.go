@@ -22,6 +22,7 @@ import (
 	"go-micro.dev/v6/store"
 
 	"mu/internal/app"
+	"mu/internal/data"
 	"mu/internal/service"
 )
 
@@ -61,13 +62,16 @@ func (s *Snapshot) handle(e broker.Event) (err error) {
 }
 
 // Publish records the snapshot to the store and announces it on the broker.
-// Best-effort: failures are logged, never block the caller's refresh.
+// Best-effort: failures are logged, never block the caller's refresh. While
+// the data store is read-only the snapshot is only announced.
 func (s *Snapshot) Publish(html string) {
 	if s == nil || html == "" {
 		return
 	}
-	if err := service.Store().Write(&store.Record{Key: s.key, Value: []byte(html)}); err != nil {
-		app.Log(s.name, "snapshot store write failed: %v", err)
+	if !data.ReadOnly() {
+		if err := service.Store().Write(&store.Record{Key: s.key, Value: []byte(html)}); err != nil {
+			app.Log(s.name, "snapshot store write failed: %v", err)
+		}
 	}
 	if err := service.Broker().Publish(s.topic, &broker.Message{Body: []byte(html)}); err != nil {
 		app.Log(s.name, "snapshot publish failed: %v", err)
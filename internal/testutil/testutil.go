@@ -0,0 +1,19 @@
+// Package testutil holds small helpers shared by package tests.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Fixture returns the contents of testdata/name, relative to the package
+// under test, failing the test if it can't be read.
+func Fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,334 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"mu/internal/app"
+	"mu/internal/data"
+)
+
+// Attachment is a file sent with a message. The metadata lives on the
+// Message in mail.json; the content is stored separately (encrypted like
+// message bodies) so the mail store stays small.
+type Attachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// Attachment limits per message.
+const (
+	MaxAttachments      = 5
+	MaxAttachmentSize   = 10 << 20 // per file
+	MaxAttachmentsTotal = 20 << 20 // all files combined
+)
+
+var (
+	ErrTooManyAttachments = fmt.Errorf("at most %d attachments per message", MaxAttachments)
+	ErrAttachmentTooLarge = fmt.Errorf("attachments are limited to %dMB each and %dMB in total", MaxAttachmentSize>>20, MaxAttachmentsTotal>>20)
+	ErrAttachmentType     = errors.New("this file type can't be attached")
+)
+
+// blockedExtensions are executable or script types that mail providers
+// routinely reject; sending them would get our mail flagged.
+var blockedExtensions = map[string]bool{
+	".exe": true, ".com": true, ".bat": true, ".cmd": true, ".scr": true,
+	".msi": true, ".dll": true, ".js": true, ".vbs": true, ".ps1": true,
+	".jar": true, ".hta": true, ".lnk": true, ".apk": true, ".sh": true,
+}
+
+// attachmentKey is the data store key for an attachment's content.
+func attachmentKey(id string) string {
+	return filepath.Join("mail", "attachments", id)
+}
+
+// cleanFilename reduces an uploaded filename to something safe to store,
+// display and put in a Content-Disposition header.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == '/' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." {
+		name = "attachment"
+	}
+	if len(name) > 100 {
+		ext := filepath.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = name[:100-len(ext)] + ext
+	}
+	return name
+}
+
+// SaveAttachment validates and stores file content, returning its metadata.
+func SaveAttachment(filename string, content []byte) (Attachment, error) {
+	filename = cleanFilename(filename)
+	ext := strings.ToLower(filepath.Ext(filename))
+	if blockedExtensions[ext] {
+		return Attachment{}, ErrAttachmentType
+	}
+	if len(content) > MaxAttachmentSize {
+		return Attachment{}, ErrAttachmentTooLarge
+	}
+
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	if ct, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = ct
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Attachment{}, err
+	}
+	att := Attachment{
+		ID:          hex.EncodeToString(b),
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+	}
+
+	stored, err := encrypt(string(content))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if err := data.SaveFile(attachmentKey(att.ID), stored); err != nil {
+		return Attachment{}, err
+	}
+	return att, nil
+}
+
+// LoadAttachment returns the content of a stored attachment.
+func LoadAttachment(id string) ([]byte, error) {
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return nil, errors.New("invalid attachment id")
+	}
+	b, err := data.LoadFile(attachmentKey(id))
+	if err != nil {
+		return nil, err
+	}
+	content, err := decrypt(string(b))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// deleteAttachments removes the stored content of the given attachments.
+func deleteAttachments(atts []Attachment) {
+	for _, a := range atts {
+		if err := data.DeleteFile(attachmentKey(a.ID)); err != nil {
+			app.Log("mail", "Failed to delete attachment %s: %v", a.ID, err)
+		}
+	}
+}
+
+// checkAttachmentLimits enforces the per-message count and size limits.
+func checkAttachmentLimits(count int, sizes []int64) error {
+	if count > MaxAttachments {
+		return ErrTooManyAttachments
+	}
+	var total int64
+	for _, s := range sizes {
+		if s > MaxAttachmentSize {
+			return ErrAttachmentTooLarge
+		}
+		total += s
+	}
+	if total > MaxAttachmentsTotal {
+		return ErrAttachmentTooLarge
+	}
+	return nil
+}
+
+// attachmentsFromForm stores the files uploaded in the "attachments" field
+// of a multipart compose form. Nothing is stored if any file is rejected.
+func attachmentsFromForm(r *http.Request) ([]Attachment, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+	var files []*multipart.FileHeader
+	for _, fh := range r.MultipartForm.File["attachments"] {
+		if fh.Filename != "" && fh.Size > 0 {
+			files = append(files, fh)
+		}
+	}
+	sizes := make([]int64, len(files))
+	for i, fh := range files {
+		sizes[i] = fh.Size
+	}
+	if err := checkAttachmentLimits(len(files), sizes); err != nil {
+		return nil, err
+	}
+
+	var atts []Attachment
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			deleteAttachments(atts)
+			return nil, err
+		}
+		content, err := io.ReadAll(io.LimitReader(f, MaxAttachmentSize+1))
+		f.Close()
+		if err != nil {
+			deleteAttachments(atts)
+			return nil, err
+		}
+		att, err := SaveAttachment(fh.Filename, content)
+		if err != nil {
+			deleteAttachments(atts)
+			return nil, err
+		}
+		atts = append(atts, att)
+	}
+	return atts, nil
+}
+
+// AttachmentUpload is an attachment in a JSON send request.
+type AttachmentUpload struct {
+	Filename string `json:"filename"`
+	Data     string `json:"data"` // base64
+}
+
+// attachmentsFromJSON stores base64-encoded attachments from an API request.
+func attachmentsFromJSON(uploads []AttachmentUpload) ([]Attachment, error) {
+	contents := make([][]byte, len(uploads))
+	sizes := make([]int64, len(uploads))
+	for i, u := range uploads {
+		b, err := base64.StdEncoding.DecodeString(u.Data)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %q is not valid base64", u.Filename)
+		}
+		contents[i] = b
+		sizes[i] = int64(len(b))
+	}
+	if err := checkAttachmentLimits(len(uploads), sizes); err != nil {
+		return nil, err
+	}
+
+	var atts []Attachment
+	for i, u := range uploads {
+		att, err := SaveAttachment(u.Filename, contents[i])
+		if err != nil {
+			deleteAttachments(atts)
+			return nil, err
+		}
+		atts = append(atts, att)
+	}
+	return atts, nil
+}
+
+// findAttachment returns an attachment on a message the user sent or
+// received. Attachment IDs are random, but access is still checked against
+// the message so a leaked link is useless to anyone else.
+func findAttachment(msgID, attID, userID string) (Attachment, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	m := GetMessageUnlocked(msgID)
+	if m == nil || (m.ToID != userID && m.FromID != userID) {
+		return Attachment{}, false
+	}
+	for _, a := range m.Attachments {
+		if a.ID == attID {
+			return a, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// serveAttachment writes an attachment as a download. It is always sent
+// with Content-Disposition: attachment and a sandbox CSP so uploaded HTML
+// or SVG can never run in the site's origin.
+func serveAttachment(w http.ResponseWriter, r *http.Request, userID string) {
+	att, ok := findAttachment(r.URL.Query().Get("msg_id"), r.URL.Query().Get("att"), userID)
+	if !ok {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+	content, err := LoadAttachment(att.ID)
+	if err != nil {
+		app.Log("mail", "Failed to load attachment %s: %v", att.ID, err)
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(content)
+}
+
+// formatAttachmentSize renders a byte count for display.
+func formatAttachmentSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// renderAttachments renders download links for a message's attachments.
+func renderAttachments(m *Message) string {
+	if len(m.Attachments) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<div class="mail-attachments mt-3">`)
+	for _, a := range m.Attachments {
+		sb.WriteString(fmt.Sprintf(`<div class="text-sm">📎 <a href="/mail?action=attachment&msg_id=%s&att=%s">%s</a> <span class="text-muted">%s</span></div>`,
+			m.ID, a.ID, html.EscapeString(a.Filename), formatAttachmentSize(a.Size)))
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+// writeAttachmentParts writes each attachment as a base64 MIME part of a
+// multipart/mixed message.
+func writeAttachmentParts(w io.Writer, boundary string, atts []Attachment) error {
+	for _, a := range atts {
+		content, err := LoadAttachment(a.ID)
+		if err != nil {
+			return fmt.Errorf("attachment %s: %v", a.Filename, err)
+		}
+		name := mime.QEncoding.Encode("utf-8", a.Filename)
+		fmt.Fprintf(w, "--%s\r\n", boundary)
+		fmt.Fprintf(w, "Content-Type: %s; name=\"%s\"\r\n", a.ContentType, name)
+		w.Write([]byte("Content-Transfer-Encoding: base64\r\n"))
+		fmt.Fprintf(w, "Content-Disposition: %s\r\n", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		w.Write([]byte("\r\n"))
+		enc := base64.StdEncoding.EncodeToString(content)
+		for len(enc) > 76 {
+			fmt.Fprintf(w, "%s\r\n", enc[:76])
+			enc = enc[76:]
+		}
+		fmt.Fprintf(w, "%s\r\n", enc)
+	}
+	return nil
+}
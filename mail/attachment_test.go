@@ -0,0 +1,108 @@
+package mail
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSaveAttachmentRoundTrip(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+
+	att, err := SaveAttachment("../../etc/report.pdf", []byte("%PDF-1.4 hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if att.Filename != "report.pdf" {
+		t.Errorf("filename not cleaned: %q", att.Filename)
+	}
+	if att.ContentType != "application/pdf" {
+		t.Errorf("content type = %q", att.ContentType)
+	}
+	got, err := LoadAttachment(att.ID)
+	if err != nil || string(got) != "%PDF-1.4 hello" {
+		t.Fatalf("LoadAttachment = %q, %v", got, err)
+	}
+
+	deleteAttachments([]Attachment{att})
+	if _, err := LoadAttachment(att.ID); err == nil {
+		t.Error("attachment should be gone after delete")
+	}
+	if _, err := LoadAttachment("../mail.json"); err == nil {
+		t.Error("non-hex id must be rejected")
+	}
+}
+
+func TestAttachmentLimits(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+
+	if _, err := SaveAttachment("setup.exe", []byte("MZ")); err != ErrAttachmentType {
+		t.Errorf("exe: got %v, want ErrAttachmentType", err)
+	}
+	if _, err := SaveAttachment("big.bin", make([]byte, MaxAttachmentSize+1)); err != ErrAttachmentTooLarge {
+		t.Errorf("oversize: got %v, want ErrAttachmentTooLarge", err)
+	}
+	if err := checkAttachmentLimits(MaxAttachments+1, nil); err != ErrTooManyAttachments {
+		t.Errorf("count: got %v, want ErrTooManyAttachments", err)
+	}
+	if err := checkAttachmentLimits(3, []int64{MaxAttachmentSize, MaxAttachmentSize, MaxAttachmentSize}); err != ErrAttachmentTooLarge {
+		t.Errorf("total: got %v, want ErrAttachmentTooLarge", err)
+	}
+}
+
+func TestServeAttachmentAccess(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	att, err := SaveAttachment("notes.html", []byte("<script>alert(1)</script>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	prev := messages
+	messages = []*Message{{ID: "m1", FromID: "alice", ToID: "bob", Attachments: []Attachment{att}}}
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		messages = prev
+		mutex.Unlock()
+	})
+
+	url := "/mail?action=attachment&msg_id=m1&att=" + att.ID
+
+	w := httptest.NewRecorder()
+	serveAttachment(w, httptest.NewRequest("GET", url, nil), "bob")
+	if w.Code != 200 || w.Body.String() != "<script>alert(1)</script>" {
+		t.Fatalf("recipient download failed: %d %q", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") || !strings.Contains(cd, "notes.html") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if w.Header().Get("Content-Security-Policy") != "sandbox" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("missing hardening headers")
+	}
+
+	w = httptest.NewRecorder()
+	serveAttachment(w, httptest.NewRequest("GET", url, nil), "mallory")
+	if w.Code != 404 {
+		t.Errorf("non-participant got %d, want 404", w.Code)
+	}
+}
+
+func TestWriteAttachmentParts(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	att, err := SaveAttachment("hello.txt", []byte("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := writeAttachmentParts(&sb, "B", []Attachment{att}); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{"--B\r\n", "Content-Transfer-Encoding: base64", `filename=hello.txt`, "aGVsbG8gd29ybGQ="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("part missing %q:\n%s", want, out)
+		}
+	}
+}
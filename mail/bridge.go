@@ -0,0 +1,275 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/chat"
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// Chat and mail bridging. A mail thread can be read and answered as a
+// chat at /mail/chat?thread=<id>: each line typed there is sent as an
+// ordinary reply, so the thread stays the one record of the
+// conversation whichever view is used. Going the other way, a chat
+// conversation can be sent into mail, as a new message or a reply on an
+// existing thread, at /mail/chat/forward?room=<id>.
+
+// MaxChatReply caps a reply typed in the chat view.
+const MaxChatReply = 10000
+
+// ChatLine is one message of a thread as shown in the chat view.
+type ChatLine struct {
+	ID   string    `json:"id"`
+	From string    `json:"from"`
+	Mine bool      `json:"mine"`
+	Body string    `json:"body"`
+	Time time.Time `json:"time"`
+}
+
+// threadCounterpart returns who replies in a thread go to: whoever last
+// wrote to the user, or the recipient if the user wrote everything.
+func threadCounterpart(userID string, thread []*Message) string {
+	for i := len(thread) - 1; i >= 0; i-- {
+		if thread[i].FromID != userID {
+			return thread[i].FromID
+		}
+	}
+	if len(thread) > 0 {
+		return thread[0].ToID
+	}
+	return ""
+}
+
+// replySubject prefixes a thread subject with "Re:" once.
+func replySubject(subject string) string {
+	subject = decodeMIMEHeader(subject)
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// chatLines renders a thread's messages as plain chat lines.
+func chatLines(userID string, thread []*Message) []ChatLine {
+	lines := make([]ChatLine, 0, len(thread))
+	for _, m := range thread {
+		lines = append(lines, ChatLine{
+			ID:   m.ID,
+			From: m.From,
+			Mine: m.FromID == userID,
+			Body: strings.TrimSpace(stripHTMLTags(m.Body)),
+			Time: m.CreatedAt,
+		})
+	}
+	return lines
+}
+
+// ReplyInThread sends body as the user's reply on a thread, to the other
+// party, with the usual credit checks.
+func ReplyInThread(userID, threadID, body string) error {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return errors.New("message is empty")
+	}
+	if len(body) > MaxChatReply {
+		return fmt.Errorf("message is longer than %d characters", MaxChatReply)
+	}
+	thread := threadMessages(userID, threadID)
+	if len(thread) == 0 {
+		return errors.New("thread not found")
+	}
+	to := threadCounterpart(userID, thread)
+	if to == "" {
+		return errors.New("no one to reply to")
+	}
+	return sendScheduled(&Scheduled{
+		UserID:  userID,
+		To:      to,
+		Subject: replySubject(thread[0].Subject),
+		Body:    body,
+		ReplyTo: thread[len(thread)-1].ID,
+	})
+}
+
+// ChatHandler serves /mail/chat?thread=<id>: GET shows the thread as a
+// chat (JSON when requested), POST {"thread","body"} replies.
+func ChatHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	if r.URL.Path == "/mail/chat/forward" {
+		forwardChat(w, r, acc)
+		return
+	}
+
+	threadID := r.URL.Query().Get("thread")
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var body string
+		if app.SendsJSON(r) {
+			var req struct {
+				Thread string `json:"thread"`
+				Body   string `json:"body"`
+			}
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+			if req.Thread != "" {
+				threadID = req.Thread
+			}
+			body = req.Body
+		} else {
+			r.ParseForm()
+			if t := r.FormValue("thread"); t != "" {
+				threadID = t
+			}
+			body = r.FormValue("body")
+		}
+		if err := ReplyInThread(acc.ID, threadID, body); err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"success": true})
+			return
+		}
+		http.Redirect(w, r, "/mail/chat?thread="+threadID+"#end", http.StatusSeeOther)
+		return
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	thread := threadMessages(acc.ID, threadID)
+	if len(thread) == 0 {
+		app.NotFound(w, r, "Thread not found")
+		return
+	}
+	with := threadCounterpart(acc.ID, thread)
+	lines := chatLines(acc.ID, thread)
+
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{
+			"thread":   threadID,
+			"subject":  decodeMIMEHeader(thread[0].Subject),
+			"with":     with,
+			"messages": lines,
+		})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<div class="text-muted text-sm mb-4">Chat with %s · <a href="/mail?id=%s">Open as mail</a></div>`,
+		html.EscapeString(with), html.EscapeString(thread[len(thread)-1].ID)))
+	sb.WriteString(`<div class="mail-chat">`)
+	for _, l := range lines {
+		class := "mail-chat-line"
+		if l.Mine {
+			class += " mine"
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="%s"><div class="mail-chat-bubble">%s</div><div class="text-xs text-muted">%s · %s</div></div>`,
+			class, strings.ReplaceAll(html.EscapeString(l.Body), "\n", "<br>"), html.EscapeString(l.From), app.TimeAgo(l.Time)))
+	}
+	sb.WriteString(`</div>`)
+	sb.WriteString(fmt.Sprintf(`<form id="end" method="POST" action="/mail/chat" class="mail-chat-form">
+		<input type="hidden" name="thread" value="%s">
+		<textarea name="body" data-mentions rows="2" placeholder="Reply to %s" required></textarea>
+		<button type="submit">Send</button>
+	</form>`, html.EscapeString(threadID), html.EscapeString(with)))
+	sb.WriteString(`<p class="mt-5"><a href="/mail" class="text-muted">← Back to mail</a></p>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest(decodeMIMEHeader(thread[0].Subject), "", sb.String(), r)))
+}
+
+// chatTranscript formats room messages as a plain-text mail body.
+func chatTranscript(title string, msgs []chat.RoomMessage) string {
+	var sb strings.Builder
+	if title != "" {
+		sb.WriteString("Chat: " + title + "\n\n")
+	}
+	for _, m := range msgs {
+		who := m.UserID
+		if m.IsLLM {
+			who = "AI"
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", m.Timestamp.Format("2 Jan 15:04"), who, m.Content))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// forwardChat serves /mail/chat/forward?room=<id>. GET shows the
+// transcript with a form; POST sends it to "to" as a new message, or as
+// a reply on "thread".
+func forwardChat(w http.ResponseWriter, r *http.Request, acc *auth.Account) {
+	r.ParseForm()
+	roomID := r.FormValue("room")
+	title, msgs := chat.RoomTranscript(roomID)
+	if roomID == "" || len(msgs) == 0 {
+		app.NotFound(w, r, "Nothing to forward from that chat")
+		return
+	}
+	transcript := chatTranscript(title, msgs)
+
+	if r.Method == "POST" {
+		var err error
+		if thread := r.FormValue("thread"); thread != "" {
+			err = ReplyInThread(acc.ID, thread, transcript)
+		} else {
+			to := strings.TrimSpace(r.FormValue("to"))
+			subject := strings.TrimSpace(r.FormValue("subject"))
+			if subject == "" {
+				subject = "Chat: " + title
+			}
+			if to == "" {
+				err = errors.New("choose who to send it to")
+			} else {
+				err = sendScheduled(&Scheduled{UserID: acc.ID, To: to, Subject: subject, Body: transcript})
+			}
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"success": true})
+			return
+		}
+		http.Redirect(w, r, "/mail?view=sent", http.StatusSeeOther)
+		return
+	}
+
+	var threads strings.Builder
+	threads.WriteString(`<option value="">New message</option>`)
+	mutex.RLock()
+	inbox := inboxes[acc.ID]
+	mutex.RUnlock()
+	if inbox != nil {
+		for i, t := range sortedThreads(inbox, nil) {
+			if i >= 20 {
+				break
+			}
+			threads.WriteString(fmt.Sprintf(`<option value="%s">%s</option>`,
+				html.EscapeString(t.Root.ThreadID), html.EscapeString(decodeMIMEHeader(t.Root.Subject))))
+		}
+	}
+
+	body := fmt.Sprintf(`<form method="POST" action="/mail/chat/forward" class="block-form">
+		<input type="hidden" name="room" value="%s">
+		<label>Add to thread <select name="thread">%s</select></label>
+		<input name="to" placeholder="To (username or email) — for a new message">
+		<input name="subject" placeholder="Subject" value="%s">
+		<pre class="mail-chat-transcript">%s</pre>
+		<button type="submit">Send to mail</button>
+	</form>`, html.EscapeString(roomID), threads.String(), html.EscapeString("Chat: "+title), html.EscapeString(transcript))
+	w.Write([]byte(app.RenderHTMLForRequest("Send chat to mail", "", body, r)))
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mu/chat"
+)
+
+func TestThreadAsChat(t *testing.T) {
+	now := time.Now()
+	thread := []*Message{
+		{ID: "1", From: "alice", FromID: "alice", To: "bob", ToID: "bob", Subject: "Trip", Body: "<p>Shall we go?</p>", CreatedAt: now.Add(-time.Hour)},
+		{ID: "2", From: "bob", FromID: "bob", To: "alice", ToID: "alice", Subject: "Re: Trip", Body: "Yes", CreatedAt: now},
+	}
+	if got := threadCounterpart("alice", thread); got != "bob" {
+		t.Errorf("counterpart = %q", got)
+	}
+	if got := threadCounterpart("alice", thread[:1]); got != "bob" {
+		t.Errorf("counterpart of own thread = %q", got)
+	}
+	lines := chatLines("alice", thread)
+	if len(lines) != 2 || !lines[0].Mine || lines[1].Mine || lines[0].Body != "Shall we go?" {
+		t.Fatalf("lines = %+v", lines)
+	}
+	if replySubject("Trip") != "Re: Trip" || replySubject("RE: Trip") != "RE: Trip" {
+		t.Error("reply subject prefixed wrongly")
+	}
+}
+
+func TestChatTranscript(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	got := chatTranscript("Dev", []chat.RoomMessage{
+		{UserID: "alice", Content: "anyone tried it?", Timestamp: ts},
+		{UserID: "micro", Content: "Yes.", Timestamp: ts, IsLLM: true},
+	})
+	want := "Chat: Dev\n\n[1 Mar 09:30] alice: anyone tried it?\n[1 Mar 09:30] AI: Yes."
+	if strings.TrimSpace(got) != want {
+		t.Errorf("transcript =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -97,6 +97,13 @@ func LoadDKIMConfig(domain, selector string) error {
 // Sends multipart/alternative with both plain text and HTML versions (like Gmail)
 // Returns the generated Message-ID for threading purposes
 func SendExternalEmail(displayName, from, to, subject, bodyPlain, bodyHTML string, replyToMsgID string) (string, error) {
+	return SendExternalEmailWithAttachments(displayName, from, to, subject, bodyPlain, bodyHTML, replyToMsgID, nil)
+}
+
+// SendExternalEmailWithAttachments is SendExternalEmail with files attached.
+// With attachments the message becomes multipart/mixed, wrapping the usual
+// multipart/alternative text and HTML bodies.
+func SendExternalEmailWithAttachments(displayName, from, to, subject, bodyPlain, bodyHTML string, replyToMsgID string, attachments []Attachment) (string, error) {
 	// Extract username from email for Message-ID
 	username := from
 	if strings.Contains(from, "@") {
@@ -129,6 +136,12 @@ func SendExternalEmail(displayName, from, to, subject, bodyPlain, bodyHTML strin
 	}
 
 	msg.WriteString("MIME-Version: 1.0\r\n")
+	mixedBoundary := fmt.Sprintf("----=_Mixed_%d", time.Now().UnixNano())
+	if len(attachments) > 0 {
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\r\n", mixedBoundary))
+		msg.WriteString("\r\n")
+		msg.WriteString(fmt.Sprintf("--%s\r\n", mixedBoundary))
+	}
 	msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
 	msg.WriteString("\r\n")
 
@@ -173,6 +186,15 @@ func SendExternalEmail(displayName, from, to, subject, bodyPlain, bodyHTML strin
 	// End boundary
 	msg.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
 
+	// Attachments follow the text/HTML alternatives
+	if len(attachments) > 0 {
+		msg.WriteString("\r\n")
+		if err := writeAttachmentParts(&msg, mixedBoundary, attachments); err != nil {
+			return "", err
+		}
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", mixedBoundary))
+	}
+
 	message := msg.Bytes()
 
 	// Apply DKIM signing if configured
@@ -6,10 +6,12 @@ import (
 	"compress/gzip"
 	"encoding/xml"
 	"fmt"
+	"html"
 	"io"
 	"strings"
 
 	"mu/internal/app"
+	"mu/internal/egress"
 )
 
 // extractZipContents extracts all files from a ZIP archive and returns their contents as a string
@@ -243,12 +245,64 @@ type SPFResult struct {
 	Result string `xml:"result"`
 }
 
-// renderDMARCReport parses DMARC XML and renders it as HTML tables
+// dmarcFormats maps the namespace of a report's root element to the
+// version of the aggregate report format: none for RFC 7489, or the
+// DMARCbis namespace. The structures above read both.
+var dmarcFormats = map[string]string{
+	"":                                 "rfc7489",
+	"urn:ietf:params:xml:ns:dmarc-2.0": "dmarc-2.0",
+}
+
+// parseDMARCReport parses an aggregate report and returns it with its
+// format version. Text that isn't a DMARC report at all is an error; a
+// report in a shape we don't know is drift.
+func parseDMARCReport(data []byte) (*DMARCReport, string, error) {
+	root, err := xmlRoot(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if root.Local != "feedback" {
+		return nil, "", fmt.Errorf("not a DMARC report: root element is <%s>", root.Local)
+	}
+	version, ok := dmarcFormats[root.Space]
+	if !ok {
+		return nil, "", egress.Drift("dmarc", "unknown namespace %q", root.Space)
+	}
+
+	var report DMARCReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, version, egress.Drift("dmarc", "%v", err)
+	}
+	if report.ReportMetadata.OrgName == "" && report.ReportMetadata.ReportID == "" {
+		return nil, version, egress.Drift("dmarc", "no report_metadata")
+	}
+	if report.PolicyPublished.Domain == "" {
+		return nil, version, egress.Drift("dmarc", "no policy_published domain")
+	}
+	return &report, version, nil
+}
+
+// xmlRoot returns the name of the root element of an XML document.
+func xmlRoot(data []byte) (xml.Name, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("not XML: %w", err)
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			return el.Name, nil
+		}
+	}
+}
+
+// renderDMARCReport parses DMARC XML and renders it as HTML tables, with
+// every value from the report escaped
 func renderDMARCReport(xmlData string) string {
 	app.Log("mail", "renderDMARCReport called with %d bytes, first 200 chars: %s", len(xmlData), xmlData[:min(200, len(xmlData))])
 
-	var report DMARCReport
-	if err := xml.Unmarshal([]byte(xmlData), &report); err != nil {
+	report, version, err := parseDMARCReport([]byte(xmlData))
+	if err := egress.CheckFormat("dmarc", version, err); err != nil {
 		// Not a DMARC report or invalid XML - return empty to fall back to raw display
 		app.Log("mail", "Failed to parse as DMARC report: %v", err)
 		return ""
@@ -256,28 +310,28 @@ func renderDMARCReport(xmlData string) string {
 
 	app.Log("mail", "Successfully parsed DMARC report from %s", report.ReportMetadata.OrgName)
 
-	var html strings.Builder
+	var b strings.Builder
 
 	// Report metadata
-	html.WriteString(`<div style="margin-bottom: 20px;">`)
-	html.WriteString(fmt.Sprintf(`<h4 style="margin: 0 0 10px 0;">DMARC Report from %s</h4>`, report.ReportMetadata.OrgName))
-	html.WriteString(`<table style="border-collapse: collapse; width: 100%; font-size: 13px;">`)
-	html.WriteString(fmt.Sprintf(`<tr><td style="padding: 4px 8px; background: #f5f5f5;"><strong>Report ID:</strong></td><td style="padding: 4px 8px;">%s</td></tr>`, report.ReportMetadata.ReportID))
-	html.WriteString(fmt.Sprintf(`<tr><td style="padding: 4px 8px; background: #f5f5f5;"><strong>Domain:</strong></td><td style="padding: 4px 8px;">%s</td></tr>`, report.PolicyPublished.Domain))
-	html.WriteString(fmt.Sprintf(`<tr><td style="padding: 4px 8px; background: #f5f5f5;"><strong>Policy:</strong></td><td style="padding: 4px 8px;">%s</td></tr>`, report.PolicyPublished.P))
-	html.WriteString(`</table></div>`)
+	b.WriteString(`<div style="margin-bottom: 20px;">`)
+	b.WriteString(fmt.Sprintf(`<h4 style="margin: 0 0 10px 0;">DMARC Report from %s</h4>`, html.EscapeString(report.ReportMetadata.OrgName)))
+	b.WriteString(`<table style="border-collapse: collapse; width: 100%; font-size: 13px;">`)
+	b.WriteString(fmt.Sprintf(`<tr><td style="padding: 4px 8px; background: #f5f5f5;"><strong>Report ID:</strong></td><td style="padding: 4px 8px;">%s</td></tr>`, html.EscapeString(report.ReportMetadata.ReportID)))
+	b.WriteString(fmt.Sprintf(`<tr><td style="padding: 4px 8px; background: #f5f5f5;"><strong>Domain:</strong></td><td style="padding: 4px 8px;">%s</td></tr>`, html.EscapeString(report.PolicyPublished.Domain)))
+	b.WriteString(fmt.Sprintf(`<tr><td style="padding: 4px 8px; background: #f5f5f5;"><strong>Policy:</strong></td><td style="padding: 4px 8px;">%s</td></tr>`, html.EscapeString(report.PolicyPublished.P)))
+	b.WriteString(`</table></div>`)
 
 	// Records table
 	if len(report.Records) > 0 {
-		html.WriteString(`<h4 style="margin: 0 0 10px 0;">Email Results</h4>`)
-		html.WriteString(`<table style="border-collapse: collapse; width: 100%; font-size: 12px; border: 1px solid #ddd;">`)
-		html.WriteString(`<thead><tr style="background: #f5f5f5;">`)
-		html.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">Source IP</th>`)
-		html.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">Count</th>`)
-		html.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">DKIM</th>`)
-		html.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">SPF</th>`)
-		html.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">Disposition</th>`)
-		html.WriteString(`</tr></thead><tbody>`)
+		b.WriteString(`<h4 style="margin: 0 0 10px 0;">Email Results</h4>`)
+		b.WriteString(`<table style="border-collapse: collapse; width: 100%; font-size: 12px; border: 1px solid #ddd;">`)
+		b.WriteString(`<thead><tr style="background: #f5f5f5;">`)
+		b.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">Source IP</th>`)
+		b.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">Count</th>`)
+		b.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">DKIM</th>`)
+		b.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">SPF</th>`)
+		b.WriteString(`<th style="padding: 8px; text-align: left; border: 1px solid #ddd;">Disposition</th>`)
+		b.WriteString(`</tr></thead><tbody>`)
 
 		for _, record := range report.Records {
 			dkimResult := "none"
@@ -299,19 +353,19 @@ func renderDMARCReport(xmlData string) string {
 				spfColor = "#f8d7da"
 			}
 
-			html.WriteString(`<tr>`)
-			html.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd;">%s</td>`, record.Row.SourceIP))
-			html.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd;">%d</td>`, record.Row.Count))
-			html.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd; background: %s;">%s</td>`, dkimColor, dkimResult))
-			html.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd; background: %s;">%s</td>`, spfColor, spfResult))
-			html.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd;">%s</td>`, record.Row.PolicyEvaluated.Disposition))
-			html.WriteString(`</tr>`)
+			b.WriteString(`<tr>`)
+			b.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd;">%s</td>`, html.EscapeString(record.Row.SourceIP)))
+			b.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd;">%d</td>`, record.Row.Count))
+			b.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd; background: %s;">%s</td>`, dkimColor, html.EscapeString(dkimResult)))
+			b.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd; background: %s;">%s</td>`, spfColor, html.EscapeString(spfResult)))
+			b.WriteString(fmt.Sprintf(`<td style="padding: 8px; border: 1px solid #ddd;">%s</td>`, html.EscapeString(record.Row.PolicyEvaluated.Disposition)))
+			b.WriteString(`</tr>`)
 		}
 
-		html.WriteString(`</tbody></table>`)
+		b.WriteString(`</tbody></table>`)
 	}
 
-	result := html.String()
+	result := b.String()
 	app.Log("mail", "renderDMARCReport returning %d bytes of HTML", len(result))
 	return result
 }
@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+
+	"mu/internal/egress"
+	"mu/internal/testutil"
+)
+
+func TestParseDMARCReport_Fixtures(t *testing.T) {
+	tests := []struct {
+		file    string
+		version string
+		org     string
+		policy  string
+		records int
+	}{
+		{"dmarc_google.xml", "rfc7489", "google.com", "quarantine", 2},
+		{"dmarc_bis.xml", "dmarc-2.0", "Enterprise Outlook", "reject", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			report, version, err := parseDMARCReport(testutil.Fixture(t, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != tt.version {
+				t.Errorf("version = %q, want %q", version, tt.version)
+			}
+			if report.ReportMetadata.OrgName != tt.org || report.PolicyPublished.P != tt.policy || report.PolicyPublished.Domain != "example.org" {
+				t.Errorf("report = %+v", report)
+			}
+			if len(report.Records) != tt.records {
+				t.Fatalf("%d records, want %d", len(report.Records), tt.records)
+			}
+			r := report.Records[0]
+			if r.Row.SourceIP != "203.0.113.25" || r.AuthResults.DKIM[0].Result != "pass" || r.AuthResults.SPF[0].Result != "pass" {
+				t.Errorf("first record = %+v", r)
+			}
+		})
+	}
+}
+
+func TestParseDMARCReport_Errors(t *testing.T) {
+	notReports := map[string]string{
+		"text": "Your mailbox is almost full.",
+		"html": "<html><body>Hello</body></html>",
+	}
+	for name, body := range notReports {
+		if _, _, err := parseDMARCReport([]byte(body)); err == nil || egress.IsDrift(err) {
+			t.Errorf("%s: err = %v, want an error that isn't drift", name, err)
+		}
+	}
+
+	drift := map[string]string{
+		"unknown namespace": `<feedback xmlns="urn:ietf:params:xml:ns:dmarc-3.0"><report_metadata><org_name>x</org_name></report_metadata></feedback>`,
+		"no metadata":       `<feedback><policy_published><domain>example.org</domain></policy_published></feedback>`,
+		"no policy":         `<feedback><report_metadata><org_name>x</org_name></report_metadata></feedback>`,
+		"count not numeric": `<feedback><report_metadata><org_name>x</org_name></report_metadata><policy_published><domain>example.org</domain></policy_published><record><row><count>many</count></row></record></feedback>`,
+	}
+	for name, body := range drift {
+		if _, _, err := parseDMARCReport([]byte(body)); !egress.IsDrift(err) {
+			t.Errorf("%s: err = %v, want drift", name, err)
+		}
+	}
+}
+
+func TestRenderDMARCReport_Escapes(t *testing.T) {
+	xml := strings.Replace(string(testutil.Fixture(t, "dmarc_google.xml")),
+		"<org_name>google.com</org_name>", "<org_name>&lt;script&gt;alert(1)&lt;/script&gt;</org_name>", 1)
+	out := renderDMARCReport(xml)
+	if out == "" {
+		t.Fatal("report not rendered")
+	}
+	if strings.Contains(out, "<script>") {
+		t.Error("org name rendered unescaped")
+	}
+	if !strings.Contains(out, "198.51.100.7") || !strings.Contains(out, "softfail") {
+		t.Error("records missing from the rendered report")
+	}
+}
@@ -0,0 +1,328 @@
+package mail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Draft is an unsent message, autosaved from the compose form so a long
+// message survives a closed tab. Drafts are stored in drafts.json with the
+// same field encryption as messages.
+type Draft struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	ReplyTo   string    `json:"reply_to,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Draft limits per account.
+const (
+	MaxDrafts      = 50
+	MaxDraftLength = 100 << 10
+)
+
+var (
+	ErrTooManyDrafts = fmt.Errorf("at most %d drafts; send or delete some first", MaxDrafts)
+	ErrDraftTooLarge = errors.New("draft is too large")
+	ErrDraftNotFound = errors.New("draft not found")
+)
+
+var (
+	draftsMu sync.RWMutex
+	drafts   = map[string][]*Draft{} // userID -> drafts
+)
+
+func loadDrafts() {
+	b, err := data.LoadFile("drafts.json")
+	if err != nil {
+		return
+	}
+	var stored map[string][]*Draft
+	if err := json.Unmarshal(b, &stored); err != nil {
+		app.Log("mail", "Failed to parse drafts: %v", err)
+		return
+	}
+	for _, list := range stored {
+		for _, d := range list {
+			var err error
+			if d.To, err = decrypt(d.To); err == nil {
+				if d.Subject, err = decrypt(d.Subject); err == nil {
+					d.Body, err = decrypt(d.Body)
+				}
+			}
+			if err != nil {
+				app.Log("mail", "WARNING: Failed to decrypt draft %s: %v", d.ID, err)
+			}
+		}
+	}
+	draftsMu.Lock()
+	drafts = stored
+	draftsMu.Unlock()
+}
+
+// saveDrafts persists every draft. Caller must hold draftsMu.
+func saveDrafts() error {
+	stored := make(map[string][]*Draft, len(drafts))
+	for user, list := range drafts {
+		out := make([]*Draft, len(list))
+		for i, d := range list {
+			cp := *d
+			var err error
+			if cp.To, err = encrypt(cp.To); err != nil {
+				return err
+			}
+			if cp.Subject, err = encrypt(cp.Subject); err != nil {
+				return err
+			}
+			if cp.Body, err = encrypt(cp.Body); err != nil {
+				return err
+			}
+			out[i] = &cp
+		}
+		stored[user] = out
+	}
+	return data.SaveJSON("drafts.json", stored)
+}
+
+// SaveDraft creates or updates one of the user's drafts. An empty or
+// unknown ID creates a new draft.
+func SaveDraft(userID string, d Draft) (*Draft, error) {
+	if len(d.To)+len(d.Subject)+len(d.Body) > MaxDraftLength {
+		return nil, ErrDraftTooLarge
+	}
+	draftsMu.Lock()
+	defer draftsMu.Unlock()
+
+	now := time.Now()
+	for _, existing := range drafts[userID] {
+		if d.ID != "" && existing.ID == d.ID {
+			existing.To = d.To
+			existing.Subject = d.Subject
+			existing.Body = d.Body
+			existing.ReplyTo = d.ReplyTo
+			existing.UpdatedAt = now
+			cp := *existing
+			return &cp, saveDrafts()
+		}
+	}
+
+	if len(drafts[userID]) >= MaxDrafts {
+		return nil, ErrTooManyDrafts
+	}
+	nd := &Draft{
+		ID:        fmt.Sprintf("%d", now.UnixNano()),
+		UserID:    userID,
+		To:        d.To,
+		Subject:   d.Subject,
+		Body:      d.Body,
+		ReplyTo:   d.ReplyTo,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	drafts[userID] = append(drafts[userID], nd)
+	cp := *nd
+	return &cp, saveDrafts()
+}
+
+// GetDrafts returns the user's drafts, most recently edited first.
+func GetDrafts(userID string) []*Draft {
+	draftsMu.RLock()
+	defer draftsMu.RUnlock()
+	out := make([]*Draft, len(drafts[userID]))
+	for i, d := range drafts[userID] {
+		cp := *d
+		out[i] = &cp
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out
+}
+
+// GetDraft returns one of the user's drafts, or nil.
+func GetDraft(userID, id string) *Draft {
+	draftsMu.RLock()
+	defer draftsMu.RUnlock()
+	for _, d := range drafts[userID] {
+		if d.ID == id {
+			cp := *d
+			return &cp
+		}
+	}
+	return nil
+}
+
+// DeleteDraft removes one of the user's drafts.
+func DeleteDraft(userID, id string) error {
+	draftsMu.Lock()
+	defer draftsMu.Unlock()
+	list := drafts[userID]
+	for i, d := range list {
+		if d.ID == id {
+			drafts[userID] = append(list[:i:i], list[i+1:]...)
+			if len(drafts[userID]) == 0 {
+				delete(drafts, userID)
+			}
+			return saveDrafts()
+		}
+	}
+	return ErrDraftNotFound
+}
+
+// DeleteUserDrafts removes all of a user's drafts. Called when an account
+// is deleted.
+func DeleteUserDrafts(userID string) {
+	draftsMu.Lock()
+	defer draftsMu.Unlock()
+	if _, ok := drafts[userID]; !ok {
+		return
+	}
+	delete(drafts, userID)
+	if err := saveDrafts(); err != nil {
+		app.Log("mail", "Failed to save drafts: %v", err)
+	}
+}
+
+// renameUserDrafts moves a renamed user's drafts to their new ID.
+func renameUserDrafts(oldID, newID string) {
+	draftsMu.Lock()
+	defer draftsMu.Unlock()
+	list, ok := drafts[oldID]
+	if !ok {
+		return
+	}
+	for _, d := range list {
+		d.UserID = newID
+	}
+	delete(drafts, oldID)
+	drafts[newID] = list
+	if err := saveDrafts(); err != nil {
+		app.Log("mail", "Failed to save drafts: %v", err)
+	}
+}
+
+// DraftHandler serves /mail/draft. POST saves a draft (the compose form
+// autosaves here) and returns its ID; POST with _method=DELETE, or DELETE,
+// removes one; GET lists drafts as JSON.
+func DraftHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		app.RespondJSON(w, map[string]interface{}{"drafts": GetDrafts(acc.ID)})
+		return
+	case "POST", "DELETE":
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	var d Draft
+	method := r.Method
+	if app.SendsJSON(r) {
+		var req struct {
+			ID      string `json:"id"`
+			To      string `json:"to"`
+			Subject string `json:"subject"`
+			Body    string `json:"body"`
+			ReplyTo string `json:"reply_to"`
+		}
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.RespondError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		d = Draft{ID: req.ID, To: req.To, Subject: req.Subject, Body: req.Body, ReplyTo: req.ReplyTo}
+	} else {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			if err := r.ParseMultipartForm(MaxDraftLength + 1<<20); err != nil {
+				app.BadRequest(w, r, "Failed to parse form")
+				return
+			}
+		}
+		if err := r.ParseForm(); err != nil {
+			app.BadRequest(w, r, "Failed to parse form")
+			return
+		}
+		d = Draft{
+			ID:      r.FormValue("draft_id"),
+			To:      r.FormValue("to"),
+			Subject: r.FormValue("subject"),
+			Body:    r.FormValue("body"),
+			ReplyTo: r.FormValue("reply_to"),
+		}
+		if r.FormValue("_method") == "DELETE" {
+			method = "DELETE"
+		}
+	}
+
+	if method == "DELETE" {
+		if err := DeleteDraft(acc.ID, d.ID); err != nil {
+			if app.SendsJSON(r) || app.WantsJSON(r) {
+				app.RespondError(w, http.StatusNotFound, err.Error())
+			} else {
+				app.NotFound(w, r, "Draft not found")
+			}
+			return
+		}
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]bool{"success": true})
+			return
+		}
+		http.Redirect(w, r, "/mail?view=drafts", http.StatusSeeOther)
+		return
+	}
+
+	// Nothing worth keeping yet: don't create an empty draft.
+	if d.ID == "" && strings.TrimSpace(d.To+d.Subject+d.Body) == "" {
+		app.RespondJSON(w, map[string]interface{}{"id": ""})
+		return
+	}
+	saved, err := SaveDraft(acc.ID, d)
+	if err != nil {
+		app.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	app.RespondJSON(w, map[string]interface{}{"id": saved.ID, "updated_at": saved.UpdatedAt})
+}
+
+// renderDrafts renders the drafts tab in lang.
+func renderDrafts(userID, lang string) string {
+	return templates.HTML("drafts", map[string]interface{}{"Lang": lang, "Drafts": GetDrafts(userID)})
+}
+
+// draftAutosaveScript saves the compose form to /mail/draft a couple of
+// seconds after the user stops typing, and when the page is hidden.
+const draftAutosaveScript = `
+(function(){
+  var f=document.getElementById('compose-form');if(!f)return;
+  var st=document.getElementById('draft-status'),t=null,last='';
+  function fields(){return ['to','subject','body','reply_to','draft_id'].map(function(n){return f.elements[n].value}).join('\u0000')}
+  function save(){
+    t=null;var cur=fields();if(cur===last)return;last=cur;
+    var b=new URLSearchParams();['to','subject','body','reply_to','draft_id'].forEach(function(n){b.append(n,f.elements[n].value)});
+    fetch('/mail/draft',{method:'POST',credentials:'same-origin',keepalive:true,headers:{'Content-Type':'application/x-www-form-urlencoded'},body:b.toString()})
+    .then(function(r){return r.json()}).then(function(j){if(j.id){f.elements.draft_id.value=j.id;last=fields();if(st)st.textContent='Draft saved'}else if(j.error&&st){st.textContent=j.error}})
+    .catch(function(){if(st)st.textContent='Draft not saved'});
+  }
+  last=fields();
+  f.addEventListener('input',function(e){if(e.target.type==='file')return;if(st)st.textContent='';clearTimeout(t);t=setTimeout(save,2000)});
+  f.addEventListener('submit',function(){clearTimeout(t);t=null});
+  document.addEventListener('visibilitychange',function(){if(document.visibilityState==='hidden'&&t){clearTimeout(t);save()}});
+})();
+`
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrafts(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	draftsMu.Lock()
+	drafts = map[string][]*Draft{}
+	draftsMu.Unlock()
+
+	d, err := SaveDraft("alice", Draft{To: "bob", Subject: "Plans", Body: "first"})
+	if err != nil || d.ID == "" {
+		t.Fatalf("SaveDraft = %+v, %v", d, err)
+	}
+	time.Sleep(time.Millisecond)
+	other, err := SaveDraft("alice", Draft{Subject: "Other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Autosave updates the same draft in place.
+	time.Sleep(time.Millisecond)
+	if _, err := SaveDraft("alice", Draft{ID: d.ID, To: "bob", Subject: "Plans", Body: "first and second"}); err != nil {
+		t.Fatal(err)
+	}
+	list := GetDrafts("alice")
+	if len(list) != 2 || list[0].ID != d.ID || list[0].Body != "first and second" {
+		t.Fatalf("unexpected drafts: %+v", list)
+	}
+	if page := renderDrafts("alice", "en"); !strings.Contains(page, "(no recipient)") || !strings.Contains(page, `name="draft_id" value="`+d.ID+`"`) {
+		t.Fatalf("unexpected drafts tab: %s", page)
+	}
+
+	// Drafts are private to their owner.
+	if GetDraft("bob", d.ID) != nil || DeleteDraft("bob", d.ID) != ErrDraftNotFound {
+		t.Fatal("another account could reach alice's draft")
+	}
+	if _, err := SaveDraft("bob", Draft{ID: d.ID, Body: "hijack"}); err != nil {
+		t.Fatal(err)
+	}
+	if GetDraft("alice", d.ID).Body != "first and second" {
+		t.Fatal("another account overwrote alice's draft")
+	}
+
+	// Drafts survive a restart.
+	loadDrafts()
+	if got := GetDraft("alice", other.ID); got == nil || got.Subject != "Other" {
+		t.Fatalf("draft not reloaded: %+v", got)
+	}
+
+	if err := DeleteDraft("alice", d.ID); err != nil {
+		t.Fatal(err)
+	}
+	if GetDraft("alice", d.ID) != nil {
+		t.Fatal("draft should be gone")
+	}
+
+	if _, err := SaveDraft("alice", Draft{Body: string(make([]byte, MaxDraftLength+1))}); err != ErrDraftTooLarge {
+		t.Fatalf("oversize: got %v, want ErrDraftTooLarge", err)
+	}
+}
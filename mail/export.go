@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+)
+
+// exporter exports the messages a user has sent or received, as JSON or
+// as an mbox file most mail clients can import.
+type exporter struct{}
+
+func (exporter) Name() string        { return "mail" }
+func (exporter) Description() string { return "Messages you have sent and received" }
+func (exporter) Formats() []string   { return []string{"mbox", "json"} }
+
+func (exporter) Export(w io.Writer, userID, format string) error {
+	mutex.RLock()
+	var msgs []Message
+	for _, m := range messages {
+		if m.FromID == userID || m.ToID == userID {
+			msgs = append(msgs, *m)
+		}
+	}
+	mutex.RUnlock()
+
+	if format == "json" {
+		if msgs == nil {
+			msgs = []Message{}
+		}
+		return json.NewEncoder(w).Encode(msgs)
+	}
+
+	// Oldest first, as a mail client would have appended them.
+	bw := bufio.NewWriter(w)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		writeMbox(bw, &msgs[i])
+	}
+	return bw.Flush()
+}
+
+// mboxAddress returns the email address and header form of a stored
+// sender or recipient: external senders are kept as they are, local
+// accounts get an address on the configured mail domain.
+func mboxAddress(name, id string) (addr, header string) {
+	if IsExternalEmail(name) {
+		return name, name
+	}
+	if id == "" {
+		id = name
+	}
+	addr = GetEmailForUser(id, GetConfiguredDomain())
+	return addr, (&mail.Address{Name: name, Address: addr}).String()
+}
+
+// writeMbox writes one message in mboxrd format.
+func writeMbox(w *bufio.Writer, m *Message) {
+	from, fromHeader := mboxAddress(m.From, m.FromID)
+	_, toHeader := mboxAddress(m.To, m.ToID)
+	fmt.Fprintf(w, "From %s %s\n", from, m.CreatedAt.UTC().Format("Mon Jan _2 15:04:05 2006"))
+	fmt.Fprintf(w, "From: %s\n", fromHeader)
+	fmt.Fprintf(w, "To: %s\n", toHeader)
+	fmt.Fprintf(w, "Subject: %s\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(w, "Date: %s\n", m.CreatedAt.Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+	if m.MessageID != "" {
+		fmt.Fprintf(w, "Message-ID: %s\n", m.MessageID)
+	}
+	w.WriteString("MIME-Version: 1.0\nContent-Type: text/plain; charset=utf-8\nContent-Transfer-Encoding: 8bit\n\n")
+	for _, line := range strings.Split(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n") {
+		// mboxrd: quote lines that would otherwise start a new message
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = ">" + line
+		}
+		w.WriteString(line + "\n")
+	}
+	w.WriteString("\n")
+}
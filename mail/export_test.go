@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMbox(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeMbox(w, &Message{
+		From:      "Alice Smith",
+		FromID:    "alice",
+		To:        "bob@example.com",
+		Subject:   "Hi",
+		Body:      "Hello\nFrom the other side\n>From here too",
+		CreatedAt: time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC),
+	})
+	w.Flush()
+	got := buf.String()
+
+	for _, want := range []string{
+		"From alice@" + GetConfiguredDomain() + " Mon Oct  5 09:30:00 2026\n",
+		`From: "Alice Smith" <alice@` + GetConfiguredDomain() + ">\n",
+		"To: bob@example.com\n",
+		"\n>From the other side\n",
+		"\n>>From here too\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("mbox missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "\nFrom ") != 0 {
+		t.Errorf("unescaped From line in body:\n%s", got)
+	}
+}
@@ -0,0 +1,1150 @@
+package mail
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Read-only IMAP access to the internal mail store.
+//
+// Members point a normal mail client at the server and sign in with their
+// username and an API token (created at /token) as the password. The store
+// is exposed as folders:
+//
+//	INBOX          received mail
+//	Sent           mail you sent
+//	Junk           mail flagged as spam
+//	Threads/...    one folder per conversation
+//
+// Every folder is opened read-only. Read/unread state comes straight from
+// the store (\Seen), and nothing a client does over IMAP changes it — reads
+// and replies happen in the web UI. Only the subset of IMAP4rev1 that
+// ordinary clients need for browsing is implemented.
+
+const (
+	imapUIDValidity = 1
+	imapMaxLine     = 8 << 10
+	imapMaxLiteral  = 64 << 10
+	imapMaxCommand  = 256 << 10
+	imapIdleTimeout = 30 * time.Minute
+
+	// Before LOGIN or AUTHENTICATE succeeds a command only carries
+	// credentials, so literals and the whole command are kept small.
+	imapMaxAuthLiteral = 1 << 10
+	imapMaxAuthCommand = 4 << 10
+)
+
+// imapUIDs assigns each message a stable 32-bit UID on first sight. IMAP
+// UIDs must never be reused, so the mapping is persisted and only grows.
+var (
+	imapUIDMu   sync.Mutex
+	imapUIDs    map[string]uint32
+	imapNextUID uint32 = 1
+)
+
+type imapUIDState struct {
+	UIDs map[string]uint32 `json:"uids"`
+	Next uint32            `json:"next"`
+}
+
+func loadIMAPUIDs() {
+	var s imapUIDState
+	if err := data.LoadJSON("imap_uids.json", &s); err == nil && s.UIDs != nil {
+		imapUIDs = s.UIDs
+		imapNextUID = s.Next
+	}
+	if imapUIDs == nil {
+		imapUIDs = map[string]uint32{}
+	}
+	if imapNextUID == 0 {
+		imapNextUID = 1
+	}
+}
+
+// assignUIDs fills in msgs[i].UID, giving every message seen for the first
+// time the next UID, oldest first.
+func assignUIDs(msgs []imapMessage) {
+	imapUIDMu.Lock()
+	defer imapUIDMu.Unlock()
+	if imapUIDs == nil {
+		loadIMAPUIDs()
+	}
+
+	var fresh []int
+	for i := range msgs {
+		if uid, ok := imapUIDs[msgs[i].ID]; ok {
+			msgs[i].UID = uid
+		} else {
+			fresh = append(fresh, i)
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+	sort.SliceStable(fresh, func(a, b int) bool {
+		return msgs[fresh[a]].CreatedAt.Before(msgs[fresh[b]].CreatedAt)
+	})
+	for _, i := range fresh {
+		imapUIDs[msgs[i].ID] = imapNextUID
+		msgs[i].UID = imapNextUID
+		imapNextUID++
+	}
+	data.SaveJSON("imap_uids.json", imapUIDState{UIDs: imapUIDs, Next: imapNextUID})
+}
+
+// imapMessage is a snapshot of a stored message plus its IMAP identity.
+type imapMessage struct {
+	Message
+	UID       uint32
+	InReplyTo string // Message-ID of the parent, if any
+	Seen      bool
+}
+
+// imapMailbox is a folder as presented to an IMAP client.
+type imapMailbox struct {
+	Name     string
+	Messages []imapMessage // sorted by UID
+}
+
+var imapFolderUnsafe = regexp.MustCompile(`[^a-zA-Z0-9 ._'-]+`)
+
+// threadFolderName builds a stable, readable folder name for a thread.
+func threadFolderName(root *Message, threadID string) string {
+	subject := strings.TrimSpace(imapFolderUnsafe.ReplaceAllString(root.Subject, " "))
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	if len(subject) > 48 {
+		subject = strings.TrimSpace(subject[:48])
+	}
+	short := threadID
+	if len(short) > 6 {
+		short = short[len(short)-6:]
+	}
+	return "Threads/" + subject + " [" + short + "]"
+}
+
+// userMailboxes snapshots all folders for a user.
+func userMailboxes(userID string) []imapMailbox {
+	mutex.RLock()
+	snap := func(m *Message) imapMessage {
+		im := imapMessage{Message: *m}
+		// Sent mail and anything not addressed to the user is implicitly read.
+		im.Seen = m.Read || m.ToID != userID
+		if m.ReplyTo != "" {
+			if parent := GetMessageUnlocked(m.ReplyTo); parent != nil {
+				im.InReplyTo = parent.MessageID
+				if im.InReplyTo == "" {
+					im.InReplyTo = internalMessageID(parent)
+				}
+			}
+		}
+		return im
+	}
+
+	inbox := imapMailbox{Name: "INBOX"}
+	sent := imapMailbox{Name: "Sent"}
+	junk := imapMailbox{Name: "Junk"}
+	for _, m := range messages {
+		switch {
+		case m.Spam && m.ToID == userID:
+			junk.Messages = append(junk.Messages, snap(m))
+		case m.Spam:
+		case m.ToID == userID:
+			inbox.Messages = append(inbox.Messages, snap(m))
+		case m.FromID == userID:
+			sent.Messages = append(sent.Messages, snap(m))
+		}
+	}
+
+	var threads []imapMailbox
+	if ib := inboxes[userID]; ib != nil {
+		for id, t := range ib.Threads {
+			mb := imapMailbox{Name: threadFolderName(t.Root, id)}
+			for _, m := range t.Messages {
+				mb.Messages = append(mb.Messages, snap(m))
+			}
+			threads = append(threads, mb)
+		}
+	}
+	mutex.RUnlock()
+
+	sort.Slice(threads, func(i, j int) bool { return threads[i].Name < threads[j].Name })
+	boxes := append([]imapMailbox{inbox, sent, junk}, threads...)
+
+	// Assign UIDs across all folders at once so a message keeps one UID
+	// wherever it appears.
+	var all []imapMessage
+	for _, b := range boxes {
+		all = append(all, b.Messages...)
+	}
+	assignUIDs(all)
+	uids := make(map[string]uint32, len(all))
+	for _, m := range all {
+		uids[m.ID] = m.UID
+	}
+	for bi := range boxes {
+		for mi := range boxes[bi].Messages {
+			boxes[bi].Messages[mi].UID = uids[boxes[bi].Messages[mi].ID]
+		}
+		sort.Slice(boxes[bi].Messages, func(i, j int) bool {
+			return boxes[bi].Messages[i].UID < boxes[bi].Messages[j].UID
+		})
+	}
+	return boxes
+}
+
+// internalMessageID synthesises a Message-ID for mail that never had one.
+func internalMessageID(m *Message) string {
+	return "<" + m.ID + "@" + GetConfiguredDomain() + ">"
+}
+
+// imapAddress returns the display name and email address for a party.
+func imapAddress(name, id string) (string, string) {
+	addr := id
+	if addr == "" {
+		addr = name
+	}
+	if !strings.Contains(addr, "@") {
+		addr = addr + "@" + GetConfiguredDomain()
+	}
+	return name, addr
+}
+
+// isHTMLBody reports whether a stored body is HTML rather than plain text.
+func isHTMLBody(body string) bool {
+	b := strings.TrimSpace(body)
+	return strings.HasPrefix(b, "<") && strings.Contains(b, ">")
+}
+
+// rfc822 renders a message as a MIME document.
+func (m *imapMessage) rfc822() (header, body string) {
+	var h strings.Builder
+	fromName, fromAddr := imapAddress(m.From, m.FromID)
+	toName, toAddr := imapAddress(m.To, m.ToID)
+	msgID := m.MessageID
+	if msgID == "" {
+		msgID = internalMessageID(&m.Message)
+	}
+	ctype := "text/plain"
+	if isHTMLBody(m.Body) {
+		ctype = "text/html"
+	}
+	fmt.Fprintf(&h, "Date: %s\r\n", m.CreatedAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&h, "From: %s <%s>\r\n", mime.QEncoding.Encode("utf-8", fromName), fromAddr)
+	fmt.Fprintf(&h, "To: %s <%s>\r\n", mime.QEncoding.Encode("utf-8", toName), toAddr)
+	fmt.Fprintf(&h, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&h, "Message-ID: %s\r\n", msgID)
+	if m.InReplyTo != "" {
+		fmt.Fprintf(&h, "In-Reply-To: %s\r\n", m.InReplyTo)
+	}
+	h.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&h, "Content-Type: %s; charset=utf-8\r\n", ctype)
+	h.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	h.WriteString("\r\n")
+
+	text := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n", "\r\n")
+	if !strings.HasSuffix(text, "\r\n") {
+		text += "\r\n"
+	}
+	return h.String(), text
+}
+
+// ============================================
+// SERVER
+// ============================================
+
+// StartIMAPServerIfEnabled starts the read-only IMAP server when IMAP_ADDR
+// is set (e.g. ":1143"). If IMAP_TLS_CERT and IMAP_TLS_KEY point at a
+// certificate, the listener speaks implicit TLS (IMAPS); otherwise it is
+// plaintext and should only be exposed on a trusted network or tunnel.
+func StartIMAPServerIfEnabled() bool {
+	addr := os.Getenv("IMAP_ADDR")
+	if addr == "" {
+		return false
+	}
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+
+	var ln net.Listener
+	var err error
+	cert, key := os.Getenv("IMAP_TLS_CERT"), os.Getenv("IMAP_TLS_KEY")
+	if cert != "" && key != "" {
+		pair, cerr := tls.LoadX509KeyPair(cert, key)
+		if cerr != nil {
+			app.Log("mail", "IMAP TLS disabled: %v", cerr)
+			return false
+		}
+		ln, err = tls.Listen("tcp", addr, &tls.Config{Certificates: []tls.Certificate{pair}})
+	} else {
+		ln, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		app.Log("mail", "IMAP listen error on %s: %v", addr, err)
+		return false
+	}
+
+	app.Log("mail", "Starting read-only IMAP server on %s (tls=%v)", addr, cert != "")
+	go serveIMAP(ln)
+	return true
+}
+
+func serveIMAP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			app.Log("mail", "IMAP accept error: %v", err)
+			return
+		}
+		go newIMAPConn(conn).serve()
+	}
+}
+
+// imapConn is one client connection.
+type imapConn struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	w        *bufio.Writer
+	user     string
+	selected *imapMailbox
+}
+
+func newIMAPConn(c net.Conn) *imapConn {
+	return &imapConn{conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}
+}
+
+var (
+	errIMAPLogout  = errors.New("logout")
+	errIMAPTooLong = errors.New("command too long")
+)
+
+func (c *imapConn) serve() {
+	defer c.conn.Close()
+	c.untagged("OK [CAPABILITY IMAP4rev1 AUTH=PLAIN] Mu IMAP ready (read-only)")
+	c.w.Flush()
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(imapIdleTimeout))
+		line, err := c.readCommand()
+		if err == errIMAPTooLong {
+			c.untagged("BYE command too long")
+			c.w.Flush()
+			return
+		}
+		if err != nil {
+			return
+		}
+		tag, cmd, args := splitIMAPCommand(line)
+		if tag == "" {
+			c.untagged("BAD missing tag")
+			c.w.Flush()
+			continue
+		}
+		err = c.dispatch(tag, cmd, args)
+		c.w.Flush()
+		if err == errIMAPLogout {
+			return
+		}
+	}
+}
+
+// readCommand reads one command line, inlining any {n} literals. Lines,
+// literals and the command as a whole are capped, more tightly until the
+// client has logged in, and going over gives errIMAPTooLong.
+func (c *imapConn) readCommand() (string, error) {
+	maxLiteral, maxCommand := imapMaxLiteral, imapMaxCommand
+	if c.user == "" {
+		maxLiteral, maxCommand = imapMaxAuthLiteral, imapMaxAuthCommand
+	}
+	var sb strings.Builder
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return "", err
+		}
+		n, ok := literalSize(line)
+		if !ok {
+			if sb.Len()+len(line) > maxCommand {
+				return "", errIMAPTooLong
+			}
+			sb.WriteString(line)
+			return sb.String(), nil
+		}
+		sync := !strings.HasSuffix(line, "+}")
+		line = line[:strings.LastIndex(line, "{")]
+		if n > maxLiteral || sb.Len()+len(line)+n > maxCommand {
+			return "", errIMAPTooLong
+		}
+		sb.WriteString(line)
+		if sync {
+			c.w.WriteString("+ Ready\r\n")
+			c.w.Flush()
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return "", err
+		}
+		sb.WriteString(quoteIMAP(string(buf)))
+	}
+}
+
+// readLine reads one line without its CRLF, giving errIMAPTooLong rather
+// than buffering more than imapMaxLine.
+func (c *imapConn) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, err := c.r.ReadSlice('\n')
+		if len(line)+len(chunk) > imapMaxLine+2 {
+			return "", errIMAPTooLong
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
+
+// literalSize parses a trailing "{n}" or "{n+}" literal marker.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	i := strings.LastIndex(line, "{")
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[i+1:len(line)-1], "+"))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func splitIMAPCommand(line string) (tag, cmd, args string) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return "", "", ""
+	}
+	tag, cmd = parts[0], strings.ToUpper(parts[1])
+	if len(parts) == 3 {
+		args = parts[2]
+	}
+	return tag, cmd, args
+}
+
+func (c *imapConn) untagged(format string, a ...interface{}) {
+	fmt.Fprintf(c.w, "* "+format+"\r\n", a...)
+}
+
+func (c *imapConn) tagged(tag, format string, a ...interface{}) {
+	fmt.Fprintf(c.w, tag+" "+format+"\r\n", a...)
+}
+
+func (c *imapConn) dispatch(tag, cmd, args string) error {
+	switch cmd {
+	case "CAPABILITY":
+		c.untagged("CAPABILITY IMAP4rev1 AUTH=PLAIN UNSELECT")
+		c.tagged(tag, "OK CAPABILITY completed")
+		return nil
+	case "NOOP", "CHECK":
+		c.tagged(tag, "OK %s completed", cmd)
+		return nil
+	case "LOGOUT":
+		c.untagged("BYE Mu IMAP signing off")
+		c.tagged(tag, "OK LOGOUT completed")
+		return errIMAPLogout
+	case "LOGIN":
+		fields := parseIMAPArgs(args)
+		if len(fields) != 2 {
+			c.tagged(tag, "BAD LOGIN expects username and password")
+			return nil
+		}
+		c.login(tag, fields[0], fields[1])
+		return nil
+	case "AUTHENTICATE":
+		c.authenticate(tag, args)
+		return nil
+	}
+
+	if c.user == "" {
+		c.tagged(tag, "NO authenticate first")
+		return nil
+	}
+
+	switch cmd {
+	case "LIST", "LSUB":
+		c.list(tag, cmd, args)
+	case "STATUS":
+		c.status(tag, args)
+	case "SELECT", "EXAMINE":
+		c.selectBox(tag, cmd, args)
+	case "CLOSE", "UNSELECT":
+		c.selected = nil
+		c.tagged(tag, "OK %s completed", cmd)
+	case "FETCH", "SEARCH":
+		c.withSelected(tag, func() { c.command(tag, cmd, args, false) })
+	case "UID":
+		sub, rest, _ := strings.Cut(args, " ")
+		sub = strings.ToUpper(sub)
+		if sub != "FETCH" && sub != "SEARCH" {
+			if sub == "STORE" || sub == "COPY" || sub == "MOVE" || sub == "EXPUNGE" {
+				c.tagged(tag, "NO [READ-ONLY] mailbox is read-only")
+			} else {
+				c.tagged(tag, "BAD unsupported UID command")
+			}
+			return nil
+		}
+		c.withSelected(tag, func() { c.command(tag, sub, rest, true) })
+	case "STORE", "COPY", "MOVE", "EXPUNGE", "APPEND", "CREATE", "DELETE", "RENAME", "SUBSCRIBE", "UNSUBSCRIBE":
+		c.tagged(tag, "NO [READ-ONLY] Mu mail is read-only over IMAP")
+	default:
+		c.tagged(tag, "BAD unknown command")
+	}
+	return nil
+}
+
+func (c *imapConn) withSelected(tag string, fn func()) {
+	if c.selected == nil {
+		c.tagged(tag, "BAD no mailbox selected")
+		return
+	}
+	fn()
+}
+
+// imapAuthenticate verifies a username and an API token allowed to read
+// mail.
+func imapAuthenticate(username, token string) (string, bool) {
+	accountID, err := auth.ValidatePATScope(token, "read:mail")
+	if err != nil || !strings.EqualFold(accountID, username) {
+		return "", false
+	}
+	if auth.IsBanned(accountID) {
+		return "", false
+	}
+	return accountID, true
+}
+
+func (c *imapConn) login(tag, username, password string) {
+	username = strings.TrimSuffix(strings.ToLower(username), "@"+strings.ToLower(GetConfiguredDomain()))
+	id, ok := imapAuthenticate(username, password)
+	if !ok {
+		app.Log("mail", "IMAP login failed for %s from %s", username, c.conn.RemoteAddr())
+		c.tagged(tag, "NO [AUTHENTICATIONFAILED] invalid username or token")
+		return
+	}
+	c.user = id
+	app.Log("mail", "IMAP login: %s", id)
+	c.tagged(tag, "OK [CAPABILITY IMAP4rev1 UNSELECT] LOGIN completed")
+}
+
+// authenticate handles AUTHENTICATE PLAIN, with or without an initial response.
+func (c *imapConn) authenticate(tag, args string) {
+	mech, initial, _ := strings.Cut(args, " ")
+	if !strings.EqualFold(mech, "PLAIN") {
+		c.tagged(tag, "NO unsupported mechanism")
+		return
+	}
+	if initial == "" {
+		c.w.WriteString("+ \r\n")
+		c.w.Flush()
+		line, err := c.readLine()
+		if err == errIMAPTooLong {
+			c.untagged("BYE command too long")
+			c.w.Flush()
+			c.conn.Close()
+			return
+		}
+		if err != nil {
+			return
+		}
+		initial = strings.TrimSpace(line)
+	}
+	raw, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		c.tagged(tag, "BAD invalid base64")
+		return
+	}
+	parts := strings.Split(string(raw), "\x00")
+	if len(parts) != 3 {
+		c.tagged(tag, "BAD invalid PLAIN response")
+		return
+	}
+	c.login(tag, parts[1], parts[2])
+}
+
+func (c *imapConn) findMailbox(name string) *imapMailbox {
+	for _, b := range userMailboxes(c.user) {
+		if b.Name == name || (strings.EqualFold(name, "INBOX") && b.Name == "INBOX") {
+			mb := b
+			return &mb
+		}
+	}
+	return nil
+}
+
+func (c *imapConn) list(tag, cmd, args string) {
+	fields := parseIMAPArgs(args)
+	if len(fields) != 2 {
+		c.tagged(tag, "BAD %s expects reference and pattern", cmd)
+		return
+	}
+	pattern := fields[0] + fields[1]
+	if fields[1] == "" {
+		c.untagged(`%s (\Noselect) "/" ""`, cmd)
+		c.tagged(tag, "OK %s completed", cmd)
+		return
+	}
+	hasThreads := false
+	for _, b := range userMailboxes(c.user) {
+		if strings.HasPrefix(b.Name, "Threads/") {
+			hasThreads = true
+		}
+		if imapMatch(pattern, b.Name) {
+			attrs := `\HasNoChildren`
+			switch b.Name {
+			case "Sent":
+				attrs += ` \Sent`
+			case "Junk":
+				attrs += ` \Junk`
+			}
+			c.untagged(`%s (%s) "/" %s`, cmd, attrs, quoteIMAP(b.Name))
+		}
+	}
+	if hasThreads && imapMatch(pattern, "Threads") {
+		c.untagged(`%s (\Noselect \HasChildren) "/" "Threads"`, cmd)
+	}
+	c.tagged(tag, "OK %s completed", cmd)
+}
+
+// imapMatch implements LIST wildcards: * matches anything, % anything but "/".
+func imapMatch(pattern, name string) bool {
+	if strings.EqualFold(pattern, "INBOX") {
+		return name == "INBOX"
+	}
+	var re strings.Builder
+	re.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			re.WriteString(".*")
+		case '%':
+			re.WriteString("[^/]*")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	ok, _ := regexp.MatchString(re.String(), name)
+	return ok
+}
+
+func (c *imapConn) status(tag, args string) {
+	fields := parseIMAPArgs(args)
+	if len(fields) < 1 {
+		c.tagged(tag, "BAD STATUS expects a mailbox")
+		return
+	}
+	mb := c.findMailbox(fields[0])
+	if mb == nil {
+		c.tagged(tag, "NO no such mailbox")
+		return
+	}
+	unseen := 0
+	for _, m := range mb.Messages {
+		if !m.Seen {
+			unseen++
+		}
+	}
+	c.untagged("STATUS %s (MESSAGES %d RECENT 0 UIDNEXT %d UIDVALIDITY %d UNSEEN %d)",
+		quoteIMAP(mb.Name), len(mb.Messages), mailboxUIDNext(mb), imapUIDValidity, unseen)
+	c.tagged(tag, "OK STATUS completed")
+}
+
+func mailboxUIDNext(mb *imapMailbox) uint32 {
+	if n := len(mb.Messages); n > 0 {
+		return mb.Messages[n-1].UID + 1
+	}
+	imapUIDMu.Lock()
+	defer imapUIDMu.Unlock()
+	return imapNextUID
+}
+
+func (c *imapConn) selectBox(tag, cmd, args string) {
+	fields := parseIMAPArgs(args)
+	if len(fields) < 1 {
+		c.tagged(tag, "BAD %s expects a mailbox", cmd)
+		return
+	}
+	mb := c.findMailbox(fields[0])
+	if mb == nil {
+		c.selected = nil
+		c.tagged(tag, "NO no such mailbox")
+		return
+	}
+	c.selected = mb
+	firstUnseen := 0
+	for i, m := range mb.Messages {
+		if !m.Seen {
+			firstUnseen = i + 1
+			break
+		}
+	}
+	c.untagged(`FLAGS (\Seen)`)
+	c.untagged(`OK [PERMANENTFLAGS ()] read-only`)
+	c.untagged("%d EXISTS", len(mb.Messages))
+	c.untagged("0 RECENT")
+	if firstUnseen > 0 {
+		c.untagged("OK [UNSEEN %d] first unseen", firstUnseen)
+	}
+	c.untagged("OK [UIDVALIDITY %d] UIDs valid", imapUIDValidity)
+	c.untagged("OK [UIDNEXT %d] predicted next UID", mailboxUIDNext(mb))
+	c.tagged(tag, "OK [READ-ONLY] %s completed", cmd)
+}
+
+// command runs FETCH or SEARCH (optionally in UID mode) on the selected box.
+func (c *imapConn) command(tag, cmd, args string, uid bool) {
+	prefix := ""
+	if uid {
+		prefix = "UID "
+	}
+	switch cmd {
+	case "FETCH":
+		set, items, ok := strings.Cut(args, " ")
+		if !ok {
+			c.tagged(tag, "BAD FETCH expects a sequence set and items")
+			return
+		}
+		msgs := c.resolveSet(set, uid)
+		for _, seq := range msgs {
+			out, err := fetchItems(&c.selected.Messages[seq-1], items, uid)
+			if err != nil {
+				c.tagged(tag, "BAD %v", err)
+				return
+			}
+			c.untagged("%d FETCH (%s)", seq, out)
+		}
+		c.tagged(tag, "OK %sFETCH completed", prefix)
+	case "SEARCH":
+		var hits []string
+		for i := range c.selected.Messages {
+			if matchSearch(c, i+1, args) {
+				if uid {
+					hits = append(hits, strconv.FormatUint(uint64(c.selected.Messages[i].UID), 10))
+				} else {
+					hits = append(hits, strconv.Itoa(i+1))
+				}
+			}
+		}
+		if len(hits) == 0 {
+			c.untagged("SEARCH")
+		} else {
+			c.untagged("SEARCH %s", strings.Join(hits, " "))
+		}
+		c.tagged(tag, "OK %sSEARCH completed", prefix)
+	}
+}
+
+// resolveSet returns the sequence numbers selected by a sequence or UID set.
+func (c *imapConn) resolveSet(set string, uid bool) []int {
+	msgs := c.selected.Messages
+	var out []int
+	for i := range msgs {
+		n := uint32(i + 1)
+		max := uint32(len(msgs))
+		if uid {
+			n = msgs[i].UID
+			if len(msgs) > 0 {
+				max = msgs[len(msgs)-1].UID
+			}
+		}
+		if inSet(set, n, max) {
+			out = append(out, i+1)
+		}
+	}
+	return out
+}
+
+// inSet reports whether n is in an IMAP sequence set like "1:4,7,9:*".
+func inSet(set string, n, max uint32) bool {
+	parse := func(s string) (uint32, bool) {
+		if s == "*" {
+			return max, true
+		}
+		v, err := strconv.ParseUint(s, 10, 32)
+		return uint32(v), err == nil
+	}
+	for _, part := range strings.Split(set, ",") {
+		lo, hi, isRange := strings.Cut(part, ":")
+		a, ok := parse(lo)
+		if !ok {
+			continue
+		}
+		if !isRange {
+			if n == a {
+				return true
+			}
+			continue
+		}
+		b, ok := parse(hi)
+		if !ok {
+			continue
+		}
+		if a > b {
+			a, b = b, a
+		}
+		if n >= a && n <= b {
+			return true
+		}
+	}
+	return false
+}
+
+var imapSectionRe = regexp.MustCompile(`(?i)^(BODY(?:\.PEEK)?)\[([^\]]*)\](?:<(\d+)(?:\.(\d+))?>)?$`)
+
+// fetchItems renders the requested data items for one message.
+func fetchItems(m *imapMessage, items string, uid bool) (string, error) {
+	list := splitFetchItems(items)
+	var out []string
+	hasUID := false
+	header, body := m.rfc822()
+	full := header + body
+
+	for _, item := range list {
+		upper := strings.ToUpper(item)
+		switch upper {
+		case "ALL":
+			list = append(list, "FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE")
+			continue
+		case "FAST":
+			list = append(list, "FLAGS", "INTERNALDATE", "RFC822.SIZE")
+			continue
+		case "FULL":
+			list = append(list, "FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE", "BODY")
+			continue
+		}
+	}
+
+	for _, item := range list {
+		upper := strings.ToUpper(item)
+		switch upper {
+		case "ALL", "FAST", "FULL":
+		case "UID":
+			hasUID = true
+			out = append(out, fmt.Sprintf("UID %d", m.UID))
+		case "FLAGS":
+			if m.Seen {
+				out = append(out, `FLAGS (\Seen)`)
+			} else {
+				out = append(out, "FLAGS ()")
+			}
+		case "INTERNALDATE":
+			out = append(out, fmt.Sprintf(`INTERNALDATE "%s"`, m.CreatedAt.Format("02-Jan-2006 15:04:05 -0700")))
+		case "RFC822.SIZE":
+			out = append(out, fmt.Sprintf("RFC822.SIZE %d", len(full)))
+		case "ENVELOPE":
+			out = append(out, "ENVELOPE "+m.envelope())
+		case "BODY", "BODYSTRUCTURE":
+			out = append(out, upper+" "+m.bodyStructure(body))
+		case "RFC822":
+			out = append(out, "RFC822 "+literal(full))
+		case "RFC822.HEADER":
+			out = append(out, "RFC822.HEADER "+literal(header))
+		case "RFC822.TEXT":
+			out = append(out, "RFC822.TEXT "+literal(body))
+		default:
+			sm := imapSectionRe.FindStringSubmatch(item)
+			if sm == nil {
+				return "", fmt.Errorf("unsupported fetch item %s", item)
+			}
+			section := strings.ToUpper(sm[2])
+			var content string
+			switch {
+			case section == "":
+				content = full
+			case section == "HEADER":
+				content = header
+			case section == "TEXT" || section == "1":
+				content = body
+			case strings.HasPrefix(section, "HEADER.FIELDS.NOT"):
+				content = filterHeader(header, parseIMAPArgs(strings.TrimSpace(section[len("HEADER.FIELDS.NOT"):])), false)
+			case strings.HasPrefix(section, "HEADER.FIELDS"):
+				content = filterHeader(header, parseIMAPArgs(strings.TrimSpace(section[len("HEADER.FIELDS"):])), true)
+			case section == "1.MIME" || section == "MIME":
+				content = filterHeader(header, []string{"CONTENT-TYPE", "CONTENT-TRANSFER-ENCODING"}, true)
+			default:
+				content = ""
+			}
+			name := "BODY[" + sm[2] + "]"
+			if sm[3] != "" {
+				start, _ := strconv.Atoi(sm[3])
+				if start > len(content) {
+					start = len(content)
+				}
+				end := len(content)
+				if sm[4] != "" {
+					if n, _ := strconv.Atoi(sm[4]); start+n < end {
+						end = start + n
+					}
+				}
+				content = content[start:end]
+				name += "<" + sm[3] + ">"
+			}
+			out = append(out, name+" "+literal(content))
+		}
+	}
+	if uid && !hasUID {
+		out = append([]string{fmt.Sprintf("UID %d", m.UID)}, out...)
+	}
+	return strings.Join(out, " "), nil
+}
+
+// splitFetchItems splits "(FLAGS BODY.PEEK[HEADER.FIELDS (FROM TO)])" into items.
+func splitFetchItems(s string) []string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+	var items []string
+	depth := 0
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case ' ':
+			if depth == 0 {
+				if i > start {
+					items = append(items, s[start:i])
+				}
+				start = i + 1
+			}
+		}
+	}
+	if start < len(s) {
+		items = append(items, s[start:])
+	}
+	return items
+}
+
+// filterHeader keeps (include=true) or drops the named header fields.
+func filterHeader(header string, fields []string, include bool) string {
+	want := map[string]bool{}
+	for _, f := range fields {
+		want[strings.ToUpper(f)] = true
+	}
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(header, "\r\n\r\n"), "\r\n") {
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if want[strings.ToUpper(name)] == include {
+			sb.WriteString(line + "\r\n")
+		}
+	}
+	sb.WriteString("\r\n")
+	return sb.String()
+}
+
+func (m *imapMessage) envelope() string {
+	fromName, fromAddr := imapAddress(m.From, m.FromID)
+	toName, toAddr := imapAddress(m.To, m.ToID)
+	msgID := m.MessageID
+	if msgID == "" {
+		msgID = internalMessageID(&m.Message)
+	}
+	from := envelopeAddress(fromName, fromAddr)
+	return fmt.Sprintf("(%s %s %s %s %s %s NIL NIL %s %s)",
+		quoteIMAP(m.CreatedAt.Format(time.RFC1123Z)),
+		quoteIMAP(m.Subject),
+		from, from, from,
+		envelopeAddress(toName, toAddr),
+		nilOrQuote(m.InReplyTo),
+		quoteIMAP(msgID),
+	)
+}
+
+func envelopeAddress(name, addr string) string {
+	local, host, _ := strings.Cut(addr, "@")
+	return fmt.Sprintf("((%s NIL %s %s))", nilOrQuote(name), quoteIMAP(local), quoteIMAP(host))
+}
+
+func (m *imapMessage) bodyStructure(body string) string {
+	subtype := "PLAIN"
+	if isHTMLBody(m.Body) {
+		subtype = "HTML"
+	}
+	return fmt.Sprintf(`("TEXT" "%s" ("CHARSET" "UTF-8") NIL NIL "8BIT" %d %d)`,
+		subtype, len(body), strings.Count(body, "\r\n"))
+}
+
+// matchSearch evaluates a (simplified) SEARCH program against one message.
+// Criteria are ANDed; NOT negates the next criterion.
+func matchSearch(c *imapConn, seq int, program string) bool {
+	m := &c.selected.Messages[seq-1]
+	tokens := parseIMAPArgs(program)
+	max := uint32(len(c.selected.Messages))
+	maxUID := uint32(0)
+	if max > 0 {
+		maxUID = c.selected.Messages[max-1].UID
+	}
+	dateArg := func(s string) time.Time {
+		t, _ := time.Parse("2-Jan-2006", s)
+		return t
+	}
+	day := time.Date(m.CreatedAt.Year(), m.CreatedAt.Month(), m.CreatedAt.Day(), 0, 0, 0, 0, time.UTC)
+	contains := func(hay, needle string) bool {
+		return strings.Contains(strings.ToLower(hay), strings.ToLower(needle))
+	}
+
+	negate := false
+	for i := 0; i < len(tokens); i++ {
+		key := strings.ToUpper(tokens[i])
+		arg := ""
+		if i+1 < len(tokens) {
+			arg = tokens[i+1]
+		}
+		var ok bool
+		switch key {
+		case "NOT":
+			negate = !negate
+			continue
+		case "ALL":
+			ok = true
+		case "SEEN":
+			ok = m.Seen
+		case "UNSEEN", "NEW":
+			ok = !m.Seen
+		case "RECENT", "ANSWERED", "DELETED", "DRAFT", "FLAGGED":
+			ok = false
+		case "UNANSWERED", "UNDELETED", "UNDRAFT", "UNFLAGGED", "OLD":
+			ok = true
+		case "FROM":
+			ok = contains(m.From+" "+m.FromID, arg)
+			i++
+		case "TO":
+			ok = contains(m.To+" "+m.ToID, arg)
+			i++
+		case "SUBJECT":
+			ok = contains(m.Subject, arg)
+			i++
+		case "BODY":
+			ok = contains(m.Body, arg)
+			i++
+		case "TEXT":
+			ok = contains(m.Subject+" "+m.From+" "+m.Body, arg)
+			i++
+		case "SINCE", "SENTSINCE":
+			ok = !day.Before(dateArg(arg))
+			i++
+		case "BEFORE", "SENTBEFORE":
+			ok = day.Before(dateArg(arg))
+			i++
+		case "ON", "SENTON":
+			ok = day.Equal(dateArg(arg))
+			i++
+		case "UID":
+			ok = inSet(arg, m.UID, maxUID)
+			i++
+		case "CHARSET":
+			i++
+			continue
+		default:
+			if strings.ContainsAny(key, "0123456789*") {
+				ok = inSet(key, uint32(seq), max)
+			} else {
+				ok = true // unknown criteria don't exclude
+			}
+		}
+		if ok == negate {
+			return false
+		}
+		negate = false
+	}
+	return true
+}
+
+// parseIMAPArgs splits arguments into atoms and quoted strings, flattening
+// parenthesised lists.
+func parseIMAPArgs(s string) []string {
+	var out []string
+	var cur strings.Builder
+	inQuote, escaped, started := false, false, false
+	flush := func() {
+		if started {
+			out = append(out, cur.String())
+		}
+		cur.Reset()
+		started = false
+	}
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case inQuote && r == '\\':
+			escaped = true
+		case r == '"':
+			if inQuote {
+				inQuote = false
+				flush()
+			} else {
+				inQuote, started = true, true
+			}
+		case inQuote:
+			cur.WriteRune(r)
+		case r == ' ' || r == '(' || r == ')':
+			flush()
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	flush()
+	return out
+}
+
+func quoteIMAP(s string) string {
+	if strings.ContainsAny(s, "\r\n") {
+		return literal(s)
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+func nilOrQuote(s string) string {
+	if s == "" {
+		return "NIL"
+	}
+	return quoteIMAP(s)
+}
+
+func literal(s string) string {
+	return fmt.Sprintf("{%d}\r\n%s", len(s), s)
+}
@@ -0,0 +1,194 @@
+package mail
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// imapSession runs a server connection for user over a pipe and returns a
+// function that sends a tagged command and returns the full response.
+func imapSession(t *testing.T, user string) func(cmd string) string {
+	t.Helper()
+	server, client := net.Pipe()
+	c := newIMAPConn(server)
+	c.user = user
+	go c.serve()
+	t.Cleanup(func() { client.Close() })
+
+	r := bufio.NewReader(client)
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := r.ReadString('\n'); err != nil { // greeting
+		t.Fatal(err)
+	}
+	return func(cmd string) string {
+		if _, err := client.Write([]byte("a1 " + cmd + "\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		var sb strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("%s: %v (got %q)", cmd, err, sb.String())
+			}
+			sb.WriteString(line)
+			if strings.HasPrefix(line, "a1 ") {
+				return sb.String()
+			}
+		}
+	}
+}
+
+func withIMAPMessages(t *testing.T, msgs []*Message) {
+	t.Helper()
+	os.Setenv("HOME", t.TempDir())
+	imapUIDMu.Lock()
+	imapUIDs, imapNextUID = map[string]uint32{}, 1
+	imapUIDMu.Unlock()
+
+	mutex.Lock()
+	prevMessages, prevInboxes := messages, inboxes
+	messages = msgs
+	inboxes = map[string]*Inbox{}
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		messages, inboxes = prevMessages, prevInboxes
+		mutex.Unlock()
+	})
+}
+
+func TestIMAPFoldersAndFlags(t *testing.T) {
+	now := time.Now()
+	withIMAPMessages(t, []*Message{
+		{ID: "1", From: "bob", FromID: "bob", To: "alice", ToID: "alice", Subject: "Hello", Body: "hi alice", Read: true, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "2", From: "carol", FromID: "carol@example.com", To: "alice", ToID: "alice", Subject: "Lunch?", Body: "<p>tomorrow</p>", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "3", From: "alice", FromID: "alice", To: "bob", ToID: "bob", Subject: "Re: Hello", Body: "hey bob", ReplyTo: "1", CreatedAt: now.Add(-time.Hour)},
+		{ID: "4", From: "spam", FromID: "spam@example.com", To: "alice", ToID: "alice", Subject: "Win", Body: "money", Spam: true, CreatedAt: now},
+		{ID: "5", From: "bob", FromID: "bob", To: "dave", ToID: "dave", Subject: "Private", Body: "not for alice", CreatedAt: now},
+	})
+	send := imapSession(t, "alice")
+
+	list := send(`LIST "" "*"`)
+	for _, want := range []string{`"INBOX"`, `"Sent"`, `"Junk"`} {
+		if !strings.Contains(list, want) {
+			t.Errorf("LIST missing %s:\n%s", want, list)
+		}
+	}
+
+	sel := send("SELECT INBOX")
+	if !strings.Contains(sel, "* 2 EXISTS") || !strings.Contains(sel, "[READ-ONLY]") || !strings.Contains(sel, "[UNSEEN 2]") {
+		t.Fatalf("unexpected SELECT response:\n%s", sel)
+	}
+
+	flags := send("FETCH 1:* (UID FLAGS)")
+	if !strings.Contains(flags, `* 1 FETCH (UID 1 FLAGS (\Seen))`) || !strings.Contains(flags, `* 2 FETCH (UID 2 FLAGS ())`) {
+		t.Fatalf("unexpected flags:\n%s", flags)
+	}
+
+	body := send("UID FETCH 2 (BODY.PEEK[])")
+	if !strings.Contains(body, "From: carol <carol@example.com>") || !strings.Contains(body, "Content-Type: text/html") || !strings.Contains(body, "<p>tomorrow</p>") {
+		t.Fatalf("unexpected body:\n%s", body)
+	}
+
+	if got := send("SEARCH UNSEEN"); !strings.Contains(got, "* SEARCH 2\r\n") {
+		t.Fatalf("unexpected SEARCH:\n%s", got)
+	}
+
+	if got := send(`STORE 1 +FLAGS (\Seen)`); !strings.Contains(got, "a1 NO") {
+		t.Fatalf("STORE should be refused:\n%s", got)
+	}
+	mutex.RLock()
+	read := messages[1].Read
+	mutex.RUnlock()
+	if read {
+		t.Fatal("IMAP must not change read state")
+	}
+
+	sent := send("SELECT Sent")
+	if !strings.Contains(sent, "* 1 EXISTS") {
+		t.Fatalf("unexpected Sent:\n%s", sent)
+	}
+	if got := send("FETCH 1 (BODY.PEEK[HEADER.FIELDS (IN-REPLY-TO)])"); !strings.Contains(got, "In-Reply-To: <1@") {
+		t.Fatalf("reply should reference parent:\n%s", got)
+	}
+
+	if got := send("SELECT Junk"); !strings.Contains(got, "* 1 EXISTS") {
+		t.Fatalf("unexpected Junk:\n%s", got)
+	}
+}
+
+func TestIMAPRequiresLogin(t *testing.T) {
+	withIMAPMessages(t, nil)
+	send := imapSession(t, "")
+	if got := send("SELECT INBOX"); !strings.Contains(got, "a1 NO") {
+		t.Fatalf("SELECT before login should fail:\n%s", got)
+	}
+	if got := send("LOGIN alice wrong"); !strings.Contains(got, "AUTHENTICATIONFAILED") {
+		t.Fatalf("bad token should fail:\n%s", got)
+	}
+}
+
+func TestIMAPCommandLimits(t *testing.T) {
+	withIMAPMessages(t, nil)
+	// dial starts a connection for user and returns its first reply to raw.
+	dial := func(user, raw string) string {
+		server, client := net.Pipe()
+		c := newIMAPConn(server)
+		c.user = user
+		go c.serve()
+		t.Cleanup(func() { client.Close() })
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+		r := bufio.NewReader(client)
+		if _, err := r.ReadString('\n'); err != nil { // greeting
+			t.Fatal(err)
+		}
+		go client.Write([]byte(raw))
+		line, _ := r.ReadString('\n')
+		return line
+	}
+
+	// Before logging in, a literal bigger than any credentials is refused
+	// without being asked for.
+	if got := dial("", "a1 LOGIN {2000}\r\n"); !strings.HasPrefix(got, "* BYE") {
+		t.Errorf("large literal before login: %q", got)
+	}
+	if got := dial("", "a1 LOGIN {20}\r\n"); !strings.HasPrefix(got, "+ ") {
+		t.Errorf("small literal before login: %q", got)
+	}
+	// Once logged in, bigger literals are fine.
+	if got := dial("alice", "a1 SELECT {2000}\r\n"); !strings.HasPrefix(got, "+ ") {
+		t.Errorf("literal after login: %q", got)
+	}
+	// A line never ending is cut off rather than buffered.
+	if got := dial("", "a1 LOGIN "+strings.Repeat("x", 2*imapMaxLine)); !strings.HasPrefix(got, "* BYE") {
+		t.Errorf("endless line: %q", got)
+	}
+	// Non-synchronising literals can't add up past the command limit.
+	lit := "{1000+}\r\n" + strings.Repeat("x", 1000)
+	if got := dial("", "a1 LOGIN "+strings.Repeat(lit+" ", 5)+"\r\n"); !strings.HasPrefix(got, "* BYE") {
+		t.Errorf("oversized command: %q", got)
+	}
+}
+
+func TestInSet(t *testing.T) {
+	cases := []struct {
+		set  string
+		n    uint32
+		want bool
+	}{
+		{"1:*", 5, true},
+		{"2,4:6", 5, true},
+		{"2,4:6", 3, false},
+		{"*", 9, true},
+		{"*:3", 4, true},
+	}
+	for _, c := range cases {
+		if got := inSet(c.set, c.n, 9); got != c.want {
+			t.Errorf("inSet(%q, %d) = %v, want %v", c.set, c.n, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"unicode"
+
+	"mu/internal/data"
+)
+
+// Mail is indexed into the shared data index (type "mail") so search
+// doesn't have to scan every message. Each participant gets their own
+// owner-scoped entry, so an account's searches only ever see its own mail.
+//
+// The index is persisted alongside public content, so it never holds mail
+// text. Instead each word is replaced by a keyed hash (a blind token) using
+// the mail encryption key: the index can answer "which of my messages
+// contain this word" without revealing what the words are. The trade-off is
+// whole-word matching; searchMail falls back to a substring scan of the
+// in-memory messages for partial words.
+
+const (
+	mailIndexType      = "mail"
+	maxTokensPerMsg    = 2000
+	maxIndexedMatches  = 500
+	minSearchWordRunes = 2
+)
+
+// searchToken returns the blind token for a lower-cased word.
+func searchToken(word string) string {
+	var sum []byte
+	if encEnabled {
+		mac := hmac.New(sha256.New, encKey)
+		mac.Write([]byte("mail-search:" + word))
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.Sum256([]byte("mail-search:" + word))
+		sum = h[:]
+	}
+	return "m" + hex.EncodeToString(sum[:8])
+}
+
+// searchWords splits text into lower-cased, de-duplicated words.
+func searchWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(fields))
+	var out []string
+	for _, f := range fields {
+		if len([]rune(f)) < minSearchWordRunes || seen[f] {
+			continue
+		}
+		seen[f] = true
+		out = append(out, f)
+	}
+	return out
+}
+
+// tokenString converts words to a space-separated string of blind tokens.
+func tokenString(words []string) string {
+	if len(words) > maxTokensPerMsg {
+		words = words[:maxTokensPerMsg]
+	}
+	toks := make([]string, len(words))
+	for i, w := range words {
+		toks[i] = searchToken(w)
+	}
+	return strings.Join(toks, " ")
+}
+
+// mailIndexID is the index entry ID for a message as seen by one owner.
+func mailIndexID(msgID, owner string) string {
+	return "mail_" + msgID + "_" + owner
+}
+
+// indexOwners returns the local accounts a message belongs to.
+func indexOwners(m *Message) []string {
+	var owners []string
+	if m.ToID != "" && !IsExternalEmail(m.ToID) {
+		owners = append(owners, m.ToID)
+	}
+	if m.FromID != "" && m.FromID != m.ToID && !IsExternalEmail(m.FromID) {
+		owners = append(owners, m.FromID)
+	}
+	return owners
+}
+
+// indexMessage adds a message to the search index for each participant.
+// The subject goes in the title so subject hits rank above body hits.
+func indexMessage(m *Message) {
+	subject := decodeMIMEHeader(m.Subject)
+	title := tokenString(searchWords(subject))
+	content := tokenString(searchWords(subject + " " + m.From + " " + m.FromID + " " + stripHTMLTags(m.Body)))
+	for _, owner := range indexOwners(m) {
+		data.IndexOwned(mailIndexID(m.ID, owner), mailIndexType, title, content, owner,
+			map[string]interface{}{"message_id": m.ID})
+	}
+}
+
+// unindexMessage removes a message from the search index.
+func unindexMessage(m *Message) {
+	for _, owner := range indexOwners(m) {
+		data.Unindex(mailIndexID(m.ID, owner))
+	}
+}
+
+// reindexMail indexes every stored message. Unchanged entries are skipped
+// by the index, so this is cheap after the first run.
+func reindexMail() {
+	mutex.RLock()
+	msgs := make([]Message, len(messages))
+	for i, m := range messages {
+		msgs[i] = *m
+	}
+	mutex.RUnlock()
+	for i := range msgs {
+		indexMessage(&msgs[i])
+	}
+}
+
+// IndexedSearch returns up to limit of the user's non-spam messages that
+// contain every word in query, looked up through the search index. Subject
+// matches rank first, then sender, then body; ties go to the newest.
+func IndexedSearch(userID, query string, limit int) []*Message {
+	words := searchWords(query)
+	if userID == "" || len(words) == 0 {
+		return nil
+	}
+
+	// Intersect the matches for each word.
+	var matched map[string]bool
+	for _, w := range words {
+		hits := map[string]bool{}
+		for _, e := range data.Search(searchToken(w), maxIndexedMatches, data.WithType(mailIndexType), data.WithOwner(userID)) {
+			if e.Owner != userID {
+				continue
+			}
+			if id, ok := e.Metadata["message_id"].(string); ok && (matched == nil || matched[id]) {
+				hits[id] = true
+			}
+		}
+		matched = hits
+		if len(matched) == 0 {
+			return nil
+		}
+	}
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	type scored struct {
+		msg   *Message
+		score int
+	}
+	var results []scored
+	for id := range matched {
+		m := GetMessageUnlocked(id)
+		if m == nil || m.Spam || (m.ToID != userID && m.FromID != userID) {
+			continue
+		}
+		subject := strings.ToLower(decodeMIMEHeader(m.Subject))
+		from := strings.ToLower(m.From + " " + m.FromID)
+		score := 0
+		for _, w := range words {
+			switch {
+			case strings.Contains(subject, w):
+				score += 3
+			case strings.Contains(from, w):
+				score += 2
+			default:
+				score++
+			}
+		}
+		results = append(results, scored{m, score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].msg.CreatedAt.After(results[j].msg.CreatedAt)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	out := make([]*Message, len(results))
+	for i, r := range results {
+		out[i] = r.msg
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+
+	"mu/internal/app"
+	"mu/internal/data"
+)
+
+// Mail is persisted as a snapshot (mail.json) plus a journal
+// (mail.journal) of the changes since. Sending, reading or deleting a
+// message appends one line to the journal instead of rewriting every
+// message; once the journal reaches journalCompactAt entries it is folded
+// into a fresh snapshot. Load replays the journal over the snapshot.
+//
+// Journal entries hold messages in their stored, encrypted form. Replaying
+// is idempotent (a put replaces by ID, a delete of a missing ID is a no-op),
+// so a crash between writing the snapshot and removing the journal loses
+// nothing.
+
+const journalKey = "mail.journal"
+
+// journalCompactAt is how many entries the journal may hold before it is
+// folded into the snapshot.
+const journalCompactAt = 500
+
+type journalEntry struct {
+	Op      string   `json:"op"` // "put" or "delete"
+	Message *Message `json:"message,omitempty"`
+	ID      string   `json:"id,omitempty"`
+}
+
+// journalEntries counts the entries since the last snapshot. Guarded by
+// mutex.
+var journalEntries int
+
+// putMessages records new or changed messages (caller must hold mutex).
+func putMessages(msgs ...*Message) error {
+	entries := make([]journalEntry, 0, len(msgs))
+	for _, m := range msgs {
+		cp := *m
+		if err := encryptMessage(&cp); err != nil {
+			app.Log("mail", "WARNING: Failed to encrypt message %s: %v", m.ID, err)
+			cp = *m
+		} else {
+			sealed[m.ID] = sealedMessage{sum: plainSum(m), msg: &cp}
+		}
+		entries = append(entries, journalEntry{Op: "put", Message: &cp})
+	}
+	return appendJournal(entries)
+}
+
+// deleteMessages records removed messages (caller must hold mutex).
+func deleteMessages(ids ...string) error {
+	entries := make([]journalEntry, 0, len(ids))
+	for _, id := range ids {
+		delete(sealed, id)
+		entries = append(entries, journalEntry{Op: "delete", ID: id})
+	}
+	return appendJournal(entries)
+}
+
+// appendJournal writes entries to the journal, compacting it into a new
+// snapshot when it has grown long enough (caller must hold mutex).
+func appendJournal(entries []journalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	if err := data.AppendFile(journalKey, buf.String()); err != nil {
+		return err
+	}
+	journalEntries += len(entries)
+	if journalEntries >= journalCompactAt {
+		return save()
+	}
+	return nil
+}
+
+// resetJournal drops the journal after a full snapshot has been written
+// (caller must hold mutex).
+func resetJournal() {
+	if journalEntries == 0 {
+		return
+	}
+	if err := data.DeleteFile(journalKey); err != nil {
+		app.Log("mail", "WARNING: Failed to remove mail journal: %v", err)
+		return
+	}
+	journalEntries = 0
+}
+
+// replayJournal applies the journal to msgs, still in their stored form,
+// and returns the result and the number of entries applied. A torn last
+// line from a crash mid-write is skipped.
+func replayJournal(msgs []*Message) ([]*Message, int) {
+	b, err := data.LoadFile(journalKey)
+	if err != nil || len(b) == 0 {
+		return msgs, 0
+	}
+	// pos is an index into msgs, or -1-i for the i'th added message.
+	pos := make(map[string]int, len(msgs))
+	for i, m := range msgs {
+		pos[m.ID] = i
+	}
+	removed := map[string]bool{}
+	var added []*Message // newest last
+
+	applied := 0
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			app.Log("mail", "WARNING: Skipping bad mail journal entry: %v", err)
+			continue
+		}
+		switch e.Op {
+		case "put":
+			if e.Message == nil || e.Message.ID == "" {
+				continue
+			}
+			id := e.Message.ID
+			delete(removed, id)
+			if i, ok := pos[id]; ok && i >= 0 {
+				msgs[i] = e.Message
+			} else if ok {
+				added[-1-i] = e.Message
+			} else {
+				pos[id] = -1 - len(added)
+				added = append(added, e.Message)
+			}
+		case "delete":
+			if _, ok := pos[e.ID]; ok {
+				removed[e.ID] = true
+			}
+		default:
+			continue
+		}
+		applied++
+	}
+
+	// New messages go first, newest first, as SendMessage orders them.
+	out := make([]*Message, 0, len(added)+len(msgs))
+	for i := len(added) - 1; i >= 0; i-- {
+		if !removed[added[i].ID] {
+			out = append(out, added[i])
+		}
+	}
+	for _, m := range msgs {
+		if !removed[m.ID] {
+			out = append(out, m)
+		}
+	}
+	return out, applied
+}
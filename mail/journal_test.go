@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"os"
+	"testing"
+
+	"mu/internal/data"
+)
+
+// TestJournalReplay verifies single-message changes go to the journal, not
+// the snapshot, and that replaying the journal over the snapshot restores
+// them.
+func TestJournalReplay(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	mutex.Lock()
+	defer mutex.Unlock()
+	messages = []*Message{
+		{ID: "m2", FromID: "bob", ToID: "alice", Subject: "two"},
+		{ID: "m1", FromID: "bob", ToID: "alice", Subject: "one"},
+	}
+	sealed = map[string]sealedMessage{}
+	journalEntries = 0
+	if err := save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new message, a read flag and a deletion.
+	m3 := &Message{ID: "m3", FromID: "carol", ToID: "alice", Subject: "three"}
+	messages = append([]*Message{m3}, messages...)
+	if err := putMessages(m3); err != nil {
+		t.Fatal(err)
+	}
+	messages[1].Read = true
+	if err := putMessages(messages[1]); err != nil {
+		t.Fatal(err)
+	}
+	messages = messages[:2]
+	if err := deleteMessages("m1"); err != nil {
+		t.Fatal(err)
+	}
+	if journalEntries != 3 {
+		t.Fatalf("journalEntries = %d, want 3", journalEntries)
+	}
+
+	// The snapshot is untouched; replaying brings it up to date.
+	var snapshot []*Message
+	if err := data.LoadJSON("mail.json", &snapshot); err != nil || len(snapshot) != 2 {
+		t.Fatalf("snapshot = %d messages, %v", len(snapshot), err)
+	}
+	got, applied := replayJournal(snapshot)
+	if applied != 3 || len(got) != 2 || got[0].ID != "m3" || got[1].ID != "m2" || !got[1].Read {
+		t.Fatalf("replayed %d entries into %v", applied, idsOf(got))
+	}
+
+	// Replaying twice, as after a crash mid-compaction, changes nothing.
+	again, _ := replayJournal(got)
+	if len(again) != 2 || again[0].ID != "m3" || again[1].ID != "m2" {
+		t.Fatalf("second replay = %v", idsOf(again))
+	}
+
+	// A snapshot clears the journal.
+	if err := save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.LoadFile(journalKey); !os.IsNotExist(err) || journalEntries != 0 {
+		t.Fatalf("journal not cleared: %v, %d entries", err, journalEntries)
+	}
+}
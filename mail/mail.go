@@ -3,6 +3,7 @@ package mail
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 
 	"encoding/base64"
 	"encoding/json"
@@ -10,6 +11,7 @@ import (
 	"html"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -20,10 +22,23 @@ import (
 	"mu/internal/auth"
 	"mu/internal/data"
 	"mu/internal/service"
-
+	"mu/internal/settings"
 	"mu/wallet"
 )
 
+func init() {
+	settings.Register("Mail",
+		settings.Var{Key: "MAIL_DOMAIN", Doc: "Domain mail is sent from and received for"},
+		settings.Var{Key: "MAIL_PORT", Default: "2525", Doc: "Port the SMTP server listens on"},
+		settings.Var{Key: "MAIL_SELECTOR", Default: "default", Doc: "DKIM selector"},
+		settings.Var{Key: "DKIM_PRIVATE_KEY", Secret: true, Doc: "DKIM signing key, PEM"},
+		settings.Var{Key: "SMTP_HOST", Doc: "Relay host for outbound mail"},
+		settings.Var{Key: "SMTP_PORT", Type: settings.TypeInt, Doc: "Relay port"},
+		settings.Var{Key: "SMTP_USER", Doc: "Relay user"},
+		settings.Var{Key: "SMTP_PASS", Secret: true, Doc: "Relay password"},
+	)
+}
+
 var mutex sync.RWMutex
 
 // stored messages
@@ -47,23 +62,24 @@ type Thread struct {
 var inboxes map[string]*Inbox
 
 type Message struct {
-	ID          string    `json:"id"`
-	From        string    `json:"from"`    // Sender username
-	FromID      string    `json:"from_id"` // Sender account ID
-	To          string    `json:"to"`      // Recipient username
-	ToID        string    `json:"to_id"`   // Recipient account ID
-	Subject     string    `json:"subject"`
-	Body        string    `json:"body"`
-	Read        bool      `json:"read"`
-	ReplyTo     string    `json:"reply_to"`               // ID of message this is replying to
-	ThreadID    string    `json:"thread_id"`              // Root message ID for O(1) thread grouping
-	MessageID   string    `json:"message_id"`             // Email Message-ID header for threading
-	Spam        bool      `json:"spam,omitempty"`         // Whether this message was flagged as spam
-	SpamScore   int       `json:"spam_score,omitempty"`   // Spam detection score
-	SpamReasons []string  `json:"spam_reasons,omitempty"` // Why it was flagged
-	SenderIP    string    `json:"sender_ip,omitempty"`    // IP address of sending server
-	RawHeaders  string    `json:"raw_headers,omitempty"`  // Original email headers for View Raw
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string       `json:"id"`
+	From        string       `json:"from"`    // Sender username
+	FromID      string       `json:"from_id"` // Sender account ID
+	To          string       `json:"to"`      // Recipient username
+	ToID        string       `json:"to_id"`   // Recipient account ID
+	Subject     string       `json:"subject"`
+	Body        string       `json:"body"`
+	Read        bool         `json:"read"`
+	ReplyTo     string       `json:"reply_to"`               // ID of message this is replying to
+	ThreadID    string       `json:"thread_id"`              // Root message ID for O(1) thread grouping
+	MessageID   string       `json:"message_id"`             // Email Message-ID header for threading
+	Spam        bool         `json:"spam,omitempty"`         // Whether this message was flagged as spam
+	SpamScore   int          `json:"spam_score,omitempty"`   // Spam detection score
+	SpamReasons []string     `json:"spam_reasons,omitempty"` // Why it was flagged
+	SenderIP    string       `json:"sender_ip,omitempty"`    // IP address of sending server
+	RawHeaders  string       `json:"raw_headers,omitempty"`  // Original email headers for View Raw
+	Attachments []Attachment `json:"attachments,omitempty"`  // Files sent with the message
+	CreatedAt   time.Time    `json:"created_at"`
 }
 
 // Load messages from disk
@@ -77,21 +93,29 @@ func Load() {
 	// Initialize encryption
 	initEncryption()
 
+	data.RegisterTable("mail.json")
+	data.RegisterExporter(exporter{})
+
 	b, err := data.LoadFile("mail.json")
-	if err != nil {
+	if err != nil || json.Unmarshal(b, &messages) != nil {
 		messages = []*Message{}
-		inboxes = make(map[string]*Inbox)
-	} else if err := json.Unmarshal(b, &messages); err != nil {
-		messages = []*Message{}
-		inboxes = make(map[string]*Inbox)
-	} else {
-		app.Log("mail", "Loaded %d messages", len(messages))
+	}
+	// Apply the changes made since the last snapshot.
+	messages, journalEntries = replayJournal(messages)
+	inboxes = make(map[string]*Inbox)
+
+	if len(messages) > 0 {
+		app.Log("mail", "Loaded %d messages (%d journal entries)", len(messages), journalEntries)
 
 		// Decrypt messages loaded from disk
+		sealed = make(map[string]sealedMessage, len(messages))
 		for _, m := range messages {
+			stored := *m
 			if err := decryptMessage(m); err != nil {
 				app.Log("mail", "WARNING: Failed to decrypt message %s: %v", m.ID, err)
+				continue
 			}
+			sealed[m.ID] = sealedMessage{sum: plainSum(m), msg: &stored}
 		}
 
 		// Fix threading for any messages with broken chains
@@ -102,8 +126,23 @@ func Load() {
 
 		// Compute email stats
 		recomputeStats()
+
+		// Bring the search index up to date
+		go reindexMail()
+	}
+
+	// Start the next session from a fresh snapshot.
+	if journalEntries > 0 {
+		if err := save(); err != nil {
+			app.Log("mail", "WARNING: Failed to compact mail journal: %v", err)
+		}
 	}
 
+	// Load saved drafts
+	loadDrafts()
+	loadSummaries()
+	loadScheduled()
+
 	// Load blocklist
 	loadBlocklist()
 
@@ -259,11 +298,38 @@ func addMessageToInbox(inbox *Inbox, msg *Message, userID string) {
 	}
 }
 
-// Save messages to disk (caller must hold mutex)
+// sealedMessage is the stored, encrypted form of a message and a hash of
+// the plaintext it was made from.
+type sealedMessage struct {
+	sum [32]byte
+	msg *Message
+}
+
+// sealed caches each message's encrypted form by ID. Encryption is
+// randomised, so reusing it while the message is unchanged keeps the stored
+// record byte-identical between saves, which lets the SQLite store skip it.
+// Guarded by mutex.
+var sealed = map[string]sealedMessage{}
+
+func plainSum(m *Message) [32]byte {
+	b, _ := json.Marshal(m)
+	return sha256.Sum256(b)
+}
+
+// save writes a full snapshot of the messages and clears the journal
+// (caller must hold mutex). Single-message changes go through putMessages
+// and deleteMessages instead.
 func save() error {
 	// Make copies with encrypted fields for storage
 	encrypted := make([]*Message, len(messages))
+	next := make(map[string]sealedMessage, len(messages))
 	for i, m := range messages {
+		sum := plainSum(m)
+		if s, ok := sealed[m.ID]; ok && s.sum == sum {
+			encrypted[i] = s.msg
+			next[m.ID] = s
+			continue
+		}
 		cp := *m
 		if err := encryptMessage(&cp); err != nil {
 			app.Log("mail", "WARNING: Failed to encrypt message %s: %v", m.ID, err)
@@ -271,14 +337,20 @@ func save() error {
 			continue
 		}
 		encrypted[i] = &cp
+		next[m.ID] = sealedMessage{sum: sum, msg: &cp}
 	}
+	sealed = next
 
 	b, err := json.Marshal(encrypted)
 	if err != nil {
 		return err
 	}
 
-	return data.SaveFile("mail.json", string(b))
+	if err := data.SaveFile("mail.json", string(b)); err != nil {
+		return err
+	}
+	resetJournal()
+	return nil
 }
 
 // Handler for /mail (inbox)
@@ -324,10 +396,12 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		// JSON body for API/MCP callers (mail_send tool)
 		if app.SendsJSON(r) {
 			var req struct {
-				To      string `json:"to"`
-				Subject string `json:"subject"`
-				Body    string `json:"body"`
-				ReplyTo string `json:"reply_to"`
+				To          string             `json:"to"`
+				Subject     string             `json:"subject"`
+				Body        string             `json:"body"`
+				ReplyTo     string             `json:"reply_to"`
+				Attachments []AttachmentUpload `json:"attachments"`
+				SendAt      string             `json:"send_at"` // RFC 3339; empty sends now
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				app.RespondError(w, http.StatusBadRequest, "invalid JSON body")
@@ -341,40 +415,59 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 				app.RespondError(w, http.StatusBadRequest, "to, subject and body are required")
 				return
 			}
+			atts, err := attachmentsFromJSON(req.Attachments)
+			if err != nil {
+				app.RespondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if req.SendAt != "" {
+				s, err := scheduleFromRequest(acc.ID, to, subject, body, replyTo, req.SendAt, atts)
+				if err != nil {
+					deleteAttachments(atts)
+					app.RespondError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				app.RespondJSON(w, map[string]interface{}{"success": true, "scheduled": s})
+				return
+			}
 			if IsExternalEmail(to) {
 				if !acc.Admin {
 					canProceed, _, cost, err := wallet.CheckQuota(acc.ID, wallet.OpExternalEmail)
 					if err != nil || !canProceed {
+						deleteAttachments(atts)
 						app.RespondError(w, http.StatusPaymentRequired, fmt.Sprintf("external email requires %d credits", cost))
 						return
 					}
 				}
 				fromEmail := GetEmailForUser(acc.ID, GetConfiguredDomain())
 				htmlBody := convertPlainTextToHTML(body)
-				messageID, err := SendExternalEmail(acc.Name, fromEmail, to, subject, body, htmlBody, replyTo)
+				messageID, err := SendExternalEmailWithAttachments(acc.Name, fromEmail, to, subject, body, htmlBody, replyTo, atts)
 				if err != nil {
+					deleteAttachments(atts)
 					app.RespondError(w, http.StatusInternalServerError, "failed to send email: "+err.Error())
 					return
 				}
 				if !acc.Admin {
 					wallet.ConsumeQuota(acc.ID, wallet.OpExternalEmail) //nolint:errcheck
 				}
-				SendMessage(acc.Name, acc.ID, to, to, subject, body, replyTo, messageID) //nolint:errcheck
+				SendMessageWithAttachments(acc.Name, acc.ID, to, to, subject, body, replyTo, messageID, atts) //nolint:errcheck
 			} else {
 				// Internal mail costs credits
 				if !acc.Admin {
 					canProceed, _, cost, _ := wallet.CheckQuota(acc.ID, wallet.OpMailSend)
 					if !canProceed {
+						deleteAttachments(atts)
 						app.RespondError(w, http.StatusPaymentRequired, fmt.Sprintf("sending mail requires %d credits", cost))
 						return
 					}
 				}
 				toAcc, err := auth.GetAccount(to)
 				if err != nil {
+					deleteAttachments(atts)
 					app.RespondError(w, http.StatusNotFound, "recipient not found")
 					return
 				}
-				if err := SendMessage(acc.Name, acc.ID, toAcc.Name, toAcc.ID, subject, body, replyTo, ""); err != nil {
+				if err := SendMessageWithAttachments(acc.Name, acc.ID, toAcc.Name, toAcc.ID, subject, body, replyTo, "", atts); err != nil {
 					app.RespondError(w, http.StatusInternalServerError, "failed to send message")
 					return
 				}
@@ -386,6 +479,13 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// The compose form is multipart when it carries attachments
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			if err := r.ParseMultipartForm(MaxAttachmentsTotal + 1<<20); err != nil {
+				app.BadRequest(w, r, "Failed to parse form")
+				return
+			}
+		}
 		if err := r.ParseForm(); err != nil {
 			app.BadRequest(w, r, "Failed to parse form")
 			return
@@ -460,12 +560,33 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		atts, err := attachmentsFromForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Send later: queue the message for the scheduler instead
+		if v := r.FormValue("send_at"); v != "" {
+			if _, err := scheduleFromRequest(acc.ID, to, subject, bodyPlain, replyTo, v, atts); err != nil {
+				deleteAttachments(atts)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if id := r.FormValue("draft_id"); id != "" {
+				DeleteDraft(acc.ID, id) //nolint:errcheck
+			}
+			http.Redirect(w, r, "/mail?view=scheduled", http.StatusSeeOther)
+			return
+		}
+
 		// Check if recipient is external (has @domain)
 		if IsExternalEmail(to) {
 			// External email costs credits (unless admin)
 			if !acc.Admin {
 				canProceed, useFree, cost, err := wallet.CheckQuota(acc.ID, wallet.OpExternalEmail)
 				if err != nil || !canProceed {
+					deleteAttachments(atts)
 					http.Error(w, fmt.Sprintf("External email requires %d credits. Top up at /wallet", cost), http.StatusPaymentRequired)
 					return
 				}
@@ -483,8 +604,9 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			htmlBody := convertPlainTextToHTML(bodyPlain)
 
 			// Send multipart email with threading headers
-			messageID, err := SendExternalEmail(displayName, fromEmail, to, subject, bodyPlain, htmlBody, replyTo)
+			messageID, err := SendExternalEmailWithAttachments(displayName, fromEmail, to, subject, bodyPlain, htmlBody, replyTo, atts)
 			if err != nil {
+				deleteAttachments(atts)
 				http.Error(w, "Failed to send email: "+err.Error(), http.StatusInternalServerError)
 				return
 			}
@@ -497,7 +619,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Store plain text in sent messages - render to HTML only at display time
-			if err := SendMessage(acc.Name, acc.ID, to, to, subject, bodyPlain, replyTo, messageID); err != nil {
+			if err := SendMessageWithAttachments(acc.Name, acc.ID, to, to, subject, bodyPlain, replyTo, messageID, atts); err != nil {
 				app.Log("mail", "Warning: Failed to store sent message: %v", err)
 			}
 		} else {
@@ -505,18 +627,20 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			if !acc.Admin {
 				canProceed, _, cost, _ := wallet.CheckQuota(acc.ID, wallet.OpMailSend)
 				if !canProceed {
+					deleteAttachments(atts)
 					http.Error(w, fmt.Sprintf("Sending mail requires %d credits. Top up at /wallet", cost), http.StatusPaymentRequired)
 					return
 				}
 			}
 			toAcc, err := auth.GetAccount(to)
 			if err != nil {
+				deleteAttachments(atts)
 				http.Error(w, "Recipient not found", http.StatusNotFound)
 				return
 			}
 
 			app.Log("mail", "Sending internal message from %s to %s with replyTo=%s", acc.Name, toAcc.Name, replyTo)
-			if err := SendMessage(acc.Name, acc.ID, toAcc.Name, toAcc.ID, subject, bodyPlain, replyTo, ""); err != nil {
+			if err := SendMessageWithAttachments(acc.Name, acc.ID, toAcc.Name, toAcc.ID, subject, bodyPlain, replyTo, "", atts); err != nil {
 				http.Error(w, "Failed to send message", http.StatusInternalServerError)
 				return
 			}
@@ -525,6 +649,11 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// The message is sent, so its draft is no longer needed
+		if id := r.FormValue("draft_id"); id != "" {
+			DeleteDraft(acc.ID, id) //nolint:errcheck
+		}
+
 		// Redirect back to thread if replying, otherwise to inbox
 		// Check if this was a reply (has reply_to parameter or id in URL)
 		threadID := r.URL.Query().Get("id")
@@ -601,6 +730,12 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Handle download of a composed attachment
+	if action == "attachment" {
+		serveAttachment(w, r, acc.ID)
+		return
+	}
+
 	// Handle download attachment action
 	if action == "download_attachment" && msgID != "" {
 		mutex.RLock()
@@ -1012,7 +1147,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 		threadSkipBodyProcessing:
 			// Process email body - renders markdown if detected, otherwise linkifies URLs
-			msgBody = renderEmailBody(msgBody, msgIsAttachment)
+			msgBody = renderEmailBody(msgBody, msgIsAttachment) + renderAttachments(m)
 
 			isSent := m.FromID == acc.ID
 			authorDisplay := m.FromID
@@ -1033,13 +1168,15 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 				<div class="thread-message-header-text">
 					<span class="thread-message-author">%s</span> <span class="thread-message-time">· %s</span>
 				</div>
-				<a href="#" onclick="if(confirm('Delete this message?')){var form=document.createElement('form');form.method='POST';form.action='/mail';var input1=document.createElement('input');input1.type='hidden';input1.name='_method';input1.value='DELETE';form.appendChild(input1);var input2=document.createElement('input');input2.type='hidden';input2.name='id';input2.value='%s';form.appendChild(input2);var input3=document.createElement('input');input3.type='hidden';input3.name='return_to';input3.value='%s';form.appendChild(input3);document.body.appendChild(form);form.submit();}return false;" class="thread-message-delete">×</a>
+				%s
 			</div>
 			<div class="thread-message-body">%s</div>
 			<div class="mt-3 border-t pt-3 text-xs">
 				<a href="/mail?action=view_raw&id=%s" class="text-muted" target="_blank">View Raw</a>
 			</div>
-		</div>`, authorDisplay, app.TimeAgo(m.CreatedAt), m.ID, msgID, msgBody, m.ID))
+		</div>`, authorDisplay, app.TimeAgo(m.CreatedAt),
+				app.PostLink("/mail", map[string]string{"_method": "DELETE", "id": m.ID, "return_to": msgID}, "Delete this message?", "×", `class="thread-message-delete"`),
+				msgBody, m.ID))
 		}
 
 		// Determine the other party in the thread
@@ -1091,7 +1228,8 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 		messageView := fmt.Sprintf(`
 	%s
-	<div class="text-muted text-sm mb-5">Thread with: %s</div>
+	<div class="text-muted text-sm mb-5">Thread with: %s · <a href="/mail/chat?thread=%s" class="text-muted">Open as chat</a></div>
+	%s
 	%s
 	<div class="mt-6 border-t pt-5">
 		<form method="POST" action="/mail?id=%s" class="d-flex flex-column gap-4" onsubmit="var replyText=document.getElementById('reply-body').innerText.trim().replace(/\n{3,}/g,'\n\n');if(!replyText){alert('Please write a reply');return false;}document.getElementById('reply-body-plain').value=replyText;var replyHTML=replyText.replace(/&/g,'&amp;').replace(/</g,'&lt;').replace(/>/g,'&gt;').replace(/\n/g,'<br>');document.getElementById('reply-body-html').value=replyHTML;return true;">
@@ -1103,7 +1241,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			<div id="reply-body" contenteditable="true" class="mail-reply-box" placeholder="Write your reply..."></div>
 			<div class="d-flex gap-3 items-center">
 				<button type="submit">Send</button>
-				<a href="#" onclick="if(confirm('Delete this entire thread?')){var form=document.createElement('form');form.method='POST';form.action='/mail';var input1=document.createElement('input');input1.type='hidden';input1.name='action';input1.value='delete_thread';form.appendChild(input1);var input2=document.createElement('input');input2.type='hidden';input2.name='msg_id';input2.value='%s';form.appendChild(input2);document.body.appendChild(form);form.submit();}return false;" class="text-error text-sm">Delete Thread</a>
+				%s
 				%s
 			</div>
 		</form>
@@ -1111,8 +1249,8 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			<a href="%s" class="text-muted">← Back to mail</a>
 		</div>
 	</div>
-`, spamActions, otherPartyDisplay, threadHTML.String(), msgID, otherParty, replySubject, replyToID, msg.ID, blockButton, backToMail)
-		w.Write([]byte(app.RenderHTML(decodedSubject, "", messageView)))
+`, spamActions, otherPartyDisplay, threadID, renderThreadSummary(acc.ID, threadID), threadHTML.String(), msgID, otherParty, replySubject, replyToID, deleteThreadLink(msg.ID, "Delete this entire thread?", "Delete Thread", `class="text-error text-sm"`), blockButton, backToMail)
+		w.Write([]byte(app.RenderHTMLForRequest(decodedSubject, "", messageView, r)))
 		return
 	}
 
@@ -1121,6 +1259,17 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		to := r.URL.Query().Get("to")
 		subject := r.URL.Query().Get("subject")
 		replyTo := r.URL.Query().Get("reply_to")
+		var body, draftID string
+
+		// Resume a saved draft
+		if id := r.URL.Query().Get("draft"); id != "" {
+			d := GetDraft(acc.ID, id)
+			if d == nil {
+				app.NotFound(w, r, "Draft not found")
+				return
+			}
+			to, subject, body, replyTo, draftID = d.To, d.Subject, d.Body, d.ReplyTo, d.ID
+		}
 		// Determine back link and page title
 		backLink := "/mail"
 		pageTitle := "New Message"
@@ -1147,23 +1296,31 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		datalist := dl.String()
 
 		composeForm := fmt.Sprintf(`
-			<form method="POST" action="/mail" class="mail-form">
+			<form method="POST" action="/mail" class="mail-form" enctype="multipart/form-data" id="compose-form">
 				<input type="hidden" name="reply_to" value="%s">
+				<input type="hidden" name="draft_id" value="%s">
 				<input type="text" name="to" placeholder="To: username or email" value="%s" required autocomplete="off" list="mail-users">
 				%s
 				<input type="text" name="subject" placeholder="Subject" value="%s" required>
-				<textarea name="body" rows="10" placeholder="Write your message..." required></textarea>
+				<textarea name="body" data-mentions rows="10" placeholder="Write your message..." required>%s</textarea>
+				<label class="text-sm text-muted">Attachments (up to %d files, %dMB each) <input type="file" name="attachments" multiple></label>
+				<details class="text-sm text-muted"><summary>Send later</summary>
+					<input type="datetime-local" name="send_at_local"> <span class="text-xs">Leave empty to send now</span>
+				</details>
+				<input type="hidden" name="send_at" value="">
 			<div class="d-flex gap-3 items-center">
 				<button type="submit">Send</button>
 				<a href="%s" class="text-muted text-sm">Cancel</a>
+				<span id="draft-status" class="text-muted text-sm"></span>
 			</div>
 		</form>
 		<div class="mt-5">
 			<a href="%s" class="text-muted">← Back</a>
 		</div>
-		`, replyTo, to, datalist, subject, backLink, backLink)
+		%s`, html.EscapeString(replyTo), draftID, html.EscapeString(to), datalist, html.EscapeString(subject), html.EscapeString(body),
+			MaxAttachments, MaxAttachmentSize>>20, backLink, backLink, app.Script(r, draftAutosaveScript+sendLaterScript))
 
-		w.Write([]byte(app.RenderHTML(pageTitle, "", composeForm)))
+		w.Write([]byte(app.RenderHTMLForRequest(pageTitle, "", composeForm, r)))
 		return
 	}
 
@@ -1173,14 +1330,23 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		view = "inbox"
 	}
 
+	lang := app.GetUserLanguage(r)
+
 	// Handle search
 	if q := r.URL.Query().Get("q"); q != "" {
 		results := searchMail(acc.ID, q)
+
+		// JSON response for API callers
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"query": q, "results": results})
+			return
+		}
+
 		var content string
 		if len(results) == 0 {
-			content = fmt.Sprintf(`<p class="text-muted">No results for "%s"</p>`, html.EscapeString(q))
+			content = `<p class="text-muted">` + app.T(lang, "mail.no_results", html.EscapeString(q)) + `</p>`
 		} else {
-			content = fmt.Sprintf(`<p class="text-muted" style="margin-bottom:12px">%d results for "%s"</p>`, len(results), html.EscapeString(q))
+			content = `<p class="text-muted" style="margin-bottom:12px">` + app.T(lang, "mail.results", len(results), html.EscapeString(q)) + `</p>`
 			for _, msg := range results {
 				from := msg.From
 				if from == "" {
@@ -1188,9 +1354,9 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 				}
 				subject := msg.Subject
 				if subject == "" {
-					subject = "(no subject)"
+					subject = app.T(lang, "mail.no_subject")
 				}
-				body := msg.Body
+				body := stripHTMLTags(msg.Body)
 				if len(body) > 100 {
 					body = body[:100] + "..."
 				}
@@ -1201,17 +1367,12 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 </div>`, msg.ID, html.EscapeString(subject), html.EscapeString(from), html.EscapeString(body))
 			}
 		}
-		searchQuery := q
-		searchBar := fmt.Sprintf(`<form action="/mail" method="GET" style="margin-bottom:12px;display:flex;gap:8px">
-<input type="text" name="q" value="%s" placeholder="Search mail..." style="flex:1;padding:8px 12px;border:1px solid #e0e0e0;border-radius:6px;font-family:inherit;font-size:14px">
-<button type="submit" style="padding:8px 16px;background:#000;color:#fff;border:none;border-radius:6px;cursor:pointer;font-family:inherit;font-size:14px">Search</button>
-</form>`, html.EscapeString(searchQuery))
 		pageHTML := app.Page(app.PageOpts{
 			Action:  "/mail?compose=true",
-			Label:   "+ Compose",
-			Content: searchBar + content,
+			Label:   app.T(lang, "mail.compose"),
+			Content: mailSearchBar(lang, q) + content,
 		})
-		w.Write([]byte(app.RenderHTML("Mail — Search", "", pageHTML)))
+		w.Write([]byte(app.RenderHTMLForRequest(app.T(lang, "mail.search_title"), "", pageHTML, r)))
 		return
 	}
 
@@ -1252,20 +1413,23 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		userInbox = &Inbox{Threads: make(map[string]*Thread), UnreadCount: 0}
 	}
 
+	// Lists are paged with ?after=<thread or message id>&limit=N
+	after, limit := app.Cursor(r)
+	next := ""
+
 	// JSON response for API/MCP callers (mail_read tool)
 	if app.WantsJSON(r) {
-		threads := make([]*Thread, 0, len(userInbox.Threads))
-		for _, t := range userInbox.Threads {
-			threads = append(threads, t)
-		}
-		sort.Slice(threads, func(i, j int) bool {
-			return threads[i].Latest.CreatedAt.After(threads[j].Latest.CreatedAt)
-		})
-		msgs := make([]*Message, 0, len(threads))
-		for _, t := range threads {
+		threads := sortedThreads(userInbox, nil)
+		start, end, next := app.PageAfter(len(threads), func(i int) string { return threads[i].Root.ID }, after, limit)
+		msgs := make([]*Message, 0, end-start)
+		for _, t := range threads[start:end] {
 			msgs = append(msgs, t.Latest)
 		}
-		app.RespondJSON(w, map[string]interface{}{"messages": msgs, "unread": userInbox.UnreadCount})
+		resp := map[string]interface{}{"messages": msgs, "unread": userInbox.UnreadCount}
+		if next != "" {
+			resp["next"] = next
+		}
+		app.RespondJSON(w, resp)
 		return
 	}
 
@@ -1273,45 +1437,27 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	var items []string
 	unreadCount := userInbox.UnreadCount // Use cached count instead of recalculating
 	if view == "inbox" {
-		app.Log("mail", "Rendering inbox with %d threads for user %s", len(userInbox.Threads), acc.Name)
-
-		// Convert threads to slice for sorting
-		threads := make([]*Thread, 0, len(userInbox.Threads))
-		for _, thread := range userInbox.Threads {
-			threads = append(threads, thread)
-		}
-
-		// Sort threads by latest message time
-		sort.Slice(threads, func(i, j int) bool {
-			return threads[i].Latest.CreatedAt.After(threads[j].Latest.CreatedAt)
-		})
-
-		// Render threads
-		for _, thread := range threads {
-			// Show threads where user is either sender or recipient of any message
-			userInThread := false
-			for _, msg := range thread.Messages {
-				if msg.ToID == acc.ID {
-					userInThread = true
-					break
-				}
-			}
-			if userInThread {
-				// Inbox message - show latest preview, link to root
-				items = append(items, renderThreadPreview(thread.Root.ID, thread.Latest, acc.ID, thread.HasUnread))
-			}
+		// Show threads where the user received any message
+		threads := sortedThreads(userInbox, func(m *Message) bool { return m.ToID == acc.ID })
+		var start, end int
+		start, end, next = app.PageAfter(len(threads), func(i int) string { return threads[i].Root.ID }, after, limit)
+		for _, thread := range threads[start:end] {
+			// Inbox message - show latest preview, link to root
+			items = append(items, renderThreadPreview(thread.Root.ID, thread.Latest, acc.ID, thread.HasUnread))
 		}
 	} else if view == "filtered" {
 		// Filtered view - show spam messages using same card format as inbox
 		spamMsgs := GetSpamMessages(acc.ID)
-		for _, msg := range spamMsgs {
+		var start, end int
+		start, end, next = app.PageAfter(len(spamMsgs), func(i int) string { return spamMsgs[i].ID }, after, limit)
+		for _, msg := range spamMsgs[start:end] {
 			reasons := ""
 			if len(msg.SpamReasons) > 0 {
 				reasons = strings.Join(msg.SpamReasons, ", ")
 			}
 			subject := html.EscapeString(decodeMIMEHeader(msg.Subject))
 			if subject == "" {
-				subject = "(no subject)"
+				subject = app.T(lang, "mail.no_subject")
 			}
 			fromDisplay := html.EscapeString(msg.From)
 			if fromDisplay == "" {
@@ -1370,28 +1516,16 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 				html.EscapeString(reasons),
 			))
 		}
+	} else if view == "drafts" {
+		items = append(items, renderDrafts(acc.ID, lang))
+	} else if view == "scheduled" {
+		items = append(items, renderScheduled(acc.ID, lang))
 	} else {
 		// Sent view - show threads where user has sent at least one message
-		threads := make([]*Thread, 0)
-		for _, thread := range userInbox.Threads {
-			// Check if user has sent any message in this thread
-			hasSent := false
-			for _, msg := range thread.Messages {
-				if msg.FromID == acc.ID {
-					hasSent = true
-					break
-				}
-			}
-			if hasSent {
-				threads = append(threads, thread)
-			}
-		}
-
-		sort.Slice(threads, func(i, j int) bool {
-			return threads[i].Latest.CreatedAt.After(threads[j].Latest.CreatedAt)
-		})
-
-		for _, thread := range threads {
+		threads := sortedThreads(userInbox, func(m *Message) bool { return m.FromID == acc.ID })
+		var start, end int
+		start, end, next = app.PageAfter(len(threads), func(i int) string { return threads[i].Root.ID }, after, limit)
+		for _, thread := range threads[start:end] {
 			// Show latest message in thread, not just root
 			items = append(items, renderSentThreadPreview(thread.Root.ID, thread.Latest, acc.ID))
 		}
@@ -1400,79 +1534,143 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	content := ""
 	if len(items) == 0 {
 		if view == "sent" {
-			content = `<p class="text-muted p-5">No sent messages yet.</p>`
+			content = `<p class="text-muted p-5">` + app.T(lang, "mail.no_sent") + `</p>`
 		} else if view == "filtered" {
-			content = `<p class="text-muted p-5">No filtered messages.</p>`
+			content = `<p class="text-muted p-5">` + app.T(lang, "mail.no_filtered") + `</p>`
 		} else {
-			content = `<p class="text-muted p-5">No messages yet.</p>`
+			content = `<p class="text-muted p-5">` + app.T(lang, "mail.no_messages") + `</p>`
 		}
 	} else {
 		content = strings.Join(items, "")
 	}
+	loadMore := ""
+	if next != "" {
+		href := fmt.Sprintf("/mail?after=%s&limit=%d", url.QueryEscape(next), limit)
+		if view != "inbox" {
+			href += "&view=" + url.QueryEscape(view)
+		}
+		loadMore = `<div class="mt-4">` + app.LoadMore("mailbox", href) + `</div>`
+	}
 
-	title := "Mail"
+	title := app.T(lang, "mail.title")
 	if view == "sent" {
-		title = "Sent Mail"
+		title = app.T(lang, "mail.sent_title")
+	} else if view == "drafts" {
+		title = app.T(lang, "mail.drafts")
+	} else if view == "scheduled" {
+		title = app.T(lang, "mail.scheduled")
 	} else if view == "filtered" {
-		title = "Filtered Mail"
+		title = app.T(lang, "mail.filtered_title")
 	} else if unreadCount > 0 {
-		title = fmt.Sprintf("Mail (%d new)", unreadCount)
+		title = app.T(lang, "mail.title_new", unreadCount)
 	}
 
 	// Build tab navigation
 	inboxClass := "mail-tab active"
 	sentClass := "mail-tab"
 	filteredClass := "mail-tab"
+	draftsClass := "mail-tab"
+	scheduledClass := "mail-tab"
 	if view == "sent" {
 		inboxClass = "mail-tab"
 		sentClass = "mail-tab active"
+	} else if view == "drafts" {
+		inboxClass = "mail-tab"
+		draftsClass = "mail-tab active"
+	} else if view == "scheduled" {
+		inboxClass = "mail-tab"
+		scheduledClass = "mail-tab active"
 	} else if view == "filtered" {
 		inboxClass = "mail-tab"
 		filteredClass = "mail-tab active"
 	}
-	inboxLabel := "Inbox"
+	inboxLabel := app.T(lang, "mail.inbox")
 	if unreadCount > 0 {
-		inboxLabel = fmt.Sprintf("Inbox (%d)", unreadCount)
+		inboxLabel = app.T(lang, "mail.inbox_count", unreadCount)
 	}
 	spamMsgs := GetSpamMessages(acc.ID)
-	filteredLabel := "Filtered"
+	filteredLabel := app.T(lang, "mail.filtered")
 	if len(spamMsgs) > 0 {
-		filteredLabel = fmt.Sprintf("Filtered (%d)", len(spamMsgs))
+		filteredLabel = app.T(lang, "mail.filtered_count", len(spamMsgs))
 	}
-	tabs := fmt.Sprintf(`<div class="mail-tabs"><a href="/mail" class="%s">%s</a><a href="/mail?view=sent" class="%s">Sent</a><a href="/mail?view=filtered" class="%s">%s</a></div>`,
-		inboxClass, inboxLabel, sentClass, filteredClass, filteredLabel)
-
-	// Search bar
-	searchQuery := r.URL.Query().Get("q")
-	searchBar := fmt.Sprintf(`<form action="/mail" method="GET" style="margin-bottom:12px;display:flex;gap:8px">
-<input type="text" name="q" value="%s" placeholder="Search mail..." style="flex:1;padding:8px 12px;border:1px solid #e0e0e0;border-radius:6px;font-family:inherit;font-size:14px">
-<button type="submit" style="padding:8px 16px;background:#000;color:#fff;border:none;border-radius:6px;cursor:pointer;font-family:inherit;font-size:14px">Search</button>
-</form>`, html.EscapeString(searchQuery))
+	draftsLabel := app.T(lang, "mail.drafts")
+	if n := len(GetDrafts(acc.ID)); n > 0 {
+		draftsLabel = app.T(lang, "mail.drafts_count", n)
+	}
+	// The scheduled tab only shows while something is queued
+	scheduledTab := ""
+	if n := len(GetScheduled(acc.ID)); n > 0 || view == "scheduled" {
+		scheduledTab = fmt.Sprintf(`<a href="/mail?view=scheduled" class="%s">%s</a>`, scheduledClass, app.T(lang, "mail.scheduled_count", n))
+	}
+	tabs := fmt.Sprintf(`<div class="mail-tabs"><a href="/mail" class="%s">%s</a><a href="/mail?view=sent" class="%s">%s</a><a href="/mail?view=drafts" class="%s">%s</a>%s<a href="/mail?view=filtered" class="%s">%s</a></div>`,
+		inboxClass, inboxLabel, sentClass, app.T(lang, "mail.sent"), draftsClass, draftsLabel, scheduledTab, filteredClass, filteredLabel)
 
 	pageHTML := app.Page(app.PageOpts{
 		Action:  "/mail?compose=true",
-		Label:   "+ Compose",
+		Label:   app.T(lang, "mail.compose"),
 		Filters: tabs,
-		Content: searchBar + `<div id="mailbox">` + content + `</div>`,
+		Content: mailSearchBar(lang, r.URL.Query().Get("q")) + `<div id="mailbox">` + content + `</div>` + loadMore,
 	})
 
-	w.Write([]byte(app.RenderHTML(title, "Your messages", pageHTML)))
+	w.Write([]byte(app.RenderHTMLForRequest(title, app.T(lang, "mail.description"), pageHTML, r)))
+}
+
+// mailSearchBar is the search form above the mailbox and search results.
+func mailSearchBar(lang, q string) string {
+	return fmt.Sprintf(`<form action="/mail" method="GET" style="margin-bottom:12px;display:flex;gap:8px">
+<input type="text" name="q" value="%s" placeholder="%s" style="flex:1;padding:8px 12px;border:1px solid #e0e0e0;border-radius:6px;font-family:inherit;font-size:14px">
+<button type="submit" style="padding:8px 16px;background:#000;color:#fff;border:none;border-radius:6px;cursor:pointer;font-family:inherit;font-size:14px">%s</button>
+</form>`, html.EscapeString(q), app.T(lang, "mail.search_placeholder"), app.T(lang, "mail.search"))
+}
+
+// sortedThreads returns the inbox's threads, newest activity first. With
+// include set, only threads with at least one message it accepts.
+func sortedThreads(inbox *Inbox, include func(*Message) bool) []*Thread {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	threads := make([]*Thread, 0, len(inbox.Threads))
+	for _, thread := range inbox.Threads {
+		if include != nil {
+			found := false
+			for _, msg := range thread.Messages {
+				if include(msg) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		threads = append(threads, thread)
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].Latest.CreatedAt.After(threads[j].Latest.CreatedAt)
+	})
+	return threads
 }
 
 // renderThreadPreview renders a thread preview showing the latest message but linking to root
 func SendMessage(from, fromID, to, toID, subject, body, replyTo, messageID string) error {
+	return SendMessageWithAttachments(from, fromID, to, toID, subject, body, replyTo, messageID, nil)
+}
+
+// SendMessageWithAttachments stores a message along with attachments
+// previously saved with SaveAttachment.
+func SendMessageWithAttachments(from, fromID, to, toID, subject, body, replyTo, messageID string, attachments []Attachment) error {
 	msg := &Message{
-		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
-		From:      from,
-		FromID:    fromID,
-		To:        to,
-		ToID:      toID,
-		Subject:   subject,
-		Body:      body,
-		Read:      false,
-		ReplyTo:   replyTo,
-		MessageID: messageID,
-		CreatedAt: time.Now(),
+		ID:          fmt.Sprintf("%d", time.Now().UnixNano()),
+		From:        from,
+		FromID:      fromID,
+		To:          to,
+		ToID:        toID,
+		Subject:     subject,
+		Body:        body,
+		Read:        false,
+		ReplyTo:     replyTo,
+		MessageID:   messageID,
+		Attachments: attachments,
+		CreatedAt:   time.Now(),
 	}
 
 	// Compute ThreadID
@@ -1490,11 +1688,12 @@ func SendMessage(from, fromID, to, toID, subject, body, replyTo, messageID strin
 
 	messages = append([]*Message{msg}, messages...)
 	rebuildInboxes()
-	err := save()
+	err := putMessages(msg)
 	mutex.Unlock()
 
-	// Update stats (outside lock)
+	// Update stats and search index (outside lock)
 	updateStats(msg)
+	indexMessage(msg)
 
 	return err
 }
@@ -1535,13 +1734,14 @@ func SendMessageTagged(from, fromID, to, toID, subject, body, replyTo, messageID
 
 	messages = append([]*Message{msg}, messages...)
 	rebuildInboxes()
-	err := save()
+	err := putMessages(msg)
 	mutex.Unlock()
 
 	// Update stats (outside lock) — only for non-spam
 	if !spam {
 		updateStats(msg)
 	}
+	indexMessage(msg)
 
 	// Notify on new inbound mail (non-spam, to a local user)
 	if !spam && toID != "" && OnNewMail != nil {
@@ -1566,6 +1766,44 @@ func GetUnreadCount(userID string) int {
 	return 0
 }
 
+// UnreadSenders returns the display names of up to limit senders with
+// unread mail for a user, most recent first, each listed once.
+func UnreadSenders(userID string, limit int) []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	inbox := inboxes[userID]
+	if inbox == nil || inbox.UnreadCount == 0 {
+		return nil
+	}
+	var unread []*Message
+	for _, t := range inbox.Threads {
+		for _, m := range t.Messages {
+			if m.ToID == userID && !m.Read && !m.Spam {
+				unread = append(unread, m)
+			}
+		}
+	}
+	sort.Slice(unread, func(i, j int) bool { return unread[i].CreatedAt.After(unread[j].CreatedAt) })
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range unread {
+		name := m.From
+		if name == "" {
+			name = m.FromID
+		}
+		if seen[m.FromID] {
+			continue
+		}
+		seen[m.FromID] = true
+		out = append(out, name)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
 // Search returns up to limit non-spam messages belonging to userID (as sender
 // or recipient) that match the query, most relevant first. It searches the
 // in-memory (decrypted) messages directly and is strictly scoped to the
@@ -1685,8 +1923,9 @@ func DeleteSpamMessage(userID, msgID string) error {
 
 	for i, msg := range messages {
 		if msg.ID == msgID && msg.ToID == userID && msg.Spam {
+			unindexMessage(msg)
 			messages = append(messages[:i], messages[i+1:]...)
-			return save()
+			return deleteMessages(msgID)
 		}
 	}
 	return fmt.Errorf("spam message not found")
@@ -1703,14 +1942,20 @@ func NotSpamMessage(userID, msgID string) error {
 			msg.SpamScore = 0
 			msg.SpamReasons = nil
 			rebuildInboxes()
-			return save()
+			return putMessages(msg)
 		}
 	}
 	return fmt.Errorf("spam message not found")
 }
 
 // searchMail finds messages matching a query for a user.
+// Whole words are looked up in the search index; if that finds nothing
+// (e.g. a partial word), the in-memory messages are scanned instead.
 func searchMail(userID, query string) []*Message {
+	if results := IndexedSearch(userID, query, 50); len(results) > 0 {
+		return results
+	}
+
 	query = strings.ToLower(query)
 	mutex.RLock()
 	defer mutex.RUnlock()
@@ -1836,9 +2081,9 @@ func MarkAsRead(msgID, userID string) error {
 						thread.HasUnread = hasUnread
 					}
 				}
+				return putMessages(msg)
 			}
-
-			return save()
+			return nil
 		}
 	}
 	return fmt.Errorf("message not found")
@@ -1870,9 +2115,11 @@ func DeleteMessage(msgID, userID string) error {
 	for i, msg := range messages {
 		// Allow deletion if user is sender or recipient
 		if msg.ID == msgID && (msg.FromID == userID || msg.ToID == userID) {
+			deleteAttachments(msg.Attachments)
+			unindexMessage(msg)
 			messages = append(messages[:i], messages[i+1:]...)
 			rebuildInboxes()
-			return save()
+			return deleteMessages(msgID)
 		}
 	}
 	return fmt.Errorf("message not found")
@@ -1904,9 +2151,14 @@ func DeleteThread(msgID, userID string) error {
 
 	// Delete all messages in this thread
 	var remaining []*Message
+	var removed []string
 	for _, m := range messages {
 		if m.ThreadID != threadID {
 			remaining = append(remaining, m)
+		} else {
+			deleteAttachments(m.Attachments)
+			unindexMessage(m)
+			removed = append(removed, m.ID)
 		}
 	}
 
@@ -1917,8 +2169,9 @@ func DeleteThread(msgID, userID string) error {
 
 	messages = remaining
 	rebuildInboxes()
+	deleteThreadSummary(userID, threadID)
 	app.Log("mail", "Deleted %d messages from thread for user %s", deleted, userID)
-	return save()
+	return deleteMessages(removed...)
 }
 
 // GetAllMessages returns all messages (for admin use)
@@ -2043,4 +2296,36 @@ func DeleteInbox(userID string) {
 	mutex.Unlock()
 	// Re-save all mail data.
 	save()
+	DeleteUserDrafts(userID)
+	DeleteUserSummaries(userID)
+	DeleteUserScheduled(userID)
+}
+
+// RenameInbox moves a renamed user's mail to their new ID, along with
+// their drafts, summaries and scheduled mail. Names shown on messages
+// follow when they were just the old ID.
+func RenameInbox(oldID, newID string) {
+	mutex.Lock()
+	for _, m := range messages {
+		if m.FromID == oldID {
+			m.FromID = newID
+			if m.From == oldID {
+				m.From = newID
+			}
+		}
+		if m.ToID == oldID {
+			m.ToID = newID
+			if m.To == oldID {
+				m.To = newID
+			}
+		}
+	}
+	rebuildInboxes()
+	if err := save(); err != nil {
+		app.Log("mail", "Failed to save mail after renaming %s: %v", oldID, err)
+	}
+	mutex.Unlock()
+	renameUserDrafts(oldID, newID)
+	renameUserSummaries(oldID, newID)
+	renameUserScheduled(oldID, newID)
 }
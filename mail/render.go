@@ -1,12 +1,24 @@
 package mail
 
 import (
+	"embed"
 	"fmt"
 	"strings"
 
 	"mu/internal/app"
 )
 
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// templates are the mail pages built with html/template.
+var templates = app.ParseTemplates(templateFiles, "templates/*.html")
+
+// deleteThreadLink renders a link that deletes the thread msgID is in.
+func deleteThreadLink(msgID, confirmMsg, label, attrs string) string {
+	return app.PostLink("/mail", map[string]string{"action": "delete_thread", "msg_id": msgID}, confirmMsg, label, attrs)
+}
+
 // renderThreadPreview renders a thread preview showing the latest message but linking to root
 func renderThreadPreview(rootID string, latestMsg *Message, viewerID string, hasUnread bool) string {
 	unreadIndicator := ""
@@ -41,8 +53,8 @@ func renderThreadPreview(rootID string, latestMsg *Message, viewerID string, has
 	relativeTime := app.TimeAgo(latestMsg.CreatedAt)
 
 	html := fmt.Sprintf(`
-		<div class="thread-preview card" onclick="window.location.href='/mail?id=%s'">
-			<a href="#" class="delete-btn" onclick="event.stopPropagation(); if(confirm('Delete this conversation?')){var form=document.createElement('form');form.method='POST';form.action='/mail';var input1=document.createElement('input');input1.type='hidden';input1.name='action';input1.value='delete_thread';form.appendChild(input1);var input2=document.createElement('input');input2.type='hidden';input2.name='msg_id';input2.value='%s';form.appendChild(input2);document.body.appendChild(form);form.submit();}return false;" title="Delete conversation">×</a>
+		<div class="thread-preview card" data-href="/mail?id=%s">
+			%s
 			<div class="mail-thread-item">
 				<strong class="mail-thread-subject">%s%s</strong>
 			</div>
@@ -52,7 +64,7 @@ func renderThreadPreview(rootID string, latestMsg *Message, viewerID string, has
 				<span class="mail-thread-time">%s</span>
 			</div>
 		</div>
-	`, rootID, rootID, unreadIndicator, fromDisplay, decodeMIMEHeader(latestMsg.Subject), bodyPreview, relativeTime)
+	`, rootID, deleteThreadLink(rootID, "Delete this conversation?", "×", `class="delete-btn" title="Delete conversation"`), unreadIndicator, fromDisplay, decodeMIMEHeader(latestMsg.Subject), bodyPreview, relativeTime)
 
 	return html
 }
@@ -88,8 +100,8 @@ func renderSentThreadPreview(rootID string, latestMsg *Message, viewerID string)
 	relativeTime := app.TimeAgo(latestMsg.CreatedAt)
 
 	html := fmt.Sprintf(`
-		<div class="thread-preview card" onclick="window.location.href='/mail?id=%s'">
-			<a href="#" class="delete-btn" onclick="event.stopPropagation(); if(confirm('Delete this conversation?')){var form=document.createElement('form');form.method='POST';form.action='/mail';var input1=document.createElement('input');input1.type='hidden';input1.name='action';input1.value='delete_thread';form.appendChild(input1);var input2=document.createElement('input');input2.type='hidden';input2.name='msg_id';input2.value='%s';form.appendChild(input2);document.body.appendChild(form);form.submit();}return false;" title="Delete conversation">×</a>
+		<div class="thread-preview card" data-href="/mail?id=%s">
+			%s
 			<div class="mail-thread-item">
 				<strong class="mail-thread-subject">%s</strong>
 			</div>
@@ -99,7 +111,7 @@ func renderSentThreadPreview(rootID string, latestMsg *Message, viewerID string)
 				<span class="mail-thread-time">%s</span>
 			</div>
 		</div>
-	`, rootID, rootID, decodeMIMEHeader(latestMsg.Subject), toDisplay, bodyPreview, relativeTime)
+	`, rootID, deleteThreadLink(rootID, "Delete this conversation?", "×", `class="delete-btn" title="Delete conversation"`), decodeMIMEHeader(latestMsg.Subject), toDisplay, bodyPreview, relativeTime)
 
 	return html
 }
@@ -0,0 +1,16 @@
+package mail
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/mail", Handler, app.Authenticated)
+	r.HandleFunc("/mail/draft", DraftHandler, app.Authenticated)         // compose autosave
+	r.HandleFunc("/mail/summary", SummaryHandler, app.Authenticated)     // thread summaries
+	r.HandleFunc("/mail/scheduled", ScheduledHandler, app.Authenticated) // send-later queue
+	r.HandleFunc("/mail/chat", ChatHandler, app.Authenticated)           // thread as chat, chat to mail
+	r.HandleFunc("/mail/chat/forward", ChatHandler, app.Authenticated)
+}
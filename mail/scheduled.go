@@ -0,0 +1,410 @@
+package mail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/wallet"
+)
+
+// Scheduled is an outbound message waiting to be sent at SendAt. The
+// scheduler sends it the same way as the compose form would have, charging
+// the wallet at send time. Stored in mail_scheduled.json with the same field
+// encryption as messages.
+type Scheduled struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"user_id"`
+	To          string       `json:"to"`
+	Subject     string       `json:"subject"`
+	Body        string       `json:"body"`
+	ReplyTo     string       `json:"reply_to,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	SendAt      time.Time    `json:"send_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Error       string       `json:"error,omitempty"` // why sending failed; failed messages are kept until cancelled
+}
+
+// Scheduling limits.
+const (
+	MaxScheduled      = 50
+	MaxScheduleAhead  = 365 * 24 * time.Hour
+	schedulerInterval = 30 * time.Second
+)
+
+var (
+	ErrTooManyScheduled  = fmt.Errorf("at most %d scheduled messages", MaxScheduled)
+	ErrSendAtInPast      = errors.New("send time must be in the future")
+	ErrSendAtTooFar      = errors.New("send time must be within a year")
+	ErrScheduledNotFound = errors.New("scheduled message not found")
+)
+
+var (
+	scheduledMu sync.Mutex
+	scheduled   []*Scheduled
+)
+
+func loadScheduled() {
+	b, err := data.LoadFile("mail_scheduled.json")
+	if err != nil {
+		return
+	}
+	var stored []*Scheduled
+	if err := json.Unmarshal(b, &stored); err != nil {
+		app.Log("mail", "Failed to parse scheduled mail: %v", err)
+		return
+	}
+	for _, s := range stored {
+		var err error
+		if s.To, err = decrypt(s.To); err == nil {
+			if s.Subject, err = decrypt(s.Subject); err == nil {
+				s.Body, err = decrypt(s.Body)
+			}
+		}
+		if err != nil {
+			app.Log("mail", "WARNING: Failed to decrypt scheduled message %s: %v", s.ID, err)
+		}
+	}
+	scheduledMu.Lock()
+	scheduled = stored
+	scheduledMu.Unlock()
+}
+
+// saveScheduled persists the queue. Caller must hold scheduledMu.
+func saveScheduled() error {
+	out := make([]*Scheduled, len(scheduled))
+	for i, s := range scheduled {
+		cp := *s
+		var err error
+		if cp.To, err = encrypt(cp.To); err != nil {
+			return err
+		}
+		if cp.Subject, err = encrypt(cp.Subject); err != nil {
+			return err
+		}
+		if cp.Body, err = encrypt(cp.Body); err != nil {
+			return err
+		}
+		out[i] = &cp
+	}
+	return data.SaveJSON("mail_scheduled.json", out)
+}
+
+// ScheduleMessage queues a message to be sent at sendAt.
+func ScheduleMessage(s Scheduled) (*Scheduled, error) {
+	now := time.Now()
+	if !s.SendAt.After(now) {
+		return nil, ErrSendAtInPast
+	}
+	if s.SendAt.After(now.Add(MaxScheduleAhead)) {
+		return nil, ErrSendAtTooFar
+	}
+
+	scheduledMu.Lock()
+	defer scheduledMu.Unlock()
+	count := 0
+	for _, q := range scheduled {
+		if q.UserID == s.UserID {
+			count++
+		}
+	}
+	if count >= MaxScheduled {
+		return nil, ErrTooManyScheduled
+	}
+	s.ID = fmt.Sprintf("%d", now.UnixNano())
+	s.CreatedAt = now
+	s.Error = ""
+	scheduled = append(scheduled, &s)
+	if err := saveScheduled(); err != nil {
+		scheduled = scheduled[:len(scheduled)-1]
+		return nil, err
+	}
+	cp := s
+	return &cp, nil
+}
+
+// GetScheduled returns the user's scheduled messages, soonest first.
+func GetScheduled(userID string) []*Scheduled {
+	scheduledMu.Lock()
+	defer scheduledMu.Unlock()
+	out := []*Scheduled{}
+	for _, s := range scheduled {
+		if s.UserID == userID {
+			cp := *s
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SendAt.Before(out[j].SendAt) })
+	return out
+}
+
+// CancelScheduled removes one of the user's scheduled messages and its
+// attachments.
+func CancelScheduled(userID, id string) error {
+	scheduledMu.Lock()
+	defer scheduledMu.Unlock()
+	for i, s := range scheduled {
+		if s.ID == id && s.UserID == userID {
+			scheduled = append(scheduled[:i:i], scheduled[i+1:]...)
+			deleteAttachments(s.Attachments)
+			return saveScheduled()
+		}
+	}
+	return ErrScheduledNotFound
+}
+
+// DeleteUserScheduled removes all of a user's scheduled messages. Called
+// when an account is deleted.
+func DeleteUserScheduled(userID string) {
+	scheduledMu.Lock()
+	defer scheduledMu.Unlock()
+	kept := scheduled[:0]
+	for _, s := range scheduled {
+		if s.UserID == userID {
+			deleteAttachments(s.Attachments)
+			continue
+		}
+		kept = append(kept, s)
+	}
+	if len(kept) == len(scheduled) {
+		return
+	}
+	for i := len(kept); i < len(scheduled); i++ {
+		scheduled[i] = nil
+	}
+	scheduled = kept
+	if err := saveScheduled(); err != nil {
+		app.Log("mail", "Failed to save scheduled mail: %v", err)
+	}
+}
+
+// renameUserScheduled moves a renamed user's scheduled mail to their new
+// ID, and readdresses mail scheduled to them.
+func renameUserScheduled(oldID, newID string) {
+	scheduledMu.Lock()
+	defer scheduledMu.Unlock()
+	renamed := false
+	for _, s := range scheduled {
+		if s.UserID == oldID {
+			s.UserID = newID
+			renamed = true
+		}
+		if s.To == oldID {
+			s.To = newID
+			renamed = true
+		}
+	}
+	if !renamed {
+		return
+	}
+	if err := saveScheduled(); err != nil {
+		app.Log("mail", "Failed to save scheduled mail: %v", err)
+	}
+}
+
+// sendScheduled sends a due message as its author, the same way the compose
+// form does: external addresses go out over SMTP, everything is stored in
+// the author's sent mail, and the wallet is charged after a successful send.
+func sendScheduled(s *Scheduled) error {
+	acc, err := auth.GetAccount(s.UserID)
+	if err != nil {
+		return errors.New("account not found")
+	}
+
+	op := wallet.OpMailSend
+	if IsExternalEmail(s.To) {
+		op = wallet.OpExternalEmail
+	}
+	if !acc.Admin {
+		if canProceed, _, cost, _ := wallet.CheckQuota(acc.ID, op); !canProceed {
+			return fmt.Errorf("not enough credits (%d needed)", cost)
+		}
+	}
+
+	if IsExternalEmail(s.To) {
+		fromEmail := GetEmailForUser(acc.ID, GetConfiguredDomain())
+		messageID, err := SendExternalEmailWithAttachments(acc.Name, fromEmail, s.To, s.Subject, s.Body, convertPlainTextToHTML(s.Body), s.ReplyTo, s.Attachments)
+		if err != nil {
+			return err
+		}
+		if err := SendMessageWithAttachments(acc.Name, acc.ID, s.To, s.To, s.Subject, s.Body, s.ReplyTo, messageID, s.Attachments); err != nil {
+			app.Log("mail", "Warning: Failed to store sent message: %v", err)
+		}
+	} else {
+		toAcc, err := auth.GetAccount(s.To)
+		if err != nil {
+			return errors.New("recipient not found")
+		}
+		if err := SendMessageWithAttachments(acc.Name, acc.ID, toAcc.Name, toAcc.ID, s.Subject, s.Body, s.ReplyTo, "", s.Attachments); err != nil {
+			return err
+		}
+	}
+
+	if !acc.Admin {
+		wallet.ConsumeQuota(acc.ID, op) //nolint:errcheck
+	}
+	return nil
+}
+
+// deliverScheduled sends a due scheduled message. Swapped out in tests.
+var deliverScheduled = sendScheduled
+
+// flushScheduled sends every message due by now. Sent messages leave the
+// queue; failed ones stay with their error so the author can see what
+// happened and cancel them.
+func flushScheduled(now time.Time) {
+	scheduledMu.Lock()
+	var due []*Scheduled
+	for _, s := range scheduled {
+		if s.Error == "" && !s.SendAt.After(now) {
+			due = append(due, s)
+		}
+	}
+	scheduledMu.Unlock()
+
+	for _, s := range due {
+		err := deliverScheduled(s)
+
+		scheduledMu.Lock()
+		for i, q := range scheduled {
+			if q != s {
+				continue
+			}
+			if err != nil {
+				q.Error = err.Error()
+			} else {
+				scheduled = append(scheduled[:i:i], scheduled[i+1:]...)
+			}
+			break
+		}
+		if saveErr := saveScheduled(); saveErr != nil {
+			app.Log("mail", "Failed to save scheduled mail: %v", saveErr)
+		}
+		scheduledMu.Unlock()
+
+		if err != nil {
+			app.Log("mail", "Scheduled message %s from %s failed: %v", s.ID, s.UserID, err)
+		} else {
+			app.Log("mail", "Sent scheduled message %s from %s", s.ID, s.UserID)
+		}
+	}
+}
+
+var schedulerOnce sync.Once
+
+// StartScheduler starts the background loop that sends scheduled mail when
+// it is due. Not started in read-only mode.
+func StartScheduler() {
+	schedulerOnce.Do(func() {
+		go func() {
+			for {
+				flushScheduled(time.Now())
+				time.Sleep(schedulerInterval)
+			}
+		}()
+	})
+}
+
+// parseSendAt parses the send-later time from the compose form or API: the
+// form's script submits RFC 3339 in UTC, and a bare datetime-local value is
+// read as UTC.
+func parseSendAt(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02T15:04", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("invalid send time")
+}
+
+// scheduleFromRequest queues a message from the compose form or API.
+// Internal recipients are checked now rather than failing at send time.
+func scheduleFromRequest(userID, to, subject, body, replyTo, sendAt string, atts []Attachment) (*Scheduled, error) {
+	at, err := parseSendAt(sendAt)
+	if err != nil {
+		return nil, err
+	}
+	if !IsExternalEmail(to) {
+		if _, err := auth.GetAccount(to); err != nil {
+			return nil, errors.New("recipient not found")
+		}
+	}
+	return ScheduleMessage(Scheduled{
+		UserID:      userID,
+		To:          to,
+		Subject:     subject,
+		Body:        body,
+		ReplyTo:     replyTo,
+		Attachments: atts,
+		SendAt:      at,
+	})
+}
+
+// ScheduledHandler serves /mail/scheduled. GET lists the user's scheduled
+// messages as JSON; POST with _method=DELETE, or DELETE, cancels one.
+func ScheduledHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		app.RespondJSON(w, map[string]interface{}{"scheduled": GetScheduled(acc.ID)})
+		return
+	case "POST", "DELETE":
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		id = r.FormValue("id")
+	}
+	if r.Method == "POST" && r.FormValue("_method") != "DELETE" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	if err := CancelScheduled(acc.ID, id); err != nil {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.RespondError(w, http.StatusNotFound, err.Error())
+		} else {
+			app.NotFound(w, r, "Scheduled message not found")
+		}
+		return
+	}
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		app.RespondJSON(w, map[string]bool{"success": true})
+		return
+	}
+	http.Redirect(w, r, "/mail?view=scheduled", http.StatusSeeOther)
+}
+
+// renderScheduled renders the scheduled tab in lang.
+func renderScheduled(userID, lang string) string {
+	return templates.HTML("scheduled", map[string]interface{}{"Lang": lang, "Scheduled": GetScheduled(userID)})
+}
+
+// sendLaterScript converts the compose form's local "send later" time to
+// UTC before submitting, so the server doesn't need the browser's timezone.
+const sendLaterScript = `
+(function(){
+  var f=document.getElementById('compose-form');if(!f)return;
+  f.addEventListener('submit',function(){
+    var l=f.elements.send_at_local,v=l&&l.value;
+    f.elements.send_at.value=v?new Date(v).toISOString():'';
+  });
+})();
+`
@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestScheduledSend verifies queued messages are sent once due, failures
+// stay queued with their error, and cancelling is limited to the author.
+func TestScheduledSend(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	scheduledMu.Lock()
+	scheduled = nil
+	scheduledMu.Unlock()
+
+	var sent []string
+	orig := deliverScheduled
+	defer func() { deliverScheduled = orig }()
+	deliverScheduled = func(s *Scheduled) error {
+		if s.To == "nobody" {
+			return errors.New("recipient not found")
+		}
+		sent = append(sent, s.Subject)
+		return nil
+	}
+
+	now := time.Now()
+	if _, err := ScheduleMessage(Scheduled{UserID: "alice", To: "bob", Subject: "past", SendAt: now.Add(-time.Minute)}); err != ErrSendAtInPast {
+		t.Fatalf("past send time: got %v, want ErrSendAtInPast", err)
+	}
+	soon, err := ScheduleMessage(Scheduled{UserID: "alice", To: "bob", Subject: "soon", Body: "hi", SendAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := ScheduleMessage(Scheduled{UserID: "alice", To: "bob", Subject: "later", SendAt: now.Add(48 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ScheduleMessage(Scheduled{UserID: "alice", To: "nobody", Subject: "bad", SendAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The queue survives a restart.
+	loadScheduled()
+	if list := GetScheduled("alice"); len(list) != 3 || list[0].ID != soon.ID || list[2].ID != later.ID {
+		t.Fatalf("GetScheduled = %+v", list)
+	}
+
+	// Nothing is due yet.
+	flushScheduled(now)
+	if len(sent) != 0 {
+		t.Fatalf("sent early: %v", sent)
+	}
+
+	// Two hours on: "soon" is sent, "bad" fails and stays with its error.
+	flushScheduled(now.Add(2 * time.Hour))
+	if len(sent) != 1 || sent[0] != "soon" {
+		t.Fatalf("sent = %v, want [soon]", sent)
+	}
+	list := GetScheduled("alice")
+	if len(list) != 2 {
+		t.Fatalf("queue = %+v, want later and bad", list)
+	}
+	var bad *Scheduled
+	for _, s := range list {
+		if s.Subject == "bad" {
+			bad = s
+		}
+	}
+	if bad == nil || bad.Error == "" {
+		t.Fatalf("failed message should stay queued with its error: %+v", list)
+	}
+	// A failed message isn't retried.
+	flushScheduled(now.Add(3 * time.Hour))
+	if len(sent) != 1 {
+		t.Fatalf("sent = %v after retry window", sent)
+	}
+
+	// Only the author can cancel.
+	if err := CancelScheduled("bob", later.ID); err != ErrScheduledNotFound {
+		t.Fatalf("bob cancelled alice's message: %v", err)
+	}
+	if err := CancelScheduled("alice", later.ID); err != nil {
+		t.Fatal(err)
+	}
+	DeleteUserScheduled("alice")
+	if list := GetScheduled("alice"); len(list) != 0 {
+		t.Fatalf("queue not cleared: %+v", list)
+	}
+}
+
+func TestParseSendAt(t *testing.T) {
+	want := time.Date(2030, 5, 1, 9, 30, 0, 0, time.UTC)
+	for _, v := range []string{"2030-05-01T09:30:00Z", "2030-05-01T10:30:00+01:00", "2030-05-01T09:30"} {
+		got, err := parseSendAt(v)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSendAt(%q) = %v, %v; want %v", v, got, err, want)
+		}
+	}
+	if _, err := parseSendAt("tomorrow"); err == nil {
+		t.Error("parseSendAt accepted garbage")
+	}
+}
@@ -1,6 +1,13 @@
 package mail
 
-import "testing"
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/data"
+)
 
 // TestSearchScopedToAccount verifies mail.Search only ever returns messages
 // belonging to the requesting account, and never spam.
@@ -36,3 +43,56 @@ func idsOf(ms []*Message) []string {
 	}
 	return out
 }
+
+// TestIndexedSearch verifies mail is found through the owner-scoped index,
+// that every query word must match, and that the index holds no plaintext.
+func TestIndexedSearch(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	data.UseSQLite = false
+	data.StartIndexing()
+
+	msgs := []*Message{
+		{ID: "i1", From: "bob", FromID: "bob", To: "alice", ToID: "alice", Subject: "Quarterly invoice", Body: "<p>The invoice for March is attached</p>", CreatedAt: time.Now()},
+		{ID: "i2", From: "carol", FromID: "carol", To: "alice", ToID: "alice", Subject: "Lunch", Body: "invoice? no, lunch on Friday", CreatedAt: time.Now()},
+		{ID: "i3", From: "bob", FromID: "bob", To: "dave", ToID: "dave", Subject: "Invoice", Body: "dave's invoice", CreatedAt: time.Now()},
+	}
+	mutex.Lock()
+	prev := messages
+	messages = msgs
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		messages = prev
+		mutex.Unlock()
+	})
+	for _, m := range msgs {
+		indexMessage(m)
+	}
+
+	var got []*Message
+	for i := 0; i < 100; i++ {
+		if got = IndexedSearch("alice", "invoice", 10); len(got) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(got) != 2 || got[0].ID != "i1" {
+		t.Fatalf("alice invoice search = %v, want [i1 i2]", idsOf(got))
+	}
+
+	if got := IndexedSearch("alice", "invoice march", 10); len(got) != 1 || got[0].ID != "i1" {
+		t.Fatalf("all words must match, got %v", idsOf(got))
+	}
+	if got := IndexedSearch("alice", "dave", 10); len(got) != 0 {
+		t.Fatalf("alice must not see dave's mail, got %v", idsOf(got))
+	}
+
+	if e := data.GetByID(mailIndexID("i1", "alice")); e == nil || strings.Contains(e.Content, "invoice") {
+		t.Fatalf("index entry missing or holds plaintext: %+v", e)
+	}
+
+	unindexMessage(msgs[0])
+	if got := IndexedSearch("alice", "march", 10); len(got) != 0 {
+		t.Fatalf("unindexed message still found: %v", idsOf(got))
+	}
+}
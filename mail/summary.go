@@ -0,0 +1,347 @@
+package mail
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/ai"
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/wallet"
+)
+
+// SummaryMinMessages is how long a thread has to be before the thread view
+// offers to summarise it.
+const SummaryMinMessages = 4
+
+// maxSummaryMessageLength caps each message sent for summarising, so one
+// long newsletter doesn't crowd out the rest of the thread.
+const maxSummaryMessageLength = 4000
+
+// ThreadSummary is a user's cached summary of a mail thread. LastMessageAt
+// points at the newest message it covers; updating it only sends the
+// replies since, folded into the existing summary. Summaries are private to
+// the user who asked for them and stored with the same field encryption as
+// messages.
+type ThreadSummary struct {
+	ThreadID      string    `json:"thread_id"`
+	UserID        string    `json:"user_id"`
+	Summary       string    `json:"summary"`
+	LastMessageID string    `json:"last_message_id"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	Covered       int       `json:"covered"` // messages summarised so far
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+var (
+	summariesMu sync.RWMutex
+	summaries   = map[string]*ThreadSummary{} // userID + ":" + threadID -> summary
+)
+
+// summariseThread generates the summary text. Swapped out in tests.
+var summariseThread = ai.SummariseThread
+
+func summaryKey(userID, threadID string) string {
+	return userID + ":" + threadID
+}
+
+func loadSummaries() {
+	var stored map[string]*ThreadSummary
+	if err := data.LoadJSON("mail_summaries.json", &stored); err != nil || stored == nil {
+		return
+	}
+	for _, s := range stored {
+		text, err := decrypt(s.Summary)
+		if err != nil {
+			app.Log("mail", "WARNING: Failed to decrypt summary for thread %s: %v", s.ThreadID, err)
+			continue
+		}
+		s.Summary = text
+	}
+	summariesMu.Lock()
+	summaries = stored
+	summariesMu.Unlock()
+}
+
+// saveSummaries persists every summary. Caller must hold summariesMu.
+func saveSummaries() error {
+	stored := make(map[string]*ThreadSummary, len(summaries))
+	for k, s := range summaries {
+		cp := *s
+		var err error
+		if cp.Summary, err = encrypt(cp.Summary); err != nil {
+			return err
+		}
+		stored[k] = &cp
+	}
+	return data.SaveJSON("mail_summaries.json", stored)
+}
+
+// GetThreadSummary returns the user's cached summary of a thread, or nil.
+func GetThreadSummary(userID, threadID string) *ThreadSummary {
+	summariesMu.RLock()
+	defer summariesMu.RUnlock()
+	if s := summaries[summaryKey(userID, threadID)]; s != nil {
+		cp := *s
+		return &cp
+	}
+	return nil
+}
+
+// deleteThreadSummary drops the user's summary of a thread.
+func deleteThreadSummary(userID, threadID string) {
+	summariesMu.Lock()
+	defer summariesMu.Unlock()
+	key := summaryKey(userID, threadID)
+	if _, ok := summaries[key]; !ok {
+		return
+	}
+	delete(summaries, key)
+	if err := saveSummaries(); err != nil {
+		app.Log("mail", "Failed to save summaries: %v", err)
+	}
+}
+
+// DeleteUserSummaries removes all of a user's thread summaries. Called when
+// an account is deleted.
+func DeleteUserSummaries(userID string) {
+	summariesMu.Lock()
+	defer summariesMu.Unlock()
+	removed := false
+	for k, s := range summaries {
+		if s.UserID == userID {
+			delete(summaries, k)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	if err := saveSummaries(); err != nil {
+		app.Log("mail", "Failed to save summaries: %v", err)
+	}
+}
+
+// renameUserSummaries moves a renamed user's summaries to their new ID.
+func renameUserSummaries(oldID, newID string) {
+	summariesMu.Lock()
+	defer summariesMu.Unlock()
+	renamed := false
+	for k, s := range summaries {
+		if s.UserID == oldID {
+			delete(summaries, k)
+			s.UserID = newID
+			summaries[summaryKey(newID, s.ThreadID)] = s
+			renamed = true
+		}
+	}
+	if !renamed {
+		return
+	}
+	if err := saveSummaries(); err != nil {
+		app.Log("mail", "Failed to save summaries: %v", err)
+	}
+}
+
+// threadMessages returns the user's non-spam messages in a thread, oldest
+// first.
+func threadMessages(userID, threadID string) []*Message {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	inbox := inboxes[userID]
+	if inbox == nil || inbox.Threads[threadID] == nil {
+		return nil
+	}
+	var out []*Message
+	for _, m := range inbox.Threads[threadID].Messages {
+		if m.Spam || (m.FromID != userID && m.ToID != userID) {
+			continue
+		}
+		cp := *m
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// pendingThreadMessages returns the messages not yet covered by summary.
+func pendingThreadMessages(thread []*Message, summary *ThreadSummary) []*Message {
+	if summary == nil {
+		return thread
+	}
+	var out []*Message
+	for _, m := range thread {
+		if m.CreatedAt.After(summary.LastMessageAt) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// updateThreadSummary folds the pending messages into the user's summary
+// of a thread and caches the result.
+func updateThreadSummary(userID, threadID string, prev *ThreadSummary, pending []*Message) (*ThreadSummary, error) {
+	lines := make([]string, len(pending))
+	for i, m := range pending {
+		name := m.From
+		if name == "" {
+			name = m.FromID
+		}
+		body := strings.TrimSpace(stripHTMLTags(m.Body))
+		if len(body) > maxSummaryMessageLength {
+			body = body[:maxSummaryMessageLength] + "..."
+		}
+		lines[i] = fmt.Sprintf("%s (subject %q): %s", name, decodeMIMEHeader(m.Subject), body)
+	}
+	previous := ""
+	covered := 0
+	if prev != nil {
+		previous = prev.Summary
+		covered = prev.Covered
+	}
+	text, err := summariseThread(previous, lines, "mail-thread-summary")
+	if err != nil {
+		return nil, err
+	}
+	last := pending[len(pending)-1]
+	s := &ThreadSummary{
+		ThreadID:      threadID,
+		UserID:        userID,
+		Summary:       text,
+		LastMessageID: last.ID,
+		LastMessageAt: last.CreatedAt,
+		Covered:       covered + len(pending),
+		UpdatedAt:     time.Now(),
+	}
+
+	summariesMu.Lock()
+	summaries[summaryKey(userID, threadID)] = s
+	err = saveSummaries()
+	summariesMu.Unlock()
+	if err != nil {
+		app.Log("mail", "Failed to save summaries: %v", err)
+	}
+	cp := *s
+	return &cp, nil
+}
+
+// SummaryHandler serves /mail/summary?thread=<id>. GET returns the cached
+// summary as JSON; POST brings it up to date, charging the wallet only when
+// there are new messages to summarise, then returns to the thread.
+func SummaryHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	threadID := r.URL.Query().Get("thread")
+	if threadID == "" {
+		threadID = r.FormValue("thread")
+	}
+	thread := threadMessages(acc.ID, threadID)
+	if len(thread) == 0 {
+		app.NotFound(w, r, "Thread not found")
+		return
+	}
+	prev := GetThreadSummary(acc.ID, threadID)
+	pending := pendingThreadMessages(thread, prev)
+	back := "/mail?id=" + thread[len(thread)-1].ID
+
+	respond := func(s *ThreadSummary, pending int, charged bool) {
+		if !app.WantsJSON(r) && !app.SendsJSON(r) {
+			http.Redirect(w, r, back, http.StatusSeeOther)
+			return
+		}
+		resp := map[string]interface{}{
+			"thread_id": threadID,
+			"pending":   pending,
+			"charged":   charged,
+		}
+		if s != nil {
+			resp["summary"] = s.Summary
+			resp["covered"] = s.Covered
+			resp["updated_at"] = s.UpdatedAt
+		}
+		app.RespondJSON(w, resp)
+	}
+	fail := func(status int, msg string) {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.RespondError(w, status, msg)
+			return
+		}
+		http.Error(w, msg, status)
+	}
+
+	switch r.Method {
+	case "GET":
+		respond(prev, len(pending), false)
+		return
+	case "POST":
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	if prev == nil && len(thread) < SummaryMinMessages {
+		fail(http.StatusBadRequest, fmt.Sprintf("Summaries need at least %d messages", SummaryMinMessages))
+		return
+	}
+	if len(pending) == 0 {
+		respond(prev, 0, false)
+		return
+	}
+
+	canProceed, _, cost, _ := wallet.CheckQuota(acc.ID, wallet.OpThreadSummary)
+	if !canProceed {
+		fail(http.StatusPaymentRequired, fmt.Sprintf("A summary costs %d credits. Top up at /wallet", cost))
+		return
+	}
+
+	s, err := updateThreadSummary(acc.ID, threadID, prev, pending)
+	if err != nil {
+		app.Log("mail", "Thread summary failed for %s: %v", threadID, err)
+		fail(http.StatusBadGateway, "Summary failed, please try again")
+		return
+	}
+	wallet.ConsumeQuota(acc.ID, wallet.OpThreadSummary) //nolint:errcheck
+	respond(s, 0, true)
+}
+
+// renderThreadSummary renders the summary box at the top of a thread view:
+// the cached summary if there is one, and a button to create or update it.
+func renderThreadSummary(userID, threadID string) string {
+	thread := threadMessages(userID, threadID)
+	s := GetThreadSummary(userID, threadID)
+	if s == nil && len(thread) < SummaryMinMessages {
+		return ""
+	}
+	pending := pendingThreadMessages(thread, s)
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="card mb-5">`)
+	if s != nil {
+		sb.WriteString(fmt.Sprintf(`<div class="text-muted text-xs mb-2">Summary of %d messages · %s</div>`, s.Covered, app.TimeAgo(s.UpdatedAt)))
+		sb.WriteString(fmt.Sprintf(`<div class="text-sm" style="white-space:pre-wrap">%s</div>`, html.EscapeString(s.Summary)))
+	}
+	if len(pending) > 0 {
+		label := "Summarise thread"
+		if s != nil {
+			label = fmt.Sprintf("Update summary (%d new)", len(pending))
+		}
+		cost := ""
+		if wallet.PaymentsEnabled() {
+			cost = fmt.Sprintf(` <span class="text-muted text-xs">%dp</span>`, wallet.CostThreadSummary)
+		}
+		sb.WriteString(fmt.Sprintf(`<form method="POST" action="/mail/summary?thread=%s" class="mt-2"><button type="submit" class="btn-secondary text-sm">%s</button>%s</form>`,
+			threadID, label, cost))
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
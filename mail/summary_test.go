@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestThreadSummaryIncremental verifies a thread summary only sends the
+// messages since the last one it covered, folded into the previous summary.
+func TestThreadSummaryIncremental(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	summariesMu.Lock()
+	summaries = map[string]*ThreadSummary{}
+	summariesMu.Unlock()
+
+	var gotPrevious string
+	var gotLines []string
+	orig := summariseThread
+	defer func() { summariseThread = orig }()
+	summariseThread = func(previous string, lines []string, caller string) (string, error) {
+		gotPrevious, gotLines = previous, lines
+		return "summary of " + strings.Join(lines, " | "), nil
+	}
+
+	now := time.Now()
+	msg := func(id, from, to, body string, ago time.Duration) *Message {
+		return &Message{ID: id, From: from, FromID: from, To: to, ToID: to, Subject: "Trip", Body: body, ReplyTo: "t1", ThreadID: "t1", CreatedAt: now.Add(-ago)}
+	}
+	mutex.Lock()
+	messages = []*Message{
+		{ID: "t1", From: "alice", FromID: "alice", To: "bob", ToID: "bob", Subject: "Trip", Body: "<p>Shall we go?</p>", ThreadID: "t1", CreatedAt: now.Add(-4 * time.Hour)},
+		msg("t2", "bob", "alice", "Yes", 3*time.Hour),
+		msg("t3", "alice", "bob", "Friday?", 2*time.Hour),
+	}
+	rebuildInboxes()
+	mutex.Unlock()
+
+	thread := threadMessages("alice", "t1")
+	if len(thread) != 3 || thread[0].ID != "t1" {
+		t.Fatalf("threadMessages = %v", idsOf(thread))
+	}
+	s, err := updateThreadSummary("alice", "t1", nil, pendingThreadMessages(thread, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPrevious != "" || len(gotLines) != 3 || !strings.Contains(gotLines[0], "Shall we go?") || strings.Contains(gotLines[0], "<p>") {
+		t.Fatalf("first summary sent previous=%q lines=%q", gotPrevious, gotLines)
+	}
+	if s.Covered != 3 || s.LastMessageID != "t3" {
+		t.Fatalf("summary = %+v", s)
+	}
+
+	// Nothing new: nothing pending.
+	if p := pendingThreadMessages(threadMessages("alice", "t1"), GetThreadSummary("alice", "t1")); len(p) != 0 {
+		t.Fatalf("pending = %v, want none", idsOf(p))
+	}
+
+	// A reply arrives: only it is sent, with the previous summary.
+	mutex.Lock()
+	messages = append(messages, msg("t4", "bob", "alice", "Friday works", time.Hour))
+	rebuildInboxes()
+	mutex.Unlock()
+	prev := GetThreadSummary("alice", "t1")
+	pending := pendingThreadMessages(threadMessages("alice", "t1"), prev)
+	if len(pending) != 1 || pending[0].ID != "t4" {
+		t.Fatalf("pending = %v, want [t4]", idsOf(pending))
+	}
+	s, err = updateThreadSummary("alice", "t1", prev, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPrevious != prev.Summary || len(gotLines) != 1 || s.Covered != 4 {
+		t.Fatalf("update sent previous=%q lines=%q, covered %d", gotPrevious, gotLines, s.Covered)
+	}
+
+	// Summaries are per user and survive a restart.
+	if GetThreadSummary("bob", "t1") != nil {
+		t.Fatal("bob should not see alice's summary")
+	}
+	loadSummaries()
+	if got := GetThreadSummary("alice", "t1"); got == nil || got.Summary != s.Summary {
+		t.Fatalf("summary not reloaded: %+v", got)
+	}
+
+	DeleteUserSummaries("alice")
+	if GetThreadSummary("alice", "t1") != nil {
+		t.Fatal("summary should be gone")
+	}
+}
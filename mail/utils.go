@@ -461,11 +461,11 @@ func renderEmailBody(body string, isAttachment bool) string {
 		rendered = strings.ReplaceAll(rendered, ">\n<", "><")
 		rendered = strings.ReplaceAll(rendered, ">\n\n<", "><")
 
-		return rendered
+		return app.LinkMentions(rendered)
 	}
 
-	// Otherwise just linkify URLs
-	return linkifyURLs(body)
+	// Otherwise just linkify URLs and mentions
+	return app.LinkMentions(linkifyURLs(body))
 }
 
 // extractHTMLBody extracts and cleans content from HTML email
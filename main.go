@@ -31,19 +31,25 @@ import (
 	"mu/home"
 	"mu/images"
 	"mu/internal/a2a"
-	"mu/internal/agents"
+	_ "mu/internal/agents" // routes only
+	"mu/internal/ai"
 	"mu/internal/api"
 	"mu/internal/app"
 	"mu/internal/auth"
 	"mu/internal/cli"
 	"mu/internal/data"
+	"mu/internal/egress"
+	"mu/internal/env"
 	"mu/internal/memory"
+	"mu/internal/netx"
+	"mu/internal/plugin"
+	"mu/internal/push"
 	"mu/internal/service"
 	"mu/internal/settings"
-	"mu/internal/setup"
 	"mu/internal/userdb"
 	"mu/mail"
 	"mu/markets"
+	"mu/media"
 	"mu/news"
 	"mu/news/digest"
 	"mu/places"
@@ -52,15 +58,23 @@ import (
 	"mu/search"
 	"mu/social"
 	"mu/stream"
+	"mu/tasks"
 	"mu/user"
 	"mu/video"
 	"mu/wallet"
 	"mu/weather"
+
+	"golang.org/x/crypto/acme/autocert"
 )
 
 var EnvFlag = flag.String("env", "dev", "Set the environment")
 var ServeFlag = flag.Bool("serve", false, "Run the server")
 var AddressFlag = flag.String("address", ":8080", "Address for server")
+var ReadOnlyFlag = flag.Bool("readonly", false, "Serve read-only: refuse writes and skip background writers")
+var TLSFlag = flag.Bool("tls", false, "Serve HTTPS with certificates from Let's Encrypt")
+var DomainFlag = flag.String("domain", "", "Domains to get certificates for with --tls, comma separated (default MU_DOMAIN)")
+var HTTPAddressFlag = flag.String("http-address", ":80", "Address for ACME challenges and the redirect to https with --tls")
+var LowMemoryFlag = flag.Bool("lowmem", os.Getenv("MU_LOW_MEMORY") == "1", "Low-memory profile for small hosts such as a Raspberry Pi")
 
 // argFloat coerces a tool argument (JSON number or string) to a float64.
 func argFloat(v any) float64 {
@@ -84,6 +98,12 @@ func main() {
 	// which talks to /mcp over HTTP and never touches server state.
 	// This keeps the existing `mu --serve` deployment completely
 	// unaffected while adding `mu news`, `mu chat "hi"`, etc.
+	//
+	// `mu seed` is the one exception: it writes fake data into the local
+	// data directory for development, so it runs here rather than in cli.
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		os.Exit(runSeed(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	if !isServerMode(os.Args[1:]) {
 		os.Exit(cli.Run(os.Args[1:]))
 	}
@@ -95,18 +115,180 @@ func main() {
 		return
 	}
 
+	// a config file with mistakes in it is refused, rather than half read
+	if err := env.ConfigError(); err != nil {
+		fmt.Println("config:", err)
+		os.Exit(1)
+	}
+
+	// With --tls, serve HTTPS on :443 unless told otherwise, and plain
+	// HTTP only to answer ACME challenges and redirect to https.
+	var certs *autocert.Manager
+	if *TLSFlag {
+		domain := *DomainFlag
+		if domain == "" {
+			domain = settings.Get("MU_DOMAIN")
+		}
+		domains := app.TLSDomains(domain)
+		if len(domains) == 0 {
+			fmt.Println("--tls needs --domain or MU_DOMAIN")
+			os.Exit(1)
+		}
+		addressSet := false
+		flag.Visit(func(f *flag.Flag) { addressSet = addressSet || f.Name == "address" })
+		if !addressSet {
+			*AddressFlag = ":443"
+		}
+		certs = app.ACME(domains)
+	}
+
+	// Create server with handler
+	server := &http.Server{
+		Addr:    *AddressFlag,
+		Handler: boot(),
+	}
+	var redirect *http.Server
+	if certs != nil {
+		server.TLSConfig = certs.TLSConfig()
+		redirect = &http.Server{Addr: *HTTPAddressFlag, Handler: certs.HTTPHandler(nil)}
+	}
+
+	// Channel to listen for interrupt signals
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	// Start SMTP server if enabled (disabled by default). Inbound mail is a
+	// write, so it stays off in read-only mode.
+	if !*ReadOnlyFlag {
+		mail.StartSMTPServerIfEnabled()
+	}
+
+	// Start read-only IMAP access to mail if IMAP_ADDR is set
+	mail.StartIMAPServerIfEnabled()
+
+	// Log initial memory usage
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	app.Log("main", "Startup complete. Memory: Alloc=%dMB Sys=%dMB NumGC=%d", m.Alloc/1024/1024, m.Sys/1024/1024, m.NumGC)
+
+	// Start memory monitoring goroutine
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			app.Log("main", "Memory: Alloc=%dMB Sys=%dMB NumGC=%d Goroutines=%d",
+				m.Alloc/1024/1024, m.Sys/1024/1024, m.NumGC, runtime.NumGoroutine())
+		}
+	}()
+
+	// Start server in a goroutine, preferring a systemd-activated socket so
+	// redeploys don't drop the listener (see serveListener).
+	go func() {
+		ln, activated, err := serveListener(*AddressFlag)
+		if err != nil {
+			app.Log("main", "Listen error on %s: %v", *AddressFlag, err)
+			quit <- syscall.SIGTERM
+			return
+		}
+		if activated {
+			app.Log("main", "Serving on systemd-activated socket (restarts queue, no 502)")
+		} else {
+			app.Log("main", "Starting server on %s", *AddressFlag)
+		}
+		if certs != nil {
+			err = server.ServeTLS(ln, "", "")
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			app.Log("main", "Server error: %v", err)
+		}
+	}()
+	if redirect != nil {
+		go func() {
+			app.Log("main", "Redirecting http on %s to https", *HTTPAddressFlag)
+			if err := redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				app.Log("main", "HTTP redirect error: %v", err)
+			}
+		}()
+	}
+
+	// Wait for interrupt signal
+	<-quit
+	app.Log("main", "Shutting down server...")
+
+	// Create shutdown context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Attempt graceful shutdown
+	if err := server.Shutdown(ctx); err != nil {
+		app.Log("main", "Server forced to shutdown: %v", err)
+	}
+	if redirect != nil {
+		redirect.Shutdown(ctx)
+	}
+
+	// Snapshot warm caches so the next boot doesn't start cold.
+	data.SaveWarm()
+
+	// Plugins are separate processes; don't leave them running.
+	plugin.Stop()
+
+	app.Log("main", "Server stopped")
+}
+
+// boot loads every package, wires them together and registers the routes,
+// returning the handler that serves them behind the auth, CSRF and
+// write-gate middleware. main serves it; the end-to-end tests boot it
+// in-process against a temporary data directory. Packages load their
+// state once, so it can only run once per process.
+func boot() http.Handler {
 	// api page is now dynamic (rendered in api.APIPageHandler)
 
+	// Read-only mode is for load tests and staging previews against a copy
+	// of a production data directory: the data store refuses every write,
+	// the middleware refuses every mutating request, and loops that publish
+	// or message people are never started.
+	if *ReadOnlyFlag {
+		data.SetReadOnly(true)
+		app.Log("main", "Read-only mode: writes are refused and background writers are off")
+	}
+
+	// The low-memory profile trades CPU for RAM on small hosts: pages are
+	// rendered on demand instead of cached, the index and metadata fetches
+	// run with less concurrency, and jobs nothing waits on start late.
+	if *LowMemoryFlag {
+		data.SetLowMemory(true)
+		app.Log("main", "Low-memory mode: on-demand rendering, smaller caches, background jobs deferred %s", lowMemoryJobDelay)
+	}
+
 	// bring up the go-micro runtime core first, so domain services can
 	// register themselves as they load.
 	service.Init()
 
+	// record and gate every outbound HTTP request (see /admin/egress)
+	egress.Install()
+
 	// load settings first so other packages can use them
 	settings.Load()
+	for _, err := range settings.Validate() {
+		app.LogWarn("config", "%v", err)
+	}
 
 	// load the data index
 	data.Load()
 
+	// embed content for semantic search with the provider's model, if set
+	if model := ai.EmbedModel(); model != "" {
+		data.SetEmbedder(model, ai.Embed)
+	}
+
+	// restore cached upstream responses from before the restart
+	netx.Load()
+
 	// load admin/flags
 	admin.Load()
 
@@ -115,7 +297,9 @@ func main() {
 
 	// load the news
 	news.Load()
-	news.StartSentimentLoop()
+	if !*ReadOnlyFlag {
+		deferJob(news.StartSentimentLoop)
+	}
 
 	// load the videos
 	video.Load()
@@ -125,6 +309,9 @@ func main() {
 
 	// load the mail (also configures SMTP and DKIM)
 	mail.Load()
+	if !*ReadOnlyFlag {
+		mail.StartScheduler()
+	}
 
 	// load places
 	places.Load()
@@ -132,20 +319,102 @@ func main() {
 	// load weather
 	weather.Load()
 
+	// load tasks
+	tasks.Load()
+
 	// load markets, reminder, wallet
 	markets.Load()
 	reminder.Load()
 	images.Load()
+	media.Load()
 	wallet.Load()
 	app.DiscordLinkCodeFunc = discord.GenerateLinkCode
-	discord.Load()
-	telegram.Load()
-	whatsapp.Load()
+	if !*ReadOnlyFlag {
+		// A second bot on the same tokens would answer real users.
+		discord.Load()
+		telegram.Load()
+		whatsapp.Load()
+	}
 	mail.OnNewMail = func(accountID, from, subject, body string) {
 		summary := discord.SummariseEmail(from, subject, body)
 		discord.NotifyNewMail(accountID, from, subject, summary)
 		telegram.NotifyUser(accountID, fmt.Sprintf("📬 *New email from %s*\n%s", from, summary))
 		whatsapp.NotifyUser(accountID, fmt.Sprintf("📬 *New email from %s*\n%s", from, summary))
+		push.Notify(accountID, push.KindMail, "New email from "+from, subject, "/mail")
+	}
+
+	markets.OnAlert = func(userID, subject, body string) {
+		acc, err := auth.GetAccount(userID)
+		if err != nil {
+			return
+		}
+		if err := mail.SendMessage("Markets", "markets", acc.Name, acc.ID, subject, body, "", ""); err != nil {
+			app.Log("markets", "Alert mail to %s failed: %v", userID, err)
+		}
+	}
+
+	// @mentions in statuses, chat rooms, posts and comments reach native
+	// clients
+	user.OnStatus = func(userID, status string) {
+		push.NotifyMentions(userID, status, "@"+userID+" mentioned you", "/@"+userID)
+	}
+	chat.OnMessage = func(roomID, roomTitle string, msg chat.RoomMessage) {
+		title := "@" + msg.UserID + " mentioned you in chat"
+		if roomTitle != "" {
+			title = "@" + msg.UserID + " mentioned you in " + roomTitle
+		}
+		push.NotifyMentions(msg.UserID, msg.Content, title, "/chat?id="+roomID)
+	}
+	// Direct messages follow the recipient's privacy settings, and reach
+	// their devices when they don't have the conversation open
+	chat.CanMessage = func(from, to string) bool {
+		return user.CanView(to, user.FieldMessages, from)
+	}
+	chat.OnDirectMessage = func(msg chat.DirectMessage, delivered bool) {
+		if !delivered {
+			push.Notify(msg.To, push.KindMessage, "@"+msg.From+" sent you a message", msg.Content, "/chat/dm?with="+msg.From)
+		}
+	}
+	blog.OnPost = func(post *blog.Post) {
+		push.NotifyMentions(post.AuthorID, post.Content, "@"+post.AuthorID+" mentioned you in a post", "/blog/post?id="+post.ID)
+	}
+	blog.OnComment = func(post *blog.Post, c *blog.Comment) {
+		push.NotifyMentions(c.AuthorID, c.Content, "@"+c.AuthorID+" mentioned you in a comment", "/blog/post?id="+post.ID+"#comment-"+c.ID)
+	}
+
+	// Prayer times look up locations with places, and remind whoever
+	// asked on their devices and linked chat apps
+	reminder.Geocode = places.Geocode
+	weather.Geocode = places.Geocode
+	if !*ReadOnlyFlag {
+		// A read-only instance leaves reminders to the primary, or
+		// they'd arrive twice.
+		reminder.OnPrayer = func(userID string, p reminder.PrayerTime) {
+			msg := fmt.Sprintf("🕌 %s at %s", p.Name, p.Clock())
+			push.Notify(userID, push.KindPrayer, msg, "", "/islam/prayer")
+			discord.NotifyUser(userID, msg)
+			telegram.NotifyUser(userID, msg)
+			whatsapp.NotifyUser(userID, msg)
+		}
+	}
+
+	// Credits sent by another member arrive with a note in the inbox and
+	// on the recipient's devices
+	wallet.OnTransfer = func(from, to string, amount int, note string) {
+		title := fmt.Sprintf("@%s sent you %d credits", from, amount)
+		push.Notify(to, push.KindCredits, title, note, "/wallet/history?op=transfer")
+		acc, err := auth.GetAccount(to)
+		if err != nil {
+			return
+		}
+		body := title + "."
+		if note != "" {
+			body += "\n\n" + note
+		}
+		body += fmt.Sprintf("\n\nYour balance is now %d credits.", wallet.GetBalance(to))
+		if err := mail.SendMessage("Wallet", "wallet", acc.Name, acc.ID, title, body, "", ""); err != nil {
+			app.Log("wallet", "Transfer mail to %s failed: %v", to, err)
+		}
 	}
 
 	// load apps
@@ -161,6 +430,33 @@ func main() {
 		return accountID
 	}
 
+	// Plugins run as their own processes and reach the home screen and
+	// chat only through these hooks, with what /admin/plugins grants them.
+	// Like other background writers they stay off in read-only mode.
+	if !*ReadOnlyFlag {
+		plugin.Load()
+	}
+	home.PluginCards = func() []home.Card {
+		var cards []home.Card
+		for _, c := range plugin.Cards() {
+			cards = append(cards, home.Card{
+				ID:         "plugin-" + c.Plugin + "-" + c.ID,
+				Title:      c.Title,
+				Column:     "right",
+				Link:       c.Link,
+				CachedHTML: c.HTML,
+			})
+		}
+		return cards
+	}
+	// Chat commands: /task is built in, the rest come from plugins
+	chat.Command = func(userID, text string) (string, string, bool) {
+		if reply, replyHTML, ok := tasks.Command(userID, text); ok {
+			return reply, replyHTML, true
+		}
+		return plugin.Command(userID, text)
+	}
+
 	// load social
 	social.Load()
 
@@ -171,7 +467,29 @@ func main() {
 	}
 
 	// load the home cards
+	home.RegisterCard(home.CardDef{
+		ID:       "places",
+		Title:    "Places",
+		Link:     "/places/favourites",
+		Column:   "right",
+		Position: 4,
+		TTL:      5 * time.Minute,
+		User:     places.FavouritesCard,
+	})
+	home.RegisterCard(home.CardDef{
+		ID:       "tasks",
+		Title:    "Tasks",
+		Link:     "/tasks",
+		Column:   "left",
+		Position: 0,
+		TTL:      time.Minute,
+		Tooltip:  "Your tasks due today, and a box to add one",
+		User:     tasks.Card,
+	})
 	home.Load()
+	if !*ReadOnlyFlag {
+		home.StartBriefings()
+	}
 
 	// load agent
 	agent.Load()
@@ -192,6 +510,14 @@ func main() {
 		if prices := markets.TopMovers(3); prices != "" {
 			parts = append(parts, "- Markets: "+prices)
 		}
+		// Weather at the user's saved place, if fetched lately.
+		if w := weather.ContextFor(accountID); w != "" {
+			parts = append(parts, "- "+w)
+		}
+		// Tasks due today or overdue.
+		if t := tasks.ForContext(accountID); t != "" {
+			parts = append(parts, "- "+t)
+		}
 		// Persistent memory — things the user has told you to remember.
 		if mem := memory.ForContext(accountID); mem != "" {
 			parts = append(parts, "User preferences/notes:\n"+mem)
@@ -232,8 +558,25 @@ func main() {
 		}
 	}
 
+	digest.WeeklyPosts = func(from, to time.Time) []digest.WeeklyItem {
+		var items []digest.WeeklyItem
+		for _, p := range blog.PostsBetween(from, to) {
+			items = append(items, digest.WeeklyItem{Title: p.Title, URL: "/blog/post?id=" + p.ID, Author: p.Author})
+		}
+		return items
+	}
+	digest.WeeklyDiscussions = func(from, to time.Time, n int) []digest.WeeklyItem {
+		var items []digest.WeeklyItem
+		for _, d := range blog.ActiveDiscussions(from, to, n) {
+			items = append(items, digest.WeeklyItem{Title: d.Post.Title, URL: "/blog/post?id=" + d.Post.ID, Author: d.Post.Author, Comments: d.Comments})
+		}
+		return items
+	}
+
 	// load daily digest scheduler
-	digest.Load()
+	if !*ReadOnlyFlag {
+		digest.Load()
+	}
 
 	// load search
 	search.Load()
@@ -275,6 +618,9 @@ func main() {
 		return result
 	}
 	user.LinkifyContent = blog.Linkify
+	blog.PublicPosts = func(authorID string) bool {
+		return user.CanView(authorID, user.FieldPosts, "")
+	}
 
 	// Wire @micro mention handling in the status stream. When a user
 	// posts a status containing "@micro ...", run the agent against
@@ -358,15 +704,54 @@ func main() {
 		apps.DeleteAppsByAuthor,
 		stream.ClearByAuthor,
 		user.ClearStatusHistory,
+		user.ClearPrivacy,
+		user.ClearFollows,
+		chat.DeleteDirectMessages,
+		admin.DeleteReports,
 		mail.DeleteInbox,
+		reminder.DeletePrayerSettings,
+		reminder.DeleteQuranBookmark,
 		func(id string) { wallet.DeleteWallet(id) },
 		func(id string) { wallet.DeleteBaseWallet(id) },
+		wallet.DeleteGiving,
+		places.DeleteFavourites,
+		places.DeleteReviews,
 		func(id string) { micro.DeleteUserAgents(id) },
 		func(id string) { discord.DeleteLinks(id) },
 		func(id string) { telegram.DeleteLinks(id) },
 		func(id string) { whatsapp.DeleteLinks(id) },
 		func(id string) { app.ClearUserPrefs(id) },
 		memory.Clear,
+		media.DeleteByOwner,
+		video.DeleteByOwner,
+		video.DeleteHistory,
+		weather.DeletePlace,
+		tasks.DeleteTasks,
+	)
+
+	// Register username change hooks — each package moves its own data.
+	auth.AccountRenameHooks = append(auth.AccountRenameHooks,
+		blog.RenameAuthor,
+		social.RenameAuthor,
+		mail.RenameInbox,
+		reminder.RenamePrayerSettings,
+		reminder.RenameQuranBookmark,
+		chat.RenameDirectMessages,
+		admin.RenameReporter,
+		wallet.RenameWallet,
+		wallet.RenameBaseWallet,
+		wallet.RenameGiving,
+		wallet.RenamePayments,
+		places.RenameFavourites,
+		places.RenameReviews,
+		weather.RenamePlace,
+		tasks.RenameTasks,
+		user.RenameUser,
+		app.RenameUserPrefs,
+		memory.Rename,
+		media.RenameOwner,
+		video.RenameOwner,
+		video.RenameHistory,
 	)
 
 	// Enable indexing after all content is loaded
@@ -376,12 +761,17 @@ func main() {
 	// Start web search topics (loads cache from disk, generates in background)
 	search.StartTopics()
 
-	// Start daily opinion generation (publishes as blog post)
-	blog.StartOpinion()
+	if !*ReadOnlyFlag {
+		// Start daily opinion generation (publishes as blog post)
+		deferJob(blog.StartOpinion)
 
-	// Start the notes loop — Mu's own story, posted to its own blog as the
-	// system account (low cadence; disable with NOTES=off).
-	blog.StartNotes()
+		// Publish scheduled blog drafts when they are due
+		blog.StartPublisher()
+
+		// Start the notes loop — Mu's own story, posted to its own blog as the
+		// system account (low cadence; disable with NOTES=off).
+		deferJob(blog.StartNotes)
+	}
 
 	// Wire guest agent news search directly to the live feed-backed provider path.
 	api.GuestNewsSearch = news.SearchToolText
@@ -466,7 +856,7 @@ func main() {
 	// Only enabled when MAIL_DOMAIN is configured to a real domain —
 	// instances without mail configured skip the verification gate
 	// entirely (see auth.VerificationRequired below).
-	if domain := mail.GetConfiguredDomain(); domain != "" && domain != "localhost" {
+	if domain := mail.GetConfiguredDomain(); domain != "" && domain != "localhost" && !*ReadOnlyFlag {
 		app.EmailSender = func(to, subject, plain, html string) error {
 			from := "no-reply@" + domain
 			_, err := mail.SendExternalEmail("Mu", from, to, subject, plain, html, "")
@@ -505,6 +895,9 @@ func main() {
 			if reason := auth.ValidateUsername(id); reason != "" {
 				return reason, fmt.Errorf("banned username")
 			}
+			if auth.SignupClosed() {
+				return "signups are closed", fmt.Errorf("signups closed")
+			}
 			if auth.InviteOnly() {
 				if err := auth.ValidateInvite(invite); err != nil {
 					return err.Error(), err
@@ -541,6 +934,9 @@ func main() {
 			if id == "" || secret == "" {
 				return "username and password are required", fmt.Errorf("missing fields")
 			}
+			if auth.MagicLinkOnly() {
+				return "password login is disabled; use an email login link at /login/link", fmt.Errorf("password login disabled")
+			}
 			sess, err := auth.Login(id, secret)
 			if err != nil {
 				return "invalid username or password", err
@@ -1158,165 +1554,6 @@ func main() {
 		return wallet.PayAndCallMCP(context.Background(), accountID, baseURL, toolName, toolArgs, bw)
 	})
 
-	authenticated := map[string]bool{
-		"/video":                 false, // Public viewing, auth for interactive features
-		"/news":                  false, // Public viewing, auth for search
-		"/chat":                  false, // Public viewing, auth for chatting
-		"/home":                  false, // Public viewing
-		"/blog":                  false, // Public viewing, auth for posting
-		"/markets":               false, // Public viewing
-		"/islam":                 false, // Public daily verse, hadith and names
-		"/about":                 false, // Public "what is Mu" pitch
-		"/oauth2/google":         false, // Google sign-in start (no session yet)
-		"/oauth2/google/connect": true,  // Link Google to the current account
-		"/oauth2/callback":       false, // Google sign-in callback (no session yet)
-		"/images":                false, // Public daily image; generation needs login
-		"/social":                false, // Public viewing, auth for search
-		"/social/thread":         false, // Public thread view, auth for messaging
-		"/places":                false, // Public map, auth for search
-		"/weather":               false, // Public page, auth for forecast lookup
-		"/mail":                  true,  // Require auth for inbox
-		"/logout":                true,
-		"/account":               true,
-		"/verify":                false, // Public — token in URL is the credential
-		"/token":                 true,  // PAT token management
-		"/passkey":               false, // Passkey login/register (auth checked in handler)
-		"/session":               false, // Public - used to check auth status
-		"/api":                   false, // Public - API documentation
-		"/admin/flag":            true,
-		"/admin":                 true,
-		"/admin/users":           true,
-		"/admin/moderate":        true,
-		"/admin/blocklist":       true,
-		"/admin/spam":            true,
-		"/admin/email":           true,
-		"/admin/api":             true,
-		"/admin/log":             true,
-		"/admin/env":             true,
-		"/admin/server":          true,
-		"/admin/usage":           true,
-		"/admin/delete":          true,
-		"/admin/console":         true,
-		"/admin/diagnostics":     true,
-		"/admin/invite":          true,
-		"/wallet":                false, // Public - shows wallet info; auth checked in handler
-
-		"/apps":      false, // Public - apps directory; auth checked in handler for create/edit
-		"/work":      false, // Public - task bounties; auth checked in handler for post/claim
-		"/search":    false, // Public - web search
-		"/web":       false, // Redirect to /search
-		"/web/fetch": false, // Public page, auth checked in handler (paid web fetch)
-		"/web/read":  false, // Public page, auth checked in handler (proxied reader)
-
-		"/status":                 false, // Public - server health status
-		"/pricing":                false, // Public - pricing page
-		"/docs":                   false, // Public - documentation
-		"/whitepaper":             false, // Public - whitepaper
-		"/mcp":                    false, // Public - MCP tools page
-		"/whatsapp/webhook":       false, // Public - WhatsApp webhook
-		"/.well-known/agent.json": false, // Public - A2A agent card
-		"/a2a":                    false, // Public - A2A protocol
-		"/agent":                  false, // Public page, auth checked in handler
-		"/setup":                  false, // First-run setup (open only until an admin exists)
-		"/agents":                 false, // API face for agents (public)
-		"/developers":             false, // Legacy alias → /agents (public)
-	}
-
-	// Static assets should not require authentication
-	staticPaths := []string{
-		".css", ".js", ".png", ".jpg", ".jpeg", ".gif", ".svg",
-		".ico", ".webmanifest", ".json",
-	}
-	// serve video
-	http.HandleFunc("/video", video.Handler)
-
-	// serve news
-	http.HandleFunc("/news", news.Handler)
-	// serve chat
-	http.HandleFunc("/chat", chat.Handler)
-
-	// serve blog (full list)
-	http.HandleFunc("/blog", blog.Handler)
-
-	// serve individual blog post (public, no auth)
-	// Serves ActivityPub JSON-LD when requested via Accept header
-	http.HandleFunc("/blog/post", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "GET" && blog.WantsActivityPub(r) {
-			blog.PostObjectHandler(w, r)
-			return
-		}
-		blog.PostHandler(w, r)
-	})
-
-	// handle comments on posts /blog/post/{id}/comment
-	http.HandleFunc("/blog/post/", blog.CommentHandler)
-
-	// Legacy redirects for old URL structure (301 so browsers/crawlers update)
-	legacyRedirect := func(oldPrefix, newPrefix string) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			target := newPrefix + r.URL.Path[len(oldPrefix):]
-			if r.URL.RawQuery != "" {
-				target += "?" + r.URL.RawQuery
-			}
-			http.Redirect(w, r, target, http.StatusMovedPermanently)
-		}
-	}
-	http.HandleFunc("/post/", legacyRedirect("/post/", "/blog/post/"))
-	http.HandleFunc("/post", legacyRedirect("/post", "/blog/post"))
-	http.HandleFunc("/fetch", legacyRedirect("/fetch", "/web/fetch"))
-	http.HandleFunc("/read", legacyRedirect("/read", "/web/read"))
-
-	// flag content
-	http.HandleFunc("/admin/flag", admin.FlagHandler)
-
-	// admin dashboard
-	http.HandleFunc("/admin", admin.AdminHandler)
-
-	// admin user management
-	http.HandleFunc("/admin/users", admin.UsersHandler)
-
-	// moderation queue
-	http.HandleFunc("/admin/moderate", admin.ModerateHandler)
-
-	// mail blocklist management
-	http.HandleFunc("/admin/blocklist", admin.BlocklistHandler)
-
-	// spam filter management
-	http.HandleFunc("/admin/spam", admin.SpamFilterHandler)
-
-	// email log
-	http.HandleFunc("/admin/email", admin.EmailLogHandler)
-
-	// external API call log
-	http.HandleFunc("/admin/api", admin.APILogHandler)
-
-	// system log
-	http.HandleFunc("/admin/log", admin.SysLogHandler)
-
-	// environment variables status
-	http.HandleFunc("/admin/env", admin.EnvHandler)
-
-	// server update and restart
-	http.HandleFunc("/admin/server", admin.UpdateHandler)
-
-	// AI usage tracking
-	http.HandleFunc("/admin/usage", admin.AIUsageHandler)
-
-	// admin delete (any content type)
-	http.HandleFunc("/admin/delete", admin.DeleteHandler)
-
-	// admin console
-	http.HandleFunc("/admin/console", admin.ConsoleHandler)
-	http.HandleFunc("/admin/diagnostics", admin.DiagnosticsHandler)
-	http.HandleFunc("/admin/invite", admin.InviteHandler)
-
-	// wallet - credits and payments
-	http.HandleFunc("/wallet", wallet.Handler)
-	http.HandleFunc("/wallet/", wallet.Handler) // Handle sub-routes like /wallet/topup
-
-	// serve whatsapp webhook
-	http.HandleFunc("/whatsapp/webhook", whatsapp.Handler)
-
 	// A2A protocol endpoints
 	domain := settings.Get("MU_DOMAIN")
 	if domain == "" {
@@ -1326,517 +1563,210 @@ func main() {
 		domain = "https://" + domain
 	}
 	a2a.BaseURL = domain
-	http.HandleFunc("/.well-known/agent.json", a2a.AgentCardHandler)
-	http.HandleFunc("/a2a", a2a.Handler)
-
-	// serve search page (local + Brave web search)
-	// serve search page
-	http.HandleFunc("/search", search.WebHandler)
-	http.HandleFunc("/web", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/search?"+r.URL.RawQuery, http.StatusMovedPermanently)
-	})
-	http.HandleFunc("/web/preview", search.PreviewHandler)
 
-	// serve web fetch page (fetch and clean a URL)
-	http.HandleFunc("/web/fetch", search.FetchHandler)
-
-	// serve clean reader page for web results
-	http.HandleFunc("/web/read", search.ReadHandler)
-
-	// serve fact-check page and API
-
-	// The dashboard lives at the named URL /home, consistent with every other
-	// section (/news, /mail, /agent …). It renders for everyone: logged out, the
-	// home screen is the public face — real cards plus the agent — so a visitor
-	// sees the product rather than a separate marketing page.
-	http.HandleFunc("/home", home.Handler)
-	http.HandleFunc("/about", home.Landing) // the "what is Mu" pitch, no longer the front door
-	http.HandleFunc("/pricing", home.PricingHandler)
-
-	// first-run setup wizard (open only until an admin exists)
-	http.HandleFunc("/setup", setup.Handler)
-
-	// The API face for agents: MCP + REST, pay-per-call over x402.
-	http.HandleFunc("/agents", agents.Handler)
-	// Redirect the old path so existing links keep working.
-	http.HandleFunc("/developers", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/agents", http.StatusMovedPermanently)
-	})
-
-	// serve the agent
-	http.HandleFunc("/agent", agent.Handler)
-	http.HandleFunc("/agent/", agent.Handler)
-	http.HandleFunc("/agent/agents", agent.AgentsHandler)
-	http.HandleFunc("/agent/new", agent.NewAgentHandler)
-	http.HandleFunc("/agent/run", agent.RunHandler)
-	http.HandleFunc("/agent/exec", agent.ExecResultHandler)
-
-	// serve mail inbox
-	http.HandleFunc("/mail", mail.Handler)
-
-	// serve markets page
-	http.HandleFunc("/markets", markets.Handler)
-	http.HandleFunc("/images", images.Handler)
-
-	// serve social page
-	http.HandleFunc("/social", social.Handler)
-	http.HandleFunc("/social/thread", social.ThreadHandler)
-	http.HandleFunc("/user/status", user.StatusHandler)
-	http.HandleFunc("/user/status/stream", user.StatusStreamHandler)
-
-	// Stream (console) routes
-	http.HandleFunc("/stream", stream.Handler)
-	http.HandleFunc("/stream/fragment", stream.FragmentHandler)
-
-	// redirect /reminder to reminder.dev
-	http.HandleFunc("/islam", reminder.Handler)
-	// Back-compat: the page used to live at /reminder.
-	http.HandleFunc("/reminder", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/islam", http.StatusMovedPermanently)
-	})
-
-	// serve places page
-	http.HandleFunc("/places", places.Handler)
-	http.HandleFunc("/places/", places.Handler)
-
-	// serve weather page
-	http.HandleFunc("/weather", weather.Handler)
-
-	// serve apps
-	http.HandleFunc("/apps", apps.Handler)
-	http.HandleFunc("/apps/", apps.Handler)
-
-	// serve work (task bounties)
-
-	// content controls (flag, save, dismiss, block, share)
-	http.HandleFunc("/app/", app.ControlsHandler)
-
-	// auth
-	http.HandleFunc("/login", app.Login)
-	http.HandleFunc("/logout", app.Logout)
-	http.HandleFunc("/signup", app.Signup)
-	http.HandleFunc("/request-invite", app.RequestInvite)
-	http.HandleFunc("/invite", app.InviteHandler)
-	http.HandleFunc("/account", app.Account)
-	http.HandleFunc("/verify", app.Verify)
-	http.HandleFunc("/session", app.Session)
-	http.HandleFunc("/updates", updatesHandler)
-	http.HandleFunc("/token", app.TokenHandler)
-	http.HandleFunc("/passkey/", app.PasskeyHandler)
-
-	// OAuth 2.1 for MCP authentication
-	http.HandleFunc("/.well-known/oauth-authorization-server", auth.OAuthMetadataHandler)
-	http.HandleFunc("/.well-known/oauth-protected-resource", auth.OAuthResourceHandler)
-	// Google sign-in (Mu as an OAuth client of Google).
-	http.HandleFunc("/oauth2/google", app.GoogleLogin)
-	http.HandleFunc("/oauth2/google/connect", app.GoogleConnect)
-	http.HandleFunc("/oauth2/callback", app.GoogleCallback)
-
-	http.HandleFunc("/oauth/register", auth.OAuthRegisterHandler)
-	http.HandleFunc("/oauth/authorize", auth.OAuthAuthorizePostHandler)
-	http.HandleFunc("/oauth/token", auth.OAuthTokenHandler)
+	// Markdown preview for the blog write and edit forms, rendered just as
+	// a published post is.
+	app.RenderPreview = blog.Linkify
 
 	// internal status (injected into admin server page)
 	app.DKIMStatusFunc = mail.DKIMStatus
 	app.DigestStatusFunc = digest.Status
 	admin.GenerateDigestFunc = digest.Generate
-
-	// public status page - service health checks
 	app.HealthCheckFunc = runHealthChecks
-	http.HandleFunc("/status", app.StatusHandler)
-
-	// whitepaper
-	http.HandleFunc("/whitepaper", docs.WhitepaperHandler)
-	http.HandleFunc("/whitepaper.pdf", docs.WhitepaperHandler)
 
-	// documentation
-	http.HandleFunc("/docs", docs.Handler)
-	http.HandleFunc("/docs/", docs.Handler)
-
-	// ActivityPub: WebFinger discovery
-	http.HandleFunc("/.well-known/webfinger", blog.WebFingerHandler)
-
-	// presence WebSocket endpoint
-	http.HandleFunc("/presence", user.PresenceHandler)
-
-	// presence ping endpoint
-	http.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
-		_, acc, err := auth.RequireSession(r)
-		if err != nil {
-			app.Unauthorized(w, r)
-			return
+	// Allow x402 payment as alternative to auth for API requests.
+	app.PaymentAuth = func(r *http.Request) (*http.Request, bool) {
+		if !wallet.X402Enabled() || !wallet.HasPayment(r) || !(app.SendsJSON(r) || app.WantsJSON(r)) {
+			return r, false
 		}
+		return r.WithContext(context.WithValue(r.Context(), wallet.X402ContextKey, true)), true
+	}
 
-		auth.UpdatePresence(acc.ID)
-
-		w.Header().Set("Content-Type", "application/json")
-		onlineCount := auth.GetOnlineCount()
-		w.Write([]byte(fmt.Sprintf(`{"status":"ok","online":%d}`, onlineCount)))
-	})
-
+	// Each package declares its own routes (see app.Register); the few
+	// left here need more of the tree than any one package has.
+	router := app.NewRouter()
+	router.Mount(app.Registered()...)
+	router.HandleFunc("/updates", updatesHandler)
+	router.HandleFunc("/ping", pingHandler)
 	// /version — what's deployed and how it's wired, for verifying releases.
-	http.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+	router.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(versionInfo())
 	})
+	router.HandlePrefix("/@", profileHandler)
+	app.Internal = router.Dispatch
 
-	// serve the api doc
-	http.HandleFunc("/api", api.APIPageHandler)
-
-	// serve the MCP page and server (GET = HTML page, POST = JSON-RPC)
-	http.HandleFunc("/mcp", api.MCPHandler)
-
-	// serve the app
-	http.Handle("/", app.Serve())
-
-	// Create server with handler
-	server := &http.Server{
-		Addr: *AddressFlag,
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Block known bot paths silently
-			if strings.HasPrefix(r.URL.Path, "/audio/") {
-				http.NotFound(w, r)
-				return
-			}
-
-			// Set Onion-Location header for Tor Browser discovery
-			if onion := os.Getenv("TOR_ONION"); onion != "" {
-				w.Header().Set("Onion-Location", "http://"+onion+r.URL.RequestURI())
-			}
-
-			// Request logging (Apache-style)
-			start := time.Now()
-			defer func() {
-				// Skip logging for static assets and frequent endpoints
-				if !strings.HasSuffix(r.URL.Path, ".css") &&
-					!strings.HasSuffix(r.URL.Path, ".js") &&
-					!strings.HasSuffix(r.URL.Path, ".png") &&
-					!strings.HasSuffix(r.URL.Path, ".ico") &&
-					!strings.HasPrefix(r.URL.Path, "/chat/ws") {
-					app.Log("http", "%s %s %s %v", r.Method, r.URL.Path, r.RemoteAddr, time.Since(start))
-				}
-			}()
-
-			if *EnvFlag == "dev" {
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-				if r.Method == "OPTIONS" {
-					w.WriteHeader(http.StatusOK)
-					return
-				}
-			}
-
-			if v := len(r.URL.Path); v > 1 && strings.HasSuffix(r.URL.Path, "/") {
-				r.URL.Path = r.URL.Path[:v-1]
-			}
-
-			// Fast path for static assets - skip all middleware
-			for _, ext := range staticPaths {
-				if strings.HasSuffix(r.URL.Path, ext) {
-					http.DefaultServeMux.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			var token string
-
-			// set via session cookie
-			if c, err := r.Cookie("session"); err == nil && c != nil {
-				token = c.Value
-			}
-
-			// Try Authorization header (Bearer token or PAT)
-			if token == "" {
-				authHeader := r.Header.Get("Authorization")
-				if authHeader != "" {
-					// Support both "Bearer <token>" and just "<token>"
-					if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-						token = authHeader[7:]
-					} else {
-						token = authHeader
-					}
-				}
-			}
-
-			// Try X-Micro-Token header (legacy support)
-			if token == "" {
-				token = r.Header.Get("X-Micro-Token")
-			}
-
-			// Check if static asset - skip authentication entirely
-			isStaticAsset := false
-			for _, ext := range staticPaths {
-				if strings.HasSuffix(r.URL.Path, ext) {
-					isStaticAsset = true
-					break
-				}
-			}
-
-			// Skip auth check for static assets
-			if !isStaticAsset {
-				var isAuthed bool
-
-				// Check if path requires authentication
-				{
-					for url, authed := range authenticated {
-						if strings.HasPrefix(r.URL.Path, url) {
-							isAuthed = authed
-							break
-						}
-					}
-				}
-
-				// check token
-				if isAuthed {
-					// deny access if invalid
-					if err := auth.ValidateToken(token); err != nil {
-						// Allow x402 payment as alternative to auth for API requests
-						if wallet.X402Enabled() && wallet.HasPayment(r) && (app.SendsJSON(r) || app.WantsJSON(r)) {
-							r = r.WithContext(context.WithValue(r.Context(), wallet.X402ContextKey, true))
-						} else if app.SendsJSON(r) || app.WantsJSON(r) {
-							// Return JSON 401 for API-style requests
-							w.Header().Set("Content-Type", "application/json")
-							w.WriteHeader(http.StatusUnauthorized)
-							w.Write([]byte(`{"error":"Authentication required"}`))
-							return
-						} else {
-							http.Redirect(w, r, "/", 302)
-							return
-						}
-					}
-				} else if r.URL.Path == "/" {
-					// Fresh instance with no admin yet → guide the operator
-					// through the one-time setup wizard.
-					if setup.Needed() {
-						http.Redirect(w, r, "/setup", http.StatusSeeOther)
-						return
-					}
-					if _, acc := auth.TrySession(r); acc != nil {
-						// Every section has a named URL: the dashboard is /home and a
-						// query goes to the agent (/agent). The root just funnels
-						// logged-in users to the right named place.
-						q := r.URL.Query()
-						if q.Get("q") != "" || q.Get("prompt") != "" {
-							http.Redirect(w, r, "/agent?"+r.URL.RawQuery, http.StatusFound)
-						} else {
-							http.Redirect(w, r, "/home", http.StatusFound)
-						}
-					} else {
-						// Logged out: the live home IS the front door — real cards
-						// plus a working guest agent — so visitors can use Mu
-						// immediately and sign up once they've felt the value,
-						// rather than bouncing off a sign-in wall. The "what is
-						// this" pitch lives at /about.
-						home.Handler(w, r)
-					}
-					return
-				}
-			}
-
-			// Check if this is a user profile request (/@username)
-			if strings.HasPrefix(r.URL.Path, "/@") {
-				rest := r.URL.Path[2:]
-
-				// Handle ActivityPub sub-endpoints: /@username/outbox, /@username/inbox
-				if strings.HasSuffix(rest, "/outbox") {
-					blog.OutboxHandler(w, r)
-					return
-				}
-				if strings.HasSuffix(rest, "/inbox") {
-					blog.InboxHandler(w, r)
-					return
-				}
-
-				// Serve ActivityPub actor JSON if requested
-				if !strings.Contains(rest, "/") && blog.WantsActivityPub(r) {
-					blog.ActorHandler(w, r)
-					return
-				}
-
-				// Otherwise serve the HTML profile page.
-				// POST /@username updates status — run through the
-				// same write gate as every other content path.
-				if !strings.Contains(rest, "/") {
-					if r.Method == "POST" {
-						op := wallet.OpSocialPost
-						sess, err := auth.GetSession(r)
-						if err != nil {
-							http.Error(w, "authentication required", http.StatusUnauthorized)
-							return
-						}
-						if !auth.CanPost(sess.Account) {
-							http.Error(w, auth.PostBlockReason(sess.Account), http.StatusForbidden)
-							return
-						}
-						if err := auth.CheckPostRate(sess.Account); err != nil {
-							http.Error(w, err.Error(), http.StatusTooManyRequests)
-							return
-						}
-						canProceed, _, cost, _ := wallet.CheckQuota(sess.Account, op)
-						if !canProceed {
-							http.Error(w, fmt.Sprintf("This costs %d credit(s). Top up at /wallet", cost), http.StatusPaymentRequired)
-							return
-						}
-						if err := wallet.ConsumeQuota(sess.Account, op); err != nil {
-							http.Error(w, err.Error(), http.StatusPaymentRequired)
-							return
-						}
-						app.Log("wallet", "Charged %s %d credit(s) for POST /@%s status", sess.Account, wallet.GetOperationCost(op), rest)
-					}
-					user.Handler(w, r)
-					return
-				}
-			}
-
-			// CSRF protection: set token cookie on every response,
-			// validate on state-changing requests.
-			auth.SetCSRFCookie(w, r)
-			if r.Method != "GET" && r.Method != "HEAD" && r.Method != "OPTIONS" {
-				// Skip CSRF for API endpoints using Bearer/PAT auth (not cookie-based)
-				isBearerAuth := r.Header.Get("Authorization") != "" || r.Header.Get("X-Micro-Token") != ""
-				// Skip CSRF for MCP endpoint (uses its own auth)
-				isMCP := r.URL.Path == "/mcp"
-				// Skip CSRF for Stripe webhooks
-				isWebhook := r.URL.Path == "/wallet/stripe/webhook"
-				// Skip CSRF for login/signup (no session yet)
-				isAuth := r.URL.Path == "/login" || r.URL.Path == "/signup" ||
-					r.URL.Path == "/request-invite" ||
-					strings.HasPrefix(r.URL.Path, "/passkey/") ||
-					strings.HasPrefix(r.URL.Path, "/oauth/")
-				// Skip CSRF for SMTP/ActivityPub inbound
-				isInbound := strings.HasSuffix(r.URL.Path, "/inbox")
-
-				if !isBearerAuth && !isMCP && !isWebhook && !isAuth && !isInbound && !auth.ValidCSRF(r) {
-					http.Error(w, `{"error":"invalid CSRF token"}`, http.StatusForbidden)
-					return
-				}
-			}
-
-			// ── Centralised write gate ──────────────────────────────
-			// Every content-creating POST is charged, rate-limited,
-			// and moderated from ONE place. Individual handlers do
-			// NOT call CheckQuota/ConsumeQuota — the middleware does
-			// it so nothing can be forgotten.
-			if op := chargedWriteOp(r); op != "" {
-				sess, err := auth.GetSession(r)
-				if err != nil {
-					http.Error(w, "authentication required", http.StatusUnauthorized)
-					return
-				}
-				if !auth.CanPost(sess.Account) {
-					msg := auth.PostBlockReason(sess.Account)
-					http.Error(w, msg, http.StatusForbidden)
-					return
-				}
-				if err := auth.CheckPostRate(sess.Account); err != nil {
-					http.Error(w, err.Error(), http.StatusTooManyRequests)
-					return
-				}
-				canProceed, _, cost, _ := wallet.CheckQuota(sess.Account, op)
-				if !canProceed {
-					http.Error(w, fmt.Sprintf("This costs %d credit(s). Top up at /wallet", cost), http.StatusPaymentRequired)
-					return
-				}
-				// Charge up-front. The handler runs only if the
-				// user can afford it. Failed handler calls (panics,
-				// 5xx) are rare enough that the lost credit is
-				// acceptable — and it's the only way to guarantee
-				// we never forget to charge.
-				if err := wallet.ConsumeQuota(sess.Account, op); err != nil {
-					http.Error(w, err.Error(), http.StatusPaymentRequired)
-					return
-				}
-				app.Log("wallet", "Charged %s %d credit(s) for %s %s", sess.Account, wallet.GetOperationCost(op), r.Method, r.URL.Path)
-			}
-
-			// x402: gate metered MCP tool calls. /mcp is a public endpoint, so
-			// the payment handshake lives here where auth + wallet are in scope.
-			// A metered tools/call with no session gets the standard 402
-			// challenge; one bearing a payment header is routed to the
-			// facilitator for verify+settle by the tool's QuotaCheck.
-			if r.URL.Path == "/mcp" && r.Method == http.MethodPost && wallet.X402Enabled() {
-				body, _ := io.ReadAll(io.LimitReader(r.Body, 1<<20))
-				r.Body.Close()
-				r.Body = io.NopCloser(bytes.NewReader(body))
-				if op := api.MCPWalletOp(body); op != "" {
-					resource := "https://" + r.Host + r.URL.Path
-					if wallet.HasPayment(r) {
-						holder := &wallet.SettleHolder{}
-						ctx := context.WithValue(r.Context(), wallet.X402ContextKey, true)
-						ctx = context.WithValue(ctx, wallet.X402SettleKey, holder)
-						r = r.WithContext(ctx)
-						w = wallet.NewSettleWriter(w, holder)
-					} else if err := auth.ValidateToken(token); err != nil {
-						wallet.WritePaymentRequired(w, op, resource)
-						return
-					}
-				}
-			}
-
-			http.DefaultServeMux.ServeHTTP(w, r)
-		}),
+	router.Wrap(blockBots, app.RequestIDs, app.OnionLocation, app.LogRequests)
+	if *EnvFlag == "dev" {
+		router.Wrap(app.CORS)
 	}
+	if *TLSFlag {
+		router.Wrap(app.HSTS)
+	}
+	router.Wrap(app.Gzip, app.CSP)
+	router.Use(
+		readOnly,
+		app.RefreshSessions,
+		app.RateLimiter,
+		router.Authenticate,
+		app.TokenScopes,
+		router.CSRF,
+		router.Audit,
+		chargeWrites,
+		meterMCP,
+	)
+	return router
+}
 
-	// Channel to listen for interrupt signals
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start SMTP server if enabled (disabled by default)
-	mail.StartSMTPServerIfEnabled()
-
-	// Log initial memory usage
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	app.Log("main", "Startup complete. Memory: Alloc=%dMB Sys=%dMB NumGC=%d", m.Alloc/1024/1024, m.Sys/1024/1024, m.NumGC)
+// blockBots answers known bot paths with a 404, silently.
+func blockBots(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/audio/") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-	// Start memory monitoring goroutine
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-		for range ticker.C {
-			var m runtime.MemStats
-			runtime.ReadMemStats(&m)
-			app.Log("main", "Memory: Alloc=%dMB Sys=%dMB NumGC=%d Goroutines=%d",
-				m.Alloc/1024/1024, m.Sys/1024/1024, m.NumGC, runtime.NumGoroutine())
+// readOnly refuses anything that could change state in read-only mode.
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if *ReadOnlyFlag && mutatingRequest(r) {
+			app.Forbidden(w, r, "This server is read-only")
+			return
 		}
-	}()
+		next.ServeHTTP(w, r)
+	})
+}
 
-	// Start server in a goroutine, preferring a systemd-activated socket so
-	// redeploys don't drop the listener (see serveListener).
-	go func() {
-		ln, activated, err := serveListener(*AddressFlag)
+// chargeWrites is the centralised write gate. Every content-creating POST
+// is charged, rate-limited, and moderated from ONE place. Individual
+// handlers do NOT call CheckQuota/ConsumeQuota — the middleware does it so
+// nothing can be forgotten.
+func chargeWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := chargedWriteOp(r)
+		if op == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sess, err := auth.GetSession(r)
 		if err != nil {
-			app.Log("main", "Listen error on %s: %v", *AddressFlag, err)
-			quit <- syscall.SIGTERM
+			http.Error(w, "authentication required", http.StatusUnauthorized)
 			return
 		}
-		if activated {
-			app.Log("main", "Serving on systemd-activated socket (restarts queue, no 502)")
-		} else {
-			app.Log("main", "Starting server on %s", *AddressFlag)
+		if !auth.CanPost(sess.Account) {
+			msg := auth.PostBlockReason(sess.Account)
+			http.Error(w, msg, http.StatusForbidden)
+			return
 		}
-		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
-			app.Log("main", "Server error: %v", err)
+		// A post held back by the reflection gate isn't published, so
+		// it's neither counted nor charged.
+		if op == wallet.OpBlogCreate && blog.Reflecting(r) {
+			next.ServeHTTP(w, r)
+			return
 		}
-	}()
+		if err := auth.CheckPostRate(sess.Account); err != nil {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		canProceed, _, cost, _ := wallet.CheckQuota(sess.Account, op)
+		if !canProceed {
+			http.Error(w, fmt.Sprintf("This costs %d credit(s). Top up at /wallet", cost), http.StatusPaymentRequired)
+			return
+		}
+		// Charge up-front. The handler runs only if the
+		// user can afford it. Failed handler calls (panics,
+		// 5xx) are rare enough that the lost credit is
+		// acceptable — and it's the only way to guarantee
+		// we never forget to charge.
+		if err := wallet.ConsumeQuota(sess.Account, op); err != nil {
+			http.Error(w, err.Error(), http.StatusPaymentRequired)
+			return
+		}
+		app.Log("wallet", "Charged %s %d credit(s) for %s %s", sess.Account, wallet.GetOperationCost(op), r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
 
-	// Wait for interrupt signal
-	<-quit
-	app.Log("main", "Shutting down server...")
+// meterMCP is x402 for metered MCP tool calls. /mcp is a public endpoint,
+// so the payment handshake lives here where auth + wallet are in scope.
+// A metered tools/call with no session gets the standard 402 challenge;
+// one bearing a payment header is routed to the facilitator for
+// verify+settle by the tool's QuotaCheck.
+func meterMCP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/mcp" && r.Method == http.MethodPost && wallet.X402Enabled() {
+			body, _ := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if op := api.MCPWalletOp(body); op != "" {
+				resource := "https://" + r.Host + r.URL.Path
+				if wallet.HasPayment(r) {
+					holder := &wallet.SettleHolder{}
+					ctx := context.WithValue(r.Context(), wallet.X402ContextKey, true)
+					ctx = context.WithValue(ctx, wallet.X402SettleKey, holder)
+					r = r.WithContext(ctx)
+					w = wallet.NewSettleWriter(w, holder)
+				} else if err := auth.ValidateToken(app.RequestToken(r)); err != nil {
+					wallet.WritePaymentRequired(w, op, resource)
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// profileHandler serves /@username: the profile page, the user's feeds
+// and their ActivityPub actor, outbox and inbox.
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	rest := r.URL.Path[2:]
 
-	// Attempt graceful shutdown
-	if err := server.Shutdown(ctx); err != nil {
-		app.Log("main", "Server forced to shutdown: %v", err)
+	// The user's posts as RSS, Atom or JSON Feed
+	if strings.HasSuffix(rest, "/feed") {
+		blog.AuthorFeedHandler(w, r)
+		return
 	}
 
-	app.Log("main", "Server stopped")
+	// Handle ActivityPub sub-endpoints: /@username/outbox, /@username/inbox
+	if strings.HasSuffix(rest, "/outbox") {
+		blog.OutboxHandler(w, r)
+		return
+	}
+	if strings.HasSuffix(rest, "/inbox") {
+		blog.InboxHandler(w, r)
+		return
+	}
+
+	if strings.Contains(rest, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	// Serve ActivityPub actor JSON if requested
+	if blog.WantsActivityPub(r) {
+		blog.ActorHandler(w, r)
+		return
+	}
+	// Otherwise the HTML profile page. POST updates the status, and is
+	// charged by chargeWrites like every other content path.
+	user.Handler(w, r)
+}
+
+// pingHandler serves /ping, which keeps the signed-in user present.
+func pingHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	auth.UpdatePresence(acc.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	onlineCount := auth.GetOnlineCount()
+	w.Write([]byte(fmt.Sprintf(`{"status":"ok","online":%d}`, onlineCount)))
 }
 
 // updatesHandler serves GET /updates?since=<unix> — a single lightweight
@@ -1904,9 +1834,11 @@ func chargedWriteOp(r *http.Request) string {
 	}
 	path := r.URL.Path
 	switch {
-	// Status updates
+	// Status updates, from the status form or the profile page
 	case path == "/user/status":
 		return wallet.OpSocialPost
+	case strings.HasPrefix(path, "/@") && !strings.Contains(path[2:], "/"):
+		return wallet.OpSocialPost
 	// Social threads and replies
 	case path == "/social":
 		return wallet.OpSocialPost
@@ -1915,7 +1847,8 @@ func chargedWriteOp(r *http.Request) string {
 	// Blog — only CREATE is charged (no id param). Updates are free.
 	case path == "/blog" && r.URL.Query().Get("id") == "":
 		return wallet.OpBlogCreate
-	case strings.HasPrefix(path, "/blog/post/") && strings.HasSuffix(path, "/comment"):
+	// Comments — an id param is an admin editing an existing comment.
+	case strings.HasPrefix(path, "/blog/post/") && strings.HasSuffix(path, "/comment") && r.URL.Query().Get("id") == "":
 		return wallet.OpBlogComment
 	// Apps
 	case path == "/apps/new":
@@ -1970,17 +1903,44 @@ func isServerMode(args []string) bool {
 	return false
 }
 
+// mutatingRequest reports whether a request can change server state: any
+// method other than GET, HEAD or OPTIONS, and websocket upgrades (chat
+// messages arrive over the socket, not as POSTs).
+func mutatingRequest(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+	}
+	return true
+}
+
+// lowMemoryJobDelay is how long non-essential background jobs wait to
+// start in low-memory mode, so they don't compete with loading and indexing.
+const lowMemoryJobDelay = 10 * time.Minute
+
+// deferJob starts a background job that nothing waits on: straight away
+// normally, after lowMemoryJobDelay in low-memory mode.
+func deferJob(start func()) {
+	if !data.LowMemory() {
+		start()
+		return
+	}
+	time.AfterFunc(lowMemoryJobDelay, start)
+}
+
 // runHealthChecks performs lightweight health checks on public-facing services
 // versionInfo reports the running build and how the system is wired, so a
 // deploy can be verified with `curl micro.mu/version`.
 func versionInfo() map[string]any {
 	info := map[string]any{
-		"version":  app.Version, // per-process id (start time)
-		"go":       runtime.Version(),
-		"agent":    agent.Mode(),       // "native" (go-micro agent) or "planner"
-		"mcp":      "go-micro/gateway", // /mcp served by go-micro's gateway
-		"services": service.Services(), // in-process go-micro services
-		"go_micro": "unknown",
+		"version":    app.Version, // per-process id (start time)
+		"go":         runtime.Version(),
+		"agent":      agent.Mode(),       // "native" (go-micro agent) or "planner"
+		"mcp":        "go-micro/gateway", // /mcp served by go-micro's gateway
+		"services":   service.Services(), // in-process go-micro services
+		"read_only":  data.ReadOnly(),
+		"low_memory": data.LowMemory(),
+		"go_micro":   "unknown",
 	}
 	if bi, ok := debug.ReadBuildInfo(); ok {
 		for _, s := range bi.Settings {
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/blog"
+	"mu/internal/apptest"
+)
+
+// The tests in this file run the whole app, booted by main's boot, with
+// its routing and middleware, against fake upstreams (see apptest). They
+// are skipped with -short.
+func TestMain(m *testing.M) {
+	apptest.Main(m, boot)
+}
+
+func TestE2ESignupPostCommentModerate(t *testing.T) {
+	env := apptest.Get(t)
+	admin := env.Admin(t, apptest.Username("admin"))
+
+	// A new member signs up, but can't post until an admin approves them.
+	writerID := apptest.Username("writer")
+	writer := env.Signup(t, writerID, "correct-horse")
+	if resp := writer.Get("/account"); resp.Status != http.StatusOK {
+		t.Fatalf("signed up but not signed in: GET /account = %d", resp.Status)
+	}
+	post := url.Values{
+		"title":   {"Notes from the allotment"},
+		"content": {"The broad beans are finally up, and the rhubarb has come back stronger than last year."},
+	}
+	if resp := writer.PostForm("/blog", post); resp.Status != http.StatusForbidden {
+		t.Fatalf("new account posted: %d %s", resp.Status, resp.Body)
+	}
+	resp := admin.PostForm("/admin/moderate", url.Values{"action": {"approve_account"}, "type": {"account"}, "id": {writerID}})
+	if resp.Status != http.StatusSeeOther {
+		t.Fatalf("approve account: %d %s", resp.Status, resp.Body)
+	}
+
+	// Posting straight off is held back for a moment; posting from the
+	// write form once the moment has passed goes through.
+	t.Setenv("POST_REFLECTION_SECONDS", "1")
+	if resp := writer.PostForm("/blog", post); resp.Status != http.StatusTooEarly || !strings.Contains(resp.Body, "Take a moment") {
+		t.Fatalf("hasty post: %d %s", resp.Status, resp.Body)
+	}
+	m := composeTokenField.FindStringSubmatch(writer.Get("/blog?write=true").Body)
+	if m == nil {
+		t.Fatal("no compose token in the write form")
+	}
+	time.Sleep(1100 * time.Millisecond)
+	post.Set("compose_token", m[1])
+	if resp := writer.PostForm("/blog", post); resp.Status != http.StatusSeeOther || resp.Location() != "/blog" {
+		t.Fatalf("post: %d %s %s", resp.Status, resp.Location(), resp.Body)
+	}
+	posts := blog.GetPostsByAuthor(writerID)
+	if len(posts) != 1 {
+		t.Fatalf("writer has %d posts", len(posts))
+	}
+	postURL := "/blog/post?id=" + posts[0].ID
+	if page := env.Client(t).Get(postURL); page.Status != http.StatusOK || !strings.Contains(page.Body, "Notes from the allotment") {
+		t.Fatalf("GET %s = %d", postURL, page.Status)
+	}
+
+	// A reader comments twice; the model takes one comment the wrong way.
+	env.LLM.Reply(func(system, prompt string) string {
+		if strings.Contains(prompt, "kill") {
+			return "HARMFUL"
+		}
+		return "OK"
+	})
+	t.Cleanup(func() { env.LLM.Reply(func(string, string) string { return "OK" }) })
+	reader := env.Member(t, apptest.Username("reader"))
+	for _, comment := range []string{
+		"Lovely to see the beans doing well.",
+		"Netting them early should kill off the pigeon problem.",
+	} {
+		resp := reader.PostForm("/blog/post/"+posts[0].ID+"/comment", url.Values{"content": {comment}})
+		if resp.Status != http.StatusSeeOther || resp.Location() != postURL {
+			t.Fatalf("comment: %d %s %s", resp.Status, resp.Location(), resp.Body)
+		}
+	}
+	comments := blog.GetComments(posts[0].ID)
+	if len(comments) != 2 {
+		t.Fatalf("post has %d comments", len(comments))
+	}
+	flagged := comments[0].ID
+	if strings.Contains(comments[1].Content, "kill") {
+		flagged = comments[1].ID
+	}
+
+	// Moderation hides the flagged comment from everyone but admins...
+	apptest.Eventually(t, 10*time.Second, "the comment to be flagged", func() bool {
+		return strings.Contains(admin.Get("/admin/moderate").Body, flagged)
+	})
+	page := env.Client(t).Get(postURL).Body
+	if !strings.Contains(page, "Lovely to see the beans") || strings.Contains(page, "pigeon problem") {
+		t.Fatal("post page should show the first comment and hide the flagged one")
+	}
+	if !strings.Contains(admin.Get(postURL).Body, "pigeon problem") {
+		t.Error("admins should still see a hidden comment")
+	}
+
+	// ...until an admin approves it.
+	resp = admin.PostForm("/admin/moderate", url.Values{"action": {"approve"}, "type": {"comment"}, "id": {flagged}})
+	if resp.Status != http.StatusSeeOther {
+		t.Fatalf("approve comment: %d %s", resp.Status, resp.Body)
+	}
+	if !strings.Contains(env.Client(t).Get(postURL).Body, "pigeon problem") {
+		t.Error("approved comment still hidden")
+	}
+	if page := admin.Get("/admin/moderate").Body; !strings.Contains(page, "<td>approve</td>") || !strings.Contains(page, flagged) {
+		t.Error("the approval isn't in the audit log")
+	}
+}
+
+var composeTokenField = regexp.MustCompile(`name="compose_token" value="([^"]+)"`)
+
+var mailThreadLink = regexp.MustCompile(`/mail\?id=([0-9A-Za-z_-]+)`)
+
+func TestE2EMailSendAndReply(t *testing.T) {
+	env := apptest.Get(t)
+	aliceID, bobID := apptest.Username("alice"), apptest.Username("bob")
+	alice, bob := env.Member(t, aliceID), env.Member(t, bobID)
+
+	resp := alice.PostForm("/mail", url.Values{
+		"to":      {bobID},
+		"subject": {"Lunch on Friday?"},
+		"body":    {"The new place on the corner does a good soup."},
+	})
+	if resp.Status != http.StatusSeeOther {
+		t.Fatalf("send: %d %s", resp.Status, resp.Body)
+	}
+
+	inbox := bob.Get("/mail")
+	if !strings.Contains(inbox.Body, "Lunch on Friday?") {
+		t.Fatalf("message not in bob's inbox:\n%s", inbox.Body)
+	}
+	m := mailThreadLink.FindStringSubmatch(inbox.Body)
+	if m == nil {
+		t.Fatal("no link to the message in bob's inbox")
+	}
+	thread := "/mail?id=" + m[1]
+	if page := bob.Get(thread); !strings.Contains(page.Body, "good soup") {
+		t.Fatalf("GET %s doesn't show the message:\n%s", thread, page.Body)
+	}
+
+	resp = bob.PostForm("/mail", url.Values{
+		"to":       {aliceID},
+		"subject":  {"Re: Lunch on Friday?"},
+		"body":     {"Friday works, see you at one."},
+		"reply_to": {m[1]},
+	})
+	if resp.Status != http.StatusSeeOther || resp.Location() != thread {
+		t.Fatalf("reply: %d %s %s", resp.Status, resp.Location(), resp.Body)
+	}
+	if page := alice.Get(thread); !strings.Contains(page.Body, "see you at one") {
+		t.Fatalf("alice doesn't see the reply in the thread:\n%s", page.Body)
+	}
+
+	// Mail is private to the people in it.
+	if page := env.Member(t, apptest.Username("carol")).Get(thread); strings.Contains(page.Body, "good soup") {
+		t.Error("someone else can read the thread")
+	}
+}
+
+func TestE2ENewsFromFeed(t *testing.T) {
+	env := apptest.Get(t)
+	c := env.Client(t)
+	headline := apptest.DefaultFeedItems[0].Title
+	apptest.Eventually(t, 30*time.Second, "the feed to be fetched", func() bool {
+		return strings.Contains(c.Get("/news").Body, headline)
+	})
+}
+
+func TestE2EPlacesSearch(t *testing.T) {
+	env := apptest.Get(t)
+	var result struct {
+		Results []struct {
+			Name string  `json:"name"`
+			Lat  float64 `json:"lat"`
+		} `json:"results"`
+	}
+	resp := env.Member(t, apptest.Username("explorer")).GetJSON("/places?q=Corner+Cafe", &result)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("search: %d %s", resp.Status, resp.Body)
+	}
+	if len(result.Results) != 1 || result.Results[0].Lat != 51.5007 {
+		t.Fatalf("results = %+v", result.Results)
+	}
+}
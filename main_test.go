@@ -30,6 +30,33 @@ func TestIsServerMode(t *testing.T) {
 	}
 }
 
+func TestMutatingRequest(t *testing.T) {
+	tests := []struct {
+		method  string
+		upgrade string
+		want    bool
+	}{
+		{method: "GET", want: false},
+		{method: "HEAD", want: false},
+		{method: "OPTIONS", want: false},
+		{method: "GET", upgrade: "websocket", want: true},
+		{method: "POST", want: true},
+		{method: "PUT", want: true},
+		{method: "PATCH", want: true},
+		{method: "DELETE", want: true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/chat", nil)
+		if tt.upgrade != "" {
+			r.Header.Set("Upgrade", tt.upgrade)
+		}
+		if got := mutatingRequest(r); got != tt.want {
+			t.Errorf("mutatingRequest(%s, upgrade=%q) = %v, want %v", tt.method, tt.upgrade, got, tt.want)
+		}
+	}
+}
+
 func TestChargedWriteOp(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -39,11 +66,14 @@ func TestChargedWriteOp(t *testing.T) {
 	}{
 		{name: "reads are free", method: "GET", path: "/social", want: ""},
 		{name: "status post", method: "POST", path: "/user/status", want: wallet.OpSocialPost},
+		{name: "profile status", method: "POST", path: "/@alice", want: wallet.OpSocialPost},
+		{name: "activitypub inbox free", method: "POST", path: "/@alice/inbox", want: ""},
 		{name: "social thread", method: "POST", path: "/social", want: wallet.OpSocialPost},
 		{name: "social reply", method: "POST", path: "/social/thread", want: wallet.OpSocialReply},
 		{name: "new blog post", method: "POST", path: "/blog", want: wallet.OpBlogCreate},
 		{name: "blog update free", method: "POST", path: "/blog?id=post-1", want: ""},
 		{name: "blog comment", method: "POST", path: "/blog/post/post-1/comment", want: wallet.OpBlogComment},
+		{name: "comment edit free", method: "POST", path: "/blog/post/post-1/comment?id=c-1", want: ""},
 		{name: "app generation", method: "POST", path: "/apps/generate", want: wallet.OpAppBuild},
 		{name: "uncharged post", method: "POST", path: "/mail", want: ""},
 	}
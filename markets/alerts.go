@@ -0,0 +1,323 @@
+package markets
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Price alerts. A user picks a ticker, a direction and a price; each time
+// prices refresh the alerts are checked, and a triggered alert sends the
+// user a mail (via OnAlert) and switches itself off.
+
+const maxAlertsPerUser = 20
+
+// Alert is one user's price threshold.
+type Alert struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Symbol    string    `json:"symbol"`
+	Above     bool      `json:"above"` // trigger at or above Price, else at or below
+	Price     float64   `json:"price"`
+	Created   time.Time `json:"created"`
+	Triggered time.Time `json:"triggered,omitempty"`
+	Hit       float64   `json:"hit,omitempty"` // price when triggered
+}
+
+// Active reports whether the alert is still waiting to trigger.
+func (a *Alert) Active() bool {
+	return a.Triggered.IsZero()
+}
+
+func (a *Alert) direction() string {
+	if a.Above {
+		return "above"
+	}
+	return "below"
+}
+
+// OnAlert is called when an alert triggers. main.go wires it to internal
+// mail.
+var OnAlert func(userID, subject, body string)
+
+var (
+	alertsMu sync.Mutex
+	alerts   = map[string][]*Alert{} // user ID → alerts
+)
+
+func loadAlerts() {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	data.LoadJSON("price_alerts.json", &alerts)
+	if alerts == nil {
+		alerts = map[string][]*Alert{}
+	}
+	auth.AccountDeleteHooks = append(auth.AccountDeleteHooks, func(userID string) {
+		alertsMu.Lock()
+		defer alertsMu.Unlock()
+		if _, ok := alerts[userID]; ok {
+			delete(alerts, userID)
+			data.SaveJSON("price_alerts.json", alerts)
+		}
+	})
+	auth.AccountRenameHooks = append(auth.AccountRenameHooks, func(oldID, newID string) {
+		alertsMu.Lock()
+		defer alertsMu.Unlock()
+		if list, ok := alerts[oldID]; ok {
+			for _, a := range list {
+				a.UserID = newID
+			}
+			delete(alerts, oldID)
+			alerts[newID] = list
+			data.SaveJSON("price_alerts.json", alerts)
+		}
+	})
+}
+
+// AddAlert sets an alert for when symbol goes above (or below) price. It
+// refuses a threshold the current price has already crossed.
+func AddAlert(userID, symbol string, above bool, price float64) (*Alert, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if price <= 0 {
+		return nil, errors.New("price must be positive")
+	}
+	current, ok := GetAllPrices()[symbol]
+	if !ok {
+		return nil, fmt.Errorf("unknown ticker %q", symbol)
+	}
+	if (above && current >= price) || (!above && current <= price) {
+		return nil, fmt.Errorf("%s is already at %s", symbol, formatPrice(current))
+	}
+
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	active := 0
+	for _, a := range alerts[userID] {
+		if a.Active() {
+			active++
+		}
+	}
+	if active >= maxAlertsPerUser {
+		return nil, fmt.Errorf("you can have at most %d active alerts", maxAlertsPerUser)
+	}
+	a := &Alert{
+		ID:      uuid.New().String(),
+		UserID:  userID,
+		Symbol:  symbol,
+		Above:   above,
+		Price:   price,
+		Created: time.Now(),
+	}
+	alerts[userID] = append(alerts[userID], a)
+	data.SaveJSON("price_alerts.json", alerts)
+	return a, nil
+}
+
+// DeleteAlert removes one of the user's alerts.
+func DeleteAlert(userID, id string) error {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	list := alerts[userID]
+	for i, a := range list {
+		if a.ID == id {
+			alerts[userID] = append(list[:i], list[i+1:]...)
+			if len(alerts[userID]) == 0 {
+				delete(alerts, userID)
+			}
+			data.SaveJSON("price_alerts.json", alerts)
+			return nil
+		}
+	}
+	return errors.New("alert not found")
+}
+
+// UserAlerts returns the user's alerts, active ones first.
+func UserAlerts(userID string) []Alert {
+	alertsMu.Lock()
+	out := make([]Alert, 0, len(alerts[userID]))
+	for _, a := range alerts[userID] {
+		out = append(out, *a)
+	}
+	alertsMu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Active() != out[j].Active() {
+			return out[i].Active()
+		}
+		return out[i].Created.After(out[j].Created)
+	})
+	return out
+}
+
+// checkAlerts triggers every active alert whose threshold prices have
+// crossed.
+func checkAlerts(prices map[string]float64) {
+	type fired struct {
+		userID, subject, body string
+	}
+	var notify []fired
+
+	alertsMu.Lock()
+	now := time.Now()
+	for _, list := range alerts {
+		for _, a := range list {
+			price, ok := prices[a.Symbol]
+			if !a.Active() || !ok || price <= 0 {
+				continue
+			}
+			if (a.Above && price < a.Price) || (!a.Above && price > a.Price) {
+				continue
+			}
+			a.Triggered = now
+			a.Hit = price
+			notify = append(notify, fired{
+				userID:  a.UserID,
+				subject: fmt.Sprintf("Price alert: %s is %s %s", a.Symbol, a.direction(), formatPrice(a.Price)),
+				body: fmt.Sprintf("%s is now %s, %s your alert at %s.\n\nSee /markets for the latest prices. Alerts switch off once they trigger; set a new one there.",
+					a.Symbol, formatPrice(price), a.direction(), formatPrice(a.Price)),
+			})
+		}
+	}
+	if len(notify) > 0 {
+		data.SaveJSON("price_alerts.json", alerts)
+	}
+	alertsMu.Unlock()
+
+	for _, n := range notify {
+		app.Log("markets", "Price alert for %s: %s", n.userID, n.subject)
+		if OnAlert != nil {
+			OnAlert(n.userID, n.subject, n.body)
+		}
+	}
+}
+
+// AlertsHandler serves /markets/alerts for the signed-in user.
+//
+//	GET                          list alerts (JSON), or back to /markets
+//	POST {"symbol","direction","price"}  add an alert; direction is above or below
+//	DELETE ?id=X                 remove an alert (forms POST action=delete)
+func AlertsHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+
+	var symbol, direction, priceStr, action, id string
+	switch r.Method {
+	case "GET":
+		if !app.WantsJSON(r) {
+			http.Redirect(w, r, "/markets#alerts", http.StatusSeeOther)
+			return
+		}
+		app.RespondJSON(w, map[string]interface{}{"alerts": UserAlerts(acc.ID)})
+		return
+	case "POST":
+		if app.SendsJSON(r) {
+			var req struct {
+				Symbol    string  `json:"symbol"`
+				Direction string  `json:"direction"`
+				Price     float64 `json:"price"`
+			}
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+			symbol, direction, priceStr = req.Symbol, req.Direction, strconv.FormatFloat(req.Price, 'f', -1, 64)
+		} else {
+			r.ParseForm()
+			symbol, direction, priceStr = r.FormValue("symbol"), r.FormValue("direction"), r.FormValue("price")
+			action, id = r.FormValue("action"), r.FormValue("id")
+		}
+	case "DELETE":
+		action, id = "delete", r.URL.Query().Get("id")
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	if action == "delete" {
+		if err := DeleteAlert(acc.ID, id); err != nil {
+			app.NotFound(w, r, err.Error())
+			return
+		}
+		if app.WantsJSON(r) || app.SendsJSON(r) || r.Method == "DELETE" {
+			app.RespondJSON(w, map[string]bool{"success": true})
+			return
+		}
+		http.Redirect(w, r, "/markets#alerts", http.StatusSeeOther)
+		return
+	}
+
+	price, err := strconv.ParseFloat(strings.TrimSpace(priceStr), 64)
+	if err != nil {
+		app.BadRequest(w, r, "price must be a number")
+		return
+	}
+	if direction != "above" && direction != "below" {
+		app.BadRequest(w, r, "direction must be above or below")
+		return
+	}
+	a, err := AddAlert(acc.ID, symbol, direction == "above", price)
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return
+	}
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		app.RespondJSON(w, a)
+		return
+	}
+	http.Redirect(w, r, "/markets#alerts", http.StatusSeeOther)
+}
+
+// renderAlerts renders the alerts section of /markets for a signed-in
+// user.
+func renderAlerts(userID string) string {
+	prices := GetAllPrices()
+	symbols := make([]string, 0, len(prices))
+	for s := range prices {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+
+	var sb strings.Builder
+	sb.WriteString(`<div id="alerts" class="markets-alerts"><h3>Price alerts</h3>`)
+	sb.WriteString(`<p class="text-muted text-sm">Get a mail when a price crosses your threshold. Prices are checked each time they refresh.</p>`)
+	sb.WriteString(`<form method="POST" action="/markets/alerts" class="markets-alert-form"><select name="symbol" required>`)
+	for _, s := range symbols {
+		fmt.Fprintf(&sb, `<option value="%s">%s</option>`, html.EscapeString(s), html.EscapeString(s))
+	}
+	sb.WriteString(`</select><select name="direction"><option value="above">goes above</option><option value="below">goes below</option></select>`)
+	sb.WriteString(`<input type="number" name="price" step="any" min="0" placeholder="Price" required><button type="submit">Add alert</button></form>`)
+
+	list := UserAlerts(userID)
+	if len(list) > 0 {
+		sb.WriteString(`<table class="markets-table"><tbody>`)
+		for _, a := range list {
+			status := "Waiting · now " + formatPrice(prices[a.Symbol])
+			if !a.Active() {
+				status = "Triggered " + app.TimeAgo(a.Triggered) + " at " + formatPrice(a.Hit)
+			}
+			fmt.Fprintf(&sb, `<tr><td>%s %s %s</td><td class="text-muted text-sm">%s</td><td><form method="POST" action="/markets/alerts"><input type="hidden" name="action" value="delete"><input type="hidden" name="id" value="%s"><button type="submit" class="btn-link">Remove</button></form></td></tr>`,
+				html.EscapeString(a.Symbol), a.direction(), formatPrice(a.Price), status, html.EscapeString(a.ID))
+		}
+		sb.WriteString(`</tbody></table>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
@@ -13,12 +13,11 @@ import (
 	"time"
 
 	"mu/internal/app"
+	"mu/internal/auth"
 	"mu/internal/data"
+	"mu/internal/egress"
 	"mu/internal/service"
 	"mu/internal/snapshot"
-
-	"github.com/piquette/finance-go/future"
-	"github.com/piquette/finance-go/quote"
 )
 
 // cardSnap is the go-micro read-plane channel for the markets card (store +
@@ -118,8 +117,18 @@ func Load() {
 	marketsMutex.RUnlock()
 	cardSnap.Publish(warm)
 
-	// Start background refresh
-	go refreshMarkets()
+	loadAlerts()
+
+	// Start background refresh. Prices restored from disk are served until
+	// they are an hour old, so a restart doesn't mean a burst of requests
+	// to the price APIs.
+	marketsMutex.RLock()
+	wait := time.Until(lastPriceRefresh.Add(time.Hour))
+	marketsMutex.RUnlock()
+	if wait > 0 {
+		app.Log("markets", "Serving prices from %s, next refresh in %s", lastPriceRefresh.Format(time.Kitchen), wait.Round(time.Minute))
+	}
+	app.Schedule(app.Job{Name: "markets", Every: time.Hour, Delay: max(wait, 0), Retries: 2, Run: refreshMarkets})
 }
 
 // TopMovers returns a short string summarising the N biggest movers
@@ -165,30 +174,30 @@ func TopMovers(n int) string {
 	return strings.Join(parts, ", ")
 }
 
-func refreshMarkets() {
-	for {
-		prices, priceData := fetchPrices()
-		if prices != nil {
-			html := generateMarketsCardHTML(prices)
-			marketsMutex.Lock()
-			cachedPrices = prices
-			cachedPriceData = priceData
-			lastPriceRefresh = time.Now().UTC()
-			marketsHTML = html
-			marketsMutex.Unlock()
-
-			// Publish the new snapshot to the go-micro store + broker; the read
-			// path serves it from a mirror (see internal/snapshot, docs/GO_MICRO_ARCHITECTURE.md).
-			cardSnap.Publish(html)
-
-			indexMarketPrices(prices)
-			data.SaveFile("markets.html", html)
-			data.SaveJSON("prices.json", cachedPrices)
-			data.SaveJSON("price_data.json", cachedPriceData)
-		}
-
-		time.Sleep(time.Hour)
-	}
+// refreshMarkets fetches prices and republishes the card.
+func refreshMarkets() error {
+	prices, priceData := fetchPrices()
+	if prices == nil {
+		return fmt.Errorf("no prices fetched")
+	}
+	html := generateMarketsCardHTML(prices)
+	marketsMutex.Lock()
+	cachedPrices = prices
+	cachedPriceData = priceData
+	lastPriceRefresh = time.Now().UTC()
+	marketsHTML = html
+	marketsMutex.Unlock()
+
+	// Publish the new snapshot to the go-micro store + broker; the read
+	// path serves it from a mirror (see internal/snapshot, docs/GO_MICRO_ARCHITECTURE.md).
+	cardSnap.Publish(html)
+
+	indexMarketPrices(prices)
+	checkAlerts(prices)
+	data.SaveFile("markets.html", html)
+	data.SaveJSON("prices.json", cachedPrices)
+	data.SaveJSON("price_data.json", cachedPriceData)
+	return nil
 }
 
 func fetchPrices() (map[string]float64, map[string]PriceData) {
@@ -203,6 +212,7 @@ func fetchPrices() (map[string]float64, map[string]PriceData) {
 
 	b, _ := ioutil.ReadAll(rsp.Body)
 	rates, err := parseCoinbaseRates(b)
+	egress.CheckFormat("coinbase", coinbaseFormat, err)
 	if err != nil {
 		app.Log("markets", "Error parsing crypto prices: %v", err)
 		return nil, nil
@@ -233,85 +243,54 @@ func fetchPrices() (map[string]float64, map[string]PriceData) {
 		}
 	}
 
-	// Get futures prices
-	app.Log("markets", "Fetching futures prices")
-	for key, ftr := range futuresSymbols {
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					app.Log("markets", "Panic getting future %s: %v", key, r)
-				}
-			}()
-
-			f, err := future.Get(ftr)
-			if err != nil {
-				app.Log("markets", "Failed to get future %s: %v", key, err)
-				return
-			}
-			if f == nil {
-				return
-			}
-			price := f.Quote.RegularMarketPrice
-			if price > 0 {
-				prices[key] = price
-				priceData[key] = PriceData{
-					Price:     price,
-					Change24h: f.Quote.RegularMarketChangePercent,
-					UpdatedAt: time.Now().UTC(),
-					Source:    "Yahoo Finance",
-				}
-			}
-		}()
-	}
-
-	// Get forex 24h changes from Yahoo Finance
-	app.Log("markets", "Fetching currency prices")
-	for currency, yahooSymbol := range forexSymbols {
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					app.Log("markets", "Panic getting forex %s: %v", currency, r)
-				}
-			}()
-
-			q, err := quote.Get(yahooSymbol)
-			if err != nil {
-				app.Log("markets", "Failed to get forex %s: %v", currency, err)
-				return
-			}
-			if q == nil {
-				return
-			}
-			price := q.RegularMarketPrice
-			if price > 0 {
-				prices[currency] = price
-				priceData[currency] = PriceData{
-					Price:     price,
-					Change24h: q.RegularMarketChangePercent,
-					UpdatedAt: time.Now().UTC(),
-					Source:    "Yahoo Finance",
-				}
-			}
-		}()
+	// Get futures and forex prices from Yahoo Finance
+	app.Log("markets", "Fetching futures and currency prices")
+	for key, symbol := range futuresSymbols {
+		fetchYahooInto(key, symbol, prices, priceData)
+	}
+	for currency, symbol := range forexSymbols {
+		fetchYahooInto(currency, symbol, prices, priceData)
 	}
 
 	app.Log("markets", "Finished fetching prices")
 	return prices, priceData
 }
 
+// coinbaseFormat is the version of the Coinbase exchange rates response
+// parseCoinbaseRates understands.
+const coinbaseFormat = "v2"
+
+// parseCoinbaseRates reads the rates from a Coinbase v2 exchange rates
+// response, keyed by currency. Rates are decimal strings; numbers are
+// accepted too, in case Coinbase stops quoting them.
 func parseCoinbaseRates(body []byte) (map[string]string, error) {
 	var res struct {
-		Data struct {
-			Rates map[string]string `json:"rates"`
+		Data *struct {
+			Rates map[string]json.RawMessage `json:"rates"`
 		} `json:"data"`
 	}
 	if err := json.Unmarshal(body, &res); err != nil {
-		return nil, err
+		return nil, egress.Drift("coinbase", "%v", err)
+	}
+	if res.Data == nil {
+		return nil, egress.Drift("coinbase", "no data object")
 	}
 	if len(res.Data.Rates) == 0 {
-		return nil, fmt.Errorf("missing rates")
+		return nil, egress.Drift("coinbase", "missing rates")
+	}
+	rates := make(map[string]string, len(res.Data.Rates))
+	for k, raw := range res.Data.Rates {
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			var n json.Number
+			if err := json.Unmarshal(raw, &n); err != nil {
+				return nil, egress.Drift("coinbase", "rate for %s is %s, not a number", k, raw)
+			}
+			s = n.String()
+		}
+		rates[k] = s
 	}
-	return res.Data.Rates, nil
+	return rates, nil
 }
 
 // fetchCoinGeckoChanges fetches 24h price changes from CoinGecko for all crypto assets
@@ -636,6 +615,9 @@ func handleHTML(w http.ResponseWriter, r *http.Request, category string) {
 
 	// Generate HTML for the selected category
 	body := generateMarketsPage(priceData, category)
+	if _, acc := auth.TrySession(r); acc != nil {
+		body += renderAlerts(acc.ID)
+	}
 
 	app.Respond(w, r, app.Response{
 		Title:       "Markets",
@@ -4,6 +4,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"mu/internal/egress"
+	"mu/internal/testutil"
 )
 
 func TestFormatPrice(t *testing.T) {
@@ -156,6 +159,28 @@ func TestParseCoinbaseRates(t *testing.T) {
 	}
 }
 
+func TestParseCoinbaseRates_Fixture(t *testing.T) {
+	rates, err := parseCoinbaseRates(testutil.Fixture(t, "coinbase_exchange_rates.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, symbol := range tickers {
+		if _, ok := rates[symbol]; !ok {
+			t.Errorf("no rate for %s", symbol)
+		}
+	}
+}
+
+func TestParseCoinbaseRatesAcceptsNumbers(t *testing.T) {
+	rates, err := parseCoinbaseRates([]byte(`{"data":{"rates":{"BTC":0.000010}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates["BTC"] != "0.000010" {
+		t.Fatalf("BTC rate = %q, want %q", rates["BTC"], "0.000010")
+	}
+}
+
 func TestParseCoinbaseRatesRejectsMalformedPayloads(t *testing.T) {
 	tests := []struct {
 		name string
@@ -165,12 +190,13 @@ func TestParseCoinbaseRatesRejectsMalformedPayloads(t *testing.T) {
 		{name: "missing data", body: `{}`},
 		{name: "missing rates", body: `{"data":{}}`},
 		{name: "wrong rate shape", body: `{"data":{"rates":[]}}`},
+		{name: "rate as object", body: `{"data":{"rates":{"BTC":{"amount":"0.00001"}}}}`},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if _, err := parseCoinbaseRates([]byte(tt.body)); err == nil {
-				t.Fatalf("parseCoinbaseRates(%s) returned nil error", tt.body)
+			if _, err := parseCoinbaseRates([]byte(tt.body)); !egress.IsDrift(err) {
+				t.Fatalf("parseCoinbaseRates(%s) = %v, want drift", tt.body, err)
 			}
 		})
 	}
@@ -254,3 +280,46 @@ func TestCategoryConstants(t *testing.T) {
 		t.Error("unexpected currencies constant")
 	}
 }
+
+func TestPriceAlertsTriggerOnce(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	marketsMutex.Lock()
+	old := cachedPrices
+	cachedPrices = map[string]float64{"BTC": 60000}
+	marketsMutex.Unlock()
+	defer func() {
+		marketsMutex.Lock()
+		cachedPrices = old
+		marketsMutex.Unlock()
+	}()
+	alertsMu.Lock()
+	alerts = map[string][]*Alert{}
+	alertsMu.Unlock()
+
+	var sent []string
+	OnAlert = func(userID, subject, body string) { sent = append(sent, userID+": "+subject) }
+	defer func() { OnAlert = nil }()
+
+	if _, err := AddAlert("alice", "btc", true, 50000); err == nil {
+		t.Error("alert already crossed was accepted")
+	}
+	if _, err := AddAlert("alice", "DOGE", true, 1); err == nil {
+		t.Error("unknown ticker accepted")
+	}
+	if _, err := AddAlert("alice", "btc", true, 65000); err != nil {
+		t.Fatalf("AddAlert: %v", err)
+	}
+
+	checkAlerts(map[string]float64{"BTC": 64000})
+	if len(sent) != 0 {
+		t.Fatalf("alert fired early: %v", sent)
+	}
+	checkAlerts(map[string]float64{"BTC": 66000})
+	checkAlerts(map[string]float64{"BTC": 67000})
+	if len(sent) != 1 || !strings.Contains(sent[0], "alice: Price alert: BTC is above") {
+		t.Fatalf("expected one alert mail, got %v", sent)
+	}
+	if got := UserAlerts("alice"); len(got) != 1 || got[0].Active() || got[0].Hit != 66000 {
+		t.Errorf("alert state after trigger: %+v", got)
+	}
+}
@@ -0,0 +1,12 @@
+package markets
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/markets", Handler)
+	r.HandleFunc("/markets/alerts", AlertsHandler)
+}
@@ -0,0 +1,122 @@
+package markets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/egress"
+)
+
+// yahooFormat is the version of the Yahoo Finance chart response
+// parseYahooChart understands.
+const yahooFormat = "v8 chart"
+
+// yahooQuote is the latest price of a symbol and its change since the
+// previous close, in percent.
+type yahooQuote struct {
+	Symbol        string
+	Price         float64
+	ChangePercent float64
+}
+
+// fetchYahooInto fetches the quote for symbol and stores it under key.
+// Failures are logged and leave key out.
+func fetchYahooInto(key, symbol string, prices map[string]float64, priceData map[string]PriceData) {
+	q, err := fetchYahooQuote(symbol)
+	if err != nil {
+		app.Log("markets", "Failed to get %s (%s): %v", key, symbol, err)
+		return
+	}
+	prices[key] = q.Price
+	priceData[key] = PriceData{
+		Price:     q.Price,
+		Change24h: q.ChangePercent,
+		UpdatedAt: time.Now().UTC(),
+		Source:    "Yahoo Finance",
+	}
+}
+
+// fetchYahooQuote gets the latest quote for a Yahoo Finance symbol, such as
+// "CL=F" or "EURUSD=X", from the chart API.
+func fetchYahooQuote(symbol string) (*yahooQuote, error) {
+	u := "https://query1.finance.yahoo.com/v8/finance/chart/" + url.PathEscape(symbol) + "?range=1d&interval=1d"
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Yahoo rate limits requests without a browser-like user agent.
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Mu/1.0)")
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != http.StatusOK && rsp.StatusCode != http.StatusNotFound {
+		// A 404 carries a chart error, which parseYahooChart reports.
+		return nil, fmt.Errorf("yahoo returned status %d", rsp.StatusCode)
+	}
+
+	q, err := parseYahooChart(b)
+	return q, egress.CheckFormat("yahoo", yahooFormat, err)
+}
+
+// parseYahooChart reads the latest price and change from a v8 chart
+// response. An error Yahoo reports for the symbol is returned as is; a
+// response in any other shape is drift.
+func parseYahooChart(body []byte) (*yahooQuote, error) {
+	var res struct {
+		Chart *struct {
+			Result []struct {
+				Meta *struct {
+					Symbol             string   `json:"symbol"`
+					RegularMarketPrice *float64 `json:"regularMarketPrice"`
+					PreviousClose      float64  `json:"previousClose"`
+					ChartPreviousClose float64  `json:"chartPreviousClose"`
+				} `json:"meta"`
+			} `json:"result"`
+			Error *struct {
+				Code        string `json:"code"`
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"chart"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, egress.Drift("yahoo", "%v", err)
+	}
+	if res.Chart == nil {
+		return nil, egress.Drift("yahoo", "no chart object")
+	}
+	if e := res.Chart.Error; e != nil {
+		return nil, fmt.Errorf("yahoo: %s: %s", e.Code, e.Description)
+	}
+	if len(res.Chart.Result) == 0 || res.Chart.Result[0].Meta == nil {
+		return nil, egress.Drift("yahoo", "chart has no result")
+	}
+	meta := res.Chart.Result[0].Meta
+	if meta.RegularMarketPrice == nil {
+		return nil, egress.Drift("yahoo", "no regularMarketPrice for %s", meta.Symbol)
+	}
+	if *meta.RegularMarketPrice <= 0 {
+		return nil, egress.Drift("yahoo", "price for %s is %v", meta.Symbol, *meta.RegularMarketPrice)
+	}
+
+	q := &yahooQuote{Symbol: meta.Symbol, Price: *meta.RegularMarketPrice}
+	prev := meta.PreviousClose
+	if prev == 0 {
+		prev = meta.ChartPreviousClose
+	}
+	if prev > 0 {
+		q.ChangePercent = (q.Price - prev) / prev * 100
+	}
+	return q, nil
+}
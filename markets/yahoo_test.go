@@ -0,0 +1,63 @@
+package markets
+
+import (
+	"math"
+	"testing"
+
+	"mu/internal/egress"
+	"mu/internal/testutil"
+)
+
+func TestParseYahooChart_Fixtures(t *testing.T) {
+	tests := []struct {
+		file   string
+		symbol string
+		price  float64
+		change float64
+	}{
+		// Futures have a previous close; the change is from it.
+		{"yahoo_chart_future.json", "CL=F", 58.27, (58.27 - 58.7) / 58.7 * 100},
+		// Currencies only have the chart's previous close.
+		{"yahoo_chart_forex.json", "EURUSD=X", 1.1642, (1.1642 - 1.1607) / 1.1607 * 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			q, err := parseYahooChart(testutil.Fixture(t, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Symbol != tt.symbol || q.Price != tt.price || math.Abs(q.ChangePercent-tt.change) > 1e-9 {
+				t.Errorf("got %+v, want %s at %v (%.4f%%)", q, tt.symbol, tt.price, tt.change)
+			}
+		})
+	}
+}
+
+func TestParseYahooChart_UpstreamError(t *testing.T) {
+	_, err := parseYahooChart(testutil.Fixture(t, "yahoo_chart_not_found.json"))
+	if err == nil || egress.IsDrift(err) {
+		t.Fatalf("err = %v, want Yahoo's own error, not drift", err)
+	}
+}
+
+func TestParseYahooChart_Drift(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", `<html>Too Many Requests</html>`},
+		{"no chart", `{"finance":{"result":null}}`},
+		{"empty result", `{"chart":{"result":[],"error":null}}`},
+		{"no meta", `{"chart":{"result":[{"timestamp":[1]}],"error":null}}`},
+		{"price moved", `{"chart":{"result":[{"meta":{"symbol":"CL=F","price":{"regular":58.27}}}],"error":null}}`},
+		{"price as string", `{"chart":{"result":[{"meta":{"symbol":"CL=F","regularMarketPrice":"58.27"}}],"error":null}}`},
+		{"zero price", `{"chart":{"result":[{"meta":{"symbol":"CL=F","regularMarketPrice":0}}],"error":null}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseYahooChart([]byte(tt.body)); !egress.IsDrift(err) {
+				t.Fatalf("err = %v, want drift", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package media
+
+import "encoding/binary"
+
+// jpegOrientation returns the EXIF orientation of a JPEG (1–8), or 1 if
+// it has none. Phones store photos as the sensor saw them and record
+// which way up they go here.
+func jpegOrientation(b []byte) int {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return 1
+		}
+		marker := b[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Image data starts: metadata only comes before it.
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(b[i+2:]))
+		start, end := i+4, i+2+size
+		if size < 2 || end > len(b) {
+			return 1
+		}
+		if marker == 0xE1 && end-start > 6 && string(b[start:start+6]) == "Exif\x00\x00" {
+			return exifOrientation(b[start+6 : end])
+		}
+		i = end
+	}
+	return 1
+}
+
+// exifOrientation reads the Orientation tag from the first IFD of a TIFF
+// structure, the form EXIF takes.
+func exifOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(t[4:]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+	n := int(order.Uint16(t[ifd:]))
+	for k := 0; k < n; k++ {
+		e := ifd + 2 + k*12
+		if e+12 > len(t) {
+			return 1
+		}
+		if order.Uint16(t[e:]) == 0x0112 {
+			if o := int(order.Uint16(t[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,394 @@
+// Package media stores images members upload for their blog posts. Each
+// upload is checked, scaled down to MaxWidth and re-encoded, which also
+// drops camera metadata such as location, then served from /media/<id>.
+package media
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	_ "image/gif"
+
+	"mu/internal/app"
+	"mu/internal/data"
+)
+
+// Upload is a stored image. The content lives under media/<id>; the
+// metadata for every upload is kept in media.json.
+type Upload struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// URL is where the image is served.
+func (u *Upload) URL() string {
+	return "/media/" + u.ID
+}
+
+// Markdown is the image as it goes into a post.
+func (u *Upload) Markdown() string {
+	alt := strings.TrimSuffix(u.Filename, filepath.Ext(u.Filename))
+	alt = strings.NewReplacer("[", "", "]", "").Replace(alt)
+	return fmt.Sprintf("![%s](%s)", alt, u.URL())
+}
+
+// Upload limits.
+const (
+	MaxUploadSize    = 10 << 20  // per file, as uploaded
+	MaxWidth         = 1600      // wider images are scaled down to this
+	MaxUploadsPerDay = 50        // per account
+	MaxStorage       = 200 << 20 // per account, after resizing
+
+	// maxPixels bounds what will be decoded, so a small file that
+	// claims to be a huge image can't exhaust memory.
+	maxPixels = 30_000_000
+)
+
+var (
+	ErrTooLarge   = fmt.Errorf("images are limited to %dMB", MaxUploadSize>>20)
+	ErrType       = errors.New("only JPEG, PNG and GIF images can be uploaded")
+	ErrDimensions = errors.New("that image has too many pixels to process")
+	ErrDailyLimit = fmt.Errorf("you can upload %d images a day", MaxUploadsPerDay)
+	ErrQuota      = fmt.Errorf("you've used your %dMB of image storage", MaxStorage>>20)
+	ErrNotFound   = errors.New("image not found")
+)
+
+var (
+	mu      sync.RWMutex
+	uploads = map[string]*Upload{} // id → upload
+)
+
+// Load restores the upload metadata.
+func Load() {
+	data.RegisterTable("media.json")
+	mu.Lock()
+	data.LoadJSON("media.json", &uploads)
+	if uploads == nil {
+		uploads = map[string]*Upload{}
+	}
+	mu.Unlock()
+}
+
+// save persists the metadata. Caller must hold mu.
+func save() {
+	data.SaveJSON("media.json", uploads)
+}
+
+// contentKey is the data store key for an upload's content.
+func contentKey(id string) string {
+	return filepath.Join("media", id)
+}
+
+// cleanFilename reduces an uploaded filename to something safe to store
+// and use as alt text.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`"<>`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." {
+		name = "image"
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return name
+}
+
+// Save validates, resizes and stores an image uploaded by owner.
+func Save(owner, filename string, content []byte) (*Upload, error) {
+	if len(content) > MaxUploadSize {
+		return nil, ErrTooLarge
+	}
+	switch http.DetectContentType(content) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrType
+	}
+	if err := checkQuota(owner, 0); err != nil {
+		return nil, err
+	}
+
+	out, contentType, w, h, err := process(content)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	u := &Upload{
+		ID:          hex.EncodeToString(b),
+		Owner:       owner,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        len(out),
+		Width:       w,
+		Height:      h,
+		CreatedAt:   time.Now(),
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Checked again now the final size is known; uploads in parallel
+	// may also have landed since.
+	if err := checkQuotaLocked(owner, u.Size); err != nil {
+		return nil, err
+	}
+	if err := data.SaveFile(contentKey(u.ID), string(out)); err != nil {
+		return nil, err
+	}
+	uploads[u.ID] = u
+	save()
+	app.Log("media", "%s uploaded %s (%dx%d, %d bytes)", owner, u.ID, w, h, u.Size)
+	return u, nil
+}
+
+// checkQuota reports whether owner may store another size bytes.
+func checkQuota(owner string, size int) error {
+	mu.RLock()
+	defer mu.RUnlock()
+	return checkQuotaLocked(owner, size)
+}
+
+// checkQuotaLocked is checkQuota for callers holding mu.
+func checkQuotaLocked(owner string, size int) error {
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	today, total := 0, size
+	for _, u := range uploads {
+		if u.Owner != owner {
+			continue
+		}
+		total += u.Size
+		if u.CreatedAt.After(dayAgo) {
+			today++
+		}
+	}
+	if today >= MaxUploadsPerDay {
+		return ErrDailyLimit
+	}
+	if total > MaxStorage {
+		return ErrQuota
+	}
+	return nil
+}
+
+// process decodes an image, turns it upright, scales it to fit MaxWidth
+// and re-encodes it in its own format. GIFs are kept as they are so
+// animations survive; they carry no camera metadata.
+func process(content []byte) ([]byte, string, int, int, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", 0, 0, ErrType
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", 0, 0, ErrDimensions
+	}
+	if format == "gif" {
+		return content, "image/gif", cfg.Width, cfg.Height, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", 0, 0, ErrType
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(content)
+	}
+
+	// Scale first, on the stored image, so the rotation has less to do.
+	// A photo taken sideways is as wide as it is stored tall.
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	shown := w
+	if orientation == 6 || orientation == 8 {
+		shown = h
+	}
+	if shown > MaxWidth {
+		img = scale(img, max(1, w*MaxWidth/shown), max(1, h*MaxWidth/shown))
+	}
+	img = orient(img, orientation)
+
+	var buf bytes.Buffer
+	contentType := "image/" + format
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		return nil, "", 0, 0, ErrType
+	}
+	if err != nil {
+		return nil, "", 0, 0, err
+	}
+	b = img.Bounds()
+	return buf.Bytes(), contentType, b.Dx(), b.Dy(), nil
+}
+
+// scale resizes img to w×h by averaging the source pixels that fall in
+// each destination pixel. It only shrinks.
+func scale(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*sh/h, b.Min.Y+(y+1)*sh/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*sw/w, b.Min.X+(x+1)*sw/w
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// orient turns an image upright according to its EXIF orientation. Only
+// the rotations cameras produce (3, 6 and 8) are handled; mirrored
+// orientations are left as they are.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation != 3 && orientation != 6 && orientation != 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var dst *image.RGBA
+	if orientation == 3 {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+			switch orientation {
+			case 3: // upside down
+				dst.Set(w-1-x, h-1-y, c)
+			case 6: // needs a quarter turn clockwise
+				dst.Set(h-1-y, x, c)
+			case 8: // needs a quarter turn anticlockwise
+				dst.Set(y, w-1-x, c)
+			}
+		}
+	}
+	return dst
+}
+
+// Get returns an upload's metadata, or nil.
+func Get(id string) *Upload {
+	mu.RLock()
+	defer mu.RUnlock()
+	return uploads[id]
+}
+
+// Content returns an upload's metadata and stored bytes.
+func Content(id string) (*Upload, []byte, error) {
+	u := Get(id)
+	if u == nil {
+		return nil, nil, ErrNotFound
+	}
+	b, err := data.LoadFile(contentKey(id))
+	if err != nil {
+		return nil, nil, ErrNotFound
+	}
+	return u, b, nil
+}
+
+// ByOwner returns an account's uploads, newest first.
+func ByOwner(owner string) []*Upload {
+	mu.RLock()
+	defer mu.RUnlock()
+	var list []*Upload
+	for _, u := range uploads {
+		if u.Owner == owner {
+			list = append(list, u)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Delete removes an upload. Posts that use it show a broken image, so it
+// is for the owner tidying up, or moderation.
+func Delete(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := uploads[id]; !ok {
+		return ErrNotFound
+	}
+	if err := data.DeleteFile(contentKey(id)); err != nil {
+		app.Log("media", "Failed to delete %s: %v", id, err)
+	}
+	delete(uploads, id)
+	save()
+	return nil
+}
+
+// DeleteByOwner removes every upload an account made (account deletion).
+func DeleteByOwner(owner string) {
+	mu.Lock()
+	defer mu.Unlock()
+	removed := 0
+	for id, u := range uploads {
+		if u.Owner != owner {
+			continue
+		}
+		if err := data.DeleteFile(contentKey(id)); err != nil {
+			app.Log("media", "Failed to delete %s: %v", id, err)
+		}
+		delete(uploads, id)
+		removed++
+	}
+	if removed > 0 {
+		save()
+	}
+}
+
+// RenameOwner moves an account's uploads to its new ID (username change).
+// The URLs don't change, so posts that use them are unaffected.
+func RenameOwner(oldID, newID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := false
+	for _, u := range uploads {
+		if u.Owner == oldID {
+			u.Owner = newID
+			changed = true
+		}
+	}
+	if changed {
+		save()
+	}
+}
@@ -0,0 +1,127 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func resetForTest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mu.Lock()
+	uploads = map[string]*Upload{}
+	mu.Unlock()
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{255, 0, 0, 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation splices an EXIF segment carrying the given orientation
+// in after a JPEG's start-of-image marker.
+func withOrientation(jpg []byte, o uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00\x01\x00")
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], o)
+	payload := append(append([]byte("Exif\x00\x00"), tiff...), entry...)
+	payload = append(payload, 0, 0, 0, 0)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, seg...)
+	out = append(out, payload...)
+	return append(out, jpg[2:]...)
+}
+
+func TestSaveResizesAndServes(t *testing.T) {
+	resetForTest(t)
+
+	if _, err := Save("alice", "notes.txt", []byte("just some text, not an image")); err != ErrType {
+		t.Fatalf("text upload: %v", err)
+	}
+
+	u, err := Save("alice", "wide.png", encodePNG(t, MaxWidth*2, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Width != MaxWidth || u.Height != 50 || u.ContentType != "image/png" {
+		t.Fatalf("stored %+v", u)
+	}
+	if u.Markdown() != "![wide](/media/"+u.ID+")" {
+		t.Errorf("markdown = %q", u.Markdown())
+	}
+	_, content, err := Content(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(content)); err != nil || cfg.Width != MaxWidth {
+		t.Fatalf("stored image %+v, %v", cfg, err)
+	}
+
+	RenameOwner("alice", "alicia")
+	if got := ByOwner("alicia"); len(got) != 1 {
+		t.Fatalf("after rename: %v", got)
+	}
+	DeleteByOwner("alicia")
+	if Get(u.ID) != nil {
+		t.Fatal("upload survived account deletion")
+	}
+}
+
+func TestSaveTurnsPhotosUpright(t *testing.T) {
+	resetForTest(t)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	jpg := withOrientation(buf.Bytes(), 6)
+	if o := jpegOrientation(jpg); o != 6 {
+		t.Fatalf("orientation = %d", o)
+	}
+
+	u, err := Save("alice", "photo.jpg", jpg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Width != 20 || u.Height != 40 {
+		t.Fatalf("sideways photo stored %dx%d", u.Width, u.Height)
+	}
+	_, content, _ := Content(u.ID)
+	if jpegOrientation(content) != 1 {
+		t.Error("EXIF kept in the stored image")
+	}
+}
+
+func TestDailyLimit(t *testing.T) {
+	resetForTest(t)
+	mu.Lock()
+	for i := 0; i < MaxUploadsPerDay; i++ {
+		id := fmt.Sprintf("u%d", i)
+		uploads[id] = &Upload{ID: id, Owner: "alice", CreatedAt: time.Now()}
+	}
+	mu.Unlock()
+	if _, err := Save("alice", "one.png", encodePNG(t, 10, 10)); err != ErrDailyLimit {
+		t.Fatalf("over the daily limit: %v", err)
+	}
+	if _, err := Save("bob", "one.png", encodePNG(t, 10, 10)); err != nil {
+		t.Fatalf("someone else's limit applied: %v", err)
+	}
+}
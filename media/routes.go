@@ -0,0 +1,12 @@
+package media
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/upload", UploadHandler, app.Authenticated) // images for blog posts
+	r.HandleFunc("/media/", Handler)                          // uploads are public
+}
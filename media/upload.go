@@ -0,0 +1,122 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// UploadHandler serves POST /upload: one image in the "file" field of a
+// multipart form. JSON callers get the upload with its URL and markdown;
+// a plain form gets a page with the markdown to copy.
+func UploadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	// Leave room for the multipart framing around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize+1<<20)
+	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
+		app.Error(w, r, http.StatusRequestEntityTooLarge, ErrTooLarge.Error())
+		return
+	}
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		app.BadRequest(w, r, "Choose an image to upload")
+		return
+	}
+	content, err := io.ReadAll(io.LimitReader(f, MaxUploadSize+1))
+	f.Close()
+	if err != nil {
+		app.BadRequest(w, r, "Failed to read the upload")
+		return
+	}
+
+	u, err := Save(acc.ID, fh.Filename, content)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrDailyLimit) || errors.Is(err, ErrQuota) {
+			status = http.StatusTooManyRequests
+		}
+		app.Error(w, r, status, err.Error())
+		return
+	}
+
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]any{
+			"id":       u.ID,
+			"url":      u.URL(),
+			"markdown": u.Markdown(),
+			"width":    u.Width,
+			"height":   u.Height,
+		})
+		return
+	}
+	body := fmt.Sprintf(`<p><img src="%s" alt="" style="max-width:100%%"></p>
+<p>Paste this into your post:</p>
+<input type="text" readonly value="%s" onclick="this.select()">
+<p><a href="/blog?write=true">Write a post →</a></p>`, u.URL(), html.EscapeString(u.Markdown()))
+	app.Respond(w, r, app.Response{Title: "Image uploaded", HTML: body})
+}
+
+// Handler serves /media/<id>: the image to anyone, and DELETE (or POST
+// with action=delete) to its owner or an admin.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/media/")
+	if id == "" || strings.Contains(id, "/") {
+		app.NotFound(w, r, "Image not found")
+		return
+	}
+
+	if r.Method == "DELETE" || (r.Method == "POST" && r.FormValue("action") == "delete") {
+		_, acc, err := auth.RequireSession(r)
+		if err != nil {
+			app.Unauthorized(w, r)
+			return
+		}
+		u := Get(id)
+		if u == nil {
+			app.NotFound(w, r, "Image not found")
+			return
+		}
+		if u.Owner != acc.ID && !acc.Admin {
+			app.Forbidden(w, r, "You can only delete your own images")
+			return
+		}
+		Delete(id)
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]any{"deleted": id})
+			return
+		}
+		http.Redirect(w, r, "/blog", http.StatusSeeOther)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	u, content, err := Content(id)
+	if err != nil {
+		app.NotFound(w, r, "Image not found")
+		return
+	}
+	// The content behind an ID never changes.
+	w.Header().Set("Content-Type", u.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Write(content)
+}
@@ -59,6 +59,12 @@ func Load() {
 	}
 
 	go scheduler()
+	// once the blog callbacks and feeds are wired
+	app.Schedule(app.Job{Name: "digest.weekly", Every: weeklyCheckInterval, Delay: 30 * time.Second, Run: func() error {
+		compileDueWeekly(time.Now())
+		sendDueMailDigests(time.Now())
+		return nil
+	}})
 }
 
 // Status returns the current digest state for the status page.
@@ -293,6 +299,17 @@ Rules:
 	return cleanResponse(draft), nil
 }
 
+// marketAssets are the symbols the digests report on, by category.
+var marketAssets = []struct {
+	name   string
+	assets []string
+}{
+	{"Crypto", []string{"BTC", "ETH", "SOL", "PAXG"}},
+	{"Futures", []string{"OIL", "GOLD", "SILVER", "COPPER"}},
+	{"Commodities", []string{"COFFEE", "WHEAT", "CORN"}},
+	{"Currencies", []string{"EUR", "GBP", "JPY", "CNY"}},
+}
+
 type ref struct {
 	title string
 	url   string
@@ -326,16 +343,7 @@ func gatherContext() (string, []ref) {
 	priceData := markets.GetAllPriceData()
 	if len(priceData) > 0 {
 		sb.WriteString("## Market Data\n\n")
-		categories := []struct {
-			name   string
-			assets []string
-		}{
-			{"Crypto", []string{"BTC", "ETH", "SOL", "PAXG"}},
-			{"Futures", []string{"OIL", "GOLD", "SILVER", "COPPER"}},
-			{"Commodities", []string{"COFFEE", "WHEAT", "CORN"}},
-			{"Currencies", []string{"EUR", "GBP", "JPY", "CNY"}},
-		}
-		for _, cat := range categories {
+		for _, cat := range marketAssets {
 			for _, symbol := range cat.assets {
 				if pd, ok := priceData[symbol]; ok && pd.Price > 0 {
 					change := ""
@@ -0,0 +1,184 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/mail"
+	"mu/news"
+)
+
+// The mail digest is the weekly digest as a personal letter: the week's
+// top headlines and new community posts, plus how much unread mail is
+// waiting. Members opt in on /account and it lands in their Mu inbox
+// early on Monday, alongside the weekly draft; those who also ask for it
+// by email get a copy at their verified address. Unlike the published
+// issue it needs no review, and an admin can send it at any time from
+// /admin/weekly.
+
+const (
+	mailDigestKey    = "mail_digests.json"
+	mailDigestPosts  = 5
+	mailDigestWindow = 48 * time.Hour // how long after the compile hour a missed send is caught up
+)
+
+// MailDigest is one member's digest for a week.
+type MailDigest struct {
+	Week   string          `json:"week"`
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	News   []WeeklySection `json:"news"`
+	Posts  []WeeklyItem    `json:"posts"`
+	Unread int             `json:"unread"`
+}
+
+var (
+	mailDigestMu   sync.Mutex
+	mailDigestSent = map[string]string{} // user ID → week last sent
+)
+
+func init() {
+	data.LoadJSON(mailDigestKey, &mailDigestSent)
+	if mailDigestSent == nil {
+		mailDigestSent = map[string]string{}
+	}
+}
+
+// MailDigestSubscribers returns the members who get the digest.
+func MailDigestSubscribers() []*auth.Account {
+	var out []*auth.Account
+	for _, acc := range auth.GetAllAccounts() {
+		if acc.WeeklyDigest && !acc.Banned {
+			out = append(out, acc)
+		}
+	}
+	return out
+}
+
+// compileMailDigest gathers a member's digest for the week starting from.
+func compileMailDigest(userID string, from time.Time) *MailDigest {
+	to := from.AddDate(0, 0, 7)
+	d := &MailDigest{
+		Week:   weekID(from),
+		From:   from,
+		To:     to,
+		News:   weeklyNews(news.GetFeed(), from, to),
+		Unread: mail.GetUnreadCount(userID),
+	}
+	if WeeklyPosts != nil {
+		d.Posts = WeeklyPosts(from, to)
+		if len(d.Posts) > mailDigestPosts {
+			d.Posts = d.Posts[:mailDigestPosts]
+		}
+	}
+	return d
+}
+
+// absURL makes a site link absolute so it works outside Mu.
+func absURL(u string) string {
+	if strings.HasPrefix(u, "/") {
+		return app.PublicURL() + u
+	}
+	return u
+}
+
+// mailDigestMarkdown renders a digest as the body of a mail.
+func mailDigestMarkdown(d *MailDigest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Your week on Mu, %s – %s.\n\n", d.From.Format("2 Jan"), d.To.AddDate(0, 0, -1).Format("2 Jan 2006"))
+
+	switch d.Unread {
+	case 0:
+		sb.WriteString("No unread mail.\n\n")
+	case 1:
+		fmt.Fprintf(&sb, "You have [1 unread message](%s).\n\n", absURL("/mail"))
+	default:
+		fmt.Fprintf(&sb, "You have [%d unread messages](%s).\n\n", d.Unread, absURL("/mail"))
+	}
+
+	if len(d.Posts) > 0 {
+		sb.WriteString("### New on the blog\n\n")
+		for _, p := range d.Posts {
+			fmt.Fprintf(&sb, "- [%s](%s)", itemTitle(p), absURL(p.URL))
+			if p.Author != "" {
+				fmt.Fprintf(&sb, " by %s", mdText(p.Author))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.News) > 0 {
+		sb.WriteString("### Top headlines\n\n")
+		for _, s := range d.News {
+			for _, item := range s.Items {
+				fmt.Fprintf(&sb, "- [%s](%s) · %s\n", itemTitle(item), absURL(item.URL), mdText(s.Category))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "You get this because you turned on the weekly digest. Turn it off at [your account](%s).", absURL("/account"))
+	return sb.String()
+}
+
+// sendMailDigest delivers a digest to the member's inbox and, if they
+// asked for it, their verified email.
+func sendMailDigest(acc *auth.Account, d *MailDigest) error {
+	subject := "Your weekly digest: " + d.From.Format("2 Jan")
+	body := mailDigestMarkdown(d)
+	if err := mail.SendMessage("Weekly Digest", "digest", acc.Name, acc.ID, subject, body, "", ""); err != nil {
+		return err
+	}
+	if acc.WeeklyDigestEmail && acc.EmailVerified && acc.Email != "" && app.EmailSender != nil {
+		if err := app.EmailSender(acc.Email, subject, body, app.RenderString(body)); err != nil {
+			app.Log("digest", "Failed to email weekly digest to %s: %v", acc.ID, err)
+		}
+	}
+	return nil
+}
+
+// SendMailDigests sends the last full week's digest to every subscriber.
+// Members who already have this week's are skipped unless force is set,
+// as when an admin sends it by hand. It returns how many were sent.
+func SendMailDigests(now time.Time, force bool) int {
+	from := weekStart(now).AddDate(0, 0, -7)
+	week := weekID(from)
+	sent := 0
+	for _, acc := range MailDigestSubscribers() {
+		mailDigestMu.Lock()
+		done := mailDigestSent[acc.ID] == week
+		mailDigestMu.Unlock()
+		if done && !force {
+			continue
+		}
+		if err := sendMailDigest(acc, compileMailDigest(acc.ID, from)); err != nil {
+			app.Log("digest", "Weekly digest for %s failed: %v", acc.ID, err)
+			continue
+		}
+		mailDigestMu.Lock()
+		mailDigestSent[acc.ID] = week
+		data.SaveJSON(mailDigestKey, mailDigestSent)
+		mailDigestMu.Unlock()
+		sent++
+	}
+	if sent > 0 {
+		app.Log("digest", "Weekly digest %s mailed to %d members", week, sent)
+	}
+	return sent
+}
+
+// sendDueMailDigests mails the digest once the week has turned over,
+// catching up for a while if the server was down at the time.
+func sendDueMailDigests(now time.Time) {
+	due := weekStart(now).Add(weeklyCompileHour * time.Hour)
+	if now.Before(due) || now.Sub(due) > mailDigestWindow {
+		return
+	}
+	SendMailDigests(now, false)
+}
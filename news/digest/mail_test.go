@@ -0,0 +1,55 @@
+package digest
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+	"mu/mail"
+)
+
+func TestMailDigestMarkdown(t *testing.T) {
+	from := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	d := &MailDigest{
+		From:   from,
+		To:     from.AddDate(0, 0, 7),
+		News:   []WeeklySection{{Category: "Tech", Items: []WeeklyItem{{Title: "Chips *again*", URL: "https://example.com/a"}}}},
+		Posts:  []WeeklyItem{{Title: "Hello", URL: "/blog/post?id=1", Author: "alice"}},
+		Unread: 3,
+	}
+	md := mailDigestMarkdown(d)
+	for _, want := range []string{"12 Oct – 18 Oct 2026", "[3 unread messages](", "/mail)", "/blog/post?id=1) by alice", `Chips \*again\*`, "· Tech", "/account)"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestSendMailDigestsOncePerWeek(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	mailDigestMu.Lock()
+	mailDigestSent = map[string]string{}
+	mailDigestMu.Unlock()
+
+	acc := &auth.Account{ID: "digestreader", Name: "Reader", Secret: "secret", Created: time.Now(), WeeklyDigest: true}
+	if err := auth.Create(acc); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount(acc.ID)
+
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	if n := SendMailDigests(now, false); n != 1 {
+		t.Fatalf("first send = %d", n)
+	}
+	if mail.GetUnreadCount(acc.ID) != 1 {
+		t.Errorf("digest not in the inbox")
+	}
+	if n := SendMailDigests(now, false); n != 0 {
+		t.Errorf("sent twice in one week: %d", n)
+	}
+	if n := SendMailDigests(now, true); n != 1 {
+		t.Errorf("forced send = %d", n)
+	}
+}
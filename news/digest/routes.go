@@ -0,0 +1,11 @@
+package digest
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/weekly", WeeklyHandler)
+}
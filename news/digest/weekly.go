@@ -0,0 +1,554 @@
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/markets"
+	"mu/news"
+	"mu/user"
+)
+
+// The weekly digest (/weekly) looks back over a Monday–Sunday week (UTC):
+// new community posts, the busiest discussions, new members who opted in,
+// a few headlines per news category and the week's market moves. Early on
+// Monday the scheduler compiles the week just gone as a draft. Nothing is
+// public until an admin has reviewed it at /admin/weekly, optionally added
+// an introduction, and published it — at which point it can also be posted
+// to the blog, which carries it to RSS and ActivityPub followers.
+
+const (
+	weeklyKey           = "weekly.json"
+	weeklyPosts         = 10
+	weeklyDiscussions   = 5
+	weeklyPerCategory   = 2
+	weeklyMovers        = 5
+	weeklyCompileHour   = 6 // Monday, UTC, as the daily digest
+	weeklyCheckInterval = time.Hour
+)
+
+// Weekly issue states.
+const (
+	WeeklyDraft     = "draft"
+	WeeklyPublished = "published"
+	WeeklyDiscarded = "discarded" // kept so the scheduler doesn't recompile it
+)
+
+// WeeklyItem is a linked entry in a weekly issue.
+type WeeklyItem struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Author   string `json:"author,omitempty"`
+	Comments int    `json:"comments,omitempty"` // discussions only
+}
+
+// WeeklySection is a news category's headlines for the week.
+type WeeklySection struct {
+	Category string       `json:"category"`
+	Items    []WeeklyItem `json:"items"`
+}
+
+// WeeklyMove is an asset's price change over the week.
+type WeeklyMove struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price"`
+	Change float64 `json:"change"` // percent
+	Period string  `json:"period"` // "week", or "24h" when there is no earlier issue to compare with
+}
+
+// WeeklyMember is a member who joined during the week.
+type WeeklyMember struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Weekly is one issue of the weekly digest.
+type Weekly struct {
+	ID          string             `json:"id"` // ISO week, e.g. 2026-W41
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Status      string             `json:"status"`
+	Intro       string             `json:"intro,omitempty"` // markdown, written at review
+	Posts       []WeeklyItem       `json:"posts"`
+	Discussions []WeeklyItem       `json:"discussions"`
+	Members     []WeeklyMember     `json:"members,omitempty"`
+	News        []WeeklySection    `json:"news"`
+	Markets     []WeeklyMove       `json:"markets"`
+	Prices      map[string]float64 `json:"prices,omitempty"` // baseline for next week's moves
+	CompiledAt  time.Time          `json:"compiled_at"`
+	PublishedAt time.Time          `json:"published_at,omitempty"`
+	PublishedBy string             `json:"published_by,omitempty"`
+}
+
+// Title is the issue's heading, e.g. "Week of 5 – 11 Oct 2026".
+func (w *Weekly) Title() string {
+	last := w.To.AddDate(0, 0, -1)
+	if w.From.Month() == last.Month() {
+		return fmt.Sprintf("Week of %d – %s", w.From.Day(), last.Format("2 Jan 2006"))
+	}
+	return fmt.Sprintf("Week of %s – %s", w.From.Format("2 Jan"), last.Format("2 Jan 2006"))
+}
+
+// WeeklyPosts returns the public member posts created in [from, to),
+// newest first. Wired in main.go.
+var WeeklyPosts func(from, to time.Time) []WeeklyItem
+
+// WeeklyDiscussions returns up to n posts with the most comments made in
+// [from, to), busiest first. Wired in main.go.
+var WeeklyDiscussions func(from, to time.Time, n int) []WeeklyItem
+
+var (
+	weeklyMu sync.RWMutex
+	weeklies []*Weekly // newest first
+)
+
+func init() {
+	b, _ := data.LoadFile(weeklyKey)
+	json.Unmarshal(b, &weeklies)
+}
+
+// weekStart returns midnight UTC on the Monday of t's week.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+// weekID returns the ISO week of from, e.g. 2026-W41.
+func weekID(from time.Time) string {
+	y, w := from.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", y, w)
+}
+
+// saveWeeklies persists all issues (caller must hold weeklyMu).
+func saveWeeklies() error {
+	return data.SaveJSON(weeklyKey, weeklies)
+}
+
+// findWeekly returns the issue with the given ID (caller must hold weeklyMu).
+func findWeekly(id string) *Weekly {
+	for _, w := range weeklies {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+// Weeklies returns every issue, including drafts, newest first.
+func Weeklies() []*Weekly {
+	weeklyMu.RLock()
+	defer weeklyMu.RUnlock()
+	return append([]*Weekly(nil), weeklies...)
+}
+
+// GetWeekly returns the issue with the given ID, or nil.
+func GetWeekly(id string) *Weekly {
+	weeklyMu.RLock()
+	defer weeklyMu.RUnlock()
+	return findWeekly(id)
+}
+
+// LatestWeekly returns the most recent published issue, or nil.
+func LatestWeekly() *Weekly {
+	weeklyMu.RLock()
+	defer weeklyMu.RUnlock()
+	for _, w := range weeklies {
+		if w.Status == WeeklyPublished {
+			return w
+		}
+	}
+	return nil
+}
+
+// compileDueWeekly drafts the issue for the week before now once that
+// week is over, unless it already exists.
+func compileDueWeekly(now time.Time) *Weekly {
+	to := weekStart(now)
+	if now.Before(to.Add(weeklyCompileHour * time.Hour)) {
+		return nil
+	}
+	from := to.AddDate(0, 0, -7)
+	if GetWeekly(weekID(from)) != nil {
+		return nil
+	}
+	w, err := compileWeekly(from)
+	if err != nil {
+		app.Log("digest", "Weekly digest compile failed: %v", err)
+		return nil
+	}
+	app.Log("digest", "Weekly digest %s drafted for review", w.ID)
+	return w
+}
+
+// CompileWeekly compiles, or recompiles, a draft issue. An empty id means
+// the last full week. Published issues can't be recompiled; a discarded
+// one comes back as a draft.
+func CompileWeekly(id string) (*Weekly, error) {
+	from := weekStart(time.Now()).AddDate(0, 0, -7)
+	if id != "" {
+		w := GetWeekly(id)
+		if w == nil {
+			return nil, fmt.Errorf("no weekly issue %s", id)
+		}
+		from = w.From
+	}
+	return compileWeekly(from)
+}
+
+func compileWeekly(from time.Time) (*Weekly, error) {
+	to := from.AddDate(0, 0, 7)
+	id := weekID(from)
+
+	weeklyMu.RLock()
+	existing := findWeekly(id)
+	var baseline map[string]float64
+	for _, w := range weeklies {
+		if w.From.Before(from) && w.Status != WeeklyDiscarded && len(w.Prices) > 0 {
+			baseline = w.Prices
+			break
+		}
+	}
+	weeklyMu.RUnlock()
+	if existing != nil && existing.Status == WeeklyPublished {
+		return nil, fmt.Errorf("weekly issue %s is already published", id)
+	}
+
+	w := &Weekly{
+		ID:         id,
+		From:       from,
+		To:         to,
+		Status:     WeeklyDraft,
+		Members:    newMembers(from, to),
+		News:       weeklyNews(news.GetFeed(), from, to),
+		CompiledAt: time.Now(),
+	}
+	if existing != nil {
+		w.Intro = existing.Intro
+	}
+	if WeeklyPosts != nil {
+		w.Posts = WeeklyPosts(from, to)
+		if len(w.Posts) > weeklyPosts {
+			w.Posts = w.Posts[:weeklyPosts]
+		}
+	}
+	if WeeklyDiscussions != nil {
+		w.Discussions = WeeklyDiscussions(from, to, weeklyDiscussions)
+	}
+	w.Markets, w.Prices = weeklyMoves(markets.GetAllPriceData(), baseline)
+
+	weeklyMu.Lock()
+	defer weeklyMu.Unlock()
+	replaced := false
+	for i, old := range weeklies {
+		if old.ID == id {
+			weeklies[i] = w
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		weeklies = append(weeklies, w)
+		sort.Slice(weeklies, func(i, j int) bool {
+			return weeklies[i].From.After(weeklies[j].From)
+		})
+	}
+	return w, saveWeeklies()
+}
+
+// newMembers lists accounts created in [from, to) that haven't kept their
+// welcome private. Whether a given reader sees them is decided when the
+// issue is shown, so opting out later still takes effect.
+func newMembers(from, to time.Time) []WeeklyMember {
+	var members []WeeklyMember
+	for _, acc := range auth.GetAllAccounts() {
+		if acc.Created.Before(from) || !acc.Created.Before(to) || acc.Banned {
+			continue
+		}
+		if user.GetVisibility(acc.ID, user.FieldWelcome) == user.Private {
+			continue
+		}
+		members = append(members, WeeklyMember{ID: acc.ID, Name: acc.Name})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
+// weeklyNews picks up to weeklyPerCategory headlines per category from
+// the items posted in [from, to).
+func weeklyNews(feed []*news.Post, from, to time.Time) []WeeklySection {
+	byCategory := map[string][]WeeklyItem{}
+	for _, p := range feed {
+		if p.Category == "" || p.PostedAt.Before(from) || !p.PostedAt.Before(to) {
+			continue
+		}
+		if len(byCategory[p.Category]) < weeklyPerCategory {
+			byCategory[p.Category] = append(byCategory[p.Category], WeeklyItem{Title: p.Title, URL: p.URL})
+		}
+	}
+	sections := make([]WeeklySection, 0, len(byCategory))
+	for cat, items := range byCategory {
+		sections = append(sections, WeeklySection{Category: cat, Items: items})
+	}
+	sort.Slice(sections, func(i, j int) bool { return sections[i].Category < sections[j].Category })
+	return sections
+}
+
+// weeklyMoves returns the biggest movers against last week's closing
+// prices (or the 24h change for assets without one) and this week's
+// prices as the next baseline.
+func weeklyMoves(prices map[string]markets.PriceData, baseline map[string]float64) ([]WeeklyMove, map[string]float64) {
+	var moves []WeeklyMove
+	closing := map[string]float64{}
+	for _, cat := range marketAssets {
+		for _, symbol := range cat.assets {
+			pd, ok := prices[symbol]
+			if !ok || pd.Price <= 0 {
+				continue
+			}
+			closing[symbol] = pd.Price
+			move := WeeklyMove{Symbol: symbol, Price: pd.Price, Change: pd.Change24h, Period: "24h"}
+			if base := baseline[symbol]; base > 0 {
+				move.Change = (pd.Price - base) / base * 100
+				move.Period = "week"
+			}
+			if move.Change != 0 {
+				moves = append(moves, move)
+			}
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return math.Abs(moves[i].Change) > math.Abs(moves[j].Change)
+	})
+	if len(moves) > weeklyMovers {
+		moves = moves[:weeklyMovers]
+	}
+	return moves, closing
+}
+
+// SetWeeklyIntro sets the introduction shown at the top of an issue.
+func SetWeeklyIntro(id, intro string) error {
+	weeklyMu.Lock()
+	defer weeklyMu.Unlock()
+	w := findWeekly(id)
+	if w == nil {
+		return fmt.Errorf("no weekly issue %s", id)
+	}
+	w.Intro = strings.TrimSpace(intro)
+	return saveWeeklies()
+}
+
+// PublishWeekly makes a draft public at /weekly. With toBlog it is also
+// posted to the blog.
+func PublishWeekly(id, by string, toBlog bool) error {
+	weeklyMu.Lock()
+	w := findWeekly(id)
+	if w == nil {
+		weeklyMu.Unlock()
+		return fmt.Errorf("no weekly issue %s", id)
+	}
+	if w.Status != WeeklyDraft {
+		weeklyMu.Unlock()
+		return fmt.Errorf("weekly issue %s is %s", id, w.Status)
+	}
+	w.Status = WeeklyPublished
+	w.PublishedAt = time.Now()
+	w.PublishedBy = by
+	err := saveWeeklies()
+	weeklyMu.Unlock()
+	if err != nil {
+		return err
+	}
+	app.Log("digest", "Weekly digest %s published by %s", id, by)
+
+	if toBlog && PublishBlogPost != nil {
+		content := weeklyMarkdown(w, "") + fmt.Sprintf("\n\n[Read it on the web](/weekly?id=%s)", w.ID)
+		if _, err := PublishBlogPost("Weekly Digest — "+w.Title(), content, app.SystemUserName, app.SystemUserID, "weekly"); err != nil {
+			return fmt.Errorf("published, but posting to the blog failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// DiscardWeekly drops a draft. The scheduler won't compile that week
+// again, but an admin can.
+func DiscardWeekly(id string) error {
+	weeklyMu.Lock()
+	defer weeklyMu.Unlock()
+	w := findWeekly(id)
+	if w == nil {
+		return fmt.Errorf("no weekly issue %s", id)
+	}
+	if w.Status != WeeklyDraft {
+		return fmt.Errorf("weekly issue %s is %s", id, w.Status)
+	}
+	w.Status = WeeklyDiscarded
+	return saveWeeklies()
+}
+
+// visibleMembers filters an issue's new members down to those whose
+// welcome the viewer may see ("" is a guest).
+func visibleMembers(w *Weekly, viewerID string) []WeeklyMember {
+	var members []WeeklyMember
+	for _, m := range w.Members {
+		if user.CanView(m.ID, user.FieldWelcome, viewerID) {
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// mdText escapes text from posts and feeds for use in weekly markdown.
+func mdText(s string) string {
+	s = html.EscapeString(strings.Join(strings.Fields(s), " "))
+	return strings.NewReplacer("[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`").Replace(s)
+}
+
+// itemTitle is an item's escaped title, or "Untitled".
+func itemTitle(item WeeklyItem) string {
+	if t := mdText(item.Title); t != "" {
+		return t
+	}
+	return "Untitled"
+}
+
+// weeklyMarkdown renders an issue as markdown for the given viewer.
+func weeklyMarkdown(w *Weekly, viewerID string) string {
+	var sb strings.Builder
+	if w.Intro != "" {
+		sb.WriteString(w.Intro + "\n\n")
+	}
+
+	if len(w.Posts) > 0 {
+		sb.WriteString("### From the community\n\n")
+		for _, p := range w.Posts {
+			fmt.Fprintf(&sb, "- [%s](%s)", itemTitle(p), p.URL)
+			if p.Author != "" {
+				fmt.Fprintf(&sb, " by %s", mdText(p.Author))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(w.Discussions) > 0 {
+		sb.WriteString("### Most discussed\n\n")
+		for _, d := range w.Discussions {
+			noun := "comments"
+			if d.Comments == 1 {
+				noun = "comment"
+			}
+			fmt.Fprintf(&sb, "- [%s](%s) · %d %s\n", itemTitle(d), d.URL, d.Comments, noun)
+		}
+		sb.WriteString("\n")
+	}
+
+	if members := visibleMembers(w, viewerID); len(members) > 0 {
+		sb.WriteString("### Welcome\n\n")
+		names := make([]string, len(members))
+		for i, m := range members {
+			names[i] = fmt.Sprintf("[%s](/@%s)", mdText(m.Name), m.ID)
+		}
+		sb.WriteString(strings.Join(names, ", ") + "\n\n")
+	}
+
+	if len(w.News) > 0 {
+		sb.WriteString("### In the news\n\n")
+		for _, s := range w.News {
+			fmt.Fprintf(&sb, "**%s**\n\n", mdText(s.Category))
+			for _, item := range s.Items {
+				fmt.Fprintf(&sb, "- [%s](%s)\n", itemTitle(item), item.URL)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(w.Markets) > 0 {
+		sb.WriteString("### Markets\n\n")
+		for _, m := range w.Markets {
+			period := "this week"
+			if m.Period == "24h" {
+				period = "in 24h"
+			}
+			fmt.Fprintf(&sb, "- %s %+.1f%% %s, at %.2f USD\n", m.Symbol, m.Change, period, m.Price)
+		}
+		sb.WriteString("\n")
+	}
+
+	if sb.Len() == 0 {
+		return "A quiet week: nothing to report."
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// WeeklyHTML renders an issue for the given viewer.
+func WeeklyHTML(w *Weekly, viewerID string) string {
+	return app.RenderString(weeklyMarkdown(w, viewerID))
+}
+
+// WeeklyHandler serves /weekly: the latest published issue, or the one
+// named by ?id=, with an archive of earlier issues. Drafts are only shown
+// to admins, at /admin/weekly.
+func WeeklyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	viewerID := ""
+	if sess, err := auth.GetSession(r); err == nil {
+		viewerID = sess.Account
+	}
+
+	issue := LatestWeekly()
+	if id := r.URL.Query().Get("id"); id != "" {
+		issue = GetWeekly(id)
+		if issue == nil || issue.Status != WeeklyPublished {
+			app.NotFound(w, r, "Weekly issue not found")
+			return
+		}
+	}
+
+	if app.WantsJSON(r) {
+		if issue == nil {
+			app.RespondJSON(w, map[string]interface{}{"weekly": nil})
+			return
+		}
+		cp := *issue
+		cp.Members = visibleMembers(issue, viewerID)
+		cp.Prices = nil
+		app.RespondJSON(w, map[string]interface{}{"weekly": cp, "markdown": weeklyMarkdown(issue, viewerID)})
+		return
+	}
+
+	var sb strings.Builder
+	if issue == nil {
+		sb.WriteString(`<div class="card"><p class="text-muted">No weekly digest has been published yet.</p></div>`)
+	} else {
+		fmt.Fprintf(&sb, `<div class="card"><h3>%s</h3>%s<p class="text-sm text-muted mt-4">Published %s</p></div>`,
+			html.EscapeString(issue.Title()), WeeklyHTML(issue, viewerID), issue.PublishedAt.Format("2 Jan 2006"))
+	}
+
+	var archive []string
+	for _, other := range Weeklies() {
+		if other.Status != WeeklyPublished || (issue != nil && other.ID == issue.ID) {
+			continue
+		}
+		archive = append(archive, fmt.Sprintf(`<p><a href="/weekly?id=%s">%s</a></p>`, other.ID, html.EscapeString(other.Title())))
+	}
+	if len(archive) > 0 {
+		sb.WriteString(`<div class="card"><h4>Earlier issues</h4>` + strings.Join(archive, "") + `</div>`)
+	}
+
+	w.Write([]byte(app.RenderHTMLForRequest("Weekly", "The week on Mu: posts, discussions, news and markets", sb.String(), r)))
+}
@@ -0,0 +1,125 @@
+package digest
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/markets"
+	"mu/user"
+)
+
+func TestWeekStart(t *testing.T) {
+	// Sunday night belongs to the week that began the Monday before.
+	sun := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	if got := weekStart(sun); !got.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("weekStart(Sun) = %v", got)
+	}
+	mon := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	if got := weekStart(mon); !got.Equal(mon) {
+		t.Fatalf("weekStart(Mon) = %v", got)
+	}
+	if id := weekID(mon); id != "2026-W43" {
+		t.Fatalf("weekID = %q", id)
+	}
+}
+
+func TestWeeklyMoves(t *testing.T) {
+	prices := map[string]markets.PriceData{
+		"BTC":  {Price: 110, Change24h: 1},
+		"GOLD": {Price: 95, Change24h: 0.5},
+		"EUR":  {Price: 1.1, Change24h: 3},
+		"XYZ":  {Price: 5, Change24h: 50}, // not a tracked asset
+	}
+	moves, closing := weeklyMoves(prices, map[string]float64{"BTC": 100, "GOLD": 100})
+	if len(moves) != 3 || closing["XYZ"] != 0 || closing["BTC"] != 110 {
+		t.Fatalf("moves = %+v, closing = %v", moves, closing)
+	}
+	// Week-on-week changes where there is a baseline, else 24h; biggest first.
+	if moves[0].Symbol != "BTC" || moves[0].Period != "week" || int(moves[0].Change) != 10 {
+		t.Errorf("first move = %+v", moves[0])
+	}
+	if moves[1].Symbol != "GOLD" || moves[1].Period != "week" {
+		t.Errorf("second move = %+v", moves[1])
+	}
+	if moves[2].Symbol != "EUR" || moves[2].Period != "24h" {
+		t.Errorf("third move = %+v", moves[2])
+	}
+}
+
+func TestWeeklyReviewAndPublish(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	weeklyMu.Lock()
+	weeklies = nil
+	weeklyMu.Unlock()
+	defer func() { WeeklyPosts, WeeklyDiscussions = nil, nil }()
+
+	WeeklyPosts = func(from, to time.Time) []WeeklyItem {
+		return []WeeklyItem{{Title: "Hello [world]", URL: "/blog/post?id=1", Author: "alice"}}
+	}
+	WeeklyDiscussions = func(from, to time.Time, n int) []WeeklyItem {
+		return []WeeklyItem{{Title: "", URL: "/blog/post?id=2", Comments: 1}}
+	}
+
+	// Nothing is compiled before Monday morning.
+	monday := weekStart(time.Now())
+	if w := compileDueWeekly(monday.Add(time.Hour)); w != nil {
+		t.Fatalf("compiled too early: %s", w.ID)
+	}
+	w := compileDueWeekly(monday.Add(7 * time.Hour))
+	if w == nil || w.Status != WeeklyDraft || !w.To.Equal(monday) {
+		t.Fatalf("draft = %+v", w)
+	}
+	if compileDueWeekly(monday.Add(8*time.Hour)) != nil {
+		t.Fatal("compiled the same week twice")
+	}
+	if LatestWeekly() != nil {
+		t.Fatal("draft is public before review")
+	}
+
+	if err := SetWeeklyIntro(w.ID, "A busy week."); err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishWeekly(w.ID, "admin", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := LatestWeekly(); got == nil || got.ID != w.ID || got.PublishedBy != "admin" {
+		t.Fatalf("latest = %+v", got)
+	}
+	if _, err := CompileWeekly(w.ID); err == nil {
+		t.Fatal("recompiled a published issue")
+	}
+	if err := DiscardWeekly(w.ID); err == nil {
+		t.Fatal("discarded a published issue")
+	}
+
+	md := weeklyMarkdown(w, "")
+	for _, want := range []string{"A busy week.", `[Hello \[world\]](/blog/post?id=1) by alice`, "[Untitled](/blog/post?id=2) · 1 comment"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestWeeklyMembersRespectPrivacy(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	w := &Weekly{Members: []WeeklyMember{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bob"}}}
+	user.SetVisibility("alice", user.FieldWelcome, user.Public)
+	user.SetVisibility("bob", user.FieldWelcome, user.Members)
+	defer user.ClearPrivacy("alice")
+	defer user.ClearPrivacy("bob")
+
+	if got := visibleMembers(w, ""); len(got) != 1 || got[0].ID != "alice" {
+		t.Errorf("guest sees %v", got)
+	}
+	if got := visibleMembers(w, "carol"); len(got) != 2 {
+		t.Errorf("member sees %v", got)
+	}
+
+	// Opting out after the issue was compiled still hides them.
+	user.SetVisibility("alice", user.FieldWelcome, user.Private)
+	if got := visibleMembers(w, ""); len(got) != 0 {
+		t.Errorf("guest sees %v after opt-out", got)
+	}
+}
@@ -0,0 +1,352 @@
+package news
+
+import (
+	"encoding/json"
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// The feed list starts out as the embedded feeds.json, sorted by name.
+// Once an admin changes it at /admin/feeds it is stored in the data
+// directory and takes precedence; each change triggers a re-parse rather
+// than waiting for the hourly refresh.
+
+// FeedSource is a configured news feed. Its name is the category its
+// posts are filed under.
+type FeedSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+const feedsKey = "feeds.json"
+
+// feedNameRe limits names to what works as a category and page anchor.
+var feedNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]{0,31}$`)
+
+// reparse wakes parseFeed early after the feed list changes.
+var reparse = make(chan struct{}, 1)
+
+// loadFeed loads the configured feeds, or the embedded defaults.
+func loadFeed() {
+	var list []FeedSource
+	if err := data.LoadJSON(feedsKey, &list); err != nil || len(list) == 0 {
+		list = defaultFeeds()
+	}
+	mutex.Lock()
+	feeds = list
+	mutex.Unlock()
+}
+
+// defaultFeeds returns the embedded feeds, sorted by name.
+func defaultFeeds() []FeedSource {
+	b, _ := f.ReadFile("feeds.json")
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		fmt.Println("Error parsing feeds.json", err)
+	}
+	list := make([]FeedSource, 0, len(m))
+	for name, u := range m {
+		list = append(list, FeedSource{Name: name, URL: u})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Feeds returns the configured feeds in display order.
+func Feeds() []FeedSource {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return append([]FeedSource(nil), feeds...)
+}
+
+// feedNames returns the feed names in display order.
+func feedNames() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	names := make([]string, len(feeds))
+	for i, fs := range feeds {
+		names[i] = fs.Name
+	}
+	return names
+}
+
+// feedIndex returns the position of the named feed, or -1 (caller must
+// hold mutex).
+func feedIndex(name string) int {
+	for i, fs := range feeds {
+		if strings.EqualFold(fs.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveFeeds stores the feed list and schedules a re-parse (caller must
+// hold mutex).
+func saveFeeds() error {
+	if err := data.SaveJSON(feedsKey, feeds); err != nil {
+		return err
+	}
+	select {
+	case reparse <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// checkFeed fetches and parses a feed URL, so a broken one is refused
+// before it is added.
+func checkFeed(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("feed URL must be an http(s) URL")
+	}
+	p := gofeed.NewParser()
+	p.UserAgent = "Mu/0.1"
+	p.Client = feedClient
+	feed, err := p.ParseURL(u)
+	if err != nil {
+		return fmt.Errorf("could not read feed: %v", err)
+	}
+	if len(feed.Items) == 0 {
+		return fmt.Errorf("feed has no items")
+	}
+	return nil
+}
+
+// AddFeed fetches and checks a feed, then adds it to the end of the list.
+func AddFeed(name, u string) error {
+	name, u = strings.TrimSpace(name), strings.TrimSpace(u)
+	if !feedNameRe.MatchString(name) {
+		return fmt.Errorf("name must be a letter followed by up to 31 letters, digits or hyphens")
+	}
+	mutex.RLock()
+	exists := feedIndex(name) >= 0
+	mutex.RUnlock()
+	if exists {
+		return fmt.Errorf("a feed named %s already exists", name)
+	}
+	if err := checkFeed(u); err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if feedIndex(name) >= 0 {
+		return fmt.Errorf("a feed named %s already exists", name)
+	}
+	feeds = append(feeds, FeedSource{Name: name, URL: u})
+	return saveFeeds()
+}
+
+// RemoveFeed removes a feed. Its posts drop out at the next parse.
+func RemoveFeed(name string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	i := feedIndex(name)
+	if i < 0 {
+		return fmt.Errorf("no feed named %s", name)
+	}
+	delete(status, feeds[i].Name)
+	feeds = append(feeds[:i:i], feeds[i+1:]...)
+	return saveFeeds()
+}
+
+// RenameFeed renames a feed, and with it the category of its posts.
+func RenameFeed(name, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if !feedNameRe.MatchString(newName) {
+		return fmt.Errorf("name must be a letter followed by up to 31 letters, digits or hyphens")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	i := feedIndex(name)
+	if i < 0 {
+		return fmt.Errorf("no feed named %s", name)
+	}
+	if j := feedIndex(newName); j >= 0 && j != i {
+		return fmt.Errorf("a feed named %s already exists", newName)
+	}
+	old := feeds[i].Name
+	if stat, ok := status[old]; ok {
+		delete(status, old)
+		stat.Name = newName
+		status[newName] = stat
+	}
+	feeds[i].Name = newName
+	return saveFeeds()
+}
+
+// ReorderFeeds sets the display order. names must list every feed once.
+func ReorderFeeds(names []string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(names) != len(feeds) {
+		return fmt.Errorf("order must list all %d feeds", len(feeds))
+	}
+	ordered := make([]FeedSource, 0, len(feeds))
+	seen := map[int]bool{}
+	for _, name := range names {
+		i := feedIndex(name)
+		if i < 0 || seen[i] {
+			return fmt.Errorf("unknown or repeated feed %s", name)
+		}
+		seen[i] = true
+		ordered = append(ordered, feeds[i])
+	}
+	feeds = ordered
+	return saveFeeds()
+}
+
+// moveFeed shifts a feed one place up (delta -1) or down (delta 1).
+func moveFeed(name string, delta int) error {
+	names := feedNames()
+	for i, n := range names {
+		if !strings.EqualFold(n, name) {
+			continue
+		}
+		j := i + delta
+		if j < 0 || j >= len(names) {
+			return nil
+		}
+		names[i], names[j] = names[j], names[i]
+		return ReorderFeeds(names)
+	}
+	return fmt.Errorf("no feed named %s", name)
+}
+
+// FeedsHandler serves /admin/feeds. GET lists the feeds with their fetch
+// status (JSON when requested); POST applies one change: add, remove,
+// rename, up, down or reorder.
+func FeedsHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+
+	if r.Method == "POST" {
+		var req struct {
+			Action  string   `json:"action"`
+			Name    string   `json:"name"`
+			URL     string   `json:"url"`
+			NewName string   `json:"new_name"`
+			Order   []string `json:"order"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Action = r.FormValue("action")
+			req.Name = r.FormValue("name")
+			req.URL = r.FormValue("url")
+			req.NewName = r.FormValue("new_name")
+			req.Order = r.Form["order"]
+		}
+		switch req.Action {
+		case "add":
+			err = AddFeed(req.Name, req.URL)
+		case "remove":
+			err = RemoveFeed(req.Name)
+		case "rename":
+			err = RenameFeed(req.Name, req.NewName)
+		case "up":
+			err = moveFeed(req.Name, -1)
+		case "down":
+			err = moveFeed(req.Name, 1)
+		case "reorder":
+			err = ReorderFeeds(req.Order)
+		default:
+			err = fmt.Errorf("unknown action")
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		app.Log("news", "%s feeds %s %s", acc.ID, req.Action, req.Name)
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"feeds": Feeds()})
+			return
+		}
+		http.Redirect(w, r, "/admin/feeds", http.StatusSeeOther)
+		return
+	}
+
+	list := Feeds()
+	errs := map[string]string{}
+	mutex.RLock()
+	for name, stat := range status {
+		if stat.Error != nil {
+			errs[name] = fmt.Sprintf("%v (attempt %d)", stat.Error, stat.Attempts)
+		}
+	}
+	mutex.RUnlock()
+
+	if app.WantsJSON(r) {
+		type feedJSON struct {
+			FeedSource
+			Error string `json:"error,omitempty"`
+		}
+		out := make([]feedJSON, len(list))
+		for i, fs := range list {
+			out[i] = feedJSON{FeedSource: fs, Error: errs[fs.Name]}
+		}
+		app.RespondJSON(w, map[string]interface{}{"feeds": out})
+		return
+	}
+
+	btn := `style="font-size:12px;padding:2px 8px;border-radius:4px;background:#fff;cursor:pointer;border:1px solid #ccc"`
+	var content strings.Builder
+	content.WriteString(`<div class="card">`)
+	content.WriteString(fmt.Sprintf(`<h3>News Feeds <span class="count">%d</span></h3>`, len(list)))
+	content.WriteString(`<p class="text-sm text-muted">Each feed is a category on <a href="/news">/news</a>, shown in this order. Changes are re-parsed straight away.</p>`)
+	content.WriteString(`<form method="POST" class="block-form">
+		<input type="hidden" name="action" value="add">
+		<input type="text" name="name" placeholder="Name, e.g. Science" required>
+		<input type="url" name="url" placeholder="https://example.com/rss" required>
+		<button type="submit">Add</button>
+	</form>`)
+	content.WriteString(`<table class="email-log">`)
+	content.WriteString(`<tr><th>Name</th><th class="hide-mobile">URL</th><th>Status</th><th></th></tr>`)
+	for _, fs := range list {
+		name := htmlpkg.EscapeString(fs.Name)
+		state := "ok"
+		if e, ok := errs[fs.Name]; ok {
+			state = `<span class="dir-out">` + htmlpkg.EscapeString(e) + `</span>`
+		}
+		action := func(a, label string) string {
+			return fmt.Sprintf(`<form method="POST" class="d-inline"><input type="hidden" name="action" value="%s"><input type="hidden" name="name" value="%s"><button type="submit" %s>%s</button></form>`, a, name, btn, label)
+		}
+		content.WriteString(fmt.Sprintf(`<tr>
+			<td><form method="POST" class="d-inline"><input type="hidden" name="action" value="rename"><input type="hidden" name="name" value="%s"><input type="text" name="new_name" value="%s" style="width:110px"></form></td>
+			<td class="addr hide-mobile">%s</td>
+			<td>%s</td>
+			<td style="white-space:nowrap">%s %s <form method="POST" class="d-inline" onsubmit="return confirm('Remove %s?')"><input type="hidden" name="action" value="remove"><input type="hidden" name="name" value="%s"><button type="submit" %s>Remove</button></form></td>
+		</tr>`,
+			name, name,
+			htmlpkg.EscapeString(fs.URL),
+			state,
+			action("up", "↑"), action("down", "↓"),
+			name, name, btn,
+		))
+	}
+	content.WriteString(`</table>`)
+	content.WriteString(`<p class="text-sm text-muted mt-2">Edit a name and press Enter to rename it.</p>`)
+	content.WriteString(`</div>`)
+	content.WriteString(`<p><a href="/admin">← Back to Admin</a></p>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest("Feeds", "News feeds", content.String(), r)))
+}
@@ -0,0 +1,79 @@
+package news
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title>
+<item><title>One</title><link>https://example.com/1</link></item>
+</channel></rss>`
+
+func TestFeedManagement(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.Write([]byte(`<rss version="2.0"><channel><title>T</title></channel></rss>`))
+			return
+		}
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	loadFeed()
+	defaults := feedNames()
+	if len(defaults) == 0 || defaults[0] != "Crypto" {
+		t.Fatalf("defaults = %v", defaults)
+	}
+
+	if err := AddFeed("Science", srv.URL+"/rss"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddFeed("science", srv.URL+"/rss"); err == nil {
+		t.Error("added a duplicate name")
+	}
+	if err := AddFeed("Empty", srv.URL+"/empty"); err == nil {
+		t.Error("added a feed with no items")
+	}
+	if err := AddFeed("Bad name!", srv.URL+"/rss"); err == nil {
+		t.Error("added an invalid name")
+	}
+	if err := AddFeed("Local", "file:///etc/passwd"); err == nil {
+		t.Error("added a non-http URL")
+	}
+	select {
+	case <-reparse:
+	default:
+		t.Error("adding a feed did not trigger a re-parse")
+	}
+
+	if err := RenameFeed("Science", "Research"); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveFeed("Research", -1); err != nil {
+		t.Fatal(err)
+	}
+	names := feedNames()
+	if names[len(names)-2] != "Research" {
+		t.Fatalf("after move up: %v", names)
+	}
+	if err := ReorderFeeds(names[1:]); err == nil {
+		t.Error("reordered with a feed missing")
+	}
+	if err := RemoveFeed("Research"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The list survives a restart.
+	if err := RemoveFeed("Crypto"); err != nil {
+		t.Fatal(err)
+	}
+	loadFeed()
+	got := strings.Join(feedNames(), ",")
+	if strings.Contains(got, "Crypto") || strings.Contains(got, "Research") || len(feedNames()) != len(defaults)-1 {
+		t.Fatalf("reloaded feeds = %s", got)
+	}
+}
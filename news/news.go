@@ -5,13 +5,13 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	htmlpkg "html"
 	"io/ioutil"
 	"math"
 	"net/http"
 	"net/url"
 	"path/filepath"
 	"regexp"
-	"runtime/debug"
 	"sort"
 	"strings"
 	"sync"
@@ -25,7 +25,9 @@ import (
 	"mu/internal/app"
 	"mu/internal/auth"
 	"mu/internal/data"
+	"mu/internal/egress"
 	"mu/internal/event"
+	"mu/internal/netx"
 	"mu/internal/service"
 	"mu/internal/snapshot"
 
@@ -41,22 +43,36 @@ var f embed.FS
 
 var mutex sync.RWMutex
 
-var feeds = map[string]string{}
+var feeds []FeedSource
 
 var status = map[string]*Feed{}
 
 // Semaphore to limit concurrent metadata fetches (reduces memory spike on startup)
 var metadataFetchSem = make(chan struct{}, 10) // Allow max 10 concurrent fetches
 
-// cached news html
-var html string
+// lowMemoryFetches is the metadata fetch concurrency in low-memory mode.
+const lowMemoryFetches = 2
 
-// cached news body (without full page wrapper)
+// Outbound clients. Feeds and article pages come from many hosts, so the
+// per-host breaker stops one dead site slowing every refresh; HN items
+// rarely change within a few minutes, so they are cached.
+var (
+	feedClient    = netx.New("news-feeds", netx.Policy{Timeout: 30 * time.Second, Retries: 1})
+	articleClient = netx.New("news-articles", netx.Policy{Timeout: 15 * time.Second, Retries: 1})
+	hnClient      = netx.New("news-hn", netx.Policy{Timeout: 10 * time.Second, Retries: 2, CacheTTL: 10 * time.Minute})
+)
+
+// cached news body (without full page wrapper); left empty in low-memory
+// mode, where the fallback is read from news.html when needed
 var newsBodyHtml string
 
 // cached headlines
 var headlinesHtml string
 
+// newsUpdated is when the cached news and headlines were last rebuilt,
+// the Last-Modified of /news. Guarded by mutex.
+var newsUpdated time.Time
+
 // the cached feed
 var feed []*Post
 
@@ -83,6 +99,7 @@ type Post struct {
 	PostedAt    time.Time `json:"posted_at"`
 	Image       string    `json:"image"`
 	Content     string    `json:"content"`
+	Sources     []Source  `json:"sources,omitempty"` // other feeds carrying the same story
 }
 
 type Metadata struct {
@@ -119,15 +136,31 @@ func canonicalPostKey(post *Post) string {
 	return "title:" + strings.Join(strings.Fields(strings.ToLower(post.Title)), " ")
 }
 
+// dedupePosts collapses the same story reported more than once: items
+// with the same canonical URL, or from different feeds with near-identical
+// titles. The first item seen stays canonical and the rest are recorded
+// as its Sources, so the article page can show who else covered it.
 func dedupePosts(posts []*Post) []*Post {
 	seen := map[string]*Post{}
 	var deduped []*Post
+	var titles [][]string // title words of deduped, by index
 	for _, post := range posts {
 		key := canonicalPostKey(post)
 		if key == "" {
 			continue
 		}
-		if existing, ok := seen[key]; ok {
+		existing, ok := seen[key]
+		words := titleWords(post.Title)
+		if !ok {
+			for i, other := range deduped {
+				if similarTitles(words, titles[i]) {
+					existing = other
+					addSource(existing, post)
+					break
+				}
+			}
+		}
+		if existing != nil {
 			if existing.URL == "" && post.URL != "" {
 				existing.URL = post.URL
 			}
@@ -141,15 +174,132 @@ func dedupePosts(posts []*Post) []*Post {
 				existing.PostedAt = post.PostedAt
 				existing.Published = post.Published
 			}
+			for _, src := range post.Sources {
+				addSource(existing, &Post{ID: src.ID, URL: src.URL, Category: src.Category})
+			}
+			seen[key] = existing
 			continue
 		}
 		copyPost := *post
+		copyPost.Sources = append([]Source(nil), post.Sources...)
 		seen[key] = &copyPost
 		deduped = append(deduped, &copyPost)
+		titles = append(titles, words)
 	}
 	return deduped
 }
 
+// Source is another feed's copy of a story folded into a canonical Post.
+type Source struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Domain   string `json:"domain"`
+	Category string `json:"category,omitempty"`
+}
+
+// addSource records dup as an alternate source of post, once per URL.
+func addSource(post, dup *Post) {
+	if dup.URL == "" || canonicalPostKey(dup) == canonicalPostKey(post) {
+		return
+	}
+	for _, s := range post.Sources {
+		if canonicalPostKey(&Post{URL: s.URL}) == canonicalPostKey(dup) {
+			return
+		}
+	}
+	post.Sources = append(post.Sources, Source{ID: dup.ID, URL: dup.URL, Domain: getDomain(dup.URL), Category: dup.Category})
+}
+
+// alsoCoveredBy renders links to the other outlets carrying the story,
+// looked up in the current feed by the article's ID or any of its
+// sources' IDs.
+func alsoCoveredBy(articleID string) string {
+	mutex.RLock()
+	var sources []Source
+	for _, post := range feed {
+		match := post.ID == articleID
+		for _, s := range post.Sources {
+			if s.ID == articleID {
+				match = true
+			}
+		}
+		if !match {
+			continue
+		}
+		sources = append(sources, Source{ID: post.ID, URL: post.URL, Domain: getDomain(post.URL)})
+		sources = append(sources, post.Sources...)
+		break
+	}
+	mutex.RUnlock()
+
+	var links []string
+	for _, s := range sources {
+		if s.ID == articleID || s.URL == "" {
+			continue
+		}
+		href := s.URL
+		if s.ID != "" {
+			href = "/news?id=" + s.ID
+		}
+		links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, htmlpkg.EscapeString(href), htmlpkg.EscapeString(s.Domain)))
+	}
+	if len(links) == 0 {
+		return ""
+	}
+	return `<div class="article-sources text-sm text-muted">Also covered by ` + strings.Join(links, ", ") + `</div>`
+}
+
+// titleStopWords are ignored when comparing headlines.
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "to": true, "in": true, "on": true,
+	"for": true, "and": true, "or": true, "is": true, "are": true, "as": true, "at": true,
+	"by": true, "with": true, "from": true, "after": true, "over": true, "its": true,
+	"says": true, "said": true,
+}
+
+// titleWords returns the significant lowercase words of a headline.
+func titleWords(title string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !titleStopWords[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// minTitleWords keeps short, generic headlines ("Live updates") from
+// matching each other.
+const minTitleWords = 4
+
+// similarTitles reports whether two headlines share most of their words
+// (Jaccard similarity of at least 0.6).
+func similarTitles(a, b []string) bool {
+	if len(a) < minTitleWords || len(b) < minTitleWords {
+		return false
+	}
+	set := map[string]bool{}
+	for _, w := range a {
+		set[w] = true
+	}
+	inter, union := 0, len(set)
+	counted := map[string]bool{}
+	for _, w := range b {
+		if counted[w] {
+			continue
+		}
+		counted[w] = true
+		if set[w] {
+			inter++
+		} else {
+			union++
+		}
+	}
+	return float64(inter)/float64(union) >= 0.6
+}
+
 func displayNewsCategory(category string) string {
 	cat := strings.TrimSpace(category)
 	if cat == "" {
@@ -378,9 +528,11 @@ func saveHtml(head, content []byte) {
   <button type="submit">Search</button>
 </form>`
 	body := fmt.Sprintf(`%s<div id="topics">%s</div><div>%s</div>`, searchForm, string(head), string(content))
-	newsBodyHtml = body
-	data.SaveFile("news.html", newsBodyHtml)
-	app.Log("news", "Saved news.html (%d bytes)", len(newsBodyHtml))
+	if !data.LowMemory() {
+		newsBodyHtml = body
+	}
+	data.SaveFile("news.html", body)
+	app.Log("news", "Saved news.html (%d bytes)", len(body))
 }
 
 // generateNewsHtml generates fresh HTML from the feed data with current timestamps
@@ -478,12 +630,7 @@ func generateNewsHtml() string {
 	mutex.RUnlock()
 
 	// Get topics header
-	var sortedFeeds []string
-	for name := range feeds {
-		sortedFeeds = append(sortedFeeds, name)
-	}
-	sort.Strings(sortedFeeds)
-	head := app.Head("news", sortedFeeds)
+	head := app.Head("news", feedNames())
 
 	return fmt.Sprintf(`%s<div id="topics">%s</div><div>%s</div>`, searchForm, head, headlines+string(content))
 }
@@ -544,17 +691,6 @@ func generateHeadlinesHtml() string {
 	return string(headline)
 }
 
-func loadFeed() {
-	// load the feeds file
-	data, _ := f.ReadFile("feeds.json")
-	// unpack into feeds
-	mutex.Lock()
-	if err := json.Unmarshal(data, &feeds); err != nil {
-		fmt.Println("Error parsing feeds.json", err)
-	}
-	mutex.Unlock()
-}
-
 func getMetadataPath(uri string) string {
 	// Generate stable ID from URL hash
 	itemID := fmt.Sprintf("%x", md5.Sum([]byte(uri)))[:16]
@@ -626,7 +762,7 @@ func getMetadata(uri string, publishedAt time.Time) (*Metadata, bool, error) {
 	}
 
 	// Fetch HTML with proper resource cleanup
-	resp, err := http.Get(u.String())
+	resp, err := articleClient.Get(u.String())
 	if err != nil {
 		return nil, false, err
 	}
@@ -900,50 +1036,76 @@ func requestArticleSummary(uri string, md *Metadata) {
 	})
 }
 
-// FetchHNComments fetches top-level comments from a HackerNews story
-func FetchHNComments(storyID string) (string, error) {
-	apiURL := fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%s.json", storyID)
+// hnFormat is the version of the Hacker News item API parseHNItem
+// understands.
+const hnFormat = "v0"
+
+// hnItem is a story or comment from the Hacker News API.
+type hnItem struct {
+	ID      int64  `json:"id"`
+	Type    string `json:"type"`
+	By      string `json:"by"`
+	Text    string `json:"text"`
+	Kids    []int  `json:"kids"`
+	Deleted bool   `json:"deleted"`
+	Dead    bool   `json:"dead"`
+}
 
-	resp, err := http.Get(apiURL)
+// parseHNItem reads an item from the Hacker News API. The API answers
+// null for an item that doesn't exist.
+func parseHNItem(body []byte) (*hnItem, error) {
+	var item *hnItem
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, egress.Drift("hackernews", "%v", err)
+	}
+	if item == nil {
+		return nil, fmt.Errorf("hackernews: no such item")
+	}
+	if item.ID == 0 || item.Type == "" {
+		return nil, egress.Drift("hackernews", "item has no id or type")
+	}
+	return item, nil
+}
+
+// fetchHNItem fetches an item from the Hacker News API.
+func fetchHNItem(id string) (*hnItem, error) {
+	resp, err := hnClient.Get(fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%s.json", id))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
-
-	var story struct {
-		Comments []int `json:"kids"`
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hacker news returned status %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
+	item, err := parseHNItem(body)
+	return item, egress.CheckFormat("hackernews", hnFormat, err)
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&story); err != nil {
+// FetchHNComments fetches top-level comments from a HackerNews story
+func FetchHNComments(storyID string) (string, error) {
+	story, err := fetchHNItem(storyID)
+	if err != nil {
 		return "", err
 	}
 
 	// Fetch top 10 comments for context
 	var comments []string
 	maxComments := 10
-	for i, commentID := range story.Comments {
+	for i, commentID := range story.Kids {
 		if i >= maxComments {
 			break
 		}
 
-		commentURL := fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%d.json", commentID)
-		commentResp, err := http.Get(commentURL)
+		comment, err := fetchHNItem(fmt.Sprint(commentID))
 		if err != nil {
 			continue
 		}
 
-		var comment struct {
-			Text string `json:"text"`
-			By   string `json:"by"`
-		}
-
-		if err := json.NewDecoder(commentResp.Body).Decode(&comment); err != nil {
-			commentResp.Body.Close()
-			continue
-		}
-		commentResp.Body.Close()
-
-		if comment.Text != "" {
+		if comment.Text != "" && !comment.Deleted && !comment.Dead {
 			// Strip HTML tags from comment
 			cleanText := sanitize.HTML(comment.Text)
 			comments = append(comments, fmt.Sprintf("[%s]: %s", comment.By, cleanText))
@@ -1279,38 +1441,28 @@ func generateHeadlinesHTML(headlines []*Post) string {
 	return sb.String()
 }
 
-func parseFeed() {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("Recovered from panic in feed parser: %v\n", r)
-			debug.PrintStack()
-			fmt.Println("Relaunching feed parser in 1 minute")
-			time.Sleep(time.Minute)
-			go parseFeed()
-		}
-	}()
-
+// parseFeed fetches every feed and rebuilds the headlines.
+func parseFeed() error {
 	fmt.Println("Parsing feed at", time.Now().String())
 	p := gofeed.NewParser()
 	p.UserAgent = "Mu/0.1"
+	p.Client = feedClient
 
-	// Collect feed URLs and stats
+	// Collect feed URLs and stats, in the configured order
 	var sorted []string
 	urls := map[string]string{}
 	stats := map[string]Feed{}
 
 	mutex.RLock()
-	for name, url := range feeds {
-		sorted = append(sorted, name)
-		urls[name] = url
-		if stat, ok := status[name]; ok {
-			stats[name] = *stat
+	for _, fs := range feeds {
+		sorted = append(sorted, fs.Name)
+		urls[fs.Name] = fs.URL
+		if stat, ok := status[fs.Name]; ok && stat.URL == fs.URL {
+			stats[fs.Name] = *stat
 		}
 	}
 	mutex.RUnlock()
 
-	sort.Strings(sorted)
-
 	// Process all feeds
 	var allContent []byte
 	var allNews []*Post
@@ -1362,6 +1514,7 @@ func parseFeed() {
 	mutex.Lock()
 	feed = allNews
 	headlinesHtml = headlineHtml
+	newsUpdated = time.Now()
 	saveHtml(head, allContent)
 	data.SaveFile("headlines.html", headlinesHtml)
 	data.SaveJSON("feed.json", feed)
@@ -1370,13 +1523,14 @@ func parseFeed() {
 	// Publish the new snapshot to the go-micro store + broker; Headlines serves
 	// it from a mirror (see internal/snapshot, docs/GO_MICRO_ARCHITECTURE.md).
 	cardSnap.Publish(headlineHtml)
-
-	// Wait an hour and go again
-	time.Sleep(time.Hour)
-	go parseFeed()
+	updateTrending()
+	return nil
 }
 
 func Load() {
+	data.RegisterTable("feed.json")
+	loadWarmSentiments()
+
 	// Register the go-micro service.
 	if err := service.Register("news", new(Server)); err != nil {
 		app.Log("news", "service register failed: %v", err)
@@ -1455,6 +1609,7 @@ func Load() {
 	// load headlines
 	b, _ := data.LoadFile("headlines.html")
 	headlinesHtml = string(b)
+	newsUpdated = time.Now()
 
 	// load cached feed
 	b, _ = data.LoadFile("feed.json")
@@ -1467,15 +1622,12 @@ func Load() {
 		}
 	}
 
-	// load news body and html
-	b, _ = data.LoadFile("news.html")
-	html = string(b)
-	// Extract body content from saved HTML for serving
-	// The newsBodyHtml will be rebuilt by parseFeed, but load from file for immediate serving
-	if len(html) > 0 {
-		// Parse out just the body content between the main content divs
-		// For now just set newsBodyHtml from the full html - parseFeed will update it
-		newsBodyHtml = html
+	// load news body for immediate serving; parseFeed rebuilds it
+	if data.LowMemory() {
+		metadataFetchSem = make(chan struct{}, lowMemoryFetches)
+	} else {
+		b, _ = data.LoadFile("news.html")
+		newsBodyHtml = string(b)
 	}
 
 	// load the feeds
@@ -1486,7 +1638,17 @@ func Load() {
 	cardSnap = snapshot.New("news")
 	cardSnap.Publish(headlinesHtml)
 
-	go parseFeed()
+	// Parse the feeds hourly, and again whenever they're changed
+	app.Schedule(app.Job{Name: "news.feeds", Every: time.Hour, Run: parseFeed})
+	go func() {
+		for range reparse {
+			app.RunJob("news.feeds")
+		}
+	}()
+	app.Schedule(app.Job{Name: "news.trending", Every: trendingInterval, Run: func() error {
+		updateTrending()
+		return nil
+	}})
 }
 
 func Headlines() string {
@@ -1679,6 +1841,11 @@ func handleArticleView(w http.ResponseWriter, r *http.Request, articleID string)
 		socialContextHTML = FetchSocialContext(articleURL, description+" "+summary)
 	}
 
+	bookmark := ""
+	if b := app.BookmarkButton(r, "news", articleID); b != "" {
+		bookmark = `<span class="mx-2">·</span>` + b
+	}
+
 	articleHtml := fmt.Sprintf(`
 		<div id="news-article">
 			%s
@@ -1688,18 +1855,23 @@ func handleArticleView(w http.ResponseWriter, r *http.Request, articleID string)
 			%s
 			%s
 			%s
+			%s
 			<div class="article-actions">
+				<a href="/news?id=%s&view=reader">Reader view →</a>
+				<span class="mx-2">·</span>
 				<a href="%s" target="_blank" rel="noopener noreferrer">Read Original →</a>
 				<span class="mx-2">·</span>
 				<a href="/chat?id=news_%s">Discuss with AI →</a>
 				<span class="mx-2">·</span>
-				<a href="#" onclick="navigator.share ? navigator.share({title: document.title, url: window.location.href}) : navigator.clipboard.writeText(window.location.href).then(() => alert('Link copied to clipboard!')); return false;">Share →</a>
+				<a href="#" data-share>Share →</a>
+				%s
 			</div>
+			%s
 			<div class="article-back">
 				<a href="/news">← Back to news</a>
 			</div>
 		</div>
-	`, imageSection, postedAt.Unix(), app.TimeAgo(postedAt), getDomain(articleURL), categoryBadge, descriptionSection, summarySection, socialContextHTML, articleURL, articleID)
+	`, imageSection, postedAt.Unix(), app.TimeAgo(postedAt), getDomain(articleURL), categoryBadge, descriptionSection, summarySection, alsoCoveredBy(articleID), socialContextHTML, articleID, articleURL, articleID, bookmark, app.LinkedFrom(articleID))
 
 	// Use title for browser tab, but empty page title since article already has its own H1
 	pageHTML := app.RenderHTML(title, title, articleHtml)
@@ -1709,6 +1881,10 @@ func handleArticleView(w http.ResponseWriter, r *http.Request, articleID string)
 func Handler(w http.ResponseWriter, r *http.Request) {
 	// Handle viewing individual news article
 	if articleID := r.URL.Query().Get("id"); articleID != "" {
+		if r.URL.Query().Get("view") == "reader" {
+			handleReaderView(w, r, articleID)
+			return
+		}
 		handleArticleView(w, r, articleID)
 		return
 	}
@@ -2584,6 +2760,7 @@ func handleGetFeed(w http.ResponseWriter, r *http.Request) {
 	mutex.RLock()
 	currentFeed := feed
 	hasContent := len(feed) > 0
+	updated := newsUpdated
 	mutex.RUnlock()
 
 	// JSON response
@@ -2598,11 +2775,15 @@ func handleGetFeed(w http.ResponseWriter, r *http.Request) {
 	body := newsBodyHtml
 	if hasContent {
 		body = generateNewsHtml()
+	} else if data.LowMemory() {
+		b, _ := data.LoadFile("news.html")
+		body = string(b)
 	}
 	app.Respond(w, r, app.Response{
 		Title:       "News",
 		Description: "Latest news headlines",
 		HTML:        body,
+		Modified:    updated,
 	})
 }
 
@@ -9,6 +9,8 @@ import (
 
 	"github.com/mmcdole/gofeed"
 	"mu/internal/data"
+	"mu/internal/egress"
+	"mu/internal/testutil"
 )
 
 func TestContentParsers_StripHNComments(t *testing.T) {
@@ -1844,6 +1846,58 @@ func TestDedupePostsCollapsesCanonicalURLsAndKeepsSource(t *testing.T) {
 	}
 }
 
+func TestDedupePostsClustersSimilarTitlesAcrossFeeds(t *testing.T) {
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)
+	posts := []*Post{
+		{ID: "bbc-1", Title: "Central bank raises interest rates to 5%", URL: "https://bbc.co.uk/news/rates", Category: "World", PostedAt: now},
+		{ID: "ft-1", Title: "Central Bank raises interest rates to 5 %!", URL: "https://ft.com/content/rates", Category: "Business", PostedAt: now},
+		{ID: "other", Title: "Local team wins cup final", URL: "https://example.com/cup", Category: "Sport", PostedAt: now},
+	}
+
+	got := dedupePosts(posts)
+	if len(got) != 2 {
+		t.Fatalf("expected similar headlines to cluster into 2 items, got %d", len(got))
+	}
+	if got[0].ID != "bbc-1" || len(got[0].Sources) != 1 || got[0].Sources[0].ID != "ft-1" || got[0].Sources[0].Domain != "ft.com" {
+		t.Fatalf("expected ft.com recorded as an alternate source, got %+v", got[0].Sources)
+	}
+
+	// Deduping again must not duplicate sources.
+	again := dedupePosts(append(got, posts[1]))
+	if len(again) != 2 || len(again[0].Sources) != 1 {
+		t.Fatalf("re-deduping changed sources: %+v", again[0].Sources)
+	}
+
+	if similarTitles(titleWords("Live updates"), titleWords("Live updates")) {
+		t.Error("short generic headlines should not cluster")
+	}
+}
+
+func TestExtractTopicsPrefersPhrases(t *testing.T) {
+	titles := []string{
+		"Federal Reserve holds rates steady",
+		"Markets rally as Federal Reserve signals cuts",
+		"What the Federal Reserve decision means for mortgages",
+		"Federal Reserve chair testifies before Congress",
+		"OpenAI launches new model",
+		"OpenAI model tops benchmarks",
+		"Investors weigh OpenAI valuation",
+		"Local bakery wins award",
+	}
+	got := extractTopics(titles, 5)
+	if len(got) < 2 || got[0].Term != "Federal Reserve" || got[0].Count != 4 {
+		t.Fatalf("expected Federal Reserve first, got %+v", got)
+	}
+	for _, topic := range got {
+		if topic.Term == "Reserve" || topic.Term == "Federal" || topic.Term == "bakery" {
+			t.Errorf("unexpected topic %q in %+v", topic.Term, got)
+		}
+	}
+	if got[1].Term != "OpenAI" {
+		t.Errorf("expected OpenAI second, got %+v", got)
+	}
+}
+
 func TestGenerateNewsHtmlLabelsNonNewsFeedEntries(t *testing.T) {
 	oldFeed := feed
 	oldHeadlines := headlinesHtml
@@ -1933,3 +1987,60 @@ func TestNewsSearchPayloadIncludesFreshnessForAPIPath(t *testing.T) {
 		t.Fatalf("expected API-path same-day caveat notice, got %#v", freshness)
 	}
 }
+
+func TestReaderParagraphs(t *testing.T) {
+	got := readerParagraphs(`<p>First sentence here.</p> loose  text <p>Fish &amp; chips <b>.</p><P></P>`)
+	want := []string{"First sentence here.", "loose text", "Fish &amp; chips &lt;b&gt;."}
+	if len(got) != len(want) {
+		t.Fatalf("got %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("paragraph %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseHNItem_Fixtures(t *testing.T) {
+	story, err := parseHNItem(testutil.Fixture(t, "hn_story.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if story.ID != 8863 || story.Type != "story" || len(story.Kids) != 32 || story.Kids[0] != 9224 {
+		t.Errorf("story = %+v", story)
+	}
+
+	comment, err := parseHNItem(testutil.Fixture(t, "hn_comment.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.By != "norvig" || !strings.HasPrefix(comment.Text, "Aw shucks") {
+		t.Errorf("comment = %+v", comment)
+	}
+
+	deleted, err := parseHNItem(testutil.Fixture(t, "hn_deleted.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.Deleted || deleted.Text != "" {
+		t.Errorf("deleted comment = %+v", deleted)
+	}
+}
+
+func TestParseHNItem_Errors(t *testing.T) {
+	if _, err := parseHNItem([]byte("null")); err == nil || egress.IsDrift(err) {
+		t.Errorf("missing item: err = %v, want an error that isn't drift", err)
+	}
+
+	drift := map[string]string{
+		"not json":    `Permission denied`,
+		"no id":       `{"by":"pg","type":"story","title":"Y Combinator"}`,
+		"kids as map": `{"id":1,"type":"story","kids":{"15":true}}`,
+		"id string":   `{"id":"8863","type":"story"}`,
+	}
+	for name, body := range drift {
+		if _, err := parseHNItem([]byte(body)); !egress.IsDrift(err) {
+			t.Errorf("%s: err = %v, want drift", name, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package news
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/data"
+)
+
+// readerRefetchAfter stops an article whose extraction came back empty
+// from being refetched on every reader view.
+const readerRefetchAfter = 10 * time.Minute
+
+var paragraphTag = regexp.MustCompile(`(?i)</?p>`)
+
+// readerParagraphs turns the text extracted by getMetadata into escaped
+// paragraphs. Extraction wraps sentence-like text in <p> and appends
+// everything else loose, so each fragment between tags becomes its own
+// paragraph.
+func readerParagraphs(content string) []string {
+	var out []string
+	for _, part := range paragraphTag.Split(content, -1) {
+		part = strings.Join(strings.Fields(part), " ")
+		if part == "" {
+			continue
+		}
+		out = append(out, htmlpkg.EscapeString(htmlpkg.UnescapeString(part)))
+	}
+	return out
+}
+
+// readerMetadata returns the cached metadata for an article, fetching it
+// again when nothing was extracted last time.
+func readerMetadata(articleURL string) (*Metadata, error) {
+	md, ok := loadCachedMetadata(articleURL)
+	if ok && (md.Content != "" || time.Since(time.Unix(0, md.Created)) < readerRefetchAfter) {
+		return md, nil
+	}
+	// A publish time of now makes getMetadata treat the cache as stale.
+	fresh, _, err := getMetadata(articleURL, time.Now())
+	if err != nil {
+		if ok {
+			return md, nil
+		}
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// handleReaderView serves /news?id=X&view=reader: the article's extracted
+// text as a plain page, so it can be read without leaving the site. Add
+// images=0 to leave out the lead image.
+func handleReaderView(w http.ResponseWriter, r *http.Request, articleID string) {
+	entry := data.GetByID(articleID)
+	if entry == nil {
+		app.NotFound(w, r, "Article not found")
+		return
+	}
+	articleURL, _ := entry.Metadata["url"].(string)
+	if articleURL == "" {
+		app.NotFound(w, r, "Article not found")
+		return
+	}
+
+	title := entry.Title
+	var paragraphs []string
+	image := ""
+	if md, err := readerMetadata(articleURL); err != nil {
+		app.Log("news", "Reader fetch failed for %s: %v", articleURL, err)
+	} else {
+		if title == "" {
+			title = md.Title
+		}
+		paragraphs = readerParagraphs(md.Content)
+		image = md.Image
+	}
+	if image == "" {
+		image, _ = entry.Metadata["image"].(string)
+	}
+
+	if app.WantsJSON(r) {
+		if paragraphs == nil {
+			paragraphs = []string{}
+		}
+		app.RespondJSON(w, map[string]interface{}{
+			"id":         articleID,
+			"title":      title,
+			"url":        articleURL,
+			"paragraphs": paragraphs,
+		})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div id="news-article" class="reader-view">`)
+	sb.WriteString(fmt.Sprintf(`<div class="article-meta">Source: <i>%s</i> · <a href="%s" target="_blank" rel="noopener noreferrer">Original →</a></div>`,
+		htmlpkg.EscapeString(getDomain(articleURL)), htmlpkg.EscapeString(articleURL)))
+	if image != "" && r.URL.Query().Get("images") != "0" {
+		sb.WriteString(fmt.Sprintf(`<img src="%s" class="article-image" referrerpolicy="no-referrer" onerror="this.style.display='none'">`, htmlpkg.EscapeString(image)))
+	}
+	if len(paragraphs) == 0 {
+		sb.WriteString(`<p class="text-muted">We couldn't extract the text of this article. Try the original link above.</p>`)
+	} else {
+		sb.WriteString(`<div class="reader-content">`)
+		for _, p := range paragraphs {
+			sb.WriteString(`<p>` + p + `</p>`)
+		}
+		sb.WriteString(`</div>`)
+	}
+	sb.WriteString(fmt.Sprintf(`<div class="article-back"><a href="/news?id=%s">← Back to article</a></div>`, htmlpkg.EscapeString(articleID)))
+	sb.WriteString(`</div>`)
+
+	w.Write([]byte(app.RenderHTMLForRequest(title, title, sb.String(), r)))
+}
@@ -0,0 +1,12 @@
+package news
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/news", Handler) // public; search needs a session
+	r.HandleFunc("/admin/feeds", FeedsHandler, app.Authenticated, app.Audited)
+}
@@ -9,6 +9,7 @@ import (
 
 	"mu/internal/ai"
 	"mu/internal/app"
+	"mu/internal/data"
 )
 
 type Sentiment struct {
@@ -44,14 +45,11 @@ func GetAllSentiments() map[string]*Sentiment {
 
 // StartSentimentLoop runs sentiment tagging every 15 minutes.
 func StartSentimentLoop() {
-	go func() {
-		time.Sleep(30 * time.Second) // let feeds load first
+	// let feeds load first
+	app.Schedule(app.Job{Name: "news.sentiment", Every: sentimentTTL, Delay: 30 * time.Second, Run: func() error {
 		tagSentiments()
-		for {
-			time.Sleep(sentimentTTL)
-			tagSentiments()
-		}
-	}()
+		return nil
+	}})
 	app.Log("news", "Sentiment tagging loop started (every %v)", sentimentTTL)
 }
 
@@ -72,6 +70,7 @@ func tagSentiments() {
 	sentimentMu.RUnlock()
 
 	if len(untagged) == 0 {
+		data.MarkWarm("news_sentiment")
 		return
 	}
 
@@ -137,10 +136,35 @@ Summary: one sentence, max 20 words.`,
 		}
 	}
 	sentimentMu.Unlock()
+	data.MarkWarm("news_sentiment")
 
 	app.Log("news", "Tagged %d articles with sentiment", len(tags))
 }
 
+// loadWarmSentiments keeps tags across restarts: an article's sentiment
+// doesn't change, so restored tags only save asking the model again.
+func loadWarmSentiments() {
+	data.RegisterWarm(data.WarmCache{
+		Name:     "news_sentiment",
+		MaxAge:   24 * time.Hour,
+		Snapshot: func() any { return GetAllSentiments() },
+		Restore: func(raw json.RawMessage, _ time.Time) error {
+			var tags map[string]*Sentiment
+			if err := json.Unmarshal(raw, &tags); err != nil {
+				return err
+			}
+			sentimentMu.Lock()
+			for url, tag := range tags {
+				if _, ok := sentimentCache[url]; !ok {
+					sentimentCache[url] = tag
+				}
+			}
+			sentimentMu.Unlock()
+			return nil
+		},
+	})
+}
+
 func truncate(s string, n int) string {
 	if len(s) <= n {
 		return s
@@ -0,0 +1,230 @@
+package news
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"mu/internal/data"
+)
+
+// Trending topics are the words and two-word phrases that recur across
+// headlines indexed in the last day. They're recomputed in the background
+// and shown as a card linking to a news search for each topic.
+
+const (
+	trendingWindow   = 24 * time.Hour
+	trendingInterval = 30 * time.Minute
+	trendingMax      = 8
+	trendingMinCount = 3 // articles a topic must appear in
+)
+
+// Topic is a trending term and how many recent articles mention it.
+type Topic struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+var (
+	trendingMu     sync.RWMutex
+	trendingTopics []Topic
+)
+
+// trendingStopWords are headline words too common to be a topic.
+var trendingStopWords = map[string]bool{
+	"new": true, "news": true, "says": true, "say": true, "year": true, "years": true,
+	"day": true, "week": true, "first": true, "how": true, "why": true, "what": true,
+	"who": true, "will": true, "could": true, "would": true, "can": true, "may": true,
+	"more": true, "than": true, "this": true, "that": true, "his": true, "her": true,
+	"they": true, "their": true, "into": true, "about": true, "out": true, "up": true,
+	"not": true, "has": true, "have": true, "was": true, "be": true, "it": true,
+	"you": true, "your": true, "we": true, "after": true, "over": true, "amid": true,
+	"ask": true, "hn": true, "show": true, "video": true, "live": true, "update": true,
+	"updates": true, "report": true, "latest": true,
+}
+
+// headlineTokens splits a headline into words, keeping the original form
+// for display alongside the lowercase form used for counting.
+func headlineTokens(title string) (keys, display []string) {
+	for _, w := range strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	}) {
+		w = strings.Trim(w, "-")
+		key := strings.ToLower(w)
+		if len(key) < 2 || titleStopWords[key] || trendingStopWords[key] {
+			// A break between words, so phrases don't span a stopword.
+			keys = append(keys, "")
+			display = append(display, "")
+			continue
+		}
+		keys = append(keys, key)
+		display = append(display, w)
+	}
+	return keys, display
+}
+
+// extractTopics counts, for each word and adjacent pair of words, the
+// number of headlines it appears in and returns the most common. A word
+// is dropped in favour of a phrase containing it that is nearly as
+// common, so "Federal Reserve" beats "Reserve".
+func extractTopics(titles []string, max int) []Topic {
+	counts := map[string]int{}
+	forms := map[string]map[string]int{} // key → display form → uses
+	for _, title := range titles {
+		keys, display := headlineTokens(title)
+		seen := map[string]bool{}
+		add := func(key, form string) {
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+			counts[key]++
+			if forms[key] == nil {
+				forms[key] = map[string]int{}
+			}
+			forms[key][form]++
+		}
+		for i, k := range keys {
+			if k == "" {
+				continue
+			}
+			if len(k) >= 3 && !isNumber(k) {
+				add(k, display[i])
+			}
+			if i+1 < len(keys) && keys[i+1] != "" {
+				add(k+" "+keys[i+1], display[i]+" "+display[i+1])
+			}
+		}
+	}
+
+	var topics []Topic
+	for key, n := range counts {
+		if n < trendingMinCount {
+			continue
+		}
+		topics = append(topics, Topic{Term: key, Count: n})
+	}
+	// Phrases first among equals, then alphabetical for a stable order.
+	sort.Slice(topics, func(i, j int) bool {
+		if topics[i].Count != topics[j].Count {
+			return topics[i].Count > topics[j].Count
+		}
+		pi, pj := strings.Contains(topics[i].Term, " "), strings.Contains(topics[j].Term, " ")
+		if pi != pj {
+			return pi
+		}
+		return topics[i].Term < topics[j].Term
+	})
+
+	var out []Topic
+	for _, t := range topics {
+		if len(out) >= max {
+			break
+		}
+		covered := false
+		for j, o := range out {
+			if phraseCovers(o, t) {
+				covered = true
+				break
+			}
+			if phraseCovers(t, o) {
+				out[j] = t // the phrase replaces the word it contains
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		out = append(out, t)
+	}
+	for i := range out {
+		out[i].Term = commonForm(forms[out[i].Term])
+	}
+	return out
+}
+
+// phraseCovers reports whether phrase a contains word b and appears in at
+// least two-thirds as many headlines.
+func phraseCovers(a, b Topic) bool {
+	if !strings.Contains(a.Term, " ") || strings.Contains(b.Term, " ") {
+		return false
+	}
+	for _, w := range strings.Fields(a.Term) {
+		if w == b.Term {
+			return a.Count*3 >= b.Count*2
+		}
+	}
+	return false
+}
+
+// commonForm picks the most used spelling, e.g. "OpenAI" over "openai".
+func commonForm(forms map[string]int) string {
+	best, bestN := "", 0
+	for f, n := range forms {
+		if n > bestN || (n == bestN && f < best) {
+			best, bestN = f, n
+		}
+	}
+	return best
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// recentHeadlines returns the titles of news articles indexed within the
+// trending window.
+func recentHeadlines(now time.Time) []string {
+	var titles []string
+	for _, e := range data.GetByType("news", 1000) {
+		if now.Sub(e.IndexedAt) > trendingWindow {
+			continue
+		}
+		if e.Title != "" {
+			titles = append(titles, e.Title)
+		}
+	}
+	return titles
+}
+
+// updateTrending recomputes the trending topics from the index.
+func updateTrending() {
+	topics := extractTopics(recentHeadlines(time.Now()), trendingMax)
+	trendingMu.Lock()
+	trendingTopics = topics
+	trendingMu.Unlock()
+}
+
+// TrendingTopics returns the current trending topics, most common first.
+func TrendingTopics() []Topic {
+	trendingMu.RLock()
+	defer trendingMu.RUnlock()
+	return append([]Topic(nil), trendingTopics...)
+}
+
+// Trending returns the trending topics card for the home page.
+func Trending() string {
+	topics := TrendingTopics()
+	if len(topics) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<div class="trending-topics">`)
+	for _, t := range topics {
+		sb.WriteString(fmt.Sprintf(`<a href="/news?query=%s" class="trending-topic">%s <span class="text-muted">%d</span></a>`,
+			url.QueryEscape(t.Term), htmlpkg.EscapeString(t.Term), t.Count))
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
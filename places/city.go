@@ -15,6 +15,8 @@ import (
 
 	"mu/internal/app"
 	"mu/internal/data"
+	"mu/internal/egress"
+	"mu/internal/netx"
 )
 
 //go:embed locations.json
@@ -32,6 +34,10 @@ type CityDef struct {
 var cities []CityDef
 var qtree *quadtree.QuadTree
 
+// cityClient fetches whole-city POI sets from Overpass. The queries are
+// heavy, so no retries: the hourly refresh will try again.
+var cityClient = netx.New("places-city", netx.Policy{Timeout: cityFetchTimeout})
+
 const (
 	maxPlacesPerCity = 2000
 	cityFetchTimeout = 60 * time.Second
@@ -130,8 +136,6 @@ func fetchMissingCities() {
 // fetchCityFromOverpass fetches major named POIs for a city from the Overpass API.
 // The query is intentionally focused on significant places to avoid huge payloads.
 func fetchCityFromOverpass(lat, lon float64, radiusM int) ([]*Place, error) {
-	client := &http.Client{Timeout: cityFetchTimeout}
-
 	// Focused on significant, named POIs to keep response size manageable
 	query := fmt.Sprintf(`[out:json][timeout:55];
 (
@@ -155,7 +159,7 @@ out center;`, radiusM, lat, lon, radiusM, lat, lon, radiusM, lat, lon, radiusM,
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("User-Agent", "Mu/1.0 (https://your-instance.com)")
 
-	resp, err := client.Do(req)
+	resp, err := cityClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("overpass city fetch failed: %w", err)
 	}
@@ -170,78 +174,18 @@ out center;`, radiusM, lat, lon, radiusM, lat, lon, radiusM, lat, lon, radiusM,
 		return nil, err
 	}
 
-	var ovResp overpassResponse
-	if err := json.Unmarshal(body, &ovResp); err != nil {
+	elements, err := parseOverpass(body)
+	if err := egress.CheckFormat("overpass", overpassFormat, err); err != nil {
 		return nil, err
 	}
 
-	places := make([]*Place, 0, min(len(ovResp.Elements), maxPlacesPerCity))
-	for _, el := range ovResp.Elements {
-		name := el.Tags["name"]
-		if name == "" {
-			continue
-		}
-
-		// Resolve coordinates: nodes have lat/lon directly; ways expose a center
-		elLat, elLon := el.Lat, el.Lon
-		if el.Center != nil && elLat == 0 && elLon == 0 {
-			elLat, elLon = el.Center.Lat, el.Center.Lon
-		}
-		if elLat == 0 && elLon == 0 {
+	places := make([]*Place, 0, min(len(elements), maxPlacesPerCity))
+	for _, el := range elements {
+		p := overpassPlace(el)
+		if p == nil {
 			continue
 		}
-
-		category := el.Tags["amenity"]
-		if category == "" {
-			category = el.Tags["tourism"]
-		}
-		if category == "" {
-			category = el.Tags["shop"]
-		}
-		if category == "" {
-			category = el.Tags["historic"]
-		}
-
-		addr := el.Tags["addr:street"]
-		if n := el.Tags["addr:housenumber"]; n != "" && addr != "" {
-			addr = n + " " + addr
-		} else if n != "" {
-			addr = n
-		}
-		if c := el.Tags["addr:city"]; c != "" {
-			if addr != "" {
-				addr += ", " + c
-			} else {
-				addr = c
-			}
-		}
-		if p := el.Tags["addr:postcode"]; p != "" {
-			addr += " " + p
-		}
-
-		phone := el.Tags["phone"]
-		if phone == "" {
-			phone = el.Tags["contact:phone"]
-		}
-		website := el.Tags["website"]
-		if website == "" {
-			website = el.Tags["contact:website"]
-		}
-		cuisine := strings.ReplaceAll(el.Tags["cuisine"], ";", ", ")
-		cuisine = strings.ReplaceAll(cuisine, "_", " ")
-
-		places = append(places, &Place{
-			ID:           fmt.Sprintf("%d", el.ID),
-			Name:         name,
-			Category:     category,
-			Address:      strings.TrimSpace(addr),
-			Lat:          elLat,
-			Lon:          elLon,
-			Phone:        phone,
-			Website:      website,
-			OpeningHours: el.Tags["opening_hours"],
-			Cuisine:      cuisine,
-		})
+		places = append(places, p)
 
 		if len(places) >= maxPlacesPerCity {
 			break
@@ -300,76 +244,16 @@ out center;`, safe, radiusM, lat, lon, safe, radiusM, lat, lon)
 		return nil, err
 	}
 
-	var ovResp overpassResponse
-	if err := json.Unmarshal(body, &ovResp); err != nil {
+	elements, err := parseOverpass(body)
+	if err := egress.CheckFormat("overpass", overpassFormat, err); err != nil {
 		return nil, err
 	}
 
 	var places []*Place
-	for _, el := range ovResp.Elements {
-		name := el.Tags["name"]
-		if name == "" {
-			continue
-		}
-		elLat, elLon := el.Lat, el.Lon
-		if el.Center != nil && elLat == 0 && elLon == 0 {
-			elLat, elLon = el.Center.Lat, el.Center.Lon
-		}
-		if elLat == 0 && elLon == 0 {
-			continue
-		}
-
-		category := el.Tags["amenity"]
-		if category == "" {
-			category = el.Tags["tourism"]
-		}
-		if category == "" {
-			category = el.Tags["shop"]
-		}
-		if category == "" {
-			category = el.Tags["historic"]
-		}
-		if category == "" {
-			category = el.Tags["leisure"]
-		}
-
-		addr := el.Tags["addr:street"]
-		if n := el.Tags["addr:housenumber"]; n != "" && addr != "" {
-			addr = n + " " + addr
-		} else if n != "" {
-			addr = n
+	for _, el := range elements {
+		if p := overpassPlace(el); p != nil {
+			places = append(places, p)
 		}
-		if c := el.Tags["addr:city"]; c != "" {
-			if addr != "" {
-				addr += ", " + c
-			} else {
-				addr = c
-			}
-		}
-
-		phone := el.Tags["phone"]
-		if phone == "" {
-			phone = el.Tags["contact:phone"]
-		}
-		website := el.Tags["website"]
-		if website == "" {
-			website = el.Tags["contact:website"]
-		}
-		cuisine := strings.ReplaceAll(el.Tags["cuisine"], ";", ", ")
-		cuisine = strings.ReplaceAll(cuisine, "_", " ")
-
-		places = append(places, &Place{
-			ID:           fmt.Sprintf("%d", el.ID),
-			Name:         name,
-			Category:     category,
-			Address:      strings.TrimSpace(addr),
-			Lat:          elLat,
-			Lon:          elLon,
-			Phone:        phone,
-			Website:      website,
-			OpeningHours: el.Tags["opening_hours"],
-			Cuisine:      cuisine,
-		})
 	}
 	return places, nil
 }
@@ -425,3 +309,77 @@ func queryLocal(lat, lon float64, radiusM int) []*Place {
 	})
 	return results
 }
+
+// overpassPlace converts an Overpass element into a place, or nil if it
+// has no name or location. A diet:halal tag of yes or only is kept as
+// halal in the cuisine, as a cuisine=halal tag would be, so the index and
+// its search find it either way.
+func overpassPlace(el overpassElement) *Place {
+	name := el.Tags["name"]
+	if name == "" {
+		return nil
+	}
+
+	// Resolve coordinates: nodes have lat/lon directly; ways expose a center
+	elLat, elLon := el.Lat, el.Lon
+	if el.Center != nil && elLat == 0 && elLon == 0 {
+		elLat, elLon = el.Center.Lat, el.Center.Lon
+	}
+	if elLat == 0 && elLon == 0 {
+		return nil
+	}
+
+	var category string
+	for _, tag := range []string{"amenity", "tourism", "shop", "historic", "leisure", "healthcare"} {
+		if category = el.Tags[tag]; category != "" {
+			break
+		}
+	}
+
+	addr := el.Tags["addr:street"]
+	if n := el.Tags["addr:housenumber"]; n != "" && addr != "" {
+		addr = n + " " + addr
+	} else if n != "" {
+		addr = n
+	}
+	if c := el.Tags["addr:city"]; c != "" {
+		if addr != "" {
+			addr += ", " + c
+		} else {
+			addr = c
+		}
+	}
+	if p := el.Tags["addr:postcode"]; p != "" {
+		addr += " " + p
+	}
+
+	phone := el.Tags["phone"]
+	if phone == "" {
+		phone = el.Tags["contact:phone"]
+	}
+	website := el.Tags["website"]
+	if website == "" {
+		website = el.Tags["contact:website"]
+	}
+	cuisine := strings.ReplaceAll(el.Tags["cuisine"], ";", ", ")
+	cuisine = strings.ReplaceAll(cuisine, "_", " ")
+	if halal := el.Tags["diet:halal"]; (halal == "yes" || halal == "only") && !strings.Contains(strings.ToLower(cuisine), "halal") {
+		if cuisine != "" {
+			cuisine += ", "
+		}
+		cuisine += "halal"
+	}
+
+	return &Place{
+		ID:           fmt.Sprintf("%d", el.ID),
+		Name:         name,
+		Category:     category,
+		Address:      strings.TrimSpace(addr),
+		Lat:          elLat,
+		Lon:          elLon,
+		Phone:        phone,
+		Website:      website,
+		OpeningHours: el.Tags["opening_hours"],
+		Cuisine:      cuisine,
+	}
+}
@@ -0,0 +1,566 @@
+package places
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Community places are imported by admins from lists people keep
+// themselves — local businesses, mosques — as CSV or GeoJSON. They're kept
+// in the data store and in the index with the "community" source, and
+// merged into search results, so they turn up even where OSM and Google
+// don't have them.
+
+// communitySource is the source of imported places.
+const communitySource = "community"
+
+const (
+	maxImportSize     = 5 << 20
+	maxImportRows     = 5000
+	maxImportGeocodes = 100 // Nominatim asks for no more than one a second
+	maxPlaceNameLen   = 200
+)
+
+var (
+	communityMu     sync.RWMutex
+	communityPlaces = map[string]*Place{} // ID -> place
+)
+
+func loadCommunity() {
+	var d map[string]*Place
+	if err := data.LoadJSON("places_community.json", &d); err == nil && d != nil {
+		communityMu.Lock()
+		communityPlaces = d
+		communityMu.Unlock()
+	}
+	// The index can be wiped by a schema change; the store is the record.
+	go indexPlaces(CommunityPlaces())
+}
+
+// CommunityPlaces returns every imported place, by name.
+func CommunityPlaces() []*Place {
+	communityMu.RLock()
+	defer communityMu.RUnlock()
+	out := make([]*Place, 0, len(communityPlaces))
+	for _, p := range communityPlaces {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out
+}
+
+// communityID is stable for a name at a spot, so importing a list again
+// updates its places rather than adding them twice.
+func communityID(name string, lat, lon float64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%.4f,%.4f", strings.ToLower(strings.TrimSpace(name)), lat, lon)))
+	return "community:" + hex.EncodeToString(sum[:6])
+}
+
+// saveCommunity adds or updates places and indexes them.
+func saveCommunity(places []*Place) {
+	communityMu.Lock()
+	for _, p := range places {
+		communityPlaces[p.ID] = p
+	}
+	data.SaveJSON("places_community.json", communityPlaces)
+	communityMu.Unlock()
+	indexPlaces(places)
+}
+
+// DeleteCommunityPlace removes an imported place from the store and the
+// index.
+func DeleteCommunityPlace(id string) error {
+	communityMu.Lock()
+	if _, ok := communityPlaces[id]; !ok {
+		communityMu.Unlock()
+		return errors.New("not a community place")
+	}
+	delete(communityPlaces, id)
+	data.SaveJSON("places_community.json", communityPlaces)
+	communityMu.Unlock()
+	return deleteIndexedPlace(id)
+}
+
+// communityMatches returns the imported places matching every word of
+// query (any, if it's empty) within radiusM of lat, lon, or anywhere if
+// radiusM is 0.
+func communityMatches(query string, lat, lon float64, radiusM int) []*Place {
+	words := strings.Fields(strings.ToLower(query))
+	communityMu.RLock()
+	defer communityMu.RUnlock()
+	var out []*Place
+	for _, p := range communityPlaces {
+		text := strings.ToLower(placeText(p))
+		match := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		c := *p
+		if radiusM > 0 {
+			if c.Distance = haversine(lat, lon, c.Lat, c.Lon); c.Distance > float64(radiusM) {
+				continue
+			}
+		}
+		out = append(out, &c)
+	}
+	return out
+}
+
+// withCommunity adds the matching imported places the results don't
+// already have. With radiusM above 0 they're kept in distance order.
+func withCommunity(results []*Place, query string, lat, lon float64, radiusM int) []*Place {
+	extra := communityMatches(query, lat, lon, radiusM)
+	if len(extra) == 0 {
+		return results
+	}
+	have := map[string]bool{}
+	for _, p := range results {
+		have[placeKey(p)] = true
+	}
+	for _, p := range extra {
+		if !have[placeKey(p)] {
+			results = append(results, p)
+		}
+	}
+	if radiusM > 0 {
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	}
+	return results
+}
+
+// importRow is one place as read from a file, before it's checked.
+type importRow struct {
+	Line   int // the CSV line or GeoJSON feature number
+	Fields map[string]string
+	Lat    *float64
+	Lon    *float64
+	Err    string // why the row can't be used, found while reading it
+}
+
+// ImportError is a row that couldn't be imported.
+type ImportError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportReport describes what an import did, or would do on a dry run.
+type ImportReport struct {
+	Imported int           `json:"imported"`
+	Geocoded int           `json:"geocoded"`
+	Errors   []ImportError `json:"errors"`
+	DryRun   bool          `json:"dry_run"`
+	Places   []*Place      `json:"places,omitempty"`
+}
+
+// importColumns maps the column and property names lists use to fields.
+var importColumns = map[string]string{
+	"name": "name", "title": "name",
+	"category": "category", "type": "category", "amenity": "category",
+	"address": "address", "addr": "address",
+	"lat": "lat", "latitude": "lat",
+	"lon": "lon", "lng": "lon", "long": "lon", "longitude": "lon",
+	"phone": "phone", "telephone": "phone",
+	"website": "website", "url": "website",
+	"opening_hours": "opening_hours", "hours": "opening_hours",
+	"cuisine": "cuisine",
+}
+
+// importField normalises a column name, or returns "" for one that isn't
+// kept.
+func importField(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	return importColumns[name]
+}
+
+// parseImportCSV reads rows from a CSV with a header line.
+func parseImportCSV(b []byte) ([]importRow, error) {
+	rd := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))))
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+	header, err := rd.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading the header: %w", err)
+	}
+	cols := make([]string, len(header))
+	hasName := false
+	for i, h := range header {
+		cols[i] = importField(h)
+		hasName = hasName || cols[i] == "name"
+	}
+	if !hasName {
+		return nil, errors.New("the header has no name column")
+	}
+
+	var rows []importRow
+	for {
+		rec, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("more than %d rows", maxImportRows)
+		}
+		line, _ := rd.FieldPos(0)
+		row := importRow{Line: line, Fields: map[string]string{}}
+		for i, v := range rec {
+			if i < len(cols) && cols[i] != "" {
+				row.Fields[cols[i]] = strings.TrimSpace(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseImportGeoJSON reads rows from a FeatureCollection of points.
+// Features without a geometry are geocoded from their address.
+func parseImportGeoJSON(b []byte) ([]importRow, error) {
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry *struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, errors.New("expected a GeoJSON FeatureCollection")
+	}
+	if len(fc.Features) > maxImportRows {
+		return nil, fmt.Errorf("more than %d features", maxImportRows)
+	}
+
+	rows := make([]importRow, 0, len(fc.Features))
+	for i, f := range fc.Features {
+		row := importRow{Line: i + 1, Fields: map[string]string{}}
+		for k, v := range f.Properties {
+			if field := importField(k); field != "" && v != nil {
+				row.Fields[field] = strings.TrimSpace(fmt.Sprint(v))
+			}
+		}
+		if g := f.Geometry; g != nil {
+			var pos []float64
+			if g.Type != "Point" || json.Unmarshal(g.Coordinates, &pos) != nil || len(pos) < 2 {
+				row.Err = "only Point geometries can be imported"
+			} else {
+				// GeoJSON puts longitude first.
+				lon, lat := pos[0], pos[1]
+				row.Lat, row.Lon = &lat, &lon
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// importPlace checks a row and makes a place of it, looking up its
+// address with locate if it has no coordinates.
+func importPlace(row importRow, locate func(string) (float64, float64, error)) (*Place, bool, error) {
+	if row.Err != "" {
+		return nil, false, errors.New(row.Err)
+	}
+	f := row.Fields
+	name := f["name"]
+	if name == "" {
+		return nil, false, errors.New("name is required")
+	}
+	if len(name) > maxPlaceNameLen {
+		return nil, false, fmt.Errorf("name is longer than %d characters", maxPlaceNameLen)
+	}
+
+	lat, lon := row.Lat, row.Lon
+	if lat == nil && (f["lat"] != "" || f["lon"] != "") {
+		la, errLat := strconv.ParseFloat(f["lat"], 64)
+		lo, errLon := strconv.ParseFloat(f["lon"], 64)
+		if errLat != nil || errLon != nil {
+			return nil, false, errors.New("lat and lon must both be numbers")
+		}
+		lat, lon = &la, &lo
+	}
+	geocoded := false
+	if lat == nil {
+		if f["address"] == "" {
+			return nil, false, errors.New("needs coordinates or an address")
+		}
+		la, lo, err := locate(f["address"])
+		if err != nil {
+			return nil, false, err
+		}
+		lat, lon, geocoded = &la, &lo, true
+	}
+	if *lat < -90 || *lat > 90 || *lon < -180 || *lon > 180 || (*lat == 0 && *lon == 0) {
+		return nil, false, fmt.Errorf("%g, %g is not a valid location", *lat, *lon)
+	}
+
+	website := f["website"]
+	if website != "" {
+		if !strings.Contains(website, "://") {
+			website = "https://" + website
+		}
+		if u, err := url.Parse(website); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, false, errors.New("website is not a web address")
+		}
+	}
+
+	return &Place{
+		ID:           communityID(name, *lat, *lon),
+		Name:         name,
+		Category:     strings.ReplaceAll(strings.ToLower(f["category"]), " ", "_"),
+		Address:      f["address"],
+		Lat:          *lat,
+		Lon:          *lon,
+		Phone:        f["phone"],
+		Website:      website,
+		OpeningHours: f["opening_hours"],
+		Cuisine:      f["cuisine"],
+		Source:       communitySource,
+	}, geocoded, nil
+}
+
+// importPlaces checks and geocodes rows, then saves them unless dryRun.
+func importPlaces(rows []importRow, dryRun bool) *ImportReport {
+	report := &ImportReport{DryRun: dryRun, Errors: []ImportError{}}
+	geocodes := 0
+	lookup := func(address string) (float64, float64, error) {
+		if geocodes == maxImportGeocodes {
+			return 0, 0, fmt.Errorf("over the limit of %d addresses to look up in one import; add coordinates", maxImportGeocodes)
+		}
+		geocodes++
+		lat, lon, err := geocode(address)
+		if err != nil {
+			return 0, 0, errors.New("address not found")
+		}
+		return lat, lon, nil
+	}
+
+	seen := map[string]int{}
+	var places []*Place
+	for _, row := range rows {
+		p, geocoded, err := importPlace(row, lookup)
+		if err != nil {
+			report.Errors = append(report.Errors, ImportError{Row: row.Line, Error: err.Error()})
+			continue
+		}
+		if first, dup := seen[p.ID]; dup {
+			report.Errors = append(report.Errors, ImportError{Row: row.Line, Error: fmt.Sprintf("same place as row %d", first)})
+			continue
+		}
+		seen[p.ID] = row.Line
+		if geocoded {
+			report.Geocoded++
+		}
+		places = append(places, p)
+	}
+	report.Imported = len(places)
+	if dryRun {
+		report.Places = places
+	} else if len(places) > 0 {
+		saveCommunity(places)
+	}
+	return report
+}
+
+// readImport returns the uploaded file and whether it's GeoJSON, from
+// the "file" field of a form or the request body.
+func readImport(w http.ResponseWriter, r *http.Request) ([]byte, bool, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	var b []byte
+	var name string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportSize); err != nil {
+			return nil, false, fmt.Errorf("the file is larger than %d MB", maxImportSize>>20)
+		}
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			return nil, false, errors.New("choose a CSV or GeoJSON file")
+		}
+		defer f.Close()
+		if b, err = io.ReadAll(io.LimitReader(f, maxImportSize+1)); err != nil {
+			return nil, false, err
+		}
+		name = fh.Filename
+	} else {
+		var err error
+		if b, err = io.ReadAll(r.Body); err != nil {
+			return nil, false, fmt.Errorf("the file is larger than %d MB", maxImportSize>>20)
+		}
+	}
+	if len(b) > maxImportSize {
+		return nil, false, fmt.Errorf("the file is larger than %d MB", maxImportSize>>20)
+	}
+
+	switch format := r.FormValue("format"); {
+	case format == "csv":
+		return b, false, nil
+	case format == "geojson":
+		return b, true, nil
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return b, false, nil
+	case ".geojson", ".json":
+		return b, true, nil
+	}
+	ct := r.Header.Get("Content-Type")
+	isJSON := strings.Contains(ct, "json") || bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+	return b, isJSON, nil
+}
+
+// handleImport serves /places/import: the admin's upload form, and POST
+// with a CSV or GeoJSON list of places. dry_run checks the list without
+// saving it.
+func handleImport(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"places": CommunityPlaces()})
+			return
+		}
+		renderImportPage(w, r, nil)
+		return
+	case http.MethodPost:
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	b, isJSON, err := readImport(w, r)
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return
+	}
+	var rows []importRow
+	if isJSON {
+		rows, err = parseImportGeoJSON(b)
+	} else {
+		rows, err = parseImportCSV(b)
+	}
+	if err != nil {
+		app.BadRequest(w, r, "Could not read the file: "+err.Error())
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.FormValue("dry_run"))
+	report := importPlaces(rows, dryRun)
+	if !dryRun {
+		app.Audit(acc.ID, "places import", fmt.Sprintf("%d places", report.Imported), r.URL.Path)
+	}
+
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, report)
+		return
+	}
+	renderImportPage(w, r, report)
+}
+
+// handleImportDelete serves POST /places/import/delete, an admin removing
+// an imported place.
+func handleImportDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Admin access required")
+		return
+	}
+	id := parseRequestParams(r)("id")
+	if err := DeleteCommunityPlace(id); err != nil {
+		app.NotFound(w, r, "Community place not found")
+		return
+	}
+	app.Audit(acc.ID, "places delete_community", id, r.URL.Path)
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"id": id, "deleted": true})
+		return
+	}
+	http.Redirect(w, r, "/places/import", http.StatusSeeOther)
+}
+
+// renderImportPage renders the upload form, the report of an import if
+// there is one, and the places imported so far.
+func renderImportPage(w http.ResponseWriter, r *http.Request, report *ImportReport) {
+	var sb strings.Builder
+	sb.WriteString(`<div class="places-page">`)
+	sb.WriteString(`<p><a href="/places">&larr; Back to Places</a></p>`)
+	sb.WriteString(`<p class="text-muted">Add places from a community-kept list. A CSV needs a header with a <code>name</code> column and either <code>lat</code>/<code>lon</code> or an <code>address</code> to look up; <code>category</code>, <code>phone</code>, <code>website</code>, <code>opening_hours</code> and <code>cuisine</code> are kept too. GeoJSON takes a FeatureCollection of points with the same properties. Importing a list again updates its places.</p>`)
+	sb.WriteString(`<form class="card" action="/places/import" method="POST" enctype="multipart/form-data">
+  <input type="file" name="file" accept=".csv,.geojson,.json" required>
+  <label class="text-sm"><input type="checkbox" name="dry_run" value="true"> Check only, don't import</label>
+  <button type="submit">Import</button>
+</form>`)
+
+	if report != nil {
+		verb := "Imported"
+		if report.DryRun {
+			verb = "Ready to import"
+		}
+		sb.WriteString(fmt.Sprintf(`<h3>%s %d place(s)</h3>`, verb, report.Imported))
+		if report.Geocoded > 0 {
+			sb.WriteString(fmt.Sprintf(`<p class="text-muted">%d located from their address.</p>`, report.Geocoded))
+		}
+		if len(report.Errors) > 0 {
+			sb.WriteString(fmt.Sprintf(`<p class="text-error">%d row(s) skipped:</p><ul class="text-sm">`, len(report.Errors)))
+			for _, e := range report.Errors {
+				sb.WriteString(fmt.Sprintf(`<li>Row %d: %s</li>`, e.Row, escapeHTML(e.Error)))
+			}
+			sb.WriteString(`</ul>`)
+		}
+		if report.DryRun && len(report.Places) > 0 {
+			sb.WriteString(renderLeafletMap(report.Places[0].Lat, report.Places[0].Lon, report.Places))
+		}
+	}
+
+	list := CommunityPlaces()
+	sb.WriteString(fmt.Sprintf(`<h3>Community places (%d)</h3>`, len(list)))
+	for _, p := range list {
+		sb.WriteString(fmt.Sprintf(`<p><a href="%s">%s</a> <span class="text-muted text-sm">%s</span>
+<form class="d-inline" action="/places/import/delete" method="POST"><input type="hidden" name="id" value="%s"><button type="submit" class="btn-link">Remove</button></form></p>`,
+			escapeHTML(viewURL(p.ID)), escapeHTML(p.Name), escapeHTML(p.Address), escapeHTML(p.ID)))
+	}
+	sb.WriteString(`</div>`)
+
+	app.Respond(w, r, app.Response{
+		Title:       "Import places",
+		Description: "Import community places",
+		HTML:        sb.String(),
+	})
+}
@@ -0,0 +1,163 @@
+package places
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+func TestParseImport(t *testing.T) {
+	rows, err := parseImportCSV([]byte("Name,Latitude,Longitude,Type,Website\n" +
+		"East London Mosque,51.5175,-0.0653,place of worship,eastlondonmosque.org.uk\n" +
+		"\"Multi\nline\",51.5,-0.1,,\n" +
+		",51.5,-0.1,cafe,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0].Fields["name"] != "East London Mosque" || rows[0].Fields["lat"] != "51.5175" || rows[2].Line != 5 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	p, _, err := importPlace(rows[0], nil)
+	if err != nil || p.Category != "place_of_worship" || p.Website != "https://eastlondonmosque.org.uk" || p.Source != communitySource {
+		t.Fatalf("place = %+v, %v", p, err)
+	}
+	if _, _, err := importPlace(rows[2], nil); err == nil {
+		t.Error("row without a name accepted")
+	}
+	if _, err := parseImportCSV([]byte("title_missing,lat\nx,1\n")); err == nil {
+		t.Error("CSV without a name column accepted")
+	}
+
+	rows, err = parseImportGeoJSON([]byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[-0.0653,51.5175]},"properties":{"name":"Mosque","phone":"020 7650 3000"}},
+		{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,1],[1,0],[0,0]]]},"properties":{"name":"Park"}},
+		{"type":"Feature","geometry":null,"properties":{"name":"Butcher","address":"1 High St"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, err := importPlace(rows[0], nil); err != nil || p.Lat != 51.5175 || p.Lon != -0.0653 || p.Phone != "020 7650 3000" {
+		t.Errorf("point = %+v, %v", p, err)
+	}
+	if _, _, err := importPlace(rows[1], nil); err == nil {
+		t.Error("polygon accepted")
+	}
+	located := func(string) (float64, float64, error) { return 51.4, -0.2, nil }
+	if p, geocoded, err := importPlace(rows[2], located); err != nil || !geocoded || p.Lat != 51.4 {
+		t.Errorf("geocoded = %+v, %v", p, err)
+	}
+}
+
+func TestImportCommunityPlaces(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	placesDBOne = sync.Once{}
+	placesDB = nil
+
+	communityMu.Lock()
+	orig := communityPlaces
+	communityPlaces = map[string]*Place{}
+	communityMu.Unlock()
+	defer func() {
+		communityMu.Lock()
+		communityPlaces = orig
+		communityMu.Unlock()
+	}()
+
+	sessions := map[string]string{}
+	for _, a := range []*auth.Account{
+		{ID: "import_admin", Name: "Admin", Secret: "secret", Admin: true, Created: time.Now()},
+		{ID: "import_user", Name: "User", Secret: "secret", Created: time.Now()},
+	} {
+		if err := auth.Create(a); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(a.ID)
+		sess, err := auth.Login(a.ID, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[a.ID] = sess.Token
+	}
+
+	upload := func(user, filename, content, dryRun string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", filename)
+		fw.Write([]byte(content))
+		mw.WriteField("dry_run", dryRun)
+		mw.Close()
+		r := httptest.NewRequest("POST", "/places/import", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: "session", Value: sessions[user]})
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+	list := "name,lat,lon,category\nNoor Mosque,51.5,-0.12,place_of_worship\nNoor Mosque,51.5,-0.12,place_of_worship\nNowhere,95,0,\n"
+
+	if w := upload("import_user", "list.csv", list, "false"); w.Code != http.StatusForbidden {
+		t.Fatalf("member imported: %d", w.Code)
+	}
+
+	var report ImportReport
+	w := upload("import_admin", "list.csv", list, "true")
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Imported != 1 || len(report.Errors) != 2 || len(CommunityPlaces()) != 0 {
+		t.Fatalf("dry run = %+v", report)
+	}
+
+	w = upload("import_admin", "list.csv", list, "false")
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 1 || len(CommunityPlaces()) != 1 {
+		t.Fatalf("import = %+v", report)
+	}
+	p := CommunityPlaces()[0]
+	// Let the place's embedding land before the data dir is removed.
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if m := data.SemanticSearch("Noor Mosque", 1, data.WithType("place")); len(m) > 0 {
+			break
+		}
+	}
+
+	// Indexed with its source, and found wherever other sources miss it.
+	if got, err := getIndexedPlace(p.ID); err != nil || got == nil || got.Source != communitySource {
+		t.Fatalf("indexed = %+v, %v", got, err)
+	}
+	if got := withCommunity(nil, "mosque", 51.501, -0.12, 500); len(got) != 1 || got[0].ID != p.ID {
+		t.Errorf("nearby = %v", got)
+	}
+	if got := withCommunity([]*Place{{ID: p.ID}}, "", 51.501, -0.12, 500); len(got) != 1 {
+		t.Error("a place already found was added again")
+	}
+	if got := withCommunity(nil, "cafe", 51.501, -0.12, 500); len(got) != 0 {
+		t.Error("a place that doesn't match was added")
+	}
+	if !strings.Contains(renderPlaceCard(p, nil), "Community") {
+		t.Error("card has no community badge")
+	}
+
+	r := httptest.NewRequest("POST", "/places/import/delete", strings.NewReader("id="+p.ID))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: "session", Value: sessions["import_admin"]})
+	w = httptest.NewRecorder()
+	Handler(w, r)
+	if w.Code != http.StatusSeeOther || len(CommunityPlaces()) != 0 {
+		t.Fatalf("delete: %d", w.Code)
+	}
+	if got, _ := getIndexedPlace(p.ID); got != nil {
+		t.Error("deleted place is still indexed")
+	}
+}
@@ -0,0 +1,344 @@
+package places
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/netx"
+)
+
+// Place embeds. A post mentions a place with a shortcode
+//
+//	[place: British Museum @ 51.5194,-0.1270]
+//
+// which renders as a compact card: the name, a map thumbnail served
+// through the tile proxy, and a directions link. Every embed is recorded
+// against the place so /places/place can list the posts mentioning it.
+
+var embedPattern = regexp.MustCompile(`\[place:\s*([^\]@<>\n]{1,100}?)\s*@\s*(-?\d{1,3}(?:\.\d+)?)\s*,\s*(-?\d{1,3}(?:\.\d+)?)\s*\]`)
+
+// Embed is a place referenced from a post.
+type Embed struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+// Key identifies the place across posts: coordinates to about 10m.
+func (e Embed) Key() string {
+	return fmt.Sprintf("%.4f,%.4f", e.Lat, e.Lon)
+}
+
+// Shortcode returns the text to paste into a post to embed the place.
+func (e Embed) Shortcode() string {
+	return fmt.Sprintf("[place: %s @ %.5f,%.5f]", e.Name, e.Lat, e.Lon)
+}
+
+// parseEmbed converts a shortcode match into an Embed, rejecting
+// coordinates off the map.
+func parseEmbed(m []string) (Embed, bool) {
+	lat, err1 := strconv.ParseFloat(m[2], 64)
+	lon, err2 := strconv.ParseFloat(m[3], 64)
+	if err1 != nil || err2 != nil || lat < -85 || lat > 85 || lon < -180 || lon > 180 {
+		return Embed{}, false
+	}
+	return Embed{Name: strings.TrimSpace(html.UnescapeString(m[1])), Lat: lat, Lon: lon}, true
+}
+
+// ParseEmbeds returns the places embedded in text, each once.
+func ParseEmbeds(text string) []Embed {
+	var out []Embed
+	seen := map[string]bool{}
+	for _, m := range embedPattern.FindAllStringSubmatch(text, -1) {
+		e, ok := parseEmbed(m)
+		if !ok || seen[e.Key()] {
+			continue
+		}
+		seen[e.Key()] = true
+		out = append(out, e)
+	}
+	return out
+}
+
+// RenderEmbeds replaces place shortcodes in rendered HTML with place cards.
+func RenderEmbeds(htmlText string) string {
+	return embedPattern.ReplaceAllStringFunc(htmlText, func(s string) string {
+		e, ok := parseEmbed(embedPattern.FindStringSubmatch(s))
+		if !ok {
+			return s
+		}
+		return renderEmbedCard(e)
+	})
+}
+
+// StripEmbeds replaces place shortcodes with the place name, for previews
+// and plain text.
+func StripEmbeds(text string) string {
+	return embedPattern.ReplaceAllString(text, "📍 $1")
+}
+
+// tileZoom is the zoom level of embed thumbnails: street level.
+const tileZoom = 15
+
+// tileFor returns the tile containing a point and the point's position
+// within it as percentages, for placing the pin.
+func tileFor(lat, lon float64, z int) (x, y int, px, py float64) {
+	n := math.Exp2(float64(z))
+	fx := (lon + 180) / 360 * n
+	rad := lat * math.Pi / 180
+	fy := (1 - math.Log(math.Tan(rad)+1/math.Cos(rad))/math.Pi) / 2 * n
+	x, y = int(fx), int(fy)
+	return x, y, (fx - float64(x)) * 100, (fy - float64(y)) * 100
+}
+
+func directionsURL(e Embed) string {
+	return fmt.Sprintf("https://www.google.com/maps/dir/?api=1&destination=%.6f,%.6f", e.Lat, e.Lon)
+}
+
+func placePageURL(e Embed) string {
+	return fmt.Sprintf("/places/place?lat=%.5f&lon=%.5f&name=%s", e.Lat, e.Lon, url.QueryEscape(e.Name))
+}
+
+func renderEmbedCard(e Embed) string {
+	x, y, px, py := tileFor(e.Lat, e.Lon, tileZoom)
+	// Spans, not divs: the shortcode usually sits inside a paragraph.
+	return fmt.Sprintf(`<span class="place-embed">
+  <a href="%s" class="place-embed-map"><img src="/tiles/%d/%d/%d.png" alt="" loading="lazy"><span class="place-embed-pin" style="left:%.1f%%;top:%.1f%%"></span></a>
+  <span class="place-embed-info">
+    <a href="%s" class="place-embed-name">%s</a>
+    <a href="%s" target="_blank" rel="noopener" class="text-sm">Directions &#8599;</a>
+  </span>
+</span>`, placePageURL(e), tileZoom, x, y, px, py, placePageURL(e), escapeHTML(e.Name), directionsURL(e))
+}
+
+// Mention records a post embedding a place.
+type Mention struct {
+	Type  string    `json:"type"`
+	ID    string    `json:"id"`
+	Title string    `json:"title"`
+	URL   string    `json:"url"`
+	Name  string    `json:"name"` // place name as the post wrote it
+	Time  time.Time `json:"time"`
+}
+
+var (
+	mentionsMu sync.RWMutex
+	mentions   = map[string][]Mention{} // place key → mentions
+)
+
+func loadMentions() {
+	mentionsMu.Lock()
+	defer mentionsMu.Unlock()
+	data.LoadJSON("place_mentions.json", &mentions)
+	if mentions == nil {
+		mentions = map[string][]Mention{}
+	}
+}
+
+// SetMentions records the places embedded in an item, replacing what was
+// recorded for it before. Pass empty text when the item is deleted or
+// made private.
+func SetMentions(itemType, itemID, title, itemURL, text string) {
+	embeds := ParseEmbeds(text)
+	mentionsMu.Lock()
+	defer mentionsMu.Unlock()
+	changed := false
+	for key, list := range mentions {
+		kept := list[:0]
+		for _, m := range list {
+			if m.Type == itemType && m.ID == itemID {
+				changed = true
+				continue
+			}
+			kept = append(kept, m)
+		}
+		if len(kept) == 0 {
+			delete(mentions, key)
+		} else {
+			mentions[key] = kept
+		}
+	}
+	for _, e := range embeds {
+		mentions[e.Key()] = append(mentions[e.Key()], Mention{
+			Type: itemType, ID: itemID, Title: title, URL: itemURL, Name: e.Name, Time: time.Now(),
+		})
+		changed = true
+	}
+	if changed {
+		data.SaveJSON("place_mentions.json", mentions)
+	}
+}
+
+// MentionsOf returns the items embedding the place, newest first.
+func MentionsOf(e Embed) []Mention {
+	mentionsMu.RLock()
+	out := append([]Mention(nil), mentions[e.Key()]...)
+	mentionsMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.After(out[j].Time) })
+	return out
+}
+
+// handlePlacePage serves /places/place?lat=&lon=&name=: the place card
+// and the posts that mention it.
+func handlePlacePage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lat, err1 := strconv.ParseFloat(q.Get("lat"), 64)
+	lon, err2 := strconv.ParseFloat(q.Get("lon"), 64)
+	if err1 != nil || err2 != nil || lat < -85 || lat > 85 || lon < -180 || lon > 180 {
+		app.BadRequest(w, r, "lat and lon are required")
+		return
+	}
+	e := Embed{Name: strings.TrimSpace(q.Get("name")), Lat: lat, Lon: lon}
+	list := MentionsOf(e)
+	if e.Name == "" && len(list) > 0 {
+		e.Name = list[0].Name
+	}
+	if e.Name == "" {
+		e.Name = fmt.Sprintf("%.5f, %.5f", lat, lon)
+	}
+
+	if app.WantsJSON(r) {
+		if list == nil {
+			list = []Mention{}
+		}
+		reviewList := Reviews(lat, lon)
+		if reviewList == nil {
+			reviewList = []*Review{}
+		}
+		avg, count := Rating(lat, lon)
+		app.RespondJSON(w, map[string]interface{}{
+			"place":        e,
+			"shortcode":    e.Shortcode(),
+			"mentioned":    list,
+			"rating":       avg,
+			"review_count": count,
+			"reviews":      reviewList,
+		})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(renderLeafletMap(lat, lon, []*Place{{Name: e.Name, Lat: lat, Lon: lon}}))
+	sb.WriteString(fmt.Sprintf(`<p class="place-links"><a href="%s" target="_blank" rel="noopener">Get Directions</a> &middot; <a href="/places/nearby?lat=%.5f&lon=%.5f">What's nearby</a></p>`,
+		directionsURL(e), lat, lon))
+	sb.WriteString(renderRatingSummary(lat, lon, e.Name))
+	sb.WriteString(`<h3>Mentioned in</h3>`)
+	if len(list) == 0 {
+		sb.WriteString(`<p class="text-muted">No posts mention this place yet.</p>`)
+	}
+	for _, m := range list {
+		title := m.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		sb.WriteString(fmt.Sprintf(`<p><a href="%s">%s</a> <span class="text-muted text-sm">%s</span></p>`,
+			escapeHTML(m.URL), escapeHTML(title), app.TimeAgo(m.Time)))
+	}
+	_, acc := auth.TrySession(r)
+	sb.WriteString(renderReviewsSection(e, "", acc, q.Get("error")))
+	sb.WriteString(fmt.Sprintf(`<p class="text-muted text-sm mt-5">Embed in a post: <code>%s</code></p>`, escapeHTML(e.Shortcode())))
+
+	app.Respond(w, r, app.Response{
+		Title:       e.Name,
+		Description: "Posts mentioning " + e.Name,
+		HTML:        sb.String(),
+	})
+}
+
+// tileClient fetches map tiles for the maps and embed thumbnails. Tiles
+// rarely change, so they're cached for a day.
+var tileClient = netx.New("tiles", netx.Policy{
+	Timeout:  10 * time.Second,
+	Retries:  1,
+	CacheTTL: 24 * time.Hour,
+	Budget:   600,
+})
+
+const tileUpstream = "https://basemaps.cartocdn.com/light_all/%d/%d/%d.png"
+
+// handleTile serves /tiles/{z}/{x}/{y}.png, and /places/tile/ for embeds
+// already in posts, from the upstream tile server, so readers' browsers
+// don't contact it directly. Both are rate limited per IP; see
+// app.RateRule.
+func handleTile(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/places/tile/"), "/tiles/")
+	var z, x, y int
+	if _, err := fmt.Sscanf(path, "%d/%d/%d.png", &z, &x, &y); err != nil {
+		app.NotFound(w, r, "")
+		return
+	}
+	max := 1 << uint(z)
+	if z < 0 || z > 19 || x < 0 || y < 0 || x >= max || y >= max {
+		app.NotFound(w, r, "")
+		return
+	}
+	resp, err := tileClient.Get(fmt.Sprintf(tileUpstream, z, x, y))
+	if err != nil {
+		app.RespondError(w, http.StatusBadGateway, "tile unavailable")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		app.RespondError(w, http.StatusBadGateway, "tile unavailable")
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	io.Copy(w, io.LimitReader(resp.Body, 1<<20))
+}
+
+// PickerHTML returns a place search box that inserts a shortcode into the
+// textarea with the given ID.
+func PickerHTML(textareaID string) string {
+	return fmt.Sprintf(`<details class="place-picker">
+  <summary class="text-sm text-muted">Add a place</summary>
+  <input type="text" class="place-picker-q" placeholder="Search for a place">
+  <div class="place-picker-results text-sm"></div>
+</details>
+<script>
+(function(){
+  var box = document.currentScript.previousElementSibling;
+  var q = box.querySelector('.place-picker-q'), out = box.querySelector('.place-picker-results');
+  var timer;
+  q.addEventListener('input', function(){
+    clearTimeout(timer);
+    if (q.value.trim().length < 3) { out.innerHTML = ''; return; }
+    timer = setTimeout(function(){
+      fetch('/places?q=' + encodeURIComponent(q.value.trim()), {headers: {'Accept': 'application/json'}})
+        .then(function(r){ return r.json(); })
+        .then(function(d){
+          out.innerHTML = '';
+          (d.results || []).slice(0, 5).forEach(function(p){
+            var a = document.createElement('a');
+            a.href = '#';
+            a.textContent = p.name + (p.address ? ' — ' + p.address : '');
+            a.onclick = function(e){
+              e.preventDefault();
+              var ta = document.getElementById(%s);
+              var code = '[place: ' + p.name.replace(/[\[\]@<>]/g, '') + ' @ ' + p.lat.toFixed(5) + ',' + p.lon.toFixed(5) + ']';
+              ta.value += (ta.value && !/\n$/.test(ta.value) ? '\n\n' : '') + code + '\n';
+              ta.dispatchEvent(new Event('input'));
+              out.innerHTML = ''; q.value = ''; box.open = false;
+            };
+            out.appendChild(a);
+            out.appendChild(document.createElement('br'));
+          });
+        });
+    }, 400);
+  });
+})();
+</script>`, jsonStr(textareaID))
+}
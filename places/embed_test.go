@@ -0,0 +1,51 @@
+package places
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAndRenderEmbeds(t *testing.T) {
+	text := "Lunch at [place: Dishoom & Co @ 51.5246,-0.1240] then [place: Off the map @ 95,0]."
+	embeds := ParseEmbeds(text)
+	if len(embeds) != 1 || embeds[0].Name != "Dishoom & Co" || embeds[0].Lat != 51.5246 {
+		t.Fatalf("ParseEmbeds = %+v", embeds)
+	}
+
+	// Markdown has already escaped the ampersand by the time cards render.
+	got := RenderEmbeds("<p>Lunch at [place: Dishoom &amp; Co @ 51.5246,-0.1240]</p>")
+	if !strings.Contains(got, `class="place-embed"`) || !strings.Contains(got, "Dishoom &amp; Co") || !strings.Contains(got, "/tiles/15/") {
+		t.Errorf("RenderEmbeds = %s", got)
+	}
+	if got := StripEmbeds(text); strings.Contains(got, "[place:") && strings.Contains(got, "Dishoom") {
+		t.Errorf("StripEmbeds left the shortcode: %s", got)
+	}
+}
+
+func TestTileFor(t *testing.T) {
+	x, y, px, py := tileFor(0, 0, 1)
+	if x != 1 || y != 1 || px != 0 || py != 0 {
+		t.Errorf("tileFor(0,0,1) = %d,%d %.1f,%.1f", x, y, px, py)
+	}
+}
+
+func TestSetMentionsReplacesPerItem(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mentionsMu.Lock()
+	mentions = map[string][]Mention{}
+	mentionsMu.Unlock()
+
+	museum := Embed{Name: "Museum", Lat: 51.5194, Lon: -0.127}
+	SetMentions("post", "1", "Day out", "/blog/post?id=1", museum.Shortcode())
+	SetMentions("post", "2", "Rainy day", "/blog/post?id=2", museum.Shortcode())
+	if got := MentionsOf(museum); len(got) != 2 {
+		t.Fatalf("expected 2 mentions, got %+v", got)
+	}
+
+	// Editing the place out of a post drops its mention.
+	SetMentions("post", "1", "Day out", "/blog/post?id=1", "No places here")
+	got := MentionsOf(museum)
+	if len(got) != 1 || got[0].ID != "2" {
+		t.Errorf("after edit: %+v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package places
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// exporter exports a user's saved searches, favourite places and
+// reviews; the favourites and the searches with coordinates also export
+// as GPX waypoints.
+type exporter struct{}
+
+func (exporter) Name() string        { return "places" }
+func (exporter) Description() string { return "Your saved place searches, favourites and reviews" }
+func (exporter) Formats() []string   { return []string{"json", "gpx"} }
+
+type gpxWaypoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+	Name string  `xml:"name"`
+	Desc string  `xml:"desc,omitempty"`
+}
+
+type gpxFile struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Xmlns     string        `xml:"xmlns,attr"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+func (exporter) Export(w io.Writer, userID, format string) error {
+	searches := getUserSavedSearches(userID)
+	favs := Favourites(userID)
+	if format == "json" {
+		return json.NewEncoder(w).Encode(map[string]interface{}{
+			"searches":   searches,
+			"favourites": favs,
+			"reviews":    userReviews(userID),
+		})
+	}
+	g := gpxFile{Version: "1.1", Creator: "Mu", Xmlns: "http://www.topografix.com/GPX/1/1"}
+	for _, f := range favs {
+		desc := f.Note
+		if desc == "" {
+			desc = f.Place.Address
+		}
+		g.Waypoints = append(g.Waypoints, gpxWaypoint{
+			Lat:  f.Place.Lat,
+			Lon:  f.Place.Lon,
+			Time: f.CreatedAt.UTC().Format(time.RFC3339),
+			Name: f.Place.Name,
+			Desc: desc,
+		})
+	}
+	for _, s := range searches {
+		if s.Lat == 0 && s.Lon == 0 {
+			continue
+		}
+		desc := s.Query
+		if desc == "" {
+			desc = s.Location
+		}
+		g.Waypoints = append(g.Waypoints, gpxWaypoint{
+			Lat:  s.Lat,
+			Lon:  s.Lon,
+			Time: s.CreatedAt.UTC().Format(time.RFC3339),
+			Name: s.Label,
+			Desc: desc,
+		})
+	}
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(g)
+}
@@ -0,0 +1,388 @@
+package places
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+
+	"github.com/google/uuid"
+)
+
+// Favourites are places a user has starred: a snapshot of the place as
+// it was found, so the list doesn't depend on searching again, and a
+// note of their own. They're private to the user.
+
+// Favourite is a starred place.
+type Favourite struct {
+	ID        string    `json:"id"`
+	Place     *Place    `json:"place"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// errFavouriteNotFound is returned for a favourite the user doesn't have.
+var errFavouriteNotFound = errors.New("favourite not found")
+
+const (
+	maxFavourites   = 500 // per user
+	maxFavouriteLen = 500 // note length
+)
+
+var (
+	favouritesMu sync.RWMutex
+	favourites   = map[string][]*Favourite{} // userID -> favourites, newest first
+)
+
+func loadFavourites() {
+	var d map[string][]*Favourite
+	if err := data.LoadJSON("places_favourites.json", &d); err == nil && d != nil {
+		favouritesMu.Lock()
+		favourites = d
+		favouritesMu.Unlock()
+	}
+}
+
+func saveFavourites() {
+	data.SaveJSON("places_favourites.json", favourites)
+}
+
+// placeKey identifies a place across searches: its ID from the source,
+// or where it is.
+func placeKey(p *Place) string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return fmt.Sprintf("%.5f,%.5f", p.Lat, p.Lon)
+}
+
+// Favourites returns the user's favourites, newest first.
+func Favourites(userID string) []*Favourite {
+	favouritesMu.RLock()
+	defer favouritesMu.RUnlock()
+	out := make([]*Favourite, len(favourites[userID]))
+	copy(out, favourites[userID])
+	return out
+}
+
+// favouriteIDs maps the user's starred places, by placeKey, to their
+// favourite IDs, for drawing the star on place cards.
+func favouriteIDs(userID string) map[string]string {
+	favouritesMu.RLock()
+	defer favouritesMu.RUnlock()
+	ids := map[string]string{}
+	for _, f := range favourites[userID] {
+		ids[placeKey(f.Place)] = f.ID
+	}
+	return ids
+}
+
+// AddFavourite stars a place. Starring one already starred keeps it and
+// updates the note if one is given.
+func AddFavourite(userID string, p *Place, note string) (*Favourite, error) {
+	if p == nil || strings.TrimSpace(p.Name) == "" {
+		return nil, errors.New("a place needs a name")
+	}
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 || (p.Lat == 0 && p.Lon == 0) {
+		return nil, errors.New("a place needs a location")
+	}
+	note = strings.TrimSpace(note)
+	if len(note) > maxFavouriteLen {
+		return nil, fmt.Errorf("keep the note under %d characters", maxFavouriteLen)
+	}
+	snap := *p
+	snap.Distance = 0 // depends on where it was searched from
+
+	favouritesMu.Lock()
+	defer favouritesMu.Unlock()
+	key := placeKey(&snap)
+	for _, f := range favourites[userID] {
+		if placeKey(f.Place) == key {
+			if note != "" {
+				f.Note = note
+				saveFavourites()
+			}
+			return f, nil
+		}
+	}
+	if len(favourites[userID]) >= maxFavourites {
+		return nil, fmt.Errorf("you can keep up to %d favourites", maxFavourites)
+	}
+	f := &Favourite{ID: uuid.New().String(), Place: &snap, Note: note, CreatedAt: time.Now()}
+	favourites[userID] = append([]*Favourite{f}, favourites[userID]...)
+	saveFavourites()
+	return f, nil
+}
+
+// SetFavouriteNote replaces a favourite's note.
+func SetFavouriteNote(userID, id, note string) error {
+	note = strings.TrimSpace(note)
+	if len(note) > maxFavouriteLen {
+		return fmt.Errorf("keep the note under %d characters", maxFavouriteLen)
+	}
+	favouritesMu.Lock()
+	defer favouritesMu.Unlock()
+	for _, f := range favourites[userID] {
+		if f.ID == id {
+			f.Note = note
+			saveFavourites()
+			return nil
+		}
+	}
+	return errFavouriteNotFound
+}
+
+// DeleteFavourite unstars a place.
+func DeleteFavourite(userID, id string) error {
+	favouritesMu.Lock()
+	defer favouritesMu.Unlock()
+	list := favourites[userID]
+	for i, f := range list {
+		if f.ID == id {
+			favourites[userID] = append(list[:i:i], list[i+1:]...)
+			if len(favourites[userID]) == 0 {
+				delete(favourites, userID)
+			}
+			saveFavourites()
+			return nil
+		}
+	}
+	return errFavouriteNotFound
+}
+
+// DeleteFavourites removes a deleted account's favourites.
+func DeleteFavourites(userID string) {
+	favouritesMu.Lock()
+	defer favouritesMu.Unlock()
+	if _, ok := favourites[userID]; ok {
+		delete(favourites, userID)
+		saveFavourites()
+	}
+}
+
+// RenameFavourites moves a renamed account's favourites to its new ID.
+func RenameFavourites(oldID, newID string) {
+	favouritesMu.Lock()
+	defer favouritesMu.Unlock()
+	if list, ok := favourites[oldID]; ok {
+		favourites[newID] = list
+		delete(favourites, oldID)
+		saveFavourites()
+	}
+}
+
+// placeFromForm reads the place snapshot the star button posts.
+func placeFromForm(r *http.Request) *Place {
+	lat, _ := strconv.ParseFloat(r.FormValue("lat"), 64)
+	lon, _ := strconv.ParseFloat(r.FormValue("lon"), 64)
+	return &Place{
+		ID:           r.FormValue("place_id"),
+		Name:         strings.TrimSpace(r.FormValue("name")),
+		Category:     r.FormValue("category"),
+		Type:         r.FormValue("type"),
+		Address:      r.FormValue("address"),
+		Lat:          lat,
+		Lon:          lon,
+		Phone:        r.FormValue("phone"),
+		Website:      r.FormValue("website"),
+		OpeningHours: r.FormValue("opening_hours"),
+		Cuisine:      r.FormValue("cuisine"),
+	}
+}
+
+// FavouritesCard renders a member's latest favourites for their home
+// screen, or nothing if they haven't starred any.
+func FavouritesCard(userID string) string {
+	list := Favourites(userID)
+	if len(list) == 0 {
+		return ""
+	}
+	if len(list) > 5 {
+		list = list[:5]
+	}
+	var sb strings.Builder
+	for _, f := range list {
+		p := f.Place
+		link := fmt.Sprintf("/places/place?lat=%.5f&lon=%.5f&name=%s", p.Lat, p.Lon, url.QueryEscape(p.Name))
+		detail := strings.ReplaceAll(p.Category, "_", " ")
+		if f.Note != "" {
+			detail = f.Note
+		} else if p.Address != "" {
+			detail = p.Address
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="item"><a href="%s">%s</a>`, link, escapeHTML(p.Name)))
+		if detail != "" {
+			sb.WriteString(fmt.Sprintf(`<div class="text-muted text-sm">%s</div>`, escapeHTML(detail)))
+		}
+		sb.WriteString(`</div>`)
+	}
+	return sb.String()
+}
+
+// handleFavourites serves /places/favourites: GET lists them, on a map
+// or as JSON, and POST stars a place from the form fields or a JSON body
+// of {"place": {...}, "note": "..."}.
+func handleFavourites(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list := Favourites(acc.ID)
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"favourites": list})
+			return
+		}
+		app.Respond(w, r, app.Response{
+			Title:       "Favourite places",
+			Description: "Places you've starred",
+			HTML:        renderFavouritesPage(list, r.URL.Query().Get("error")),
+		})
+
+	case http.MethodPost:
+		var p *Place
+		var note string
+		if app.SendsJSON(r) {
+			var body struct {
+				Place *Place `json:"place"`
+				Note  string `json:"note"`
+			}
+			if err := app.DecodeJSON(r, &body); err != nil {
+				app.BadRequest(w, r, "Invalid JSON body")
+				return
+			}
+			p, note = body.Place, body.Note
+		} else {
+			r.ParseForm()
+			p, note = placeFromForm(r), r.FormValue("note")
+		}
+		f, err := AddFavourite(acc.ID, p, note)
+		if err != nil {
+			favouriteError(w, r, err)
+			return
+		}
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"favourite": f})
+			return
+		}
+		http.Redirect(w, r, "/places/favourites", http.StatusSeeOther)
+
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+// handleFavouriteDelete serves POST /places/favourites/delete.
+func handleFavouriteDelete(w http.ResponseWriter, r *http.Request) {
+	handleFavouriteChange(w, r, func(userID, id string) error { return DeleteFavourite(userID, id) })
+}
+
+// handleFavouriteNote serves POST /places/favourites/note.
+func handleFavouriteNote(w http.ResponseWriter, r *http.Request) {
+	handleFavouriteChange(w, r, func(userID, id string) error { return SetFavouriteNote(userID, id, r.FormValue("note")) })
+}
+
+// handleFavouriteChange reads a favourite's id, from a form or JSON, and
+// applies change to it.
+func handleFavouriteChange(w http.ResponseWriter, r *http.Request, change func(userID, id string) error) {
+	if r.Method != http.MethodPost {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+	if app.SendsJSON(r) {
+		var body struct {
+			ID   string `json:"id"`
+			Note string `json:"note"`
+		}
+		if err := app.DecodeJSON(r, &body); err != nil {
+			app.BadRequest(w, r, "Invalid JSON body")
+			return
+		}
+		r.Form = map[string][]string{"id": {body.ID}, "note": {body.Note}}
+	} else {
+		r.ParseForm()
+	}
+	if err := change(acc.ID, r.FormValue("id")); err != nil {
+		favouriteError(w, r, err)
+		return
+	}
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		app.RespondJSON(w, map[string]bool{"ok": true})
+		return
+	}
+	http.Redirect(w, r, "/places/favourites", http.StatusSeeOther)
+}
+
+// favouriteError reports err as JSON, or back on the favourites page.
+func favouriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		if err == errFavouriteNotFound {
+			app.NotFound(w, r, err.Error())
+		} else {
+			app.BadRequest(w, r, err.Error())
+		}
+		return
+	}
+	http.Redirect(w, r, "/places/favourites?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+}
+
+// renderFavouritesPage shows every favourite on one map, then each as a
+// card with its note.
+func renderFavouritesPage(list []*Favourite, errMsg string) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="places-page">`)
+	sb.WriteString(`<p><a href="/places">&larr; Back to Places</a></p>`)
+	if errMsg != "" {
+		sb.WriteString(fmt.Sprintf(`<p class="text-error">%s</p>`, escapeHTML(errMsg)))
+	}
+	if len(list) == 0 {
+		sb.WriteString(`<p class="text-muted">No favourites yet. Star a place in your search results to keep it here.</p></div>`)
+		return sb.String()
+	}
+	places := make([]*Place, len(list))
+	favs := map[string]string{}
+	for i, f := range list {
+		places[i] = f.Place
+		favs[placeKey(f.Place)] = f.ID
+	}
+	sb.WriteString(fmt.Sprintf(`<p class="text-muted">%d place(s)</p>`, len(list)))
+	sb.WriteString(renderLeafletMap(places[0].Lat, places[0].Lon, places))
+	sb.WriteString(renderTypeFilter(places))
+	sb.WriteString(`<div class="places-results">`)
+	for _, f := range list {
+		sb.WriteString(renderPlaceCard(f.Place, favs))
+		sb.WriteString(fmt.Sprintf(`<form class="place-fav-note" action="/places/favourites/note" method="POST">
+  <input type="hidden" name="id" value="%s">
+  <input type="text" name="note" value="%s" placeholder="Add a note" maxlength="%d">
+  <button type="submit" class="btn-link">Save note</button>
+</form>`, escapeHTML(f.ID), escapeHTML(f.Note), maxFavouriteLen))
+	}
+	sb.WriteString(`</div>`)
+	sb.WriteString(renderPlacesPageJS())
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
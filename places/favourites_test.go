@@ -0,0 +1,105 @@
+package places
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestFavourites(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := auth.Create(&auth.Account{ID: "fav_user", Name: "fav_user", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("fav_user")
+	sess, err := auth.Login("fav_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	favouritesMu.Lock()
+	orig := favourites
+	favourites = map[string][]*Favourite{}
+	favouritesMu.Unlock()
+	defer func() {
+		favouritesMu.Lock()
+		favourites = orig
+		favouritesMu.Unlock()
+	}()
+
+	do := func(method, path, body string, wantJSON bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if wantJSON {
+			r.Header.Set("Accept", "application/json")
+		}
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+
+	// Starring from a card's form keeps a snapshot, not the distance.
+	form := "place_id=osm-1&name=Corner+Cafe&category=cafe&address=1+High+St&lat=51.5&lon=-0.12&note=good+coffee"
+	if w := do("POST", "/places/favourites", form, false); w.Code != http.StatusSeeOther {
+		t.Fatalf("add: %d %s", w.Code, w.Body.String())
+	}
+	// Starring it again keeps the one favourite.
+	do("POST", "/places/favourites", "place_id=osm-1&name=Corner+Cafe&lat=51.5&lon=-0.12", false)
+	list := Favourites("fav_user")
+	if len(list) != 1 || list[0].Place.Name != "Corner Cafe" || list[0].Note != "good coffee" {
+		t.Fatalf("favourites = %+v", list)
+	}
+	id := list[0].ID
+
+	if w := do("POST", "/places/favourites", "name=Nowhere", true); w.Code != http.StatusBadRequest {
+		t.Errorf("place without a location: %d", w.Code)
+	}
+
+	w := do("GET", "/places/favourites", "", true)
+	var got struct {
+		Favourites []*Favourite `json:"favourites"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil || len(got.Favourites) != 1 || got.Favourites[0].ID != id {
+		t.Fatalf("JSON list: %v %s", err, w.Body.String())
+	}
+
+	page := do("GET", "/places/favourites", "", false).Body.String()
+	if !strings.Contains(page, "Corner Cafe") || !strings.Contains(page, `value="good coffee"`) || !strings.Contains(page, "&#9733; Saved") {
+		t.Error("page is missing the place, its note or its star")
+	}
+
+	if w := do("POST", "/places/favourites/note", "id="+id+"&note=closed+mondays", true); w.Code != http.StatusOK {
+		t.Errorf("note: %d", w.Code)
+	}
+	if Favourites("fav_user")[0].Note != "closed mondays" {
+		t.Error("note not saved")
+	}
+
+	// The star on a search result reflects what's saved.
+	card := renderPlaceCard(&Place{ID: "osm-1", Name: "Corner Cafe", Lat: 51.5, Lon: -0.12}, favouriteIDs("fav_user"))
+	if !strings.Contains(card, `action="/places/favourites/delete"`) || !strings.Contains(card, `value="`+id+`"`) {
+		t.Error("saved place's card doesn't offer to unstar it")
+	}
+	if card := renderPlaceCard(&Place{ID: "osm-2", Name: "Other", Lat: 51.5, Lon: -0.1}, favouriteIDs("fav_user")); !strings.Contains(card, `action="/places/favourites"`) {
+		t.Error("unsaved place's card doesn't offer to star it")
+	}
+	if strings.Contains(renderPlaceCard(&Place{Name: "Anon"}, nil), "place-fav-form") {
+		t.Error("star shown without a session")
+	}
+
+	if w := do("POST", "/places/favourites/delete", "id="+id, true); w.Code != http.StatusOK {
+		t.Errorf("delete: %d", w.Code)
+	}
+	if w := do("POST", "/places/favourites/delete", "id="+id, true); w.Code != http.StatusNotFound {
+		t.Errorf("deleting twice: %d", w.Code)
+	}
+	if len(Favourites("fav_user")) != 0 {
+		t.Error("favourite not deleted")
+	}
+}
@@ -0,0 +1,310 @@
+package places
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/egress"
+)
+
+// Curated filters are the categories people look for most, one tap from
+// the nearby form. Each maps to a fixed set of Overpass tag queries, or
+// Google Places types when a key is configured, rather than a keyword
+// search that would miss places not named after what they are.
+
+// Filter is a curated category of places.
+type Filter struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Icon  string `json:"icon"`
+
+	overpass    []string // tag selectors, each queried for named nodes and ways
+	googleTypes []string // Places API types for a nearby search
+	googleQuery string   // or a text search, where there's no type
+	local       string   // indexed places to fall back on
+}
+
+var filters = []*Filter{
+	{
+		Key: "halal", Label: "Halal food", Icon: "&#127869;",
+		overpass: []string{
+			`["amenity"~"^(restaurant|fast_food|cafe|food_court)$"]["diet:halal"~"^(yes|only)$"]`,
+			`["amenity"~"^(restaurant|fast_food|cafe|food_court)$"]["cuisine"~"halal",i]`,
+			`["shop"~"^(butcher|supermarket|convenience)$"]["diet:halal"~"^(yes|only)$"]`,
+		},
+		googleQuery: "halal restaurant",
+		local:       "halal",
+	},
+	{
+		Key: "mosque", Label: "Mosques", Icon: "&#128332;",
+		overpass:    []string{`["amenity"="place_of_worship"]["religion"="muslim"]`},
+		googleTypes: []string{"mosque"},
+		local:       "mosque",
+	},
+	{
+		Key: "pharmacy", Label: "Pharmacies", Icon: "&#128138;",
+		overpass:    []string{`["amenity"="pharmacy"]`, `["healthcare"="pharmacy"]`},
+		googleTypes: []string{"pharmacy"},
+		local:       "pharmacy",
+	},
+}
+
+// getFilter returns the filter with key, or nil.
+func getFilter(key string) *Filter {
+	for _, f := range filters {
+		if f.Key == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// findFilteredPlaces finds the filter's places within radiusM of lat,
+// lon: from Google Places when configured, otherwise Overpass, otherwise
+// the index. Halal food also takes in the places moderators have
+// verified, whatever the sources say.
+func findFilteredPlaces(f *Filter, lat, lon float64, radiusM int) ([]*Place, error) {
+	var found []*Place
+	var err error
+	if googleAPIKey() != "" {
+		if len(f.googleTypes) > 0 {
+			found, err = googleNearbyTypes(lat, lon, radiusM, f.googleTypes)
+		} else {
+			found, err = googleSearch(f.googleQuery, lat, lon, radiusM)
+		}
+		if err != nil {
+			app.Log("places", "google %s filter error: %v", f.Key, err)
+			found = nil
+		}
+	}
+	if found == nil {
+		if found, err = overpassFilter(f, lat, lon, radiusM); err != nil {
+			app.Log("places", "overpass %s filter error: %v", f.Key, err)
+			found, _ = searchPlacesFTS(f.local, lat, lon, radiusM, true)
+		}
+	}
+	go indexPlaces(found)
+	found = withCommunity(found, f.local, lat, lon, radiusM)
+
+	if f.Key == "halal" {
+		found = append(found, verifiedHalalNear(lat, lon, radiusM, found)...)
+	}
+
+	within := make([]*Place, 0, len(found))
+	for _, p := range found {
+		p.Distance = haversine(lat, lon, p.Lat, p.Lon)
+		if p.Distance <= float64(radiusM) {
+			within = append(within, p)
+		}
+	}
+	sort.Slice(within, func(i, j int) bool { return within[i].Distance < within[j].Distance })
+	return within, nil
+}
+
+// overpassFilter runs the filter's tag queries against Overpass.
+func overpassFilter(f *Filter, lat, lon float64, radiusM int) ([]*Place, error) {
+	if radiusM > 10000 {
+		radiusM = 10000
+	}
+	var q strings.Builder
+	q.WriteString("[out:json][timeout:25];(\n")
+	for _, sel := range f.overpass {
+		fmt.Fprintf(&q, "  node%s[\"name\"](around:%d,%f,%f);\n", sel, radiusM, lat, lon)
+		fmt.Fprintf(&q, "  way%s[\"name\"](around:%d,%f,%f);\n", sel, radiusM, lat, lon)
+	}
+	q.WriteString(");\nout center;")
+
+	req, err := http.NewRequest("POST", "https://overpass-api.de/api/interpreter",
+		strings.NewReader("data="+url.QueryEscape(q.String())))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Mu/1.0 (https://your-instance.com)")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("overpass filter search failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("overpass returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := parseOverpass(body)
+	if err := egress.CheckFormat("overpass", overpassFormat, err); err != nil {
+		return nil, err
+	}
+
+	places := []*Place{}
+	seen := map[int64]bool{}
+	for _, el := range elements {
+		if seen[el.ID] {
+			continue // matched more than one selector
+		}
+		seen[el.ID] = true
+		if p := overpassPlace(el); p != nil {
+			places = append(places, p)
+		}
+	}
+	return places, nil
+}
+
+// IsHalal reports whether the place's tags say it serves halal food.
+func (p *Place) IsHalal() bool {
+	return strings.Contains(strings.ToLower(p.Cuisine), "halal")
+}
+
+// HalalVerification records a moderator confirming a place is halal.
+type HalalVerification struct {
+	Place      *Place    `json:"place"`
+	VerifiedBy string    `json:"verified_by"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+var (
+	halalMu  sync.RWMutex
+	halalSet = map[string]*HalalVerification{} // placeKey -> verification
+)
+
+func loadHalal() {
+	var d map[string]*HalalVerification
+	if err := data.LoadJSON("places_halal.json", &d); err == nil && d != nil {
+		halalMu.Lock()
+		halalSet = d
+		halalMu.Unlock()
+	}
+}
+
+// VerifiedHalal returns the verification of the place, or nil.
+func VerifiedHalal(p *Place) *HalalVerification {
+	halalMu.RLock()
+	defer halalMu.RUnlock()
+	return halalSet[placeKey(p)]
+}
+
+// SetVerifiedHalal marks the place as verified halal by a moderator, or
+// clears the mark.
+func SetVerifiedHalal(p *Place, moderatorID string, verified bool) {
+	halalMu.Lock()
+	defer halalMu.Unlock()
+	key := placeKey(p)
+	if verified {
+		snap := *p
+		snap.Distance = 0
+		halalSet[key] = &HalalVerification{Place: &snap, VerifiedBy: moderatorID, VerifiedAt: time.Now()}
+	} else {
+		delete(halalSet, key)
+	}
+	data.SaveJSON("places_halal.json", halalSet)
+}
+
+// verifiedHalalNear returns the verified places within radiusM of lat,
+// lon that aren't already in found.
+func verifiedHalalNear(lat, lon float64, radiusM int, found []*Place) []*Place {
+	have := map[string]bool{}
+	for _, p := range found {
+		have[placeKey(p)] = true
+	}
+	halalMu.RLock()
+	defer halalMu.RUnlock()
+	var out []*Place
+	for key, v := range halalSet {
+		if have[key] || haversine(lat, lon, v.Place.Lat, v.Place.Lon) > float64(radiusM) {
+			continue
+		}
+		p := *v.Place
+		out = append(out, &p)
+	}
+	return out
+}
+
+// renderHalalBadge marks a place verified halal, or halal by its tags.
+func renderHalalBadge(p *Place) string {
+	if VerifiedHalal(p) != nil {
+		return ` <span class="place-halal verified" title="Verified by a moderator">&#10003; Verified halal</span>`
+	}
+	if p.IsHalal() {
+		return ` <span class="place-halal">Halal</span>`
+	}
+	return ""
+}
+
+// renderFilterChips renders the curated filters as chips that run a
+// nearby search, the active one highlighted.
+func renderFilterChips(active string) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="places-filter-chips">`)
+	for _, f := range filters {
+		// Tapping the active chip again clears it.
+		class, key := "place-chip", f.Key
+		if f.Key == active {
+			class, key = "place-chip active", ""
+		}
+		sb.WriteString(fmt.Sprintf(`<button type="button" class="%s" data-nearby-filter="%s">%s %s</button>`,
+			class, key, f.Icon, escapeHTML(f.Label)))
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+// renderHalalForm is the moderator's control on a place's page.
+func renderHalalForm(p *Place) string {
+	verified := VerifiedHalal(p) != nil
+	label, value := "Mark as verified halal", "true"
+	if verified {
+		label, value = "Remove halal verification", "false"
+	}
+	return fmt.Sprintf(`<form class="d-inline" action="/places/halal" method="POST">
+  <input type="hidden" name="id" value="%s">
+  <input type="hidden" name="verified" value="%s">
+  <button type="submit" class="btn-link">%s</button>
+</form>`, escapeHTML(p.ID), value, label)
+}
+
+// handleHalal serves POST /places/halal, a moderator marking an indexed
+// place as verified halal or clearing the mark.
+func handleHalal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireAdmin(r)
+	if err != nil {
+		app.Forbidden(w, r, "Moderator access required")
+		return
+	}
+	formValue := parseRequestParams(r)
+	id := formValue("id")
+	verified, _ := strconv.ParseBool(formValue("verified"))
+	p, err := getIndexedPlace(id)
+	if err != nil || p == nil {
+		app.NotFound(w, r, "Place not found")
+		return
+	}
+	SetVerifiedHalal(p, acc.ID, verified)
+	action := "places verify_halal"
+	if !verified {
+		action = "places unverify_halal"
+	}
+	app.Audit(acc.ID, action, p.ID, r.URL.Path)
+
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"id": p.ID, "verified": verified})
+		return
+	}
+	http.Redirect(w, r, viewURL(p.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,127 @@
+package places
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+func TestOverpassPlaceHalal(t *testing.T) {
+	el := overpassElement{ID: 7, Lat: 51.5, Lon: -0.1, Tags: map[string]string{
+		"name": "Grill House", "amenity": "restaurant", "cuisine": "turkish;kebab", "diet:halal": "yes",
+	}}
+	p := overpassPlace(el)
+	if p == nil || p.Cuisine != "turkish, kebab, halal" || !p.IsHalal() {
+		t.Fatalf("place = %+v", p)
+	}
+	el.Tags["cuisine"] = "halal"
+	if p := overpassPlace(el); p.Cuisine != "halal" {
+		t.Errorf("halal listed twice: %q", p.Cuisine)
+	}
+	el.Tags = map[string]string{"name": "Corner Chemist", "healthcare": "pharmacy"}
+	if p := overpassPlace(el); p.Category != "pharmacy" || p.IsHalal() {
+		t.Errorf("pharmacy = %+v", p)
+	}
+}
+
+func TestVerifiedHalal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	placesDBOne = sync.Once{}
+	placesDB = nil
+
+	halalMu.Lock()
+	orig := halalSet
+	halalSet = map[string]*HalalVerification{}
+	halalMu.Unlock()
+	defer func() {
+		halalMu.Lock()
+		halalSet = orig
+		halalMu.Unlock()
+	}()
+
+	sessions := map[string]string{}
+	for _, a := range []*auth.Account{
+		{ID: "halal_mod", Name: "Mod", Secret: "secret", Admin: true, Created: time.Now()},
+		{ID: "halal_user", Name: "User", Secret: "secret", Created: time.Now()},
+	} {
+		if err := auth.Create(a); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(a.ID)
+		sess, err := auth.Login(a.ID, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[a.ID] = sess.Token
+	}
+
+	indexPlaces([]*Place{{ID: "h1", Name: "Noor Kitchen", Category: "restaurant", Lat: 51.5, Lon: -0.12}})
+	// Let the place's embedding land before the data dir is removed.
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if m := data.SemanticSearch("Noor Kitchen restaurant", 1, data.WithType("place")); len(m) > 0 {
+			break
+		}
+	}
+
+	post := func(user, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/places/halal", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: "session", Value: sessions[user]})
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+
+	if w := post("halal_user", "id=h1&verified=true"); w.Code != http.StatusForbidden {
+		t.Fatalf("member verified a place: %d", w.Code)
+	}
+	if w := post("halal_mod", "id=h1&verified=true"); w.Code != http.StatusSeeOther || w.Header().Get("Location") != viewURL("h1") {
+		t.Fatalf("verify: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	p := &Place{ID: "h1", Name: "Noor Kitchen", Lat: 51.5, Lon: -0.12}
+	if v := VerifiedHalal(p); v == nil || v.VerifiedBy != "halal_mod" {
+		t.Fatalf("verification = %+v", v)
+	}
+	if !strings.Contains(renderPlaceCard(p, nil), "Verified halal") {
+		t.Error("card has no verified badge")
+	}
+	// Verified places turn up in a halal search even if no source has them.
+	if near := verifiedHalalNear(51.501, -0.12, 500, nil); len(near) != 1 || near[0].ID != "h1" {
+		t.Errorf("verified nearby = %v", near)
+	}
+	if near := verifiedHalalNear(51.501, -0.12, 500, []*Place{p}); len(near) != 0 {
+		t.Error("a place already found was added again")
+	}
+
+	post("halal_mod", "id=h1&verified=false")
+	if VerifiedHalal(p) != nil {
+		t.Error("verification not cleared")
+	}
+	if w := post("halal_mod", "id=missing&verified=true"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown place: %d", w.Code)
+	}
+}
+
+func TestFilterChips(t *testing.T) {
+	html := renderNearbyFormHTML("", "", "", "", "mosque")
+	for _, f := range filters {
+		if !strings.Contains(html, f.Label) {
+			t.Errorf("form has no %s chip", f.Key)
+		}
+	}
+	if !strings.Contains(html, `name="filter" id="nearby-filter" value="mosque"`) {
+		t.Error("form doesn't carry the active filter")
+	}
+	if chips := renderFilterChips(""); strings.Contains(chips, "onclick") || !strings.Contains(chips, `data-nearby-filter="halal"`) {
+		t.Error("chips should use data-nearby-filter, not inline handlers")
+	}
+	if getFilter("halal") == nil || getFilter("bogus") != nil {
+		t.Error("getFilter")
+	}
+}
@@ -11,8 +11,15 @@ import (
 	"time"
 
 	"mu/internal/app"
+	"mu/internal/settings"
 )
 
+func init() {
+	settings.Register("Places",
+		settings.Var{Key: "GOOGLE_API_KEY", Secret: true, Doc: "Google Places and Weather API key; OpenStreetMap is used without it"},
+	)
+}
+
 const googlePlacesBaseURL = "https://places.googleapis.com/v1/places"
 
 // googleFieldMask lists the fields requested from the Places API (New).
@@ -53,6 +60,12 @@ type googlePlacesResponse struct {
 // googleNearby fetches POIs near a location using the Places API (New) Nearby Search.
 // Returns nil, nil when GOOGLE_API_KEY is not set.
 func googleNearby(lat, lon float64, radiusM int) ([]*Place, error) {
+	return googleNearbyTypes(lat, lon, radiusM, nil)
+}
+
+// googleNearbyTypes is googleNearby for places of the given Places API
+// types only, or any type if types is empty.
+func googleNearbyTypes(lat, lon float64, radiusM int, types []string) ([]*Place, error) {
 	key := googleAPIKey()
 	if key == "" {
 		return nil, nil
@@ -69,6 +82,9 @@ func googleNearby(lat, lon float64, radiusM int) ([]*Place, error) {
 			},
 		},
 	}
+	if len(types) > 0 {
+		body["includedTypes"] = types
+	}
 	return googleDo(googlePlacesBaseURL+":searchNearby", key, body)
 }
 
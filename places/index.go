@@ -129,6 +129,7 @@ func initPlacesDB() error {
 				website      TEXT,
 				opening_hours TEXT,
 				cuisine      TEXT,
+				source       TEXT,
 				indexed_at   DATETIME DEFAULT CURRENT_TIMESTAMP
 			);
 			CREATE INDEX IF NOT EXISTS idx_places_lat     ON places(lat);
@@ -148,6 +149,9 @@ func initPlacesDB() error {
 			initErr = fmt.Errorf("places db schema: %w", err)
 			return
 		}
+		// source came after v3; it's added to older tables rather than
+		// wiping them, and fails harmlessly where it's already there.
+		placesDB.Exec(`ALTER TABLE places ADD COLUMN source TEXT`)
 
 		// Persist the version record when the DB is freshly created or wiped.
 		if storedVer != schemaVersion {
@@ -169,8 +173,9 @@ func getPlacesDB() (*sql.DB, error) {
 }
 
 // indexPlaces batch-upserts places into the SQLite places table and FTS index.
+// Nothing is written while the data store is read-only.
 func indexPlaces(places []*Place) {
-	if len(places) == 0 {
+	if len(places) == 0 || data.ReadOnly() {
 		return
 	}
 	db, err := getPlacesDB()
@@ -189,14 +194,14 @@ func indexPlaces(places []*Place) {
 	}
 
 	mainStmt, err := tx.Prepare(`
-		INSERT INTO places (id, name, category, address, lat, lon, geohash, phone, website, opening_hours, cuisine, indexed_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO places (id, name, category, address, lat, lon, geohash, phone, website, opening_hours, cuisine, source, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name=excluded.name, category=excluded.category, address=excluded.address,
 			lat=excluded.lat, lon=excluded.lon, geohash=excluded.geohash,
 			phone=excluded.phone, website=excluded.website,
 			opening_hours=excluded.opening_hours, cuisine=excluded.cuisine,
-			indexed_at=excluded.indexed_at
+			source=excluded.source, indexed_at=excluded.indexed_at
 	`)
 	if err != nil {
 		tx.Rollback()
@@ -225,7 +230,7 @@ func indexPlaces(places []*Place) {
 	for _, p := range places {
 		gh := encodeGeohash(p.Lat, p.Lon, 6)
 		if _, err := mainStmt.Exec(p.ID, p.Name, p.Category, p.Address,
-			p.Lat, p.Lon, gh, p.Phone, p.Website, p.OpeningHours, p.Cuisine, now); err != nil {
+			p.Lat, p.Lon, gh, p.Phone, p.Website, p.OpeningHours, p.Cuisine, p.Source, now); err != nil {
 			app.Log("places", "indexPlaces: insert %s: %v", p.ID, err)
 			continue
 		}
@@ -238,7 +243,69 @@ func indexPlaces(places []*Place) {
 	if err := tx.Commit(); err != nil {
 		tx.Rollback()
 		app.Log("places", "indexPlaces: commit: %v", err)
+		return
+	}
+
+	for _, p := range places {
+		data.IndexEmbedding(placeEmbeddingPrefix+p.ID, "place", placeText(p))
+	}
+}
+
+// placeEmbeddingPrefix keeps place IDs apart from other embedded content.
+const placeEmbeddingPrefix = "place:"
+
+// placeText is what a place is embedded from for semantic search.
+func placeText(p *Place) string {
+	return strings.Join(strings.Fields(strings.Join([]string{
+		p.Name, strings.ReplaceAll(p.Category, "_", " "), p.Cuisine, p.Address,
+	}, " ")), " ")
+}
+
+// searchPlacesSemantic finds indexed places within radiusM of the
+// reference point that mean what query means, though they may not share
+// a word with it ("somewhere to eat" finds restaurants), nearest first.
+func searchPlacesSemantic(query string, refLat, refLon float64, radiusM int) ([]*Place, error) {
+	matches := data.SemanticSearch(query, 200, data.WithType("place"))
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	db, err := getPlacesDB()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(matches))
+	args := make([]interface{}, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, "?")
+		args = append(args, strings.TrimPrefix(m.ID, placeEmbeddingPrefix))
+	}
+	rows, err := db.Query(`
+		SELECT id, name, category, address, lat, lon,
+		       phone, website, opening_hours, cuisine, COALESCE(source, '')
+		FROM places
+		WHERE id IN (`+strings.Join(ids, ",")+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("places semantic query: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*Place
+	for rows.Next() {
+		p := &Place{}
+		if err := rows.Scan(&p.ID, &p.Name, &p.Category, &p.Address,
+			&p.Lat, &p.Lon, &p.Phone, &p.Website, &p.OpeningHours, &p.Cuisine, &p.Source); err != nil {
+			continue
+		}
+		p.Distance = haversine(refLat, refLon, p.Lat, p.Lon)
+		if p.Distance > float64(radiusM) {
+			continue
+		}
+		result = append(result, p)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Distance < result[j].Distance
+	})
+	return result, nil
 }
 
 // sanitizeFTSQuery converts a raw query into a safe FTS5 MATCH expression.
@@ -282,7 +349,7 @@ func searchPlacesFTS(query string, refLat, refLon float64, radiusM int, hasRef b
 		}
 		rows, err = db.Query(`
 			SELECT p.id, p.name, p.category, p.address, p.lat, p.lon,
-			       p.phone, p.website, p.opening_hours, p.cuisine
+			       p.phone, p.website, p.opening_hours, p.cuisine, COALESCE(p.source, '')
 			FROM places p
 			WHERE p.lat BETWEEN ? AND ?
 			  AND p.lon BETWEEN ? AND ?
@@ -299,7 +366,7 @@ func searchPlacesFTS(query string, refLat, refLon float64, radiusM int, hasRef b
 		}
 		rows, err = db.Query(`
 			SELECT p.id, p.name, p.category, p.address, p.lat, p.lon,
-			       p.phone, p.website, p.opening_hours, p.cuisine
+			       p.phone, p.website, p.opening_hours, p.cuisine, COALESCE(p.source, '')
 			FROM places p
 			WHERE p.id IN (SELECT id FROM places_fts WHERE places_fts MATCH ?)
 			LIMIT ?`,
@@ -310,7 +377,7 @@ func searchPlacesFTS(query string, refLat, refLon float64, radiusM int, hasRef b
 		lonDelta := float64(radiusM) / (111000.0 * math.Cos(refLat*math.Pi/180))
 		rows, err = db.Query(`
 			SELECT id, name, category, address, lat, lon,
-			       phone, website, opening_hours, cuisine
+			       phone, website, opening_hours, cuisine, COALESCE(source, '')
 			FROM places
 			WHERE lat BETWEEN ? AND ? AND lon BETWEEN ? AND ?
 			LIMIT ?`,
@@ -331,7 +398,7 @@ func searchPlacesFTS(query string, refLat, refLon float64, radiusM int, hasRef b
 	for rows.Next() {
 		p := &Place{}
 		if err := rows.Scan(&p.ID, &p.Name, &p.Category, &p.Address,
-			&p.Lat, &p.Lon, &p.Phone, &p.Website, &p.OpeningHours, &p.Cuisine); err != nil {
+			&p.Lat, &p.Lon, &p.Phone, &p.Website, &p.OpeningHours, &p.Cuisine, &p.Source); err != nil {
 			continue
 		}
 		if hasRef {
@@ -352,6 +419,44 @@ func searchPlacesFTS(query string, refLat, refLon float64, radiusM int, hasRef b
 	return result, nil
 }
 
+// getIndexedPlace returns the indexed place with id, or nil if it isn't
+// in the index.
+func getIndexedPlace(id string) (*Place, error) {
+	db, err := getPlacesDB()
+	if err != nil {
+		return nil, err
+	}
+	p := &Place{}
+	err = db.QueryRow(`
+		SELECT id, name, category, address, lat, lon,
+		       phone, website, opening_hours, cuisine, COALESCE(source, '')
+		FROM places WHERE id = ?`, id).Scan(&p.ID, &p.Name, &p.Category, &p.Address,
+		&p.Lat, &p.Lon, &p.Phone, &p.Website, &p.OpeningHours, &p.Cuisine, &p.Source)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("places lookup: %w", err)
+	}
+	return p, nil
+}
+
+// deleteIndexedPlace removes the place with id from the index.
+func deleteIndexedPlace(id string) error {
+	db, err := getPlacesDB()
+	if err != nil {
+		return err
+	}
+	placesDBMu.Lock()
+	defer placesDBMu.Unlock()
+	if _, err := db.Exec(`DELETE FROM places WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("places delete: %w", err)
+	}
+	db.Exec(`DELETE FROM places_fts WHERE id = ?`, id)
+	data.ForgetEmbedding(placeEmbeddingPrefix + id)
+	return nil
+}
+
 // startHourlyRefresh launches a background goroutine that cycles through the
 // known cities once per hour, refreshing each city's place index from Overpass.
 // Used only when no Google API key is configured.
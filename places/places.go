@@ -15,6 +15,9 @@ import (
 
 	"mu/internal/app"
 	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/egress"
+	"mu/internal/netx"
 	"mu/internal/service"
 	"mu/wallet"
 )
@@ -58,6 +61,7 @@ type Place struct {
 	Website      string  `json:"website,omitempty"`
 	OpeningHours string  `json:"opening_hours,omitempty"`
 	Cuisine      string  `json:"cuisine,omitempty"`
+	Source       string  `json:"source,omitempty"` // "community" for imported places
 }
 
 // nominatimResult represents a result from the Nominatim API
@@ -68,6 +72,7 @@ type nominatimResult struct {
 	Lon         string `json:"lon"`
 	Type        string `json:"type"`
 	Class       string `json:"class"`
+	Category    string `json:"category"` // class, in format=jsonv2
 	Address     struct {
 		Road        string `json:"road"`
 		City        string `json:"city"`
@@ -96,13 +101,41 @@ type overpassElement struct {
 	Tags   map[string]string `json:"tags"`
 }
 
-type overpassResponse struct {
-	Elements []overpassElement `json:"elements"`
+// overpassFormat is the version of the Overpass API response parseOverpass
+// understands: [out:json].
+const overpassFormat = "json"
+
+// parseOverpass reads the elements from an Overpass API response. A query
+// that failed on the server comes back as a 200 with a remark saying so,
+// which is returned as an error; a response without elements is drift.
+func parseOverpass(body []byte) ([]overpassElement, error) {
+	var res struct {
+		Elements *[]overpassElement `json:"elements"`
+		Remark   string             `json:"remark"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, egress.Drift("overpass", "%v", err)
+	}
+	if strings.Contains(res.Remark, "error") {
+		return nil, fmt.Errorf("overpass: %s", res.Remark)
+	}
+	if res.Elements == nil {
+		return nil, egress.Drift("overpass", "no elements")
+	}
+	return *res.Elements, nil
 }
 
-// httpClient is the shared HTTP client with timeout.
+// httpClient is the shared HTTP client for place lookups.
 // 35s accommodates Overpass queries which use a 25s server-side timeout.
-var httpClient = &http.Client{Timeout: 35 * time.Second}
+// Nominatim asks for at most one request a second and for results to be
+// cached, hence the budget and cache.
+var httpClient = netx.New("places", netx.Policy{
+	Timeout:  35 * time.Second,
+	Retries:  1,
+	Backoff:  time.Second,
+	CacheTTL: time.Hour,
+	Budget:   60,
+})
 
 // Load initialises the places package
 func Load() {
@@ -117,6 +150,12 @@ func Load() {
 		startHourlyRefresh()
 	}
 	loadSavedSearches()
+	loadFavourites()
+	loadReviews()
+	loadHalal()
+	loadCommunity()
+	loadMentions()
+	data.RegisterExporter(exporter{})
 }
 
 // searchNominatim searches for places using the Nominatim API
@@ -147,9 +186,28 @@ func searchNominatim(query string) ([]*Place, error) {
 		return nil, err
 	}
 
+	places, err := parseNominatim(body)
+	return places, egress.CheckFormat("nominatim", nominatimFormat, err)
+}
+
+// nominatimFormat is the version of the Nominatim search response
+// parseNominatim understands: format=json. jsonv2, which renames class to
+// category, is read too.
+const nominatimFormat = "json"
+
+// parseNominatim turns a Nominatim search response into places. Results
+// without usable coordinates are skipped, but if none of them have any
+// the format has changed.
+func parseNominatim(body []byte) ([]*Place, error) {
 	var results []nominatimResult
 	if err := json.Unmarshal(body, &results); err != nil {
-		return nil, err
+		var e struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && len(e.Error) > 0 {
+			return nil, fmt.Errorf("nominatim: %s", e.Error)
+		}
+		return nil, egress.Drift("nominatim", "%v", err)
 	}
 
 	places := make([]*Place, 0, len(results))
@@ -166,10 +224,14 @@ func searchNominatim(query string) ([]*Place, error) {
 		addr := buildAddress(r)
 		name := extractDisplayName(r)
 
+		category := r.Class
+		if category == "" {
+			category = r.Category
+		}
 		p := &Place{
 			ID:          fmt.Sprintf("%d", r.PlaceID),
 			Name:        name,
-			Category:    r.Class,
+			Category:    category,
 			Type:        r.Type,
 			Address:     addr,
 			Lat:         lat,
@@ -190,6 +252,9 @@ func searchNominatim(query string) ([]*Place, error) {
 		}
 		places = append(places, p)
 	}
+	if len(places) == 0 && len(results) > 0 {
+		return nil, egress.Drift("nominatim", "none of %d results has a lat and lon", len(results))
+	}
 
 	return places, nil
 }
@@ -197,7 +262,8 @@ func searchNominatim(query string) ([]*Place, error) {
 // searchNearbyKeyword searches for POIs near a location whose name, category,
 // or cuisine matches the given keyword.
 // When a Google API key is configured, queries Google Places directly and
-// indexes the results into SQLite. Otherwise falls back to Overpass.
+// indexes the results into SQLite. Otherwise falls back to Overpass, then
+// to indexed places similar in meaning.
 func searchNearbyKeyword(query string, lat, lon float64, radiusM int) ([]*Place, error) {
 	if radiusM <= 0 {
 		radiusM = 1000
@@ -233,6 +299,13 @@ func searchNearbyKeyword(query string, lat, lon float64, radiusM int) ([]*Place,
 		return ovPlaces, nil
 	}
 
+	// Nothing by name: indexed places that match by meaning
+	if similar, err := searchPlacesSemantic(query, lat, lon, radiusM); err != nil {
+		app.Log("places", "semantic search error: %v", err)
+	} else if len(similar) > 0 {
+		return similar, nil
+	}
+
 	return nil, nil
 }
 
@@ -290,6 +363,19 @@ func geocode(address string) (float64, float64, error) {
 	return results[0].Lat, results[0].Lon, nil
 }
 
+// Geocode resolves a place name or address to its name and coordinates.
+func Geocode(query string) (string, float64, float64, error) {
+	results, err := searchNominatim(query)
+	if err != nil || len(results) == 0 {
+		return "", 0, 0, fmt.Errorf("could not find %s", query)
+	}
+	name := results[0].Name
+	if name == "" {
+		name = results[0].DisplayName
+	}
+	return name, results[0].Lat, results[0].Lon, nil
+}
+
 // haversine returns the great-circle distance in metres between two lat/lon points.
 func haversine(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371000 // Earth radius in metres
@@ -353,8 +439,37 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	case "/places/save/delete":
 		handleDeleteSavedSearch(w, r)
 		return
+	case "/places/place":
+		handlePlacePage(w, r)
+		return
+	case "/places/view":
+		handleView(w, r)
+		return
+	case "/places/favourites":
+		handleFavourites(w, r)
+		return
+	case "/places/favourites/delete":
+		handleFavouriteDelete(w, r)
+		return
+	case "/places/favourites/note":
+		handleFavouriteNote(w, r)
+		return
+	case "/places/reviews":
+		handleReviews(w, r)
+		return
+	case "/places/reviews/delete":
+		handleReviewDelete(w, r)
+		return
+	case "/places/halal":
+		handleHalal(w, r)
+		return
+	case "/places/import":
+		handleImport(w, r)
+		return
+	case "/places/import/delete":
+		handleImportDelete(w, r)
+		return
 	}
-
 	// Handle JSON API requests for /places
 	if app.WantsJSON(r) {
 		q := r.URL.Query().Get("q")
@@ -457,12 +572,21 @@ func handleSearch(w http.ResponseWriter, r *http.Request) {
 		results, err = searchNearbyKeyword(query, nearLat, nearLon, radiusM)
 	} else {
 		results, err = searchNominatim(query)
+		if err == nil {
+			// Index them like every other search, so their pages work.
+			go indexPlaces(results)
+		}
 	}
 	if err != nil {
 		app.Log("places", "Search error: %v", err)
 		app.ServerError(w, r, fmt.Sprintf("Search failed: %v", err))
 		return
 	}
+	if hasNearLoc {
+		results = withCommunity(results, query, nearLat, nearLon, radiusM)
+	} else {
+		results = withCommunity(results, query, 0, 0, 0)
+	}
 
 	// Apply sort order
 	sortBy := formValue("sort")
@@ -482,7 +606,7 @@ func handleSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Render results page
-	html := renderSearchResults(query, results, hasNearLoc, nearAddr, nearLat, nearLon, sortBy, radiusM)
+	html := renderSearchResults(query, results, hasNearLoc, nearAddr, nearLat, nearLon, sortBy, radiusM, favouriteIDs(acc.ID))
 	app.Respond(w, r, app.Response{
 		Title:       "Places - " + query,
 		Description: fmt.Sprintf("Search results for %s", query),
@@ -574,7 +698,15 @@ func handleNearby(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	results, err := findNearbyPlaces(lat, lon, radius)
+	// A curated filter narrows the search to its own queries.
+	filter := getFilter(formValue("filter"))
+	var results []*Place
+	if filter != nil {
+		results, err = findFilteredPlaces(filter, lat, lon, radius)
+	} else {
+		results, err = findNearbyPlaces(lat, lon, radius)
+		results = withCommunity(results, "", lat, lon, radius)
+	}
 	if err != nil {
 		app.Log("places", "Nearby error: %v", err)
 		app.ServerError(w, r, fmt.Sprintf("Nearby search failed: %v", err))
@@ -593,13 +725,17 @@ func handleNearby(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if app.WantsJSON(r) {
-		app.RespondJSON(w, map[string]interface{}{
+		resp := map[string]interface{}{
 			"results": results,
 			"count":   len(results),
 			"lat":     lat,
 			"lon":     lon,
 			"radius":  radius,
-		})
+		}
+		if filter != nil {
+			resp["filter"] = filter.Key
+		}
+		app.RespondJSON(w, resp)
 		return
 	}
 
@@ -608,7 +744,7 @@ func handleNearby(w http.ResponseWriter, r *http.Request) {
 	if label == "" {
 		label = fmt.Sprintf("%.4f, %.4f", lat, lon)
 	}
-	html := renderNearbyResults(label, lat, lon, radius, results)
+	html := renderNearbyResults(label, lat, lon, radius, filter, results, favouriteIDs(acc.ID))
 	app.Respond(w, r, app.Response{
 		Title:       "Nearby - " + label,
 		Description: fmt.Sprintf("Places near %s", label),
@@ -628,7 +764,7 @@ func renderPlacesPage(r *http.Request) string {
 
 	savedHTML := ""
 	if isLoggedIn {
-		savedHTML = renderSavedSearchesSection(acc.ID)
+		savedHTML = `<p><a href="/places/favourites">&#9733; Favourite places</a></p>` + renderSavedSearchesSection(acc.ID)
 	}
 
 	cityCardsHTML := renderCitiesSection()
@@ -653,12 +789,13 @@ func renderPlacesPage(r *http.Request) string {
 %s
 %s
 %s
-</div>`, authNote, renderSearchFormHTML("", "", "", "", "", ""), renderNearbyFormHTML("", "", "", ""), savedHTML, mapHTML, cityCardsHTML, renderPlacesPageJS())
+</div>`, authNote, renderSearchFormHTML("", "", "", "", "", ""), renderNearbyFormHTML("", "", "", "", ""), savedHTML, mapHTML, cityCardsHTML, renderPlacesPageJS())
 }
 
 // renderNearbyFormHTML returns a form for listing places near a location.
 // It is used on the main places page and on the nearby results page.
-func renderNearbyFormHTML(address, lat, lon, radius string) string {
+// filter is the key of the curated filter in use, if any.
+func renderNearbyFormHTML(address, lat, lon, radius, filter string) string {
 	if radius == "" {
 		radius = "1000"
 	}
@@ -683,6 +820,7 @@ func renderNearbyFormHTML(address, lat, lon, radius string) string {
 	return fmt.Sprintf(`<form id="nearby-form" action="/places/nearby" method="POST">
     <input type="hidden" name="lat" id="nearby-lat" value="%s">
     <input type="hidden" name="lon" id="nearby-lon" value="%s">
+    <input type="hidden" name="filter" id="nearby-filter" value="%s">
     <div class="places-location-row">
       <input type="text" name="address" id="nearby-address" placeholder="Address or postcode" value="%s">
       <a href="#" onclick="useNearbyLocation(this);return false;" class="btn-link">&#128205; Use my location</a>
@@ -693,8 +831,9 @@ func renderNearbyFormHTML(address, lat, lon, radius string) string {
     <div class="places-actions-row">
       <button type="submit">Find Nearby <span class="cost-badge">2p</span></button>
     </div>
+    %s
   </form>`,
-		escapeHTML(lat), escapeHTML(lon), escapeHTML(address), radiusOptions)
+		escapeHTML(lat), escapeHTML(lon), escapeHTML(filter), escapeHTML(address), radiusOptions, renderFilterChips(filter))
 }
 
 // renderIndexMap returns an embedded Leaflet.js map for the main places page.
@@ -710,7 +849,7 @@ var placesIndexMarker = null;
     // Default: world overview centred on 20°N 0°E, zoom 2
     lat = lat || 20; lon = lon || 0; zoom = zoom || 2;
     placesIndexMap = L.map('places-index-map').setView([lat, lon], zoom);
-    L.tileLayer('https://{s}.basemaps.cartocdn.com/light_all/{z}/{x}/{y}{r}.png',{
+    L.tileLayer('/tiles/{z}/{x}/{y}.png',{
       attribution:'&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors &copy; <a href="https://carto.com/attributions">CARTO</a>',maxZoom:19
     }).addTo(placesIndexMap);
     if (zoom > 2) {
@@ -730,11 +869,13 @@ var placesIndexMarker = null;
   function loadLeafletThenInit() {
     var lnk=document.createElement('link');
     lnk.rel='stylesheet';
-    lnk.href='https://unpkg.com/leaflet@1.9.4/dist/leaflet.css';
+    lnk.href='/leaflet/leaflet.css';
+    lnk.integrity='` + app.LeafletCSSIntegrity + `';
     lnk.crossOrigin='anonymous';
     document.head.appendChild(lnk);
     var s=document.createElement('script');
-    s.src='https://unpkg.com/leaflet@1.9.4/dist/leaflet.js';
+    s.src='/leaflet/leaflet.js';
+    s.integrity='` + app.LeafletJSIntegrity + `';
     s.crossOrigin='anonymous';
     s.onload=tryGeolocation;
     document.head.appendChild(s);
@@ -859,7 +1000,8 @@ func renderSavedSearchesSection(userID string) string {
 }
 
 // renderSearchResults renders search results as a list
-func renderSearchResults(query string, places []*Place, nearLocation bool, nearAddr string, nearLat, nearLon float64, sortBy string, radiusM int) string {
+// favs maps the viewer's starred places to their favourite IDs.
+func renderSearchResults(query string, places []*Place, nearLocation bool, nearAddr string, nearLat, nearLon float64, sortBy string, radiusM int, favs map[string]string) string {
 	var sb strings.Builder
 
 	nearLatStr, nearLonStr := "", ""
@@ -908,7 +1050,7 @@ func renderSearchResults(query string, places []*Place, nearLocation bool, nearA
 
 	sb.WriteString(`<div class="places-results">`)
 	for _, p := range places {
-		sb.WriteString(renderPlaceCard(p))
+		sb.WriteString(renderPlaceCard(p, favs))
 	}
 	sb.WriteString(`</div></div>`)
 
@@ -916,7 +1058,9 @@ func renderSearchResults(query string, places []*Place, nearLocation bool, nearA
 }
 
 // renderNearbyResults renders nearby search results as a list
-func renderNearbyResults(label string, lat, lon float64, radius int, places []*Place) string {
+// favs maps the viewer's starred places to their favourite IDs; filter is
+// the curated filter the search used, or nil.
+func renderNearbyResults(label string, lat, lon float64, radius int, filter *Filter, places []*Place, favs map[string]string) string {
 	var sb strings.Builder
 
 	radiusLabel := radiusName(radius)
@@ -926,10 +1070,14 @@ func renderNearbyResults(label string, lat, lon float64, radius int, places []*P
 
 	sb.WriteString(`<div class="places-page">`)
 	sb.WriteString(`<p><a href="/places">&larr; Back to Places</a></p>`)
-	sb.WriteString(renderNearbyFormHTML(label, latStr, lonStr, radiusStr))
+	filterKey, heading := "", "Nearby"
+	if filter != nil {
+		filterKey, heading = filter.Key, filter.Label+" nearby"
+	}
+	sb.WriteString(renderNearbyFormHTML(label, latStr, lonStr, radiusStr, filterKey))
 	sb.WriteString(renderPlacesPageJS())
 
-	sb.WriteString(`<h2>Nearby</h2>`)
+	sb.WriteString(fmt.Sprintf(`<h2>%s</h2>`, escapeHTML(heading)))
 	sb.WriteString(fmt.Sprintf(`<p class="text-muted"><strong>%s</strong> &middot; %s</p>`, escapeHTML(label), escapeHTML(radiusLabel)))
 
 	if len(places) == 0 {
@@ -943,7 +1091,7 @@ func renderNearbyResults(label string, lat, lon float64, radius int, places []*P
 
 	sb.WriteString(`<div class="places-results">`)
 	for _, p := range places {
-		sb.WriteString(renderPlaceCard(p))
+		sb.WriteString(renderPlaceCard(p, favs))
 	}
 	sb.WriteString(`</div></div>`)
 
@@ -965,7 +1113,7 @@ func renderLeafletMap(centerLat, centerLon float64, places []*Place) string {
 (function(){
   function initPlacesMap(){
     var map=L.map('places-map').setView([%f,%f],15);
-    L.tileLayer('https://{s}.basemaps.cartocdn.com/light_all/{z}/{x}/{y}{r}.png',{
+    L.tileLayer('/tiles/{z}/{x}/{y}.png',{
       attribution:'&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors &copy; <a href="https://carto.com/attributions">CARTO</a>',maxZoom:19
     }).addTo(map);
     var ps=%s;
@@ -981,11 +1129,13 @@ func renderLeafletMap(centerLat, centerLon float64, places []*Place) string {
   } else {
     var lnk=document.createElement('link');
     lnk.rel='stylesheet';
-    lnk.href='https://unpkg.com/leaflet@1.9.4/dist/leaflet.css';
+    lnk.href='/leaflet/leaflet.css';
+    lnk.integrity='`+app.LeafletCSSIntegrity+`';
     lnk.crossOrigin='anonymous';
     document.head.appendChild(lnk);
     var s=document.createElement('script');
-    s.src='https://unpkg.com/leaflet@1.9.4/dist/leaflet.js';
+    s.src='/leaflet/leaflet.js';
+    s.integrity='`+app.LeafletJSIntegrity+`';
     s.crossOrigin='anonymous';
     s.onload=initPlacesMap;
     document.head.appendChild(s);
@@ -1058,6 +1208,33 @@ function runSavedSearch(type, q, near, nearLat, nearLon, radius, sortBy) {
     document.getElementById('places-form').submit();
   }
 }
+document.addEventListener('submit', function(e) {
+  var form = e.target;
+  if (!form.classList || !form.classList.contains('place-fav-form') || location.pathname === '/places/favourites') return;
+  e.preventDefault();
+  var adding = form.getAttribute('action') === '/places/favourites';
+  var btn = form.querySelector('.place-fav-btn');
+  fetch(form.getAttribute('action'), {
+    method: 'POST',
+    headers: {'Accept': 'application/json', 'Content-Type': 'application/x-www-form-urlencoded'},
+    body: new URLSearchParams(new FormData(form))
+  }).then(function(res) {
+    return res.json().then(function(data) {
+      if (!res.ok) throw new Error(data.error || 'Could not update favourites');
+      return data;
+    });
+  }).then(function(data) {
+    if (adding) {
+      form.elements.id.value = data.favourite.id;
+      form.setAttribute('action', '/places/favourites/delete');
+      btn.innerHTML = '&#9733; Saved';
+    } else {
+      form.elements.id.value = '';
+      form.setAttribute('action', '/places/favourites');
+      btn.innerHTML = '&#9734; Save';
+    }
+  }).catch(function(err) { showToast(err.message, 'error'); });
+});
 function filterByType(btn) {
   var cat = btn.dataset.filter || '';
   document.querySelectorAll('.place-card').forEach(function(c) {
@@ -1070,8 +1247,10 @@ function filterByType(btn) {
 </script>`
 }
 
-// renderPlaceCard renders a single place card with rich details and map links
-func renderPlaceCard(p *Place) string {
+// renderPlaceCard renders a single place card with rich details and map
+// links. favs maps the viewer's starred places to their favourite IDs;
+// nil leaves out the star.
+func renderPlaceCard(p *Place, favs map[string]string) string {
 	cat := ""
 	if p.Category != "" {
 		label := strings.ReplaceAll(p.Category, "_", " ")
@@ -1080,6 +1259,10 @@ func renderPlaceCard(p *Place) string {
 		}
 		cat = fmt.Sprintf(` <span class="place-category">%s</span>`, escapeHTML(label))
 	}
+	cat += renderHalalBadge(p)
+	if p.Source == communitySource {
+		cat += ` <span class="place-source" title="From a community-kept list">Community</span>`
+	}
 
 	addr := p.Address
 	if addr == "" && p.DisplayName != "" {
@@ -1122,11 +1305,59 @@ func renderPlaceCard(p *Place) string {
 		extraHTML += fmt.Sprintf(`<p class="place-info"><a href="%s" target="_blank" rel="noopener noreferrer">Website &#8599;</a></p>`, escapeHTML(p.Website))
 	}
 
+	ratingHTML := renderRatingSummary(p.Lat, p.Lon, p.Name)
+	if ratingHTML == "" && favs != nil {
+		ratingHTML = fmt.Sprintf(`<a href="%s#reviews">Review</a>`, escapeHTML(placePageURL(Embed{Name: p.Name, Lat: p.Lat, Lon: p.Lon})))
+	}
+	if ratingHTML != "" {
+		ratingHTML = " &middot; " + ratingHTML
+	}
+
+	starHTML := ""
+	if favs != nil {
+		starHTML = renderFavouriteStar(p, favs[placeKey(p)])
+	}
+
+	// Indexed places have their own page; the name links there and Google
+	// Maps moves to the links.
+	titleHTML := fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener">%s</a>`, gmapsViewURL, escapeHTML(p.Name))
+	mapsHTML := ""
+	if p.ID != "" {
+		titleHTML = fmt.Sprintf(`<a href="%s">%s</a>`, escapeHTML(viewURL(p.ID)), escapeHTML(p.Name))
+		mapsHTML = fmt.Sprintf(` &middot; <a href="%s" target="_blank" rel="noopener">Google Maps &#8599;</a>`, gmapsViewURL)
+	}
+
 	return fmt.Sprintf(`<div class="card place-card" data-category="%s">
-  <h4><a href="%s" target="_blank" rel="noopener">%s</a>%s%s</h4>
+  <h4>%s%s%s</h4>
   %s%s
-  <p class="place-links"><a href="%s" target="_blank" rel="noopener">Get Directions</a></p>
-</div>`, escapeHTML(p.Category), gmapsViewURL, escapeHTML(p.Name), cat, distHTML, addrHTML, extraHTML, gmapsDirURL)
+  <p class="place-links"><a href="%s" target="_blank" rel="noopener">Get Directions</a>%s%s%s</p>
+</div>`, escapeHTML(p.Category), titleHTML, cat, distHTML, addrHTML, extraHTML, gmapsDirURL, mapsHTML, ratingHTML, starHTML)
+}
+
+// renderFavouriteStar renders the button that stars a place, or unstars
+// it if favID is set. The form carries the place itself so starring
+// keeps a snapshot of it.
+func renderFavouriteStar(p *Place, favID string) string {
+	action, label := "/places/favourites", "&#9734; Save"
+	if favID != "" {
+		action, label = "/places/favourites/delete", "&#9733; Saved"
+	}
+	return fmt.Sprintf(`<form class="place-fav-form" action="%s" method="POST">
+  <input type="hidden" name="id" value="%s">
+  <input type="hidden" name="place_id" value="%s">
+  <input type="hidden" name="name" value="%s">
+  <input type="hidden" name="category" value="%s">
+  <input type="hidden" name="type" value="%s">
+  <input type="hidden" name="address" value="%s">
+  <input type="hidden" name="lat" value="%f">
+  <input type="hidden" name="lon" value="%f">
+  <input type="hidden" name="phone" value="%s">
+  <input type="hidden" name="website" value="%s">
+  <input type="hidden" name="opening_hours" value="%s">
+  <input type="hidden" name="cuisine" value="%s">
+  <button type="submit" class="btn-link place-fav-btn">%s</button>
+</form>`, action, escapeHTML(favID), escapeHTML(p.ID), escapeHTML(p.Name), escapeHTML(p.Category), escapeHTML(p.Type),
+		escapeHTML(p.Address), p.Lat, p.Lon, escapeHTML(p.Phone), escapeHTML(p.Website), escapeHTML(p.OpeningHours), escapeHTML(p.Cuisine), label)
 }
 
 // renderTypeFilter renders category filter buttons for a set of places.
@@ -0,0 +1,102 @@
+package places
+
+import (
+	"testing"
+
+	"mu/internal/egress"
+	"mu/internal/testutil"
+)
+
+func TestParseNominatim_Fixture(t *testing.T) {
+	places, err := parseNominatim(testutil.Fixture(t, "nominatim_search.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(places) != 2 {
+		t.Fatalf("got %d places, want 2", len(places))
+	}
+	p := places[0]
+	if p.ID != "259138263" || p.Name != "Café in the Crypt" || p.Category != "amenity" || p.Type != "cafe" {
+		t.Errorf("place = %+v", p)
+	}
+	if p.Lat != 51.508019 || p.Lon != -0.128118 {
+		t.Errorf("coordinates = %v, %v", p.Lat, p.Lon)
+	}
+	if p.Address != "1 Duncannon Street, London, WC2N 4JJ, United Kingdom" {
+		t.Errorf("address = %q", p.Address)
+	}
+	if p.Phone != "+44 20 7766 1158" || p.OpeningHours == "" || p.Website == "" {
+		t.Errorf("extra tags not read: %+v", p)
+	}
+	if places[1].Phone != "+44 20 7766 1100" {
+		t.Errorf("phone = %q", places[1].Phone)
+	}
+}
+
+func TestParseNominatim_JSONv2(t *testing.T) {
+	places, err := parseNominatim(testutil.Fixture(t, "nominatim_search_v2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(places) != 1 || places[0].Category != "amenity" {
+		t.Fatalf("places = %+v, want the category read from jsonv2", places)
+	}
+}
+
+func TestParseNominatim_Errors(t *testing.T) {
+	if places, err := parseNominatim([]byte(`[]`)); err != nil || len(places) != 0 {
+		t.Errorf("no results: %v, %v", places, err)
+	}
+	if _, err := parseNominatim([]byte(`{"error":"Unable to geocode"}`)); err == nil || egress.IsDrift(err) {
+		t.Errorf("upstream error: err = %v, want Nominatim's error, not drift", err)
+	}
+
+	drift := map[string]string{
+		"not json":        `<html><body>Bandwidth limit exceeded</body></html>`,
+		"object":          `{"results":[]}`,
+		"numeric coords":  `[{"place_id":1,"lat":51.5,"lon":-0.12}]`,
+		"renamed coords":  `[{"place_id":1,"latitude":"51.5","longitude":"-0.12"}]`,
+		"place_id string": `[{"place_id":"N123","lat":"51.5","lon":"-0.12"}]`,
+	}
+	for name, body := range drift {
+		if _, err := parseNominatim([]byte(body)); !egress.IsDrift(err) {
+			t.Errorf("%s: err = %v, want drift", name, err)
+		}
+	}
+}
+
+func TestParseOverpass_Fixture(t *testing.T) {
+	elements, err := parseOverpass(testutil.Fixture(t, "overpass_city.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 3 {
+		t.Fatalf("got %d elements, want 3", len(elements))
+	}
+	if el := elements[0]; el.Type != "node" || el.Lat != 51.508019 || el.Tags["name"] != "Café in the Crypt" {
+		t.Errorf("node = %+v", el)
+	}
+	if el := elements[1]; el.Type != "way" || el.Center == nil || el.Center.Lat != 51.5081124 {
+		t.Errorf("way = %+v, want its center", el)
+	}
+}
+
+func TestParseOverpass_Errors(t *testing.T) {
+	if _, err := parseOverpass(testutil.Fixture(t, "overpass_timeout.json")); err == nil || egress.IsDrift(err) {
+		t.Errorf("timeout: err = %v, want the remark, not drift", err)
+	}
+	if elements, err := parseOverpass([]byte(`{"version":0.6,"elements":[]}`)); err != nil || len(elements) != 0 {
+		t.Errorf("nothing found: %v, %v", elements, err)
+	}
+
+	drift := map[string]string{
+		"xml":         `<?xml version="1.0" encoding="UTF-8"?><osm version="0.6"></osm>`,
+		"no elements": `{"version":0.6,"features":[]}`,
+		"id string":   `{"elements":[{"type":"node","id":"n1","lat":51.5,"lon":-0.12}]}`,
+	}
+	for name, body := range drift {
+		if _, err := parseOverpass([]byte(body)); !egress.IsDrift(err) {
+			t.Errorf("%s: err = %v, want drift", name, err)
+		}
+	}
+}
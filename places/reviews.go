@@ -0,0 +1,478 @@
+package places
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/flag"
+
+	"github.com/google/uuid"
+)
+
+// Reviews are members' ratings of a place, one to five stars with a few
+// words, one per member per place. They're keyed like mentions, by the
+// place's coordinates, so a search result and the place page agree.
+// Reviews go through moderation like comments: they can be reported,
+// three reports hide one, and hidden reviews don't count towards the
+// average.
+
+// Review is a member's rating of a place.
+type Review struct {
+	ID        string    `json:"id"`
+	PlaceID   string    `json:"place_id"` // Embed.Key of the place
+	PlaceName string    `json:"place_name"`
+	Lat       float64   `json:"lat"`
+	Lon       float64   `json:"lon"`
+	UserID    string    `json:"user_id"`
+	Author    string    `json:"author"`
+	Rating    int       `json:"rating"` // 1 to 5
+	Text      string    `json:"text,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// reviewContentType is the review's type to the moderation subsystem.
+const reviewContentType = "place_review"
+
+const maxReviewLen = 1000
+
+var errReviewNotFound = errors.New("review not found")
+
+var (
+	reviewsMu sync.RWMutex
+	reviews   = map[string][]*Review{} // place key -> reviews, newest first
+)
+
+func loadReviews() {
+	var d map[string][]*Review
+	if err := data.LoadJSON("place_reviews.json", &d); err == nil && d != nil {
+		reviewsMu.Lock()
+		reviews = d
+		reviewsMu.Unlock()
+	}
+	flag.RegisterDeleter(reviewContentType, reviewDeleter{})
+}
+
+func saveReviews() {
+	data.SaveJSON("place_reviews.json", reviews)
+}
+
+// reviewKey identifies the place at lat, lon.
+func reviewKey(lat, lon float64) string {
+	return Embed{Lat: lat, Lon: lon}.Key()
+}
+
+// visibleReview reports whether a review is shown: not hidden by
+// moderation and not by a banned member.
+func visibleReview(rv *Review) bool {
+	return !flag.IsHidden(reviewContentType, rv.ID) && !auth.IsBanned(rv.UserID)
+}
+
+// Reviews returns the visible reviews of the place at lat, lon, newest
+// first.
+func Reviews(lat, lon float64) []*Review {
+	reviewsMu.RLock()
+	defer reviewsMu.RUnlock()
+	var out []*Review
+	for _, rv := range reviews[reviewKey(lat, lon)] {
+		if visibleReview(rv) {
+			out = append(out, rv)
+		}
+	}
+	return out
+}
+
+// Rating is the average of the visible reviews of the place at lat, lon,
+// and how many there are.
+func Rating(lat, lon float64) (float64, int) {
+	list := Reviews(lat, lon)
+	if len(list) == 0 {
+		return 0, 0
+	}
+	sum := 0
+	for _, rv := range list {
+		sum += rv.Rating
+	}
+	return float64(sum) / float64(len(list)), len(list)
+}
+
+// userReview returns userID's review of the place at lat, lon, if any.
+func userReview(userID string, lat, lon float64) *Review {
+	reviewsMu.RLock()
+	defer reviewsMu.RUnlock()
+	for _, rv := range reviews[reviewKey(lat, lon)] {
+		if rv.UserID == userID {
+			return rv
+		}
+	}
+	return nil
+}
+
+// AddReview records acc's review of a place, replacing any review they
+// already left on it.
+func AddReview(acc *auth.Account, name string, lat, lon float64, rating int, text string) (*Review, error) {
+	if lat < -85 || lat > 85 || lon < -180 || lon > 180 {
+		return nil, errors.New("a review needs the place's location")
+	}
+	if rating < 1 || rating > 5 {
+		return nil, errors.New("rate the place from 1 to 5 stars")
+	}
+	text = strings.TrimSpace(text)
+	if len(text) > maxReviewLen {
+		return nil, fmt.Errorf("keep the review under %d characters", maxReviewLen)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fmt.Sprintf("%.5f, %.5f", lat, lon)
+	}
+
+	reviewsMu.Lock()
+	key := reviewKey(lat, lon)
+	var rv *Review
+	for _, existing := range reviews[key] {
+		if existing.UserID == acc.ID {
+			rv = existing
+			break
+		}
+	}
+	if rv == nil {
+		rv = &Review{ID: uuid.New().String(), PlaceID: key, UserID: acc.ID, CreatedAt: time.Now()}
+		reviews[key] = append([]*Review{rv}, reviews[key]...)
+	} else {
+		rv.UpdatedAt = time.Now()
+	}
+	rv.PlaceName, rv.Lat, rv.Lon = name, lat, lon
+	rv.Author, rv.Rating, rv.Text = acc.Name, rating, text
+	saveReviews()
+	reviewsMu.Unlock()
+
+	if text != "" {
+		go flag.CheckContent(reviewContentType, rv.ID, "Review of "+name, text)
+	}
+	return rv, nil
+}
+
+// findReview returns the review with id.
+func findReview(id string) *Review {
+	reviewsMu.RLock()
+	defer reviewsMu.RUnlock()
+	for _, list := range reviews {
+		for _, rv := range list {
+			if rv.ID == id {
+				return rv
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteReview removes the review with id. userID must have written it,
+// unless it's empty, as when a moderator rejects it.
+func DeleteReview(userID, id string) error {
+	reviewsMu.Lock()
+	defer reviewsMu.Unlock()
+	for key, list := range reviews {
+		for i, rv := range list {
+			if rv.ID != id || (userID != "" && rv.UserID != userID) {
+				continue
+			}
+			reviews[key] = append(list[:i:i], list[i+1:]...)
+			if len(reviews[key]) == 0 {
+				delete(reviews, key)
+			}
+			saveReviews()
+			return nil
+		}
+	}
+	return errReviewNotFound
+}
+
+// userReviews returns every review userID has written, newest first.
+func userReviews(userID string) []*Review {
+	reviewsMu.RLock()
+	defer reviewsMu.RUnlock()
+	var out []*Review
+	for _, list := range reviews {
+		for _, rv := range list {
+			if rv.UserID == userID {
+				out = append(out, rv)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// DeleteReviews removes a deleted account's reviews.
+func DeleteReviews(userID string) {
+	reviewsMu.Lock()
+	defer reviewsMu.Unlock()
+	changed := false
+	for key, list := range reviews {
+		kept := list[:0:0]
+		for _, rv := range list {
+			if rv.UserID != userID {
+				kept = append(kept, rv)
+			}
+		}
+		if len(kept) == len(list) {
+			continue
+		}
+		changed = true
+		if len(kept) == 0 {
+			delete(reviews, key)
+		} else {
+			reviews[key] = kept
+		}
+	}
+	if changed {
+		saveReviews()
+	}
+}
+
+// RenameReviews moves a renamed account's reviews to its new ID.
+func RenameReviews(oldID, newID string) {
+	reviewsMu.Lock()
+	defer reviewsMu.Unlock()
+	changed := false
+	for _, list := range reviews {
+		for _, rv := range list {
+			if rv.UserID == oldID {
+				rv.UserID = newID
+				changed = true
+			}
+		}
+	}
+	if changed {
+		saveReviews()
+	}
+}
+
+// reviewDeleter implements flag.ContentDeleter for reviews.
+type reviewDeleter struct{}
+
+func (reviewDeleter) Delete(id string) error {
+	return DeleteReview("", id)
+}
+
+func (reviewDeleter) Get(id string) interface{} {
+	rv := findReview(id)
+	if rv == nil {
+		return nil
+	}
+	return flag.PostContent{
+		ID:        rv.ID,
+		Title:     "Review of " + rv.PlaceName,
+		Content:   fmt.Sprintf("%s %s", stars(rv.Rating), rv.Text),
+		Author:    rv.Author,
+		AuthorID:  rv.UserID,
+		CreatedAt: rv.CreatedAt,
+		URL:       placePageURL(Embed{Name: rv.PlaceName, Lat: rv.Lat, Lon: rv.Lon}) + "#reviews",
+	}
+}
+
+// Hidden reviews are filtered as pages are rendered.
+func (reviewDeleter) RefreshCache() {}
+
+// stars renders a rating as filled and empty stars.
+func stars(rating int) string {
+	return strings.Repeat("★", rating) + strings.Repeat("☆", 5-rating)
+}
+
+// handleReviews serves /places/reviews: GET lists a place's reviews and
+// rating as JSON, for ?lat= and ?lon=, and POST adds or replaces the
+// member's review from lat, lon, name, rating and text. A place_id
+// returns to that place's page rather than the one for the coordinates.
+func handleReviews(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		lat, err1 := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+		lon, err2 := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+		if err1 != nil || err2 != nil {
+			app.BadRequest(w, r, "lat and lon are required")
+			return
+		}
+		if !app.WantsJSON(r) {
+			http.Redirect(w, r, placePageURL(Embed{Name: r.URL.Query().Get("name"), Lat: lat, Lon: lon})+"#reviews", http.StatusSeeOther)
+			return
+		}
+		avg, count := Rating(lat, lon)
+		list := Reviews(lat, lon)
+		if list == nil {
+			list = []*Review{}
+		}
+		app.RespondJSON(w, map[string]interface{}{"rating": avg, "count": count, "reviews": list})
+
+	case http.MethodPost:
+		_, acc, err := auth.RequireSession(r)
+		if err != nil {
+			if app.WantsJSON(r) || app.SendsJSON(r) {
+				app.Unauthorized(w, r)
+			} else {
+				app.RedirectToLogin(w, r)
+			}
+			return
+		}
+		var req struct {
+			Name   string  `json:"name"`
+			Lat    float64 `json:"lat"`
+			Lon    float64 `json:"lon"`
+			Rating int     `json:"rating"`
+			Text   string  `json:"text"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "Invalid JSON body")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.Name = r.FormValue("name")
+			req.Lat, _ = strconv.ParseFloat(r.FormValue("lat"), 64)
+			req.Lon, _ = strconv.ParseFloat(r.FormValue("lon"), 64)
+			req.Rating, _ = strconv.Atoi(r.FormValue("rating"))
+			req.Text = r.FormValue("text")
+		}
+		page := placePageURL(Embed{Name: req.Name, Lat: req.Lat, Lon: req.Lon})
+		if id := r.FormValue("place_id"); id != "" {
+			page = viewURL(id)
+		}
+		rv, err := AddReview(acc, req.Name, req.Lat, req.Lon, req.Rating, req.Text)
+		if err != nil {
+			if app.WantsJSON(r) || app.SendsJSON(r) {
+				app.BadRequest(w, r, err.Error())
+			} else {
+				http.Redirect(w, r, page+"&error="+url.QueryEscape(err.Error())+"#reviews", http.StatusSeeOther)
+			}
+			return
+		}
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"review": rv})
+			return
+		}
+		http.Redirect(w, r, page+"#reviews", http.StatusSeeOther)
+
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+// handleReviewDelete serves POST /places/reviews/delete, for a member
+// removing their own review.
+func handleReviewDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	var id string
+	if app.SendsJSON(r) {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "Invalid JSON body")
+			return
+		}
+		id = req.ID
+	} else {
+		r.ParseForm()
+		id = r.FormValue("id")
+	}
+	rv := findReview(id)
+	if rv == nil || rv.UserID != acc.ID {
+		app.NotFound(w, r, errReviewNotFound.Error())
+		return
+	}
+	DeleteReview(acc.ID, id)
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		app.RespondJSON(w, map[string]bool{"ok": true})
+		return
+	}
+	http.Redirect(w, r, placePageURL(Embed{Name: rv.PlaceName, Lat: rv.Lat, Lon: rv.Lon})+"#reviews", http.StatusSeeOther)
+}
+
+// renderRatingSummary renders a place's average, linking to its reviews,
+// or nothing if it has none.
+func renderRatingSummary(lat, lon float64, name string) string {
+	avg, count := Rating(lat, lon)
+	if count == 0 {
+		return ""
+	}
+	noun := "reviews"
+	if count == 1 {
+		noun = "review"
+	}
+	return fmt.Sprintf(`<a href="%s#reviews" class="place-rating" title="%.1f out of 5">&#9733; %.1f <span class="text-muted">(%d %s)</span></a>`,
+		escapeHTML(placePageURL(Embed{Name: name, Lat: lat, Lon: lon})), avg, avg, count, noun)
+}
+
+// renderReviewsSection renders the reviews on a place's page, with a form
+// for a signed-in viewer to leave or change theirs. placeID is set on an
+// indexed place's page, to come back to it.
+func renderReviewsSection(e Embed, placeID string, acc *auth.Account, errMsg string) string {
+	var sb strings.Builder
+	sb.WriteString(`<h3 id="reviews">Reviews</h3>`)
+	list := Reviews(e.Lat, e.Lon)
+	if avg, count := Rating(e.Lat, e.Lon); count > 0 {
+		sb.WriteString(fmt.Sprintf(`<p><span class="place-rating">%s</span> %.1f out of 5 from %d review(s)</p>`, stars(int(avg+0.5)), avg, count))
+	} else {
+		sb.WriteString(`<p class="text-muted">No reviews yet.</p>`)
+	}
+	for _, rv := range list {
+		actions := ""
+		if acc != nil && acc.ID == rv.UserID {
+			actions = fmt.Sprintf(`<form class="d-inline" action="/places/reviews/delete" method="POST"><input type="hidden" name="id" value="%s"><button type="submit" class="btn-link text-muted">Delete</button></form>`, escapeHTML(rv.ID))
+		} else if acc != nil {
+			actions = fmt.Sprintf(`<a href="#" class="text-muted" data-flag="%s" data-id="%s">Report</a>`, reviewContentType, escapeHTML(rv.ID))
+		}
+		text := ""
+		if rv.Text != "" {
+			text = fmt.Sprintf(`<p class="whitespace-pre-wrap">%s</p>`, escapeHTML(rv.Text))
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="place-review"><p><span class="place-rating">%s</span> <a href="/@%s">%s</a> <span class="text-muted text-sm">%s</span> %s</p>%s</div>`,
+			stars(rv.Rating), escapeHTML(rv.UserID), escapeHTML(rv.Author), app.TimeAgo(rv.CreatedAt), actions, text))
+	}
+
+	if acc == nil {
+		sb.WriteString(`<p class="text-muted text-sm"><a href="/login">Login</a> to review this place.</p>`)
+		return sb.String()
+	}
+	rating, text, label := 0, "", "Leave a review"
+	if mine := userReview(acc.ID, e.Lat, e.Lon); mine != nil {
+		rating, text, label = mine.Rating, mine.Text, "Update your review"
+	}
+	sb.WriteString(`<form class="place-review-form" action="/places/reviews" method="POST">`)
+	sb.WriteString(fmt.Sprintf(`<h4>%s</h4>`, label))
+	if errMsg != "" {
+		sb.WriteString(fmt.Sprintf(`<p class="text-error">%s</p>`, escapeHTML(errMsg)))
+	}
+	sb.WriteString(fmt.Sprintf(`<input type="hidden" name="name" value="%s"><input type="hidden" name="lat" value="%f"><input type="hidden" name="lon" value="%f"><input type="hidden" name="place_id" value="%s">`,
+		escapeHTML(e.Name), e.Lat, e.Lon, escapeHTML(placeID)))
+	sb.WriteString(`<select name="rating" required><option value="">Rating</option>`)
+	for i := 5; i >= 1; i-- {
+		selected := ""
+		if i == rating {
+			selected = " selected"
+		}
+		sb.WriteString(fmt.Sprintf(`<option value="%d"%s>%s</option>`, i, selected, stars(i)))
+	}
+	sb.WriteString(`</select>`)
+	sb.WriteString(fmt.Sprintf(`<textarea name="text" rows="3" maxlength="%d" placeholder="What was it like? (optional)">%s</textarea>`, maxReviewLen, escapeHTML(text)))
+	sb.WriteString(`<button type="submit">Save review</button></form>`)
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package places
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/flag"
+)
+
+func TestReviews(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	sessions := map[string]string{}
+	for _, id := range []string{"rev_a", "rev_b"} {
+		if err := auth.Create(&auth.Account{ID: id, Name: id, Secret: "secret", Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(id)
+		sess, err := auth.Login(id, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[id] = sess.Token
+	}
+
+	reviewsMu.Lock()
+	orig := reviews
+	reviews = map[string][]*Review{}
+	reviewsMu.Unlock()
+	defer func() {
+		reviewsMu.Lock()
+		reviews = orig
+		reviewsMu.Unlock()
+	}()
+
+	post := func(user, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: "session", Value: sessions[user]})
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+
+	if w := post("rev_a", "/places/reviews", "name=Corner+Cafe&lat=51.5&lon=-0.12&rating=5&text=great"); w.Code != http.StatusSeeOther || !strings.HasSuffix(w.Header().Get("Location"), "#reviews") {
+		t.Fatalf("review: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	post("rev_b", "/places/reviews", "name=Corner+Cafe&lat=51.50001&lon=-0.12&rating=2")
+	// A second review from the same member replaces the first.
+	post("rev_b", "/places/reviews", "name=Corner+Cafe&lat=51.5&lon=-0.12&rating=3&text=ok")
+	if w := post("rev_a", "/places/reviews", "name=Corner+Cafe&lat=51.5&lon=-0.12&rating=6"); !strings.Contains(w.Header().Get("Location"), "error=") {
+		t.Error("rating out of range accepted")
+	}
+
+	if avg, n := Rating(51.5, -0.12); n != 2 || avg != 4 {
+		t.Fatalf("rating = %.1f from %d", avg, n)
+	}
+	card := renderPlaceCard(&Place{Name: "Corner Cafe", Lat: 51.5, Lon: -0.12}, nil)
+	if !strings.Contains(card, "&#9733; 4.0") || !strings.Contains(card, "(2 reviews)") {
+		t.Errorf("card doesn't show the average: %s", card)
+	}
+
+	// Reported reviews are hidden and drop out of the average.
+	mine := userReview("rev_b", 51.5, -0.12)
+	if c, ok := (reviewDeleter{}).Get(mine.ID).(flag.PostContent); !ok || c.AuthorID != "rev_b" || !strings.Contains(c.URL, "/places/place?") {
+		t.Errorf("moderation view = %+v", c)
+	}
+	flag.AdminFlag(reviewContentType, mine.ID, "admin")
+	defer flag.Delete(reviewContentType, mine.ID)
+	if avg, n := Rating(51.5, -0.12); n != 1 || avg != 5 {
+		t.Errorf("rating with a hidden review = %.1f from %d", avg, n)
+	}
+
+	r := httptest.NewRequest("GET", "/places/place?lat=51.5&lon=-0.12&name=Corner+Cafe", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: sessions["rev_a"]})
+	w := httptest.NewRecorder()
+	Handler(w, r)
+	page := w.Body.String()
+	if !strings.Contains(page, "great") || strings.Contains(page, "<p class=\"whitespace-pre-wrap\">ok</p>") || !strings.Contains(page, "Update your review") {
+		t.Error("place page shows the wrong reviews or form")
+	}
+
+	if w := post("rev_b", "/places/reviews/delete", "id="+userReview("rev_a", 51.5, -0.12).ID); w.Code != http.StatusNotFound {
+		t.Errorf("deleting someone else's review: %d", w.Code)
+	}
+	DeleteReviews("rev_a")
+	if avg, n := Rating(51.5, -0.12); n != 0 || avg != 0 {
+		t.Errorf("deleted account's review still counted: %.1f from %d", avg, n)
+	}
+}
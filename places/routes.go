@@ -0,0 +1,14 @@
+package places
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/places", Handler) // public map; search needs a session
+	r.HandleFunc("/places/", Handler)
+	r.HandleFunc("/tiles/", handleTile, app.Dynamic)       // map tiles, proxied
+	r.HandleFunc("/places/tile/", handleTile, app.Dynamic) // tiles for older embeds
+}
@@ -0,0 +1,147 @@
+package places
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// Place pages. Every place a search finds is kept in the local index, so
+// /places/view?id= can show it in full at a stable address: its map,
+// details, reviews and similar places nearby. Result cards link there.
+
+// similarRadius is how far around a place to look for similar ones.
+const similarRadius = 1000
+
+// maxSimilar is how many similar places a place page lists.
+const maxSimilar = 6
+
+// viewURL is the permalink of the indexed place with id.
+func viewURL(id string) string {
+	return "/places/view?id=" + url.QueryEscape(id)
+}
+
+// similarPlaces returns indexed places of the same category near p,
+// nearest first.
+func similarPlaces(p *Place) []*Place {
+	if p.Category == "" {
+		return nil
+	}
+	nearby, err := searchPlacesFTS("", p.Lat, p.Lon, similarRadius, true)
+	if err != nil {
+		return nil
+	}
+	var out []*Place
+	for _, n := range nearby {
+		if n.ID == p.ID || n.Category != p.Category {
+			continue
+		}
+		out = append(out, n)
+		if len(out) == maxSimilar {
+			break
+		}
+	}
+	return out
+}
+
+// handleView serves /places/view?id=, the page of an indexed place.
+func handleView(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.URL.Query().Get("id"))
+	if id == "" {
+		app.BadRequest(w, r, "id is required")
+		return
+	}
+	p, err := getIndexedPlace(id)
+	if err != nil {
+		app.Log("places", "view %s: %v", id, err)
+		app.ServerError(w, r, "Could not load the place")
+		return
+	}
+	if p == nil {
+		app.NotFound(w, r, "Place not found")
+		return
+	}
+	similar := similarPlaces(p)
+	e := Embed{Name: p.Name, Lat: p.Lat, Lon: p.Lon}
+
+	if app.WantsJSON(r) {
+		reviewList := Reviews(p.Lat, p.Lon)
+		if reviewList == nil {
+			reviewList = []*Review{}
+		}
+		if similar == nil {
+			similar = []*Place{}
+		}
+		avg, count := Rating(p.Lat, p.Lon)
+		app.RespondJSON(w, map[string]interface{}{
+			"place":          p,
+			"url":            viewURL(p.ID),
+			"shortcode":      e.Shortcode(),
+			"rating":         avg,
+			"review_count":   count,
+			"reviews":        reviewList,
+			"similar":        similar,
+			"halal":          p.IsHalal() || VerifiedHalal(p) != nil,
+			"verified_halal": VerifiedHalal(p) != nil,
+		})
+		return
+	}
+
+	_, acc := auth.TrySession(r)
+	var favs map[string]string
+	if acc != nil {
+		favs = favouriteIDs(acc.ID)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="places-page">`)
+	sb.WriteString(`<p><a href="/places">&larr; Back to Places</a></p>`)
+	sb.WriteString(renderLeafletMap(p.Lat, p.Lon, []*Place{p}))
+	// The card without its distance is the place's details.
+	sb.WriteString(renderPlaceCard(p, favs))
+	sb.WriteString(fmt.Sprintf(`<p class="place-links"><a href="/places/nearby?lat=%.5f&lon=%.5f">What's nearby</a>`, p.Lat, p.Lon))
+	if acc != nil && acc.Admin {
+		sb.WriteString(" &middot; " + renderHalalForm(p))
+	}
+	sb.WriteString(`</p>`)
+
+	sb.WriteString(renderReviewsSection(e, p.ID, acc, r.URL.Query().Get("error")))
+
+	if len(similar) > 0 {
+		sb.WriteString(`<h3>Similar places nearby</h3><div class="places-results">`)
+		for _, s := range similar {
+			sb.WriteString(renderPlaceCard(s, favs))
+		}
+		sb.WriteString(`</div>`)
+	}
+
+	if list := MentionsOf(e); len(list) > 0 {
+		sb.WriteString(`<h3>Mentioned in</h3>`)
+		for _, m := range list {
+			title := m.Title
+			if title == "" {
+				title = "Untitled"
+			}
+			sb.WriteString(fmt.Sprintf(`<p><a href="%s">%s</a> <span class="text-muted text-sm">%s</span></p>`,
+				escapeHTML(m.URL), escapeHTML(title), app.TimeAgo(m.Time)))
+		}
+	}
+	sb.WriteString(fmt.Sprintf(`<p class="text-muted text-sm mt-5">Link: <code>%s</code> &middot; Embed in a post: <code>%s</code></p>`,
+		escapeHTML(app.PublicURL()+viewURL(p.ID)), escapeHTML(e.Shortcode())))
+	sb.WriteString(renderPlacesPageJS())
+	sb.WriteString(`</div>`)
+
+	desc := p.Name
+	if p.Address != "" {
+		desc += ", " + p.Address
+	}
+	app.Respond(w, r, app.Response{
+		Title:       p.Name,
+		Description: desc,
+		HTML:        sb.String(),
+	})
+}
@@ -0,0 +1,65 @@
+package places
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestViewPlace(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	placesDBOne = sync.Once{}
+	placesDB = nil
+
+	indexPlaces([]*Place{
+		{ID: "v1", Name: "Blue Bottle Coffee", Category: "cafe", Address: "Market St", Lat: 37.7749, Lon: -122.4194, Phone: "+1 555 0100", OpeningHours: "Mo-Fr 07:00-18:00"},
+		{ID: "v2", Name: "Zuni Cafe", Category: "cafe", Address: "Market St", Lat: 37.7751, Lon: -122.4198},
+		{ID: "v3", Name: "Tartine Bakery", Category: "bakery", Address: "Guerrero St", Lat: 37.7755, Lon: -122.4190},
+	})
+
+	get := func(path string, wantJSON bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if wantJSON {
+			r.Header.Set("Accept", "application/json")
+		}
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+
+	w := get("/places/view?id=v1", false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("view: %d", w.Code)
+	}
+	page := w.Body.String()
+	for _, want := range []string{"Blue Bottle Coffee", "+1 555 0100", "Mo-Fr 07:00-18:00", `id="reviews"`, "Similar places nearby", "Zuni Cafe"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	if strings.Contains(page, "Tartine Bakery") {
+		t.Error("a place of another category is listed as similar")
+	}
+
+	var got struct {
+		Place   *Place   `json:"place"`
+		URL     string   `json:"url"`
+		Similar []*Place `json:"similar"`
+	}
+	if err := json.NewDecoder(get("/places/view?id=v1", true).Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Place == nil || got.Place.Name != "Blue Bottle Coffee" || got.URL != "/places/view?id=v1" || len(got.Similar) != 1 || got.Similar[0].ID != "v2" {
+		t.Errorf("JSON = %+v", got)
+	}
+
+	if w := get("/places/view?id=missing", false); w.Code != http.StatusNotFound {
+		t.Errorf("unknown place: %d", w.Code)
+	}
+	if card := renderPlaceCard(&Place{ID: "v2", Name: "Zuni Cafe"}, nil); !strings.Contains(card, `href="/places/view?id=v2"`) {
+		t.Error("card doesn't link to the place's page")
+	}
+}
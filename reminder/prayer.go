@@ -0,0 +1,246 @@
+package reminder
+
+import (
+	"fmt"
+	"math"
+	"time"
+	// Users pick an IANA timezone; embed the database so that works on
+	// hosts without /usr/share/zoneinfo, like the scratch Docker image.
+	_ "time/tzdata"
+)
+
+// Prayer times are calculated here rather than fetched: it's the usual
+// astronomical method (as on praytimes.org), the sun's position for the
+// day and the twilight angle of the chosen convention. The Hijri date is
+// the tabular Islamic calendar, which can differ by a day from a sighted
+// month.
+
+// Prayer names, in the order of the day. Sunrise isn't a prayer but ends
+// the time for Fajr, so it's shown with them.
+const (
+	Fajr    = "Fajr"
+	Sunrise = "Sunrise"
+	Dhuhr   = "Dhuhr"
+	Asr     = "Asr"
+	Maghrib = "Maghrib"
+	Isha    = "Isha"
+)
+
+var prayerNames = []string{Fajr, Sunrise, Dhuhr, Asr, Maghrib, Isha}
+
+// Method is a calculation convention: the sun's angle below the horizon
+// for Fajr and Isha. Some use a fixed interval after Maghrib for Isha.
+type Method struct {
+	Name        string
+	Fajr        float64
+	Isha        float64
+	IshaMinutes int // after Maghrib, instead of an angle
+}
+
+// Methods are the conventions a user can choose, keyed by setting value.
+var Methods = map[string]Method{
+	"mwl":     {Name: "Muslim World League", Fajr: 18, Isha: 17},
+	"isna":    {Name: "Islamic Society of North America", Fajr: 15, Isha: 15},
+	"egypt":   {Name: "Egyptian General Authority of Survey", Fajr: 19.5, Isha: 17.5},
+	"makkah":  {Name: "Umm al-Qura, Makkah", Fajr: 18.5, IshaMinutes: 90},
+	"karachi": {Name: "University of Islamic Sciences, Karachi", Fajr: 18, Isha: 18},
+}
+
+// DefaultMethod is used when a user hasn't chosen one.
+const DefaultMethod = "mwl"
+
+// Asr is when an object's shadow is its length plus its noon shadow
+// (standard), or twice its length (Hanafi).
+const (
+	AsrStandard = "standard"
+	AsrHanafi   = "hanafi"
+)
+
+// PrayerTime is one entry in a day's timetable.
+type PrayerTime struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// PrayerTimes returns the timetable for the day of date in loc, at lat,
+// lon. Unknown methods use DefaultMethod.
+func PrayerTimes(date time.Time, lat, lon float64, loc *time.Location, method, asr string) []PrayerTime {
+	m, ok := Methods[method]
+	if !ok {
+		m = Methods[DefaultMethod]
+	}
+	shadow := 1.0
+	if asr == AsrHanafi {
+		shadow = 2
+	}
+	y, mo, d := date.In(loc).Date()
+	midnight := time.Date(y, mo, d, 0, 0, 0, 0, loc)
+	_, offset := time.Date(y, mo, d, 12, 0, 0, 0, loc).Zone()
+	jd := julianDate(y, int(mo), d) - lon/(15*24)
+
+	// Start from rough guesses and refine once with the sun's position
+	// at those times.
+	guess := []float64{5, 6, 12, 13, 18, 18}
+	for i := range guess {
+		guess[i] /= 24
+	}
+	hours := []float64{
+		sunAngleTime(jd, lat, m.Fajr, guess[0], true),
+		sunAngleTime(jd, lat, 0.833, guess[1], true),
+		midDay(jd, guess[2]),
+		asrTime(jd, lat, shadow, guess[3]),
+		sunAngleTime(jd, lat, 0.833, guess[4], false),
+		sunAngleTime(jd, lat, m.Isha, guess[5], false),
+	}
+	// Convert from local solar time to the zone's clock.
+	for i := range hours {
+		hours[i] += float64(offset)/3600 - lon/15
+	}
+	sunrise, maghrib := hours[1], hours[4]
+	if m.IshaMinutes > 0 {
+		hours[5] = maghrib + float64(m.IshaMinutes)/60
+	}
+
+	// Near the poles twilight can last all night, so the angle is never
+	// reached. Then Fajr and Isha take the angle's share of the night.
+	night := 24 - (maghrib - sunrise)
+	if math.IsNaN(hours[0]) || sunrise-hours[0] > m.Fajr/60*night {
+		hours[0] = sunrise - m.Fajr/60*night
+	}
+	if m.IshaMinutes == 0 && (math.IsNaN(hours[5]) || hours[5]-maghrib > m.Isha/60*night) {
+		hours[5] = maghrib + m.Isha/60*night
+	}
+
+	out := make([]PrayerTime, len(prayerNames))
+	for i, name := range prayerNames {
+		at := midnight.Add(time.Duration(hours[i] * float64(time.Hour))).Truncate(time.Minute)
+		out[i] = PrayerTime{Name: name, Time: at}
+	}
+	return out
+}
+
+// NextPrayer returns the first prayer after now, looking into tomorrow's
+// timetable after Isha. Sunrise is skipped.
+func NextPrayer(now time.Time, lat, lon float64, loc *time.Location, method, asr string) PrayerTime {
+	for _, day := range []time.Time{now, now.AddDate(0, 0, 1)} {
+		for _, p := range PrayerTimes(day, lat, lon, loc, method, asr) {
+			if p.Name != Sunrise && p.Time.After(now) {
+				return p
+			}
+		}
+	}
+	return PrayerTime{}
+}
+
+func dsin(d float64) float64    { return math.Sin(d * math.Pi / 180) }
+func dcos(d float64) float64    { return math.Cos(d * math.Pi / 180) }
+func dtan(d float64) float64    { return math.Tan(d * math.Pi / 180) }
+func darcsin(x float64) float64 { return math.Asin(x) * 180 / math.Pi }
+func darccos(x float64) float64 { return math.Acos(x) * 180 / math.Pi }
+func darccot(x float64) float64 { return math.Atan(1/x) * 180 / math.Pi }
+func darctan2(y, x float64) float64 {
+	return math.Atan2(y, x) * 180 / math.Pi
+}
+
+func fix(a, b float64) float64 {
+	a = a - b*math.Floor(a/b)
+	if a < 0 {
+		a += b
+	}
+	return a
+}
+
+// julianDate is the Julian date at midnight UTC starting the given day.
+func julianDate(year, month, day int) float64 {
+	if month <= 2 {
+		year--
+		month += 12
+	}
+	a := math.Floor(float64(year) / 100)
+	b := 2 - a + math.Floor(a/4)
+	return math.Floor(365.25*float64(year+4716)) + math.Floor(30.6001*float64(month+1)) + float64(day) + b - 1524.5
+}
+
+// sunPosition returns the sun's declination and the equation of time
+// (in hours) at Julian date jd.
+func sunPosition(jd float64) (decl, eqt float64) {
+	d := jd - 2451545.0
+	g := fix(357.529+0.98560028*d, 360)
+	q := fix(280.459+0.98564736*d, 360)
+	l := fix(q+1.915*dsin(g)+0.020*dsin(2*g), 360)
+	e := 23.439 - 0.00000036*d
+	ra := darctan2(dcos(e)*dsin(l), dcos(l)) / 15
+	eqt = q/15 - fix(ra, 24)
+	decl = darcsin(dsin(e) * dsin(l))
+	return decl, eqt
+}
+
+// midDay is solar noon, in hours, on the day part t of jd.
+func midDay(jd, t float64) float64 {
+	_, eqt := sunPosition(jd + t)
+	return fix(12-eqt, 24)
+}
+
+// sunAngleTime is when the sun is angle degrees below the horizon:
+// before noon if ccw, else after. NaN if it never gets there that day.
+func sunAngleTime(jd, lat, angle, t float64, ccw bool) float64 {
+	decl, _ := sunPosition(jd + t)
+	noon := midDay(jd, t)
+	h := darccos((-dsin(angle)-dsin(decl)*dsin(lat))/(dcos(decl)*dcos(lat))) / 15
+	if ccw {
+		return noon - h
+	}
+	return noon + h
+}
+
+// asrTime is when a shadow is shadow times an object's length plus its
+// noon shadow.
+func asrTime(jd, lat, shadow, t float64) float64 {
+	decl, _ := sunPosition(jd + t)
+	angle := -darccot(shadow + dtan(math.Abs(lat-decl)))
+	return sunAngleTime(jd, lat, angle, t, false)
+}
+
+// HijriDate is a date in the Islamic calendar.
+type HijriDate struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+var hijriMonths = []string{
+	"Muharram", "Safar", "Rabi al-Awwal", "Rabi al-Thani", "Jumada al-Awwal", "Jumada al-Thani",
+	"Rajab", "Shaban", "Ramadan", "Shawwal", "Dhu al-Qadah", "Dhu al-Hijjah",
+}
+
+// MonthName is the month's English transliteration.
+func (h HijriDate) MonthName() string {
+	if h.Month < 1 || h.Month > 12 {
+		return ""
+	}
+	return hijriMonths[h.Month-1]
+}
+
+func (h HijriDate) String() string {
+	return fmt.Sprintf("%d %s %d AH", h.Day, h.MonthName(), h.Year)
+}
+
+// Hijri converts the calendar day of t to the tabular Islamic calendar.
+func Hijri(t time.Time) HijriDate {
+	y, m, d := t.Date()
+	// Julian day number, then the arithmetical calendar's 30-year cycle.
+	a := (14 - int(m)) / 12
+	yy := y + 4800 - a
+	mm := int(m) + 12*a - 3
+	jdn := d + (153*mm+2)/5 + 365*yy + yy/4 - yy/100 + yy/400 - 32045
+
+	l := jdn - 1948440 + 10632
+	n := (l - 1) / 10631
+	l = l - 10631*n + 354
+	j := ((10985-l)/5316)*((50*l)/17719) + (l/5670)*((43*l)/15238)
+	l = l - ((30-j)/15)*((17719*j)/50) - (j/16)*((15238*j)/43) + 29
+	month := (24 * l) / 709
+	day := l - (709*month)/24
+	year := 30*n + j - 30
+	return HijriDate{Year: year, Month: month, Day: day}
+}
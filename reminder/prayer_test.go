@@ -0,0 +1,142 @@
+package reminder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestPrayerTimesMakkah(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Published Umm al-Qura times for 15 March 2024, to within a few
+	// minutes: the calendar adds its own adjustments.
+	want := map[string]string{
+		Fajr: "05:20", Sunrise: "06:32", Dhuhr: "12:31", Asr: "15:55", Maghrib: "18:30", Isha: "20:00",
+	}
+	times := PrayerTimes(time.Date(2024, 3, 15, 0, 0, 0, 0, loc), 21.4225, 39.8262, loc, "makkah", AsrStandard)
+	for i, p := range times {
+		if p.Name != prayerNames[i] {
+			t.Fatalf("prayer %d is %s", i, p.Name)
+		}
+		w, _ := time.ParseInLocation("2006-01-02 15:04", "2024-03-15 "+want[p.Name], loc)
+		if d := p.Time.Sub(w); d < -8*time.Minute || d > 8*time.Minute {
+			t.Errorf("%s at %s, want about %s", p.Name, p.Clock(), want[p.Name])
+		}
+		if i > 0 && !p.Time.After(times[i-1].Time) {
+			t.Errorf("%s isn't after %s", p.Name, times[i-1].Name)
+		}
+	}
+}
+
+func TestPrayerTimesHighLatitude(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Oslo")
+	// Twilight lasts all night in an Oslo June, so Fajr and Isha come
+	// from the share of the night instead of the angle.
+	times := PrayerTimes(time.Date(2024, 6, 21, 0, 0, 0, 0, loc), 59.91, 10.75, loc, "mwl", AsrHanafi)
+	for i := 1; i < len(times); i++ {
+		if !times[i].Time.After(times[i-1].Time) {
+			t.Fatalf("%s %s isn't after %s %s", times[i].Name, times[i].Clock(), times[i-1].Name, times[i-1].Clock())
+		}
+	}
+}
+
+func TestNextPrayerRollsOver(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/London")
+	late := time.Date(2024, 1, 10, 23, 30, 0, 0, loc)
+	next := NextPrayer(late, 51.5, -0.13, loc, "mwl", AsrStandard)
+	if next.Name != Fajr || next.Time.Day() != 11 {
+		t.Fatalf("next after Isha = %s on the %d", next.Name, next.Time.Day())
+	}
+}
+
+func TestHijri(t *testing.T) {
+	for date, want := range map[string]string{
+		"2023-07-19": "1 Muharram 1445 AH",
+		"2024-03-11": "1 Ramadan 1445 AH",
+	} {
+		d, _ := time.Parse("2006-01-02", date)
+		if got := Hijri(d).String(); got != want {
+			t.Errorf("Hijri(%s) = %s, want %s", date, got, want)
+		}
+	}
+}
+
+func TestNotifyPrayersOnce(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var got []string
+	OnPrayer = func(userID string, p PrayerTime) { got = append(got, userID+" "+p.Name) }
+	defer func() { OnPrayer = nil }()
+
+	s := &PrayerSettings{Location: "London", Lat: 51.5, Lon: -0.13, Timezone: "Europe/London", NotifyBefore: 10}
+	SetPrayerSettings("prayer_notify", s)
+	defer DeletePrayerSettings("prayer_notify")
+	SetPrayerSettings("prayer_quiet", &PrayerSettings{Lat: 51.5, Lon: -0.13})
+	defer DeletePrayerSettings("prayer_quiet")
+
+	dhuhr := s.Times(time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC))[2]
+	notifyPrayers(dhuhr.Time.Add(-20 * time.Minute))
+	if len(got) != 0 {
+		t.Fatalf("notified too early: %v", got)
+	}
+	notifyPrayers(dhuhr.Time.Add(-9 * time.Minute))
+	notifyPrayers(dhuhr.Time.Add(-8 * time.Minute))
+	if len(got) != 1 || got[0] != "prayer_notify Dhuhr" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestPrayerHandler(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	Geocode = func(q string) (string, float64, float64, error) { return "Makkah", 21.4225, 39.8262, nil }
+	defer func() { Geocode = nil }()
+	if err := auth.Create(&auth.Account{ID: "prayer_user", Name: "P", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("prayer_user")
+	defer DeletePrayerSettings("prayer_user")
+	sess, err := auth.Login("prayer_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/islam/prayer", strings.NewReader("location=makkah&timezone=Asia/Riyadh&method=makkah&notify_before=15"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	w := httptest.NewRecorder()
+	PrayerHandler(w, r)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("save: %d %s", w.Code, w.Body.String())
+	}
+	s := GetPrayerSettings("prayer_user")
+	if s == nil || s.Location != "Makkah" || s.Method != "makkah" || s.Asr != AsrStandard || s.NotifyBefore != 15 {
+		t.Fatalf("saved %+v", s)
+	}
+
+	r = httptest.NewRequest("GET", "/islam/prayer", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	w = httptest.NewRecorder()
+	PrayerHandler(w, r)
+	if body := w.Body.String(); !strings.Contains(body, "Makkah") || !strings.Contains(body, "AH") || !strings.Contains(body, `class="prayer-times"`) {
+		t.Fatal("page doesn't show the saved timetable")
+	}
+
+	// Guests can't save.
+	r = httptest.NewRequest("POST", "/islam/prayer", strings.NewReader("lat=1&lon=2"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	PrayerHandler(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("guest save: %d", w.Code)
+	}
+
+	if card := PrayerCard(""); !strings.Contains(card, "Set your location") {
+		t.Fatal("guest card should ask for a location")
+	}
+}
@@ -0,0 +1,458 @@
+package reminder
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/netx"
+	"mu/internal/settings"
+)
+
+// The Quran at /quran: each surah's ayahs in Arabic with a translation,
+// and a bookmark of where each account stopped reading. A few short
+// surahs are bundled so it works offline. An admin can point
+// QURAN_DATA_URL at a complete dataset in the same shape as quran.json,
+// which is downloaded once and kept. Every ayah is indexed so search and
+// the chat can find and cite it.
+
+func init() {
+	settings.Register("Islam",
+		settings.Var{Key: "QURAN_DATA_URL", Type: settings.TypeURL, Doc: "Complete Quran dataset to download, shaped like reminder/quran.json; a few surahs are bundled"},
+	)
+}
+
+//go:embed quran.json
+var bundledQuran []byte
+
+// Quran is a dataset: the surahs and the translations they carry.
+type Quran struct {
+	Source       string        `json:"source,omitempty"` // "bundled", or the URL it came from
+	Attribution  string        `json:"attribution,omitempty"`
+	Translations []Translation `json:"translations"`
+	Surahs       []*Surah      `json:"surahs"`
+}
+
+// Translation names one of the translations each ayah may have.
+type Translation struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Language string `json:"language"`
+}
+
+// Surah is a chapter.
+type Surah struct {
+	Number     int    `json:"number"`
+	Name       string `json:"name"`   // transliterated, like Al-Fatihah
+	Arabic     string `json:"arabic"` // its name in Arabic
+	English    string `json:"english"`
+	Revelation string `json:"revelation,omitempty"` // Meccan or Medinan
+	Ayahs      []Ayah `json:"ayahs"`
+}
+
+// Ayah is a verse, in Arabic with its translations keyed by ID.
+type Ayah struct {
+	Number       int               `json:"number"`
+	Text         string            `json:"text"`
+	Translations map[string]string `json:"translations,omitempty"`
+}
+
+// QuranBookmark is where an account last stopped reading.
+type QuranBookmark struct {
+	Surah       int       `json:"surah"`
+	Ayah        int       `json:"ayah"`
+	Translation string    `json:"translation,omitempty"`
+	Updated     time.Time `json:"updated"`
+}
+
+// quranClient downloads QURAN_DATA_URL, a few megabytes.
+var quranClient = netx.New("quran", netx.Policy{Timeout: 2 * time.Minute, Retries: 1})
+
+var (
+	quranMu       sync.RWMutex
+	quran         *Quran
+	bookmarkMu    sync.Mutex
+	quranMarks    = map[string]*QuranBookmark{} // account ID → bookmark
+	quranIndexing sync.Mutex
+)
+
+// parseQuran reads and checks a dataset. Surahs are sorted by number.
+func parseQuran(b []byte) (*Quran, error) {
+	var q Quran
+	if err := json.Unmarshal(b, &q); err != nil {
+		return nil, err
+	}
+	if len(q.Surahs) == 0 {
+		return nil, fmt.Errorf("no surahs")
+	}
+	if len(q.Translations) == 0 {
+		return nil, fmt.Errorf("no translations")
+	}
+	seen := map[int]bool{}
+	for _, s := range q.Surahs {
+		if s.Number < 1 || s.Number > 114 || seen[s.Number] {
+			return nil, fmt.Errorf("bad surah number %d", s.Number)
+		}
+		seen[s.Number] = true
+		for i, a := range s.Ayahs {
+			if a.Number != i+1 {
+				return nil, fmt.Errorf("surah %d: ayah %d out of order", s.Number, a.Number)
+			}
+		}
+	}
+	sort.Slice(q.Surahs, func(i, j int) bool { return q.Surahs[i].Number < q.Surahs[j].Number })
+	return &q, nil
+}
+
+// loadQuran uses the downloaded dataset if there is one, else the
+// bundled surahs, and loads the bookmarks.
+func loadQuran() {
+	q, err := parseQuran(bundledQuran)
+	if err != nil {
+		app.Log("reminder", "Bundled Quran: %v", err)
+	}
+	if b, err := data.LoadFile("quran.json"); err == nil {
+		if full, err := parseQuran(b); err == nil {
+			q = full
+		} else {
+			app.Log("reminder", "Downloaded Quran: %v", err)
+		}
+	}
+	setQuran(q)
+
+	var marks map[string]*QuranBookmark
+	if err := data.LoadJSON("quran_bookmarks.json", &marks); err == nil && marks != nil {
+		bookmarkMu.Lock()
+		quranMarks = marks
+		bookmarkMu.Unlock()
+	}
+}
+
+func setQuran(q *Quran) {
+	quranMu.Lock()
+	quran = q
+	quranMu.Unlock()
+	if q != nil {
+		go indexQuran(q)
+	}
+}
+
+// downloadQuran fetches QURAN_DATA_URL unless it's the dataset already
+// loaded.
+func downloadQuran() error {
+	src := settings.String("QURAN_DATA_URL")
+	if src == "" {
+		return nil
+	}
+	quranMu.RLock()
+	have := quran != nil && quran.Source == src
+	quranMu.RUnlock()
+	if have {
+		return nil
+	}
+
+	resp, err := quranClient.Get(src)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	q, err := parseQuran(b)
+	if err != nil {
+		return fmt.Errorf("%s: %v", src, err)
+	}
+	q.Source = src
+	if err := data.SaveJSON("quran.json", q); err != nil {
+		return err
+	}
+	setQuran(q)
+	app.Log("reminder", "Loaded the Quran from %s: %d surahs", src, len(q.Surahs))
+	return nil
+}
+
+// indexQuran indexes every ayah with its first translation, skipping
+// those already indexed with the same text.
+func indexQuran(q *Quran) {
+	quranIndexing.Lock()
+	defer quranIndexing.Unlock()
+	tr := q.Translations[0].ID
+	for _, s := range q.Surahs {
+		for _, a := range s.Ayahs {
+			id := fmt.Sprintf("quran-%d-%d", s.Number, a.Number)
+			content := a.Translations[tr] + "\n\n" + a.Text
+			if e := data.GetByID(id); e != nil && e.Content == content {
+				continue
+			}
+			data.Index(id, "quran", fmt.Sprintf("Quran %d:%d (%s)", s.Number, a.Number, s.Name), content, map[string]interface{}{
+				"url":   ayahURL(s.Number, a.Number),
+				"surah": s.Number,
+				"ayah":  a.Number,
+			})
+		}
+	}
+}
+
+func ayahURL(surah, ayah int) string {
+	return fmt.Sprintf("/quran/%d#ayah-%d", surah, ayah)
+}
+
+// currentQuran is the loaded dataset.
+func currentQuran() *Quran {
+	quranMu.RLock()
+	defer quranMu.RUnlock()
+	return quran
+}
+
+// surah returns surah n, or nil if the dataset doesn't have it.
+func (q *Quran) surah(n int) *Surah {
+	if q == nil {
+		return nil
+	}
+	for _, s := range q.Surahs {
+		if s.Number == n {
+			return s
+		}
+	}
+	return nil
+}
+
+// translation returns the translation with the given ID, or the first.
+func (q *Quran) translation(id string) Translation {
+	for _, t := range q.Translations {
+		if t.ID == id {
+			return t
+		}
+	}
+	return q.Translations[0]
+}
+
+// Complete reports whether every surah is loaded.
+func (q *Quran) Complete() bool {
+	return q != nil && len(q.Surahs) == 114
+}
+
+// GetQuranBookmark returns a copy of the account's bookmark, or nil.
+func GetQuranBookmark(userID string) *QuranBookmark {
+	bookmarkMu.Lock()
+	defer bookmarkMu.Unlock()
+	b, ok := quranMarks[userID]
+	if !ok {
+		return nil
+	}
+	cp := *b
+	return &cp
+}
+
+// SetQuranBookmark saves where the account stopped reading.
+func SetQuranBookmark(userID string, b *QuranBookmark) error {
+	cp := *b
+	bookmarkMu.Lock()
+	defer bookmarkMu.Unlock()
+	quranMarks[userID] = &cp
+	return data.SaveJSON("quran_bookmarks.json", quranMarks)
+}
+
+// DeleteQuranBookmark removes an account's bookmark. Called when the
+// account is deleted.
+func DeleteQuranBookmark(userID string) {
+	bookmarkMu.Lock()
+	defer bookmarkMu.Unlock()
+	if _, ok := quranMarks[userID]; !ok {
+		return
+	}
+	delete(quranMarks, userID)
+	if err := data.SaveJSON("quran_bookmarks.json", quranMarks); err != nil {
+		app.Log("reminder", "Saving Quran bookmarks: %v", err)
+	}
+}
+
+// RenameQuranBookmark moves a renamed account's bookmark to its new ID.
+func RenameQuranBookmark(oldID, newID string) {
+	bookmarkMu.Lock()
+	defer bookmarkMu.Unlock()
+	b, ok := quranMarks[oldID]
+	if !ok {
+		return
+	}
+	quranMarks[newID] = b
+	delete(quranMarks, oldID)
+	if err := data.SaveJSON("quran_bookmarks.json", quranMarks); err != nil {
+		app.Log("reminder", "Saving Quran bookmarks: %v", err)
+	}
+}
+
+// quranView is what the Quran templates render.
+type quranView struct {
+	Quran        *Quran
+	Surah        *Surah
+	Translation  Translation
+	Bookmark     *QuranBookmark
+	BookmarkName string // the bookmarked surah's name
+	SignedIn     bool
+}
+
+// QuranCard renders a link to the Quran, or to where the account left
+// off. It's on the Islam page.
+func QuranCard(userID string) string {
+	v := &quranView{Quran: currentQuran()}
+	if userID != "" {
+		v.withBookmark(userID)
+	}
+	return templates.HTML("quran-card", v)
+}
+
+func (v *quranView) withBookmark(userID string) {
+	v.Bookmark = GetQuranBookmark(userID)
+	if v.Bookmark != nil {
+		if s := v.Quran.surah(v.Bookmark.Surah); s != nil {
+			v.BookmarkName = s.Name
+		}
+	}
+}
+
+// QuranHandler serves /quran, the list of surahs, /quran/{surah} and
+// /quran/{surah}/{ayah}. ?t= picks the translation.
+func QuranHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	q := currentQuran()
+	if q == nil {
+		app.ServerError(w, r, "The Quran isn't loaded")
+		return
+	}
+	sess, _ := auth.TrySession(r)
+	v := &quranView{Quran: q, SignedIn: sess != nil}
+	if sess != nil {
+		v.withBookmark(sess.Account)
+	}
+	tr := r.URL.Query().Get("t")
+	if tr == "" && v.Bookmark != nil {
+		tr = v.Bookmark.Translation
+	}
+	v.Translation = q.translation(tr)
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/quran"), "/"), "/")
+	if parts[0] == "" {
+		if app.WantsJSON(r) {
+			type summary struct {
+				Number     int    `json:"number"`
+				Name       string `json:"name"`
+				Arabic     string `json:"arabic"`
+				English    string `json:"english"`
+				Revelation string `json:"revelation,omitempty"`
+				Ayahs      int    `json:"ayahs"`
+			}
+			list := make([]summary, len(q.Surahs))
+			for i, s := range q.Surahs {
+				list[i] = summary{s.Number, s.Name, s.Arabic, s.English, s.Revelation, len(s.Ayahs)}
+			}
+			app.RespondJSON(w, map[string]interface{}{"surahs": list, "translations": q.Translations, "bookmark": v.Bookmark})
+			return
+		}
+		templates.Page(w, r, "Quran", "Read the Quran with a translation", "quran-index", v)
+		return
+	}
+
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n < 1 || n > 114 || len(parts) > 2 {
+		app.NotFound(w, r, "No such surah")
+		return
+	}
+	s := q.surah(n)
+	if s == nil {
+		app.NotFound(w, r, fmt.Sprintf("Surah %d isn't in this server's copy of the Quran", n))
+		return
+	}
+	if len(parts) == 2 {
+		a, err := strconv.Atoi(parts[1])
+		if err != nil || a < 1 || a > len(s.Ayahs) {
+			app.NotFound(w, r, "No such ayah")
+			return
+		}
+		if !app.WantsJSON(r) {
+			http.Redirect(w, r, ayahURL(n, a), http.StatusFound)
+			return
+		}
+		ayah := s.Ayahs[a-1]
+		app.RespondJSON(w, map[string]interface{}{
+			"surah":       n,
+			"name":        s.Name,
+			"ayah":        a,
+			"text":        ayah.Text,
+			"translation": ayah.Translations[v.Translation.ID],
+			"url":         ayahURL(n, a),
+		})
+		return
+	}
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, s)
+		return
+	}
+	v.Surah = s
+	templates.Page(w, r, fmt.Sprintf("%d. %s", s.Number, s.Name), s.English, "quran-surah", v)
+}
+
+// QuranBookmarkHandler saves the account's place: POST surah, ayah and
+// optionally translation, as a form or JSON.
+func QuranBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	var req struct {
+		Surah       int    `json:"surah"`
+		Ayah        int    `json:"ayah"`
+		Translation string `json:"translation"`
+	}
+	if app.SendsJSON(r) {
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Surah, _ = strconv.Atoi(r.FormValue("surah"))
+		req.Ayah, _ = strconv.Atoi(r.FormValue("ayah"))
+		req.Translation = r.FormValue("translation")
+	}
+
+	q := currentQuran()
+	s := q.surah(req.Surah)
+	if s == nil || req.Ayah < 1 || req.Ayah > len(s.Ayahs) {
+		app.BadRequest(w, r, "No such ayah")
+		return
+	}
+	b := &QuranBookmark{Surah: req.Surah, Ayah: req.Ayah, Translation: q.translation(req.Translation).ID, Updated: time.Now()}
+	if err := SetQuranBookmark(sess.Account, b); err != nil {
+		app.ServerError(w, r, "Could not save your bookmark")
+		return
+	}
+	if app.SendsJSON(r) || app.WantsJSON(r) {
+		app.RespondJSON(w, b)
+		return
+	}
+	http.Redirect(w, r, ayahURL(b.Surah, b.Ayah), http.StatusSeeOther)
+}
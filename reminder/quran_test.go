@@ -0,0 +1,103 @@
+package reminder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestBundledQuran(t *testing.T) {
+	q, err := parseQuran(bundledQuran)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fatihah := q.surah(1)
+	if fatihah == nil || len(fatihah.Ayahs) != 7 || fatihah.Name != "Al-Fatihah" {
+		t.Fatalf("Al-Fatihah = %+v", fatihah)
+	}
+	for _, s := range q.Surahs {
+		for _, a := range s.Ayahs {
+			if a.Text == "" || a.Translations[q.Translations[0].ID] == "" {
+				t.Errorf("%d:%d is missing its text or translation", s.Number, a.Number)
+			}
+		}
+	}
+	if q.Complete() {
+		t.Error("the bundled surahs aren't the whole Quran")
+	}
+	if _, err := parseQuran([]byte(`{"translations":[{"id":"x"}],"surahs":[{"number":2,"ayahs":[{"number":2}]}]}`)); err == nil {
+		t.Error("ayahs out of order should be rejected")
+	}
+}
+
+func TestQuranHandler(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	q, _ := parseQuran(bundledQuran)
+	quranMu.Lock()
+	quran = q
+	quranMu.Unlock()
+
+	if err := auth.Create(&auth.Account{ID: "quran_user", Name: "Q", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("quran_user")
+	defer DeleteQuranBookmark("quran_user")
+	sess, err := auth.Login("quran_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		w := httptest.NewRecorder()
+		QuranHandler(w, r)
+		return w
+	}
+
+	if body := get("/quran").Body.String(); !strings.Contains(body, "Al-Ikhlas") || !strings.Contains(body, "QURAN_DATA_URL") {
+		t.Fatal("index doesn't list the surahs")
+	}
+	if body := get("/quran/112").Body.String(); !strings.Contains(body, `id="ayah-4"`) || !strings.Contains(body, "none comparable") {
+		t.Fatal("surah page is missing its ayahs")
+	}
+	if w := get("/quran/2"); w.Code != http.StatusNotFound {
+		t.Errorf("surah that isn't loaded: %d", w.Code)
+	}
+	if w := get("/quran/1/5"); w.Code != http.StatusFound || w.Header().Get("Location") != "/quran/1#ayah-5" {
+		t.Errorf("ayah link: %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	r := httptest.NewRequest("POST", "/quran/bookmark", strings.NewReader("surah=113&ayah=3"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	w := httptest.NewRecorder()
+	QuranBookmarkHandler(w, r)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("bookmark: %d %s", w.Code, w.Body.String())
+	}
+	if b := GetQuranBookmark("quran_user"); b == nil || b.Surah != 113 || b.Ayah != 3 || b.Translation != "pickthall" {
+		t.Fatalf("bookmark = %+v", b)
+	}
+	if card := QuranCard("quran_user"); !strings.Contains(card, "Al-Falaq 113:3") {
+		t.Error("card doesn't offer to continue reading")
+	}
+
+	r = httptest.NewRequest("POST", "/quran/bookmark", strings.NewReader("surah=1&ayah=8"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	w = httptest.NewRecorder()
+	QuranBookmarkHandler(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bookmark past the end: %d", w.Code)
+	}
+
+	RenameQuranBookmark("quran_user", "quran_renamed")
+	defer DeleteQuranBookmark("quran_renamed")
+	if GetQuranBookmark("quran_user") != nil || GetQuranBookmark("quran_renamed") == nil {
+		t.Error("rename didn't move the bookmark")
+	}
+}
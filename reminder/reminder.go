@@ -11,8 +11,10 @@ import (
 	"time"
 
 	"mu/internal/app"
+	"mu/internal/auth"
 	"mu/internal/data"
 	"mu/internal/event"
+	"mu/internal/netx"
 	"mu/internal/service"
 )
 
@@ -21,6 +23,10 @@ var (
 	reminderHTML  string
 )
 
+// reminderClient fetches from reminder.dev. Responses are already cached
+// on disk, so the client only adds retries and the breaker.
+var reminderClient = netx.New("reminder", netx.Policy{Timeout: 15 * time.Second, Retries: 2})
+
 // Load initializes the reminder data
 func Load() {
 	if err := service.Register("reminder", new(Server)); err != nil {
@@ -36,20 +42,25 @@ func Load() {
 	}
 
 	// Start background refresh
-	go refreshReminder()
-}
-
-func refreshReminder() {
-	for {
+	app.Schedule(app.Job{Name: "reminder", Every: time.Hour, Run: func() error {
 		fetchReminder()
-		time.Sleep(time.Hour)
-	}
+		return nil
+	}})
+
+	loadPrayerSettings()
+	app.Schedule(app.Job{Name: "reminder.prayers", Every: time.Minute, Run: func() error {
+		notifyPrayers(time.Now())
+		return nil
+	}})
+
+	loadQuran()
+	app.Schedule(app.Job{Name: "reminder.quran", Every: 24 * time.Hour, Retries: 2, Run: downloadQuran})
 }
 
 func fetchReminder() {
 	app.Log("reminder", "Fetching reminder")
 
-	resp, err := http.Get("https://reminder.dev/api/latest")
+	resp, err := reminderClient.Get("https://reminder.dev/api/latest")
 	if err != nil {
 		app.Log("reminder", "Error fetching: %v", err)
 		return
@@ -142,10 +153,15 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		app.RespondJSON(w, GetReminderData())
 		return
 	}
+	viewer := ""
+	if sess, _ := auth.TrySession(r); sess != nil {
+		viewer = sess.Account
+	}
+	prayer := `<div class="card"><h3>Prayer times</h3>` + PrayerCard(viewer) + app.Link("All times", "/islam/prayer") + `</div>`
 	app.Respond(w, r, app.Response{
 		Title:       "Islam",
-		Description: "A daily verse, name of Allah, hadith and reflection",
-		HTML:        renderIslamPage(GetReminderData()),
+		Description: "Prayer times, the Quran, and a daily verse, name of Allah, hadith and reflection",
+		HTML:        prayer + QuranCard(viewer) + renderIslamPage(GetReminderData()),
 	})
 }
 
@@ -239,7 +255,7 @@ func GetDailyReminderForDate(date string) *ReminderData {
 		url += "?date=" + date
 	}
 
-	resp, err := http.Get(url)
+	resp, err := reminderClient.Get(url)
 	if err != nil {
 		app.Log("reminder", "Error fetching daily reminder for %s: %v", date, err)
 		// Only fall back to latest for today
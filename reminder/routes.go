@@ -0,0 +1,23 @@
+package reminder
+
+import (
+	"net/http"
+
+	"mu/internal/app"
+)
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/islam", Handler)
+	r.HandleFunc("/islam/prayer", PrayerHandler)
+	r.HandleFunc("/quran", QuranHandler)
+	r.HandleFunc("/quran/", QuranHandler)
+	r.HandleFunc("/quran/bookmark", QuranBookmarkHandler)
+	// Back-compat: the page used to live at /reminder.
+	r.HandleFunc("/reminder", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/islam", http.StatusMovedPermanently)
+	})
+}
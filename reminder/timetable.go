@@ -0,0 +1,436 @@
+package reminder
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Each account can save where it prays and how times are worked out
+// there. /islam/prayer shows the day's timetable, the home card and the
+// Islam page show the next prayer, and a job calls OnPrayer shortly
+// before each one for accounts that asked to be told.
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var templates = app.ParseTemplates(templateFiles, "templates/*.html")
+
+// Geocode resolves a place name to coordinates. main wires it to places,
+// which reminder doesn't import.
+var Geocode func(query string) (name string, lat, lon float64, err error)
+
+// OnPrayer is called NotifyBefore minutes before each prayer, once per
+// prayer, for accounts that set it.
+var OnPrayer func(userID string, p PrayerTime)
+
+// PrayerSettings is where an account prays and the convention it follows.
+type PrayerSettings struct {
+	Location     string  `json:"location"`
+	Lat          float64 `json:"lat"`
+	Lon          float64 `json:"lon"`
+	Timezone     string  `json:"timezone,omitempty"` // IANA name, from the browser
+	Method       string  `json:"method,omitempty"`
+	Asr          string  `json:"asr,omitempty"`
+	NotifyBefore int     `json:"notify_before,omitempty"` // minutes; 0 for no notification
+}
+
+// notifyChoices are the offered NotifyBefore values.
+var notifyChoices = []int{0, 5, 10, 15, 30}
+
+var (
+	prayerMu       sync.Mutex
+	prayerSettings = map[string]*PrayerSettings{} // account ID → settings
+	prayerNotified = map[string]time.Time{}       // account ID → last prayer notified
+)
+
+func loadPrayerSettings() {
+	var s map[string]*PrayerSettings
+	if err := data.LoadJSON("prayer.json", &s); err == nil && s != nil {
+		prayerMu.Lock()
+		prayerSettings = s
+		prayerMu.Unlock()
+	}
+}
+
+// savePrayerSettings persists every account's settings. Caller must hold
+// prayerMu.
+func savePrayerSettings() error {
+	return data.SaveJSON("prayer.json", prayerSettings)
+}
+
+// GetPrayerSettings returns a copy of the account's settings, or nil if
+// it hasn't saved any.
+func GetPrayerSettings(userID string) *PrayerSettings {
+	prayerMu.Lock()
+	defer prayerMu.Unlock()
+	s, ok := prayerSettings[userID]
+	if !ok {
+		return nil
+	}
+	cp := *s
+	return &cp
+}
+
+// SetPrayerSettings saves the account's settings.
+func SetPrayerSettings(userID string, s *PrayerSettings) error {
+	cp := *s
+	prayerMu.Lock()
+	defer prayerMu.Unlock()
+	prayerSettings[userID] = &cp
+	delete(prayerNotified, userID)
+	return savePrayerSettings()
+}
+
+// DeletePrayerSettings removes an account's settings. Called when the
+// account is deleted.
+func DeletePrayerSettings(userID string) {
+	prayerMu.Lock()
+	defer prayerMu.Unlock()
+	if _, ok := prayerSettings[userID]; !ok {
+		return
+	}
+	delete(prayerSettings, userID)
+	delete(prayerNotified, userID)
+	if err := savePrayerSettings(); err != nil {
+		app.Log("reminder", "Saving prayer settings: %v", err)
+	}
+}
+
+// RenamePrayerSettings moves a renamed account's settings to its new ID.
+func RenamePrayerSettings(oldID, newID string) {
+	prayerMu.Lock()
+	defer prayerMu.Unlock()
+	s, ok := prayerSettings[oldID]
+	if !ok {
+		return
+	}
+	prayerSettings[newID] = s
+	delete(prayerSettings, oldID)
+	delete(prayerNotified, oldID)
+	if err := savePrayerSettings(); err != nil {
+		app.Log("reminder", "Saving prayer settings: %v", err)
+	}
+}
+
+// zone is the settings' timezone. Without a valid one it's the solar
+// offset of the longitude, to the half hour.
+func (s *PrayerSettings) zone() *time.Location {
+	if s.Timezone != "" {
+		if loc, err := time.LoadLocation(s.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone("", int(math.Round(s.Lon/7.5))*1800)
+}
+
+// Times returns the timetable for the day of t where the settings are.
+func (s *PrayerSettings) Times(t time.Time) []PrayerTime {
+	return PrayerTimes(t, s.Lat, s.Lon, s.zone(), s.Method, s.Asr)
+}
+
+// Next returns the first prayer after now.
+func (s *PrayerSettings) Next(now time.Time) PrayerTime {
+	return NextPrayer(now, s.Lat, s.Lon, s.zone(), s.Method, s.Asr)
+}
+
+// Clock is the prayer's local time of day.
+func (p PrayerTime) Clock() string {
+	return p.Time.Format("15:04")
+}
+
+// parsePrayerSettings reads settings from a form or query, geocoding the
+// location if no coordinates came with it.
+func parsePrayerSettings(get func(string) string) (*PrayerSettings, error) {
+	s := &PrayerSettings{
+		Location: strings.TrimSpace(get("location")),
+		Timezone: strings.TrimSpace(get("timezone")),
+		Method:   get("method"),
+		Asr:      get("asr"),
+	}
+	lat, latErr := strconv.ParseFloat(get("lat"), 64)
+	lon, lonErr := strconv.ParseFloat(get("lon"), 64)
+	switch {
+	case latErr == nil && lonErr == nil:
+		if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("coordinates are out of range")
+		}
+		s.Lat, s.Lon = lat, lon
+		if s.Location == "" {
+			s.Location = fmt.Sprintf("%.3f, %.3f", lat, lon)
+		}
+	case s.Location != "":
+		if Geocode == nil {
+			return nil, fmt.Errorf("looking up places isn't available; use your device's location")
+		}
+		name, lat, lon, err := Geocode(s.Location)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find %q", s.Location)
+		}
+		s.Location, s.Lat, s.Lon = name, lat, lon
+	default:
+		return nil, fmt.Errorf("a location is required")
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			s.Timezone = ""
+		}
+	}
+	if _, ok := Methods[s.Method]; !ok {
+		s.Method = DefaultMethod
+	}
+	if s.Asr != AsrHanafi {
+		s.Asr = AsrStandard
+	}
+	if n, err := strconv.Atoi(get("notify_before")); err == nil {
+		for _, c := range notifyChoices {
+			if n == c {
+				s.NotifyBefore = n
+			}
+		}
+	}
+	return s, nil
+}
+
+// notifyPrayers calls OnPrayer for each account whose next prayer is
+// within its NotifyBefore.
+func notifyPrayers(now time.Time) {
+	if OnPrayer == nil {
+		return
+	}
+	type due struct {
+		userID string
+		prayer PrayerTime
+	}
+	var send []due
+	prayerMu.Lock()
+	for id, s := range prayerSettings {
+		if s.NotifyBefore <= 0 {
+			continue
+		}
+		next := s.Next(now)
+		if next.Time.IsZero() || next.Time.Sub(now) > time.Duration(s.NotifyBefore)*time.Minute {
+			continue
+		}
+		if prayerNotified[id].Equal(next.Time) {
+			continue
+		}
+		prayerNotified[id] = next.Time
+		send = append(send, due{id, next})
+	}
+	prayerMu.Unlock()
+	for _, d := range send {
+		OnPrayer(d.userID, d.prayer)
+	}
+}
+
+// until is how long until t, like "1h 20m".
+func until(now, t time.Time) string {
+	d := t.Sub(now).Round(time.Minute)
+	if d < time.Minute {
+		return "now"
+	}
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	if h == 0 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %dm", h, m)
+}
+
+// prayerView is what the prayer templates render.
+type prayerView struct {
+	Hijri        HijriDate
+	Date         time.Time
+	Settings     *PrayerSettings
+	Times        []PrayerTime
+	Next         PrayerTime
+	Until        string
+	SignedIn     bool
+	Saved        bool
+	Error        string
+	Methods      []methodOption
+	Notify       []int
+	Method       string // selected in the form
+	Asr          string
+	NotifyBefore int
+	Script       template.HTML
+	Permalink    string
+}
+
+type methodOption struct {
+	Value, Name string
+}
+
+// newPrayerView fills in the view for s at now. s may be nil.
+func newPrayerView(s *PrayerSettings, now time.Time) *prayerView {
+	v := &prayerView{Date: now, Hijri: Hijri(now), Settings: s, Notify: notifyChoices, Method: DefaultMethod, Asr: AsrStandard}
+	if s != nil {
+		v.Method, v.Asr, v.NotifyBefore = s.Method, s.Asr, s.NotifyBefore
+		now = now.In(s.zone())
+		v.Date, v.Hijri = now, Hijri(now)
+		v.Times = s.Times(now)
+		v.Next = s.Next(now)
+		v.Until = until(now, v.Next.Time)
+	}
+	for value, m := range Methods {
+		v.Methods = append(v.Methods, methodOption{value, m.Name})
+	}
+	sort.Slice(v.Methods, func(i, j int) bool { return v.Methods[i].Name < v.Methods[j].Name })
+	return v
+}
+
+// PrayerCard renders the next prayer and the Hijri date for an account,
+// or just the date and a prompt to set a location. It's the home card
+// and the top of the Islam page.
+func PrayerCard(userID string) string {
+	var s *PrayerSettings
+	if userID != "" {
+		s = GetPrayerSettings(userID)
+	}
+	return templates.HTML("prayer-card", newPrayerView(s, time.Now()))
+}
+
+// prayerScript fills in the browser's timezone and, on request, its
+// coordinates.
+const prayerScript = `(function(){
+  var f = document.getElementById('prayer-form');
+  if (!f) return;
+  try { f.timezone.value = Intl.DateTimeFormat().resolvedOptions().timeZone || ''; } catch (e) {}
+  var b = document.getElementById('prayer-locate');
+  if (!b || !navigator.geolocation) { if (b) b.style.display = 'none'; return; }
+  b.addEventListener('click', function() {
+    b.disabled = true;
+    navigator.geolocation.getCurrentPosition(function(p) {
+      f.lat.value = p.coords.latitude.toFixed(4);
+      f.lon.value = p.coords.longitude.toFixed(4);
+      f.location.value = '';
+      f.submit();
+    }, function() { b.disabled = false; alert('Could not get your location'); });
+  });
+})();`
+
+// PrayerHandler serves /islam/prayer. GET shows today's timetable for
+// the account's saved location, or for ?location= or ?lat=&lon= without
+// saving. POST saves the account's settings.
+func PrayerHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		showPrayerTimes(w, r)
+	case "POST":
+		savePrayerTimes(w, r)
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+func showPrayerTimes(w http.ResponseWriter, r *http.Request) {
+	sess, _ := auth.TrySession(r)
+	var s *PrayerSettings
+	var errMsg string
+	q := r.URL.Query()
+	if q.Get("location") != "" || q.Get("lat") != "" {
+		var err error
+		if s, err = parsePrayerSettings(q.Get); err != nil {
+			errMsg = err.Error()
+		}
+	} else if sess != nil {
+		s = GetPrayerSettings(sess.Account)
+	}
+
+	v := newPrayerView(s, time.Now())
+	if app.WantsJSON(r) {
+		resp := map[string]interface{}{"hijri": v.Hijri, "hijri_date": v.Hijri.String()}
+		if s != nil {
+			resp["settings"] = s
+			resp["times"] = v.Times
+			resp["next"] = v.Next
+		}
+		if errMsg != "" {
+			app.BadRequest(w, r, errMsg)
+			return
+		}
+		app.RespondJSON(w, resp)
+		return
+	}
+	v.SignedIn = sess != nil
+	v.Saved = q.Get("saved") == "1"
+	v.Error = errMsg
+	v.Script = template.HTML(app.Script(r, prayerScript))
+	if s != nil && sess == nil {
+		// A guest's settings aren't saved, so offer a link that keeps them.
+		v.Permalink = "/islam/prayer?" + url.Values{
+			"lat":      {strconv.FormatFloat(s.Lat, 'f', 4, 64)},
+			"lon":      {strconv.FormatFloat(s.Lon, 'f', 4, 64)},
+			"location": {s.Location},
+			"timezone": {s.Timezone},
+			"method":   {s.Method},
+			"asr":      {s.Asr},
+		}.Encode()
+	}
+	templates.Page(w, r, "Prayer Times", "Today's prayer times and the Hijri date", "prayer", v)
+}
+
+func savePrayerTimes(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	get := func(k string) string { return r.FormValue(k) }
+	if app.SendsJSON(r) {
+		var req struct {
+			Location     string   `json:"location"`
+			Lat          *float64 `json:"lat"`
+			Lon          *float64 `json:"lon"`
+			Timezone     string   `json:"timezone"`
+			Method       string   `json:"method"`
+			Asr          string   `json:"asr"`
+			NotifyBefore int      `json:"notify_before"`
+		}
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+		fields := map[string]string{
+			"location":      req.Location,
+			"timezone":      req.Timezone,
+			"method":        req.Method,
+			"asr":           req.Asr,
+			"notify_before": strconv.Itoa(req.NotifyBefore),
+		}
+		if req.Lat != nil && req.Lon != nil {
+			fields["lat"] = strconv.FormatFloat(*req.Lat, 'f', -1, 64)
+			fields["lon"] = strconv.FormatFloat(*req.Lon, 'f', -1, 64)
+		}
+		get = func(k string) string { return fields[k] }
+	} else {
+		r.ParseForm()
+	}
+
+	s, err := parsePrayerSettings(get)
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return
+	}
+	if err := SetPrayerSettings(sess.Account, s); err != nil {
+		app.ServerError(w, r, "Could not save your prayer settings")
+		return
+	}
+	if app.SendsJSON(r) || app.WantsJSON(r) {
+		app.RespondJSON(w, s)
+		return
+	}
+	http.Redirect(w, r, "/islam/prayer?saved=1", http.StatusSeeOther)
+}
@@ -0,0 +1,39 @@
+#!/bin/bash
+# Vendor Leaflet for the places maps.
+#
+# The maps load Leaflet from /leaflet/, served from internal/app/html
+# like the rest of the static files, so readers' browsers never contact a
+# CDN. This fetches the pinned release into internal/app/html/leaflet and
+# checks the script and stylesheet against its published hashes; commit
+# what it writes.
+
+set -e
+
+VERSION="1.9.4"
+UPSTREAM="https://unpkg.com/leaflet@$VERSION/dist"
+DEST="$(cd "$(dirname "$0")/.." && pwd)/internal/app/html/leaflet"
+
+FILES="leaflet.js leaflet.css images/layers.png images/layers-2x.png images/marker-icon.png images/marker-icon-2x.png images/marker-shadow.png"
+
+# Published subresource integrity hashes of the release.
+declare -A SHA256=(
+  [leaflet.js]="20nQCchB9co0qIjJZRGuk2/Z9VM+kNiyxNV1lvTlZBo="
+  [leaflet.css]="p4NxAoJBhIIN+hmNHrzRCf9tD/miZyoHS5obTRR9BMY="
+)
+
+mkdir -p "$DEST/images"
+for f in $FILES; do
+  echo "Fetching $f..."
+  curl -fsSL "$UPSTREAM/$f" -o "$DEST/$f"
+  if [ -n "${SHA256[$f]}" ]; then
+    got=$(openssl dgst -sha256 -binary "$DEST/$f" | openssl base64)
+    if [ "$got" != "${SHA256[$f]}" ]; then
+      echo "Error: $f doesn't match Leaflet $VERSION (sha256-$got)"
+      rm -f "$DEST/$f"
+      exit 1
+    fi
+  fi
+done
+
+echo ""
+echo "Leaflet $VERSION vendored in $DEST"
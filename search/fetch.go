@@ -15,6 +15,7 @@ import (
 
 	"mu/internal/app"
 	"mu/internal/auth"
+	"mu/internal/egress"
 	"mu/wallet"
 )
 
@@ -26,10 +27,10 @@ var fetchLookupIP = net.DefaultResolver.LookupIPAddr
 
 var fetchClient = &http.Client{
 	Timeout: 15 * time.Second,
-	Transport: &http.Transport{
+	Transport: egress.Wrap(&http.Transport{
 		Proxy:       http.ProxyFromEnvironment,
 		DialContext: fetchDialContext,
-	},
+	}),
 	CheckRedirect: func(req *http.Request, via []*http.Request) error {
 		if len(via) >= 5 {
 			return fmt.Errorf("too many redirects")
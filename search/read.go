@@ -138,11 +138,10 @@ func ReadHandler(w http.ResponseWriter, r *http.Request) {
 		<div class="article-actions">
 			<a href="%s" target="_blank" rel="noopener noreferrer">Visit Original →</a>
 			<span class="mx-2">·</span>
-			<a href="#" onclick="navigator.share ? navigator.share({title: document.title, url: '%s'}) : navigator.clipboard.writeText('%s').then(() => alert('Link copied!')); return false;">Share →</a>
+			%s
 		</div>`,
 		html.EscapeString(rawURL),
-		html.EscapeString(strings.ReplaceAll(rawURL, "'", "\\'")),
-		html.EscapeString(strings.ReplaceAll(rawURL, "'", "\\'")),
+		app.ShareLink(rawURL, "Share →", ""),
 	))
 
 	b.WriteString(`<div class="article-back"><a href="javascript:history.back()">← Back to results</a></div>`)
@@ -0,0 +1,23 @@
+package search
+
+import (
+	"net/http"
+
+	"mu/internal/app"
+)
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/search", WebHandler)
+	r.HandleFunc("/web", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/search?"+r.URL.RawQuery, http.StatusMovedPermanently)
+	})
+	r.HandleFunc("/web/preview", PreviewHandler)
+	r.HandleFunc("/web/fetch", FetchHandler) // fetch and clean a URL
+	r.HandleFunc("/web/read", ReadHandler)   // clean reader for web results
+	r.HandleFunc("/fetch", app.Moved("/fetch", "/web/fetch"))
+	r.HandleFunc("/read", app.Moved("/read", "/web/read"))
+}
@@ -17,9 +17,16 @@ import (
 	"mu/internal/auth"
 	"mu/internal/data"
 	"mu/internal/service"
+	"mu/internal/settings"
 	"mu/wallet"
 )
 
+func init() {
+	settings.Register("Search",
+		settings.Var{Key: "BRAVE_API_KEY", Secret: true, Doc: "Brave Search API key for web search"},
+	)
+}
+
 // Load initializes the search building block.
 func Load() {
 	if err := service.Register("search", new(Server)); err != nil {
@@ -212,7 +219,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		b.WriteString(`<p class="empty">No results found.</p>`)
 	} else {
 		for _, entry := range localResults {
-			link := entryLink(entry)
+			link := entry.Link()
 			b.WriteString(`<div class="card" style="margin-bottom:12px;">`)
 			b.WriteString(`<div><a href="` + html.EscapeString(link) + `" class="card-title">` +
 				html.EscapeString(entry.Title) + `</a>`)
@@ -371,23 +378,6 @@ func PreviewHandler(w http.ResponseWriter, r *http.Request) {
 	app.RespondJSON(w, map[string]interface{}{"results": results})
 }
 
-// entryLink returns the URL for a local search result entry.
-func entryLink(entry *data.IndexEntry) string {
-	switch entry.Type {
-	case "news":
-		return "/news?id=" + url.QueryEscape(entry.ID)
-	case "video":
-		if u, ok := entry.Metadata["url"].(string); ok && u != "" {
-			return u
-		}
-		return "/video"
-	case "blog", "post":
-		return "/blog/post?id=" + url.QueryEscape(entry.ID)
-	default:
-		return "/" + entry.Type
-	}
-}
-
 // htmlTagRe matches any HTML tag.
 var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
 
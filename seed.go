@@ -0,0 +1,463 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"mu/blog"
+	"mu/chat"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/mail"
+	"mu/places"
+)
+
+// `mu seed` fills a development data directory with realistic fake
+// content — accounts, posts, comments, mail threads, chat history, saved
+// places and search index entries — so UI and performance work can be done
+// without a copy of production data.
+//
+// It writes the stores directly rather than going through the packages, so
+// content can be spread over past dates and nothing is sent or fetched. It
+// only runs with --env dev and refuses to touch a data directory that holds
+// real accounts; point HOME at a scratch directory:
+//
+//	HOME=/tmp/mu-dev mu seed --size medium
+//	HOME=/tmp/mu-dev mu --serve
+
+// seedMarker records a seeded data directory, so re-seeding it is allowed.
+const seedMarker = "seed.json"
+
+// seedPassword is the password of every seeded account.
+const seedPassword = "password"
+
+// seedSize is the amount of content generated for a preset.
+type seedSize struct {
+	Accounts         int
+	Posts            int
+	CommentsPerPost  int
+	Threads          int
+	RepliesPerThread int
+	Rooms            int
+	SavesPerUser     int
+}
+
+var seedSizes = map[string]seedSize{
+	"small":  {Accounts: 10, Posts: 25, CommentsPerPost: 3, Threads: 30, RepliesPerThread: 3, Rooms: 5, SavesPerUser: 2},
+	"medium": {Accounts: 100, Posts: 400, CommentsPerPost: 6, Threads: 500, RepliesPerThread: 5, Rooms: 30, SavesPerUser: 3},
+	"large":  {Accounts: 1000, Posts: 5000, CommentsPerPost: 10, Threads: 8000, RepliesPerThread: 8, Rooms: 200, SavesPerUser: 5},
+}
+
+// seedRecord is written to the marker file after a run.
+type seedRecord struct {
+	Size      string    `json:"size"`
+	Seed      int64     `json:"seed"`
+	Accounts  int       `json:"accounts"`
+	Posts     int       `json:"posts"`
+	Comments  int       `json:"comments"`
+	Messages  int       `json:"messages"`
+	Rooms     int       `json:"rooms"`
+	Saves     int       `json:"saves"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// runSeed implements `mu seed` and returns the process exit code.
+func runSeed(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	size := fs.String("size", "small", "Amount of data: small, medium or large")
+	env := fs.String("env", "dev", "Environment; seeding only runs in dev")
+	seed := fs.Int64("seed", 1, "Random seed, for reproducible data")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mu seed [--size small|medium|large] [--seed N]")
+		fmt.Fprintln(stderr, "\nGenerates fake data in $HOME/.mu/data for development.")
+		fmt.Fprintln(stderr, "Every seeded account uses the password \""+seedPassword+"\".")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if *env != "dev" {
+		fmt.Fprintf(stderr, "mu seed: refusing to run with --env %s (dev only)\n", *env)
+		return 1
+	}
+	preset, ok := seedSizes[*size]
+	if !ok {
+		fmt.Fprintf(stderr, "mu seed: unknown size %q (small, medium or large)\n", *size)
+		return 2
+	}
+	if err := checkSeedable(); err != nil {
+		fmt.Fprintf(stderr, "mu seed: %v\n", err)
+		return 1
+	}
+
+	rec, err := generateSeed(preset, rand.New(rand.NewSource(*seed)), time.Now())
+	if err != nil {
+		fmt.Fprintf(stderr, "mu seed: %v\n", err)
+		return 1
+	}
+	rec.Size = *size
+	rec.Seed = *seed
+	if err := data.SaveJSON(seedMarker, rec); err != nil {
+		fmt.Fprintf(stderr, "mu seed: %v\n", err)
+		return 1
+	}
+
+	dir := filepath.Join(os.ExpandEnv("$HOME/.mu"), "data")
+	fmt.Fprintf(stdout, "Seeded %s (%s): %d accounts, %d posts, %d comments, %d messages, %d chat rooms, %d saved places\n",
+		dir, rec.Size, rec.Accounts, rec.Posts, rec.Comments, rec.Messages, rec.Rooms, rec.Saves)
+	fmt.Fprintf(stdout, "Log in as admin (or any seeded account) with password %q.\n", seedPassword)
+	return 0
+}
+
+// checkSeedable refuses a data directory that holds accounts unless it was
+// created by a previous seed run.
+func checkSeedable() error {
+	var marker seedRecord
+	if err := data.LoadJSON(seedMarker, &marker); err == nil {
+		return nil
+	}
+	var accounts map[string]*auth.Account
+	if err := data.LoadJSON("accounts.json", &accounts); err == nil && len(accounts) > 0 {
+		return fmt.Errorf("%s/.mu/data has %d existing accounts; seed an empty data directory (e.g. HOME=/tmp/mu-dev)",
+			os.Getenv("HOME"), len(accounts))
+	}
+	return nil
+}
+
+// generateSeed writes every store and returns what it wrote. Dates are
+// spread over the weeks before now; chat history stays within the last day,
+// which is all the chat package keeps.
+func generateSeed(sz seedSize, rnd *rand.Rand, now time.Time) (*seedRecord, error) {
+	rec := &seedRecord{CreatedAt: now}
+
+	// bcrypt is slow by design; every account shares one hash.
+	hash, err := bcrypt.GenerateFromPassword([]byte(seedPassword), bcrypt.MinCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// Accounts, with "admin" first.
+	accounts := map[string]*auth.Account{}
+	var users []*auth.Account
+	for i := 0; i < sz.Accounts; i++ {
+		id, name := "admin", "Admin"
+		if i > 0 {
+			first := seedFirstNames[rnd.Intn(len(seedFirstNames))]
+			last := seedLastNames[rnd.Intn(len(seedLastNames))]
+			name = first + " " + last
+			id = strings.ToLower(first) + strings.ToLower(last[:1])
+			for n := 2; accounts[id] != nil; n++ {
+				id = fmt.Sprintf("%s%s%d", strings.ToLower(first), strings.ToLower(last[:1]), n)
+			}
+		}
+		acc := &auth.Account{
+			ID:       id,
+			Name:     name,
+			Secret:   string(hash),
+			Created:  now.Add(-time.Duration(60+rnd.Intn(300)) * 24 * time.Hour),
+			Admin:    i == 0,
+			Language: "en",
+			Approved: true,
+		}
+		accounts[id] = acc
+		users = append(users, acc)
+	}
+	if err := data.SaveJSON("accounts.json", accounts); err != nil {
+		return nil, err
+	}
+	rec.Accounts = len(accounts)
+	pick := func() *auth.Account { return users[rnd.Intn(len(users))] }
+
+	// Posts and comments, with a search index entry per post.
+	index := map[string]*data.IndexEntry{}
+	var posts []*blog.Post
+	var comments []*blog.Comment
+	ids := seedIDs(now)
+	for i := 0; i < sz.Posts; i++ {
+		author := pick()
+		created := seedTime(rnd, now, 60*24*time.Hour)
+		topic := seedTopics[rnd.Intn(len(seedTopics))]
+		post := &blog.Post{
+			ID:        ids(),
+			Title:     seedTitle(rnd, topic),
+			Content:   seedParagraphs(rnd, topic, 1+rnd.Intn(4)),
+			Author:    author.Name,
+			AuthorID:  author.ID,
+			Tags:      topic,
+			Private:   rnd.Intn(20) == 0,
+			CreatedAt: created,
+		}
+		posts = append(posts, post)
+		if !post.Private {
+			index[post.ID] = &data.IndexEntry{
+				ID:      post.ID,
+				Type:    "post",
+				Title:   post.Title,
+				Content: post.Content,
+				Metadata: map[string]interface{}{
+					"url":    "/blog/post?id=" + post.ID,
+					"author": post.Author,
+					"tags":   post.Tags,
+				},
+				IndexedAt: now,
+			}
+		}
+
+		for c := rnd.Intn(sz.CommentsPerPost + 1); c > 0; c-- {
+			commenter := pick()
+			comments = append(comments, &blog.Comment{
+				ID:        ids(),
+				PostID:    post.ID,
+				Content:   seedSentence(rnd, topic),
+				Author:    commenter.Name,
+				AuthorID:  commenter.ID,
+				CreatedAt: created.Add(time.Duration(1+rnd.Intn(48*60)) * time.Minute),
+			})
+		}
+	}
+	// The blog keeps posts newest first.
+	sort.Slice(posts, func(i, j int) bool { return posts[i].CreatedAt.After(posts[j].CreatedAt) })
+	if err := data.SaveJSON("blog.json", posts); err != nil {
+		return nil, err
+	}
+	if err := data.SaveJSON("comments.json", comments); err != nil {
+		return nil, err
+	}
+	rec.Posts, rec.Comments = len(posts), len(comments)
+
+	// Mail threads between pairs of accounts. Messages are written in
+	// plaintext, which the mail store accepts and encrypts on its next save;
+	// mail is indexed for search when the server loads it.
+	var msgs []*mail.Message
+	for i := 0; i < sz.Threads && len(users) > 1; i++ {
+		a, b := pick(), pick()
+		for b.ID == a.ID {
+			b = pick()
+		}
+		topic := seedTopics[rnd.Intn(len(seedTopics))]
+		subject := seedTitle(rnd, topic)
+		at := seedTime(rnd, now, 30*24*time.Hour)
+		root := &mail.Message{
+			ID:        ids(),
+			From:      a.Name,
+			FromID:    a.ID,
+			To:        b.Name,
+			ToID:      b.ID,
+			Subject:   subject,
+			Body:      seedParagraphs(rnd, topic, 1+rnd.Intn(2)),
+			Read:      rnd.Intn(3) > 0,
+			CreatedAt: at,
+		}
+		root.ThreadID = root.ID
+		msgs = append(msgs, root)
+
+		prev := root
+		for r := rnd.Intn(sz.RepliesPerThread + 1); r > 0; r-- {
+			at = at.Add(time.Duration(5+rnd.Intn(24*60)) * time.Minute)
+			if at.After(now) {
+				break
+			}
+			from, to := prev.ToID, prev.FromID
+			sender, recipient := accounts[from], accounts[to]
+			reply := &mail.Message{
+				ID:        ids(),
+				From:      sender.Name,
+				FromID:    sender.ID,
+				To:        recipient.Name,
+				ToID:      recipient.ID,
+				Subject:   "Re: " + subject,
+				Body:      seedSentence(rnd, topic),
+				Read:      rnd.Intn(3) > 0,
+				ReplyTo:   prev.ID,
+				ThreadID:  root.ID,
+				CreatedAt: at,
+			}
+			msgs = append(msgs, reply)
+			prev = reply
+		}
+	}
+	// The mail store keeps messages newest first.
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].CreatedAt.After(msgs[j].CreatedAt) })
+	if err := data.SaveJSON("mail.json", msgs); err != nil {
+		return nil, err
+	}
+	rec.Messages = len(msgs)
+
+	// Chat history in rooms attached to recent public posts.
+	for i := 0; i < sz.Rooms && i < len(posts); i++ {
+		post := posts[i]
+		if post.Private {
+			continue
+		}
+		var history []chat.RoomMessage
+		at := now.Add(-time.Duration(1+rnd.Intn(20)) * time.Hour)
+		for m := 5 + rnd.Intn(15); m > 0 && at.Before(now); m-- {
+			history = append(history, chat.RoomMessage{
+				UserID:    pick().ID,
+				Content:   seedSentence(rnd, post.Tags),
+				Timestamp: at,
+			})
+			at = at.Add(time.Duration(1+rnd.Intn(20)) * time.Minute)
+		}
+		if err := data.SaveJSON("room_post_"+post.ID+".json", history); err != nil {
+			return nil, err
+		}
+		rec.Rooms++
+	}
+
+	// Saved place searches.
+	saved := map[string][]places.SavedSearch{}
+	for _, u := range users {
+		for s := rnd.Intn(sz.SavesPerUser + 1); s > 0; s-- {
+			city := seedCities[rnd.Intn(len(seedCities))]
+			query := seedPlaceQueries[rnd.Intn(len(seedPlaceQueries))]
+			saved[u.ID] = append(saved[u.ID], places.SavedSearch{
+				ID:        ids(),
+				Label:     strings.ToUpper(query[:1]) + query[1:] + " in " + city.name,
+				Type:      "search",
+				Query:     query,
+				Location:  city.name,
+				Lat:       city.lat,
+				Lon:       city.lon,
+				Radius:    1000 * (1 + rnd.Intn(5)),
+				CreatedAt: seedTime(rnd, now, 90*24*time.Hour),
+			})
+			rec.Saves++
+		}
+	}
+	if err := data.SaveJSON("places_saved.json", saved); err != nil {
+		return nil, err
+	}
+
+	if err := data.SaveJSON("index.json", index); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// seedIDs returns a generator of unique, time-based IDs in the same format
+// the packages use.
+func seedIDs(now time.Time) func() string {
+	n := now.UnixNano()
+	return func() string {
+		n++
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// seedTime returns a random time within span before now.
+func seedTime(rnd *rand.Rand, now time.Time, span time.Duration) time.Time {
+	return now.Add(-time.Duration(rnd.Int63n(int64(span))))
+}
+
+// seedTitle returns a post or mail subject about topic.
+func seedTitle(rnd *rand.Rand, topic string) string {
+	t := seedTitles[rnd.Intn(len(seedTitles))]
+	subject := seedSubjects[topic][rnd.Intn(len(seedSubjects[topic]))]
+	return fmt.Sprintf(t, subject)
+}
+
+// seedSentence returns one sentence about topic.
+func seedSentence(rnd *rand.Rand, topic string) string {
+	s := seedSentences[rnd.Intn(len(seedSentences))]
+	subject := seedSubjects[topic][rnd.Intn(len(seedSubjects[topic]))]
+	return fmt.Sprintf(s, subject)
+}
+
+// seedParagraphs returns n paragraphs of a few sentences each.
+func seedParagraphs(rnd *rand.Rand, topic string, n int) string {
+	paras := make([]string, n)
+	for i := range paras {
+		sentences := make([]string, 2+rnd.Intn(4))
+		for j := range sentences {
+			sentences[j] = seedSentence(rnd, topic)
+		}
+		paras[i] = strings.Join(sentences, " ")
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+var seedFirstNames = []string{
+	"Aisha", "Omar", "Fatima", "Yusuf", "Maryam", "Ibrahim", "Zainab", "Hassan",
+	"Sarah", "James", "Emily", "Daniel", "Olivia", "Samuel", "Grace", "Adam",
+	"Noor", "Bilal", "Hana", "Idris", "Leila", "Tariq", "Amina", "Khalid",
+	"Chloe", "Lucas", "Sofia", "Mateo", "Priya", "Arjun", "Mei", "Kenji",
+}
+
+var seedLastNames = []string{
+	"Khan", "Ahmed", "Hussain", "Ali", "Rahman", "Malik", "Siddiqui", "Patel",
+	"Smith", "Jones", "Taylor", "Brown", "Wilson", "Evans", "Thomas", "Roberts",
+	"Garcia", "Lopez", "Nakamura", "Chen", "Okafor", "Mensah", "Haddad", "Yilmaz",
+}
+
+// seedTopics are the default blog topics.
+var seedTopics = []string{"Crypto", "Dev", "Finance", "Islam", "Politics", "Tech", "UK", "World"}
+
+var seedSubjects = map[string][]string{
+	"Crypto":   {"stablecoin payments", "self-custody wallets", "Bitcoin fees", "on-chain identity", "the latest ETF flows"},
+	"Dev":      {"Go generics", "SQLite in production", "server-rendered HTML", "small binaries", "websocket backpressure"},
+	"Finance":  {"interest rates", "index funds", "household budgets", "the housing market", "emergency savings"},
+	"Islam":    {"Ramadan planning", "zakat calculations", "the Seerah", "Quran memorisation", "halal investing"},
+	"Politics": {"the spending review", "local elections", "digital ID", "energy policy", "the planning bill"},
+	"Tech":     {"local-first apps", "AI assistants", "open protocols", "phone batteries", "the attention economy"},
+	"UK":       {"rail fares", "the NHS backlog", "council tax", "the weather this week", "high street closures"},
+	"World":    {"the climate talks", "food prices", "the trade agreement", "water shortages", "the aid corridor"},
+}
+
+var seedTitles = []string{
+	"Thoughts on %s",
+	"What I learned about %s",
+	"A short guide to %s",
+	"Why %s matters",
+	"Is anyone else following %s?",
+	"Notes on %s",
+	"Question about %s",
+}
+
+var seedSentences = []string{
+	"I've been reading a lot about %s lately and it's more nuanced than it looks.",
+	"Most of the coverage of %s misses the practical side.",
+	"Has anyone here had first-hand experience with %s?",
+	"The honest answer on %s is that it depends on your situation.",
+	"I changed my mind about %s after looking at the numbers.",
+	"There's a good long read on %s that I'll share when I find it.",
+	"Agree with this, %s deserves more attention than it gets.",
+	"For %s the simplest approach usually wins.",
+	"My worry with %s is what happens in five years.",
+	"Would love a follow-up post on %s.",
+}
+
+type seedCity struct {
+	name     string
+	lat, lon float64
+}
+
+var seedCities = []seedCity{
+	{"London", 51.5074, -0.1278},
+	{"Birmingham", 52.4862, -1.8904},
+	{"Manchester", 53.4808, -2.2426},
+	{"Leeds", 53.8008, -1.5491},
+	{"Glasgow", 55.8642, -4.2518},
+	{"Istanbul", 41.0082, 28.9784},
+	{"Dubai", 25.2048, 55.2708},
+	{"Kuala Lumpur", 3.1390, 101.6869},
+}
+
+var seedPlaceQueries = []string{
+	"mosque", "cafe", "halal restaurant", "park", "library", "bakery", "gym", "pharmacy",
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/blog"
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/mail"
+)
+
+func TestGenerateSeed(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	now := time.Now()
+	rec, err := generateSeed(seedSizes["small"], rand.New(rand.NewSource(1)), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Accounts != seedSizes["small"].Accounts || rec.Posts != seedSizes["small"].Posts {
+		t.Fatalf("unexpected counts: %+v", rec)
+	}
+
+	var accounts map[string]*auth.Account
+	if err := data.LoadJSON("accounts.json", &accounts); err != nil {
+		t.Fatal(err)
+	}
+	if a := accounts["admin"]; a == nil || !a.Admin {
+		t.Fatal("expected an admin account")
+	}
+
+	var posts []*blog.Post
+	if err := data.LoadJSON("blog.json", &posts); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(posts); i++ {
+		if posts[i].CreatedAt.After(posts[i-1].CreatedAt) {
+			t.Fatal("posts should be newest first")
+		}
+	}
+
+	var msgs []*mail.Message
+	if err := data.LoadJSON("mail.json", &msgs); err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]*mail.Message{}
+	for _, m := range msgs {
+		byID[m.ID] = m
+		if accounts[m.FromID] == nil || accounts[m.ToID] == nil {
+			t.Fatalf("message %s between unknown accounts", m.ID)
+		}
+		if m.CreatedAt.After(now) {
+			t.Fatalf("message %s is in the future", m.ID)
+		}
+	}
+	for _, m := range msgs {
+		if m.ReplyTo != "" && byID[m.ReplyTo] == nil {
+			t.Fatalf("reply %s points at a missing message", m.ID)
+		}
+	}
+
+	// The same seed generates the same content.
+	again, err := generateSeed(seedSizes["small"], rand.New(rand.NewSource(1)), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *again != *rec {
+		t.Fatalf("seed not reproducible: %+v vs %+v", again, rec)
+	}
+}
+
+func TestRunSeedGuards(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	var out, errOut bytes.Buffer
+	if code := runSeed([]string{"--env", "prod"}, &out, &errOut); code == 0 {
+		t.Fatal("seed should refuse outside dev")
+	}
+	if code := runSeed([]string{"--size", "huge"}, &out, &errOut); code == 0 {
+		t.Fatal("seed should reject an unknown size")
+	}
+
+	// A data directory with real accounts is left alone.
+	dir := filepath.Join(home, ".mu", "data")
+	os.MkdirAll(dir, 0700)
+	os.WriteFile(filepath.Join(dir, "accounts.json"), []byte(`{"alice":{"id":"alice","name":"Alice"}}`), 0600)
+	errOut.Reset()
+	if code := runSeed(nil, &out, &errOut); code == 0 {
+		t.Fatal("seed should refuse a directory with existing accounts")
+	}
+	if !strings.Contains(errOut.String(), "existing accounts") {
+		t.Fatalf("unexpected error: %s", errOut.String())
+	}
+
+	// An empty directory can be seeded, and re-seeded.
+	os.Remove(filepath.Join(dir, "accounts.json"))
+	for i := 0; i < 2; i++ {
+		if code := runSeed([]string{"--size", "small"}, &out, &errOut); code != 0 {
+			t.Fatalf("run %d failed: %s", i, errOut.String())
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package social
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/social", Handler)
+	r.HandleFunc("/social/thread", ThreadHandler)
+}
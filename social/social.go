@@ -103,25 +103,21 @@ func Load() {
 
 	loadedAt = time.Now()
 
+	flag.RegisterDeleter("social", &messageDeleter{})
+
 	// Detect breaking stories — headlines reported by multiple sources
-	go detectBreakingStories()
+	// once news has had time to load
+	app.Schedule(app.Job{Name: "social.breaking", Every: time.Hour, Delay: 3 * time.Minute, Run: func() error {
+		surfaceBreakingFromNews()
+		return nil
+	}})
 
 	app.Log("social", "Loaded %d messages", len(messages))
 }
 
-// detectBreakingStories checks the news feed for stories covered by multiple
-// categories/sources. If the same story appears across 2+ sources, it's
-// significant enough to surface as a social thread.
-func detectBreakingStories() {
-	// Wait for news to load first
-	time.Sleep(3 * time.Minute)
-
-	for {
-		surfaceBreakingFromNews()
-		time.Sleep(time.Hour)
-	}
-}
-
+// surfaceBreakingFromNews checks the news feed for stories covered by
+// multiple categories/sources. If the same story appears across 2+
+// sources, it's significant enough to surface as a social thread.
 func surfaceBreakingFromNews() {
 	feed := news.GetFeed()
 	if len(feed) == 0 {
@@ -322,6 +318,24 @@ func getReplies(threadID string) []*Message {
 	return replies
 }
 
+// lastActivity returns when a thread last had a message. Caller must hold
+// read lock.
+func lastActivity(thread *Message) time.Time {
+	last := thread.PostedAt
+	for _, p := range messages {
+		if p.ReplyTo == thread.ID && p.PostedAt.After(last) {
+			last = p.PostedAt
+		}
+	}
+	return last
+}
+
+// threadArchived reports whether a thread is closed to replies (see
+// app.IsArchived). Caller must hold read lock.
+func threadArchived(thread *Message) bool {
+	return app.IsArchived("social", thread.ID, lastActivity(thread))
+}
+
 // Handler serves the /social endpoint
 func Handler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -479,7 +493,7 @@ func handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	mutex.Lock()
-	found := false
+	found, moderated := false, false
 	for i, p := range messages {
 		if p.ID == threadID {
 			// Only author or admin can delete
@@ -489,7 +503,7 @@ func handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			messages = append(messages[:i], messages[i+1:]...)
-			found = true
+			found, moderated = true, p.AuthorID != acc.ID
 			break
 		}
 	}
@@ -504,6 +518,9 @@ func handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	save()
+	if moderated {
+		app.Audit(acc.ID, "social delete", threadID, r.URL.Path)
+	}
 
 	if app.WantsJSON(r) {
 		app.RespondJSON(w, map[string]interface{}{"success": true})
@@ -513,22 +530,20 @@ func handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleGetFeed(w http.ResponseWriter, r *http.Request) {
-	mutex.RLock()
-	all := make([]*Message, len(messages))
-	copy(all, messages)
-	mutex.RUnlock()
-
-	// Filter out flagged/banned messages and replies (only show threads in feed)
+	// Filter out flagged/banned messages, replies (only show threads in
+	// feed) and archived threads, which stay searchable
 	var visible []*Message
-	for _, p := range all {
+	mutex.RLock()
+	for _, p := range messages {
 		if p.ReplyTo != "" {
 			continue
 		}
-		if flag.IsHidden("social", p.ID) || auth.IsBanned(p.AuthorID) {
+		if flag.IsHidden("social", p.ID) || auth.IsBanned(p.AuthorID) || threadArchived(p) {
 			continue
 		}
 		visible = append(visible, p)
 	}
+	mutex.RUnlock()
 
 	if app.WantsJSON(r) {
 		app.RespondJSON(w, map[string]interface{}{"threads": visible})
@@ -571,14 +586,15 @@ func ThreadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	replies := getReplies(threadID)
+	archived := threadArchived(p)
 	mutex.RUnlock()
 
 	if app.WantsJSON(r) {
-		app.RespondJSON(w, map[string]interface{}{"thread": p, "messages": replies})
+		app.RespondJSON(w, map[string]interface{}{"thread": p, "messages": replies, "archived": archived})
 		return
 	}
 
-	body := generateThreadHTML(p, replies, r)
+	body := generateThreadHTML(p, replies, archived, r)
 
 	app.Respond(w, r, app.Response{
 		Title:       "Thread by " + p.Author,
@@ -618,11 +634,16 @@ func handleCreateReply(w http.ResponseWriter, r *http.Request) {
 	// Verify parent exists
 	mutex.RLock()
 	parent := getMessage(parentID)
+	archived := parent != nil && threadArchived(parent)
 	mutex.RUnlock()
 	if parent == nil {
 		app.BadRequest(w, r, "Thread not found")
 		return
 	}
+	if archived {
+		app.Forbidden(w, r, "This thread is archived and closed to new messages")
+		return
+	}
 
 	replyID := fmt.Sprintf("%d", time.Now().UnixNano())
 	reply := &Message{
@@ -647,7 +668,7 @@ func handleCreateReply(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/social/thread?id="+parentID, http.StatusSeeOther)
 }
 
-func generateThreadHTML(p *Message, replies []*Message, r *http.Request) string {
+func generateThreadHTML(p *Message, replies []*Message, archived bool, r *http.Request) string {
 	var sb strings.Builder
 	sb.WriteString(`<div style="max-width:600px;">`)
 
@@ -696,6 +717,7 @@ func generateThreadHTML(p *Message, replies []*Message, r *http.Request) string
 		content,
 		linkCard,
 	))
+	sb.WriteString(app.ArchiveNotice(r, "social", p.ID, archived))
 
 	// Message count
 	msgLabel := "messages"
@@ -706,8 +728,9 @@ func generateThreadHTML(p *Message, replies []*Message, r *http.Request) string
 		sb.WriteString(fmt.Sprintf(`<div style="padding:12px 0;color:#888;font-size:13px;border-bottom:1px solid #f0f0f0;">%d %s</div>`, len(replies), msgLabel))
 	}
 
-	// Reply form (for logged-in users)
-	if acc != nil {
+	// Reply form (for logged-in users, while the thread is open; the
+	// archive notice above explains its absence)
+	if acc != nil && !archived {
 		sb.WriteString(fmt.Sprintf(`<div style="margin:16px 0;">
   <form method="POST" action="/social/thread" id="reply-form">
     <input type="hidden" name="reply_to" value="%s">
@@ -727,7 +750,7 @@ func generateThreadHTML(p *Message, replies []*Message, r *http.Request) string
     });
   </script>
 </div>`, p.ID))
-	} else {
+	} else if !archived {
 		sb.WriteString(`<div style="margin:16px 0;padding:12px;background:#f9f9f9;border-radius:8px;text-align:center;">
   <a href="/login" style="color:#000;font-weight:bold;">Log in</a> to join the conversation
 </div>`)
@@ -885,7 +908,7 @@ func generateCardHTML(allMessages []*Message) string {
 		if p.ReplyTo != "" {
 			continue // skip replies in home card
 		}
-		if flag.IsHidden("social", p.ID) || auth.IsBanned(p.AuthorID) {
+		if flag.IsHidden("social", p.ID) || auth.IsBanned(p.AuthorID) || threadArchived(p) {
 			continue
 		}
 		if p.AuthorID == "_system" {
@@ -1273,3 +1296,75 @@ func DeleteByAuthor(authorID string) {
 	mutex.Unlock()
 	save()
 }
+
+// DeleteMessage removes a thread or reply by ID.
+func DeleteMessage(id string) error {
+	mutex.Lock()
+	found := false
+	for i, m := range messages {
+		if m.ID == id {
+			messages = append(messages[:i], messages[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if found {
+		updateCacheLocked()
+	}
+	mutex.Unlock()
+	if !found {
+		return fmt.Errorf("message not found")
+	}
+	return save()
+}
+
+// messageDeleter implements flag.ContentDeleter for threads and replies.
+type messageDeleter struct{}
+
+func (d *messageDeleter) Delete(id string) error { return DeleteMessage(id) }
+
+func (d *messageDeleter) Get(id string) interface{} {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	m := getMessage(id)
+	if m == nil {
+		return nil
+	}
+	thread, title := m.ID, "Thread"
+	if m.ReplyTo != "" {
+		thread, title = m.ReplyTo, "Reply"
+	}
+	return flag.PostContent{
+		ID:        m.ID,
+		Title:     title,
+		Content:   m.Content,
+		Author:    m.Author,
+		AuthorID:  m.AuthorID,
+		CreatedAt: m.PostedAt,
+		URL:       "/social/thread?id=" + thread,
+	}
+}
+
+func (d *messageDeleter) RefreshCache() {
+	mutex.Lock()
+	updateCacheLocked()
+	mutex.Unlock()
+}
+
+// RenameAuthor moves a renamed member's messages to their new ID, and
+// their author name where it was just the old ID.
+func RenameAuthor(oldID, newID string) {
+	mutex.Lock()
+	for _, m := range messages {
+		if m.AuthorID != oldID {
+			continue
+		}
+		m.AuthorID = newID
+		if m.Author == oldID {
+			m.Author = newID
+		}
+	}
+	updateCacheLocked()
+	mutex.Unlock()
+	save()
+}
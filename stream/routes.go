@@ -0,0 +1,12 @@
+package stream
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/stream", Handler)
+	r.HandleFunc("/stream/fragment", FragmentHandler)
+}
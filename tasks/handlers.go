@@ -0,0 +1,331 @@
+package tasks
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// Handler serves /tasks: GET lists them, as a page or JSON, and POST
+// adds one from the form or a JSON body of {"title": "...", "due":
+// "2006-01-02"}. Without a due day, one at the end of the title is used,
+// as in chat.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list := List(acc.ID)
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"tasks": list})
+			return
+		}
+		app.Respond(w, r, app.Response{
+			Title:       "Tasks",
+			Description: "Things to do",
+			HTML:        renderTasksPage(list, r.URL.Query().Get("error"), time.Now()),
+		})
+
+	case http.MethodPost:
+		if err := readBody(r); err != nil {
+			app.BadRequest(w, r, "Invalid JSON body")
+			return
+		}
+		title, due := r.FormValue("title"), r.FormValue("due")
+		if due == "" {
+			title, due = ParseCapture(title, time.Now())
+		}
+		t, err := Add(acc.ID, title, due)
+		if err != nil {
+			taskError(w, r, err)
+			return
+		}
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			app.RespondJSON(w, map[string]interface{}{"task": t})
+			return
+		}
+		http.Redirect(w, r, returnTo(r), http.StatusSeeOther)
+
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+// ActionHandler serves POST /tasks/done, /tasks/schedule, /tasks/delete
+// and /tasks/clear, reading the task's id (and done or due) from a form
+// or JSON.
+func ActionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+	if err := readBody(r); err != nil {
+		app.BadRequest(w, r, "Invalid JSON body")
+		return
+	}
+	id := r.FormValue("id")
+
+	var t *Task
+	rsp := map[string]interface{}{"ok": true}
+	switch strings.TrimPrefix(r.URL.Path, "/tasks/") {
+	case "done":
+		done := true
+		if v := r.FormValue("done"); v != "" {
+			done, _ = strconv.ParseBool(v)
+		}
+		t, err = Complete(acc.ID, id, done)
+	case "schedule":
+		t, err = Schedule(acc.ID, id, r.FormValue("due"))
+	case "delete":
+		err = Delete(acc.ID, id)
+	case "clear":
+		rsp["cleared"] = ClearDone(acc.ID)
+	default:
+		app.NotFound(w, r, "Not found")
+		return
+	}
+	if err != nil {
+		taskError(w, r, err)
+		return
+	}
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		if t != nil {
+			rsp["task"] = t
+		}
+		app.RespondJSON(w, rsp)
+		return
+	}
+	http.Redirect(w, r, returnTo(r), http.StatusSeeOther)
+}
+
+// readBody puts the fields of a JSON body into r.Form, so handlers read
+// forms and JSON alike.
+func readBody(r *http.Request) error {
+	if !app.SendsJSON(r) {
+		return r.ParseForm()
+	}
+	var body map[string]interface{}
+	if err := app.DecodeJSON(r, &body); err != nil {
+		return err
+	}
+	r.Form = url.Values{}
+	for k, v := range body {
+		if v != nil {
+			r.Form.Set(k, fmt.Sprint(v))
+		}
+	}
+	return nil
+}
+
+// returnTo is where a form goes back to: the page it names, if it's one
+// of ours, or /tasks.
+func returnTo(r *http.Request) string {
+	if ret := r.FormValue("return"); strings.HasPrefix(ret, "/") && !strings.HasPrefix(ret, "//") {
+		return ret
+	}
+	return "/tasks"
+}
+
+// taskError reports err as JSON, or back on the tasks page.
+func taskError(w http.ResponseWriter, r *http.Request, err error) {
+	if app.WantsJSON(r) || app.SendsJSON(r) {
+		if err == errTaskNotFound {
+			app.NotFound(w, r, err.Error())
+		} else {
+			app.BadRequest(w, r, err.Error())
+		}
+		return
+	}
+	http.Redirect(w, r, "/tasks?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+}
+
+// Card shows the user's tasks for today, and overdue ones, with a box
+// to add another.
+func Card(userID string) string {
+	now := time.Now()
+	var sb strings.Builder
+	list := Today(userID, now)
+	if len(list) == 0 {
+		sb.WriteString(`<p class="text-muted text-sm">Nothing due today.</p>`)
+	}
+	for _, t := range list {
+		sb.WriteString(renderTask(t, now, "/home"))
+	}
+	sb.WriteString(`<form method="POST" action="/tasks" class="task-add">
+<input type="hidden" name="return" value="/home">
+<input type="text" name="title" placeholder="Add a task, e.g. call the bank friday" maxlength="` + strconv.Itoa(maxTitleLen) + `" required>
+</form>`)
+	if n := len(Open(userID)) - len(list); n > 0 {
+		sb.WriteString(app.Link(fmt.Sprintf("%d more", n), "/tasks"))
+	} else {
+		sb.WriteString(app.Link("All tasks", "/tasks"))
+	}
+	return sb.String()
+}
+
+// renderTask is a task with a box to tick it off, which returns to ret.
+func renderTask(t *Task, now time.Time, ret string) string {
+	class := "task"
+	if t.Done {
+		class += " task-done"
+	} else if t.Overdue(now.Format(dateFormat)) {
+		class += " task-overdue"
+	}
+	checked := ""
+	if t.Done {
+		checked = " checked"
+	}
+	due := ""
+	if label := t.DueLabel(now); label != "" {
+		due = fmt.Sprintf(` <span class="task-due">%s</span>`, label)
+	}
+	return fmt.Sprintf(`<form method="POST" action="/tasks/done" class="%s">
+<input type="hidden" name="id" value="%s"><input type="hidden" name="done" value="%t"><input type="hidden" name="return" value="%s">
+<label><input type="checkbox" data-autosubmit%s> <span class="task-title">%s</span>%s</label>
+</form>`, class, html.EscapeString(t.ID), !t.Done, html.EscapeString(ret), checked, html.EscapeString(t.Title), due)
+}
+
+// renderTasksPage lists open tasks, with their days and a way to move
+// or delete each, then those done.
+func renderTasksPage(list []*Task, errMsg string, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="tasks-page">`)
+	if errMsg != "" {
+		sb.WriteString(fmt.Sprintf(`<p class="text-error">%s</p>`, html.EscapeString(errMsg)))
+	}
+	sb.WriteString(`<form method="POST" action="/tasks" class="task-add">
+<input type="text" name="title" placeholder="Add a task" maxlength="` + strconv.Itoa(maxTitleLen) + `" required>
+<input type="date" name="due">
+<button type="submit">Add</button>
+</form>
+<p class="text-muted text-sm">Tip: type <code>/task call the bank friday</code> in chat to add one from there.</p>`)
+
+	var open, done []*Task
+	for _, t := range list {
+		if t.Done {
+			done = append(done, t)
+		} else {
+			open = append(open, t)
+		}
+	}
+	if len(open) == 0 {
+		sb.WriteString(`<p class="text-muted">Nothing to do.</p>`)
+	}
+	for _, t := range open {
+		sb.WriteString(`<div class="task-row">`)
+		sb.WriteString(renderTask(t, now, "/tasks"))
+		sb.WriteString(fmt.Sprintf(`<form method="POST" action="/tasks/schedule" class="task-schedule">
+<input type="hidden" name="id" value="%s">
+<input type="date" name="due" value="%s" data-autosubmit aria-label="Due">
+</form>
+<form method="POST" action="/tasks/delete" class="task-delete">
+<input type="hidden" name="id" value="%s">
+<button type="submit" class="btn-link" title="Delete">Delete</button>
+</form>`, html.EscapeString(t.ID), t.Due, html.EscapeString(t.ID)))
+		sb.WriteString(`</div>`)
+	}
+	if len(done) > 0 {
+		sb.WriteString(fmt.Sprintf(`<h3>Done (%d)</h3>`, len(done)))
+		for _, t := range done {
+			sb.WriteString(renderTask(t, now, "/tasks"))
+		}
+		sb.WriteString(`<form method="POST" action="/tasks/clear"><button type="submit" class="btn-link">Clear done</button></form>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+// Command answers "/task" in chat: "/task call the bank friday" adds a
+// task, "/task" or "/tasks" lists open ones, numbered, and "/task done
+// 2" ticks off the second. It returns the reply as markdown and HTML,
+// and false for other commands.
+func Command(userID, text string) (reply, replyHTML string, ok bool) {
+	name, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	name = strings.ToLower(name)
+	if name != "/task" && name != "/tasks" {
+		return "", "", false
+	}
+	if userID == "" {
+		reply = "Log in to keep tasks."
+		return reply, html.EscapeString(reply), true
+	}
+	args = strings.TrimSpace(args)
+	now := time.Now()
+
+	if args == "" || name == "/tasks" {
+		open := Open(userID)
+		if len(open) == 0 {
+			reply = "No tasks. Add one with `/task call the bank friday`."
+			return reply, "No tasks. Add one with <code>/task call the bank friday</code>.", true
+		}
+		var md, h strings.Builder
+		h.WriteString("<ol>")
+		for i, t := range open {
+			due := ""
+			if label := t.DueLabel(now); label != "" {
+				due = " — " + label
+			}
+			fmt.Fprintf(&md, "%d. %s%s\n", i+1, t.Title, due)
+			fmt.Fprintf(&h, "<li>%s%s</li>", html.EscapeString(t.Title), due)
+		}
+		h.WriteString(`</ol><p><a href="/tasks">All tasks</a></p>`)
+		return md.String(), h.String(), true
+	}
+
+	if verb, rest, _ := strings.Cut(args, " "); strings.EqualFold(verb, "done") {
+		n, err := strconv.Atoi(strings.TrimSpace(rest))
+		open := Open(userID)
+		if err != nil || n < 1 || n > len(open) {
+			reply = "Which one? Use the number from `/tasks`, like `/task done 1`."
+			return reply, "Which one? Use the number from <code>/tasks</code>, like <code>/task done 1</code>.", true
+		}
+		t, err := Complete(userID, open[n-1].ID, true)
+		if err != nil {
+			reply = err.Error()
+			return reply, html.EscapeString(reply), true
+		}
+		reply = "Done: " + t.Title
+		return reply, "Done: <strong>" + html.EscapeString(t.Title) + "</strong>", true
+	}
+
+	title, due := ParseCapture(args, now)
+	t, err := Add(userID, title, due)
+	if err != nil {
+		reply = err.Error()
+		return reply, html.EscapeString(reply), true
+	}
+	reply = "Added: " + t.Title
+	replyHTML = "Added: <strong>" + html.EscapeString(t.Title) + "</strong>"
+	if label := t.DueLabel(now); label != "" {
+		reply += " (" + label + ")"
+		replyHTML += " (" + label + ")"
+	}
+	replyHTML += ` · <a href="/tasks">Tasks</a>`
+	return reply, replyHTML, true
+}
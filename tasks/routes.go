@@ -0,0 +1,12 @@
+package tasks
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/tasks", Handler)
+	r.HandleFunc("/tasks/", ActionHandler) // done, schedule, delete, clear
+}
@@ -0,0 +1,361 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/data"
+
+	"github.com/google/uuid"
+)
+
+// Tasks are a member's own to-do list. A task can be given a day it's
+// due; the home card shows what's due today or overdue, and "/task" in
+// chat adds one without leaving the conversation. They're private to
+// the member.
+
+// Task is something to do.
+type Task struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Due       string    `json:"due,omitempty"` // 2006-01-02, empty for someday
+	Done      bool      `json:"done"`
+	DoneAt    time.Time `json:"done_at,omitzero"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// dateFormat is how Due is written.
+const dateFormat = "2006-01-02"
+
+const (
+	maxTasks    = 1000 // per user
+	maxTitleLen = 200
+)
+
+// errTaskNotFound is returned for a task the user doesn't have.
+var errTaskNotFound = errors.New("task not found")
+
+var (
+	tasksMu sync.RWMutex
+	tasks   = map[string][]*Task{} // userID -> tasks, newest first
+)
+
+// Load reads the saved tasks.
+func Load() {
+	var d map[string][]*Task
+	if err := data.LoadJSON("tasks.json", &d); err == nil && d != nil {
+		tasksMu.Lock()
+		tasks = d
+		tasksMu.Unlock()
+	}
+	data.RegisterExporter(exporter{})
+}
+
+// saveTasks persists every user's tasks. Caller must hold tasksMu.
+func saveTasks() {
+	data.SaveJSON("tasks.json", tasks)
+}
+
+// Overdue reports whether the task is still open after its day.
+func (t *Task) Overdue(today string) bool {
+	return !t.Done && t.Due != "" && t.Due < today
+}
+
+// DueLabel is when the task is due, like "Today" or "Fri 20 Oct".
+func (t *Task) DueLabel(now time.Time) string {
+	if t.Due == "" {
+		return ""
+	}
+	switch t.Due {
+	case now.Format(dateFormat):
+		return "Today"
+	case now.AddDate(0, 0, 1).Format(dateFormat):
+		return "Tomorrow"
+	case now.AddDate(0, 0, -1).Format(dateFormat):
+		return "Yesterday"
+	}
+	d, err := time.Parse(dateFormat, t.Due)
+	if err != nil {
+		return t.Due
+	}
+	if d.Year() != now.Year() {
+		return d.Format("Mon 2 Jan 2006")
+	}
+	return d.Format("Mon 2 Jan")
+}
+
+// List returns the user's tasks: open ones first, by day due with those
+// without a day last, then done ones, most recently done first.
+func List(userID string) []*Task {
+	tasksMu.RLock()
+	out := make([]*Task, len(tasks[userID]))
+	for i, t := range tasks[userID] {
+		cp := *t
+		out[i] = &cp
+	}
+	tasksMu.RUnlock()
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Done != b.Done {
+			return !a.Done
+		}
+		if a.Done {
+			return a.DoneAt.After(b.DoneAt)
+		}
+		if (a.Due == "") != (b.Due == "") {
+			return a.Due != ""
+		}
+		return a.Due < b.Due
+	})
+	return out
+}
+
+// Open returns the user's open tasks, in List order.
+func Open(userID string) []*Task {
+	var out []*Task
+	for _, t := range List(userID) {
+		if !t.Done {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Today returns the user's open tasks due today or before.
+func Today(userID string, now time.Time) []*Task {
+	today := now.Format(dateFormat)
+	var out []*Task
+	for _, t := range Open(userID) {
+		if t.Due != "" && t.Due <= today {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// parseDue checks a day given as 2006-01-02. Empty is no day.
+func parseDue(due string) (string, error) {
+	due = strings.TrimSpace(due)
+	if due == "" {
+		return "", nil
+	}
+	d, err := time.Parse(dateFormat, due)
+	if err != nil {
+		return "", fmt.Errorf("%q isn't a date like 2006-01-02", due)
+	}
+	return d.Format(dateFormat), nil
+}
+
+// Add creates a task, due on the day given or never.
+func Add(userID, title, due string) (*Task, error) {
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return nil, errors.New("a task needs a title")
+	}
+	if len(title) > maxTitleLen {
+		return nil, fmt.Errorf("keep tasks under %d characters", maxTitleLen)
+	}
+	due, err := parseDue(due)
+	if err != nil {
+		return nil, err
+	}
+
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if len(tasks[userID]) >= maxTasks {
+		return nil, fmt.Errorf("you can keep up to %d tasks", maxTasks)
+	}
+	t := &Task{ID: uuid.New().String(), Title: title, Due: due, CreatedAt: time.Now()}
+	tasks[userID] = append([]*Task{t}, tasks[userID]...)
+	saveTasks()
+	cp := *t
+	return &cp, nil
+}
+
+// change applies fn to one of the user's tasks and saves it.
+func change(userID, id string, fn func(t *Task)) (*Task, error) {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	for _, t := range tasks[userID] {
+		if t.ID == id {
+			fn(t)
+			saveTasks()
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, errTaskNotFound
+}
+
+// Complete marks a task done, or open again.
+func Complete(userID, id string, done bool) (*Task, error) {
+	return change(userID, id, func(t *Task) {
+		if t.Done == done {
+			return
+		}
+		t.Done = done
+		t.DoneAt = time.Time{}
+		if done {
+			t.DoneAt = time.Now()
+		}
+	})
+}
+
+// Schedule sets the day a task is due; empty takes the day away.
+func Schedule(userID, id, due string) (*Task, error) {
+	due, err := parseDue(due)
+	if err != nil {
+		return nil, err
+	}
+	return change(userID, id, func(t *Task) { t.Due = due })
+}
+
+// Delete removes a task.
+func Delete(userID, id string) error {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	list := tasks[userID]
+	for i, t := range list {
+		if t.ID == id {
+			tasks[userID] = append(list[:i:i], list[i+1:]...)
+			if len(tasks[userID]) == 0 {
+				delete(tasks, userID)
+			}
+			saveTasks()
+			return nil
+		}
+	}
+	return errTaskNotFound
+}
+
+// ClearDone removes the user's finished tasks and returns how many.
+func ClearDone(userID string) int {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	var keep []*Task
+	for _, t := range tasks[userID] {
+		if !t.Done {
+			keep = append(keep, t)
+		}
+	}
+	n := len(tasks[userID]) - len(keep)
+	if n == 0 {
+		return 0
+	}
+	if len(keep) == 0 {
+		delete(tasks, userID)
+	} else {
+		tasks[userID] = keep
+	}
+	saveTasks()
+	return n
+}
+
+// DeleteTasks removes a deleted account's tasks.
+func DeleteTasks(userID string) {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if _, ok := tasks[userID]; ok {
+		delete(tasks, userID)
+		saveTasks()
+	}
+}
+
+// RenameTasks moves a renamed account's tasks to its new ID.
+func RenameTasks(oldID, newID string) {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if list, ok := tasks[oldID]; ok {
+		tasks[newID] = list
+		delete(tasks, oldID)
+		saveTasks()
+	}
+}
+
+// weekdays are the day names quick capture understands, with their
+// short forms.
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// ParseCapture splits quick capture like "call the bank tomorrow" into
+// the task and the day it's due. A day at the end, as today, tomorrow,
+// a weekday (the next one, or today) or 2006-01-02, and an "on", "by"
+// or "due" before it, are taken off the title.
+func ParseCapture(text string, now time.Time) (title, due string) {
+	words := strings.Fields(text)
+	if len(words) < 2 {
+		return strings.Join(words, " "), ""
+	}
+	last := strings.ToLower(strings.TrimRight(words[len(words)-1], ".,!"))
+	switch {
+	case last == "today" || last == "tonight":
+		due = now.Format(dateFormat)
+	case last == "tomorrow":
+		due = now.AddDate(0, 0, 1).Format(dateFormat)
+	default:
+		if wd, ok := weekdays[last]; ok {
+			ahead := (int(wd) - int(now.Weekday()) + 7) % 7
+			due = now.AddDate(0, 0, ahead).Format(dateFormat)
+		} else if d, err := time.Parse(dateFormat, last); err == nil {
+			due = d.Format(dateFormat)
+		}
+	}
+	if due == "" {
+		return strings.Join(words, " "), ""
+	}
+	words = words[:len(words)-1]
+	if n := len(words); n > 1 {
+		switch strings.ToLower(words[n-1]) {
+		case "on", "by", "due":
+			words = words[:n-1]
+		}
+	}
+	return strings.Join(words, " "), due
+}
+
+// ForContext lists the user's tasks due today or overdue for the agent,
+// or is empty when there are none.
+func ForContext(userID string) string {
+	now := time.Now()
+	today := Today(userID, now)
+	if len(today) == 0 {
+		return ""
+	}
+	var titles []string
+	for _, t := range today {
+		title := t.Title
+		if t.Overdue(now.Format(dateFormat)) {
+			title += " (overdue)"
+		}
+		titles = append(titles, title)
+	}
+	return "Tasks due today: " + strings.Join(titles, "; ")
+}
+
+// exporter exports a member's tasks.
+type exporter struct{}
+
+func (exporter) Name() string        { return "tasks" }
+func (exporter) Description() string { return "Your tasks" }
+func (exporter) Formats() []string   { return []string{"json"} }
+
+func (exporter) Export(w io.Writer, userID, format string) error {
+	list := List(userID)
+	if list == nil {
+		list = []*Task{}
+	}
+	return json.NewEncoder(w).Encode(list)
+}
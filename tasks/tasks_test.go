@@ -0,0 +1,185 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestParseCapture(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC) // a Wednesday
+	for _, tt := range []struct {
+		text, title, due string
+	}{
+		{"buy milk", "buy milk", ""},
+		{"buy milk today", "buy milk", "2026-10-14"},
+		{"call the bank tomorrow", "call the bank", "2026-10-15"},
+		{"pay rent by friday", "pay rent", "2026-10-16"},
+		{"book dentist Monday.", "book dentist", "2026-10-19"},
+		{"water plants wed", "water plants", "2026-10-14"},
+		{"renew passport on 2026-12-01", "renew passport", "2026-12-01"},
+		{"tomorrow", "tomorrow", ""},
+		{"  plan   the   week  ", "plan the week", ""},
+	} {
+		title, due := ParseCapture(tt.text, now)
+		if title != tt.title || due != tt.due {
+			t.Errorf("ParseCapture(%q) = %q, %q; want %q, %q", tt.text, title, due, tt.title, tt.due)
+		}
+	}
+}
+
+func TestTasks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tasksMu.Lock()
+	orig := tasks
+	tasks = map[string][]*Task{}
+	tasksMu.Unlock()
+	defer func() {
+		tasksMu.Lock()
+		tasks = orig
+		tasksMu.Unlock()
+	}()
+
+	if err := auth.Create(&auth.Account{ID: "tasks_user", Name: "Tasks", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("tasks_user")
+	sess, err := auth.Login("tasks_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(target, body string) *httptest.ResponseRecorder {
+		method := "POST"
+		if body == "" {
+			method = "GET"
+		}
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		w := httptest.NewRecorder()
+		if target == "/tasks" {
+			Handler(w, r)
+		} else {
+			ActionHandler(w, r)
+		}
+		return w
+	}
+	add := func(body string) *Task {
+		t.Helper()
+		w := do("/tasks", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("add %s: %d %s", body, w.Code, w.Body)
+		}
+		var rsp struct{ Task *Task }
+		json.NewDecoder(w.Body).Decode(&rsp)
+		return rsp.Task
+	}
+
+	now := time.Now()
+	today := now.Format(dateFormat)
+	yesterday := now.AddDate(0, 0, -1).Format(dateFormat)
+	later := add(`{"title":"someday"}`)
+	overdue := add(`{"title":"file taxes","due":"` + yesterday + `"}`)
+	add(`{"title":"call the bank today"}`)
+	if w := do("/tasks", `{"title":"   "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty title: %d", w.Code)
+	}
+	if w := do("/tasks", `{"title":"x","due":"next week"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bad date: %d", w.Code)
+	}
+
+	// Dated tasks first, earliest first.
+	var titles []string
+	for _, task := range List("tasks_user") {
+		titles = append(titles, task.Title)
+	}
+	if got := strings.Join(titles, ","); got != "file taxes,call the bank,someday" {
+		t.Errorf("list = %s", got)
+	}
+	if got := Today("tasks_user", now); len(got) != 2 || got[1].Due != today {
+		t.Errorf("today = %+v", got)
+	}
+	card := Card("tasks_user")
+	if !strings.Contains(card, "call the bank") || !strings.Contains(card, "task-overdue") || strings.Contains(card, "someday") {
+		t.Errorf("card = %s", card)
+	}
+	if got := ForContext("tasks_user"); got != "Tasks due today: file taxes (overdue); call the bank" {
+		t.Errorf("context = %q", got)
+	}
+
+	if w := do("/tasks/done", `{"id":"`+overdue.ID+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("done: %d %s", w.Code, w.Body)
+	}
+	if w := do("/tasks/schedule", `{"id":"`+later.ID+`","due":"`+today+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("schedule: %d %s", w.Code, w.Body)
+	}
+	if got := Today("tasks_user", now); len(got) != 2 || got[0].Title == "file taxes" {
+		t.Errorf("today after changes = %+v", got)
+	}
+	if w := do("/tasks/done", `{"id":"nope"}`); w.Code != http.StatusNotFound {
+		t.Errorf("someone else's task: %d", w.Code)
+	}
+	if w := do("/tasks/clear", `{}`); w.Code != http.StatusOK || len(List("tasks_user")) != 2 {
+		t.Errorf("clear: %d %s", w.Code, w.Body)
+	}
+
+	w := do("/tasks", "")
+	var rsp struct{ Tasks []*Task }
+	json.NewDecoder(w.Body).Decode(&rsp)
+	if w.Code != http.StatusOK || len(rsp.Tasks) != 2 {
+		t.Errorf("GET: %d %+v", w.Code, rsp.Tasks)
+	}
+
+	// Signed out, nothing's shown.
+	r := httptest.NewRequest("GET", "/tasks", nil)
+	r.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	Handler(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: %d", w.Code)
+	}
+}
+
+func TestTaskCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tasksMu.Lock()
+	orig := tasks
+	tasks = map[string][]*Task{}
+	tasksMu.Unlock()
+	defer func() {
+		tasksMu.Lock()
+		tasks = orig
+		tasksMu.Unlock()
+	}()
+
+	if _, _, ok := Command("u", "/weather london"); ok {
+		t.Error("answered another command")
+	}
+	reply, replyHTML, ok := Command("u", "/task <b>buy</b> milk tomorrow")
+	if !ok || reply != "Added: <b>buy</b> milk (Tomorrow)" || strings.Contains(replyHTML, "<b>") {
+		t.Errorf("add = %q, %q", reply, replyHTML)
+	}
+	Command("u", "/task book dentist")
+	if reply, _, _ := Command("u", "/tasks"); reply != "1. <b>buy</b> milk — Tomorrow\n2. book dentist\n" {
+		t.Errorf("list = %q", reply)
+	}
+	if reply, _, _ := Command("u", "/task done 2"); reply != "Done: book dentist" {
+		t.Errorf("done = %q", reply)
+	}
+	if reply, _, _ := Command("u", "/task done 5"); !strings.HasPrefix(reply, "Which one?") {
+		t.Errorf("done out of range = %q", reply)
+	}
+	if open := Open("u"); len(open) != 1 || open[0].Title != "<b>buy</b> milk" {
+		t.Errorf("open = %+v", open)
+	}
+}
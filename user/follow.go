@@ -0,0 +1,191 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Following is one-way: anyone signed in can follow anyone else. Two
+// people who follow each other are mutual followers, which the Mutual
+// privacy level keys on.
+
+var (
+	followMutex sync.RWMutex
+	follows     = map[string]map[string]bool{} // follower → followed
+)
+
+func init() {
+	b, _ := data.LoadFile("follows.json")
+	json.Unmarshal(b, &follows)
+}
+
+// Follow makes from a follower of to.
+func Follow(from, to string) error {
+	if from == "" || from == to {
+		return fmt.Errorf("you can't follow yourself")
+	}
+	if _, err := auth.GetAccount(to); err != nil {
+		return fmt.Errorf("no such user")
+	}
+	followMutex.Lock()
+	defer followMutex.Unlock()
+	if follows[from] == nil {
+		follows[from] = map[string]bool{}
+	}
+	follows[from][to] = true
+	return data.SaveJSON("follows.json", follows)
+}
+
+// Unfollow stops from following to.
+func Unfollow(from, to string) error {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+	if !follows[from][to] {
+		return nil
+	}
+	delete(follows[from], to)
+	if len(follows[from]) == 0 {
+		delete(follows, from)
+	}
+	return data.SaveJSON("follows.json", follows)
+}
+
+// IsFollowing reports whether from follows to.
+func IsFollowing(from, to string) bool {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+	return follows[from][to]
+}
+
+// FollowEachOther reports whether a and b follow each other.
+func FollowEachOther(a, b string) bool {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+	return a != b && follows[a][b] && follows[b][a]
+}
+
+// Following returns who userID follows, sorted.
+func Following(userID string) []string {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+	out := make([]string, 0, len(follows[userID]))
+	for id := range follows[userID] {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Followers returns who follows userID, sorted.
+func Followers(userID string) []string {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+	var out []string
+	for from, set := range follows {
+		if set[userID] {
+			out = append(out, from)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ClearFollows removes a user from the follow graph, both ways. Used on
+// account deletion.
+func ClearFollows(userID string) {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+	delete(follows, userID)
+	for from, set := range follows {
+		delete(set, userID)
+		if len(set) == 0 {
+			delete(follows, from)
+		}
+	}
+	data.SaveJSON("follows.json", follows)
+}
+
+// renameFollows moves a renamed user's follows, both ways.
+func renameFollows(oldID, newID string) {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+	changed := false
+	if set, ok := follows[oldID]; ok {
+		delete(follows, oldID)
+		follows[newID] = set
+		changed = true
+	}
+	for _, set := range follows {
+		if set[oldID] {
+			delete(set, oldID)
+			set[newID] = true
+			changed = true
+		}
+	}
+	if changed {
+		data.SaveJSON("follows.json", follows)
+	}
+}
+
+// FollowHandler serves /user/follow. POST follows or unfollows a user
+// (action "follow" or "unfollow"); GET lists who the caller follows and
+// who follows them.
+func FollowHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		app.RespondJSON(w, map[string]interface{}{
+			"following": Following(acc.ID),
+			"followers": Followers(acc.ID),
+		})
+	case "POST":
+		var req struct {
+			ID     string `json:"id"`
+			Action string `json:"action"`
+		}
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &req); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			req.ID = r.FormValue("id")
+			req.Action = r.FormValue("action")
+		}
+		switch req.Action {
+		case "follow":
+			err = Follow(acc.ID, req.ID)
+		case "unfollow":
+			err = Unfollow(acc.ID, req.ID)
+		default:
+			err = fmt.Errorf("unknown action")
+		}
+		if err != nil {
+			app.BadRequest(w, r, err.Error())
+			return
+		}
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{
+				"following": IsFollowing(acc.ID, req.ID),
+				"mutual":    FollowEachOther(acc.ID, req.ID),
+			})
+			return
+		}
+		http.Redirect(w, r, "/@"+req.ID, http.StatusSeeOther)
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
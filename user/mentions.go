@@ -0,0 +1,65 @@
+package user
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// maxMentionSuggestions is how many usernames MentionsHandler returns.
+const maxMentionSuggestions = 8
+
+// Mention is a username suggested while typing an @mention.
+type Mention struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SuggestMentions returns the accounts whose username or name starts with
+// prefix, usernames first, for the @mention autocomplete. Banned accounts
+// are left out.
+func SuggestMentions(prefix string, max int) []Mention {
+	prefix = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(prefix), "@"))
+	if prefix == "" {
+		return []Mention{}
+	}
+	var byID, byName []Mention
+	for _, acc := range auth.GetAllAccounts() {
+		if acc.Banned {
+			continue
+		}
+		m := Mention{ID: acc.ID, Name: acc.Name}
+		switch {
+		case strings.HasPrefix(acc.ID, prefix):
+			byID = append(byID, m)
+		case strings.HasPrefix(strings.ToLower(acc.Name), prefix):
+			byName = append(byName, m)
+		}
+	}
+	sort.Slice(byID, func(i, j int) bool { return byID[i].ID < byID[j].ID })
+	sort.Slice(byName, func(i, j int) bool { return byName[i].ID < byName[j].ID })
+	out := append(append([]Mention{}, byID...), byName...)
+	if len(out) > max {
+		out = out[:max]
+	}
+	return out
+}
+
+// MentionsHandler suggests usernames for the @mention autocomplete in the
+// compose forms.
+//
+//	GET /user/mentions?q={prefix}
+func MentionsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := auth.RequireSession(r); err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	if r.Method != "GET" {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	app.RespondJSON(w, SuggestMentions(r.URL.Query().Get("q"), maxMentionSuggestions))
+}
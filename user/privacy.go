@@ -0,0 +1,280 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Visibility controls who can see a profile element.
+type Visibility string
+
+const (
+	// Public elements are visible to everyone, including guests.
+	Public Visibility = "public"
+	// Members elements are visible to any logged-in account.
+	Members Visibility = "members"
+	// Mutual elements are visible to mutual followers (see Follow).
+	Mutual Visibility = "mutual"
+	// Private elements are visible only to the owner (and admins).
+	Private Visibility = "private"
+)
+
+// Profile elements that carry their own visibility setting.
+const (
+	FieldStatus   = "status"   // current status message
+	FieldHistory  = "history"  // past statuses (the activity feed)
+	FieldPosts    = "posts"    // blog posts listed on the profile
+	FieldApps     = "apps"     // apps listed on the profile
+	FieldPresence = "presence" // online indicator
+	FieldWelcome  = "welcome"  // named as a new member in the weekly digest
+	FieldMessages = "messages" // who can send direct messages
+)
+
+// PrivacyField describes a profile element for the settings page.
+type PrivacyField struct {
+	ID      string
+	Label   string
+	Default Visibility
+}
+
+// PrivacyFields is the ordered list of controllable profile elements and
+// their defaults. Anything people already see today stays public by
+// default (the home status stream is built from status history); only
+// presence defaults to members so a drive-by visitor can't tell when
+// someone is around. Being welcomed in the weekly digest is opt-in. For
+// direct messages "seeing" means being able to send one, which always
+// takes an account, so Everyone and Members come to the same thing.
+var PrivacyFields = []PrivacyField{
+	{FieldStatus, "Status", Public},
+	{FieldHistory, "Status history", Public},
+	{FieldPosts, "Posts", Public},
+	{FieldApps, "Apps", Public},
+	{FieldPresence, "Online presence", Members},
+	{FieldWelcome, "Welcome in the weekly digest", Private},
+	{FieldMessages, "Direct messages", Members},
+}
+
+var (
+	privacyMutex sync.RWMutex
+	privacy      = map[string]map[string]Visibility{} // userID → field → visibility
+)
+
+func init() {
+	b, _ := data.LoadFile("privacy.json")
+	json.Unmarshal(b, &privacy)
+}
+
+// validVisibility reports whether v is one of the known levels.
+func validVisibility(v Visibility) bool {
+	return v == Public || v == Members || v == Mutual || v == Private
+}
+
+// fieldDefault returns the default visibility for a field, or Public for
+// unknown fields.
+func fieldDefault(field string) Visibility {
+	for _, f := range PrivacyFields {
+		if f.ID == field {
+			return f.Default
+		}
+	}
+	return Public
+}
+
+// GetVisibility returns the effective visibility of a profile element.
+func GetVisibility(userID, field string) Visibility {
+	privacyMutex.RLock()
+	v, ok := privacy[userID][field]
+	privacyMutex.RUnlock()
+	if !ok || !validVisibility(v) {
+		return fieldDefault(field)
+	}
+	return v
+}
+
+// SetVisibility stores the visibility of a profile element.
+func SetVisibility(userID, field string, v Visibility) error {
+	if !validVisibility(v) {
+		return fmt.Errorf("invalid visibility %q", v)
+	}
+	known := false
+	for _, f := range PrivacyFields {
+		if f.ID == field {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown profile field %q", field)
+	}
+	privacyMutex.Lock()
+	defer privacyMutex.Unlock()
+	if privacy[userID] == nil {
+		privacy[userID] = map[string]Visibility{}
+	}
+	privacy[userID][field] = v
+	return data.SaveJSON("privacy.json", privacy)
+}
+
+// CanView reports whether viewerID may see the given element of ownerID's
+// profile. An empty viewerID is a guest. Owners and admins see everything.
+func CanView(ownerID, field, viewerID string) bool {
+	if viewerID != "" && viewerID == ownerID {
+		return true
+	}
+	switch GetVisibility(ownerID, field) {
+	case Public:
+		return true
+	case Members:
+		return viewerID != ""
+	case Mutual:
+		if viewerID != "" && FollowEachOther(ownerID, viewerID) {
+			return true
+		}
+	}
+	if viewerID == "" {
+		return false
+	}
+	acc, err := auth.GetAccount(viewerID)
+	return err == nil && acc.Admin
+}
+
+// PrivacyAudit returns the effective visibility of every profile element
+// for a user, keyed by field ID.
+func PrivacyAudit(userID string) map[string]Visibility {
+	audit := make(map[string]Visibility, len(PrivacyFields))
+	for _, f := range PrivacyFields {
+		audit[f.ID] = GetVisibility(userID, f.ID)
+	}
+	return audit
+}
+
+// ClearPrivacy removes a user's privacy settings. Used on account deletion.
+func ClearPrivacy(userID string) {
+	privacyMutex.Lock()
+	defer privacyMutex.Unlock()
+	if _, ok := privacy[userID]; ok {
+		delete(privacy, userID)
+		data.SaveJSON("privacy.json", privacy)
+	}
+}
+
+// visibleOnlineUsers filters the online user list down to the accounts
+// whose presence the viewer is allowed to see.
+func visibleOnlineUsers(users []string, viewerID string) []string {
+	visible := make([]string, 0, len(users))
+	for _, u := range users {
+		if CanView(u, FieldPresence, viewerID) {
+			visible = append(visible, u)
+		}
+	}
+	return visible
+}
+
+// PrivacyHandler serves /user/privacy. GET renders the settings page with
+// an audit of what is currently visible to whom (JSON when requested);
+// POST updates one or more fields.
+func PrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var updates map[string]Visibility
+		if app.SendsJSON(r) {
+			if err := app.DecodeJSON(r, &updates); err != nil {
+				app.BadRequest(w, r, "invalid JSON")
+				return
+			}
+		} else {
+			r.ParseForm()
+			updates = map[string]Visibility{}
+			for _, f := range PrivacyFields {
+				if v := r.Form.Get(f.ID); v != "" {
+					updates[f.ID] = Visibility(v)
+				}
+			}
+		}
+		for field, v := range updates {
+			if err := SetVisibility(acc.ID, field, v); err != nil {
+				app.BadRequest(w, r, err.Error())
+				return
+			}
+		}
+		app.Log("user", "Privacy updated for %s", acc.ID)
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.RespondJSON(w, PrivacyAudit(acc.ID))
+			return
+		}
+		http.Redirect(w, r, "/user/privacy", http.StatusSeeOther)
+		return
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	audit := PrivacyAudit(acc.ID)
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, audit)
+		return
+	}
+
+	labels := map[Visibility]string{
+		Public:  "Everyone",
+		Members: "Members",
+		Mutual:  "Mutual followers",
+		Private: "Only me",
+	}
+	var rows string
+	for _, f := range PrivacyFields {
+		var opts string
+		for _, v := range []Visibility{Public, Members, Mutual, Private} {
+			selected := ""
+			if audit[f.ID] == v {
+				selected = " selected"
+			}
+			opts += fmt.Sprintf(`<option value="%s"%s>%s</option>`, v, selected, labels[v])
+		}
+		rows += fmt.Sprintf(`<label style="display:flex;align-items:center;justify-content:space-between;gap:8px;padding:6px 0;font-size:14px;border-bottom:1px solid #f0f0f0">%s <select name="%s" class="form-select text-sm">%s</select></label>`, f.Label, f.ID, opts)
+	}
+
+	var public []string
+	for _, f := range PrivacyFields {
+		if audit[f.ID] == Public {
+			public = append(public, f.Label)
+		}
+	}
+	summary := "Nothing on your profile is public."
+	if len(public) > 0 {
+		summary = "Visible to everyone right now: "
+		for i, l := range public {
+			if i > 0 {
+				summary += ", "
+			}
+			summary += l
+		}
+		summary += "."
+	}
+
+	content := fmt.Sprintf(`<div class="card">
+<h4>Privacy</h4>
+<p class="text-sm text-muted">Choose who can see each part of your <a href="/@%s">profile</a>. Members are anyone signed in to this instance; mutual followers are people you follow who follow you back.</p>
+<p class="text-sm">%s</p>
+<form action="/user/privacy" method="POST" style="margin-top:8px">
+%s
+<button type="submit" class="mt-2">Save</button>
+</form>
+</div>
+<p><a href="/account">← Account</a></p>`, acc.ID, summary, rows)
+
+	w.Write([]byte(app.RenderHTML("Privacy", "Profile privacy settings", content)))
+}
@@ -0,0 +1,147 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func withPrivacy(t *testing.T, settings map[string]map[string]Visibility) {
+	t.Helper()
+	privacyMutex.Lock()
+	saved := privacy
+	privacy = settings
+	privacyMutex.Unlock()
+	t.Cleanup(func() {
+		privacyMutex.Lock()
+		privacy = saved
+		privacyMutex.Unlock()
+	})
+}
+
+func TestCanView_Defaults(t *testing.T) {
+	withPrivacy(t, map[string]map[string]Visibility{})
+
+	if !CanView("alice", FieldStatus, "") {
+		t.Error("status should be public by default")
+	}
+	if CanView("alice", FieldPresence, "") {
+		t.Error("presence should be hidden from guests by default")
+	}
+	if !CanView("alice", FieldPresence, "bob") {
+		t.Error("presence should be visible to members by default")
+	}
+}
+
+func TestCanView_Levels(t *testing.T) {
+	withPrivacy(t, map[string]map[string]Visibility{
+		"alice": {FieldStatus: Members, FieldPosts: Private},
+	})
+
+	cases := []struct {
+		field, viewer string
+		want          bool
+	}{
+		{FieldStatus, "", false},
+		{FieldStatus, "bob", true},
+		{FieldPosts, "", false},
+		{FieldPosts, "bob", false},
+		{FieldPosts, "alice", true},
+		{FieldApps, "", true},
+	}
+	for _, tc := range cases {
+		if got := CanView("alice", tc.field, tc.viewer); got != tc.want {
+			t.Errorf("CanView(alice, %s, %q) = %v, want %v", tc.field, tc.viewer, got, tc.want)
+		}
+	}
+}
+
+func TestSetVisibility_Validates(t *testing.T) {
+	withPrivacy(t, map[string]map[string]Visibility{})
+
+	if err := SetVisibility("alice", FieldStatus, "friends"); err == nil {
+		t.Error("expected error for unknown visibility")
+	}
+	if err := SetVisibility("alice", "shoe_size", Public); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestStatusStream_HonoursPrivacy(t *testing.T) {
+	withPrivacy(t, map[string]map[string]Visibility{
+		"alice": {FieldStatus: Private, FieldHistory: Private},
+		"bob":   {FieldHistory: Members},
+	})
+	profileMutex.Lock()
+	saved := profiles
+	now := time.Now()
+	profiles = map[string]*Profile{
+		"alice": {UserID: "alice", Status: "secret", UpdatedAt: now},
+		"bob": {UserID: "bob", Status: "bob now", UpdatedAt: now,
+			History: []StatusHistory{{Status: "bob old", SetAt: now.Add(-time.Minute)}}},
+	}
+	profileMutex.Unlock()
+	t.Cleanup(func() {
+		profileMutex.Lock()
+		profiles = saved
+		profileMutex.Unlock()
+	})
+
+	guest := StatusStream(100, "")
+	if len(guest) != 1 || guest[0].Status != "bob now" {
+		t.Fatalf("guest stream = %+v, want only bob's current status", guest)
+	}
+	member := StatusStream(100, "carol")
+	if len(member) != 2 {
+		t.Fatalf("member stream has %d entries, want 2: %+v", len(member), member)
+	}
+	own := StatusStream(100, "alice")
+	if len(own) != 3 {
+		t.Fatalf("owner stream has %d entries, want 3: %+v", len(own), own)
+	}
+}
+
+func TestVisibleOnlineUsers(t *testing.T) {
+	withPrivacy(t, map[string]map[string]Visibility{
+		"alice": {FieldPresence: Public},
+		"bob":   {FieldPresence: Private},
+	})
+	users := []string{"alice", "bob", "carol"}
+
+	if got := visibleOnlineUsers(users, ""); len(got) != 1 || got[0] != "alice" {
+		t.Errorf("guest sees %v, want [alice]", got)
+	}
+	if got := visibleOnlineUsers(users, "dave"); len(got) != 2 {
+		t.Errorf("member sees %v, want [alice carol]", got)
+	}
+	if got := visibleOnlineUsers(users, "bob"); len(got) != 3 {
+		t.Errorf("bob sees %v, want everyone", got)
+	}
+}
+
+func TestCanView_MutualFollowers(t *testing.T) {
+	withPrivacy(t, map[string]map[string]Visibility{
+		"alice": {FieldMessages: Mutual},
+	})
+	followMutex.Lock()
+	saved := follows
+	follows = map[string]map[string]bool{
+		"alice": {"bob": true, "dave": true},
+		"bob":   {"alice": true},
+		"carol": {"alice": true},
+	}
+	followMutex.Unlock()
+	t.Cleanup(func() {
+		followMutex.Lock()
+		follows = saved
+		followMutex.Unlock()
+	})
+
+	for viewer, want := range map[string]bool{"bob": true, "carol": false, "dave": false, "": false, "alice": true} {
+		if got := CanView("alice", FieldMessages, viewer); got != want {
+			t.Errorf("CanView(alice, messages, %q) = %v, want %v", viewer, got, want)
+		}
+	}
+	if !CanView("bob", FieldMessages, "carol") {
+		t.Error("messages should be open to members by default")
+	}
+}
@@ -0,0 +1,16 @@
+package user
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/user/status", StatusHandler)
+	r.HandleFunc("/user/status/stream", StatusStreamHandler)
+	r.HandleFunc("/user/privacy", PrivacyHandler)
+	r.HandleFunc("/user/follow", FollowHandler)
+	r.HandleFunc("/user/mentions", MentionsHandler)
+	r.HandleFunc("/presence", PresenceHandler) // presence websocket
+}
@@ -21,11 +21,11 @@ import (
 // UserPost is a simplified post representation for profile rendering.
 // Wired from blog building block via GetUserPosts callback.
 type UserPost struct {
-	ID        string
-	Title     string
-	Content   string
-	CreatedAt time.Time
-	Private   bool
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	Private   bool      `json:"private,omitempty"`
 }
 
 // GetUserPosts returns posts by author name. Wired from main.go.
@@ -33,10 +33,10 @@ var GetUserPosts func(authorName string) []UserPost
 
 // UserApp is a simplified app representation for profile rendering.
 type UserApp struct {
-	Slug        string
-	Name        string
-	Description string
-	Icon        string
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon,omitempty"`
 }
 
 // GetUserApps returns public apps by author ID. Wired from main.go.
@@ -115,16 +115,17 @@ func presenceBroadcaster() {
 func broadcastPresence() {
 	users := auth.GetOnlineUsers()
 
-	msg := PresenceMessage{
-		Type:  "presence",
-		Users: users,
-		Count: len(users),
-	}
-
-	data, _ := json.Marshal(msg)
-
+	// Each client only sees the users whose presence is visible to it,
+	// so the message is built per connection.
 	presenceClientsMutex.RLock()
-	for conn := range presenceClients {
+	for conn, client := range presenceClients {
+		visible := visibleOnlineUsers(users, client.UserID)
+		msg := PresenceMessage{
+			Type:  "presence",
+			Users: visible,
+			Count: len(visible),
+		}
+		data, _ := json.Marshal(msg)
 		err := conn.WriteMessage(websocket.TextMessage, data)
 		if err != nil {
 			conn.Close()
@@ -163,7 +164,7 @@ func PresenceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send current user list immediately
-	users := auth.GetOnlineUsers()
+	users := visibleOnlineUsers(auth.GetOnlineUsers(), userID)
 	msg := PresenceMessage{
 		Type:  "presence",
 		Users: users,
@@ -223,12 +224,20 @@ func GetProfile(userID string) *Profile {
 // aliased — UpdateProfile reads the old status from the map correctly
 // and pushes it to history before storing the new one.
 func UpdateStatus(userID, newStatus string) error {
-	return UpdateProfile(&Profile{
+	err := UpdateProfile(&Profile{
 		UserID: userID,
 		Status: newStatus,
 	})
+	if err == nil && newStatus != "" && OnStatus != nil {
+		go OnStatus(userID, newStatus)
+	}
+	return err
 }
 
+// OnStatus is called after a user posts a non-empty status. Wired from
+// main.go.
+var OnStatus func(userID, status string)
+
 // UpdateProfile saves a user's profile. Every non-empty previous
 // status is pushed onto the history so the full timeline of what a
 // user has said is preserved. Empty updates (clearing a status) are
@@ -292,6 +301,9 @@ func RecentStatuses(viewerID string, max int) []StatusEntry {
 		if p.Status == "" || p.UpdatedAt.Before(cutoff) {
 			continue
 		}
+		if !CanView(p.UserID, FieldStatus, viewerID) {
+			continue
+		}
 		name := p.UserID
 		if acc, err := auth.GetAccount(p.UserID); err == nil {
 			name = acc.Name
@@ -357,7 +369,7 @@ func StatusStreamCapped(maxTotal, maxPerUser int, viewerID string) []StatusEntry
 		// BEFORE merging, so an older entry from a flooding user can't
 		// push a more recent entry from another user off the end.
 		var userEntries []StatusEntry
-		if p.Status != "" && !p.UpdatedAt.Before(cutoff) {
+		if p.Status != "" && !p.UpdatedAt.Before(cutoff) && CanView(p.UserID, FieldStatus, viewerID) {
 			userEntries = append(userEntries, StatusEntry{
 				UserID:    p.UserID,
 				Name:      name,
@@ -365,8 +377,9 @@ func StatusStreamCapped(maxTotal, maxPerUser int, viewerID string) []StatusEntry
 				UpdatedAt: p.UpdatedAt,
 			})
 		}
+		showHistory := CanView(p.UserID, FieldHistory, viewerID)
 		for _, h := range p.History {
-			if h.SetAt.Before(cutoff) {
+			if !showHistory || h.SetAt.Before(cutoff) {
 				continue
 			}
 			userEntries = append(userEntries, StatusEntry{
@@ -426,11 +439,12 @@ func StatusCountSince(since time.Time, viewerID string) int {
 		if auth.IsBanned(p.UserID) && p.UserID != viewerID {
 			continue
 		}
-		if !p.UpdatedAt.IsZero() && !p.UpdatedAt.Before(cutoff) && p.UpdatedAt.After(since) {
+		if !p.UpdatedAt.IsZero() && !p.UpdatedAt.Before(cutoff) && p.UpdatedAt.After(since) && CanView(p.UserID, FieldStatus, viewerID) {
 			count++
 		}
+		showHistory := CanView(p.UserID, FieldHistory, viewerID)
 		for _, h := range p.History {
-			if !h.SetAt.Before(cutoff) && h.SetAt.After(since) {
+			if showHistory && !h.SetAt.Before(cutoff) && h.SetAt.After(since) {
 				count++
 			}
 		}
@@ -570,6 +584,29 @@ func ClearStatusHistory(userID string) {
 	}
 }
 
+// RenameUser moves a renamed user's profile, privacy settings and follows
+// to their new ID. Called when a username changes.
+func RenameUser(oldID, newID string) {
+	profileMutex.Lock()
+	if p, ok := profiles[oldID]; ok {
+		p.UserID = newID
+		delete(profiles, oldID)
+		profiles[newID] = p
+		data.SaveJSON("profiles.json", profiles)
+	}
+	profileMutex.Unlock()
+
+	privacyMutex.Lock()
+	if v, ok := privacy[oldID]; ok {
+		delete(privacy, oldID)
+		privacy[newID] = v
+		data.SaveJSON("privacy.json", privacy)
+	}
+	privacyMutex.Unlock()
+
+	renameFollows(oldID, newID)
+}
+
 // ClearAllStatuses wipes every user's status + history. Nuclear option
 // for when the feed is full of garbage.
 func ClearAllStatuses() {
@@ -663,23 +700,42 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the user account
+	// Get the user account. A recently changed username redirects to
+	// the new one.
 	acc, err := auth.GetAccount(username)
 	if err != nil {
+		if to, ok := auth.RenamedTo(username); ok {
+			target := "/@" + to
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
 		http.Error(w, "User not found", 404)
 		return
 	}
 
+	// Resolve the viewer once — every profile element is gated on it.
+	sess, viewerAcc := auth.TrySession(r)
+	viewerID := ""
+	if sess != nil {
+		viewerID = sess.Account
+	}
+	isAdmin := viewerAcc != nil && viewerAcc.Admin
+	showPosts := CanView(acc.ID, FieldPosts, viewerID)
+
+	if app.WantsJSON(r) {
+		RespondProfileJSON(w, acc, viewerID)
+		return
+	}
+
 	// Get all posts by this user via callback (wired in main.go)
 	var userPosts string
 	var postCount int
-	if GetUserPosts != nil {
+	if GetUserPosts != nil && showPosts {
 		posts := GetUserPosts(acc.Name)
 
-		// Check if viewer is admin
-		_, viewerAcc := auth.TrySession(r)
-		isAdmin := viewerAcc != nil && viewerAcc.Admin
-
 		// Filter private posts for non-admins
 		var visiblePosts []UserPost
 		for _, post := range posts {
@@ -725,23 +781,28 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if userPosts == "" {
+	if !showPosts {
+		userPosts = "<p class='info'>Posts are hidden.</p>"
+	} else if userPosts == "" {
 		userPosts = "<p class='info'>No blog posts yet.</p>"
 	}
+	if postCount > 0 && CanView(acc.ID, FieldPosts, "") {
+		feed := "/@" + acc.ID + "/feed"
+		userPosts += fmt.Sprintf(`<p class="text-muted text-sm mt-4">Subscribe: <a href="%s" class="text-muted">RSS</a> · <a href="%s?format=atom" class="text-muted">Atom</a> · <a href="%s?format=json" class="text-muted">JSON</a></p>`, feed, feed, feed)
+	}
 
 	// Get user profile
 	profile := GetProfile(acc.ID)
 
 	// Check if viewing own profile
-	sess, _ := auth.TrySession(r)
 	isOwnProfile := sess != nil && sess.Account == username
 
 	// Build status section
 	statusSection := ""
-	if profile.Status != "" {
+	if profile.Status != "" && CanView(acc.ID, FieldStatus, viewerID) {
 		statusSection = fmt.Sprintf(`<p class="info italic mt-3">"%s"</p>`, htmlpkg.EscapeString(profile.Status))
 	}
-	if len(profile.History) > 0 {
+	if len(profile.History) > 0 && CanView(acc.ID, FieldHistory, viewerID) {
 		statusSection += `<details style="margin-top:8px;"><summary style="font-size:13px;color:#999;cursor:pointer;">Status history</summary><div style="margin-top:6px;">`
 		for _, h := range profile.History {
 			statusSection += fmt.Sprintf(`<p style="font-size:13px;color:#888;margin:4px 0;font-style:italic;">"%s" <span style="color:#bbb;">— %s</span></p>`,
@@ -757,18 +818,36 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 <form method="POST" class="mt-4">
 <input type="text" name="status" placeholder="Set your status..." value="%s" maxlength="%d" class="form-input w-full">
 <button type="submit" class="mt-2">Update Status</button>
-</form>`, htmlpkg.EscapeString(profile.Status), MaxStatusLength)
+</form>
+<p class="text-sm mt-2"><a href="/user/privacy">Privacy settings →</a></p>`, htmlpkg.EscapeString(profile.Status), MaxStatusLength)
 	}
 
-	// Build message link (only show if not own profile)
+	// Build follow button and message links (only show if not own profile).
+	// Chat is offered when the owner's privacy settings allow it.
 	messageLink := ""
 	if !isOwnProfile {
-		messageLink = fmt.Sprintf(`<p class="mt-4"><a href="/mail?compose=true&to=%s">Send a message</a></p>`, acc.ID)
+		if viewerID != "" {
+			action, label := "follow", "Follow"
+			if IsFollowing(viewerID, acc.ID) {
+				action, label = "unfollow", "Unfollow"
+			}
+			messageLink = fmt.Sprintf(`<form method="POST" action="/user/follow" class="mt-4 d-inline"><input type="hidden" name="id" value="%s"><input type="hidden" name="action" value="%s"><button type="submit">%s</button></form>`, acc.ID, action, label)
+			if FollowEachOther(viewerID, acc.ID) {
+				messageLink += ` <span class="text-sm text-muted">You follow each other</span>`
+			} else if IsFollowing(acc.ID, viewerID) {
+				messageLink += ` <span class="text-sm text-muted">Follows you</span>`
+			}
+		}
+		messageLink += `<p class="mt-4">`
+		if viewerID != "" && CanView(acc.ID, FieldMessages, viewerID) {
+			messageLink += fmt.Sprintf(`<a href="/chat/dm?with=%s">Chat</a> · `, acc.ID)
+		}
+		messageLink += fmt.Sprintf(`<a href="/mail?compose=true&to=%s">Send a message</a></p>`, acc.ID)
 	}
 
 	// Apps section
 	appsSection := ""
-	if GetUserApps != nil {
+	if GetUserApps != nil && CanView(acc.ID, FieldApps, viewerID) {
 		userApps := GetUserApps(acc.ID)
 		if len(userApps) > 0 {
 			var appsSB strings.Builder
@@ -817,6 +896,62 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
+// ProfileJSON is the public JSON representation of a profile. Elements
+// the viewer isn't allowed to see are omitted.
+type ProfileJSON struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Joined   time.Time       `json:"joined"`
+	Verified bool            `json:"verified"`
+	Status   string          `json:"status,omitempty"`
+	History  []StatusHistory `json:"history,omitempty"`
+	Posts    []UserPost      `json:"posts,omitempty"`
+	Apps     []UserApp       `json:"apps,omitempty"`
+	Online   *bool           `json:"online,omitempty"`
+}
+
+// BuildProfileJSON assembles the JSON view of acc as seen by viewerID,
+// applying the owner's privacy settings to every element.
+func BuildProfileJSON(acc *auth.Account, viewerID string) ProfileJSON {
+	out := ProfileJSON{
+		ID:       acc.ID,
+		Name:     acc.Name,
+		Joined:   acc.Created,
+		Verified: acc.Admin || acc.Approved || acc.EmailVerified,
+	}
+	profile := GetProfile(acc.ID)
+	if CanView(acc.ID, FieldStatus, viewerID) {
+		out.Status = profile.Status
+	}
+	if CanView(acc.ID, FieldHistory, viewerID) {
+		out.History = profile.History
+	}
+	if GetUserPosts != nil && CanView(acc.ID, FieldPosts, viewerID) {
+		isAdmin := false
+		if viewer, err := auth.GetAccount(viewerID); err == nil {
+			isAdmin = viewer.Admin
+		}
+		for _, p := range GetUserPosts(acc.Name) {
+			if !p.Private || isAdmin {
+				out.Posts = append(out.Posts, p)
+			}
+		}
+	}
+	if GetUserApps != nil && CanView(acc.ID, FieldApps, viewerID) {
+		out.Apps = GetUserApps(acc.ID)
+	}
+	if CanView(acc.ID, FieldPresence, viewerID) {
+		online := auth.IsOnline(acc.ID)
+		out.Online = &online
+	}
+	return out
+}
+
+// RespondProfileJSON writes the privacy-filtered profile as JSON.
+func RespondProfileJSON(w http.ResponseWriter, acc *auth.Account, viewerID string) {
+	app.RespondJSON(w, BuildProfileJSON(acc, viewerID))
+}
+
 // avatarColors are the palette used for status card avatars.
 var avatarColors = []string{
 	"#56a8a1", // teal
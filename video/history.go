@@ -0,0 +1,467 @@
+package video
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Watch is how far a member got through a video.
+type Watch struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
+	Local     bool      `json:"local,omitempty"` // a member upload rather than YouTube
+	Position  float64   `json:"position"`        // seconds in
+	Duration  float64   `json:"duration,omitempty"`
+	WatchedAt time.Time `json:"watched_at"`
+}
+
+// Finished reports whether the video was watched to (nearly) the end.
+func (w *Watch) Finished() bool {
+	return w.Duration > 0 && (w.Position >= w.Duration-15 || w.Position >= w.Duration*0.95)
+}
+
+// URL links to the video, picking up where it was left.
+func (w *Watch) URL() string {
+	t := ""
+	if !w.Finished() && w.Position >= minResume {
+		t = "&t=" + strconv.Itoa(int(w.Position))
+	}
+	if w.Local {
+		if t != "" {
+			t = "?" + t[1:]
+		}
+		return "/video/v/" + w.ID + t
+	}
+	return "/video?id=" + w.ID + t
+}
+
+// watchHistory is one member's history. While Paused nothing new is
+// recorded and nothing is resumed.
+type watchHistory struct {
+	Paused  bool     `json:"paused,omitempty"`
+	Watched []*Watch `json:"watched"` // most recent first
+}
+
+const (
+	// maxHistory is how many videos a member's history keeps.
+	maxHistory = 200
+	// minResume is how far in a video must be to be worth resuming.
+	minResume = 10
+)
+
+var (
+	historyMu sync.RWMutex
+	histories = map[string]*watchHistory{} // account → history
+)
+
+// youtubeID matches a YouTube video ID.
+var youtubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// loadHistory restores everyone's watch history.
+func loadHistory() {
+	historyMu.Lock()
+	data.LoadJSON("video_history.json", &histories)
+	if histories == nil {
+		histories = map[string]*watchHistory{}
+	}
+	historyMu.Unlock()
+	data.RegisterExporter(historyExporter{})
+}
+
+// saveHistory persists histories. Caller must hold historyMu.
+func saveHistory() {
+	data.SaveJSON("video_history.json", histories)
+}
+
+// RecordWatch notes that user is position seconds into video id, which
+// lasts duration seconds (0 if unknown). It does nothing while the user
+// has paused their history.
+func RecordWatch(user, id string, position, duration float64) error {
+	w := &Watch{ID: id, WatchedAt: time.Now()}
+	if u := GetUpload(id); u != nil && u.Status == StatusReady {
+		w.Title, w.Thumbnail, w.Local = u.Title, u.ThumbnailURL(), true
+		if duration <= 0 {
+			duration = u.Duration
+		}
+	} else if youtubeID.MatchString(id) {
+		w.Title = id
+		if e := data.GetByID("video_" + id); e != nil {
+			w.Title = e.Title
+			w.Thumbnail, _ = e.Metadata["thumbnail"].(string)
+		}
+	} else {
+		return ErrVideoNotFound
+	}
+	if math.IsNaN(position) || math.IsInf(position, 0) || position < 0 {
+		position = 0
+	}
+	if math.IsNaN(duration) || math.IsInf(duration, 0) || duration < 0 {
+		duration = 0
+	}
+	if duration > 0 && position > duration {
+		position = duration
+	}
+	w.Position, w.Duration = position, duration
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	h := histories[user]
+	if h == nil {
+		h = &watchHistory{}
+		histories[user] = h
+	}
+	if h.Paused {
+		return nil
+	}
+	list := []*Watch{w}
+	for _, old := range h.Watched {
+		if old.ID != id {
+			list = append(list, old)
+		}
+	}
+	if len(list) > maxHistory {
+		list = list[:maxHistory]
+	}
+	h.Watched = list
+	saveHistory()
+	return nil
+}
+
+// History returns what user has watched, most recent first. Member
+// uploads deleted since are left out.
+func History(user string) []*Watch {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+	h := histories[user]
+	if h == nil {
+		return nil
+	}
+	var list []*Watch
+	for _, w := range h.Watched {
+		if w.Local && GetUpload(w.ID) == nil {
+			continue
+		}
+		c := *w
+		list = append(list, &c)
+	}
+	return list
+}
+
+// ContinueWatching returns up to n videos user is part way through.
+func ContinueWatching(user string, n int) []*Watch {
+	if HistoryPaused(user) {
+		return nil
+	}
+	var list []*Watch
+	for _, w := range History(user) {
+		if len(list) == n {
+			break
+		}
+		if w.Position >= minResume && !w.Finished() {
+			list = append(list, w)
+		}
+	}
+	return list
+}
+
+// ResumePosition returns where user left video id, or 0 to start from
+// the beginning.
+func ResumePosition(user, id string) float64 {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+	h := histories[user]
+	if h == nil || h.Paused {
+		return 0
+	}
+	for _, w := range h.Watched {
+		if w.ID == id {
+			if w.Position < minResume || w.Finished() {
+				return 0
+			}
+			return w.Position
+		}
+	}
+	return 0
+}
+
+// HistoryPaused reports whether user has turned their history off.
+func HistoryPaused(user string) bool {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+	h := histories[user]
+	return h != nil && h.Paused
+}
+
+// SetHistoryPaused turns user's history off, or back on.
+func SetHistoryPaused(user string, paused bool) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	h := histories[user]
+	if h == nil {
+		h = &watchHistory{}
+		histories[user] = h
+	}
+	h.Paused = paused
+	saveHistory()
+}
+
+// RemoveWatch removes one video from user's history.
+func RemoveWatch(user, id string) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	h := histories[user]
+	if h == nil {
+		return
+	}
+	for i, w := range h.Watched {
+		if w.ID == id {
+			h.Watched = append(h.Watched[:i], h.Watched[i+1:]...)
+			saveHistory()
+			return
+		}
+	}
+}
+
+// ClearHistory removes everything user has watched. Whether history is
+// paused is kept.
+func ClearHistory(user string) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if h := histories[user]; h != nil {
+		h.Watched = nil
+		saveHistory()
+	}
+}
+
+// DeleteHistory removes a member's history (account deletion).
+func DeleteHistory(user string) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if _, ok := histories[user]; ok {
+		delete(histories, user)
+		saveHistory()
+	}
+}
+
+// RenameHistory moves a member's history to their new ID (username
+// change).
+func RenameHistory(oldID, newID string) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if h, ok := histories[oldID]; ok {
+		histories[newID] = h
+		delete(histories, oldID)
+		saveHistory()
+	}
+}
+
+// historyExporter exports a member's watch history.
+type historyExporter struct{}
+
+func (historyExporter) Name() string        { return "video" }
+func (historyExporter) Description() string { return "Your video watch history" }
+func (historyExporter) Formats() []string   { return []string{"json"} }
+
+func (historyExporter) Export(w io.Writer, userID, format string) error {
+	list := History(userID)
+	if list == nil {
+		list = []*Watch{}
+	}
+	return json.NewEncoder(w).Encode(list)
+}
+
+// watchBeacon returns the script that reports, every so often and when
+// the page is left, how far the viewer is through video id. pos and dur
+// are JavaScript expressions for the position and length in seconds.
+func watchBeacon(r *http.Request, id, pos, dur string) string {
+	sess, _ := auth.TrySession(r)
+	if sess == nil || HistoryPaused(sess.Account) {
+		return ""
+	}
+	return app.Script(r, fmt.Sprintf(`
+(function(){
+  var last=-1;
+  function send(){
+    var p=%s,d=%s;
+    if(!(p>=0)||Math.abs(p-last)<2)return;
+    last=p;
+    var f=new FormData();
+    f.append('id',%s);f.append('t',Math.floor(p));f.append('d',Math.floor(d||0));
+    var c=(document.cookie.match(/(?:^|; )csrf_token=([^;]+)/)||[])[1];
+    if(c)f.append('_csrf',decodeURIComponent(c));
+    navigator.sendBeacon('/video/history/beacon',f);
+  }
+  setInterval(send,15000);
+  document.addEventListener('visibilitychange',function(){if(document.visibilityState==='hidden')send();});
+  window.addEventListener('pagehide',send);
+})();
+`, pos, dur, app.JSString(id)))
+}
+
+// startAt returns where a watch page should start: ?t= if given,
+// otherwise where the viewer left off.
+func startAt(r *http.Request, id string) int {
+	if t, err := strconv.Atoi(r.URL.Query().Get("t")); err == nil && t > 0 {
+		return t
+	}
+	if sess, _ := auth.TrySession(r); sess != nil {
+		return int(ResumePosition(sess.Account, id))
+	}
+	return 0
+}
+
+// BeaconHandler records the player's position: POST id, t (seconds in)
+// and d (the video's length) at /video/history/beacon.
+func BeaconHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	t, _ := strconv.ParseFloat(r.FormValue("t"), 64)
+	d, _ := strconv.ParseFloat(r.FormValue("d"), 64)
+	if err := RecordWatch(acc.ID, r.FormValue("id"), t, d); err != nil {
+		app.NotFound(w, r, "Video not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HistoryHandler serves /video/history: GET lists what the member has
+// watched; POST with action=remove&id=, action=clear, action=pause or
+// action=resume manages it.
+func HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || r.Method != http.MethodGet {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		switch r.FormValue("action") {
+		case "remove":
+			RemoveWatch(acc.ID, r.FormValue("id"))
+		case "clear":
+			ClearHistory(acc.ID)
+		case "pause":
+			SetHistoryPaused(acc.ID, true)
+		case "resume":
+			SetHistoryPaused(acc.ID, false)
+		default:
+			app.BadRequest(w, r, "Unknown action")
+			return
+		}
+		if !app.WantsJSON(r) {
+			http.Redirect(w, r, "/video/history", http.StatusSeeOther)
+			return
+		}
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	list := History(acc.ID)
+	paused := HistoryPaused(acc.ID)
+	if app.WantsJSON(r) {
+		if list == nil {
+			list = []*Watch{}
+		}
+		app.RespondJSON(w, map[string]any{"paused": paused, "history": list})
+		return
+	}
+
+	var sb strings.Builder
+	pause := `<button type="submit" name="action" value="pause" class="btn-link">Pause history</button>`
+	if paused {
+		sb.WriteString(`<p class="text-muted">Your history is paused: videos you watch aren't recorded and won't resume where you left off.</p>`)
+		pause = `<button type="submit" name="action" value="resume" class="btn-link">Turn history back on</button>`
+	}
+	clear := ""
+	if len(list) > 0 {
+		clear = ` · <form method="POST" action="/video/history" data-confirm="Clear your watch history?"><button type="submit" name="action" value="clear" class="btn-link">Clear history</button></form>`
+	}
+	sb.WriteString(fmt.Sprintf(`<div class="video-history-actions"><form method="POST" action="/video/history">%s</form>%s</div>`, pause, clear))
+
+	if len(list) == 0 {
+		sb.WriteString(`<p class="text-muted">Nothing watched yet.</p>`)
+	}
+	for _, v := range list {
+		sb.WriteString(renderWatch(v, true))
+	}
+	sb.WriteString(`<p><a href="/video">&larr; Video</a></p>`)
+
+	app.Respond(w, r, app.Response{
+		Title:       "Watch history",
+		Description: "Videos you've watched",
+		HTML:        sb.String(),
+	})
+}
+
+// renderWatch renders a video from the history, with how far through it
+// is and, on the history page, a button to forget it.
+func renderWatch(v *Watch, remove bool) string {
+	img := `<div class="video-no-thumb">&#9654;</div>`
+	if v.Thumbnail != "" {
+		img = fmt.Sprintf(`<img src="%s" alt="">`, html.EscapeString(v.Thumbnail))
+	}
+	progress := ""
+	if v.Duration > 0 {
+		pct := int(v.Position / v.Duration * 100)
+		if v.Finished() {
+			pct = 100
+		}
+		progress = fmt.Sprintf(`<div class="video-progress"><span style="width:%d%%"></span></div>`, pct)
+	}
+	info := fmt.Sprintf(`<span data-timestamp="%d">%s</span>`, v.WatchedAt.Unix(), app.TimeAgo(v.WatchedAt))
+	switch {
+	case v.Finished():
+		info += " · Watched"
+	case v.Position >= minResume:
+		info += " · Resume at " + formatDuration(v.Position)
+	}
+	if remove {
+		info += fmt.Sprintf(` · <form method="POST" action="/video/history" style="display:inline"><input type="hidden" name="action" value="remove"><input type="hidden" name="id" value="%s"><button type="submit" class="btn-link">Remove</button></form>`,
+			html.EscapeString(v.ID))
+	}
+	return fmt.Sprintf(`<div class="thumbnail"><a href="%s">%s%s<h3>%s</h3></a><div class="info">%s</div></div>`,
+		html.EscapeString(v.URL()), img, progress, html.EscapeString(v.Title), info)
+}
+
+// ContinueWatchingHTML renders the videos user is part way through, to
+// lead the home card. It's empty if there are none.
+func ContinueWatchingHTML(user string) string {
+	list := ContinueWatching(user, 3)
+	if len(list) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<div class="video-continue"><h5>Continue watching</h5>`)
+	for _, v := range list {
+		sb.WriteString(renderWatch(v, false))
+	}
+	sb.WriteString(`<a href="/video/history" class="text-sm">History</a></div>`)
+	return sb.String()
+}
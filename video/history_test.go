@@ -0,0 +1,106 @@
+package video
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestWatchHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	historyMu.Lock()
+	orig := histories
+	histories = map[string]*watchHistory{}
+	historyMu.Unlock()
+	uploadsMu.Lock()
+	origUploads := uploads
+	uploads = map[string]*Upload{
+		"local1": {ID: "local1", Owner: "someone", Title: "Allotment tour", Status: StatusReady, Duration: 600},
+	}
+	uploadsMu.Unlock()
+	defer func() {
+		historyMu.Lock()
+		histories = orig
+		historyMu.Unlock()
+		uploadsMu.Lock()
+		uploads = origUploads
+		uploadsMu.Unlock()
+	}()
+
+	if err := auth.Create(&auth.Account{ID: "history_user", Name: "Viewer", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("history_user")
+	sess, err := auth.Login("history_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(target, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		w := httptest.NewRecorder()
+		if strings.HasPrefix(target, "/video/history/beacon") {
+			BeaconHandler(w, r)
+		} else {
+			HistoryHandler(w, r)
+		}
+		return w
+	}
+
+	if w := post("/video/history/beacon", "id=local1&t=125.4&d=0"); w.Code != http.StatusNoContent {
+		t.Fatalf("beacon: %d", w.Code)
+	}
+	post("/video/history/beacon", "id=dQw4w9WgXcQ&t=200&d=212")
+	if w := post("/video/history/beacon", "id=../etc&t=5"); w.Code != http.StatusNotFound {
+		t.Errorf("bogus id: %d", w.Code)
+	}
+
+	list := History("history_user")
+	if len(list) != 2 || list[0].ID != "dQw4w9WgXcQ" || !list[1].Local || list[1].Duration != 600 {
+		t.Fatalf("history = %+v", list)
+	}
+	// The YouTube video was watched to the end, so only the upload resumes.
+	if got := ResumePosition("history_user", "local1"); got != 125.4 {
+		t.Errorf("resume = %v", got)
+	}
+	if got := ResumePosition("history_user", "dQw4w9WgXcQ"); got != 0 {
+		t.Errorf("finished video resumes at %v", got)
+	}
+	if c := ContinueWatching("history_user", 3); len(c) != 1 || c[0].URL() != "/video/v/local1?t=125" {
+		t.Errorf("continue = %+v", c)
+	}
+	if html := ContinueWatchingHTML("history_user"); !strings.Contains(html, "Allotment tour") || !strings.Contains(html, "Resume at 2:05") {
+		t.Errorf("card = %s", html)
+	}
+
+	// Paused, nothing's recorded, resumed or shown.
+	post("/video/history", "action=pause")
+	post("/video/history/beacon", "id=local1&t=300")
+	if ResumePosition("history_user", "local1") != 0 || ContinueWatchingHTML("history_user") != "" {
+		t.Error("paused history still resumes")
+	}
+	r := httptest.NewRequest("GET", "/video/v/local1", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	if watchBeacon(r, "local1", "0", "0") != "" {
+		t.Error("paused history still reports")
+	}
+	post("/video/history", "action=resume")
+	if got := ResumePosition("history_user", "local1"); got != 125.4 {
+		t.Errorf("resume after pause = %v", got)
+	}
+
+	post("/video/history", "action=remove&id=local1")
+	if list := History("history_user"); len(list) != 1 {
+		t.Errorf("after remove = %+v", list)
+	}
+	post("/video/history", "action=clear")
+	if list := History("history_user"); len(list) != 0 {
+		t.Errorf("after clear = %+v", list)
+	}
+}
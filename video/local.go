@@ -0,0 +1,500 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"mu/internal/app"
+	"mu/internal/data"
+	"mu/internal/settings"
+)
+
+// Member videos. Members upload their own videos rather than linking to
+// YouTube: the file comes up in chunks, so a large one survives a dropped
+// connection, and is kept on disk under video/. A background job makes
+// each one's thumbnail with ffmpeg, then /video/v/<id> plays it.
+
+func init() {
+	settings.Register("Video uploads",
+		settings.Var{Key: "FFMPEG_PATH", Default: "ffmpeg", Doc: "ffmpeg binary used to make thumbnails of uploaded videos"},
+	)
+}
+
+// Upload is a member's video. Its content lives in video/<id> and its
+// thumbnail in video/<id>.jpg; the records are kept in video_uploads.json.
+type Upload struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size"`     // as declared when the upload started
+	Received    int64     `json:"received"` // bytes stored so far
+	Duration    float64   `json:"duration,omitempty"`
+	Status      string    `json:"status"`
+	Thumbnail   bool      `json:"thumbnail"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Upload statuses.
+const (
+	StatusUploading  = "uploading"  // chunks still to come
+	StatusProcessing = "processing" // waiting for its thumbnail
+	StatusReady      = "ready"
+)
+
+// URL is the video's page.
+func (u *Upload) URL() string { return "/video/v/" + u.ID }
+
+// FileURL is where the video itself is served.
+func (u *Upload) FileURL() string { return "/video/file/" + u.ID }
+
+// ThumbnailURL is where the thumbnail is served, or "" if there's none.
+func (u *Upload) ThumbnailURL() string {
+	if !u.Thumbnail {
+		return ""
+	}
+	return "/video/thumb/" + u.ID
+}
+
+// Upload limits.
+const (
+	MaxVideoSize    = 500 << 20 // per video
+	MaxVideoStorage = 2 << 30   // per account
+	MaxVideosPerDay = 10        // per account
+	ChunkSize       = 8 << 20   // the most one request may carry
+
+	maxTitleLength       = 200
+	maxDescriptionLength = 5000
+
+	// uploadExpiry is how long an upload may sit unfinished before it's
+	// removed.
+	uploadExpiry = 24 * time.Hour
+)
+
+var (
+	ErrVideoTooLarge   = fmt.Errorf("videos are limited to %dMB", MaxVideoSize>>20)
+	ErrVideoType       = errors.New("only MP4, WebM, Ogg and QuickTime videos can be uploaded")
+	ErrVideoDailyLimit = fmt.Errorf("you can upload %d videos a day", MaxVideosPerDay)
+	ErrVideoQuota      = fmt.Errorf("you've used your %dGB of video storage", MaxVideoStorage>>30)
+	ErrVideoNotFound   = errors.New("video not found")
+	ErrVideoOffset     = errors.New("chunk doesn't start where the upload left off")
+	ErrVideoComplete   = errors.New("upload is already complete")
+)
+
+var (
+	uploadsMu sync.RWMutex
+	uploads   = map[string]*Upload{} // id → upload
+)
+
+// loadUploads restores the upload records and starts the job that
+// processes them.
+func loadUploads() {
+	data.RegisterTable("video_uploads.json")
+	uploadsMu.Lock()
+	data.LoadJSON("video_uploads.json", &uploads)
+	if uploads == nil {
+		uploads = map[string]*Upload{}
+	}
+	uploadsMu.Unlock()
+
+	// Runs hourly to tidy up, and straight away when an upload completes.
+	app.Schedule(app.Job{Name: "video.process", Every: time.Hour, Delay: time.Minute, Run: processUploads})
+}
+
+// saveUploads persists the records. Caller must hold uploadsMu.
+func saveUploads() {
+	data.SaveJSON("video_uploads.json", uploads)
+}
+
+// contentKey and thumbKey are the data store keys of an upload's files.
+func contentKey(id string) string { return filepath.Join("video", id) }
+func thumbKey(id string) string   { return filepath.Join("video", id+".jpg") }
+
+// removeFiles deletes an upload's files.
+func removeFiles(id string) {
+	for _, key := range []string{contentKey(id), thumbKey(id)} {
+		if path, err := data.FilePath(key); err == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				app.Log("video", "Failed to delete %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// cleanText trims s, drops control characters other than newlines and
+// cuts it to max bytes.
+func cleanText(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r != '\n' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+	if len(s) > max {
+		s = strings.ToValidUTF8(s[:max], "")
+	}
+	return s
+}
+
+// StartUpload creates an upload of size bytes for owner to send in
+// chunks.
+func StartUpload(owner, title, description, filename string, size int64) (*Upload, error) {
+	if size <= 0 {
+		return nil, errors.New("size is required")
+	}
+	if size > MaxVideoSize {
+		return nil, ErrVideoTooLarge
+	}
+	filename = cleanText(filepath.Base(strings.ReplaceAll(filename, `\`, "/")), 100)
+	title = cleanText(title, maxTitleLength)
+	if title == "" {
+		title = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+	if title == "" {
+		return nil, errors.New("title is required")
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	u := &Upload{
+		ID:          hex.EncodeToString(b),
+		Owner:       owner,
+		Title:       title,
+		Description: cleanText(description, maxDescriptionLength),
+		Filename:    filename,
+		Size:        size,
+		Status:      StatusUploading,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	if err := checkVideoQuotaLocked(owner, size); err != nil {
+		return nil, err
+	}
+	uploads[u.ID] = u
+	saveUploads()
+	return u, nil
+}
+
+// checkVideoQuotaLocked reports whether owner may start an upload of
+// size bytes. Unfinished uploads count at their full size. Caller must
+// hold uploadsMu.
+func checkVideoQuotaLocked(owner string, size int64) error {
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	today, total := 0, size
+	for _, u := range uploads {
+		if u.Owner != owner {
+			continue
+		}
+		total += u.Size
+		if u.CreatedAt.After(dayAgo) {
+			today++
+		}
+	}
+	if today >= MaxVideosPerDay {
+		return ErrVideoDailyLimit
+	}
+	if total > MaxVideoStorage {
+		return ErrVideoQuota
+	}
+	return nil
+}
+
+// sniffVideo returns the type of video b starts with, or "" if it isn't
+// one that's accepted.
+func sniffVideo(b []byte) string {
+	switch {
+	case len(b) >= 12 && string(b[4:8]) == "ftyp":
+		if string(b[8:12]) == "qt  " {
+			return "video/quicktime"
+		}
+		return "video/mp4"
+	case bytes.HasPrefix(b, []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		return "video/webm"
+	case bytes.HasPrefix(b, []byte("OggS")):
+		return "video/ogg"
+	}
+	return ""
+}
+
+// WriteChunk stores the chunk of owner's upload that starts at offset.
+// Chunks must come in order; a chunk that was cut off part way is sent
+// again from where the upload says it's up to. When the last one lands
+// the upload is queued for processing.
+func WriteChunk(id, owner string, offset int64, r io.Reader) (*Upload, error) {
+	// Read before locking, so a slow connection holds up no one else.
+	chunk, err := io.ReadAll(io.LimitReader(r, ChunkSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	u := uploads[id]
+	if u == nil || u.Owner != owner {
+		return nil, ErrVideoNotFound
+	}
+	if u.Status != StatusUploading {
+		return u, ErrVideoComplete
+	}
+	if offset != u.Received {
+		return u, ErrVideoOffset
+	}
+	if len(chunk) > ChunkSize || offset+int64(len(chunk)) > u.Size {
+		return u, ErrVideoTooLarge
+	}
+	if offset == 0 {
+		if u.ContentType = sniffVideo(chunk); u.ContentType == "" {
+			delete(uploads, id)
+			saveUploads()
+			return nil, ErrVideoType
+		}
+	}
+
+	path, err := data.FilePath(contentKey(id))
+	if err != nil {
+		return u, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return u, err
+	}
+	// Anything past the offset is from a chunk that was cut off.
+	if err := f.Truncate(offset); err == nil {
+		_, err = f.WriteAt(chunk, offset)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return u, err
+	}
+
+	u.Received += int64(len(chunk))
+	u.UpdatedAt = time.Now()
+	if u.Received == u.Size {
+		u.Status = StatusProcessing
+		app.Log("video", "%s uploaded %s (%d bytes)", owner, u.ID, u.Size)
+	}
+	saveUploads()
+	if u.Status == StatusProcessing {
+		app.RunJob("video.process")
+	}
+	return u, nil
+}
+
+// thumbnailer makes a JPEG of a frame of the video at src in dst and
+// returns the video's length in seconds. Tests replace it.
+var thumbnailer = ffmpegThumbnail
+
+// ffmpegDuration finds the length ffmpeg reports for its input.
+var ffmpegDuration = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+// ffmpegThumbnail takes the frame a second in, or the first frame of a
+// shorter video, scaled to at most 640 wide.
+func ffmpegThumbnail(src, dst string) (float64, error) {
+	bin := settings.Get("FFMPEG_PATH")
+	if bin == "" {
+		bin = "ffmpeg"
+	}
+	var out []byte
+	var err error
+	for _, seek := range []string{"1", "0"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		cmd := exec.CommandContext(ctx, bin, "-hide_banner", "-nostdin", "-y",
+			"-ss", seek, "-i", src, "-frames:v", "1", "-vf", "scale='min(640,iw)':-2", "-q:v", "4", dst)
+		out, err = cmd.CombinedOutput()
+		cancel()
+		if fi, serr := os.Stat(dst); err == nil && serr == nil && fi.Size() > 0 {
+			break
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return 0, err
+		}
+	}
+	var duration float64
+	if m := ffmpegDuration.FindSubmatch(out); m != nil {
+		h, _ := strconv.Atoi(string(m[1]))
+		min, _ := strconv.Atoi(string(m[2]))
+		sec, _ := strconv.ParseFloat(string(m[3]), 64)
+		duration = float64(h*3600+min*60) + sec
+	}
+	if err != nil {
+		return duration, fmt.Errorf("ffmpeg: %v: %s", err, lastLine(out))
+	}
+	return duration, nil
+}
+
+// lastLine returns the last line of command output, where the error is.
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return lines[len(lines)-1]
+}
+
+// processUploads makes thumbnails for completed uploads and removes those
+// left unfinished. A video whose thumbnail can't be made is published
+// without one; browsers may still play what ffmpeg can't read.
+func processUploads() error {
+	uploadsMu.RLock()
+	var pending []string
+	var stale []string
+	for id, u := range uploads {
+		switch {
+		case u.Status == StatusProcessing:
+			pending = append(pending, id)
+		case u.Status == StatusUploading && time.Since(u.UpdatedAt) > uploadExpiry:
+			stale = append(stale, id)
+		}
+	}
+	uploadsMu.RUnlock()
+
+	var failed int
+	for _, id := range pending {
+		src, err := data.FilePath(contentKey(id))
+		if err != nil {
+			return err
+		}
+		dst, err := data.FilePath(thumbKey(id))
+		if err != nil {
+			return err
+		}
+		duration, err := thumbnailer(src, dst)
+		if err != nil {
+			failed++
+			app.Log("video", "Thumbnail for %s: %v", id, err)
+		}
+		_, statErr := os.Stat(dst)
+
+		uploadsMu.Lock()
+		if u := uploads[id]; u != nil {
+			u.Status = StatusReady
+			u.Duration = duration
+			u.Thumbnail = err == nil && statErr == nil
+			u.UpdatedAt = time.Now()
+			saveUploads()
+			indexUpload(u)
+		} else {
+			os.Remove(dst) // deleted while its thumbnail was made
+		}
+		uploadsMu.Unlock()
+	}
+
+	if len(stale) > 0 {
+		uploadsMu.Lock()
+		for _, id := range stale {
+			removeFiles(id)
+			delete(uploads, id)
+		}
+		saveUploads()
+		uploadsMu.Unlock()
+		app.Log("video", "Removed %d unfinished uploads", len(stale))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d thumbnails failed", failed, len(pending))
+	}
+	return nil
+}
+
+// indexUpload makes a ready video searchable alongside the YouTube ones.
+func indexUpload(u *Upload) {
+	data.Index("video_"+u.ID, "video", u.Title, u.Description, map[string]interface{}{
+		"url":       u.URL(),
+		"channel":   u.Owner,
+		"published": u.CreatedAt,
+		"thumbnail": u.ThumbnailURL(),
+	})
+}
+
+// GetUpload returns an upload, or nil.
+func GetUpload(id string) *Upload {
+	uploadsMu.RLock()
+	defer uploadsMu.RUnlock()
+	return uploads[id]
+}
+
+// Uploads returns the videos ready to watch, newest first, only owner's
+// if owner is set.
+func Uploads(owner string) []*Upload {
+	uploadsMu.RLock()
+	defer uploadsMu.RUnlock()
+	var list []*Upload
+	for _, u := range uploads {
+		if u.Status == StatusReady && (owner == "" || u.Owner == owner) {
+			list = append(list, u)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// DeleteUpload removes an upload and its files.
+func DeleteUpload(id string) error {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	if _, ok := uploads[id]; !ok {
+		return ErrVideoNotFound
+	}
+	removeFiles(id)
+	delete(uploads, id)
+	saveUploads()
+	data.Unindex("video_" + id)
+	return nil
+}
+
+// DeleteByOwner removes every video an account uploaded (account
+// deletion).
+func DeleteByOwner(owner string) {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	removed := 0
+	for id, u := range uploads {
+		if u.Owner == owner {
+			removeFiles(id)
+			delete(uploads, id)
+			data.Unindex("video_" + id)
+			removed++
+		}
+	}
+	if removed > 0 {
+		saveUploads()
+	}
+}
+
+// RenameOwner moves an account's videos to its new ID (username change).
+func RenameOwner(oldID, newID string) {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	changed := false
+	for _, u := range uploads {
+		if u.Owner == oldID {
+			u.Owner = newID
+			changed = true
+		}
+	}
+	if changed {
+		saveUploads()
+	}
+}
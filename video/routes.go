@@ -0,0 +1,14 @@
+package video
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/video", Handler)
+	r.HandleFunc("/video/", LocalHandler) // member uploads; auth checked in the handler
+	r.HandleFunc("/video/history", HistoryHandler)
+	r.HandleFunc("/video/history/beacon", BeaconHandler)
+}
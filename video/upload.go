@@ -0,0 +1,432 @@
+package video
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// LocalHandler serves member videos under /video/:
+//
+//	GET  /video/upload          the upload page
+//	POST /video/upload          start an upload: title, description, filename, size
+//	PUT  /video/upload/<id>     a chunk, at ?offset=
+//	GET  /video/upload/<id>     how far an upload has got, to resume it
+//	GET  /video/uploads         member videos, ?owner= for one member's
+//	GET  /video/v/<id>          a video's page
+//	GET  /video/file/<id>       the video itself, with range requests
+//	GET  /video/thumb/<id>      its thumbnail
+//	POST /video/v/<id>          with action=delete, to its owner or an admin
+func LocalHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/video/")
+	kind, id, _ := strings.Cut(path, "/")
+	if strings.Contains(id, "/") {
+		app.NotFound(w, r, "Video not found")
+		return
+	}
+	switch {
+	case kind == "upload" && id == "":
+		handleStartUpload(w, r)
+	case kind == "upload":
+		handleChunk(w, r, id)
+	case kind == "uploads" && id == "":
+		handleUploads(w, r)
+	case kind == "v" && id != "":
+		handleWatch(w, r, id)
+	case kind == "file" && id != "":
+		serveVideoFile(w, r, id, contentKey(id), "")
+	case kind == "thumb" && id != "":
+		serveVideoFile(w, r, id, thumbKey(id), "image/jpeg")
+	default:
+		app.NotFound(w, r, "Video not found")
+	}
+}
+
+// uploadError responds with err at the status it calls for.
+func uploadError(w http.ResponseWriter, r *http.Request, u *Upload, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrVideoNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrVideoOffset), errors.Is(err, ErrVideoComplete):
+		// The client resumes from what it's told was received.
+		if u != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, `{"error":%q,"received":%d}`, err.Error(), u.Received)
+			return
+		}
+		status = http.StatusConflict
+	case errors.Is(err, ErrVideoTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrVideoType):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrVideoDailyLimit), errors.Is(err, ErrVideoQuota):
+		status = http.StatusTooManyRequests
+	}
+	app.Error(w, r, status, err.Error())
+}
+
+// handleStartUpload serves the upload page and starts uploads.
+func handleStartUpload(w http.ResponseWriter, r *http.Request) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || r.Method != http.MethodGet {
+			app.Unauthorized(w, r)
+		} else {
+			app.RedirectToLogin(w, r)
+		}
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		renderUploadPage(w, r)
+		return
+	case http.MethodPost:
+	default:
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	var req struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Filename    string `json:"filename"`
+		Size        int64  `json:"size"`
+	}
+	if app.SendsJSON(r) {
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "Invalid request")
+			return
+		}
+	} else {
+		req.Title = r.FormValue("title")
+		req.Description = r.FormValue("description")
+		req.Filename = r.FormValue("filename")
+		req.Size, _ = strconv.ParseInt(r.FormValue("size"), 10, 64)
+	}
+	u, err := StartUpload(acc.ID, req.Title, req.Description, req.Filename, req.Size)
+	if err != nil {
+		uploadError(w, r, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.RespondJSON(w, map[string]any{
+		"id":         u.ID,
+		"upload_url": "/video/upload/" + u.ID,
+		"chunk_size": ChunkSize,
+		"received":   u.Received,
+		"url":        u.URL(),
+	})
+}
+
+// handleChunk takes a chunk of an upload, or reports its progress.
+func handleChunk(w http.ResponseWriter, r *http.Request, id string) {
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		u := GetUpload(id)
+		if u == nil || (u.Owner != acc.ID && !acc.Admin) {
+			uploadError(w, r, nil, ErrVideoNotFound)
+			return
+		}
+		app.RespondJSON(w, u)
+	case http.MethodPut, http.MethodPost:
+		offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			app.BadRequest(w, r, "offset is required")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, ChunkSize)
+		u, err := WriteChunk(id, acc.ID, offset, r.Body)
+		if err != nil {
+			var tooBig *http.MaxBytesError
+			if errors.As(err, &tooBig) {
+				err = fmt.Errorf("chunks are limited to %dMB", ChunkSize>>20)
+				app.Error(w, r, http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			uploadError(w, r, u, err)
+			return
+		}
+		app.RespondJSON(w, u)
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+// serveVideoFile serves one of a ready video's files. Range requests let
+// players seek without downloading the whole video.
+func serveVideoFile(w http.ResponseWriter, r *http.Request, id, key, contentType string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	u := GetUpload(id)
+	if u == nil || u.Status != StatusReady {
+		app.NotFound(w, r, "Video not found")
+		return
+	}
+	path, err := data.FilePath(key)
+	if err != nil {
+		app.NotFound(w, r, "Video not found")
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		app.NotFound(w, r, "Video not found")
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		app.ServerError(w, r, "Could not read the video")
+		return
+	}
+	if contentType == "" {
+		contentType = u.ContentType
+	}
+	// The content behind an ID never changes.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	http.ServeContent(w, r, "", fi.ModTime(), f)
+}
+
+// formatDuration renders seconds as m:ss or h:mm:ss.
+func formatDuration(secs float64) string {
+	s := int(secs + 0.5)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// renderThumbnail renders a video's card in the listing style of the
+// YouTube results.
+func renderThumbnail(u *Upload) string {
+	img := `<div class="video-no-thumb">&#9654;</div>`
+	if t := u.ThumbnailURL(); t != "" {
+		img = fmt.Sprintf(`<img src="%s" alt="">`, t)
+	}
+	info := fmt.Sprintf(`<a href="/@%s">%s</a> · <span data-timestamp="%d">%s</span>`,
+		html.EscapeString(u.Owner), html.EscapeString(u.Owner), u.CreatedAt.Unix(), app.TimeAgo(u.CreatedAt))
+	if u.Duration > 0 {
+		info += " · " + formatDuration(u.Duration)
+	}
+	return fmt.Sprintf(`<div class="thumbnail"><a href="%s">%s<h3>%s</h3></a><div class="info">%s</div></div>`,
+		u.URL(), img, html.EscapeString(u.Title), info)
+}
+
+// handleUploads lists member videos, newest first.
+func handleUploads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	owner := r.URL.Query().Get("owner")
+	list := Uploads(owner)
+	if app.WantsJSON(r) {
+		if list == nil {
+			list = []*Upload{}
+		}
+		app.RespondJSON(w, map[string]any{"videos": list})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<p><a href="/video">&larr; Video</a> · <a href="/video/upload">Upload a video</a></p>`)
+	if len(list) == 0 {
+		sb.WriteString(`<p class="text-muted">No videos yet.</p>`)
+	}
+	sb.WriteString(`<div id="results">`)
+	for _, u := range list {
+		sb.WriteString(renderThumbnail(u))
+	}
+	sb.WriteString(`</div>`)
+
+	title := "Member videos"
+	if owner != "" {
+		title = "Videos by " + owner
+	}
+	app.Respond(w, r, app.Response{Title: title, Description: "Videos uploaded by members", HTML: sb.String()})
+}
+
+// handleWatch serves a video's page, and its deletion.
+func handleWatch(w http.ResponseWriter, r *http.Request, id string) {
+	u := GetUpload(id)
+	if r.Method == http.MethodDelete || (r.Method == http.MethodPost && r.FormValue("action") == "delete") {
+		_, acc, err := auth.RequireSession(r)
+		if err != nil {
+			app.Unauthorized(w, r)
+			return
+		}
+		if u == nil {
+			app.NotFound(w, r, "Video not found")
+			return
+		}
+		if u.Owner != acc.ID && !acc.Admin {
+			app.Forbidden(w, r, "You can only delete your own videos")
+			return
+		}
+		DeleteUpload(id)
+		if u.Owner != acc.ID {
+			app.Audit(acc.ID, "video delete", id, r.URL.Path)
+		}
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]any{"deleted": id})
+			return
+		}
+		http.Redirect(w, r, "/video/uploads", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodGet {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+
+	_, acc := auth.TrySession(r)
+	mine := acc != nil && u != nil && (u.Owner == acc.ID || acc.Admin)
+	if u == nil || (u.Status != StatusReady && !mine) {
+		app.NotFound(w, r, "Video not found")
+		return
+	}
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]any{
+			"video":     u,
+			"file":      u.FileURL(),
+			"thumbnail": u.ThumbnailURL(),
+		})
+		return
+	}
+
+	var sb strings.Builder
+	if u.Status == StatusReady {
+		poster := ""
+		if t := u.ThumbnailURL(); t != "" {
+			poster = fmt.Sprintf(` poster="%s"`, t)
+		}
+		// A media fragment starts playback where the viewer left off.
+		src := u.FileURL()
+		if t := startAt(r, u.ID); t > 0 {
+			src += fmt.Sprintf("#t=%d", t)
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="video-local"><video id="player" controls preload="metadata" playsinline%s src="%s"></video></div>`,
+			poster, src))
+		sb.WriteString(watchBeacon(r, u.ID,
+			"(document.getElementById('player').currentTime||-1)",
+			"document.getElementById('player').duration"))
+	} else {
+		sb.WriteString(fmt.Sprintf(`<p class="text-muted">This video is still %s. Only you can see this page until it's ready.</p>`, u.Status))
+	}
+	meta := fmt.Sprintf(`<a href="/video/uploads?owner=%s">%s</a> · %s`,
+		html.EscapeString(u.Owner), html.EscapeString(u.Owner), app.TimeAgo(u.CreatedAt))
+	if u.Duration > 0 {
+		meta += " · " + formatDuration(u.Duration)
+	}
+	sb.WriteString(fmt.Sprintf(`<p class="text-muted">%s · %s</p>`, meta, app.BookmarkButton(r, "video", u.ID)))
+	if u.Description != "" {
+		sb.WriteString(fmt.Sprintf(`<p class="video-description">%s</p>`,
+			strings.ReplaceAll(html.EscapeString(u.Description), "\n", "<br>")))
+	}
+	if mine {
+		sb.WriteString(fmt.Sprintf(`<form action="%s" method="POST" data-confirm="Delete this video?">
+  <input type="hidden" name="action" value="delete">
+  <button type="submit" class="btn-link">Delete</button>
+</form>`, u.URL()))
+	}
+	sb.WriteString(`<p><a href="/video/uploads">&larr; Member videos</a></p>`)
+
+	app.Respond(w, r, app.Response{
+		Title:       u.Title,
+		Description: summary(u.Description),
+		HTML:        sb.String(),
+	})
+}
+
+// summary is the start of a description, for the page's meta tags.
+func summary(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 160 {
+		s = strings.ToValidUTF8(s[:157], "") + "..."
+	}
+	return s
+}
+
+// renderUploadPage renders the upload form.
+func renderUploadPage(w http.ResponseWriter, r *http.Request) {
+	body := fmt.Sprintf(`<p><a href="/video/uploads">&larr; Member videos</a></p>
+<form id="video-upload-form" class="video-upload-form">
+  <input type="file" id="video-file" accept="video/mp4,video/webm,video/ogg,video/quicktime" required>
+  <input type="text" id="video-title" placeholder="Title" maxlength="%d">
+  <textarea id="video-description" rows="4" placeholder="Description (optional)" maxlength="%d"></textarea>
+  <button type="submit">Upload</button>
+  <progress id="video-progress" value="0" max="100" hidden></progress>
+  <p id="video-status" class="text-muted text-sm">MP4, WebM, Ogg or QuickTime, up to %dMB.</p>
+</form>
+%s`, maxTitleLength, maxDescriptionLength, MaxVideoSize>>20, app.Script(r, uploadScript))
+
+	app.Respond(w, r, app.Response{
+		Title:       "Upload a video",
+		Description: "Upload a video to share with members",
+		HTML:        body,
+	})
+}
+
+// uploadScript sends the upload form's file in chunks and, if a chunk
+// fails, picks up from what the server has.
+const uploadScript = `
+(function(){
+  var form = document.getElementById('video-upload-form');
+  var status = document.getElementById('video-status');
+  var bar = document.getElementById('video-progress');
+  function json(res) {
+    return res.json().then(function(d) { if (!res.ok && res.status !== 409) throw new Error(d.error || 'Upload failed'); d.status_code = res.status; return d; });
+  }
+  form.addEventListener('submit', function(e) {
+    e.preventDefault();
+    var file = document.getElementById('video-file').files[0];
+    if (!file) return;
+    form.querySelector('button').disabled = true;
+    bar.hidden = false;
+    fetch('/video/upload', {
+      method: 'POST',
+      headers: {'Content-Type': 'application/json', 'Accept': 'application/json'},
+      body: JSON.stringify({title: document.getElementById('video-title').value, description: document.getElementById('video-description').value, filename: file.name, size: file.size})
+    }).then(json).then(function(up) {
+      var retries = 0;
+      function send(offset) {
+        if (offset >= file.size) { window.location = up.url; return; }
+        bar.value = Math.floor(offset * 100 / file.size);
+        status.textContent = 'Uploading… ' + bar.value + '%';
+        fetch(up.upload_url + '?offset=' + offset, {method: 'PUT', headers: {'Accept': 'application/json'}, body: file.slice(offset, offset + up.chunk_size)})
+          .then(json).then(function(d) { retries = 0; send(d.received); })
+          .catch(function(err) {
+            if (++retries > 5) throw err;
+            // Ask where the upload got to and carry on from there.
+            setTimeout(function() { fetch(up.upload_url, {headers: {'Accept': 'application/json'}}).then(json).then(function(d) { send(d.received); }).catch(fail); }, 2000 * retries);
+          }).catch(fail);
+      }
+      send(0);
+    }).catch(fail);
+  });
+  function fail(err) {
+    status.textContent = err.message;
+    status.className = 'text-error text-sm';
+    form.querySelector('button').disabled = false;
+  }
+})();
+`
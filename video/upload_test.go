@@ -0,0 +1,192 @@
+package video
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// testMP4 is enough of an MP4 to pass the type check.
+func testMP4(size int) []byte {
+	b := bytes.Repeat([]byte{0x2a}, size)
+	copy(b, []byte("\x00\x00\x00\x18ftypmp42"))
+	return b
+}
+
+func TestLocalUpload(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	uploadsMu.Lock()
+	orig := uploads
+	uploads = map[string]*Upload{}
+	uploadsMu.Unlock()
+	origThumb := thumbnailer
+	thumbnailer = func(src, dst string) (float64, error) {
+		return 83, os.WriteFile(dst, []byte("jpeg"), 0600)
+	}
+	defer func() {
+		uploadsMu.Lock()
+		uploads = orig
+		uploadsMu.Unlock()
+		thumbnailer = origThumb
+	}()
+
+	sessions := map[string]string{}
+	for _, a := range []*auth.Account{
+		{ID: "video_owner", Name: "Owner", Secret: "secret", Created: time.Now()},
+		{ID: "video_other", Name: "Other", Secret: "secret", Created: time.Now()},
+	} {
+		if err := auth.Create(a); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(a.ID)
+		sess, err := auth.Login(a.ID, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[a.ID] = sess.Token
+	}
+
+	do := func(user, method, target string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		if method == "POST" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		r.Header.Set("Accept", "application/json")
+		if user != "" {
+			r.AddCookie(&http.Cookie{Name: "session", Value: sessions[user]})
+		}
+		w := httptest.NewRecorder()
+		LocalHandler(w, r)
+		return w
+	}
+	start := func(size int) string {
+		w := do("video_owner", "POST", "/video/upload", []byte(fmt.Sprintf(`{"title":"Park walk","description":"Autumn","filename":"walk.mp4","size":%d}`, size)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("start: %d %s", w.Code, w.Body)
+		}
+		var rsp struct {
+			UploadURL string `json:"upload_url"`
+		}
+		json.NewDecoder(w.Body).Decode(&rsp)
+		return rsp.UploadURL
+	}
+
+	if w := do("", "POST", "/video/upload", []byte(`{"title":"x","size":10}`)); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous upload: %d", w.Code)
+	}
+	if w := do("video_owner", "POST", "/video/upload", []byte(fmt.Sprintf(`{"title":"x","size":%d}`, MaxVideoSize+1))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload: %d", w.Code)
+	}
+
+	// Something that isn't a video is turned away at its first chunk.
+	url := start(100)
+	if w := do("video_owner", "PUT", url+"?offset=0", bytes.Repeat([]byte("x"), 100)); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text upload: %d", w.Code)
+	}
+
+	// A video in two chunks, with a retried chunk in between.
+	video := testMP4(3000)
+	url = start(len(video))
+	if w := do("video_other", "PUT", url+"?offset=0", video[:1000]); w.Code != http.StatusNotFound {
+		t.Errorf("someone else's upload: %d", w.Code)
+	}
+	if w := do("video_owner", "PUT", url+"?offset=0", video[:1000]); w.Code != http.StatusOK {
+		t.Fatalf("first chunk: %d %s", w.Code, w.Body)
+	}
+	w := do("video_owner", "PUT", url+"?offset=0", video[:1000])
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"received":1000`) {
+		t.Errorf("repeated chunk: %d %s", w.Code, w.Body)
+	}
+	if w := do("video_owner", "PUT", url+"?offset=1000", video[1000:]); w.Code != http.StatusOK {
+		t.Fatalf("last chunk: %d %s", w.Code, w.Body)
+	}
+	id := strings.TrimPrefix(url, "/video/upload/")
+	if u := GetUpload(id); u == nil || u.Status != StatusProcessing || u.ContentType != "video/mp4" {
+		t.Fatalf("upload = %+v", u)
+	}
+	if w := do("video_owner", "GET", "/video/file/"+id, nil); w.Code != http.StatusNotFound {
+		t.Errorf("served before processing: %d", w.Code)
+	}
+
+	if err := processUploads(); err != nil {
+		t.Fatal(err)
+	}
+	// Let the video's embedding land before the data dir is removed.
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if m := data.SemanticSearch("Park walk", 1, data.WithType("video")); len(m) > 0 {
+			break
+		}
+	}
+	u := GetUpload(id)
+	if u.Status != StatusReady || !u.Thumbnail || u.Duration != 83 {
+		t.Fatalf("processed = %+v", u)
+	}
+
+	r := httptest.NewRequest("GET", "/video/file/"+id, nil)
+	r.Header.Set("Range", "bytes=1000-1999")
+	w = httptest.NewRecorder()
+	LocalHandler(w, r)
+	if w.Code != http.StatusPartialContent || !bytes.Equal(w.Body.Bytes(), video[1000:2000]) {
+		t.Errorf("range: %d, %d bytes", w.Code, w.Body.Len())
+	}
+	w = httptest.NewRecorder()
+	LocalHandler(w, httptest.NewRequest("GET", "/video/v/"+id, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/video/file/"+id) || !strings.Contains(w.Body.String(), "1:23") {
+		t.Errorf("watch page: %d", w.Code)
+	}
+	if list := Uploads("video_owner"); len(list) != 1 || list[0].ID != id {
+		t.Errorf("uploads = %v", list)
+	}
+
+	// Only the owner (or an admin) may delete it.
+	if w := do("video_other", "DELETE", "/video/v/"+id, nil); w.Code != http.StatusForbidden {
+		t.Errorf("someone else deleted it: %d", w.Code)
+	}
+	if w := do("video_owner", "DELETE", "/video/v/"+id, nil); w.Code >= 400 || GetUpload(id) != nil {
+		t.Errorf("delete: %d", w.Code)
+	}
+	if path, _ := data.FilePath(contentKey(id)); fileExists(path) {
+		t.Error("video file left behind")
+	}
+}
+
+func TestVideoQuota(t *testing.T) {
+	uploadsMu.Lock()
+	orig := uploads
+	uploads = map[string]*Upload{
+		"a": {ID: "a", Owner: "q", Size: MaxVideoStorage - 100, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+	defer func() { uploads = orig; uploadsMu.Unlock() }()
+
+	if err := checkVideoQuotaLocked("q", 100); err != nil {
+		t.Errorf("within quota: %v", err)
+	}
+	if err := checkVideoQuotaLocked("q", 101); err != ErrVideoQuota {
+		t.Errorf("over quota: %v", err)
+	}
+	for i := 0; i < MaxVideosPerDay; i++ {
+		id := fmt.Sprint(i)
+		uploads[id] = &Upload{ID: id, Owner: "q", Size: 1, CreatedAt: time.Now()}
+	}
+	if err := checkVideoQuotaLocked("q", 1); err != ErrVideoDailyLimit {
+		t.Errorf("daily limit: %v", err)
+	}
+	if err := checkVideoQuotaLocked("someone", 1); err != nil {
+		t.Errorf("other account: %v", err)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -10,21 +10,29 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
+
 	"mu/internal/app"
 	"mu/internal/auth"
 	"mu/internal/data"
 	"mu/internal/service"
+	"mu/internal/settings"
 	"mu/internal/snapshot"
-
 	"mu/wallet"
 )
 
+func init() {
+	settings.Register("Video",
+		settings.Var{Key: "YOUTUBE_API_KEY", Secret: true, Doc: "YouTube Data API key"},
+	)
+}
+
 // cardSnap is the go-micro read-plane channel for the video card (store +
 // broker); see internal/snapshot and docs/GO_MICRO_ARCHITECTURE.md.
 var cardSnap *snapshot.Snapshot
@@ -43,7 +51,8 @@ var videos = map[string]Channel{}
 // latest video
 var latestHtml string
 
-// saved videos
+// saved videos; left empty in low-memory mode, where the page is read
+// from videos.html when served
 var videosHtml string
 
 type Channel struct {
@@ -265,6 +274,7 @@ var Template = `<form id="video-search" class="search-bar" action="/video" metho
 </form>
 <div id="topics">%s</div>
 <div id="recent-searches-container"></div>
+<p class="video-local-links"><a href="/video/uploads">Member videos</a> · <a href="/video/upload">Upload a video</a> · <a href="/video/history">History</a></p>
 <div>%s</div>
 ` + recentSearchesScript
 
@@ -306,8 +316,10 @@ func Load() {
 		b, _ = data.LoadFile("latest.html")
 		latestHtml = string(b)
 
-		b, _ = data.LoadFile("videos.html")
-		videosHtml = string(b)
+		if !data.LowMemory() {
+			b, _ = data.LoadFile("videos.html")
+			videosHtml = string(b)
+		}
 		app.Log("video", "No cached JSON, loaded HTML files")
 	}
 
@@ -319,8 +331,11 @@ func Load() {
 	mutex.RUnlock()
 	cardSnap.Publish(warm)
 
-	// load fresh videos
-	go loadVideos()
+	loadUploads()
+	loadHistory()
+
+	// load fresh videos, then hourly
+	app.Schedule(app.Job{Name: "video", Every: time.Hour, Run: loadVideos})
 }
 
 // regenerateHTML creates HTML from cached video data
@@ -416,13 +431,15 @@ func regenerateHTML() {
 		body.WriteString(`</div>`)
 	}
 
-	videosHtml = app.RenderHTML("Video", "Search for videos", fmt.Sprintf(Template, head, body.String()))
+	if !data.LowMemory() {
+		videosHtml = app.RenderHTML("Video", "Search for videos", fmt.Sprintf(Template, head, body.String()))
+	}
 
 	// Publish the rebuilt card snapshot (nil-safe before Load wires cardSnap).
 	cardSnap.Publish(latestHtml)
 }
 
-func loadVideos() {
+func loadVideos() error {
 	app.Log("video", "Loading videos")
 
 	mutex.RLock()
@@ -467,13 +484,10 @@ func loadVideos() {
 
 	// Check if we got any videos
 	if len(latest) == 0 {
-		app.Log("video", "WARNING: No videos loaded from any channel. Check YouTube API key and channel handles.")
 		mutex.Lock()
 		videos = vids
 		mutex.Unlock()
-		time.Sleep(time.Hour)
-		go loadVideos()
-		return
+		return fmt.Errorf("no videos loaded from any channel; check the YouTube API key and channel handles")
 	}
 
 	// add to body
@@ -536,15 +550,15 @@ func loadVideos() {
 		data.SaveFile("latest.html", latestHtml)
 	}
 	videos = vids
-	videosHtml = vidHtml
+	if !data.LowMemory() {
+		videosHtml = vidHtml
+	}
 	lh := latestHtml
 	mutex.Unlock()
 
 	// Publish the refreshed card snapshot to the go-micro store + broker.
 	cardSnap.Publish(lh)
-
-	time.Sleep(time.Hour)
-	go loadVideos()
+	return nil
 }
 
 func embedVideo(id string) string {
@@ -552,10 +566,18 @@ func embedVideo(id string) string {
 }
 
 func embedVideoWithAutoplay(id string, autoplay bool) string {
+	return embedVideoAt(id, autoplay, 0)
+}
+
+// embedVideoAt embeds a video starting start seconds in.
+func embedVideoAt(id string, autoplay bool, start int) string {
 	u := "https://www.youtube.com/embed/" + id + "?enablejsapi=1&playsinline=1"
 	if autoplay {
 		u += "&autoplay=1"
 	}
+	if start > 0 {
+		u += "&start=" + strconv.Itoa(start)
+	}
 	style := `style="position: absolute; top: 0; left: 0; right: 0; width: 100%; height: 100%; border: none;"`
 	return `<iframe id="ytplayer" width="560" height="315" ` + style + ` src="` + u + `" title="YouTube video player" frameborder="0" allow="accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture" playsinline allowfullscreen></iframe>`
 }
@@ -1110,6 +1132,12 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Member videos have their own page; bookmarks of them land here.
+	if len(id) > 0 && GetUpload(id) != nil {
+		http.Redirect(w, r, "/video/v/"+id, http.StatusSeeOther)
+		return
+	}
+
 	// render watch page
 	if len(id) > 0 {
 		// Check if autoplay is requested
@@ -1121,7 +1149,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
   <head>
     <meta name="viewport" content="width=device-width, initial-scale=1">
     <title>Video | Mu</title>
-    <link rel="stylesheet" href="/mu.css?%s">
+    <link rel="stylesheet" href="%s">
   </head>
   <body class="video-player-body">
     <div class="video-embed">
@@ -1134,6 +1162,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
       <button id="audioBtn" onclick="toggleAudio()">♫ Audio only</button>
       <span id="audioTime"></span>
       <button id="playBtn" onclick="togglePlay()" style="display:none">▶</button>
+      %s
     </div>
     <script>
     var player, apiReady=false, tInt;
@@ -1178,10 +1207,14 @@ func Handler(w http.ResponseWriter, r *http.Request) {
       player.getPlayerState()===1?player.pauseVideo():player.playVideo();
     }
     </script>
+    %s
   </body>
 </html>
 `
-		html := fmt.Sprintf(tmpl, app.Version, embedVideoWithAutoplay(id, autoplay))
+		beacon := watchBeacon(r, id,
+			"(player&&player.getCurrentTime?player.getCurrentTime():-1)",
+			"(player&&player.getDuration?player.getDuration():0)")
+		html := fmt.Sprintf(tmpl, app.Asset("/mu.css"), embedVideoAt(id, autoplay, startAt(r, id)), app.BookmarkButton(r, "video", id), beacon)
 		w.Write([]byte(html))
 
 		return
@@ -1201,5 +1234,9 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if currentHtml == "" && data.LowMemory() {
+		b, _ := data.LoadFile("videos.html")
+		currentHtml = string(b)
+	}
 	w.Write([]byte(currentHtml))
 }
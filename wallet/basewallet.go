@@ -22,6 +22,14 @@ import (
 	"mu/internal/settings"
 )
 
+func init() {
+	settings.Register("Trading",
+		settings.Var{Key: "BASE_RPC_URL", Type: settings.TypeURL, Default: "https://mainnet.base.org", Doc: "Base JSON-RPC endpoint for wallets and trades"},
+		settings.Var{Key: "TRADE_RPC_URL", Type: settings.TypeURL, Doc: "Older name for BASE_RPC_URL"},
+		settings.Var{Key: "TRADE_CHAIN", Doc: "Chain trades run on"},
+	)
+}
+
 // USDC on Base mainnet (6 decimals) — the asset x402 settles in.
 const (
 	baseUSDC        = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
@@ -87,6 +95,19 @@ func GetOrCreateWallet(accountID string) (*BaseWallet, error) {
 	return w, nil
 }
 
+// RenameBaseWallet moves an account's on-chain wallet to its new ID
+// (username change).
+func RenameBaseWallet(oldID, newID string) {
+	loadWallets()
+	walletMu.Lock()
+	defer walletMu.Unlock()
+	if w, ok := userWallets[oldID]; ok {
+		delete(userWallets, oldID)
+		userWallets[newID] = w
+		data.SaveJSON(walletsFile, userWallets)
+	}
+}
+
 // DeleteBaseWallet removes an account's on-chain wallet (account teardown).
 func DeleteBaseWallet(accountID string) {
 	loadWallets()
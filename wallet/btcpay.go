@@ -0,0 +1,154 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/netx"
+	"mu/internal/settings"
+)
+
+// BTCPay Server takes top-ups in bitcoin, on-chain or over Lightning,
+// through a store the operator runs themselves. Invoices are priced in
+// pounds and BTCPay settles them at its own rate.
+
+func init() {
+	settings.Register("Payments",
+		settings.Var{Key: "BTCPAY_URL", Doc: "BTCPay Server URL for bitcoin top-ups"},
+		settings.Var{Key: "BTCPAY_STORE_ID", Doc: "BTCPay store invoices are created in"},
+		settings.Var{Key: "BTCPAY_API_KEY", Secret: true, Doc: "BTCPay API key with permission to create invoices"},
+		settings.Var{Key: "BTCPAY_WEBHOOK_SECRET", Secret: true, Doc: "BTCPay webhook secret"},
+	)
+	RegisterProvider(btcpayProvider{})
+}
+
+var btcpayClient = netx.New("btcpay", netx.Policy{Timeout: 30 * time.Second})
+
+func btcpayURL() string { return strings.TrimSuffix(settings.Get("BTCPAY_URL"), "/") }
+
+// BTCPayEnabled reports whether bitcoin top-ups are configured.
+func BTCPayEnabled() bool {
+	return btcpayURL() != "" && settings.Get("BTCPAY_STORE_ID") != "" && settings.Get("BTCPAY_API_KEY") != ""
+}
+
+type btcpayProvider struct{}
+
+func (btcpayProvider) Name() string  { return "btcpay" }
+func (btcpayProvider) Label() string { return "Bitcoin" }
+func (btcpayProvider) Enabled() bool { return BTCPayEnabled() }
+
+// Checkout creates a BTCPay invoice for amount pence.
+func (btcpayProvider) Checkout(userID string, amount int, successURL, cancelURL string) (string, string, error) {
+	if !BTCPayEnabled() {
+		return "", "", fmt.Errorf("btcpay not configured")
+	}
+	if amount < 100 {
+		return "", "", fmt.Errorf("minimum top-up is £1")
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"amount":   fmt.Sprintf("%d.%02d", amount/100, amount%100),
+		"currency": "GBP",
+		"metadata": map[string]string{
+			"user_id": userID,
+			"credits": strconv.Itoa(amount),
+		},
+		"checkout": map[string]string{"redirectURL": successURL},
+	})
+	req, err := http.NewRequest("POST", btcpayURL()+"/api/v1/stores/"+settings.Get("BTCPAY_STORE_ID")+"/invoices", bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "token "+settings.Get("BTCPAY_API_KEY"))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := btcpayClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		app.Log("btcpay", "invoice error: %s", string(b))
+		return "", "", fmt.Errorf("btcpay error: %s", resp.Status)
+	}
+	var invoice struct {
+		ID           string `json:"id"`
+		CheckoutLink string `json:"checkoutLink"`
+	}
+	if err := json.Unmarshal(b, &invoice); err != nil {
+		return "", "", err
+	}
+	app.Log("btcpay", "created invoice %s for user %s, %d credits", invoice.ID, userID, amount)
+	return invoice.CheckoutLink, invoice.ID, nil
+}
+
+// Webhook settles InvoiceSettled events. The invoice's metadata names the
+// account; older BTCPay versions leave it out of the event, so the
+// record from checkout fills it in.
+func (btcpayProvider) Webhook(header http.Header, body []byte) ([]Settlement, error) {
+	secret := settings.Get("BTCPAY_WEBHOOK_SECRET")
+	if secret == "" {
+		return nil, errWebhookNotConfigured
+	}
+	if !verifyBTCPaySignature(body, header.Get("BTCPay-Sig"), secret) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	var event struct {
+		Type      string `json:"type"`
+		InvoiceID string `json:"invoiceId"`
+		Metadata  struct {
+			UserID  string `json:"user_id"`
+			Credits string `json:"credits"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("parse error: %v", err)
+	}
+	app.Log("btcpay", "webhook received: %s", event.Type)
+	if event.Type != "InvoiceSettled" || event.InvoiceID == "" {
+		return nil, nil
+	}
+
+	userID := event.Metadata.UserID
+	credits, _ := strconv.Atoi(event.Metadata.Credits)
+	if p := findPayment("btcpay", event.InvoiceID); p != nil {
+		if userID == "" {
+			userID = p.UserID
+		}
+		if credits <= 0 {
+			credits = p.Credits
+		}
+	}
+	if userID == "" || credits <= 0 {
+		return nil, fmt.Errorf("invoice %s names no account", event.InvoiceID)
+	}
+	return []Settlement{{
+		PaymentID: event.InvoiceID,
+		UserID:    userID,
+		Amount:    credits, // 1 credit = 1p
+		Credits:   credits,
+		Metadata:  map[string]interface{}{"source": "btcpay", "invoice_id": event.InvoiceID},
+	}}, nil
+}
+
+// verifyBTCPaySignature checks a BTCPay-Sig header, "sha256=" and the
+// hex HMAC of the body.
+func verifyBTCPaySignature(payload []byte, sigHeader, secret string) bool {
+	sig, ok := strings.CutPrefix(sigHeader, "sha256=")
+	if !ok || sig == "" {
+		return false
+	}
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(payload)
+	return hmac.Equal([]byte(sig), []byte(hex.EncodeToString(h.Sum(nil))))
+}
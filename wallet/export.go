@@ -0,0 +1,33 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exporter exports a user's credit ledger.
+type exporter struct{}
+
+func (exporter) Name() string        { return "wallet" }
+func (exporter) Description() string { return "Your credit top-ups and spending" }
+func (exporter) Formats() []string   { return []string{"csv", "json"} }
+
+func (exporter) Export(w io.Writer, userID, format string) error {
+	mutex.RLock()
+	txs := append([]*Transaction{}, transactions[userID]...)
+	mutex.RUnlock()
+
+	if format == "json" {
+		return json.NewEncoder(w).Encode(txs)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "created_at", "type", "operation", "amount", "balance"})
+	for _, tx := range txs {
+		cw.Write([]string{tx.ID, tx.CreatedAt.Format(time.RFC3339), tx.Type, tx.Operation, strconv.Itoa(tx.Amount), strconv.Itoa(tx.Balance)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
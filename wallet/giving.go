@@ -0,0 +1,591 @@
+package wallet
+
+import (
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+
+	"github.com/google/uuid"
+)
+
+// Giving is a private record of an account's donations, made through Mu
+// or anywhere else, and a zakat target for each year. Nothing here moves
+// money or credits: it's a log the account keeps for itself, shown on
+// /wallet and /wallet/giving and exported as a yearly statement. It
+// isn't indexed and only its owner can see it.
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var templates = app.ParseTemplates(templateFiles, "templates/*.html")
+
+// Kinds of donation.
+const (
+	KindZakat   = "zakat"
+	KindSadaqah = "sadaqah"
+	KindOther   = "other"
+)
+
+var donationKinds = []string{KindZakat, KindSadaqah, KindOther}
+
+// Donation is one gift. Amounts are in the currency's minor unit, like
+// pence.
+type Donation struct {
+	ID        string    `json:"id"`
+	Date      time.Time `json:"date"`
+	Amount    int64     `json:"amount"`
+	Currency  string    `json:"currency"`
+	Recipient string    `json:"recipient"`
+	Kind      string    `json:"kind"`
+	Internal  bool      `json:"internal"` // given through Mu rather than elsewhere
+	Note      string    `json:"note,omitempty"`
+}
+
+// Giving is an account's donations and zakat targets.
+type Giving struct {
+	Currency  string        `json:"currency"`          // for targets, and the default for new donations
+	Targets   map[int]int64 `json:"targets,omitempty"` // year → zakat target
+	Donations []*Donation   `json:"donations"`
+}
+
+// givingCurrencies are offered in the forms; any three-letter code is
+// accepted.
+var givingCurrencies = []string{"GBP", "USD", "EUR"}
+
+var (
+	givingMu sync.Mutex
+	giving   = map[string]*Giving{} // account ID → record
+)
+
+func loadGiving() {
+	var g map[string]*Giving
+	if err := data.LoadJSON("giving.json", &g); err == nil && g != nil {
+		givingMu.Lock()
+		giving = g
+		givingMu.Unlock()
+	}
+}
+
+// saveGiving persists every account's record. Caller must hold givingMu.
+func saveGiving() error {
+	return data.SaveJSON("giving.json", giving)
+}
+
+// record returns the account's record, creating it. Caller must hold
+// givingMu.
+func record(userID string) *Giving {
+	g, ok := giving[userID]
+	if !ok {
+		g = &Giving{Currency: "GBP"}
+		giving[userID] = g
+	}
+	if g.Targets == nil {
+		g.Targets = map[int]int64{}
+	}
+	return g
+}
+
+// GetGiving returns a copy of the account's record, donations newest
+// first.
+func GetGiving(userID string) *Giving {
+	givingMu.Lock()
+	defer givingMu.Unlock()
+	g, ok := giving[userID]
+	if !ok {
+		return &Giving{Currency: "GBP", Targets: map[int]int64{}}
+	}
+	cp := &Giving{Currency: g.Currency, Targets: map[int]int64{}}
+	for y, t := range g.Targets {
+		cp.Targets[y] = t
+	}
+	for _, d := range g.Donations {
+		dc := *d
+		cp.Donations = append(cp.Donations, &dc)
+	}
+	sort.SliceStable(cp.Donations, func(i, j int) bool { return cp.Donations[i].Date.After(cp.Donations[j].Date) })
+	return cp
+}
+
+// AddDonation logs a donation, filling in its ID and, if unset, the
+// account's currency.
+func AddDonation(userID string, d *Donation) error {
+	if d.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if strings.TrimSpace(d.Recipient) == "" {
+		return errors.New("who you gave to is required")
+	}
+	if !validKind(d.Kind) {
+		return fmt.Errorf("unknown kind %q", d.Kind)
+	}
+	givingMu.Lock()
+	defer givingMu.Unlock()
+	g := record(userID)
+	cp := *d
+	cp.ID = uuid.New().String()
+	if cp.Currency == "" {
+		cp.Currency = g.Currency
+	}
+	if cp.Date.IsZero() {
+		cp.Date = time.Now()
+	}
+	g.Donations = append(g.Donations, &cp)
+	*d = cp
+	return saveGiving()
+}
+
+// DeleteDonation removes one of the account's donations.
+func DeleteDonation(userID, id string) error {
+	givingMu.Lock()
+	defer givingMu.Unlock()
+	g, ok := giving[userID]
+	if !ok {
+		return errors.New("donation not found")
+	}
+	for i, d := range g.Donations {
+		if d.ID == id {
+			g.Donations = append(g.Donations[:i], g.Donations[i+1:]...)
+			return saveGiving()
+		}
+	}
+	return errors.New("donation not found")
+}
+
+// SetZakatTarget sets the account's target for a year, in currency.
+// A zero amount clears it.
+func SetZakatTarget(userID string, year int, amount int64, currency string) error {
+	if amount < 0 {
+		return errors.New("target can't be negative")
+	}
+	givingMu.Lock()
+	defer givingMu.Unlock()
+	g := record(userID)
+	if currency != "" {
+		g.Currency = currency
+	}
+	if amount == 0 {
+		delete(g.Targets, year)
+	} else {
+		g.Targets[year] = amount
+	}
+	return saveGiving()
+}
+
+// DeleteGiving removes an account's record. Called when the account is
+// deleted.
+func DeleteGiving(userID string) {
+	givingMu.Lock()
+	defer givingMu.Unlock()
+	if _, ok := giving[userID]; !ok {
+		return
+	}
+	delete(giving, userID)
+	if err := saveGiving(); err != nil {
+		app.Log("wallet", "Saving giving: %v", err)
+	}
+}
+
+// RenameGiving moves a renamed account's record to its new ID.
+func RenameGiving(oldID, newID string) {
+	givingMu.Lock()
+	defer givingMu.Unlock()
+	g, ok := giving[oldID]
+	if !ok {
+		return
+	}
+	giving[newID] = g
+	delete(giving, oldID)
+	if err := saveGiving(); err != nil {
+		app.Log("wallet", "Saving giving: %v", err)
+	}
+}
+
+// Formatted is the donation's amount in its currency.
+func (d *Donation) Formatted() string {
+	return FormatAmount(d.Amount, d.Currency)
+}
+
+func validKind(k string) bool {
+	for _, v := range donationKinds {
+		if k == v {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCurrency accepts a three-letter code, in any case.
+func parseCurrency(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) != 3 || strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("%q isn't a currency code", s)
+	}
+	return s, nil
+}
+
+// parseAmount reads a decimal amount like 12.50 into minor units.
+func parseAmount(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%q has more than two decimal places", s)
+	}
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || w < 0 {
+		return 0, fmt.Errorf("%q isn't an amount", s)
+	}
+	var f int64
+	if frac != "" {
+		if f, err = strconv.ParseInt((frac + "0")[:2], 10, 64); err != nil || f < 0 {
+			return 0, fmt.Errorf("%q isn't an amount", s)
+		}
+	}
+	return w*100 + f, nil
+}
+
+var currencySymbols = map[string]string{"GBP": "£", "USD": "$", "EUR": "€"}
+
+// FormatAmount shows minor units in a currency, like £12.50 or 12.50 SAR.
+func FormatAmount(amount int64, currency string) string {
+	n := fmt.Sprintf("%d.%02d", amount/100, amount%100)
+	if sym, ok := currencySymbols[currency]; ok {
+		return sym + n
+	}
+	return n + " " + currency
+}
+
+// YearSummary is a year of an account's giving.
+type YearSummary struct {
+	Year      int              `json:"year"`
+	Currency  string           `json:"currency"`
+	Target    int64            `json:"target"`  // zakat target in Currency; 0 if unset
+	Zakat     int64            `json:"zakat"`   // zakat given in Currency
+	Percent   int              `json:"percent"` // of the target, capped at 100
+	Totals    map[string]int64 `json:"totals"`  // currency → everything given
+	Donations []*Donation      `json:"donations"`
+}
+
+// Summary totals an account's giving in year.
+func (g *Giving) Summary(year int) *YearSummary {
+	s := &YearSummary{Year: year, Currency: g.Currency, Target: g.Targets[year], Totals: map[string]int64{}}
+	for _, d := range g.Donations {
+		if d.Date.Year() != year {
+			continue
+		}
+		s.Donations = append(s.Donations, d)
+		s.Totals[d.Currency] += d.Amount
+		if d.Kind == KindZakat && d.Currency == g.Currency {
+			s.Zakat += d.Amount
+		}
+	}
+	if s.Target > 0 {
+		s.Percent = int(s.Zakat * 100 / s.Target)
+		if s.Percent > 100 {
+			s.Percent = 100
+		}
+	}
+	return s
+}
+
+// Format shows an amount in the summary's currency.
+func (s *YearSummary) Format(n int64) string {
+	return FormatAmount(n, s.Currency)
+}
+
+// TargetInput is the target as the form shows it, like 250.00.
+func (s *YearSummary) TargetInput() string {
+	if s.Target == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%02d", s.Target/100, s.Target%100)
+}
+
+// Remaining is how much zakat is left to give toward the target.
+func (s *YearSummary) Remaining() int64 {
+	if s.Zakat >= s.Target {
+		return 0
+	}
+	return s.Target - s.Zakat
+}
+
+// TotalsList is Totals sorted by currency, formatted.
+func (s *YearSummary) TotalsList() []string {
+	var out []string
+	for c, n := range s.Totals {
+		out = append(out, FormatAmount(n, c))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// givingView is what the giving templates render.
+type givingView struct {
+	*YearSummary
+	Years      []int
+	Kinds      []string
+	Currencies []string
+	Today      string
+	Error      string
+}
+
+func newGivingView(userID string, year int) *givingView {
+	g := GetGiving(userID)
+	v := &givingView{
+		YearSummary: g.Summary(year),
+		Kinds:       donationKinds,
+		Currencies:  givingCurrencies,
+		Today:       time.Now().Format("2006-01-02"),
+	}
+	seen := map[int]bool{time.Now().Year(): true, year: true}
+	for y := range g.Targets {
+		seen[y] = true
+	}
+	for _, d := range g.Donations {
+		seen[d.Date.Year()] = true
+	}
+	for y := range seen {
+		v.Years = append(v.Years, y)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(v.Years)))
+	return v
+}
+
+// givingCard renders this year's zakat progress for the /wallet page.
+func givingCard(userID string) string {
+	return templates.HTML("giving-card", newGivingView(userID, time.Now().Year()))
+}
+
+// requestYear is ?year=, or this year.
+func requestYear(r *http.Request) int {
+	if y, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil && y > 1900 && y < 3000 {
+		return y
+	}
+	return time.Now().Year()
+}
+
+// handleGiving serves /wallet/giving: GET shows a year's donations and
+// zakat progress, POST logs a donation.
+func handleGiving(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) || app.SendsJSON(r) {
+			app.Unauthorized(w, r)
+			return
+		}
+		app.RedirectToLogin(w, r)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		year := requestYear(r)
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, GetGiving(sess.Account).Summary(year))
+			return
+		}
+		v := newGivingView(sess.Account, year)
+		v.Error = r.URL.Query().Get("error")
+		templates.Page(w, r, "Giving", "Your donations and zakat", "giving", v)
+	case "POST":
+		addGiving(w, r, sess.Account)
+	default:
+		app.MethodNotAllowed(w, r)
+	}
+}
+
+func addGiving(w http.ResponseWriter, r *http.Request, userID string) {
+	var req struct {
+		Date      string `json:"date"`
+		Amount    string `json:"amount"`
+		Currency  string `json:"currency"`
+		Recipient string `json:"recipient"`
+		Kind      string `json:"kind"`
+		Internal  bool   `json:"internal"`
+		Note      string `json:"note"`
+	}
+	if app.SendsJSON(r) {
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Date = r.FormValue("date")
+		req.Amount = r.FormValue("amount")
+		req.Currency = r.FormValue("currency")
+		req.Recipient = r.FormValue("recipient")
+		req.Kind = r.FormValue("kind")
+		req.Internal = r.FormValue("internal") == "on"
+		req.Note = r.FormValue("note")
+	}
+
+	fail := func(msg string) {
+		if app.SendsJSON(r) || app.WantsJSON(r) {
+			app.BadRequest(w, r, msg)
+			return
+		}
+		http.Redirect(w, r, "/wallet/giving?error="+neturl.QueryEscape(msg), http.StatusSeeOther)
+	}
+	d := &Donation{
+		Recipient: strings.TrimSpace(req.Recipient),
+		Kind:      req.Kind,
+		Internal:  req.Internal,
+		Note:      strings.TrimSpace(req.Note),
+	}
+	if d.Kind == "" {
+		d.Kind = KindOther
+	}
+	var err error
+	if d.Amount, err = parseAmount(req.Amount); err != nil {
+		fail(err.Error())
+		return
+	}
+	if req.Currency != "" {
+		if d.Currency, err = parseCurrency(req.Currency); err != nil {
+			fail(err.Error())
+			return
+		}
+	}
+	if req.Date != "" {
+		if d.Date, err = time.ParseInLocation("2006-01-02", req.Date, time.Local); err != nil {
+			fail("date should look like 2006-01-02")
+			return
+		}
+	}
+	if err := AddDonation(userID, d); err != nil {
+		fail(err.Error())
+		return
+	}
+	if app.SendsJSON(r) || app.WantsJSON(r) {
+		app.RespondJSON(w, d)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/wallet/giving?year=%d", d.Date.Year()), http.StatusSeeOther)
+}
+
+// handleGivingTarget sets a year's zakat target: POST year, amount and
+// currency.
+func handleGivingTarget(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	r.ParseForm()
+	year, err := strconv.Atoi(r.FormValue("year"))
+	if err != nil {
+		year = time.Now().Year()
+	}
+	back := fmt.Sprintf("/wallet/giving?year=%d", year)
+	amount, err := parseAmount(r.FormValue("amount"))
+	if err != nil {
+		http.Redirect(w, r, back+"&error="+neturl.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	var currency string
+	if c := r.FormValue("currency"); c != "" {
+		if currency, err = parseCurrency(c); err != nil {
+			http.Redirect(w, r, back+"&error="+neturl.QueryEscape(err.Error()), http.StatusSeeOther)
+			return
+		}
+	}
+	if err := SetZakatTarget(sess.Account, year, amount, currency); err != nil {
+		app.ServerError(w, r, "Could not save your target")
+		return
+	}
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, GetGiving(sess.Account).Summary(year))
+		return
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// handleGivingDelete removes a donation: POST id.
+func handleGivingDelete(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	r.ParseForm()
+	if err := DeleteDonation(sess.Account, r.FormValue("id")); err != nil {
+		app.NotFound(w, r, err.Error())
+		return
+	}
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]bool{"deleted": true})
+		return
+	}
+	http.Redirect(w, r, "/wallet/giving?year="+r.FormValue("year"), http.StatusSeeOther)
+}
+
+// handleGivingStatement serves a year's statement: ?format=csv or json
+// downloads it, else it's a page to print.
+func handleGivingStatement(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		app.RedirectToLogin(w, r)
+		return
+	}
+	year := requestYear(r)
+	s := GetGiving(sess.Account).Summary(year)
+	name := fmt.Sprintf("giving-%d", year)
+	switch r.URL.Query().Get("format") {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		writeDonationsCSV(w, s.Donations)
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+		json.NewEncoder(w).Encode(s)
+	default:
+		acc, _ := auth.GetAccount(sess.Account)
+		templates.Page(w, r, fmt.Sprintf("Giving statement %d", year), "", "giving-statement", map[string]interface{}{
+			"Summary":   s,
+			"Account":   acc,
+			"Generated": time.Now(),
+		})
+	}
+}
+
+func writeDonationsCSV(w io.Writer, ds []*Donation) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "recipient", "kind", "through_mu", "amount", "currency", "note"})
+	for _, d := range ds {
+		cw.Write([]string{d.Date.Format("2006-01-02"), d.Recipient, d.Kind, strconv.FormatBool(d.Internal), fmt.Sprintf("%d.%02d", d.Amount/100, d.Amount%100), d.Currency, d.Note})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// givingExporter exports every donation and target an account has logged.
+type givingExporter struct{}
+
+func (givingExporter) Name() string        { return "giving" }
+func (givingExporter) Description() string { return "Your donations and zakat targets" }
+func (givingExporter) Formats() []string   { return []string{"csv", "json"} }
+
+func (givingExporter) Export(w io.Writer, userID, format string) error {
+	g := GetGiving(userID)
+	if format == "json" {
+		return json.NewEncoder(w).Encode(g)
+	}
+	return writeDonationsCSV(w, g.Donations)
+}
@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestParseAmount(t *testing.T) {
+	for in, want := range map[string]int64{"12.50": 1250, "12.5": 1250, "7": 700, ".99": 99, "1,000": 100000} {
+		if got, err := parseAmount(in); err != nil || got != want {
+			t.Errorf("parseAmount(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "abc", "-5", "1.234"} {
+		if got, err := parseAmount(in); err == nil && got > 0 {
+			t.Errorf("parseAmount(%q) = %d, want an error", in, got)
+		}
+	}
+	if got := FormatAmount(1250, "GBP"); got != "£12.50" {
+		t.Errorf("FormatAmount = %q", got)
+	}
+	if got := FormatAmount(5, "SAR"); got != "0.05 SAR" {
+		t.Errorf("FormatAmount = %q", got)
+	}
+}
+
+func TestGivingSummary(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer DeleteGiving("giver")
+	SetZakatTarget("giver", 2026, 100000, "GBP")
+	for _, d := range []*Donation{
+		{Amount: 40000, Recipient: "Masjid", Kind: KindZakat, Date: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Amount: 5000, Recipient: "Food bank", Kind: KindSadaqah, Internal: true, Date: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{Amount: 2000, Currency: "USD", Recipient: "Relief", Kind: KindZakat, Date: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{Amount: 9900, Recipient: "Masjid", Kind: KindZakat, Date: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		if err := AddDonation("giver", d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddDonation("giver", &Donation{Amount: 100, Recipient: "x", Kind: "bribe"}); err == nil {
+		t.Error("unknown kind should be refused")
+	}
+
+	s := GetGiving("giver").Summary(2026)
+	if s.Zakat != 40000 || s.Percent != 40 || s.Remaining() != 60000 {
+		t.Errorf("zakat %d, %d%%, %d to go", s.Zakat, s.Percent, s.Remaining())
+	}
+	if len(s.Donations) != 3 || s.Totals["GBP"] != 45000 || s.Totals["USD"] != 2000 {
+		t.Errorf("donations %d, totals %v", len(s.Donations), s.Totals)
+	}
+	if !s.Donations[0].Date.After(s.Donations[1].Date) {
+		t.Error("donations should be newest first")
+	}
+}
+
+func TestGivingHandlers(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, id := range []string{"giving_user", "giving_other"} {
+		if err := auth.Create(&auth.Account{ID: id, Name: id, Secret: "secret", Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(id)
+		defer DeleteGiving(id)
+	}
+	login := func(id string) *http.Cookie {
+		sess, err := auth.Login(id, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Cookie{Name: "session", Value: sess.Token}
+	}
+	user, other := login("giving_user"), login("giving_other")
+	year := strconv.Itoa(time.Now().Year())
+	post := func(path, body string, c *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c != nil {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+
+	if w := post("/wallet/giving", "recipient=Masjid&amount=25&kind=zakat&date="+year+"-02-01", user); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/wallet/giving?year="+year {
+		t.Fatalf("log: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := post("/wallet/giving", "recipient=Masjid&amount=lots", user); !strings.Contains(w.Header().Get("Location"), "error=") {
+		t.Error("bad amount should come back as an error")
+	}
+	post("/wallet/giving/target", "year="+year+"&amount=100&currency=GBP", user)
+	g := GetGiving("giving_user")
+	if len(g.Donations) != 1 || g.Targets[time.Now().Year()] != 10000 {
+		t.Fatalf("giving = %+v", g)
+	}
+
+	r := httptest.NewRequest("GET", "/wallet/giving/statement?year="+year+"&format=csv", nil)
+	r.AddCookie(user)
+	w := httptest.NewRecorder()
+	Handler(w, r)
+	if !strings.Contains(w.Body.String(), year+"-02-01,Masjid,zakat,false,25.00,GBP") {
+		t.Errorf("statement = %q", w.Body.String())
+	}
+
+	r = httptest.NewRequest("GET", "/wallet/giving", nil)
+	r.AddCookie(user)
+	w = httptest.NewRecorder()
+	Handler(w, r)
+	if body := w.Body.String(); !strings.Contains(body, "Masjid") || !strings.Contains(body, `class="progress-bar"`) {
+		t.Error("giving page doesn't show the donation and progress")
+	}
+
+	// Another account sees none of it and can't delete it.
+	if s := GetGiving("giving_other").Summary(time.Now().Year()); len(s.Donations) != 0 {
+		t.Error("giving leaked to another account")
+	}
+	if w := post("/wallet/giving/delete", "id="+g.Donations[0].ID, other); w.Code != http.StatusNotFound {
+		t.Errorf("other account's delete: %d", w.Code)
+	}
+	if w := post("/wallet/giving", "recipient=x&amount=1", nil); w.Code == http.StatusSeeOther && strings.Contains(w.Header().Get("Location"), "/wallet/giving") {
+		t.Error("guests can't log donations")
+	}
+
+	if card := givingCard("giving_user"); !strings.Contains(card, "£25.00 of £100.00 zakat") {
+		t.Errorf("card = %s", card)
+	}
+}
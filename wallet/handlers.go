@@ -7,7 +7,7 @@ import (
 	neturl "net/url"
 	"os"
 	"strings"
-	"time"
+	"unicode/utf8"
 
 	"mu/internal/app"
 	"mu/internal/auth"
@@ -87,12 +87,15 @@ func WalletPage(userID string) string {
 	} else {
 		sb.WriteString(fmt.Sprintf(`<p>%d credits</p>`, wallet.Balance))
 	}
-	sb.WriteString(`<p><a href="/wallet/topup">Add Credits →</a> · <a href="/wallet/transfer">Transfer →</a></p>`)
+	sb.WriteString(`<p><a href="/wallet/topup">Add Credits →</a> · <a href="/wallet/send">Send credits →</a></p>`)
 	sb.WriteString(`</div>`)
 
 	// Crypto wallet — the other way to pay (USDC on Base via x402).
 	sb.WriteString(cryptoWalletCard(userID))
 
+	// Donations and zakat the user has logged — private to them.
+	sb.WriteString(givingCard(userID))
+
 	// App earnings summary
 	var totalEarnings int
 	for _, tx := range transactions {
@@ -129,6 +132,8 @@ func WalletPage(userID string) string {
 	sb.WriteString(fmt.Sprintf(`<tr><td>Blog post</td><td>%dp</td></tr>`, CostBlogCreate))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Blog comment</td><td>%dp</td></tr>`, CostBlogComment))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Chat query</td><td>%dp</td></tr>`, CostChatQuery))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Thread summary</td><td>%dp</td></tr>`, CostThreadSummary))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Daily briefing</td><td>%dp</td></tr>`, CostDailyBriefing))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Agent (standard)</td><td>%dp</td></tr>`, CostAgentQuery))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Agent (premium)</td><td>%dp</td></tr>`, CostAgentQueryPremium))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Weather forecast</td><td>%dp</td></tr>`, CostWeatherForecast))
@@ -152,44 +157,8 @@ func WalletPage(userID string) string {
 		sb.WriteString(`<tr><th>Date</th><th>Type</th><th>Amount</th><th>Balance</th></tr>`)
 
 		for _, tx := range transactions {
-			typeLabel := tx.Operation
-			if tx.Operation == OpAppUse {
-				if appSlug, ok := tx.Metadata["app"].(string); ok {
-					typeLabel = "App: " + appSlug
-				} else {
-					typeLabel = "App usage"
-				}
-			} else if tx.Operation == OpAppRevenue {
-				if appSlug, ok := tx.Metadata["app"].(string); ok {
-					typeLabel = "Earned: " + appSlug
-				} else {
-					typeLabel = "App revenue"
-				}
-			} else if tx.Type == TxTopup {
-				typeLabel = "Deposit"
-			} else if tx.Type == TxTransfer {
-				if tx.Amount > 0 {
-					if from, ok := tx.Metadata["from"].(string); ok {
-						typeLabel = "Transfer from " + from
-					} else {
-						typeLabel = "Transfer in"
-					}
-				} else {
-					if to, ok := tx.Metadata["to"].(string); ok {
-						typeLabel = "Transfer to " + to
-					} else {
-						typeLabel = "Transfer out"
-					}
-				}
-			}
-			var amountStr string
-			if tx.Amount == 0 {
-				amountStr = "included"
-			} else if tx.Amount > 0 {
-				amountStr = fmt.Sprintf("+%d", tx.Amount)
-			} else {
-				amountStr = fmt.Sprintf("-%d", abs(tx.Amount))
-			}
+			typeLabel := txLabel(tx)
+			amountStr := txAmount(tx)
 			sb.WriteString(fmt.Sprintf(`<tr>
 				<td>%s</td>
 				<td>%s</td>
@@ -199,6 +168,7 @@ func WalletPage(userID string) string {
 		}
 
 		sb.WriteString(`</table>`)
+		sb.WriteString(`<p><a href="/wallet/history">Full history →</a></p>`)
 		sb.WriteString(`</div>`)
 	}
 
@@ -260,18 +230,36 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		handleDepositPage(w, r)
 	case path == "/wallet/stripe/subscribe" && r.Method == "POST":
 		handleStripeSubscribe(w, r)
-	case path == "/wallet/stripe/checkout" && r.Method == "POST":
-		handleStripeCheckout(w, r)
-	case path == "/wallet/stripe/success" && r.Method == "GET":
-		handleStripeSuccess(w, r)
+	case (path == "/wallet/checkout" || path == "/wallet/stripe/checkout") && r.Method == "POST":
+		handleCheckout(w, r)
+	case (path == "/wallet/checkout/success" || path == "/wallet/stripe/success") && r.Method == "GET":
+		handleCheckoutSuccess(w, r)
 	case path == "/wallet/stripe/webhook" && r.Method == "POST":
 		HandleStripeWebhook(w, r)
+	case strings.HasPrefix(path, "/wallet/webhook/"):
+		handleWebhook(w, r)
 	case path == "/wallet/convert" && r.Method == "POST":
 		handleConvert(w, r)
-	case path == "/wallet/transfer" && r.Method == "POST":
+	case (path == "/wallet/send" || path == "/wallet/transfer") && r.Method == "POST":
 		handleTransfer(w, r)
+	case path == "/wallet/send" && r.Method == "GET":
+		handleSendPage(w, r)
 	case path == "/wallet/transfer" && r.Method == "GET":
-		handleTransferPage(w, r)
+		target := "/wallet/send"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	case path == "/wallet/history" && r.Method == "GET":
+		handleHistory(w, r)
+	case path == "/wallet/giving":
+		handleGiving(w, r)
+	case path == "/wallet/giving/target" && r.Method == "POST":
+		handleGivingTarget(w, r)
+	case path == "/wallet/giving/delete" && r.Method == "POST":
+		handleGivingDelete(w, r)
+	case path == "/wallet/giving/statement" && r.Method == "GET":
+		handleGivingStatement(w, r)
 	case path == "/wallet/pricing":
 		handlePricing(w, r)
 	default:
@@ -335,6 +323,8 @@ func PublicWalletPage() string {
 	sb.WriteString(fmt.Sprintf(`<tr><td>Blog post</td><td>%dp</td></tr>`, CostBlogCreate))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Blog comment</td><td>%dp</td></tr>`, CostBlogComment))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Chat query</td><td>%dp</td></tr>`, CostChatQuery))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Thread summary</td><td>%dp</td></tr>`, CostThreadSummary))
+	sb.WriteString(fmt.Sprintf(`<tr><td>Daily briefing</td><td>%dp</td></tr>`, CostDailyBriefing))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Places search</td><td>%dp</td></tr>`, CostPlacesSearch))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Places nearby</td><td>%dp</td></tr>`, CostPlacesNearby))
 	sb.WriteString(fmt.Sprintf(`<tr><td>Send mail</td><td>%dp</td></tr>`, CostMailSend))
@@ -352,6 +342,9 @@ func PublicWalletPage() string {
 	if StripeEnabled() {
 		sb.WriteString(`<li><strong>Card</strong> — secure payment via Stripe</li>`)
 	}
+	if BTCPayEnabled() {
+		sb.WriteString(`<li><strong>Bitcoin</strong> — on-chain or Lightning via BTCPay</li>`)
+	}
 	sb.WriteString(`</ul>`)
 	sb.WriteString(`<p><a href="/login">Login</a> or <a href="/signup">sign up</a> to top up.</p>`)
 	sb.WriteString(`</div>`)
@@ -384,8 +377,8 @@ func handleDepositPage(w http.ResponseWriter, r *http.Request) {
 
 	var sb strings.Builder
 
-	if StripeEnabled() {
-		sb.WriteString(renderStripeDeposit(sess.Account, r.URL.Query().Get("error")))
+	if len(Providers()) > 0 {
+		sb.WriteString(renderDeposit(sess.Account, r.URL.Query().Get("error")))
 	} else {
 		sb.WriteString(`<div class="card"><p class="text-error">No payment methods available.</p></div>`)
 	}
@@ -394,7 +387,7 @@ func handleDepositPage(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
-func renderStripeDeposit(userID, errMsg string) string {
+func renderDeposit(userID, errMsg string) string {
 	var sb strings.Builder
 
 	sb.WriteString(`<div class="card">`)
@@ -402,19 +395,37 @@ func renderStripeDeposit(userID, errMsg string) string {
 		sb.WriteString(fmt.Sprintf(`<p class="text-error">%s</p>`, errMsg))
 	}
 	// Monthly subscription plans.
-	sb.WriteString(`<h4>Monthly Plan</h4>`)
-	sb.WriteString(`<p class="text-sm text-muted mb-2">Subscribe for monthly credits — auto-renews via Stripe.</p>`)
-	sb.WriteString(`<div class="d-flex gap-2 mb-3">`)
-	for _, plan := range SubscriptionPlans {
-		sb.WriteString(fmt.Sprintf(
-			`<form method="POST" action="/wallet/stripe/subscribe" style="display:inline"><input type="hidden" name="plan" value="%s"><button type="submit" class="btn">%s</button></form>`,
-			plan.ID, plan.Label))
+	if StripeEnabled() {
+		sb.WriteString(`<h4>Monthly Plan</h4>`)
+		sb.WriteString(`<p class="text-sm text-muted mb-2">Subscribe for monthly credits — auto-renews via Stripe.</p>`)
+		sb.WriteString(`<div class="d-flex gap-2 mb-3">`)
+		for _, plan := range SubscriptionPlans {
+			sb.WriteString(fmt.Sprintf(
+				`<form method="POST" action="/wallet/stripe/subscribe" style="display:inline"><input type="hidden" name="plan" value="%s"><button type="submit" class="btn">%s</button></form>`,
+				plan.ID, plan.Label))
+		}
+		sb.WriteString(`</div>`)
+		sb.WriteString(`<hr style="border:none;border-top:1px solid #eee;margin:16px 0">`)
 	}
-	sb.WriteString(`</div>`)
-	sb.WriteString(`<hr style="border:none;border-top:1px solid #eee;margin:16px 0">`)
 
 	sb.WriteString("<h4>One-time top-up</h4>")
-	sb.WriteString(`<form method="POST" action="/wallet/stripe/checkout">`)
+	sb.WriteString(`<form method="POST" action="/wallet/checkout">`)
+
+	// Payment method, when there's a choice
+	methods := Providers()
+	if len(methods) == 1 {
+		sb.WriteString(fmt.Sprintf(`<input type="hidden" name="provider" value="%s">`, methods[0].Name()))
+	} else {
+		sb.WriteString(`<div class="d-flex gap-3 mt-2">`)
+		for i, p := range methods {
+			checked := ""
+			if i == 0 {
+				checked = " checked"
+			}
+			sb.WriteString(fmt.Sprintf(`<label><input type="radio" name="provider" value="%s"%s> %s</label>`, p.Name(), checked, p.Label()))
+		}
+		sb.WriteString(`</div>`)
+	}
 
 	// Preset quick-select buttons
 	sb.WriteString(`<div class="d-flex gap-2 mb-3 mt-2">`)
@@ -436,7 +447,14 @@ func renderStripeDeposit(userID, errMsg string) string {
 	sb.WriteString(`</div>`)
 
 	sb.WriteString(`<div class="card">`)
-	sb.WriteString(`<p class="text-sm text-muted">Secure payment via Stripe. 1 credit = 1p.</p>`)
+	var via []string
+	if StripeEnabled() {
+		via = append(via, "cards via Stripe")
+	}
+	if BTCPayEnabled() {
+		via = append(via, "bitcoin via BTCPay")
+	}
+	sb.WriteString(fmt.Sprintf(`<p class="text-sm text-muted">Secure payment: %s. 1 credit = 1p.</p>`, strings.Join(via, ", ")))
 	sb.WriteString(`</div>`)
 
 	return sb.String()
@@ -445,64 +463,8 @@ func renderStripeDeposit(userID, errMsg string) string {
 // maxTransferCredits is the maximum allowed transfer amount in credits
 const maxTransferCredits = 50000 // £500
 
-func handleTransferPage(w http.ResponseWriter, r *http.Request) {
-	sess, _, err := auth.RequireSession(r)
-	if err != nil {
-		app.RedirectToLogin(w, r)
-		return
-	}
-
-	balance := GetBalance(sess.Account)
-	errMsg := r.URL.Query().Get("error")
-	successMsg := r.URL.Query().Get("success")
-
-	var sb strings.Builder
-
-	sb.WriteString(`<div class="card">`)
-	sb.WriteString(`<h3>Transfer Credits</h3>`)
-	if errMsg != "" {
-		sb.WriteString(fmt.Sprintf(`<p class="text-error">%s</p>`, errMsg))
-	}
-	if successMsg != "" {
-		sb.WriteString(fmt.Sprintf(`<p class="text-success">%s</p>`, successMsg))
-	}
-	sb.WriteString(fmt.Sprintf(`<p>Your balance: <strong>%d credits</strong></p>`, balance))
-	remaining := DailyTransferCap - DailyTransferTotal(sess.Account, time.Now())
-	if remaining < 0 {
-		remaining = 0
-	}
-	sb.WriteString(fmt.Sprintf(`<p class="text-sm text-muted">Daily transfer limit: %d credits. Remaining today: %d credits.</p>`, DailyTransferCap, remaining))
-	// Build datalist of usernames for autocomplete
-	allAccounts := auth.GetAllAccounts()
-	sb.WriteString(`<datalist id="user-list">`)
-	for _, a := range allAccounts {
-		if a.ID == sess.Account {
-			continue
-		}
-		sb.WriteString(fmt.Sprintf(`<option value="%s">`, a.Name))
-	}
-	sb.WriteString(`</datalist>`)
-
-	sb.WriteString(`<form method="POST" action="/wallet/transfer">`)
-	sb.WriteString(`<div>`)
-	sb.WriteString(`<label for="transfer-to" class="text-sm">Recipient</label>`)
-	sb.WriteString(`<input type="text" id="transfer-to" name="to" placeholder="username" required class="form-input w-full mt-1" list="user-list" autocomplete="off">`)
-	sb.WriteString(`</div>`)
-	sb.WriteString(`<div class="mt-3">`)
-	sb.WriteString(`<label for="transfer-amount" class="text-sm">Amount (credits)</label>`)
-	sb.WriteString(fmt.Sprintf(`<input type="number" id="transfer-amount" name="amount" min="1" max="%d" placeholder="e.g. 100" required class="form-input w-full mt-1">`, maxTransferCredits))
-	sb.WriteString(`</div>`)
-	sb.WriteString(`<button type="submit" class="btn mt-4">Transfer</button>`)
-	sb.WriteString(`</form>`)
-	sb.WriteString(`</div>`)
-
-	sb.WriteString(`<div class="card">`)
-	sb.WriteString(fmt.Sprintf(`<p class="text-sm text-muted">1 credit = 1p. Transfers are instant and non-reversible. Daily transfer limit: %d credits.</p>`, DailyTransferCap))
-	sb.WriteString(`</div>`)
-
-	html := app.RenderHTMLForRequest("Transfer Credits", "Send credits to another user", sb.String(), r)
-	w.Write([]byte(html))
-}
+// maxTransferNote is the longest note a transfer can carry.
+const maxTransferNote = 140
 
 func handleTransfer(w http.ResponseWriter, r *http.Request) {
 	sess, _, err := auth.RequireSession(r)
@@ -517,7 +479,7 @@ func handleTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var to string
+	var to, note string
 	var amount int
 
 	if app.SendsJSON(r) {
@@ -525,6 +487,7 @@ func handleTransfer(w http.ResponseWriter, r *http.Request) {
 		var body struct {
 			To     string `json:"to"`
 			Amount int    `json:"amount"`
+			Note   string `json:"note"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			app.RespondJSON(w, map[string]string{"error": "invalid request body"})
@@ -532,14 +495,21 @@ func handleTransfer(w http.ResponseWriter, r *http.Request) {
 		}
 		to = body.To
 		amount = body.Amount
+		note = body.Note
 	} else {
 		// Form submission
 		if err := r.ParseForm(); err != nil {
-			http.Redirect(w, r, "/wallet/transfer?error=Invalid+form", http.StatusSeeOther)
+			respondTransferError(w, r, "Invalid form")
 			return
 		}
 		to = r.FormValue("to")
 		fmt.Sscanf(r.FormValue("amount"), "%d", &amount)
+		note = r.FormValue("note")
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxTransferNote {
+		respondTransferError(w, r, fmt.Sprintf("Keep the note under %d characters", maxTransferNote))
+		return
 	}
 
 	to = strings.TrimSpace(to)
@@ -571,7 +541,7 @@ func handleTransfer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform the transfer
-	if err := TransferCredits(sess.Account, recipient.ID, amount); err != nil {
+	if err := SendCredits(sess.Account, recipient.ID, amount, note); err != nil {
 		respondTransferError(w, r, err.Error())
 		return
 	}
@@ -588,7 +558,7 @@ func handleTransfer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	msg := fmt.Sprintf("Transferred %d credits to %s", amount, recipient.Name)
-	http.Redirect(w, r, "/wallet/transfer?success="+neturl.QueryEscape(msg), http.StatusSeeOther)
+	http.Redirect(w, r, r.URL.Path+"?success="+neturl.QueryEscape(msg), http.StatusSeeOther)
 }
 
 func respondTransferError(w http.ResponseWriter, r *http.Request, msg string) {
@@ -596,15 +566,17 @@ func respondTransferError(w http.ResponseWriter, r *http.Request, msg string) {
 		app.RespondJSON(w, map[string]string{"error": msg})
 		return
 	}
-	http.Redirect(w, r, "/wallet/transfer?error="+neturl.QueryEscape(msg), http.StatusSeeOther)
+	http.Redirect(w, r, r.URL.Path+"?error="+neturl.QueryEscape(msg), http.StatusSeeOther)
 }
 
 // maxTopupPounds is the maximum allowed top-up amount in whole pounds
 const maxTopupPounds = 500
 
 type TopupMethod struct {
-	Type  string            `json:"type"`            // "card"
-	Tiers []StripeTopupTier `json:"tiers,omitempty"` // For card/Stripe
+	Type     string            `json:"type"`     // "card", "bitcoin"
+	Provider string            `json:"provider"` // posted to /wallet/checkout
+	Label    string            `json:"label"`
+	Tiers    []StripeTopupTier `json:"tiers,omitempty"` // 1 credit = 1p for every provider
 }
 
 func handleTopupJSON(w http.ResponseWriter, r *http.Request) {
@@ -616,10 +588,12 @@ func handleTopupJSON(w http.ResponseWriter, r *http.Request) {
 
 	var methods []TopupMethod
 
-	if StripeEnabled() {
+	for _, p := range Providers() {
 		methods = append(methods, TopupMethod{
-			Type:  "card",
-			Tiers: StripeTopupTiers,
+			Type:     strings.ToLower(p.Label()),
+			Provider: p.Name(),
+			Label:    p.Label(),
+			Tiers:    StripeTopupTiers,
 		})
 	}
 
@@ -659,7 +633,9 @@ func handleStripeSubscribe(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, url, http.StatusSeeOther)
 }
 
-func handleStripeCheckout(w http.ResponseWriter, r *http.Request) {
+// handleCheckout starts a one-time top-up with the provider the form
+// picked, Stripe if it doesn't say, and records it as pending.
+func handleCheckout(w http.ResponseWriter, r *http.Request) {
 	sess, _, err := auth.RequireSession(r)
 	if err != nil {
 		app.RedirectToLogin(w, r)
@@ -671,7 +647,17 @@ func handleStripeCheckout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Amount is submitted in whole pounds; convert to pence for Stripe
+	name := r.FormValue("provider")
+	if name == "" {
+		name = "stripe"
+	}
+	provider, ok := GetProvider(name)
+	if !ok || !provider.Enabled() {
+		http.Redirect(w, r, "/wallet/topup?error=Choose+a+payment+method", http.StatusSeeOther)
+		return
+	}
+
+	// Amount is submitted in whole pounds; convert to pence
 	amountStr := r.FormValue("amount")
 	var pounds int
 	fmt.Sscanf(amountStr, "%d", &pounds)
@@ -704,13 +690,12 @@ func handleStripeCheckout(w http.ResponseWriter, r *http.Request) {
 		}
 		baseURL = fmt.Sprintf("%s://%s", scheme, r.Host)
 	}
-	successURL := baseURL + "/wallet/stripe/success?session_id={CHECKOUT_SESSION_ID}"
+	successURL := baseURL + "/wallet/checkout/success"
 	cancelURL := baseURL + "/wallet/topup"
 
-	// Create checkout session
-	checkoutURL, err := CreateCheckoutSession(sess.Account, amount, successURL, cancelURL)
+	checkoutURL, paymentID, err := provider.Checkout(sess.Account, amount, successURL, cancelURL)
 	if err != nil {
-		app.Log("stripe", "checkout error: %v", err)
+		app.Log(provider.Name(), "checkout error: %v", err)
 		content := `<div class="card"><h2>Payment Error</h2><p>Failed to create checkout session. Please try again.</p><p><a href="/wallet/topup" class="btn">Back</a></p></div>`
 		html := app.RenderHTMLForRequest("Payment Error", "Checkout failed", content, r)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -718,11 +703,11 @@ func handleStripeCheckout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Redirect to Stripe
+	recordCheckout(provider.Name(), paymentID, sess.Account, amount)
 	http.Redirect(w, r, checkoutURL, http.StatusSeeOther)
 }
 
-func handleStripeSuccess(w http.ResponseWriter, r *http.Request) {
+func handleCheckoutSuccess(w http.ResponseWriter, r *http.Request) {
 	// Just show success message - actual crediting happens via webhook
 	content := `<div class="card">
 		<h2>Payment Successful</h2>
@@ -750,6 +735,8 @@ func getPricingData() []pricingItem {
 		{OpBlogCreate, "Blog post", CostBlogCreate, "credits"},
 		{OpBlogComment, "Blog comment", CostBlogComment, "credits"},
 		{OpChatQuery, "Chat query", CostChatQuery, "credits"},
+		{OpThreadSummary, "Thread summary", CostThreadSummary, "credits"},
+		{OpDailyBriefing, "Daily briefing", CostDailyBriefing, "credits"},
 		{OpAgentQuery, "Agent (standard)", CostAgentQuery, "credits"},
 		{OpAgentQueryPremium, "Agent (premium)", CostAgentQueryPremium, "credits"},
 		{OpWeatherForecast, "Weather forecast", CostWeatherForecast, "credits"},
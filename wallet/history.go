@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// /wallet/history is the whole ledger: every top-up, charge and transfer,
+// newest first, with the balance after each. It can be narrowed to one
+// operation, paged with ?after=, and downloaded as CSV.
+
+// operationLabels name the operations in the history filter.
+var operationLabels = map[string]string{
+	OpNewsSearch:        "News search",
+	OpVideoSearch:       "Video search",
+	OpChatQuery:         "Chat",
+	OpBlogCreate:        "Blog post",
+	OpMailSend:          "Mail",
+	OpExternalEmail:     "External email",
+	OpPlacesSearch:      "Places search",
+	OpPlacesNearby:      "Places nearby",
+	OpWeatherForecast:   "Weather forecast",
+	OpWeatherPollen:     "Weather pollen",
+	OpWebSearch:         "Web search",
+	OpWebFetch:          "Web fetch",
+	OpDBWrite:           "Database write",
+	OpImageGenerate:     "Image",
+	OpAgentQuery:        "Agent",
+	OpAgentQueryPremium: "Agent (premium)",
+	OpSocialSearch:      "Social search",
+	OpSocialPost:        "Status update",
+	OpSocialReply:       "Reply",
+	OpBlogComment:       "Blog comment",
+	OpAppBuild:          "App build",
+	OpAppEdit:           "App edit",
+	OpThreadSummary:     "Thread summary",
+	OpDailyBriefing:     "Daily briefing",
+	OpAppUse:            "App usage",
+	OpAppRevenue:        "App revenue",
+	OpTopup:             "Deposit",
+	OpRefund:            "Refund",
+	OpTransfer:          "Transfer",
+	OpEscrowHold:        "Escrow hold",
+	OpEscrowRelease:     "Escrow release",
+	OpEscrowRefund:      "Escrow refund",
+}
+
+// operationLabel names an operation, falling back to its ID.
+func operationLabel(op string) string {
+	if l, ok := operationLabels[op]; ok {
+		return l
+	}
+	return strings.ReplaceAll(op, "_", " ")
+}
+
+// txLabel describes a transaction for the history tables.
+func txLabel(tx *Transaction) string {
+	switch {
+	case tx.Operation == OpAppUse:
+		if appSlug, ok := tx.Metadata["app"].(string); ok {
+			return "App: " + appSlug
+		}
+		return "App usage"
+	case tx.Operation == OpAppRevenue:
+		if appSlug, ok := tx.Metadata["app"].(string); ok {
+			return "Earned: " + appSlug
+		}
+		return "App revenue"
+	case tx.Type == TxTopup:
+		if p, ok := tx.Metadata["provider"].(string); ok {
+			return "Deposit (" + providerLabel(p) + ")"
+		}
+		return "Deposit"
+	case tx.Type == TxTransfer:
+		label := "Transfer out"
+		if from, ok := tx.Metadata["from"].(string); ok && tx.Amount > 0 {
+			label = "Transfer from " + from
+		} else if to, ok := tx.Metadata["to"].(string); ok && tx.Amount < 0 {
+			label = "Transfer to " + to
+		} else if tx.Amount > 0 {
+			label = "Transfer in"
+		}
+		if note, ok := tx.Metadata["note"].(string); ok && note != "" {
+			label += ": " + note
+		}
+		return label
+	}
+	return tx.Operation
+}
+
+// txAmount is a transaction's signed amount, or "included" if free.
+func txAmount(tx *Transaction) string {
+	switch {
+	case tx.Amount == 0:
+		return "included"
+	case tx.Amount > 0:
+		return fmt.Sprintf("+%d", tx.Amount)
+	}
+	return fmt.Sprintf("-%d", abs(tx.Amount))
+}
+
+// History returns the user's transactions, newest first, for operation
+// op or all of them if op is "".
+func History(userID, op string) []*Transaction {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	txs := transactions[userID]
+	out := make([]*Transaction, 0, len(txs))
+	for i := len(txs) - 1; i >= 0; i-- {
+		if op == "" || txs[i].Operation == op {
+			out = append(out, txs[i])
+		}
+	}
+	return out
+}
+
+// historyOperations are the operations in the user's ledger, for the
+// filter.
+func historyOperations(userID string) []historyOption {
+	mutex.RLock()
+	seen := map[string]bool{}
+	for _, tx := range transactions[userID] {
+		seen[tx.Operation] = true
+	}
+	mutex.RUnlock()
+	out := make([]historyOption, 0, len(seen))
+	for op := range seen {
+		out = append(out, historyOption{op, operationLabel(op)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Label < out[j].Label })
+	return out
+}
+
+type historyOption struct {
+	Value, Label string
+}
+
+// historyRow is a transaction as the history template shows it.
+type historyRow struct {
+	Date    time.Time
+	Label   string
+	Amount  string
+	Balance int
+}
+
+// handleHistory serves /wallet/history: ?op= filters by operation,
+// ?after=&limit= pages, ?format=csv downloads the filtered ledger and
+// JSON callers get a page with the cursor for the next.
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		if app.WantsJSON(r) {
+			app.Unauthorized(w, r)
+			return
+		}
+		app.RedirectToLogin(w, r)
+		return
+	}
+	op := r.URL.Query().Get("op")
+	txs := History(sess.Account, op)
+
+	if r.URL.Query().Get("format") == "csv" {
+		name := "wallet-history"
+		if _, ok := operationLabels[op]; ok {
+			name += "-" + op
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		writeTransactionsCSV(w, txs)
+		return
+	}
+
+	after, limit := app.Cursor(r)
+	start, end, next := app.PageAfter(len(txs), func(i int) string { return txs[i].ID }, after, limit)
+	page := txs[start:end]
+
+	if app.WantsJSON(r) {
+		resp := map[string]interface{}{"transactions": page, "balance": GetBalance(sess.Account)}
+		if next != "" {
+			resp["next"] = next
+		}
+		app.RespondJSON(w, resp)
+		return
+	}
+
+	rows := make([]historyRow, len(page))
+	for i, tx := range page {
+		rows[i] = historyRow{Date: tx.CreatedAt, Label: txLabel(tx), Amount: txAmount(tx), Balance: tx.Balance}
+	}
+	base := neturl.Values{}
+	if op != "" {
+		base.Set("op", op)
+	}
+	href := func(extra ...string) string {
+		v := neturl.Values{}
+		for k, vs := range base {
+			v[k] = vs
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			v.Set(extra[i], extra[i+1])
+		}
+		if len(v) == 0 {
+			return "/wallet/history"
+		}
+		return "/wallet/history?" + v.Encode()
+	}
+	pages := app.Pagination{List: "history-list"}
+	if next != "" {
+		pages.Next = href("after", next, "limit", strconv.Itoa(limit))
+	}
+	if after != "" {
+		pages.Prev = href()
+	}
+	templates.Page(w, r, "History", "Your wallet transactions", "history", map[string]interface{}{
+		"Rows":       rows,
+		"Operations": historyOperations(sess.Account),
+		"Op":         op,
+		"Balance":    GetBalance(sess.Account),
+		"Pages":      pages,
+		"CSV":        href("format", "csv"),
+	})
+}
+
+func writeTransactionsCSV(w io.Writer, txs []*Transaction) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "created_at", "type", "operation", "description", "amount", "balance"})
+	for _, tx := range txs {
+		cw.Write([]string{tx.ID, tx.CreatedAt.Format(time.RFC3339), tx.Type, tx.Operation, txLabel(tx), strconv.Itoa(tx.Amount), strconv.Itoa(tx.Balance)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
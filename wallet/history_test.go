@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := auth.Create(&auth.Account{ID: "history_user", Name: "H", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("history_user")
+	sess, err := auth.Login("history_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	origTx := transactions
+	var txs []*Transaction
+	balance := 100
+	txs = append(txs, &Transaction{ID: "t0", Type: TxTopup, Amount: 100, Balance: balance, Operation: OpTopup, CreatedAt: time.Now()})
+	for i := 1; i <= 30; i++ {
+		op := OpChatQuery
+		if i%3 == 0 {
+			op = OpPlacesSearch
+		}
+		balance -= 2
+		txs = append(txs, &Transaction{ID: fmt.Sprintf("t%d", i), Type: TxSpend, Amount: -2, Balance: balance, Operation: op, CreatedAt: time.Now()})
+	}
+	transactions = map[string][]*Transaction{"history_user": txs}
+	mutex.Unlock()
+	defer func() {
+		mutex.Lock()
+		transactions = origTx
+		mutex.Unlock()
+	}()
+
+	get := func(path string, wantJSON bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		if wantJSON {
+			r.Header.Set("Accept", "application/json")
+		}
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+
+	var page struct {
+		Transactions []*Transaction `json:"transactions"`
+		Next         string         `json:"next"`
+	}
+	json.Unmarshal(get("/wallet/history?op=places_search&limit=4", true).Body.Bytes(), &page)
+	if len(page.Transactions) != 4 || page.Transactions[0].ID != "t30" || page.Next != "t21" {
+		t.Fatalf("first page: %d, first %v, next %q", len(page.Transactions), page.Transactions, page.Next)
+	}
+	for _, tx := range page.Transactions {
+		if tx.Operation != OpPlacesSearch {
+			t.Errorf("filter let through %s", tx.Operation)
+		}
+	}
+	page.Next = ""
+	json.Unmarshal(get("/wallet/history?op=places_search&limit=4&after=t9", true).Body.Bytes(), &page)
+	if len(page.Transactions) != 2 || page.Transactions[1].ID != "t3" || page.Next != "" {
+		t.Fatalf("last page: %d, next %q", len(page.Transactions), page.Next)
+	}
+
+	body := get("/wallet/history", false).Body.String()
+	if !strings.Contains(body, `id="history-list"`) || !strings.Contains(body, "Load more") || !strings.Contains(body, `<option value="places_search">Places search</option>`) {
+		t.Error("history page is missing the list, paging or filter")
+	}
+
+	csv := get("/wallet/history?format=csv&op=topup", false)
+	if cd := csv.Header().Get("Content-Disposition"); !strings.Contains(cd, "wallet-history-topup.csv") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if lines := strings.Split(strings.TrimSpace(csv.Body.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "t0,") || !strings.HasSuffix(lines[1], ",Deposit,100,100") {
+		t.Errorf("csv = %q", csv.Body.String())
+	}
+}
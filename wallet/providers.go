@@ -0,0 +1,299 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/data"
+)
+
+// A Provider is a payment processor that sells credits. Each one makes a
+// hosted checkout for a top-up and turns its webhooks into settlements;
+// settlePayment does the rest, so every processor credits the ledger and
+// keeps its reconciliation record the same way. Checkout and webhooks are
+// served for all of them at /wallet/checkout and /wallet/webhook/{name}.
+type Provider interface {
+	// Name identifies the provider in URLs, records and the ledger.
+	Name() string
+	// Label is what checkout shows, e.g. "Card".
+	Label() string
+	// Enabled reports whether the provider is configured.
+	Enabled() bool
+	// Checkout starts paying amount pence for userID and returns the
+	// page to send them to and the provider's ID for the payment.
+	Checkout(userID string, amount int, successURL, cancelURL string) (checkoutURL, paymentID string, err error)
+	// Webhook verifies a webhook and returns the payments it settles.
+	Webhook(header http.Header, body []byte) ([]Settlement, error)
+}
+
+// Settlement is a payment a provider has confirmed.
+type Settlement struct {
+	PaymentID string
+	UserID    string
+	Amount    int // pence paid
+	Credits   int
+	Metadata  map[string]interface{} // added to the ledger entry
+}
+
+// errWebhookNotConfigured is returned by providers with no webhook secret.
+var errWebhookNotConfigured = errors.New("webhook not configured")
+
+var providers = map[string]Provider{}
+
+func init() { RegisterProvider(stripeProvider{}) }
+
+// RegisterProvider adds a payment provider.
+func RegisterProvider(p Provider) {
+	providers[p.Name()] = p
+}
+
+// GetProvider returns the named provider, if it's registered.
+func GetProvider(name string) (Provider, bool) {
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Providers returns the configured providers, Stripe first.
+func Providers() []Provider {
+	var out []Provider
+	for _, p := range providers {
+		if p.Enabled() {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if (out[i].Name() == "stripe") != (out[j].Name() == "stripe") {
+			return out[i].Name() == "stripe"
+		}
+		return out[i].Name() < out[j].Name()
+	})
+	return out
+}
+
+// providerLabel names a provider for the ledger, falling back to its ID.
+func providerLabel(name string) string {
+	if p, ok := providers[name]; ok {
+		return p.Label()
+	}
+	return name
+}
+
+// Payment statuses.
+const (
+	PaymentPending = "pending"
+	PaymentSettled = "settled"
+)
+
+// Payment is the reconciliation record of a top-up: the provider's ID for
+// the payment next to the ledger transaction that credited it. It's made
+// pending at checkout and settled by the webhook, so a payment the
+// processor took but never credited shows up as a settled record with no
+// transaction, or one left pending.
+type Payment struct {
+	ID            string    `json:"id"` // provider:external ID
+	Provider      string    `json:"provider"`
+	ExternalID    string    `json:"external_id"`
+	UserID        string    `json:"user_id"`
+	Amount        int       `json:"amount"` // pence
+	Credits       int       `json:"credits"`
+	Status        string    `json:"status"`
+	Created       time.Time `json:"created"`
+	Settled       time.Time `json:"settled"`
+	TransactionID string    `json:"transaction_id,omitempty"`
+}
+
+// Reconciled reports whether a settled payment has its ledger entry.
+func (p *Payment) Reconciled() bool {
+	return p.Status == PaymentSettled && p.TransactionID != ""
+}
+
+var (
+	paymentsMu sync.Mutex
+	payments   = map[string]*Payment{}
+)
+
+func loadPayments() {
+	paymentsMu.Lock()
+	defer paymentsMu.Unlock()
+	var p map[string]*Payment
+	if err := data.LoadJSON("payments.json", &p); err == nil && p != nil {
+		payments = p
+	}
+}
+
+func savePayments() error {
+	return data.SaveJSON("payments.json", payments)
+}
+
+func paymentKey(provider, externalID string) string {
+	return provider + ":" + externalID
+}
+
+// recordCheckout keeps a pending payment for a checkout just made.
+func recordCheckout(provider, externalID, userID string, amount int) {
+	if externalID == "" {
+		return
+	}
+	paymentsMu.Lock()
+	defer paymentsMu.Unlock()
+	key := paymentKey(provider, externalID)
+	if _, ok := payments[key]; ok {
+		return
+	}
+	payments[key] = &Payment{
+		ID: key, Provider: provider, ExternalID: externalID, UserID: userID,
+		Amount: amount, Credits: amount, Status: PaymentPending, Created: time.Now(),
+	}
+	savePayments()
+}
+
+// findPayment returns a copy of a payment's record, or nil.
+func findPayment(provider, externalID string) *Payment {
+	paymentsMu.Lock()
+	defer paymentsMu.Unlock()
+	p, ok := payments[paymentKey(provider, externalID)]
+	if !ok {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+// settlePayment credits a settled payment once, however many times the
+// provider delivers it, and records the ledger transaction against it.
+// It reports whether the payment was credited now.
+func settlePayment(provider string, s Settlement) (bool, error) {
+	if s.PaymentID == "" || s.UserID == "" || s.Credits <= 0 {
+		return false, fmt.Errorf("incomplete settlement")
+	}
+	paymentsMu.Lock()
+	defer paymentsMu.Unlock()
+
+	key := paymentKey(provider, s.PaymentID)
+	p, ok := payments[key]
+	if ok && p.Status == PaymentSettled {
+		return false, nil
+	}
+	if !ok {
+		p = &Payment{ID: key, Provider: provider, ExternalID: s.PaymentID, Created: time.Now()}
+		payments[key] = p
+	}
+	p.UserID, p.Amount, p.Credits = s.UserID, s.Amount, s.Credits
+
+	meta := map[string]interface{}{}
+	for k, v := range s.Metadata {
+		meta[k] = v
+	}
+	meta["provider"] = provider
+	meta["payment_id"] = s.PaymentID
+	meta["amount"] = s.Amount
+	if err := AddCredits(s.UserID, s.Credits, OpTopup, meta); err != nil {
+		return false, err
+	}
+	p.Status = PaymentSettled
+	p.Settled = time.Now()
+	p.TransactionID = paymentTransaction(s.UserID, provider, s.PaymentID)
+	savePayments()
+	return true, nil
+}
+
+// paymentTransaction finds the ledger entry that credited a payment.
+func paymentTransaction(userID, provider, externalID string) string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	txs := transactions[userID]
+	for i := len(txs) - 1; i >= 0; i-- {
+		m := txs[i].Metadata
+		if m["provider"] == provider && m["payment_id"] == externalID {
+			return txs[i].ID
+		}
+	}
+	return ""
+}
+
+// Payments returns the reconciliation records, newest first: userID's,
+// or everyone's if userID is "".
+func Payments(userID string) []*Payment {
+	paymentsMu.Lock()
+	defer paymentsMu.Unlock()
+	var out []*Payment
+	for _, p := range payments {
+		if userID == "" || p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out
+}
+
+// RenamePayments moves a renamed user's payment records to their new ID.
+// Payments aren't removed with an account: they're the record of money
+// the processors took.
+func RenamePayments(oldID, newID string) {
+	paymentsMu.Lock()
+	defer paymentsMu.Unlock()
+	changed := false
+	for _, p := range payments {
+		if p.UserID == oldID {
+			p.UserID = newID
+			changed = true
+		}
+	}
+	if changed {
+		savePayments()
+	}
+}
+
+// handleWebhook serves /wallet/webhook/{provider}.
+func handleWebhook(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/wallet/webhook/")
+	p, ok := GetProvider(name)
+	if !ok {
+		app.NotFound(w, r, "Unknown payment provider")
+		return
+	}
+	serveWebhook(w, r, p)
+}
+
+// serveWebhook reads, verifies and settles a provider's webhook.
+func serveWebhook(w http.ResponseWriter, r *http.Request, p Provider) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "read error", http.StatusBadRequest)
+		return
+	}
+	settlements, err := p.Webhook(r.Header, body)
+	if errors.Is(err, errWebhookNotConfigured) {
+		app.Log(p.Name(), "CRITICAL: webhook secret not configured, rejecting webhook")
+		http.Error(w, "webhook not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		app.Log(p.Name(), "webhook rejected: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, s := range settlements {
+		credited, err := settlePayment(p.Name(), s)
+		switch {
+		case err != nil:
+			app.Log(p.Name(), "failed to credit %s for %s: %v", s.UserID, s.PaymentID, err)
+		case credited:
+			app.Log(p.Name(), "credited %d to %s (%s)", s.Credits, s.UserID, s.PaymentID)
+		default:
+			app.Log(p.Name(), "payment %s already settled, skipping", s.PaymentID)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func btcpaySign(body, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+func TestVerifyBTCPaySignature(t *testing.T) {
+	body := []byte(`{"type":"InvoiceSettled"}`)
+	if !verifyBTCPaySignature(body, btcpaySign(string(body), "s3cret"), "s3cret") {
+		t.Error("valid signature rejected")
+	}
+	for _, sig := range []string{"", btcpaySign(string(body), "other"), strings.TrimPrefix(btcpaySign(string(body), "s3cret"), "sha256=")} {
+		if verifyBTCPaySignature(body, sig, "s3cret") {
+			t.Errorf("signature %q accepted", sig)
+		}
+	}
+}
+
+func TestProviderWebhooks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BTCPAY_WEBHOOK_SECRET", "btc-secret")
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_test")
+
+	mutex.Lock()
+	origWallets, origTx := wallets, transactions
+	wallets, transactions = map[string]*Wallet{}, map[string][]*Transaction{}
+	mutex.Unlock()
+	paymentsMu.Lock()
+	origPayments := payments
+	payments = map[string]*Payment{}
+	paymentsMu.Unlock()
+	defer func() {
+		mutex.Lock()
+		wallets, transactions = origWallets, origTx
+		mutex.Unlock()
+		paymentsMu.Lock()
+		payments = origPayments
+		paymentsMu.Unlock()
+	}()
+
+	post := func(path, body string, header map[string]string) int {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w.Code
+	}
+
+	// A BTCPay invoice made at checkout, settled by a webhook without
+	// metadata, delivered twice.
+	recordCheckout("btcpay", "inv1", "payer", 1500)
+	body := `{"type":"InvoiceSettled","invoiceId":"inv1"}`
+	if code := post("/wallet/webhook/btcpay", body, map[string]string{"BTCPay-Sig": "sha256=00"}); code != http.StatusBadRequest {
+		t.Errorf("bad signature: %d", code)
+	}
+	for i := 0; i < 2; i++ {
+		if code := post("/wallet/webhook/btcpay", body, map[string]string{"BTCPay-Sig": btcpaySign(body, "btc-secret")}); code != http.StatusOK {
+			t.Fatalf("webhook: %d", code)
+		}
+	}
+	if b := GetBalance("payer"); b != 1500 {
+		t.Errorf("balance = %d, want 1500 once", b)
+	}
+	p := Payments("payer")
+	if len(p) != 1 || !p[0].Reconciled() || p[0].TransactionID != transactions["payer"][0].ID {
+		t.Fatalf("payment record = %+v", p)
+	}
+	if got := txLabel(transactions["payer"][0]); got != "Deposit (Bitcoin)" {
+		t.Errorf("label = %q", got)
+	}
+
+	// Stripe, through both its old URL and the shared one.
+	session := `{"type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_status":"paid","amount_total":500,"metadata":{"user_id":"payer","credits":"500"}}}}`
+	ts := fmt.Sprint(time.Now().Unix())
+	sig := "t=" + ts + ",v1=" + computeHMAC(ts+"."+session, "whsec_test")
+	for _, path := range []string{"/wallet/stripe/webhook", "/wallet/webhook/stripe"} {
+		if code := post(path, session, map[string]string{"Stripe-Signature": sig}); code != http.StatusOK {
+			t.Fatalf("%s: %d", path, code)
+		}
+	}
+	if b := GetBalance("payer"); b != 2000 {
+		t.Errorf("balance after stripe = %d, want 2000", b)
+	}
+
+	if code := post("/wallet/webhook/paypal", "{}", nil); code != http.StatusNotFound {
+		t.Errorf("unknown provider: %d", code)
+	}
+	t.Setenv("BTCPAY_WEBHOOK_SECRET", "")
+	if code := post("/wallet/webhook/btcpay", body, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("unconfigured webhook: %d", code)
+	}
+}
@@ -0,0 +1,14 @@
+package wallet
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/wallet", Handler) // auth checked in the handler
+	r.HandleFunc("/wallet/", Handler)
+	r.HandleFunc("/wallet/stripe/webhook", Handler, app.SkipCSRF) // signed by Stripe
+	r.HandleFunc("/wallet/webhook/", Handler, app.SkipCSRF)       // signed by each provider
+}
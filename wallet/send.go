@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+)
+
+// /wallet/send gives credits to another member, say to cover their
+// searches. The transfer is recorded on both sides of the ledger with
+// the sender's note, and OnTransfer tells the recipient.
+
+// recentTransfers are the user's latest transfers in and out.
+func recentTransfers(userID string, n int) []historyRow {
+	var rows []historyRow
+	for _, tx := range History(userID, OpTransfer) {
+		if len(rows) == n {
+			break
+		}
+		rows = append(rows, historyRow{Date: tx.CreatedAt, Label: txLabel(tx), Amount: txAmount(tx), Balance: tx.Balance})
+	}
+	return rows
+}
+
+// handleSendPage serves the form: ?to= fills in the recipient.
+func handleSendPage(w http.ResponseWriter, r *http.Request) {
+	sess, _, err := auth.RequireSession(r)
+	if err != nil {
+		app.RedirectToLogin(w, r)
+		return
+	}
+	balance := GetBalance(sess.Account)
+	remaining := DailyTransferCap - DailyTransferTotal(sess.Account, time.Now())
+	if remaining < 0 {
+		remaining = 0
+	}
+	max := min(balance, remaining, maxTransferCredits)
+
+	var names []string
+	for _, a := range auth.GetAllAccounts() {
+		if a.ID != sess.Account {
+			names = append(names, a.Name)
+		}
+	}
+
+	q := r.URL.Query()
+	templates.Page(w, r, "Send Credits", "Send credits to another user", "send", map[string]interface{}{
+		"Balance":   balance,
+		"Cap":       DailyTransferCap,
+		"Remaining": remaining,
+		"Max":       max,
+		"To":        strings.TrimPrefix(q.Get("to"), "@"),
+		"NoteMax":   maxTransferNote,
+		"Names":     names,
+		"Recent":    recentTransfers(sess.Account, 5),
+		"Error":     q.Get("error"),
+		"Success":   q.Get("success"),
+	})
+}
@@ -0,0 +1,79 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+)
+
+func TestSendCredits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, id := range []string{"send_from", "send_to"} {
+		if err := auth.Create(&auth.Account{ID: id, Name: id, Secret: "secret", Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		defer auth.DeleteAccount(id)
+	}
+	sess, err := auth.Login("send_from", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	origWallets, origTx := wallets, transactions
+	wallets = map[string]*Wallet{"send_from": {UserID: "send_from", Balance: 300}}
+	transactions = map[string][]*Transaction{}
+	mutex.Unlock()
+	defer func() {
+		mutex.Lock()
+		wallets, transactions = origWallets, origTx
+		mutex.Unlock()
+	}()
+	var told []string
+	OnTransfer = func(from, to string, amount int, note string) { told = append(told, from+">"+to+":"+note) }
+	defer func() { OnTransfer = nil }()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		w := httptest.NewRecorder()
+		Handler(w, r)
+		return w
+	}
+
+	if w := do("POST", "/wallet/send", "to=@send_to&amount=120&note=for+your+searches"); w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/wallet/send?success=") {
+		t.Fatalf("send: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if GetBalance("send_from") != 180 || GetBalance("send_to") != 120 {
+		t.Errorf("balances %d, %d", GetBalance("send_from"), GetBalance("send_to"))
+	}
+	if len(told) != 1 || told[0] != "send_from>send_to:for your searches" {
+		t.Errorf("OnTransfer calls = %v", told)
+	}
+	if got := txLabel(transactions["send_to"][0]); got != "Transfer from send_from: for your searches" {
+		t.Errorf("recipient's entry = %q", got)
+	}
+
+	if w := do("POST", "/wallet/send", "to=send_to&amount=500"); !strings.Contains(w.Header().Get("Location"), "error=insufficient") {
+		t.Errorf("overdraft: %s", w.Header().Get("Location"))
+	}
+	if w := do("POST", "/wallet/send", "to=send_to&amount=1&note="+strings.Repeat("x", maxTransferNote+1)); !strings.Contains(w.Header().Get("Location"), "error=") {
+		t.Error("long note accepted")
+	}
+	if len(told) != 1 {
+		t.Error("failed transfers notified the recipient")
+	}
+
+	body := do("GET", "/wallet/send?to=send_to", "").Body.String()
+	if !strings.Contains(body, `value="send_to"`) || !strings.Contains(body, `max="180"`) || !strings.Contains(body, "Transfer to send_to: for your searches") {
+		t.Error("send page is missing the recipient, limit or recent transfer")
+	}
+	if w := do("GET", "/wallet/transfer", ""); w.Code != http.StatusMovedPermanently || !strings.HasPrefix(w.Header().Get("Location"), "/wallet/send") {
+		t.Errorf("old transfer page: %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
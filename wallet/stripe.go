@@ -15,9 +15,14 @@ import (
 	"mu/internal/settings"
 )
 
-var (
-	processedSessions = make(map[string]bool)
-)
+func init() {
+	settings.Register("Payments",
+		settings.Var{Key: "STRIPE_SECRET_KEY", Secret: true, Doc: "Stripe secret key for card payments"},
+		settings.Var{Key: "STRIPE_PUBLISHABLE_KEY", Doc: "Stripe publishable key"},
+		settings.Var{Key: "STRIPE_WEBHOOK_SECRET", Secret: true, Doc: "Stripe webhook signing secret"},
+		settings.Var{Key: "X402_PAY_TO", Doc: "Wallet address x402 payments go to"},
+	)
+}
 
 func stripeSecret() string  { return settings.Get("STRIPE_SECRET_KEY") }
 func stripePublic() string  { return settings.Get("STRIPE_PUBLISHABLE_KEY") }
@@ -139,12 +144,18 @@ var StripeTopupTiers = []StripeTopupTier{
 
 // CreateCheckoutSession creates a Stripe Checkout Session for topup
 func CreateCheckoutSession(userID string, amount int, successURL, cancelURL string) (string, error) {
+	url, _, err := createCheckoutSession(userID, amount, successURL, cancelURL)
+	return url, err
+}
+
+// createCheckoutSession returns the checkout page and the session ID.
+func createCheckoutSession(userID string, amount int, successURL, cancelURL string) (string, string, error) {
 	if !StripeEnabled() {
-		return "", fmt.Errorf("stripe not configured")
+		return "", "", fmt.Errorf("stripe not configured")
 	}
 
 	if amount < 100 {
-		return "", fmt.Errorf("minimum top-up is £1")
+		return "", "", fmt.Errorf("minimum top-up is £1")
 	}
 
 	// Flat rate: 1 pence = 1 credit
@@ -180,7 +191,7 @@ func CreateCheckoutSession(userID string, amount int, successURL, cancelURL stri
 
 	req, err := http.NewRequest("POST", "https://api.stripe.com/v1/checkout/sessions", strings.NewReader(formData))
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	req.SetBasicAuth(stripeSecret(), "")
@@ -189,7 +200,7 @@ func CreateCheckoutSession(userID string, amount int, successURL, cancelURL stri
 	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer resp.Body.Close()
 
@@ -197,7 +208,7 @@ func CreateCheckoutSession(userID string, amount int, successURL, cancelURL stri
 
 	if resp.StatusCode != 200 {
 		app.Log("stripe", "checkout session error: %s", string(body))
-		return "", fmt.Errorf("stripe error: %s", resp.Status)
+		return "", "", fmt.Errorf("stripe error: %s", resp.Status)
 	}
 
 	var result struct {
@@ -205,11 +216,11 @@ func CreateCheckoutSession(userID string, amount int, successURL, cancelURL stri
 		URL string `json:"url"`
 	}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	app.Log("stripe", "created checkout session %s for user %s, %d credits", result.ID, userID, credits)
-	return result.URL, nil
+	return result.URL, result.ID, nil
 }
 
 // jsonToForm converts nested JSON to Stripe's form-urlencoded format
@@ -262,33 +273,34 @@ func urlEncode(s string) string {
 	return s
 }
 
-// HandleStripeWebhook processes Stripe webhook events
+// HandleStripeWebhook serves /wallet/stripe/webhook, where Stripe was
+// pointed before /wallet/webhook/stripe.
 func HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	serveWebhook(w, r, stripeProvider{})
+}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "read error", http.StatusBadRequest)
-		return
-	}
+// stripeProvider takes card payments through Stripe Checkout.
+type stripeProvider struct{}
+
+func (stripeProvider) Name() string  { return "stripe" }
+func (stripeProvider) Label() string { return "Card" }
+func (stripeProvider) Enabled() bool { return StripeEnabled() }
+
+func (stripeProvider) Checkout(userID string, amount int, successURL, cancelURL string) (string, string, error) {
+	return createCheckoutSession(userID, amount, successURL+"?session_id={CHECKOUT_SESSION_ID}", cancelURL)
+}
 
+// Webhook settles paid checkout sessions, and subscription invoices each
+// month they renew.
+func (stripeProvider) Webhook(header http.Header, body []byte) ([]Settlement, error) {
 	// Verify webhook signature — REQUIRED for security
 	if stripeWebhook() == "" {
-		app.Log("stripe", "CRITICAL: STRIPE_WEBHOOK_SECRET not configured, rejecting webhook")
-		http.Error(w, "webhook not configured", http.StatusServiceUnavailable)
-		return
+		return nil, errWebhookNotConfigured
 	}
-	sig := r.Header.Get("Stripe-Signature")
-	if !verifyStripeSignature(body, sig, stripeWebhook()) {
-		app.Log("stripe", "webhook signature verification failed")
-		http.Error(w, "invalid signature", http.StatusBadRequest)
-		return
+	if !verifyStripeSignature(body, header.Get("Stripe-Signature"), stripeWebhook()) {
+		return nil, fmt.Errorf("invalid signature")
 	}
 
-	// Parse event
 	var event struct {
 		Type string `json:"type"`
 		Data struct {
@@ -296,15 +308,12 @@ func HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
 		} `json:"data"`
 	}
 	if err := json.Unmarshal(body, &event); err != nil {
-		app.Log("stripe", "webhook parse error: %v", err)
-		http.Error(w, "parse error", http.StatusBadRequest)
-		return
+		return nil, fmt.Errorf("parse error: %v", err)
 	}
-
 	app.Log("stripe", "webhook received: %s", event.Type)
 
-	// Handle checkout.session.completed
-	if event.Type == "checkout.session.completed" {
+	switch event.Type {
+	case "checkout.session.completed":
 		var session struct {
 			ID            string `json:"id"`
 			PaymentStatus string `json:"payment_status"`
@@ -315,46 +324,25 @@ func HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
 			AmountTotal int `json:"amount_total"`
 		}
 		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
-			app.Log("stripe", "session parse error: %v", err)
-			http.Error(w, "parse error", http.StatusBadRequest)
-			return
+			return nil, fmt.Errorf("session parse error: %v", err)
 		}
-
-		if session.PaymentStatus == "paid" {
-			// Check for duplicate processing
-			mutex.Lock()
-			if processedSessions[session.ID] {
-				mutex.Unlock()
-				app.Log("stripe", "session %s already processed, skipping", session.ID)
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			processedSessions[session.ID] = true
-			mutex.Unlock()
-
-			userID := session.Metadata.UserID
-			var credits int
-			fmt.Sscanf(session.Metadata.Credits, "%d", &credits)
-
-			if userID != "" && credits > 0 {
-				err := AddCredits(userID, credits, OpTopup, map[string]interface{}{
-					"source":     "stripe",
-					"session_id": session.ID,
-					"amount":     session.AmountTotal,
-				})
-				if err != nil {
-					app.Log("stripe", "failed to credit user %s: %v", userID, err)
-				} else {
-					app.Log("stripe", "credited %d to user %s (session %s)", credits, userID, session.ID)
-				}
-			}
+		var credits int
+		fmt.Sscanf(session.Metadata.Credits, "%d", &credits)
+		if session.PaymentStatus != "paid" || session.Metadata.UserID == "" || credits <= 0 {
+			return nil, nil
 		}
-	}
-
-	// Handle invoice.payment_succeeded — subscription renewal credits.
-	if event.Type == "invoice.payment_succeeded" {
+		return []Settlement{{
+			PaymentID: session.ID,
+			UserID:    session.Metadata.UserID,
+			Amount:    session.AmountTotal,
+			Credits:   credits,
+			Metadata:  map[string]interface{}{"source": "stripe", "session_id": session.ID},
+		}}, nil
+
+	case "invoice.payment_succeeded":
 		var invoice struct {
 			ID               string `json:"id"`
+			AmountPaid       int    `json:"amount_paid"`
 			SubscriptionData struct {
 				Metadata struct {
 					UserID  string `json:"user_id"`
@@ -365,42 +353,28 @@ func HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
 			Subscription string `json:"subscription"`
 		}
 		if err := json.Unmarshal(event.Data.Object, &invoice); err != nil {
-			app.Log("stripe", "invoice parse error: %v", err)
-			http.Error(w, "parse error", http.StatusBadRequest)
-			return
+			return nil, fmt.Errorf("invoice parse error: %v", err)
 		}
-
-		userID := invoice.SubscriptionData.Metadata.UserID
+		meta := invoice.SubscriptionData.Metadata
 		var credits int
-		fmt.Sscanf(invoice.SubscriptionData.Metadata.Credits, "%d", &credits)
-
-		if userID != "" && credits > 0 {
-			// Dedup by invoice ID.
-			mutex.Lock()
-			if processedSessions[invoice.ID] {
-				mutex.Unlock()
-				app.Log("stripe", "invoice %s already processed, skipping", invoice.ID)
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			processedSessions[invoice.ID] = true
-			mutex.Unlock()
-
-			err := AddCredits(userID, credits, OpTopup, map[string]interface{}{
+		fmt.Sscanf(meta.Credits, "%d", &credits)
+		if meta.UserID == "" || credits <= 0 {
+			return nil, nil
+		}
+		return []Settlement{{
+			PaymentID: invoice.ID,
+			UserID:    meta.UserID,
+			Amount:    invoice.AmountPaid,
+			Credits:   credits,
+			Metadata: map[string]interface{}{
 				"source":       "stripe_subscription",
 				"invoice_id":   invoice.ID,
 				"subscription": invoice.Subscription,
-				"plan":         invoice.SubscriptionData.Metadata.PlanID,
-			})
-			if err != nil {
-				app.Log("stripe", "failed to credit subscriber %s: %v", userID, err)
-			} else {
-				app.Log("stripe", "subscription: credited %d to %s (invoice %s)", credits, userID, invoice.ID)
-			}
-		}
+				"plan":         meta.PlanID,
+			},
+		}}, nil
 	}
-
-	w.WriteHeader(http.StatusOK)
+	return nil, nil
 }
 
 // verifyStripeSignature verifies the Stripe webhook signature
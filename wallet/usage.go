@@ -0,0 +1,137 @@
+package wallet
+
+import (
+	"sort"
+	"time"
+)
+
+// Usage reports sum the ledger for the admin usage dashboard: what
+// members used, what it cost them, and how many credits came in. Uses
+// covered by the daily quota or an admin account are in the ledger at
+// no charge, so they count as uses but not as credits spent.
+
+// OperationUsage is one operation's total over a report's window.
+type OperationUsage struct {
+	Operation string `json:"operation"`
+	Label     string `json:"label"`
+	Uses      int    `json:"uses"`
+	Paid      int    `json:"paid"`    // uses that cost credits
+	Credits   int    `json:"credits"` // credits spent
+	Users     int    `json:"users"`
+}
+
+// UserUsage is one account's consumption over a report's window.
+type UserUsage struct {
+	UserID  string         `json:"user_id"`
+	Uses    int            `json:"uses"`
+	Credits int            `json:"credits"` // credits spent
+	Issued  int            `json:"issued"`  // credits bought or granted
+	Ops     map[string]int `json:"operations"`
+}
+
+// TopOperation is the operation the user used most.
+func (u *UserUsage) TopOperation() string {
+	top, n := "", 0
+	for op, c := range u.Ops {
+		if c > n || (c == n && op < top) {
+			top, n = op, c
+		}
+	}
+	return operationLabel(top)
+}
+
+// UsageReport is the ledger summed from Since, or over all time if Since
+// is zero.
+type UsageReport struct {
+	Since       time.Time         `json:"since"`
+	Issued      int               `json:"issued"`      // top-ups and admin grants
+	Spent       int               `json:"spent"`       // charged for operations
+	Transferred int               `json:"transferred"` // moved between members
+	Uses        int               `json:"uses"`
+	Operations  []*OperationUsage `json:"operations"` // most credits first
+	Users       []*UserUsage      `json:"users"`      // most credits first
+}
+
+// internalOperations move credits between accounts without any coming
+// in or being used up.
+var internalOperations = map[string]bool{
+	OpEscrowHold:    true,
+	OpEscrowRelease: true,
+	OpEscrowRefund:  true,
+	OpAppRevenue:    true,
+}
+
+// Usage sums every account's ledger from since.
+func Usage(since time.Time) *UsageReport {
+	report := &UsageReport{Since: since}
+	ops := map[string]*OperationUsage{}
+	opUsers := map[string]map[string]bool{}
+
+	mutex.RLock()
+	for userID, txs := range transactions {
+		u := &UserUsage{UserID: userID, Ops: map[string]int{}}
+		for _, tx := range txs {
+			if tx == nil || tx.CreatedAt.Before(since) || internalOperations[tx.Operation] {
+				continue
+			}
+			switch {
+			case tx.Type == TxTransfer:
+				if tx.Amount > 0 {
+					report.Transferred += tx.Amount
+				}
+			case tx.Type == TxTopup:
+				report.Issued += tx.Amount
+				u.Issued += tx.Amount
+			case tx.Type == TxSpend:
+				o, ok := ops[tx.Operation]
+				if !ok {
+					o = &OperationUsage{Operation: tx.Operation, Label: operationLabel(tx.Operation)}
+					ops[tx.Operation] = o
+					opUsers[tx.Operation] = map[string]bool{}
+				}
+				cost := -tx.Amount
+				o.Uses++
+				if cost > 0 {
+					o.Paid++
+					o.Credits += cost
+				}
+				opUsers[tx.Operation][userID] = true
+				u.Uses++
+				u.Credits += cost
+				u.Ops[tx.Operation]++
+				report.Uses++
+				report.Spent += cost
+			}
+		}
+		if u.Uses > 0 || u.Issued > 0 {
+			report.Users = append(report.Users, u)
+		}
+	}
+	mutex.RUnlock()
+
+	for op, o := range ops {
+		o.Users = len(opUsers[op])
+		report.Operations = append(report.Operations, o)
+	}
+	sort.Slice(report.Operations, func(i, j int) bool {
+		a, b := report.Operations[i], report.Operations[j]
+		if a.Credits != b.Credits {
+			return a.Credits > b.Credits
+		}
+		if a.Uses != b.Uses {
+			return a.Uses > b.Uses
+		}
+		return a.Operation < b.Operation
+	})
+	sort.Slice(report.Users, func(i, j int) bool {
+		a, b := report.Users[i], report.Users[j]
+		if a.Credits != b.Credits {
+			return a.Credits > b.Credits
+		}
+		if a.Uses != b.Uses {
+			return a.Uses > b.Uses
+		}
+		return a.UserID < b.UserID
+	})
+	return report
+}
@@ -0,0 +1,53 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+	mutex.Lock()
+	origTx := transactions
+	transactions = map[string][]*Transaction{
+		"heavy": {
+			{Type: TxTopup, Amount: 500, Operation: OpTopup, CreatedAt: now},
+			{Type: TxSpend, Amount: -5, Operation: OpPlacesSearch, CreatedAt: now},
+			{Type: TxSpend, Amount: -5, Operation: OpPlacesSearch, CreatedAt: now},
+			{Type: TxSpend, Amount: -3, Operation: OpChatQuery, CreatedAt: now},
+			{Type: TxSpend, Amount: -50, Operation: OpEscrowHold, CreatedAt: now},
+			{Type: TxTransfer, Amount: -20, Operation: OpTransfer, CreatedAt: now},
+			{Type: TxSpend, Amount: -99, Operation: OpChatQuery, CreatedAt: old},
+		},
+		"light": {
+			{Type: TxSpend, Amount: 0, Operation: OpChatQuery, CreatedAt: now},
+			{Type: TxTransfer, Amount: 20, Operation: OpTransfer, CreatedAt: now},
+			{Type: TxTopup, Amount: 40, Operation: OpAppRevenue, CreatedAt: now},
+		},
+	}
+	mutex.Unlock()
+	defer func() {
+		mutex.Lock()
+		transactions = origTx
+		mutex.Unlock()
+	}()
+
+	r := Usage(now.Add(-7 * 24 * time.Hour))
+	if r.Issued != 500 || r.Spent != 13 || r.Transferred != 20 || r.Uses != 4 {
+		t.Errorf("issued %d, spent %d, transferred %d, uses %d", r.Issued, r.Spent, r.Transferred, r.Uses)
+	}
+	if len(r.Operations) != 2 || r.Operations[0].Operation != OpPlacesSearch || r.Operations[0].Credits != 10 {
+		t.Fatalf("operations = %+v", r.Operations)
+	}
+	if chat := r.Operations[1]; chat.Uses != 2 || chat.Paid != 1 || chat.Users != 2 {
+		t.Errorf("chat = %+v", chat)
+	}
+	if len(r.Users) != 2 || r.Users[0].UserID != "heavy" || r.Users[0].TopOperation() != "Places search" {
+		t.Errorf("users = %+v", r.Users)
+	}
+
+	if all := Usage(time.Time{}); all.Spent != 112 {
+		t.Errorf("all-time spent = %d", all.Spent)
+	}
+}
@@ -40,6 +40,8 @@ var (
 	CostImageGenerate     = getEnvInt("CREDIT_COST_IMAGE", 15)
 	CostAppBuild          = getEnvInt("CREDIT_COST_APP_BUILD", 100)
 	CostAppEdit           = getEnvInt("CREDIT_COST_APP_EDIT", 50)
+	CostThreadSummary     = getEnvInt("CREDIT_COST_SUMMARY", 3)
+	CostDailyBriefing     = getEnvInt("CREDIT_COST_BRIEFING", 3)
 	DailyQuota            = getEnvInt("DAILY_QUOTA", getEnvInt("FREE_DAILY_QUOTA", 100))
 )
 
@@ -48,7 +50,7 @@ const DailyTransferCap = 10000
 // PaymentsEnabled returns true if payments are configured
 // When false, quotas are disabled (self-hosted, no restrictions)
 func PaymentsEnabled() bool {
-	return StripeEnabled() || X402Enabled()
+	return StripeEnabled() || BTCPayEnabled() || X402Enabled()
 }
 
 // Operation types
@@ -75,6 +77,8 @@ const (
 	OpBlogComment       = "blog_comment"
 	OpAppBuild          = "app_build"
 	OpAppEdit           = "app_edit"
+	OpThreadSummary     = "thread_summary"
+	OpDailyBriefing     = "daily_briefing"
 	OpAppUse            = "app_use"
 	OpAppRevenue        = "app_revenue"
 	OpTopup             = "topup"
@@ -143,7 +147,10 @@ func init() {
 
 // Load initializes wallet
 func Load() {
-	// Wallet loaded
+	data.RegisterExporter(exporter{})
+	data.RegisterExporter(givingExporter{})
+	loadGiving()
+	loadPayments()
 }
 
 // getEnvInt gets an environment variable as int with default
@@ -287,6 +294,26 @@ func DailyTransferTotal(userID string, day time.Time) int {
 
 // TransferCredits transfers credits from one user to another
 func TransferCredits(fromUserID, toUserID string, amount int) error {
+	return SendCredits(fromUserID, toUserID, amount, "")
+}
+
+// OnTransfer is called after credits move between accounts, to tell the
+// recipient. main.go wires it to notifications.
+var OnTransfer func(fromUserID, toUserID string, amount int, note string)
+
+// SendCredits transfers credits with a note for the recipient, which both
+// ledger entries keep, and calls OnTransfer.
+func SendCredits(fromUserID, toUserID string, amount int, note string) error {
+	if err := transferCredits(fromUserID, toUserID, amount, note); err != nil {
+		return err
+	}
+	if OnTransfer != nil {
+		OnTransfer(fromUserID, toUserID, amount, note)
+	}
+	return nil
+}
+
+func transferCredits(fromUserID, toUserID string, amount int, note string) error {
 	if amount <= 0 {
 		return errors.New("amount must be positive")
 	}
@@ -339,6 +366,11 @@ func TransferCredits(fromUserID, toUserID string, amount int) error {
 
 	now := time.Now()
 	txID := uuid.New().String()
+	sent := map[string]interface{}{"to": toUserID}
+	received := map[string]interface{}{"from": fromUserID}
+	if note != "" {
+		sent["note"], received["note"] = note, note
+	}
 
 	// Record sender transaction
 	senderTx := &Transaction{
@@ -348,7 +380,7 @@ func TransferCredits(fromUserID, toUserID string, amount int) error {
 		Amount:    -amount,
 		Balance:   sender.Balance,
 		Operation: OpTransfer,
-		Metadata:  map[string]interface{}{"to": toUserID},
+		Metadata:  sent,
 		CreatedAt: now,
 	}
 	transactions[fromUserID] = append(transactions[fromUserID], senderTx)
@@ -361,7 +393,7 @@ func TransferCredits(fromUserID, toUserID string, amount int) error {
 		Amount:    amount,
 		Balance:   receiver.Balance,
 		Operation: OpTransfer,
-		Metadata:  map[string]interface{}{"from": fromUserID},
+		Metadata:  received,
 		CreatedAt: now,
 	}
 	transactions[toUserID] = append(transactions[toUserID], receiverTx)
@@ -512,6 +544,10 @@ func GetOperationCost(operation string) int {
 		return CostAppBuild
 	case OpAppEdit:
 		return CostAppEdit
+	case OpThreadSummary:
+		return CostThreadSummary
+	case OpDailyBriefing:
+		return CostDailyBriefing
 	default:
 		return 1
 	}
@@ -585,8 +621,9 @@ func ConsumeQuota(userID string, operation string) error {
 		return errors.New("account not found")
 	}
 
-	// Admins get unlimited access but usage is tracked
-	if acc.Admin {
+	// Admins get unlimited access but usage is tracked, as does everyone
+	// when payments aren't configured (CheckQuota lets them through).
+	if acc.Admin || !PaymentsEnabled() {
 		RecordUsage(userID, operation)
 		return nil
 	}
@@ -797,6 +834,35 @@ func FormatCredits(credits int) string {
 	return fmt.Sprintf("£%d.%02d", pounds, pence)
 }
 
+// RenameWallet moves a renamed user's balance, transaction history and
+// today's usage to their new ID.
+func RenameWallet(oldID, newID string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if w, ok := wallets[oldID]; ok {
+		w.UserID = newID
+		delete(wallets, oldID)
+		wallets[newID] = w
+	}
+	if txs, ok := transactions[oldID]; ok {
+		for _, tx := range txs {
+			tx.UserID = newID
+		}
+		delete(transactions, oldID)
+		transactions[newID] = txs
+	}
+	for k, u := range dailyUsage {
+		if u.UserID == oldID {
+			delete(dailyUsage, k)
+			u.UserID = newID
+			dailyUsage[newID+":"+u.Date] = u
+		}
+	}
+	data.SaveJSON("wallets.json", wallets)
+	data.SaveJSON("transactions.json", transactions)
+	data.SaveJSON("daily_usage.json", dailyUsage)
+}
+
 // DeleteWallet removes a user's wallet and transaction history.
 func DeleteWallet(userID string) {
 	mutex.Lock()
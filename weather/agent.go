@@ -14,7 +14,7 @@ func ForecastText(lat, lon float64) string {
 		return "Weather is unavailable because the requested coordinates are invalid."
 	}
 
-	wf, err := FetchWeather(lat, lon)
+	wf, err := Forecast(lat, lon)
 	if err != nil || wf == nil {
 		return weatherUnavailableMessage
 	}
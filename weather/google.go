@@ -201,12 +201,13 @@ type googlePollenTypeInfo struct {
 	HealthRecommendations []string `json:"healthRecommendations"`
 }
 
-// FetchWeather retrieves weather forecast from the Google Weather API.
-// Returns an error when GOOGLE_API_KEY is not set.
+// FetchWeather retrieves weather forecast from the Google Weather API,
+// or from the keyless providers when GOOGLE_API_KEY is not set or Google
+// fails.
 func FetchWeather(lat, lon float64) (*WeatherForecast, error) {
 	key := googleAPIKey()
 	if key == "" {
-		return fetchNWSWeather(lat, lon)
+		return fetchKeylessWeather(lat, lon)
 	}
 
 	// Fetch daily forecast (10 days)
@@ -311,13 +312,23 @@ func FetchWeather(lat, lon float64) (*WeatherForecast, error) {
 }
 
 func fetchNWSWeatherFallback(lat, lon float64, originalErr error) (*WeatherForecast, error) {
-	wf, err := fetchNWSWeather(lat, lon)
+	wf, err := fetchKeylessWeather(lat, lon)
 	if err == nil {
 		return wf, nil
 	}
 	return nil, originalErr
 }
 
+// fetchKeylessWeather tries Open-Meteo, which covers everywhere, then
+// the National Weather Service, which covers the US.
+func fetchKeylessWeather(lat, lon float64) (*WeatherForecast, error) {
+	wf, err := fetchOpenMeteo(lat, lon)
+	if err == nil {
+		return wf, nil
+	}
+	return fetchNWSWeather(lat, lon)
+}
+
 func fetchNWSWeather(lat, lon float64) (*WeatherForecast, error) {
 	pointsURL := fmt.Sprintf("%s/%0.4f,%0.4f", nwsBaseURL, lat, lon)
 	pointsResp, err := weatherGet(pointsURL, "nws_points")
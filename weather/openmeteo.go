@@ -0,0 +1,202 @@
+package weather
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Open-Meteo needs no key and covers the whole world, so it's the
+// forecast when no Google key is set. It gives a week of days.
+const openMeteoForecastURL = "https://api.open-meteo.com/v1/forecast"
+
+var openMeteoBaseURL = openMeteoForecastURL
+
+type openMeteoResponse struct {
+	UTCOffsetSeconds int `json:"utc_offset_seconds"`
+	Current          *struct {
+		Time        string   `json:"time"`
+		Temperature float64  `json:"temperature_2m"`
+		FeelsLike   float64  `json:"apparent_temperature"`
+		Humidity    *int     `json:"relative_humidity_2m"`
+		Wind        *float64 `json:"wind_speed_10m"`
+		Code        int      `json:"weather_code"`
+	} `json:"current"`
+	Hourly struct {
+		Time          []string  `json:"time"`
+		Temperature   []float64 `json:"temperature_2m"`
+		Code          []int     `json:"weather_code"`
+		Precipitation []float64 `json:"precipitation"`
+	} `json:"hourly"`
+	Daily struct {
+		Time          []string  `json:"time"`
+		Code          []int     `json:"weather_code"`
+		Max           []float64 `json:"temperature_2m_max"`
+		Min           []float64 `json:"temperature_2m_min"`
+		Precipitation []float64 `json:"precipitation_sum"`
+		Probability   []int     `json:"precipitation_probability_max"`
+	} `json:"daily"`
+}
+
+// fetchOpenMeteo gets current conditions, the next 24 hours and 7 days
+// from Open-Meteo.
+func fetchOpenMeteo(lat, lon float64) (*WeatherForecast, error) {
+	apiURL := fmt.Sprintf("%s?latitude=%.4f&longitude=%.4f"+
+		"&current=temperature_2m,apparent_temperature,relative_humidity_2m,wind_speed_10m,weather_code"+
+		"&hourly=temperature_2m,weather_code,precipitation&forecast_hours=24"+
+		"&daily=weather_code,temperature_2m_max,temperature_2m_min,precipitation_sum,precipitation_probability_max"+
+		"&forecast_days=7&timezone=auto", openMeteoBaseURL, lat, lon)
+	body, err := weatherGet(apiURL, "open_meteo")
+	if err != nil {
+		return nil, err
+	}
+	var om openMeteoResponse
+	if err := json.Unmarshal(body, &om); err != nil {
+		return nil, fmt.Errorf("failed to parse Open-Meteo response: %w", err)
+	}
+
+	// Times come in the place's local time, without a zone.
+	zone := time.FixedZone("", om.UTCOffsetSeconds)
+	localTime := func(s string) (time.Time, bool) {
+		t, err := time.ParseInLocation("2006-01-02T15:04", s, zone)
+		return t.UTC(), err == nil
+	}
+
+	wf := &WeatherForecast{Source: "Open-Meteo", GeneratedAt: time.Now().UTC()}
+	if c := om.Current; c != nil {
+		if t, ok := localTime(c.Time); ok {
+			wf.ObservedAt = t
+		}
+		wf.Current = &CurrentConditions{
+			TempC:       c.Temperature,
+			FeelsLikeC:  c.FeelsLike,
+			Description: wmoDescription(c.Code),
+			IconCode:    wmoIcon(c.Code),
+		}
+		if c.Humidity != nil {
+			wf.Current.Humidity = *c.Humidity
+			wf.Current.HumidityAvailable = true
+		}
+		if c.Wind != nil {
+			wf.Current.WindKph = *c.Wind
+			wf.Current.WindKphAvailable = true
+		}
+	}
+
+	h := om.Hourly
+	for i, s := range h.Time {
+		t, ok := localTime(s)
+		if !ok || i >= len(h.Temperature) {
+			continue
+		}
+		item := HourlyItem{Time: t, TempC: h.Temperature[i]}
+		if i < len(h.Code) {
+			item.Description = wmoDescription(h.Code[i])
+			item.IconCode = wmoIcon(h.Code[i])
+		}
+		if i < len(h.Precipitation) {
+			item.PrecipMM = h.Precipitation[i]
+		}
+		wf.HourlyItems = append(wf.HourlyItems, item)
+	}
+
+	d := om.Daily
+	for i, s := range d.Time {
+		date, err := time.Parse("2006-01-02", s)
+		if err != nil || i >= len(d.Max) || i >= len(d.Min) {
+			continue
+		}
+		item := DailyItem{Date: date, MaxTempC: d.Max[i], MinTempC: d.Min[i]}
+		if i < len(d.Code) {
+			item.Description = wmoDescription(d.Code[i])
+		}
+		if i < len(d.Precipitation) {
+			item.RainMM = d.Precipitation[i]
+		}
+		if i < len(d.Probability) {
+			item.WillRain = d.Probability[i] >= 30
+		}
+		wf.DailyItems = append(wf.DailyItems, item)
+	}
+
+	if wf.Current == nil && len(wf.DailyItems) == 0 {
+		return nil, fmt.Errorf("Open-Meteo response had no forecast")
+	}
+	return wf, nil
+}
+
+// wmoDescription describes a WMO weather interpretation code.
+func wmoDescription(code int) string {
+	switch code {
+	case 0:
+		return "Clear sky"
+	case 1:
+		return "Mainly clear"
+	case 2:
+		return "Partly cloudy"
+	case 3:
+		return "Overcast"
+	case 45, 48:
+		return "Fog"
+	case 51, 53, 55:
+		return "Drizzle"
+	case 56, 57:
+		return "Freezing drizzle"
+	case 61:
+		return "Light rain"
+	case 63:
+		return "Rain"
+	case 65:
+		return "Heavy rain"
+	case 66, 67:
+		return "Freezing rain"
+	case 71:
+		return "Light snow"
+	case 73:
+		return "Snow"
+	case 75:
+		return "Heavy snow"
+	case 77:
+		return "Snow grains"
+	case 80, 81:
+		return "Rain showers"
+	case 82:
+		return "Heavy showers"
+	case 85, 86:
+		return "Snow showers"
+	case 95:
+		return "Thunderstorm"
+	case 96, 99:
+		return "Thunderstorm with hail"
+	}
+	return ""
+}
+
+// wmoIcon maps a WMO code to the condition types the page has icons for.
+func wmoIcon(code int) string {
+	switch {
+	case code == 0:
+		return "CLEAR"
+	case code == 1:
+		return "MOSTLY_CLEAR"
+	case code == 2:
+		return "PARTLY_CLOUDY"
+	case code == 3:
+		return "CLOUDY"
+	case code == 45 || code == 48:
+		return "FOG"
+	case code >= 51 && code <= 57:
+		return "DRIZZLE"
+	case code == 65 || code == 82:
+		return "HEAVY_RAIN"
+	case code == 66 || code == 67:
+		return "FREEZING_RAIN"
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "SNOW"
+	case code >= 61 && code <= 82:
+		return "RAIN"
+	case code >= 95:
+		return "THUNDERSTORM"
+	}
+	return ""
+}
@@ -0,0 +1,434 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mu/internal/app"
+	"mu/internal/auth"
+	"mu/internal/data"
+)
+
+// Each account can save a place for its weather. The home card and
+// /weather show that place's forecast without asking the browser where
+// it is, and its current conditions are indexed, privately, so the
+// agent can answer "what's the weather". Forecasts are cached by spot,
+// so everyone in a town shares one fetch.
+
+// Geocode resolves a place name to coordinates. main wires it to places,
+// which weather doesn't import.
+var Geocode func(query string) (name string, lat, lon float64, err error)
+
+// Place is where an account wants its weather.
+type Place struct {
+	Name    string    `json:"name"`
+	Lat     float64   `json:"lat"`
+	Lon     float64   `json:"lon"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+const (
+	forecastTTL     = 30 * time.Minute
+	maxPlaceNameLen = 100
+)
+
+var (
+	placesMu    sync.Mutex
+	savedPlaces = map[string]*Place{} // account ID → place
+
+	forecastMu sync.Mutex
+	forecasts  = map[string]*cachedForecast{} // cacheKey → forecast
+)
+
+type cachedForecast struct {
+	forecast *WeatherForecast
+	fetched  time.Time
+}
+
+func loadPlaces() {
+	var p map[string]*Place
+	if err := data.LoadJSON("weather_places.json", &p); err == nil && p != nil {
+		placesMu.Lock()
+		savedPlaces = p
+		placesMu.Unlock()
+	}
+}
+
+// savePlaces persists every account's place. Caller must hold placesMu.
+func savePlaces() error {
+	return data.SaveJSON("weather_places.json", savedPlaces)
+}
+
+// SavedPlace returns a copy of the account's place, or nil if it hasn't
+// saved one.
+func SavedPlace(userID string) *Place {
+	placesMu.Lock()
+	defer placesMu.Unlock()
+	p, ok := savedPlaces[userID]
+	if !ok {
+		return nil
+	}
+	cp := *p
+	return &cp
+}
+
+// SetPlace saves the account's place.
+func SetPlace(userID string, p *Place) error {
+	if !validCoordinates(p.Lat, p.Lon) {
+		return errors.New("coordinates are out of range")
+	}
+	cp := *p
+	cp.SavedAt = time.Now()
+	placesMu.Lock()
+	defer placesMu.Unlock()
+	savedPlaces[userID] = &cp
+	return savePlaces()
+}
+
+// DeletePlace forgets an account's place and unindexes its weather.
+// Called when the account is deleted.
+func DeletePlace(userID string) {
+	placesMu.Lock()
+	defer placesMu.Unlock()
+	if _, ok := savedPlaces[userID]; !ok {
+		return
+	}
+	delete(savedPlaces, userID)
+	data.Unindex(indexID(userID))
+	if err := savePlaces(); err != nil {
+		app.Log("weather", "Saving places: %v", err)
+	}
+}
+
+// RenamePlace moves a renamed account's place to its new ID.
+func RenamePlace(oldID, newID string) {
+	placesMu.Lock()
+	defer placesMu.Unlock()
+	p, ok := savedPlaces[oldID]
+	if !ok {
+		return
+	}
+	savedPlaces[newID] = p
+	delete(savedPlaces, oldID)
+	data.Unindex(indexID(oldID))
+	if err := savePlaces(); err != nil {
+		app.Log("weather", "Saving places: %v", err)
+	}
+}
+
+// parsePlace reads a place from a form, geocoding the name if no
+// coordinates came with it.
+func parsePlace(get func(string) string) (*Place, error) {
+	p := &Place{Name: strings.TrimSpace(get("location"))}
+	if len(p.Name) > maxPlaceNameLen {
+		p.Name = p.Name[:maxPlaceNameLen]
+	}
+	lat, latErr := strconv.ParseFloat(get("lat"), 64)
+	lon, lonErr := strconv.ParseFloat(get("lon"), 64)
+	switch {
+	case latErr == nil && lonErr == nil:
+		if !validCoordinates(lat, lon) {
+			return nil, errors.New("coordinates are out of range")
+		}
+		p.Lat, p.Lon = lat, lon
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("%.3f, %.3f", lat, lon)
+		}
+	case p.Name != "":
+		if Geocode == nil {
+			return nil, errors.New("looking up places isn't available; use your device's location")
+		}
+		name, lat, lon, err := Geocode(p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find %q", p.Name)
+		}
+		p.Name, p.Lat, p.Lon = name, lat, lon
+	default:
+		return nil, errors.New("a location is required")
+	}
+	return p, nil
+}
+
+// cacheKey rounds coordinates to about a kilometre, which is finer than
+// any forecast.
+func cacheKey(lat, lon float64) string {
+	return fmt.Sprintf("%.2f,%.2f", lat, lon)
+}
+
+// cached returns a forecast fetched for the spot in the last forecastTTL,
+// or nil.
+func cached(lat, lon float64) *WeatherForecast {
+	forecastMu.Lock()
+	defer forecastMu.Unlock()
+	c, ok := forecasts[cacheKey(lat, lon)]
+	if !ok || time.Since(c.fetched) > forecastTTL {
+		return nil
+	}
+	return c.forecast
+}
+
+// Forecast returns the forecast for a spot, from the cache if it was
+// fetched recently. The forecast is shared; don't change it.
+func Forecast(lat, lon float64) (*WeatherForecast, error) {
+	if wf := cached(lat, lon); wf != nil {
+		return wf, nil
+	}
+	wf, err := FetchWeather(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	forecastMu.Lock()
+	now := time.Now()
+	for k, c := range forecasts {
+		if now.Sub(c.fetched) > forecastTTL {
+			delete(forecasts, k)
+		}
+	}
+	forecasts[cacheKey(lat, lon)] = &cachedForecast{forecast: wf, fetched: now}
+	forecastMu.Unlock()
+	return wf, nil
+}
+
+// PlaceForecast returns the forecast for the account's saved place and
+// indexes its current conditions for the account. It returns nil, nil
+// without a saved place.
+func PlaceForecast(userID string) (*Place, *WeatherForecast, error) {
+	p := SavedPlace(userID)
+	if p == nil {
+		return nil, nil, nil
+	}
+	fresh := cached(p.Lat, p.Lon) == nil
+	wf, err := Forecast(p.Lat, p.Lon)
+	if err != nil {
+		return p, nil, err
+	}
+	if fresh {
+		indexConditions(userID, p, wf)
+	}
+	return p, wf, nil
+}
+
+func indexID(userID string) string {
+	return "weather_" + userID
+}
+
+// indexConditions indexes the weather at the account's place, private
+// to it.
+func indexConditions(userID string, p *Place, wf *WeatherForecast) {
+	text := ConditionsText(p.Name, wf)
+	if text == "" {
+		return
+	}
+	meta := map[string]interface{}{
+		"url":     "/weather",
+		"lat":     p.Lat,
+		"lon":     p.Lon,
+		"updated": wf.GeneratedAt.Format(time.RFC3339),
+	}
+	if c := wf.Current; c != nil {
+		meta["temp_c"] = math.Round(c.TempC)
+		meta["description"] = c.Description
+	}
+	data.IndexOwned(indexID(userID), "weather", "Weather in "+p.Name, text, userID, meta)
+}
+
+// ConditionsText is a line on the weather now and today, like
+// "Weather in Leeds: 14°C, light rain, humidity 80%. Today 9–15°C".
+func ConditionsText(name string, wf *WeatherForecast) string {
+	if wf == nil || (wf.Current == nil && len(wf.DailyItems) == 0) {
+		return ""
+	}
+	var parts []string
+	if c := wf.Current; c != nil {
+		now := fmt.Sprintf("%.0f°C", c.TempC)
+		if c.Description != "" {
+			now += ", " + strings.ToLower(c.Description)
+		}
+		if c.HumidityAvailable {
+			now += fmt.Sprintf(", humidity %d%%", c.Humidity)
+		}
+		if c.WindKphAvailable {
+			now += fmt.Sprintf(", wind %.0f km/h", c.WindKph)
+		}
+		parts = append(parts, now)
+	}
+	if d, ok := dailyItemForDate(wf.DailyItems, time.Now().UTC()); ok {
+		today := fmt.Sprintf("Today %.0f–%.0f°C", d.MinTempC, d.MaxTempC)
+		if d.Description != "" {
+			today += ", " + strings.ToLower(d.Description)
+		}
+		parts = append(parts, today)
+	}
+	return fmt.Sprintf("Weather in %s: %s (%s, %s).", name, strings.Join(parts, ". "),
+		wf.Source, wf.GeneratedAt.UTC().Format("15:04 UTC"))
+}
+
+// ContextFor tells the agent where the account keeps its weather and,
+// if it's been fetched lately, what it's like there. It never fetches.
+func ContextFor(userID string) string {
+	p := SavedPlace(userID)
+	if p == nil {
+		return ""
+	}
+	if wf := cached(p.Lat, p.Lon); wf != nil {
+		if text := ConditionsText(p.Name, wf); text != "" {
+			return text
+		}
+	}
+	return fmt.Sprintf("Weather place: %s (%.4f, %.4f)", p.Name, p.Lat, p.Lon)
+}
+
+// Card is the home card: the forecast for the account's place, or the
+// browser's location without one.
+func Card(userID string) string {
+	p, wf, err := PlaceForecast(userID)
+	if p == nil {
+		return CardHTML()
+	}
+	if err != nil {
+		return `<p class="card-meta">Weather unavailable</p>`
+	}
+	var sb strings.Builder
+	if c := wf.Current; c != nil {
+		fmt.Fprintf(&sb, `<div class="weather-card-now"><span class="weather-card-temp">%.0f°C</span> <span class="weather-card-desc">%s</span></div>`,
+			c.TempC, html.EscapeString(c.Description))
+	}
+	fmt.Fprintf(&sb, `<div class="card-meta">%s</div>`, html.EscapeString(p.Name))
+	if len(wf.DailyItems) > 0 {
+		sb.WriteString(`<div class="weather-card-days">`)
+		for _, d := range wf.DailyItems[:min(3, len(wf.DailyItems))] {
+			fmt.Fprintf(&sb, `<span>%s %.0f°/%.0f°</span>`, d.Date.Format("Mon"), d.MaxTempC, d.MinTempC)
+		}
+		sb.WriteString(`</div>`)
+	}
+	sb.WriteString(app.Link("7-day forecast", "/weather"))
+	return sb.String()
+}
+
+// renderPlace is the saved place's forecast on /weather, with the form to
+// change it.
+func renderPlace(userID string) string {
+	var sb strings.Builder
+	p, wf, err := PlaceForecast(userID)
+	if p != nil {
+		fmt.Fprintf(&sb, `<div class="card weather-place"><h3>%s</h3>`, html.EscapeString(p.Name))
+		switch {
+		case err != nil:
+			sb.WriteString(`<p class="text-error">` + weatherUnavailableMessage + `</p>`)
+		default:
+			sb.WriteString(renderForecast(wf))
+		}
+		sb.WriteString(`<form method="POST" action="/weather/place" class="weather-place-form">
+<input type="hidden" name="action" value="forget">
+<button type="submit" class="btn-secondary">Forget this place</button>
+</form></div>`)
+	}
+	label := "Save a place for your weather"
+	if p != nil {
+		label = "Change your place"
+	}
+	fmt.Fprintf(&sb, `<form method="POST" action="/weather/place" class="weather-place-form">
+<input type="text" name="location" placeholder="City or postcode" class="weather-search-input" required>
+<button type="submit" class="btn">%s</button>
+</form>`, label)
+	return sb.String()
+}
+
+// renderForecast is current conditions and the daily forecast.
+func renderForecast(wf *WeatherForecast) string {
+	var sb strings.Builder
+	if c := wf.Current; c != nil {
+		sb.WriteString(`<div class="weather-current">`)
+		fmt.Fprintf(&sb, `<div class="weather-temp">%.0f°C</div>`, c.TempC)
+		fmt.Fprintf(&sb, `<div class="weather-desc">%s</div>`, html.EscapeString(c.Description))
+		if c.FeelsLikeC != c.TempC {
+			fmt.Fprintf(&sb, `<div class="card-meta">Feels like %.0f°C</div>`, c.FeelsLikeC)
+		}
+		if c.HumidityAvailable {
+			fmt.Fprintf(&sb, `<div class="card-meta">Humidity: %d%%</div>`, c.Humidity)
+		}
+		if c.WindKphAvailable {
+			fmt.Fprintf(&sb, `<div class="card-meta">Wind: %.0f km/h</div>`, c.WindKph)
+		}
+		sb.WriteString(`</div>`)
+	}
+	if len(wf.DailyItems) > 0 {
+		fmt.Fprintf(&sb, `<h3>%d-Day Forecast</h3>`, len(wf.DailyItems))
+		sb.WriteString(`<div class="table-scroll"><table class="data-table weather-table">`)
+		sb.WriteString(`<thead><tr><th>Date</th><th>Conditions</th><th>High</th><th>Low</th><th>Rain</th></tr></thead><tbody>`)
+		for _, d := range wf.DailyItems {
+			rain := "—"
+			if d.WillRain || d.RainMM > 0 {
+				rain = fmt.Sprintf("%.1fmm", d.RainMM)
+			}
+			desc := d.Description
+			if desc == "" {
+				desc = "—"
+			}
+			fmt.Fprintf(&sb, `<tr><td>%s</td><td>%s</td><td>%.0f°C</td><td>%.0f°C</td><td>%s</td></tr>`,
+				d.Date.Format("Mon 2 Jan"), html.EscapeString(desc), d.MaxTempC, d.MinTempC, rain)
+		}
+		sb.WriteString(`</tbody></table></div>`)
+	}
+	fmt.Fprintf(&sb, `<p class="card-meta">%s · updated %s</p>`, html.EscapeString(wf.Source), app.TimeAgo(wf.GeneratedAt))
+	return sb.String()
+}
+
+// PlaceHandler saves or forgets the account's place.
+func PlaceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		app.MethodNotAllowed(w, r)
+		return
+	}
+	_, acc, err := auth.RequireSession(r)
+	if err != nil {
+		app.Unauthorized(w, r)
+		return
+	}
+	get := r.FormValue
+	if app.SendsJSON(r) {
+		var req map[string]interface{}
+		if err := app.DecodeJSON(r, &req); err != nil {
+			app.BadRequest(w, r, "invalid JSON")
+			return
+		}
+		get = func(k string) string {
+			if v, ok := req[k]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}
+	}
+
+	if get("action") == "forget" {
+		DeletePlace(acc.ID)
+		if app.WantsJSON(r) {
+			app.RespondJSON(w, map[string]interface{}{"place": nil})
+			return
+		}
+		http.Redirect(w, r, "/weather", http.StatusSeeOther)
+		return
+	}
+
+	p, err := parsePlace(get)
+	if err != nil {
+		app.BadRequest(w, r, err.Error())
+		return
+	}
+	if err := SetPlace(acc.ID, p); err != nil {
+		app.ServerError(w, r, "Couldn't save your place")
+		return
+	}
+	if app.WantsJSON(r) {
+		app.RespondJSON(w, map[string]interface{}{"place": SavedPlace(acc.ID)})
+		return
+	}
+	http.Redirect(w, r, "/weather", http.StatusSeeOther)
+}
@@ -0,0 +1,168 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mu/internal/auth"
+	"mu/internal/data"
+	"mu/internal/service"
+)
+
+const openMeteoJSON = `{
+	"utc_offset_seconds": 3600,
+	"current": {"time": "2026-07-02T13:00", "temperature_2m": 17.4, "apparent_temperature": 16.1,
+		"relative_humidity_2m": 81, "wind_speed_10m": 12.2, "weather_code": 61},
+	"hourly": {"time": ["2026-07-02T13:00", "2026-07-02T14:00"], "temperature_2m": [17.4, 18],
+		"weather_code": [61, 3], "precipitation": [0.4, 0]},
+	"daily": {"time": ["2026-07-02", "2026-07-03", "2026-07-04", "2026-07-05", "2026-07-06", "2026-07-07", "2026-07-08"],
+		"weather_code": [61, 3, 0, 1, 2, 80, 95],
+		"temperature_2m_max": [19, 20, 23, 24, 22, 18, 17],
+		"temperature_2m_min": [11, 12, 13, 14, 13, 12, 10],
+		"precipitation_sum": [2.5, 0, 0, 0, 0, 4, 9],
+		"precipitation_probability_max": [70, 10, 0, 0, 5, 60, 90]}
+}`
+
+// openMeteoStub serves openMeteoJSON, counting requests.
+func openMeteoStub(t *testing.T) *int32 {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, openMeteoJSON)
+	}))
+	t.Cleanup(server.Close)
+	restoreOpenMeteoBaseURL(t, server.URL)
+	t.Setenv("GOOGLE_API_KEY", "")
+
+	forecastMu.Lock()
+	orig := forecasts
+	forecasts = map[string]*cachedForecast{}
+	forecastMu.Unlock()
+	t.Cleanup(func() {
+		forecastMu.Lock()
+		forecasts = orig
+		forecastMu.Unlock()
+	})
+	return &hits
+}
+
+func TestOpenMeteo(t *testing.T) {
+	openMeteoStub(t)
+
+	wf, err := FetchWeather(53.8, -1.55)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wf.Source != "Open-Meteo" || len(wf.DailyItems) != 7 || len(wf.HourlyItems) != 2 {
+		t.Fatalf("forecast = %+v", wf)
+	}
+	c := wf.Current
+	if c.Description != "Light rain" || c.IconCode != "RAIN" || !c.HumidityAvailable || c.Humidity != 81 || c.WindKph != 12.2 {
+		t.Errorf("current = %+v", c)
+	}
+	// Local times are read in the place's offset.
+	if want := time.Date(2026, 7, 2, 12, 0, 0, 0, time.UTC); !wf.ObservedAt.Equal(want) {
+		t.Errorf("observed at %v, want %v", wf.ObservedAt, want)
+	}
+	if d := wf.DailyItems[6]; d.Description != "Thunderstorm" || !d.WillRain || d.RainMM != 9 || d.Date.Format("2006-01-02") != "2026-07-08" {
+		t.Errorf("last day = %+v", d)
+	}
+	if got := wmoIcon(73); got != "SNOW" {
+		t.Errorf("wmoIcon(73) = %q", got)
+	}
+}
+
+func TestSavedPlace(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// The index workers publish through the service mesh. Set it up here,
+	// before they start, so they don't race later tests to do it.
+	service.Init()
+	data.StartIndexing()
+	hits := openMeteoStub(t)
+
+	placesMu.Lock()
+	origPlaces := savedPlaces
+	savedPlaces = map[string]*Place{}
+	placesMu.Unlock()
+	Geocode = func(q string) (string, float64, float64, error) { return "Leeds", 53.8, -1.55, nil }
+	defer func() {
+		placesMu.Lock()
+		savedPlaces = origPlaces
+		placesMu.Unlock()
+		Geocode = nil
+	}()
+
+	if err := auth.Create(&auth.Account{ID: "weather_user", Name: "Weather", Secret: "secret", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	defer auth.DeleteAccount("weather_user")
+	sess, err := auth.Login("weather_user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/weather/place", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+		w := httptest.NewRecorder()
+		PlaceHandler(w, r)
+		return w
+	}
+
+	// Without a place the card asks the browser.
+	if got := Card("weather_user"); !strings.Contains(got, "weather-card-content") {
+		t.Errorf("card without a place = %s", got)
+	}
+	if w := post("lat=100&lon=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("bad coordinates: %d", w.Code)
+	}
+	if w := post("location=leeds"); w.Code != http.StatusSeeOther {
+		t.Fatalf("save: %d %s", w.Code, w.Body)
+	}
+	if p := SavedPlace("weather_user"); p == nil || p.Name != "Leeds" || p.Lat != 53.8 {
+		t.Fatalf("place = %+v", p)
+	}
+
+	card := Card("weather_user")
+	for _, want := range []string{"17°C", "Light rain", "Leeds", "/weather"} {
+		if !strings.Contains(card, want) {
+			t.Errorf("card missing %q: %s", want, card)
+		}
+	}
+	r := httptest.NewRequest("GET", "/weather", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: sess.Token})
+	if page := renderWeatherPage(r); !strings.Contains(page, "7-Day Forecast") || !strings.Contains(page, "Forget this place") {
+		t.Errorf("page without the forecast:\n%s", page)
+	}
+	// Both came from one fetch.
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("%d fetches, want 1", n)
+	}
+	if got := ContextFor("weather_user"); !strings.HasPrefix(got, "Weather in Leeds: 17°C, light rain, humidity 81%") {
+		t.Errorf("context = %q", got)
+	}
+	// The conditions are indexed for the account alone.
+	var found bool
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline) && !found; time.Sleep(5 * time.Millisecond) {
+		found = data.GetByID(indexID("weather_user")) != nil
+	}
+	if !found {
+		t.Error("conditions not indexed")
+	}
+	if len(data.Search("Leeds", 5, data.WithType("weather"))) != 0 {
+		t.Error("conditions are public")
+	}
+
+	if w := post("action=forget"); w.Code != http.StatusSeeOther || SavedPlace("weather_user") != nil {
+		t.Errorf("forget: %d", w.Code)
+	}
+	if ContextFor("weather_user") != "" {
+		t.Error("forgotten place still in context")
+	}
+}
@@ -0,0 +1,12 @@
+package weather
+
+import "mu/internal/app"
+
+func init() { app.Register(routes{}) }
+
+type routes struct{}
+
+func (routes) RegisterRoutes(r *app.Router) {
+	r.HandleFunc("/weather", Handler)
+	r.HandleFunc("/weather/place", PlaceHandler)
+}
@@ -14,6 +14,7 @@ import (
 
 // Load initialises the weather package and registers its go-micro service.
 func Load() {
+	loadPlaces()
 	if err := service.Register("weather", new(Server)); err != nil {
 		app.Log("weather", "service register failed: %v", err)
 	}
@@ -143,27 +144,28 @@ func handleJSON(w http.ResponseWriter, r *http.Request) {
 
 	includePollen := r.URL.Query().Get("pollen") == "1"
 
-	// Check credits
-	canProceed, _, cost, _ := wallet.CheckQuota(acc.ID, wallet.OpWeatherForecast)
-	if !canProceed {
-		app.RespondError(w, http.StatusPaymentRequired, "Insufficient credits. Top up your wallet to continue.")
-		return
-	}
-
-	// Fetch weather
-	forecast, err := FetchWeather(lat, lon)
-	if err != nil {
-		app.RespondError(w, http.StatusServiceUnavailable, weatherUnavailableMessage)
-		return
-	}
-
-	// Deduct credits
-	if cost > 0 {
-		wallet.DeductCredits(acc.ID, cost, wallet.OpWeatherForecast, nil)
+	// A forecast already fetched for the spot is free; a new one costs
+	forecast := cached(lat, lon)
+	if forecast == nil {
+		canProceed, _, cost, _ := wallet.CheckQuota(acc.ID, wallet.OpWeatherForecast)
+		if !canProceed {
+			app.RespondError(w, http.StatusPaymentRequired, "Insufficient credits. Top up your wallet to continue.")
+			return
+		}
+		forecast, err = Forecast(lat, lon)
+		if err != nil {
+			app.RespondError(w, http.StatusServiceUnavailable, weatherUnavailableMessage)
+			return
+		}
+		if cost > 0 {
+			wallet.DeductCredits(acc.ID, cost, wallet.OpWeatherForecast, nil)
+		}
 	}
 
 	result := map[string]interface{}{
 		"forecast": forecast,
+		"lat":      lat,
+		"lon":      lon,
 	}
 
 	// Fetch pollen if requested and quota allows
@@ -209,6 +211,9 @@ func renderWeatherPage(r *http.Request) string {
 		return sb.String()
 	}
 
+	// The saved place's forecast, or the form to save one
+	sb.WriteString(renderPlace(sess.Account))
+
 	// Cost info
 	sb.WriteString(`<p class="card-desc">Get the local weather forecast for your area. `)
 
@@ -339,9 +344,17 @@ func renderWeatherPage(r *http.Request) string {
     var f = data.forecast;
     document.getElementById('weather-result').style.display = '';
 
-    // Location header
+    // Location header, with a button to make it the saved place
     if (locationName) {
-      document.getElementById('weather-location').innerHTML = '<h2 style="margin-bottom:12px;">' + escHtml(locationName) + '</h2>';
+      var save = '';
+      if (data.lat !== undefined) {
+        save = '<form method="POST" action="/weather/place" class="weather-place-form">' +
+          '<input type="hidden" name="location" value="' + escHtml(locationName) + '">' +
+          '<input type="hidden" name="lat" value="' + data.lat + '">' +
+          '<input type="hidden" name="lon" value="' + data.lon + '">' +
+          '<button type="submit" class="btn-secondary">Save as my place</button></form>';
+      }
+      document.getElementById('weather-location').innerHTML = '<h2 style="margin-bottom:12px;">' + escHtml(locationName) + '</h2>' + save;
     }
 
     // Current conditions
@@ -81,6 +81,7 @@ func TestForecastTextProviderUnavailableIsClear(t *testing.T) {
 	server := httptest.NewServer(http.NotFoundHandler())
 	t.Cleanup(server.Close)
 	restoreNWSBaseURL(t, server.URL)
+	restoreOpenMeteoBaseURL(t, server.URL)
 
 	got := ForecastText(51.5074, -0.1278)
 	if got != weatherUnavailableMessage {
@@ -95,6 +96,7 @@ func TestForecastTextUsesNWSFallbackWithoutGoogleKey(t *testing.T) {
 	server := httptest.NewServer(mux)
 	t.Cleanup(server.Close)
 	restoreNWSBaseURL(t, server.URL+"/points")
+	restoreOpenMeteoBaseURL(t, server.URL+"/open-meteo")
 
 	mux.HandleFunc("/points/40.7128,-74.0060", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, `{
@@ -153,6 +155,13 @@ func restoreNWSBaseURL(t *testing.T, value string) {
 	t.Cleanup(func() { nwsBaseURL = old })
 }
 
+func restoreOpenMeteoBaseURL(t *testing.T, value string) {
+	t.Helper()
+	old := openMeteoBaseURL
+	openMeteoBaseURL = value
+	t.Cleanup(func() { openMeteoBaseURL = old })
+}
+
 func TestCardHTMLShowsWeatherUnavailableOnFetchFailure(t *testing.T) {
 	got := CardHTML()
 	if !strings.Contains(got, "Weather unavailable") {